@@ -17,10 +17,12 @@ limitations under the License.
 package blnk
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
 	"github.com/blnkfinance/blnk/internal/request"
+	"github.com/blnkfinance/blnk/internal/resilience"
 	"github.com/blnkfinance/blnk/model"
 
 	"github.com/blnkfinance/blnk/config"
@@ -55,7 +57,7 @@ func applyExternalAccount(account *model.Account) error {
 		// Set the Authorization header for the HTTP request using the configuration.
 		req.Header.Set("Authorization", cnf.AccountNumberGeneration.HttpService.Headers.Authorization)
 		var response accountDetails
-		_, err = request.Call(req, &response)
+		_, err = request.CallWith(resilience.Get(resilience.AccountNumbers).Client(), req, &response)
 		if err != nil {
 			return err
 		}
@@ -74,18 +76,19 @@ func applyExternalAccount(account *model.Account) error {
 // based on the identity type (organization or individual).
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - account *model.Account: A pointer to the Account model to which the name will be applied.
 //
 // Returns:
 // - error: An error if the identity could not be retrieved.
-func (l *Blnk) applyAccountName(account *model.Account) error {
+func (l *Blnk) applyAccountName(ctx context.Context, account *model.Account) error {
 	if account.Name == "" {
 
-		identity, err := l.GetIdentity(account.IdentityID)
+		identity, err := l.GetIdentity(ctx, account.IdentityID)
 		if err != nil {
 			return err
 		}
-		if identity.IdentityType == "organization" {
+		if identityKind(identity.IdentityType) == model.IdentityTypeOrganization {
 			account.Name = identity.OrganizationName
 		} else {
 			account.Name = fmt.Sprintf("%s %s", identity.FirstName, identity.LastName)
@@ -99,12 +102,13 @@ func (l *Blnk) applyAccountName(account *model.Account) error {
 // with the balance's identity ID, ledger ID, and currency if they are not empty.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - account *model.Account: A pointer to the Account model to be updated.
 //
 // Returns:
 // - error: An error if the balance could not be retrieved.
-func (l *Blnk) overrideLedgerAndIdentity(account *model.Account) error {
-	balance, err := l.datasource.GetBalanceByIDLite(account.BalanceID)
+func (l *Blnk) overrideLedgerAndIdentity(ctx context.Context, account *model.Account) error {
+	balance, err := l.datasource.GetBalanceByIDLite(ctx, account.BalanceID)
 	if err != nil {
 		return err
 	}
@@ -127,18 +131,19 @@ func (l *Blnk) overrideLedgerAndIdentity(account *model.Account) error {
 // It overrides the ledger and identity details, applies the account name, and fetches external account details.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - account model.Account: The Account model to be created.
 //
 // Returns:
 // - model.Account: The created Account model.
 // - error: An error if the account could not be created.
-func (l *Blnk) CreateAccount(account model.Account) (model.Account, error) {
-	err := l.overrideLedgerAndIdentity(&account)
+func (l *Blnk) CreateAccount(ctx context.Context, account model.Account) (model.Account, error) {
+	err := l.overrideLedgerAndIdentity(ctx, &account)
 	if err != nil {
 		return model.Account{}, err
 	}
 
-	err = l.applyAccountName(&account)
+	err = l.applyAccountName(ctx, &account)
 	if err != nil {
 		return model.Account{}, err
 	}
@@ -147,33 +152,35 @@ func (l *Blnk) CreateAccount(account model.Account) (model.Account, error) {
 	if err != nil {
 		return model.Account{}, err
 	}
-	return l.datasource.CreateAccount(account)
+	return l.datasource.CreateAccount(ctx, account)
 }
 
 // GetAccount retrieves an account by its ID.
 // It fetches the account from the datasource and includes additional data as specified.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - id string: The ID of the account to retrieve.
 // - include []string: A slice of strings specifying additional data to include.
 //
 // Returns:
 // - *model.Account: A pointer to the Account model if found.
 // - error: An error if the account could not be retrieved.
-func (l *Blnk) GetAccount(id string, include []string) (*model.Account, error) {
-	return l.datasource.GetAccountByID(id, include)
+func (l *Blnk) GetAccount(ctx context.Context, id string, include []string) (*model.Account, error) {
+	return l.datasource.GetAccountByID(ctx, id, include)
 }
 
 // GetAccountByNumber retrieves an account from the database by its account number.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - id string: The account number of the account to retrieve.
 //
 // Returns:
 // - *model.Account: A pointer to the Account model if found.
 // - error: An error if the account could not be retrieved.
-func (l *Blnk) GetAccountByNumber(id string) (*model.Account, error) {
-	return l.datasource.GetAccountByNumber(id)
+func (l *Blnk) GetAccountByNumber(ctx context.Context, id string) (*model.Account, error) {
+	return l.datasource.GetAccountByNumber(ctx, id)
 }
 
 // GetAllAccounts retrieves all accounts from the database.
@@ -181,6 +188,6 @@ func (l *Blnk) GetAccountByNumber(id string) (*model.Account, error) {
 // Returns:
 // - []model.Account: A slice of Account models.
 // - error: An error if the accounts could not be retrieved.
-func (l *Blnk) GetAllAccounts() ([]model.Account, error) {
-	return l.datasource.GetAllAccounts()
+func (l *Blnk) GetAllAccounts(ctx context.Context) ([]model.Account, error) {
+	return l.datasource.GetAllAccounts(ctx)
 }
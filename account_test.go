@@ -16,6 +16,7 @@ limitations under the License.
 package blnk
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 	"time"
@@ -66,7 +67,7 @@ func TestCreateAccount(t *testing.T) {
 
 	config.MockConfig(&config.Configuration{Server: config.ServerConfig{SecretKey: "some-secret"}, AccountNumberGeneration: config.AccountNumberGenerationConfig{HttpService: config.AccountGenerationHttpService{}}})
 
-	result, err := d.CreateAccount(account)
+	result, err := d.CreateAccount(context.Background(), account)
 	assert.NoError(t, err)
 	assert.Equal(t, account.Name, result.Name)
 	assert.Equal(t, account.Number, result.Number)
@@ -115,7 +116,7 @@ func TestCreateAccountWithExternalGenerator(t *testing.T) {
 		WithArgs(sqlmock.AnyArg(), account.Name, account.Number, account.BankName, account.Currency, account.LedgerID, account.IdentityID, account.BalanceID, sqlmock.AnyArg(), metaDataJSON).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	result, err := d.CreateAccount(account)
+	result, err := d.CreateAccount(context.Background(), account)
 	assert.NoError(t, err)
 	assert.Equal(t, "123456789", result.Number)
 	assert.Equal(t, "Blnk Bank", result.BankName)
@@ -175,7 +176,7 @@ func TestGetAccountByID(t *testing.T) {
 
 	// Expect transaction to commit
 	mock.ExpectCommit()
-	result, err := d.GetAccount(testID, nil)
+	result, err := d.GetAccount(context.Background(), testID, nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, testID, result.AccountID)
@@ -228,7 +229,7 @@ func TestGetAllAccounts(t *testing.T) {
 
 	mock.ExpectQuery("SELECT .* FROM blnk.accounts").WillReturnRows(rows)
 
-	result, err := d.GetAllAccounts()
+	result, err := d.GetAllAccounts(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, result, 2)
 	assert.Equal(t, account1.AccountID, result[0].AccountID)
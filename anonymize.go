@@ -0,0 +1,429 @@
+package blnk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+)
+
+// AnonymizeOptions selects the time slice of a ledger to export anonymized and how it is anonymized.
+type AnonymizeOptions struct {
+	ReplayExportOptions
+
+	// Salt keys the fakes personal data is replaced with: the same value is replaced by the same fake wherever
+	// it appears, and by the same fake across exports anonymized with the same salt. A random salt is used when
+	// empty, so exports cannot be linked to each other.
+	Salt string
+
+	// PerturbAmounts moves every amount by up to this fraction of it, from 0 to leave amounts as recorded up to
+	// but not including 1.
+	PerturbAmounts float64
+}
+
+// Fakes personal data is replaced with. They are picked by the keyed hash of the value they replace.
+var (
+	anonymizedFirstNames = []string{"Abena", "Bola", "Carmen", "Dmitri", "Emeka", "Fatima", "Grace", "Hiro", "Ines", "Jonas", "Kemi", "Liam", "Mei", "Nora", "Omar", "Priya", "Quentin", "Rosa", "Samuel", "Tariq", "Uma", "Victor", "Wanjiru", "Yusuf", "Zara"}
+	anonymizedLastNames  = []string{"Adeyemi", "Bauer", "Castillo", "Dubois", "Eriksen", "Fernandes", "Gallagher", "Haddad", "Ivanova", "Johansson", "Kowalski", "Lindqvist", "Mensah", "Nakamura", "Okafor", "Petrov", "Quinn", "Rossi", "Santos", "Tanaka", "Usman", "Varga", "Whitfield", "Yilmaz", "Zhou"}
+	anonymizedCompanies  = []string{"Acme Trading", "Blue Harbor Logistics", "Cedar & Pine Studio", "Delta Fresh Foods", "Evergreen Analytics", "Foxglove Health", "Granite Works", "Helios Energy", "Ironbridge Capital", "Juniper Retail"}
+	anonymizedStreets    = []string{"Maple Avenue", "Oak Street", "Harbour Road", "Station Lane", "Church Street", "Mill Road", "Park Crescent", "River Walk", "Victoria Road", "Willow Close"}
+	anonymizedCities     = []string{"Springfield", "Riverside", "Fairview", "Kingsport", "Lakewood", "Milton", "Greenville", "Ashford", "Bridgeton", "Westbrook"}
+	anonymizedNarratives = []string{"Grocery purchase", "Coffee shop", "Online subscription", "Ride share", "Utility bill", "Restaurant", "Bookstore", "Pharmacy", "Transfer to savings", "Salary payment", "Hardware store", "Airline ticket"}
+)
+
+// ExportAnonymizedSlice exports a time slice of a ledger as ExportReplaySlice does, with the identities of its
+// balances, and anonymizes it with AnonymizeSlice.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - opts AnonymizeOptions: The ledger and window to export, and how to anonymize them.
+//
+// Returns:
+// - *model.AnonymizedSlice: The anonymized slice.
+// - error: An error if the options are invalid or the slice could not be read.
+func (l *Blnk) ExportAnonymizedSlice(ctx context.Context, opts AnonymizeOptions) (*model.AnonymizedSlice, error) {
+	ctx, span := tracer.Start(ctx, "ExportAnonymizedSlice")
+	defer span.End()
+
+	if opts.PerturbAmounts < 0 || opts.PerturbAmounts >= 1 {
+		return nil, errors.New("perturb amounts must be at least 0 and less than 1")
+	}
+	slice, err := l.ExportReplaySlice(ctx, opts.ReplayExportOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Slices are exported with the lite balances, which leave out the identities and metadata of balances
+	var identities []*model.Identity
+	seen := make(map[string]bool)
+	for i, balance := range slice.Balances {
+		details, err := l.datasource.GetBalanceByID(ctx, balance.BalanceID, nil, false)
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("failed to get balance %s: %w", balance.BalanceID, err)
+		}
+		for _, b := range []*model.Balance{balance, slice.Production[i]} {
+			b.IdentityID = details.IdentityID
+			b.MetaData = details.MetaData
+		}
+
+		if details.IdentityID == "" || seen[details.IdentityID] {
+			continue
+		}
+		seen[details.IdentityID] = true
+		identity, err := l.datasource.GetIdentityByIDIncludingDeleted(ctx, details.IdentityID)
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("failed to get identity %s: %w", details.IdentityID, err)
+		}
+		identities = append(identities, identity)
+	}
+
+	return AnonymizeSlice(slice, identities, opts)
+}
+
+// AnonymizeSlice returns a copy of a replay slice and the identities of its balances with their personal data
+// replaced by realistic fakes. IDs, references, indicators, hashes and the string values of metadata are
+// replaced consistently, keeping ID prefixes and metadata keys; names, contact details and addresses are
+// replaced by made up ones and dates of birth shifted. Currencies, countries, statuses and timestamps are kept.
+//
+// When amounts are perturbed, each transaction is scaled by a factor it shares with the transactions committing
+// or voiding it, and the states balances start in by a factor of their own. The states production reached are
+// recomputed by replaying the anonymized slice and moved by how far production drifted from the replay of the
+// original slice, so an anonymized slice replays with the same differences as the original.
+//
+// Parameters:
+// - slice *model.ReplaySlice: The slice to anonymize. It is left untouched.
+// - identities []*model.Identity: The identities of the slice's balances.
+// - opts AnonymizeOptions: The salt and perturbation to anonymize with.
+//
+// Returns:
+// - *model.AnonymizedSlice: The anonymized slice.
+// - error: An error if the options are invalid or a random salt could not be generated.
+func AnonymizeSlice(slice *model.ReplaySlice, identities []*model.Identity, opts AnonymizeOptions) (*model.AnonymizedSlice, error) {
+	if opts.PerturbAmounts < 0 || opts.PerturbAmounts >= 1 {
+		return nil, errors.New("perturb amounts must be at least 0 and less than 1")
+	}
+	salt := opts.Salt
+	if salt == "" {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+		salt = hex.EncodeToString(random)
+	}
+	a := anonymizer{key: []byte(salt), perturb: opts.PerturbAmounts}
+
+	anonymized := &model.AnonymizedSlice{
+		ReplaySlice: model.ReplaySlice{
+			LedgerID:     a.id(slice.LedgerID),
+			From:         slice.From,
+			To:           slice.To,
+			ExportedAt:   slice.ExportedAt,
+			Balances:     make([]*model.Balance, 0, len(slice.Balances)),
+			Transactions: make([]*model.Transaction, 0, len(slice.Transactions)),
+			Production:   make([]*model.Balance, 0, len(slice.Production)),
+		},
+		Identities:       make([]*model.Identity, 0, len(identities)),
+		AmountsPerturbed: opts.PerturbAmounts,
+	}
+	for _, balance := range slice.Balances {
+		anonymized.Balances = append(anonymized.Balances, a.balance(balance, true))
+	}
+	for _, txn := range slice.Transactions {
+		anonymized.Transactions = append(anonymized.Transactions, a.transaction(txn))
+	}
+	for _, identity := range identities {
+		anonymized.Identities = append(anonymized.Identities, a.identity(identity))
+	}
+
+	if a.perturb == 0 {
+		for _, balance := range slice.Production {
+			anonymized.Production = append(anonymized.Production, a.balance(balance, false))
+		}
+		return anonymized, nil
+	}
+
+	drift := make(map[string]map[string]*big.Int)
+	for _, diff := range Replay(slice, ReplayOptions{}).Diffs {
+		id := a.id(diff.BalanceID)
+		if drift[id] == nil {
+			drift[id] = make(map[string]*big.Int)
+		}
+		drift[id][diff.Field] = diff.Difference
+	}
+	replayed := make(map[string]*model.Balance)
+	for _, balance := range Replay(&anonymized.ReplaySlice, ReplayOptions{}).Balances {
+		replayed[balance.BalanceID] = balance
+	}
+	for _, balance := range slice.Production {
+		production := a.balance(balance, false)
+		if state, ok := replayed[production.BalanceID]; ok {
+			fields := drift[production.BalanceID]
+			production.Balance = new(big.Int).Sub(state.Balance, orZero(fields["balance"]))
+			production.CreditBalance = new(big.Int).Sub(state.CreditBalance, orZero(fields["credit_balance"]))
+			production.DebitBalance = new(big.Int).Sub(state.DebitBalance, orZero(fields["debit_balance"]))
+			production.InflightCreditBalance = new(big.Int).Sub(state.InflightCreditBalance, orZero(fields["inflight_credit_balance"]))
+			production.InflightDebitBalance = new(big.Int).Sub(state.InflightDebitBalance, orZero(fields["inflight_debit_balance"]))
+			production.InflightBalance = new(big.Int).Sub(production.InflightCreditBalance, production.InflightDebitBalance)
+		}
+		anonymized.Production = append(anonymized.Production, production)
+	}
+	return anonymized, nil
+}
+
+// anonymizer replaces values with fakes picked by their hash keyed with the salt.
+type anonymizer struct {
+	key     []byte
+	perturb float64
+}
+
+// sum returns the keyed hash of a value. The kind separates values that must not be replaced alike, such as a
+// first and a last name that happen to be equal.
+func (a anonymizer) sum(kind, value string) []byte {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// pick returns the fake from a list the value is replaced with.
+func (a anonymizer) pick(kind, value string, fakes []string) string {
+	return fakes[binary.BigEndian.Uint64(a.sum(kind, value))%uint64(len(fakes))]
+}
+
+// digits returns n digits derived from a value.
+func (a anonymizer) digits(kind, value string, n int) string {
+	sum := a.sum(kind, value)
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteByte('0' + sum[i%len(sum)]%10)
+	}
+	return sb.String()
+}
+
+// id replaces an ID, keeping its prefix, such as bln_ or txn_.
+func (a anonymizer) id(id string) string {
+	if id == "" {
+		return ""
+	}
+	prefix := ""
+	if i := strings.LastIndex(id, "_"); i > 0 {
+		prefix = id[:i+1]
+	}
+	sum := a.sum("id", id)
+	sum[6] = (sum[6] & 0x0f) | 0x40
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(sum[:16])
+	return fmt.Sprintf("%s%s-%s-%s-%s-%s", prefix, h[:8], h[8:12], h[12:16], h[16:20], h[20:32])
+}
+
+// token replaces a free-form value with a token of the same kind.
+func (a anonymizer) token(kind, value string) string {
+	if value == "" {
+		return ""
+	}
+	return kind + "_" + hex.EncodeToString(a.sum(kind, value)[:8])
+}
+
+// indicator replaces the indicator of a balance, keeping the @ of system balances.
+func (a anonymizer) indicator(indicator string) string {
+	if indicator == "" {
+		return ""
+	}
+	if strings.HasPrefix(indicator, "@") {
+		return "@" + a.token("account", indicator)
+	}
+	return a.token("account", indicator)
+}
+
+// metaData replaces the string values of metadata, in nested objects and lists too, keeping its keys.
+func (a anonymizer) metaData(metaData map[string]interface{}) map[string]interface{} {
+	if metaData == nil {
+		return nil
+	}
+	anonymized := make(map[string]interface{}, len(metaData))
+	for key, value := range metaData {
+		anonymized[key] = a.metaDataValue(value)
+	}
+	return anonymized
+}
+
+func (a anonymizer) metaDataValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return a.token("value", v)
+	case map[string]interface{}:
+		return a.metaData(v)
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = a.metaDataValue(item)
+		}
+		return values
+	case nil, bool, float64, int, int64:
+		return v
+	default:
+		// Values of other types, such as structs stored before the metadata was serialized, are dropped rather
+		// than risk leaking what they hold
+		return nil
+	}
+}
+
+// scale moves an amount by the factor of a key, between 1 - perturb and 1 + perturb. Scaled amounts are rounded
+// down, so the scaled commits of an inflight transaction never add up to more than the scaled transaction.
+func (a anonymizer) scale(key string, amount *big.Int) *big.Int {
+	if amount == nil || a.perturb == 0 {
+		return amount
+	}
+	u := float64(binary.BigEndian.Uint64(a.sum("amount", key))>>11) / (1 << 53)
+	factor := new(big.Rat).SetFloat64(1 + a.perturb*(2*u-1))
+	scaled := new(big.Rat).Mul(new(big.Rat).SetInt(amount), factor)
+	return new(big.Int).Quo(scaled.Num(), scaled.Denom())
+}
+
+// balance anonymizes a balance. The states balances start in are perturbed; the states production reached are
+// recomputed by the caller instead.
+func (a anonymizer) balance(balance *model.Balance, perturb bool) *model.Balance {
+	anonymized := cloneReplayBalance(balance)
+	anonymized.BalanceID = a.id(balance.BalanceID)
+	anonymized.LedgerID = a.id(balance.LedgerID)
+	anonymized.IdentityID = a.id(balance.IdentityID)
+	anonymized.Indicator = a.indicator(balance.Indicator)
+	anonymized.MetaData = a.metaData(balance.MetaData)
+	anonymized.Identity = nil
+	anonymized.Ledger = nil
+	anonymized.ShardOf = ""
+
+	if perturb {
+		anonymized.CreditBalance = a.scale(balance.BalanceID, anonymized.CreditBalance)
+		anonymized.DebitBalance = a.scale(balance.BalanceID, anonymized.DebitBalance)
+		anonymized.InflightCreditBalance = a.scale(balance.BalanceID, anonymized.InflightCreditBalance)
+		anonymized.InflightDebitBalance = a.scale(balance.BalanceID, anonymized.InflightDebitBalance)
+		anonymized.Balance = new(big.Int).Sub(anonymized.CreditBalance, anonymized.DebitBalance)
+		anonymized.InflightBalance = new(big.Int).Sub(anonymized.InflightCreditBalance, anonymized.InflightDebitBalance)
+	}
+	return anonymized
+}
+
+// transaction anonymizes a transaction, scaling it by the factor of the transaction it commits or voids, if any.
+func (a anonymizer) transaction(txn *model.Transaction) *model.Transaction {
+	anonymized := &model.Transaction{
+		TransactionID:     a.id(txn.TransactionID),
+		ParentTransaction: a.id(txn.ParentTransaction),
+		Source:            a.id(txn.Source),
+		Destination:       a.id(txn.Destination),
+		Reference:         a.token("ref", txn.Reference),
+		GroupID:           a.token("group", txn.GroupID),
+		ExternalAccountID: a.id(txn.ExternalAccountID),
+		Currency:          txn.Currency,
+		Status:            txn.Status,
+		Hash:              a.token("hash", txn.Hash),
+		Rate:              txn.Rate,
+		Precision:         txn.Precision,
+		Amount:            txn.Amount,
+		CreatedAt:         txn.CreatedAt,
+		EffectiveDate:     txn.EffectiveDate,
+		MetaData:          a.metaData(txn.MetaData),
+	}
+	if txn.Description != "" {
+		anonymized.Description = a.pick("narrative", txn.Description, anonymizedNarratives)
+	}
+
+	if txn.PreciseAmount != nil {
+		key := txn.TransactionID
+		if txn.ParentTransaction != "" {
+			key = txn.ParentTransaction
+		}
+		anonymized.PreciseAmount = new(big.Int).Set(a.scale(key, txn.PreciseAmount))
+		if a.perturb > 0 {
+			precision := txn.Precision
+			if precision == 0 {
+				precision = 1
+			}
+			anonymized.Amount, _ = new(big.Float).Quo(new(big.Float).SetInt(anonymized.PreciseAmount), big.NewFloat(precision)).Float64()
+		}
+	}
+	return anonymized
+}
+
+// identity anonymizes an identity. Only the fields that describe the kind of customer rather than who they are
+// are kept, so personal fields added to identities later are left out rather than leaked.
+func (a anonymizer) identity(identity *model.Identity) *model.Identity {
+	id := identity.IdentityID
+	anonymized := &model.Identity{
+		IdentityID:              a.id(id),
+		IdentityType:            identity.IdentityType,
+		Category:                identity.Category,
+		Gender:                  identity.Gender,
+		Nationality:             identity.Nationality,
+		Country:                 identity.Country,
+		State:                   identity.State,
+		CreatedAt:               identity.CreatedAt,
+		MetaData:                a.metaData(identity.MetaData),
+		Locale:                  identity.Locale,
+		Timezone:                identity.Timezone,
+		VerificationStatus:      identity.VerificationStatus,
+		VerificationSubmittedAt: identity.VerificationSubmittedAt,
+		VerifiedAt:              identity.VerifiedAt,
+		VerificationRejectedAt:  identity.VerificationRejectedAt,
+		RiskScore:               identity.RiskScore,
+		RiskLevel:               identity.RiskLevel,
+		RiskScoredAt:            identity.RiskScoredAt,
+		VerifiedEmail:           identity.VerifiedEmail,
+		VerifiedPhone:           identity.VerifiedPhone,
+		EmailVerifiedAt:         identity.EmailVerifiedAt,
+		PhoneVerifiedAt:         identity.PhoneVerifiedAt,
+		Tags:                    identity.Tags,
+		Status:                  identity.Status,
+		StatusChangedAt:         identity.StatusChangedAt,
+		Version:                 identity.Version,
+		DeletedAt:               identity.DeletedAt,
+	}
+
+	first := a.pick("first_name", id, anonymizedFirstNames)
+	last := a.pick("last_name", id, anonymizedLastNames)
+	if identity.FirstName != "" {
+		anonymized.FirstName = first
+	}
+	if identity.LastName != "" {
+		anonymized.LastName = last
+	}
+	if identity.OtherNames != "" {
+		anonymized.OtherNames = a.pick("other_names", id, anonymizedFirstNames)
+	}
+	if identity.OrganizationName != "" {
+		anonymized.OrganizationName = a.pick("organization_name", id, anonymizedCompanies)
+	}
+	if identity.EmailAddress != "" {
+		anonymized.EmailAddress = fmt.Sprintf("%s.%s%s@example.com", strings.ToLower(first), strings.ToLower(last), a.digits("email", id, 3))
+	}
+	if identity.PhoneNumber != "" {
+		anonymized.PhoneNumber = "+1555" + a.digits("phone", id, 7)
+	}
+	if identity.Street != "" {
+		anonymized.Street = fmt.Sprintf("%d %s", 1+binary.BigEndian.Uint16(a.sum("street_number", id))%200, a.pick("street", id, anonymizedStreets))
+	}
+	if identity.City != "" {
+		anonymized.City = a.pick("city", id, anonymizedCities)
+	}
+	if identity.PostCode != "" {
+		anonymized.PostCode = a.digits("post_code", id, 5)
+	}
+	if !identity.DOB.IsZero() {
+		// Dates of birth move by up to half a year, so ages stay about right
+		days := int(binary.BigEndian.Uint16(a.sum("dob", id))%365) - 182
+		anonymized.DOB = identity.DOB.Add(time.Duration(days) * 24 * time.Hour)
+	}
+	return anonymized
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func anonymizeTestIdentity() *model.Identity {
+	return &model.Identity{
+		IdentityID: "idt_ada", IdentityType: model.IdentityTypeIndividual, FirstName: "Ada", LastName: "Lovelace",
+		EmailAddress: "ada@lovelace.dev", PhoneNumber: "+447700900123", Street: "12 St James's Square",
+		City: "London", PostCode: "SW1Y 4JH", Country: "GB", DOB: time.Date(1990, 12, 10, 0, 0, 0, 0, time.UTC),
+		MetaData: map[string]interface{}{"employer": "Analytical Engines", "tier": float64(2)},
+	}
+}
+
+func TestAnonymizeSlice_ReplacesPersonalData(t *testing.T) {
+	slice := replayTestSlice()
+	slice.Balances[1].IdentityID = "idt_ada"
+	slice.Balances[1].Indicator = "ada@lovelace.dev"
+	slice.Transactions[1].Reference = "invoice-ada-42"
+	slice.Transactions[1].Description = "Rent for Ada Lovelace"
+	slice.Transactions[1].MetaData = map[string]interface{}{"note": "paid by Ada", "items": []interface{}{"Ada"}}
+
+	anonymized, err := AnonymizeSlice(slice, []*model.Identity{anonymizeTestIdentity()}, AnonymizeOptions{Salt: "s3cret"})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(anonymized)
+	require.NoError(t, err)
+	for _, pii := range []string{"Ada", "Lovelace", "lovelace", "idt_ada", "bln_a", "txn_1", "invoice", "London", "SW1Y", "+4477", "Analytical"} {
+		assert.NotContains(t, string(data), pii)
+	}
+
+	identity := anonymized.Identities[0]
+	assert.True(t, strings.HasPrefix(identity.IdentityID, "idt_"))
+	assert.Equal(t, identity.IdentityID, anonymized.Balances[1].IdentityID)
+	assert.NotEmpty(t, identity.FirstName)
+	assert.Contains(t, identity.EmailAddress, "@example.com")
+	assert.Equal(t, "GB", identity.Country)
+	assert.Equal(t, float64(2), identity.MetaData["tier"])
+	assert.InDelta(t, 0, identity.DOB.Sub(time.Date(1990, 12, 10, 0, 0, 0, 0, time.UTC)).Hours()/24, 183)
+
+	// The slice is left untouched and IDs are replaced consistently, so the anonymized slice replays as the
+	// original does
+	assert.Equal(t, "bln_a", slice.Balances[1].BalanceID)
+	txn := anonymized.Transactions[1]
+	assert.True(t, strings.HasPrefix(txn.TransactionID, "txn_"))
+	assert.Equal(t, anonymized.Balances[1].BalanceID, txn.Source)
+	assert.Equal(t, anonymized.Transactions[0].Hash, txn.Hash, "transactions sharing a hash still share one")
+	assert.Equal(t, big.NewInt(1000), txn.PreciseAmount)
+	result := Replay(&anonymized.ReplaySlice, ReplayOptions{})
+	assert.True(t, result.Matches, "diffs: %+v", result.Diffs)
+	assert.Len(t, result.Duplicates, 1)
+
+	again, err := AnonymizeSlice(slice, []*model.Identity{anonymizeTestIdentity()}, AnonymizeOptions{Salt: "s3cret"})
+	require.NoError(t, err)
+	assert.Equal(t, anonymized, again)
+
+	other, err := AnonymizeSlice(slice, []*model.Identity{anonymizeTestIdentity()}, AnonymizeOptions{})
+	require.NoError(t, err)
+	assert.NotEqual(t, anonymized.Balances[1].BalanceID, other.Balances[1].BalanceID)
+}
+
+func TestAnonymizeSlice_PerturbAmounts(t *testing.T) {
+	slice := replayTestSlice()
+
+	anonymized, err := AnonymizeSlice(slice, nil, AnonymizeOptions{Salt: "s3cret", PerturbAmounts: 0.2})
+	require.NoError(t, err)
+	assert.Equal(t, 0.2, anonymized.AmountsPerturbed)
+
+	changed := false
+	for i, txn := range anonymized.Transactions {
+		original := slice.Transactions[i].PreciseAmount.Int64()
+		amount := txn.PreciseAmount.Int64()
+		assert.GreaterOrEqual(t, amount, original*8/10)
+		assert.LessOrEqual(t, amount, original*12/10)
+		assert.InDelta(t, float64(amount)/100, txn.Amount, 0.001)
+		changed = changed || amount != original
+	}
+	assert.True(t, changed)
+
+	result := Replay(&anonymized.ReplaySlice, ReplayOptions{})
+	assert.True(t, result.Matches, "diffs: %+v failures: %+v", result.Diffs, result.Failures)
+
+	// Production keeps drifting from the replay as far as it did in the original slice
+	slice.Production[0].Balance = big.NewInt(7600)
+	anonymized, err = AnonymizeSlice(slice, nil, AnonymizeOptions{Salt: "s3cret", PerturbAmounts: 0.2})
+	require.NoError(t, err)
+	result = Replay(&anonymized.ReplaySlice, ReplayOptions{})
+	require.Len(t, result.Diffs, 1)
+	assert.Equal(t, "balance", result.Diffs[0].Field)
+	assert.Equal(t, big.NewInt(100), result.Diffs[0].Difference)
+
+	_, err = AnonymizeSlice(slice, nil, AnonymizeOptions{PerturbAmounts: 1})
+	assert.Error(t, err)
+}
+
+func TestExportAnonymizedSlice(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	mockDS.On("GetReplayTransactions", mock.Anything, "ldg_1", from, to).Return([]*model.Transaction{
+		{TransactionID: "txn_1", Source: "bln_b", Destination: "bln_a", Status: StatusApplied, PreciseAmount: big.NewInt(100)},
+	}, nil)
+	for _, id := range []string{"bln_a", "bln_b"} {
+		mockDS.On("GetBalanceByIDLite", mock.Anything, id).Return(&model.Balance{BalanceID: id, LedgerID: "ldg_1", Currency: "USD"}, nil)
+		mockDS.On("GetReplayBalanceAt", mock.Anything, id, from).Return(&model.Balance{BalanceID: id, Balance: big.NewInt(0)}, nil)
+		mockDS.On("GetReplayBalanceAt", mock.Anything, id, to).Return(&model.Balance{BalanceID: id, Balance: big.NewInt(100)}, nil)
+		mockDS.On("GetBalanceByID", mock.Anything, id, []string(nil), false).Return(&model.Balance{BalanceID: id, IdentityID: "idt_ada"}, nil)
+	}
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_ada").Return(anonymizeTestIdentity(), nil).Once()
+
+	anonymized, err := b.ExportAnonymizedSlice(context.Background(), AnonymizeOptions{
+		ReplayExportOptions: ReplayExportOptions{LedgerID: "ldg_1", From: from, To: to},
+	})
+	require.NoError(t, err)
+	require.Len(t, anonymized.Identities, 1)
+	assert.Equal(t, anonymized.Identities[0].IdentityID, anonymized.Balances[0].IdentityID)
+	assert.Equal(t, anonymized.Identities[0].IdentityID, anonymized.Production[1].IdentityID)
+	assert.NotEqual(t, "Ada", anonymized.Identities[0].FirstName)
+	mockDS.AssertExpectations(t)
+}
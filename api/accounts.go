@@ -48,7 +48,7 @@ func (a Api) CreateAccount(c *gin.Context) {
 		return
 	}
 
-	resp, err := a.blnk.CreateAccount(newAccount.ToAccount())
+	resp, err := a.blnk.CreateAccount(c.Request.Context(), newAccount.ToAccount())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -72,7 +72,7 @@ func (a Api) GetAccount(c *gin.Context) {
 
 	includes := c.QueryArray("include")
 
-	account, err := a.blnk.GetAccount(id, includes)
+	account, err := a.blnk.GetAccount(c.Request.Context(), id, includes)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -90,12 +90,12 @@ func (a Api) GetAccount(c *gin.Context) {
 // - 400 Bad Request: If there's an error in fetching the accounts.
 // - 200 OK: If the accounts are successfully retrieved.
 func (a Api) GetAllAccounts(c *gin.Context) {
-	accounts, err := a.blnk.GetAllAccounts()
+	accounts, err := a.blnk.GetAllAccounts(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, accounts)
+	a.respondList(c, accounts, listPage{})
 }
 
 // generateMockAccount generates and returns a mock account for testing purposes.
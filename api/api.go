@@ -19,6 +19,7 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/typesense/typesense-go/typesense/api"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
@@ -43,12 +44,47 @@ type Api struct {
 func (a Api) Router() *gin.Engine {
 	router := a.router
 
+	// Record requests before anything else touches them, so the log shows what the caller sent and received
+	router.Use(middleware.RequestLogging(a.blnk))
+
+	// Resolve the API version first so the other middleware see requests in the handlers' shape
+	router.Use(middleware.APIVersioning(apiVersions, defaultAPIVersion))
+
 	// Apply auth middleware to all routes
 	router.Use(a.auth.Authenticate())
+	router.Use(middleware.UsageMetering(a.blnk))
+
+	// Reject changes during maintenance once the caller is known, so reads keep being served
+	router.Use(middleware.MaintenanceMode(a.blnk))
+
+	// Alias renamed fields before the metadata middleware, so it sees requests and responses in the handlers' shape
+	router.Use(middleware.FieldAliasing(fieldRenames, defaultAPIVersion))
+	router.Use(middleware.EncryptedMetadata(a.blnk))
 
+	// Unversioned routes are served as the default version
+	a.registerRoutes(router)
+	for _, version := range apiVersions {
+		a.registerRoutes(router.Group("/" + version.Name))
+	}
+
+	return a.router
+}
+
+// registerRoutes registers the API routes on a router or a version group.
+//
+// Parameters:
+// - router: The router or group to register the routes on.
+func (a Api) registerRoutes(router gin.IRouter) {
 	// Ledger routes
 	router.POST("/ledgers", a.CreateLedger)
 	router.GET("/ledgers/:id", a.GetLedger)
+	router.GET("/ledgers/:id/sequence", a.GetLedgerSequence)
+	router.GET("/ledgers/:id/posting-rules", a.GetPostingRules)
+	router.PUT("/ledgers/:id/posting-rules", a.SetPostingRules)
+	router.DELETE("/ledgers/:id/posting-rules", a.DeletePostingRules)
+	router.GET("/ledgers/:id/template", a.ExportLedgerTemplate)
+	router.POST("/ledgers/:id/clone", a.CloneLedger)
+	router.POST("/ledgers/import", a.ImportLedgerTemplate)
 	router.GET("/ledgers", a.GetAllLedgers)
 
 	// Balance routes
@@ -57,8 +93,21 @@ func (a Api) Router() *gin.Engine {
 	router.GET("/balances/:id", a.GetBalance)
 	router.GET("/balances/indicator/:indicator/currency/:currency", a.GetBalanceByIndicator)
 	router.GET("/balances/:id/at", a.GetBalanceAtTime)
+	router.GET("/balances/:id/breakdown", a.GetBalanceBreakdown)
 	router.POST("/balances-snapshots", a.TakeBalanceSnapshots)
 	router.PUT("/balances/:id/identity", a.UpdateBalanceIdentity)
+	router.PUT("/balances/:id/notification-preferences", a.SetBalanceNotificationPreference)
+	router.GET("/balances/:id/notification-preferences", a.GetBalanceNotificationPreference)
+	router.DELETE("/balances/:id/notification-preferences", a.DeleteBalanceNotificationPreference)
+	router.POST("/balances/:id/shards", a.ShardBalance)
+	router.GET("/balances/:id/shards", a.GetBalanceSharding)
+
+	// Balance alias routes
+	router.POST("/balance-aliases", a.CreateBalanceAlias)
+	router.GET("/balance-aliases", a.ListBalanceAliases)
+	router.GET("/balance-aliases/:alias", a.GetBalanceAlias)
+	router.PUT("/balance-aliases/:alias", a.UpdateBalanceAlias)
+	router.DELETE("/balance-aliases/:alias", a.DeleteBalanceAlias)
 
 	// Balance Monitor routes
 	router.POST("/balance-monitors", a.CreateBalanceMonitor)
@@ -71,13 +120,58 @@ func (a Api) Router() *gin.Engine {
 	router.POST("/transactions", a.QueueTransaction)
 	router.POST("/transactions/bulk", a.CreateBulkTransactions)
 	router.POST("/refund-transaction/:id", a.RefundTransaction)
+	router.GET("/transactions/scheduled-failures", a.GetScheduledTransactionFailures)
 	router.GET("/transactions/:id", a.GetTransaction)
+	router.GET("/transactions/:id/history", a.GetTransactionHistory)
+	router.GET("/transactions/:id/timings", a.GetTransactionTimings)
+	router.GET("/transactions/:id/receipt", a.GetTransactionReceipt)
 	router.PUT("/transactions/inflight/:txID", a.UpdateInflightStatus)
+	router.GET("/groups/:id", a.GetTransactionGroup)
 
 	// Identity routes
 	router.POST("/identities", a.CreateIdentity)
+	router.POST("/identities/import", a.ImportIdentities)
+	router.POST("/identities/exports", a.StartIdentityExport)
+	router.GET("/identities/exports/:id", a.GetIdentityExport)
 	router.GET("/identities/:id", a.GetIdentity)
 	router.PUT("/identities/:id", a.UpdateIdentity)
+	router.PATCH("/identities/:id", a.PatchIdentity)
+	router.DELETE("/identities/:id", a.DeleteIdentity)
+	router.POST("/identities/:id/restore", a.RestoreIdentity)
+	router.GET("/identities/:id/history", a.GetIdentityHistory)
+	router.POST("/identities/:id/verification", a.UpdateIdentityVerification)
+	router.POST("/identities/:id/status", a.UpdateIdentityStatus)
+	router.POST("/identities/:id/risk-score", a.ScoreIdentityRisk)
+	router.POST("/identities/:id/screenings", a.ScreenIdentity)
+	router.GET("/identities/:id/screenings", a.GetIdentityScreenings)
+	router.GET("/identities/:id/balances", a.GetIdentityBalances)
+	router.GET("/identities/:id/net-worth", a.GetIdentityNetWorth)
+	router.GET("/identities/:id/transactions", a.GetIdentityTransactions)
+	router.POST("/identities/:id/documents", a.UploadIdentityDocument)
+	router.GET("/identities/:id/documents", a.ListIdentityDocuments)
+	router.GET("/identities/:id/documents/:document_id", a.GetIdentityDocument)
+	router.GET("/identities/:id/documents/:document_id/download", a.DownloadIdentityDocument)
+	router.POST("/identities/:id/documents/:document_id/review", a.ReviewIdentityDocument)
+	router.POST("/identities/:id/relationships", a.CreateIdentityRelationship)
+	router.GET("/identities/:id/relationships/:relationship_id", a.GetIdentityRelationship)
+	router.PUT("/identities/:id/relationships/:relationship_id", a.UpdateIdentityRelationship)
+	router.DELETE("/identities/:id/relationships/:relationship_id", a.DeleteIdentityRelationship)
+	router.GET("/identities/:id/related", a.GetRelatedIdentities)
+	router.POST("/identities/:id/addresses", a.CreateIdentityAddress)
+	router.GET("/identities/:id/addresses", a.GetIdentityAddresses)
+	router.GET("/identities/:id/addresses/:address_id", a.GetIdentityAddress)
+	router.PUT("/identities/:id/addresses/:address_id", a.UpdateIdentityAddress)
+	router.DELETE("/identities/:id/addresses/:address_id", a.DeleteIdentityAddress)
+	router.POST("/identities/:id/tags", a.AddIdentityTags)
+	router.DELETE("/identities/:id/tags/:tag", a.RemoveIdentityTag)
+	router.GET("/identities/:id/external-accounts", a.GetExternalAccounts)
+	router.POST("/identities/:id/contact-verifications", a.RequestContactVerification)
+	router.POST("/identities/:id/contact-verifications/confirm", a.ConfirmContactVerification)
+	router.GET("/identities/:id/duplicates", a.FindDuplicateIdentities)
+	router.POST("/identities/:id/merge", a.MergeIdentity)
+	router.GET("/identities/:id/merges", a.GetIdentityMerges)
+	router.POST("/identities/:id/anonymize", a.AnonymizeIdentity)
+	router.GET("/identities/:id/erasure", a.GetIdentityErasure)
 	router.GET("/identities", a.GetAllIdentities)
 	router.GET("/identities/:id/tokenized-fields", a.GetTokenizedFields)
 	router.POST("/identities/:id/tokenize/:field", a.TokenizeIdentityField)
@@ -89,6 +183,9 @@ func (a Api) Router() *gin.Engine {
 	router.POST("/accounts", a.CreateAccount)
 	router.GET("/accounts/:id", a.GetAccount)
 	router.GET("/accounts", a.GetAllAccounts)
+	router.POST("/external-accounts", a.CreateExternalAccount)
+	router.GET("/external-accounts/:id", a.GetExternalAccount)
+	router.POST("/external-accounts/:id/review", a.ReviewExternalAccount)
 
 	// Mocked Account route
 	router.GET("/mocked-account", a.generateMockAccount)
@@ -99,17 +196,78 @@ func (a Api) Router() *gin.Engine {
 
 	// Search routes
 	router.POST("/search/:collection", a.Search)
+	router.GET("/search/identities", a.SearchIdentities)
 	router.POST("/multi-search", a.MultiSearch)
 
 	// Reconciliation routes
 	router.POST("/reconciliation/upload", a.UploadExternalData)
+	router.POST("/reconciliation/external-records", a.RecordExternalRecords)
 	router.POST("/reconciliation/matching-rules", a.CreateMatchingRule)
 	router.POST("/reconciliation/start", a.StartReconciliation)
 	router.POST("/reconciliation/start-instant", a.InstantReconciliation)
+	router.POST("/reconciliation/start-intercompany", a.StartIntercompanyReconciliation)
+	router.GET("/reconciliation/ingestions", a.ListStatementIngestions)
+	router.POST("/reconciliation/ingestions/poll", a.PollStatementIngestion)
 	router.GET("/reconciliation/:id", a.GetReconciliation)
+	router.GET("/reconciliation/:id/review", a.GetReconciliationReviewQueue)
+	router.POST("/reconciliation/:id/review", a.ReviewMatch)
+	router.GET("/reconciliation/:id/scores", a.GetMatchScoreDistribution)
+	router.GET("/reconciliation/:id/dry-run-report", a.GetDryRunReport)
+	router.GET("/reconciliation/:id/breaks", a.GetReconciliationBreaks)
+
+	// Statement routes
+	router.POST("/statements/schedules", a.CreateStatementSchedule)
+	router.GET("/statements/schedules/:id", a.GetStatementSchedule)
+	router.GET("/statements/schedules/:id/statements", a.ListStatements)
+	router.GET("/statements/:id", a.GetStatement)
+	router.POST("/statements/:id/resend", a.ResendStatement)
+
+	// Card authorization routes
+	router.POST("/authorize", a.AuthorizeBalance)
+	router.POST("/card-authorizations", a.AuthorizeCard)
+	router.GET("/card-authorizations/:id", a.GetCardAuthorization)
+	router.POST("/card-authorizations/:id/increment", a.IncrementCardAuthorization)
+	router.POST("/card-authorizations/:id/reverse", a.ReverseCardAuthorization)
+	router.POST("/card-authorizations/:id/clear", a.ClearCardAuthorization)
+
+	// Netting routes
+	router.POST("/netting-groups", a.CreateNettingGroup)
+	router.GET("/netting-groups", a.ListNettingGroups)
+	router.GET("/netting-groups/:id", a.GetNettingGroup)
+	router.GET("/netting-groups/:id/entries", a.ListNettingEntries)
+	router.POST("/netting-groups/:id/settle", a.SettleNettingGroup)
+	router.POST("/netting-groups/:id/deactivate", a.DeactivateNettingGroup)
+	router.GET("/netting-settlements/:id", a.GetNettingSettlement)
+	router.POST("/netting-settlements/:id/retry", a.RetryNettingSettlement)
+
+	// Dormancy and escheatment routes
+	router.GET("/dormant-balances", a.ListDormantBalances)
+	router.POST("/dormant-balances/scan", a.RunDormancyScan)
+	router.POST("/dormant-balances/:id/reactivate", a.ReactivateBalance)
+	router.POST("/escheatment-batches", a.CreateEscheatmentBatch)
+	router.GET("/escheatment-batches", a.ListEscheatmentBatches)
+	router.GET("/escheatment-batches/:id", a.GetEscheatmentBatch)
+	router.POST("/escheatment-batches/:id/apply", a.ApplyEscheatmentBatch)
+	router.GET("/escheatment-batches/:id/report", a.GetEscheatmentReport)
+
+	// Request log routes
+	router.GET("/request-logs", a.ListRequestLogs)
+	router.GET("/request-logs/:id", a.GetRequestLog)
+
+	// Warehouse sync routes
+	router.GET("/warehouse/sync", a.GetWarehouseSyncStates)
+	router.POST("/warehouse/sync", a.RunWarehouseSync)
+
+	// Compliance export routes
+	router.GET("/compliance-export", a.GetComplianceExportState)
+	router.POST("/compliance-export/run", a.RunComplianceExport)
+	router.GET("/compliance-export/verify", a.VerifyComplianceExport)
 
 	// Metadata routes
 	router.POST("/:entity-id/metadata", a.UpdateMetadata)
+	router.POST("/ledgers/:id/metadata/increment", a.IncrementMetadata)
+	router.POST("/balances/:id/metadata/increment", a.IncrementMetadata)
+	router.POST("/identities/:id/metadata/increment", a.IncrementMetadata)
 
 	// Hook management routes
 	router.POST("/hooks", a.RegisterHook)
@@ -118,12 +276,96 @@ func (a Api) Router() *gin.Engine {
 	router.GET("/hooks", a.ListHooks)
 	router.DELETE("/hooks/:id", a.DeleteHook)
 
+	// Webhook replay routes
+	router.POST("/webhooks/replay", a.ReplayWebhooks)
+	router.GET("/webhooks/replay/:id", a.GetWebhookReplay)
+	router.GET("/webhooks/signing-secret", a.GetWebhookSigningSecretState)
+	router.POST("/webhooks/signing-secret/rotate", a.RotateWebhookSigningSecret)
+	router.POST("/webhooks/signing-secret/expire-rotation", a.ExpireWebhookSigningSecretRotation)
+	router.GET("/webhooks/circuits", a.ListWebhookCircuits)
+	router.GET("/webhooks/:id/analytics", a.GetWebhookAnalytics)
+	router.POST("/webhooks/:id/enable", a.EnableWebhookEndpoint)
+
+	// Usage metering routes
+	router.GET("/usage", a.GetUsage)
+
+	// Feature flag routes
+	router.GET("/feature-flags", a.ListFeatureFlags)
+	router.GET("/feature-flags/:name", a.GetFeatureFlag)
+	router.PUT("/feature-flags/:name", a.SetFeatureFlag)
+	router.DELETE("/feature-flags/:name", a.ResetFeatureFlag)
+
+	// Maintenance mode routes
+	router.GET("/maintenance", a.GetMaintenanceStatus)
+	router.PUT("/maintenance", a.SetMaintenanceMode)
+	router.PUT("/maintenance/ledgers/:id", a.SetLedgerMaintenanceMode)
+
+	// Diagnostics routes
+	router.GET("/diagnostics/schema", a.GetSchemaAdvice)
+
+	// Transaction challenge routes
+	router.GET("/challenges", a.ListTransactionChallenges)
+	router.GET("/challenges/:id", a.GetTransactionChallenge)
+	router.POST("/challenges/:id/callback", a.ChallengeCallback)
+
+	// Saved report routes
+	router.POST("/reports", a.CreateReportDefinition)
+	router.GET("/reports", a.ListReportDefinitions)
+	router.GET("/reports/runs/:id", a.GetReportRun)
+	router.GET("/reports/:id", a.GetReportDefinition)
+	router.DELETE("/reports/:id", a.DeleteReportDefinition)
+	router.POST("/reports/:id/run", a.RunReport)
+	router.GET("/reports/:id/runs", a.ListReportRuns)
+
+	// Currency routes
+	router.GET("/currencies", a.ListCurrencies)
+	router.GET("/currencies/:code", a.GetCurrency)
+	router.GET("/currencies/:code/format", a.FormatAmount)
+	router.GET("/currencies/:code/value", a.ValueAmount)
+
+	// Business calendar routes
+	router.GET("/calendars/:name/days/:date", a.GetCalendarDay)
+
+	// Minimum balance routes
+	router.POST("/minimum-balances", a.SetMinimumBalance)
+	router.GET("/minimum-balances", a.ListMinimumBalances)
+	router.GET("/minimum-balances/:id", a.GetMinimumBalance)
+	router.DELETE("/minimum-balances/:id", a.DeleteMinimumBalance)
+
+	// Calculator routes
+	router.POST("/calculate", a.Calculate)
+
+	// Session routes
+	router.POST("/sessions", a.CreateSession)
+	router.GET("/sessions/:id", a.GetSession)
+	router.POST("/sessions/:id/ledgers", a.StageSessionLedger)
+	router.POST("/sessions/:id/identities", a.StageSessionIdentity)
+	router.POST("/sessions/:id/balances", a.StageSessionBalance)
+	router.POST("/sessions/:id/transactions", a.StageSessionTransaction)
+	router.POST("/sessions/:id/commit", a.CommitSession)
+	router.POST("/sessions/:id/rollback", a.RollbackSession)
+
+	// End-of-day processing routes
+	router.POST("/eod/runs", a.RunEOD)
+	router.GET("/eod/runs", a.ListEODRuns)
+	router.GET("/eod/runs/current", a.GetCurrentEODRun)
+	router.GET("/eod/runs/:date", a.GetEODRun)
+
 	// API Key routes
 	router.POST("/api-keys", a.CreateAPIKey)
 	router.GET("/api-keys", a.ListAPIKeys)
 	router.DELETE("/api-keys/:id", a.RevokeAPIKey)
-
-	return a.router
+	router.POST("/api-keys/:id/rotate", a.RotateAPIKey)
+	router.POST("/api-keys/:id/expire-rotation", a.ExpireAPIKeyRotation)
+	router.PUT("/api-keys/:id/network-policy", a.UpdateAPIKeyNetworkPolicy)
+	router.GET("/api-keys/:id/access-denials", a.ListAPIKeyAccessDenials)
+	router.PUT("/api-keys/:id/ledger-scope", a.UpdateAPIKeyLedgerScope)
+	router.PUT("/api-keys/:id/field-compatibility", a.UpdateAPIKeyFieldCompatibility)
+
+	router.POST("/service-accounts", a.CreateServiceAccount)
+	router.GET("/service-accounts", a.ListServiceAccounts)
+	router.DELETE("/service-accounts/:id", a.RevokeServiceAccount)
+	router.POST("/auth/token", a.IssueServiceToken)
 }
 
 // NewAPI creates a new Api instance with the provided Blnk service and sets up the router.
@@ -140,6 +382,10 @@ func NewAPI(b *blnk.Blnk) *Api {
 		return nil
 	}
 	r := gin.Default()
+	// Only the configured proxies may set the client address through X-Forwarded-For
+	if err := r.SetTrustedProxies(conf.NetworkPolicy.TrustedProxies); err != nil {
+		return nil
+	}
 	auth := middleware.NewAuthMiddleware(b)
 	r.Use(middleware.RateLimitMiddleware(conf))
 	r.Use(otelgin.Middleware("BLNK"))
@@ -186,7 +432,7 @@ func (a Api) Search(c *gin.Context) {
 		return
 	}
 
-	resp, err := a.blnk.Search(collection, &query)
+	resp, err := a.blnk.Search(c.Request.Context(), collection, &query)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -195,6 +441,28 @@ func (a Api) Search(c *gin.Context) {
 	c.JSON(http.StatusCreated, resp)
 }
 
+// SearchIdentities finds identities by partial name, email address or phone number, tolerating typos. The query
+// is passed as q, and the page and per_page query parameters page through the results.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If q is missing or the search fails.
+// - 200 OK: Returns the identities found, best matches first.
+func (a Api) SearchIdentities(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+	resp, err := a.blnk.SearchIdentities(c.Request.Context(), c.Query("q"), page, perPage)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
 // MultiSearch performs a multi-search query.
 // It binds the incoming JSON request to a MultiSearchParameter object,
 // executes the multi-search query, and responds with the search results.
@@ -212,7 +480,7 @@ func (a Api) MultiSearch(c *gin.Context) {
 		return
 	}
 
-	resp, err := a.blnk.MultiSearch(&searchRequests)
+	resp, err := a.blnk.MultiSearch(c.Request.Context(), &searchRequests)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
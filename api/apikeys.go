@@ -2,9 +2,13 @@ package api
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/blnkfinance/blnk/api/model"
 	"github.com/blnkfinance/blnk/database"
+	"github.com/blnkfinance/blnk/internal/tenant"
+	blnkmodel "github.com/blnkfinance/blnk/model"
 	"github.com/gin-gonic/gin"
 )
 
@@ -32,13 +36,14 @@ func (a Api) CreateAPIKey(c *gin.Context) {
 	c.JSON(http.StatusCreated, apiKey)
 }
 
-// ListAPIKeys lists all API keys for the authenticated user
+// ListAPIKeys lists a page of the API keys of the authenticated user, newest first
 //
 // Parameters:
 // - c: The Gin context containing the request and response
 //
 // Responses:
 // - 200 OK: Returns the list of API keys
+// - 400 Bad Request: If the cursor is invalid
 // - 500 Internal Server Error: If there's an error retrieving the keys
 func (a Api) ListAPIKeys(c *gin.Context) {
 	owner := c.GetString("owner")
@@ -47,13 +52,27 @@ func (a Api) ListAPIKeys(c *gin.Context) {
 		return
 	}
 
-	keys, err := a.blnk.ListAPIKeys(c.Request.Context(), owner)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	page, err := queryPage(c, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	keys, err := a.blnk.ListAPIKeys(c.Request.Context(), owner, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, keys)
+	next := blnkmodel.NextPageCursor(keys, limit, (*blnkmodel.APIKey).PageCursor)
+	a.respondList(c, keys, listPage{limit: limit, offset: page.Offset, fetched: len(keys), next: next})
 }
 
 // RevokeAPIKey revokes an API key
@@ -87,3 +106,177 @@ func (a Api) RevokeAPIKey(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+// RotateAPIKey issues a replacement for an API key. The old key keeps working until the overlap period ends.
+//
+// Parameters:
+// - c: The Gin context containing the request and response
+//
+// Responses:
+// - 201 Created: Returns the replacement API key
+// - 404 Not Found: If the API key is not found or is revoked
+// - 409 Conflict: If the API key has already been rotated
+func (a Api) RotateAPIKey(c *gin.Context) {
+	id := c.Param("id")
+	owner := c.GetString("owner")
+	if owner == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req model.RotateSecretRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	apiKey, err := a.blnk.RotateAPIKey(c.Request.Context(), id, owner, time.Duration(req.OverlapSeconds)*time.Second)
+	if err != nil {
+		switch err {
+		case database.ErrAPIKeyNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		case database.ErrAPIKeyAlreadyRotated:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiKey)
+}
+
+// ExpireAPIKeyRotation ends the overlap window of a rotated API key so the old key stops working immediately
+//
+// Parameters:
+// - c: The Gin context containing the request and response
+//
+// Responses:
+// - 204 No Content: If the overlap window was ended
+// - 404 Not Found: If the API key is not found or has not been rotated
+func (a Api) ExpireAPIKeyRotation(c *gin.Context) {
+	id := c.Param("id")
+	owner := c.GetString("owner")
+	if owner == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := a.blnk.ExpireAPIKeyRotation(c.Request.Context(), id, owner); err != nil {
+		if err == database.ErrAPIKeyNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "rotated API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UpdateAPIKeyNetworkPolicy sets the CIDR allow-list and blocked countries of an API key.
+// Sending empty lists removes the restrictions.
+//
+// Parameters:
+// - c: The Gin context containing the request and response
+//
+// Responses:
+// - 200 OK: Returns the saved policy
+// - 400 Bad Request: If the policy is invalid or no owner is given
+// - 404 Not Found: If the API key is not found
+func (a Api) UpdateAPIKeyNetworkPolicy(c *gin.Context) {
+	owner := requestOwner(c)
+	if owner == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "owner is required"})
+		return
+	}
+
+	var req blnkmodel.NetworkPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := a.blnk.UpdateAPIKeyNetworkPolicy(c.Request.Context(), c.Param("id"), owner, req)
+	if err != nil {
+		if err == database.ErrAPIKeyNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateAPIKeyFieldCompatibility sets the names renamed JSON fields are emitted under for an API key on v1:
+// legacy, both or current. Sending an empty mode makes the key use the deployment's default.
+//
+// Parameters:
+// - c: The Gin context containing the request and response
+//
+// Responses:
+// - 200 OK: Returns the saved mode
+// - 400 Bad Request: If the mode is unknown or no owner is given
+// - 404 Not Found: If the API key is not found
+func (a Api) UpdateAPIKeyFieldCompatibility(c *gin.Context) {
+	owner := requestOwner(c)
+	if owner == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "owner is required"})
+		return
+	}
+
+	var req model.UpdateFieldCompatibilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := a.blnk.UpdateAPIKeyFieldCompatibility(c.Request.Context(), c.Param("id"), owner, req.Mode); err != nil {
+		if err == database.ErrAPIKeyNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
+
+// ListAPIKeyAccessDenials lists the most recent requests rejected by an API key's network policy
+//
+// Parameters:
+// - c: The Gin context containing the request and response
+//
+// Responses:
+// - 200 OK: Returns the rejected requests, newest first
+// - 400 Bad Request: If no owner is given
+func (a Api) ListAPIKeyAccessDenials(c *gin.Context) {
+	owner := requestOwner(c)
+	if owner == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "owner is required"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	denials, err := a.blnk.ListAPIKeyAccessDenials(c.Request.Context(), c.Param("id"), owner, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	a.respondList(c, denials, listPage{})
+}
+
+// requestOwner returns the tenant of the request, falling back to the owner query parameter
+// for requests made with the master key.
+func requestOwner(c *gin.Context) string {
+	if owner := tenant.FromContext(c.Request.Context()); owner != "" {
+		return owner
+	}
+	return c.Query("owner")
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/api/middleware"
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AuthorizeBalance decides a card authorization against a balance in a single round trip: the amount and
+// velocity limits are checked and the amount is held with an inflight transaction, which is later committed or
+// voided through the inflight transaction endpoint. Declines are decisions, not errors, and are returned with
+// the code and reason they were declined for.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid.
+// - 500 Internal Server Error: If the authorization could not be decided.
+// - 503 Service Unavailable: If a ledger of the source or destination is in maintenance mode.
+// - 200 OK: Returns the decision, approved or declined.
+func (a Api) AuthorizeBalance(c *gin.Context) {
+	var req apimodel.BalanceAuthorizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), req.Source)) {
+		return
+	}
+
+	decision, err := a.blnk.AuthorizeBalance(c.Request.Context(), &model.Transaction{
+		Reference:          req.Reference,
+		Source:             req.Source,
+		Destination:        req.Destination,
+		Currency:           req.Currency,
+		Amount:             req.Amount,
+		PreciseAmount:      req.PreciseAmount,
+		Precision:          req.Precision,
+		InflightExpiryDate: req.ExpiresAt,
+		Description:        req.Description,
+		MetaData:           req.MetaData,
+	})
+	if middleware.RespondMaintenance(c, err) {
+		return
+	}
+	if err != nil {
+		if errors.Is(err, blnk.ErrInvalidAuthorization) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
+}
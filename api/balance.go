@@ -16,10 +16,14 @@ limitations under the License.
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/api/middleware"
 	model2 "github.com/blnkfinance/blnk/api/model"
 
 	"github.com/blnkfinance/blnk/model"
@@ -36,6 +40,7 @@ import (
 //
 // Responses:
 // - 400 Bad Request: If there's an error in binding JSON or validating the balance.
+// - 403 Forbidden: If the balance's identity is flagged by sanctions screening and such balances are blocked.
 // - 201 Created: If the balance is successfully created.
 func (a Api) CreateBalance(c *gin.Context) {
 	var newBalance model2.CreateBalance
@@ -50,7 +55,18 @@ func (a Api) CreateBalance(c *gin.Context) {
 		return
 	}
 
+	if respondLedgerScopeError(c, a.blnk.CheckLedgerAccess(c.Request.Context(), newBalance.LedgerId)) {
+		return
+	}
+
 	resp, err := a.blnk.CreateBalance(c.Request.Context(), newBalance.ToBalance())
+	if middleware.RespondMaintenance(c, err) {
+		return
+	}
+	if errors.Is(err, blnk.ErrIdentityFlagged) || errors.Is(err, blnk.ErrContactNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -83,6 +99,10 @@ func (a Api) GetBalance(c *gin.Context) {
 	// Extract 'with_queued' parameter from the query, default to false
 	withQueued := c.DefaultQuery("with_queued", "false") == "true"
 
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), id)) {
+		return
+	}
+
 	resp, err := a.blnk.GetBalanceByID(c.Request.Context(), id, includes, withQueued)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -92,6 +112,36 @@ func (a Api) GetBalance(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// GetBalanceBreakdown retrieves a balance split into its settled funds, the inflight credits still on their way
+// in and the inflight debits still on their way out.
+// Pass ?with_queued=true to also count queued transactions.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the balance cannot be found.
+// - 500 Internal Server Error: If the breakdown cannot be retrieved.
+// - 200 OK: If the breakdown is successfully retrieved.
+func (a Api) GetBalanceBreakdown(c *gin.Context) {
+	id := c.Param("id")
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), id)) {
+		return
+	}
+
+	breakdown, err := a.blnk.GetBalanceBreakdown(c.Request.Context(), id, c.DefaultQuery("with_queued", "false") == "true")
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
 // GetBalances retrieves a list of balance records with pagination.
 // It extracts the 'limit' and 'offset' query parameters to control pagination,
 // and the 'include' query parameter to fetch additional related information.
@@ -110,20 +160,22 @@ func (a Api) GetBalances(c *gin.Context) {
 		return
 	}
 
-	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0")) // Default to 0 if not specified
-	if err != nil || offset < 0 {
+	page, err := queryPage(c, limit) // Starts at the first balance if neither cursor nor offset is specified
+	if err != nil || page.Offset < 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset value"})
 		return
 	}
 
 	// Fetch balances with pagination
-	resp, err := a.blnk.GetAllBalances(c.Request.Context(), limit, offset)
+	resp, err := a.blnk.GetAllBalances(c.Request.Context(), page)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	list := listPage{limit: limit, offset: page.Offset, fetched: len(resp), table: "blnk.balances"}
+	list.next = model.NextPageCursor(resp, limit, model.Balance.PageCursor)
 
-	c.JSON(http.StatusOK, resp)
+	a.respondList(c, resp, list)
 }
 
 // CreateBalanceMonitor creates a new balance monitor record in the system.
@@ -202,7 +254,7 @@ func (a Api) GetAllBalanceMonitors(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, monitors)
+	a.respondList(c, monitors, listPage{})
 }
 
 // GetBalanceMonitorsByBalanceID retrieves all balance monitors associated with a specific balance ID.
@@ -228,7 +280,7 @@ func (a Api) GetBalanceMonitorsByBalanceID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, monitors)
+	a.respondList(c, monitors, listPage{})
 }
 
 // UpdateBalanceMonitor updates an existing balance monitor record by its ID.
@@ -354,6 +406,10 @@ func (a Api) GetBalanceAtTime(c *gin.Context) {
 	fromSourceStr := c.Query("from_source")
 	fromSource := fromSourceStr == "true" || fromSourceStr == "1"
 
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), balanceID)) {
+		return
+	}
+
 	balance, err := a.blnk.GetBalanceAtTime(c.Request.Context(), balanceID, timestamp, fromSource)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -405,6 +461,10 @@ func (a Api) GetBalanceByIndicator(c *gin.Context) {
 		return
 	}
 
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), resp.BalanceID)) {
+		return
+	}
+
 	c.JSON(http.StatusOK, resp)
 }
 
@@ -428,7 +488,11 @@ func (a Api) UpdateBalanceIdentity(c *gin.Context) {
 		return
 	}
 
-	if err := a.blnk.UpdateBalanceIdentity(balanceID, request.IdentityId); err != nil {
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), balanceID)) {
+		return
+	}
+
+	if err := a.blnk.UpdateBalanceIdentity(c.Request.Context(), balanceID, request.IdentityId); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
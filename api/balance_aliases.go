@@ -0,0 +1,166 @@
+package api
+
+import (
+	"net/http"
+
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateBalanceAlias names a balance with an alias, such as @main_usd_float, that transactions can use in place
+// of its ID as a source, destination or distribution. Aliases are unique within the tenant of their balance and
+// take precedence over balance indicators of the same name.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body or alias is invalid.
+// - 403 Forbidden: If the balance is outside the caller's ledger scope.
+// - 404 Not Found: If the balance does not exist.
+// - 409 Conflict: If the alias is already in use.
+// - 201 Created: Returns the alias.
+func (a Api) CreateBalanceAlias(c *gin.Context) {
+	var req model.BalanceAlias
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.BalanceID != "" && respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), req.BalanceID)) {
+		return
+	}
+
+	alias, err := a.blnk.CreateBalanceAlias(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, alias)
+}
+
+// ListBalanceAliases lists the balance aliases of the caller's tenant in alias order. The balance_id query
+// parameter narrows the list to the aliases of a balance.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 403 Forbidden: If the balance is outside the caller's ledger scope.
+// - 500 Internal Server Error: If the aliases cannot be retrieved.
+// - 200 OK: Returns the aliases.
+func (a Api) ListBalanceAliases(c *gin.Context) {
+	ctx := c.Request.Context()
+	balanceID := c.Query("balance_id")
+	if balanceID != "" && respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(ctx, balanceID)) {
+		return
+	}
+
+	aliases, err := a.blnk.ListBalanceAliases(ctx, balanceID)
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// Callers restricted to a ledger scope only see the aliases of balances in it
+	inScope := make([]*model.BalanceAlias, 0, len(aliases))
+	for _, alias := range aliases {
+		if a.blnk.CheckBalanceAccess(ctx, alias.BalanceID) == nil {
+			inScope = append(inScope, alias)
+		}
+	}
+
+	a.respondList(c, inScope, listPage{})
+}
+
+// GetBalanceAlias retrieves a balance alias of the caller's tenant.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 403 Forbidden: If the alias's balance is outside the caller's ledger scope.
+// - 404 Not Found: If the alias does not exist.
+// - 200 OK: Returns the alias.
+func (a Api) GetBalanceAlias(c *gin.Context) {
+	alias, ok := a.findBalanceAlias(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, alias)
+}
+
+// UpdateBalanceAlias points a balance alias at another balance of its tenant.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body is invalid.
+// - 403 Forbidden: If either balance is outside the caller's ledger scope.
+// - 404 Not Found: If the alias or the balance does not exist.
+// - 200 OK: Returns the updated alias.
+func (a Api) UpdateBalanceAlias(c *gin.Context) {
+	var req apimodel.UpdateBalanceAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	current, ok := a.findBalanceAlias(c)
+	if !ok {
+		return
+	}
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), req.BalanceID)) {
+		return
+	}
+
+	alias, err := a.blnk.UpdateBalanceAlias(c.Request.Context(), current.Alias, req.BalanceID)
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, alias)
+}
+
+// DeleteBalanceAlias removes a balance alias of the caller's tenant. Transactions can no longer use it.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 403 Forbidden: If the alias's balance is outside the caller's ledger scope.
+// - 404 Not Found: If the alias does not exist.
+// - 204 No Content: If the alias is removed.
+func (a Api) DeleteBalanceAlias(c *gin.Context) {
+	alias, ok := a.findBalanceAlias(c)
+	if !ok {
+		return
+	}
+
+	if err := a.blnk.DeleteBalanceAlias(c.Request.Context(), alias.Alias); err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// findBalanceAlias retrieves the alias of the path and checks its balance is within the caller's ledger scope.
+//
+// Returns:
+// - bool: false if a response was written and the handler should stop.
+func (a Api) findBalanceAlias(c *gin.Context) (*model.BalanceAlias, bool) {
+	alias, err := a.blnk.GetBalanceAlias(c.Request.Context(), c.Param("alias"))
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), alias.BalanceID)) {
+		return nil, false
+	}
+	return alias, true
+}
@@ -38,7 +38,7 @@ func TestCreateBalance(t *testing.T) {
 	}
 
 	// Create a ledger for positive test case
-	newLedger, err := b.CreateLedger(model.Ledger{Name: gofakeit.Name()})
+	newLedger, err := b.CreateLedger(context.Background(), model.Ledger{Name: gofakeit.Name()})
 	if err != nil {
 		t.Fatalf("Failed to create ledger: %v", err)
 	}
@@ -115,7 +115,7 @@ func TestCreateBalance(t *testing.T) {
 
 func TestGetBalance(t *testing.T) {
 	router, b, _ := setupRouter()
-	newLedger, err := b.CreateLedger(model.Ledger{Name: gofakeit.Name()})
+	newLedger, err := b.CreateLedger(context.Background(), model.Ledger{Name: gofakeit.Name()})
 	if err != nil {
 		return
 	}
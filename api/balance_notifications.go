@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+)
+
+// SetBalanceNotificationPreference creates or replaces the notification preference of a balance.
+// Thresholds are given in the balance's precise (minor) units.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body or the preference is invalid.
+// - 403 Forbidden: If the balance is outside the caller's ledger scope.
+// - 200 OK: Returns the saved preference.
+func (a Api) SetBalanceNotificationPreference(c *gin.Context) {
+	balanceID := c.Param("id")
+
+	var req model.BalanceNotificationPreference
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.BalanceID = balanceID
+
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), balanceID)) {
+		return
+	}
+
+	preference, err := a.blnk.SetBalanceNotificationPreference(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preference)
+}
+
+// GetBalanceNotificationPreference retrieves the notification preference of a balance.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 403 Forbidden: If the balance is outside the caller's ledger scope.
+// - 404 Not Found: If the balance has no preference.
+// - 200 OK: Returns the preference.
+func (a Api) GetBalanceNotificationPreference(c *gin.Context) {
+	balanceID := c.Param("id")
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), balanceID)) {
+		return
+	}
+
+	preference, err := a.blnk.GetBalanceNotificationPreference(c.Request.Context(), balanceID)
+	if err != nil {
+		respondBalanceNotificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preference)
+}
+
+// DeleteBalanceNotificationPreference removes the notification preference of a balance, so every posting
+// on it is delivered again.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 403 Forbidden: If the balance is outside the caller's ledger scope.
+// - 404 Not Found: If the balance has no preference.
+// - 200 OK: If the preference is removed.
+func (a Api) DeleteBalanceNotificationPreference(c *gin.Context) {
+	balanceID := c.Param("id")
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), balanceID)) {
+		return
+	}
+
+	if err := a.blnk.DeleteBalanceNotificationPreference(c.Request.Context(), balanceID); err != nil {
+		respondBalanceNotificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification preference removed"})
+}
+
+func respondBalanceNotificationError(c *gin.Context, err error) {
+	if strings.Contains(err.Error(), "not found") {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+)
+
+// ShardBalance splits a balance into shards, or adds shards to a sharded balance, so that postings on it are
+// spread over several balance rows. Reading the balance afterwards returns the sum of its shards.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body is invalid or the balance cannot be sharded.
+// - 403 Forbidden: If the balance is outside the caller's ledger scope.
+// - 404 Not Found: If the balance cannot be found.
+// - 200 OK: Returns the sharding of the balance.
+func (a Api) ShardBalance(c *gin.Context) {
+	balanceID := c.Param("id")
+
+	var req model.BalanceShardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Strategy == "" {
+		req.Strategy = model.ShardRoundRobin
+	}
+
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), balanceID)) {
+		return
+	}
+
+	sharding, err := a.blnk.ShardBalance(c.Request.Context(), balanceID, req)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sharding)
+}
+
+// GetBalanceSharding retrieves the sharding of a balance and the amounts held by each shard.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 403 Forbidden: If the balance is outside the caller's ledger scope.
+// - 404 Not Found: If the balance is not sharded.
+// - 200 OK: Returns the sharding and its shards.
+func (a Api) GetBalanceSharding(c *gin.Context) {
+	balanceID := c.Param("id")
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), balanceID)) {
+		return
+	}
+
+	sharding, err := a.blnk.GetBalanceSharding(c.Request.Context(), balanceID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sharding)
+}
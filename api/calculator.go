@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+)
+
+// Calculate quotes the fees, interest and currency conversion of a balance's current amount or of a
+// hypothetical amount, using the configured pricing and rounding rules. Nothing is posted.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid, a currency is unknown or no exchange rate is configured.
+// - 403 Forbidden: If the balance is outside the caller's ledger scope.
+// - 200 OK: Returns the quote.
+func (a Api) Calculate(c *gin.Context) {
+	var req model.CalculationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.BalanceID != "" && respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), req.BalanceID)) {
+		return
+	}
+
+	calculation, err := a.blnk.Calculate(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, calculation)
+}
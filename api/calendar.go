@@ -0,0 +1,36 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/gin-gonic/gin"
+)
+
+// GetCalendarDay describes a date of a business calendar: whether it is a business day, and the nearest
+// business days on either side of it.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the date is not in YYYY-MM-DD.
+// - 404 Not Found: If the calendar is not configured.
+// - 200 OK: Returns the date.
+func (a Api) GetCalendarDay(c *gin.Context) {
+	day, err := a.blnk.GetCalendarDay(c.Request.Context(), c.Param("name"), c.Param("date"))
+	if err != nil {
+		switch {
+		case errors.Is(err, blnk.ErrUnknownCalendar):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, blnk.ErrInvalidCalendarDate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, day)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AuthorizeCard places a hold for a card authorization and returns the resource that tracks its lifecycle.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid or the hold is declined.
+// - 201 Created: If the authorization is approved.
+func (a Api) AuthorizeCard(c *gin.Context) {
+	var req apimodel.CardAuthorizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), req.Source)) {
+		return
+	}
+
+	auth := model.CardAuthorization{
+		Scheme:      req.Scheme,
+		Reference:   req.Reference,
+		Source:      req.Source,
+		Destination: req.Destination,
+		Currency:    req.Currency,
+		Precision:   req.Precision,
+		ExpiresAt:   req.ExpiresAt,
+		MetaData:    req.MetaData,
+	}
+	if auth.Precision == 0 {
+		auth.Precision = 1
+	}
+
+	created, err := a.blnk.AuthorizeCard(c.Request.Context(), auth, cardAmount(&auth, req.Amount, req.PreciseAmount))
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetCardAuthorization retrieves a card authorization with its holds and lifecycle events.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the authorization cannot be found.
+// - 200 OK: If the authorization is successfully retrieved.
+func (a Api) GetCardAuthorization(c *gin.Context) {
+	auth, err := a.blnk.GetCardAuthorization(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondCardAuthorizationError(c, err)
+		return
+	}
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), auth.Source)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, auth)
+}
+
+// IncrementCardAuthorization raises the authorized amount of a card authorization.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid or the additional hold is declined.
+// - 404 Not Found: If the authorization cannot be found.
+// - 409 Conflict: If the authorization is no longer open.
+// - 200 OK: Returns the updated authorization.
+func (a Api) IncrementCardAuthorization(c *gin.Context) {
+	a.updateCardAuthorization(c, a.blnk.IncrementCardAuthorization, false)
+}
+
+// ReverseCardAuthorization releases part or all of the held amount of a card authorization.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the amount exceeds what is held.
+// - 404 Not Found: If the authorization cannot be found.
+// - 409 Conflict: If the authorization is no longer open.
+// - 200 OK: Returns the updated authorization.
+func (a Api) ReverseCardAuthorization(c *gin.Context) {
+	a.updateCardAuthorization(c, a.blnk.ReverseCardAuthorization, true)
+}
+
+// ClearCardAuthorization settles a card authorization for the presented amount.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If a posting fails.
+// - 404 Not Found: If the authorization cannot be found.
+// - 409 Conflict: If the authorization is no longer open.
+// - 200 OK: Returns the cleared authorization.
+func (a Api) ClearCardAuthorization(c *gin.Context) {
+	a.updateCardAuthorization(c, a.blnk.ClearCardAuthorization, false)
+}
+
+// updateCardAuthorization binds the amount of a lifecycle request and applies it to the authorization.
+// When optionalAmount is set, a request without an amount passes nil so the whole held amount is used.
+func (a Api) updateCardAuthorization(c *gin.Context, apply func(context.Context, string, *big.Int) (*model.CardAuthorization, error), optionalAmount bool) {
+	var req apimodel.CardAuthorizationAmountRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !(optionalAmount && errors.Is(err, io.EOF)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	auth, err := a.blnk.GetCardAuthorization(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondCardAuthorizationError(c, err)
+		return
+	}
+	if respondLedgerScopeError(c, a.blnk.CheckBalanceAccess(c.Request.Context(), auth.Source)) {
+		return
+	}
+
+	var amount *big.Int
+	if !optionalAmount || req.Amount != 0 || req.PreciseAmount != nil {
+		amount = cardAmount(auth, req.Amount, req.PreciseAmount)
+	}
+
+	updated, err := apply(c.Request.Context(), auth.AuthorizationID, amount)
+	if err != nil {
+		respondCardAuthorizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// cardAmount converts a request amount to the authorization's precise units.
+func cardAmount(auth *model.CardAuthorization, amount float64, precise *big.Int) *big.Int {
+	if precise != nil {
+		return precise
+	}
+	return auth.ToPrecise(amount)
+}
+
+func respondCardAuthorizationError(c *gin.Context, err error) {
+	logrus.Error(err)
+	switch {
+	case errors.Is(err, blnk.ErrCardAuthorizationClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card authorization not found"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
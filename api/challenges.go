@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ChallengeCallback receives the result of a strong customer authentication challenge from the authentication
+// system. The request is not authenticated with an API key: its body must name the challenge in challenge_id
+// and be signed with the challenge callback secret in the X-Blnk-Signature header, like webhook deliveries are.
+// Each signature is accepted once.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the body is not a valid callback.
+// - 401 Unauthorized: If the signature is missing, invalid, already used or signed for another challenge.
+// - 404 Not Found: If the challenge cannot be found.
+// - 409 Conflict: If the challenge has already been resolved or has expired.
+// - 200 OK: Returns the resolved challenge.
+func (a Api) ChallengeCallback(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+
+	challenge, err := a.blnk.HandleChallengeCallback(c.Request.Context(), c.Param("id"), body, c.GetHeader(blnk.WebhookSignatureHeader))
+	if err != nil {
+		logrus.Error(err)
+		switch {
+		case errors.Is(err, blnk.ErrInvalidChallengeSignature):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, blnk.ErrChallengeResolved), errors.Is(err, blnk.ErrChallengeExpired):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Challenge not found"})
+		case strings.Contains(err.Error(), "invalid challenge callback"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, challenge)
+}
+
+// ListTransactionChallenges lists strong customer authentication challenges, newest first. Use ?status= to
+// filter them by pending, confirmed, failed or expired.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the cursor is invalid.
+// - 500 Internal Server Error: If the challenges cannot be retrieved.
+// - 200 OK: If the challenges are successfully retrieved.
+func (a Api) ListTransactionChallenges(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	page, err := queryPage(c, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	challenges, err := a.blnk.ListTransactionChallenges(c.Request.Context(), c.Query("status"), page)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	next := model.NextPageCursor(challenges, limit, (*model.TransactionChallenge).PageCursor)
+	a.respondList(c, challenges, listPage{limit: limit, offset: page.Offset, fetched: len(challenges), next: next})
+}
+
+// GetTransactionChallenge retrieves a strong customer authentication challenge with its trail.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the challenge cannot be found.
+// - 200 OK: If the challenge is successfully retrieved.
+func (a Api) GetTransactionChallenge(c *gin.Context) {
+	challenge, err := a.blnk.GetTransactionChallenge(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Challenge not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, challenge)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetComplianceExportState returns how far postings have been exported to the compliance bucket and how the
+// last export went.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 500 Internal Server Error: If the state cannot be retrieved.
+// - 200 OK: Returns the state of the export.
+func (a Api) GetComplianceExportState(c *gin.Context) {
+	state, err := a.blnk.GetComplianceExportState(c.Request.Context())
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// RunComplianceExport exports the postings numbered since the last export to the compliance bucket
+// immediately instead of waiting for the next export.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If no compliance bucket is configured.
+// - 409 Conflict: If an export is already running.
+// - 422 Unprocessable Entity: If a ledger's postings do not continue from the last one exported. What was
+// exported before the gap is returned.
+// - 500 Internal Server Error: If a batch could not be exported. What was exported is returned.
+// - 200 OK: Returns what was exported.
+func (a Api) RunComplianceExport(c *gin.Context) {
+	run, err := a.blnk.RunComplianceExport(c.Request.Context())
+	if err != nil {
+		logrus.Error(err)
+		switch {
+		case strings.Contains(err.Error(), "not configured"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case strings.Contains(err.Error(), "failed to acquire lock"):
+			c.JSON(http.StatusConflict, gin.H{"error": "A compliance export is already running"})
+		case errors.Is(err, blnk.ErrComplianceSequenceGap):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "run": run})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "run": run})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// VerifyComplianceExport reads back every batch in the compliance bucket and checks that the numbering,
+// signatures, hash chain and ledger sequences are intact.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If no compliance bucket is configured.
+// - 500 Internal Server Error: If the bucket cannot be read.
+// - 200 OK: Returns what was checked and any issues found.
+func (a Api) VerifyComplianceExport(c *gin.Context) {
+	verification, err := a.blnk.VerifyComplianceExport(c.Request.Context())
+	if err != nil {
+		logrus.Error(err)
+		if strings.Contains(err.Error(), "not configured") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, verification)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestContactVerification generates a code to verify the email address or phone number of the identity of
+// the path. The code is returned for it to be delivered to the identity, and is also sent in a webhook.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid or the identity has no contact to verify on the channel.
+// - 404 Not Found: If the identity does not exist.
+// - 201 Created: Returns the verification with its code.
+func (a Api) RequestContactVerification(c *gin.Context) {
+	var request apimodel.ContactVerificationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	verification, err := a.blnk.RequestContactVerification(c.Request.Context(), c.Param("id"), request.Channel)
+	if err != nil {
+		respondContactVerificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, verification)
+}
+
+// ConfirmContactVerification checks a code the identity of the path received and marks its email address or
+// phone number verified when it matches.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid, or the code does not match or has expired.
+// - 404 Not Found: If the identity does not exist.
+// - 200 OK: Returns the identity.
+func (a Api) ConfirmContactVerification(c *gin.Context) {
+	var request apimodel.ConfirmContactVerificationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	identity, err := a.blnk.ConfirmContactVerification(c.Request.Context(), c.Param("id"), request.Channel, request.Code)
+	if err != nil {
+		respondContactVerificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, identity)
+}
+
+// respondContactVerificationError maps contact verification errors to a response.
+func respondContactVerificationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, blnk.ErrUnknownContactChannel), errors.Is(err, blnk.ErrNoContactToVerify),
+		errors.Is(err, blnk.ErrInvalidVerificationCode), errors.Is(err, blnk.ErrVerificationCodeExpired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
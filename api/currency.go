@@ -0,0 +1,136 @@
+package api
+
+import (
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+)
+
+// ListCurrencies lists the currencies configured in the currency registry, optionally only those of a
+// ?type= such as points or commodity.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the type is unknown.
+// - 500 Internal Server Error: If the currencies cannot be listed.
+// - 200 OK: Returns the configured currencies.
+func (a Api) ListCurrencies(c *gin.Context) {
+	kind := strings.ToLower(c.Query("type"))
+	if kind != "" {
+		if err := model.ValidateCurrencyType(kind); err != nil {
+			c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, err.Error(), err))
+			return
+		}
+	}
+
+	currencies, err := a.blnk.ListCurrencies(kind)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, currencies)
+}
+
+// GetCurrency returns a currency from the currency registry.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the currency is not registered and not an ISO 4217 currency.
+// - 200 OK: If the currency is successfully retrieved.
+func (a Api) GetCurrency(c *gin.Context) {
+	currency, err := a.blnk.GetCurrency(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, apierror.NewAPIError(apierror.ErrNotFound, "currency not found", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, currency)
+}
+
+// FormatAmount renders an amount of a currency for display. Pass the amount as ?precise_amount= with its
+// ?precision=, which defaults to the currency's multiplier, and optionally ?locale= and ?display= (symbol,
+// narrow or code).
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the amount, precision, locale or display is invalid.
+// - 404 Not Found: If the currency is unknown.
+// - 200 OK: Returns the formatted amount.
+func (a Api) FormatAmount(c *gin.Context) {
+	preciseAmount, ok := new(big.Int).SetString(c.Query("precise_amount"), 10)
+	if !ok {
+		c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "precise_amount must be an integer", c.Query("precise_amount")))
+		return
+	}
+	precision := 0.0
+	if raw := c.Query("precision"); raw != "" {
+		var err error
+		if precision, err = strconv.ParseFloat(raw, 64); err != nil {
+			c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "precision must be a number", err))
+			return
+		}
+	}
+
+	if _, err := a.blnk.GetCurrency(c.Param("code")); err != nil {
+		c.JSON(http.StatusNotFound, apierror.NewAPIError(apierror.ErrNotFound, "currency not found", err))
+		return
+	}
+	formatted, err := a.blnk.FormatAmount(c.Param("code"), preciseAmount, precision, c.Query("locale"), strings.ToLower(c.Query("display")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "failed to format amount", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, formatted)
+}
+
+// ValueAmount converts an amount of a currency at the configured exchange rates, by default to the fiat
+// currency the currency is valued in. Pass the amount as ?precise_amount= with its ?precision=, which defaults
+// to the currency's multiplier, and optionally the currency to convert to as ?to=.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the amount or precision is invalid, or the amount cannot be converted.
+// - 404 Not Found: If the currency is unknown.
+// - 200 OK: Returns the converted amount.
+func (a Api) ValueAmount(c *gin.Context) {
+	preciseAmount, ok := new(big.Int).SetString(c.Query("precise_amount"), 10)
+	if !ok {
+		c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "precise_amount must be an integer", c.Query("precise_amount")))
+		return
+	}
+	precision := 0.0
+	if raw := c.Query("precision"); raw != "" {
+		var err error
+		if precision, err = strconv.ParseFloat(raw, 64); err != nil {
+			c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "precision must be a number", err))
+			return
+		}
+	}
+
+	if _, err := a.blnk.GetCurrency(c.Param("code")); err != nil {
+		c.JSON(http.StatusNotFound, apierror.NewAPIError(apierror.ErrNotFound, "currency not found", err))
+		return
+	}
+	value, err := a.blnk.ValueAmount(c.Param("code"), preciseAmount, precision, strings.ToUpper(c.Query("to")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "failed to value amount", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, value)
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ListDormantBalances lists dormant balances, longest dormant first. Use ?status=dormant or ?status=escheated
+// to filter them.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the cursor is invalid.
+// - 500 Internal Server Error: If the balances cannot be retrieved.
+// - 200 OK: If the balances are successfully retrieved.
+func (a Api) ListDormantBalances(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	page, err := queryPage(c, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	balances, err := a.blnk.ListDormantBalances(c.Request.Context(), c.Query("status"), page)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	next := model.NextPageCursor(balances, limit, (*model.DormantBalance).PageCursor)
+	a.respondList(c, balances, listPage{limit: limit, offset: page.Offset, fetched: len(balances), next: next})
+}
+
+// RunDormancyScan flags inactive balances as dormant immediately instead of waiting for the next scan.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 500 Internal Server Error: If the scan fails.
+// - 200 OK: Returns the number of balances flagged and reactivated.
+func (a Api) RunDormancyScan(c *gin.Context) {
+	scan, err := a.blnk.RunDormancyScan(c.Request.Context())
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scan)
+}
+
+// ReactivateBalance clears the dormancy flag of a balance and unfreezes it.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the balance is not dormant.
+// - 409 Conflict: If the balance is part of a pending escheatment batch.
+// - 200 OK: Returns the dormancy record that was cleared.
+func (a Api) ReactivateBalance(c *gin.Context) {
+	balance, err := a.blnk.ReactivateBalance(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondDormancyError(c, err, "Dormant balance not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
+
+// CreateEscheatmentBatch creates a pending batch transferring the funds of the dormant balances of a currency
+// to the holding balance.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body is invalid.
+// - 201 Created: If the batch is successfully created.
+func (a Api) CreateEscheatmentBatch(c *gin.Context) {
+	var req model.EscheatmentBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batch, err := a.blnk.CreateEscheatmentBatch(c.Request.Context(), req)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, batch)
+}
+
+// ListEscheatmentBatches lists escheatment batches, newest first.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the cursor is invalid.
+// - 500 Internal Server Error: If the batches cannot be retrieved.
+// - 200 OK: If the batches are successfully retrieved.
+func (a Api) ListEscheatmentBatches(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	page, err := queryPage(c, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	batches, err := a.blnk.ListEscheatmentBatches(c.Request.Context(), page)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	next := model.NextPageCursor(batches, limit, (*model.EscheatmentBatch).PageCursor)
+	a.respondList(c, batches, listPage{limit: limit, offset: page.Offset, fetched: len(batches), next: next})
+}
+
+// GetEscheatmentBatch retrieves an escheatment batch and its items.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the batch cannot be found.
+// - 200 OK: If the batch is successfully retrieved.
+func (a Api) GetEscheatmentBatch(c *gin.Context) {
+	batch, err := a.blnk.GetEscheatmentBatch(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondDormancyError(c, err, "Escheatment batch not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// ApplyEscheatmentBatch transfers the funds of a batch's balances to the holding balance.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the batch cannot be found.
+// - 409 Conflict: If the batch has already been applied.
+// - 200 OK: Returns the batch, which may have failed to apply.
+func (a Api) ApplyEscheatmentBatch(c *gin.Context) {
+	batch, err := a.blnk.ApplyEscheatmentBatch(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondDormancyError(c, err, "Escheatment batch not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// GetEscheatmentReport downloads the report of an escheatment batch as CSV.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the batch cannot be found.
+// - 200 OK: Returns the CSV report.
+func (a Api) GetEscheatmentReport(c *gin.Context) {
+	report, err := a.blnk.EscheatmentReport(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondDormancyError(c, err, "Escheatment batch not found")
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\""+c.Param("id")+".csv\"")
+	c.Data(http.StatusOK, "text/csv", report)
+}
+
+func respondDormancyError(c *gin.Context, err error, notFound string) {
+	logrus.Error(err)
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	case strings.Contains(err.Error(), "already been applied"), strings.Contains(err.Error(), "is part of escheatment batch"):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
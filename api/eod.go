@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	blnkmodel "github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+)
+
+// RunEOD starts the end-of-day processing of a business date, or resumes it if its run failed. The steps run
+// in the background; follow their progress with GetEODRun.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the body or business date is invalid, or end-of-day processing is not configured.
+// - 409 Conflict: If the business date's run is already in progress.
+// - 200 OK: If the business date's run had already completed, returns that run.
+// - 202 Accepted: Returns the run as it started.
+func (a Api) RunEOD(c *gin.Context) {
+	var req model.RunEODRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	run, err := a.blnk.StartEOD(c.Request.Context(), req.BusinessDate)
+	if err != nil {
+		respondEODError(c, err)
+		return
+	}
+	if run.Finished() {
+		c.JSON(http.StatusOK, run)
+		return
+	}
+	c.JSON(http.StatusAccepted, run)
+}
+
+// ListEODRuns lists end-of-day runs, latest business date first.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the cursor is invalid.
+// - 200 OK: If the runs are successfully retrieved.
+func (a Api) ListEODRuns(c *gin.Context) {
+	page, ok := reportPage(c)
+	if !ok {
+		return
+	}
+
+	runs, err := a.blnk.ListEODRuns(c.Request.Context(), page)
+	if err != nil {
+		respondEODError(c, err)
+		return
+	}
+
+	next := blnkmodel.NextPageCursor(runs, page.Limit, (*blnkmodel.EODRun).PageCursor)
+	a.respondList(c, runs, listPage{limit: page.Limit, offset: page.Offset, fetched: len(runs), next: next})
+}
+
+// GetCurrentEODRun retrieves the run of the latest business date with the progress of each of its steps.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If no business date has been run.
+// - 200 OK: Returns the run.
+func (a Api) GetCurrentEODRun(c *gin.Context) {
+	run, err := a.blnk.GetCurrentEODRun(c.Request.Context())
+	if err != nil {
+		respondEODError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// GetEODRun retrieves the run of a business date with the progress of each of its steps.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the business date has not been run.
+// - 200 OK: Returns the run.
+func (a Api) GetEODRun(c *gin.Context) {
+	run, err := a.blnk.GetEODRun(c.Request.Context(), c.Param("date"))
+	if err != nil {
+		respondEODError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// respondEODError maps end-of-day errors to their HTTP status.
+func respondEODError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, blnk.ErrEODNotConfigured), errors.Is(err, blnk.ErrInvalidBusinessDate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, blnk.ErrEODInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateExternalAccount adds a bank account or wallet to the external account directory. IBANs, routing numbers
+// and wallet addresses are checked, and the account is pending until reviewed.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid or the account details are malformed.
+// - 404 Not Found: If the identity does not exist.
+// - 201 Created: Returns the account.
+func (a Api) CreateExternalAccount(c *gin.Context) {
+	var request apimodel.ExternalAccountRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := a.blnk.CreateExternalAccount(c.Request.Context(), model.ExternalAccount{
+		IdentityID:    request.IdentityID,
+		Type:          request.Type,
+		HolderName:    request.HolderName,
+		Currency:      request.Currency,
+		Country:       request.Country,
+		BankName:      request.BankName,
+		IBAN:          request.IBAN,
+		AccountNumber: request.AccountNumber,
+		RoutingNumber: request.RoutingNumber,
+		BIC:           request.BIC,
+		Network:       request.Network,
+		WalletAddress: request.WalletAddress,
+		MetaData:      request.MetaData,
+	})
+	if err != nil {
+		respondExternalAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+// GetExternalAccount retrieves an external account.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the account does not exist.
+// - 200 OK: Returns the account.
+func (a Api) GetExternalAccount(c *gin.Context) {
+	account, err := a.blnk.GetExternalAccount(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondExternalAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// GetExternalAccounts lists the external accounts of the identity of the path, newest first.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the identity does not exist.
+// - 200 OK: Returns the accounts.
+func (a Api) GetExternalAccounts(c *gin.Context) {
+	accounts, err := a.blnk.GetExternalAccounts(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondExternalAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// ReviewExternalAccount records the review of an external account. Pending accounts are verified or rejected,
+// and verified accounts can be rejected to stop payouts to them. Each review sends an
+// external_account.<status> webhook.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid.
+// - 404 Not Found: If the account does not exist.
+// - 409 Conflict: If the account cannot move to the status or was reviewed concurrently.
+// - 200 OK: Returns the reviewed account.
+func (a Api) ReviewExternalAccount(c *gin.Context) {
+	var request apimodel.ReviewExternalAccountRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := a.blnk.ReviewExternalAccount(c.Request.Context(), c.Param("id"), request.Status, request.Reason)
+	if err != nil {
+		respondExternalAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// respondExternalAccountError maps external account errors to a response.
+func respondExternalAccountError(c *gin.Context, err error) {
+	var apiErr apierror.APIError
+	switch {
+	case errors.Is(err, blnk.ErrInvalidExternalAccount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, blnk.ErrInvalidVerificationTransition), errors.As(err, &apiErr) && apiErr.Code == apierror.ErrConflict:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
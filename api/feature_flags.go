@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/featureflags"
+	"github.com/gin-gonic/gin"
+)
+
+// ListFeatureFlags returns every known feature flag with its current state.
+func (a *Api) ListFeatureFlags(c *gin.Context) {
+	flags, err := a.blnk.ListFeatureFlags(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, apierror.NewAPIError(apierror.ErrInternalServer, "failed to list feature flags", err))
+		return
+	}
+
+	a.respondList(c, flags, listPage{})
+}
+
+// GetFeatureFlag returns a single feature flag.
+func (a *Api) GetFeatureFlag(c *gin.Context) {
+	flag, err := a.blnk.GetFeatureFlag(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, apierror.NewAPIError(apierror.ErrNotFound, "feature flag not found", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, flag)
+}
+
+// SetFeatureFlag stores a runtime override for a feature flag.
+func (a *Api) SetFeatureFlag(c *gin.Context) {
+	var flag featureflags.Flag
+	if err := c.ShouldBindJSON(&flag); err != nil {
+		c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "invalid feature flag data", err))
+		return
+	}
+	flag.Name = c.Param("name")
+
+	if err := a.blnk.SetFeatureFlag(c.Request.Context(), &flag); err != nil {
+		c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "failed to set feature flag", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, flag)
+}
+
+// ResetFeatureFlag removes the runtime override for a feature flag, rolling it back to its configured default.
+func (a *Api) ResetFeatureFlag(c *gin.Context) {
+	if err := a.blnk.ResetFeatureFlag(c.Request.Context(), c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, apierror.NewAPIError(apierror.ErrInternalServer, "failed to reset feature flag", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "feature flag reset to default"})
+}
@@ -2,9 +2,12 @@ package api
 
 import (
 	"net/http"
+	"time"
 
+	apimodel "github.com/blnkfinance/blnk/api/model"
 	"github.com/blnkfinance/blnk/internal/apierror"
 	"github.com/blnkfinance/blnk/internal/hooks"
+	"github.com/blnkfinance/blnk/model"
 	"github.com/gin-gonic/gin"
 )
 
@@ -62,7 +65,7 @@ func (a *Api) ListHooks(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, hooks)
+	a.respondList(c, hooks, listPage{})
 }
 
 // DeleteHook removes a webhook by ID.
@@ -75,3 +78,113 @@ func (a *Api) DeleteHook(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "hook deleted successfully"})
 }
+
+// ReplayWebhooks starts a rate-limited replay of historical webhook events for a time window.
+func (a *Api) ReplayWebhooks(c *gin.Context) {
+	var req model.WebhookReplay
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "invalid replay data", err))
+		return
+	}
+
+	replay, err := a.blnk.ReplayWebhooks(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "failed to start webhook replay", err))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, replay)
+}
+
+// GetWebhookReplay retrieves the progress of a webhook replay by ID.
+func (a *Api) GetWebhookReplay(c *gin.Context) {
+	replay, err := a.blnk.GetWebhookReplay(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, apierror.NewAPIError(apierror.ErrNotFound, "webhook replay not found", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, replay)
+}
+
+// RotateWebhookSigningSecret generates a new webhook signing secret. Deliveries are signed with both
+// secrets until the overlap period ends.
+func (a *Api) RotateWebhookSigningSecret(c *gin.Context) {
+	var req apimodel.RotateSecretRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "invalid rotation data", err))
+			return
+		}
+	}
+
+	secret, err := a.blnk.RotateWebhookSigningSecret(c.Request.Context(), time.Duration(req.OverlapSeconds)*time.Second)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, apierror.NewAPIError(apierror.ErrInternalServer, "failed to rotate webhook signing secret", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"signing_secret":      secret.Current,
+		"rotated_at":          secret.RotatedAt,
+		"previous_expires_at": secret.PreviousExpiresAt,
+	})
+}
+
+// GetWebhookSigningSecretState returns the rotation state of the webhook signing secret.
+func (a *Api) GetWebhookSigningSecretState(c *gin.Context) {
+	state, err := a.blnk.GetWebhookSigningSecretState(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, apierror.NewAPIError(apierror.ErrInternalServer, "failed to get webhook signing secret state", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// ExpireWebhookSigningSecretRotation stops signing deliveries with the previous secret immediately.
+func (a *Api) ExpireWebhookSigningSecretRotation(c *gin.Context) {
+	if err := a.blnk.ExpireWebhookSigningSecretRotation(c.Request.Context()); err != nil {
+		c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "failed to expire webhook signing secret rotation", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "previous signing secret expired"})
+}
+
+// ListWebhookCircuits returns the circuit breaker state of webhook endpoints that are degraded or recovering.
+func (a *Api) ListWebhookCircuits(c *gin.Context) {
+	circuits, err := a.blnk.ListWebhookCircuits(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, apierror.NewAPIError(apierror.ErrInternalServer, "failed to list webhook circuits", err))
+		return
+	}
+
+	a.respondList(c, circuits, listPage{})
+}
+
+// EnableWebhookEndpoint enables a webhook endpoint that was disabled after failing every delivery. A
+// verification ping is sent first, and the endpoint's parked deliveries are released only when it succeeds. The
+// ID "current" refers to the configured endpoint.
+func (a *Api) EnableWebhookEndpoint(c *gin.Context) {
+	circuit, err := a.blnk.EnableWebhookEndpoint(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, circuit)
+}
+
+// GetWebhookAnalytics returns the success rate, latency percentiles, failures by status and volume by event of
+// the deliveries to a webhook endpoint. The window query parameter chooses the last 1h, 24h, 7d or 30d, and
+// defaults to 24h. The ID "current" refers to the configured endpoint.
+func (a *Api) GetWebhookAnalytics(c *gin.Context) {
+	analytics, err := a.blnk.GetWebhookAnalytics(c.Request.Context(), c.Param("id"), c.Query("window"))
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
+}
@@ -16,10 +16,15 @@ limitations under the License.
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/blnkfinance/blnk"
 	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/internal/apierror"
 	"github.com/blnkfinance/blnk/model"
 	"github.com/gin-gonic/gin"
 )
@@ -33,7 +38,8 @@ import (
 // - c: The Gin context containing the request and response.
 //
 // Responses:
-// - 400 Bad Request: If there's an error in binding JSON or creating the identity.
+// - 400 Bad Request: If there's an error in binding JSON or creating the identity. Fields of the meta_data that do
+// not match the schema of the identity type are listed in "fields".
 // - 201 Created: If the identity is successfully created.
 func (a Api) CreateIdentity(c *gin.Context) {
 	var identity model.Identity
@@ -42,17 +48,18 @@ func (a Api) CreateIdentity(c *gin.Context) {
 		return
 	}
 
-	resp, err := a.blnk.CreateIdentity(identity)
+	resp, err := a.blnk.CreateIdentity(c.Request.Context(), identity)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondIdentityError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, resp)
 }
 
-// GetIdentity retrieves an identity record by its ID.
-// It extracts the ID from the route parameters and fetches the identity record.
+// GetIdentity retrieves an identity record by its ID, with its addresses.
+// It extracts the ID from the route parameters and fetches the identity record. Deleted identities are only
+// returned with the include_deleted=true query parameter.
 // If the ID is missing or there's an error retrieving the identity, it responds
 // with an appropriate error message.
 //
@@ -69,12 +76,25 @@ func (a Api) GetIdentity(c *gin.Context) {
 		return
 	}
 
-	resp, err := a.blnk.GetIdentity(id)
+	var resp *model.Identity
+	var err error
+	if c.Query("include_deleted") == "true" {
+		resp, err = a.blnk.GetIdentityIncludingDeleted(c.Request.Context(), id)
+	} else {
+		resp, err = a.blnk.GetIdentity(c.Request.Context(), id)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp.Addresses, err = a.blnk.GetIdentityAddresses(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	setVersionETag(c, resp.Version)
 	c.JSON(http.StatusOK, resp)
 }
 
@@ -82,14 +102,42 @@ func (a Api) GetIdentity(c *gin.Context) {
 // It binds the incoming JSON request to an Identity object, updates the record,
 // and responds with a success message. If any errors occur during binding,
 // validation, or update, it responds with an appropriate error message.
+// The update must name the version of the identity it was made against, in an If-Match header or the version
+// field, so that concurrent updates cannot silently overwrite each other.
 //
 // Parameters:
 // - c: The Gin context containing the request and response.
 //
 // Responses:
-// - 400 Bad Request: If there's an error in binding JSON, updating the identity, or missing ID.
-// - 200 OK: If the identity is successfully updated.
+// - 400 Bad Request: If there's an error in binding JSON, updating the identity, or missing ID. Fields of the
+// meta_data that do not match the schema of the identity type are listed in "fields".
+// - 409 Conflict: If the identity has been updated since the version.
+// - 428 Precondition Required: If the update names no version.
+// - 200 OK: If the identity is successfully updated, with its new version.
 func (a Api) UpdateIdentity(c *gin.Context) {
+	a.updateIdentity(c, false)
+}
+
+// PatchIdentity updates an existing identity record by its ID like UpdateIdentity, except that its meta_data is
+// a JSON merge patch (RFC 7386) of the identity's metadata: keys set to null are removed, objects are merged and
+// other values are set, so that single keys can be changed without sending the whole metadata back.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If there's an error in binding JSON, updating the identity, or missing ID. Fields of the
+// merged meta_data that do not match the schema of the identity type are listed in "fields".
+// - 409 Conflict: If the identity has been updated since the version.
+// - 428 Precondition Required: If the update names no version.
+// - 200 OK: If the identity is successfully updated, with its new version.
+func (a Api) PatchIdentity(c *gin.Context) {
+	a.updateIdentity(c, true)
+}
+
+// updateIdentity updates the identity of the path with the fields of the body, replacing its metadata or merging
+// into it.
+func (a Api) updateIdentity(c *gin.Context, mergeMetaData bool) {
 	var identity model.Identity
 	id, passed := c.Params.Get("id")
 	if !passed {
@@ -102,19 +150,27 @@ func (a Api) UpdateIdentity(c *gin.Context) {
 		return
 	}
 
+	version, ok := requireVersion(c, identity.Version)
+	if !ok {
+		return
+	}
+
 	identity.IdentityID = id
-	err := a.blnk.UpdateIdentity(&identity)
+	identity.Version = version
+	identity.MergeMetaData = mergeMetaData
+	err := a.blnk.UpdateIdentity(c.Request.Context(), &identity)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondIdentityError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Identity updated successfully"})
+	setVersionETag(c, identity.Version)
+	c.JSON(http.StatusOK, gin.H{"message": "Identity updated successfully", "version": identity.Version})
 }
 
-// DeleteIdentity deletes an existing identity record by its ID.
-// It extracts the ID from the route parameters and deletes the record. If the ID is missing
-// or there's an error deleting the identity, it responds with an appropriate error message.
+// DeleteIdentity soft-deletes an existing identity record by its ID.
+// It extracts the ID from the route parameters and marks the record as deleted, keeping it for audit. If the ID
+// is missing or there's an error deleting the identity, it responds with an appropriate error message.
 //
 // Parameters:
 // - c: The Gin context containing the request and response.
@@ -129,7 +185,7 @@ func (a Api) DeleteIdentity(c *gin.Context) {
 		return
 	}
 
-	err := a.blnk.DeleteIdentity(id)
+	err := a.blnk.DeleteIdentity(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -138,24 +194,335 @@ func (a Api) DeleteIdentity(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Identity deleted successfully"})
 }
 
-// GetAllIdentities retrieves all identity records in the system.
-// It fetches the identity records and responds with the list of identities.
-// If there's an error retrieving the identities, it responds with an appropriate error message.
+// RestoreIdentity restores a deleted identity record by its ID.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the ID is missing or there's an error restoring the identity.
+// - 200 OK: If the identity is successfully restored.
+func (a Api) RestoreIdentity(c *gin.Context) {
+	id, passed := c.Params.Get("id")
+	if !passed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is required. pass id in the route /:id"})
+		return
+	}
+
+	err := a.blnk.RestoreIdentity(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity restored successfully"})
+}
+
+// GetIdentityHistory retrieves the change log of an identity: its previous versions, oldest first, each with the
+// fields the update that replaced it changed, who made the update and when.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the identity does not exist.
+// - 500 Internal Server Error: If the history cannot be retrieved.
+// - 200 OK: Returns the previous versions of the identity.
+func (a Api) GetIdentityHistory(c *gin.Context) {
+	history, err := a.blnk.GetIdentityHistory(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// GetAllIdentities retrieves identity records in the system, newest first.
+// The email_address, phone_number, nationality, country, category and identity_type query parameters narrow the
+// list to the identities matching all of them, verification_status lists the identities in a KYC verification
+// stage, risk_level and min_risk_score list the identities for compliance review, riskiest first when
+// min_risk_score is set, and include_deleted=true also lists deleted identities. created_after and created_before
+// (RFC 3339) bound when the identities were created, and meta_data takes comma-separated key:value pairs, or bare
+// keys, the identities' metadata must contain. Without a limit every matching identity is returned; with one the
+// list is paginated by offset or cursor.
 //
 // Parameters:
 // - c: The Gin context containing the request and response.
 //
 // Responses:
-// - 400 Bad Request: If there's an error retrieving the identities.
+// - 400 Bad Request: If the query parameters are invalid or there's an error retrieving the identities.
 // - 200 OK: If the identities are successfully retrieved.
 func (a Api) GetAllIdentities(c *gin.Context) {
-	identities, err := a.blnk.GetAllIdentities()
+	var filter model.IdentityFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var page model.Page
+	if c.Query("limit") != "" {
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		if limit <= 0 || limit > 100 {
+			limit = 20
+		}
+		var err error
+		page, err = queryPage(c, limit)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if page.Offset < 0 {
+			page.Offset = 0
+		}
+	}
+
+	identities, err := a.blnk.GetIdentities(c.Request.Context(), filter, page)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, identities)
+	list := listPage{limit: page.Limit, offset: page.Offset, fetched: len(identities)}
+	// Identities filtered by risk score are ordered riskiest first, so their next page is found by offset.
+	if filter.MinRiskScore <= 0 {
+		list.next = model.NextPageCursor(identities, page.Limit, model.Identity.PageCursor)
+	}
+	a.respondList(c, identities, list)
+}
+
+// UpdateIdentityVerification moves an identity's KYC verification to a new status: unverified identities are
+// submitted as pending, and pending ones become verified or rejected. Each change sends an
+// identity.verification.<status> webhook.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid.
+// - 404 Not Found: If the identity does not exist.
+// - 409 Conflict: If the identity cannot move to the status from its current one.
+// - 200 OK: Returns the identity with its new verification status.
+func (a Api) UpdateIdentityVerification(c *gin.Context) {
+	id, passed := c.Params.Get("id")
+	if !passed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id is required. pass id in the route /:id"})
+		return
+	}
+
+	var request apimodel.UpdateIdentityVerificationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	identity, err := a.blnk.UpdateIdentityVerification(c.Request.Context(), id, request.Status, request.Reason)
+	if err != nil {
+		var apiErr apierror.APIError
+		switch {
+		case errors.Is(err, blnk.ErrInvalidVerificationTransition), errors.As(err, &apiErr) && apiErr.Code == apierror.ErrConflict:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, identity)
+}
+
+// UpdateIdentityStatus moves an identity to a new lifecycle status: active identities can be blocked or closed,
+// and blocked ones activated again or closed. Transactions on the balances of blocked and closed identities are
+// rejected. Each change sends an identity.status.<status> webhook.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid.
+// - 404 Not Found: If the identity does not exist.
+// - 409 Conflict: If the identity cannot move to the status from its current one.
+// - 200 OK: Returns the identity with its new status.
+func (a Api) UpdateIdentityStatus(c *gin.Context) {
+	var request apimodel.UpdateIdentityStatusRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	identity, err := a.blnk.ChangeIdentityStatus(c.Request.Context(), c.Param("id"), request.Status, request.Reason)
+	if err != nil {
+		var apiErr apierror.APIError
+		switch {
+		case errors.Is(err, blnk.ErrInvalidIdentityStatusTransition), errors.As(err, &apiErr) && apiErr.Code == apierror.ErrConflict:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, identity)
+}
+
+// ScoreIdentityRisk scores an identity's risk now and records its risk score and level, such as after the risk
+// rules change. Identities are otherwise scored as they are created and updated.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If risk scoring is not enabled or the identity cannot be scored.
+// - 404 Not Found: If the identity does not exist.
+// - 200 OK: Returns the identity with its new risk score and level.
+func (a Api) ScoreIdentityRisk(c *gin.Context) {
+	identity, err := a.blnk.ScoreIdentityRisk(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, identity)
+}
+
+// ScreenIdentity screens an identity against sanctions and PEP lists now and records the result, such as after
+// the lists were updated. Identities are otherwise screened as they are created and updated.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If screening is not enabled or the identity cannot be screened.
+// - 404 Not Found: If the identity does not exist.
+// - 201 Created: Returns the screening.
+func (a Api) ScreenIdentity(c *gin.Context) {
+	screening, err := a.blnk.ScreenIdentity(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, screening)
+}
+
+// GetIdentityScreenings lists the screenings of an identity, newest first. The newest is the identity's current
+// standing.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the identity does not exist.
+// - 500 Internal Server Error: If the screenings cannot be retrieved.
+// - 200 OK: Returns the screenings.
+func (a Api) GetIdentityScreenings(c *gin.Context) {
+	screenings, err := a.blnk.GetIdentityScreenings(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, screenings)
+}
+
+// GetIdentityBalances lists the balances of an identity, oldest first, so everything a customer holds can be
+// seen in one call. Balances outside the ledger scope of the API key are left out.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the identity does not exist.
+// - 500 Internal Server Error: If the balances cannot be retrieved.
+// - 200 OK: Returns the balances.
+func (a Api) GetIdentityBalances(c *gin.Context) {
+	balances, err := a.blnk.GetIdentityBalances(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	a.respondList(c, balances, listPage{})
+}
+
+// GetIdentityNetWorth returns what an identity holds across its balances, summed by currency and converted to
+// the base currency in the base query parameter, with the total. Balances outside the ledger scope of the API
+// key are left out.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the base currency is missing or unknown.
+// - 404 Not Found: If the identity does not exist.
+// - 500 Internal Server Error: If the balances cannot be retrieved.
+// - 200 OK: Returns the net worth.
+func (a Api) GetIdentityNetWorth(c *gin.Context) {
+	base := c.Query("base")
+	if base == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "base currency is required"})
+		return
+	}
+
+	netWorth, err := a.blnk.GetIdentityNetWorth(c.Request.Context(), c.Param("id"), base)
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, netWorth)
+}
+
+// GetIdentityTransactions lists the transactions that moved money in or out of the balances of an identity,
+// newest first, with pagination. Transactions outside the ledger scope of the API key are left out.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the cursor or offset is invalid.
+// - 404 Not Found: If the identity does not exist.
+// - 500 Internal Server Error: If the transactions cannot be retrieved.
+// - 200 OK: Returns the transactions.
+func (a Api) GetIdentityTransactions(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	page, err := queryPage(c, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	transactions, err := a.blnk.GetIdentityTransactions(c.Request.Context(), c.Param("id"), page)
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	list := listPage{limit: limit, offset: page.Offset, fetched: len(transactions)}
+	list.next = model.NextPageCursor(transactions, limit, a.blnk.TransactionHistoryCursor)
+
+	a.respondList(c, transactions, list)
 }
 
 // TokenizeIdentityField tokenizes a specific field in an identity.
@@ -182,7 +549,7 @@ func (a Api) TokenizeIdentityField(c *gin.Context) {
 		return
 	}
 
-	err := a.blnk.TokenizeIdentityField(id, field)
+	err := a.blnk.TokenizeIdentityField(c.Request.Context(), id, field)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -215,7 +582,7 @@ func (a Api) DetokenizeIdentityField(c *gin.Context) {
 		return
 	}
 
-	originalValue, err := a.blnk.DetokenizeIdentityField(id, field)
+	originalValue, err := a.blnk.DetokenizeIdentityField(c.Request.Context(), id, field)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -252,7 +619,7 @@ func (a Api) TokenizeIdentity(c *gin.Context) {
 		return
 	}
 
-	err := a.blnk.TokenizeIdentity(id, request.Fields)
+	err := a.blnk.TokenizeIdentity(c.Request.Context(), id, request.Fields)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -286,7 +653,7 @@ func (a Api) DetokenizeIdentity(c *gin.Context) {
 
 	// If no specific fields are provided, detokenize all tokenized fields
 	if len(request.Fields) == 0 {
-		detokenizedFields, err := a.blnk.DetokenizeIdentity(id)
+		detokenizedFields, err := a.blnk.DetokenizeIdentity(c.Request.Context(), id)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -299,7 +666,7 @@ func (a Api) DetokenizeIdentity(c *gin.Context) {
 	// Detokenize specific fields
 	result := make(map[string]string)
 	for _, field := range request.Fields {
-		value, err := a.blnk.DetokenizeIdentityField(id, field)
+		value, err := a.blnk.DetokenizeIdentityField(c.Request.Context(), id, field)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -325,7 +692,7 @@ func (a Api) GetTokenizedFields(c *gin.Context) {
 		return
 	}
 
-	identity, err := a.blnk.GetIdentity(id)
+	identity, err := a.blnk.GetIdentity(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -367,3 +734,53 @@ func (a Api) GetTokenizedFields(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"tokenized_fields": tokenizedFields})
 }
+
+// respondIdentityError answers a failed change of an identity, listing the fields of its meta_data that do not
+// match the schema of its identity type, or the fields its identity type requires that it lacks.
+func respondIdentityError(c *gin.Context, err error) {
+	var schemaErr *model.IdentitySchemaError
+	if errors.As(err, &schemaErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": schemaErr.Errors})
+		return
+	}
+	var apiErr apierror.APIError
+	if errors.As(err, &apiErr) {
+		if typeErr, ok := apiErr.Details.(*model.IdentityTypeError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": typeErr.Error(), "fields": typeErr.Errors})
+			return
+		}
+		if apiErr.Code == apierror.ErrConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// requireVersion reads the version an update of a versioned resource was made against, from the If-Match header,
+// as the resource's ETag, or else from the version of the body. It responds 428 Precondition Required when the
+// update names no version, and 400 Bad Request when the If-Match header is not a version.
+func requireVersion(c *gin.Context, bodyVersion int) (int, bool) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" {
+		if bodyVersion <= 0 {
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": "the version being updated is required, in an If-Match header or the version field"})
+			return 0, false
+		}
+		return bodyVersion, true
+	}
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`))
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid If-Match header '%s', expected the version being updated", ifMatch)})
+		return 0, false
+	}
+	return version, true
+}
+
+// setVersionETag sets the ETag of a response to the version of the resource it returns, for updates to send back
+// in their If-Match header.
+func setVersionETag(c *gin.Context, version int) {
+	if version > 0 {
+		c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateIdentityAddress adds an address to the identity of the path. Addresses are residential, mailing or
+// business addresses, and an identity can hold several of each.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid or the address is rejected.
+// - 404 Not Found: If the identity does not exist.
+// - 201 Created: Returns the address.
+func (a Api) CreateIdentityAddress(c *gin.Context) {
+	var request apimodel.IdentityAddressRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address := identityAddressFromRequest(request)
+	address.IdentityID = c.Param("id")
+	created, err := a.blnk.CreateIdentityAddress(c.Request.Context(), address)
+	if err != nil {
+		respondIdentityAddressError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetIdentityAddresses lists the addresses of the identity of the path, oldest first.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the identity does not exist.
+// - 200 OK: Returns the addresses.
+func (a Api) GetIdentityAddresses(c *gin.Context) {
+	addresses, err := a.blnk.GetIdentityAddresses(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondIdentityAddressError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, addresses)
+}
+
+// GetIdentityAddress retrieves an address of the identity of the path.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the address does not exist or belongs to another identity.
+// - 200 OK: Returns the address.
+func (a Api) GetIdentityAddress(c *gin.Context) {
+	address, err := a.blnk.GetIdentityAddress(c.Request.Context(), c.Param("id"), c.Param("address_id"))
+	if err != nil {
+		respondIdentityAddressError(c, err)
+		return
+	}
+
+	setVersionETag(c, address.Version)
+	c.JSON(http.StatusOK, address)
+}
+
+// UpdateIdentityAddress replaces an address of the identity of the path. The update must name the version of the
+// address it was made against, in an If-Match header or the version field.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid or the address is rejected.
+// - 404 Not Found: If the address does not exist or belongs to another identity.
+// - 409 Conflict: If the address has been updated since the version.
+// - 428 Precondition Required: If the update names no version.
+// - 200 OK: Returns the updated address.
+func (a Api) UpdateIdentityAddress(c *gin.Context) {
+	var request apimodel.IdentityAddressRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, ok := requireVersion(c, request.Version)
+	if !ok {
+		return
+	}
+	update := identityAddressFromRequest(request)
+	update.Version = version
+
+	address, err := a.blnk.UpdateIdentityAddress(c.Request.Context(), c.Param("id"), c.Param("address_id"), update)
+	if err != nil {
+		respondIdentityAddressError(c, err)
+		return
+	}
+
+	setVersionETag(c, address.Version)
+	c.JSON(http.StatusOK, address)
+}
+
+// DeleteIdentityAddress removes an address of the identity of the path.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the address does not exist or belongs to another identity.
+// - 200 OK: If the address was deleted.
+func (a Api) DeleteIdentityAddress(c *gin.Context) {
+	if err := a.blnk.DeleteIdentityAddress(c.Request.Context(), c.Param("id"), c.Param("address_id")); err != nil {
+		respondIdentityAddressError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity address deleted successfully"})
+}
+
+func identityAddressFromRequest(request apimodel.IdentityAddressRequest) model.IdentityAddress {
+	return model.IdentityAddress{
+		Type:     request.Type,
+		Street:   request.Street,
+		City:     request.City,
+		State:    request.State,
+		PostCode: request.PostCode,
+		Country:  request.Country,
+		MetaData: request.MetaData,
+	}
+}
+
+// respondIdentityAddressError maps identity address errors to a response.
+func respondIdentityAddressError(c *gin.Context, err error) {
+	var apiErr apierror.APIError
+	switch {
+	case errors.Is(err, blnk.ErrInvalidIdentityAddress):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.As(err, &apiErr) && apiErr.Code == apierror.ErrConflict:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk"
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// UploadIdentityDocument attaches a verification document, such as a passport scan or a utility bill, to an
+// identity. The request is a multipart form with the file in "file", its type in "document_type" and an optional
+// "expires_at" date (YYYY-MM-DD or RFC 3339). PDFs, JPEGs and PNGs of up to 10 MB are accepted.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the form is invalid or the file is rejected.
+// - 404 Not Found: If the identity does not exist.
+// - 500 Internal Server Error: If the file cannot be stored.
+// - 201 Created: Returns the document.
+func (a Api) UploadIdentityDocument(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+
+	document := model.IdentityDocument{
+		IdentityID:   c.Param("id"),
+		DocumentType: c.PostForm("document_type"),
+		FileName:     header.Filename,
+	}
+	if expiresAt := c.PostForm("expires_at"); expiresAt != "" {
+		parsed, err := parseDocumentExpiry(expiresAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"})
+			return
+		}
+		document.ExpiresAt = &parsed
+	}
+
+	uploaded, err := a.blnk.UploadIdentityDocument(c.Request.Context(), document, file)
+	if err != nil {
+		respondIdentityDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, uploaded)
+}
+
+// ListIdentityDocuments retrieves the documents attached to an identity, newest first.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the identity does not exist.
+// - 200 OK: Returns the documents.
+func (a Api) ListIdentityDocuments(c *gin.Context) {
+	documents, err := a.blnk.ListIdentityDocuments(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondIdentityDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, documents)
+}
+
+// GetIdentityDocument retrieves the metadata of a document attached to an identity.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the document does not exist or belongs to another identity.
+// - 200 OK: Returns the document.
+func (a Api) GetIdentityDocument(c *gin.Context) {
+	document, err := a.blnk.GetIdentityDocument(c.Request.Context(), c.Param("id"), c.Param("document_id"))
+	if err != nil {
+		respondIdentityDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, document)
+}
+
+// DownloadIdentityDocument returns the file of a document attached to an identity.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the document does not exist or belongs to another identity.
+// - 500 Internal Server Error: If the file cannot be loaded.
+// - 200 OK: Returns the file.
+func (a Api) DownloadIdentityDocument(c *gin.Context) {
+	document, data, err := a.blnk.DownloadIdentityDocument(c.Request.Context(), c.Param("id"), c.Param("document_id"))
+	if err != nil {
+		respondIdentityDocumentError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\""+strings.ReplaceAll(document.FileName, "\"", "")+"\"")
+	c.Data(http.StatusOK, document.ContentType, data)
+}
+
+// ReviewIdentityDocument records the review of a pending identity document, which ends verified or rejected.
+// Each review sends an identity.document.<status> webhook.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid or an expired document is verified.
+// - 404 Not Found: If the document does not exist or belongs to another identity.
+// - 409 Conflict: If the document was already reviewed or the status is not a review outcome.
+// - 200 OK: Returns the reviewed document.
+func (a Api) ReviewIdentityDocument(c *gin.Context) {
+	var request apimodel.ReviewIdentityDocumentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	document, err := a.blnk.ReviewIdentityDocument(c.Request.Context(), c.Param("id"), c.Param("document_id"), request.Status, request.Reason)
+	if err != nil {
+		respondIdentityDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, document)
+}
+
+// parseDocumentExpiry parses a document's expiry as a date or an RFC 3339 timestamp.
+func parseDocumentExpiry(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.DateOnly, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// respondIdentityDocumentError maps identity document errors to a response.
+func respondIdentityDocumentError(c *gin.Context, err error) {
+	var apiErr apierror.APIError
+	switch {
+	case errors.Is(err, blnk.ErrInvalidIdentityDocument):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, blnk.ErrInvalidVerificationTransition), errors.As(err, &apiErr) && apiErr.Code == apierror.ErrConflict:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
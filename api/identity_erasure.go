@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// AnonymizeIdentity irreversibly erases the personal fields of an identity for a right-to-erasure request,
+// keeping its ID and the balances and transactions that reference it.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body is invalid.
+// - 404 Not Found: If the identity does not exist.
+// - 409 Conflict: If the identity was already erased.
+// - 200 OK: Returns the receipt of the erasure.
+func (a Api) AnonymizeIdentity(c *gin.Context) {
+	var request apimodel.AnonymizeIdentityRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	erasure, err := a.blnk.AnonymizeIdentity(c.Request.Context(), c.Param("id"), request.Reason)
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, erasure)
+}
+
+// GetIdentityErasure retrieves the receipt of an identity's erasure.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the identity was not erased.
+// - 200 OK: Returns the receipt of the erasure.
+func (a Api) GetIdentityErasure(c *gin.Context) {
+	erasure, err := a.blnk.GetIdentityErasure(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, erasure)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+)
+
+// StartIdentityExport starts writing the identities matching a filter to a CSV or Parquet file in the
+// background, in the export directory or the S3 bucket.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid, or its format or destination is unknown.
+// - 500 Internal Server Error: If the export cannot be started.
+// - 202 Accepted: Returns the export, whose progress can be polled.
+func (a Api) StartIdentityExport(c *gin.Context) {
+	var request apimodel.IdentityExportRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	export, err := a.blnk.StartIdentityExport(c.Request.Context(), model.IdentityExport{
+		Format:      request.Format,
+		Destination: request.Destination,
+		Filter:      request.Filter,
+	})
+	if err != nil {
+		if errors.Is(err, blnk.ErrInvalidIdentityExport) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, export)
+}
+
+// GetIdentityExport returns the progress of an identity export, and where its file is once completed.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the export does not exist.
+// - 500 Internal Server Error: If the export cannot be read.
+// - 200 OK: Returns the export.
+func (a Api) GetIdentityExport(c *gin.Context) {
+	export, err := a.blnk.GetIdentityExport(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ImportIdentities creates identities in bulk from a CSV or NDJSON file. The file is either the request body or
+// the "file" field of a multipart form. Its format is taken from the format query parameter, or else from the
+// body's content type or the file's extension. Invalid rows are reported without stopping the import.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the file is missing, its format is unknown or it cannot be read.
+// - 200 OK: Returns the outcome of every row.
+func (a Api) ImportIdentities(c *gin.Context) {
+	format := c.Query("format")
+	var file io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		upload, header, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		defer upload.Close()
+		file = upload
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+		}
+	}
+	if format == "" {
+		format = identityImportFormat(c.ContentType())
+	}
+
+	report, err := a.blnk.ImportIdentities(c.Request.Context(), format, file)
+	if err != nil {
+		if errors.Is(err, blnk.ErrInvalidIdentityImport) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// identityImportFormat returns the import format of a content type, or an empty string if it is not one.
+func identityImportFormat(contentType string) string {
+	switch contentType {
+	case "text/csv":
+		return model.IdentityImportCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return model.IdentityImportNDJSON
+	}
+	return ""
+}
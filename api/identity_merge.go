@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// FindDuplicateIdentities lists the identities that are likely duplicates of an identity: those sharing its
+// email address, its phone number, or its date of birth and name.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the identity does not exist.
+// - 200 OK: Returns the duplicates with the fields each matched on.
+func (a Api) FindDuplicateIdentities(c *gin.Context) {
+	duplicates, err := a.blnk.FindDuplicateIdentities(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondIdentityMergeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, duplicates)
+}
+
+// MergeIdentity merges a duplicate identity into the identity of the path, which survives the merge. The
+// balances and accounts of the duplicate move to the survivor and the duplicate is deleted.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid or the identities are not duplicates.
+// - 404 Not Found: If either identity does not exist or is deleted.
+// - 201 Created: Returns the record of the merge.
+func (a Api) MergeIdentity(c *gin.Context) {
+	var request apimodel.MergeIdentityRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	merge, err := a.blnk.MergeIdentities(c.Request.Context(), c.Param("id"), request.MergedIdentityID, request.Reason)
+	if err != nil {
+		respondIdentityMergeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, merge)
+}
+
+// GetIdentityMerges lists the merges an identity took part in, as survivor or as the merged identity.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 500 Internal Server Error: If the merges cannot be retrieved.
+// - 200 OK: Returns the merges, newest first.
+func (a Api) GetIdentityMerges(c *gin.Context) {
+	merges, err := a.blnk.GetIdentityMerges(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondIdentityMergeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, merges)
+}
+
+// respondIdentityMergeError maps identity merge errors to a response.
+func respondIdentityMergeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, blnk.ErrInvalidIdentityMerge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateIdentityRelationship relates the identity of the path to another identity, such as an individual being a
+// member of an organization or the guardian of a minor. Members, directors and beneficial owners can only be
+// related to organizations.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid or the relationship is rejected.
+// - 404 Not Found: If either identity does not exist.
+// - 409 Conflict: If the identities are already related by the type.
+// - 201 Created: Returns the relationship.
+func (a Api) CreateIdentityRelationship(c *gin.Context) {
+	var request apimodel.CreateIdentityRelationshipRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	relationship, err := a.blnk.CreateIdentityRelationship(c.Request.Context(), model.IdentityRelationship{
+		IdentityID:        c.Param("id"),
+		RelatedIdentityID: request.RelatedIdentityID,
+		Type:              request.Type,
+		Role:              request.Role,
+		MetaData:          request.MetaData,
+	})
+	if err != nil {
+		respondIdentityRelationshipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, relationship)
+}
+
+// GetIdentityRelationship retrieves a relationship of the identity of the path, in either direction.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the relationship does not exist or does not involve the identity.
+// - 200 OK: Returns the relationship.
+func (a Api) GetIdentityRelationship(c *gin.Context) {
+	relationship, err := a.blnk.GetIdentityRelationship(c.Request.Context(), c.Param("id"), c.Param("relationship_id"))
+	if err != nil {
+		respondIdentityRelationshipError(c, err)
+		return
+	}
+
+	setVersionETag(c, relationship.Version)
+	c.JSON(http.StatusOK, relationship)
+}
+
+// UpdateIdentityRelationship replaces the role and metadata of a relationship of the identity of the path. The
+// update must name the version of the relationship it was made against, in an If-Match header or the version
+// field.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid.
+// - 404 Not Found: If the relationship does not exist or does not involve the identity.
+// - 409 Conflict: If the relationship has been updated since the version.
+// - 428 Precondition Required: If the update names no version.
+// - 200 OK: Returns the updated relationship.
+func (a Api) UpdateIdentityRelationship(c *gin.Context) {
+	var request apimodel.UpdateIdentityRelationshipRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version, ok := requireVersion(c, request.Version)
+	if !ok {
+		return
+	}
+
+	relationship, err := a.blnk.UpdateIdentityRelationship(c.Request.Context(), c.Param("id"), c.Param("relationship_id"), request.Role, request.MetaData, version)
+	if err != nil {
+		respondIdentityRelationshipError(c, err)
+		return
+	}
+
+	setVersionETag(c, relationship.Version)
+	c.JSON(http.StatusOK, relationship)
+}
+
+// DeleteIdentityRelationship removes a relationship of the identity of the path.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the relationship does not exist or does not involve the identity.
+// - 200 OK: If the relationship was deleted.
+func (a Api) DeleteIdentityRelationship(c *gin.Context) {
+	if err := a.blnk.DeleteIdentityRelationship(c.Request.Context(), c.Param("id"), c.Param("relationship_id")); err != nil {
+		respondIdentityRelationshipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity relationship deleted successfully"})
+}
+
+// GetRelatedIdentities lists the identities related to the identity of the path, in either direction, with the
+// relationship to each and its direction: outgoing relationships are held by the identity, such as its
+// memberships, and incoming ones by the related identity, such as its members.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the identity does not exist.
+// - 200 OK: Returns the related identities.
+func (a Api) GetRelatedIdentities(c *gin.Context) {
+	related, err := a.blnk.GetRelatedIdentities(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondIdentityRelationshipError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, related)
+}
+
+// respondIdentityRelationshipError maps identity relationship errors to a response.
+func respondIdentityRelationshipError(c *gin.Context, err error) {
+	var apiErr apierror.APIError
+	switch {
+	case errors.Is(err, blnk.ErrInvalidIdentityRelationship):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.As(err, &apiErr) && apiErr.Code == apierror.ErrConflict:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AddIdentityTags adds tags to the identity of the path. Tags are lowercased and kept once, so adding a tag the
+// identity already has changes nothing.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid or a tag is malformed.
+// - 404 Not Found: If the identity does not exist.
+// - 200 OK: Returns the identity with its tags.
+func (a Api) AddIdentityTags(c *gin.Context) {
+	var request apimodel.IdentityTagsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	identity, err := a.blnk.AddIdentityTags(c.Request.Context(), c.Param("id"), request.Tags)
+	if err != nil {
+		respondIdentityTagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, identity)
+}
+
+// RemoveIdentityTag removes the tag of the path from the identity of the path. Removing a tag the identity does
+// not have changes nothing.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the tag is malformed.
+// - 404 Not Found: If the identity does not exist.
+// - 200 OK: Returns the identity with its remaining tags.
+func (a Api) RemoveIdentityTag(c *gin.Context) {
+	identity, err := a.blnk.RemoveIdentityTags(c.Request.Context(), c.Param("id"), []string{c.Param("tag")})
+	if err != nil {
+		respondIdentityTagError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, identity)
+}
+
+func respondIdentityTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, blnk.ErrInvalidIdentityTags):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
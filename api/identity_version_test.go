@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupIdentityRelationshipRouter(t *testing.T) (*gin.Engine, *mocks.MockDataSource) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis: config.RedisConfig{Dns: mr.Addr()},
+		Queue: config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+	})
+
+	mockDS := new(mocks.MockDataSource)
+	b, err := blnk.NewBlnk(mockDS)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	a := Api{blnk: b, router: router}
+	router.PUT("/identities/:id/relationships/:relationship_id", a.UpdateIdentityRelationship)
+	return router, mockDS
+}
+
+func updateIdentityRelationship(router *gin.Engine, ifMatch, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/identities/idt_ada/relationships/rel_1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateIdentityRelationship_RequiresVersion(t *testing.T) {
+	router, mockDS := setupIdentityRelationshipRouter(t)
+	mockDS.On("GetIdentityRelationship", mock.Anything, "rel_1").Return(&model.IdentityRelationship{RelationshipID: "rel_1", IdentityID: "idt_ada", RelatedIdentityID: "idt_org", Type: model.RelationshipDirector, Version: 2}, nil)
+	mockDS.On("UpdateIdentityRelationship", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*model.IdentityRelationship).Version++
+	}).Return(nil)
+
+	w := updateIdentityRelationship(router, "", `{"role":"CFO"}`)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	w = updateIdentityRelationship(router, "version-2", `{"role":"CFO"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = updateIdentityRelationship(router, `"1"`, `{"role":"CFO"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	mockDS.AssertNotCalled(t, "UpdateIdentityRelationship", mock.Anything, mock.Anything)
+
+	w = updateIdentityRelationship(router, `"2"`, `{"role":"CFO"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+}
+
+func TestUpdateIdentityRelationship_VersionInBody(t *testing.T) {
+	router, mockDS := setupIdentityRelationshipRouter(t)
+	mockDS.On("GetIdentityRelationship", mock.Anything, "rel_1").Return(&model.IdentityRelationship{RelationshipID: "rel_1", IdentityID: "idt_ada", RelatedIdentityID: "idt_org", Type: model.RelationshipDirector, Version: 1}, nil)
+	mockDS.On("UpdateIdentityRelationship", mock.Anything, mock.Anything).Return(nil)
+
+	w := updateIdentityRelationship(router, "", `{"role":"CFO","version":1}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
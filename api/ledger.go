@@ -18,8 +18,10 @@ package api
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	model2 "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/model"
 
 	"github.com/gin-gonic/gin"
 )
@@ -48,7 +50,12 @@ func (a Api) CreateLedger(c *gin.Context) {
 		return
 	}
 
-	resp, err := a.blnk.CreateLedger(newLedger.ToLedger())
+	// Keys restricted to specific ledgers cannot create new ones
+	if respondLedgerScopeError(c, a.blnk.CheckLedgerAccess(c.Request.Context(), "")) {
+		return
+	}
+
+	resp, err := a.blnk.CreateLedger(c.Request.Context(), newLedger.ToLedger())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -76,7 +83,11 @@ func (a Api) GetLedger(c *gin.Context) {
 		return
 	}
 
-	resp, err := a.blnk.GetLedgerByID(id)
+	if respondLedgerScopeError(c, a.blnk.CheckLedgerAccess(c.Request.Context(), id)) {
+		return
+	}
+
+	resp, err := a.blnk.GetLedgerByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -97,28 +108,172 @@ func (a Api) GetLedger(c *gin.Context) {
 // - 200 OK: If the ledger records are successfully retrieved.
 func (a Api) GetAllLedgers(c *gin.Context) {
 	// Extract limit and offset from query parameters
-	limit := c.DefaultQuery("limit", "10")  // Default limit is 10 if not provided
-	offset := c.DefaultQuery("offset", "0") // Default offset is 0 if not provided
+	limit := c.DefaultQuery("limit", "10") // Default limit is 10 if not provided
 
-	// Convert limit and offset to integers
+	// Convert limit to an integer and take the offset from the cursor or offset parameter
 	limitInt, err := strconv.Atoi(limit)
 	if err != nil || limitInt < 1 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
 		return
 	}
 
-	offsetInt, err := strconv.Atoi(offset)
-	if err != nil || offsetInt < 0 {
+	page, err := queryPage(c, limitInt)
+	if err != nil || page.Offset < 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset value"})
 		return
 	}
 
-	// Call the GetAllLedgers method with limit and offset
-	resp, err := a.blnk.GetAllLedgers(limitInt, offsetInt)
+	// Call the GetAllLedgers method with the page
+	resp, err := a.blnk.GetAllLedgers(c.Request.Context(), page)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	list := listPage{limit: limitInt, offset: page.Offset, fetched: len(resp), table: "blnk.ledgers"}
+	list.next = model.NextPageCursor(resp, limitInt, model.Ledger.PageCursor)
 
-	c.JSON(http.StatusOK, resp)
+	a.respondList(c, resp, list)
+}
+
+// GetLedgerSequence lists the transactions of a ledger by their sequence number. Pass the last sequence number
+// already read as ?after= to read the next page; a consumer has read every transaction once it reaches
+// last_sequence.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If after or limit is invalid.
+// - 404 Not Found: If the ledger cannot be found.
+// - 200 OK: Returns the transactions and the last sequence number of the ledger.
+func (a Api) GetLedgerSequence(c *gin.Context) {
+	id := c.Param("id")
+	if respondLedgerScopeError(c, a.blnk.CheckLedgerAccess(c.Request.Context(), id)) {
+		return
+	}
+
+	after, err := strconv.ParseInt(c.DefaultQuery("after", "0"), 10, 64)
+	if err != nil || after < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "after must be a sequence number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number"})
+		return
+	}
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	page, err := a.blnk.GetLedgerSequence(c.Request.Context(), id, after, limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ExportLedgerTemplate exports the structure of a ledger, without its transaction data, as a template that
+// POST /ledgers/import recreates in another environment.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 403 Forbidden: If the ledger is outside the caller's ledger scope.
+// - 404 Not Found: If the ledger cannot be found.
+// - 200 OK: Returns the template.
+func (a Api) ExportLedgerTemplate(c *gin.Context) {
+	id := c.Param("id")
+	if respondLedgerScopeError(c, a.blnk.CheckLedgerAccess(c.Request.Context(), id)) {
+		return
+	}
+
+	template, err := a.blnk.ExportLedgerTemplate(c.Request.Context(), id)
+	if err != nil {
+		respondLedgerTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// CloneLedger creates a new ledger with the structure of an existing one: its balances with zero amounts,
+// their monitors and minimums. Transactions are not copied.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid or the copy could not be created.
+// - 403 Forbidden: If the caller is restricted to specific ledgers.
+// - 404 Not Found: If the ledger cannot be found.
+// - 201 Created: Returns the new ledger and the balances created for the ledger's balances.
+func (a Api) CloneLedger(c *gin.Context) {
+	var req model2.CloneLedger
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Keys restricted to specific ledgers cannot create new ones
+	if respondLedgerScopeError(c, a.blnk.CheckLedgerAccess(c.Request.Context(), "")) {
+		return
+	}
+
+	result, err := a.blnk.CloneLedger(c.Request.Context(), c.Param("id"), req.Name)
+	if err != nil {
+		respondLedgerTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// ImportLedgerTemplate creates a new ledger from a template exported by GET /ledgers/:id/template, typically
+// in another environment. Every currency of the template must be registered here.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the template is invalid or the ledger could not be created.
+// - 403 Forbidden: If the caller is restricted to specific ledgers.
+// - 201 Created: Returns the new ledger and the balances created for the template's balances.
+func (a Api) ImportLedgerTemplate(c *gin.Context) {
+	var req model2.ImportLedgerTemplate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Keys restricted to specific ledgers cannot create new ones
+	if respondLedgerScopeError(c, a.blnk.CheckLedgerAccess(c.Request.Context(), "")) {
+		return
+	}
+
+	result, err := a.blnk.ImportLedgerTemplate(c.Request.Context(), req.Template, req.Name)
+	if err != nil {
+		respondLedgerTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// respondLedgerTemplateError maps ledger template errors to a 404 when the ledger is not found, or a 400.
+func respondLedgerTemplateError(c *gin.Context, err error) {
+	if strings.Contains(err.Error(), "not found") {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 }
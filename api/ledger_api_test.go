@@ -17,6 +17,7 @@ limitations under the License.
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -143,7 +144,7 @@ func TestCreateLedger(t *testing.T) {
 
 			if tt.expectedCode == http.StatusCreated {
 				// Verify that the ledger is actually created in the database
-				ledgerFromDB, err := blnk.GetLedgerByID(response.LedgerID)
+				ledgerFromDB, err := blnk.GetLedgerByID(context.Background(), response.LedgerID)
 				if err != nil {
 					t.Errorf("Failed to retrieve ledger by ID: %v", err)
 					return
@@ -158,7 +159,7 @@ func TestCreateLedger(t *testing.T) {
 func TestGetLedger(t *testing.T) {
 	router, b, _ := setupRouter()
 	validPayload := model.Ledger{Name: gofakeit.Name()}
-	newLedger, err := b.CreateLedger(validPayload)
+	newLedger, err := b.CreateLedger(context.Background(), validPayload)
 	if err != nil {
 		return
 	}
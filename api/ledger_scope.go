@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/database"
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	"github.com/gin-gonic/gin"
+)
+
+// respondLedgerScopeError writes the response for a failed ledger scope check. Access outside the
+// caller's ledger scope is a 403; any other error, such as an unknown balance, is a 400.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+// - err: The error returned by the scope check.
+//
+// Returns:
+// - bool: true if a response was written and the handler should stop.
+func respondLedgerScopeError(c *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, blnk.ErrOutsideLedgerScope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "outside_ledger_scope"})
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	return true
+}
+
+// UpdateAPIKeyLedgerScope restricts an API key to specific ledger IDs and balance ID prefixes.
+// Sending empty lists removes the restriction.
+//
+// Parameters:
+// - c: The Gin context containing the request and response
+//
+// Responses:
+// - 200 OK: Returns the saved scope
+// - 400 Bad Request: If the request body is invalid or no owner is given
+// - 404 Not Found: If the API key is not found
+func (a Api) UpdateAPIKeyLedgerScope(c *gin.Context) {
+	owner := requestOwner(c)
+	if owner == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "owner is required"})
+		return
+	}
+
+	var req model.UpdateLedgerScopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope := ledgerscope.Scope{LedgerIDs: req.LedgerIDs, BalancePrefixes: req.BalancePrefixes}
+	if err := a.blnk.UpdateAPIKeyLedgerScope(c.Request.Context(), c.Param("id"), owner, scope); err != nil {
+		if errors.Is(err, database.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scope)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	apimodel "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetMaintenanceStatus retrieves the global maintenance mode and the ledgers in maintenance.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 500 Internal Server Error: If the status could not be read.
+// - 200 OK: Returns the maintenance status.
+func (a Api) GetMaintenanceStatus(c *gin.Context) {
+	status, err := a.blnk.GetMaintenanceStatus(c.Request.Context())
+	if err != nil {
+		respondMaintenanceModeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// SetMaintenanceMode enables or disables global maintenance mode. While it is enabled, requests that change data
+// are rejected with 503 Service Unavailable and reads are still served.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid.
+// - 200 OK: Returns the maintenance mode.
+func (a Api) SetMaintenanceMode(c *gin.Context) {
+	a.setMaintenanceMode(c, "")
+}
+
+// SetLedgerMaintenanceMode enables or disables maintenance mode for the ledger of the path. While it is enabled,
+// balances cannot be created in the ledger and transactions cannot move money in or out of its balances.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request is invalid.
+// - 404 Not Found: If the ledger does not exist.
+// - 200 OK: Returns the maintenance mode.
+func (a Api) SetLedgerMaintenanceMode(c *gin.Context) {
+	a.setMaintenanceMode(c, c.Param("id"))
+}
+
+func (a Api) setMaintenanceMode(c *gin.Context, ledgerID string) {
+	var request apimodel.MaintenanceModeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mode, err := a.blnk.SetMaintenanceMode(c.Request.Context(), model.MaintenanceMode{
+		LedgerID:   ledgerID,
+		Enabled:    request.Enabled,
+		Reason:     request.Reason,
+		RetryAfter: request.RetryAfter,
+	})
+	if err != nil {
+		respondMaintenanceModeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mode)
+}
+
+// respondMaintenanceModeError maps maintenance mode errors to a response.
+func respondMaintenanceModeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, blnk.ErrInvalidMaintenanceMode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/blnkfinance/blnk/internal/apierror"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,6 +14,13 @@ type MetadataRequest struct {
 	Metadata map[string]interface{} `json:"meta_data" binding:"required"`
 }
 
+// MetadataIncrementRequest represents the structure for metadata increment requests: the key to increment and
+// the amount to add to it, 1 when not given.
+type MetadataIncrementRequest struct {
+	Key string   `json:"key" binding:"required"`
+	By  *float64 `json:"by"`
+}
+
 // UpdateMetadata handles HTTP requests to update metadata for various entity types.
 // It processes requests to update metadata for ledgers, transactions, balances, and identities.
 // The entity type is determined automatically from the entity ID prefix.
@@ -38,6 +46,10 @@ func (a Api) UpdateMetadata(c *gin.Context) {
 		return
 	}
 
+	if respondLedgerScopeError(c, a.blnk.CheckEntityAccess(c.Request.Context(), entityID)) {
+		return
+	}
+
 	updatedMetadata, err := a.blnk.UpdateMetadata(c.Request.Context(), entityID, req.Metadata)
 	if err != nil {
 		if errors.Is(err, errors.New("entity not found")) {
@@ -50,3 +62,41 @@ func (a Api) UpdateMetadata(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"metadata": updatedMetadata})
 }
+
+// IncrementMetadata handles HTTP requests to atomically increment a numeric metadata key of a ledger, balance or
+// identity, such as a counter, without reading and writing back the whole metadata.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body is invalid or the key cannot be incremented, such as when it holds a
+// string.
+// - 403 Forbidden: If the entity is outside the caller's ledger scope.
+// - 404 Not Found: If the specified entity doesn't exist.
+// - 200 OK: Returns the entity's metadata after the increment.
+func (a Api) IncrementMetadata(c *gin.Context) {
+	entityID := c.Param("id")
+
+	var req MetadataIncrementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	by := 1.0
+	if req.By != nil {
+		by = *req.By
+	}
+
+	if respondLedgerScopeError(c, a.blnk.CheckEntityAccess(c.Request.Context(), entityID)) {
+		return
+	}
+
+	metadata, err := a.blnk.IncrementMetadata(c.Request.Context(), entityID, req.Key, by)
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"metadata": metadata})
+}
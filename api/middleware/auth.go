@@ -24,6 +24,9 @@ import (
 
 	"github.com/blnkfinance/blnk"
 	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	"github.com/blnkfinance/blnk/internal/servicetoken"
+	"github.com/blnkfinance/blnk/internal/tenant"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -35,24 +38,79 @@ const (
 // pathToResource maps URL paths to their corresponding resource types.
 // This is used by the authentication middleware to determine the required permissions.
 var pathToResource = map[string]Resource{
-	"ledgers":          ResourceLedgers,
-	"balances":         ResourceBalances,
-	"accounts":         ResourceAccounts,
-	"identities":       ResourceIdentities,
-	"transactions":     ResourceTransactions,
-	"balance-monitors": ResourceBalanceMonitors,
-	"hooks":            ResourceHooks,
-	"api-keys":         ResourceAPIKeys,
-	"search":           ResourceSearch,
-	"reconciliation":   ResourceReconciliation,
-	"metadata":         ResourceMetadata,
-	"backup":           ResourceBackup,
+	"ledgers":             ResourceLedgers,
+	"balances":            ResourceBalances,
+	"balance-aliases":     ResourceBalances,
+	"accounts":            ResourceAccounts,
+	"external-accounts":   ResourceAccounts,
+	"identities":          ResourceIdentities,
+	"transactions":        ResourceTransactions,
+	"groups":              ResourceTransactions,
+	"balance-monitors":    ResourceBalanceMonitors,
+	"hooks":               ResourceHooks,
+	"webhooks":            ResourceHooks,
+	"api-keys":            ResourceAPIKeys,
+	"search":              ResourceSearch,
+	"reconciliation":      ResourceReconciliation,
+	"metadata":            ResourceMetadata,
+	"backup":              ResourceBackup,
+	"statements":          ResourceStatements,
+	"feature-flags":       ResourceFeatureFlags,
+	"usage":               ResourceUsage,
+	"service-accounts":    ResourceServiceAccounts,
+	"netting-groups":      ResourceNetting,
+	"netting-settlements": ResourceNetting,
+	"card-authorizations": ResourceCardAuthorizations,
+	"authorize":           ResourceCardAuthorizations,
+	"dormant-balances":    ResourceEscheatment,
+	"escheatment-batches": ResourceEscheatment,
+	"request-logs":        ResourceRequestLogs,
+	"warehouse":           ResourceWarehouse,
+	"compliance-export":   ResourceComplianceExport,
+	"currencies":          ResourceCurrencies,
+	"calendars":           ResourceCalendars,
+	"calculate":           ResourceCalculator,
+	"minimum-balances":    ResourceMinimumBalances,
+	"challenges":          ResourceChallenges,
+	"reports":             ResourceReports,
+	"sessions":            ResourceSessions,
+	"eod":                 ResourceEOD,
+	"maintenance":         ResourceMaintenance,
+	"diagnostics":         ResourceDiagnostics,
+}
+
+// ledgerUnscopedResources are resources that cannot be filtered by ledger, so API keys with a ledger scope
+// are denied access to them. API keys and service accounts are listed so that a scoped key cannot widen its
+// own scope or mint credentials without one.
+var ledgerUnscopedResources = map[Resource]bool{
+	ResourceAccounts:         true,
+	ResourceAPIKeys:          true,
+	ResourceServiceAccounts:  true,
+	ResourceBalanceMonitors:  true,
+	ResourceHooks:            true,
+	ResourceSearch:           true,
+	ResourceReconciliation:   true,
+	ResourceBackup:           true,
+	ResourceStatements:       true,
+	ResourceNetting:          true,
+	ResourceEscheatment:      true,
+	ResourceRequestLogs:      true,
+	ResourceWarehouse:        true,
+	ResourceComplianceExport: true,
+	ResourceChallenges:       true,
+	ResourceReports:          true,
+	ResourceMinimumBalances:  true,
+	ResourceSessions:         true,
+	ResourceEOD:              true,
+	ResourceMaintenance:      true,
+	ResourceDiagnostics:      true,
 }
 
 // AuthMiddleware handles authentication and authorization for API routes.
-// It supports both master key and API key authentication using the X-Blnk-Key header.
+// It supports master key, API key and service token authentication using the X-Blnk-Key header.
 type AuthMiddleware struct {
-	service *blnk.Blnk
+	service         *blnk.Blnk
+	countryResolver CountryResolver
 }
 
 // NewAuthMiddleware creates a new instance of AuthMiddleware.
@@ -66,6 +124,13 @@ func NewAuthMiddleware(blnk *blnk.Blnk) *AuthMiddleware {
 	return &AuthMiddleware{service: blnk}
 }
 
+// isChallengeCallback reports whether a path, without its version prefix, is the callback endpoint of a
+// transaction challenge: /challenges/:id/callback.
+func isChallengeCallback(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	return len(parts) == 3 && parts[0] == "challenges" && parts[1] != "" && parts[2] == "callback"
+}
+
 // getResourceFromPath determines the resource type from the URL path.
 //
 // Parameters:
@@ -74,8 +139,8 @@ func NewAuthMiddleware(blnk *blnk.Blnk) *AuthMiddleware {
 // Returns:
 // - Resource: The determined resource type, or empty string if not found.
 func getResourceFromPath(path string) Resource {
-	// Remove leading slash and get first path segment
-	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	// Remove the version prefix and leading slash and get first path segment
+	parts := strings.Split(strings.TrimPrefix(stripVersionPrefix(path), "/"), "/")
 	if len(parts) == 0 {
 		return ""
 	}
@@ -168,7 +233,7 @@ func injectAPIKeyToMetadata(c *gin.Context, apiKeyID string) error {
 
 // Authenticate returns a middleware function that handles authentication and authorization for all routes.
 // It checks for the X-Blnk-Key header and validates it against either the master key or API keys.
-// For API keys, it verifies the key's validity and checks permissions based on the resource and HTTP method.
+// For API keys, it verifies the key's validity and network policy and checks permissions based on the resource and HTTP method.
 // For POST requests with API keys, it injects the API key ID into the metadata of the request body.
 //
 // Returns:
@@ -177,7 +242,7 @@ func injectAPIKeyToMetadata(c *gin.Context, apiKeyID string) error {
 // Responses:
 // - 200 OK: When authentication succeeds.
 // - 401 Unauthorized: When the API key is missing or invalid.
-// - 403 Forbidden: When the API key lacks sufficient permissions.
+// - 403 Forbidden: When the API key lacks sufficient permissions or its network policy rejects the request.
 func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip auth for root path
@@ -192,6 +257,30 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 			return
 		}
 
+		// Skip auth for the readiness check, which only reports whether the server is ready or read-only
+		if c.Request != nil && c.Request.URL != nil && c.Request.URL.Path == "/readyz" {
+			c.Next()
+			return
+		}
+
+		// Skip auth for metrics, which only carry counts per dependency
+		if c.Request != nil && c.Request.URL != nil && c.Request.URL.Path == "/metrics" {
+			c.Next()
+			return
+		}
+
+		// Skip auth for the token endpoint, which authenticates with service account credentials
+		if c.Request != nil && c.Request.URL != nil && stripVersionPrefix(c.Request.URL.Path) == "/auth/token" {
+			c.Next()
+			return
+		}
+
+		// Skip auth for challenge callbacks, which the authentication system signs with the callback secret
+		if c.Request != nil && c.Request.URL != nil && isChallengeCallback(stripVersionPrefix(c.Request.URL.Path)) {
+			c.Next()
+			return
+		}
+
 		// Check if secure mode is enabled
 		conf, err := config.Fetch()
 		if err == nil && conf != nil && !conf.Server.Secure {
@@ -215,6 +304,12 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 			return
 		}
 
+		// Service tokens are issued to service accounts and carry their own scopes
+		if servicetoken.IsToken(key) {
+			m.authenticateServiceToken(c, key)
+			return
+		}
+
 		// If not master key, try API key authentication
 		apiKey, err := m.service.GetAPIKeyByKey(c.Request.Context(), key)
 		if err != nil {
@@ -229,6 +324,10 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 			return
 		}
 
+		if !m.enforceNetworkPolicy(c, apiKey) {
+			return
+		}
+
 		// Determine required resource from path
 		if c.Request == nil || c.Request.URL == nil {
 			c.JSON(500, gin.H{"error": "Invalid request"})
@@ -259,16 +358,68 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 			}
 		}
 
+		// Keys restricted to specific ledgers cannot use endpoints whose results are not scoped by ledger
+		if apiKey.LedgerScope.Restricted() {
+			if ledgerUnscopedResources[resource] {
+				c.JSON(403, gin.H{"error": "API key is restricted to specific ledgers and cannot access " + string(resource), "code": "outside_ledger_scope"})
+				c.Abort()
+				return
+			}
+			c.Request = c.Request.WithContext(ledgerscope.WithScope(c.Request.Context(), apiKey.LedgerScope))
+		}
+
 		// Update last used timestamp in background
 		go func() {
 			_ = m.service.UpdateLastUsed(c.Request.Context(), apiKey.APIKeyID)
 		}()
 
+		// The owner of the API key is the tenant for feature flags and usage metering
+		c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), apiKey.OwnerID))
+
 		c.Set("apiKey", apiKey)
 		c.Next()
 	}
 }
 
+// authenticateServiceToken validates a service token and checks that its scopes allow the request.
+//
+// Parameters:
+// - c: The Gin context containing the request.
+// - token: The service token from the X-Blnk-Key header.
+func (m *AuthMiddleware) authenticateServiceToken(c *gin.Context, token string) {
+	claims, err := m.service.ValidateServiceToken(c.Request.Context(), token)
+	if err != nil {
+		c.JSON(401, gin.H{"error": "Invalid or expired service token"})
+		c.Abort()
+		return
+	}
+
+	resource := getResourceFromPath(c.Request.URL.Path)
+	if resource == "" {
+		c.JSON(403, gin.H{"error": "Unknown resource type"})
+		c.Abort()
+		return
+	}
+
+	if !HasPermission(claims.Scopes, resource, c.Request.Method) {
+		action := methodToAction[c.Request.Method]
+		c.JSON(403, gin.H{"error": "Insufficient permissions for " + string(resource) + ":" + string(action)})
+		c.Abort()
+		return
+	}
+
+	if c.Request.Method == "POST" && c.Request.Body != nil {
+		if err := injectAPIKeyToMetadata(c, claims.ServiceAccountID); err != nil {
+			logrus.Error("Failed to inject service account ID into metadata:", err)
+		}
+	}
+
+	c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), claims.OwnerID))
+
+	c.Set("serviceAccount", claims)
+	c.Next()
+}
+
 // extractKey retrieves the authentication key from the X-Blnk-Key header.
 //
 // Parameters:
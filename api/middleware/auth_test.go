@@ -7,11 +7,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/blnkfinance/blnk"
 	"github.com/blnkfinance/blnk/config"
 	"github.com/blnkfinance/blnk/database"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	"github.com/blnkfinance/blnk/model"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func setupBlnk() (*blnk.Blnk, error) {
@@ -631,6 +637,51 @@ func TestGetResourceFromPath(t *testing.T) {
 			path:     "/backup",
 			expected: ResourceBackup,
 		},
+		{
+			name:     "Valid challenges path with ID",
+			path:     "/challenges/chl_1",
+			expected: ResourceChallenges,
+		},
+		{
+			name:     "Valid report runs path",
+			path:     "/reports/runs/report_run_1",
+			expected: ResourceReports,
+		},
+		{
+			name:     "Valid calendar day path",
+			path:     "/calendars/US/days/2026-12-25",
+			expected: ResourceCalendars,
+		},
+		{
+			name:     "Valid calculate path",
+			path:     "/calculate",
+			expected: ResourceCalculator,
+		},
+		{
+			name:     "Valid minimum balances path",
+			path:     "/minimum-balances/min_123",
+			expected: ResourceMinimumBalances,
+		},
+		{
+			name:     "Valid session commit path",
+			path:     "/sessions/ses_123/commit",
+			expected: ResourceSessions,
+		},
+		{
+			name:     "Valid eod runs path",
+			path:     "/eod/runs/current",
+			expected: ResourceEOD,
+		},
+		{
+			name:     "Valid diagnostics path",
+			path:     "/diagnostics/schema",
+			expected: ResourceDiagnostics,
+		},
+		{
+			name:     "Valid authorize path",
+			path:     "/authorize",
+			expected: ResourceCardAuthorizations,
+		},
 		{
 			name:     "Unknown resource",
 			path:     "/unknown",
@@ -747,3 +798,44 @@ func TestHasPermission(t *testing.T) {
 		})
 	}
 }
+
+func TestIsChallengeCallback(t *testing.T) {
+	assert.True(t, isChallengeCallback("/challenges/chl_1/callback"))
+	assert.True(t, isChallengeCallback(stripVersionPrefix("/v2/challenges/chl_1/callback")))
+	assert.False(t, isChallengeCallback("/challenges/chl_1"))
+	assert.False(t, isChallengeCallback("/challenges//callback"))
+	assert.False(t, isChallengeCallback("/transactions/chl_1/callback"))
+}
+
+func TestAuthMiddleware_LedgerScopedKeyCannotManageCredentials(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{Redis: config.RedisConfig{Dns: mr.Addr()}, Server: config.ServerConfig{Secure: true}})
+	apiKey := &model.APIKey{
+		APIKeyID:    "api_key_1",
+		Scopes:      []string{"*:*"},
+		ExpiresAt:   time.Now().Add(time.Hour),
+		LedgerScope: ledgerscope.Scope{LedgerIDs: []string{"ldg_payouts"}},
+	}
+	mockDS := new(mocks.MockDataSource)
+	mockDS.On("GetAPIKey", mock.Anything, "scoped-key").Return(apiKey, nil)
+	mockDS.On("UpdateLastUsed", mock.Anything, mock.Anything).Return(nil).Maybe()
+	b, err := blnk.NewBlnk(mockDS)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(NewAuthMiddleware(b).Authenticate())
+	router.Any("/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, target := range []string{"/api-keys", "/api-keys/api_key_1/ledger-scope", "/service-accounts"} {
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		req.Header.Set("X-Blnk-Key", "scoped-key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code, target)
+		assert.Contains(t, w.Body.String(), "outside_ledger_scope", target)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/servicetoken"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// metadataResponseWriter holds back the response body so encrypted metadata can be revealed or masked.
+type metadataResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *metadataResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *metadataResponseWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// EncryptedMetadata returns a middleware that encrypts the configured metadata keys in JSON request bodies
+// before they are stored, and reveals encrypted values in JSON responses. Callers without the
+// encrypted-metadata:read scope see the configured mask instead of the value.
+// It must run after authentication so the caller's scopes are known.
+//
+// Parameters:
+// - b: The Blnk service that encrypts and decrypts metadata.
+//
+// Returns:
+// - gin.HandlerFunc: A middleware function that protects encrypted metadata.
+func EncryptedMetadata(b *blnk.Blnk) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf, err := config.Fetch()
+		if err != nil || len(conf.EncryptedMetadata.Keys) == 0 {
+			c.Next()
+			return
+		}
+
+		if err := encryptRequestMetadata(c, b); err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"error": "failed to encrypt metadata: " + err.Error()})
+			return
+		}
+
+		writer := &metadataResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		if bytes.Contains(body, []byte(blnk.EncryptedMetadataPrefix)) && strings.Contains(c.Writer.Header().Get("Content-Type"), "application/json") {
+			body = revealResponseMetadata(b, body, canReadEncryptedMetadata(c))
+		}
+		if _, err := c.Writer.Write(body); err != nil {
+			logrus.Error("Failed to write response:", err)
+		}
+	}
+}
+
+// encryptRequestMetadata rewrites a JSON request body with its metadata values encrypted.
+func encryptRequestMetadata(c *gin.Context, b *blnk.Blnk) error {
+	if c.Request.Body == nil || (c.Request.Method != "POST" && c.Request.Method != "PUT" && c.Request.Method != "PATCH") {
+		return nil
+	}
+
+	bodyBytes, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	_ = c.Request.Body.Close()
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+
+	decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		// Not JSON; leave the body for the handler to reject
+		return nil
+	}
+
+	if err := b.EncryptMetadataFields(document); err != nil {
+		return err
+	}
+
+	modified, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(modified))
+	c.Request.ContentLength = int64(len(modified))
+	return nil
+}
+
+// revealResponseMetadata decrypts or masks the encrypted metadata in a JSON response body.
+func revealResponseMetadata(b *blnk.Blnk, body []byte, decrypt bool) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return body
+	}
+
+	b.RevealMetadataFields(document, decrypt)
+
+	revealed, err := json.Marshal(document)
+	if err != nil {
+		logrus.Error("Failed to encode response metadata:", err)
+		return body
+	}
+	return revealed
+}
+
+// canReadEncryptedMetadata reports whether the caller may see encrypted metadata values. The master key
+// and unsecured servers always can; API keys and service tokens need the dedicated scope.
+func canReadEncryptedMetadata(c *gin.Context) bool {
+	return hasExplicitScope(c, ResourceEncryptedMetadata, ActionRead, ActionAll)
+}
+
+// CanOverrideMinimumBalance reports whether the caller may post transactions that breach minimum balances.
+// The master key and unsecured servers always can; API keys and service tokens need the
+// minimum-balances:override scope.
+func CanOverrideMinimumBalance(c *gin.Context) bool {
+	return hasExplicitScope(c, ResourceMinimumBalances, ActionOverride)
+}
+
+// CallerHasPermission reports whether the caller may use an HTTP method on a resource, for endpoints that act
+// on resources other than the one their path names. The master key and unsecured servers are granted
+// everything.
+func CallerHasPermission(c *gin.Context, resource Resource, method string) bool {
+	if c.GetBool("isMasterKey") {
+		return true
+	}
+	if conf, err := config.Fetch(); err == nil && !conf.Server.Secure {
+		return true
+	}
+	return HasPermission(callerScopes(c), resource, method)
+}
+
+// callerScopes returns the scopes of the API key or service token the request was authenticated with.
+func callerScopes(c *gin.Context) []string {
+	var scopes []string
+	if apiKey, ok := c.Get("apiKey"); ok {
+		if key, ok := apiKey.(*model.APIKey); ok {
+			scopes = key.Scopes
+		}
+	}
+	if claims, ok := c.Get("serviceAccount"); ok {
+		if token, ok := claims.(*servicetoken.Claims); ok {
+			scopes = token.Scopes
+		}
+	}
+	return scopes
+}
+
+// hasExplicitScope reports whether the caller was granted one of actions on a resource by name rather than
+// by a wildcard resource. The master key and unsecured servers are granted everything.
+func hasExplicitScope(c *gin.Context, resource Resource, actions ...Action) bool {
+	if c.GetBool("isMasterKey") {
+		return true
+	}
+	if conf, err := config.Fetch(); err == nil && !conf.Server.Secure {
+		return true
+	}
+
+	for _, scope := range callerScopes(c) {
+		scopeResource, scopeAction := ParseScope(scope)
+		if scopeResource != resource {
+			continue
+		}
+		for _, action := range actions {
+			if scopeAction == action {
+				return true
+			}
+		}
+	}
+	return false
+}
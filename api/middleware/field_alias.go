@@ -0,0 +1,287 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// FieldRename is a JSON field that was renamed. The handlers still use the Deprecated name; the aliasing
+// layer accepts either name in requests and emits the names the caller's compatibility setting asks for.
+type FieldRename struct {
+	Deprecated string
+	Current    string
+}
+
+// fieldAliasMetrics counts the requests that sent deprecated field names, by field, and the requests served
+// in each compatibility mode, so that operators can tell when the deprecated names can be retired.
+var fieldAliasMetrics = struct {
+	mu         sync.Mutex
+	deprecated map[string]uint64
+	modes      map[string]uint64
+}{deprecated: map[string]uint64{}, modes: map[string]uint64{}}
+
+// FieldAliasing returns a middleware that lets renamed JSON fields be sent under either name and emits them
+// according to the caller's field compatibility: the API key's own setting, or else the deployment's
+// default. Legacy callers receive the deprecated names, current callers the current names, and callers
+// migrating between them both. It only applies to requests served as one of versions, since later versions
+// speak the current names only, and must run after authentication so the API key is known.
+//
+// Parameters:
+// - renames: The renamed fields.
+// - versions: The API versions the aliasing applies to.
+//
+// Returns:
+// - gin.HandlerFunc: A middleware function that aliases renamed fields.
+func FieldAliasing(renames []FieldRename, versions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !containsString(versions, RequestAPIVersion(c)) || c.Request.URL.Path == "/metrics" {
+			c.Next()
+			return
+		}
+
+		mode := requestFieldCompatibility(c)
+		recordFieldCompatibilityMode(mode)
+
+		if err := aliasRequestBody(c, renames); err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if mode == model.FieldCompatibilityLegacy {
+			c.Next()
+			return
+		}
+
+		writer := &versionResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		if strings.Contains(c.Writer.Header().Get("Content-Type"), "application/json") {
+			body = adaptJSON(body, func(document interface{}) interface{} {
+				return aliasResponseFields(document, renames, mode == model.FieldCompatibilityBoth)
+			})
+		}
+		if _, err := c.Writer.Write(body); err != nil {
+			logrus.Error("Failed to write response:", err)
+		}
+	}
+}
+
+// requestFieldCompatibility returns the field compatibility mode of the caller: its API key's setting, or
+// else the deployment's default, which is legacy unless configured otherwise.
+func requestFieldCompatibility(c *gin.Context) string {
+	if apiKey, ok := c.Get("apiKey"); ok {
+		if key, ok := apiKey.(*model.APIKey); ok && key.FieldCompatibility != "" {
+			return key.FieldCompatibility
+		}
+	}
+	if conf, err := config.Fetch(); err == nil && model.ValidFieldCompatibility(conf.APIVersions.FieldCompatibility) {
+		return conf.APIVersions.FieldCompatibility
+	}
+	return model.FieldCompatibilityLegacy
+}
+
+// aliasRequestBody rewrites the current names of renamed fields in a JSON request body to the deprecated names
+// the handlers use, counting the fields that were sent under their deprecated names.
+func aliasRequestBody(c *gin.Context, renames []FieldRename) error {
+	if c.Request.Body == nil || (c.Request.Method != "POST" && c.Request.Method != "PUT" && c.Request.Method != "PATCH") {
+		return nil
+	}
+
+	bodyBytes, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	_ = c.Request.Body.Close()
+
+	// Most bodies name none of the renamed fields, and are passed on without being decoded
+	mentioned := false
+	for _, rename := range renames {
+		if bytes.Contains(bodyBytes, []byte(`"`+rename.Deprecated+`"`)) || bytes.Contains(bodyBytes, []byte(`"`+rename.Current+`"`)) {
+			mentioned = true
+			break
+		}
+	}
+	if mentioned {
+		used := map[string]bool{}
+		bodyBytes = adaptJSON(bodyBytes, func(document interface{}) interface{} {
+			return aliasRequestFields(document, renames, used)
+		})
+		for field := range used {
+			recordDeprecatedField(field)
+		}
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	c.Request.ContentLength = int64(len(bodyBytes))
+	return nil
+}
+
+// aliasRequestFields renames the current names of renamed fields to their deprecated names in every object of a
+// JSON document, recording in used the deprecated names the document already had. When an object has both
+// names the current value wins, except that two objects are merged, so that values added to the deprecated
+// field by earlier middleware are kept. The values of renamed fields are left as they are.
+func aliasRequestFields(document interface{}, renames []FieldRename, used map[string]bool) interface{} {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		aliased := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			if renamedField(renames, key) {
+				aliased[key] = child
+				continue
+			}
+			aliased[key] = aliasRequestFields(child, renames, used)
+		}
+		for _, rename := range renames {
+			current, hasCurrent := aliased[rename.Current]
+			deprecated, hasDeprecated := aliased[rename.Deprecated]
+			if hasDeprecated {
+				used[rename.Deprecated] = true
+			}
+			if !hasCurrent {
+				continue
+			}
+			delete(aliased, rename.Current)
+			currentObject, currentIsObject := current.(map[string]interface{})
+			deprecatedObject, deprecatedIsObject := deprecated.(map[string]interface{})
+			if hasDeprecated && currentIsObject && deprecatedIsObject {
+				for key, child := range currentObject {
+					deprecatedObject[key] = child
+				}
+				continue
+			}
+			aliased[rename.Deprecated] = current
+		}
+		return aliased
+	case []interface{}:
+		for i, child := range value {
+			value[i] = aliasRequestFields(child, renames, used)
+		}
+		return value
+	default:
+		return document
+	}
+}
+
+// aliasResponseFields renames the deprecated names of renamed fields to their current names in every object of
+// a JSON document, or with both set adds the current names alongside the deprecated ones. The values of
+// renamed fields are left as they are.
+func aliasResponseFields(document interface{}, renames []FieldRename, both bool) interface{} {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		aliased := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			if renamedField(renames, key) {
+				aliased[key] = child
+				continue
+			}
+			aliased[key] = aliasResponseFields(child, renames, both)
+		}
+		for _, rename := range renames {
+			deprecated, ok := aliased[rename.Deprecated]
+			if !ok {
+				continue
+			}
+			aliased[rename.Current] = deprecated
+			if !both {
+				delete(aliased, rename.Deprecated)
+			}
+		}
+		return aliased
+	case []interface{}:
+		for i, child := range value {
+			value[i] = aliasResponseFields(child, renames, both)
+		}
+		return value
+	default:
+		return document
+	}
+}
+
+// renamedField reports whether a key is either name of a renamed field.
+func renamedField(renames []FieldRename, key string) bool {
+	for _, rename := range renames {
+		if key == rename.Deprecated || key == rename.Current {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func recordDeprecatedField(field string) {
+	fieldAliasMetrics.mu.Lock()
+	fieldAliasMetrics.deprecated[field]++
+	fieldAliasMetrics.mu.Unlock()
+}
+
+func recordFieldCompatibilityMode(mode string) {
+	fieldAliasMetrics.mu.Lock()
+	fieldAliasMetrics.modes[mode]++
+	fieldAliasMetrics.mu.Unlock()
+}
+
+// WriteFieldAliasMetrics writes the use of deprecated field names in the Prometheus text format: the requests
+// that sent each deprecated name and the requests served in each field compatibility mode.
+//
+// Parameters:
+// - w: The writer the metrics are written to.
+//
+// Returns:
+// - error: An error if the metrics could not be written.
+func WriteFieldAliasMetrics(w io.Writer) error {
+	fieldAliasMetrics.mu.Lock()
+	deprecated := copyCounts(fieldAliasMetrics.deprecated)
+	modes := copyCounts(fieldAliasMetrics.modes)
+	fieldAliasMetrics.mu.Unlock()
+
+	metrics := []struct {
+		name, help, label string
+		counts            map[string]uint64
+	}{
+		{"blnk_deprecated_field_requests_total", "Requests that sent a renamed field under its deprecated name.", "field", deprecated},
+		{"blnk_field_compatibility_requests_total", "Requests served in each field compatibility mode.", "mode", modes},
+	}
+	for _, metric := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", metric.name, metric.help, metric.name); err != nil {
+			return err
+		}
+		labels := make([]string, 0, len(metric.counts))
+		for label := range metric.counts {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			if _, err := fmt.Fprintf(w, "%s{%s=%q} %d\n", metric.name, metric.label, label, metric.counts[label]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func copyCounts(counts map[string]uint64) map[string]uint64 {
+	copied := make(map[string]uint64, len(counts))
+	for key, count := range counts {
+		copied[key] = count
+	}
+	return copied
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFieldRenames = []FieldRename{{Deprecated: "meta_data", Current: "metadata"}}
+
+// setupFieldAliasRouter serves an echo handler behind FieldAliasing, as v1 and as a key with the given field
+// compatibility.
+func setupFieldAliasRouter(keyMode, defaultMode string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	config.ConfigStore.Store(&config.Configuration{APIVersions: config.APIVersionConfig{FieldCompatibility: defaultMode}})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(versionContextKey, "v1")
+		c.Set("apiKey", &model.APIKey{APIKeyID: "api_key_1", FieldCompatibility: keyMode})
+	})
+	router.Use(FieldAliasing(testFieldRenames, "v1"))
+	router.POST("/ledgers", func(c *gin.Context) {
+		var body map[string]interface{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, body)
+	})
+	return router
+}
+
+func postLedger(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/ledgers", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestFieldAliasing_LegacyAcceptsCurrentNames(t *testing.T) {
+	router := setupFieldAliasRouter("", "")
+
+	w := postLedger(router, `{"name":"a","metadata":{"tier":"gold"}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"name":"a","meta_data":{"tier":"gold"}}`, w.Body.String())
+}
+
+func TestFieldAliasing_CurrentEmitsCurrentNames(t *testing.T) {
+	router := setupFieldAliasRouter(model.FieldCompatibilityCurrent, model.FieldCompatibilityLegacy)
+
+	w := postLedger(router, `{"name":"a","meta_data":{"meta_data":"kept"}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"name":"a","metadata":{"meta_data":"kept"}}`, w.Body.String())
+}
+
+func TestFieldAliasing_BothEmitsBothNames(t *testing.T) {
+	router := setupFieldAliasRouter("", model.FieldCompatibilityBoth)
+
+	w := postLedger(router, `{"metadata":{"tier":"gold"}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"meta_data":{"tier":"gold"},"metadata":{"tier":"gold"}}`, w.Body.String())
+}
+
+func TestFieldAliasing_MergesBothNames(t *testing.T) {
+	router := setupFieldAliasRouter("", "")
+
+	w := postLedger(router, `{"meta_data":{"BLNK_GENERATED_BY":"api_key_1","tier":"silver"},"metadata":{"tier":"gold"}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"meta_data":{"BLNK_GENERATED_BY":"api_key_1","tier":"gold"}}`, w.Body.String())
+}
+
+func TestFieldAliasing_SkipsOtherVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(versionContextKey, "v2") })
+	router.Use(FieldAliasing(testFieldRenames, "v1"))
+	router.POST("/ledgers", func(c *gin.Context) {
+		var body map[string]interface{}
+		_ = c.ShouldBindJSON(&body)
+		c.JSON(http.StatusOK, body)
+	})
+
+	w := postLedger(router, `{"metadata":{}}`)
+	assert.JSONEq(t, `{"metadata":{}}`, w.Body.String())
+}
+
+func TestWriteFieldAliasMetrics(t *testing.T) {
+	router := setupFieldAliasRouter(model.FieldCompatibilityCurrent, "")
+	postLedger(router, `{"meta_data":{}}`)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteFieldAliasMetrics(&out))
+	assert.Contains(t, out.String(), "# TYPE blnk_deprecated_field_requests_total counter")
+	assert.Contains(t, out.String(), `blnk_deprecated_field_requests_total{field="meta_data"}`)
+	assert.Contains(t, out.String(), `blnk_field_compatibility_requests_total{mode="current"}`)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/gin-gonic/gin"
+)
+
+// maintenanceExemptResources are served during maintenance whatever their method: the maintenance endpoints
+// themselves, so maintenance can be ended, and the endpoints that only read despite being posted to.
+var maintenanceExemptResources = map[Resource]bool{
+	ResourceMaintenance: true,
+	ResourceSearch:      true,
+	ResourceCalculator:  true,
+}
+
+// MaintenanceMode returns a middleware that rejects requests that change data while Blnk is in maintenance mode,
+// with 503 Service Unavailable and a Retry-After header. Reads are still served. It must run after
+// authentication, so that callers without access are not told about the maintenance.
+//
+// Parameters:
+// - b: The Blnk service that keeps the maintenance mode.
+//
+// Returns:
+// - gin.HandlerFunc: A middleware function that enforces maintenance mode.
+func MaintenanceMode(b *blnk.Blnk) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if methodToAction[c.Request.Method] == ActionRead || c.Request.Method == http.MethodOptions ||
+			maintenanceExemptResources[getResourceFromPath(c.Request.URL.Path)] ||
+			stripVersionPrefix(c.Request.URL.Path) == "/auth/token" {
+			c.Next()
+			return
+		}
+
+		if RespondMaintenance(c, b.CheckMaintenance(c.Request.Context())) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// RespondMaintenance responds with 503 Service Unavailable when err is a *blnk.MaintenanceError, telling the
+// client when to retry, and reports whether it responded.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+// - err: The error of the operation.
+//
+// Returns:
+// - bool: True if the request was rejected for maintenance.
+func RespondMaintenance(c *gin.Context, err error) bool {
+	var maintenance *blnk.MaintenanceError
+	if !errors.As(err, &maintenance) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(maintenance.Mode.RetryAfter))
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error":       maintenance.Error(),
+		"code":        "maintenance_mode",
+		"retry_after": maintenance.Mode.RetryAfter,
+	})
+	return true
+}
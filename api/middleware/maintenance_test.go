@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{Redis: config.RedisConfig{Dns: mr.Addr()}})
+	b, err := blnk.NewBlnk(new(mocks.MockDataSource))
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(MaintenanceMode(b))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/balances", ok)
+	router.POST("/transactions", ok)
+	router.PUT("/maintenance", ok)
+	router.POST("/search/:collection", ok)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/transactions").Code)
+
+	_, err = b.SetMaintenanceMode(context.Background(), model.MaintenanceMode{Enabled: true, Reason: "database failover", RetryAfter: 120})
+	require.NoError(t, err)
+
+	w := serve(http.MethodPost, "/transactions")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "120", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "database failover")
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/balances").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/maintenance").Code, "maintenance can be ended during maintenance")
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/search/transactions").Code)
+
+	_, err = b.SetMaintenanceMode(context.Background(), model.MaintenanceMode{Enabled: false})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/transactions").Code)
+}
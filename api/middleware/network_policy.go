@@ -0,0 +1,148 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CountryResolver returns the ISO 3166-1 alpha-2 country code of a request, or an empty string
+// if it cannot be determined. A GeoIP lookup can be plugged in with SetCountryResolver.
+type CountryResolver func(c *gin.Context) string
+
+// headerCountryResolver reads the country from the header configured in network_policy.country_header. The
+// header is only read from requests forwarded by a trusted proxy, since clients can set it themselves.
+func headerCountryResolver(c *gin.Context) string {
+	policy := networkPolicyConfig()
+	if policy.CountryHeader == "" || !policy.TrustsProxy(net.ParseIP(c.RemoteIP())) {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimSpace(c.GetHeader(policy.CountryHeader)))
+}
+
+// networkPolicyConfig returns the network policy configuration, which trusts no proxy when the configuration
+// cannot be read.
+func networkPolicyConfig() config.NetworkPolicyConfig {
+	conf, err := config.Fetch()
+	if err != nil {
+		return config.NetworkPolicyConfig{}
+	}
+	return conf.NetworkPolicy
+}
+
+// clientIP returns the IP address of the client of a request. X-Forwarded-For is followed from the right
+// only while the hop that added an entry is a trusted proxy, so a client cannot forge the address checked
+// against an allow-list. Without trusted proxies it is the peer of the connection.
+func clientIP(c *gin.Context, policy config.NetworkPolicyConfig) net.IP {
+	ip := net.ParseIP(c.RemoteIP())
+	if !policy.TrustsProxy(ip) {
+		return ip
+	}
+	hops := strings.Split(strings.Join(c.Request.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !policy.TrustsProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// SetCountryResolver replaces the resolver used to find the country of a request.
+//
+// Parameters:
+// - resolver: The resolver to use. The header based resolver is restored when nil.
+func (m *AuthMiddleware) SetCountryResolver(resolver CountryResolver) {
+	m.countryResolver = resolver
+}
+
+// enforceNetworkPolicy checks the request against the API key's network policy. Rejected requests
+// receive a 403 with a machine readable code and are recorded for audit.
+//
+// Parameters:
+// - c: The Gin context containing the request.
+// - apiKey: The authenticated API key.
+//
+// Returns:
+// - bool: true if the request may continue.
+func (m *AuthMiddleware) enforceNetworkPolicy(c *gin.Context, apiKey *model.APIKey) bool {
+	policy := apiKey.NetworkPolicy
+	if len(policy.AllowedCIDRs) == 0 && len(policy.BlockedCountries) == 0 {
+		return true
+	}
+
+	resolver := m.countryResolver
+	if resolver == nil {
+		resolver = headerCountryResolver
+	}
+
+	ip := clientIP(c, networkPolicyConfig())
+	country := ""
+	if len(policy.BlockedCountries) > 0 {
+		country = resolver(c)
+	}
+
+	reason := policy.Check(ip, country)
+	if reason == "" {
+		return true
+	}
+
+	denial := &model.APIKeyAccessDenial{
+		APIKeyID:  apiKey.APIKeyID,
+		IPAddress: ip.String(),
+		Country:   country,
+		Reason:    reason,
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		CreatedAt: time.Now(),
+	}
+	logrus.WithFields(logrus.Fields{
+		"api_key_id": denial.APIKeyID,
+		"ip":         denial.IPAddress,
+		"country":    denial.Country,
+		"reason":     denial.Reason,
+	}).Warn("API key request rejected by network policy")
+
+	// Record in the background with a detached context since the request ends here
+	go func() {
+		if err := m.service.RecordAPIKeyAccessDenial(context.Background(), denial); err != nil {
+			logrus.Error("Failed to record API key access denial:", err)
+		}
+	}()
+
+	c.JSON(403, gin.H{"error": networkPolicyMessages[reason], "code": reason})
+	c.Abort()
+	return false
+}
+
+var networkPolicyMessages = map[string]string{
+	model.AccessDenialIPNotAllowed:      "Request IP address is not allowed for this API key",
+	model.AccessDenialCountryBlocked:    "Requests from this country are blocked for this API key",
+	model.AccessDenialCountryUnresolved: "Request country could not be determined for this API key",
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newNetworkPolicyRouter(t *testing.T, policy config.NetworkPolicyConfig, apiKey *model.APIKey) *gin.Engine {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{Redis: config.RedisConfig{Dns: mr.Addr()}, NetworkPolicy: policy})
+	mockDS := new(mocks.MockDataSource)
+	mockDS.On("RecordAPIKeyAccessDenial", mock.Anything, mock.Anything).Return(nil).Maybe()
+	b, err := blnk.NewBlnk(mockDS)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	auth := NewAuthMiddleware(b)
+	router.GET("/balances", func(c *gin.Context) {
+		if auth.enforceNetworkPolicy(c, apiKey) {
+			c.Status(http.StatusOK)
+		}
+	})
+	return router
+}
+
+func serveFrom(router *gin.Engine, remoteAddr string, headers map[string]string) int {
+	req := httptest.NewRequest(http.MethodGet, "/balances", nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestNetworkPolicy_IgnoresForgedForwardedFor(t *testing.T) {
+	apiKey := &model.APIKey{APIKeyID: "api_key_1", NetworkPolicy: model.NetworkPolicy{AllowedCIDRs: []string{"10.1.0.0/16"}}}
+	router := newNetworkPolicyRouter(t, config.NetworkPolicyConfig{}, apiKey)
+
+	assert.Equal(t, http.StatusForbidden, serveFrom(router, "203.0.113.7:4000", map[string]string{"X-Forwarded-For": "10.1.2.3"}))
+	assert.Equal(t, http.StatusOK, serveFrom(router, "10.1.2.3:4000", nil))
+}
+
+func TestNetworkPolicy_FollowsTrustedProxies(t *testing.T) {
+	apiKey := &model.APIKey{APIKeyID: "api_key_1", NetworkPolicy: model.NetworkPolicy{AllowedCIDRs: []string{"10.1.0.0/16"}}}
+	router := newNetworkPolicyRouter(t, config.NetworkPolicyConfig{TrustedProxies: []string{"192.168.0.0/24"}}, apiKey)
+
+	assert.Equal(t, http.StatusOK, serveFrom(router, "192.168.0.5:4000", map[string]string{"X-Forwarded-For": "10.1.2.3"}))
+	// The client prepended an allowed address; the proxy appended the real one
+	assert.Equal(t, http.StatusForbidden, serveFrom(router, "192.168.0.5:4000", map[string]string{"X-Forwarded-For": "10.1.2.3, 203.0.113.7"}))
+}
+
+func TestNetworkPolicy_CountryHeaderOnlyFromTrustedProxies(t *testing.T) {
+	apiKey := &model.APIKey{APIKeyID: "api_key_1", NetworkPolicy: model.NetworkPolicy{BlockedCountries: []string{"KP"}}}
+	policy := config.NetworkPolicyConfig{CountryHeader: "CF-IPCountry", TrustedProxies: []string{"192.168.0.5"}}
+	router := newNetworkPolicyRouter(t, policy, apiKey)
+
+	assert.Equal(t, http.StatusOK, serveFrom(router, "192.168.0.5:4000", map[string]string{"CF-IPCountry": "ng"}))
+	assert.Equal(t, http.StatusForbidden, serveFrom(router, "192.168.0.5:4000", map[string]string{"CF-IPCountry": "KP"}))
+	// A direct client cannot vouch for its own country
+	assert.Equal(t, http.StatusForbidden, serveFrom(router, "203.0.113.7:4000", map[string]string{"CF-IPCountry": "NG"}))
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/servicetoken"
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestLogHeader carries the ID of the request log entry of a response, so that integrators can look
+// the request up.
+const RequestLogHeader = "X-Blnk-Request-Log-Id"
+
+const redactedValue = "[REDACTED]"
+
+// credentialFields are redacted from every logged body and query, whatever the configuration.
+var credentialFields = []string{
+	"password", "secret", "client_secret", "secret_key", "key", "api_key", "token", "access_token",
+	"refresh_token", "authorization", "card_number", "cvv", "pin",
+}
+
+// requestLogCapture records up to limit bytes of a request or response body.
+type requestLogCapture struct {
+	body      bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (c *requestLogCapture) capture(data []byte) {
+	remaining := c.limit - c.body.Len()
+	if len(data) > remaining {
+		data = data[:max(remaining, 0)]
+		c.truncated = true
+	}
+	c.body.Write(data)
+}
+
+// requestLogBody records the request body as the handlers read it.
+type requestLogBody struct {
+	io.ReadCloser
+	requestLogCapture
+}
+
+func (b *requestLogBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.capture(p[:n])
+	return n, err
+}
+
+// requestLogWriter records the response body as it is written.
+type requestLogWriter struct {
+	gin.ResponseWriter
+	requestLogCapture
+}
+
+func (w *requestLogWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *requestLogWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// RequestLogging returns a middleware that records API requests in the request log when it is enabled. It
+// must run before the other middleware so that requests are recorded as they were sent, including those
+// rejected by authentication. The ID of the entry is returned in the X-Blnk-Request-Log-Id header.
+//
+// Parameters:
+// - b: The Blnk service that stores the request log.
+//
+// Returns:
+// - gin.HandlerFunc: A middleware function that records requests.
+func RequestLogging(b *blnk.Blnk) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf, err := config.Fetch()
+		path := c.Request.URL.Path
+		if err != nil || !conf.RequestLog.Enabled || path == "/" || path == "/health" || path == "/readyz" ||
+			strings.HasPrefix(stripVersionPrefix(path), "/request-logs") {
+			c.Next()
+			return
+		}
+
+		entry := &model.RequestLog{
+			LogID:     model.GenerateUUIDWithSuffix("req"),
+			Method:    c.Request.Method,
+			Path:      path,
+			IPAddress: c.ClientIP(),
+			CreatedAt: time.Now().UTC(),
+		}
+		c.Header(RequestLogHeader, entry.LogID)
+
+		limit := conf.RequestLog.MaxBodyBytes
+		var body *requestLogBody
+		if c.Request.Body != nil {
+			body = &requestLogBody{ReadCloser: c.Request.Body, requestLogCapture: requestLogCapture{limit: limit}}
+			c.Request.Body = body
+		}
+		writer := &requestLogWriter{ResponseWriter: c.Writer, requestLogCapture: requestLogCapture{limit: limit}}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+		entry.StatusCode = writer.Status()
+		entry.LatencyMs = time.Since(entry.CreatedAt).Milliseconds()
+		entry.KeyID = requestKeyID(c)
+		entry.OwnerID = tenant.FromContext(c.Request.Context())
+
+		redactor := requestLogRedactorFor(conf)
+		entry.Query = redactor.redactQuery(c.Request.URL.RawQuery)
+		if body != nil {
+			entry.RequestBody = redactor.redactBody(body.body.Bytes(), c.ContentType())
+			entry.RequestTruncated = body.truncated
+		}
+		entry.ResponseBody = redactor.redactBody(writer.body.Bytes(), writer.Header().Get("Content-Type"))
+		entry.ResponseTruncated = writer.truncated
+
+		go func() {
+			if err := b.RecordRequestLog(context.Background(), entry); err != nil {
+				logrus.Errorf("failed to record request log: %v", err)
+			}
+		}()
+	}
+}
+
+// requestKeyID returns the ID of the credential a request was authenticated with.
+func requestKeyID(c *gin.Context) string {
+	if c.GetBool("isMasterKey") {
+		return "master"
+	}
+	if apiKey, ok := c.Get("apiKey"); ok {
+		if key, ok := apiKey.(*model.APIKey); ok {
+			return key.APIKeyID
+		}
+	}
+	if claims, ok := c.Get("serviceAccount"); ok {
+		if token, ok := claims.(*servicetoken.Claims); ok {
+			return token.ServiceAccountID
+		}
+	}
+	return ""
+}
+
+// requestLogRedactor replaces the values of sensitive fields in logged bodies and queries.
+type requestLogRedactor struct {
+	fields map[string]bool
+	// jsonField matches a sensitive field and its value in JSON, including a string value cut off by
+	// truncation.
+	jsonField *regexp.Regexp
+}
+
+var (
+	redactorMu     sync.Mutex
+	redactorFields string
+	redactor       *requestLogRedactor
+)
+
+// requestLogRedactorFor returns the redactor of the configured fields, reusing the last one while the
+// configuration is unchanged.
+func requestLogRedactorFor(conf *config.Configuration) *requestLogRedactor {
+	fields := append(append(append([]string{}, credentialFields...), conf.RequestLog.RedactFields...), conf.EncryptedMetadata.Keys...)
+	joined := strings.Join(fields, "\x00")
+
+	redactorMu.Lock()
+	defer redactorMu.Unlock()
+	if redactor == nil || redactorFields != joined {
+		redactor = newRequestLogRedactor(fields)
+		redactorFields = joined
+	}
+	return redactor
+}
+
+func newRequestLogRedactor(fields []string) *requestLogRedactor {
+	r := &requestLogRedactor{fields: make(map[string]bool, len(fields))}
+	quoted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" || r.fields[field] {
+			continue
+		}
+		r.fields[field] = true
+		quoted = append(quoted, regexp.QuoteMeta(field))
+	}
+	r.jsonField = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^\s,}\]]+)`)
+	return r
+}
+
+// redactBody redacts a captured body. JSON and form bodies are kept with their sensitive values replaced;
+// other bodies, such as uploaded files, are not kept.
+func (r *requestLogRedactor) redactBody(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	switch {
+	case strings.Contains(contentType, "json"):
+		return r.jsonField.ReplaceAllString(string(body), `$1"`+redactedValue+`"`)
+	case strings.Contains(contentType, "application/x-www-form-urlencoded"):
+		return r.redactQuery(string(body))
+	case strings.HasPrefix(contentType, "text/"):
+		return string(body)
+	}
+	return ""
+}
+
+// redactQuery redacts the values of sensitive parameters in a URL-encoded query.
+func (r *requestLogRedactor) redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return ""
+	}
+	for name := range values {
+		if r.fields[strings.ToLower(name)] {
+			values[name] = []string{redactedValue}
+		}
+	}
+	return values.Encode()
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRequestLogRouter returns a router that logs requests and a channel receiving the recorded entries.
+func setupRequestLogRouter(t *testing.T, requestLog config.RequestLogConfig) (*gin.Engine, chan *model.RequestLog) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis:      config.RedisConfig{Dns: mr.Addr()},
+		RequestLog: requestLog,
+	})
+	mockDS := new(mocks.MockDataSource)
+	b, err := blnk.NewBlnk(mockDS)
+	require.NoError(t, err)
+
+	recorded := make(chan *model.RequestLog, 1)
+	mockDS.On("RecordRequestLog", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		recorded <- args.Get(1).(*model.RequestLog)
+	}).Return(nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestLogging(b))
+	router.Use(func(c *gin.Context) {
+		c.Set("apiKey", &model.APIKey{APIKeyID: "api_key_1"})
+	})
+	router.POST("/transactions", func(c *gin.Context) {
+		var body map[string]interface{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"reference": body["reference"], "access_token": "tok_123"})
+	})
+	router.GET("/request-logs", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	return router, recorded
+}
+
+func waitForRequestLog(t *testing.T, recorded chan *model.RequestLog) *model.RequestLog {
+	select {
+	case entry := <-recorded:
+		return entry
+	case <-time.After(2 * time.Second):
+		t.Fatal("request was not logged")
+		return nil
+	}
+}
+
+func TestRequestLogging_RecordsRedactedRequest(t *testing.T) {
+	router, recorded := setupRequestLogRouter(t, config.RequestLogConfig{
+		Enabled:      true,
+		MaxBodyBytes: 4096,
+		RedactFields: []string{"account_number"},
+	})
+
+	body := `{"reference":"ref_1","password":"hunter2","meta_data":{"Account_Number":"12345678"}}`
+	req := httptest.NewRequest(http.MethodPost, "/transactions?limit=5&api_key=secret", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	entry := waitForRequestLog(t, recorded)
+	assert.Equal(t, w.Header().Get(RequestLogHeader), entry.LogID)
+	assert.Equal(t, http.MethodPost, entry.Method)
+	assert.Equal(t, "/transactions", entry.Path)
+	assert.Equal(t, "api_key=%5BREDACTED%5D&limit=5", entry.Query)
+	assert.Equal(t, "api_key_1", entry.KeyID)
+	assert.Equal(t, http.StatusCreated, entry.StatusCode)
+	assert.Equal(t, `{"reference":"ref_1","password":"[REDACTED]","meta_data":{"Account_Number":"[REDACTED]"}}`, entry.RequestBody)
+	assert.Contains(t, entry.ResponseBody, `"access_token":"[REDACTED]"`)
+	assert.Contains(t, entry.ResponseBody, `"reference":"ref_1"`)
+	assert.False(t, entry.RequestTruncated)
+}
+
+func TestRequestLogging_TruncatesBodies(t *testing.T) {
+	router, recorded := setupRequestLogRouter(t, config.RequestLogConfig{Enabled: true, MaxBodyBytes: 40})
+
+	body := `{"reference":"ref_1","password":"a-very-long-password-that-gets-cut"}`
+	req := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	entry := waitForRequestLog(t, recorded)
+	assert.True(t, entry.RequestTruncated)
+	assert.Equal(t, `{"reference":"ref_1","password":"[REDACTED]"`, entry.RequestBody)
+	assert.True(t, entry.ResponseTruncated)
+	assert.Equal(t, `{"access_token":"[REDACTED]","reference":"r`, entry.ResponseBody)
+}
+
+func TestRequestLogging_Skipped(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  config.RequestLogConfig
+		request *http.Request
+	}{
+		{
+			name:    "disabled",
+			config:  config.RequestLogConfig{},
+			request: httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(`{}`)),
+		},
+		{
+			name:    "request log search",
+			config:  config.RequestLogConfig{Enabled: true},
+			request: httptest.NewRequest(http.MethodGet, "/v2/request-logs", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, recorded := setupRequestLogRouter(t, tt.config)
+			tt.request.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, tt.request)
+
+			assert.Empty(t, w.Header().Get(RequestLogHeader))
+			select {
+			case <-recorded:
+				t.Fatal("request was logged")
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
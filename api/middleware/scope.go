@@ -33,6 +33,10 @@ const (
 	ActionDelete Action = "delete"
 	ActionAll    Action = "*"
 
+	// ActionOverride lets a caller bypass a control of a resource. Like encrypted metadata reads, it is
+	// never implied by wildcards and must be granted explicitly, e.g. as minimum-balances:override.
+	ActionOverride Action = "override"
+
 	// Resources
 	ResourceLedgers         Resource = "ledgers"
 	ResourceBalances        Resource = "balances"
@@ -46,7 +50,63 @@ const (
 	ResourceReconciliation  Resource = "reconciliation"
 	ResourceMetadata        Resource = "metadata"
 	ResourceBackup          Resource = "backup"
+	ResourceStatements      Resource = "statements"
+	ResourceFeatureFlags    Resource = "feature-flags"
+	ResourceUsage           Resource = "usage"
+	ResourceServiceAccounts Resource = "service-accounts"
+	ResourceNetting         Resource = "netting"
 	ResourceAll             Resource = "*"
+
+	// ResourceEscheatment covers dormant balances and escheatment batches.
+	ResourceEscheatment Resource = "escheatment"
+
+	// ResourceRequestLogs covers the searchable history of API requests, including their bodies.
+	ResourceRequestLogs Resource = "request-logs"
+
+	// ResourceWarehouse covers the state of the warehouse sync and running it.
+	ResourceWarehouse Resource = "warehouse"
+
+	// ResourceComplianceExport covers the state of the compliance export, running it and verifying the bucket.
+	ResourceComplianceExport Resource = "compliance-export"
+
+	// ResourceCardAuthorizations covers the card authorization lifecycle endpoints and balance authorizations.
+	ResourceCardAuthorizations Resource = "card-authorizations"
+
+	// ResourceCurrencies covers the currency registry and amount formatting.
+	ResourceCurrencies Resource = "currencies"
+
+	// ResourceCalendars covers the business calendars scheduled transactions and end-of-day processing keep to.
+	ResourceCalendars Resource = "calendars"
+
+	// ResourceCalculator covers quoting fees, interest and conversions without posting anything.
+	ResourceCalculator Resource = "calculate"
+
+	// ResourceMinimumBalances covers the minimum balances debits cannot breach. Its override action lets
+	// transactions, such as administrative sweeps, breach them.
+	ResourceMinimumBalances Resource = "minimum-balances"
+
+	// ResourceChallenges covers the strong customer authentication challenges transactions are held back by.
+	ResourceChallenges Resource = "challenges"
+
+	// ResourceReports covers saved report definitions and their runs, which read across ledgers.
+	ResourceReports Resource = "reports"
+
+	// ResourceSessions covers units of work that create resources and record transactions together. Staging an
+	// operation in a session also requires write access to the resource it creates.
+	ResourceSessions Resource = "sessions"
+
+	// ResourceEOD covers end-of-day processing, whose runs settle, snapshot and report across ledgers.
+	ResourceEOD Resource = "eod"
+
+	// ResourceMaintenance covers the maintenance mode that makes Blnk, or a ledger, read-only.
+	ResourceMaintenance Resource = "maintenance"
+
+	// ResourceDiagnostics covers the schema advisor, which reads database statistics across every ledger.
+	ResourceDiagnostics Resource = "diagnostics"
+
+	// ResourceEncryptedMetadata allows reading encrypted metadata values. It is never implied by the
+	// wildcard resource and must be granted explicitly as encrypted-metadata:read.
+	ResourceEncryptedMetadata Resource = "encrypted-metadata"
 )
 
 // methodToAction maps HTTP methods to actions
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package middleware
+
+import (
+	"context"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// UsageMetering returns a middleware that counts API calls per tenant for usage metering.
+// It must run after authentication so the tenant is known; health checks and rejected requests are not counted.
+//
+// Parameters:
+// - b: The Blnk service that records usage.
+//
+// Returns:
+// - gin.HandlerFunc: A middleware function that meters requests.
+func UsageMetering(b *blnk.Blnk) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.IsAborted() || c.Request.URL.Path == "/" || c.Request.URL.Path == "/health" || c.Request.URL.Path == "/readyz" {
+			return
+		}
+
+		ctx := tenant.WithTenant(context.Background(), tenant.FromContext(c.Request.Context()))
+		go func() {
+			if err := b.RecordAPICall(ctx); err != nil {
+				logrus.Errorf("failed to record API usage: %v", err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// VersionHeader names the API version that served a response.
+	VersionHeader = "Blnk-Api-Version"
+
+	versionContextKey = "apiVersion"
+)
+
+// VersionAdapter converts between the JSON shapes of an API version and the shapes the handlers use.
+// Either function may be nil when the version does not change that direction.
+type VersionAdapter struct {
+	// Request rewrites a decoded request body into the handlers' shape.
+	Request func(document interface{}) interface{}
+	// Response rewrites a decoded response body into the version's shape.
+	Response func(document interface{}) interface{}
+}
+
+// APIVersion is a version of the API served under the /<Name> path prefix.
+type APIVersion struct {
+	Name    string
+	Adapter *VersionAdapter
+}
+
+// versionResponseWriter holds back the response body so it can be adapted to the requested version.
+type versionResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *versionResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *versionResponseWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// APIVersioning returns a middleware that resolves the API version of a request from its path prefix,
+// rejects disabled versions with 410 Gone, marks deprecated versions with Deprecation and Sunset headers
+// and applies the version's request and response adapters. Unversioned paths are served as defaultVersion.
+// It must run before the other middleware so they see requests in the handlers' shape.
+//
+// Parameters:
+// - versions: The versions served under a path prefix.
+// - defaultVersion: The version unversioned paths are served as.
+//
+// Returns:
+// - gin.HandlerFunc: A middleware function that applies API versioning.
+func APIVersioning(versions []APIVersion, defaultVersion string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if path == "/" || path == "/health" || path == "/readyz" {
+			c.Next()
+			return
+		}
+
+		version := APIVersion{Name: defaultVersion}
+		first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+		for _, v := range versions {
+			if v.Name == first {
+				version = v
+				break
+			}
+		}
+
+		conf, err := config.Fetch()
+		if err == nil {
+			for _, disabled := range conf.APIVersions.Disabled {
+				if disabled == version.Name {
+					c.AbortWithStatusJSON(http.StatusGone, gin.H{"error": "API version " + version.Name + " is no longer available"})
+					return
+				}
+			}
+			setDeprecationHeaders(c, conf.APIVersions, version.Name)
+		}
+
+		c.Set(versionContextKey, version.Name)
+		c.Header(VersionHeader, version.Name)
+
+		if version.Adapter == nil {
+			c.Next()
+			return
+		}
+
+		if version.Adapter.Request != nil {
+			if err := adaptRequestBody(c, version.Adapter.Request); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if version.Adapter.Response == nil {
+			c.Next()
+			return
+		}
+
+		writer := &versionResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		if strings.Contains(c.Writer.Header().Get("Content-Type"), "application/json") {
+			body = adaptJSON(body, version.Adapter.Response)
+		}
+		if _, err := c.Writer.Write(body); err != nil {
+			logrus.Error("Failed to write response:", err)
+		}
+	}
+}
+
+// RequestAPIVersion returns the API version a request is served as.
+//
+// Parameters:
+// - c: The Gin context of the request.
+//
+// Returns:
+// - string: The version name, or an empty string outside APIVersioning.
+func RequestAPIVersion(c *gin.Context) string {
+	return c.GetString(versionContextKey)
+}
+
+// stripVersionPrefix removes a leading /v<number> segment from a path.
+func stripVersionPrefix(path string) string {
+	trimmed := strings.TrimPrefix(path, "/")
+	first, rest, _ := strings.Cut(trimmed, "/")
+	if len(first) < 2 || first[0] != 'v' || strings.Trim(first[1:], "0123456789") != "" {
+		return path
+	}
+	return "/" + rest
+}
+
+// setDeprecationHeaders marks responses of a deprecated version. The Sunset date may be given as a date
+// or an RFC 3339 timestamp.
+func setDeprecationHeaders(c *gin.Context, conf config.APIVersionConfig, version string) {
+	deprecated := false
+	for _, v := range conf.Deprecated {
+		if v == version {
+			deprecated = true
+			break
+		}
+	}
+	if !deprecated {
+		return
+	}
+
+	c.Header("Deprecation", "true")
+	sunset, ok := conf.Sunset[version]
+	if !ok {
+		return
+	}
+	at, err := time.Parse(time.RFC3339, sunset)
+	if err != nil {
+		at, err = time.Parse("2006-01-02", sunset)
+	}
+	if err != nil {
+		logrus.Warnf("invalid sunset date %q for API version %s", sunset, version)
+		return
+	}
+	c.Header("Sunset", at.UTC().Format(http.TimeFormat))
+}
+
+// adaptRequestBody rewrites a JSON request body with the version's request adapter.
+func adaptRequestBody(c *gin.Context, adapt func(interface{}) interface{}) error {
+	if c.Request.Body == nil || (c.Request.Method != "POST" && c.Request.Method != "PUT" && c.Request.Method != "PATCH") {
+		return nil
+	}
+
+	bodyBytes, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	_ = c.Request.Body.Close()
+
+	adapted := adaptJSON(bodyBytes, adapt)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(adapted))
+	c.Request.ContentLength = int64(len(adapted))
+	return nil
+}
+
+// adaptJSON applies an adapter to a JSON document. Bodies that are not JSON are returned unchanged.
+func adaptJSON(body []byte, adapt func(interface{}) interface{}) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return body
+	}
+
+	adapted, err := json.Marshal(adapt(document))
+	if err != nil {
+		logrus.Error("Failed to encode adapted body:", err)
+		return body
+	}
+	return adapted
+}
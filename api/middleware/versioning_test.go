@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// upperKeys renames the "name" key of a JSON object to "NAME".
+func upperKeys(document interface{}) interface{} {
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		return document
+	}
+	if value, ok := object["name"]; ok {
+		delete(object, "name")
+		object["NAME"] = value
+	}
+	return object
+}
+
+// lowerKeys renames the "NAME" key of a JSON object to "name".
+func lowerKeys(document interface{}) interface{} {
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		return document
+	}
+	if value, ok := object["NAME"]; ok {
+		delete(object, "NAME")
+		object["name"] = value
+	}
+	return object
+}
+
+func setupVersionedRouter(apiVersions config.APIVersionConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	config.ConfigStore.Store(&config.Configuration{APIVersions: apiVersions})
+
+	versions := []APIVersion{
+		{Name: "v1"},
+		{Name: "v2", Adapter: &VersionAdapter{Request: lowerKeys, Response: upperKeys}},
+	}
+	router := gin.New()
+	router.Use(APIVersioning(versions, "v1"))
+
+	echo := func(c *gin.Context) {
+		var body map[string]interface{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		body["version"] = RequestAPIVersion(c)
+		c.JSON(http.StatusOK, body)
+	}
+	router.POST("/ledgers", echo)
+	router.POST("/v1/ledgers", echo)
+	router.POST("/v2/ledgers", echo)
+	return router
+}
+
+func TestAPIVersioning_ResolvesVersion(t *testing.T) {
+	router := setupVersionedRouter(config.APIVersionConfig{})
+
+	tests := []struct {
+		path    string
+		version string
+	}{
+		{"/ledgers", "v1"},
+		{"/v1/ledgers", "v1"},
+		{"/v2/ledgers", "v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", tt.path, strings.NewReader(`{"name":"a"}`))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.version, w.Header().Get(VersionHeader))
+			assert.Contains(t, w.Body.String(), `"version":"`+tt.version+`"`)
+			assert.Empty(t, w.Header().Get("Deprecation"))
+		})
+	}
+}
+
+func TestAPIVersioning_AppliesAdapters(t *testing.T) {
+	router := setupVersionedRouter(config.APIVersionConfig{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v2/ledgers", strings.NewReader(`{"NAME":"a","amount":10.50}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"NAME":"a","amount":10.50,"version":"v2"}`, w.Body.String())
+
+	// v1 requests pass through unchanged
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/v1/ledgers", strings.NewReader(`{"NAME":"a"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"NAME":"a","version":"v1"}`, w.Body.String())
+}
+
+func TestAPIVersioning_DisabledVersion(t *testing.T) {
+	router := setupVersionedRouter(config.APIVersionConfig{Disabled: []string{"v1"}})
+
+	for _, path := range []string{"/ledgers", "/v1/ledgers"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(`{"name":"a"}`))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusGone, w.Code, path)
+		assert.Contains(t, w.Body.String(), "API version v1 is no longer available")
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v2/ledgers", strings.NewReader(`{"NAME":"a"}`))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAPIVersioning_DeprecationHeaders(t *testing.T) {
+	router := setupVersionedRouter(config.APIVersionConfig{
+		Deprecated: []string{"v1"},
+		Sunset:     map[string]string{"v1": "2027-01-31"},
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v1/ledgers", strings.NewReader(`{"name":"a"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Sun, 31 Jan 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/v2/ledgers", strings.NewReader(`{"NAME":"a"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Sunset"))
+}
+
+func TestAPIVersioning_NonJSONResponse(t *testing.T) {
+	router := setupVersionedRouter(config.APIVersionConfig{})
+	router.GET("/v2/statement", func(c *gin.Context) {
+		c.String(http.StatusOK, `{"name":"a"}`)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v2/statement", nil)
+	router.ServeHTTP(w, req)
+
+	body, _ := io.ReadAll(w.Body)
+	assert.Equal(t, `{"name":"a"}`, string(body))
+}
+
+func TestStripVersionPrefix(t *testing.T) {
+	assert.Equal(t, "/ledgers", stripVersionPrefix("/v2/ledgers"))
+	assert.Equal(t, "/ledgers/ldg_1", stripVersionPrefix("/v10/ledgers/ldg_1"))
+	assert.Equal(t, "/ledgers", stripVersionPrefix("/ledgers"))
+	assert.Equal(t, "/vault/items", stripVersionPrefix("/vault/items"))
+	assert.Equal(t, ResourceLedgers, getResourceFromPath("/v2/ledgers"))
+	assert.Equal(t, ResourceTransactions, getResourceFromPath("/v1/refund-transaction/txn_1"))
+}
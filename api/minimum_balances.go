@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/blnkfinance/blnk/api/middleware"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// SetMinimumBalance sets the minimum balance of a balance, or of every balance of a ledger in a currency.
+// Debits that would take a balance below its minimum are rejected whether or not they allow an overdraft.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body is invalid, or the balance or ledger does not exist.
+// - 201 Created: If the minimum balance is set.
+func (a Api) SetMinimumBalance(c *gin.Context) {
+	var req model.MinimumBalance
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	minimum, err := a.blnk.SetMinimumBalance(c.Request.Context(), req)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, minimum)
+}
+
+// ListMinimumBalances lists a page of minimum balances, newest first.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the cursor is invalid.
+// - 500 Internal Server Error: If the minimum balances cannot be retrieved.
+// - 200 OK: If the minimum balances are successfully retrieved.
+func (a Api) ListMinimumBalances(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	page, err := queryPage(c, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	minimums, err := a.blnk.ListMinimumBalances(c.Request.Context(), page)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	next := model.NextPageCursor(minimums, limit, (*model.MinimumBalance).PageCursor)
+	a.respondList(c, minimums, listPage{limit: limit, offset: page.Offset, fetched: len(minimums), next: next})
+}
+
+// GetMinimumBalance retrieves a minimum balance by ID.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the minimum balance cannot be found.
+// - 200 OK: If the minimum balance is successfully retrieved.
+func (a Api) GetMinimumBalance(c *gin.Context) {
+	minimum, err := a.blnk.GetMinimumBalance(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondStatementError(c, err, "Minimum balance not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, minimum)
+}
+
+// DeleteMinimumBalance removes a minimum balance.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the minimum balance cannot be found.
+// - 204 No Content: If the minimum balance is removed.
+func (a Api) DeleteMinimumBalance(c *gin.Context) {
+	if err := a.blnk.DeleteMinimumBalance(c.Request.Context(), c.Param("id")); err != nil {
+		respondStatementError(c, err, "Minimum balance not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// denyMinimumBalanceOverride responds with a 403 when a transaction asks to override minimum balances but
+// the caller lacks the minimum-balances:override scope.
+//
+// Returns:
+// - bool: true if a response was written and the handler should stop.
+func denyMinimumBalanceOverride(c *gin.Context, override bool) bool {
+	if !override || middleware.CanOverrideMinimumBalance(c) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "overriding minimum balances requires the minimum-balances:override scope"})
+	return true
+}
+
+// respondMinimumBalanceError writes the response for a transaction that would breach a minimum balance,
+// with its reason code so that clients can tell it apart from insufficient funds.
+//
+// Returns:
+// - bool: true if the error was a minimum balance breach and a response was written.
+func respondMinimumBalanceError(c *gin.Context, err error) bool {
+	var breach *model.MinimumBalanceError
+	if !errors.As(err, &breach) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": model.ReasonMinimumBalance})
+	return true
+}
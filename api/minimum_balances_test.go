@@ -0,0 +1,69 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBulkTransactions_DeniesMinimumBalanceOverride(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis:  config.RedisConfig{Dns: mr.Addr()},
+		Queue:  config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+		Server: config.ServerConfig{Secure: true},
+	})
+
+	// No datasource calls are expected: the batch must be refused before anything is queued
+	mockDS := new(mocks.MockDataSource)
+	b, err := blnk.NewBlnk(mockDS)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("apiKey", &model.APIKey{APIKeyID: "api_key_1", Scopes: []string{"transactions:write"}})
+	})
+	a := Api{blnk: b, router: router}
+	router.POST("/transactions/bulk", a.CreateBulkTransactions)
+
+	body := `{"transactions": [
+		{"amount": 10, "currency": "USD", "source": "@World", "destination": "bln_1", "reference": "ref_1"},
+		{"amount": 10, "currency": "USD", "source": "bln_1", "destination": "@Fees", "reference": "ref_2", "override_minimum_balance": true}
+	]}`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/transactions/bulk", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "minimum-balances:override")
+	mockDS.AssertExpectations(t)
+}
@@ -8,3 +8,35 @@ type CreateAPIKeyRequest struct {
 	Owner     string    `json:"owner" binding:"required"`
 	ExpiresAt time.Time `json:"expires_at" binding:"required"`
 }
+
+// RotateSecretRequest is the request body for rotating an API key or webhook signing secret.
+// OverlapSeconds is how long the old credential keeps working; the configured default is used when zero.
+type RotateSecretRequest struct {
+	OverlapSeconds int `json:"overlap_seconds"`
+}
+
+type CreateServiceAccountRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+	Owner  string   `json:"owner" binding:"required"`
+}
+
+// IssueServiceTokenRequest exchanges service account credentials for a short-lived token.
+// Scopes narrows the token to a subset of the account's scopes; all of them are granted when empty.
+type IssueServiceTokenRequest struct {
+	ClientID     string   `json:"client_id" binding:"required"`
+	ClientSecret string   `json:"client_secret" binding:"required"`
+	Scopes       []string `json:"scopes"`
+}
+
+// UpdateLedgerScopeRequest restricts an API key to ledger IDs and balance ID prefixes.
+type UpdateLedgerScopeRequest struct {
+	LedgerIDs       []string `json:"ledger_ids"`
+	BalancePrefixes []string `json:"balance_prefixes"`
+}
+
+// UpdateFieldCompatibilityRequest sets the names renamed JSON fields are emitted under for an API key: legacy,
+// both or current. An empty mode uses the deployment's default.
+type UpdateFieldCompatibilityRequest struct {
+	Mode string `json:"mode"`
+}
@@ -42,3 +42,8 @@ type MonitorCondition struct {
 type UpdateBalanceIdentity struct {
 	IdentityId string `json:"identity_id" binding:"required"`
 }
+
+// UpdateBalanceAliasRequest represents the payload required to point a balance alias at another balance.
+type UpdateBalanceAliasRequest struct {
+	BalanceID string `json:"balance_id" binding:"required"`
+}
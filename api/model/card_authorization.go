@@ -0,0 +1,44 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+// CardAuthorizationRequest is the request body for authorizing a card payment. Amount is in major units;
+// PreciseAmount takes precedence when set.
+type CardAuthorizationRequest struct {
+	Scheme        string                 `json:"scheme" binding:"required"`
+	Reference     string                 `json:"reference" binding:"required"`
+	Source        string                 `json:"source" binding:"required"`
+	Destination   string                 `json:"destination" binding:"required"`
+	Currency      string                 `json:"currency" binding:"required"`
+	Amount        float64                `json:"amount"`
+	PreciseAmount *big.Int               `json:"precise_amount,omitempty"`
+	Precision     float64                `json:"precision"`
+	ExpiresAt     time.Time              `json:"expires_at,omitempty"`
+	MetaData      map[string]interface{} `json:"meta_data"`
+}
+
+// CardAuthorizationAmountRequest is the request body for incremental authorizations, reversals and clearing.
+// Amount is in major units; PreciseAmount takes precedence when set. A reversal without an amount releases
+// everything still held.
+type CardAuthorizationAmountRequest struct {
+	Amount        float64  `json:"amount"`
+	PreciseAmount *big.Int `json:"precise_amount,omitempty"`
+}
+
+// BalanceAuthorizationRequest is the request body for authorizing a payment against a balance. Amount is in
+// major units; PreciseAmount takes precedence when set. The hold expires at ExpiresAt when set.
+type BalanceAuthorizationRequest struct {
+	Reference     string                 `json:"reference" binding:"required"`
+	Source        string                 `json:"source" binding:"required"`
+	Destination   string                 `json:"destination" binding:"required"`
+	Currency      string                 `json:"currency" binding:"required"`
+	Amount        float64                `json:"amount"`
+	PreciseAmount *big.Int               `json:"precise_amount,omitempty"`
+	Precision     float64                `json:"precision"`
+	ExpiresAt     time.Time              `json:"expires_at,omitempty"`
+	Description   string                 `json:"description"`
+	MetaData      map[string]interface{} `json:"meta_data"`
+}
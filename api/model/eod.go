@@ -0,0 +1,7 @@
+package model
+
+// RunEODRequest starts the end-of-day processing of a business date. Its body is optional; without a business
+// date, the latest date whose cutoff passed is run.
+type RunEODRequest struct {
+	BusinessDate string `json:"business_date"`
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+// ExternalAccountRequest adds a bank account or wallet to the external account directory. Bank accounts give an
+// IBAN or an account number, with the routing number and BIC their payments need; wallets give their network
+// and address.
+type ExternalAccountRequest struct {
+	IdentityID    string                 `json:"identity_id"`
+	Type          string                 `json:"type" binding:"required"`
+	HolderName    string                 `json:"holder_name" binding:"required"`
+	Currency      string                 `json:"currency" binding:"required"`
+	Country       string                 `json:"country"`
+	BankName      string                 `json:"bank_name"`
+	IBAN          string                 `json:"iban"`
+	AccountNumber string                 `json:"account_number"`
+	RoutingNumber string                 `json:"routing_number"`
+	BIC           string                 `json:"bic"`
+	Network       string                 `json:"network"`
+	WalletAddress string                 `json:"wallet_address"`
+	MetaData      map[string]interface{} `json:"meta_data"`
+}
+
+// ReviewExternalAccountRequest records the review of an external account, which ends verified or rejected.
+type ReviewExternalAccountRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
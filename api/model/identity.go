@@ -15,7 +15,11 @@ limitations under the License.
 */
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+)
 
 type CreateIdentity struct {
 	IdentityType     string                 `json:"identity_type"`
@@ -45,3 +49,88 @@ type TokenizeRequest struct {
 type DetokenizeRequest struct {
 	Fields []string `json:"fields" binding:"required"`
 }
+
+// UpdateIdentityVerificationRequest moves an identity's KYC verification to a new status.
+type UpdateIdentityVerificationRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// UpdateIdentityStatusRequest moves an identity to a new lifecycle status: active, blocked or closed.
+type UpdateIdentityStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// ReviewIdentityDocumentRequest records the review of an identity document, which ends verified or rejected.
+type ReviewIdentityDocumentRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// MergeIdentityRequest merges a duplicate identity into the identity of the request's path.
+type MergeIdentityRequest struct {
+	MergedIdentityID string `json:"merged_identity_id" binding:"required"`
+	Reason           string `json:"reason"`
+}
+
+// AnonymizeIdentityRequest erases the personal fields of the identity of the request's path. Its body is optional.
+type AnonymizeIdentityRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CreateIdentityRelationshipRequest relates the identity of the request's path to another identity: the identity
+// is the Type of RelatedIdentityID.
+type CreateIdentityRelationshipRequest struct {
+	RelatedIdentityID string                 `json:"related_identity_id" binding:"required"`
+	Type              string                 `json:"type" binding:"required"`
+	Role              string                 `json:"role"`
+	MetaData          map[string]interface{} `json:"meta_data"`
+}
+
+// UpdateIdentityRelationshipRequest replaces the role and metadata of an identity relationship. Version is the
+// version of the relationship the update was made against, unless sent in an If-Match header.
+type UpdateIdentityRelationshipRequest struct {
+	Role     string                 `json:"role"`
+	MetaData map[string]interface{} `json:"meta_data"`
+	Version  int                    `json:"version"`
+}
+
+// IdentityAddressRequest is an address of the identity of the request's path, as it is created or replaced.
+// Replacements name the version of the address they were made against, unless sent in an If-Match header.
+type IdentityAddressRequest struct {
+	Type     string                 `json:"type" binding:"required"`
+	Street   string                 `json:"street"`
+	City     string                 `json:"city"`
+	State    string                 `json:"state"`
+	PostCode string                 `json:"post_code"`
+	Country  string                 `json:"country" binding:"required"`
+	MetaData map[string]interface{} `json:"meta_data"`
+	Version  int                    `json:"version"`
+}
+
+// IdentityTagsRequest lists tags to add to the identity of the request's path.
+type IdentityTagsRequest struct {
+	Tags []string `json:"tags" binding:"required"`
+}
+
+// ContactVerificationRequest requests a code to verify the email address or phone number of the identity of the
+// request's path.
+type ContactVerificationRequest struct {
+	Channel string `json:"channel" binding:"required"`
+}
+
+// ConfirmContactVerificationRequest confirms the email address or phone number of the identity of the request's
+// path with the code it received.
+type ConfirmContactVerificationRequest struct {
+	Channel string `json:"channel" binding:"required"`
+	Code    string `json:"code" binding:"required"`
+}
+
+// IdentityExportRequest starts an export of the identities matching Filter to a CSV or Parquet file, in the
+// export directory or the S3 bucket.
+type IdentityExportRequest struct {
+	Format      string               `json:"format" binding:"required"`
+	Destination string               `json:"destination"`
+	Filter      model.IdentityFilter `json:"filter"`
+}
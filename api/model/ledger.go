@@ -15,7 +15,20 @@ limitations under the License.
 */
 package model
 
+import "github.com/blnkfinance/blnk/model"
+
 type CreateLedger struct {
 	Name     string                 `json:"name"`
 	MetaData map[string]interface{} `json:"meta_data"`
 }
+
+// CloneLedger names the copy of a ledger.
+type CloneLedger struct {
+	Name string `json:"name"`
+}
+
+// ImportLedgerTemplate creates a ledger from a template exported by another environment.
+type ImportLedgerTemplate struct {
+	Name     string                `json:"name"`
+	Template *model.LedgerTemplate `json:"template" binding:"required"`
+}
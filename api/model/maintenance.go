@@ -0,0 +1,9 @@
+package model
+
+// MaintenanceModeRequest enables or disables maintenance mode, globally or for the ledger of the request's path.
+// RetryAfter is how many seconds rejected clients should wait, a minute when omitted.
+type MaintenanceModeRequest struct {
+	Enabled    bool   `json:"enabled"`
+	Reason     string `json:"reason"`
+	RetryAfter int    `json:"retry_after"`
+}
@@ -145,6 +145,20 @@ func (t *RecordTransaction) ValidateRecordTransaction() error {
 			return validateDateFormat("2006-01-02T15:04:05Z07:00", dateStr)
 		})),
 		),
+		validation.Field(&t.RetryPolicy, validation.When(t.RetryPolicy != nil, validation.By(func(value interface{}) error {
+			if t.RetryPolicy.Attempts != 0 || t.RetryPolicy.FirstFailedAt != nil || t.RetryPolicy.ExecutedAmount != nil {
+				return errors.New("retry_policy only accepts max_attempts, window_days and allow_partial")
+			}
+			return t.RetryPolicy.Validate()
+		})),
+		),
+		validation.Field(&t.BusinessDayRule, validation.When(t.BusinessDayRule != nil, validation.By(func(value interface{}) error {
+			return t.BusinessDayRule.Validate()
+		})),
+		),
+		validation.Field(&t.RoundingMode, validation.By(func(value interface{}) error {
+			return t.RoundingMode.Validate()
+		})),
 	)
 }
 
@@ -213,5 +227,11 @@ func (t *RecordTransaction) ToTransaction() *model.Transaction {
 
 	}
 
-	return &model.Transaction{Currency: t.Currency, Source: t.Source, Description: t.Description, Reference: t.Reference, ScheduledFor: scheduledFor, Destination: t.Destination, Amount: t.Amount, AllowOverdraft: t.AllowOverDraft, MetaData: t.MetaData, Sources: t.Sources, Destinations: t.Destinations, Inflight: t.Inflight, Precision: t.Precision, InflightExpiryDate: inflightExpiryDate, Rate: t.Rate, SkipQueue: t.SkipQueue, EffectiveDate: t.EffectiveDate, OverdraftLimit: t.OverdraftLimit, PreciseAmount: t.PreciseAmount, Atomic: t.Atomic}
+	// Clients capturing transactions offline may send the time they recorded them as created_at
+	transactionTime := t.TransactionTime
+	if transactionTime == nil {
+		transactionTime = t.CreatedAt
+	}
+
+	return &model.Transaction{Currency: t.Currency, Source: t.Source, Description: t.Description, Reference: t.Reference, GroupID: t.GroupID, ExternalAccountID: t.ExternalAccountID, ScheduledFor: scheduledFor, Destination: t.Destination, Amount: t.Amount, AllowOverdraft: t.AllowOverDraft, MetaData: t.MetaData, Sources: t.Sources, Destinations: t.Destinations, Inflight: t.Inflight, Precision: t.Precision, InflightExpiryDate: inflightExpiryDate, Rate: t.Rate, SkipQueue: t.SkipQueue, EffectiveDate: t.EffectiveDate, TransactionTime: transactionTime, OverdraftLimit: t.OverdraftLimit, PreciseAmount: t.PreciseAmount, Atomic: t.Atomic, OverrideMinimumBalance: t.OverrideMinimumBalance, RetryPolicy: t.RetryPolicy, BusinessDayRule: t.BusinessDayRule, RoundingMode: t.RoundingMode}
 }
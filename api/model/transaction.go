@@ -23,27 +23,35 @@ import (
 )
 
 type RecordTransaction struct {
-	Amount             float64                `json:"amount"`
-	Rate               float64                `json:"rate"`
-	Precision          float64                `json:"precision"`
-	OverdraftLimit     float64                `json:"overdraft_limit"`
-	PreciseAmount      *big.Int               `json:"precise_amount"`
-	AllowOverDraft     bool                   `json:"allow_overdraft"`
-	Inflight           bool                   `json:"inflight"`
-	SkipQueue          bool                   `json:"skip_queue"`
-	Atomic             bool                   `json:"atomic"`
-	Source             string                 `json:"source"`
-	Reference          string                 `json:"reference"`
-	Destination        string                 `json:"destination"`
-	Description        string                 `json:"description"`
-	Currency           string                 `json:"currency"`
-	BalanceId          string                 `json:"balance_id"`
-	ScheduledFor       string                 `json:"scheduled_for"`
-	InflightExpiryDate string                 `json:"inflight_expiry_date,omitempty"`
-	Sources            []model.Distribution   `json:"sources"`
-	Destinations       []model.Distribution   `json:"destinations"`
-	MetaData           map[string]interface{} `json:"meta_data"`
-	EffectiveDate      *time.Time             `json:"effective_date,omitempty"`
+	Amount                 float64                `json:"amount"`
+	Rate                   float64                `json:"rate"`
+	Precision              float64                `json:"precision"`
+	OverdraftLimit         float64                `json:"overdraft_limit"`
+	PreciseAmount          *big.Int               `json:"precise_amount"`
+	AllowOverDraft         bool                   `json:"allow_overdraft"`
+	Inflight               bool                   `json:"inflight"`
+	SkipQueue              bool                   `json:"skip_queue"`
+	Atomic                 bool                   `json:"atomic"`
+	OverrideMinimumBalance bool                   `json:"override_minimum_balance"`
+	Source                 string                 `json:"source"`
+	Reference              string                 `json:"reference"`
+	GroupID                string                 `json:"group_id"`
+	ExternalAccountID      string                 `json:"external_account_id"`
+	Destination            string                 `json:"destination"`
+	Description            string                 `json:"description"`
+	Currency               string                 `json:"currency"`
+	BalanceId              string                 `json:"balance_id"`
+	ScheduledFor           string                 `json:"scheduled_for"`
+	InflightExpiryDate     string                 `json:"inflight_expiry_date,omitempty"`
+	Sources                []model.Distribution   `json:"sources"`
+	Destinations           []model.Distribution   `json:"destinations"`
+	MetaData               map[string]interface{} `json:"meta_data"`
+	EffectiveDate          *time.Time             `json:"effective_date,omitempty"`
+	TransactionTime        *time.Time             `json:"transaction_time,omitempty"`
+	CreatedAt              *time.Time             `json:"created_at,omitempty"`
+	RetryPolicy            *model.RetryPolicy     `json:"retry_policy,omitempty"`
+	BusinessDayRule        *model.BusinessDayRule `json:"business_day_rule,omitempty"`
+	RoundingMode           model.RoundingMode     `json:"rounding_mode,omitempty"`
 }
 
 type InflightUpdate struct {
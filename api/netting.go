@@ -0,0 +1,211 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateNettingGroup creates a group of balances whose postings to each other are settled net at a daily cutoff.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body or the group is invalid.
+// - 201 Created: If the group is successfully created.
+func (a Api) CreateNettingGroup(c *gin.Context) {
+	var req model.NettingGroup
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := a.blnk.CreateNettingGroup(c.Request.Context(), req)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// ListNettingGroups lists a page of netting groups, oldest first.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the cursor is invalid.
+// - 500 Internal Server Error: If the groups cannot be retrieved.
+// - 200 OK: If the groups are successfully retrieved.
+func (a Api) ListNettingGroups(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	page, err := queryPage(c, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	groups, err := a.blnk.ListNettingGroups(c.Request.Context(), page)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	next := model.NextPageCursor(groups, limit, (*model.NettingGroup).PageCursor)
+	a.respondList(c, groups, listPage{limit: limit, offset: page.Offset, fetched: len(groups), next: next})
+}
+
+// GetNettingGroup retrieves a netting group with its settlements.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the group cannot be found.
+// - 200 OK: If the group is successfully retrieved.
+func (a Api) GetNettingGroup(c *gin.Context) {
+	group, err := a.blnk.GetNettingGroup(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondNettingError(c, err, "Netting group not found")
+		return
+	}
+
+	settlements, err := a.blnk.ListNettingSettlements(c.Request.Context(), group.GroupID)
+	if err != nil {
+		respondNettingError(c, err, "Netting group not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"group": group, "settlements": settlements})
+}
+
+// ListNettingEntries lists the gross memo entries of a netting group. Use ?status=pending to see
+// the postings waiting for the next cutoff.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the cursor is invalid.
+// - 500 Internal Server Error: If the entries cannot be retrieved.
+// - 200 OK: If the entries are successfully retrieved.
+func (a Api) ListNettingEntries(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	page, err := queryPage(c, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	entries, err := a.blnk.ListNettingEntries(c.Request.Context(), c.Param("id"), c.Query("status"), page)
+	if err != nil {
+		respondNettingError(c, err, "Netting group not found")
+		return
+	}
+
+	next := model.NextPageCursor(entries, limit, (*model.NettingEntry).PageCursor)
+	a.respondList(c, entries, listPage{limit: limit, offset: page.Offset, fetched: len(entries), next: next})
+}
+
+// SettleNettingGroup settles the pending entries of a netting group immediately instead of waiting for its cutoff.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the group cannot be found.
+// - 200 OK: Returns the settlement, which may have failed to apply.
+func (a Api) SettleNettingGroup(c *gin.Context) {
+	settlement, err := a.blnk.SettleNettingGroup(c.Request.Context(), c.Param("id"), time.Now().UTC())
+	if err != nil {
+		respondNettingError(c, err, "Netting group not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, settlement)
+}
+
+// DeactivateNettingGroup stops netting for a group and settles its pending entries.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the group cannot be found.
+// - 200 OK: Returns the settlement of the pending entries.
+func (a Api) DeactivateNettingGroup(c *gin.Context) {
+	settlement, err := a.blnk.DeactivateNettingGroup(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondNettingError(c, err, "Netting group not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, settlement)
+}
+
+// GetNettingSettlement retrieves a netting settlement and its net transfers.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the settlement cannot be found.
+// - 200 OK: If the settlement is successfully retrieved.
+func (a Api) GetNettingSettlement(c *gin.Context) {
+	settlement, err := a.blnk.GetNettingSettlement(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondNettingError(c, err, "Netting settlement not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, settlement)
+}
+
+// RetryNettingSettlement applies the remaining transfers of a failed netting settlement.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the settlement cannot be found.
+// - 500 Internal Server Error: If the settlement cannot be retried.
+// - 200 OK: Returns the settlement with its updated status.
+func (a Api) RetryNettingSettlement(c *gin.Context) {
+	settlement, err := a.blnk.RetryNettingSettlement(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondNettingError(c, err, "Netting settlement not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, settlement)
+}
+
+func respondNettingError(c *gin.Context, err error, notFound string) {
+	logrus.Error(err)
+	if strings.Contains(err.Error(), "not found") {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/blnkfinance/blnk/api/middleware"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// listPage describes the page of results a list handler fetched. The zero value describes a list that is
+// returned whole.
+type listPage struct {
+	limit   int               // The page size, or 0 when the list is not paginated
+	offset  int               // The number of rows skipped before the page
+	fetched int               // The number of rows fetched, before any filtering by ledger scope
+	next    *model.PageCursor // The position of the last row fetched, for lists paged by cursor
+	table   string            // The table a paginated list reads in full, used to estimate its total
+}
+
+// listEnvelope is the shape of list responses when the envelope is in use.
+type listEnvelope struct {
+	Data  interface{} `json:"data"`
+	Meta  listMeta    `json:"meta"`
+	Links listLinks   `json:"links"`
+}
+
+type listMeta struct {
+	Cursor        *string `json:"cursor"`
+	TotalEstimate int64   `json:"total_estimate"`
+}
+
+type listLinks struct {
+	Next *string `json:"next"`
+}
+
+// queryPage returns the page of a list request. A cursor from a previous page takes precedence over the
+// offset query parameter, which clients that page by offset still send.
+//
+// Parameters:
+// - c: The Gin context containing the request.
+// - limit: The size of the page, already validated by the handler.
+//
+// Returns:
+// - model.Page: The page to read.
+// - error: An error if the cursor or offset is malformed.
+func queryPage(c *gin.Context, limit int) (model.Page, error) {
+	if cursor := c.Query("cursor"); cursor != "" {
+		return model.ParsePageToken(cursor, limit)
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		return model.Page{}, err
+	}
+	return model.Page{Limit: limit, Offset: offset}, nil
+}
+
+// useListEnvelope reports whether list responses to a request are wrapped in the envelope. Versions after
+// v1 always use it; v1 uses it when the deployment opts in.
+func useListEnvelope(c *gin.Context) bool {
+	if version := middleware.RequestAPIVersion(c); version != "" && version != defaultAPIVersion {
+		return true
+	}
+	conf, err := config.Fetch()
+	return err == nil && conf.APIVersions.ListEnvelope
+}
+
+// respondList writes a list response. Without the envelope the items are written as a bare array, as v1
+// clients expect. With it, the items are returned under data along with the cursor and link of the next
+// page and an estimate of the total number of items.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+// - items: The slice of items to return.
+// - page: The page the items belong to.
+func (a Api) respondList(c *gin.Context, items interface{}, page listPage) {
+	if !useListEnvelope(c) {
+		c.JSON(http.StatusOK, items)
+		return
+	}
+
+	value := reflect.ValueOf(items)
+	if value.Kind() != reflect.Slice {
+		c.JSON(http.StatusOK, items)
+		return
+	}
+	if value.IsNil() {
+		items = []interface{}{}
+	}
+	if page.limit == 0 {
+		page.fetched = value.Len()
+	}
+
+	envelope := listEnvelope{Data: items}
+	envelope.Meta.TotalEstimate = int64(page.offset + page.fetched)
+	if page.limit > 0 && page.fetched >= page.limit {
+		// A full page means there may be more, so the total is at least one more than what was seen
+		cursor := model.EncodeOffsetCursor(page.offset + page.fetched)
+		if page.next != nil {
+			cursor = page.next.Encode()
+		}
+		next := nextPageLink(c, cursor)
+		envelope.Meta.Cursor = &cursor
+		envelope.Links.Next = &next
+		envelope.Meta.TotalEstimate++
+
+		if page.table != "" {
+			estimate, err := a.blnk.EstimateRowCount(c.Request.Context(), page.table)
+			if err != nil {
+				logrus.WithError(err).Warn("failed to estimate list total")
+			} else if estimate > envelope.Meta.TotalEstimate {
+				envelope.Meta.TotalEstimate = estimate
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, envelope)
+}
+
+// nextPageLink returns the request's URL with its offset replaced by cursor.
+func nextPageLink(c *gin.Context, cursor string) string {
+	query := c.Request.URL.Query()
+	query.Del("offset")
+	query.Set("cursor", cursor)
+	return c.Request.URL.Path + "?" + query.Encode()
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/api/middleware"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupListRouter(t *testing.T, listEnvelope bool) (*gin.Engine, *mocks.MockDataSource) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis:       config.RedisConfig{Dns: mr.Addr()},
+		Queue:       config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+		APIVersions: config.APIVersionConfig{ListEnvelope: listEnvelope},
+	})
+
+	mockDS := new(mocks.MockDataSource)
+	b, err := blnk.NewBlnk(mockDS)
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.APIVersioning(apiVersions, defaultAPIVersion))
+	a := Api{blnk: b, router: router}
+	router.GET("/ledgers", a.GetAllLedgers)
+	router.GET("/v2/ledgers", a.GetAllLedgers)
+	return router, mockDS
+}
+
+func TestQueryPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	after := model.PageCursor{Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), ID: "ldg_1"}
+	tests := []struct {
+		query string
+		want  model.Page
+	}{
+		{"", model.Page{Limit: 2}},
+		{"offset=40", model.Page{Limit: 2, Offset: 40}},
+		{"cursor=" + model.EncodeOffsetCursor(40), model.Page{Limit: 2, Offset: 40}},
+		{"cursor=" + after.Encode() + "&offset=40", model.Page{Limit: 2, After: &after}},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/ledgers?"+tt.query, nil)
+		page, err := queryPage(c, 2)
+		assert.NoError(t, err, tt.query)
+		assert.Equal(t, tt.want, page, tt.query)
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/ledgers?cursor=not-a-cursor", nil)
+	_, err := queryPage(c, 2)
+	assert.Error(t, err)
+}
+
+func TestListResponse_BareArrayByDefault(t *testing.T) {
+	router, mockDS := setupListRouter(t, false)
+	mockDS.On("GetAllLedgers", mock.Anything, model.Page{Limit: 2}).Return([]model.Ledger{{LedgerID: "ldg_1"}, {LedgerID: "ldg_2"}}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/ledgers?limit=2", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var ledgers []model.Ledger
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ledgers))
+	assert.Len(t, ledgers, 2)
+}
+
+func TestListResponse_Envelope(t *testing.T) {
+	router, mockDS := setupListRouter(t, true)
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	firstPage := []model.Ledger{{LedgerID: "ldg_2", CreatedAt: created}, {LedgerID: "ldg_1", CreatedAt: created}}
+	after := firstPage[1].PageCursor()
+	mockDS.On("GetAllLedgers", mock.Anything, model.Page{Limit: 2}).Return(firstPage, nil)
+	mockDS.On("GetAllLedgers", mock.Anything, model.Page{Limit: 2, After: &after}).Return([]model.Ledger{{LedgerID: "ldg_0"}}, nil)
+	mockDS.On("EstimateRowCount", mock.Anything, "blnk.ledgers").Return(int64(250), nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/ledgers?limit=2", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var first struct {
+		Data []model.Ledger `json:"data"`
+		Meta struct {
+			Cursor        *string `json:"cursor"`
+			TotalEstimate int64   `json:"total_estimate"`
+		} `json:"meta"`
+		Links struct {
+			Next *string `json:"next"`
+		} `json:"links"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+	assert.Len(t, first.Data, 2)
+	require.NotNil(t, first.Meta.Cursor)
+	assert.Equal(t, after.Encode(), *first.Meta.Cursor)
+	assert.Equal(t, int64(250), first.Meta.TotalEstimate)
+	require.NotNil(t, first.Links.Next)
+	assert.Equal(t, "/ledgers?cursor="+*first.Meta.Cursor+"&limit=2", *first.Links.Next)
+
+	// Following the next link returns the last page, read after the last ledger of the first
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", *first.Links.Next, nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"cursor":null,"total_estimate":1}`, extractJSONField(t, w.Body.Bytes(), "meta"))
+	assert.JSONEq(t, `{"next":null}`, extractJSONField(t, w.Body.Bytes(), "links"))
+	mockDS.AssertExpectations(t)
+}
+
+func TestListResponse_EnvelopeForLaterVersions(t *testing.T) {
+	router, mockDS := setupListRouter(t, false)
+	mockDS.On("GetAllLedgers", mock.Anything, model.Page{Limit: 10}).Return([]model.Ledger{}, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v2/ledgers", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":[],"meta":{"cursor":null,"total_estimate":0},"links":{"next":null}}`, w.Body.String())
+}
+
+func TestListResponse_InvalidCursor(t *testing.T) {
+	router, _ := setupListRouter(t, true)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/ledgers?cursor=bogus", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func extractJSONField(t *testing.T, body []byte, field string) string {
+	var document map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &document))
+	return string(document[field])
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// SetPostingRules sets how strictly the postings of a ledger are validated: whether zero-amount transactions
+// and transactions from a balance to itself are allowed, whether a description is required, and the format
+// references must match. Test ledgers can be made permissive while production ledgers stay strict.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body or reference pattern is invalid.
+// - 404 Not Found: If the ledger cannot be found.
+// - 200 OK: Returns the saved rules.
+func (a Api) SetPostingRules(c *gin.Context) {
+	id := c.Param("id")
+	if respondLedgerScopeError(c, a.blnk.CheckLedgerAccess(c.Request.Context(), id)) {
+		return
+	}
+
+	var req model.PostingRules
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.LedgerID = id
+
+	rules, err := a.blnk.SetPostingRules(c.Request.Context(), req)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		logrus.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// GetPostingRules retrieves the posting rules of a ledger.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the ledger has no posting rules.
+// - 200 OK: Returns the rules.
+func (a Api) GetPostingRules(c *gin.Context) {
+	id := c.Param("id")
+	if respondLedgerScopeError(c, a.blnk.CheckLedgerAccess(c.Request.Context(), id)) {
+		return
+	}
+
+	rules, err := a.blnk.GetPostingRules(c.Request.Context(), id)
+	if err != nil {
+		respondStatementError(c, err, "Posting rules not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// DeletePostingRules removes the posting rules of a ledger, so its postings are only checked by the usual
+// validation again.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the ledger has no posting rules.
+// - 204 No Content: If the rules are removed.
+func (a Api) DeletePostingRules(c *gin.Context) {
+	id := c.Param("id")
+	if respondLedgerScopeError(c, a.blnk.CheckLedgerAccess(c.Request.Context(), id)) {
+		return
+	}
+
+	if err := a.blnk.DeletePostingRules(c.Request.Context(), id); err != nil {
+		respondStatementError(c, err, "Posting rules not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -16,9 +16,12 @@ limitations under the License.
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/blnkfinance/blnk"
 	"github.com/blnkfinance/blnk/model"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -56,6 +59,38 @@ func (a Api) UploadExternalData(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"upload_id": uploadID, "record_count": total, "source": source})
 }
 
+// RecordExternalRecords stores a batch of external transaction records pushed as JSON, for partners that send
+// records as they happen instead of in a daily file. Records whose IDs were already recorded are skipped and
+// reported as duplicates, so batches can be retried.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the batch is empty, too large, or has a record missing a required field.
+// - 500 Internal Server Error: If the records cannot be stored.
+// - 201 Created: Returns the upload the records were added to and the duplicates skipped.
+func (a Api) RecordExternalRecords(c *gin.Context) {
+	var batch model.ExternalRecordBatch
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := a.blnk.RecordExternalRecords(c.Request.Context(), batch)
+	if err != nil {
+		if errors.Is(err, blnk.ErrInvalidExternalRecords) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record external records"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
 // StartReconciliation initiates a new reconciliation process based on the provided parameters.
 // It starts the reconciliation process and returns the reconciliation ID.
 //
@@ -253,3 +288,274 @@ func (a Api) DeleteMatchingRule(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Matching rule deleted successfully"})
 }
+
+// GetReconciliationReviewQueue lists the matches of a reconciliation that are waiting for review
+// because their confidence fell between the review and auto-confirm thresholds.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the reconciliation ID is missing.
+// - 500 Internal Server Error: If there is an error retrieving the review queue.
+// - 200 OK: If the review queue is successfully retrieved.
+func (a Api) GetReconciliationReviewQueue(c *gin.Context) {
+	reconciliationID := c.Param("id")
+	if reconciliationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reconciliation ID is required"})
+		return
+	}
+
+	matches, err := a.blnk.GetReconciliationReviewQueue(c.Request.Context(), reconciliationID)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve review queue"})
+		return
+	}
+
+	a.respondList(c, matches, listPage{})
+}
+
+// ReviewMatch confirms or rejects a match from a reconciliation's review queue.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the reconciliation ID is missing or the request body is invalid.
+// - 404 Not Found: If the match cannot be found.
+// - 500 Internal Server Error: If there is an error updating the match.
+// - 200 OK: If the match is successfully reviewed.
+func (a Api) ReviewMatch(c *gin.Context) {
+	reconciliationID := c.Param("id")
+	if reconciliationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reconciliation ID is required"})
+		return
+	}
+
+	var req struct {
+		ExternalTransactionID string `json:"external_transaction_id" binding:"required"`
+		InternalTransactionID string `json:"internal_transaction_id" binding:"required"`
+		Approve               bool   `json:"approve"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := a.blnk.ReviewMatch(c.Request.Context(), reconciliationID, req.ExternalTransactionID, req.InternalTransactionID, req.Approve)
+	if err != nil {
+		logrus.Error(err)
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review match"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Match reviewed successfully"})
+}
+
+// GetMatchScoreDistribution returns the distribution of match confidence scores for a reconciliation run,
+// which is used to tune matching rules and thresholds.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the reconciliation ID is missing.
+// - 404 Not Found: If the reconciliation cannot be found.
+// - 500 Internal Server Error: If there is an error computing the distribution.
+// - 200 OK: If the distribution is successfully computed.
+func (a Api) GetMatchScoreDistribution(c *gin.Context) {
+	reconciliationID := c.Param("id")
+	if reconciliationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reconciliation ID is required"})
+		return
+	}
+
+	distribution, err := a.blnk.GetMatchScoreDistribution(c.Request.Context(), reconciliationID)
+	if err != nil {
+		logrus.Error(err)
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reconciliation not found"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve score distribution"})
+		return
+	}
+
+	c.JSON(http.StatusOK, distribution)
+}
+
+// GetDryRunReport returns the report of a completed dry-run reconciliation: every match with its
+// confidence score, and the external transactions left as exceptions.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the reconciliation ID is missing, or the run is not a completed dry run.
+// - 404 Not Found: If the reconciliation or its report cannot be found.
+// - 200 OK: If the report is successfully retrieved.
+func (a Api) GetDryRunReport(c *gin.Context) {
+	reconciliationID := c.Param("id")
+	if reconciliationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reconciliation ID is required"})
+		return
+	}
+
+	report, err := a.blnk.GetDryRunReport(c.Request.Context(), reconciliationID)
+	if err != nil {
+		logrus.Error(err)
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// StartIntercompanyReconciliation reconciles this ledger against the transactions of another Blnk instance.
+// The remote instance's applied transactions within the requested window are used as the external source.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body is invalid or required fields are missing.
+// - 500 Internal Server Error: If the remote transactions cannot be fetched or the reconciliation fails to start.
+// - 200 OK: If the reconciliation process is successfully started.
+func (a Api) StartIntercompanyReconciliation(c *gin.Context) {
+	var req struct {
+		Source           model.IntercompanySource `json:"source" binding:"required"`
+		Strategy         string                   `json:"strategy" binding:"required"`
+		GroupingCriteria string                   `json:"grouping_criteria"`
+		DryRun           bool                     `json:"dry_run"`
+		MatchingRuleIDs  []string                 `json:"matching_rule_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reconciliationID, err := a.blnk.StartIntercompanyReconciliation(
+		c.Request.Context(),
+		req.Source,
+		req.Strategy,
+		req.GroupingCriteria,
+		req.MatchingRuleIDs,
+		req.DryRun,
+	)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reconciliation_id": reconciliationID})
+}
+
+// GetReconciliationBreaks lists the external transactions a reconciliation could not match.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the reconciliation ID is missing.
+// - 404 Not Found: If the reconciliation cannot be found.
+// - 500 Internal Server Error: If there is an error retrieving the breaks.
+// - 200 OK: If the breaks are successfully retrieved.
+func (a Api) GetReconciliationBreaks(c *gin.Context) {
+	reconciliationID := c.Param("id")
+	if reconciliationID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reconciliation ID is required"})
+		return
+	}
+
+	breaks, err := a.blnk.GetReconciliationBreaks(c.Request.Context(), reconciliationID)
+	if err != nil {
+		logrus.Error(err)
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reconciliation not found"})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reconciliation breaks"})
+		return
+	}
+
+	a.respondList(c, breaks, listPage{})
+}
+
+// ListStatementIngestions lists the statement files collected from the dead drops, newest first.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the cursor is invalid.
+// - 500 Internal Server Error: If there is an error retrieving the ingestions.
+// - 200 OK: If the ingestions are successfully retrieved.
+func (a Api) ListStatementIngestions(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	page, err := queryPage(c, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	ingestions, err := a.blnk.ListStatementIngestions(c.Request.Context(), page)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve statement ingestions"})
+		return
+	}
+
+	next := model.NextPageCursor(ingestions, limit, (*model.StatementIngestion).PageCursor)
+	a.respondList(c, ingestions, listPage{limit: limit, offset: page.Offset, fetched: len(ingestions), next: next})
+}
+
+// PollStatementIngestion collects and ingests new statement files immediately instead of waiting for the
+// next poll.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If no dead drop is configured.
+// - 409 Conflict: If a poll is already running.
+// - 500 Internal Server Error: If a dead drop could not be read. The files ingested before the failure are returned.
+// - 200 OK: Returns the ingestions of the files collected.
+func (a Api) PollStatementIngestion(c *gin.Context) {
+	ingestions, err := a.blnk.RunStatementIngestion(c.Request.Context())
+	if err != nil {
+		logrus.Error(err)
+		switch {
+		case strings.Contains(err.Error(), "not configured"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case strings.Contains(err.Error(), "failed to acquire lock"):
+			c.JSON(http.StatusConflict, gin.H{"error": "A statement poll is already running"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "ingestions": ingestions})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ingestions)
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateReportDefinition saves a report definition: the source it reads, its filters, group-bys and
+// aggregates, its schedule and the format and destination of its output.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body is invalid or the report cannot be created.
+// - 201 Created: If the report is successfully created.
+func (a Api) CreateReportDefinition(c *gin.Context) {
+	var req model.ReportDefinition
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	definition, err := a.blnk.CreateReportDefinition(c.Request.Context(), req)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, definition)
+}
+
+// ListReportDefinitions lists report definitions, newest first.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the cursor is invalid.
+// - 500 Internal Server Error: If the reports cannot be retrieved.
+// - 200 OK: If the reports are successfully retrieved.
+func (a Api) ListReportDefinitions(c *gin.Context) {
+	page, ok := reportPage(c)
+	if !ok {
+		return
+	}
+
+	definitions, err := a.blnk.ListReportDefinitions(c.Request.Context(), page)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	next := model.NextPageCursor(definitions, page.Limit, (*model.ReportDefinition).PageCursor)
+	a.respondList(c, definitions, listPage{limit: page.Limit, offset: page.Offset, fetched: len(definitions), next: next})
+}
+
+// GetReportDefinition retrieves a report definition by ID.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the report cannot be found.
+// - 200 OK: If the report is successfully retrieved.
+func (a Api) GetReportDefinition(c *gin.Context) {
+	definition, err := a.blnk.GetReportDefinition(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondStatementError(c, err, "Report not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+}
+
+// DeleteReportDefinition deletes a report definition and its runs.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the report cannot be found.
+// - 204 No Content: If the report is deleted.
+func (a Api) DeleteReportDefinition(c *gin.Context) {
+	if err := a.blnk.DeleteReportDefinition(c.Request.Context(), c.Param("id")); err != nil {
+		respondStatementError(c, err, "Report not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RunReport runs a report immediately and returns the run.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the report cannot be found.
+// - 200 OK: Returns the run, which may have failed.
+func (a Api) RunReport(c *gin.Context) {
+	run, err := a.blnk.RunReport(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondStatementError(c, err, "Report not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// ListReportRuns lists the runs of a report, newest first, without their rows.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the cursor is invalid.
+// - 404 Not Found: If the report cannot be found.
+// - 200 OK: If the runs are successfully retrieved.
+func (a Api) ListReportRuns(c *gin.Context) {
+	page, ok := reportPage(c)
+	if !ok {
+		return
+	}
+
+	runs, err := a.blnk.ListReportRuns(c.Request.Context(), c.Param("id"), page)
+	if err != nil {
+		respondStatementError(c, err, "Report not found")
+		return
+	}
+
+	next := model.NextPageCursor(runs, page.Limit, (*model.ReportRun).PageCursor)
+	a.respondList(c, runs, listPage{limit: page.Limit, offset: page.Offset, fetched: len(runs), next: next})
+}
+
+// GetReportRun retrieves a report run with its rows. Use ?format=csv or ?format=json to download the rows
+// in that format instead.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the format is unsupported.
+// - 404 Not Found: If the run cannot be found.
+// - 200 OK: Returns the run, or its rows in the requested format.
+func (a Api) GetReportRun(c *gin.Context) {
+	run, err := a.blnk.GetReportRun(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondStatementError(c, err, "Report run not found")
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		c.JSON(http.StatusOK, run)
+		return
+	}
+	data, contentType, err := blnk.RenderReportRun(run, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=\""+run.RunID+"."+format+"\"")
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// reportPage reads the page of a report listing, responding with a 400 if the cursor is invalid.
+func reportPage(c *gin.Context) (model.Page, bool) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	page, err := queryPage(c, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return model.Page{}, false
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+	return page, true
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ListRequestLogs searches the API request log, newest first. Results can be filtered with ?key_id=,
+// ?method=, ?path= (a path prefix), ?status=, ?min_status=, ?from= and ?to= (RFC 3339 times) and ?q=, which
+// matches text in the request or response body.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If a filter or the cursor is invalid.
+// - 500 Internal Server Error: If the entries cannot be retrieved.
+// - 200 OK: If the entries are successfully retrieved.
+func (a Api) ListRequestLogs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	page, err := queryPage(c, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	filter, err := requestLogFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := a.blnk.ListRequestLogs(c.Request.Context(), filter, page)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	next := model.NextPageCursor(entries, limit, (*model.RequestLog).PageCursor)
+	a.respondList(c, entries, listPage{limit: limit, offset: page.Offset, fetched: len(entries), next: next})
+}
+
+// GetRequestLog retrieves an entry of the API request log by the ID returned in the X-Blnk-Request-Log-Id
+// header.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the entry cannot be found.
+// - 500 Internal Server Error: If the entry cannot be retrieved.
+// - 200 OK: If the entry is successfully retrieved.
+func (a Api) GetRequestLog(c *gin.Context) {
+	entry, err := a.blnk.GetRequestLog(c.Request.Context(), c.Param("id"), requestOwner(c))
+	if err != nil {
+		logrus.Error(err)
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Request log not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// requestLogFilter reads the filters of a request log search from the query string.
+func requestLogFilter(c *gin.Context) (model.RequestLogFilter, error) {
+	filter := model.RequestLogFilter{
+		OwnerID:    requestOwner(c),
+		KeyID:      c.Query("key_id"),
+		Method:     strings.ToUpper(c.Query("method")),
+		PathPrefix: c.Query("path"),
+		Contains:   c.Query("q"),
+	}
+
+	for name, target := range map[string]*int{"status": &filter.StatusCode, "min_status": &filter.MinStatus} {
+		if value := c.Query(name); value != "" {
+			status, err := strconv.Atoi(value)
+			if err != nil || status < 100 || status > 599 {
+				return filter, fmt.Errorf("invalid %s %q", name, value)
+			}
+			*target = status
+		}
+	}
+
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(name); value != "" {
+			at, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s time %q, expected RFC 3339", name, value)
+			}
+			*target = &at
+		}
+	}
+	return filter, nil
+}
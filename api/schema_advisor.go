@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/gin-gonic/gin"
+)
+
+// GetSchemaAdvice runs the schema advisor and returns its recommendations for the blnk schema's statements,
+// indexes and tables. The limit, slow_statement_ms and min_rows query parameters override the advisor's
+// thresholds; missing or invalid values use the defaults.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 200 OK: Returns the advisor report. Checks that could not run carry their error in the report.
+func (a Api) GetSchemaAdvice(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	slowStatementMs, _ := strconv.Atoi(c.Query("slow_statement_ms"))
+	minRows, _ := strconv.ParseInt(c.Query("min_rows"), 10, 64)
+
+	report := a.blnk.AdviseSchema(c.Request.Context(), blnk.SchemaAdvisorOptions{
+		Limit:             limit,
+		SlowStatementTime: time.Duration(slowStatementMs) * time.Millisecond,
+		MinRows:           minRows,
+	})
+
+	c.JSON(http.StatusOK, report)
+}
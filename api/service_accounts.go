@@ -0,0 +1,121 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/database"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateServiceAccount creates a service account. The secret is only returned in this response.
+//
+// Parameters:
+// - c: The Gin context containing the request and response
+//
+// Responses:
+// - 400 Bad Request: If there's an error in the request body
+// - 201 Created: Returns the service account and its secret
+func (a Api) CreateServiceAccount(c *gin.Context) {
+	var req model.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, secret, err := a.blnk.CreateServiceAccount(c.Request.Context(), req.Name, req.Owner, req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"service_account": account, "client_id": account.ServiceAccountID, "client_secret": secret})
+}
+
+// ListServiceAccounts lists the service accounts of an owner. Requests authenticated with an API key
+// or service token only see the accounts of their own owner.
+//
+// Parameters:
+// - c: The Gin context containing the request and response
+//
+// Responses:
+// - 200 OK: Returns the list of service accounts
+// - 400 Bad Request: If no owner is given
+func (a Api) ListServiceAccounts(c *gin.Context) {
+	owner := requestOwner(c)
+	if owner == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "owner is required"})
+		return
+	}
+
+	accounts, err := a.blnk.ListServiceAccounts(c.Request.Context(), owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	a.respondList(c, accounts, listPage{})
+}
+
+// RevokeServiceAccount revokes a service account and invalidates the tokens issued to it
+//
+// Parameters:
+// - c: The Gin context containing the request and response
+//
+// Responses:
+// - 204 No Content: If the service account is revoked
+// - 400 Bad Request: If no owner is given
+// - 404 Not Found: If the service account is not found
+func (a Api) RevokeServiceAccount(c *gin.Context) {
+	owner := requestOwner(c)
+	if owner == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "owner is required"})
+		return
+	}
+
+	if err := a.blnk.RevokeServiceAccount(c.Request.Context(), c.Param("id"), owner); err != nil {
+		if errors.Is(err, database.ErrServiceAccountNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "service account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// IssueServiceToken exchanges service account credentials for a short-lived scoped token
+//
+// Parameters:
+// - c: The Gin context containing the request and response
+//
+// Responses:
+// - 200 OK: Returns the token and its expiry
+// - 400 Bad Request: If there's an error in the request body
+// - 401 Unauthorized: If the credentials are invalid or the account is revoked
+// - 403 Forbidden: If a requested scope is not granted to the account
+func (a Api) IssueServiceToken(c *gin.Context) {
+	var req model.IssueServiceTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := a.blnk.IssueServiceToken(c.Request.Context(), req.ClientID, req.ClientSecret, req.Scopes)
+	if err != nil {
+		switch {
+		case errors.Is(err, blnk.ErrInvalidServiceAccountCredentials), errors.Is(err, blnk.ErrServiceAccountRevoked):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, blnk.ErrScopeNotGranted):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/api/middleware"
+	model2 "github.com/blnkfinance/blnk/api/model"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateSession opens a session that ledgers, identities, balances and transactions can be staged in and then
+// committed or rolled back together.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 500 Internal Server Error: If the session cannot be saved.
+// - 201 Created: Returns the session.
+func (a Api) CreateSession(c *gin.Context) {
+	session, err := a.blnk.CreateSession(c.Request.Context())
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetSession retrieves a session with the operations staged in it.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the session does not exist or has expired.
+// - 200 OK: Returns the session.
+func (a Api) GetSession(c *gin.Context) {
+	session, err := a.blnk.GetSession(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// StageSessionLedger stages the creation of a ledger in a session. The body is the same as for creating a
+// ledger, and the ledger is returned with the ID it will be created with.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the ledger is invalid.
+// - 403 Forbidden: If the caller cannot create ledgers or its API key is scoped to ledgers.
+// - 404 Not Found: If the session does not exist or has expired.
+// - 409 Conflict: If the session was already committed or rolled back.
+// - 201 Created: Returns the staged ledger.
+func (a Api) StageSessionLedger(c *gin.Context) {
+	if denySessionOperation(c, middleware.ResourceLedgers) {
+		return
+	}
+	var newLedger model2.CreateLedger
+	if err := c.ShouldBindJSON(&newLedger); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": err.Error()})
+		return
+	}
+	if err := newLedger.ValidateCreateLedger(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": err.Error()})
+		return
+	}
+
+	ledger, err := a.blnk.StageLedger(c.Request.Context(), c.Param("id"), newLedger.ToLedger())
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ledger)
+}
+
+// StageSessionIdentity stages the creation of an identity in a session. The body is the same as for creating
+// an identity, and the identity is returned with the ID it will be created with.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the identity is invalid.
+// - 403 Forbidden: If the caller cannot create identities.
+// - 404 Not Found: If the session does not exist or has expired.
+// - 409 Conflict: If the session was already committed or rolled back.
+// - 201 Created: Returns the staged identity.
+func (a Api) StageSessionIdentity(c *gin.Context) {
+	if denySessionOperation(c, middleware.ResourceIdentities) {
+		return
+	}
+	var identity model.Identity
+	if err := c.ShouldBindJSON(&identity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	staged, err := a.blnk.StageIdentity(c.Request.Context(), c.Param("id"), identity)
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, staged)
+}
+
+// StageSessionBalance stages the creation of a balance in a session. The body is the same as for creating a
+// balance; its ledger and identity may be ones staged earlier in the session. The balance is returned with the
+// ID it will be created with.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the balance is invalid.
+// - 403 Forbidden: If the caller cannot create balances or the ledger is outside its ledger scope.
+// - 404 Not Found: If the session, ledger or identity does not exist.
+// - 409 Conflict: If the session was already committed or rolled back.
+// - 201 Created: Returns the staged balance.
+func (a Api) StageSessionBalance(c *gin.Context) {
+	if denySessionOperation(c, middleware.ResourceBalances) {
+		return
+	}
+	var newBalance model2.CreateBalance
+	if err := c.ShouldBindJSON(&newBalance); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := newBalance.ValidateCreateBalance(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": err.Error()})
+		return
+	}
+
+	balance, err := a.blnk.StageBalance(c.Request.Context(), c.Param("id"), newBalance.ToBalance())
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, balance)
+}
+
+// StageSessionTransaction stages a transaction in a session. The body is the same as for recording a
+// transaction; its source and destination may be balances staged earlier in the session. Only immediate
+// transfers between two balances can be staged, and funds are checked when the session is committed.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the transaction is invalid or cannot be staged.
+// - 403 Forbidden: If the caller cannot record transactions or override minimum balances, or a balance is outside its ledger scope.
+// - 404 Not Found: If the session does not exist or has expired.
+// - 409 Conflict: If the session was already committed or rolled back.
+// - 201 Created: Returns the staged transaction.
+func (a Api) StageSessionTransaction(c *gin.Context) {
+	if denySessionOperation(c, middleware.ResourceTransactions) {
+		return
+	}
+	var newTransaction model2.RecordTransaction
+	if err := c.ShouldBindJSON(&newTransaction); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": err.Error()})
+		return
+	}
+	if err := newTransaction.ValidateRecordTransaction(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": err.Error()})
+		return
+	}
+	if denyMinimumBalanceOverride(c, newTransaction.OverrideMinimumBalance) {
+		return
+	}
+
+	transaction, err := a.blnk.StageTransaction(c.Request.Context(), c.Param("id"), newTransaction.ToTransaction())
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, transformTransaction(transaction))
+}
+
+// CommitSession writes everything staged in a session at once. If any operation fails, such as a transaction
+// without enough funds, nothing is written and the session stays open.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If an operation of the session fails.
+// - 403 Forbidden: If a transaction posts to a balance outside the caller's ledger scope.
+// - 404 Not Found: If the session does not exist or has expired.
+// - 409 Conflict: If the session was already committed or rolled back, or a resource it writes changed.
+// - 503 Service Unavailable: If a ledger the session writes to is in maintenance mode.
+// - 200 OK: Returns the committed session with the resources as written.
+func (a Api) CommitSession(c *gin.Context) {
+	session, err := a.blnk.CommitSession(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if respondMinimumBalanceError(c, err) || middleware.RespondMaintenance(c, err) {
+			return
+		}
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// RollbackSession discards everything staged in a session.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the session does not exist or has expired.
+// - 409 Conflict: If the session was already committed or rolled back.
+// - 200 OK: Returns the rolled back session.
+func (a Api) RollbackSession(c *gin.Context) {
+	session, err := a.blnk.RollbackSession(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// denySessionOperation responds with 403 if the caller cannot create the resource an operation stages. The
+// sessions scope alone lets callers open and commit sessions, not create everything through them.
+func denySessionOperation(c *gin.Context, resource middleware.Resource) bool {
+	if middleware.CallerHasPermission(c, resource, http.MethodPost) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "staging this operation requires the " + middleware.BuildScope(resource, middleware.ActionWrite) + " scope"})
+	return true
+}
+
+// respondSessionError writes the response for a failed session request.
+func respondSessionError(c *gin.Context, err error) {
+	var apiErr apierror.APIError
+	var schemaErr *model.IdentitySchemaError
+	switch {
+	case errors.Is(err, blnk.ErrOutsideLedgerScope):
+		respondLedgerScopeError(c, err)
+	case errors.As(err, &schemaErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": schemaErr.Errors})
+	case errors.Is(err, model.ErrSessionNotOpen), errors.As(err, &apiErr) && apiErr.Code == apierror.ErrConflict:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case strings.Contains(err.Error(), "not found"), errors.As(err, &apiErr) && apiErr.Code == apierror.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &apiErr) && apiErr.Code == apierror.ErrInternalServer:
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
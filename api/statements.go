@@ -0,0 +1,135 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateStatementSchedule registers a monthly statement for a balance or identity.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If the request body is invalid or the schedule cannot be created.
+// - 201 Created: If the schedule is successfully created.
+func (a Api) CreateStatementSchedule(c *gin.Context) {
+	var req model.StatementSchedule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := a.blnk.CreateStatementSchedule(c.Request.Context(), req)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// GetStatementSchedule retrieves a statement schedule by ID.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the schedule cannot be found.
+// - 200 OK: If the schedule is successfully retrieved.
+func (a Api) GetStatementSchedule(c *gin.Context) {
+	schedule, err := a.blnk.GetStatementSchedule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondStatementError(c, err, "Statement schedule not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// ListStatements lists the statements generated by a schedule with their delivery status.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 500 Internal Server Error: If the statements cannot be retrieved.
+// - 200 OK: If the statements are successfully retrieved.
+func (a Api) ListStatements(c *gin.Context) {
+	statements, err := a.blnk.ListStatements(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve statements"})
+		return
+	}
+
+	a.respondList(c, statements, listPage{})
+}
+
+// GetStatement retrieves a generated statement and its delivery status.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the statement cannot be found.
+// - 200 OK: If the statement is successfully retrieved.
+func (a Api) GetStatement(c *gin.Context) {
+	statement, err := a.blnk.GetStatement(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondStatementError(c, err, "Statement not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// ResendStatement delivers a generated statement again over its schedule's channel.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the statement cannot be found.
+// - 500 Internal Server Error: If the statement file cannot be loaded.
+// - 200 OK: Returns the statement with its updated delivery status.
+func (a Api) ResendStatement(c *gin.Context) {
+	statement, err := a.blnk.ResendStatement(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondStatementError(c, err, "Statement not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// respondStatementError maps statement lookup errors to a 404 or 500 response.
+func respondStatementError(c *gin.Context, err error, notFound string) {
+	logrus.Error(err)
+	if strings.Contains(err.Error(), "not found") {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -172,7 +172,7 @@ func TestRecordTransaction(t *testing.T) {
 	}
 
 	// Create ledger and balances for testing
-	newLedger, err := b.CreateLedger(model.Ledger{Name: gofakeit.Name()})
+	newLedger, err := b.CreateLedger(context.Background(), model.Ledger{Name: gofakeit.Name()})
 	if err != nil {
 		t.Fatalf("Failed to create ledger: %v", err)
 	}
@@ -321,7 +321,7 @@ func TestRecordTransaction(t *testing.T) {
 
 func TestRecordTransactionWithExitingRef(t *testing.T) {
 	router, b, _ := setupRouter()
-	newLedger, err := b.CreateLedger(model.Ledger{Name: gofakeit.Name()})
+	newLedger, err := b.CreateLedger(context.Background(), model.Ledger{Name: gofakeit.Name()})
 	if err != nil {
 		return
 	}
@@ -384,7 +384,7 @@ func TestInflightTransaction_Commit_API(t *testing.T) {
 	defer cleanupWorker()
 
 	ctx := context.Background()
-	ledger, err := b.CreateLedger(model.Ledger{Name: gofakeit.Name()})
+	ledger, err := b.CreateLedger(ctx, model.Ledger{Name: gofakeit.Name()})
 	assert.NoError(t, err)
 
 	sourceBalance, err := b.CreateBalance(ctx, model.Balance{LedgerID: ledger.LedgerID, Currency: "USD"})
@@ -488,7 +488,7 @@ func TestInflightTransaction_Void_API(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	ledger, err := b.CreateLedger(model.Ledger{Name: gofakeit.Name()})
+	ledger, err := b.CreateLedger(ctx, model.Ledger{Name: gofakeit.Name()})
 	assert.NoError(t, err)
 
 	sourceBalance, err := b.CreateBalance(ctx, model.Balance{LedgerID: ledger.LedgerID, Currency: "EUR"})
@@ -589,7 +589,7 @@ func TestInflightTransaction_Commit_WithAmount_API(t *testing.T) {
 	defer cleanupWorker()
 
 	ctx := context.Background()
-	ledger, err := b.CreateLedger(model.Ledger{Name: gofakeit.Name()})
+	ledger, err := b.CreateLedger(ctx, model.Ledger{Name: gofakeit.Name()})
 	assert.NoError(t, err)
 
 	sourceBalance, err := b.CreateBalance(ctx, model.Balance{LedgerID: ledger.LedgerID, Currency: "CAD"})
@@ -19,11 +19,16 @@ import (
 	"errors"
 	"math/big"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/api/middleware"
 	model2 "github.com/blnkfinance/blnk/api/model"
 	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
 	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
 	"github.com/blnkfinance/blnk/model"
 
@@ -105,9 +110,16 @@ func (a Api) RecordTransaction(c *gin.Context) {
 		return
 	}
 
+	if denyMinimumBalanceOverride(c, newTransaction.OverrideMinimumBalance) {
+		return
+	}
+
 	// Record the transaction using the Blnk service
 	resp, err := a.blnk.RecordTransaction(c.Request.Context(), newTransaction.ToTransaction())
 	if err != nil {
+		if respondMinimumBalanceError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -143,10 +155,21 @@ func (a Api) QueueTransaction(c *gin.Context) {
 		return
 	}
 
+	transaction := newTransaction.ToTransaction()
+	if respondLedgerScopeError(c, a.blnk.CheckTransactionAccess(c.Request.Context(), transaction)) {
+		return
+	}
+	if denyMinimumBalanceOverride(c, transaction.OverrideMinimumBalance) {
+		return
+	}
+
 	// Queue the transaction using the Blnk service
-	resp, err := a.blnk.QueueTransaction(c.Request.Context(), newTransaction.ToTransaction())
+	resp, err := a.blnk.QueueTransaction(c.Request.Context(), transaction)
 	if err != nil {
 		logrus.Error(err)
+		if respondMinimumBalanceError(c, err) || middleware.RespondMaintenance(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -171,6 +194,9 @@ func (a Api) RefundTransaction(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "id is required. pass id in the route /:id"})
 		return
 	}
+	if respondLedgerScopeError(c, a.blnk.CheckTransactionIDAccess(c.Request.Context(), id)) {
+		return
+	}
 	transaction, err := a.blnk.ProcessTransactionInBatches(c.Request.Context(), id, big.NewInt(0), 1, false, a.blnk.GetRefundableTransactionsByParentID, a.blnk.RefundWorker)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -208,9 +234,133 @@ func (a Api) GetTransaction(c *gin.Context) {
 		return
 	}
 
+	if respondLedgerScopeError(c, a.blnk.CheckTransactionAccess(c.Request.Context(), resp)) {
+		return
+	}
+
 	c.JSON(http.StatusOK, transformTransaction(resp))
 }
 
+// GetTransactionHistory retrieves the statuses a transaction went through, oldest first, with the reason code
+// and actor of each change. Statuses of the transactions it led to, such as the commits and voids of an
+// inflight transaction, are included.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the transaction cannot be found.
+// - 500 Internal Server Error: If the history cannot be retrieved.
+// - 200 OK: If the history is successfully retrieved.
+func (a Api) GetTransactionHistory(c *gin.Context) {
+	transaction, err := a.blnk.GetTransaction(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if respondLedgerScopeError(c, a.blnk.CheckTransactionAccess(c.Request.Context(), transaction)) {
+		return
+	}
+
+	history, err := a.blnk.GetTransactionStatusHistory(c.Request.Context(), transaction.TransactionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// GetTransactionTimings retrieves the time a transaction spent in each stage of the posting pipeline, such as
+// waiting for the lock of its source balance or being committed, for debugging slow postings. Timings of the
+// transactions it led to, such as the queued copy that was applied, are included.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the transaction cannot be found.
+// - 500 Internal Server Error: If the timings cannot be retrieved.
+// - 200 OK: If the timings are successfully retrieved.
+func (a Api) GetTransactionTimings(c *gin.Context) {
+	transaction, err := a.blnk.GetTransaction(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if respondLedgerScopeError(c, a.blnk.CheckTransactionAccess(c.Request.Context(), transaction)) {
+		return
+	}
+
+	timings, err := a.blnk.GetTransactionTimings(c.Request.Context(), transaction.TransactionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, timings)
+}
+
+// GetTransactionReceipt renders the receipt of a transaction with the receipt template configured for the event
+// of its status, such as transaction.applied, in the content type of the template.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 404 Not Found: If the transaction cannot be found or its event has no receipt template.
+// - 500 Internal Server Error: If the receipt cannot be rendered.
+// - 200 OK: Returns the receipt.
+func (a Api) GetTransactionReceipt(c *gin.Context) {
+	transaction, err := a.blnk.GetTransaction(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if respondLedgerScopeError(c, a.blnk.CheckTransactionAccess(c.Request.Context(), transaction)) {
+		return
+	}
+
+	receipt, err := a.blnk.RenderTransactionReceipt(transaction)
+	if errors.Is(err, blnk.ErrNoReceiptTemplate) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, receipt.ContentType, receipt.Body)
+}
+
+// GetTransactionGroup retrieves the transactions of a logical operation, such as the payment, fees, refunds and
+// chargebacks of an order, recorded with the group ID, and the net position of each balance they moved.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 403 Forbidden: If a transaction of the group is outside the ledgers the API key is scoped to.
+// - 404 Not Found: If no transaction was recorded with the group ID.
+// - 500 Internal Server Error: If the transactions cannot be retrieved.
+// - 200 OK: Returns the group.
+func (a Api) GetTransactionGroup(c *gin.Context) {
+	group, err := a.blnk.GetTransactionGroup(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(apierror.MapErrorToHTTPStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	for i, transaction := range group.Transactions {
+		if respondLedgerScopeError(c, a.blnk.CheckTransactionAccess(c.Request.Context(), transaction)) {
+			return
+		}
+		group.Transactions[i] = transformTransaction(transaction)
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
 // UpdateInflightStatus updates the status of an inflight transaction based on the provided ID and status.
 // It processes the transaction in batches according to the specified status (commit or void).
 // If any errors occur during processing or if the status is unsupported, it responds with an appropriate error message.
@@ -220,6 +370,7 @@ func (a Api) GetTransaction(c *gin.Context) {
 //
 // Responses:
 // - 400 Bad Request: If there's an error in updating the status or if the ID or status is missing or unsupported.
+// - 503 Service Unavailable: If a ledger of the transaction is in maintenance mode.
 // - 200 OK: If the inflight transaction status is successfully updated.
 func (a Api) UpdateInflightStatus(c *gin.Context) {
 	var resp *model.Transaction
@@ -235,6 +386,10 @@ func (a Api) UpdateInflightStatus(c *gin.Context) {
 		return
 	}
 
+	if respondLedgerScopeError(c, a.blnk.CheckTransactionIDAccess(c.Request.Context(), id)) {
+		return
+	}
+
 	cnf, err := config.Fetch()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -255,6 +410,9 @@ func (a Api) UpdateInflightStatus(c *gin.Context) {
 	status := req.Status
 	if status == "commit" {
 		transaction, err := a.blnk.ProcessTransactionInBatches(c.Request.Context(), id, amount, 1, false, a.blnk.GetInflightTransactionsByParentID, a.blnk.CommitWorker)
+		if middleware.RespondMaintenance(c, err) {
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -266,6 +424,9 @@ func (a Api) UpdateInflightStatus(c *gin.Context) {
 		resp = transformTransaction(transaction[0])
 	} else if status == "void" {
 		transaction, err := a.blnk.ProcessTransactionInBatches(c.Request.Context(), id, amount, 1, false, a.blnk.GetInflightTransactionsByParentID, a.blnk.VoidWorker)
+		if middleware.RespondMaintenance(c, err) {
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -294,8 +455,20 @@ func (a Api) CreateBulkTransactions(c *gin.Context) {
 		return
 	}
 
+	for _, transaction := range req.Transactions {
+		if respondLedgerScopeError(c, a.blnk.CheckTransactionAccess(c.Request.Context(), transaction)) {
+			return
+		}
+		if denyMinimumBalanceOverride(c, transaction.OverrideMinimumBalance) {
+			return
+		}
+	}
+
 	// Call the service layer method to handle bulk transaction creation
 	result, err := a.blnk.CreateBulkTransactions(c.Request.Context(), &req)
+	if middleware.RespondMaintenance(c, err) {
+		return
+	}
 	// Handle the response based on the result and error from the service layer
 	if err != nil {
 		// If there was an error during synchronous processing
@@ -324,3 +497,52 @@ func (a Api) CreateBulkTransactions(c *gin.Context) {
 		})
 	}
 }
+
+// GetScheduledTransactionFailures reports scheduled transactions that failed permanently after their
+// retry policy ran out. The period defaults to the last 30 days.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If start or end is not an RFC 3339 timestamp, the period is empty or the cursor is invalid.
+// - 200 OK: Returns the failed occurrences, newest first.
+func (a Api) GetScheduledTransactionFailures(c *gin.Context) {
+	end := time.Now()
+	start := end.AddDate(0, 0, -30)
+	var err error
+	if value := c.Query("start"); value != "" {
+		if start, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+	if value := c.Query("end"); value != "" {
+		if end, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "end must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	page, err := queryPage(c, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	failures, err := a.blnk.GetScheduledTransactionFailures(c.Request.Context(), start, end, page)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	next := model.NextPageCursor(failures, limit, (*model.ScheduledTransactionFailure).PageCursor)
+	a.respondList(c, failures, listPage{limit: limit, offset: page.Offset, fetched: len(failures), next: next})
+}
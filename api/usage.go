@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUsage returns daily usage records filtered by tenant, ledger and date range.
+// Requests authenticated with an API key only see the usage of the key's owner.
+func (a *Api) GetUsage(c *gin.Context) {
+	filter := model.UsageFilter{
+		Tenant:   c.Query("tenant"),
+		LedgerID: c.Query("ledger_id"),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+	}
+	if t := tenant.FromContext(c.Request.Context()); t != "" {
+		filter.Tenant = t
+	}
+
+	records, err := a.blnk.GetUsage(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, apierror.NewAPIError(apierror.ErrInvalidInput, "failed to get usage", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
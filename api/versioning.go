@@ -0,0 +1,58 @@
+package api
+
+import "github.com/blnkfinance/blnk/api/middleware"
+
+// defaultAPIVersion is the version unversioned routes are served as.
+const defaultAPIVersion = "v1"
+
+// apiVersions are the versions served under a /<version> path prefix. The handlers speak v1; later
+// versions translate to and from it with adapters.
+var apiVersions = []middleware.APIVersion{
+	{Name: "v1"},
+	{Name: "v2", Adapter: &middleware.VersionAdapter{
+		Request: func(document interface{}) interface{} {
+			for _, rename := range fieldRenames {
+				document = renameKey(document, rename.Current, rename.Deprecated)
+			}
+			return document
+		},
+		Response: func(document interface{}) interface{} {
+			for _, rename := range fieldRenames {
+				document = renameKey(document, rename.Deprecated, rename.Current)
+			}
+			return document
+		},
+	}},
+}
+
+// fieldRenames are the JSON fields renamed since v1. The handlers use the deprecated names. v2 only speaks the
+// current names, while v1 accepts either and emits the names each API key's field compatibility asks for.
+var fieldRenames = []middleware.FieldRename{
+	{Deprecated: "meta_data", Current: "metadata"},
+}
+
+// renameKey renames the key from to the key to in every object of a JSON document. The values of renamed
+// keys are left as they are, so user metadata that happens to use either name is not touched. When an
+// object has both keys, the renamed value wins.
+func renameKey(document interface{}, from, to string) interface{} {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		renamed := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			if key != from {
+				renamed[key] = renameKey(child, from, to)
+			}
+		}
+		if child, ok := value[from]; ok {
+			renamed[to] = child
+		}
+		return renamed
+	case []interface{}:
+		for i, child := range value {
+			value[i] = renameKey(child, from, to)
+		}
+		return value
+	default:
+		return document
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenameKey(t *testing.T) {
+	document := map[string]interface{}{
+		"ledger_id": "ldg_1",
+		"metadata":  map[string]interface{}{"metadata": "kept", "tier": "gold"},
+		"balances": []interface{}{
+			map[string]interface{}{"balance_id": "bln_1", "metadata": map[string]interface{}{}},
+		},
+	}
+
+	renamed := renameKey(document, "metadata", "meta_data").(map[string]interface{})
+
+	assert.NotContains(t, renamed, "metadata")
+	assert.Equal(t, map[string]interface{}{"metadata": "kept", "tier": "gold"}, renamed["meta_data"])
+	balance := renamed["balances"].([]interface{})[0].(map[string]interface{})
+	assert.Contains(t, balance, "meta_data")
+	assert.NotContains(t, balance, "metadata")
+}
+
+func TestRegisterVersionedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	api := Api{blnk: &blnk.Blnk{}, router: gin.New()}
+
+	assert.NotPanics(t, func() {
+		api.registerRoutes(api.router)
+		for _, version := range apiVersions {
+			api.registerRoutes(api.router.Group("/" + version.Name))
+		}
+	})
+}
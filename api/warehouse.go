@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetWarehouseSyncStates lists how far transactions, balances and identities have been exported to the
+// warehouse.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 500 Internal Server Error: If the states cannot be retrieved.
+// - 200 OK: Returns the state of each entity that has been synced.
+func (a Api) GetWarehouseSyncStates(c *gin.Context) {
+	states, err := a.blnk.GetWarehouseSyncStates(c.Request.Context())
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, states)
+}
+
+// RunWarehouseSync exports the rows written since the last sync to the warehouse immediately instead of
+// waiting for the next sync.
+//
+// Parameters:
+// - c: The Gin context containing the request and response.
+//
+// Responses:
+// - 400 Bad Request: If no warehouse is configured.
+// - 409 Conflict: If a sync is already running.
+// - 500 Internal Server Error: If an entity could not be exported. What was exported is returned.
+// - 200 OK: Returns what was exported for each entity.
+func (a Api) RunWarehouseSync(c *gin.Context) {
+	run, err := a.blnk.RunWarehouseSync(c.Request.Context())
+	if err != nil {
+		logrus.Error(err)
+		switch {
+		case strings.Contains(err.Error(), "not configured"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case strings.Contains(err.Error(), "failed to acquire lock"):
+			c.JSON(http.StatusConflict, gin.H{"error": "A warehouse sync is already running"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "run": run})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
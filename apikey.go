@@ -2,8 +2,11 @@ package blnk
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
 	"github.com/blnkfinance/blnk/model"
 )
 
@@ -23,17 +26,18 @@ func (l *Blnk) CreateAPIKey(ctx context.Context, name, ownerID string, scopes []
 	return l.datasource.CreateAPIKey(ctx, name, ownerID, scopes, expiresAt)
 }
 
-// ListAPIKeys retrieves all API keys for a specific owner
+// ListAPIKeys retrieves a page of the API keys of a specific owner, newest first
 //
 // Parameters:
 // - ctx: The context for the operation
 // - ownerID: ID of the key owner
+// - page: The page of API keys to read
 //
 // Returns:
 // - []*model.APIKey: List of API keys
 // - error: An error if the operation fails
-func (l *Blnk) ListAPIKeys(ctx context.Context, ownerID string) ([]*model.APIKey, error) {
-	return l.datasource.ListAPIKeys(ctx, ownerID)
+func (l *Blnk) ListAPIKeys(ctx context.Context, ownerID string, page model.Page) ([]*model.APIKey, error) {
+	return l.datasource.ListAPIKeys(ctx, ownerID, page)
 }
 
 // RevokeAPIKey revokes an API key if it belongs to the specified owner
@@ -73,3 +77,136 @@ func (l *Blnk) GetAPIKeyByKey(ctx context.Context, key string) (*model.APIKey, e
 func (l *Blnk) UpdateLastUsed(ctx context.Context, id string) error {
 	return l.datasource.UpdateLastUsed(ctx, id)
 }
+
+// RotateAPIKey replaces an API key with a new one. The old key remains valid for the overlap period
+// so that clients can switch to the new key without downtime.
+//
+// Parameters:
+// - ctx: The context for the operation
+// - id: ID of the API key to rotate
+// - ownerID: ID of the key owner
+// - overlap: How long the old key stays valid. The configured default is used when zero.
+//
+// Returns:
+// - *model.APIKey: The replacement API key
+// - error: An error if the operation fails
+func (l *Blnk) RotateAPIKey(ctx context.Context, id, ownerID string, overlap time.Duration) (*model.APIKey, error) {
+	return l.datasource.RotateAPIKey(ctx, id, ownerID, rotationOverlap(overlap))
+}
+
+// ExpireAPIKeyRotation ends the overlap window of a rotated API key so the old key stops working immediately
+//
+// Parameters:
+// - ctx: The context for the operation
+// - id: ID of the rotated API key
+// - ownerID: ID of the key owner
+//
+// Returns:
+// - error: An error if the operation fails
+func (l *Blnk) ExpireAPIKeyRotation(ctx context.Context, id, ownerID string) error {
+	return l.datasource.ExpireAPIKeyRotation(ctx, id, ownerID)
+}
+
+// UpdateAPIKeyNetworkPolicy sets the IP allow-list and blocked countries of an API key.
+// An empty policy removes all network restrictions from the key.
+//
+// Parameters:
+// - ctx: The context for the operation
+// - id: ID of the API key
+// - ownerID: ID of the key owner
+// - policy: The network policy to apply
+//
+// Returns:
+// - *model.NetworkPolicy: The normalized policy that was saved
+// - error: An error if the policy is invalid or the operation fails
+func (l *Blnk) UpdateAPIKeyNetworkPolicy(ctx context.Context, id, ownerID string, policy model.NetworkPolicy) (*model.NetworkPolicy, error) {
+	normalized, err := policy.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	if err := l.datasource.UpdateAPIKeyNetworkPolicy(ctx, id, ownerID, normalized); err != nil {
+		return nil, err
+	}
+	return &normalized, nil
+}
+
+// UpdateAPIKeyLedgerScope restricts an API key to specific ledgers and balance ID prefixes.
+// An empty scope removes the restriction.
+//
+// Parameters:
+// - ctx: The context for the operation
+// - id: ID of the API key
+// - ownerID: ID of the key owner
+// - scope: The ledgers and balance ID prefixes the key may access
+//
+// Returns:
+// - error: An error if the operation fails
+func (l *Blnk) UpdateAPIKeyLedgerScope(ctx context.Context, id, ownerID string, scope ledgerscope.Scope) error {
+	if scope.LedgerIDs == nil {
+		scope.LedgerIDs = []string{}
+	}
+	if scope.BalancePrefixes == nil {
+		scope.BalancePrefixes = []string{}
+	}
+	return l.datasource.UpdateAPIKeyLedgerScope(ctx, id, ownerID, scope)
+}
+
+// UpdateAPIKeyFieldCompatibility sets the names renamed JSON fields are emitted under for an API key.
+// An empty mode makes the key use the deployment's default.
+//
+// Parameters:
+// - ctx: The context for the operation
+// - id: ID of the API key
+// - ownerID: ID of the key owner
+// - mode: The field compatibility mode: legacy, both or current
+//
+// Returns:
+// - error: An error if the mode is unknown or the operation fails
+func (l *Blnk) UpdateAPIKeyFieldCompatibility(ctx context.Context, id, ownerID, mode string) error {
+	if mode != "" && !model.ValidFieldCompatibility(mode) {
+		return fmt.Errorf("unknown field compatibility mode %q", mode)
+	}
+	return l.datasource.UpdateAPIKeyFieldCompatibility(ctx, id, ownerID, mode)
+}
+
+// RecordAPIKeyAccessDenial saves a request that an API key's network policy rejected
+//
+// Parameters:
+// - ctx: The context for the operation
+// - denial: The rejected request
+//
+// Returns:
+// - error: An error if the operation fails
+func (l *Blnk) RecordAPIKeyAccessDenial(ctx context.Context, denial *model.APIKeyAccessDenial) error {
+	return l.datasource.RecordAPIKeyAccessDenial(ctx, denial)
+}
+
+// ListAPIKeyAccessDenials retrieves the most recent requests rejected by an API key's network policy
+//
+// Parameters:
+// - ctx: The context for the operation
+// - id: ID of the API key
+// - ownerID: ID of the key owner
+// - limit: Maximum number of records to return
+//
+// Returns:
+// - []*model.APIKeyAccessDenial: The rejected requests, newest first
+// - error: An error if the operation fails
+func (l *Blnk) ListAPIKeyAccessDenials(ctx context.Context, id, ownerID string, limit int) ([]*model.APIKeyAccessDenial, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	return l.datasource.ListAPIKeyAccessDenials(ctx, id, ownerID, limit)
+}
+
+// rotationOverlap returns the requested overlap, falling back to the configured default
+func rotationOverlap(overlap time.Duration) time.Duration {
+	if overlap > 0 {
+		return overlap
+	}
+	conf, err := config.Fetch()
+	if err != nil || conf.SecretRotation.OverlapPeriod <= 0 {
+		return 24 * time.Hour
+	}
+	return conf.SecretRotation.OverlapPeriod
+}
@@ -0,0 +1,332 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const authorizationVelocityKeyPrefix = "authorization-velocity"
+
+// EventLimitWarning is sent when an authorization is approved despite exceeding soft authorization rules.
+const EventLimitWarning = "limit.warning"
+
+// ErrInvalidAuthorization is returned when a balance authorization request is incomplete.
+var ErrInvalidAuthorization = errors.New("invalid authorization")
+
+// authorizationVelocityScript reserves an authorization against the velocity limits of the rules it matches in
+// one round trip. KEYS holds a count key and a total key per rule; ARGV holds the amount, then each rule's count
+// limit, total limit, window in milliseconds and whether the rule is soft. Nothing is reserved unless every hard
+// rule allows it, and {i} is returned with the 1-based index of the first hard rule that does not. Otherwise {0}
+// is returned, followed by the indexes of the soft rules the authorization exceeds, whose counters are reserved
+// like the others.
+var authorizationVelocityScript = redis.NewScript(`
+local amount = tonumber(ARGV[1])
+local rules = #KEYS / 2
+local exceeded = {0}
+for i = 1, rules do
+	local maxCount = tonumber(ARGV[i * 4 - 2])
+	local maxTotal = tonumber(ARGV[i * 4 - 1])
+	local soft = ARGV[i * 4 + 1] == '1'
+	local over = (maxCount > 0 and tonumber(redis.call('GET', KEYS[i * 2 - 1]) or '0') + 1 > maxCount) or
+		(maxTotal > 0 and tonumber(redis.call('GET', KEYS[i * 2]) or '0') + amount > maxTotal)
+	if over then
+		if not soft then
+			return {i}
+		end
+		table.insert(exceeded, i)
+	end
+end
+for i = 1, rules do
+	local window = ARGV[i * 4]
+	redis.call('INCR', KEYS[i * 2 - 1])
+	redis.call('PEXPIRE', KEYS[i * 2 - 1], window)
+	redis.call('INCRBY', KEYS[i * 2], ARGV[1])
+	redis.call('PEXPIRE', KEYS[i * 2], window)
+end
+return exceeded
+`)
+
+// authorizationReleaseScript gives back a reservation made by authorizationVelocityScript, for authorizations
+// declined after their limits were checked. Counters that already expired with their window are left alone.
+var authorizationReleaseScript = redis.NewScript(`
+for i = 1, #KEYS / 2 do
+	if redis.call('EXISTS', KEYS[i * 2 - 1]) == 1 then
+		redis.call('DECR', KEYS[i * 2 - 1])
+	end
+	if redis.call('EXISTS', KEYS[i * 2]) == 1 then
+		redis.call('DECRBY', KEYS[i * 2], ARGV[1])
+	end
+end
+return 0
+`)
+
+// authorizationPosting is the outcome of placing an authorization's hold.
+type authorizationPosting struct {
+	hold *model.Transaction
+	err  error
+}
+
+// AuthorizeBalance decides a card authorization against a balance in one call. It checks the amount and velocity
+// limits of the authorization rules the payment matches, then holds the amount with an inflight transaction from
+// source to destination, which declines the payment if the source's available balance cannot cover it. Built for
+// card authorization, it posts the hold directly rather than through the queue, and skips the splitting, netting
+// and challenges of QueueTransaction.
+//
+// Soft rules do not decline: an authorization exceeding them is decided as if they were not there, and when
+// approved its hold lists them under LimitWarningsMetaKey and a limit.warning event is sent.
+//
+// Authorizations not decided within the configured latency budget are declined. A hold that is still being
+// placed when the budget runs out is left to finish, so that its balances stay consistent, and is voided once
+// placed. Approved holds are committed or voided like any inflight transaction, and expire after the card
+// authorization default expiry unless the payment sets its own InflightExpiryDate.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - transaction *model.Transaction: The payment to authorize. Source, destination, currency, reference and a
+// positive amount are required.
+//
+// Returns:
+// - *model.BalanceAuthorization: The decision, approved or declined.
+// - error: ErrInvalidAuthorization if the payment is incomplete, or an error if it could not be decided.
+func (l *Blnk) AuthorizeBalance(ctx context.Context, transaction *model.Transaction) (*model.BalanceAuthorization, error) {
+	started := time.Now()
+	ctx, span := tracer.Start(ctx, "AuthorizeBalance")
+	defer span.End()
+
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Source == "" || transaction.Destination == "" || transaction.Currency == "" || transaction.Reference == "" {
+		return nil, fmt.Errorf("%w: source, destination, currency and reference are required", ErrInvalidAuthorization)
+	}
+
+	transaction.Inflight = true
+	transaction.SkipQueue = true
+	transaction.Status = StatusQueued
+	if transaction.InflightExpiryDate.IsZero() {
+		transaction.InflightExpiryDate = started.Add(cnf.CardAuthorization.DefaultExpiry)
+	}
+	setTransactionMetadata(transaction)
+	if transaction.PreciseAmount == nil || transaction.PreciseAmount.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: amount must be greater than zero", ErrInvalidAuthorization)
+	}
+	if err := l.CheckTransactionMaintenance(ctx, transaction); err != nil {
+		return nil, err
+	}
+
+	decision := &model.BalanceAuthorization{
+		Reference:     transaction.Reference,
+		Source:        transaction.Source,
+		Destination:   transaction.Destination,
+		Currency:      transaction.Currency,
+		PreciseAmount: transaction.PreciseAmount,
+	}
+	decide := func(code, reason, rule string) *model.BalanceAuthorization {
+		decision.DeclineCode, decision.DeclineReason, decision.Rule = code, reason, rule
+		decision.ElapsedMs = float64(time.Since(started).Microseconds()) / 1000
+		return decision
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cnf.Authorization.LatencyBudget)
+	defer cancel()
+
+	rules := authorizationRulesFor(cnf.Authorization.Rules, transaction)
+	for _, rule := range rules {
+		if rule.MaxAmount > 0 && transaction.PreciseAmount.Cmp(authorizationLimit(rule.MaxAmount, transaction.Precision)) > 0 {
+			reason := fmt.Sprintf("amount exceeds the limit of %v per authorization", rule.MaxAmount)
+			if !rule.IsSoft() {
+				return decide(model.DeclineAmountLimit, reason, rule.Name), nil
+			}
+			decision.Warnings = append(decision.Warnings, model.LimitWarning{Rule: rule.Name, Code: model.DeclineAmountLimit, Reason: reason})
+		}
+	}
+
+	reserved, declinedBy, exceeded, err := l.reserveAuthorizationVelocity(ctx, rules, transaction, started)
+	if err != nil {
+		span.RecordError(err)
+		if ctx.Err() != nil {
+			return decide(model.DeclineTimeout, "authorization was not decided within its latency budget", ""), nil
+		}
+		return nil, err
+	}
+	if declinedBy != nil {
+		return decide(model.DeclineVelocityLimit, velocityLimitReason(declinedBy), declinedBy.Name), nil
+	}
+	for _, rule := range exceeded {
+		decision.Warnings = append(decision.Warnings, model.LimitWarning{Rule: rule.Name, Code: model.DeclineVelocityLimit, Reason: velocityLimitReason(rule)})
+	}
+	if len(decision.Warnings) > 0 {
+		transaction.MetaData[model.LimitWarningsMetaKey] = decision.Warnings
+	}
+
+	// The hold is placed without the latency budget, so that a posting cut short cannot leave its balances
+	// updated without the transaction, and the budget is enforced while waiting for it instead
+	posted := make(chan authorizationPosting, 1)
+	go func() {
+		hold, err := l.RecordTransaction(context.WithoutCancel(ctx), transaction)
+		posted <- authorizationPosting{hold: hold, err: err}
+	}()
+
+	var posting authorizationPosting
+	select {
+	case posting = <-posted:
+	case <-ctx.Done():
+		l.releaseAuthorizationVelocity(reserved, transaction)
+		go l.voidAbandonedHold(posted)
+		return decide(model.DeclineTimeout, "authorization was not decided within its latency budget", ""), nil
+	}
+
+	if posting.err != nil {
+		l.releaseAuthorizationVelocity(reserved, transaction)
+		if code := authorizationDeclineCode(posting.err); code != "" {
+			return decide(code, posting.err.Error(), ""), nil
+		}
+		span.RecordError(posting.err)
+		return nil, posting.err
+	}
+
+	if err := l.queue.QueueInflightExpiry(ctx, posting.hold); err != nil {
+		span.RecordError(err)
+		logrus.WithError(err).WithField("transaction_id", posting.hold.TransactionID).Error("failed to schedule the expiry of an authorization hold")
+	}
+	l.recordTransactionUsage(ctx, posting.hold)
+
+	decision.Approved = true
+	decision.TransactionID = posting.hold.TransactionID
+	decision.ExpiresAt = posting.hold.InflightExpiryDate
+	decide("", "", "")
+	if len(decision.Warnings) > 0 {
+		l.sendLimitWarning(decision)
+	}
+	return decision, nil
+}
+
+// velocityLimitReason explains why a payment exceeds the velocity limits of a rule.
+func velocityLimitReason(rule *config.AuthorizationRule) string {
+	return fmt.Sprintf("balance exceeds the authorizations allowed within %s", rule.Window)
+}
+
+// sendLimitWarning sends the limit.warning event for an authorization approved despite exceeding soft limits.
+func (l *Blnk) sendLimitWarning(decision *model.BalanceAuthorization) {
+	payload := *decision
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: EventLimitWarning, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
+
+// authorizationRulesFor returns the authorization rules a payment matches: those for its currency, or for any
+// currency, that list its source or no sources at all.
+func authorizationRulesFor(rules []config.AuthorizationRule, transaction *model.Transaction) []*config.AuthorizationRule {
+	var matched []*config.AuthorizationRule
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Currency != "" && !strings.EqualFold(rule.Currency, transaction.Currency) {
+			continue
+		}
+		if len(rule.Sources) > 0 && !containsString(rule.Sources, transaction.Source) {
+			continue
+		}
+		matched = append(matched, rule)
+	}
+	return matched
+}
+
+// authorizationLimit converts a limit in major units to the precise units of a payment.
+func authorizationLimit(limit, precision float64) *big.Int {
+	precise, _ := new(big.Float).Mul(big.NewFloat(limit), big.NewFloat(precision)).Int(nil)
+	return precise
+}
+
+// reserveAuthorizationVelocity counts a payment against the velocity limits of the rules it matches, in fixed
+// windows per source balance. It returns the keys of the counters reserved and the soft rules whose limits the
+// payment exceeds, or the hard rule whose limits the payment would exceed, in which case nothing is reserved.
+// Totals are kept in the precise units of the payments.
+func (l *Blnk) reserveAuthorizationVelocity(ctx context.Context, rules []*config.AuthorizationRule, transaction *model.Transaction, at time.Time) ([]string, *config.AuthorizationRule, []*config.AuthorizationRule, error) {
+	var velocityRules []*config.AuthorizationRule
+	keys := make([]string, 0, len(rules)*2)
+	args := make([]interface{}, 1, len(rules)*4+1)
+	args[0] = transaction.PreciseAmount.String()
+	for _, rule := range rules {
+		if rule.MaxCount <= 0 && rule.MaxTotal <= 0 {
+			continue
+		}
+		velocityRules = append(velocityRules, rule)
+		// The source is a hash tag, so that the counters of a balance stay on one cluster node for the script
+		prefix := fmt.Sprintf("%s:{%s}:%s:%d", authorizationVelocityKeyPrefix, transaction.Source, rule.Name, at.UnixNano()/int64(rule.Window))
+		maxTotal := "0"
+		if rule.MaxTotal > 0 {
+			maxTotal = authorizationLimit(rule.MaxTotal, transaction.Precision).String()
+		}
+		soft := "0"
+		if rule.IsSoft() {
+			soft = "1"
+		}
+		keys = append(keys, prefix+":count", prefix+":total")
+		args = append(args, rule.MaxCount, maxTotal, strconv.FormatInt(rule.Window.Milliseconds(), 10), soft)
+	}
+	if len(velocityRules) == 0 {
+		return nil, nil, nil, nil
+	}
+
+	result, err := authorizationVelocityScript.Run(ctx, l.redis, keys, args...).Int64Slice()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to check authorization velocity: %w", err)
+	}
+	if result[0] > 0 {
+		return nil, velocityRules[result[0]-1], nil, nil
+	}
+	var exceeded []*config.AuthorizationRule
+	for _, i := range result[1:] {
+		exceeded = append(exceeded, velocityRules[i-1])
+	}
+	return keys, nil, exceeded, nil
+}
+
+// releaseAuthorizationVelocity gives back the velocity counters reserved for a payment that was declined.
+func (l *Blnk) releaseAuthorizationVelocity(keys []string, transaction *model.Transaction) {
+	if len(keys) == 0 {
+		return
+	}
+	if err := authorizationReleaseScript.Run(context.Background(), l.redis, keys, transaction.PreciseAmount.String()).Err(); err != nil {
+		logrus.WithError(err).WithField("reference", transaction.Reference).Error("failed to release authorization velocity")
+	}
+}
+
+// voidAbandonedHold waits for the hold of an authorization declined for exceeding its latency budget and voids
+// it once placed.
+func (l *Blnk) voidAbandonedHold(posted <-chan authorizationPosting) {
+	posting := <-posted
+	if posting.err != nil {
+		return
+	}
+	ctx := WithStatusReason(context.Background(), model.ReasonInflightVoided, "authorization exceeded its latency budget")
+	if _, err := l.VoidInflightTransaction(ctx, posting.hold.TransactionID); err != nil {
+		logrus.WithError(err).WithField("transaction_id", posting.hold.TransactionID).Error("failed to void the hold of a timed out authorization")
+	}
+}
+
+// authorizationDeclineCode returns the code a payment is declined with when placing its hold failed because of
+// its source balance, or an empty string when the failure is not a decline.
+func authorizationDeclineCode(err error) string {
+	if code := model.RejectionReasonCode(err.Error()); code != model.ReasonRejected {
+		return code
+	}
+	if strings.Contains(err.Error(), "is frozen") {
+		return model.DeclineBalanceFrozen
+	}
+	return ""
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func useAuthorizationRules(t *testing.T, rules ...config.AuthorizationRule) {
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	updated := *cnf
+	updated.Authorization = config.AuthorizationConfig{LatencyBudget: time.Second, Rules: rules}
+	updated.CardAuthorization.DefaultExpiry = time.Hour
+	config.ConfigStore.Store(&updated)
+}
+
+func authorizationPayment(amount float64) *model.Transaction {
+	return &model.Transaction{
+		Reference:   fmt.Sprintf("auth_%d", time.Now().UnixNano()),
+		Source:      "bln_card",
+		Destination: "bln_merchant",
+		Currency:    "USD",
+		Amount:      amount,
+		Precision:   100,
+	}
+}
+
+func TestAuthorizeBalance_Validation(t *testing.T) {
+	b, _, _ := newBalanceNotificationTestBlnk(t)
+	useAuthorizationRules(t)
+
+	_, err := b.AuthorizeBalance(context.Background(), &model.Transaction{Source: "bln_card", Destination: "bln_merchant", Currency: "USD", Amount: 10})
+	assert.True(t, errors.Is(err, ErrInvalidAuthorization))
+
+	_, err = b.AuthorizeBalance(context.Background(), authorizationPayment(0))
+	assert.True(t, errors.Is(err, ErrInvalidAuthorization))
+}
+
+func TestAuthorizeBalance_AmountLimit(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	useAuthorizationRules(t,
+		config.AuthorizationRule{Name: "eur-cap", Currency: "EUR", MaxAmount: 1},
+		config.AuthorizationRule{Name: "usd-cap", Currency: "USD", MaxAmount: 500},
+	)
+
+	decision, err := b.AuthorizeBalance(context.Background(), authorizationPayment(500.01))
+	require.NoError(t, err)
+	assert.False(t, decision.Approved)
+	assert.Equal(t, model.DeclineAmountLimit, decision.DeclineCode)
+	assert.Equal(t, "usd-cap", decision.Rule)
+	assert.Equal(t, "50001", decision.PreciseAmount.String())
+	mockDS.AssertNotCalled(t, "TransactionExistsByRef", mock.Anything, mock.Anything)
+}
+
+func TestReserveAuthorizationVelocity(t *testing.T) {
+	b, _, mr := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+	rules := []*config.AuthorizationRule{
+		{Name: "cap", MaxAmount: 1000},
+		{Name: "hourly", MaxCount: 2, MaxTotal: 100, Window: time.Hour},
+	}
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	first := authorizationPayment(60)
+	setTransactionMetadata(first)
+	keys, declined, _, err := b.reserveAuthorizationVelocity(ctx, rules, first, at)
+	require.NoError(t, err)
+	assert.Nil(t, declined)
+	require.Len(t, keys, 2)
+	total, err := mr.Get(keys[1])
+	require.NoError(t, err)
+	assert.Equal(t, "6000", total)
+
+	// A second payment would take the hourly total above 100
+	second := authorizationPayment(50)
+	setTransactionMetadata(second)
+	_, declined, _, err = b.reserveAuthorizationVelocity(ctx, rules, second, at)
+	require.NoError(t, err)
+	require.NotNil(t, declined)
+	assert.Equal(t, "hourly", declined.Name)
+
+	// Releasing the first payment makes room again, and the next window starts afresh
+	b.releaseAuthorizationVelocity(keys, first)
+	_, declined, _, err = b.reserveAuthorizationVelocity(ctx, rules, second, at)
+	require.NoError(t, err)
+	assert.Nil(t, declined)
+	_, declined, _, err = b.reserveAuthorizationVelocity(ctx, rules, second, at.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, declined)
+}
+
+func TestReserveAuthorizationVelocity_SoftRule(t *testing.T) {
+	b, _, _ := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+	rules := []*config.AuthorizationRule{
+		{Name: "hourly", MaxTotal: 1000, Window: time.Hour},
+		{Name: "new-hourly", MaxCount: 1, Window: time.Hour, Enforcement: config.EnforcementSoft},
+	}
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	first := authorizationPayment(60)
+	setTransactionMetadata(first)
+	keys, declined, exceeded, err := b.reserveAuthorizationVelocity(ctx, rules, first, at)
+	require.NoError(t, err)
+	assert.Nil(t, declined)
+	assert.Empty(t, exceeded)
+	require.Len(t, keys, 4)
+
+	// The second payment exceeds the soft count but is still reserved against every rule
+	second := authorizationPayment(60)
+	setTransactionMetadata(second)
+	keys, declined, exceeded, err = b.reserveAuthorizationVelocity(ctx, rules, second, at)
+	require.NoError(t, err)
+	assert.Nil(t, declined)
+	require.Len(t, exceeded, 1)
+	assert.Equal(t, "new-hourly", exceeded[0].Name)
+	require.Len(t, keys, 4)
+}
+
+func TestAuthorizeBalance_SoftAmountLimit(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	useAuthorizationRules(t,
+		config.AuthorizationRule{Name: "usd-cap", Currency: "USD", MaxAmount: 500},
+		config.AuthorizationRule{Name: "usd-new-cap", Currency: "USD", MaxAmount: 50, Enforcement: config.EnforcementSoft},
+	)
+
+	card := &model.Balance{BalanceID: "bln_card", Currency: "USD", Balance: big.NewInt(10000), CreditBalance: big.NewInt(10000), DebitBalance: big.NewInt(0), InflightBalance: big.NewInt(0), InflightCreditBalance: big.NewInt(0), InflightDebitBalance: big.NewInt(0)}
+	merchant := &model.Balance{BalanceID: "bln_merchant", Currency: "USD", Balance: big.NewInt(0), CreditBalance: big.NewInt(0), DebitBalance: big.NewInt(0), InflightBalance: big.NewInt(0), InflightCreditBalance: big.NewInt(0), InflightDebitBalance: big.NewInt(0)}
+	mockDS.On("ListBalanceShardings", mock.Anything).Return([]*model.BalanceSharding{}, nil)
+	mockDS.On("ListMinimumBalances", mock.Anything, model.Page{}).Return([]*model.MinimumBalance{}, nil)
+	mockDS.On("ListPostingRules", mock.Anything).Return([]*model.PostingRules{}, nil)
+	mockDS.On("TransactionExistsByRef", mock.Anything, mock.Anything).Return(false, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_card").Return(card, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_merchant").Return(merchant, nil)
+	mockDS.On("UpdateBalances", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDS.On("GetBalanceMonitors", mock.Anything, mock.Anything).Return([]model.BalanceMonitor{}, nil)
+	mockDS.On("GetBalanceByID", mock.Anything, mock.Anything, mock.Anything, false).Return(card, nil)
+	mockDS.On("ListBalanceNotificationPreferences", mock.Anything).Return([]*model.BalanceNotificationPreference{}, nil).Maybe()
+
+	var recorded *model.Transaction
+	mockDS.On("RecordTransaction", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(*model.Transaction)
+	}).Return(&model.Transaction{TransactionID: "txn_hold", Status: StatusInflight, InflightExpiryDate: time.Now().Add(time.Hour)}, nil)
+
+	decision, err := b.AuthorizeBalance(context.Background(), authorizationPayment(60))
+	require.NoError(t, err)
+	assert.True(t, decision.Approved)
+	assert.Empty(t, decision.DeclineCode)
+	require.Len(t, decision.Warnings, 1)
+	assert.Equal(t, "usd-new-cap", decision.Warnings[0].Rule)
+	assert.Equal(t, model.DeclineAmountLimit, decision.Warnings[0].Code)
+
+	require.NotNil(t, recorded)
+	assert.Equal(t, decision.Warnings, recorded.MetaData[model.LimitWarningsMetaKey])
+	assert.Eventually(t, func() bool {
+		tasks, _ := mr.List("asynq:{webhook_queue}:pending")
+		for _, task := range tasks {
+			if strings.Contains(mr.HGet("asynq:{webhook_queue}:t:"+task, "msg"), EventLimitWarning) {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+
+	// Hard rules still decline
+	declined, err := b.AuthorizeBalance(context.Background(), authorizationPayment(600))
+	require.NoError(t, err)
+	assert.False(t, declined.Approved)
+	assert.Equal(t, "usd-cap", declined.Rule)
+}
+
+func TestAuthorizationDeclineCode(t *testing.T) {
+	assert.Equal(t, model.ReasonInsufficientFunds, authorizationDeclineCode(errors.New("failed to apply transaction to balances: insufficient funds in source balance")))
+	assert.Equal(t, model.DeclineBalanceFrozen, authorizationDeclineCode(errors.New("balance bln_1 is frozen for dormancy and must be reactivated first")))
+	assert.Empty(t, authorizationDeclineCode(errors.New("reference ref_1 has already been used")))
+}
+
+func TestAuthorizeBalance_ApprovesAndDeclines(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	useAuthorizationRules(t, config.AuthorizationRule{Name: "daily", Currency: "USD", MaxCount: 5, Window: 24 * time.Hour})
+
+	card := &model.Balance{BalanceID: "bln_card", Currency: "USD", Balance: big.NewInt(10000), CreditBalance: big.NewInt(10000), DebitBalance: big.NewInt(0), InflightBalance: big.NewInt(0), InflightCreditBalance: big.NewInt(0), InflightDebitBalance: big.NewInt(0)}
+	merchant := &model.Balance{BalanceID: "bln_merchant", Currency: "USD", Balance: big.NewInt(0), CreditBalance: big.NewInt(0), DebitBalance: big.NewInt(0), InflightBalance: big.NewInt(0), InflightCreditBalance: big.NewInt(0), InflightDebitBalance: big.NewInt(0)}
+	mockDS.On("ListBalanceShardings", mock.Anything).Return([]*model.BalanceSharding{}, nil)
+	mockDS.On("ListMinimumBalances", mock.Anything, model.Page{}).Return([]*model.MinimumBalance{}, nil)
+	mockDS.On("ListPostingRules", mock.Anything).Return([]*model.PostingRules{}, nil)
+	mockDS.On("TransactionExistsByRef", mock.Anything, mock.Anything).Return(false, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_card").Return(card, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_merchant").Return(merchant, nil)
+	mockDS.On("UpdateBalances", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDS.On("GetBalanceMonitors", mock.Anything, mock.Anything).Return([]model.BalanceMonitor{}, nil)
+	mockDS.On("GetBalanceByID", mock.Anything, mock.Anything, mock.Anything, false).Return(card, nil)
+	mockDS.On("RecordTransaction", mock.Anything, mock.Anything).Return(&model.Transaction{TransactionID: "txn_hold", Status: StatusInflight, InflightExpiryDate: time.Now().Add(time.Hour)}, nil)
+	mockDS.On("ListBalanceNotificationPreferences", mock.Anything).Return([]*model.BalanceNotificationPreference{}, nil).Maybe()
+
+	approved, err := b.AuthorizeBalance(context.Background(), authorizationPayment(60))
+	require.NoError(t, err)
+	assert.True(t, approved.Approved)
+	assert.Equal(t, "txn_hold", approved.TransactionID)
+	assert.False(t, approved.ExpiresAt.IsZero())
+
+	declined, err := b.AuthorizeBalance(context.Background(), authorizationPayment(60))
+	require.NoError(t, err)
+	assert.False(t, declined.Approved)
+	assert.Equal(t, model.ReasonInsufficientFunds, declined.DeclineCode)
+}
@@ -60,7 +60,7 @@ func (l *Blnk) checkBalanceMonitors(ctx context.Context, updatedBalance *model.B
 	defer span.End()
 
 	// Fetch monitors for this balance using datasource
-	monitors, err := l.datasource.GetBalanceMonitors(updatedBalance.BalanceID)
+	monitors, err := l.datasource.GetBalanceMonitors(ctx, updatedBalance.BalanceID)
 	if err != nil {
 		span.RecordError(err)
 		notification.NotifyError(err)
@@ -109,7 +109,7 @@ func (l *Blnk) getOrCreateBalanceByIndicator(ctx context.Context, indicator, cur
 		return nil, err
 	}
 
-	balance, err := l.datasource.GetBalanceByIndicator(indicator, currency)
+	balance, err := l.datasource.GetBalanceByIndicator(ctx, indicator, currency)
 	if err != nil {
 		span.AddEvent("Creating new balance")
 		balance = &model.Balance{
@@ -122,7 +122,7 @@ func (l *Blnk) getOrCreateBalanceByIndicator(ctx context.Context, indicator, cur
 			span.RecordError(err)
 			return nil, err
 		}
-		balance, err = l.datasource.GetBalanceByIndicator(indicator, currency)
+		balance, err = l.datasource.GetBalanceByIndicator(ctx, indicator, currency)
 		if err != nil {
 			span.RecordError(err)
 			return nil, err
@@ -131,7 +131,7 @@ func (l *Blnk) getOrCreateBalanceByIndicator(ctx context.Context, indicator, cur
 
 		// If queued checks are enabled, fetch the balance with queued data
 		if cfg.Transaction.EnableQueuedChecks {
-			balance, err = l.datasource.GetBalanceByID(balance.BalanceID, []string{}, true)
+			balance, err = l.datasource.GetBalanceByID(ctx, balance.BalanceID, []string{}, true)
 			if err != nil {
 				span.RecordError(err)
 				return nil, err
@@ -143,7 +143,7 @@ func (l *Blnk) getOrCreateBalanceByIndicator(ctx context.Context, indicator, cur
 
 	// If queued checks are enabled, fetch the balance with queued data
 	if cfg.Transaction.EnableQueuedChecks {
-		balance, err = l.datasource.GetBalanceByID(balance.BalanceID, []string{}, true)
+		balance, err = l.datasource.GetBalanceByID(ctx, balance.BalanceID, []string{}, true)
 		if err != nil {
 			span.RecordError(err)
 			return nil, err
@@ -183,7 +183,10 @@ func (l *Blnk) postBalanceActions(ctx context.Context, balance *model.Balance) {
 }
 
 // CreateBalance creates a new balance.
-// It starts a tracing span, creates the balance, and performs post-creation actions.
+// It starts a tracing span, creates the balance, and performs post-creation actions. Balances of identities
+// flagged by sanctions screening are rejected with ErrIdentityFlagged when such balances are blocked, and those of
+// identities without the verified contact details balances require with ErrContactNotVerified. Balances of a
+// ledger in maintenance mode are rejected with a *MaintenanceError.
 //
 // Parameters:
 // - ctx context.Context: The context for the operation.
@@ -196,7 +199,19 @@ func (l *Blnk) CreateBalance(ctx context.Context, balance model.Balance) (model.
 	ctx, span := balanceTracer.Start(ctx, "CreateBalance")
 	defer span.End()
 
-	balance, err := l.datasource.CreateBalance(balance)
+	if err := l.checkLedgerMaintenance(ctx, balance.LedgerID); err != nil {
+		span.RecordError(err)
+		return model.Balance{}, err
+	}
+	if err := l.checkIdentityScreening(ctx, balance.IdentityID); err != nil {
+		span.RecordError(err)
+		return model.Balance{}, err
+	}
+	if err := l.checkContactVerification(ctx, balance.IdentityID); err != nil {
+		span.RecordError(err)
+		return model.Balance{}, err
+	}
+	balance, err := l.datasource.CreateBalance(ctx, balance)
 	if err != nil {
 		span.RecordError(err)
 		return model.Balance{}, err
@@ -221,33 +236,44 @@ func (l *Blnk) GetBalanceByID(ctx context.Context, id string, include []string,
 	_, span := balanceTracer.Start(ctx, "GetBalanceByID")
 	defer span.End()
 
-	balance, err := l.datasource.GetBalanceByID(id, include, withQueued)
+	balance, err := l.datasource.GetBalanceByID(ctx, id, include, withQueued)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
+	if err := l.aggregateShards(ctx, balance); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
 	span.AddEvent("Balance retrieved", trace.WithAttributes(attribute.String("balance.id", id)))
 	return balance, nil
 }
 
-// GetAllBalances retrieves all balances.
-// It starts a tracing span, fetches all balances, and records relevant events.
+// GetAllBalances retrieves a page of balances, newest first.
+// It starts a tracing span, fetches the balances, and records relevant events.
 //
 // Parameters:
 // - ctx context.Context: The context for the operation.
+// - page model.Page: The page of balances to read.
 //
 // Returns:
 // - []model.Balance: A slice of Balance models.
 // - error: An error if the balances could not be retrieved.
-func (l *Blnk) GetAllBalances(ctx context.Context, limit, offset int) ([]model.Balance, error) {
+func (l *Blnk) GetAllBalances(ctx context.Context, page model.Page) ([]model.Balance, error) {
 	_, span := balanceTracer.Start(ctx, "GetAllBalances")
 	defer span.End()
 
-	balances, err := l.datasource.GetAllBalances(limit, offset)
+	balances, err := l.datasource.GetAllBalances(ctx, page)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
+	for i := range balances {
+		if err := l.aggregateShards(ctx, &balances[i]); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+	}
 	span.AddEvent("All balances retrieved", trace.WithAttributes(attribute.Int("balance.count", len(balances))))
 	return balances, nil
 }
@@ -267,10 +293,8 @@ func (l *Blnk) CreateMonitor(ctx context.Context, monitor model.BalanceMonitor)
 	_, span := balanceTracer.Start(ctx, "CreateMonitor")
 	defer span.End()
 
-	amount := int64(monitor.Condition.Value * monitor.Condition.Precision) // apply precision to value
-	amountBigInt := model.Int64ToBigInt(amount)
-	monitor.Condition.PreciseValue = amountBigInt
-	monitor, err := l.datasource.CreateMonitor(monitor)
+	monitor.Condition.PreciseValue = model.MoneyFromFloat(monitor.Condition.Value, "", monitor.Condition.Precision).Amount
+	monitor, err := l.datasource.CreateMonitor(ctx, monitor)
 	if err != nil {
 		span.RecordError(err)
 		return model.BalanceMonitor{}, err
@@ -293,7 +317,7 @@ func (l *Blnk) GetMonitorByID(ctx context.Context, id string) (*model.BalanceMon
 	_, span := balanceTracer.Start(ctx, "GetMonitorByID")
 	defer span.End()
 
-	monitor, err := l.datasource.GetMonitorByID(id)
+	monitor, err := l.datasource.GetMonitorByID(ctx, id)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -315,7 +339,7 @@ func (l *Blnk) GetAllMonitors(ctx context.Context) ([]model.BalanceMonitor, erro
 	_, span := balanceTracer.Start(ctx, "GetAllMonitors")
 	defer span.End()
 
-	monitors, err := l.datasource.GetAllMonitors()
+	monitors, err := l.datasource.GetAllMonitors(ctx)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -338,7 +362,7 @@ func (l *Blnk) GetBalanceMonitors(ctx context.Context, balanceID string) ([]mode
 	_, span := balanceTracer.Start(ctx, "GetBalanceMonitors")
 	defer span.End()
 
-	monitors, err := l.datasource.GetBalanceMonitors(balanceID)
+	monitors, err := l.datasource.GetBalanceMonitors(ctx, balanceID)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -360,7 +384,7 @@ func (l *Blnk) UpdateMonitor(ctx context.Context, monitor *model.BalanceMonitor)
 	_, span := balanceTracer.Start(ctx, "UpdateMonitor")
 	defer span.End()
 
-	err := l.datasource.UpdateMonitor(monitor)
+	err := l.datasource.UpdateMonitor(ctx, monitor)
 	if err != nil {
 		span.RecordError(err)
 		return err
@@ -382,7 +406,7 @@ func (l *Blnk) DeleteMonitor(ctx context.Context, id string) error {
 	_, span := balanceTracer.Start(ctx, "DeleteMonitor")
 	defer span.End()
 
-	err := l.datasource.DeleteMonitor(id)
+	err := l.datasource.DeleteMonitor(ctx, id)
 	if err != nil {
 		span.RecordError(err)
 		return err
@@ -531,7 +555,7 @@ func (l *Blnk) GetBalanceByIndicator(ctx context.Context, indicator, currency st
 		attribute.String("balance.currency", currency),
 	)
 
-	balance, err := l.datasource.GetBalanceByIndicator(indicator, currency)
+	balance, err := l.datasource.GetBalanceByIndicator(ctx, indicator, currency)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -545,26 +569,27 @@ func (l *Blnk) GetBalanceByIndicator(ctx context.Context, indicator, currency st
 // It validates that both the balance and the identity exist before applying the change.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - balanceID string: The ID of the balance whose identity reference should be modified.
 // - identityID string: The new identity ID to associate with the balance.
 //
 // Returns:
 // - error: An error is returned if either the balance or identity records are not found or the update fails.
-func (l *Blnk) UpdateBalanceIdentity(balanceID, identityID string) error {
+func (l *Blnk) UpdateBalanceIdentity(ctx context.Context, balanceID, identityID string) error {
 	// Ensure the referenced identity exists
-	_, err := l.datasource.GetIdentityByID(identityID)
+	_, err := l.datasource.GetIdentityByID(ctx, identityID)
 	if err != nil {
 		return fmt.Errorf("identity validation failed: %w", err)
 	}
 
 	// Ensure the balance exists (lite lookup)
-	_, err = l.datasource.GetBalanceByIDLite(balanceID)
+	_, err = l.datasource.GetBalanceByIDLite(ctx, balanceID)
 	if err != nil {
 		return fmt.Errorf("balance validation failed: %w", err)
 	}
 
 	// Apply the update
-	if err := l.datasource.UpdateBalanceIdentity(balanceID, identityID); err != nil {
+	if err := l.datasource.UpdateBalanceIdentity(ctx, balanceID, identityID); err != nil {
 		return err
 	}
 
@@ -0,0 +1,174 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/blnkfinance/blnk/model"
+)
+
+const balanceAliasCacheTTL = 30 * time.Second
+
+// balanceAliasCache keeps resolved aliases in memory so that transactions naming balances by alias do not query
+// them every time. Aliases changed on another instance are picked up within balanceAliasCacheTTL.
+type balanceAliasCache struct {
+	mu      sync.Mutex
+	entries map[string]balanceAliasEntry // Keyed by tenant and alias
+}
+
+type balanceAliasEntry struct {
+	balanceID string // Empty when no balance has the alias
+	loadedAt  time.Time
+}
+
+// CreateBalanceAlias names a balance with an alias, such as @main_usd_float, that transactions can use in place
+// of its ID. The alias belongs to the tenant of the balance and must be unique within it.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - alias model.BalanceAlias: The alias and the balance it names.
+//
+// Returns:
+// - *model.BalanceAlias: The saved alias.
+// - error: An error if the alias is invalid, the balance does not exist, or the alias is already used by a
+// balance of the tenant or a balance indicator.
+func (l *Blnk) CreateBalanceAlias(ctx context.Context, alias model.BalanceAlias) (*model.BalanceAlias, error) {
+	if err := alias.Validate(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, err.Error(), err)
+	}
+
+	alias.CreatedAt = time.Now()
+	alias.UpdatedAt = alias.CreatedAt
+	if err := l.datasource.CreateBalanceAlias(ctx, &alias); err != nil {
+		return nil, err
+	}
+	l.invalidateBalanceAliases()
+	return &alias, nil
+}
+
+// GetBalanceAlias retrieves an alias of the tenant the request acts for.
+func (l *Blnk) GetBalanceAlias(ctx context.Context, alias string) (*model.BalanceAlias, error) {
+	return l.datasource.GetBalanceAlias(ctx, tenant.FromContext(ctx), alias)
+}
+
+// ListBalanceAliases lists the aliases of the tenant the request acts for, in alias order, optionally only those
+// of a balance.
+func (l *Blnk) ListBalanceAliases(ctx context.Context, balanceID string) ([]*model.BalanceAlias, error) {
+	return l.datasource.ListBalanceAliases(ctx, tenant.FromContext(ctx), balanceID)
+}
+
+// UpdateBalanceAlias points an alias at another balance of its tenant, so transactions using it post to that
+// balance from then on.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - name string: The alias.
+// - balanceID string: The ID of the balance to point the alias at.
+//
+// Returns:
+// - *model.BalanceAlias: The updated alias.
+// - error: An error if the alias or the balance is not found, or the alias cannot be updated.
+func (l *Blnk) UpdateBalanceAlias(ctx context.Context, name, balanceID string) (*model.BalanceAlias, error) {
+	alias, err := l.GetBalanceAlias(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	alias.BalanceID = balanceID
+	if err := alias.Validate(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, err.Error(), err)
+	}
+
+	alias.UpdatedAt = time.Now()
+	if err := l.datasource.UpdateBalanceAlias(ctx, alias); err != nil {
+		return nil, err
+	}
+	l.invalidateBalanceAliases()
+	return alias, nil
+}
+
+// DeleteBalanceAlias removes an alias of the tenant the request acts for.
+func (l *Blnk) DeleteBalanceAlias(ctx context.Context, name string) error {
+	alias, err := l.GetBalanceAlias(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := l.datasource.DeleteBalanceAlias(ctx, alias.TenantID, alias.Alias); err != nil {
+		return err
+	}
+	l.invalidateBalanceAliases()
+	return nil
+}
+
+// resolveBalanceAlias returns the ID of the balance an alias names in the tenant the request acts for. Anything
+// that is not an alias of a balance, such as a balance ID or an indicator, is returned as it is. Resolutions are
+// cached for balanceAliasCacheTTL, including those of names that are not aliases.
+func (l *Blnk) resolveBalanceAlias(ctx context.Context, identifier string) (string, error) {
+	if l.aliases == nil || !model.IsBalanceAlias(identifier) {
+		return identifier, nil
+	}
+	key := tenant.FromContext(ctx) + "/" + identifier
+
+	l.aliases.mu.Lock()
+	entry, ok := l.aliases.entries[key]
+	l.aliases.mu.Unlock()
+	if !ok || time.Since(entry.loadedAt) >= balanceAliasCacheTTL {
+		alias, err := l.GetBalanceAlias(ctx, identifier)
+		var apiErr apierror.APIError
+		switch {
+		case err == nil:
+			entry = balanceAliasEntry{balanceID: alias.BalanceID, loadedAt: time.Now()}
+		case errors.As(err, &apiErr) && apiErr.Code == apierror.ErrNotFound:
+			entry = balanceAliasEntry{loadedAt: time.Now()}
+		default:
+			return "", err
+		}
+
+		l.aliases.mu.Lock()
+		if l.aliases.entries == nil {
+			l.aliases.entries = make(map[string]balanceAliasEntry)
+		}
+		l.aliases.entries[key] = entry
+		l.aliases.mu.Unlock()
+	}
+
+	if entry.balanceID == "" {
+		return identifier, nil
+	}
+	return entry.balanceID, nil
+}
+
+// resolveTransactionAliases replaces the aliases a transaction names its balances by, in its source,
+// destination and distributions, with the IDs of the balances. Names that are not aliases, such as indicators,
+// are left for the usual resolution.
+func (l *Blnk) resolveTransactionAliases(ctx context.Context, transaction *model.Transaction) error {
+	identifiers := []*string{&transaction.Source, &transaction.Destination}
+	for i := range transaction.Sources {
+		identifiers = append(identifiers, &transaction.Sources[i].Identifier)
+	}
+	for i := range transaction.Destinations {
+		identifiers = append(identifiers, &transaction.Destinations[i].Identifier)
+	}
+
+	for _, identifier := range identifiers {
+		resolved, err := l.resolveBalanceAlias(ctx, *identifier)
+		if err != nil {
+			return err
+		}
+		*identifier = resolved
+	}
+	return nil
+}
+
+// invalidateBalanceAliases forces aliases to be resolved again.
+func (l *Blnk) invalidateBalanceAliases() {
+	if l.aliases == nil {
+		return
+	}
+	l.aliases.mu.Lock()
+	l.aliases.entries = nil
+	l.aliases.mu.Unlock()
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func aliasNotFound(alias string) error {
+	return apierror.NewAPIError(apierror.ErrNotFound, "Balance alias '"+alias+"' not found", nil)
+}
+
+func TestResolveTransactionAliases(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetBalanceAlias", mock.Anything, "", "@main_usd_float").Return(&model.BalanceAlias{Alias: "@main_usd_float", BalanceID: "bln_float"}, nil).Once()
+	mockDS.On("GetBalanceAlias", mock.Anything, "", "@World").Return(nil, aliasNotFound("@World")).Once()
+
+	txn := &model.Transaction{
+		Source:       "@main_usd_float",
+		Destination:  "@World",
+		Destinations: []model.Distribution{{Identifier: "@main_usd_float"}, {Identifier: "bln_2"}},
+	}
+	require.NoError(t, b.resolveTransactionAliases(context.Background(), txn))
+	assert.Equal(t, "bln_float", txn.Source)
+	assert.Equal(t, "@World", txn.Destination, "names that are not aliases are left for indicator resolution")
+	assert.Equal(t, "bln_float", txn.Destinations[0].Identifier)
+	assert.Equal(t, "bln_2", txn.Destinations[1].Identifier)
+
+	// Resolutions, including of names that are not aliases, are cached
+	again := &model.Transaction{Source: "@main_usd_float", Destination: "@World"}
+	require.NoError(t, b.resolveTransactionAliases(context.Background(), again))
+	assert.Equal(t, "bln_float", again.Source)
+	mockDS.AssertExpectations(t)
+}
+
+func TestResolveBalanceAlias_PerTenant(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetBalanceAlias", mock.Anything, "owner_1", "@float").Return(&model.BalanceAlias{Alias: "@float", BalanceID: "bln_owner_1", TenantID: "owner_1"}, nil)
+	mockDS.On("GetBalanceAlias", mock.Anything, "owner_2", "@float").Return(&model.BalanceAlias{Alias: "@float", BalanceID: "bln_owner_2", TenantID: "owner_2"}, nil)
+
+	resolved, err := b.resolveBalanceAlias(tenant.WithTenant(context.Background(), "owner_1"), "@float")
+	require.NoError(t, err)
+	assert.Equal(t, "bln_owner_1", resolved)
+
+	resolved, err = b.resolveBalanceAlias(tenant.WithTenant(context.Background(), "owner_2"), "@float")
+	require.NoError(t, err)
+	assert.Equal(t, "bln_owner_2", resolved)
+}
+
+func TestCreateBalanceAlias_InvalidatesResolutions(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetBalanceAlias", mock.Anything, "", "@fees").Return(nil, aliasNotFound("@fees")).Once()
+	mockDS.On("CreateBalanceAlias", mock.Anything, mock.MatchedBy(func(alias *model.BalanceAlias) bool {
+		return alias.Alias == "@fees" && alias.BalanceID == "bln_fees" && !alias.CreatedAt.IsZero()
+	})).Return(nil)
+	mockDS.On("GetBalanceAlias", mock.Anything, "", "@fees").Return(&model.BalanceAlias{Alias: "@fees", BalanceID: "bln_fees"}, nil).Once()
+
+	resolved, err := b.resolveBalanceAlias(context.Background(), "@fees")
+	require.NoError(t, err)
+	assert.Equal(t, "@fees", resolved)
+
+	_, err = b.CreateBalanceAlias(context.Background(), model.BalanceAlias{Alias: "@fees", BalanceID: "bln_fees"})
+	require.NoError(t, err)
+
+	resolved, err = b.resolveBalanceAlias(context.Background(), "@fees")
+	require.NoError(t, err)
+	assert.Equal(t, "bln_fees", resolved)
+	mockDS.AssertExpectations(t)
+}
+
+func TestCreateBalanceAlias_Invalid(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	_, err := b.CreateBalanceAlias(context.Background(), model.BalanceAlias{Alias: "main float", BalanceID: "bln_1"})
+	require.Error(t, err)
+	assert.Equal(t, apierror.ErrInvalidInput, err.(apierror.APIError).Code)
+	mockDS.AssertNotCalled(t, "CreateBalanceAlias", mock.Anything, mock.Anything)
+}
+
+func TestUpdateAndDeleteBalanceAlias(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	ctx := tenant.WithTenant(context.Background(), "owner_1")
+	mockDS.On("GetBalanceAlias", mock.Anything, "owner_1", "@float").Return(&model.BalanceAlias{Alias: "@float", BalanceID: "bln_1", TenantID: "owner_1"}, nil)
+	mockDS.On("UpdateBalanceAlias", mock.Anything, mock.MatchedBy(func(alias *model.BalanceAlias) bool {
+		return alias.BalanceID == "bln_2" && alias.TenantID == "owner_1"
+	})).Return(nil)
+	mockDS.On("DeleteBalanceAlias", mock.Anything, "owner_1", "@float").Return(nil)
+
+	alias, err := b.UpdateBalanceAlias(ctx, "@float", "bln_2")
+	require.NoError(t, err)
+	assert.Equal(t, "bln_2", alias.BalanceID)
+	require.NoError(t, b.DeleteBalanceAlias(ctx, "@float"))
+	mockDS.AssertExpectations(t)
+}
+
+func TestCheckTransactionAccess_Alias(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetBalanceAlias", mock.Anything, "", "@loans_float").Return(&model.BalanceAlias{Alias: "@loans_float", BalanceID: "bln_loans_1"}, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_cards_1").Return(&model.Balance{BalanceID: "bln_cards_1", LedgerID: "ldg_cards"}, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_loans_1").Return(&model.Balance{BalanceID: "bln_loans_1", LedgerID: "ldg_loans"}, nil)
+
+	ctx := ledgerscope.WithScope(context.Background(), ledgerscope.Scope{LedgerIDs: []string{"ldg_cards"}})
+	txn := &model.Transaction{Source: "bln_cards_1", Destination: "@loans_float", Currency: "USD"}
+	assert.ErrorIs(t, b.CheckTransactionAccess(ctx, txn), ErrOutsideLedgerScope, "aliases are checked as the balance they name")
+	assert.Equal(t, "@loans_float", txn.Destination, "checking access does not change the transaction")
+}
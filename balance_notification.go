@@ -0,0 +1,295 @@
+package blnk
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	notificationPreferenceCacheTTL = 30 * time.Second
+
+	notificationDigestKeyPrefix = "notification_digest"
+	notificationDigestRetention = 7 * 24 * time.Hour
+)
+
+// notificationPreferenceCache keeps the balance notification preferences in memory so that transaction
+// webhooks do not query them for every posting. Changes made on another instance are picked up within
+// notificationPreferenceCacheTTL.
+type notificationPreferenceCache struct {
+	mu          sync.Mutex
+	preferences map[string]*model.BalanceNotificationPreference
+	loadedAt    time.Time
+}
+
+// notificationPreferences returns the cached preferences keyed by balance ID, reloading them when the cache
+// is stale. A failed reload keeps the previous preferences so that a database hiccup does not drop events.
+func (l *Blnk) notificationPreferences(ctx context.Context) map[string]*model.BalanceNotificationPreference {
+	if l.notifyPrefs == nil {
+		return nil
+	}
+	l.notifyPrefs.mu.Lock()
+	defer l.notifyPrefs.mu.Unlock()
+
+	if time.Since(l.notifyPrefs.loadedAt) < notificationPreferenceCacheTTL {
+		return l.notifyPrefs.preferences
+	}
+	l.notifyPrefs.loadedAt = time.Now()
+
+	preferences, err := l.datasource.ListBalanceNotificationPreferences(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to load balance notification preferences")
+		return l.notifyPrefs.preferences
+	}
+	byBalance := make(map[string]*model.BalanceNotificationPreference, len(preferences))
+	for _, preference := range preferences {
+		byBalance[preference.BalanceID] = preference
+	}
+	l.notifyPrefs.preferences = byBalance
+	return byBalance
+}
+
+// invalidateNotificationPreferences forces the next transaction webhook to reload the preferences.
+func (l *Blnk) invalidateNotificationPreferences() {
+	if l.notifyPrefs == nil {
+		return
+	}
+	l.notifyPrefs.mu.Lock()
+	l.notifyPrefs.loadedAt = time.Time{}
+	l.notifyPrefs.mu.Unlock()
+}
+
+// SetBalanceNotificationPreference creates or replaces the notification preference of a balance.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - preference: The preference to save.
+//
+// Returns:
+// - *model.BalanceNotificationPreference: The saved preference.
+// - error: An error if the preference is invalid, the balance does not exist or the preference could not be saved.
+func (l *Blnk) SetBalanceNotificationPreference(ctx context.Context, preference model.BalanceNotificationPreference) (*model.BalanceNotificationPreference, error) {
+	if preference.Mode == "" {
+		preference.Mode = model.NotificationModeRealtime
+	}
+	if err := preference.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := l.datasource.GetBalanceByIDLite(ctx, preference.BalanceID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	preference.CreatedAt = now
+	if existing, err := l.datasource.GetBalanceNotificationPreference(ctx, preference.BalanceID); err == nil {
+		preference.CreatedAt = existing.CreatedAt
+	}
+	preference.UpdatedAt = now
+
+	if err := l.datasource.UpsertBalanceNotificationPreference(ctx, &preference); err != nil {
+		return nil, err
+	}
+	l.invalidateNotificationPreferences()
+	return &preference, nil
+}
+
+// GetBalanceNotificationPreference retrieves the notification preference of a balance.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - balanceID: The ID of the balance.
+//
+// Returns:
+// - *model.BalanceNotificationPreference: The preference.
+// - error: An error if the balance has no preference.
+func (l *Blnk) GetBalanceNotificationPreference(ctx context.Context, balanceID string) (*model.BalanceNotificationPreference, error) {
+	return l.datasource.GetBalanceNotificationPreference(ctx, balanceID)
+}
+
+// DeleteBalanceNotificationPreference removes the notification preference of a balance, so every posting
+// on it is delivered again.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - balanceID: The ID of the balance.
+//
+// Returns:
+// - error: An error if the balance has no preference or it could not be removed.
+func (l *Blnk) DeleteBalanceNotificationPreference(ctx context.Context, balanceID string) error {
+	if err := l.datasource.DeleteBalanceNotificationPreference(ctx, balanceID); err != nil {
+		return err
+	}
+	l.invalidateNotificationPreferences()
+	return nil
+}
+
+// shouldSendTransactionWebhook applies the notification preferences of the balances a transaction posts to.
+// The event is delivered when either balance has no preference or its preference asks for the posting;
+// rejections are always delivered. Applied postings on balances in digest mode are added to their digest.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - transaction: The transaction the event is about.
+//
+// Returns:
+// - bool: Whether the transaction event should be delivered.
+func (l *Blnk) shouldSendTransactionWebhook(ctx context.Context, transaction *model.Transaction) bool {
+	if strings.EqualFold(transaction.Status, StatusRejected) {
+		return true
+	}
+	// Without a webhook consumer there is nothing to filter or summarise.
+	if conf, err := config.Fetch(); err != nil || conf.Notification.Webhook.Url == "" {
+		return true
+	}
+	preferences := l.notificationPreferences(ctx)
+	if len(preferences) == 0 {
+		return true
+	}
+
+	notify := false
+	for _, side := range []struct {
+		balanceID string
+		debit     bool
+	}{{transaction.Source, true}, {transaction.Destination, false}} {
+		preference, ok := preferences[side.balanceID]
+		if !ok {
+			notify = true
+			continue
+		}
+		if preference.NotifyImmediately(side.debit, transaction.PreciseAmount) {
+			notify = true
+		}
+		if preference.Mode == model.NotificationModeDigest && strings.EqualFold(transaction.Status, StatusApplied) {
+			if err := l.addToNotificationDigest(ctx, side.balanceID, side.debit, transaction); err != nil {
+				logrus.WithError(err).WithField("balance_id", side.balanceID).Warn("failed to add posting to notification digest")
+			}
+		}
+	}
+	return notify
+}
+
+// addToNotificationDigest records a posting in the digest of a balance for the day it was created.
+func (l *Blnk) addToNotificationDigest(ctx context.Context, balanceID string, debit bool, transaction *model.Transaction) error {
+	if l.redis == nil || transaction.PreciseAmount == nil {
+		return nil
+	}
+	at := transaction.CreatedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	date := at.UTC().Format(usageDateLayout)
+	key := fmt.Sprintf("%s:%s:%s", notificationDigestKeyPrefix, date, balanceID)
+	indexKey := fmt.Sprintf("%s:index:%s", notificationDigestKeyPrefix, date)
+
+	direction := "C"
+	if debit {
+		direction = "D"
+	}
+
+	// Amounts are kept as strings and summed when the digest is sent, since precise amounts may exceed int64.
+	pipe := l.redis.TxPipeline()
+	pipe.RPush(ctx, key, direction+transaction.PreciseAmount.String())
+	pipe.Expire(ctx, key, notificationDigestRetention)
+	pipe.SAdd(ctx, indexKey, balanceID)
+	pipe.Expire(ctx, indexKey, notificationDigestRetention)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// balanceNotificationDigest summarises the postings recorded in a balance's digest for a day.
+func (l *Blnk) balanceNotificationDigest(ctx context.Context, date, balanceID string) (model.BalanceNotificationDigest, error) {
+	digest := model.BalanceNotificationDigest{
+		BalanceID:   balanceID,
+		Date:        date,
+		DebitTotal:  big.NewInt(0),
+		CreditTotal: big.NewInt(0),
+	}
+	postings, err := l.redis.LRange(ctx, fmt.Sprintf("%s:%s:%s", notificationDigestKeyPrefix, date, balanceID), 0, -1).Result()
+	if err != nil {
+		return digest, err
+	}
+	for _, posting := range postings {
+		if len(posting) < 2 {
+			continue
+		}
+		amount, ok := new(big.Int).SetString(posting[1:], 10)
+		if !ok {
+			continue
+		}
+		if posting[0] == 'D' {
+			digest.DebitCount++
+			digest.DebitTotal.Add(digest.DebitTotal, amount)
+		} else {
+			digest.CreditCount++
+			digest.CreditTotal.Add(digest.CreditTotal, amount)
+		}
+	}
+	return digest, nil
+}
+
+// SendBalanceNotificationDigests publishes a "balance.digest" event for every balance in digest mode that
+// had postings on a completed day. Each day is sent at most once; repeated calls for the same day are no-ops.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - day: The day to send the digests for.
+//
+// Returns:
+// - int: The number of digests sent.
+// - error: An error if the digests could not be read or published.
+func (l *Blnk) SendBalanceNotificationDigests(ctx context.Context, day time.Time) (int, error) {
+	date := day.UTC().Format(usageDateLayout)
+	markerKey := fmt.Sprintf("%s:sent:%s", notificationDigestKeyPrefix, date)
+	claimed, err := l.redis.SetNX(ctx, markerKey, time.Now().Unix(), notificationDigestRetention).Result()
+	if err != nil {
+		return 0, err
+	}
+	if !claimed {
+		return 0, nil
+	}
+
+	balanceIDs, err := l.redis.SMembers(ctx, fmt.Sprintf("%s:index:%s", notificationDigestKeyPrefix, date)).Result()
+	if err != nil {
+		l.redis.Del(ctx, markerKey)
+		return 0, err
+	}
+
+	sent := 0
+	for _, balanceID := range balanceIDs {
+		digest, err := l.balanceNotificationDigest(ctx, date, balanceID)
+		if err != nil {
+			l.redis.Del(ctx, markerKey)
+			return sent, err
+		}
+		l.formatNotificationDigest(ctx, &digest)
+		if err := l.SendWebhook(NewWebhook{Event: "balance.digest", Payload: digest}); err != nil {
+			l.redis.Del(ctx, markerKey)
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// formatNotificationDigest adds the display totals of a digest, formatted for the identity of its balance.
+// Digests of balances that cannot be loaded are sent without them.
+func (l *Blnk) formatNotificationDigest(ctx context.Context, digest *model.BalanceNotificationDigest) {
+	balance, err := l.datasource.GetBalanceByIDLite(ctx, digest.BalanceID)
+	if err != nil {
+		logrus.WithError(err).WithField("balance_id", digest.BalanceID).Warn("failed to load balance for notification digest")
+		return
+	}
+	format := l.identityLocaleFormat(ctx, balance.IdentityID)
+	digest.Currency = balance.Currency
+	digest.DisplayDebitTotal = format.FormatAmount(digest.DebitTotal, balance.CurrencyMultiplier)
+	digest.DisplayCreditTotal = format.FormatAmount(digest.CreditTotal, balance.CurrencyMultiplier)
+	digest.Locale = format.Locale
+	digest.Timezone = format.Timezone
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newBalanceNotificationTestBlnk(t *testing.T) (*Blnk, *mocks.MockDataSource, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis:        config.RedisConfig{Dns: mr.Addr()},
+		Queue:        config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+		Notification: config.Notification{Webhook: config.WebhookConfig{Url: "http://localhost/webhook"}},
+	})
+
+	mockDS := new(mocks.MockDataSource)
+	b, err := NewBlnk(mockDS)
+	require.NoError(t, err)
+	return b, mockDS, mr
+}
+
+func notificationTestTransaction(amount int64, status string) *model.Transaction {
+	return &model.Transaction{
+		TransactionID: "txn_" + time.Now().Format("150405.000000000"),
+		Source:        "bln_busy",
+		Destination:   "bln_quiet",
+		PreciseAmount: big.NewInt(amount),
+		Status:        status,
+		CreatedAt:     time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestShouldSendTransactionWebhook_ThresholdAndDigest(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+
+	mockDS.On("ListBalanceNotificationPreferences", mock.Anything).Return([]*model.BalanceNotificationPreference{
+		{BalanceID: "bln_busy", Mode: model.NotificationModeDigest, MinDebitAmount: big.NewInt(10000)},
+		{BalanceID: "bln_quiet", Mode: model.NotificationModeRealtime, MinCreditAmount: big.NewInt(10000)},
+	}, nil).Once()
+
+	assert.False(t, b.shouldSendTransactionWebhook(ctx, notificationTestTransaction(500, StatusApplied)))
+	assert.False(t, b.shouldSendTransactionWebhook(ctx, notificationTestTransaction(700, StatusApplied)))
+	assert.True(t, b.shouldSendTransactionWebhook(ctx, notificationTestTransaction(20000, StatusApplied)))
+	assert.True(t, b.shouldSendTransactionWebhook(ctx, notificationTestTransaction(1, StatusRejected)), "rejections are always delivered")
+
+	digest, err := b.balanceNotificationDigest(ctx, "2026-03-01", "bln_busy")
+	require.NoError(t, err)
+	assert.Equal(t, 3, digest.DebitCount)
+	assert.Equal(t, "21200", digest.DebitTotal.String())
+	assert.Equal(t, 0, digest.CreditCount)
+
+	// The realtime balance keeps no digest
+	digest, err = b.balanceNotificationDigest(ctx, "2026-03-01", "bln_quiet")
+	require.NoError(t, err)
+	assert.Equal(t, 0, digest.CreditCount)
+	mockDS.AssertExpectations(t)
+}
+
+func TestShouldSendTransactionWebhook_BalanceWithoutPreference(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	mockDS.On("ListBalanceNotificationPreferences", mock.Anything).Return([]*model.BalanceNotificationPreference{
+		{BalanceID: "bln_busy", Mode: model.NotificationModeDigest},
+	}, nil).Once()
+
+	// bln_quiet has no preference, so the event is still delivered for it
+	assert.True(t, b.shouldSendTransactionWebhook(context.Background(), notificationTestTransaction(500, StatusApplied)))
+}
+
+func TestSendBalanceNotificationDigests_SentOncePerDay(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+
+	mockDS.On("ListBalanceNotificationPreferences", mock.Anything).Return([]*model.BalanceNotificationPreference{
+		{BalanceID: "bln_busy", Mode: model.NotificationModeDigest},
+	}, nil).Once()
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_busy").Return(&model.Balance{BalanceID: "bln_busy", Currency: "USD", CurrencyMultiplier: 100}, nil)
+	b.shouldSendTransactionWebhook(ctx, notificationTestTransaction(500, StatusApplied))
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	sent, err := b.SendBalanceNotificationDigests(ctx, day)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	tasks, err := mr.List("asynq:{webhook_queue}:pending")
+	require.NoError(t, err)
+	assert.Len(t, tasks, 1)
+
+	sent, err = b.SendBalanceNotificationDigests(ctx, day)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+}
+
+func TestFormatNotificationDigest_UsesIdentityLocale(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_busy").Return(&model.Balance{BalanceID: "bln_busy", IdentityID: "idt_1", Currency: "EUR", CurrencyMultiplier: 100}, nil)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1", Locale: "de-DE", Timezone: "Europe/Berlin"}, nil)
+
+	digest := model.BalanceNotificationDigest{BalanceID: "bln_busy", DebitTotal: big.NewInt(123456789), CreditTotal: big.NewInt(5)}
+	b.formatNotificationDigest(context.Background(), &digest)
+
+	assert.Equal(t, "EUR", digest.Currency)
+	assert.Equal(t, "1.234.567,89", digest.DisplayDebitTotal)
+	assert.Equal(t, "0,05", digest.DisplayCreditTotal)
+	assert.Equal(t, "de-DE", digest.Locale)
+	assert.Equal(t, "Europe/Berlin", digest.Timezone)
+}
+
+func TestSetBalanceNotificationPreference_KeepsCreatedAt(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+	createdAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_busy").Return(&model.Balance{BalanceID: "bln_busy"}, nil)
+	mockDS.On("GetBalanceNotificationPreference", mock.Anything, "bln_busy").Return(&model.BalanceNotificationPreference{
+		BalanceID: "bln_busy", Mode: model.NotificationModeRealtime, CreatedAt: createdAt,
+	}, nil)
+	mockDS.On("UpsertBalanceNotificationPreference", mock.Anything, mock.MatchedBy(func(p *model.BalanceNotificationPreference) bool {
+		return p.Mode == model.NotificationModeDigest && p.CreatedAt.Equal(createdAt) && p.UpdatedAt.After(createdAt)
+	})).Return(nil)
+
+	preference, err := b.SetBalanceNotificationPreference(ctx, model.BalanceNotificationPreference{BalanceID: "bln_busy", Mode: model.NotificationModeDigest})
+	require.NoError(t, err)
+	assert.Equal(t, model.NotificationModeDigest, preference.Mode)
+
+	_, err = b.SetBalanceNotificationPreference(ctx, model.BalanceNotificationPreference{BalanceID: "bln_busy", Mode: "hourly"})
+	assert.ErrorContains(t, err, "mode")
+	mockDS.AssertExpectations(t)
+}
@@ -0,0 +1,212 @@
+package blnk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	redlock "github.com/blnkfinance/blnk/internal/lock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	balanceShardingCacheTTL = 30 * time.Second
+	balanceShardLockTimeout = time.Minute
+)
+
+// balanceShardingCache keeps the shardings of sharded balances in memory so that postings do not query them
+// for every transaction, along with the number of postings routed to each balance for round robin routing.
+// Balances sharded on another instance are picked up within balanceShardingCacheTTL.
+type balanceShardingCache struct {
+	mu        sync.Mutex
+	shardings map[string]*model.BalanceSharding
+	turns     map[string]uint64
+	loadedAt  time.Time
+}
+
+// balanceShardings returns the cached shardings keyed by balance ID, reloading them when the cache is stale.
+// A failed reload keeps the previous shardings.
+func (l *Blnk) balanceShardings(ctx context.Context) map[string]*model.BalanceSharding {
+	if l.shards == nil {
+		return nil
+	}
+	l.shards.mu.Lock()
+	defer l.shards.mu.Unlock()
+	return l.loadBalanceShardings(ctx)
+}
+
+// loadBalanceShardings reloads the shardings when the cache is stale. The cache must be locked.
+func (l *Blnk) loadBalanceShardings(ctx context.Context) map[string]*model.BalanceSharding {
+	if time.Since(l.shards.loadedAt) < balanceShardingCacheTTL {
+		return l.shards.shardings
+	}
+	l.shards.loadedAt = time.Now()
+
+	shardings, err := l.datasource.ListBalanceShardings(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to load balance shardings")
+		return l.shards.shardings
+	}
+	byBalance := make(map[string]*model.BalanceSharding, len(shardings))
+	for _, sharding := range shardings {
+		byBalance[sharding.BalanceID] = sharding
+	}
+	l.shards.shardings = byBalance
+	return byBalance
+}
+
+// invalidateBalanceShardings forces the next posting to reload the shardings.
+func (l *Blnk) invalidateBalanceShardings() {
+	if l.shards == nil {
+		return
+	}
+	l.shards.mu.Lock()
+	l.shards.loadedAt = time.Time{}
+	l.shards.mu.Unlock()
+}
+
+// routeToShards picks the shards a transaction on sharded balances is applied to. It must run before the
+// transaction's lock is taken, so that postings on different shards of a balance do not wait for each other.
+// Balances are matched by ID, so postings that address a balance by indicator are not sharded.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - transaction *model.Transaction: The transaction to route.
+func (l *Blnk) routeToShards(ctx context.Context, transaction *model.Transaction) {
+	if l.shards == nil {
+		return
+	}
+	l.shards.mu.Lock()
+	defer l.shards.mu.Unlock()
+
+	shardings := l.loadBalanceShardings(ctx)
+	if len(shardings) == 0 {
+		return
+	}
+	if l.shards.turns == nil {
+		l.shards.turns = make(map[string]uint64)
+	}
+	route := func(balanceID string) string {
+		sharding, ok := shardings[balanceID]
+		if !ok {
+			return ""
+		}
+		turn := l.shards.turns[balanceID]
+		l.shards.turns[balanceID] = turn + 1
+		return sharding.Shard(transaction.Reference, turn)
+	}
+	transaction.SourceShard = route(transaction.Source)
+	transaction.DestinationShard = route(transaction.Destination)
+}
+
+// ShardBalance splits a balance into shards, or adds shards to a sharded balance. The balance becomes the
+// first shard and keeps its amounts; the other shards are new balances in the same ledger and currency.
+// Postings on the balance are then applied to one of its shards, picked with the request's strategy, which
+// removes the contention of a single balance row for balances receiving many postings at once. Debits are
+// checked against the funds of the shard they are applied to, so sharding suits balances that mostly receive
+// funds or may overdraw. The number of shards cannot be reduced.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - balanceID string: The ID of the balance to shard.
+// - req model.BalanceShardRequest: The number of shards and the routing strategy.
+//
+// Returns:
+// - *model.BalanceSharding: The sharding of the balance.
+// - error: An error if the request is invalid, the balance cannot be sharded or the shards could not be created.
+func (l *Blnk) ShardBalance(ctx context.Context, balanceID string, req model.BalanceShardRequest) (*model.BalanceSharding, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	balance, err := l.datasource.GetBalanceByID(ctx, balanceID, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if shardOf, ok := balance.MetaData[model.ShardOfMetaKey]; ok {
+		return nil, fmt.Errorf("balance %s is a shard of balance %v and cannot be sharded", balanceID, shardOf)
+	}
+
+	locker := redlock.NewLocker(l.redis, "balance-sharding:"+balanceID, model.GenerateUUIDWithSuffix("loc"))
+	if err := locker.Lock(ctx, balanceShardLockTimeout); err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	defer l.releaseLock(ctx, locker)
+
+	now := time.Now().UTC()
+	sharding := &model.BalanceSharding{BalanceID: balanceID, ShardIDs: []string{balanceID}, CreatedAt: now}
+	if existing, err := l.datasource.GetBalanceSharding(ctx, balanceID); err == nil {
+		sharding = existing
+	}
+	if req.Shards < len(sharding.ShardIDs) {
+		return nil, fmt.Errorf("balance %s has %d shards and the number of shards cannot be reduced", balanceID, len(sharding.ShardIDs))
+	}
+	sharding.Strategy = req.Strategy
+	sharding.UpdatedAt = now
+
+	for len(sharding.ShardIDs) < req.Shards {
+		shard, err := l.datasource.CreateBalance(ctx, model.Balance{
+			LedgerID:           balance.LedgerID,
+			IdentityID:         balance.IdentityID,
+			Currency:           balance.Currency,
+			CurrencyMultiplier: balance.CurrencyMultiplier,
+			MetaData:           map[string]interface{}{model.ShardOfMetaKey: balanceID},
+		})
+		if err != nil {
+			return nil, err
+		}
+		sharding.ShardIDs = append(sharding.ShardIDs, shard.BalanceID)
+	}
+
+	if err := l.datasource.UpsertBalanceSharding(ctx, sharding); err != nil {
+		return nil, err
+	}
+	l.invalidateBalanceShardings()
+	return sharding, nil
+}
+
+// GetBalanceSharding retrieves the sharding of a balance along with the amounts of each of its shards.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - balanceID string: The ID of the sharded balance.
+//
+// Returns:
+// - *model.BalanceSharding: The sharding and its shards.
+// - error: An error if the balance is not sharded or a shard could not be retrieved.
+func (l *Blnk) GetBalanceSharding(ctx context.Context, balanceID string) (*model.BalanceSharding, error) {
+	sharding, err := l.datasource.GetBalanceSharding(ctx, balanceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, shardID := range sharding.ShardIDs {
+		shard, err := l.datasource.GetBalanceByIDLite(ctx, shardID)
+		if err != nil {
+			return nil, err
+		}
+		sharding.Shards = append(sharding.Shards, shard)
+	}
+	return sharding, nil
+}
+
+// aggregateShards adds the amounts of the other shards of a sharded balance to the balance, which holds the
+// amounts of its first shard. Balances that are not sharded are left as they are.
+func (l *Blnk) aggregateShards(ctx context.Context, balance *model.Balance) error {
+	sharding, ok := l.balanceShardings(ctx)[balance.BalanceID]
+	if !ok {
+		return nil
+	}
+	for _, shardID := range sharding.ShardIDs {
+		if shardID == balance.BalanceID {
+			continue
+		}
+		shard, err := l.datasource.GetBalanceByIDLite(ctx, shardID)
+		if err != nil {
+			return err
+		}
+		balance.AddShard(shard)
+	}
+	balance.ShardCount = len(sharding.ShardIDs)
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newBalanceShardTestBlnk(t *testing.T) (*Blnk, *mocks.MockDataSource) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{Redis: config.RedisConfig{Dns: mr.Addr()}})
+	mockDS := new(mocks.MockDataSource)
+	b, err := NewBlnk(mockDS)
+	require.NoError(t, err)
+	return b, mockDS
+}
+
+func TestShardBalance(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	ctx := context.Background()
+
+	mockDS.On("GetBalanceByID", mock.Anything, "bln_hot", []string(nil), false).Return(&model.Balance{
+		BalanceID: "bln_hot", LedgerID: "ldg_1", IdentityID: "idt_1", Currency: "USD", CurrencyMultiplier: 100,
+	}, nil)
+	mockDS.On("GetBalanceSharding", ctx, "bln_hot").Return(nil, assert.AnError)
+	isShard := mock.MatchedBy(func(balance model.Balance) bool {
+		return balance.LedgerID == "ldg_1" && balance.IdentityID == "idt_1" && balance.Currency == "USD" &&
+			balance.MetaData[model.ShardOfMetaKey] == "bln_hot"
+	})
+	mockDS.On("CreateBalance", mock.Anything, isShard).Return(model.Balance{BalanceID: "bln_shard_a"}, nil).Once()
+	mockDS.On("CreateBalance", mock.Anything, isShard).Return(model.Balance{BalanceID: "bln_shard_b"}, nil).Once()
+	mockDS.On("UpsertBalanceSharding", ctx, mock.AnythingOfType("*model.BalanceSharding")).Return(nil)
+
+	sharding, err := b.ShardBalance(ctx, "bln_hot", model.BalanceShardRequest{Shards: 3, Strategy: model.ShardRoundRobin})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bln_hot", "bln_shard_a", "bln_shard_b"}, sharding.ShardIDs)
+	assert.Equal(t, model.ShardRoundRobin, sharding.Strategy)
+	mockDS.AssertNumberOfCalls(t, "CreateBalance", 2)
+}
+
+func TestShardBalance_CannotReduceShards(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	ctx := context.Background()
+
+	mockDS.On("GetBalanceByID", mock.Anything, "bln_hot", []string(nil), false).Return(&model.Balance{BalanceID: "bln_hot"}, nil)
+	mockDS.On("GetBalanceSharding", ctx, "bln_hot").Return(&model.BalanceSharding{
+		BalanceID: "bln_hot", Strategy: model.ShardHash, ShardIDs: []string{"bln_hot", "bln_shard_a", "bln_shard_b"},
+	}, nil)
+
+	_, err := b.ShardBalance(ctx, "bln_hot", model.BalanceShardRequest{Shards: 2, Strategy: model.ShardHash})
+	assert.ErrorContains(t, err, "cannot be reduced")
+	mockDS.AssertNotCalled(t, "UpsertBalanceSharding", mock.Anything, mock.Anything)
+}
+
+func TestShardBalance_RejectsShard(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+
+	mockDS.On("GetBalanceByID", mock.Anything, "bln_shard_a", []string(nil), false).Return(&model.Balance{
+		BalanceID: "bln_shard_a", MetaData: map[string]interface{}{model.ShardOfMetaKey: "bln_hot"},
+	}, nil)
+
+	_, err := b.ShardBalance(context.Background(), "bln_shard_a", model.BalanceShardRequest{Shards: 2, Strategy: model.ShardHash})
+	assert.ErrorContains(t, err, "is a shard of balance bln_hot")
+}
+
+func TestRouteToShards(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	ctx := context.Background()
+
+	mockDS.On("ListBalanceShardings", ctx).Return([]*model.BalanceSharding{
+		{BalanceID: "bln_hot", Strategy: model.ShardRoundRobin, ShardIDs: []string{"bln_hot", "bln_shard_a", "bln_shard_b"}},
+	}, nil)
+
+	var routed []string
+	for i := 0; i < 4; i++ {
+		txn := &model.Transaction{Source: "bln_customer", Destination: "bln_hot", Reference: "ref"}
+		b.routeToShards(ctx, txn)
+		assert.Empty(t, txn.SourceShard)
+		routed = append(routed, txn.DestinationShard)
+	}
+	assert.Equal(t, []string{"bln_hot", "bln_shard_a", "bln_shard_b", "bln_hot"}, routed)
+	mockDS.AssertNumberOfCalls(t, "ListBalanceShardings", 1)
+}
+
+func TestGetPostingBalance_RecordsAgainstShardedBalance(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_shard_a").Return(&model.Balance{BalanceID: "bln_shard_a"}, nil)
+
+	balance, err := b.getPostingBalance(context.Background(), "bln_hot", "bln_shard_a", false)
+	require.NoError(t, err)
+	assert.Equal(t, "bln_shard_a", balance.BalanceID)
+	assert.Equal(t, "bln_hot", balance.PostingID())
+}
+
+func TestGetBalanceByID_AggregatesShards(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	ctx := context.Background()
+
+	mockDS.On("GetBalanceByID", mock.Anything, "bln_hot", []string(nil), false).Return(&model.Balance{
+		BalanceID: "bln_hot", Balance: big.NewInt(100), CreditBalance: big.NewInt(100), DebitBalance: big.NewInt(0),
+	}, nil)
+	mockDS.On("ListBalanceShardings", ctx).Return([]*model.BalanceSharding{
+		{BalanceID: "bln_hot", Strategy: model.ShardHash, ShardIDs: []string{"bln_hot", "bln_shard_a"}},
+	}, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_shard_a").Return(&model.Balance{
+		BalanceID: "bln_shard_a", Balance: big.NewInt(250), CreditBalance: big.NewInt(300), DebitBalance: big.NewInt(50),
+	}, nil)
+
+	balance, err := b.GetBalanceByID(ctx, "bln_hot", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(350), balance.Balance)
+	assert.Equal(t, big.NewInt(400), balance.CreditBalance)
+	assert.Equal(t, big.NewInt(50), balance.DebitBalance)
+	assert.Equal(t, 2, balance.ShardCount)
+}
@@ -245,11 +245,11 @@ func TestGetAllBalances(t *testing.T) {
 			time.Now(), `{"key":"value"}`,
 		)
 
-	mock.ExpectQuery(`SELECT balance_id, indicator, balance, credit_balance, debit_balance, currency, currency_multiplier, ledger_id, created_at, meta_data FROM blnk.balances ORDER BY created_at DESC LIMIT \$1 OFFSET \$2`).
+	mock.ExpectQuery(`SELECT balance_id, indicator, balance, credit_balance, debit_balance, currency, currency_multiplier, ledger_id, created_at, meta_data FROM blnk.balances WHERE TRUE ORDER BY created_at DESC, balance_id DESC LIMIT \$1 OFFSET \$2`).
 		WithArgs(1, 1).
 		WillReturnRows(rows)
 
-	result, err := d.GetAllBalances(context.Background(), 1, 1)
+	result, err := d.GetAllBalances(context.Background(), model.Page{Limit: 1, Offset: 1})
 
 	assert.NoError(t, err)
 	assert.Len(t, result, 1)
@@ -289,7 +289,7 @@ func TestUpdateBalance(t *testing.T) {
         SET balance = $2, credit_balance = $3, debit_balance = $4, currency = $5, currency_multiplier = $6, ledger_id = $7, created_at = $8, meta_data = $9
         WHERE balance_id = $1`)).WithArgs(balance.BalanceID, balance.Balance.String(), balance.CreditBalance.String(), balance.DebitBalance.String(), balance.Currency, balance.CurrencyMultiplier, balance.LedgerID, balance.CreatedAt, metaDataJSON).WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err = d.datasource.UpdateBalance(balance)
+	err = d.datasource.UpdateBalance(context.Background(), balance)
 	assert.NoError(t, err)
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -20,32 +20,49 @@ import (
 	"context"
 	"embed"
 	"net/http"
-	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/typesense/typesense-go/typesense/api"
 
 	"github.com/blnkfinance/blnk/config"
 	"github.com/blnkfinance/blnk/database"
+	"github.com/blnkfinance/blnk/internal/egress"
+	"github.com/blnkfinance/blnk/internal/featureflags"
 	"github.com/blnkfinance/blnk/internal/hooks"
 	redis_db "github.com/blnkfinance/blnk/internal/redis-db"
+	"github.com/blnkfinance/blnk/internal/resilience"
 	"github.com/blnkfinance/blnk/internal/tokenization"
 
 	"github.com/blnkfinance/blnk/model"
+	"github.com/blnkfinance/blnk/plugins"
 	"github.com/redis/go-redis/v9"
 )
 
 // Blnk represents the main struct for the Blnk application.
 type Blnk struct {
-	queue       *Queue
-	search      *TypesenseClient
-	redis       redis.UniversalClient
-	asynqClient *asynq.Client
-	datasource  database.IDataSource
-	bt          *model.BalanceTracker
-	tokenizer   *tokenization.TokenizationService
-	httpClient  *http.Client
-	Hooks       hooks.HookManager
+	queue        *Queue
+	search       *TypesenseClient
+	redis        redis.UniversalClient
+	asynqClient  *asynq.Client
+	datasource   database.IDataSource
+	bt           *model.BalanceTracker
+	tokenizer    *tokenization.TokenizationService
+	httpClient   *http.Client
+	statements   statementStore
+	Hooks        hooks.HookManager
+	Plugins      *plugins.Registry
+	flags        *featureflags.Store
+	netting      *nettingGroupCache
+	notifyPrefs  *notificationPreferenceCache
+	frozen       *frozenBalanceCache
+	inactive     *inactiveIdentityCache
+	shards       *balanceShardingCache
+	minimums     *minimumBalanceCache
+	postingRules *postingRulesCache
+	riskScorer   IdentityRiskScorer
+	screener     IdentityScreener
+	enricher     NarrativeEnricher
+	aliases      *balanceAliasCache
 }
 
 const (
@@ -55,6 +72,11 @@ const (
 //go:embed sql/*.sql
 var SQLFiles embed.FS
 
+// RLSFiles holds the optional tenant row-level security policies, applied when EnableRLS is configured.
+//
+//go:embed sql/rls/*.sql
+var RLSFiles embed.FS
+
 // initializeRedisClients sets up both the Redis client and Asynq client
 func initializeRedisClients(config *config.Configuration) (redis.UniversalClient, *asynq.Client, error) {
 	redisClient, err := redis_db.NewRedisClient([]string{config.Redis.Dns}, config.Redis.SkipTLSVerify)
@@ -88,15 +110,14 @@ func initializeTokenizationService(config *config.Configuration) *tokenization.T
 }
 
 // initializeHTTPClient creates and configures the HTTP client for webhook requests
-func initializeHTTPClient() *http.Client {
-	return &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:        100,
-			MaxIdleConnsPerHost: 10,
-			IdleConnTimeout:     90 * time.Second,
-		},
-	}
+func initializeHTTPClient(config *config.Configuration) (*http.Client, error) {
+	return egress.NewHTTPClient(config.Notification.Webhook.Egress)
+}
+
+// webhookClient returns the HTTP client webhooks are delivered with, which follows the webhooks dependency
+// policy.
+func (l *Blnk) webhookClient() *http.Client {
+	return resilience.Get(resilience.Webhooks).Wrap(l.httpClient)
 }
 
 // NewBlnk initializes a new instance of Blnk with the provided database datasource.
@@ -124,37 +145,55 @@ func NewBlnk(db database.IDataSource) (*Blnk, error) {
 	newSearch := NewTypesenseClient(configuration.TypeSenseKey, []string{configuration.TypeSense.Dns})
 	hookManager := hooks.NewHookManager(redisClient)
 	tokenizer := initializeTokenizationService(configuration)
-	httpClient := initializeHTTPClient()
+	httpClient, err := initializeHTTPClient(configuration)
+	if err != nil {
+		return nil, err
+	}
+	processors := plugins.NewRegistry()
+	if err := plugins.LoadFromConfig(processors, configuration.Plugins); err != nil {
+		return nil, err
+	}
 
 	return &Blnk{
-		datasource:  db,
-		bt:          bt,
-		queue:       newQueue,
-		redis:       redisClient,
-		asynqClient: asynqClient,
-		search:      newSearch,
-		tokenizer:   tokenizer,
-		httpClient:  httpClient,
-		Hooks:       hookManager,
+		datasource:   db,
+		bt:           bt,
+		queue:        newQueue,
+		redis:        redisClient,
+		asynqClient:  asynqClient,
+		search:       newSearch,
+		tokenizer:    tokenizer,
+		httpClient:   httpClient,
+		Hooks:        hookManager,
+		Plugins:      processors,
+		flags:        featureflags.NewStore(redisClient, configuration.FeatureFlags),
+		netting:      &nettingGroupCache{},
+		notifyPrefs:  &notificationPreferenceCache{},
+		frozen:       &frozenBalanceCache{},
+		inactive:     &inactiveIdentityCache{},
+		shards:       &balanceShardingCache{},
+		minimums:     &minimumBalanceCache{},
+		postingRules: &postingRulesCache{},
+		aliases:      &balanceAliasCache{},
 	}, nil
 }
 
 // Search performs a search on the specified collection using the provided query parameters.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - collection string: The name of the collection to search.
 // - query *api.SearchCollectionParams: The search query parameters.
 //
 // Returns:
 // - interface{}: The search results.
 // - error: An error if the search operation fails.
-func (l *Blnk) Search(collection string, query *api.SearchCollectionParams) (interface{}, error) {
-	return l.search.Search(context.Background(), collection, query)
+func (l *Blnk) Search(ctx context.Context, collection string, query *api.SearchCollectionParams) (interface{}, error) {
+	return l.search.Search(ctx, collection, query)
 }
 
 // MultiSearch performs a multi-search operation across collections.
-func (l *Blnk) MultiSearch(searchParams *api.MultiSearchSearchesParameter) (*api.MultiSearchResult, error) {
-	return l.search.MultiSearch(context.Background(), *searchParams)
+func (l *Blnk) MultiSearch(ctx context.Context, searchParams *api.MultiSearchSearchesParameter) (*api.MultiSearchResult, error) {
+	return l.search.MultiSearch(ctx, *searchParams)
 }
 
 // Close properly closes all connections and resources used by the Blnk instance.
@@ -0,0 +1,185 @@
+package blnk
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/shopspring/decimal"
+)
+
+// daysPerYear is the day count interest is projected with.
+const daysPerYear = 365
+
+// Calculate quotes the fees, interest and currency conversion of an amount using the configured pricing and
+// rounding rules, without posting anything. The amount is the current balance of a balance or a hypothetical
+// amount. Fees and interest are only charged on positive amounts.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - req: The amount to quote and the currency to convert it to.
+//
+// Returns:
+// - *model.Calculation: The quote.
+// - error: An error if the request is invalid, the balance or a currency is unknown or no exchange rate is
+// configured for the conversion.
+func (l *Blnk) Calculate(ctx context.Context, req model.CalculationRequest) (*model.Calculation, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	preciseAmount, currency := req.PreciseAmount, req.Currency
+	if req.BalanceID != "" {
+		balance, err := l.datasource.GetBalanceByIDLite(ctx, req.BalanceID)
+		if err != nil {
+			return nil, err
+		}
+		preciseAmount, currency = balance.Balance, balance.Currency
+	}
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+
+	precision, err := calculationPrecision(currency, req.Precision)
+	if err != nil {
+		return nil, err
+	}
+	mode := roundingModeFor(cnf, currency)
+	if err := mode.Validate(); err != nil {
+		return nil, err
+	}
+	if preciseAmount == nil {
+		preciseAmount, _ = mode.Round(decimal.NewFromFloat(req.Amount).Mul(decimal.NewFromFloat(precision)))
+	}
+
+	calculation := &model.Calculation{
+		BalanceID:    req.BalanceID,
+		Currency:     currency,
+		Precision:    precision,
+		RoundingMode: mode,
+		Amount:       model.NewCalculatedAmount(preciseAmount, precision),
+		Fees:         []model.CalculatedFee{},
+	}
+
+	totalFees := new(big.Int)
+	if preciseAmount.Sign() > 0 {
+		for _, rule := range cnf.Pricing.Fees {
+			if rule.Currency != "" && !strings.EqualFold(rule.Currency, currency) {
+				continue
+			}
+			fee := calculateFee(rule, preciseAmount, precision, mode)
+			totalFees.Add(totalFees, fee)
+			calculation.Fees = append(calculation.Fees, model.CalculatedFee{Name: rule.Name, CalculatedAmount: model.NewCalculatedAmount(fee, precision)})
+		}
+
+		if rate, ok := lookupPricingRate(cnf.Pricing.InterestRates, currency); ok {
+			days := req.Days
+			if days == 0 {
+				days = daysPerYear
+			}
+			interest, _ := mode.Round(decimal.NewFromBigInt(preciseAmount, 0).
+				Mul(decimal.NewFromFloat(rate)).
+				Mul(decimal.NewFromInt(int64(days))).
+				Div(decimal.NewFromInt(100 * daysPerYear)))
+			calculation.Interest = &model.CalculatedInterest{AnnualRate: rate, Days: days, CalculatedAmount: model.NewCalculatedAmount(interest, precision)}
+		}
+	}
+	netAmount := new(big.Int).Sub(preciseAmount, totalFees)
+	calculation.TotalFees = model.NewCalculatedAmount(totalFees, precision)
+	calculation.NetAmount = model.NewCalculatedAmount(netAmount, precision)
+
+	if req.ToCurrency != "" {
+		conversion, err := convertCalculation(cnf, netAmount, currency, precision, req.ToCurrency)
+		if err != nil {
+			return nil, err
+		}
+		calculation.Conversion = conversion
+	}
+	return calculation, nil
+}
+
+// calculationPrecision returns the precision of a quote: the requested one, or else the multiplier of the
+// currency in the currency registry.
+func calculationPrecision(currency string, requested float64) (float64, error) {
+	if requested > 0 {
+		return requested, nil
+	}
+	registered, err := LookupCurrency(currency)
+	if err != nil {
+		return 0, fmt.Errorf("precision is required for %s: %w", currency, err)
+	}
+	return registered.Multiplier, nil
+}
+
+// calculateFee returns the fee a rule charges on a precise amount, bounded by the rule's minimum and maximum.
+func calculateFee(rule config.FeeRule, preciseAmount *big.Int, precision float64, mode model.RoundingMode) *big.Int {
+	precisionDec := decimal.NewFromFloat(precision)
+	fee := decimal.NewFromBigInt(preciseAmount, 0).Mul(decimal.NewFromFloat(rule.Percentage)).Div(decimal.NewFromInt(100)).
+		Add(decimal.NewFromFloat(rule.Flat).Mul(precisionDec))
+	if minFee := decimal.NewFromFloat(rule.Min).Mul(precisionDec); rule.Min > 0 && fee.LessThan(minFee) {
+		fee = minFee
+	}
+	if maxFee := decimal.NewFromFloat(rule.Max).Mul(precisionDec); rule.Max > 0 && fee.GreaterThan(maxFee) {
+		fee = maxFee
+	}
+	rounded, _ := mode.Round(fee)
+	return rounded
+}
+
+// convertCalculation converts a precise amount to the minor units of another currency at the configured rate,
+// rounding with the target currency's mode.
+func convertCalculation(cnf *config.Configuration, preciseAmount *big.Int, from string, precision float64, to string) (*model.CalculatedConversion, error) {
+	target, err := LookupCurrency(to)
+	if err != nil {
+		return nil, err
+	}
+	rate, ok := lookupFXRate(cnf.Pricing.FXRates, from, target.Code)
+	if !ok {
+		return nil, fmt.Errorf("no exchange rate is configured from %s to %s", from, target.Code)
+	}
+	mode := roundingModeFor(cnf, target.Code)
+	if err := mode.Validate(); err != nil {
+		return nil, err
+	}
+
+	converted, _ := mode.Round(decimal.NewFromBigInt(preciseAmount, 0).
+		Mul(decimal.NewFromFloat(rate)).
+		Mul(decimal.NewFromFloat(target.Multiplier)).
+		Div(decimal.NewFromFloat(precision)))
+	return &model.CalculatedConversion{
+		ToCurrency:       target.Code,
+		Rate:             rate,
+		Precision:        target.Multiplier,
+		CalculatedAmount: model.NewCalculatedAmount(converted, target.Multiplier),
+	}, nil
+}
+
+// lookupFXRate returns the rate converting one unit of from into to, using the inverse of the opposite pair's
+// rate when only that one is configured.
+func lookupFXRate(rates map[string]float64, from, to string) (float64, bool) {
+	if strings.EqualFold(from, to) {
+		return 1, true
+	}
+	if rate, ok := lookupPricingRate(rates, from+"/"+to); ok {
+		return rate, true
+	}
+	if rate, ok := lookupPricingRate(rates, to+"/"+from); ok {
+		return 1 / rate, true
+	}
+	return 0, false
+}
+
+// lookupPricingRate returns the rate configured under a key, ignoring case and spaces.
+func lookupPricingRate(rates map[string]float64, key string) (float64, bool) {
+	for configured, rate := range rates {
+		if strings.EqualFold(strings.ReplaceAll(configured, " ", ""), key) {
+			return rate, true
+		}
+	}
+	return 0, false
+}
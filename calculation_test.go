@@ -0,0 +1,113 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"math/big"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func storePricingConfig(pricing config.PricingConfig, rounding config.RoundingConfig) {
+	config.ConfigStore.Store(&config.Configuration{Pricing: pricing, Rounding: rounding})
+}
+
+func TestCalculate_HypotheticalAmount(t *testing.T) {
+	b, _, _ := newBalanceNotificationTestBlnk(t)
+	storePricingConfig(config.PricingConfig{
+		Fees: []config.FeeRule{
+			{Name: "processing", Currency: "USD", Percentage: 1.5, Flat: 0.30, Min: 0.50},
+			{Name: "fx_markup", Currency: "EUR", Percentage: 2},
+		},
+		InterestRates: map[string]float64{"USD": 5},
+		FXRates:       map[string]float64{"EUR/USD": 1.25},
+	}, config.RoundingConfig{DefaultMode: "half_even"})
+
+	calculation, err := b.Calculate(context.Background(), model.CalculationRequest{Amount: 100, Currency: "usd", ToCurrency: "EUR", Days: 30})
+	require.NoError(t, err)
+
+	assert.Equal(t, "USD", calculation.Currency)
+	assert.Equal(t, float64(100), calculation.Precision)
+	assert.Equal(t, model.RoundHalfEven, calculation.RoundingMode)
+	assert.Equal(t, big.NewInt(10000), calculation.Amount.PreciseAmount)
+
+	// Only the USD fee applies: 1.5% of 100.00 plus 0.30
+	require.Len(t, calculation.Fees, 1)
+	assert.Equal(t, "processing", calculation.Fees[0].Name)
+	assert.Equal(t, big.NewInt(180), calculation.TotalFees.PreciseAmount)
+	assert.Equal(t, big.NewInt(9820), calculation.NetAmount.PreciseAmount)
+	assert.Equal(t, 98.2, calculation.NetAmount.Amount)
+
+	// 5% a year over 30 days is 41.09 cents, rounded half to even
+	require.NotNil(t, calculation.Interest)
+	assert.Equal(t, 30, calculation.Interest.Days)
+	assert.Equal(t, big.NewInt(41), calculation.Interest.PreciseAmount)
+
+	// The net amount converts at the inverse of the EUR/USD rate
+	require.NotNil(t, calculation.Conversion)
+	assert.Equal(t, "EUR", calculation.Conversion.ToCurrency)
+	assert.InDelta(t, 0.8, calculation.Conversion.Rate, 1e-9)
+	assert.Equal(t, big.NewInt(7856), calculation.Conversion.PreciseAmount)
+}
+
+func TestCalculate_Balance(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	storePricingConfig(config.PricingConfig{
+		Fees: []config.FeeRule{{Name: "withdrawal", Percentage: 5, Max: 10}},
+	}, config.RoundingConfig{})
+
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_1").Return(&model.Balance{BalanceID: "bln_1", Balance: big.NewInt(50000), Currency: "NGN"}, nil)
+
+	calculation, err := b.Calculate(context.Background(), model.CalculationRequest{BalanceID: "bln_1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "bln_1", calculation.BalanceID)
+	assert.Equal(t, "NGN", calculation.Currency)
+	// 5% of 500.00 is 25.00, capped at 10.00
+	assert.Equal(t, big.NewInt(1000), calculation.TotalFees.PreciseAmount)
+	assert.Equal(t, big.NewInt(49000), calculation.NetAmount.PreciseAmount)
+	assert.Nil(t, calculation.Interest)
+	assert.Nil(t, calculation.Conversion)
+	mockDS.AssertExpectations(t)
+}
+
+func TestCalculate_Errors(t *testing.T) {
+	b, _, _ := newBalanceNotificationTestBlnk(t)
+	storePricingConfig(config.PricingConfig{}, config.RoundingConfig{})
+
+	tests := []struct {
+		name string
+		req  model.CalculationRequest
+	}{
+		{name: "balance and amount", req: model.CalculationRequest{BalanceID: "bln_1", Amount: 10, Currency: "USD"}},
+		{name: "missing currency", req: model.CalculationRequest{Amount: 10}},
+		{name: "unregistered currency without precision", req: model.CalculationRequest{Amount: 10, Currency: "POINTS"}},
+		{name: "no exchange rate", req: model.CalculationRequest{Amount: 10, Currency: "USD", ToCurrency: "GBP"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := b.Calculate(context.Background(), tt.req)
+			assert.Error(t, err)
+		})
+	}
+}
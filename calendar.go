@@ -0,0 +1,134 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/calendar"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// rolledFromMetadataKey records the date a scheduled transaction was scheduled for before it was rolled to a
+// business day.
+const rolledFromMetadataKey = "BLNK_ROLLED_FROM"
+
+var (
+	// ErrUnknownCalendar is returned when a calendar is not configured.
+	ErrUnknownCalendar = errors.New("unknown calendar")
+	// ErrInvalidCalendarDate is returned when a calendar date is not a date in YYYY-MM-DD.
+	ErrInvalidCalendarDate = errors.New("invalid calendar date")
+)
+
+// businessCalendar returns a configured calendar by name.
+func businessCalendar(name string) (*calendar.Calendar, error) {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	cfg, ok := cnf.Calendars[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCalendar, name)
+	}
+	return calendar.New(name, cfg)
+}
+
+// GetCalendarDay describes a date of a business calendar: whether it is a business day, why not if it is not,
+// and the nearest business days before and after it.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - name string: The name of the calendar.
+// - date string: The date, as YYYY-MM-DD.
+//
+// Returns:
+// - *model.CalendarDay: The date.
+// - error: ErrUnknownCalendar or ErrInvalidCalendarDate.
+func (l *Blnk) GetCalendarDay(_ context.Context, name, date string) (*model.CalendarDay, error) {
+	cal, err := businessCalendar(name)
+	if err != nil {
+		return nil, err
+	}
+	day, err := time.ParseInLocation(time.DateOnly, date, cal.Location())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q, expected YYYY-MM-DD", ErrInvalidCalendarDate, date)
+	}
+	next, err := cal.Next(day)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := cal.Previous(day)
+	if err != nil {
+		return nil, err
+	}
+	reason := cal.Reason(day)
+	return &model.CalendarDay{
+		Calendar:            name,
+		Date:                date,
+		BusinessDay:         reason == "",
+		Reason:              reason,
+		NextBusinessDay:     next.Format(time.DateOnly),
+		PreviousBusinessDay: previous.Format(time.DateOnly),
+	}, nil
+}
+
+// businessDayRule returns the business day rule of a scheduled transaction. Transactions without their own rule
+// use the configured default; nil means the transaction may run on any day.
+func businessDayRule(transaction *model.Transaction) *model.BusinessDayRule {
+	if transaction.BusinessDayRule != nil {
+		return transaction.BusinessDayRule
+	}
+	cnf, err := config.Fetch()
+	if err != nil || cnf.ScheduledCalendar.Calendar == "" {
+		return nil
+	}
+	return &model.BusinessDayRule{Calendar: cnf.ScheduledCalendar.Calendar, Roll: cnf.ScheduledCalendar.Roll}
+}
+
+// rollScheduledFor rolls the date a scheduled transaction runs on to a business day by its business day rule,
+// recording the date it was scheduled for in its metadata. Dates are not rolled earlier than notBefore, when it
+// is set; a date rolled back past it is rolled forward instead.
+//
+// Parameters:
+// - transaction *model.Transaction: The transaction to roll. Transactions that are not scheduled are left alone.
+// - notBefore time.Time: The earliest time the transaction may be rolled to, or the zero time for no limit.
+//
+// Returns:
+// - error: ErrUnknownCalendar if the rule's calendar is not configured, or an error if no business day is found.
+func rollScheduledFor(transaction *model.Transaction, notBefore time.Time) error {
+	if transaction.ScheduledFor.IsZero() {
+		return nil
+	}
+	rule := businessDayRule(transaction)
+	if rule == nil {
+		return nil
+	}
+	cal, err := businessCalendar(rule.Calendar)
+	if err != nil {
+		return err
+	}
+
+	rolled, err := cal.Roll(transaction.ScheduledFor, rule.Roll)
+	if err != nil {
+		return err
+	}
+	if !notBefore.IsZero() && rolled.Before(notBefore) {
+		if rolled, err = cal.Roll(transaction.ScheduledFor, model.RollFollowing); err != nil {
+			return err
+		}
+	}
+	if rolled.Equal(transaction.ScheduledFor) {
+		return nil
+	}
+
+	if transaction.MetaData == nil {
+		transaction.MetaData = make(map[string]interface{})
+	}
+	if _, ok := transaction.MetaData[rolledFromMetadataKey]; !ok {
+		transaction.MetaData[rolledFromMetadataKey] = transaction.ScheduledFor.Format(time.RFC3339)
+	}
+	transaction.ScheduledFor = rolled
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func useCalendars(t *testing.T, scheduled config.ScheduledCalendarConfig, calendars map[string]config.CalendarConfig) {
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	updated := *cnf
+	updated.Calendars = calendars
+	updated.ScheduledCalendar = scheduled
+	config.ConfigStore.Store(&updated)
+}
+
+var testCalendars = map[string]config.CalendarConfig{
+	"NG": {Timezone: "Africa/Lagos", Weekend: []string{"saturday", "sunday"}, Holidays: map[string]string{"10-01": "Independence Day"}},
+}
+
+func TestRollScheduledFor(t *testing.T) {
+	newBalanceNotificationTestBlnk(t)
+	useCalendars(t, config.ScheduledCalendarConfig{Calendar: "NG", Roll: model.RollFollowing}, testCalendars)
+	lagos, err := time.LoadLocation("Africa/Lagos")
+	require.NoError(t, err)
+
+	// The configured rule rolls a Saturday to Monday
+	saturday := time.Date(2026, 10, 17, 9, 0, 0, 0, lagos)
+	txn := &model.Transaction{ScheduledFor: saturday, MetaData: map[string]interface{}{}}
+	require.NoError(t, rollScheduledFor(txn, time.Time{}))
+	assert.True(t, txn.ScheduledFor.Equal(time.Date(2026, 10, 19, 9, 0, 0, 0, lagos)))
+	assert.Equal(t, saturday.Format(time.RFC3339), txn.MetaData[rolledFromMetadataKey])
+
+	// A transaction's own rule takes precedence over the configured one
+	holiday := time.Date(2026, 10, 1, 9, 0, 0, 0, lagos)
+	txn = &model.Transaction{ScheduledFor: holiday, BusinessDayRule: &model.BusinessDayRule{Calendar: "NG", Roll: model.RollPreceding}}
+	require.NoError(t, rollScheduledFor(txn, time.Time{}))
+	assert.True(t, txn.ScheduledFor.Equal(time.Date(2026, 9, 30, 9, 0, 0, 0, lagos)))
+
+	// Retries are never rolled back before now
+	txn = &model.Transaction{ScheduledFor: saturday, BusinessDayRule: &model.BusinessDayRule{Calendar: "NG", Roll: model.RollPreceding}}
+	require.NoError(t, rollScheduledFor(txn, saturday.Add(-time.Hour)))
+	assert.True(t, txn.ScheduledFor.Equal(time.Date(2026, 10, 19, 9, 0, 0, 0, lagos)))
+
+	// Business days and unscheduled transactions are left alone
+	friday := time.Date(2026, 10, 16, 9, 0, 0, 0, lagos)
+	txn = &model.Transaction{ScheduledFor: friday}
+	require.NoError(t, rollScheduledFor(txn, time.Time{}))
+	assert.True(t, txn.ScheduledFor.Equal(friday))
+	assert.Nil(t, txn.MetaData)
+
+	txn = &model.Transaction{ScheduledFor: saturday, BusinessDayRule: &model.BusinessDayRule{Calendar: "GH", Roll: model.RollFollowing}}
+	assert.True(t, errors.Is(rollScheduledFor(txn, time.Time{}), ErrUnknownCalendar))
+}
+
+func TestGetCalendarDay(t *testing.T) {
+	b, _, _ := newBalanceNotificationTestBlnk(t)
+	useCalendars(t, config.ScheduledCalendarConfig{}, testCalendars)
+
+	day, err := b.GetCalendarDay(context.Background(), "NG", "2027-10-01")
+	require.NoError(t, err)
+	assert.False(t, day.BusinessDay)
+	assert.Equal(t, "Independence Day", day.Reason)
+	assert.Equal(t, "2027-10-04", day.NextBusinessDay)
+	assert.Equal(t, "2027-09-30", day.PreviousBusinessDay)
+
+	_, err = b.GetCalendarDay(context.Background(), "NG", "01/10/2027")
+	assert.True(t, errors.Is(err, ErrInvalidCalendarDate))
+	_, err = b.GetCalendarDay(context.Background(), "GH", "2027-10-01")
+	assert.True(t, errors.Is(err, ErrUnknownCalendar))
+}
+
+func TestRunDueEOD_SkipsNonBusinessDays(t *testing.T) {
+	b, _, _ := newBalanceNotificationTestBlnk(t)
+	now := time.Now().UTC()
+	useCalendars(t, config.ScheduledCalendarConfig{}, map[string]config.CalendarConfig{
+		"closed": {Timezone: "UTC", Holidays: map[string]string{
+			now.Format(time.DateOnly):                   "Closed",
+			now.AddDate(0, 0, -1).Format(time.DateOnly): "Closed",
+		}},
+	})
+	useEODSteps(t, config.EODStepConfig{Name: "cutoff", Kind: model.EODKindCutoff})
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.EOD.Calendar = "closed"
+
+	run, err := b.RunDueEOD(context.Background())
+	require.NoError(t, err)
+	// No run is looked up or started, which the mock datasource would fail on
+	assert.Nil(t, run)
+}
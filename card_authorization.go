@@ -0,0 +1,394 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	redlock "github.com/blnkfinance/blnk/internal/lock"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	cardAuthorizationLockTimeout = time.Minute
+	cardAuthorizationExpiryBatch = 100
+	// cardAuthorizationMetaKey links the transactions of a card authorization back to it.
+	cardAuthorizationMetaKey = "BLNK_CARD_AUTHORIZATION"
+)
+
+// ErrCardAuthorizationClosed is returned when an authorization is changed after it was cleared, reversed or expired.
+var ErrCardAuthorizationClosed = errors.New("card authorization is no longer open")
+
+// cardAuthorizationExpiry returns how long an authorization for the scheme stays valid.
+func cardAuthorizationExpiry(scheme string) time.Duration {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return 7 * 24 * time.Hour
+	}
+	if expiry, ok := cnf.CardAuthorization.SchemeExpiry[strings.ToLower(scheme)]; ok && expiry > 0 {
+		return expiry
+	}
+	return cnf.CardAuthorization.DefaultExpiry
+}
+
+// AuthorizeCard places a hold for a card authorization and starts tracking its lifecycle. The hold is an
+// inflight transaction from the cardholder's balance to the destination, so a decline surfaces as the
+// error of that transaction.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - auth: The authorization to create. Scheme, reference, source, destination and currency are required.
+// - amount: The authorized amount in precise units.
+//
+// Returns:
+// - *model.CardAuthorization: The created authorization.
+// - error: An error if the authorization is invalid or the hold could not be placed.
+func (l *Blnk) AuthorizeCard(ctx context.Context, auth model.CardAuthorization, amount *big.Int) (*model.CardAuthorization, error) {
+	if auth.Scheme == "" || auth.Reference == "" {
+		return nil, errors.New("scheme and reference are required")
+	}
+	if auth.Source == "" || auth.Destination == "" || auth.Currency == "" {
+		return nil, errors.New("source, destination and currency are required")
+	}
+	if amount == nil || amount.Sign() <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+
+	now := time.Now()
+	if auth.Precision == 0 {
+		auth.Precision = 1
+	}
+	if auth.ExpiresAt.IsZero() {
+		auth.ExpiresAt = now.Add(cardAuthorizationExpiry(auth.Scheme))
+	}
+	if !auth.ExpiresAt.After(now) {
+		return nil, errors.New("expires_at must be in the future")
+	}
+	auth.AuthorizationID = model.GenerateUUIDWithSuffix("cauth")
+	auth.Status = model.CardAuthorizationAuthorized
+	auth.AuthorizedAmount = big.NewInt(0)
+	auth.ReversedAmount = big.NewInt(0)
+	auth.ClearedAmount = big.NewInt(0)
+	auth.Holds = []model.CardAuthorizationHold{}
+	auth.Events = []model.CardAuthorizationEvent{}
+	auth.CreatedAt = now
+
+	hold, err := l.placeCardHold(ctx, &auth, amount)
+	if err != nil {
+		return nil, err
+	}
+	auth.AuthorizedAmount.Add(auth.AuthorizedAmount, amount)
+	auth.RecordEvent(model.CardEventAuthorization, amount, hold.TransactionID)
+
+	if err := l.datasource.CreateCardAuthorization(ctx, &auth); err != nil {
+		// Release the hold so a failed save does not leave funds held without an authorization to clear them.
+		if _, voidErr := l.VoidInflightTransaction(ctx, hold.TransactionID); voidErr != nil {
+			logrus.WithError(voidErr).WithField("transaction_id", hold.TransactionID).Error("failed to release card hold")
+		}
+		return nil, err
+	}
+	l.sendCardAuthorizationWebhook(model.CardEventAuthorization, &auth)
+	return &auth, nil
+}
+
+// GetCardAuthorization retrieves a card authorization by ID.
+func (l *Blnk) GetCardAuthorization(ctx context.Context, authorizationID string) (*model.CardAuthorization, error) {
+	return l.datasource.GetCardAuthorization(ctx, authorizationID)
+}
+
+// IncrementCardAuthorization raises the authorized amount by placing an additional hold, as for an
+// incremental authorization when a hotel stay or fuel purchase grows. The expiry is not extended.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - authorizationID: The ID of the authorization.
+// - amount: The additional amount in precise units.
+//
+// Returns:
+// - *model.CardAuthorization: The updated authorization.
+// - error: An error if the authorization is closed or the hold is declined.
+func (l *Blnk) IncrementCardAuthorization(ctx context.Context, authorizationID string, amount *big.Int) (*model.CardAuthorization, error) {
+	if amount == nil || amount.Sign() <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	return l.updateCardAuthorization(ctx, authorizationID, model.CardEventIncrementalAuthorization, func(auth *model.CardAuthorization) error {
+		hold, err := l.placeCardHold(ctx, auth, amount)
+		if err != nil {
+			return err
+		}
+		auth.AuthorizedAmount.Add(auth.AuthorizedAmount, amount)
+		auth.RecordEvent(model.CardEventIncrementalAuthorization, amount, hold.TransactionID)
+		return nil
+	})
+}
+
+// ReverseCardAuthorization releases part or all of the held amount. A full reversal closes the authorization.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - authorizationID: The ID of the authorization.
+// - amount: The amount to release in precise units, or nil to release everything still held.
+//
+// Returns:
+// - *model.CardAuthorization: The updated authorization.
+// - error: An error if the authorization is closed or the amount exceeds what is held.
+func (l *Blnk) ReverseCardAuthorization(ctx context.Context, authorizationID string, amount *big.Int) (*model.CardAuthorization, error) {
+	return l.updateCardAuthorization(ctx, authorizationID, model.CardEventReversal, func(auth *model.CardAuthorization) error {
+		held := auth.HeldAmount()
+		if amount == nil {
+			amount = held
+		}
+		if amount.Sign() <= 0 {
+			return errors.New("amount must be greater than zero")
+		}
+		if amount.Cmp(held) > 0 {
+			return fmt.Errorf("reversal amount %s exceeds held amount %s", amount, held)
+		}
+
+		txnIDs, err := l.releaseCardHolds(ctx, auth, amount)
+		if err != nil {
+			return err
+		}
+		auth.ReversedAmount.Add(auth.ReversedAmount, amount)
+		auth.RecordEvent(model.CardEventReversal, amount, txnIDs...)
+		if auth.HeldAmount().Sign() == 0 {
+			auth.Status = model.CardAuthorizationReversed
+		}
+		return nil
+	})
+}
+
+// ClearCardAuthorization settles an authorization for the final amount presented by the scheme, which may
+// differ from the authorized amount. Holds are committed oldest first up to the cleared amount and whatever
+// is left is released. An amount above the held amount is posted as an additional transaction; clearing
+// cannot be declined, so that posting is allowed to overdraw the source.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - authorizationID: The ID of the authorization.
+// - amount: The cleared amount in precise units.
+//
+// Returns:
+// - *model.CardAuthorization: The cleared authorization.
+// - error: An error if the authorization is closed or a posting fails.
+func (l *Blnk) ClearCardAuthorization(ctx context.Context, authorizationID string, amount *big.Int) (*model.CardAuthorization, error) {
+	if amount == nil || amount.Sign() < 0 {
+		return nil, errors.New("amount must not be negative")
+	}
+	return l.updateCardAuthorization(ctx, authorizationID, model.CardEventClearing, func(auth *model.CardAuthorization) error {
+		var txnIDs []string
+		remaining := new(big.Int).Set(amount)
+		for i := range auth.Holds {
+			hold := &auth.Holds[i]
+			if hold.Status != model.CardHoldHeld {
+				continue
+			}
+			if remaining.Sign() > 0 {
+				commit := new(big.Int).Set(hold.Amount)
+				if remaining.Cmp(commit) < 0 {
+					commit.Set(remaining)
+				}
+				committed, err := l.CommitInflightTransaction(ctx, hold.TransactionID, commit)
+				if err != nil {
+					return fmt.Errorf("failed to commit hold %s: %w", hold.TransactionID, err)
+				}
+				txnIDs = append(txnIDs, committed.TransactionID)
+				remaining.Sub(remaining, commit)
+				if commit.Cmp(hold.Amount) < 0 {
+					if _, err := l.VoidInflightTransaction(ctx, hold.TransactionID); err != nil {
+						return fmt.Errorf("failed to release hold %s: %w", hold.TransactionID, err)
+					}
+				}
+				hold.Status = model.CardHoldCleared
+				continue
+			}
+			voided, err := l.VoidInflightTransaction(ctx, hold.TransactionID)
+			if err != nil {
+				return fmt.Errorf("failed to release hold %s: %w", hold.TransactionID, err)
+			}
+			txnIDs = append(txnIDs, voided.TransactionID)
+			hold.Status = model.CardHoldReleased
+		}
+
+		if remaining.Sign() > 0 {
+			txn, err := l.QueueTransaction(ctx, &model.Transaction{
+				Source:         auth.Source,
+				Destination:    auth.Destination,
+				PreciseAmount:  new(big.Int).Set(remaining),
+				Precision:      auth.Precision,
+				Currency:       auth.Currency,
+				Reference:      fmt.Sprintf("%s_clearing", auth.AuthorizationID),
+				Description:    fmt.Sprintf("%s clearing above authorized amount", auth.Scheme),
+				AllowOverdraft: true,
+				SkipQueue:      true,
+				MetaData:       map[string]interface{}{cardAuthorizationMetaKey: auth.AuthorizationID},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to post clearing above authorized amount: %w", err)
+			}
+			txnIDs = append(txnIDs, txn.TransactionID)
+		}
+
+		auth.ClearedAmount.Set(amount)
+		auth.Status = model.CardAuthorizationCleared
+		auth.RecordEvent(model.CardEventClearing, amount, txnIDs...)
+		return nil
+	})
+}
+
+// ExpireCardAuthorizations releases the holds of authorizations that passed their expiry without being
+// cleared. Holds the inflight expiry worker already voided are marked released as well. Authorizations whose
+// holds are on a ledger in maintenance mode are left for a later run.
+//
+// Parameters:
+// - ctx: The context for the operation.
+//
+// Returns:
+// - int: The number of authorizations expired.
+// - error: An error if the expired authorizations could not be loaded.
+func (l *Blnk) ExpireCardAuthorizations(ctx context.Context) (int, error) {
+	auths, err := l.datasource.GetExpiredCardAuthorizations(ctx, time.Now(), cardAuthorizationExpiryBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, candidate := range auths {
+		_, err := l.updateCardAuthorization(ctx, candidate.AuthorizationID, model.CardEventExpiry, func(auth *model.CardAuthorization) error {
+			held := auth.HeldAmount()
+			var txnIDs []string
+			for i := range auth.Holds {
+				hold := &auth.Holds[i]
+				if hold.Status != model.CardHoldHeld {
+					continue
+				}
+				if voided, err := l.VoidInflightTransaction(WithStatusReason(ctx, model.ReasonInflightExpired, "card authorization expired"), hold.TransactionID); err == nil {
+					txnIDs = append(txnIDs, voided.TransactionID)
+				} else if !strings.Contains(err.Error(), "already been voided") {
+					return fmt.Errorf("failed to release hold %s: %w", hold.TransactionID, err)
+				}
+				hold.Status = model.CardHoldReleased
+			}
+			auth.Status = model.CardAuthorizationExpired
+			auth.RecordEvent(model.CardEventExpiry, held, txnIDs...)
+			return nil
+		})
+		if errors.Is(err, ErrMaintenanceMode) {
+			// Expired once the ledger of its holds leaves maintenance
+			continue
+		}
+		if err != nil {
+			logrus.WithError(err).WithField("authorization_id", candidate.AuthorizationID).Error("failed to expire card authorization")
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// updateCardAuthorization applies a lifecycle step to an open authorization under a lock and saves the result.
+// Authorizations past their expiry only accept the expiry step.
+func (l *Blnk) updateCardAuthorization(ctx context.Context, authorizationID, event string, apply func(*model.CardAuthorization) error) (*model.CardAuthorization, error) {
+	locker := redlock.NewLocker(l.redis, "card-authorization:"+authorizationID, model.GenerateUUIDWithSuffix("loc"))
+	if err := locker.Lock(ctx, cardAuthorizationLockTimeout); err != nil {
+		return nil, fmt.Errorf("failed to acquire lock for card authorization %s: %w", authorizationID, err)
+	}
+	defer l.releaseLock(ctx, locker)
+
+	auth, err := l.datasource.GetCardAuthorization(ctx, authorizationID)
+	if err != nil {
+		return nil, err
+	}
+	if auth.Status != model.CardAuthorizationAuthorized {
+		return nil, fmt.Errorf("%w: status is %s", ErrCardAuthorizationClosed, auth.Status)
+	}
+	if event != model.CardEventExpiry && !time.Now().Before(auth.ExpiresAt) {
+		return nil, fmt.Errorf("%w: authorization expired at %s", ErrCardAuthorizationClosed, auth.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+
+	applyErr := apply(auth)
+	// Holds placed or released before a failure are real postings, so the state is saved either way.
+	auth.UpdatedAt = time.Now()
+	if err := l.datasource.UpdateCardAuthorization(ctx, auth); err != nil {
+		return nil, err
+	}
+	if applyErr != nil {
+		return nil, applyErr
+	}
+	l.sendCardAuthorizationWebhook(event, auth)
+	return auth, nil
+}
+
+// placeCardHold places an inflight transaction for an authorization and records it as a hold.
+func (l *Blnk) placeCardHold(ctx context.Context, auth *model.CardAuthorization, amount *big.Int) (*model.CardAuthorizationHold, error) {
+	txn, err := l.QueueTransaction(ctx, &model.Transaction{
+		Source:             auth.Source,
+		Destination:        auth.Destination,
+		PreciseAmount:      new(big.Int).Set(amount),
+		Precision:          auth.Precision,
+		Currency:           auth.Currency,
+		Reference:          fmt.Sprintf("%s_hold_%d", auth.AuthorizationID, len(auth.Holds)+1),
+		Description:        fmt.Sprintf("%s authorization %s", auth.Scheme, auth.Reference),
+		Inflight:           true,
+		InflightExpiryDate: auth.ExpiresAt,
+		SkipQueue:          true,
+		MetaData:           map[string]interface{}{cardAuthorizationMetaKey: auth.AuthorizationID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	auth.Holds = append(auth.Holds, model.CardAuthorizationHold{
+		TransactionID: txn.TransactionID,
+		Amount:        new(big.Int).Set(amount),
+		Status:        model.CardHoldHeld,
+	})
+	return &auth.Holds[len(auth.Holds)-1], nil
+}
+
+// releaseCardHolds releases amount from the newest holds first. A hold that is only partly released is
+// voided and replaced by a new hold for the part that stays authorized.
+func (l *Blnk) releaseCardHolds(ctx context.Context, auth *model.CardAuthorization, amount *big.Int) ([]string, error) {
+	var txnIDs []string
+	remaining := new(big.Int).Set(amount)
+	for i := len(auth.Holds) - 1; i >= 0 && remaining.Sign() > 0; i-- {
+		if auth.Holds[i].Status != model.CardHoldHeld {
+			continue
+		}
+		holdAmount := new(big.Int).Set(auth.Holds[i].Amount)
+		voided, err := l.VoidInflightTransaction(ctx, auth.Holds[i].TransactionID)
+		if err != nil {
+			return txnIDs, fmt.Errorf("failed to release hold %s: %w", auth.Holds[i].TransactionID, err)
+		}
+		auth.Holds[i].Status = model.CardHoldReleased
+		txnIDs = append(txnIDs, voided.TransactionID)
+
+		if holdAmount.Cmp(remaining) > 0 {
+			kept := new(big.Int).Sub(holdAmount, remaining)
+			hold, err := l.placeCardHold(ctx, auth, kept)
+			if err != nil {
+				return txnIDs, fmt.Errorf("failed to re-hold remaining amount %s: %w", kept, err)
+			}
+			txnIDs = append(txnIDs, hold.TransactionID)
+			remaining.SetInt64(0)
+			break
+		}
+		remaining.Sub(remaining, holdAmount)
+	}
+	return txnIDs, nil
+}
+
+// sendCardAuthorizationWebhook notifies subscribers of a lifecycle step, for example card_authorization.clearing.
+func (l *Blnk) sendCardAuthorizationWebhook(event string, auth *model.CardAuthorization) {
+	payload := *auth
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: "card_authorization." + event, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newCardAuthorizationTestBlnk(t *testing.T) (*Blnk, *mocks.MockDataSource) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis: config.RedisConfig{Dns: mr.Addr()},
+		Queue: config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+		CardAuthorization: config.CardAuthorizationConfig{
+			DefaultExpiry: 7 * 24 * time.Hour,
+			SchemeExpiry:  map[string]time.Duration{"mastercard": 30 * 24 * time.Hour},
+		},
+	})
+
+	mockDS := new(mocks.MockDataSource)
+	b, err := NewBlnk(mockDS)
+	require.NoError(t, err)
+	return b, mockDS
+}
+
+func TestCardAuthorizationExpiry_PerScheme(t *testing.T) {
+	newCardAuthorizationTestBlnk(t)
+
+	assert.Equal(t, 30*24*time.Hour, cardAuthorizationExpiry("MasterCard"))
+	assert.Equal(t, 7*24*time.Hour, cardAuthorizationExpiry("visa"))
+}
+
+func TestAuthorizeCard_Validation(t *testing.T) {
+	b, mockDS := newCardAuthorizationTestBlnk(t)
+	ctx := context.Background()
+
+	_, err := b.AuthorizeCard(ctx, model.CardAuthorization{Scheme: "visa", Source: "bln_a", Destination: "bln_b", Currency: "USD"}, big.NewInt(100))
+	assert.ErrorContains(t, err, "reference")
+
+	_, err = b.AuthorizeCard(ctx, model.CardAuthorization{Scheme: "visa", Reference: "rrn_1", Source: "bln_a", Destination: "bln_b", Currency: "USD"}, big.NewInt(0))
+	assert.ErrorContains(t, err, "greater than zero")
+
+	_, err = b.AuthorizeCard(ctx, model.CardAuthorization{
+		Scheme: "visa", Reference: "rrn_1", Source: "bln_a", Destination: "bln_b", Currency: "USD", ExpiresAt: time.Now().Add(-time.Hour),
+	}, big.NewInt(100))
+	assert.ErrorContains(t, err, "expires_at")
+
+	mockDS.AssertNotCalled(t, "CreateCardAuthorization", mock.Anything, mock.Anything)
+}
+
+func TestCardAuthorization_ClosedAuthorizationRejectsChanges(t *testing.T) {
+	b, mockDS := newCardAuthorizationTestBlnk(t)
+	ctx := context.Background()
+
+	mockDS.On("GetCardAuthorization", mock.Anything, "cauth_cleared").Return(&model.CardAuthorization{
+		AuthorizationID: "cauth_cleared", Status: model.CardAuthorizationCleared, ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+	mockDS.On("GetCardAuthorization", mock.Anything, "cauth_expired").Return(&model.CardAuthorization{
+		AuthorizationID: "cauth_expired", Status: model.CardAuthorizationAuthorized, ExpiresAt: time.Now().Add(-time.Minute),
+	}, nil)
+
+	_, err := b.IncrementCardAuthorization(ctx, "cauth_cleared", big.NewInt(100))
+	assert.True(t, errors.Is(err, ErrCardAuthorizationClosed))
+
+	_, err = b.ClearCardAuthorization(ctx, "cauth_expired", big.NewInt(100))
+	assert.True(t, errors.Is(err, ErrCardAuthorizationClosed))
+
+	mockDS.AssertNotCalled(t, "UpdateCardAuthorization", mock.Anything, mock.Anything)
+}
+
+func TestReverseCardAuthorization_AmountAboveHeld(t *testing.T) {
+	b, mockDS := newCardAuthorizationTestBlnk(t)
+
+	auth := &model.CardAuthorization{
+		AuthorizationID:  "cauth_1",
+		Status:           model.CardAuthorizationAuthorized,
+		AuthorizedAmount: big.NewInt(500),
+		ReversedAmount:   big.NewInt(0),
+		ClearedAmount:    big.NewInt(0),
+		Holds: []model.CardAuthorizationHold{
+			{TransactionID: "txn_1", Amount: big.NewInt(300), Status: model.CardHoldHeld},
+			{TransactionID: "txn_2", Amount: big.NewInt(200), Status: model.CardHoldReleased},
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	mockDS.On("GetCardAuthorization", mock.Anything, "cauth_1").Return(auth, nil)
+	mockDS.On("UpdateCardAuthorization", mock.Anything, auth).Return(nil)
+
+	assert.Equal(t, "300", auth.HeldAmount().String())
+
+	_, err := b.ReverseCardAuthorization(context.Background(), "cauth_1", big.NewInt(400))
+	assert.ErrorContains(t, err, "exceeds held amount")
+	assert.Equal(t, model.CardAuthorizationAuthorized, auth.Status)
+	assert.Empty(t, auth.Events)
+}
+
+func TestExpireCardAuthorizations_LeavesLedgerInMaintenance(t *testing.T) {
+	b, mockDS := newCardAuthorizationTestBlnk(t)
+	ctx := context.Background()
+
+	auth := &model.CardAuthorization{
+		AuthorizationID:  "cauth_1",
+		Status:           model.CardAuthorizationAuthorized,
+		AuthorizedAmount: big.NewInt(300),
+		ReversedAmount:   big.NewInt(0),
+		ClearedAmount:    big.NewInt(0),
+		Holds:            []model.CardAuthorizationHold{{TransactionID: "txn_hold", Amount: big.NewInt(300), Status: model.CardHoldHeld}},
+		ExpiresAt:        time.Now().Add(-time.Minute),
+	}
+	mockDS.On("GetExpiredCardAuthorizations", mock.Anything, mock.Anything, cardAuthorizationExpiryBatch).Return([]*model.CardAuthorization{auth}, nil)
+	mockDS.On("GetCardAuthorization", mock.Anything, "cauth_1").Return(auth, nil)
+	mockDS.On("UpdateCardAuthorization", mock.Anything, auth).Return(nil)
+	mockDS.On("GetTransaction", mock.Anything, "txn_hold").Return(&model.Transaction{
+		TransactionID: "txn_hold", Source: "bln_card", Destination: "bln_settlement", Currency: "USD", Status: StatusInflight,
+	}, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_card").Return(&model.Balance{BalanceID: "bln_card", LedgerID: "ldg_cards"}, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_settlement").Return(&model.Balance{BalanceID: "bln_settlement", LedgerID: "ldg_cards"}, nil)
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_cards").Return(&model.Ledger{LedgerID: "ldg_cards"}, nil)
+
+	_, err := b.SetMaintenanceMode(ctx, model.MaintenanceMode{LedgerID: "ldg_cards", Enabled: true})
+	require.NoError(t, err)
+
+	expired, err := b.ExpireCardAuthorizations(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+	assert.Equal(t, model.CardAuthorizationAuthorized, auth.Status)
+	assert.Equal(t, model.CardHoldHeld, auth.Holds[0].Status)
+}
@@ -0,0 +1,440 @@
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/internal/resilience"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+// StatusChallenged is the status of a transaction held back until the customer passes a strong customer
+// authentication challenge. It is queued once the challenge is confirmed, and rejected otherwise.
+const StatusChallenged = "CHALLENGED"
+
+const (
+	// challengeMetaKey links a transaction to its challenge, and marks transactions released by a confirmed
+	// challenge so they are not challenged again.
+	challengeMetaKey = "BLNK_CHALLENGE_ID"
+
+	// challengeSignatureTolerance is how far the timestamp of a callback signature may be from now, which
+	// stops captured callbacks from being replayed later.
+	challengeSignatureTolerance = 5 * time.Minute
+
+	// challengeSignatureKey marks a callback signature as used until it can no longer be within the tolerance,
+	// which stops captured callbacks from being replayed sooner.
+	challengeSignatureKey = "challenge_callback_signature"
+)
+
+// Errors returned for callbacks that cannot resolve their challenge.
+var (
+	ErrInvalidChallengeSignature = errors.New("invalid challenge callback signature")
+	ErrChallengeResolved         = errors.New("challenge has already been resolved")
+	ErrChallengeExpired          = errors.New("challenge has expired")
+)
+
+// challengeRuleFor returns the first configured challenge rule a transaction matches, or nil when it does not
+// need a challenge.
+func challengeRuleFor(transaction *model.Transaction) *config.ChallengeRule {
+	if _, released := transaction.MetaData[challengeMetaKey]; released {
+		return nil
+	}
+	if _, ok := transaction.MetaData[nettingSettlementMetaKey]; ok {
+		return nil
+	}
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil
+	}
+
+	for i := range cnf.Challenge.Rules {
+		rule := &cnf.Challenge.Rules[i]
+		if rule.Currency != "" && !strings.EqualFold(rule.Currency, transaction.Currency) {
+			continue
+		}
+		if rule.MinAmount > 0 && transaction.Amount < rule.MinAmount {
+			continue
+		}
+		if len(rule.Sources) > 0 && !containsString(rule.Sources, transaction.Source) {
+			continue
+		}
+		if rule.MetaDataKey != "" {
+			if _, ok := transaction.MetaData[rule.MetaDataKey]; !ok {
+				continue
+			}
+		}
+		return rule
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// challengeTransaction holds a transaction back behind a challenge instead of queueing it, and sends the
+// challenge to the authentication system.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - transaction: The transaction to hold back. Its metadata and precise amount must already be set.
+// - rule: The rule the transaction matched.
+//
+// Returns:
+// - *model.Transaction: The transaction with status CHALLENGED.
+// - error: An error if the reference has been used or the challenge could not be saved.
+func (l *Blnk) challengeTransaction(ctx context.Context, transaction *model.Transaction, rule *config.ChallengeRule) (*model.Transaction, error) {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	exists, err := l.datasource.TransactionExistsByRef(ctx, transaction.Reference)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("reference %s has already been used", transaction.Reference)
+	}
+
+	now := time.Now()
+	challenge := &model.TransactionChallenge{
+		ChallengeID:   model.GenerateUUIDWithSuffix("chl"),
+		TransactionID: transaction.TransactionID,
+		Reference:     transaction.Reference,
+		Rule:          rule.Name,
+		Status:        model.ChallengePending,
+		Transaction:   transaction,
+		Trail:         []model.ChallengeEvent{{Event: model.ChallengeEventCreated, Detail: "matched rule " + rule.Name, At: now}},
+		ExpiresAt:     now.Add(cnf.Challenge.Timeout),
+		CreatedAt:     now,
+	}
+	if err := l.datasource.CreateTransactionChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	transaction.Status = StatusChallenged
+	transaction.MetaData[challengeMetaKey] = challenge.ChallengeID
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		event := model.ChallengeEvent{Event: model.ChallengeEventSent, At: time.Now()}
+		if err := l.sendChallenge(ctx, cnf.Challenge, challenge); err != nil {
+			event = model.ChallengeEvent{Event: model.ChallengeEventSendFailed, Detail: err.Error(), At: time.Now()}
+			notification.NotifyError(err)
+		}
+		if cnf.Challenge.URL != "" {
+			if err := l.datasource.AppendChallengeEvent(ctx, challenge.ChallengeID, event); err != nil {
+				logrus.Errorf("failed to record challenge event: %v", err)
+			}
+		}
+		if err := l.SendWebhook(NewWebhook{Event: getEventFromStatus(StatusChallenged), Payload: transaction}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+	return transaction, nil
+}
+
+// challengeRequest is what the authentication system is sent about a new challenge.
+type challengeRequest struct {
+	ChallengeID   string    `json:"challenge_id"`
+	TransactionID string    `json:"transaction_id"`
+	Reference     string    `json:"reference"`
+	Rule          string    `json:"rule"`
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	Source        string    `json:"source"`
+	Destination   string    `json:"destination"`
+	Description   string    `json:"description,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// sendChallenge posts a challenge to the authentication system, signed with the callback secret so the
+// system can tell it came from the ledger. Nothing is sent when no URL is configured, in which case the
+// authentication system learns of challenges from the transaction.challenged webhook.
+func (l *Blnk) sendChallenge(ctx context.Context, cnf config.ChallengeConfig, challenge *model.TransactionChallenge) error {
+	if cnf.URL == "" {
+		return nil
+	}
+	txn := challenge.Transaction
+	body, err := json.Marshal(challengeRequest{
+		ChallengeID:   challenge.ChallengeID,
+		TransactionID: challenge.TransactionID,
+		Reference:     challenge.Reference,
+		Rule:          challenge.Rule,
+		Amount:        txn.Amount,
+		Currency:      txn.Currency,
+		Source:        txn.Source,
+		Destination:   txn.Destination,
+		Description:   txn.Description,
+		ExpiresAt:     challenge.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cnf.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookSignatureHeader, signWebhookPayload(body, []string{cnf.CallbackSecret}, time.Now()))
+
+	resp, err := resilience.Get(resilience.Challenge).Wrap(l.httpClient).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("challenge request failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// verifyChallengeSignature checks a callback's signature header, which is built like the signature of webhook
+// deliveries: "t=<unix time>,v1=<hex HMAC-SHA256 of '<unix time>.<body>'>". It returns the signature that
+// matched.
+func verifyChallengeSignature(body []byte, header, secret string, now time.Time) (string, error) {
+	if secret == "" || header == "" {
+		return "", ErrInvalidChallengeSignature
+	}
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", ErrInvalidChallengeSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > challengeSignatureTolerance || age < -challengeSignatureTolerance {
+		return "", ErrInvalidChallengeSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return hex.EncodeToString(expected), nil
+		}
+	}
+	return "", ErrInvalidChallengeSignature
+}
+
+// claimChallengeSignature marks a callback signature as used, and fails when it has been used before.
+func (l *Blnk) claimChallengeSignature(ctx context.Context, signature string) error {
+	claimed, err := l.redis.SetNX(ctx, fmt.Sprintf("%s:%s", challengeSignatureKey, signature), 1, 2*challengeSignatureTolerance).Result()
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return fmt.Errorf("%w: the signature has already been used", ErrInvalidChallengeSignature)
+	}
+	return nil
+}
+
+// HandleChallengeCallback resolves a challenge with the result the authentication system reports. A confirmed
+// challenge queues its transaction; a failed one rejects it. The callback must name the challenge it is for, and
+// each signature is accepted once. Every callback is added to the challenge's trail, including those with an
+// invalid signature.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - challengeID: The ID of the challenge.
+// - body: The raw request body, a model.ChallengeCallback.
+// - signature: The value of the X-Blnk-Signature header.
+//
+// Returns:
+//   - *model.TransactionChallenge: The resolved challenge.
+//   - error: ErrInvalidChallengeSignature, ErrChallengeResolved or ErrChallengeExpired when the callback cannot
+//     resolve the challenge, or an error if the body is invalid or the challenge cannot be found.
+func (l *Blnk) HandleChallengeCallback(ctx context.Context, challengeID string, body []byte, signature string) (*model.TransactionChallenge, error) {
+	ctx, span := tracer.Start(ctx, "HandleChallengeCallback")
+	defer span.End()
+
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := l.datasource.GetTransactionChallenge(ctx, challengeID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	verified, err := verifyChallengeSignature(body, signature, cnf.Challenge.CallbackSecret, now)
+	if err != nil {
+		span.RecordError(err)
+		l.appendChallengeEvent(ctx, challengeID, model.ChallengeEvent{Event: model.ChallengeEventInvalidSignature, At: now})
+		return nil, err
+	}
+
+	var callback model.ChallengeCallback
+	if err := json.Unmarshal(body, &callback); err != nil {
+		return nil, fmt.Errorf("invalid challenge callback: %w", err)
+	}
+	if callback.Status != model.ChallengeConfirmed && callback.Status != model.ChallengeFailed {
+		return nil, fmt.Errorf("invalid challenge callback status %q, expected confirmed or failed", callback.Status)
+	}
+	// The signature covers the body only, so the body must name the challenge it was signed for
+	if callback.ChallengeID != challengeID {
+		err := fmt.Errorf("%w: the callback is for challenge %q", ErrInvalidChallengeSignature, callback.ChallengeID)
+		span.RecordError(err)
+		l.appendChallengeEvent(ctx, challengeID, model.ChallengeEvent{Event: model.ChallengeEventInvalidSignature, Detail: err.Error(), At: now})
+		return nil, err
+	}
+	if err := l.claimChallengeSignature(ctx, verified); err != nil {
+		span.RecordError(err)
+		l.appendChallengeEvent(ctx, challengeID, model.ChallengeEvent{Event: model.ChallengeEventInvalidSignature, Detail: err.Error(), At: now})
+		return nil, err
+	}
+	l.appendChallengeEvent(ctx, challengeID, model.ChallengeEvent{Event: model.ChallengeEventCallback, Detail: callback.Status, At: now})
+
+	if challenge.Status != model.ChallengePending {
+		return nil, ErrChallengeResolved
+	}
+	if !now.Before(challenge.ExpiresAt) {
+		if err := l.expireChallenge(ctx, challenge); err != nil {
+			return nil, err
+		}
+		return nil, ErrChallengeExpired
+	}
+
+	if callback.Status == model.ChallengeFailed {
+		reason := "challenge failed"
+		if callback.Reason != "" {
+			reason += ": " + callback.Reason
+		}
+		if err := l.resolveChallenge(ctx, challenge, model.ChallengeFailed, model.ChallengeEventFailed, model.ReasonChallengeFailed, reason); err != nil {
+			return nil, err
+		}
+		return challenge, nil
+	}
+
+	resolved, err := l.datasource.ResolveTransactionChallenge(ctx, challengeID, model.ChallengeConfirmed, model.ChallengeEvent{Event: model.ChallengeEventConfirmed, At: now})
+	if err != nil {
+		return nil, err
+	}
+	if !resolved {
+		return nil, ErrChallengeResolved
+	}
+	challenge.Status = model.ChallengeConfirmed
+	challenge.ResolvedAt = &now
+
+	// The transaction was held back before it was queued, so it is released through the same path
+	transaction := challenge.Transaction
+	transaction.Status = ""
+	if transaction.MetaData == nil {
+		transaction.MetaData = make(map[string]interface{})
+	}
+	transaction.MetaData[challengeMetaKey] = challenge.ChallengeID
+	if _, err := l.QueueTransaction(ctx, transaction); err != nil {
+		span.RecordError(err)
+		l.appendChallengeEvent(ctx, challengeID, model.ChallengeEvent{Event: model.ChallengeEventReleaseFailed, Detail: err.Error(), At: time.Now()})
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// ExpireTransactionChallenges rejects the transactions of pending challenges that had no result before they
+// timed out.
+//
+// Parameters:
+// - ctx: The context for the operation.
+//
+// Returns:
+// - int: The number of challenges that expired.
+// - error: An error if the expired challenges cannot be retrieved.
+func (l *Blnk) ExpireTransactionChallenges(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "ExpireTransactionChallenges")
+	defer span.End()
+
+	challenges, err := l.datasource.GetExpiredTransactionChallenges(ctx, time.Now(), 100)
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+
+	expired := 0
+	for _, challenge := range challenges {
+		if err := l.expireChallenge(ctx, challenge); err != nil {
+			if !errors.Is(err, ErrChallengeResolved) {
+				logrus.Errorf("failed to expire challenge %s: %v", challenge.ChallengeID, err)
+			}
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+func (l *Blnk) expireChallenge(ctx context.Context, challenge *model.TransactionChallenge) error {
+	return l.resolveChallenge(ctx, challenge, model.ChallengeExpired, model.ChallengeEventExpired, model.ReasonChallengeExpired, "challenge expired")
+}
+
+// resolveChallenge moves a pending challenge to a final status that rejects its transaction, and records the
+// rejection.
+func (l *Blnk) resolveChallenge(ctx context.Context, challenge *model.TransactionChallenge, status, event, reasonCode, reason string) error {
+	now := time.Now()
+	resolved, err := l.datasource.ResolveTransactionChallenge(ctx, challenge.ChallengeID, status, model.ChallengeEvent{Event: event, Detail: reason, At: now})
+	if err != nil {
+		return err
+	}
+	if !resolved {
+		return ErrChallengeResolved
+	}
+	challenge.Status = status
+	challenge.ResolvedAt = &now
+
+	transaction := challenge.Transaction
+	if transaction.MetaData == nil {
+		transaction.MetaData = make(map[string]interface{})
+	}
+	transaction.MetaData[challengeMetaKey] = challenge.ChallengeID
+	_, err = l.RejectTransaction(WithStatusReason(ctx, reasonCode, reason), transaction, reason)
+	return err
+}
+
+func (l *Blnk) appendChallengeEvent(ctx context.Context, challengeID string, event model.ChallengeEvent) {
+	if err := l.datasource.AppendChallengeEvent(ctx, challengeID, event); err != nil {
+		logrus.Errorf("failed to record challenge event: %v", err)
+	}
+}
+
+// GetTransactionChallenge retrieves a challenge with its trail.
+func (l *Blnk) GetTransactionChallenge(ctx context.Context, challengeID string) (*model.TransactionChallenge, error) {
+	return l.datasource.GetTransactionChallenge(ctx, challengeID)
+}
+
+// ListTransactionChallenges lists challenges, newest first, optionally only those with a status.
+func (l *Blnk) ListTransactionChallenges(ctx context.Context, status string, page model.Page) ([]*model.TransactionChallenge, error) {
+	return l.datasource.ListTransactionChallenges(ctx, status, page)
+}
@@ -0,0 +1,219 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func useChallengeConfig(t *testing.T, rules ...config.ChallengeRule) {
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	withChallenge := *cnf
+	withChallenge.Challenge = config.ChallengeConfig{CallbackSecret: "callback-secret", Timeout: 5 * time.Minute, Rules: rules}
+	config.ConfigStore.Store(&withChallenge)
+}
+
+func signedCallback(body string, at time.Time) string {
+	return signWebhookPayload([]byte(body), []string{"callback-secret"}, at)
+}
+
+func TestChallengeRuleFor(t *testing.T) {
+	newBalanceNotificationTestBlnk(t)
+	useChallengeConfig(t,
+		config.ChallengeRule{Name: "large-usd", Currency: "USD", MinAmount: 1000},
+		config.ChallengeRule{Name: "flagged", MetaDataKey: "requires_sca"},
+	)
+
+	rule := challengeRuleFor(&model.Transaction{Currency: "usd", Amount: 5000})
+	require.NotNil(t, rule)
+	assert.Equal(t, "large-usd", rule.Name)
+
+	assert.Nil(t, challengeRuleFor(&model.Transaction{Currency: "USD", Amount: 50}))
+
+	rule = challengeRuleFor(&model.Transaction{Currency: "EUR", MetaData: map[string]interface{}{"requires_sca": true}})
+	require.NotNil(t, rule)
+	assert.Equal(t, "flagged", rule.Name)
+
+	// Transactions released by a confirmed challenge are not challenged again
+	assert.Nil(t, challengeRuleFor(&model.Transaction{Currency: "USD", Amount: 5000, MetaData: map[string]interface{}{challengeMetaKey: "chl_1"}}))
+}
+
+func TestVerifyChallengeSignature(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"status":"confirmed"}`)
+
+	verified, err := verifyChallengeSignature(body, signWebhookPayload(body, []string{"secret"}, now), "secret", now)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, verified)
+	_, err = verifyChallengeSignature(body, signWebhookPayload(body, []string{"other"}, now), "secret", now)
+	assert.ErrorIs(t, err, ErrInvalidChallengeSignature)
+	_, err = verifyChallengeSignature([]byte(`{"status":"failed"}`), signWebhookPayload(body, []string{"secret"}, now), "secret", now)
+	assert.ErrorIs(t, err, ErrInvalidChallengeSignature)
+	_, err = verifyChallengeSignature(body, signWebhookPayload(body, []string{"secret"}, now.Add(-time.Hour)), "secret", now)
+	assert.ErrorIs(t, err, ErrInvalidChallengeSignature)
+	_, err = verifyChallengeSignature(body, "", "secret", now)
+	assert.ErrorIs(t, err, ErrInvalidChallengeSignature)
+}
+
+func pendingChallenge(expiresAt time.Time) *model.TransactionChallenge {
+	return &model.TransactionChallenge{
+		ChallengeID:   "chl_1",
+		TransactionID: "txn_1",
+		Reference:     "ref_1",
+		Rule:          "large-usd",
+		Status:        model.ChallengePending,
+		Transaction: &model.Transaction{
+			TransactionID: "txn_1",
+			Reference:     "ref_1",
+			Source:        "bln_a",
+			Destination:   "bln_b",
+			Amount:        5000,
+			PreciseAmount: big.NewInt(500000),
+			Precision:     100,
+			Currency:      "USD",
+			MetaData:      map[string]interface{}{},
+		},
+		ExpiresAt: expiresAt,
+	}
+}
+
+func TestHandleChallengeCallback_Failed(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	useChallengeConfig(t)
+
+	body := `{"challenge_id":"chl_1","status":"failed","reason":"wrong code"}`
+	mockDS.On("GetTransactionChallenge", mock.Anything, "chl_1").Return(pendingChallenge(time.Now().Add(time.Minute)), nil)
+	mockDS.On("AppendChallengeEvent", mock.Anything, "chl_1", mock.MatchedBy(func(e model.ChallengeEvent) bool {
+		return e.Event == model.ChallengeEventCallback && e.Detail == model.ChallengeFailed
+	})).Return(nil).Once()
+	mockDS.On("ResolveTransactionChallenge", mock.Anything, "chl_1", model.ChallengeFailed, mock.Anything).Return(true, nil).Once()
+	mockDS.On("RecordTransaction", mock.Anything, mock.MatchedBy(func(txn *model.Transaction) bool {
+		return txn.Status == StatusRejected && txn.StatusChange.ReasonCode == model.ReasonChallengeFailed &&
+			txn.StatusChange.Reason == "challenge failed: wrong code"
+	})).Return(&model.Transaction{TransactionID: "txn_1", Status: StatusRejected}, nil).Once()
+
+	challenge, err := b.HandleChallengeCallback(context.Background(), "chl_1", []byte(body), signedCallback(body, time.Now()))
+	require.NoError(t, err)
+	assert.Equal(t, model.ChallengeFailed, challenge.Status)
+	mockDS.AssertExpectations(t)
+}
+
+func TestHandleChallengeCallback_InvalidSignature(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	useChallengeConfig(t)
+
+	mockDS.On("GetTransactionChallenge", mock.Anything, "chl_1").Return(pendingChallenge(time.Now().Add(time.Minute)), nil)
+	mockDS.On("AppendChallengeEvent", mock.Anything, "chl_1", mock.MatchedBy(func(e model.ChallengeEvent) bool {
+		return e.Event == model.ChallengeEventInvalidSignature
+	})).Return(nil).Once()
+
+	_, err := b.HandleChallengeCallback(context.Background(), "chl_1", []byte(`{"status":"confirmed"}`), "t=1,v1=00")
+	assert.ErrorIs(t, err, ErrInvalidChallengeSignature)
+	mockDS.AssertNotCalled(t, "ResolveTransactionChallenge", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleChallengeCallback_RejectsReplays(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	useChallengeConfig(t)
+
+	other := pendingChallenge(time.Now().Add(time.Minute))
+	other.ChallengeID = "chl_2"
+	body := `{"challenge_id":"chl_1","status":"failed"}`
+	signature := signedCallback(body, time.Now())
+	mockDS.On("GetTransactionChallenge", mock.Anything, "chl_1").Return(pendingChallenge(time.Now().Add(time.Minute)), nil)
+	mockDS.On("GetTransactionChallenge", mock.Anything, "chl_2").Return(other, nil)
+	mockDS.On("AppendChallengeEvent", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDS.On("ResolveTransactionChallenge", mock.Anything, "chl_1", model.ChallengeFailed, mock.Anything).Return(true, nil).Once()
+	mockDS.On("RecordTransaction", mock.Anything, mock.Anything).Return(&model.Transaction{TransactionID: "txn_1", Status: StatusRejected}, nil).Once()
+
+	// A callback signed for one challenge cannot resolve another
+	_, err := b.HandleChallengeCallback(context.Background(), "chl_2", []byte(body), signature)
+	assert.ErrorIs(t, err, ErrInvalidChallengeSignature)
+	mockDS.AssertNotCalled(t, "ResolveTransactionChallenge", mock.Anything, "chl_2", mock.Anything, mock.Anything)
+
+	_, err = b.HandleChallengeCallback(context.Background(), "chl_1", []byte(body), signature)
+	require.NoError(t, err)
+
+	// Nor can it be used twice
+	_, err = b.HandleChallengeCallback(context.Background(), "chl_1", []byte(body), signature)
+	assert.ErrorIs(t, err, ErrInvalidChallengeSignature)
+	mockDS.AssertExpectations(t)
+}
+
+func TestHandleChallengeCallback_AfterTimeout(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	useChallengeConfig(t)
+
+	body := `{"challenge_id":"chl_1","status":"confirmed"}`
+	mockDS.On("GetTransactionChallenge", mock.Anything, "chl_1").Return(pendingChallenge(time.Now().Add(-time.Second)), nil)
+	mockDS.On("AppendChallengeEvent", mock.Anything, "chl_1", mock.Anything).Return(nil)
+	mockDS.On("ResolveTransactionChallenge", mock.Anything, "chl_1", model.ChallengeExpired, mock.Anything).Return(true, nil).Once()
+	mockDS.On("RecordTransaction", mock.Anything, mock.MatchedBy(func(txn *model.Transaction) bool {
+		return txn.Status == StatusRejected && txn.StatusChange.ReasonCode == model.ReasonChallengeExpired
+	})).Return(&model.Transaction{TransactionID: "txn_1", Status: StatusRejected}, nil).Once()
+
+	_, err := b.HandleChallengeCallback(context.Background(), "chl_1", []byte(body), signedCallback(body, time.Now()))
+	assert.ErrorIs(t, err, ErrChallengeExpired)
+	mockDS.AssertExpectations(t)
+}
+
+func TestExpireTransactionChallenges(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	useChallengeConfig(t)
+
+	first, second := pendingChallenge(time.Now().Add(-time.Minute)), pendingChallenge(time.Now().Add(-time.Minute))
+	second.ChallengeID = "chl_2"
+	mockDS.On("GetExpiredTransactionChallenges", mock.Anything, mock.Anything, 100).Return([]*model.TransactionChallenge{first, second}, nil)
+	mockDS.On("ResolveTransactionChallenge", mock.Anything, "chl_1", model.ChallengeExpired, mock.Anything).Return(true, nil)
+	// The second challenge was confirmed by a callback in the meantime
+	mockDS.On("ResolveTransactionChallenge", mock.Anything, "chl_2", model.ChallengeExpired, mock.Anything).Return(false, nil)
+	mockDS.On("RecordTransaction", mock.Anything, mock.Anything).Return(&model.Transaction{TransactionID: "txn_1", Status: StatusRejected}, nil).Once()
+
+	expired, err := b.ExpireTransactionChallenges(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	mockDS.AssertExpectations(t)
+}
+
+func TestExpireTransactionChallenges_Error(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	mockDS.On("GetExpiredTransactionChallenges", mock.Anything, mock.Anything, 100).Return([]*model.TransactionChallenge{}, errors.New("db down"))
+
+	_, err := b.ExpireTransactionChallenges(context.Background())
+	assert.Error(t, err)
+}
+
+func TestChallengeTransaction_HoldsTransactionBack(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	useChallengeConfig(t, config.ChallengeRule{Name: "large-usd", Currency: "USD", MinAmount: 1000})
+
+	mockDS.On("ListNettingGroups", mock.Anything, model.Page{}).Return([]*model.NettingGroup{}, nil)
+	mockDS.On("TransactionExistsByRef", mock.Anything, mock.Anything).Return(false, nil)
+	mockDS.On("CreateTransactionChallenge", mock.Anything, mock.MatchedBy(func(c *model.TransactionChallenge) bool {
+		return c.Rule == "large-usd" && c.Status == model.ChallengePending && c.ExpiresAt.After(time.Now().Add(4*time.Minute))
+	})).Return(nil).Once()
+
+	txn, err := b.QueueTransaction(context.Background(), &model.Transaction{
+		Reference:   fmt.Sprintf("ref_%d", time.Now().UnixNano()),
+		Source:      "bln_a",
+		Destination: "bln_b",
+		Amount:      5000,
+		Precision:   100,
+		Currency:    "USD",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusChallenged, txn.Status)
+	assert.NotEmpty(t, txn.MetaData[challengeMetaKey])
+	mockDS.AssertExpectations(t)
+}
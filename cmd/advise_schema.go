@@ -0,0 +1,68 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/spf13/cobra"
+)
+
+// adviseSchemaCommands creates the command that inspects the database and prints a JSON report of schema and
+// index recommendations. It only reads statistics, so it can run on a schedule against a live database.
+func adviseSchemaCommands(b *blnkInstance) *cobra.Command {
+	var limit int
+	var slowStatement time.Duration
+	var minRows int64
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "advise-schema",
+		Short: "recommend schema and index changes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report := b.blnk.AdviseSchema(context.Background(), blnk.SchemaAdvisorOptions{
+				Limit:             limit,
+				SlowStatementTime: slowStatement,
+				MinRows:           minRows,
+			})
+
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("error encoding report: %v", err)
+			}
+
+			if output == "" {
+				fmt.Println(string(data))
+			} else if err := os.WriteFile(output, data, 0o644); err != nil {
+				return fmt.Errorf("error writing report: %v", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 20, "maximum number of recommendations per check")
+	cmd.Flags().DurationVar(&slowStatement, "slow-statement", 100*time.Millisecond, "report statements averaging at least this long")
+	cmd.Flags().Int64Var(&minRows, "min-rows", 10000, "ignore tables with fewer rows than this when looking for missing indexes")
+	cmd.Flags().StringVar(&output, "output", "", "write the report to this file instead of stdout")
+
+	return cmd
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/spf13/cobra"
+)
+
+// anonymizeExportCommands creates the command that exports a time slice of a ledger with its personal data
+// replaced by realistic fakes, for sharing with vendors, training environments and debugging. The file it writes
+// can be replayed with replay run.
+func anonymizeExportCommands(b *blnkInstance) *cobra.Command {
+	var ledgerID, from, to, output, salt string
+	var perturbAmounts float64
+
+	cmd := &cobra.Command{
+		Use:   "anonymize-export",
+		Short: "export an anonymized time slice of a ledger",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := blnk.AnonymizeOptions{
+				ReplayExportOptions: blnk.ReplayExportOptions{LedgerID: ledgerID},
+				Salt:                salt,
+				PerturbAmounts:      perturbAmounts,
+			}
+			var err error
+			if opts.From, err = time.Parse(time.RFC3339, from); err != nil {
+				return fmt.Errorf("invalid --from: %v", err)
+			}
+			if to != "" {
+				if opts.To, err = time.Parse(time.RFC3339, to); err != nil {
+					return fmt.Errorf("invalid --to: %v", err)
+				}
+			}
+
+			slice, err := b.blnk.ExportAnonymizedSlice(context.Background(), opts)
+			if err != nil {
+				return fmt.Errorf("error exporting anonymized slice: %v", err)
+			}
+			return writeReplayJSON(slice, output)
+		},
+	}
+
+	cmd.Flags().StringVar(&ledgerID, "ledger", "", "ID of the ledger to export")
+	cmd.Flags().StringVar(&from, "from", "", "start of the slice (RFC3339)")
+	cmd.Flags().StringVar(&to, "to", "", "end of the slice (RFC3339), defaults to now")
+	cmd.Flags().StringVar(&output, "output", "", "write the slice to this file instead of stdout")
+	cmd.Flags().StringVar(&salt, "salt", "", "replace values with the same fakes as other exports with this salt, random by default")
+	cmd.Flags().Float64Var(&perturbAmounts, "perturb-amounts", 0, "move every amount by up to this fraction of it, such as 0.1")
+	_ = cmd.MarkFlagRequired("ledger")
+	_ = cmd.MarkFlagRequired("from")
+
+	return cmd
+}
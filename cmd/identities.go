@@ -0,0 +1,119 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/spf13/cobra"
+)
+
+// identityCommands creates the root command for managing identities in bulk.
+func identityCommands(b *blnkInstance) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "identities",
+		Short: "manage identities in bulk",
+	}
+
+	cmd.AddCommand(identityImportCommands(b))
+	cmd.AddCommand(identityExportCommands(b))
+
+	return cmd
+}
+
+// identityImportCommands creates the command that imports identities from a CSV or NDJSON file and prints the
+// outcome of every row. The command exits with status 1 when any row was not imported.
+func identityImportCommands(b *blnkInstance) *cobra.Command {
+	var input, format, output string
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "import identities from a CSV or NDJSON file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := os.Open(input)
+			if err != nil {
+				return fmt.Errorf("error opening file: %v", err)
+			}
+			defer file.Close()
+
+			if format == "" {
+				format = strings.TrimPrefix(strings.ToLower(filepath.Ext(input)), ".")
+			}
+			report, err := b.blnk.ImportIdentities(context.Background(), format, file)
+			if err != nil {
+				return fmt.Errorf("error importing identities: %v", err)
+			}
+
+			if err := writeReplayJSON(report, output); err != nil {
+				return err
+			}
+			if report.Failed > 0 {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&input, "file", "", "CSV or NDJSON file of identities")
+	cmd.Flags().StringVar(&format, "format", "", "format of the file, csv or ndjson; defaults to the file's extension")
+	cmd.Flags().StringVar(&output, "output", "", "write the report to this file instead of stdout")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+// identityExportCommands creates the command that exports identities to a CSV or Parquet file on disk or in S3
+// and prints the finished export. The command exits with status 1 when the export fails.
+func identityExportCommands(b *blnkInstance) *cobra.Command {
+	var export model.IdentityExport
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "export identities to a CSV or Parquet file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if export.Format == "" {
+				export.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(export.Location)), ".")
+			}
+			err := b.blnk.RunIdentityExport(context.Background(), &export, nil)
+			if writeErr := writeReplayJSON(export, ""); writeErr != nil {
+				return writeErr
+			}
+			if err != nil {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&export.Format, "format", "", "format of the file, csv or parquet; defaults to the file's extension")
+	cmd.Flags().StringVar(&export.Destination, "destination", model.IdentityExportLocal, "where to write the file, local or s3")
+	cmd.Flags().StringVar(&export.Location, "file", "", "path of the file, or its key in the S3 bucket; defaults to a new file in the export directory")
+	cmd.Flags().StringVar(&export.Filter.IdentityType, "identity-type", "", "only export identities of this type")
+	cmd.Flags().StringVar(&export.Filter.Category, "category", "", "only export identities in this category")
+	cmd.Flags().StringVar(&export.Filter.Country, "country", "", "only export identities in this country")
+	cmd.Flags().StringVar(&export.Filter.Nationality, "nationality", "", "only export identities of this nationality")
+	cmd.Flags().StringVar(&export.Filter.VerificationStatus, "verification-status", "", "only export identities with this verification status")
+	cmd.Flags().StringVar(&export.Filter.RiskLevel, "risk-level", "", "only export identities at this risk level")
+	cmd.Flags().BoolVar(&export.Filter.IncludeDeleted, "include-deleted", false, "also export deleted identities")
+
+	return cmd
+}
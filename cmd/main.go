@@ -118,9 +118,16 @@ func NewCLI() *Blnk {
 	rootCmd.PersistentPreRunE = preRun(b)
 
 	// Add various subcommands to the root command.
-	rootCmd.AddCommand(serverCommands(b))  // Command for starting the server
-	rootCmd.AddCommand(workerCommands(b))  // Command for worker processes
-	rootCmd.AddCommand(migrateCommands(b)) // Command for database/schema migrations
+	rootCmd.AddCommand(serverCommands(b))          // Command for starting the server
+	rootCmd.AddCommand(workerCommands(b))          // Command for worker processes
+	rootCmd.AddCommand(migrateCommands(b))         // Command for database/schema migrations
+	rootCmd.AddCommand(verifyCommands(b))          // Command for ledger integrity verification
+	rootCmd.AddCommand(tokenCommands(b))           // Command for issuing service account tokens
+	rootCmd.AddCommand(seedCommands(b))            // Command for provisioning demo data
+	rootCmd.AddCommand(replayCommands(b))          // Command for exporting and replaying ledger slices
+	rootCmd.AddCommand(identityCommands(b))        // Command for importing identities in bulk
+	rootCmd.AddCommand(anonymizeExportCommands(b)) // Command for exporting anonymized ledger slices
+	rootCmd.AddCommand(adviseSchemaCommands(b))    // Command for schema and index recommendations
 
 	return &Blnk{cmd: rootCmd}
 }
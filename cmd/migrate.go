@@ -22,6 +22,7 @@ This includes commands for applying and rolling back migrations.
 package main
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
 
@@ -78,8 +79,18 @@ func migrateUpCommands() *cobra.Command {
 			n, err := migrate.Exec(db, "postgres", migrations, migrate.Up)
 			if err != nil {
 				log.Printf("Error migrating up: %v", err)
-			} else {
-				fmt.Printf("Applied %d migrations!\n", n)
+				return
+			}
+			fmt.Printf("Applied %d migrations!\n", n)
+
+			// Apply or remove the row-level security policies to match the configuration.
+			n, err = syncRowLevelSecurity(db, cnf.DataSource.EnableRLS)
+			if err != nil {
+				log.Printf("Error updating row-level security policies: %v", err)
+			} else if n > 0 && cnf.DataSource.EnableRLS {
+				fmt.Printf("Applied %d row-level security migrations!\n", n)
+			} else if n > 0 {
+				fmt.Printf("Removed %d row-level security migrations!\n", n)
 			}
 		},
 	}
@@ -112,6 +123,12 @@ func migrateDownCommands() *cobra.Command {
 				return
 			}
 
+			// Remove the row-level security policies first, as they depend on the schema.
+			if _, err := syncRowLevelSecurity(db, false); err != nil {
+				log.Printf("Error removing row-level security policies: %v", err)
+				return
+			}
+
 			// Roll back the migrations.
 			n, err := migrate.Exec(db, "postgres", migrations, migrate.Down)
 			if err != nil {
@@ -124,3 +141,19 @@ func migrateDownCommands() *cobra.Command {
 
 	return cmd
 }
+
+// rlsMigrationSet tracks the row-level security policies in their own table, so they can be applied
+// and removed independently of the schema migrations.
+var rlsMigrationSet = migrate.MigrationSet{TableName: "rls_migrations", SchemaName: "blnk"}
+
+// syncRowLevelSecurity applies the tenant row-level security policies when enabled and removes them otherwise.
+func syncRowLevelSecurity(db *sql.DB, enabled bool) (int, error) {
+	migrations := migrate.EmbedFileSystemMigrationSource{
+		FileSystem: blnk.RLSFiles,
+		Root:       "sql/rls",
+	}
+	if enabled {
+		return rlsMigrationSet.Exec(db, "postgres", migrations, migrate.Up)
+	}
+	return rlsMigrationSet.Exec(db, "postgres", migrations, migrate.Down)
+}
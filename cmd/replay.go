@@ -0,0 +1,176 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database"
+	"github.com/blnkfinance/blnk/model"
+	migrate "github.com/rubenv/sql-migrate"
+	"github.com/spf13/cobra"
+)
+
+// replayCommands creates the root command for exporting a slice of a ledger and replaying it, so incidents
+// can be reproduced away from production.
+func replayCommands(b *blnkInstance) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "export and replay ledger slices",
+	}
+
+	cmd.AddCommand(replayExportCommands(b))
+	cmd.AddCommand(replayRunCommands(b))
+
+	return cmd
+}
+
+// replayExportCommands creates the command that exports a time slice of a ledger to a file.
+func replayExportCommands(b *blnkInstance) *cobra.Command {
+	var ledgerID, from, to, output string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "export a time slice of a ledger for replay",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := blnk.ReplayExportOptions{LedgerID: ledgerID}
+			var err error
+			if opts.From, err = time.Parse(time.RFC3339, from); err != nil {
+				return fmt.Errorf("invalid --from: %v", err)
+			}
+			if to != "" {
+				if opts.To, err = time.Parse(time.RFC3339, to); err != nil {
+					return fmt.Errorf("invalid --to: %v", err)
+				}
+			}
+
+			slice, err := b.blnk.ExportReplaySlice(context.Background(), opts)
+			if err != nil {
+				return fmt.Errorf("error exporting slice: %v", err)
+			}
+			return writeReplayJSON(slice, output)
+		},
+	}
+
+	cmd.Flags().StringVar(&ledgerID, "ledger", "", "ID of the ledger to export")
+	cmd.Flags().StringVar(&from, "from", "", "start of the slice (RFC3339)")
+	cmd.Flags().StringVar(&to, "to", "", "end of the slice (RFC3339), defaults to now")
+	cmd.Flags().StringVar(&output, "output", "", "write the slice to this file instead of stdout")
+	_ = cmd.MarkFlagRequired("ledger")
+	_ = cmd.MarkFlagRequired("from")
+
+	return cmd
+}
+
+// replayRunCommands creates the command that replays an exported slice and prints how the replayed balances
+// differ from production. With --scratch-dsn the replayed slice is also written to that database, which is
+// migrated first, so it can be inspected with the usual tooling. The command exits with status 1 when the
+// replay does not match production.
+func replayRunCommands(b *blnkInstance) *cobra.Command {
+	var input, scratchDSN, output string
+	var skipDuplicates bool
+
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "replay an exported ledger slice",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				return fmt.Errorf("error reading slice: %v", err)
+			}
+			var slice model.ReplaySlice
+			if err := json.Unmarshal(data, &slice); err != nil {
+				return fmt.Errorf("error decoding slice: %v", err)
+			}
+
+			result := blnk.Replay(&slice, blnk.ReplayOptions{SkipDuplicates: skipDuplicates})
+
+			if scratchDSN != "" {
+				if err := restoreReplay(b.cnf, scratchDSN, &slice, result); err != nil {
+					return fmt.Errorf("error restoring replay: %v", err)
+				}
+			}
+
+			if err := writeReplayJSON(result, output); err != nil {
+				return err
+			}
+			if !result.Matches {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&input, "input", "", "slice file written by replay export")
+	cmd.Flags().StringVar(&scratchDSN, "scratch-dsn", "", "write the replayed slice to this scratch database")
+	cmd.Flags().BoolVar(&skipDuplicates, "skip-duplicates", false, "replay only the first of the transactions that share a hash")
+	cmd.Flags().StringVar(&output, "output", "", "write the result to this file instead of stdout")
+	_ = cmd.MarkFlagRequired("input")
+
+	return cmd
+}
+
+// restoreReplay migrates the scratch database and writes the replayed slice to it.
+func restoreReplay(cnf *config.Configuration, scratchDSN string, slice *model.ReplaySlice, result *model.ReplayResult) error {
+	if cnf != nil && scratchDSN == cnf.DataSource.Dns {
+		return errors.New("the scratch database must not be the configured database")
+	}
+
+	db, err := database.ConnectDB(config.DataSourceConfig{Dns: scratchDSN})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrations := migrate.EmbedFileSystemMigrationSource{
+		FileSystem: blnk.SQLFiles,
+		Root:       "sql",
+	}
+	migrate.SetSchema("blnk")
+	if _, err := migrate.Exec(db, "postgres", migrations, migrate.Up); err != nil {
+		return fmt.Errorf("error migrating scratch database: %v", err)
+	}
+
+	scratch, err := blnk.NewBlnk(&database.Datasource{Conn: db})
+	if err != nil {
+		return err
+	}
+	return scratch.RestoreReplay(context.Background(), slice, result)
+}
+
+// writeReplayJSON prints v as indented JSON, or writes it to output when set.
+func writeReplayJSON(v interface{}, output string) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding output: %v", err)
+	}
+	if output == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(output, data, 0o644); err != nil {
+		return fmt.Errorf("error writing output: %v", err)
+	}
+	return nil
+}
@@ -26,7 +26,10 @@ import (
 
 	"github.com/blnkfinance/blnk"
 	"github.com/blnkfinance/blnk/api"
+	"github.com/blnkfinance/blnk/api/middleware"
 	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database"
+	"github.com/blnkfinance/blnk/internal/resilience"
 	trace "github.com/blnkfinance/blnk/internal/traces"
 	"github.com/caddyserver/certmagic"
 	"github.com/gin-gonic/gin"
@@ -148,12 +151,53 @@ func healthCheckHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "UP"})
 }
 
+// readinessHandler reports whether the server is ready to serve traffic. A server in maintenance stays ready,
+// since it still serves reads, and reports itself read-only along with the maintenance it is in.
+func readinessHandler(b *blnk.Blnk) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := b.GetMaintenanceStatus(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "NOT_READY", "error": err.Error()})
+			return
+		}
+		state := "READY"
+		if status.Global.Enabled {
+			state = "READ_ONLY"
+		}
+		c.JSON(http.StatusOK, gin.H{"status": state, "maintenance": status})
+	}
+}
+
 func initializeRouter(b *blnkInstance) *gin.Engine {
 	router := api.NewAPI(b.blnk).Router()
 	router.GET("/health", healthCheckHandler) // Add health check route
+	router.GET("/readyz", readinessHandler(b.blnk))
+	router.GET("/metrics", metricsHandler)
 	return router
 }
 
+// metricsHandler serves the health of the dependencies, the state of the database connection pools, the use of
+// deprecated API field names and the time transactions spend in each stage of the posting pipeline in the
+// Prometheus text format.
+func metricsHandler(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4")
+	if err := resilience.WriteMetrics(c.Writer); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := database.WritePoolMetrics(c.Writer); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := middleware.WriteFieldAliasMetrics(c.Writer); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := blnk.WritePipelineMetrics(c.Writer); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+	}
+}
+
 func initializeOpenTelemetry(ctx context.Context) (func(context.Context) error, error) {
 	shutdown, err := trace.SetupOTelSDK(ctx, "BLNK")
 	if err != nil {
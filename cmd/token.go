@@ -0,0 +1,76 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// tokenCommands creates the command that exchanges service account credentials for a short-lived token.
+// Credentials are read from flags or from the BLNK_CLIENT_ID and BLNK_CLIENT_SECRET environment variables
+// so that workers and scripts do not need to hold a long-lived API key.
+func tokenCommands(b *blnkInstance) *cobra.Command {
+	var clientID string
+	var clientSecret string
+	var scopes []string
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "issue a short-lived service account token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if clientID == "" {
+				clientID = os.Getenv("BLNK_CLIENT_ID")
+			}
+			if clientSecret == "" {
+				clientSecret = os.Getenv("BLNK_CLIENT_SECRET")
+			}
+			if clientID == "" || clientSecret == "" {
+				return fmt.Errorf("service account credentials are required: use --client-id and --client-secret or BLNK_CLIENT_ID and BLNK_CLIENT_SECRET")
+			}
+
+			token, err := b.blnk.IssueServiceToken(context.Background(), clientID, clientSecret, scopes)
+			if err != nil {
+				return fmt.Errorf("error issuing token: %v", err)
+			}
+
+			if !asJSON {
+				fmt.Println(token.Token)
+				return nil
+			}
+
+			data, err := json.MarshalIndent(token, "", "  ")
+			if err != nil {
+				return fmt.Errorf("error encoding token: %v", err)
+			}
+			fmt.Println(string(data))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&clientID, "client-id", "", "service account ID")
+	cmd.Flags().StringVar(&clientSecret, "client-secret", "", "service account secret")
+	cmd.Flags().StringSliceVar(&scopes, "scope", nil, "scope to request, may be repeated; defaults to all of the account's scopes")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the token with its scopes and expiry as JSON")
+
+	return cmd
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/blnkfinance/blnk"
+	"github.com/spf13/cobra"
+)
+
+// verifyCommands creates the command that checks ledger invariants and prints a JSON report with suggested repairs.
+// The command exits with status 1 when any check fails so it can gate scripts and scheduled jobs.
+func verifyCommands(b *blnkInstance) *cobra.Command {
+	var limit int
+	var staleInflightAge time.Duration
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "verify ledger integrity",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report := b.blnk.VerifyLedgerIntegrity(context.Background(), blnk.VerifyOptions{
+				Limit:            limit,
+				StaleInflightAge: staleInflightAge,
+			})
+
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("error encoding report: %v", err)
+			}
+
+			if output == "" {
+				fmt.Println(string(data))
+			} else if err := os.WriteFile(output, data, 0o644); err != nil {
+				return fmt.Errorf("error writing report: %v", err)
+			}
+
+			if !report.Healthy {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 1000, "maximum number of issues reported per check")
+	cmd.Flags().DurationVar(&staleInflightAge, "stale-inflight", 30*24*time.Hour, "report inflight transactions older than this that were never committed or voided")
+	cmd.Flags().StringVar(&output, "output", "", "write the report to this file instead of stdout")
+
+	return cmd
+}
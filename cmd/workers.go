@@ -19,10 +19,12 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -31,6 +33,8 @@ import (
 	"github.com/blnkfinance/blnk"
 	"github.com/blnkfinance/blnk/config"
 	redis_db "github.com/blnkfinance/blnk/internal/redis-db"
+	"github.com/blnkfinance/blnk/internal/resilience"
+	"github.com/blnkfinance/blnk/internal/workerpool"
 	"github.com/blnkfinance/blnk/model"
 
 	"github.com/hibiken/asynq"
@@ -38,14 +42,17 @@ import (
 )
 
 // indexData represents the data structure used for indexing data in the system.
-// It includes the collection name and the payload which is the data to be indexed.
+// It includes the collection name and the payload which is the data to be indexed, or the ID of the document
+// to remove from the collection.
 type indexData struct {
 	Collection string                 `json:"collection"`
 	Payload    map[string]interface{} `json:"payload"`
+	DeleteID   string                 `json:"delete_id"`
 }
 
 // processTransaction processes a transaction received from the Redis queue.
-// If a transaction fails due to "insufficient funds", it is rejected, and a webhook is sent.
+// If a transaction fails due to "insufficient funds", its retry policy decides what happens next; without
+// a policy it is rejected, and a webhook is sent.
 // Otherwise, it retries the transaction in case of other failures.
 func (b *blnkInstance) processTransaction(ctx context.Context, t *asynq.Task) error {
 	ctx, span := otel.Tracer("blnk.transactions.worker").Start(ctx, "Process Transaction From Redis Queue")
@@ -57,6 +64,11 @@ func (b *blnkInstance) processTransaction(ctx context.Context, t *asynq.Task) er
 		return err
 	}
 
+	// Transactions queued before maintenance began wait for it to end
+	if err := b.blnk.CheckTransactionMaintenance(ctx, &txn); err != nil {
+		return err
+	}
+
 	_, err := b.blnk.RecordTransaction(ctx, &txn)
 	if err != nil {
 		// Handle reference already used error
@@ -65,6 +77,11 @@ func (b *blnkInstance) processTransaction(ctx context.Context, t *asynq.Task) er
 		}
 
 		if strings.Contains(strings.ToLower(err.Error()), "insufficient funds") {
+			// Transactions with a retry policy are retried, partially executed or failed by the policy
+			if handled, retryErr := b.blnk.HandleInsufficientFunds(ctx, &txn, err); handled {
+				return retryErr
+			}
+
 			cfg, _ := config.Fetch()
 			if !cfg.Queue.InsufficientFundRetries {
 				return handleTransactionRejection(ctx, b, &txn, err)
@@ -132,6 +149,15 @@ func (b *blnkInstance) indexData(_ context.Context, t *asynq.Task) error {
 		return err
 	}
 
+	if data.DeleteID != "" {
+		if err := newSearch.DeleteDocument(context.Background(), collection, data.DeleteID); err != nil {
+			log.Println("Error removing indexed data", err)
+			return err
+		}
+		log.Println(" [*] Indexed data removed", collection)
+		return nil
+	}
+
 	// Handle the notification and send the payload to the collection for indexing.
 	err = newSearch.HandleNotification(collection, payload)
 	if err != nil {
@@ -154,6 +180,7 @@ func (b *blnkInstance) processInflightExpiry(cxt context.Context, t *asynq.Task)
 	}
 
 	// Void the inflight transaction by its ID.
+	cxt = blnk.WithStatusReason(cxt, model.ReasonInflightExpired, "inflight expiry date passed")
 	_, err := b.blnk.VoidInflightTransaction(cxt, txnID)
 	if err != nil {
 		return err
@@ -196,12 +223,29 @@ func initializeWorkerServer(conf *config.Configuration, queues map[string]int) (
 			TLSConfig: redisOption.TLSConfig,
 		},
 		asynq.Config{
-			Concurrency: 1,
+			// The worker pool decides how many of these run at once
+			Concurrency: conf.Queue.MaxConcurrency,
 			Queues:      queues,
+			// Tasks rejected by maintenance mode are retried once it is expected to end, without using up
+			// their retries
+			IsFailure: func(err error) bool {
+				return !errors.Is(err, blnk.ErrMaintenanceMode)
+			},
+			RetryDelayFunc: maintenanceRetryDelay,
 		},
 	), nil
 }
 
+// maintenanceRetryDelay waits the retry_after of the maintenance mode that rejected a task, and the default
+// backoff otherwise.
+func maintenanceRetryDelay(n int, err error, t *asynq.Task) time.Duration {
+	var maintenance *blnk.MaintenanceError
+	if errors.As(err, &maintenance) {
+		return time.Duration(maintenance.Mode.RetryAfter) * time.Second
+	}
+	return asynq.DefaultRetryDelayFunc(n, err, t)
+}
+
 func initializeTaskHandlers(b *blnkInstance, mux *asynq.ServeMux) {
 	cfg, err := config.Fetch()
 	if err != nil {
@@ -221,6 +265,401 @@ func initializeTaskHandlers(b *blnkInstance, mux *asynq.ServeMux) {
 	mux.HandleFunc(cfg.Queue.InflightExpiryQueue, b.processInflightExpiry)
 }
 
+// newWorkerPool returns the pool that scales the worker's concurrency, by the number of transactions pending in
+// the transaction queues.
+func newWorkerPool(conf *config.Configuration, redisOpt asynq.RedisConnOpt) *workerpool.Pool {
+	inspector := asynq.NewInspector(redisOpt)
+	prefix := conf.Queue.TransactionQueue + "_"
+	return workerpool.New(conf.Queue, func(_ context.Context) (int, error) {
+		queues, err := inspector.Queues()
+		if err != nil {
+			return 0, err
+		}
+		depth := 0
+		for _, queue := range queues {
+			if !strings.HasPrefix(queue, prefix) {
+				continue
+			}
+			info, err := inspector.GetQueueInfo(queue)
+			if err != nil {
+				return 0, err
+			}
+			depth += info.Pending
+		}
+		return depth, nil
+	})
+}
+
+// workerPoolMiddleware runs each task in a slot of the worker pool, and reports how long transactions took to
+// apply so that the pool can scale by it.
+func workerPoolMiddleware(pool *workerpool.Pool, transactionQueue string) asynq.MiddlewareFunc {
+	prefix := transactionQueue + "_"
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			if err := pool.Acquire(ctx); err != nil {
+				return err
+			}
+			defer pool.Release()
+
+			started := time.Now()
+			err := next.ProcessTask(ctx, t)
+			if strings.HasPrefix(t.Type(), prefix) {
+				pool.ObserveApply(time.Since(started))
+			}
+			return err
+		})
+	}
+}
+
+// pausedForMaintenance reports whether Blnk is in maintenance mode, in which case the scheduled jobs that change
+// ledger data skip their run. Ledgers in maintenance are left to the checks of the operations the jobs perform.
+func pausedForMaintenance(ctx context.Context, b *blnkInstance, job string) bool {
+	if err := b.blnk.CheckMaintenance(ctx); err != nil {
+		logrus.Infof("Skipping %s: %v", job, err)
+		return true
+	}
+	return false
+}
+
+// runStatementScheduler periodically generates and delivers account statements that are due.
+func runStatementScheduler(ctx context.Context, b *blnkInstance) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if !pausedForMaintenance(ctx, b, "statement schedules") {
+			generated, err := b.blnk.RunDueStatements(ctx)
+			if err != nil {
+				logrus.Errorf("Error running statement schedules: %v", err)
+			} else if generated > 0 {
+				logrus.Infof(" [*] Generated %d account statements", generated)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runReportScheduler periodically runs the saved reports that are due. It checks every minute so that hourly
+// reports run close to the hour.
+func runReportScheduler(ctx context.Context, b *blnkInstance) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		completed, err := b.blnk.RunDueReports(ctx)
+		if err != nil {
+			logrus.Errorf("Error running scheduled reports: %v", err)
+		} else if completed > 0 {
+			logrus.Infof(" [*] Ran %d scheduled reports", completed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runEODScheduler runs the end-of-day processing of each business date once its cutoff has passed. It checks
+// every minute so that runs start close to the cutoff.
+func runEODScheduler(ctx context.Context, b *blnkInstance) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if !pausedForMaintenance(ctx, b, "end-of-day processing") {
+			run, err := b.blnk.RunDueEOD(ctx)
+			if err != nil {
+				logrus.Errorf("Error running end-of-day processing: %v", err)
+			} else if run != nil {
+				logrus.Infof(" [*] End-of-day processing of %s %s", run.BusinessDate, run.Status)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runUsageExporter publishes each completed day's usage records once the day has ended.
+func runUsageExporter(ctx context.Context, b *blnkInstance) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		exported, err := b.blnk.ExportDailyUsage(ctx, time.Now().UTC().AddDate(0, 0, -1))
+		if err != nil {
+			logrus.Errorf("Error exporting usage: %v", err)
+		} else if exported > 0 {
+			logrus.Infof(" [*] Exported %d usage records", exported)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNettingScheduler settles netting groups once their daily cutoff has passed.
+func runNettingScheduler(ctx context.Context, b *blnkInstance) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if !pausedForMaintenance(ctx, b, "netting settlements") {
+			settled, err := b.blnk.RunDueNettingSettlements(ctx)
+			if err != nil {
+				logrus.Errorf("Error running netting settlements: %v", err)
+			} else if settled > 0 {
+				logrus.Infof(" [*] Settled %d netting groups", settled)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDormancyScanner flags balances without recent transactions as dormant at the configured scan interval.
+func runDormancyScanner(ctx context.Context, b *blnkInstance, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if !pausedForMaintenance(ctx, b, "dormancy scan") {
+			scan, err := b.blnk.RunDormancyScan(ctx)
+			if err != nil {
+				logrus.Errorf("Error running dormancy scan: %v", err)
+			} else if scan.Flagged > 0 || scan.Reactivated > 0 {
+				logrus.Infof(" [*] Flagged %d dormant balances, reactivated %d", scan.Flagged, scan.Reactivated)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runStatementIngestion collects statement files from the dead drops at the configured poll interval.
+func runStatementIngestion(ctx context.Context, b *blnkInstance, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if !pausedForMaintenance(ctx, b, "statement ingestion") {
+			ingestions, err := b.blnk.RunStatementIngestion(ctx)
+			if err != nil {
+				logrus.Errorf("Error ingesting statements: %v", err)
+			}
+			if len(ingestions) > 0 {
+				logrus.Infof(" [*] Ingested %d statement files", len(ingestions))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runComplianceExport appends the postings numbered since the last export to the compliance bucket at the
+// configured interval.
+func runComplianceExport(ctx context.Context, b *blnkInstance, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		run, err := b.blnk.RunComplianceExport(ctx)
+		if err != nil {
+			logrus.Errorf("Error exporting to compliance bucket: %v", err)
+		}
+		if run != nil && run.Records > 0 {
+			logrus.Infof(" [*] Exported %d postings in %d compliance batches", run.Records, run.Batches)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runWarehouseSync exports the rows written since the last sync to the warehouse at the configured interval.
+func runWarehouseSync(ctx context.Context, b *blnkInstance, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		run, err := b.blnk.RunWarehouseSync(ctx)
+		if err != nil {
+			logrus.Errorf("Error syncing warehouse: %v", err)
+		}
+		if run != nil {
+			for _, entity := range run.Entities {
+				if entity.Rows > 0 {
+					logrus.Infof(" [*] Exported %d %s to %s", entity.Rows, entity.Entity, run.Provider)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runRequestLogPruner deletes request log entries beyond the configured retention and size every hour.
+func runRequestLogPruner(ctx context.Context, b *blnkInstance) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := b.blnk.PruneRequestLogs(ctx)
+		if err != nil {
+			logrus.Errorf("Error pruning request logs: %v", err)
+		} else if deleted > 0 {
+			logrus.Infof(" [*] Pruned %d request log entries", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runWebhookDeliveryPruner deletes the records of webhook deliveries beyond the configured retention every hour.
+func runWebhookDeliveryPruner(ctx context.Context, b *blnkInstance) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := b.blnk.PruneWebhookDeliveries(ctx)
+		if err != nil {
+			logrus.Errorf("Error pruning webhook deliveries: %v", err)
+		} else if deleted > 0 {
+			logrus.Infof(" [*] Pruned %d webhook delivery records", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNotificationDigestSender publishes the daily digests of balances in digest mode once the day has ended.
+func runNotificationDigestSender(ctx context.Context, b *blnkInstance) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		sent, err := b.blnk.SendBalanceNotificationDigests(ctx, time.Now().UTC().AddDate(0, 0, -1))
+		if err != nil {
+			logrus.Errorf("Error sending notification digests: %v", err)
+		} else if sent > 0 {
+			logrus.Infof(" [*] Sent %d balance notification digests", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runWebhookCircuitProber probes the open webhook circuits whose probe is due.
+func runWebhookCircuitProber(ctx context.Context, b *blnkInstance) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		closed, err := b.blnk.ProbeWebhookCircuits(ctx)
+		if err != nil {
+			logrus.Errorf("Error probing webhook circuits: %v", err)
+		} else if closed > 0 {
+			logrus.Infof(" [*] Closed %d webhook circuits", closed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runCardAuthorizationExpiry releases the holds of card authorizations that expired without being cleared.
+func runCardAuthorizationExpiry(ctx context.Context, b *blnkInstance) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if !pausedForMaintenance(ctx, b, "card authorization expiry") {
+			expired, err := b.blnk.ExpireCardAuthorizations(ctx)
+			if err != nil {
+				logrus.Errorf("Error expiring card authorizations: %v", err)
+			} else if expired > 0 {
+				logrus.Infof(" [*] Expired %d card authorizations", expired)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runChallengeExpiry rejects the transactions of challenges that timed out without a result.
+func runChallengeExpiry(ctx context.Context, b *blnkInstance) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if !pausedForMaintenance(ctx, b, "challenge expiry") {
+			expired, err := b.blnk.ExpireTransactionChallenges(ctx)
+			if err != nil {
+				logrus.Errorf("Error expiring transaction challenges: %v", err)
+			} else if expired > 0 {
+				logrus.Infof(" [*] Expired %d transaction challenges", expired)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // workerCommands defines the "workers" command to start worker processes.
 // The workers listen to various queues such as transaction processing, indexing, and inflight expiry.
 func workerCommands(b *blnkInstance) *cobra.Command {
@@ -261,20 +700,24 @@ func workerCommands(b *blnkInstance) *cobra.Command {
 				log.Fatal(err)
 			}
 
-			// Initialize task handlers
+			redisOption, _ := redis_db.ParseRedisURL(conf.Redis.Dns, conf.Redis.SkipTLSVerify)
+			redisConnOpt := asynq.RedisClientOpt{
+				Addr:      redisOption.Addr,
+				Password:  redisOption.Password,
+				DB:        redisOption.DB,
+				TLSConfig: redisOption.TLSConfig,
+			}
+
+			// Initialize task handlers, run within the worker pool
+			pool := newWorkerPool(conf, redisConnOpt)
 			mux := asynq.NewServeMux()
+			mux.Use(workerPoolMiddleware(pool, conf.Queue.TransactionQueue))
 			initializeTaskHandlers(b, mux)
 
 			// Start monitoring server with health check and asynqmon dashboard
-			redisOption, _ := redis_db.ParseRedisURL(conf.Redis.Dns, conf.Redis.SkipTLSVerify)
 			asynqmonHandler := asynqmon.New(asynqmon.Options{
-				RootPath: "/monitoring", //  Optional: if you want to serve asynqmon under a sub-path.
-				RedisConnOpt: asynq.RedisClientOpt{
-					Addr:      redisOption.Addr,
-					Password:  redisOption.Password,
-					DB:        redisOption.DB,
-					TLSConfig: redisOption.TLSConfig,
-				},
+				RootPath:     "/monitoring", //  Optional: if you want to serve asynqmon under a sub-path.
+				RedisConnOpt: redisConnOpt,
 			})
 
 			// Create a custom HTTP mux for monitoring port
@@ -287,18 +730,92 @@ func workerCommands(b *blnkInstance) *cobra.Command {
 				fmt.Fprintf(w, `{"status": "UP", "service": "worker"}`)
 			})
 
+			// Expose the health of external dependencies, the concurrency of the worker pool and the pipeline stage timings
+			monitoringMux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+				if err := resilience.WriteMetrics(w); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				if err := pool.WriteMetrics(w); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				if err := blnk.WritePipelineMetrics(w); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+				}
+			})
+
 			// Mount asynqmon dashboard at /monitoring
 			monitoringMux.Handle("/monitoring/", asynqmonHandler)
 
 			// Start monitoring HTTP server in a new goroutine
 			go func() {
 				monitoringAddr := fmt.Sprintf(":%s", conf.Queue.MonitoringPort)
-				log.Printf("Worker monitoring server listening on %s (health: /health, dashboard: /monitoring)", monitoringAddr)
+				log.Printf("Worker monitoring server listening on %s (health: /health, metrics: /metrics, dashboard: /monitoring)", monitoringAddr)
 				if err := http.ListenAndServe(monitoringAddr, monitoringMux); err != nil {
 					log.Fatalf("could not start monitoring server: %v", err)
 				}
 			}()
 
+			// Scale the worker's concurrency with the depth of the transaction queues
+			go pool.Run(ctx)
+
+			// Generate scheduled account statements in the background
+			go runStatementScheduler(ctx, b)
+
+			// Run saved reports on their schedules
+			go runReportScheduler(ctx, b)
+
+			// Publish the previous day's usage records to the event stream
+			go runUsageExporter(ctx, b)
+
+			// Apply net settlements for netting groups at their cutoff
+			go runNettingScheduler(ctx, b)
+
+			// Run end-of-day processing once each business date reaches its cutoff
+			if len(conf.EOD.Steps) > 0 {
+				go runEODScheduler(ctx, b)
+			}
+
+			// Expire card authorizations that were neither cleared nor reversed
+			go runCardAuthorizationExpiry(ctx, b)
+
+			// Reject transactions whose strong customer authentication challenge timed out
+			go runChallengeExpiry(ctx, b)
+
+			// Send the previous day's notification digests for balances in digest mode
+			go runNotificationDigestSender(ctx, b)
+
+			// Flag balances without recent transactions as dormant
+			if conf.Dormancy.InactivityPeriod > 0 {
+				go runDormancyScanner(ctx, b, conf.Dormancy.ScanInterval)
+			}
+
+			// Collect reconciliation statements from the configured mailbox and SFTP folder
+			if conf.StatementIngestion.IMAP.Host != "" || conf.StatementIngestion.SFTP.Host != "" {
+				go runStatementIngestion(ctx, b, conf.StatementIngestion.PollInterval)
+			}
+
+			// Export transactions, balances and identities to the analytics warehouse
+			if conf.Warehouse.Provider != "" {
+				go runWarehouseSync(ctx, b, conf.Warehouse.SyncInterval)
+			}
+
+			// Append postings to the write-once compliance bucket
+			if conf.ComplianceExport.Bucket != "" {
+				go runComplianceExport(ctx, b, conf.ComplianceExport.Interval)
+			}
+
+			// Keep the request log within its retention and size limits
+			go runRequestLogPruner(ctx, b)
+
+			// Probe degraded webhook endpoints and release their parked deliveries once they recover
+			go runWebhookCircuitProber(ctx, b)
+
+			// Keep the webhook delivery records within their retention
+			go runWebhookDeliveryPruner(ctx, b)
+
 			// Start worker server
 			if err := srv.Run(mux); err != nil {
 				log.Fatalf("could not run server: %v", err)
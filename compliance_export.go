@@ -0,0 +1,447 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/blnkfinance/blnk/config"
+	redlock "github.com/blnkfinance/blnk/internal/lock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	complianceExportLockTimeout = 30 * time.Minute
+	complianceVerifyMaxIssues   = 100
+
+	// Metadata stored with each batch object. Object Lock keeps it as immutable as the batch itself.
+	complianceHashMetadata      = "blnk-sha256"
+	complianceSignatureMetadata = "blnk-signature"
+)
+
+var (
+	// ErrComplianceSequenceGap is returned when the postings of a ledger do not continue from the last one
+	// exported, which means a numbered transaction is missing from the ledger.
+	ErrComplianceSequenceGap = errors.New("compliance export sequence gap")
+
+	// errComplianceBatchNotFound is returned by a compliance store for a batch that has not been written.
+	errComplianceBatchNotFound = errors.New("compliance batch not found")
+)
+
+// complianceStore writes the batches of the compliance export to immutable storage and reads them back.
+type complianceStore interface {
+	PutLocked(ctx context.Context, key string, body []byte, metadata map[string]string, retainUntil time.Time) error
+	Get(ctx context.Context, key string) ([]byte, map[string]string, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// newComplianceStore returns the store of the configured compliance bucket. It is a variable so tests can
+// replace the bucket.
+var newComplianceStore = func(cnf *config.Configuration) (complianceStore, error) {
+	client, err := newS3Client(cnf)
+	if err != nil {
+		return nil, err
+	}
+	return &s3ComplianceStore{client: client, bucket: cnf.ComplianceExport.Bucket, mode: cnf.ComplianceExport.RetentionMode}, nil
+}
+
+// s3ComplianceStore stores compliance batches in a bucket with S3 Object Lock enabled.
+type s3ComplianceStore struct {
+	client *s3.S3
+	bucket string
+	mode   string
+}
+
+// PutLocked writes an object locked in the store's retention mode until retainUntil. S3 requires the MD5 of
+// the body for writes that set a retention.
+func (s *s3ComplianceStore) PutLocked(ctx context.Context, key string, body []byte, metadata map[string]string, retainUntil time.Time) error {
+	sum := md5.Sum(body)
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:                    aws.String(s.bucket),
+		Key:                       aws.String(key),
+		Body:                      bytes.NewReader(body),
+		ContentType:               aws.String("application/json"),
+		ContentMD5:                aws.String(base64.StdEncoding.EncodeToString(sum[:])),
+		Metadata:                  aws.StringMap(metadata),
+		ObjectLockMode:            aws.String(s.mode),
+		ObjectLockRetainUntilDate: aws.Time(retainUntil),
+	})
+	return err
+}
+
+// Get reads an object and its metadata, with the metadata keys in lower case.
+func (s *s3ComplianceStore) Get(ctx context.Context, key string) ([]byte, map[string]string, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && (aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound") {
+			return nil, nil, errComplianceBatchNotFound
+		}
+		return nil, nil, err
+	}
+	defer out.Body.Close()
+
+	body, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata := make(map[string]string, len(out.Metadata))
+	for name, value := range out.Metadata {
+		metadata[strings.ToLower(name)] = aws.StringValue(value)
+	}
+	return body, metadata, nil
+}
+
+// List returns the keys of the objects under a prefix.
+func (s *s3ComplianceStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+		return true
+	})
+	return keys, err
+}
+
+// complianceBatchKey returns the key of a batch. Numbers are zero-padded so keys sort in batch order.
+func complianceBatchKey(prefix string, number int64) string {
+	return path.Join(prefix, fmt.Sprintf("%020d.json", number))
+}
+
+// signComplianceBatch returns the SHA-256 hash of a batch body and its HMAC-SHA256 signature, both in hex.
+func signComplianceBatch(body []byte, key string) (string, string) {
+	sum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return hex.EncodeToString(sum[:]), hex.EncodeToString(mac.Sum(nil))
+}
+
+// RunComplianceExport appends the postings numbered since the last export to the compliance bucket. Postings
+// are written in batches, each numbered after the last, chained to it by its hash, signed, and locked with S3
+// Object Lock so it cannot be changed or deleted before its retention ends. Every ledger's postings must
+// continue from the last sequence number exported for it: a gap stops the export and is recorded in its state,
+// since skipping it would leave the immutable record incomplete. A batch already in the bucket under the next
+// number, written by an export that stopped before saving its state, is taken up instead of written again.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - *model.ComplianceExportRun: What was exported.
+// - error: An error if the export is not configured, is already running, or a batch could not be exported.
+func (l *Blnk) RunComplianceExport(ctx context.Context) (*model.ComplianceExportRun, error) {
+	ctx, span := tracer.Start(ctx, "RunComplianceExport")
+	defer span.End()
+
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	cfg := cnf.ComplianceExport
+	if cfg.Bucket == "" {
+		return nil, errors.New("compliance export is not configured")
+	}
+
+	locker := redlock.NewLocker(l.redis, "compliance-export", model.GenerateUUIDWithSuffix("loc"))
+	if err := locker.Lock(ctx, complianceExportLockTimeout); err != nil {
+		return nil, fmt.Errorf("failed to acquire lock for compliance export: %w", err)
+	}
+	defer l.releaseLock(ctx, locker)
+
+	store, err := newComplianceStore(cnf)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	state, err := l.datasource.GetComplianceExportState(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	run := &model.ComplianceExportRun{StartedAt: time.Now().UTC()}
+	defer func() {
+		run.CompletedAt = time.Now().UTC()
+		run.LastBatch = state.LastBatch
+	}()
+	for {
+		if err := ctx.Err(); err != nil {
+			return run, err
+		}
+
+		key := complianceBatchKey(cfg.Prefix, state.LastBatch+1)
+		body, metadata, err := store.Get(ctx, key)
+		if err == nil {
+			if err := l.takeUpComplianceBatch(ctx, state, cfg, body, metadata); err != nil {
+				span.RecordError(err)
+				return run, l.saveComplianceExportFailure(ctx, state, err)
+			}
+			continue
+		}
+		if !errors.Is(err, errComplianceBatchNotFound) {
+			span.RecordError(err)
+			return run, l.saveComplianceExportFailure(ctx, state, err)
+		}
+
+		backlog, err := l.datasource.GetComplianceBacklog(ctx, cfg.BatchSize)
+		if err != nil {
+			span.RecordError(err)
+			return run, l.saveComplianceExportFailure(ctx, state, err)
+		}
+		if len(backlog.Records) == 0 {
+			break
+		}
+
+		now := time.Now().UTC()
+		batch, cursors, err := buildComplianceBatch(state.LastBatch+1, state.LastHash, backlog, now)
+		if err != nil {
+			span.RecordError(err)
+			return run, l.saveComplianceExportFailure(ctx, state, err)
+		}
+		body, err = json.Marshal(batch)
+		if err != nil {
+			return run, err
+		}
+		hash, signature := signComplianceBatch(body, cfg.SigningKey)
+		metadata = map[string]string{complianceHashMetadata: hash, complianceSignatureMetadata: signature}
+		retainUntil := now.AddDate(0, 0, cfg.RetentionDays)
+		if err := store.PutLocked(ctx, key, body, metadata, retainUntil); err != nil {
+			span.RecordError(err)
+			return run, l.saveComplianceExportFailure(ctx, state, err)
+		}
+
+		state.LastBatch = batch.BatchNumber
+		state.LastHash = hash
+		state.RecordsExported += int64(len(batch.Records))
+		state.LastExportedAt = &now
+		state.LastError = ""
+		if err := l.datasource.SaveComplianceExportState(ctx, state, cursors); err != nil {
+			span.RecordError(err)
+			return run, err
+		}
+		run.Batches++
+		run.Records += len(batch.Records)
+		if len(backlog.Records) < cfg.BatchSize {
+			break
+		}
+	}
+
+	if state.LastError != "" {
+		state.LastError = ""
+		if err := l.datasource.SaveComplianceExportState(ctx, state, nil); err != nil {
+			return run, err
+		}
+	}
+	return run, nil
+}
+
+// GetComplianceExportState retrieves how far postings have been exported to the compliance bucket and how the
+// last export went.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - *model.ComplianceExportState: The state of the export.
+// - error: An error if the state could not be retrieved.
+func (l *Blnk) GetComplianceExportState(ctx context.Context) (*model.ComplianceExportState, error) {
+	return l.datasource.GetComplianceExportState(ctx)
+}
+
+// buildComplianceBatch builds the next batch from the backlog, checking that the postings of every ledger
+// continue from the last sequence number exported for it without gaps.
+func buildComplianceBatch(number int64, previousHash string, backlog *model.ComplianceBacklog, now time.Time) (*model.ComplianceBatch, map[string]int64, error) {
+	batch := &model.ComplianceBatch{BatchNumber: number, PreviousHash: previousHash, CreatedAt: now, Records: backlog.Records}
+	cursors := map[string]int64{}
+	for _, record := range backlog.Records {
+		last, seen := cursors[record.LedgerID]
+		if !seen {
+			last = backlog.Exported[record.LedgerID]
+			batch.Ranges = append(batch.Ranges, model.ComplianceSequenceRange{LedgerID: record.LedgerID, FirstSequence: record.Sequence})
+		}
+		if record.Sequence != last+1 {
+			return nil, nil, fmt.Errorf("%w: ledger %s continues at sequence %d, expected %d", ErrComplianceSequenceGap, record.LedgerID, record.Sequence, last+1)
+		}
+		if record.TransactionID == "" {
+			return nil, nil, fmt.Errorf("%w: the transaction of sequence %d of ledger %s is missing", ErrComplianceSequenceGap, record.Sequence, record.LedgerID)
+		}
+		cursors[record.LedgerID] = record.Sequence
+		batch.Ranges[len(batch.Ranges)-1].LastSequence = record.Sequence
+	}
+	return batch, cursors, nil
+}
+
+// takeUpComplianceBatch advances the export state past a batch found in the bucket under the next number. The
+// batch must be signed with the signing key and follow the last batch exported, or the export stops for an
+// operator to look at the bucket.
+func (l *Blnk) takeUpComplianceBatch(ctx context.Context, state *model.ComplianceExportState, cfg config.ComplianceExportConfig, body []byte, metadata map[string]string) error {
+	hash, signature := signComplianceBatch(body, cfg.SigningKey)
+	var batch model.ComplianceBatch
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return fmt.Errorf("batch %d in the compliance bucket cannot be read: %w", state.LastBatch+1, err)
+	}
+	if !hmac.Equal([]byte(metadata[complianceSignatureMetadata]), []byte(signature)) ||
+		batch.BatchNumber != state.LastBatch+1 || batch.PreviousHash != state.LastHash {
+		return fmt.Errorf("batch %d in the compliance bucket does not continue the export", state.LastBatch+1)
+	}
+
+	cursors := make(map[string]int64, len(batch.Ranges))
+	for _, sequences := range batch.Ranges {
+		cursors[sequences.LedgerID] = sequences.LastSequence
+	}
+	logrus.Warnf("compliance export: taking up batch %d found in the bucket", batch.BatchNumber)
+	state.LastBatch = batch.BatchNumber
+	state.LastHash = hash
+	state.RecordsExported += int64(len(batch.Records))
+	state.LastExportedAt = &batch.CreatedAt
+	return l.datasource.SaveComplianceExportState(ctx, state, cursors)
+}
+
+// saveComplianceExportFailure records the failure of an export and returns it. The state is otherwise kept,
+// so the next export retries from the same batch.
+func (l *Blnk) saveComplianceExportFailure(ctx context.Context, state *model.ComplianceExportState, cause error) error {
+	state.LastError = cause.Error()
+	if err := l.datasource.SaveComplianceExportState(ctx, state, nil); err != nil {
+		logrus.Errorf("failed to save compliance export state: %v", err)
+	}
+	return cause
+}
+
+// VerifyComplianceExport reads every batch in the compliance bucket and checks that they form an intact record:
+// batches are numbered without gaps, each hash and signature matches its contents, each batch carries the hash
+// of the one before it, the sequence of every ledger continues from one posting to the next, and the last batch
+// is the one the export state records.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - *model.ComplianceVerification: What was checked and any issues found.
+// - error: An error if the export is not configured or the bucket could not be read.
+func (l *Blnk) VerifyComplianceExport(ctx context.Context) (*model.ComplianceVerification, error) {
+	ctx, span := tracer.Start(ctx, "VerifyComplianceExport")
+	defer span.End()
+
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	cfg := cnf.ComplianceExport
+	if cfg.Bucket == "" {
+		return nil, errors.New("compliance export is not configured")
+	}
+
+	store, err := newComplianceStore(cnf)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	keys, err := store.List(ctx, cfg.Prefix+"/")
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	verification := &model.ComplianceVerification{VerifiedAt: time.Now().UTC(), Issues: []string{}}
+	issue := func(format string, args ...interface{}) {
+		verification.Issues = append(verification.Issues, fmt.Sprintf(format, args...))
+	}
+	sequences := map[string]int64{}
+	var lastBatch int64
+	for _, key := range keys {
+		if len(verification.Issues) >= complianceVerifyMaxIssues {
+			issue("verification stopped after %d issues", complianceVerifyMaxIssues)
+			break
+		}
+		body, metadata, err := store.Get(ctx, key)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		var batch model.ComplianceBatch
+		if err := json.Unmarshal(body, &batch); err != nil {
+			issue("%s cannot be read: %v", key, err)
+			continue
+		}
+		hash, signature := signComplianceBatch(body, cfg.SigningKey)
+		if metadata[complianceHashMetadata] != hash {
+			issue("batch %d does not match its hash", batch.BatchNumber)
+		}
+		if !hmac.Equal([]byte(metadata[complianceSignatureMetadata]), []byte(signature)) {
+			issue("batch %d has an invalid signature", batch.BatchNumber)
+		}
+		if key != complianceBatchKey(cfg.Prefix, batch.BatchNumber) {
+			issue("%s holds batch %d", key, batch.BatchNumber)
+		}
+		if batch.BatchNumber != lastBatch+1 {
+			issue("batches %d to %d are missing", lastBatch+1, batch.BatchNumber-1)
+		}
+		if batch.PreviousHash != verification.LastHash {
+			issue("batch %d does not follow the batch before it", batch.BatchNumber)
+		}
+		for _, record := range batch.Records {
+			if expected := sequences[record.LedgerID] + 1; record.Sequence != expected {
+				issue("batch %d: ledger %s continues at sequence %d, expected %d", batch.BatchNumber, record.LedgerID, record.Sequence, expected)
+			}
+			sequences[record.LedgerID] = record.Sequence
+		}
+
+		verification.Batches++
+		verification.Records += int64(len(batch.Records))
+		verification.LastHash = hash
+		lastBatch = batch.BatchNumber
+	}
+	verification.Ledgers = len(sequences)
+
+	state, err := l.datasource.GetComplianceExportState(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if state.LastBatch != lastBatch {
+		issue("the export state records batch %d as the last, the bucket ends at batch %d", state.LastBatch, lastBatch)
+	}
+	verification.Valid = len(verification.Issues) == 0
+	return verification, nil
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeComplianceObject struct {
+	body        []byte
+	metadata    map[string]string
+	retainUntil time.Time
+}
+
+type fakeComplianceStore struct {
+	objects map[string]fakeComplianceObject
+}
+
+func (s *fakeComplianceStore) PutLocked(_ context.Context, key string, body []byte, metadata map[string]string, retainUntil time.Time) error {
+	if _, exists := s.objects[key]; exists {
+		return errors.New("object is locked")
+	}
+	s.objects[key] = fakeComplianceObject{body: body, metadata: metadata, retainUntil: retainUntil}
+	return nil
+}
+
+func (s *fakeComplianceStore) Get(_ context.Context, key string) ([]byte, map[string]string, error) {
+	object, exists := s.objects[key]
+	if !exists {
+		return nil, nil, errComplianceBatchNotFound
+	}
+	return object.body, object.metadata, nil
+}
+
+func (s *fakeComplianceStore) List(_ context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func useFakeComplianceStore(t *testing.T) *fakeComplianceStore {
+	store := &fakeComplianceStore{objects: map[string]fakeComplianceObject{}}
+	previous := newComplianceStore
+	newComplianceStore = func(*config.Configuration) (complianceStore, error) { return store, nil }
+	t.Cleanup(func() { newComplianceStore = previous })
+
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.ComplianceExport = config.ComplianceExportConfig{
+		Bucket:        "ledger-archive",
+		Prefix:        "compliance",
+		BatchSize:     2,
+		RetentionMode: "COMPLIANCE",
+		RetentionDays: 30,
+		SigningKey:    "secret",
+	}
+	return store
+}
+
+func complianceRecord(ledgerID string, sequence int64) model.ComplianceRecord {
+	return model.ComplianceRecord{LedgerID: ledgerID, Sequence: sequence, TransactionID: ledgerID + "_txn", PreciseAmount: "100", Currency: "USD"}
+}
+
+func TestRunComplianceExport(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	store := useFakeComplianceStore(t)
+
+	first := &model.ComplianceBacklog{
+		Records:  []model.ComplianceRecord{complianceRecord("ldg_1", 1), complianceRecord("ldg_2", 1)},
+		Exported: map[string]int64{"ldg_1": 0, "ldg_2": 0},
+	}
+	second := &model.ComplianceBacklog{Records: []model.ComplianceRecord{complianceRecord("ldg_2", 2)}, Exported: map[string]int64{"ldg_2": 1}}
+	mockDS.On("GetComplianceExportState", mock.Anything).Return(&model.ComplianceExportState{}, nil)
+	mockDS.On("GetComplianceBacklog", mock.Anything, 2).Return(first, nil).Once()
+	mockDS.On("GetComplianceBacklog", mock.Anything, 2).Return(second, nil).Once()
+
+	var cursors []map[string]int64
+	mockDS.On("SaveComplianceExportState", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		cursors = append(cursors, args.Get(2).(map[string]int64))
+	}).Return(nil)
+
+	run, err := b.RunComplianceExport(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, run.Batches)
+	assert.Equal(t, 3, run.Records)
+	assert.Equal(t, int64(2), run.LastBatch)
+	assert.Equal(t, []map[string]int64{{"ldg_1": 1, "ldg_2": 1}, {"ldg_2": 2}}, cursors)
+
+	object := store.objects["compliance/00000000000000000002.json"]
+	var batch model.ComplianceBatch
+	require.NoError(t, json.Unmarshal(object.body, &batch))
+	firstHash, _ := signComplianceBatch(store.objects["compliance/00000000000000000001.json"].body, "secret")
+	assert.Equal(t, firstHash, batch.PreviousHash)
+	assert.Equal(t, []model.ComplianceSequenceRange{{LedgerID: "ldg_2", FirstSequence: 2, LastSequence: 2}}, batch.Ranges)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), object.retainUntil, time.Minute)
+
+	mockDS.On("GetComplianceExportState", mock.Anything).Unset()
+	mockDS.On("GetComplianceExportState", mock.Anything).Return(&model.ComplianceExportState{LastBatch: 2, LastHash: object.metadata[complianceHashMetadata]}, nil)
+	verification, err := b.VerifyComplianceExport(context.Background())
+	require.NoError(t, err)
+	assert.True(t, verification.Valid, verification.Issues)
+	assert.Equal(t, int64(3), verification.Records)
+	assert.Equal(t, 2, verification.Ledgers)
+}
+
+func TestRunComplianceExport_SequenceGap(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	store := useFakeComplianceStore(t)
+
+	backlog := &model.ComplianceBacklog{Records: []model.ComplianceRecord{complianceRecord("ldg_1", 5)}, Exported: map[string]int64{"ldg_1": 3}}
+	mockDS.On("GetComplianceExportState", mock.Anything).Return(&model.ComplianceExportState{}, nil)
+	mockDS.On("GetComplianceBacklog", mock.Anything, 2).Return(backlog, nil)
+
+	var saved model.ComplianceExportState
+	mockDS.On("SaveComplianceExportState", mock.Anything, mock.Anything, map[string]int64(nil)).Run(func(args mock.Arguments) {
+		saved = *args.Get(1).(*model.ComplianceExportState)
+	}).Return(nil)
+
+	_, err := b.RunComplianceExport(context.Background())
+	assert.ErrorIs(t, err, ErrComplianceSequenceGap)
+	assert.Contains(t, saved.LastError, "continues at sequence 5, expected 4")
+	assert.Empty(t, store.objects)
+}
+
+func TestRunComplianceExport_TakesUpWrittenBatch(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	store := useFakeComplianceStore(t)
+
+	batch := model.ComplianceBatch{
+		BatchNumber: 1,
+		Ranges:      []model.ComplianceSequenceRange{{LedgerID: "ldg_1", FirstSequence: 1, LastSequence: 1}},
+		Records:     []model.ComplianceRecord{complianceRecord("ldg_1", 1)},
+	}
+	body, err := json.Marshal(batch)
+	require.NoError(t, err)
+	hash, signature := signComplianceBatch(body, "secret")
+	store.objects["compliance/00000000000000000001.json"] = fakeComplianceObject{
+		body:     body,
+		metadata: map[string]string{complianceHashMetadata: hash, complianceSignatureMetadata: signature},
+	}
+
+	mockDS.On("GetComplianceExportState", mock.Anything).Return(&model.ComplianceExportState{}, nil)
+	mockDS.On("GetComplianceBacklog", mock.Anything, 2).Return(&model.ComplianceBacklog{}, nil)
+	mockDS.On("SaveComplianceExportState", mock.Anything, mock.Anything, map[string]int64{"ldg_1": 1}).Return(nil).Once()
+
+	run, err := b.RunComplianceExport(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, run.Batches)
+	assert.Equal(t, int64(1), run.LastBatch)
+	mockDS.AssertExpectations(t)
+}
+
+func TestVerifyComplianceExport_DetectsTampering(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	store := useFakeComplianceStore(t)
+
+	previousHash := ""
+	for number, sequence := range []int64{1, 3} {
+		batch := model.ComplianceBatch{BatchNumber: int64(number + 1), PreviousHash: previousHash, Records: []model.ComplianceRecord{complianceRecord("ldg_1", sequence)}}
+		body, err := json.Marshal(batch)
+		require.NoError(t, err)
+		hash, signature := signComplianceBatch(body, "secret")
+		store.objects[complianceBatchKey("compliance", batch.BatchNumber)] = fakeComplianceObject{
+			body:     body,
+			metadata: map[string]string{complianceHashMetadata: hash, complianceSignatureMetadata: signature},
+		}
+		previousHash = hash
+	}
+	first := store.objects["compliance/00000000000000000001.json"]
+	first.body = []byte(strings.Replace(string(first.body), `"precise_amount":"100"`, `"precise_amount":"900"`, 1))
+	store.objects["compliance/00000000000000000001.json"] = first
+
+	mockDS.On("GetComplianceExportState", mock.Anything).Return(&model.ComplianceExportState{LastBatch: 2}, nil)
+
+	verification, err := b.VerifyComplianceExport(context.Background())
+	require.NoError(t, err)
+	assert.False(t, verification.Valid)
+	assert.Equal(t, []string{
+		"batch 1 does not match its hash",
+		"batch 1 has an invalid signature",
+		"batch 2 does not follow the batch before it",
+		"batch 2: ledger ldg_1 continues at sequence 3, expected 2",
+	}, verification.Issues)
+}
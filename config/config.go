@@ -19,10 +19,14 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"net"
 	"os"
+	"regexp"
 	"strings"
 	"sync/atomic"
+	"text/template"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -47,6 +51,9 @@ var (
 		LockDuration:       30 * time.Minute,
 		IndexQueuePrefix:   "transactions",
 		EnableQueuedChecks: false,
+		MaxTimeSkewPast:    24 * time.Hour,
+		MaxTimeSkewFuture:  5 * time.Minute,
+		HistoryOrder:       "created_at",
 	}
 
 	defaultReconciliation = ReconciliationConfig{
@@ -55,6 +62,8 @@ var (
 		MaxRetries:       3,
 		RetryDelay:       5 * time.Second,
 	}
+	defaultAutoConfirmThreshold = 0.9
+	defaultReviewThreshold      = 0.6
 
 	defaultQueue = QueueConfig{
 		TransactionQueue:    "new:transaction",
@@ -63,14 +72,115 @@ var (
 		InflightExpiryQueue: "new:inflight-expiry",
 		NumberOfQueues:      20,
 		MonitoringPort:      DEFAULT_MONITORING_PORT,
+		MinConcurrency:      1,
+		ScaleInterval:       10 * time.Second,
+		TargetApplyLatency:  500 * time.Millisecond,
 	}
 
 	defaultDatabase = DataSourceConfig{
+		Driver:          DriverPostgres,
 		MaxOpenConns:    25,
 		MaxIdleConns:    10,
 		ConnMaxLifetime: 30 * time.Minute,
 		ConnMaxIdleTime: 5 * time.Minute,
+
+		ReplicaMaxLag:        10 * time.Second,
+		ReplicaCheckInterval: 5 * time.Second,
+	}
+
+	defaultSecretRotation = SecretRotationConfig{
+		OverlapPeriod: 24 * time.Hour,
+	}
+
+	defaultServiceAccounts = ServiceAccountConfig{
+		TokenTTL: 15 * time.Minute,
+	}
+
+	defaultEncryptedMetadataMask = "********"
+
+	defaultCardAuthorization = CardAuthorizationConfig{
+		DefaultExpiry: 7 * 24 * time.Hour,
+	}
+
+	defaultAuthorizationLatencyBudget = 50 * time.Millisecond
+
+	defaultChallengeTimeout = 5 * time.Minute
+
+	defaultContactVerification = ContactVerificationConfig{
+		CodeTTL:     15 * time.Minute,
+		MaxAttempts: 5,
+	}
+
+	defaultRiskScoring = RiskScoringConfig{
+		MediumThreshold: 40,
+		HighThreshold:   70,
+	}
+
+	defaultWebhookCircuit = WebhookCircuitConfig{
+		FailureThreshold: 5,
+		ProbeInterval:    time.Minute,
+		MaxProbeInterval: 30 * time.Minute,
+	}
+
+	defaultRounding = RoundingConfig{
+		DifferenceBalance: "@RoundingDifference",
+	}
+
+	defaultDormancy = DormancyConfig{
+		HoldingBalance: "@EscheatmentHolding",
+		ScanInterval:   time.Hour,
+	}
+
+	defaultStatementIngestion = StatementIngestionConfig{
+		PollInterval: 5 * time.Minute,
+		IMAP:         IMAPConfig{Port: 993, Mailbox: "INBOX"},
+		SFTP:         SFTPConfig{Port: 22},
+	}
+
+	defaultRequestLog = RequestLogConfig{
+		Retention:    7 * 24 * time.Hour,
+		MaxEntries:   100000,
+		MaxBodyBytes: 4096,
 	}
+
+	defaultWarehouse = WarehouseConfig{
+		SyncInterval: 15 * time.Minute,
+		BatchSize:    5000,
+		SettleDelay:  time.Minute,
+	}
+
+	defaultComplianceExport = ComplianceExportConfig{
+		Prefix:        "compliance",
+		Interval:      5 * time.Minute,
+		BatchSize:     1000,
+		RetentionMode: "COMPLIANCE",
+		RetentionDays: 2555,
+	}
+
+	defaultEOD = EODConfig{
+		Timezone:   "UTC",
+		CutoffTime: "23:59",
+	}
+
+	defaultEODStep = EODStepConfig{
+		RetryBackoff: time.Minute,
+		Timeout:      30 * time.Minute,
+	}
+
+	defaultCalendar = CalendarConfig{
+		Timezone: "UTC",
+		Weekend:  []string{"saturday", "sunday"},
+	}
+
+	defaultScheduledCalendarRoll = "following"
+
+	defaultEgress = EgressConfig{
+		ConnectTimeout:      10,
+		TLSHandshakeTimeout: 10,
+		RequestTimeout:      30,
+	}
+
+	defaultWebhookDeliveryRetention = 30 * 24 * time.Hour
 )
 
 var ConfigStore atomic.Value
@@ -84,12 +194,35 @@ type ServerConfig struct {
 	Port      string `json:"port" envconfig:"BLNK_SERVER_PORT"`
 }
 
+// DataSourceConfig configures the Postgres connection pool. EnableRLS applies the tenant row-level security
+// policies on migrate up and sets the tenant of each statement on its database session.
+//
+// The pool settings apply to the primary and to each read replica. Unset settings take Blnk's defaults rather
+// than those of database/sql, which keeps no limit on open connections and only two idle ones, so bursts of
+// load open and close connections faster than Postgres can accept them. A negative MaxOpenConns removes the
+// limit, and a negative MaxIdleConns keeps no idle connections.
 type DataSourceConfig struct {
-	Dns             string        `json:"dns" envconfig:"BLNK_DATA_SOURCE_DNS"`
-	MaxOpenConns    int           `json:"max_open_conns" envconfig:"BLNK_DATABASE_MAX_OPEN_CONNS"`
-	MaxIdleConns    int           `json:"max_idle_conns" envconfig:"BLNK_DATABASE_MAX_IDLE_CONNS"`
+	Dns string `json:"dns" envconfig:"BLNK_DATA_SOURCE_DNS"`
+	// Driver is the Postgres driver the primary and read replicas are opened with: DriverPostgres (lib/pq) or
+	// DriverPgx, which uses the binary protocol, pipelines batched inserts and copies bulk imports with COPY in
+	// binary format.
+	Driver string `json:"driver" envconfig:"BLNK_DATABASE_DRIVER"`
+	// MaxOpenConns is the most connections the pool opens, in use or idle.
+	MaxOpenConns int `json:"max_open_conns" envconfig:"BLNK_DATABASE_MAX_OPEN_CONNS"`
+	// MaxIdleConns is the most idle connections kept open for reuse. It cannot exceed MaxOpenConns.
+	MaxIdleConns int `json:"max_idle_conns" envconfig:"BLNK_DATABASE_MAX_IDLE_CONNS"`
+	// ConnMaxLifetime is how long a connection is reused before it is closed and replaced.
 	ConnMaxLifetime time.Duration `json:"conn_max_lifetime" envconfig:"BLNK_DATABASE_CONN_MAX_LIFETIME"`
+	// ConnMaxIdleTime is how long a connection can stay idle before it is closed.
 	ConnMaxIdleTime time.Duration `json:"conn_max_idle_time" envconfig:"BLNK_DATABASE_CONN_MAX_IDLE_TIME"`
+	EnableRLS       bool          `json:"enable_rls" envconfig:"BLNK_DATABASE_ENABLE_RLS"`
+	// Replicas are the connection strings of read replicas. List, history and point-in-time balance reads
+	// go to a replica whose lag is within ReplicaMaxLag, and to the primary when none is.
+	Replicas []string `json:"replicas" envconfig:"BLNK_DATABASE_REPLICAS"`
+	// ReplicaMaxLag is how far a replica can be behind the primary and still serve reads.
+	ReplicaMaxLag time.Duration `json:"replica_max_lag" envconfig:"BLNK_DATABASE_REPLICA_MAX_LAG"`
+	// ReplicaCheckInterval is how often the lag of each replica is measured.
+	ReplicaCheckInterval time.Duration `json:"replica_check_interval" envconfig:"BLNK_DATABASE_REPLICA_CHECK_INTERVAL"`
 }
 
 type RedisConfig struct {
@@ -123,16 +256,785 @@ type SlackWebhook struct {
 	WebhookUrl string `json:"webhook_url" envconfig:"BLNK_SLACK_WEBHOOK_URL"`
 }
 
+// WebhookConfig configures the endpoint webhooks are delivered to. Every delivery is recorded for the delivery
+// analytics of the endpoint, and records are deleted once they are older than DeliveryRetention.
 type WebhookConfig struct {
-	Url     string            `json:"url" envconfig:"BLNK_WEBHOOK_URL"`
-	Headers map[string]string `json:"headers" envconfig:"BLNK_WEBHOOK_HEADERS"`
+	Url               string            `json:"url" envconfig:"BLNK_WEBHOOK_URL"`
+	Headers           map[string]string `json:"headers" envconfig:"BLNK_WEBHOOK_HEADERS"`
+	SigningSecret     string            `json:"signing_secret" envconfig:"BLNK_WEBHOOK_SIGNING_SECRET"`
+	Egress            EgressConfig      `json:"egress"`
+	DeliveryRetention time.Duration     `json:"delivery_retention" envconfig:"BLNK_WEBHOOK_DELIVERY_RETENTION"`
+}
+
+// SecretRotationConfig controls how long a rotated credential keeps working alongside its replacement.
+type SecretRotationConfig struct {
+	OverlapPeriod time.Duration `json:"overlap_period" envconfig:"BLNK_SECRET_ROTATION_OVERLAP"`
+}
+
+// ServiceAccountConfig controls the short-lived tokens issued to service accounts. When no signing key
+// is set, tokens are signed with the server secret key.
+type ServiceAccountConfig struct {
+	TokenSigningKey string        `json:"token_signing_key" envconfig:"BLNK_SERVICE_ACCOUNT_TOKEN_SIGNING_KEY"`
+	TokenTTL        time.Duration `json:"token_ttl" envconfig:"BLNK_SERVICE_ACCOUNT_TOKEN_TTL"`
+}
+
+// NetworkPolicyConfig controls how API key network policies find where a request comes from.
+// TrustedProxies lists the IP addresses or CIDR ranges of the proxies and load balancers in front of
+// Blnk. X-Forwarded-For is only followed through them, and CountryHeader, a header set by a proxy or
+// CDN (for example CF-IPCountry), is only read from requests they forwarded. Without trusted proxies the
+// client is the peer of the connection and the country header is ignored.
+type NetworkPolicyConfig struct {
+	CountryHeader  string   `json:"country_header" envconfig:"BLNK_NETWORK_POLICY_COUNTRY_HEADER"`
+	TrustedProxies []string `json:"trusted_proxies" envconfig:"BLNK_NETWORK_POLICY_TRUSTED_PROXIES"`
+}
+
+// TrustsProxy reports whether ip is one of the trusted proxies.
+func (c NetworkPolicyConfig) TrustsProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if ip.Equal(net.ParseIP(proxy)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c NetworkPolicyConfig) validate() error {
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("trusted proxy %q is not an IP address or CIDR range", proxy)
+		}
+	}
+	return nil
+}
+
+// EncryptedMetadataConfig lists metadata keys whose values are encrypted at rest. Only callers holding
+// the encrypted-metadata:read scope see the values; everyone else sees Mask in their place.
+type EncryptedMetadataConfig struct {
+	Keys []string `json:"keys" envconfig:"BLNK_ENCRYPTED_METADATA_KEYS"`
+	Mask string   `json:"mask" envconfig:"BLNK_ENCRYPTED_METADATA_MASK"`
+}
+
+// CardAuthorizationConfig controls how long card authorization holds last before they expire.
+// SchemeExpiry overrides DefaultExpiry per card scheme (for example visa or mastercard), keyed in lower case.
+type CardAuthorizationConfig struct {
+	DefaultExpiry time.Duration            `json:"default_expiry" envconfig:"BLNK_CARD_AUTHORIZATION_DEFAULT_EXPIRY"`
+	SchemeExpiry  map[string]time.Duration `json:"scheme_expiry" envconfig:"BLNK_CARD_AUTHORIZATION_SCHEME_EXPIRY"`
+}
+
+// AuthorizationConfig tunes POST /authorize, the hot path that approves card payments against a balance.
+// Authorizations not decided within LatencyBudget are declined rather than left waiting. Rules are the amount
+// and velocity limits checked before the hold is placed.
+type AuthorizationConfig struct {
+	LatencyBudget time.Duration       `json:"latency_budget" envconfig:"BLNK_AUTHORIZATION_LATENCY_BUDGET"`
+	Rules         []AuthorizationRule `json:"rules"`
+}
+
+// AuthorizationRule limits the authorizations of a source balance. It applies to authorizations in Currency
+// and from one of Sources, or to every authorization when those are empty. No single authorization may exceed
+// MaxAmount, and within each Window a balance may be authorized at most MaxCount times and for at most MaxTotal
+// in all. Limits left at zero are not enforced.
+//
+// Enforcement is hard, the default, or soft. Authorizations exceeding a hard rule are declined; those exceeding a
+// soft rule are approved with a limit.warning event and the rule noted on their hold, so that a new limit can be
+// monitored before it is enforced.
+type AuthorizationRule struct {
+	Name        string        `json:"name"`
+	Currency    string        `json:"currency"`
+	Sources     []string      `json:"sources"`
+	MaxAmount   float64       `json:"max_amount"`
+	MaxCount    int           `json:"max_count"`
+	MaxTotal    float64       `json:"max_total"`
+	Window      time.Duration `json:"window"`
+	Enforcement string        `json:"enforcement"`
+}
+
+// Postgres drivers the data source can be opened with.
+const (
+	DriverPostgres = "postgres"
+	DriverPgx      = "pgx"
+)
+
+// Enforcements of authorization rules.
+const (
+	EnforcementHard = "hard"
+	EnforcementSoft = "soft"
+)
+
+// IsSoft reports whether the rule only warns about authorizations exceeding it.
+func (r AuthorizationRule) IsSoft() bool {
+	return r.Enforcement == EnforcementSoft
+}
+
+// ScheduledRetryConfig is the retry policy applied to scheduled transactions that fail for insufficient funds
+// and do not carry their own policy. Retries are disabled while MaxAttempts is zero.
+type ScheduledRetryConfig struct {
+	MaxAttempts  int  `json:"max_attempts" envconfig:"BLNK_SCHEDULED_RETRY_MAX_ATTEMPTS"`
+	WindowDays   int  `json:"window_days" envconfig:"BLNK_SCHEDULED_RETRY_WINDOW_DAYS"`
+	AllowPartial bool `json:"allow_partial" envconfig:"BLNK_SCHEDULED_RETRY_ALLOW_PARTIAL"`
+}
+
+// ScheduledCalendarConfig is the business day rule applied to scheduled transactions that do not carry their
+// own: the dates they run on are kept to the business days of Calendar, one of the configured calendars, rolling
+// dates that fall on weekends or holidays by Roll. No rule is applied while Calendar is empty.
+type ScheduledCalendarConfig struct {
+	Calendar string `json:"calendar" envconfig:"BLNK_SCHEDULED_CALENDAR"`
+	Roll     string `json:"roll" envconfig:"BLNK_SCHEDULED_CALENDAR_ROLL"`
+}
+
+// APIVersionConfig controls which API versions a deployment serves. Deprecated versions keep working but
+// carry a Deprecation header, and a Sunset header when Sunset has a date for them. Disabled versions
+// answer 410 Gone. Unversioned routes are served as v1.
+// ListEnvelope wraps v1 list responses in the data/meta/links envelope that later versions always use;
+// it is off by default so existing clients keep receiving bare arrays.
+type APIVersionConfig struct {
+	Disabled     []string          `json:"disabled" envconfig:"BLNK_API_DISABLED_VERSIONS"`
+	Deprecated   []string          `json:"deprecated" envconfig:"BLNK_API_DEPRECATED_VERSIONS"`
+	Sunset       map[string]string `json:"sunset" envconfig:"BLNK_API_VERSION_SUNSET"`
+	ListEnvelope bool              `json:"list_envelope" envconfig:"BLNK_API_LIST_ENVELOPE"`
+
+	// FieldCompatibility is the default field compatibility mode of v1 callers whose API key has none:
+	// legacy, both or current. It defaults to legacy.
+	FieldCompatibility string `json:"field_compatibility" envconfig:"BLNK_API_FIELD_COMPATIBILITY"`
+}
+
+// WebhookCircuitConfig controls the circuit breaker in front of each webhook endpoint. After FailureThreshold
+// consecutive failed deliveries the circuit opens and further deliveries are parked. A parked delivery is
+// sent as a probe after ProbeInterval, which doubles after each failed probe up to MaxProbeInterval; a
+// successful probe closes the circuit and releases the parked deliveries. A negative FailureThreshold
+// disables the breaker.
+//
+// When DisableAfter is set, an endpoint whose circuit stays open that long, failing every delivery and
+// probe, is disabled: probing stops, operators are alerted, and deliveries stay parked until the endpoint is
+// enabled again.
+type WebhookCircuitConfig struct {
+	FailureThreshold int           `json:"failure_threshold" envconfig:"BLNK_WEBHOOK_CIRCUIT_FAILURE_THRESHOLD"`
+	ProbeInterval    time.Duration `json:"probe_interval" envconfig:"BLNK_WEBHOOK_CIRCUIT_PROBE_INTERVAL"`
+	MaxProbeInterval time.Duration `json:"max_probe_interval" envconfig:"BLNK_WEBHOOK_CIRCUIT_MAX_PROBE_INTERVAL"`
+	DisableAfter     time.Duration `json:"disable_after" envconfig:"BLNK_WEBHOOK_CIRCUIT_DISABLE_AFTER"`
+}
+
+// RoundingConfig controls how amounts that fall between two minor units are rounded when transactions are
+// split or converted. DefaultMode applies to every currency without an entry in Currencies, and both take
+// half_up, half_even or floor; without a mode amounts are truncated as before. The remainder a rounded split
+// leaves is posted to DifferenceBalance.
+type RoundingConfig struct {
+	DefaultMode       string            `json:"default_mode" envconfig:"BLNK_ROUNDING_DEFAULT_MODE"`
+	Currencies        map[string]string `json:"currencies" envconfig:"BLNK_ROUNDING_CURRENCIES"`
+	DifferenceBalance string            `json:"difference_balance" envconfig:"BLNK_ROUNDING_DIFFERENCE_BALANCE"`
+}
+
+// DormancyConfig controls dormancy tracking. A periodic scan flags balances with no transactions for
+// InactivityPeriod as dormant, and freezes them when Freeze is set so that postings to and from them are
+// rejected until they are reactivated. Escheatment batches transfer the funds of dormant balances to
+// HoldingBalance. A zero InactivityPeriod disables the scan.
+type DormancyConfig struct {
+	InactivityPeriod time.Duration `json:"inactivity_period" envconfig:"BLNK_DORMANCY_INACTIVITY_PERIOD"`
+	Freeze           bool          `json:"freeze" envconfig:"BLNK_DORMANCY_FREEZE"`
+	HoldingBalance   string        `json:"holding_balance" envconfig:"BLNK_DORMANCY_HOLDING_BALANCE"`
+	ScanInterval     time.Duration `json:"scan_interval" envconfig:"BLNK_DORMANCY_SCAN_INTERVAL"`
+}
+
+// StatementIngestionConfig configures the dead drop that reconciliation statements are collected from. Every
+// PollInterval, the IMAP mailbox and SFTP directory that have a host set are checked for new statement files.
+// Each file is matched to the first profile that accepts it, uploaded for reconciliation and, if the profile
+// says so, reconciled.
+type StatementIngestionConfig struct {
+	PollInterval time.Duration      `json:"poll_interval" envconfig:"BLNK_STATEMENT_INGESTION_POLL_INTERVAL"`
+	IMAP         IMAPConfig         `json:"imap"`
+	SFTP         SFTPConfig         `json:"sftp"`
+	Profiles     []IngestionProfile `json:"profiles"`
+}
+
+// IMAPConfig configures the mailbox statements are emailed to. Unseen messages are read over TLS and marked
+// seen once their attachments have been ingested.
+type IMAPConfig struct {
+	Host     string `json:"host" envconfig:"BLNK_STATEMENT_INGESTION_IMAP_HOST"`
+	Port     int    `json:"port" envconfig:"BLNK_STATEMENT_INGESTION_IMAP_PORT"`
+	Username string `json:"username" envconfig:"BLNK_STATEMENT_INGESTION_IMAP_USERNAME"`
+	Password string `json:"password" envconfig:"BLNK_STATEMENT_INGESTION_IMAP_PASSWORD"`
+	Mailbox  string `json:"mailbox" envconfig:"BLNK_STATEMENT_INGESTION_IMAP_MAILBOX"`
+}
+
+// SFTPConfig configures the folder statements are dropped in. HostKey is the server's public key in
+// authorized_keys format and is required. Ingested files are moved to ProcessedDirectory, or left in place
+// when it is empty.
+type SFTPConfig struct {
+	Host               string `json:"host" envconfig:"BLNK_STATEMENT_INGESTION_SFTP_HOST"`
+	Port               int    `json:"port" envconfig:"BLNK_STATEMENT_INGESTION_SFTP_PORT"`
+	Username           string `json:"username" envconfig:"BLNK_STATEMENT_INGESTION_SFTP_USERNAME"`
+	Password           string `json:"password" envconfig:"BLNK_STATEMENT_INGESTION_SFTP_PASSWORD"`
+	PrivateKeyFile     string `json:"private_key_file" envconfig:"BLNK_STATEMENT_INGESTION_SFTP_PRIVATE_KEY_FILE"`
+	HostKey            string `json:"host_key" envconfig:"BLNK_STATEMENT_INGESTION_SFTP_HOST_KEY"`
+	Directory          string `json:"directory" envconfig:"BLNK_STATEMENT_INGESTION_SFTP_DIRECTORY"`
+	ProcessedDirectory string `json:"processed_directory" envconfig:"BLNK_STATEMENT_INGESTION_SFTP_PROCESSED_DIRECTORY"`
+}
+
+// IngestionProfile describes the statements of one counterparty. A profile accepts a file when the address it
+// was emailed from is one of Senders, either exactly or by domain when written as "@bank.com", and its name
+// matches the FilenamePattern glob. Either can be left out, but not both. Columns maps the ID, Amount, Date, Reference, Description and Currency
+// columns the reconciliation expects to the headers the counterparty uses. When AutoReconcile is set, a
+// reconciliation with Strategy, GroupCriteria and MatchingRuleIDs is started once the file is uploaded.
+type IngestionProfile struct {
+	Name            string            `json:"name"`
+	Source          string            `json:"source"`
+	Senders         []string          `json:"senders"`
+	FilenamePattern string            `json:"filename_pattern"`
+	Columns         map[string]string `json:"columns"`
+	AutoReconcile   bool              `json:"auto_reconcile"`
+	Strategy        string            `json:"strategy"`
+	GroupCriteria   string            `json:"group_criteria"`
+	MatchingRuleIDs []string          `json:"matching_rule_ids"`
+	DryRun          bool              `json:"dry_run"`
+	NotifyEmail     string            `json:"notify_email"`
+}
+
+// RequestLogConfig controls the request log, which records the API requests the server receives so that
+// integrators can see what their systems sent. Bodies are cut to MaxBodyBytes, and the values of the JSON
+// fields and query parameters named in RedactFields are replaced, in addition to credentials and the
+// encrypted metadata keys. Entries are deleted once they are older than Retention or there are more than
+// MaxEntries of them.
+type RequestLogConfig struct {
+	Enabled      bool          `json:"enabled" envconfig:"BLNK_REQUEST_LOG_ENABLED"`
+	Retention    time.Duration `json:"retention" envconfig:"BLNK_REQUEST_LOG_RETENTION"`
+	MaxEntries   int           `json:"max_entries" envconfig:"BLNK_REQUEST_LOG_MAX_ENTRIES"`
+	MaxBodyBytes int           `json:"max_body_bytes" envconfig:"BLNK_REQUEST_LOG_MAX_BODY_BYTES"`
+	RedactFields []string      `json:"redact_fields" envconfig:"BLNK_REQUEST_LOG_REDACT_FIELDS"`
+}
+
+// WarehouseConfig configures the sync of transactions, balances and identities to an analytics warehouse, so
+// analytics can run there instead of on the production database. Every SyncInterval, the rows written since
+// the last sync are exported in batches of BatchSize to the Provider, "bigquery", "snowflake" or "redshift",
+// and the sync is disabled while Provider is empty. Rows written within the last SettleDelay are left for the
+// next sync, so rows committed late by slow transactions are not skipped. Tables maps "transactions",
+// "balances" and "identities" to the table and columns they are written to.
+type WarehouseConfig struct {
+	Provider     string                          `json:"provider" envconfig:"BLNK_WAREHOUSE_PROVIDER"`
+	SyncInterval time.Duration                   `json:"sync_interval" envconfig:"BLNK_WAREHOUSE_SYNC_INTERVAL"`
+	BatchSize    int                             `json:"batch_size" envconfig:"BLNK_WAREHOUSE_BATCH_SIZE"`
+	SettleDelay  time.Duration                   `json:"settle_delay" envconfig:"BLNK_WAREHOUSE_SETTLE_DELAY"`
+	Tables       map[string]WarehouseTableConfig `json:"tables"`
+	BigQuery     BigQueryConfig                  `json:"bigquery"`
+	Snowflake    SnowflakeConfig                 `json:"snowflake"`
+	Redshift     RedshiftConfig                  `json:"redshift"`
+}
+
+// ComplianceExportConfig configures the compliance export, which appends every posting to a bucket with S3
+// Object Lock so regulators have an immutable record of the ledger kept outside it. Every Interval, the postings
+// numbered since the last export are written under Prefix in Bucket, in batches of up to BatchSize signed with
+// SigningKey. Each batch is locked in RetentionMode, "COMPLIANCE" or "GOVERNANCE", for RetentionDays. The
+// bucket must have Object Lock enabled and is reached with the S3 settings used for backups. The export is
+// disabled while Bucket is empty.
+type ComplianceExportConfig struct {
+	Bucket        string        `json:"bucket" envconfig:"BLNK_COMPLIANCE_EXPORT_BUCKET"`
+	Prefix        string        `json:"prefix" envconfig:"BLNK_COMPLIANCE_EXPORT_PREFIX"`
+	Interval      time.Duration `json:"interval" envconfig:"BLNK_COMPLIANCE_EXPORT_INTERVAL"`
+	BatchSize     int           `json:"batch_size" envconfig:"BLNK_COMPLIANCE_EXPORT_BATCH_SIZE"`
+	RetentionMode string        `json:"retention_mode" envconfig:"BLNK_COMPLIANCE_EXPORT_RETENTION_MODE"`
+	RetentionDays int           `json:"retention_days" envconfig:"BLNK_COMPLIANCE_EXPORT_RETENTION_DAYS"`
+	SigningKey    string        `json:"signing_key" envconfig:"BLNK_COMPLIANCE_EXPORT_SIGNING_KEY"`
+}
+
+// EODConfig configures end-of-day processing. A business date closes at CutoffTime, in HH:MM, in Timezone, after
+// which its Steps run once in dependency order. With a Calendar, one of the configured calendars, only its
+// business days are processed. End-of-day processing is disabled while Steps is empty.
+type EODConfig struct {
+	Timezone   string          `json:"timezone" envconfig:"BLNK_EOD_TIMEZONE"`
+	CutoffTime string          `json:"cutoff_time" envconfig:"BLNK_EOD_CUTOFF_TIME"`
+	Calendar   string          `json:"calendar" envconfig:"BLNK_EOD_CALENDAR"`
+	Steps      []EODStepConfig `json:"steps"`
+}
+
+// EODStepConfig is a step of end-of-day processing. Kind is what the step does: "cutoff" records the business
+// date's cutoff, "settlements", "snapshots", "reports", "statements", "usage_export" and "dormancy" run the
+// ledger's own jobs, and "http" posts the business date to URL, signed with Secret, for work done by other
+// services such as interest accruals and fee sweeps. A step runs once the steps it DependsOn have succeeded, and
+// is skipped when any of them failed. Failed attempts are retried up to Retries times, RetryBackoff apart, and
+// each attempt is cut off after Timeout. The run completes with errors rather than failing when only Optional
+// steps failed.
+type EODStepConfig struct {
+	Name         string        `json:"name"`
+	Kind         string        `json:"kind"`
+	DependsOn    []string      `json:"depends_on"`
+	Retries      int           `json:"retries"`
+	RetryBackoff time.Duration `json:"retry_backoff"`
+	Timeout      time.Duration `json:"timeout"`
+	Optional     bool          `json:"optional"`
+	URL          string        `json:"url"`
+	Secret       string        `json:"secret"`
+}
+
+// OrderedSteps returns the steps in the order they run: every step after the steps it depends on, and otherwise
+// in the order they are configured. It fails if a step depends on an unknown step or the dependencies form a
+// cycle.
+func (c EODConfig) OrderedSteps() ([]EODStepConfig, error) {
+	index := make(map[string]int, len(c.Steps))
+	for i, step := range c.Steps {
+		index[step.Name] = i
+	}
+	for _, step := range c.Steps {
+		for _, dependency := range step.DependsOn {
+			if _, ok := index[dependency]; !ok {
+				return nil, fmt.Errorf("step %s depends on unknown step %s", step.Name, dependency)
+			}
+		}
+	}
+
+	ordered := make([]EODStepConfig, 0, len(c.Steps))
+	placed := make(map[string]bool, len(c.Steps))
+	for len(ordered) < len(c.Steps) {
+		progressed := false
+		for _, step := range c.Steps {
+			if placed[step.Name] {
+				continue
+			}
+			ready := true
+			for _, dependency := range step.DependsOn {
+				if !placed[dependency] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, step)
+				placed[step.Name] = true
+				progressed = true
+				break
+			}
+		}
+		if !progressed {
+			return nil, errors.New("step dependencies form a cycle")
+		}
+	}
+	return ordered, nil
+}
+
+func (c EODConfig) validate() error {
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", c.Timezone)
+	}
+	if _, err := time.Parse("15:04", c.CutoffTime); err != nil {
+		return fmt.Errorf("cutoff_time %q must be in HH:MM", c.CutoffTime)
+	}
+	names := make(map[string]bool, len(c.Steps))
+	for i, step := range c.Steps {
+		if step.Name == "" {
+			return fmt.Errorf("step %d: name is required", i)
+		}
+		if names[step.Name] {
+			return fmt.Errorf("step %s: name is used by another step", step.Name)
+		}
+		names[step.Name] = true
+		switch step.Kind {
+		case "cutoff", "settlements", "snapshots", "reports", "statements", "usage_export", "dormancy":
+		case "http":
+			if step.URL == "" {
+				return fmt.Errorf("step %s: url is required for http steps", step.Name)
+			}
+		default:
+			return fmt.Errorf("step %s: unknown kind %q", step.Name, step.Kind)
+		}
+		if step.Retries < 0 || step.RetryBackoff < 0 || step.Timeout < 0 {
+			return fmt.Errorf("step %s: retries, retry_backoff and timeout cannot be negative", step.Name)
+		}
+	}
+	_, err := c.OrderedSteps()
+	return err
+}
+
+// WarehouseTableConfig maps an exported entity to its warehouse table. Table defaults to the name of the
+// entity. Columns renames columns, keyed by their name in Blnk, and the columns in Exclude are not exported,
+// e.g. to keep personal details of identities out of the warehouse. Disabled stops the entity being synced.
+type WarehouseTableConfig struct {
+	Table    string            `json:"table"`
+	Columns  map[string]string `json:"columns"`
+	Exclude  []string          `json:"exclude"`
+	Disabled bool              `json:"disabled"`
+}
+
+// BigQueryConfig configures streaming inserts into a BigQuery dataset, authenticated with the JSON key of a
+// service account.
+type BigQueryConfig struct {
+	ProjectID       string `json:"project_id" envconfig:"BLNK_WAREHOUSE_BIGQUERY_PROJECT_ID"`
+	Dataset         string `json:"dataset" envconfig:"BLNK_WAREHOUSE_BIGQUERY_DATASET"`
+	CredentialsFile string `json:"credentials_file" envconfig:"BLNK_WAREHOUSE_BIGQUERY_CREDENTIALS_FILE"`
+}
+
+// SnowflakeConfig configures inserts through the Snowflake SQL API, authenticated with key-pair
+// authentication. PrivateKeyFile holds the user's unencrypted PKCS#8 private key in PEM format.
+type SnowflakeConfig struct {
+	Account        string `json:"account" envconfig:"BLNK_WAREHOUSE_SNOWFLAKE_ACCOUNT"`
+	User           string `json:"user" envconfig:"BLNK_WAREHOUSE_SNOWFLAKE_USER"`
+	PrivateKeyFile string `json:"private_key_file" envconfig:"BLNK_WAREHOUSE_SNOWFLAKE_PRIVATE_KEY_FILE"`
+	Database       string `json:"database" envconfig:"BLNK_WAREHOUSE_SNOWFLAKE_DATABASE"`
+	Schema         string `json:"schema" envconfig:"BLNK_WAREHOUSE_SNOWFLAKE_SCHEMA"`
+	Warehouse      string `json:"warehouse" envconfig:"BLNK_WAREHOUSE_SNOWFLAKE_WAREHOUSE"`
+	Role           string `json:"role" envconfig:"BLNK_WAREHOUSE_SNOWFLAKE_ROLE"`
+}
+
+// RedshiftConfig configures loads into Redshift. Batches are staged in S3Bucket under S3Prefix and loaded with
+// COPY using IAMRole, which Redshift assumes to read them. The S3 credentials and endpoint are the ones
+// backups use.
+type RedshiftConfig struct {
+	Dns      string `json:"dns" envconfig:"BLNK_WAREHOUSE_REDSHIFT_DNS"`
+	Schema   string `json:"schema" envconfig:"BLNK_WAREHOUSE_REDSHIFT_SCHEMA"`
+	S3Bucket string `json:"s3_bucket" envconfig:"BLNK_WAREHOUSE_REDSHIFT_S3_BUCKET"`
+	S3Prefix string `json:"s3_prefix" envconfig:"BLNK_WAREHOUSE_REDSHIFT_S3_PREFIX"`
+	IAMRole  string `json:"iam_role" envconfig:"BLNK_WAREHOUSE_REDSHIFT_IAM_ROLE"`
+}
+
+// DependencyPolicy controls how calls to an external dependency are made. Each attempt is given Timeout.
+// Idempotent requests that fail with a network error, a 5xx or a 429 are retried up to Retries times, waiting
+// RetryBackoff before the first retry and twice as long before each further one. After FailureThreshold
+// consecutive failures to a host its circuit opens and calls to it fail immediately for OpenDuration, after
+// which a single call is let through to probe it. A negative Timeout, Retries or FailureThreshold disables the
+// timeout, retries or the circuit breaker; calls without a timeout are bounded by the caller's context.
+type DependencyPolicy struct {
+	Timeout          time.Duration `json:"timeout"`
+	Retries          int           `json:"retries"`
+	RetryBackoff     time.Duration `json:"retry_backoff"`
+	FailureThreshold int           `json:"failure_threshold"`
+	OpenDuration     time.Duration `json:"open_duration"`
+}
+
+// DependencyConfig is the policy of an external dependency, such as "webhooks", "typesense", "s3", "hooks",
+// "account_numbers", "slack", "warehouse", "intercompany", "challenge", "screening" or "eod". Endpoints
+// overrides the policy for individual hosts of the dependency; fields left at zero keep the dependency's value.
+type DependencyConfig struct {
+	DependencyPolicy
+	Endpoints map[string]DependencyPolicy `json:"endpoints"`
+}
+
+// CurrencyConfig registers a currency in the currency registry, or replaces the ISO 4217 definition of one.
+// MinorUnits is the number of digits after the decimal separator, e.g. 8 for BTC, and Symbol replaces the
+// symbol of the reader's locale when amounts are formatted.
+//
+// Currencies need not be money: Type registers loyalty points, gift card units or a commodity such as grams of
+// gold under a code of their own, e.g. "POINTS" or "XAU_G", instead of a borrowed ISO code. SymbolPosition
+// places the symbol "before" or "after" the amount whatever the locale, e.g. "1,250 pts", and ValueCurrency
+// is the fiat currency the asset is valued in, at the rate of the pair in pricing.fx_rates.
+type CurrencyConfig struct {
+	MinorUnits     int    `json:"minor_units"`
+	Symbol         string `json:"symbol"`
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	SymbolPosition string `json:"symbol_position"`
+	ValueCurrency  string `json:"value_currency"`
+}
+
+// currencyTypes are the kinds of asset a currency can be. Unset, ISO 4217 currencies are fiat and others are
+// "other".
+var currencyTypes = map[string]bool{"fiat": true, "crypto": true, "points": true, "voucher": true, "commodity": true, "other": true}
+
+// validate checks a registered currency. A currency valued in another needs a rate between the two.
+func (c CurrencyConfig) validate(code string, fxRates map[string]float64) error {
+	if c.MinorUnits < 0 || c.MinorUnits > 18 {
+		return errors.New("minor_units must be between 0 and 18")
+	}
+	if c.Type != "" && !currencyTypes[c.Type] {
+		return fmt.Errorf("unknown type %q, expected fiat, crypto, points, voucher, commodity or other", c.Type)
+	}
+	switch c.SymbolPosition {
+	case "", "before", "after":
+	default:
+		return fmt.Errorf("unknown symbol_position %q, expected before or after", c.SymbolPosition)
+	}
+	if c.ValueCurrency == "" {
+		return nil
+	}
+	if strings.EqualFold(c.ValueCurrency, code) {
+		return errors.New("value_currency cannot be the currency itself")
+	}
+	for pair := range fxRates {
+		from, to, _ := strings.Cut(strings.ReplaceAll(pair, " ", ""), "/")
+		if (strings.EqualFold(from, code) && strings.EqualFold(to, c.ValueCurrency)) ||
+			(strings.EqualFold(from, c.ValueCurrency) && strings.EqualFold(to, code)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("value_currency %s needs a rate for %s/%s in pricing.fx_rates", c.ValueCurrency, code, c.ValueCurrency)
+}
+
+// CalendarConfig is a business calendar, usually of a country, whose dates are in Timezone. The days of the
+// week in Weekend, such as "saturday", are not business days, and neither are Holidays, keyed by YYYY-MM-DD, or
+// by MM-DD for holidays that fall on the same date every year, with their names. BusinessDays are custom dates
+// that are business days regardless, such as a working Saturday.
+type CalendarConfig struct {
+	Timezone     string            `json:"timezone"`
+	Weekend      []string          `json:"weekend"`
+	Holidays     map[string]string `json:"holidays"`
+	BusinessDays []string          `json:"business_days"`
+}
+
+func (c CalendarConfig) validate() error {
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", c.Timezone)
+	}
+	weekend := make(map[time.Weekday]bool, len(c.Weekend))
+	for _, name := range c.Weekend {
+		known := false
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(day.String(), strings.TrimSpace(name)) {
+				weekend[day], known = true, true
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown weekday %q", name)
+		}
+	}
+	if len(weekend) == 7 {
+		return errors.New("weekend cannot include every day of the week")
+	}
+	for date := range c.Holidays {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			if _, err := time.Parse("01-02", date); err != nil {
+				return fmt.Errorf("holiday %q must be a date in YYYY-MM-DD, or MM-DD for every year", date)
+			}
+		}
+	}
+	for _, date := range c.BusinessDays {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return fmt.Errorf("business day %q must be a date in YYYY-MM-DD", date)
+		}
+	}
+	return nil
+}
+
+// PricingConfig holds the fees, interest rates and exchange rates that quotes from the calculator are made
+// with. InterestRates are annual percentages keyed by currency, and FXRates convert one unit of the first
+// currency of a pair into the second, keyed like "USD/EUR". A pair without a rate is converted at the inverse
+// of the opposite pair's rate.
+type PricingConfig struct {
+	Fees          []FeeRule          `json:"fees"`
+	InterestRates map[string]float64 `json:"interest_rates"`
+	FXRates       map[string]float64 `json:"fx_rates"`
+}
+
+// FeeRule charges Percentage percent of an amount plus Flat, in major units, on amounts in Currency, or in
+// every currency when it is empty. Min and Max bound the fee when set.
+type FeeRule struct {
+	Name       string  `json:"name"`
+	Currency   string  `json:"currency"`
+	Percentage float64 `json:"percentage"`
+	Flat       float64 `json:"flat"`
+	Min        float64 `json:"min"`
+	Max        float64 `json:"max"`
+}
+
+// ChallengeConfig holds back transactions matching any of Rules until the customer passes a strong customer
+// authentication challenge. Each challenge is posted to URL, and the authentication system reports the result
+// to the challenge's callback endpoint, signed with CallbackSecret like webhook deliveries are. Challenges
+// without a result after Timeout expire and their transactions are rejected.
+type ChallengeConfig struct {
+	URL            string          `json:"url" envconfig:"BLNK_CHALLENGE_URL"`
+	CallbackSecret string          `json:"callback_secret" envconfig:"BLNK_CHALLENGE_CALLBACK_SECRET"`
+	Timeout        time.Duration   `json:"timeout" envconfig:"BLNK_CHALLENGE_TIMEOUT"`
+	Rules          []ChallengeRule `json:"rules"`
+}
+
+// ChallengeRule selects the transactions to challenge. A transaction matches when it meets every criterion
+// the rule sets: its currency, an amount of at least MinAmount, one of Sources as its source, or MetaDataKey
+// among its metadata.
+type ChallengeRule struct {
+	Name        string   `json:"name"`
+	Currency    string   `json:"currency"`
+	MinAmount   float64  `json:"min_amount"`
+	Sources     []string `json:"sources"`
+	MetaDataKey string   `json:"meta_data_key"`
+}
+
+// RiskScoringConfig scores identities for compliance review as they are created and updated. The Score of every
+// rule an identity matches is added up, and the total, from 0 to 100, is a "medium" risk from MediumThreshold and
+// a "high" risk from HighThreshold. Identities are not scored while Rules is empty, unless a scorer is plugged in.
+type RiskScoringConfig struct {
+	MediumThreshold float64    `json:"medium_threshold" envconfig:"BLNK_RISK_SCORING_MEDIUM_THRESHOLD"`
+	HighThreshold   float64    `json:"high_threshold" envconfig:"BLNK_RISK_SCORING_HIGH_THRESHOLD"`
+	Rules           []RiskRule `json:"rules"`
+}
+
+// RiskRule adds Score to the risk score of identities whose Field is one of Values, compared without case, or
+// of identities without a value for Field when Values is empty. Field is one of identity_type, category,
+// nationality, country, state, city, gender or verification_status.
+type RiskRule struct {
+	Field  string   `json:"field"`
+	Values []string `json:"values"`
+	Score  float64  `json:"score"`
+}
+
+// riskRuleFields are the identity fields risk rules can match.
+var riskRuleFields = map[string]bool{
+	"identity_type": true, "category": true, "nationality": true, "country": true,
+	"state": true, "city": true, "gender": true, "verification_status": true,
+}
+
+// ScreeningConfig screens identities against sanctions and politically exposed person lists, such as OFAC's, as
+// they are created and updated. Identities are posted to URL, a screening provider or a service in front of one,
+// with Headers, and it answers with the status and matches of the screening. Balances cannot be created for
+// identities whose latest screening flagged them when BlockFlaggedBalances is set. Identities are not screened
+// while URL is empty, unless a screener is plugged in.
+type ScreeningConfig struct {
+	URL                  string            `json:"url" envconfig:"BLNK_SCREENING_URL"`
+	Headers              map[string]string `json:"headers" envconfig:"BLNK_SCREENING_HEADERS"`
+	BlockFlaggedBalances bool              `json:"block_flagged_balances" envconfig:"BLNK_SCREENING_BLOCK_FLAGGED_BALANCES"`
+}
+
+// EnrichmentConfig enriches the descriptions of transactions as they are recorded. While Enabled, descriptions are
+// cleaned up, such as by removing the card processor prefixes of card narratives, and merchants named and
+// transactions categorized by the first of Rules matching their description. When URL is set, transactions are
+// also posted to an enrichment API with Headers, whose answer overrides what the rules found. Transactions keep
+// the description they were sent with, and the enriched narrative is stored next to it in their meta_data.
+type EnrichmentConfig struct {
+	Enabled bool              `json:"enabled" envconfig:"BLNK_ENRICHMENT_ENABLED"`
+	URL     string            `json:"url" envconfig:"BLNK_ENRICHMENT_URL"`
+	Headers map[string]string `json:"headers" envconfig:"BLNK_ENRICHMENT_HEADERS"`
+	Rules   []NarrativeRule   `json:"rules"`
+}
+
+// NarrativeRule names the merchant of and categorizes transactions whose description matches Pattern, a regular
+// expression matched regardless of case. Merchant or Category may be empty, but not both.
+type NarrativeRule struct {
+	Pattern  string `json:"pattern"`
+	Merchant string `json:"merchant"`
+	Category string `json:"category"`
+}
+
+func (c EnrichmentConfig) validate() error {
+	for i, rule := range c.Rules {
+		if rule.Pattern == "" {
+			return fmt.Errorf("rule %d: pattern is required", i)
+		}
+		if _, err := regexp.Compile("(?i)" + rule.Pattern); err != nil {
+			return fmt.Errorf("rule %d: invalid pattern: %w", i, err)
+		}
+		if rule.Merchant == "" && rule.Category == "" {
+			return fmt.Errorf("rule %d: merchant or category is required", i)
+		}
+	}
+	return nil
+}
+
+// ContactVerificationConfig governs the codes identities confirm their email address and phone number with. Codes
+// expire after CodeTTL and are spent after MaxAttempts wrong guesses. Balances cannot be created for identities
+// without a verified email address when RequireVerifiedEmail is set, or a verified phone number when
+// RequireVerifiedPhone is.
+type ContactVerificationConfig struct {
+	CodeTTL              time.Duration `json:"code_ttl" envconfig:"BLNK_CONTACT_VERIFICATION_CODE_TTL"`
+	MaxAttempts          int           `json:"max_attempts" envconfig:"BLNK_CONTACT_VERIFICATION_MAX_ATTEMPTS"`
+	RequireVerifiedEmail bool          `json:"require_verified_email" envconfig:"BLNK_CONTACT_VERIFICATION_REQUIRE_VERIFIED_EMAIL"`
+	RequireVerifiedPhone bool          `json:"require_verified_phone" envconfig:"BLNK_CONTACT_VERIFICATION_REQUIRE_VERIFIED_PHONE"`
+}
+
+// TemplatesConfig holds the Go templates payloads are reshaped with, for consumers that expect a different body
+// than the JSON Blnk sends, such as legacy systems. Webhooks are keyed by endpoint URL, so the configured webhook
+// and replay targets can each receive their own shape. Receipts render the receipts of transactions.
+type TemplatesConfig struct {
+	Webhooks map[string]PayloadTemplates `json:"webhooks"`
+	Receipts PayloadTemplates            `json:"receipts"`
+}
+
+// PayloadTemplates map event types, such as transaction.applied, to the templates their payloads are rendered
+// with, with "*" applying to every other event. Events without a template keep their usual payload. Templates are
+// given the event as .event and its data as .data, with the field names of the JSON payload, and format amounts
+// and dates for Locale and Timezone with the helpers money, amount and date. ContentType is the media type of the
+// rendered payloads.
+type PayloadTemplates struct {
+	ContentType string            `json:"content_type"`
+	Locale      string            `json:"locale"`
+	Timezone    string            `json:"timezone"`
+	Events      map[string]string `json:"events"`
+}
+
+// payloadTemplateFuncs stand in for the helpers templates are rendered with, which live with the renderer, so that
+// templates can be parsed when the configuration is loaded. Keep them in step with the renderer's helpers.
+var payloadTemplateFuncs = template.FuncMap{
+	"money": templateFuncStub, "amount": templateFuncStub, "date": templateFuncStub, "json": templateFuncStub,
+	"upper": templateFuncStub, "lower": templateFuncStub, "default": templateFuncStub,
+}
+
+func templateFuncStub(...interface{}) string { return "" }
+
+func (t PayloadTemplates) validate() error {
+	for event, text := range t.Events {
+		if _, err := template.New(event).Funcs(payloadTemplateFuncs).Parse(text); err != nil {
+			return fmt.Errorf("event %s: %w", event, err)
+		}
+	}
+	return nil
+}
+
+func (c TemplatesConfig) validate() error {
+	for endpoint, templates := range c.Webhooks {
+		if err := templates.validate(); err != nil {
+			return fmt.Errorf("webhook %s: %w", endpoint, err)
+		}
+	}
+	if err := c.Receipts.validate(); err != nil {
+		return fmt.Errorf("receipts: %w", err)
+	}
+	return nil
+}
+
+func (c RiskScoringConfig) validate() error {
+	if c.MediumThreshold <= 0 || c.HighThreshold > 100 || c.MediumThreshold > c.HighThreshold {
+		return errors.New("thresholds must satisfy 0 < medium_threshold <= high_threshold <= 100")
+	}
+	for i, rule := range c.Rules {
+		if !riskRuleFields[rule.Field] {
+			return fmt.Errorf("rule %d: unknown field %q", i, rule.Field)
+		}
+		if rule.Score == 0 {
+			return fmt.Errorf("rule %d: score is required", i)
+		}
+	}
+	return nil
+}
+
+// EgressConfig controls how outbound webhook requests leave the server. A proxy can be used to
+// send all deliveries from a static source IP that partners can allow-list.
+type EgressConfig struct {
+	ProxyURL            string                       `json:"proxy_url" envconfig:"BLNK_WEBHOOK_PROXY_URL"`
+	CACertFile          string                       `json:"ca_cert_file" envconfig:"BLNK_WEBHOOK_CA_CERT_FILE"`
+	ConnectTimeout      int                          `json:"connect_timeout" envconfig:"BLNK_WEBHOOK_CONNECT_TIMEOUT"`
+	TLSHandshakeTimeout int                          `json:"tls_handshake_timeout" envconfig:"BLNK_WEBHOOK_TLS_HANDSHAKE_TIMEOUT"`
+	RequestTimeout      int                          `json:"request_timeout" envconfig:"BLNK_WEBHOOK_REQUEST_TIMEOUT"`
+	Endpoints           map[string]EndpointTLSConfig `json:"endpoints"`
+}
+
+// EndpointTLSConfig overrides TLS settings for a single webhook host, e.g. to present a client
+// certificate or trust a partner's private CA.
+type EndpointTLSConfig struct {
+	CACertFile         string `json:"ca_cert_file"`
+	ClientCertFile     string `json:"client_cert_file"`
+	ClientKeyFile      string `json:"client_key_file"`
+	ServerName         string `json:"server_name"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+}
+
+// SMTPConfig configures outbound email, used for delivering account statements.
+type SMTPConfig struct {
+	Host     string `json:"host" envconfig:"BLNK_SMTP_HOST"`
+	Port     int    `json:"port" envconfig:"BLNK_SMTP_PORT"`
+	Username string `json:"username" envconfig:"BLNK_SMTP_USERNAME"`
+	Password string `json:"password" envconfig:"BLNK_SMTP_PASSWORD"`
+	From     string `json:"from" envconfig:"BLNK_SMTP_FROM"`
 }
 
 type Notification struct {
 	Slack   SlackWebhook  `json:"slack"`
 	Webhook WebhookConfig `json:"webhook"`
+	SMTP    SMTPConfig    `json:"smtp"`
 }
 
+// TransactionConfig controls how transactions are processed. A transaction captured offline can carry the
+// time the client recorded it, which must be no more than MaxTimeSkewPast before and MaxTimeSkewFuture after
+// the server's time. HistoryOrder is the time transaction history is ordered by: "created_at", the server's
+// time, or "transaction_time", the client's time where there is one.
 type TransactionConfig struct {
 	BatchSize          int           `json:"batch_size" envconfig:"BLNK_TRANSACTION_BATCH_SIZE"`
 	MaxQueueSize       int           `json:"max_queue_size" envconfig:"BLNK_TRANSACTION_MAX_QUEUE_SIZE"`
@@ -140,13 +1042,33 @@ type TransactionConfig struct {
 	LockDuration       time.Duration `json:"lock_duration" envconfig:"BLNK_TRANSACTION_LOCK_DURATION"`
 	IndexQueuePrefix   string        `json:"index_queue_prefix" envconfig:"BLNK_TRANSACTION_INDEX_QUEUE_PREFIX"`
 	EnableQueuedChecks bool          `json:"enable_queued_checks" envconfig:"BLNK_TRANSACTION_ENABLE_QUEUED_CHECKS"`
+	MaxTimeSkewPast    time.Duration `json:"max_time_skew_past" envconfig:"BLNK_TRANSACTION_MAX_TIME_SKEW_PAST"`
+	MaxTimeSkewFuture  time.Duration `json:"max_time_skew_future" envconfig:"BLNK_TRANSACTION_MAX_TIME_SKEW_FUTURE"`
+	HistoryOrder       string        `json:"history_order" envconfig:"BLNK_TRANSACTION_HISTORY_ORDER"`
 }
 
+// ReconciliationConfig configures reconciliation runs. Matches scoring at least AutoConfirmThreshold are
+// confirmed and those scoring at least ReviewThreshold are queued for review. The thresholds are pointers so
+// that an explicit 0 is kept rather than replaced by the default.
 type ReconciliationConfig struct {
-	DefaultStrategy  string        `json:"default_strategy" envconfig:"BLNK_RECONCILIATION_DEFAULT_STRATEGY"`
-	ProgressInterval int           `json:"progress_interval" envconfig:"BLNK_RECONCILIATION_PROGRESS_INTERVAL"`
-	MaxRetries       int           `json:"max_retries" envconfig:"BLNK_RECONCILIATION_MAX_RETRIES"`
-	RetryDelay       time.Duration `json:"retry_delay" envconfig:"BLNK_RECONCILIATION_RETRY_DELAY"`
+	DefaultStrategy      string        `json:"default_strategy" envconfig:"BLNK_RECONCILIATION_DEFAULT_STRATEGY"`
+	ProgressInterval     int           `json:"progress_interval" envconfig:"BLNK_RECONCILIATION_PROGRESS_INTERVAL"`
+	MaxRetries           int           `json:"max_retries" envconfig:"BLNK_RECONCILIATION_MAX_RETRIES"`
+	RetryDelay           time.Duration `json:"retry_delay" envconfig:"BLNK_RECONCILIATION_RETRY_DELAY"`
+	AutoConfirmThreshold *float64      `json:"auto_confirm_threshold" envconfig:"BLNK_RECONCILIATION_AUTO_CONFIRM_THRESHOLD"`
+	ReviewThreshold      *float64      `json:"review_threshold" envconfig:"BLNK_RECONCILIATION_REVIEW_THRESHOLD"`
+}
+
+// Thresholds returns the auto-confirm and review thresholds, with the defaults of those left unset.
+func (r ReconciliationConfig) Thresholds() (autoConfirm, review float64) {
+	autoConfirm, review = defaultAutoConfirmThreshold, defaultReviewThreshold
+	if r.AutoConfirmThreshold != nil {
+		autoConfirm = *r.AutoConfirmThreshold
+	}
+	if r.ReviewThreshold != nil {
+		review = *r.ReviewThreshold
+	}
+	return autoConfirm, review
 }
 
 type QueueConfig struct {
@@ -158,11 +1080,21 @@ type QueueConfig struct {
 	InsufficientFundRetries bool   `json:"insufficient_fund_retries" envconfig:"BLNK_QUEUE_INSUFFICIENT_FUND_RETRIES"`
 	MaxRetryAttempts        int    `json:"max_retry_attempts" envconfig:"BLNK_QUEUE_MAX_RETRY_ATTEMPTS"`
 	MonitoringPort          string `json:"monitoring_port" envconfig:"BLNK_QUEUE_MONITORING_PORT"`
+
+	// MinConcurrency and MaxConcurrency bound the number of tasks a worker processes at once. Between them the
+	// worker scales its concurrency every ScaleInterval by the depth of the transaction queues and how long
+	// transactions take to apply, backing off when applying takes longer than TargetApplyLatency. Equal bounds
+	// fix the concurrency.
+	MinConcurrency     int           `json:"min_concurrency" envconfig:"BLNK_QUEUE_MIN_CONCURRENCY"`
+	MaxConcurrency     int           `json:"max_concurrency" envconfig:"BLNK_QUEUE_MAX_CONCURRENCY"`
+	ScaleInterval      time.Duration `json:"scale_interval" envconfig:"BLNK_QUEUE_SCALE_INTERVAL"`
+	TargetApplyLatency time.Duration `json:"target_apply_latency" envconfig:"BLNK_QUEUE_TARGET_APPLY_LATENCY"`
 }
 
 type Configuration struct {
 	ProjectName             string                        `json:"project_name" envconfig:"BLNK_PROJECT_NAME"`
 	BackupDir               string                        `json:"backup_dir" envconfig:"BLNK_BACKUP_DIR"`
+	ExportDir               string                        `json:"export_dir" envconfig:"BLNK_EXPORT_DIR"`
 	AwsAccessKeyId          string                        `json:"aws_access_key_id" envconfig:"BLNK_AWS_ACCESS_KEY_ID"`
 	S3Endpoint              string                        `json:"s3_endpoint" envconfig:"BLNK_S3_ENDPOINT"`
 	AwsSecretAccessKey      string                        `json:"aws_secret_access_key" envconfig:"BLNK_AWS_SECRET_ACCESS_KEY"`
@@ -182,6 +1114,62 @@ type Configuration struct {
 	Transaction             TransactionConfig             `json:"transaction"`
 	Reconciliation          ReconciliationConfig          `json:"reconciliation"`
 	Queue                   QueueConfig                   `json:"queue"`
+	SecretRotation          SecretRotationConfig          `json:"secret_rotation"`
+	ServiceAccounts         ServiceAccountConfig          `json:"service_accounts"`
+	NetworkPolicy           NetworkPolicyConfig           `json:"network_policy"`
+	EncryptedMetadata       EncryptedMetadataConfig       `json:"encrypted_metadata"`
+	CardAuthorization       CardAuthorizationConfig       `json:"card_authorization"`
+	Authorization           AuthorizationConfig           `json:"authorization"`
+	ScheduledRetry          ScheduledRetryConfig          `json:"scheduled_retry"`
+	ScheduledCalendar       ScheduledCalendarConfig       `json:"scheduled_calendar"`
+	APIVersions             APIVersionConfig              `json:"api_versions"`
+	WebhookCircuit          WebhookCircuitConfig          `json:"webhook_circuit"`
+	Rounding                RoundingConfig                `json:"rounding"`
+	Dormancy                DormancyConfig                `json:"dormancy"`
+	StatementIngestion      StatementIngestionConfig      `json:"statement_ingestion"`
+	RequestLog              RequestLogConfig              `json:"request_log"`
+	Warehouse               WarehouseConfig               `json:"warehouse"`
+	ComplianceExport        ComplianceExportConfig        `json:"compliance_export"`
+	EOD                     EODConfig                     `json:"eod"`
+	Dependencies            map[string]DependencyConfig   `json:"dependencies"`
+	Currencies              map[string]CurrencyConfig     `json:"currencies"`
+	Calendars               map[string]CalendarConfig     `json:"calendars"`
+	Pricing                 PricingConfig                 `json:"pricing"`
+	Challenge               ChallengeConfig               `json:"challenge"`
+	RiskScoring             RiskScoringConfig             `json:"risk_scoring"`
+	Templates               TemplatesConfig               `json:"templates"`
+	Screening               ScreeningConfig               `json:"screening"`
+	ContactVerification     ContactVerificationConfig     `json:"contact_verification"`
+	Enrichment              EnrichmentConfig              `json:"enrichment"`
+	Plugins                 []PluginConfig                `json:"plugins"`
+	FeatureFlags            map[string]bool               `json:"feature_flags" envconfig:"BLNK_FEATURE_FLAGS"`
+
+	// IdentitySchemas are JSON schemas of the custom fields identities keep in their meta_data, keyed by identity
+	// type. The meta_data of identities of a type with a schema is validated against it as they are created and
+	// updated, so institutions can give their own attributes structure without schema migrations.
+	IdentitySchemas map[string]json.RawMessage `json:"identity_schemas"`
+
+	// IdentityTypes define identity types besides individual and organization, such as trust or joint-account, and
+	// can redefine those two to require fields. Once any is defined, identities must have a built-in or defined
+	// type and the fields it requires.
+	IdentityTypes map[string]IdentityTypeConfig `json:"identity_types"`
+}
+
+// IdentityTypeConfig defines an identity type. Kind says whether identities of the type are individuals or
+// organizations, and defaults to individual. RequiredFields name the fields identities of the type must have, as
+// in the API, such as organization_name or country.
+type IdentityTypeConfig struct {
+	Kind           string   `json:"kind"`
+	RequiredFields []string `json:"required_fields"`
+}
+
+// PluginConfig declares an external transaction processor reached over gRPC.
+type PluginConfig struct {
+	Name          string   `json:"name"`
+	Address       string   `json:"address"`
+	Stages        []string `json:"stages"`
+	TimeoutMs     int      `json:"timeout_ms"`
+	FailurePolicy string   `json:"failure_policy"`
 }
 
 func loadConfigFromFile(file string) error {
@@ -244,6 +1232,199 @@ func (cnf *Configuration) validateAndAddDefaults() error {
 		log.Println("Warning: Tokenization secret should be 32 bytes for AES-256 encryption")
 	}
 
+	if err := cnf.DataSource.validatePool(); err != nil {
+		return fmt.Errorf("data_source: %w", err)
+	}
+
+	if order := cnf.Transaction.HistoryOrder; order != "created_at" && order != "transaction_time" {
+		return fmt.Errorf("unknown transaction history order %q, expected created_at or transaction_time", order)
+	}
+
+	switch cnf.Warehouse.Provider {
+	case "", "bigquery", "snowflake", "redshift":
+	default:
+		return fmt.Errorf("unknown warehouse provider %q, expected bigquery, snowflake or redshift", cnf.Warehouse.Provider)
+	}
+
+	if cnf.ComplianceExport.Bucket != "" {
+		if mode := cnf.ComplianceExport.RetentionMode; mode != "COMPLIANCE" && mode != "GOVERNANCE" {
+			return fmt.Errorf("unknown compliance export retention mode %q, expected COMPLIANCE or GOVERNANCE", mode)
+		}
+		if cnf.ComplianceExport.SigningKey == "" {
+			return errors.New("compliance export requires a signing key")
+		}
+	}
+
+	for code, currency := range cnf.Currencies {
+		if strings.TrimSpace(code) == "" {
+			return errors.New("currencies cannot have an empty code")
+		}
+		if err := currency.validate(code, cnf.Pricing.FXRates); err != nil {
+			return fmt.Errorf("currency %s: %w", code, err)
+		}
+	}
+
+	if err := cnf.Pricing.validate(); err != nil {
+		return fmt.Errorf("pricing: %w", err)
+	}
+
+	for name, dependency := range cnf.Dependencies {
+		if err := dependency.validate(); err != nil {
+			return fmt.Errorf("dependency %s: %w", name, err)
+		}
+		for host, endpoint := range dependency.Endpoints {
+			if err := endpoint.validate(); err != nil {
+				return fmt.Errorf("dependency %s endpoint %s: %w", name, host, err)
+			}
+		}
+	}
+
+	if cnf.Queue.MinConcurrency < 1 {
+		return errors.New("queue: min_concurrency must be at least 1")
+	}
+	if cnf.Queue.MaxConcurrency < cnf.Queue.MinConcurrency {
+		return errors.New("queue: max_concurrency cannot be less than min_concurrency")
+	}
+	if cnf.Queue.ScaleInterval < 0 || cnf.Queue.TargetApplyLatency < 0 {
+		return errors.New("queue: scale_interval and target_apply_latency cannot be negative")
+	}
+
+	if cnf.Authorization.LatencyBudget < 0 {
+		return errors.New("authorization: latency_budget cannot be negative")
+	}
+	authorizationRules := make(map[string]bool, len(cnf.Authorization.Rules))
+	for i, rule := range cnf.Authorization.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("authorization rule %d: name is required", i)
+		}
+		if authorizationRules[rule.Name] {
+			return fmt.Errorf("authorization rule %s: name is used by another rule", rule.Name)
+		}
+		authorizationRules[rule.Name] = true
+		if rule.MaxAmount <= 0 && rule.MaxCount <= 0 && rule.MaxTotal <= 0 {
+			return fmt.Errorf("authorization rule %s: at least one of max_amount, max_count or max_total is required", rule.Name)
+		}
+		if (rule.MaxCount > 0 || rule.MaxTotal > 0) && rule.Window <= 0 {
+			return fmt.Errorf("authorization rule %s: window is required with max_count or max_total", rule.Name)
+		}
+		switch rule.Enforcement {
+		case "":
+			cnf.Authorization.Rules[i].Enforcement = EnforcementHard
+		case EnforcementHard, EnforcementSoft:
+		default:
+			return fmt.Errorf("authorization rule %s: unknown enforcement %q, expected hard or soft", rule.Name, rule.Enforcement)
+		}
+	}
+
+	for name, calendar := range cnf.Calendars {
+		if err := calendar.validate(); err != nil {
+			return fmt.Errorf("calendar %s: %w", name, err)
+		}
+	}
+	if rule := cnf.ScheduledCalendar; rule.Calendar != "" {
+		if _, ok := cnf.Calendars[rule.Calendar]; !ok {
+			return fmt.Errorf("scheduled_calendar: unknown calendar %q", rule.Calendar)
+		}
+		switch rule.Roll {
+		case "none", "following", "modified_following", "preceding":
+		default:
+			return fmt.Errorf("scheduled_calendar: unknown roll %q, expected following, modified_following, preceding or none", rule.Roll)
+		}
+	}
+
+	if err := cnf.NetworkPolicy.validate(); err != nil {
+		return fmt.Errorf("network_policy: %w", err)
+	}
+	if err := cnf.EOD.validate(); err != nil {
+		return fmt.Errorf("eod: %w", err)
+	}
+	if _, ok := cnf.Calendars[cnf.EOD.Calendar]; cnf.EOD.Calendar != "" && !ok {
+		return fmt.Errorf("eod: unknown calendar %q", cnf.EOD.Calendar)
+	}
+
+	if len(cnf.Challenge.Rules) > 0 {
+		if cnf.Challenge.CallbackSecret == "" {
+			return errors.New("challenge: callback_secret is required to verify challenge callbacks")
+		}
+		if cnf.Challenge.Timeout < 0 {
+			return errors.New("challenge: timeout cannot be negative")
+		}
+		if cnf.Challenge.Timeout == 0 {
+			cnf.Challenge.Timeout = defaultChallengeTimeout
+		}
+		for i, rule := range cnf.Challenge.Rules {
+			if rule.Name == "" {
+				return fmt.Errorf("challenge rule %d: name is required", i)
+			}
+			if rule.Currency == "" && rule.MinAmount <= 0 && len(rule.Sources) == 0 && rule.MetaDataKey == "" {
+				return fmt.Errorf("challenge rule %s: at least one of currency, min_amount, sources or meta_data_key is required", rule.Name)
+			}
+		}
+	}
+
+	if err := cnf.RiskScoring.validate(); err != nil {
+		return fmt.Errorf("risk_scoring: %w", err)
+	}
+
+	if cnf.ContactVerification.CodeTTL < 0 || cnf.ContactVerification.MaxAttempts < 0 {
+		return errors.New("contact_verification: code_ttl and max_attempts cannot be negative")
+	}
+	if cnf.ContactVerification.CodeTTL == 0 {
+		cnf.ContactVerification.CodeTTL = defaultContactVerification.CodeTTL
+	}
+	if cnf.ContactVerification.MaxAttempts == 0 {
+		cnf.ContactVerification.MaxAttempts = defaultContactVerification.MaxAttempts
+	}
+
+	if err := cnf.Templates.validate(); err != nil {
+		return fmt.Errorf("templates: %w", err)
+	}
+
+	if err := cnf.Enrichment.validate(); err != nil {
+		return fmt.Errorf("enrichment: %w", err)
+	}
+
+	for name, identityType := range cnf.IdentityTypes {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("identity_types cannot have an empty name")
+		}
+		switch identityType.Kind {
+		case "", "individual", "organization":
+		default:
+			return fmt.Errorf("identity type %s: unknown kind %q, expected individual or organization", name, identityType.Kind)
+		}
+	}
+
+	return nil
+}
+
+func (p DependencyPolicy) validate() error {
+	if p.Timeout < 0 || p.RetryBackoff < 0 || p.OpenDuration < 0 {
+		return errors.New("timeout, retry_backoff and open_duration cannot be negative")
+	}
+	return nil
+}
+
+func (p PricingConfig) validate() error {
+	for _, fee := range p.Fees {
+		if fee.Percentage < 0 || fee.Percentage > 100 {
+			return fmt.Errorf("fee %s: percentage must be between 0 and 100", fee.Name)
+		}
+		if fee.Flat < 0 || fee.Min < 0 || fee.Max < 0 {
+			return fmt.Errorf("fee %s: flat, min and max cannot be negative", fee.Name)
+		}
+		if fee.Max > 0 && fee.Min > fee.Max {
+			return fmt.Errorf("fee %s: min cannot exceed max", fee.Name)
+		}
+	}
+	for pair, rate := range p.FXRates {
+		if from, to, ok := strings.Cut(pair, "/"); !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+			return fmt.Errorf("fx rate %q: expected a currency pair such as USD/EUR", pair)
+		}
+		if rate <= 0 {
+			return fmt.Errorf("fx rate %s: rate must be positive", pair)
+		}
+	}
 	return nil
 }
 
@@ -276,6 +1457,10 @@ func (cnf *Configuration) setDefaultValues() {
 		cnf.TypeSenseKey = DEFAULT_TYPESENSE_KEY
 	}
 
+	if cnf.ExportDir == "" {
+		cnf.ExportDir = "exports"
+	}
+
 	// Tokenization defaults
 	if cnf.TokenizationSecret == "" {
 		cnf.TokenizationSecret = "blnk-default-tokenization-key!!!!"
@@ -287,6 +1472,58 @@ func (cnf *Configuration) setDefaultValues() {
 	cnf.setTransactionDefaults()
 	cnf.setReconciliationDefaults()
 	cnf.setQueueDefaults()
+	cnf.setEgressDefaults()
+	if cnf.Notification.Webhook.DeliveryRetention == 0 {
+		cnf.Notification.Webhook.DeliveryRetention = defaultWebhookDeliveryRetention
+	}
+	if cnf.Notification.SMTP.Host != "" && cnf.Notification.SMTP.Port == 0 {
+		cnf.Notification.SMTP.Port = 587
+	}
+	if cnf.SecretRotation.OverlapPeriod == 0 {
+		cnf.SecretRotation.OverlapPeriod = defaultSecretRotation.OverlapPeriod
+	}
+	if cnf.EncryptedMetadata.Mask == "" {
+		cnf.EncryptedMetadata.Mask = defaultEncryptedMetadataMask
+	}
+	if cnf.ServiceAccounts.TokenTTL == 0 {
+		cnf.ServiceAccounts.TokenTTL = defaultServiceAccounts.TokenTTL
+	}
+	if cnf.CardAuthorization.DefaultExpiry == 0 {
+		cnf.CardAuthorization.DefaultExpiry = defaultCardAuthorization.DefaultExpiry
+	}
+	if cnf.Authorization.LatencyBudget == 0 {
+		cnf.Authorization.LatencyBudget = defaultAuthorizationLatencyBudget
+	}
+	cnf.setWebhookCircuitDefaults()
+	if cnf.Rounding.DifferenceBalance == "" {
+		cnf.Rounding.DifferenceBalance = defaultRounding.DifferenceBalance
+	}
+	if cnf.Dormancy.HoldingBalance == "" {
+		cnf.Dormancy.HoldingBalance = defaultDormancy.HoldingBalance
+	}
+	if cnf.Dormancy.ScanInterval == 0 {
+		cnf.Dormancy.ScanInterval = defaultDormancy.ScanInterval
+	}
+	cnf.setStatementIngestionDefaults()
+	if cnf.RequestLog.Retention == 0 {
+		cnf.RequestLog.Retention = defaultRequestLog.Retention
+	}
+	if cnf.RequestLog.MaxEntries == 0 {
+		cnf.RequestLog.MaxEntries = defaultRequestLog.MaxEntries
+	}
+	if cnf.RequestLog.MaxBodyBytes == 0 {
+		cnf.RequestLog.MaxBodyBytes = defaultRequestLog.MaxBodyBytes
+	}
+	cnf.setWarehouseDefaults()
+	cnf.setComplianceExportDefaults()
+	cnf.setEODDefaults()
+	cnf.setCalendarDefaults()
+	if cnf.RiskScoring.MediumThreshold == 0 {
+		cnf.RiskScoring.MediumThreshold = defaultRiskScoring.MediumThreshold
+	}
+	if cnf.RiskScoring.HighThreshold == 0 {
+		cnf.RiskScoring.HighThreshold = defaultRiskScoring.HighThreshold
+	}
 
 	// For a financial application, telemetry is opt-in for privacy reasons, don't enable by default if it's not specified
 	if cnf.EnableTelemetry {
@@ -320,6 +1557,97 @@ func (cnf *Configuration) setTransactionDefaults() {
 	if cnf.Transaction.IndexQueuePrefix == "" {
 		cnf.Transaction.IndexQueuePrefix = defaultTransaction.IndexQueuePrefix
 	}
+	if cnf.Transaction.MaxTimeSkewPast == 0 {
+		cnf.Transaction.MaxTimeSkewPast = defaultTransaction.MaxTimeSkewPast
+	}
+	if cnf.Transaction.MaxTimeSkewFuture == 0 {
+		cnf.Transaction.MaxTimeSkewFuture = defaultTransaction.MaxTimeSkewFuture
+	}
+	if cnf.Transaction.HistoryOrder == "" {
+		cnf.Transaction.HistoryOrder = defaultTransaction.HistoryOrder
+	}
+}
+
+func (cnf *Configuration) setStatementIngestionDefaults() {
+	ingestion := &cnf.StatementIngestion
+	if ingestion.PollInterval == 0 {
+		ingestion.PollInterval = defaultStatementIngestion.PollInterval
+	}
+	if ingestion.IMAP.Port == 0 {
+		ingestion.IMAP.Port = defaultStatementIngestion.IMAP.Port
+	}
+	if ingestion.IMAP.Mailbox == "" {
+		ingestion.IMAP.Mailbox = defaultStatementIngestion.IMAP.Mailbox
+	}
+	if ingestion.SFTP.Port == 0 {
+		ingestion.SFTP.Port = defaultStatementIngestion.SFTP.Port
+	}
+}
+
+func (cnf *Configuration) setWarehouseDefaults() {
+	warehouse := &cnf.Warehouse
+	if warehouse.SyncInterval == 0 {
+		warehouse.SyncInterval = defaultWarehouse.SyncInterval
+	}
+	if warehouse.BatchSize == 0 {
+		warehouse.BatchSize = defaultWarehouse.BatchSize
+	}
+	if warehouse.SettleDelay == 0 {
+		warehouse.SettleDelay = defaultWarehouse.SettleDelay
+	}
+}
+
+func (cnf *Configuration) setComplianceExportDefaults() {
+	export := &cnf.ComplianceExport
+	if export.Prefix == "" {
+		export.Prefix = defaultComplianceExport.Prefix
+	}
+	if export.Interval == 0 {
+		export.Interval = defaultComplianceExport.Interval
+	}
+	if export.BatchSize == 0 {
+		export.BatchSize = defaultComplianceExport.BatchSize
+	}
+	if export.RetentionMode == "" {
+		export.RetentionMode = defaultComplianceExport.RetentionMode
+	}
+	if export.RetentionDays == 0 {
+		export.RetentionDays = defaultComplianceExport.RetentionDays
+	}
+}
+
+func (cnf *Configuration) setEODDefaults() {
+	eod := &cnf.EOD
+	if eod.Timezone == "" {
+		eod.Timezone = defaultEOD.Timezone
+	}
+	if eod.CutoffTime == "" {
+		eod.CutoffTime = defaultEOD.CutoffTime
+	}
+	for i := range eod.Steps {
+		step := &eod.Steps[i]
+		if step.RetryBackoff == 0 {
+			step.RetryBackoff = defaultEODStep.RetryBackoff
+		}
+		if step.Timeout == 0 {
+			step.Timeout = defaultEODStep.Timeout
+		}
+	}
+}
+
+func (cnf *Configuration) setCalendarDefaults() {
+	for name, calendar := range cnf.Calendars {
+		if calendar.Timezone == "" {
+			calendar.Timezone = defaultCalendar.Timezone
+		}
+		if calendar.Weekend == nil {
+			calendar.Weekend = defaultCalendar.Weekend
+		}
+		cnf.Calendars[name] = calendar
+	}
+	if cnf.ScheduledCalendar.Calendar != "" && cnf.ScheduledCalendar.Roll == "" {
+		cnf.ScheduledCalendar.Roll = defaultScheduledCalendarRoll
+	}
 }
 
 func (cnf *Configuration) setReconciliationDefaults() {
@@ -335,6 +1663,8 @@ func (cnf *Configuration) setReconciliationDefaults() {
 	if cnf.Reconciliation.RetryDelay == 0 {
 		cnf.Reconciliation.RetryDelay = defaultReconciliation.RetryDelay
 	}
+	autoConfirm, review := cnf.Reconciliation.Thresholds()
+	cnf.Reconciliation.AutoConfirmThreshold, cnf.Reconciliation.ReviewThreshold = &autoConfirm, &review
 }
 
 func (cnf *Configuration) setQueueDefaults() {
@@ -356,14 +1686,61 @@ func (cnf *Configuration) setQueueDefaults() {
 	if cnf.Queue.MonitoringPort == "" {
 		cnf.Queue.MonitoringPort = defaultQueue.MonitoringPort
 	}
+	if cnf.Queue.MinConcurrency == 0 {
+		cnf.Queue.MinConcurrency = defaultQueue.MinConcurrency
+	}
+	if cnf.Queue.MaxConcurrency == 0 {
+		cnf.Queue.MaxConcurrency = cnf.Queue.MinConcurrency
+	}
+	if cnf.Queue.ScaleInterval == 0 {
+		cnf.Queue.ScaleInterval = defaultQueue.ScaleInterval
+	}
+	if cnf.Queue.TargetApplyLatency == 0 {
+		cnf.Queue.TargetApplyLatency = defaultQueue.TargetApplyLatency
+	}
+}
+
+func (cnf *Configuration) setEgressDefaults() {
+	egress := &cnf.Notification.Webhook.Egress
+	if egress.ConnectTimeout == 0 {
+		egress.ConnectTimeout = defaultEgress.ConnectTimeout
+	}
+	if egress.TLSHandshakeTimeout == 0 {
+		egress.TLSHandshakeTimeout = defaultEgress.TLSHandshakeTimeout
+	}
+	if egress.RequestTimeout == 0 {
+		egress.RequestTimeout = defaultEgress.RequestTimeout
+	}
+}
+
+func (cnf *Configuration) setWebhookCircuitDefaults() {
+	circuit := &cnf.WebhookCircuit
+	if circuit.FailureThreshold == 0 {
+		circuit.FailureThreshold = defaultWebhookCircuit.FailureThreshold
+	}
+	if circuit.ProbeInterval == 0 {
+		circuit.ProbeInterval = defaultWebhookCircuit.ProbeInterval
+	}
+	if circuit.MaxProbeInterval == 0 {
+		circuit.MaxProbeInterval = defaultWebhookCircuit.MaxProbeInterval
+	}
+	if circuit.MaxProbeInterval < circuit.ProbeInterval {
+		circuit.MaxProbeInterval = circuit.ProbeInterval
+	}
 }
 
 func (cnf *Configuration) setDatabaseDefaults() {
+	if cnf.DataSource.Driver == "" {
+		cnf.DataSource.Driver = defaultDatabase.Driver
+	}
 	if cnf.DataSource.MaxOpenConns == 0 {
 		cnf.DataSource.MaxOpenConns = defaultDatabase.MaxOpenConns
 	}
 	if cnf.DataSource.MaxIdleConns == 0 {
 		cnf.DataSource.MaxIdleConns = defaultDatabase.MaxIdleConns
+		if cnf.DataSource.MaxOpenConns > 0 && cnf.DataSource.MaxIdleConns > cnf.DataSource.MaxOpenConns {
+			cnf.DataSource.MaxIdleConns = cnf.DataSource.MaxOpenConns
+		}
 	}
 	if cnf.DataSource.ConnMaxLifetime == 0 {
 		cnf.DataSource.ConnMaxLifetime = defaultDatabase.ConnMaxLifetime
@@ -371,12 +1748,39 @@ func (cnf *Configuration) setDatabaseDefaults() {
 	if cnf.DataSource.ConnMaxIdleTime == 0 {
 		cnf.DataSource.ConnMaxIdleTime = defaultDatabase.ConnMaxIdleTime
 	}
+	if cnf.DataSource.ReplicaMaxLag == 0 {
+		cnf.DataSource.ReplicaMaxLag = defaultDatabase.ReplicaMaxLag
+	}
+	if cnf.DataSource.ReplicaCheckInterval == 0 {
+		cnf.DataSource.ReplicaCheckInterval = defaultDatabase.ReplicaCheckInterval
+	}
+}
+
+// validatePool checks the driver and the connection pool settings against each other.
+func (ds DataSourceConfig) validatePool() error {
+	if ds.Driver != DriverPostgres && ds.Driver != DriverPgx {
+		return fmt.Errorf("unknown driver %q, expected %s or %s", ds.Driver, DriverPostgres, DriverPgx)
+	}
+	if ds.MaxOpenConns > 0 && ds.MaxIdleConns > ds.MaxOpenConns {
+		return fmt.Errorf("max_idle_conns (%d) cannot exceed max_open_conns (%d)", ds.MaxIdleConns, ds.MaxOpenConns)
+	}
+	if ds.ConnMaxLifetime < 0 || ds.ConnMaxIdleTime < 0 {
+		return errors.New("conn_max_lifetime and conn_max_idle_time cannot be negative")
+	}
+	return nil
 }
 
 func (cnf *Configuration) trimWhitespace() {
 	cnf.ProjectName = strings.TrimSpace(cnf.ProjectName)
 	cnf.Server.Port = strings.TrimSpace(cnf.Server.Port)
 	cnf.DataSource.Dns = strings.TrimSpace(cnf.DataSource.Dns)
+	cnf.DataSource.Driver = strings.ToLower(strings.TrimSpace(cnf.DataSource.Driver))
+	for i, proxy := range cnf.NetworkPolicy.TrustedProxies {
+		cnf.NetworkPolicy.TrustedProxies[i] = strings.TrimSpace(proxy)
+	}
+	for i, dns := range cnf.DataSource.Replicas {
+		cnf.DataSource.Replicas[i] = strings.TrimSpace(dns)
+	}
 	cnf.Redis.Dns = strings.TrimSpace(cnf.Redis.Dns)
 }
 
@@ -17,8 +17,11 @@ package config
 
 import (
 	"encoding/json"
+	"net"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidateAndAddDefaults(t *testing.T) {
@@ -168,3 +171,384 @@ func TestInitConfig(t *testing.T) {
 		t.Errorf("Expected DataSource.Dns to be 'init-config-dns', got '%s'", loadedConfig.DataSource.Dns)
 	}
 }
+
+func TestEODOrderedSteps(t *testing.T) {
+	eod := EODConfig{Steps: []EODStepConfig{
+		{Name: "reports", DependsOn: []string{"snapshots"}},
+		{Name: "cutoff"},
+		{Name: "snapshots", DependsOn: []string{"cutoff"}},
+		{Name: "accruals", DependsOn: []string{"cutoff"}},
+	}}
+	steps, err := eod.OrderedSteps()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var names []string
+	for _, step := range steps {
+		names = append(names, step.Name)
+	}
+	if got, want := strings.Join(names, ","), "cutoff,snapshots,reports,accruals"; got != want {
+		t.Errorf("Expected order %s, got %s", want, got)
+	}
+
+	eod.Steps[1].DependsOn = []string{"reports"}
+	if _, err := eod.OrderedSteps(); err == nil || err.Error() != "step dependencies form a cycle" {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+
+	eod.Steps[1].DependsOn = []string{"settlements"}
+	if _, err := eod.OrderedSteps(); err == nil || err.Error() != "step cutoff depends on unknown step settlements" {
+		t.Errorf("Expected unknown step error, got %v", err)
+	}
+}
+
+func TestValidateEOD(t *testing.T) {
+	cnf := Configuration{
+		DataSource: DataSourceConfig{Dns: "some-dns"},
+		Redis:      RedisConfig{Dns: "localhost:6379"},
+		EOD: EODConfig{Steps: []EODStepConfig{
+			{Name: "cutoff", Kind: "cutoff"},
+			{Name: "accruals", Kind: "http", DependsOn: []string{"cutoff"}},
+		}},
+	}
+	err := cnf.validateAndAddDefaults()
+	if err == nil || err.Error() != "eod: step accruals: url is required for http steps" {
+		t.Errorf("Expected url required error, got %v", err)
+	}
+
+	cnf.EOD.Steps[1].URL = "https://accruals.internal/run"
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cnf.EOD.Timezone != "UTC" || cnf.EOD.CutoffTime != "23:59" {
+		t.Errorf("Expected default timezone and cutoff, got %s %s", cnf.EOD.Timezone, cnf.EOD.CutoffTime)
+	}
+	if cnf.EOD.Steps[0].Timeout != defaultEODStep.Timeout || cnf.EOD.Steps[0].RetryBackoff != defaultEODStep.RetryBackoff {
+		t.Errorf("Expected step defaults, got %+v", cnf.EOD.Steps[0])
+	}
+
+	cnf.EOD.CutoffTime = "25:00"
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected invalid cutoff time error")
+	}
+}
+
+func TestValidateCalendars(t *testing.T) {
+	cnf := Configuration{
+		DataSource:        DataSourceConfig{Dns: "some-dns"},
+		Redis:             RedisConfig{Dns: "localhost:6379"},
+		Calendars:         map[string]CalendarConfig{"US": {Holidays: map[string]string{"12-25": "Christmas Day"}}},
+		ScheduledCalendar: ScheduledCalendarConfig{Calendar: "US"},
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if us := cnf.Calendars["US"]; us.Timezone != "UTC" || strings.Join(us.Weekend, ",") != "saturday,sunday" {
+		t.Errorf("Expected calendar defaults, got %+v", us)
+	}
+	if cnf.ScheduledCalendar.Roll != "following" {
+		t.Errorf("Expected default roll following, got %s", cnf.ScheduledCalendar.Roll)
+	}
+
+	cnf.ScheduledCalendar.Calendar = "UK"
+	if err := cnf.validateAndAddDefaults(); err == nil || err.Error() != `scheduled_calendar: unknown calendar "UK"` {
+		t.Errorf("Expected unknown calendar error, got %v", err)
+	}
+
+	cnf.ScheduledCalendar.Calendar = ""
+	cnf.Calendars["US"] = CalendarConfig{Timezone: "UTC", Holidays: map[string]string{"Dec 25": "Christmas Day"}}
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected invalid holiday error")
+	}
+}
+
+func TestValidateRiskScoring(t *testing.T) {
+	cnf := Configuration{
+		DataSource:  DataSourceConfig{Dns: "some-dns"},
+		Redis:       RedisConfig{Dns: "localhost:6379"},
+		RiskScoring: RiskScoringConfig{Rules: []RiskRule{{Field: "nationality", Values: []string{"XX"}, Score: 50}}},
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cnf.RiskScoring.MediumThreshold != 40 || cnf.RiskScoring.HighThreshold != 70 {
+		t.Errorf("Expected default thresholds, got %+v", cnf.RiskScoring)
+	}
+
+	cnf.RiskScoring.Rules[0].Field = "meta_data"
+	if err := cnf.validateAndAddDefaults(); err == nil || err.Error() != `risk_scoring: rule 0: unknown field "meta_data"` {
+		t.Errorf("Expected unknown field error, got %v", err)
+	}
+
+	cnf.RiskScoring.Rules[0].Field = "country"
+	cnf.RiskScoring.MediumThreshold = 80
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected invalid thresholds error")
+	}
+}
+
+func TestValidateContactVerification(t *testing.T) {
+	cnf := Configuration{
+		DataSource: DataSourceConfig{Dns: "some-dns"},
+		Redis:      RedisConfig{Dns: "localhost:6379"},
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cnf.ContactVerification.CodeTTL != 15*time.Minute || cnf.ContactVerification.MaxAttempts != 5 {
+		t.Errorf("Expected default contact verification, got %+v", cnf.ContactVerification)
+	}
+
+	cnf.ContactVerification.MaxAttempts = -1
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected negative max_attempts error")
+	}
+}
+
+func TestValidateDatabasePool(t *testing.T) {
+	cnf := Configuration{
+		DataSource: DataSourceConfig{Dns: "some-dns", MaxOpenConns: 5},
+		Redis:      RedisConfig{Dns: "localhost:6379"},
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cnf.DataSource.MaxIdleConns != 5 {
+		t.Errorf("Expected the default idle connections to be capped at max_open_conns, got %d", cnf.DataSource.MaxIdleConns)
+	}
+
+	cnf.DataSource.MaxIdleConns = 8
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected max_idle_conns above max_open_conns error")
+	}
+
+	cnf.DataSource.MaxIdleConns = 2
+	cnf.DataSource.ConnMaxIdleTime = -time.Second
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected negative conn_max_idle_time error")
+	}
+}
+
+func TestDatabaseReplicaDefaults(t *testing.T) {
+	cnf := Configuration{
+		DataSource: DataSourceConfig{Dns: "some-dns", Replicas: []string{" postgres://replica-1/blnk "}},
+		Redis:      RedisConfig{Dns: "localhost:6379"},
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cnf.DataSource.Replicas[0] != "postgres://replica-1/blnk" {
+		t.Errorf("Expected trimmed replica DNS, got %q", cnf.DataSource.Replicas[0])
+	}
+	if cnf.DataSource.ReplicaMaxLag != 10*time.Second || cnf.DataSource.ReplicaCheckInterval != 5*time.Second {
+		t.Errorf("Expected default replica lag settings, got %+v", cnf.DataSource)
+	}
+}
+
+func TestReconciliationThresholdDefaults(t *testing.T) {
+	var cnf Configuration
+	raw := `{"data_source": {"dns": "some-dns"}, "redis": {"dns": "localhost:6379"}, "reconciliation": {"auto_confirm_threshold": 0}}`
+	if err := json.Unmarshal([]byte(raw), &cnf); err != nil {
+		t.Fatalf("Unable to decode configuration: %v", err)
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// The explicit 0 is kept; the threshold left unset takes its default
+	if autoConfirm, review := cnf.Reconciliation.Thresholds(); autoConfirm != 0 || review != 0.6 {
+		t.Errorf("Expected thresholds 0 and 0.6, got %v and %v", autoConfirm, review)
+	}
+	if *cnf.Reconciliation.AutoConfirmThreshold != 0 || *cnf.Reconciliation.ReviewThreshold != 0.6 {
+		t.Errorf("Expected defaults to be filled in, got %+v", cnf.Reconciliation)
+	}
+}
+
+func TestValidateComplianceExport(t *testing.T) {
+	cnf := Configuration{
+		DataSource:       DataSourceConfig{Dns: "some-dns"},
+		Redis:            RedisConfig{Dns: "localhost:6379"},
+		ComplianceExport: ComplianceExportConfig{Bucket: "ledger-archive", SigningKey: "secret"},
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cnf.ComplianceExport.RetentionMode != "COMPLIANCE" || cnf.ComplianceExport.RetentionDays != 2555 {
+		t.Errorf("Expected default retention, got %+v", cnf.ComplianceExport)
+	}
+
+	cnf.ComplianceExport.SigningKey = ""
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected missing signing key error")
+	}
+
+	cnf.ComplianceExport.SigningKey = "secret"
+	cnf.ComplianceExport.RetentionMode = "LEGAL_HOLD"
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected invalid retention mode error")
+	}
+}
+
+func TestValidateCurrencies(t *testing.T) {
+	cnf := Configuration{
+		DataSource: DataSourceConfig{Dns: "some-dns"},
+		Redis:      RedisConfig{Dns: "localhost:6379"},
+		Currencies: map[string]CurrencyConfig{
+			"POINTS": {Type: "points", SymbolPosition: "after", ValueCurrency: "USD"},
+		},
+		Pricing: PricingConfig{FXRates: map[string]float64{"USD / POINTS": 100}},
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	invalid := []CurrencyConfig{
+		{Type: "stock"},
+		{SymbolPosition: "middle"},
+		{ValueCurrency: "EUR"},
+		{ValueCurrency: "points"},
+		{MinorUnits: 19},
+	}
+	for _, currency := range invalid {
+		cnf.Currencies["POINTS"] = currency
+		if err := cnf.validateAndAddDefaults(); err == nil {
+			t.Errorf("Expected an error for %+v", currency)
+		}
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	cnf := Configuration{
+		DataSource: DataSourceConfig{Dns: "some-dns"},
+		Redis:      RedisConfig{Dns: "localhost:6379"},
+		Templates: TemplatesConfig{
+			Webhooks: map[string]PayloadTemplates{
+				"https://legacy.example.com/hook": {Events: map[string]string{"*": `{{.event}};{{money .data.precise_amount .data.precision .data.currency}}`}},
+			},
+		},
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	cnf.Templates.Receipts.Events = map[string]string{"transaction.applied": `{{currency .data.amount}}`}
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected an error for a template calling an unknown helper")
+	}
+
+	cnf.Templates.Receipts.Events = map[string]string{"transaction.applied": `{{.data.amount`}
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected an error for a malformed template")
+	}
+}
+
+func TestValidateIdentityTypes(t *testing.T) {
+	cnf := Configuration{
+		DataSource: DataSourceConfig{Dns: "some-dns"},
+		Redis:      RedisConfig{Dns: "localhost:6379"},
+		IdentityTypes: map[string]IdentityTypeConfig{
+			"trust":         {Kind: "organization", RequiredFields: []string{"organization_name", "country"}},
+			"joint-account": {RequiredFields: []string{"first_name", "last_name"}},
+		},
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	cnf.IdentityTypes["government"] = IdentityTypeConfig{Kind: "agency"}
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected an error for an unknown identity type kind")
+	}
+}
+
+func TestValidateEnrichmentRules(t *testing.T) {
+	cnf := Configuration{
+		DataSource: DataSourceConfig{Dns: "some-dns"},
+		Redis:      RedisConfig{Dns: "localhost:6379"},
+		Enrichment: EnrichmentConfig{
+			Enabled: true,
+			Rules:   []NarrativeRule{{Pattern: `uber\s*(trip|eats)?`, Merchant: "Uber", Category: "transport"}},
+		},
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	cnf.Enrichment.Rules = append(cnf.Enrichment.Rules, NarrativeRule{Pattern: `netflix(`, Category: "entertainment"})
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+
+	cnf.Enrichment.Rules[1] = NarrativeRule{Pattern: `netflix`}
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected an error for a rule without merchant or category")
+	}
+}
+
+func TestValidateAuthorizationRuleEnforcement(t *testing.T) {
+	cnf := Configuration{
+		DataSource: DataSourceConfig{Dns: "some-dns"},
+		Redis:      RedisConfig{Dns: "localhost:6379"},
+		Authorization: AuthorizationConfig{Rules: []AuthorizationRule{
+			{Name: "cap", MaxAmount: 500},
+			{Name: "new-cap", MaxAmount: 200, Enforcement: EnforcementSoft},
+		}},
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cnf.Authorization.Rules[0].Enforcement != EnforcementHard {
+		t.Errorf("Expected rules to be hard by default, got %q", cnf.Authorization.Rules[0].Enforcement)
+	}
+	if !cnf.Authorization.Rules[1].IsSoft() {
+		t.Error("Expected the soft rule to stay soft")
+	}
+
+	cnf.Authorization.Rules[1].Enforcement = "advisory"
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected an error for an unknown enforcement")
+	}
+}
+
+func TestValidateDatabaseDriver(t *testing.T) {
+	cnf := Configuration{
+		DataSource: DataSourceConfig{Dns: "some-dns"},
+		Redis:      RedisConfig{Dns: "localhost:6379"},
+	}
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cnf.DataSource.Driver != DriverPostgres {
+		t.Errorf("Expected default driver %q, got %q", DriverPostgres, cnf.DataSource.Driver)
+	}
+
+	cnf.DataSource.Driver = " PGX "
+	if err := cnf.validateAndAddDefaults(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cnf.DataSource.Driver != DriverPgx {
+		t.Errorf("Expected driver %q, got %q", DriverPgx, cnf.DataSource.Driver)
+	}
+
+	cnf.DataSource.Driver = "mysql"
+	if err := cnf.validateAndAddDefaults(); err == nil {
+		t.Error("Expected unknown driver error")
+	}
+}
+
+func TestNetworkPolicyTrustedProxies(t *testing.T) {
+	policy := NetworkPolicyConfig{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}}
+	if err := policy.validate(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !policy.TrustsProxy(net.ParseIP("10.2.3.4")) || !policy.TrustsProxy(net.ParseIP("192.168.1.1")) {
+		t.Error("Expected configured proxies to be trusted")
+	}
+	if policy.TrustsProxy(net.ParseIP("192.168.1.2")) || policy.TrustsProxy(nil) {
+		t.Error("Expected other addresses not to be trusted")
+	}
+
+	policy.TrustedProxies = []string{"not-an-ip"}
+	if err := policy.validate(); err == nil {
+		t.Error("Expected invalid trusted proxy error")
+	}
+}
@@ -0,0 +1,237 @@
+package blnk
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// Events sent as identities verify their email addresses and phone numbers. The requested event carries the code,
+// for a notification service to deliver it to the identity.
+const (
+	EventContactVerificationRequested = "identity.contact_verification.requested"
+	EventContactVerified              = "identity.contact.verified"
+)
+
+var (
+	// ErrUnknownContactChannel is returned when a contact is verified on a channel other than email or phone.
+	ErrUnknownContactChannel = errors.New("unknown contact channel, expected email or phone")
+
+	// ErrNoContactToVerify is returned when a code is requested for an identity without the email address or
+	// phone number to send it to, or whose value is tokenized.
+	ErrNoContactToVerify = errors.New("identity has no contact to verify")
+
+	// ErrInvalidVerificationCode is returned when a verification code does not match the one sent.
+	ErrInvalidVerificationCode = errors.New("invalid verification code")
+
+	// ErrVerificationCodeExpired is returned when a verification code can no longer be used: it expired, too
+	// many wrong codes were entered, or the contact it was sent to changed. A new code must be requested.
+	ErrVerificationCodeExpired = errors.New("verification code expired")
+
+	// ErrContactNotVerified is returned when a balance is created for an identity without the verified contact
+	// details balances require.
+	ErrContactNotVerified = errors.New("identity contact is not verified")
+)
+
+// RequestContactVerification generates a code to confirm an identity's email address or phone number. The code
+// is returned, and sent in a webhook, for it to be delivered to the identity; only its hash is kept. Requesting a
+// code supersedes the codes requested before it.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - identityID string: The ID of the identity.
+// - channel string: The contact to verify, email or phone.
+//
+// Returns:
+// - *model.ContactVerification: The verification, with its code and destination.
+// - error: An error if the channel is unknown, the identity has nothing to verify on it, or the code could not be saved.
+func (l *Blnk) RequestContactVerification(ctx context.Context, identityID, channel string) (*model.ContactVerification, error) {
+	ctx, span := tracer.Start(ctx, "RequestContactVerification")
+	defer span.End()
+
+	identity, err := l.datasource.GetIdentityByID(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+	destination, err := contactToVerify(identity, channel)
+	if err != nil {
+		return nil, err
+	}
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	code, err := generateVerificationCode()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	verification := &model.ContactVerification{
+		VerificationID:  model.GenerateUUIDWithSuffix("cvr"),
+		IdentityID:      identityID,
+		Channel:         channel,
+		Destination:     destination,
+		DestinationHash: hashContact(destination),
+		ExpiresAt:       now.Add(cnf.ContactVerification.CodeTTL),
+		CreatedAt:       now,
+	}
+	verification.CodeHash = hashVerificationCode(verification.VerificationID, code)
+	if err := l.datasource.CreateContactVerification(ctx, verification); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	verification.Code = code
+
+	payload := *verification
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: EventContactVerificationRequested, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+	return verification, nil
+}
+
+// ConfirmContactVerification checks a code sent to an identity's email address or phone number and, when it
+// matches the latest code requested, marks the contact verified. Wrong codes count against the code, which is
+// spent once the configured number of attempts is reached.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - identityID string: The ID of the identity.
+// - channel string: The contact verified, email or phone.
+// - code string: The code the identity received.
+//
+// Returns:
+// - *model.Identity: The identity, with the contact verified.
+// - error: ErrInvalidVerificationCode if the code does not match, ErrVerificationCodeExpired if it can no longer
+// be used, or an error if the contact could not be verified.
+func (l *Blnk) ConfirmContactVerification(ctx context.Context, identityID, channel, code string) (*model.Identity, error) {
+	ctx, span := tracer.Start(ctx, "ConfirmContactVerification")
+	defer span.End()
+
+	identity, err := l.datasource.GetIdentityByID(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+	destination, err := contactToVerify(identity, channel)
+	if err != nil {
+		return nil, err
+	}
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	verification, err := l.datasource.GetPendingContactVerification(ctx, identityID, channel)
+	var apiErr apierror.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == apierror.ErrNotFound {
+		return nil, fmt.Errorf("%w: no %s code is pending", ErrInvalidVerificationCode, channel)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(verification.ExpiresAt) || verification.Attempts >= cnf.ContactVerification.MaxAttempts {
+		return nil, ErrVerificationCodeExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(verification.DestinationHash), []byte(hashContact(destination))) != 1 {
+		return nil, fmt.Errorf("%w: the %s changed since the code was sent", ErrVerificationCodeExpired, channel)
+	}
+	if subtle.ConstantTimeCompare([]byte(verification.CodeHash), []byte(hashVerificationCode(verification.VerificationID, code))) != 1 {
+		if _, err := l.datasource.IncrementContactVerificationAttempts(ctx, verification.VerificationID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidVerificationCode
+	}
+
+	verifiedAt := time.Now()
+	verification.VerifiedAt = &verifiedAt
+	if err := l.datasource.ConfirmContactVerification(ctx, verification); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if channel == model.ContactChannelEmail {
+		identity.VerifiedEmail, identity.EmailVerifiedAt = true, &verifiedAt
+	} else {
+		identity.VerifiedPhone, identity.PhoneVerifiedAt = true, &verifiedAt
+	}
+
+	payload := *verification
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: EventContactVerified, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+	return identity, nil
+}
+
+// checkContactVerification rejects a balance for an identity without a verified email address or phone number,
+// when balances require them.
+func (l *Blnk) checkContactVerification(ctx context.Context, identityID string) error {
+	if identityID == "" {
+		return nil
+	}
+	cnf, err := config.Fetch()
+	if err != nil || (!cnf.ContactVerification.RequireVerifiedEmail && !cnf.ContactVerification.RequireVerifiedPhone) {
+		return nil
+	}
+
+	identity, err := l.datasource.GetIdentityByID(ctx, identityID)
+	if err != nil {
+		return err
+	}
+	if cnf.ContactVerification.RequireVerifiedEmail && !identity.VerifiedEmail {
+		return fmt.Errorf("%w: identity %s has no verified email address", ErrContactNotVerified, identityID)
+	}
+	if cnf.ContactVerification.RequireVerifiedPhone && !identity.VerifiedPhone {
+		return fmt.Errorf("%w: identity %s has no verified phone number", ErrContactNotVerified, identityID)
+	}
+	return nil
+}
+
+// contactToVerify returns the email address or phone number of an identity verified on a channel.
+func contactToVerify(identity *model.Identity, channel string) (string, error) {
+	destination, ok := identity.ContactDestination(channel)
+	if !ok {
+		return "", ErrUnknownContactChannel
+	}
+	field := "EmailAddress"
+	if channel == model.ContactChannelPhone {
+		field = "PhoneNumber"
+	}
+	if destination == "" || identity.IsFieldTokenized(field) {
+		return "", fmt.Errorf("%w: no %s to send a code to", ErrNoContactToVerify, channel)
+	}
+	return destination, nil
+}
+
+// generateVerificationCode returns a random six digit code.
+func generateVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashVerificationCode hashes a code with the ID of its verification, so equal codes hash differently.
+func hashVerificationCode(verificationID, code string) string {
+	sum := sha256.Sum256([]byte(verificationID + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+func hashContact(destination string) string {
+	sum := sha256.Sum256([]byte(destination))
+	return hex.EncodeToString(sum[:])
+}
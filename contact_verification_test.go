@@ -0,0 +1,150 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newContactVerificationTestBlnk(t *testing.T) (*Blnk, *mocks.MockDataSource) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.ContactVerification = config.ContactVerificationConfig{CodeTTL: 15 * time.Minute, MaxAttempts: 3}
+	return b, mockDS
+}
+
+func TestRequestAndConfirmContactVerification(t *testing.T) {
+	b, mockDS := newContactVerificationTestBlnk(t)
+	identity := &model.Identity{IdentityID: "idt_ada", EmailAddress: "ada@example.com"}
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_ada").Return(identity, nil)
+
+	var saved *model.ContactVerification
+	mockDS.On("CreateContactVerification", mock.Anything, mock.AnythingOfType("*model.ContactVerification")).
+		Run(func(args mock.Arguments) { copied := *args.Get(1).(*model.ContactVerification); saved = &copied }).
+		Return(nil)
+
+	verification, err := b.RequestContactVerification(context.Background(), "idt_ada", model.ContactChannelEmail)
+	require.NoError(t, err)
+	assert.Len(t, verification.Code, 6)
+	assert.Equal(t, "ada@example.com", verification.Destination)
+	require.NotNil(t, saved)
+	assert.Empty(t, saved.Code, "only the hash of the code is saved")
+	assert.NotEqual(t, verification.Code, saved.CodeHash)
+
+	mockDS.On("GetPendingContactVerification", mock.Anything, "idt_ada", model.ContactChannelEmail).Return(saved, nil)
+	mockDS.On("IncrementContactVerificationAttempts", mock.Anything, saved.VerificationID).Return(1, nil)
+	mockDS.On("ConfirmContactVerification", mock.Anything, mock.AnythingOfType("*model.ContactVerification")).Return(nil)
+
+	wrong := "000000"
+	if verification.Code == wrong {
+		wrong = "111111"
+	}
+	_, err = b.ConfirmContactVerification(context.Background(), "idt_ada", model.ContactChannelEmail, wrong)
+	assert.ErrorIs(t, err, ErrInvalidVerificationCode)
+	mockDS.AssertCalled(t, "IncrementContactVerificationAttempts", mock.Anything, saved.VerificationID)
+	mockDS.AssertNotCalled(t, "ConfirmContactVerification", mock.Anything, mock.Anything)
+
+	verified, err := b.ConfirmContactVerification(context.Background(), "idt_ada", model.ContactChannelEmail, verification.Code)
+	require.NoError(t, err)
+	assert.True(t, verified.VerifiedEmail)
+	assert.NotNil(t, verified.EmailVerifiedAt)
+	assert.False(t, verified.VerifiedPhone)
+}
+
+func TestRequestContactVerification_NothingToVerify(t *testing.T) {
+	b, mockDS := newContactVerificationTestBlnk(t)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_ada").Return(&model.Identity{IdentityID: "idt_ada", EmailAddress: "ada@example.com"}, nil)
+
+	_, err := b.RequestContactVerification(context.Background(), "idt_ada", model.ContactChannelPhone)
+	assert.ErrorIs(t, err, ErrNoContactToVerify)
+
+	_, err = b.RequestContactVerification(context.Background(), "idt_ada", "fax")
+	assert.ErrorIs(t, err, ErrUnknownContactChannel)
+	mockDS.AssertNotCalled(t, "CreateContactVerification", mock.Anything, mock.Anything)
+}
+
+func TestConfirmContactVerification_Unusable(t *testing.T) {
+	pending := func() *model.ContactVerification {
+		return &model.ContactVerification{
+			VerificationID:  "cvr_1",
+			IdentityID:      "idt_ada",
+			Channel:         model.ContactChannelPhone,
+			DestinationHash: hashContact("+447700900123"),
+			CodeHash:        hashVerificationCode("cvr_1", "123456"),
+			ExpiresAt:       time.Now().Add(time.Minute),
+		}
+	}
+	expired, spent, changed := pending(), pending(), pending()
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	spent.Attempts = 3
+	changed.DestinationHash = hashContact("+447700900999")
+
+	tests := []struct {
+		name    string
+		pending *model.ContactVerification
+		err     error
+		wantErr error
+	}{
+		{name: "expired", pending: expired, wantErr: ErrVerificationCodeExpired},
+		{name: "attempts spent", pending: spent, wantErr: ErrVerificationCodeExpired},
+		{name: "phone number changed", pending: changed, wantErr: ErrVerificationCodeExpired},
+		{name: "no code requested", err: apierror.NewAPIError(apierror.ErrNotFound, "No pending phone verification", nil), wantErr: ErrInvalidVerificationCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, mockDS := newContactVerificationTestBlnk(t)
+			mockDS.On("GetIdentityByID", mock.Anything, "idt_ada").Return(&model.Identity{IdentityID: "idt_ada", PhoneNumber: "+447700900123"}, nil)
+			mockDS.On("GetPendingContactVerification", mock.Anything, "idt_ada", model.ContactChannelPhone).Return(tt.pending, tt.err)
+
+			_, err := b.ConfirmContactVerification(context.Background(), "idt_ada", model.ContactChannelPhone, "123456")
+			assert.ErrorIs(t, err, tt.wantErr)
+			mockDS.AssertNotCalled(t, "ConfirmContactVerification", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreateBalance_RequiresVerifiedContact(t *testing.T) {
+	b, mockDS := newContactVerificationTestBlnk(t)
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.ContactVerification.RequireVerifiedEmail = true
+	t.Cleanup(func() { cnf.ContactVerification = config.ContactVerificationConfig{} })
+
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_new").Return(&model.Identity{IdentityID: "idt_new", EmailAddress: "new@example.com"}, nil)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_ada").Return(&model.Identity{IdentityID: "idt_ada", VerifiedEmail: true}, nil)
+
+	_, err = b.CreateBalance(context.Background(), model.Balance{LedgerID: "ldg_1", Currency: "USD", IdentityID: "idt_new"})
+	assert.ErrorIs(t, err, ErrContactNotVerified)
+	mockDS.AssertNotCalled(t, "CreateBalance", mock.Anything, mock.Anything)
+
+	assert.NoError(t, b.checkContactVerification(context.Background(), "idt_ada"))
+	assert.NoError(t, b.checkContactVerification(context.Background(), ""))
+
+	cnf.ContactVerification.RequireVerifiedPhone = true
+	assert.ErrorIs(t, b.checkContactVerification(context.Background(), "idt_ada"), ErrContactNotVerified)
+}
@@ -0,0 +1,168 @@
+package blnk
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// LookupCurrency returns a currency from the currency registry. Currencies configured under "currencies"
+// take precedence over their ISO 4217 definition, so ledgers can register non-ISO currencies such as BTC or
+// loyalty points and override the minor units or symbol of ISO ones.
+func LookupCurrency(code string) (model.Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return model.Currency{}, fmt.Errorf("currency code is required")
+	}
+
+	iso, isISO := model.ISOCurrency(code)
+	if cnf, err := config.Fetch(); err == nil {
+		for configured, currency := range cnf.Currencies {
+			if strings.ToUpper(configured) != code {
+				continue
+			}
+			return registeredCurrency(code, currency, isISO), nil
+		}
+	}
+	if isISO {
+		return iso, nil
+	}
+	return model.Currency{}, fmt.Errorf("currency %s not found", code)
+}
+
+// registeredCurrency returns the registry entry of a configured currency.
+func registeredCurrency(code string, currency config.CurrencyConfig, isISO bool) model.Currency {
+	kind := currency.Type
+	if kind == "" {
+		kind = model.CurrencyTypeOther
+		if isISO {
+			kind = model.CurrencyTypeFiat
+		}
+	}
+	return model.Currency{
+		Code:           code,
+		Name:           currency.Name,
+		Type:           kind,
+		MinorUnits:     currency.MinorUnits,
+		Multiplier:     math.Pow10(currency.MinorUnits),
+		Symbol:         currency.Symbol,
+		SymbolPosition: currency.SymbolPosition,
+		ValueCurrency:  strings.ToUpper(currency.ValueCurrency),
+		ISO:            isISO,
+	}
+}
+
+// ListCurrencies returns the currencies configured under "currencies", in code order, optionally only those of
+// a type. ISO 4217 currencies that are not configured are left out.
+//
+// Parameters:
+// - kind: The type of the currencies to list, one of the model.CurrencyType values. Empty lists every type.
+//
+// Returns:
+// - []model.Currency: The configured currencies.
+// - error: An error if the configuration is not loaded.
+func (l *Blnk) ListCurrencies(kind string) ([]model.Currency, error) {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	currencies := []model.Currency{}
+	for configured, currency := range cnf.Currencies {
+		code := strings.ToUpper(strings.TrimSpace(configured))
+		_, isISO := model.ISOCurrency(code)
+		registered := registeredCurrency(code, currency, isISO)
+		if kind != "" && registered.Type != kind {
+			continue
+		}
+		currencies = append(currencies, registered)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
+	return currencies, nil
+}
+
+// ValueAmount converts an amount of a currency to another at the configured exchange rates, by default to the
+// fiat currency it is valued in, e.g. loyalty points to the dollars they are worth.
+//
+// Parameters:
+// - code: The currency of the amount.
+// - preciseAmount: The amount in units of precision.
+// - precision: The precision of the amount. Zero uses the currency's multiplier.
+// - to: The currency to convert to. Empty uses the currency's value currency.
+//
+// Returns:
+// - *model.CalculatedConversion: The amount in the target currency, rounded with its rounding mode.
+// - error: An error if a currency is unknown, the currency has no value currency and none was given, or no
+// exchange rate is configured between the two.
+func (l *Blnk) ValueAmount(code string, preciseAmount *big.Int, precision float64, to string) (*model.CalculatedConversion, error) {
+	currency, err := LookupCurrency(code)
+	if err != nil {
+		return nil, err
+	}
+	if to == "" {
+		to = currency.ValueCurrency
+	}
+	if to == "" {
+		return nil, fmt.Errorf("currency %s has no value currency", currency.Code)
+	}
+	if precision < 0 {
+		return nil, fmt.Errorf("precision cannot be negative")
+	}
+	if precision == 0 {
+		precision = currency.Multiplier
+	}
+	if preciseAmount == nil {
+		return nil, fmt.Errorf("precise amount is required")
+	}
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	return convertCalculation(cnf, preciseAmount, currency.Code, precision, to)
+}
+
+// GetCurrency returns a currency from the currency registry.
+func (l *Blnk) GetCurrency(code string) (model.Currency, error) {
+	return LookupCurrency(code)
+}
+
+// FormatAmount renders a precise amount of a currency for display in a locale, so clients show money the
+// same way the statements and notifications of the ledger do.
+//
+// Parameters:
+// - code: The currency of the amount.
+// - preciseAmount: The amount in units of precision.
+// - precision: The precision of the amount. Zero uses the currency's multiplier.
+// - locale: The BCP 47 locale to format the amount for. Empty uses model.DefaultLocale.
+// - display: How the currency is shown, one of the model.CurrencyDisplay values.
+//
+// Returns:
+// - model.FormattedAmount: The formatted amount.
+// - error: An error if the currency is unknown or the locale or display is invalid.
+func (l *Blnk) FormatAmount(code string, preciseAmount *big.Int, precision float64, locale, display string) (model.FormattedAmount, error) {
+	currency, err := LookupCurrency(code)
+	if err != nil {
+		return model.FormattedAmount{}, err
+	}
+	if locale != "" {
+		if locale, err = model.CanonicalLocale(locale); err != nil {
+			return model.FormattedAmount{}, err
+		}
+	}
+	if err := model.ValidateCurrencyDisplay(display); err != nil {
+		return model.FormattedAmount{}, err
+	}
+	if precision < 0 {
+		return model.FormattedAmount{}, fmt.Errorf("precision cannot be negative")
+	}
+	if preciseAmount == nil {
+		return model.FormattedAmount{}, fmt.Errorf("precise amount is required")
+	}
+
+	return model.NewLocaleFormat(locale, "").FormatMoney(preciseAmount, precision, currency, display), nil
+}
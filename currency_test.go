@@ -0,0 +1,131 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCurrency(t *testing.T) {
+	config.ConfigStore.Store(&config.Configuration{
+		Currencies: map[string]config.CurrencyConfig{
+			"btc": {MinorUnits: 8, Symbol: "₿"},
+			"JPY": {MinorUnits: 2},
+		},
+	})
+	defer config.ConfigStore.Store(&config.Configuration{})
+
+	btc, err := LookupCurrency("BTC")
+	require.NoError(t, err)
+	assert.Equal(t, 8, btc.MinorUnits)
+	assert.Equal(t, float64(1e8), btc.Multiplier)
+	assert.Equal(t, "₿", btc.Symbol)
+	assert.False(t, btc.ISO)
+
+	jpy, err := LookupCurrency("jpy")
+	require.NoError(t, err)
+	assert.Equal(t, 2, jpy.MinorUnits)
+	assert.True(t, jpy.ISO)
+
+	usd, err := LookupCurrency("USD")
+	require.NoError(t, err)
+	assert.Equal(t, 2, usd.MinorUnits)
+
+	_, err = LookupCurrency("XYZ1")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestLookupCurrency_NonMonetary(t *testing.T) {
+	config.ConfigStore.Store(&config.Configuration{
+		Currencies: map[string]config.CurrencyConfig{
+			"points": {Name: "Reward points", Type: model.CurrencyTypePoints, Symbol: "pts", SymbolPosition: "after", ValueCurrency: "usd"},
+			"XAU_G":  {Name: "Gold", Type: model.CurrencyTypeCommodity, MinorUnits: 3, Symbol: "g"},
+			"GIFT":   {MinorUnits: 2},
+			"usd":    {MinorUnits: 2, Symbol: "US$"},
+		},
+		Pricing: config.PricingConfig{FXRates: map[string]float64{"POINTS/USD": 0.01, "USD/XAU_G": 0.0125}},
+	})
+	defer config.ConfigStore.Store(&config.Configuration{})
+	b := &Blnk{}
+
+	points, err := LookupCurrency("POINTS")
+	require.NoError(t, err)
+	assert.Equal(t, model.Currency{
+		Code: "POINTS", Name: "Reward points", Type: model.CurrencyTypePoints, Multiplier: 1,
+		Symbol: "pts", SymbolPosition: "after", ValueCurrency: "USD",
+	}, points)
+
+	gift, err := LookupCurrency("GIFT")
+	require.NoError(t, err)
+	assert.Equal(t, model.CurrencyTypeOther, gift.Type)
+
+	all, err := b.ListCurrencies("")
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+	assert.Equal(t, []string{"GIFT", "POINTS", "USD", "XAU_G"}, []string{all[0].Code, all[1].Code, all[2].Code, all[3].Code})
+	assert.Equal(t, model.CurrencyTypeFiat, all[2].Type)
+
+	commodities, err := b.ListCurrencies(model.CurrencyTypeCommodity)
+	require.NoError(t, err)
+	require.Len(t, commodities, 1)
+	assert.Equal(t, "Gold", commodities[0].Name)
+
+	formatted, err := b.FormatAmount("POINTS", big.NewInt(1250), 0, "en-US", "")
+	require.NoError(t, err)
+	assert.Equal(t, "1,250\u00a0pts", formatted.Formatted)
+
+	// 1,250 points at $0.01 each
+	value, err := b.ValueAmount("points", big.NewInt(1250), 0, "")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", value.ToCurrency)
+	assert.Equal(t, int64(1250), value.PreciseAmount.Int64())
+
+	// 2.5 grams of gold at $80 a gram, converted at the inverse of the USD/XAU_G rate
+	value, err = b.ValueAmount("XAU_G", big.NewInt(2500), 0, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20000), value.PreciseAmount.Int64())
+
+	_, err = b.ValueAmount("GIFT", big.NewInt(100), 0, "")
+	assert.ErrorContains(t, err, "no value currency")
+	_, err = b.ValueAmount("GIFT", big.NewInt(100), 0, "USD")
+	assert.ErrorContains(t, err, "no exchange rate")
+}
+
+func TestFormatAmount(t *testing.T) {
+	config.ConfigStore.Store(&config.Configuration{})
+	b := &Blnk{}
+
+	formatted, err := b.FormatAmount("eur", big.NewInt(123450), 100, "fr-FR", "")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", formatted.Currency)
+	assert.Equal(t, "1234.50", formatted.Amount)
+	assert.Equal(t, "€", formatted.Symbol)
+	assert.Contains(t, formatted.Formatted, "234,50\u00a0€")
+
+	_, err = b.FormatAmount("USD", big.NewInt(1), 100, "not a locale!", "")
+	assert.Error(t, err)
+	_, err = b.FormatAmount("USD", big.NewInt(1), 100, "en", "long")
+	assert.Error(t, err)
+	_, err = b.FormatAmount("USD", big.NewInt(1), -1, "en", "")
+	assert.Error(t, err)
+}
@@ -30,11 +30,12 @@ import (
 // CreateAccount inserts a new Account into the database.
 // This function handles metadata serialization and database insertion.
 // Parameters:
+// - ctx: The context for the operation.
 // - account: The account model containing fields such as name, number, bank name, currency, ledger ID, identity ID, and balance ID.
 // Returns:
 // - model.Account: The created account with the assigned account ID and creation timestamp.
 // - error: Returns an error if any issue occurs while marshalling metadata or executing the database query.
-func (d Datasource) CreateAccount(account model.Account) (model.Account, error) {
+func (d Datasource) CreateAccount(ctx context.Context, account model.Account) (model.Account, error) {
 	// Serialize metadata into JSON
 	metaDataJSON, err := json.Marshal(account.MetaData)
 	if err != nil {
@@ -46,7 +47,7 @@ func (d Datasource) CreateAccount(account model.Account) (model.Account, error)
 	account.CreatedAt = time.Now()
 
 	// Insert the new account into the database
-	_, err = d.Conn.Exec(`
+	_, err = d.Conn.ExecContext(ctx, `
 		INSERT INTO blnk.accounts (account_id, name, number, bank_name, currency, ledger_id, identity_id, balance_id, created_at, meta_data)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`, account.AccountID, account.Name, account.Number, account.BankName, account.Currency, account.LedgerID, account.IdentityID, account.BalanceID, account.CreatedAt, metaDataJSON)
@@ -59,12 +60,13 @@ func (d Datasource) CreateAccount(account model.Account) (model.Account, error)
 // It uses a transaction to ensure consistency and can include additional
 // related entities like balance, identity, or ledger if specified in the `include` parameter.
 // Parameters:
+// - ctx: The context for the operation.
 // - id: The ID of the account to retrieve.
 // - include: A list of related entities to include in the query result.
 // Returns:
 // - A pointer to the retrieved Account or an error if something goes wrong.
-func (d Datasource) GetAccountByID(id string, include []string) (*model.Account, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+func (d Datasource) GetAccountByID(ctx context.Context, id string, include []string) (*model.Account, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
 	defer cancel()
 
 	// Start a transaction
@@ -78,7 +80,7 @@ func (d Datasource) GetAccountByID(id string, include []string) (*model.Account,
 	query := prepareAccountQueries(queryBuilder, include)
 
 	// Execute the query
-	row := tx.QueryRow(query, id)
+	row := tx.QueryRowContext(ctx, query, id)
 
 	// Scan the result into the account object
 	account, err := scanAccountRow(row, tx, include)
@@ -246,9 +248,9 @@ func scanAccountRow(row *sql.Row, tx *sql.Tx, include []string) (*model.Account,
 // It returns a list of Account objects, each populated with metadata and account details.
 // Returns:
 // - A slice of Account objects or an error if the query or scan fails.
-func (d Datasource) GetAllAccounts() ([]model.Account, error) {
+func (d Datasource) GetAllAccounts(ctx context.Context) ([]model.Account, error) {
 	// Execute the SQL query to retrieve account data
-	rows, err := d.Conn.Query(`
+	rows, err := d.Conn.QueryContext(ctx, `
 		SELECT account_id, name, number, bank_name, currency, created_at, meta_data 
 		FROM blnk.accounts
 		ORDER BY created_at DESC
@@ -289,12 +291,13 @@ func (d Datasource) GetAllAccounts() ([]model.Account, error) {
 // GetAccountByNumber retrieves an account based on its number.
 // It queries the database for an account with the given number and returns the account details if found.
 // Parameters:
+// - ctx: The context for the operation.
 // - number: The account number to search for.
 // Returns:
 // - A pointer to the Account object if found, or an error if the account is not found or a query error occurs.
-func (d Datasource) GetAccountByNumber(number string) (*model.Account, error) {
+func (d Datasource) GetAccountByNumber(ctx context.Context, number string) (*model.Account, error) {
 	// Query the database for the account with the given number
-	row := d.Conn.QueryRow(`
+	row := d.Conn.QueryRowContext(ctx, `
 		SELECT account_id, name, number, bank_name, created_at, meta_data 
 		FROM blnk.accounts WHERE number = $1
 	`, number)
@@ -324,10 +327,11 @@ func (d Datasource) GetAccountByNumber(number string) (*model.Account, error) {
 // UpdateAccount updates a specific account in the database.
 // It updates the account's name, number, bank name, and metadata based on the account ID.
 // Parameters:
+// - ctx: The context for the operation.
 // - account: A pointer to the Account object containing the updated account information.
 // Returns:
 // - An error if the update fails, otherwise returns nil.
-func (d Datasource) UpdateAccount(account *model.Account) error {
+func (d Datasource) UpdateAccount(ctx context.Context, account *model.Account) error {
 	// Marshal the MetaData field into JSON
 	metaDataJSON, err := json.Marshal(account.MetaData)
 	if err != nil {
@@ -335,7 +339,7 @@ func (d Datasource) UpdateAccount(account *model.Account) error {
 	}
 
 	// Execute the SQL update statement
-	_, err = d.Conn.Exec(`
+	_, err = d.Conn.ExecContext(ctx, `
 		UPDATE blnk.accounts
 		SET name = $2, number = $3, bank_name = $4, meta_data = $5
 		WHERE account_id = $1
@@ -348,12 +352,13 @@ func (d Datasource) UpdateAccount(account *model.Account) error {
 // DeleteAccount deletes a specific account from the database.
 // It removes the account with the given account ID from the accounts table.
 // Parameters:
+// - ctx: The context for the operation.
 // - id: The unique ID of the account to be deleted.
 // Returns:
 // - An error if the deletion fails, otherwise returns nil.
-func (d Datasource) DeleteAccount(id string) error {
+func (d Datasource) DeleteAccount(ctx context.Context, id string) error {
 	// Execute the SQL delete statement
-	_, err := d.Conn.Exec(`
+	_, err := d.Conn.ExecContext(ctx, `
 		DELETE FROM blnk.accounts WHERE account_id = $1
 	`, id)
 
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 	"time"
@@ -37,7 +38,7 @@ func TestCreateAccount_Success(t *testing.T) {
 		WithArgs(sqlmock.AnyArg(), account.Name, account.Number, account.BankName, account.Currency, account.LedgerID, account.IdentityID, account.BalanceID, sqlmock.AnyArg(), metaDataJSON).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	createdAccount, err := ds.CreateAccount(account)
+	createdAccount, err := ds.CreateAccount(context.Background(), account)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, createdAccount.AccountID)
 }
@@ -69,7 +70,7 @@ func TestGetAccountByID_Success(t *testing.T) {
 	// Commit transaction expectation
 	mock.ExpectCommit()
 
-	account, err := ds.GetAccountByID("acc1", []string{})
+	account, err := ds.GetAccountByID(context.Background(), "acc1", []string{})
 	assert.NoError(t, err)
 	assert.Equal(t, "acc1", account.AccountID)
 	assert.Equal(t, "Test Account", account.Name)
@@ -99,7 +100,7 @@ func TestGetAllAccounts_Success(t *testing.T) {
 	mock.ExpectQuery("SELECT account_id, name, number, bank_name").
 		WillReturnRows(rows)
 
-	accounts, err := ds.GetAllAccounts()
+	accounts, err := ds.GetAllAccounts(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, accounts, 2)
 	assert.Equal(t, "acc1", accounts[0].AccountID)
@@ -126,7 +127,7 @@ func TestGetAccountByNumber_Success(t *testing.T) {
 		WithArgs("1234567890").
 		WillReturnRows(row)
 
-	account, err := ds.GetAccountByNumber("1234567890")
+	account, err := ds.GetAccountByNumber(context.Background(), "1234567890")
 	assert.NoError(t, err)
 	assert.Equal(t, "acc1", account.AccountID)
 	assert.Equal(t, "Test Account", account.Name)
@@ -156,7 +157,7 @@ func TestUpdateAccount_Success(t *testing.T) {
 		WithArgs(account.AccountID, account.Name, account.Number, account.BankName, metaDataJSON).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err = ds.UpdateAccount(account)
+	err = ds.UpdateAccount(context.Background(), account)
 	assert.NoError(t, err)
 }
 
@@ -171,6 +172,6 @@ func TestDeleteAccount_Success(t *testing.T) {
 		WithArgs("acc1").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err = ds.DeleteAccount("acc1")
+	err = ds.DeleteAccount(context.Background(), "acc1")
 	assert.NoError(t, err)
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
 	"github.com/blnkfinance/blnk/model"
 	"github.com/lib/pq"
 )
@@ -14,6 +15,8 @@ import (
 var (
 	ErrAPIKeyNotFound = errors.New("api key not found")
 	ErrInvalidAPIKey  = errors.New("invalid api key")
+
+	ErrAPIKeyAlreadyRotated = errors.New("api key has already been rotated")
 )
 
 // CreateAPIKey creates a new API key
@@ -49,13 +52,13 @@ func (s *Datasource) CreateAPIKey(ctx context.Context, name, ownerID string, sco
 // GetAPIKey retrieves an API key by its key string
 func (s *Datasource) GetAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
 	query := `
-		SELECT api_key_id, key, name, owner_id, scopes, expires_at, created_at, last_used_at, is_revoked, revoked_at
+		SELECT api_key_id, key, name, owner_id, scopes, expires_at, created_at, last_used_at, is_revoked, revoked_at, rotated_to, rotation_expires_at, allowed_cidrs, blocked_countries, allowed_ledgers, allowed_balance_prefixes, field_compatibility
 		FROM blnk.api_keys
 		WHERE key = $1
 	`
 
 	apiKey := &model.APIKey{}
-	var scopes pq.StringArray
+	var scopes, allowedCIDRs, blockedCountries, allowedLedgers, allowedBalancePrefixes pq.StringArray
 	err := s.Conn.QueryRowContext(ctx, query, key).Scan(
 		&apiKey.APIKeyID,
 		&apiKey.Key,
@@ -67,8 +70,17 @@ func (s *Datasource) GetAPIKey(ctx context.Context, key string) (*model.APIKey,
 		&apiKey.LastUsedAt,
 		&apiKey.IsRevoked,
 		&apiKey.RevokedAt,
+		&apiKey.RotatedTo,
+		&apiKey.RotationExpiresAt,
+		&allowedCIDRs,
+		&blockedCountries,
+		&allowedLedgers,
+		&allowedBalancePrefixes,
+		&apiKey.FieldCompatibility,
 	)
 	apiKey.Scopes = []string(scopes)
+	apiKey.NetworkPolicy = model.NetworkPolicy{AllowedCIDRs: allowedCIDRs, BlockedCountries: blockedCountries}
+	apiKey.LedgerScope = ledgerscope.Scope{LedgerIDs: allowedLedgers, BalancePrefixes: allowedBalancePrefixes}
 
 	if err == sql.ErrNoRows {
 		fmt.Println("API key not found", key)
@@ -119,16 +131,18 @@ func (s *Datasource) UpdateLastUsed(ctx context.Context, id string) error {
 	return err
 }
 
-// ListAPIKeys lists all API keys for an owner
-func (s *Datasource) ListAPIKeys(ctx context.Context, ownerID string) ([]*model.APIKey, error) {
+// apiKeyKeyset is the order API keys are listed in, newest first.
+var apiKeyKeyset = keyset{timeColumn: "created_at", idColumn: "api_key_id", descending: true}
+
+// ListAPIKeys lists a page of the API keys of an owner, newest first
+func (s *Datasource) ListAPIKeys(ctx context.Context, ownerID string, page model.Page) ([]*model.APIKey, error) {
+	condition, suffix, args := apiKeyKeyset.page(page, []interface{}{ownerID})
 	query := `
-		SELECT api_key_id, key, name, owner_id, scopes, expires_at, created_at, last_used_at, is_revoked, revoked_at
+		SELECT api_key_id, key, name, owner_id, scopes, expires_at, created_at, last_used_at, is_revoked, revoked_at, rotated_to, rotation_expires_at, allowed_cidrs, blocked_countries, allowed_ledgers, allowed_balance_prefixes, field_compatibility
 		FROM blnk.api_keys
-		WHERE owner_id = $1
-		ORDER BY created_at DESC
-	`
+		WHERE owner_id = $1 AND ` + condition + suffix
 
-	rows, err := s.Conn.QueryContext(ctx, query, ownerID)
+	rows, err := s.Conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -137,7 +151,7 @@ func (s *Datasource) ListAPIKeys(ctx context.Context, ownerID string) ([]*model.
 	var apiKeys []*model.APIKey
 	for rows.Next() {
 		apiKey := &model.APIKey{}
-		var scopes pq.StringArray
+		var scopes, allowedCIDRs, blockedCountries, allowedLedgers, allowedBalancePrefixes pq.StringArray
 		err := rows.Scan(
 			&apiKey.APIKeyID,
 			&apiKey.Key,
@@ -149,8 +163,18 @@ func (s *Datasource) ListAPIKeys(ctx context.Context, ownerID string) ([]*model.
 			&apiKey.LastUsedAt,
 			&apiKey.IsRevoked,
 			&apiKey.RevokedAt,
+			&apiKey.RotatedTo,
+			&apiKey.RotationExpiresAt,
+			&allowedCIDRs,
+			&blockedCountries,
+			&allowedLedgers,
+			&allowedBalancePrefixes,
+			&apiKey.FieldCompatibility,
 		)
 		apiKey.Scopes = []string(scopes)
+		apiKey.NetworkPolicy = model.NetworkPolicy{AllowedCIDRs: allowedCIDRs, BlockedCountries: blockedCountries}
+		apiKey.LedgerScope = ledgerscope.Scope{LedgerIDs: allowedLedgers, BalancePrefixes: allowedBalancePrefixes}
+		apiKey.LedgerScope = ledgerscope.Scope{LedgerIDs: allowedLedgers, BalancePrefixes: allowedBalancePrefixes}
 		if err != nil {
 			return nil, err
 		}
@@ -159,3 +183,241 @@ func (s *Datasource) ListAPIKeys(ctx context.Context, ownerID string) ([]*model.
 
 	return apiKeys, nil
 }
+
+// RotateAPIKey issues a replacement for an API key with the same name, scopes, expiry, ledger scope, network
+// policy and field compatibility. The old key stays valid until the overlap period has passed and records the
+// ID of the key that replaced it.
+func (s *Datasource) RotateAPIKey(ctx context.Context, id, ownerID string, overlap time.Duration) (*model.APIKey, error) {
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var (
+		name, fieldCompatibility                string
+		scopes, allowedLedgers, balancePrefixes pq.StringArray
+		allowedCIDRs, blockedCountries          pq.StringArray
+		expiresAt                               time.Time
+		rotatedTo                               sql.NullString
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT name, scopes, expires_at, rotated_to, allowed_ledgers, allowed_balance_prefixes, allowed_cidrs, blocked_countries, field_compatibility
+		FROM blnk.api_keys
+		WHERE api_key_id = $1 AND owner_id = $2 AND is_revoked = false
+		FOR UPDATE
+	`, id, ownerID).Scan(&name, &scopes, &expiresAt, &rotatedTo, &allowedLedgers, &balancePrefixes, &allowedCIDRs, &blockedCountries, &fieldCompatibility)
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rotatedTo.Valid {
+		return nil, ErrAPIKeyAlreadyRotated
+	}
+
+	apiKey, err := model.NewAPIKey(name, ownerID, []string(scopes), expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	apiKey.LedgerScope = ledgerscope.Scope{LedgerIDs: allowedLedgers, BalancePrefixes: balancePrefixes}
+	apiKey.NetworkPolicy = model.NetworkPolicy{AllowedCIDRs: allowedCIDRs, BlockedCountries: blockedCountries}
+	apiKey.FieldCompatibility = fieldCompatibility
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO blnk.api_keys (api_key_id, key, name, owner_id, scopes, expires_at, created_at, last_used_at, is_revoked, allowed_ledgers, allowed_balance_prefixes, allowed_cidrs, blocked_countries, field_compatibility)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`,
+		apiKey.APIKeyID,
+		apiKey.Key,
+		apiKey.Name,
+		apiKey.OwnerID,
+		pq.StringArray(apiKey.Scopes),
+		apiKey.ExpiresAt,
+		apiKey.CreatedAt,
+		apiKey.LastUsedAt,
+		apiKey.IsRevoked,
+		allowedLedgers,
+		balancePrefixes,
+		allowedCIDRs,
+		blockedCountries,
+		fieldCompatibility,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE blnk.api_keys
+		SET rotated_to = $1, rotation_expires_at = $2
+		WHERE api_key_id = $3
+	`, apiKey.APIKeyID, time.Now().Add(overlap), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// ExpireAPIKeyRotation ends the overlap window of a rotated API key immediately
+func (s *Datasource) ExpireAPIKeyRotation(ctx context.Context, id, ownerID string) error {
+	query := `
+		UPDATE blnk.api_keys
+		SET rotation_expires_at = $1
+		WHERE api_key_id = $2 AND owner_id = $3 AND rotated_to IS NOT NULL
+	`
+
+	result, err := s.Conn.ExecContext(ctx, query, time.Now(), id, ownerID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// UpdateAPIKeyNetworkPolicy replaces the IP allow-list and blocked countries of an API key
+func (s *Datasource) UpdateAPIKeyNetworkPolicy(ctx context.Context, id, ownerID string, policy model.NetworkPolicy) error {
+	query := `
+		UPDATE blnk.api_keys
+		SET allowed_cidrs = $1, blocked_countries = $2
+		WHERE api_key_id = $3 AND owner_id = $4
+	`
+
+	result, err := s.Conn.ExecContext(ctx, query, pq.StringArray(policy.AllowedCIDRs), pq.StringArray(policy.BlockedCountries), id, ownerID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// UpdateAPIKeyLedgerScope replaces the ledgers and balance ID prefixes an API key is restricted to
+func (s *Datasource) UpdateAPIKeyLedgerScope(ctx context.Context, id, ownerID string, scope ledgerscope.Scope) error {
+	query := `
+		UPDATE blnk.api_keys
+		SET allowed_ledgers = $1, allowed_balance_prefixes = $2
+		WHERE api_key_id = $3 AND owner_id = $4
+	`
+
+	result, err := s.Conn.ExecContext(ctx, query, pq.StringArray(scope.LedgerIDs), pq.StringArray(scope.BalancePrefixes), id, ownerID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// UpdateAPIKeyFieldCompatibility sets the names renamed JSON fields are emitted under for an API key
+func (s *Datasource) UpdateAPIKeyFieldCompatibility(ctx context.Context, id, ownerID, mode string) error {
+	query := `
+		UPDATE blnk.api_keys
+		SET field_compatibility = $1
+		WHERE api_key_id = $2 AND owner_id = $3
+	`
+
+	result, err := s.Conn.ExecContext(ctx, query, mode, id, ownerID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// RecordAPIKeyAccessDenial saves a request that was rejected by an API key's network policy
+func (s *Datasource) RecordAPIKeyAccessDenial(ctx context.Context, denial *model.APIKeyAccessDenial) error {
+	query := `
+		INSERT INTO blnk.api_key_access_denials (api_key_id, ip_address, country, reason, method, path, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := s.Conn.ExecContext(ctx, query,
+		denial.APIKeyID,
+		denial.IPAddress,
+		denial.Country,
+		denial.Reason,
+		denial.Method,
+		denial.Path,
+		denial.CreatedAt,
+	)
+	return err
+}
+
+// ListAPIKeyAccessDenials lists the most recent rejected requests for an API key owned by ownerID
+func (s *Datasource) ListAPIKeyAccessDenials(ctx context.Context, id, ownerID string, limit int) ([]*model.APIKeyAccessDenial, error) {
+	query := `
+		SELECT d.api_key_id, d.ip_address, COALESCE(d.country, ''), d.reason, d.method, d.path, d.created_at
+		FROM blnk.api_key_access_denials d
+		JOIN blnk.api_keys k ON k.api_key_id = d.api_key_id
+		WHERE d.api_key_id = $1 AND k.owner_id = $2
+		ORDER BY d.created_at DESC
+		LIMIT $3
+	`
+
+	rows, err := s.Conn.QueryContext(ctx, query, id, ownerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	denials := []*model.APIKeyAccessDenial{}
+	for rows.Next() {
+		denial := &model.APIKeyAccessDenial{}
+		if err := rows.Scan(
+			&denial.APIKeyID,
+			&denial.IPAddress,
+			&denial.Country,
+			&denial.Reason,
+			&denial.Method,
+			&denial.Path,
+			&denial.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		denials = append(denials, denial)
+	}
+
+	return denials, rows.Err()
+}
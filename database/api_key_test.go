@@ -0,0 +1,127 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectRotation sets up the queries RotateAPIKey runs for a key whose old row carries the given
+// ledger scope, network policy and field compatibility columns.
+func expectRotation(mock sqlmock.Sqlmock, expiresAt time.Time, ledgers, prefixes, cidrs, countries, fieldCompatibility string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.api_keys")).
+		WithArgs("api_key_1", "owner_1").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "scopes", "expires_at", "rotated_to", "allowed_ledgers", "allowed_balance_prefixes", "allowed_cidrs", "blocked_countries", "field_compatibility"}).
+			AddRow("payouts", "{balances:read}", expiresAt, nil, ledgers, prefixes, cidrs, countries, fieldCompatibility))
+}
+
+func TestRotateAPIKey_KeepsLedgerScope(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	expectRotation(mock, expiresAt, "{ldg_payouts}", "{bln_vip_}", "{}", "{}", "")
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.api_keys")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "payouts", "owner_1", "{\"balances:read\"}", expiresAt, sqlmock.AnyArg(), sqlmock.AnyArg(), false,
+			"{\"ldg_payouts\"}", "{\"bln_vip_\"}", "{}", "{}", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("SET rotated_to = $1")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	apiKey, err := ds.RotateAPIKey(context.Background(), "api_key_1", "owner_1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ldg_payouts"}, apiKey.LedgerScope.LedgerIDs)
+	assert.Equal(t, []string{"bln_vip_"}, apiKey.LedgerScope.BalancePrefixes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateAPIKey_KeepsNetworkPolicy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	expectRotation(mock, expiresAt, "{}", "{}", "{10.1.0.0/16}", "{KP}", "")
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.api_keys")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "payouts", "owner_1", "{\"balances:read\"}", expiresAt, sqlmock.AnyArg(), sqlmock.AnyArg(), false,
+			"{}", "{}", "{\"10.1.0.0/16\"}", "{\"KP\"}", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("SET rotated_to = $1")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	apiKey, err := ds.RotateAPIKey(context.Background(), "api_key_1", "owner_1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.1.0.0/16"}, apiKey.NetworkPolicy.AllowedCIDRs)
+	assert.Equal(t, []string{"KP"}, apiKey.NetworkPolicy.BlockedCountries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateAPIKey_KeepsFieldCompatibility(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	expectRotation(mock, expiresAt, "{}", "{}", "{}", "{}", model.FieldCompatibilityLegacy)
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.api_keys")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "payouts", "owner_1", "{\"balances:read\"}", expiresAt, sqlmock.AnyArg(), sqlmock.AnyArg(), false,
+			"{}", "{}", "{}", "{}", model.FieldCompatibilityLegacy).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("SET rotated_to = $1")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	apiKey, err := ds.RotateAPIKey(context.Background(), "api_key_1", "owner_1", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, model.FieldCompatibilityLegacy, apiKey.FieldCompatibility)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListAPIKeys_AfterCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	createdAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	after := &model.PageCursor{Time: createdAt.Add(time.Hour), ID: "api_key_2"}
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE owner_id = $1 AND (created_at, api_key_id) < ($2, $3) ORDER BY created_at DESC, api_key_id DESC LIMIT $4")).
+		WithArgs("owner_1", after.Time, after.ID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"api_key_id", "key", "name", "owner_id", "scopes", "expires_at", "created_at", "last_used_at", "is_revoked", "revoked_at", "rotated_to", "rotation_expires_at", "allowed_cidrs", "blocked_countries", "allowed_ledgers", "allowed_balance_prefixes", "field_compatibility"}).
+			AddRow("api_key_1", "key_1", "payouts", "owner_1", "{balances:read}", createdAt.Add(24*time.Hour), createdAt, createdAt, false, nil, nil, nil, "{}", "{}", "{}", "{}", ""))
+
+	apiKeys, err := ds.ListAPIKeys(context.Background(), "owner_1", model.Page{Limit: 1, After: after})
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 1)
+	assert.Equal(t, "api_key_1", apiKeys[0].APIKeyID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -25,10 +25,10 @@ import (
 	"strings"
 	"time"
 
-	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 
 	"github.com/blnkfinance/blnk/internal/apierror"
+	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
 	"github.com/blnkfinance/blnk/model"
 )
 
@@ -171,12 +171,13 @@ func scanRow(row *sql.Row, tx *sql.Tx, include []string) (*model.Balance, error)
 // It handles the generation of a unique balance ID, default values for fields, and any necessary error handling.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - balance: A model.Balance object containing the balance information to be created.
 //
 // Returns:
 // - model.Balance: The created balance with its ID and timestamp populated.
 // - error: Returns an APIError in case of failures such as database conflicts or other issues.
-func (d Datasource) CreateBalance(balance model.Balance) (model.Balance, error) {
+func (d Datasource) CreateBalance(ctx context.Context, balance model.Balance) (model.Balance, error) {
 	// Marshal metadata into JSON
 	metaDataJSON, err := json.Marshal(balance.MetaData)
 	if err != nil {
@@ -187,17 +188,6 @@ func (d Datasource) CreateBalance(balance model.Balance) (model.Balance, error)
 	balance.BalanceID = model.GenerateUUIDWithSuffix("bln")
 	balance.CreatedAt = time.Now()
 
-	// Handle nullable fields
-	var identityID interface{} = balance.IdentityID
-	if balance.IdentityID == "" {
-		identityID = nil
-	}
-
-	var indicator interface{} = balance.Indicator
-	if balance.Indicator == "" {
-		indicator = nil
-	}
-
 	// Set default values for balance fields if they are nil
 	if balance.Balance == nil {
 		balance.Balance = big.NewInt(0)
@@ -219,21 +209,17 @@ func (d Datasource) CreateBalance(balance model.Balance) (model.Balance, error)
 	}
 
 	// Insert the balance into the database
-	_, err = d.Conn.Exec(`
-		INSERT INTO blnk.balances (balance_id, balance, credit_balance, debit_balance, currency, currency_multiplier, ledger_id, identity_id, indicator, created_at, meta_data)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,$11)
-	`, balance.BalanceID, balance.Balance.String(), balance.CreditBalance.String(), balance.DebitBalance.String(), balance.Currency, balance.CurrencyMultiplier, balance.LedgerID, identityID, indicator, balance.CreatedAt, &metaDataJSON)
+	err = insertBalance(ctx, d.Conn, &balance, metaDataJSON)
 	if err != nil {
 		// Handle specific PostgreSQL errors (e.g., unique or foreign key violations)
-		pqErr, ok := err.(*pq.Error)
-		if ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				if strings.Contains(pqErr.Message, "unique_indicator_currency") {
+		if pgErr, ok := pgconn.AsError(err); ok {
+			switch pgErr.Code {
+			case pgconn.UniqueViolation:
+				if pgErr.Constraint == "unique_indicator_currency" || strings.Contains(pgErr.Message, "unique_indicator_currency") {
 					return model.Balance{}, nil
 				}
 				return model.Balance{}, apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Balance already exists: %s", balance.BalanceID), err)
-			case "foreign_key_violation":
+			case pgconn.ForeignKeyViolation:
 				return model.Balance{}, apierror.NewAPIError(apierror.ErrBadRequest, "Invalid ledger ID", err)
 			default:
 				return model.Balance{}, apierror.NewAPIError(apierror.ErrInternalServer, "Database error occurred", err)
@@ -247,10 +233,31 @@ func (d Datasource) CreateBalance(balance model.Balance) (model.Balance, error)
 	return balance, nil
 }
 
+// insertBalance inserts a balance whose ID, creation time and amounts are already set.
+func insertBalance(ctx context.Context, db dbExecutor, balance *model.Balance, metaDataJSON []byte) error {
+	// Handle nullable fields
+	var identityID interface{} = balance.IdentityID
+	if balance.IdentityID == "" {
+		identityID = nil
+	}
+
+	var indicator interface{} = balance.Indicator
+	if balance.Indicator == "" {
+		indicator = nil
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO blnk.balances (balance_id, balance, credit_balance, debit_balance, currency, currency_multiplier, ledger_id, identity_id, indicator, created_at, meta_data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,$11)
+	`, balance.BalanceID, balance.Balance.String(), balance.CreditBalance.String(), balance.DebitBalance.String(), balance.Currency, balance.CurrencyMultiplier, balance.LedgerID, identityID, indicator, balance.CreatedAt, &metaDataJSON)
+	return err
+}
+
 // GetBalanceByID retrieves a balance by its ID from the database, along with optional related data such as identity or ledger, based on the `include` parameter.
 // The method starts a transaction, executes the query, and processes the result.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - id: The unique ID of the balance to retrieve.
 // - include: A slice of strings that specifies which related data to include in the result. Possible values include "identity" and "ledger".
 // - withQueued: A boolean that specifies whether to include queued amounts in the result.
@@ -258,9 +265,9 @@ func (d Datasource) CreateBalance(balance model.Balance) (model.Balance, error)
 // Returns:
 // - *model.Balance: A pointer to the retrieved Balance object.
 // - error: Returns an APIError in case of errors such as database failures or if the balance is not found.
-func (d Datasource) GetBalanceByID(id string, include []string, withQueued bool) (*model.Balance, error) {
+func (d Datasource) GetBalanceByID(ctx context.Context, id string, include []string, withQueued bool) (*model.Balance, error) {
 	// Set a context with a 1-minute timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
 	defer cancel()
 
 	// Start a transaction
@@ -279,7 +286,7 @@ func (d Datasource) GetBalanceByID(id string, include []string, withQueued bool)
 	// Prepare and execute the query
 	var queryBuilder strings.Builder
 	query := prepareQueries(queryBuilder, include)
-	row := tx.QueryRow(query, id)
+	row := tx.QueryRowContext(ctx, query, id)
 
 	// Scan the result into a Balance object
 	balance, err := scanRow(row, tx, include)
@@ -295,7 +302,7 @@ func (d Datasource) GetBalanceByID(id string, include []string, withQueued bool)
 
 	// Get queued amounts only if requested
 	if withQueued {
-		queuedDebit, queuedCredit, err := d.GetQueuedAmounts(context.Background(), id)
+		queuedDebit, queuedCredit, err := d.GetQueuedAmounts(ctx, id)
 		if err != nil {
 			return nil, err
 		}
@@ -316,12 +323,13 @@ func (d Datasource) GetBalanceByID(id string, include []string, withQueued bool)
 // This version avoids loading additional related data like identity and ledger.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - id: The ID of the balance to retrieve.
 //
 // Returns:
 // - *model.Balance: A pointer to the retrieved Balance object.
 // - error: Returns an APIError in case of errors such as database failures or if the balance is not found.
-func (d Datasource) GetBalanceByIDLite(id string) (*model.Balance, error) {
+func (d Datasource) GetBalanceByIDLite(ctx context.Context, id string) (*model.Balance, error) {
 	var balance model.Balance
 	// Use string variables instead of int64 to handle any size
 	var balanceValue, creditBalanceValue, debitBalanceValue string
@@ -329,7 +337,7 @@ func (d Datasource) GetBalanceByIDLite(id string) (*model.Balance, error) {
 	var indicator sql.NullString
 
 	// Execute the query
-	row := d.Conn.QueryRow(`
+	row := d.Conn.QueryRowContext(ctx, `
        SELECT balance_id, indicator, currency, currency_multiplier, ledger_id, balance, credit_balance, debit_balance, inflight_balance, inflight_credit_balance, inflight_debit_balance, created_at, version
        FROM blnk.balances
        WHERE balance_id = $1
@@ -392,20 +400,21 @@ func (d Datasource) GetBalanceByIDLite(id string) (*model.Balance, error) {
 // It returns the balance if found, or an error if the balance does not exist.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - indicator: A unique identifier associated with the balance (e.g., an account identifier).
 // - currency: The currency in which the balance is denominated.
 //
 // Returns:
 // - *model.Balance: The retrieved balance object or an empty Balance object if not found.
 // - error: An error if any issues occur during the query execution or data retrieval.
-func (d Datasource) GetBalanceByIndicator(indicator, currency string) (*model.Balance, error) {
+func (d Datasource) GetBalanceByIndicator(ctx context.Context, indicator, currency string) (*model.Balance, error) {
 	var balance model.Balance
 	// Change to string variables to handle large numbers
 	var balanceValue, creditBalanceValue, debitBalanceValue string
 	var inflightBalanceValue, inflightCreditBalanceValue, inflightDebitBalanceValue string
 
 	// Execute query to find the balance with the given indicator and currency
-	row := d.Conn.QueryRow(`
+	row := d.Conn.QueryRowContext(ctx, `
        SELECT balance_id, indicator, currency, currency_multiplier, ledger_id, balance, credit_balance, debit_balance, inflight_balance, inflight_credit_balance, inflight_debit_balance, created_at, version
        FROM blnk.balances
        WHERE indicator = $1 AND currency = $2
@@ -456,26 +465,30 @@ func (d Datasource) GetBalanceByIndicator(indicator, currency string) (*model.Ba
 	return &balance, nil
 }
 
-// GetAllBalances retrieves a limited set of balances from the database, up to 20 records.
+// balanceKeyset is the order balances are listed in, newest first.
+var balanceKeyset = keyset{timeColumn: "created_at", idColumn: "balance_id", descending: true}
+
+// GetAllBalances retrieves a page of balances from the database, newest first. Balances outside the ledger scope
+// of ctx are left out.
 // It processes each balance by scanning the query result, converting numerical fields to big.Int, and parsing metadata from JSON format.
 // The function returns a slice of Balance objects or an error if any issues occur during the database query or data processing.
+// Balances are read from a read replica when one is configured, so a balance written moments ago may not be listed yet.
 //
 // Parameters:
-// - limit: The maximum number of balances to return (e.g., 20).
-// - offset: The offset to start fetching balances from (for pagination).
+// - ctx: The context for the operation.
+// - page: The page of balances to read.
 //
 // Returns:
 // - []model.Balance: A slice of Balance objects containing balance information such as balance amount, credit balance, debit balance, and metadata.
 // - error: An error if any occurs during the query execution, data retrieval, or JSON parsing.
-func (d Datasource) GetAllBalances(limit, offset int) ([]model.Balance, error) {
+func (d Datasource) GetAllBalances(ctx context.Context, page model.Page) ([]model.Balance, error) {
 	var indicator sql.NullString
-	// Execute SQL query to select all balances with a limit of 20 records
-	rows, err := d.Conn.Query(`
+	scope, args := ledgerScopeBalances(ctx, "balance_id", "ledger_id", nil)
+	condition, suffix, args := balanceKeyset.page(page, args)
+	rows, err := d.readQuery(ctx, `
         SELECT balance_id, indicator, balance, credit_balance, debit_balance, currency, currency_multiplier, ledger_id, created_at, meta_data
         FROM blnk.balances
-        ORDER BY created_at DESC
-        LIMIT $1 OFFSET $2
-    `, limit, offset)
+        WHERE `+and(scope, condition)+suffix, args...)
 	if err != nil {
 		return nil, err // Return error if the query fails
 	}
@@ -549,15 +562,16 @@ func (d Datasource) GetAllBalances(limit, offset int) ([]model.Balance, error) {
 // It returns a slice of pointers to Balance objects or an error if any issues occur during the query or data processing.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - sourceId: The ID of the source balance to retrieve.
 // - destinationId: The ID of the destination balance to retrieve.
 //
 // Returns:
 // - []*model.Balance: A slice of pointers to Balance objects containing the source and destination balances with their details such as balance amount, credit balance, debit balance, and metadata.
 // - error: An error if any occurs during the query execution, data retrieval, or JSON parsing.
-func (d Datasource) GetSourceDestination(sourceId, destinationId string) ([]*model.Balance, error) {
+func (d Datasource) GetSourceDestination(ctx context.Context, sourceId, destinationId string) ([]*model.Balance, error) {
 	// Execute SQL query to select balances for source and destination using a stored procedure
-	rows, err := d.Conn.Query(`
+	rows, err := d.Conn.QueryContext(ctx, `
 		SELECT blnk.get_balances_by_id($1,$2)
 	`, sourceId, destinationId)
 	if err != nil {
@@ -708,11 +722,12 @@ func updateBalance(ctx context.Context, tx *sql.Tx, balance *model.Balance) erro
 // It handles both the balance data and the associated metadata.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - balance: A pointer to the balance object containing the updated balance information. This includes fields such as `balance`, `credit_balance`, `debit_balance`, `currency`, `currency_multiplier`, and `meta_data`.
 //
 // Returns:
 // - error: If the update operation encounters an error, such as a database failure or if the balance ID is not found, an `APIError` is returned.
-func (d Datasource) UpdateBalance(balance *model.Balance) error {
+func (d Datasource) UpdateBalance(ctx context.Context, balance *model.Balance) error {
 	// Marshal the MetaData into JSON format
 	metaDataJSON, err := json.Marshal(balance.MetaData)
 	if err != nil {
@@ -720,7 +735,7 @@ func (d Datasource) UpdateBalance(balance *model.Balance) error {
 	}
 
 	// Execute the SQL query to update the balance in the database
-	result, err := d.Conn.Exec(`
+	result, err := d.Conn.ExecContext(ctx, `
 		UPDATE blnk.balances
 		SET balance = $2, credit_balance = $3, debit_balance = $4, currency = $5, currency_multiplier = $6, ledger_id = $7, created_at = $8, meta_data = $9
 		WHERE balance_id = $1
@@ -750,13 +765,14 @@ func (d Datasource) UpdateBalance(balance *model.Balance) error {
 // and inserts the monitor's data into the `blnk.balance_monitors` table.
 //
 // Parameters:
+// - ctx: The context for the operation.
 //   - monitor: A model.BalanceMonitor object containing details of the monitor to be created.
 //     It includes fields like balance ID, field to monitor, operator, value, precision, precise_value, description, callback URL, etc.
 //
 // Returns:
 // - model.BalanceMonitor: The newly created BalanceMonitor object with updated MonitorID and CreatedAt timestamp.
 // - error: If any errors occur during the creation process, an `APIError` is returned.
-func (d Datasource) CreateMonitor(monitor model.BalanceMonitor) (model.BalanceMonitor, error) {
+func (d Datasource) CreateMonitor(ctx context.Context, monitor model.BalanceMonitor) (model.BalanceMonitor, error) {
 	// Generate a unique MonitorID and set the current timestamp for CreatedAt
 	monitor.MonitorID = model.GenerateUUIDWithSuffix("mon")
 	monitor.CreatedAt = time.Now()
@@ -767,20 +783,19 @@ func (d Datasource) CreateMonitor(monitor model.BalanceMonitor) (model.BalanceMo
 	}
 
 	// Insert the monitor data into the balance_monitors table
-	_, err := d.Conn.Exec(`
+	_, err := d.Conn.ExecContext(ctx, `
 		INSERT INTO blnk.balance_monitors (monitor_id, balance_id, field, operator, value, precision, precise_value, description, call_back_url, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`, monitor.MonitorID, monitor.BalanceID, monitor.Condition.Field, monitor.Condition.Operator, monitor.Condition.Value, monitor.Condition.Precision, monitor.Condition.PreciseValue.String(), monitor.Description, monitor.CallBackURL, monitor.CreatedAt)
 	// Handle database errors
 	if err != nil {
-		pqErr, ok := err.(*pq.Error)
-		if ok {
+		if pgErr, ok := pgconn.AsError(err); ok {
 			// Handle unique violation error
-			switch pqErr.Code.Name() {
-			case "unique_violation":
+			switch pgErr.Code {
+			case pgconn.UniqueViolation:
 				return model.BalanceMonitor{}, apierror.NewAPIError(apierror.ErrConflict, "Monitor with this ID already exists", err)
 			// Handle foreign key violation error
-			case "foreign_key_violation":
+			case pgconn.ForeignKeyViolation:
 				return model.BalanceMonitor{}, apierror.NewAPIError(apierror.ErrBadRequest, "Invalid balance ID", err)
 			// Handle other database errors
 			default:
@@ -799,16 +814,17 @@ func (d Datasource) CreateMonitor(monitor model.BalanceMonitor) (model.BalanceMo
 // It queries the `blnk.balance_monitors` table and maps the result into a model.BalanceMonitor object.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - id: The MonitorID of the monitor to retrieve.
 //
 // Returns:
 // - *model.BalanceMonitor: A pointer to the BalanceMonitor object if found.
 // - error: If the monitor is not found or if any errors occur during the query, an `APIError` is returned.
-func (d Datasource) GetMonitorByID(id string) (*model.BalanceMonitor, error) {
+func (d Datasource) GetMonitorByID(ctx context.Context, id string) (*model.BalanceMonitor, error) {
 	var preciseValue int64 // Temporary variable to hold the precise value as int64
 
 	// Query the database to get the monitor details by MonitorID
-	row := d.Conn.QueryRow(`
+	row := d.Conn.QueryRowContext(ctx, `
 		SELECT monitor_id, balance_id, field, operator, value, precision, precise_value, description, call_back_url, created_at
 		FROM blnk.balance_monitors WHERE monitor_id = $1
 	`, id)
@@ -843,9 +859,9 @@ func (d Datasource) GetMonitorByID(id string) (*model.BalanceMonitor, error) {
 // Returns:
 // - []model.BalanceMonitor: A slice of BalanceMonitor objects if the query is successful.
 // - error: If an error occurs during the query or while scanning the result set, an `APIError` is returned.
-func (d Datasource) GetAllMonitors() ([]model.BalanceMonitor, error) {
+func (d Datasource) GetAllMonitors(ctx context.Context) ([]model.BalanceMonitor, error) {
 	// Query the database for all balance monitors
-	rows, err := d.Conn.Query(`
+	rows, err := d.Conn.QueryContext(ctx, `
 		SELECT monitor_id, balance_id, field, operator, value, description, call_back_url, created_at
 		FROM blnk.balance_monitors
 	`)
@@ -891,14 +907,15 @@ func (d Datasource) GetAllMonitors() ([]model.BalanceMonitor, error) {
 // It queries the `blnk.balance_monitors` table to find all monitors linked to the provided `balanceID`.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - balanceID: The ID of the balance for which monitors are being retrieved.
 //
 // Returns:
 // - []model.BalanceMonitor: A slice of BalanceMonitor objects associated with the balance ID.
 // - error: If an error occurs during the query or while scanning the result set, an `APIError` is returned.
-func (d Datasource) GetBalanceMonitors(balanceID string) ([]model.BalanceMonitor, error) {
+func (d Datasource) GetBalanceMonitors(ctx context.Context, balanceID string) ([]model.BalanceMonitor, error) {
 	// Query the database for monitors associated with the given balance ID
-	rows, err := d.Conn.Query(`
+	rows, err := d.Conn.QueryContext(ctx, `
 		SELECT monitor_id, balance_id, field, operator, value, description, call_back_url, created_at, precision, precise_value
 		FROM blnk.balance_monitors WHERE balance_id = $1
 	`, balanceID)
@@ -947,13 +964,14 @@ func (d Datasource) GetBalanceMonitors(balanceID string) ([]model.BalanceMonitor
 // for the monitor identified by `monitor_id`.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - monitor: A pointer to the `BalanceMonitor` object containing the updated values.
 //
 // Returns:
 // - error: If the update fails, an appropriate `APIError` is returned.
-func (d Datasource) UpdateMonitor(monitor *model.BalanceMonitor) error {
+func (d Datasource) UpdateMonitor(ctx context.Context, monitor *model.BalanceMonitor) error {
 	// Execute the SQL update statement, replacing the placeholder values with the monitor's data
-	result, err := d.Conn.Exec(`
+	result, err := d.Conn.ExecContext(ctx, `
 		UPDATE blnk.balance_monitors
 		SET balance_id = $2, field = $3, operator = $4, value = $5, description = $6, call_back_url = $7
 		WHERE monitor_id = $1
@@ -983,13 +1001,14 @@ func (d Datasource) UpdateMonitor(monitor *model.BalanceMonitor) error {
 // It removes the monitor from the `blnk.balance_monitors` table.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - id: The ID of the monitor to be deleted.
 //
 // Returns:
 // - error: If the deletion fails or the monitor is not found, an appropriate `APIError` is returned.
-func (d Datasource) DeleteMonitor(id string) error {
+func (d Datasource) DeleteMonitor(ctx context.Context, id string) error {
 	// Execute the SQL DELETE statement, removing the monitor by its ID
-	result, err := d.Conn.Exec(`
+	result, err := d.Conn.ExecContext(ctx, `
 		DELETE FROM blnk.balance_monitors WHERE monitor_id = $1
 	`, id)
 	// If an error occurred during execution, return an internal server error
@@ -1206,7 +1225,8 @@ func (d Datasource) calculateBalanceFromTransactions(ctx context.Context, tx *sq
 // GetBalanceAtTime retrieves the balance state at a specific point in time.
 // It finds the most recent snapshot before the target time and applies any subsequent
 // transactions to calculate the exact balance state. If fromSource is true, it skips
-// using snapshots and calculates directly from all transactions.
+// using snapshots and calculates directly from all transactions. The calculation runs on a read replica when one
+// is configured.
 //
 // Parameters:
 // - ctx: Context for the database operations
@@ -1228,7 +1248,7 @@ func (d Datasource) GetBalanceAtTime(ctx context.Context, balanceID string, targ
 	}
 
 	// Start transaction
-	tx, err := d.Conn.BeginTx(ctx, &sql.TxOptions{
+	tx, err := d.beginReadTx(ctx, &sql.TxOptions{
 		ReadOnly:  true,
 		Isolation: sql.LevelRepeatableRead,
 	})
@@ -1300,14 +1320,15 @@ func (d Datasource) GetBalanceAtTime(ctx context.Context, balanceID string, targ
 // UpdateBalanceIdentity updates the identity_id of a balance entry in the database.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - balanceID: The unique identifier of the balance whose identity reference is to be updated.
 // - identityID: The identity ID to be associated with the balance.
 //
 // Returns:
 // - error: An error is returned if the balance or identity does not exist or the database operation fails.
-func (d Datasource) UpdateBalanceIdentity(balanceID string, identityID string) error {
+func (d Datasource) UpdateBalanceIdentity(ctx context.Context, balanceID string, identityID string) error {
 	// Execute the SQL update statement to change the identity_id for the specified balance.
-	result, err := d.Conn.Exec(`
+	result, err := d.Conn.ExecContext(ctx, `
 		UPDATE blnk.balances
 		SET identity_id = $2
 		WHERE balance_id = $1
@@ -1331,3 +1352,86 @@ func (d Datasource) UpdateBalanceIdentity(balanceID string, identityID string) e
 
 	return nil
 }
+
+// GetBalancesByIdentity retrieves all balances that belong to an identity, oldest first.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - identityID: The ID of the identity.
+//
+// Returns:
+// - []model.Balance: The identity's balances.
+// - error: An error if the query fails.
+func (d Datasource) GetBalancesByIdentity(ctx context.Context, identityID string) ([]model.Balance, error) {
+	return d.getBalancesWhere(ctx, "identity_id", identityID)
+}
+
+// GetBalancesByLedger retrieves all balances of a ledger, oldest first.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - ledgerID: The ID of the ledger.
+//
+// Returns:
+// - []model.Balance: The ledger's balances.
+// - error: An error if the query fails.
+func (d Datasource) GetBalancesByLedger(ctx context.Context, ledgerID string) ([]model.Balance, error) {
+	return d.getBalancesWhere(ctx, "ledger_id", ledgerID)
+}
+
+// getBalancesWhere retrieves the balances whose column equals a value, oldest first. Balances outside the ledger
+// scope of ctx are left out.
+func (d Datasource) getBalancesWhere(ctx context.Context, column, value string) ([]model.Balance, error) {
+	scope, args := ledgerScopeBalances(ctx, "balance_id", "ledger_id", []interface{}{value})
+	rows, err := d.Conn.QueryContext(ctx, fmt.Sprintf(`
+		SELECT balance_id, indicator, balance, credit_balance, debit_balance, currency, currency_multiplier, ledger_id, identity_id, created_at, meta_data
+		FROM blnk.balances
+		WHERE %s
+		ORDER BY created_at ASC
+	`, and(column+" = $1", scope)), args...)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve balances", err)
+	}
+	defer rows.Close()
+
+	balances := []model.Balance{}
+	for rows.Next() {
+		balance := model.Balance{}
+		var indicator, identityID sql.NullString
+		var balanceValue, creditBalanceValue, debitBalanceValue string
+		var metaDataJSON []byte
+
+		err = rows.Scan(
+			&balance.BalanceID,
+			&indicator,
+			&balanceValue,
+			&creditBalanceValue,
+			&debitBalanceValue,
+			&balance.Currency,
+			&balance.CurrencyMultiplier,
+			&balance.LedgerID,
+			&identityID,
+			&balance.CreatedAt,
+			&metaDataJSON,
+		)
+		if err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan balance data", err)
+		}
+
+		balance.Indicator = indicator.String
+		balance.IdentityID = identityID.String
+		balance.Balance, _ = new(big.Int).SetString(balanceValue, 10)
+		balance.CreditBalance, _ = new(big.Int).SetString(creditBalanceValue, 10)
+		balance.DebitBalance, _ = new(big.Int).SetString(debitBalanceValue, 10)
+
+		if err = json.Unmarshal(metaDataJSON, &balance.MetaData); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal metadata", err)
+		}
+		balances = append(balances, balance)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over balances", err)
+	}
+	return balances, nil
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const balanceAliasColumns = `alias, balance_id, COALESCE(tenant_id, ''), created_at, updated_at`
+
+// CreateBalanceAlias saves an alias of a balance in the tenant of the balance, which is set on the alias.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - alias: The alias to save.
+// Returns:
+// - An error if the balance does not exist, the alias is used by a balance indicator or another balance of the
+// tenant, or the alias cannot be saved.
+func (d Datasource) CreateBalanceAlias(ctx context.Context, alias *model.BalanceAlias) error {
+	ctx, span := otel.Tracer("balance_alias.database").Start(ctx, "Creating balance alias")
+	defer span.End()
+
+	var indicatorExists bool
+	if err := d.Conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM blnk.balances WHERE indicator = $1)`, alias.Alias).Scan(&indicatorExists); err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create balance alias", err)
+	}
+	if indicatorExists {
+		return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Alias '%s' is already used as a balance indicator", alias.Alias), nil)
+	}
+
+	err := d.Conn.QueryRowContext(ctx, `
+		INSERT INTO blnk.balance_aliases (alias, balance_id, tenant_id, created_at, updated_at)
+		SELECT $1, b.balance_id, b.tenant_id, $3, $3
+		FROM blnk.balances b
+		WHERE b.balance_id = $2
+		RETURNING COALESCE(tenant_id, '')
+	`, alias.Alias, alias.BalanceID, alias.CreatedAt).Scan(&alias.TenantID)
+	if err == sql.ErrNoRows {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Balance with ID '%s' not found", alias.BalanceID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		if pgconn.HasCode(err, pgconn.UniqueViolation) {
+			return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Alias '%s' is already in use", alias.Alias), err)
+		}
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create balance alias", err)
+	}
+	return nil
+}
+
+// GetBalanceAlias retrieves an alias of a tenant. Without a tenant, the alias of any tenant is found, unless
+// several tenants use it.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - tenantID: The tenant the alias belongs to, or empty for any tenant.
+// - alias: The alias.
+// Returns:
+// - The alias, or an error if it is not found, is ambiguous or the query fails.
+func (d Datasource) GetBalanceAlias(ctx context.Context, tenantID, alias string) (*model.BalanceAlias, error) {
+	ctx, span := otel.Tracer("balance_alias.database").Start(ctx, "Getting balance alias")
+	defer span.End()
+
+	aliases, err := d.queryBalanceAliases(ctx, `WHERE alias = $1 AND ($2 = '' OR tenant_id = $2) LIMIT 2`, alias, tenantID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	switch len(aliases) {
+	case 0:
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Balance alias '%s' not found", alias), nil)
+	case 1:
+		return aliases[0], nil
+	default:
+		return nil, apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Balance alias '%s' is used by more than one tenant", alias), nil)
+	}
+}
+
+// ListBalanceAliases retrieves the aliases of a tenant, or of every tenant without one, in alias order.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - tenantID: The tenant the aliases belong to, or empty for every tenant.
+// - balanceID: The balance to list the aliases of, or empty for every balance.
+// Returns:
+// - The aliases, or an error if the query fails.
+func (d Datasource) ListBalanceAliases(ctx context.Context, tenantID, balanceID string) ([]*model.BalanceAlias, error) {
+	ctx, span := otel.Tracer("balance_alias.database").Start(ctx, "Listing balance aliases")
+	defer span.End()
+
+	aliases, err := d.queryBalanceAliases(ctx, `WHERE ($1 = '' OR tenant_id = $1) AND ($2 = '' OR balance_id = $2) ORDER BY alias ASC`, tenantID, balanceID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// UpdateBalanceAlias points an alias at another balance of its tenant.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - alias: The alias, with its tenant and the balance to point it at.
+// Returns:
+// - An error if the alias or a balance of its tenant with the ID is not found, or the update fails.
+func (d Datasource) UpdateBalanceAlias(ctx context.Context, alias *model.BalanceAlias) error {
+	ctx, span := otel.Tracer("balance_alias.database").Start(ctx, "Updating balance alias")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.balance_aliases
+		SET balance_id = $3, updated_at = $4
+		WHERE alias = $1 AND COALESCE(tenant_id, '') = $2
+			AND EXISTS (SELECT 1 FROM blnk.balances b WHERE b.balance_id = $3 AND COALESCE(b.tenant_id, '') = $2)
+	`, alias.Alias, alias.TenantID, alias.BalanceID, alias.UpdatedAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update balance alias", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Balance alias '%s' or balance with ID '%s' not found", alias.Alias, alias.BalanceID), nil)
+	}
+	return nil
+}
+
+// DeleteBalanceAlias removes an alias of a tenant.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - tenantID: The tenant the alias belongs to.
+// - alias: The alias.
+// Returns:
+// - An error if the alias is not found or cannot be deleted.
+func (d Datasource) DeleteBalanceAlias(ctx context.Context, tenantID, alias string) error {
+	ctx, span := otel.Tracer("balance_alias.database").Start(ctx, "Deleting balance alias")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `DELETE FROM blnk.balance_aliases WHERE alias = $1 AND COALESCE(tenant_id, '') = $2`, alias, tenantID)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to delete balance alias", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Balance alias '%s' not found", alias), nil)
+	}
+	return nil
+}
+
+// queryBalanceAliases retrieves the aliases matching a condition.
+func (d Datasource) queryBalanceAliases(ctx context.Context, condition string, args ...interface{}) ([]*model.BalanceAlias, error) {
+	rows, err := d.Conn.QueryContext(ctx, `SELECT `+balanceAliasColumns+` FROM blnk.balance_aliases `+condition, args...)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve balance aliases", err)
+	}
+	defer rows.Close()
+
+	aliases := []*model.BalanceAlias{}
+	for rows.Next() {
+		alias := &model.BalanceAlias{}
+		if err := rows.Scan(&alias.Alias, &alias.BalanceID, &alias.TenantID, &alias.CreatedAt, &alias.UpdatedAt); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan balance alias", err)
+		}
+		aliases = append(aliases, alias)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over balance aliases", err)
+	}
+	return aliases, nil
+}
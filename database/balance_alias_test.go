@@ -0,0 +1,115 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBalanceAlias(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	indicatorCheck := regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM blnk.balances WHERE indicator = $1)")
+	insert := regexp.QuoteMeta("INSERT INTO blnk.balance_aliases")
+
+	mock.ExpectQuery(indicatorCheck).WithArgs("@main_usd_float").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(insert).WithArgs("@main_usd_float", "bln_1", now).WillReturnRows(sqlmock.NewRows([]string{"tenant_id"}).AddRow("owner_1"))
+	alias := &model.BalanceAlias{Alias: "@main_usd_float", BalanceID: "bln_1", CreatedAt: now}
+	require.NoError(t, ds.CreateBalanceAlias(context.Background(), alias))
+	assert.Equal(t, "owner_1", alias.TenantID)
+
+	mock.ExpectQuery(indicatorCheck).WithArgs("@World").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	err = ds.CreateBalanceAlias(context.Background(), &model.BalanceAlias{Alias: "@World", BalanceID: "bln_1", CreatedAt: now})
+	assert.Equal(t, apierror.ErrConflict, err.(apierror.APIError).Code, "indicators keep their names")
+
+	mock.ExpectQuery(indicatorCheck).WithArgs("@main_usd_float").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(insert).WithArgs("@main_usd_float", "bln_2", now).WillReturnError(&pq.Error{Code: "23505"})
+	err = ds.CreateBalanceAlias(context.Background(), &model.BalanceAlias{Alias: "@main_usd_float", BalanceID: "bln_2", CreatedAt: now})
+	assert.Equal(t, apierror.ErrConflict, err.(apierror.APIError).Code)
+
+	mock.ExpectQuery(indicatorCheck).WithArgs("@missing").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(insert).WithArgs("@missing", "bln_404", now).WillReturnRows(sqlmock.NewRows([]string{"tenant_id"}))
+	err = ds.CreateBalanceAlias(context.Background(), &model.BalanceAlias{Alias: "@missing", BalanceID: "bln_404", CreatedAt: now})
+	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetBalanceAlias(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	columns := []string{"alias", "balance_id", "tenant_id", "created_at", "updated_at"}
+	query := regexp.QuoteMeta("FROM blnk.balance_aliases WHERE alias = $1 AND ($2 = '' OR tenant_id = $2) LIMIT 2")
+
+	mock.ExpectQuery(query).WithArgs("@float", "owner_1").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("@float", "bln_1", "owner_1", now, now))
+	alias, err := ds.GetBalanceAlias(context.Background(), "owner_1", "@float")
+	require.NoError(t, err)
+	assert.Equal(t, "bln_1", alias.BalanceID)
+
+	mock.ExpectQuery(query).WithArgs("@float", "").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("@float", "bln_1", "owner_1", now, now).AddRow("@float", "bln_9", "owner_2", now, now))
+	_, err = ds.GetBalanceAlias(context.Background(), "", "@float")
+	assert.Equal(t, apierror.ErrConflict, err.(apierror.APIError).Code, "the alias is ambiguous without a tenant")
+
+	mock.ExpectQuery(query).WithArgs("@World", "owner_1").WillReturnRows(sqlmock.NewRows(columns))
+	_, err = ds.GetBalanceAlias(context.Background(), "owner_1", "@World")
+	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateAndDeleteBalanceAlias(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.balance_aliases")).
+		WithArgs("@float", "owner_1", "bln_2", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.balance_aliases")).
+		WithArgs("@float", "owner_1", "bln_other_tenant", now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM blnk.balance_aliases WHERE alias = $1 AND COALESCE(tenant_id, '') = $2")).
+		WithArgs("@float", "owner_1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, ds.UpdateBalanceAlias(context.Background(), &model.BalanceAlias{Alias: "@float", TenantID: "owner_1", BalanceID: "bln_2", UpdatedAt: now}))
+	err = ds.UpdateBalanceAlias(context.Background(), &model.BalanceAlias{Alias: "@float", TenantID: "owner_1", BalanceID: "bln_other_tenant", UpdatedAt: now})
+	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
+	err = ds.DeleteBalanceAlias(context.Background(), "owner_1", "@float")
+	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const balanceNotificationPreferenceColumns = `balance_id, mode, min_debit_amount, min_credit_amount, created_at, updated_at`
+
+// UpsertBalanceNotificationPreference saves the notification preference of a balance, replacing any existing one.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - preference: The preference to store.
+// Returns:
+// - An error if the preference could not be saved.
+func (d Datasource) UpsertBalanceNotificationPreference(ctx context.Context, preference *model.BalanceNotificationPreference) error {
+	ctx, span := otel.Tracer("balance_notification.database").Start(ctx, "Saving balance notification preference")
+	defer span.End()
+
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.balance_notification_preferences (`+balanceNotificationPreferenceColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (balance_id) DO UPDATE SET
+			mode = EXCLUDED.mode,
+			min_debit_amount = EXCLUDED.min_debit_amount,
+			min_credit_amount = EXCLUDED.min_credit_amount,
+			updated_at = EXCLUDED.updated_at
+	`,
+		preference.BalanceID, preference.Mode, nullableAmount(preference.MinDebitAmount), nullableAmount(preference.MinCreditAmount),
+		preference.CreatedAt, preference.UpdatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to save balance notification preference", err)
+	}
+	return nil
+}
+
+// GetBalanceNotificationPreference retrieves the notification preference of a balance.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - balanceID: The ID of the balance.
+// Returns:
+// - The preference, or an error if the balance has none.
+func (d Datasource) GetBalanceNotificationPreference(ctx context.Context, balanceID string) (*model.BalanceNotificationPreference, error) {
+	ctx, span := otel.Tracer("balance_notification.database").Start(ctx, "Fetching balance notification preference")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `SELECT `+balanceNotificationPreferenceColumns+` FROM blnk.balance_notification_preferences WHERE balance_id = $1`, balanceID)
+
+	preference, err := scanBalanceNotificationPreference(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Notification preference for balance '%s' not found", balanceID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve balance notification preference", err)
+	}
+	return preference, nil
+}
+
+// ListBalanceNotificationPreferences retrieves the notification preferences of all balances.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// Returns:
+// - The preferences, or an error if the query fails.
+func (d Datasource) ListBalanceNotificationPreferences(ctx context.Context) ([]*model.BalanceNotificationPreference, error) {
+	ctx, span := otel.Tracer("balance_notification.database").Start(ctx, "Listing balance notification preferences")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `SELECT `+balanceNotificationPreferenceColumns+` FROM blnk.balance_notification_preferences ORDER BY balance_id`)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve balance notification preferences", err)
+	}
+	defer rows.Close()
+
+	preferences := []*model.BalanceNotificationPreference{}
+	for rows.Next() {
+		preference, err := scanBalanceNotificationPreference(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan balance notification preference", err)
+		}
+		preferences = append(preferences, preference)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over balance notification preferences", err)
+	}
+	return preferences, nil
+}
+
+// DeleteBalanceNotificationPreference removes the notification preference of a balance.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - balanceID: The ID of the balance.
+// Returns:
+// - An error if the balance has no preference or it could not be removed.
+func (d Datasource) DeleteBalanceNotificationPreference(ctx context.Context, balanceID string) error {
+	ctx, span := otel.Tracer("balance_notification.database").Start(ctx, "Deleting balance notification preference")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `DELETE FROM blnk.balance_notification_preferences WHERE balance_id = $1`, balanceID)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to delete balance notification preference", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to delete balance notification preference", err)
+	}
+	if affected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Notification preference for balance '%s' not found", balanceID), nil)
+	}
+	return nil
+}
+
+func scanBalanceNotificationPreference(row rowScanner) (*model.BalanceNotificationPreference, error) {
+	preference := &model.BalanceNotificationPreference{}
+	var minDebit, minCredit sql.NullString
+	err := row.Scan(&preference.BalanceID, &preference.Mode, &minDebit, &minCredit, &preference.CreatedAt, &preference.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if minDebit.Valid {
+		preference.MinDebitAmount, _ = new(big.Int).SetString(minDebit.String, 10)
+	}
+	if minCredit.Valid {
+		preference.MinCreditAmount, _ = new(big.Int).SetString(minCredit.String, 10)
+	}
+	return preference, nil
+}
+
+// nullableAmount converts an optional amount to a NUMERIC parameter.
+func nullableAmount(amount *big.Int) interface{} {
+	if amount == nil {
+		return nil
+	}
+	return amount.String()
+}
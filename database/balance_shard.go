@@ -0,0 +1,119 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+const balanceShardingColumns = `balance_id, strategy, shard_ids, created_at, updated_at`
+
+// UpsertBalanceSharding saves the sharding of a balance, replacing any existing one.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - sharding: The sharding to store.
+// Returns:
+// - An error if the sharding could not be saved.
+func (d Datasource) UpsertBalanceSharding(ctx context.Context, sharding *model.BalanceSharding) error {
+	ctx, span := otel.Tracer("balance_shard.database").Start(ctx, "Saving balance sharding")
+	defer span.End()
+
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.balance_shardings (`+balanceShardingColumns+`)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (balance_id) DO UPDATE SET
+			strategy = EXCLUDED.strategy,
+			shard_ids = EXCLUDED.shard_ids,
+			updated_at = EXCLUDED.updated_at
+	`, sharding.BalanceID, sharding.Strategy, pq.StringArray(sharding.ShardIDs), sharding.CreatedAt, sharding.UpdatedAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to save balance sharding", err)
+	}
+	return nil
+}
+
+// GetBalanceSharding retrieves the sharding of a balance.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - balanceID: The ID of the sharded balance.
+// Returns:
+// - The sharding, or an error if the balance is not sharded.
+func (d Datasource) GetBalanceSharding(ctx context.Context, balanceID string) (*model.BalanceSharding, error) {
+	ctx, span := otel.Tracer("balance_shard.database").Start(ctx, "Fetching balance sharding")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `SELECT `+balanceShardingColumns+` FROM blnk.balance_shardings WHERE balance_id = $1`, balanceID)
+
+	sharding, err := scanBalanceSharding(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Sharding for balance '%s' not found", balanceID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve balance sharding", err)
+	}
+	return sharding, nil
+}
+
+// ListBalanceShardings retrieves the shardings of all sharded balances.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// Returns:
+// - The shardings, or an error if the query fails.
+func (d Datasource) ListBalanceShardings(ctx context.Context) ([]*model.BalanceSharding, error) {
+	ctx, span := otel.Tracer("balance_shard.database").Start(ctx, "Listing balance shardings")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `SELECT `+balanceShardingColumns+` FROM blnk.balance_shardings`)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve balance shardings", err)
+	}
+	defer rows.Close()
+
+	shardings := []*model.BalanceSharding{}
+	for rows.Next() {
+		sharding, err := scanBalanceSharding(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan balance sharding", err)
+		}
+		shardings = append(shardings, sharding)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over balance shardings", err)
+	}
+	return shardings, nil
+}
+
+func scanBalanceSharding(row rowScanner) (*model.BalanceSharding, error) {
+	sharding := &model.BalanceSharding{}
+	var shardIDs pq.StringArray
+	if err := row.Scan(&sharding.BalanceID, &sharding.Strategy, &shardIDs, &sharding.CreatedAt, &sharding.UpdatedAt); err != nil {
+		return nil, err
+	}
+	sharding.ShardIDs = []string(shardIDs)
+	return sharding, nil
+}
@@ -60,7 +60,7 @@ func TestCreateBalance_Success(t *testing.T) {
 		WithArgs(sqlmock.AnyArg(), balance.Balance.String(), balance.CreditBalance.String(), balance.DebitBalance.String(), balance.Currency, balance.CurrencyMultiplier, balance.LedgerID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), metaDataJSON).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	createdBalance, err := ds.CreateBalance(balance)
+	createdBalance, err := ds.CreateBalance(context.Background(), balance)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, createdBalance.BalanceID)
 	assert.WithinDuration(t, time.Now(), createdBalance.CreatedAt, time.Second)
@@ -92,7 +92,7 @@ func TestCreateBalance_UniqueViolation(t *testing.T) {
 		WithArgs(sqlmock.AnyArg(), balance.Balance.String(), balance.CreditBalance.String(), balance.DebitBalance.String(), balance.Currency, balance.CurrencyMultiplier, balance.LedgerID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), metaDataJSON).
 		WillReturnError(&pq.Error{Code: "23505", Message: "unique_violation"})
 
-	_, err = ds.CreateBalance(balance)
+	_, err = ds.CreateBalance(context.Background(), balance)
 	assert.Error(t, err)
 	apiErr, ok := err.(apierror.APIError)
 	assert.True(t, ok)
@@ -142,7 +142,7 @@ func TestGetBalanceByID_Success(t *testing.T) {
 	// Mock the transaction commit call
 	mock.ExpectCommit()
 
-	retrievedBalance, err := ds.GetBalanceByID("bln1", []string{}, false)
+	retrievedBalance, err := ds.GetBalanceByID(context.Background(), "bln1", []string{}, false)
 	assert.NoError(t, err)
 	assert.Equal(t, balance.BalanceID, retrievedBalance.BalanceID)
 
@@ -164,7 +164,7 @@ func TestGetBalanceByID_NotFound(t *testing.T) {
 		WithArgs("bln1").
 		WillReturnError(sql.ErrNoRows)
 
-	_, err = ds.GetBalanceByID("bln1", []string{}, false)
+	_, err = ds.GetBalanceByID(context.Background(), "bln1", []string{}, false)
 	assert.Error(t, err)
 	apiErr, ok := err.(apierror.APIError)
 	assert.True(t, ok)
@@ -514,7 +514,7 @@ func TestGetBalanceByID_WithQueuedTransactions(t *testing.T) {
 	mock.ExpectCommit()
 
 	// Execute the function
-	retrievedBalance, err := ds.GetBalanceByID("bln1", []string{}, true)
+	retrievedBalance, err := ds.GetBalanceByID(context.Background(), "bln1", []string{}, true)
 	assert.NoError(t, err)
 	assert.Equal(t, balance.BalanceID, retrievedBalance.BalanceID)
 
@@ -582,7 +582,7 @@ func TestGetBalanceByID_WithoutQueuedTransactions(t *testing.T) {
 	mock.ExpectCommit()
 
 	// Execute GetBalanceByID with withQueued=false
-	retrievedBalance, err := ds.GetBalanceByID("bln1", []string{}, false)
+	retrievedBalance, err := ds.GetBalanceByID(context.Background(), "bln1", []string{}, false)
 	assert.NoError(t, err)
 	assert.Equal(t, balance.BalanceID, retrievedBalance.BalanceID)
 
@@ -670,7 +670,7 @@ func TestGetBalanceByID_WithQueuedTransactions_HasAppliedChild(t *testing.T) {
 	mock.ExpectCommit()
 
 	// Execute the function
-	retrievedBalance, err := ds.GetBalanceByID("bln1", []string{}, true)
+	retrievedBalance, err := ds.GetBalanceByID(context.Background(), "bln1", []string{}, true)
 	assert.NoError(t, err)
 	assert.Equal(t, balance.BalanceID, retrievedBalance.BalanceID)
 
@@ -682,3 +682,26 @@ func TestGetBalanceByID_WithQueuedTransactions_HasAppliedChild(t *testing.T) {
 	err = mock.ExpectationsWereMet()
 	assert.NoError(t, err)
 }
+
+func TestGetBalancesByLedger(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.balances\n\t\tWHERE ledger_id = $1\n\t\tORDER BY created_at ASC")).
+		WithArgs("ldg_1").
+		WillReturnRows(sqlmock.NewRows([]string{"balance_id", "indicator", "balance", "credit_balance", "debit_balance", "currency", "currency_multiplier", "ledger_id", "identity_id", "created_at", "meta_data"}).
+			AddRow("bln_1", "@fees", "500", "500", "0", "USD", 100, "ldg_1", nil, time.Now(), []byte(`{"team":"payments"}`)).
+			AddRow("bln_2", nil, "0", "0", "0", "EUR", 100, "ldg_1", "idt_1", time.Now(), []byte(`{}`)))
+
+	balances, err := ds.GetBalancesByLedger(context.Background(), "ldg_1")
+	assert.NoError(t, err)
+	assert.Len(t, balances, 2)
+	assert.Equal(t, "@fees", balances[0].Indicator)
+	assert.Empty(t, balances[0].IdentityID, "balances without an identity are read")
+	assert.Equal(t, big.NewInt(500), balances[0].Balance)
+	assert.Equal(t, "idt_1", balances[1].IdentityID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const cardAuthorizationColumns = `authorization_id, scheme, reference, source, destination, currency, precision, authorized_amount, reversed_amount, cleared_amount, status, holds, events, meta_data, expires_at, created_at, updated_at`
+
+// CreateCardAuthorization saves a new card authorization.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - auth: The authorization to store.
+// Returns:
+// - An error if the authorization could not be saved, including when its reference was already used.
+func (d Datasource) CreateCardAuthorization(ctx context.Context, auth *model.CardAuthorization) error {
+	ctx, span := otel.Tracer("card_authorization.database").Start(ctx, "Creating card authorization")
+	defer span.End()
+
+	holds, events, metaData, err := encodeCardAuthorization(auth)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInvalidInput, "Failed to encode card authorization", err)
+	}
+
+	_, err = d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.card_authorizations (`+cardAuthorizationColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	`,
+		auth.AuthorizationID, auth.Scheme, auth.Reference, auth.Source, auth.Destination, auth.Currency, auth.Precision,
+		auth.AuthorizedAmount.String(), auth.ReversedAmount.String(), auth.ClearedAmount.String(), auth.Status,
+		holds, events, metaData, auth.ExpiresAt, auth.CreatedAt, auth.UpdatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		if pgconn.HasCode(err, pgconn.UniqueViolation) {
+			return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Card authorization with reference '%s' already exists", auth.Reference), err)
+		}
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create card authorization", err)
+	}
+	return nil
+}
+
+// GetCardAuthorization retrieves a card authorization by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - authorizationID: The ID of the authorization.
+// Returns:
+// - The authorization, or an error if it does not exist.
+func (d Datasource) GetCardAuthorization(ctx context.Context, authorizationID string) (*model.CardAuthorization, error) {
+	ctx, span := otel.Tracer("card_authorization.database").Start(ctx, "Fetching card authorization")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `SELECT `+cardAuthorizationColumns+` FROM blnk.card_authorizations WHERE authorization_id = $1`, authorizationID)
+
+	auth, err := scanCardAuthorization(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Card authorization with ID '%s' not found", authorizationID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve card authorization", err)
+	}
+	return auth, nil
+}
+
+// UpdateCardAuthorization saves the amounts, status, holds and events of a card authorization.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - auth: The authorization to update.
+// Returns:
+// - An error if the authorization could not be updated.
+func (d Datasource) UpdateCardAuthorization(ctx context.Context, auth *model.CardAuthorization) error {
+	ctx, span := otel.Tracer("card_authorization.database").Start(ctx, "Updating card authorization")
+	defer span.End()
+
+	holds, events, metaData, err := encodeCardAuthorization(auth)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInvalidInput, "Failed to encode card authorization", err)
+	}
+
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.card_authorizations
+		SET authorized_amount = $2, reversed_amount = $3, cleared_amount = $4, status = $5,
+			holds = $6, events = $7, meta_data = $8, updated_at = $9
+		WHERE authorization_id = $1
+	`,
+		auth.AuthorizationID, auth.AuthorizedAmount.String(), auth.ReversedAmount.String(), auth.ClearedAmount.String(),
+		auth.Status, holds, events, metaData, auth.UpdatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update card authorization", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Card authorization with ID '%s' not found", auth.AuthorizationID), nil)
+	}
+	return nil
+}
+
+// GetExpiredCardAuthorizations retrieves authorizations that are still authorized after their expiry.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - now: Authorizations expiring before this time are returned.
+// - limit: The maximum number of authorizations to return.
+// Returns:
+// - The authorizations, or an error if the query fails.
+func (d Datasource) GetExpiredCardAuthorizations(ctx context.Context, now time.Time, limit int) ([]*model.CardAuthorization, error) {
+	ctx, span := otel.Tracer("card_authorization.database").Start(ctx, "Fetching expired card authorizations")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+cardAuthorizationColumns+`
+		FROM blnk.card_authorizations
+		WHERE status = $1 AND expires_at <= $2
+		ORDER BY expires_at ASC
+		LIMIT $3
+	`, model.CardAuthorizationAuthorized, now, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve expired card authorizations", err)
+	}
+	defer rows.Close()
+
+	auths := []*model.CardAuthorization{}
+	for rows.Next() {
+		auth, err := scanCardAuthorization(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan card authorization", err)
+		}
+		auths = append(auths, auth)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over card authorizations", err)
+	}
+	return auths, nil
+}
+
+func encodeCardAuthorization(auth *model.CardAuthorization) (holds, events, metaData []byte, err error) {
+	if holds, err = json.Marshal(auth.Holds); err != nil {
+		return nil, nil, nil, err
+	}
+	if events, err = json.Marshal(auth.Events); err != nil {
+		return nil, nil, nil, err
+	}
+	if metaData, err = json.Marshal(auth.MetaData); err != nil {
+		return nil, nil, nil, err
+	}
+	return holds, events, metaData, nil
+}
+
+func scanCardAuthorization(row rowScanner) (*model.CardAuthorization, error) {
+	auth := &model.CardAuthorization{}
+	var authorized, reversed, cleared string
+	var holds, events, metaData []byte
+	err := row.Scan(
+		&auth.AuthorizationID, &auth.Scheme, &auth.Reference, &auth.Source, &auth.Destination, &auth.Currency, &auth.Precision,
+		&authorized, &reversed, &cleared, &auth.Status, &holds, &events, &metaData,
+		&auth.ExpiresAt, &auth.CreatedAt, &auth.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	auth.AuthorizedAmount, _ = new(big.Int).SetString(authorized, 10)
+	auth.ReversedAmount, _ = new(big.Int).SetString(reversed, 10)
+	auth.ClearedAmount, _ = new(big.Int).SetString(cleared, 10)
+	if err := json.Unmarshal(holds, &auth.Holds); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(events, &auth.Events); err != nil {
+		return nil, err
+	}
+	if len(metaData) > 0 {
+		if err := json.Unmarshal(metaData, &auth.MetaData); err != nil {
+			return nil, err
+		}
+	}
+	return auth, nil
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const transactionChallengeColumns = `challenge_id, transaction_id, reference, rule, status, transaction, trail, expires_at, created_at, resolved_at`
+
+// CreateTransactionChallenge saves a new transaction challenge.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - challenge: The challenge to store, with the transaction it holds back.
+// Returns:
+// - An error if the challenge could not be saved, including when its reference is already held back.
+func (d Datasource) CreateTransactionChallenge(ctx context.Context, challenge *model.TransactionChallenge) error {
+	ctx, span := otel.Tracer("challenge.database").Start(ctx, "Creating transaction challenge")
+	defer span.End()
+
+	transaction, err := json.Marshal(challenge.Transaction)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal challenged transaction", err)
+	}
+	trail, err := json.Marshal(challenge.Trail)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal challenge trail", err)
+	}
+
+	_, err = d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.transaction_challenges (`+transactionChallengeColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`,
+		challenge.ChallengeID, challenge.TransactionID, challenge.Reference, challenge.Rule, challenge.Status,
+		transaction, trail, challenge.ExpiresAt, challenge.CreatedAt, challenge.ResolvedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create transaction challenge", err)
+	}
+	return nil
+}
+
+// GetTransactionChallenge retrieves a transaction challenge by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - challengeID: The ID of the challenge.
+// Returns:
+// - The challenge, or an error if it does not exist.
+func (d Datasource) GetTransactionChallenge(ctx context.Context, challengeID string) (*model.TransactionChallenge, error) {
+	ctx, span := otel.Tracer("challenge.database").Start(ctx, "Fetching transaction challenge")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `SELECT `+transactionChallengeColumns+` FROM blnk.transaction_challenges WHERE challenge_id = $1`, challengeID)
+
+	challenge, err := scanTransactionChallenge(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Transaction challenge with ID '%s' not found", challengeID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve transaction challenge", err)
+	}
+	return challenge, nil
+}
+
+// transactionChallengeKeyset is the order transaction challenges are listed in, newest first.
+var transactionChallengeKeyset = keyset{timeColumn: "created_at", idColumn: "challenge_id", descending: true}
+
+// ListTransactionChallenges retrieves transaction challenges, newest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - status: Only challenges with this status are returned, or all of them when empty.
+// - page: The page of challenges to read.
+// Returns:
+// - The challenges, or an error if the query fails.
+func (d Datasource) ListTransactionChallenges(ctx context.Context, status string, page model.Page) ([]*model.TransactionChallenge, error) {
+	ctx, span := otel.Tracer("challenge.database").Start(ctx, "Listing transaction challenges")
+	defer span.End()
+
+	condition, suffix, args := transactionChallengeKeyset.page(page, []interface{}{status})
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+transactionChallengeColumns+` FROM blnk.transaction_challenges
+		WHERE ($1 = '' OR status = $1) AND `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve transaction challenges", err)
+	}
+	return collectTransactionChallenges(rows)
+}
+
+// GetExpiredTransactionChallenges retrieves pending challenges that timed out before a time, oldest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - now: Challenges that expired before this time are returned.
+// - limit: The maximum number of challenges to return.
+// Returns:
+// - The challenges, or an error if the query fails.
+func (d Datasource) GetExpiredTransactionChallenges(ctx context.Context, now time.Time, limit int) ([]*model.TransactionChallenge, error) {
+	ctx, span := otel.Tracer("challenge.database").Start(ctx, "Fetching expired transaction challenges")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+transactionChallengeColumns+` FROM blnk.transaction_challenges
+		WHERE status = 'pending' AND expires_at <= $1
+		ORDER BY expires_at ASC
+		LIMIT $2
+	`, now, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve expired transaction challenges", err)
+	}
+	return collectTransactionChallenges(rows)
+}
+
+// AppendChallengeEvent adds an event to the trail of a challenge.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - challengeID: The ID of the challenge.
+// - event: The event to add.
+// Returns:
+// - An error if the event could not be saved.
+func (d Datasource) AppendChallengeEvent(ctx context.Context, challengeID string, event model.ChallengeEvent) error {
+	ctx, span := otel.Tracer("challenge.database").Start(ctx, "Appending challenge event")
+	defer span.End()
+
+	data, err := json.Marshal([]model.ChallengeEvent{event})
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal challenge event", err)
+	}
+	_, err = d.Conn.ExecContext(ctx, `
+		UPDATE blnk.transaction_challenges SET trail = trail || $2::jsonb WHERE challenge_id = $1
+	`, challengeID, data)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to append challenge event", err)
+	}
+	return nil
+}
+
+// ResolveTransactionChallenge moves a pending challenge to its final status and adds the event that resolved
+// it to its trail. A challenge is resolved once: concurrent callbacks and expiry race for the pending row.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - challengeID: The ID of the challenge.
+// - status: The final status of the challenge.
+// - event: The event that resolved the challenge.
+// Returns:
+// - Whether the challenge was pending and is now resolved, or an error if the update fails.
+func (d Datasource) ResolveTransactionChallenge(ctx context.Context, challengeID, status string, event model.ChallengeEvent) (bool, error) {
+	ctx, span := otel.Tracer("challenge.database").Start(ctx, "Resolving transaction challenge")
+	defer span.End()
+
+	data, err := json.Marshal([]model.ChallengeEvent{event})
+	if err != nil {
+		return false, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal challenge event", err)
+	}
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.transaction_challenges
+		SET status = $2, trail = trail || $3::jsonb, resolved_at = $4
+		WHERE challenge_id = $1 AND status = 'pending'
+	`, challengeID, status, data, event.At)
+	if err != nil {
+		span.RecordError(err)
+		return false, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to resolve transaction challenge", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to resolve transaction challenge", err)
+	}
+	return affected == 1, nil
+}
+
+func collectTransactionChallenges(rows *sql.Rows) ([]*model.TransactionChallenge, error) {
+	defer rows.Close()
+
+	challenges := []*model.TransactionChallenge{}
+	for rows.Next() {
+		challenge, err := scanTransactionChallenge(rows)
+		if err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan transaction challenge", err)
+		}
+		challenges = append(challenges, challenge)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over transaction challenges", err)
+	}
+	return challenges, nil
+}
+
+func scanTransactionChallenge(row rowScanner) (*model.TransactionChallenge, error) {
+	challenge := &model.TransactionChallenge{}
+	var transaction, trail []byte
+	err := row.Scan(
+		&challenge.ChallengeID, &challenge.TransactionID, &challenge.Reference, &challenge.Rule, &challenge.Status,
+		&transaction, &trail, &challenge.ExpiresAt, &challenge.CreatedAt, &challenge.ResolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(transaction, &challenge.Transaction); err != nil {
+		return nil, err
+	}
+	if len(trail) > 0 {
+		if err := json.Unmarshal(trail, &challenge.Trail); err != nil {
+			return nil, err
+		}
+	}
+	return challenge, nil
+}
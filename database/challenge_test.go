@@ -0,0 +1,78 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTransactionChallenge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.transaction_challenges WHERE challenge_id = $1")).
+		WithArgs("chl_1").
+		WillReturnRows(sqlmock.NewRows([]string{"challenge_id", "transaction_id", "reference", "rule", "status", "transaction", "trail", "expires_at", "created_at", "resolved_at"}).
+			AddRow("chl_1", "txn_1", "ref_1", "large-usd", "pending",
+				[]byte(`{"transaction_id":"txn_1","reference":"ref_1","precise_amount":500000,"currency":"USD"}`),
+				[]byte(`[{"event":"created","at":"2024-01-01T00:00:00Z"}]`), now.Add(time.Minute), now, nil))
+
+	challenge, err := ds.GetTransactionChallenge(context.Background(), "chl_1")
+	require.NoError(t, err)
+	assert.Equal(t, "large-usd", challenge.Rule)
+	assert.Equal(t, "500000", challenge.Transaction.PreciseAmount.String())
+	require.Len(t, challenge.Trail, 1)
+	assert.Equal(t, model.ChallengeEventCreated, challenge.Trail[0].Event)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResolveTransactionChallenge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	event := model.ChallengeEvent{Event: model.ChallengeEventConfirmed, At: at}
+	mock.ExpectExec(regexp.QuoteMeta("WHERE challenge_id = $1 AND status = 'pending'")).
+		WithArgs("chl_1", model.ChallengeConfirmed, []byte(`[{"event":"confirmed","at":"2024-01-01T00:00:00Z"}]`), at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("WHERE challenge_id = $1 AND status = 'pending'")).
+		WithArgs("chl_1", model.ChallengeExpired, sqlmock.AnyArg(), at).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	resolved, err := ds.ResolveTransactionChallenge(context.Background(), "chl_1", model.ChallengeConfirmed, event)
+	require.NoError(t, err)
+	assert.True(t, resolved)
+
+	// A challenge that is no longer pending is not resolved again
+	resolved, err = ds.ResolveTransactionChallenge(context.Background(), "chl_1", model.ChallengeExpired, model.ChallengeEvent{Event: model.ChallengeEventExpired, At: at})
+	require.NoError(t, err)
+	assert.False(t, resolved)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+// GetComplianceBacklog reads the next postings to export to the compliance bucket: the numbered transactions
+// of every ledger after the last sequence number exported for it, in ledger and sequence order. Only ledgers
+// whose counter is ahead of their export cursor are read, so the query stays cheap once the export has caught
+// up. A posting whose transaction is missing is returned without a transaction ID, for the export to refuse.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - limit int: The maximum number of postings to read.
+//
+// Returns:
+// - *model.ComplianceBacklog: The postings and the last sequence number exported for each of their ledgers.
+// - error: An error if the postings could not be read.
+func (d Datasource) GetComplianceBacklog(ctx context.Context, limit int) (*model.ComplianceBacklog, error) {
+	ctx, span := otel.Tracer("compliance_export.database").Start(ctx, "GetComplianceBacklog")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		WITH pending AS (
+			SELECT ls.ledger_id, COALESCE(c.last_sequence, 0) AS exported
+			FROM blnk.ledger_sequences ls
+			LEFT JOIN blnk.compliance_export_cursors c ON c.ledger_id = ls.ledger_id
+			WHERE ls.last_sequence > COALESCE(c.last_sequence, 0)
+		)
+		SELECT p.exported, s.ledger_id, s.sequence, COALESCE(t.transaction_id, ''), COALESCE(t.parent_transaction, ''),
+			COALESCE(t.reference, ''), COALESCE(t.source, ''), COALESCE(t.destination, ''), COALESCE(t.precise_amount::text, '0'),
+			COALESCE(t.precision, 0), COALESCE(t.currency, ''), COALESCE(t.status, ''), COALESCE(t.description, ''),
+			COALESCE(t.hash, ''), t.meta_data, t.effective_date, s.created_at
+		FROM pending p
+		JOIN blnk.transaction_sequences s ON s.ledger_id = p.ledger_id AND s.sequence > p.exported
+		LEFT JOIN blnk.transactions t ON t.transaction_id = s.transaction_id
+		ORDER BY s.ledger_id ASC, s.sequence ASC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve compliance backlog", err)
+	}
+	defer rows.Close()
+
+	backlog := &model.ComplianceBacklog{Records: []model.ComplianceRecord{}, Exported: map[string]int64{}}
+	for rows.Next() {
+		var record model.ComplianceRecord
+		var exported int64
+		var metaData []byte
+		var effectiveDate sql.NullTime
+		if err := rows.Scan(&exported, &record.LedgerID, &record.Sequence, &record.TransactionID, &record.ParentTransaction,
+			&record.Reference, &record.Source, &record.Destination, &record.PreciseAmount, &record.Precision, &record.Currency,
+			&record.Status, &record.Description, &record.Hash, &metaData, &effectiveDate, &record.CreatedAt); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan compliance backlog", err)
+		}
+		if len(metaData) > 0 {
+			record.MetaData = metaData
+		}
+		if effectiveDate.Valid {
+			record.EffectiveDate = &effectiveDate.Time
+		}
+		backlog.Exported[record.LedgerID] = exported
+		backlog.Records = append(backlog.Records, record)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over compliance backlog", err)
+	}
+	return backlog, nil
+}
+
+// GetComplianceExportState retrieves how far postings have been exported. An export that never ran has an
+// empty state.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - *model.ComplianceExportState: The state of the export.
+// - error: An error if the state could not be retrieved.
+func (d Datasource) GetComplianceExportState(ctx context.Context) (*model.ComplianceExportState, error) {
+	ctx, span := otel.Tracer("compliance_export.database").Start(ctx, "GetComplianceExportState")
+	defer span.End()
+
+	state := &model.ComplianceExportState{}
+	var lastExportedAt sql.NullTime
+	err := d.Conn.QueryRowContext(ctx, `
+		SELECT last_batch, last_hash, records_exported, last_exported_at, COALESCE(last_error, '')
+		FROM blnk.compliance_export_state
+		WHERE id = 1
+	`).Scan(&state.LastBatch, &state.LastHash, &state.RecordsExported, &lastExportedAt, &state.LastError)
+	if err == sql.ErrNoRows {
+		return state, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve compliance export state", err)
+	}
+	if lastExportedAt.Valid {
+		state.LastExportedAt = &lastExportedAt.Time
+	}
+	return state, nil
+}
+
+// SaveComplianceExportState saves how far postings have been exported, together with the last sequence number
+// exported for each ledger of the batch that was written, in one transaction so the cursors never get ahead
+// of or fall behind the batch chain.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - state *model.ComplianceExportState: The state to save.
+// - cursors map[string]int64: The last sequence number exported for each ledger of the batch, or nil when only
+// the state changed.
+//
+// Returns:
+// - error: An error if the state could not be saved.
+func (d Datasource) SaveComplianceExportState(ctx context.Context, state *model.ComplianceExportState, cursors map[string]int64) error {
+	ctx, span := otel.Tracer("compliance_export.database").Start(ctx, "SaveComplianceExportState")
+	defer span.End()
+
+	tx, err := d.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to save compliance export state", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO blnk.compliance_export_state (id, last_batch, last_hash, records_exported, last_exported_at, last_error)
+		VALUES (1, $1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (id) DO UPDATE
+		SET last_batch = EXCLUDED.last_batch, last_hash = EXCLUDED.last_hash, records_exported = EXCLUDED.records_exported,
+			last_exported_at = EXCLUDED.last_exported_at, last_error = EXCLUDED.last_error
+	`, state.LastBatch, state.LastHash, state.RecordsExported, state.LastExportedAt, state.LastError)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to save compliance export state", err)
+	}
+
+	if len(cursors) > 0 {
+		ledgerIDs := make([]string, 0, len(cursors))
+		for ledgerID := range cursors {
+			ledgerIDs = append(ledgerIDs, ledgerID)
+		}
+		sort.Strings(ledgerIDs)
+		sequences := make([]int64, len(ledgerIDs))
+		for i, ledgerID := range ledgerIDs {
+			sequences[i] = cursors[ledgerID]
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO blnk.compliance_export_cursors (ledger_id, last_sequence)
+			SELECT * FROM unnest($1::text[], $2::bigint[])
+			ON CONFLICT (ledger_id) DO UPDATE SET last_sequence = EXCLUDED.last_sequence
+		`, pq.Array(ledgerIDs), pq.Array(sequences))
+		if err != nil {
+			span.RecordError(err)
+			return apierror.NewAPIError(apierror.ErrInternalServer, fmt.Sprintf("Failed to save compliance export cursors of %d ledgers", len(ledgerIDs)), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to save compliance export state", err)
+	}
+	return nil
+}
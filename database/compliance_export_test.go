@@ -0,0 +1,74 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetComplianceBacklog(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+	createdAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	columns := []string{"exported", "ledger_id", "sequence", "transaction_id", "parent_transaction", "reference", "source", "destination",
+		"precise_amount", "precision", "currency", "status", "description", "hash", "meta_data", "effective_date", "created_at"}
+	mock.ExpectQuery(regexp.QuoteMeta("JOIN blnk.transaction_sequences s ON s.ledger_id = p.ledger_id AND s.sequence > p.exported")).
+		WithArgs(500).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(7, "ldg_1", 8, "txn_1", "", "ref_1", "bln_1", "bln_2", "1000", 100, "USD", "APPLIED", "", "h1", []byte(`{"a":1}`), nil, createdAt).
+			AddRow(7, "ldg_1", 9, "", "", "", "", "", "0", 0, "", "", "", "", nil, nil, createdAt))
+
+	backlog, err := ds.GetComplianceBacklog(context.Background(), 500)
+	require.NoError(t, err)
+	require.Len(t, backlog.Records, 2)
+	assert.Equal(t, map[string]int64{"ldg_1": 7}, backlog.Exported)
+	assert.Equal(t, "1000", backlog.Records[0].PreciseAmount)
+	assert.JSONEq(t, `{"a":1}`, string(backlog.Records[0].MetaData))
+	assert.Empty(t, backlog.Records[1].TransactionID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveComplianceExportState(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+	state := &model.ComplianceExportState{LastBatch: 3, LastHash: "abc", RecordsExported: 12}
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.compliance_export_state")).
+		WithArgs(int64(3), "abc", int64(12), nil, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.compliance_export_cursors")).
+		WithArgs("{\"ldg_1\",\"ldg_2\"}", "{4,9}").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, ds.SaveComplianceExportState(context.Background(), state, map[string]int64{"ldg_2": 9, "ldg_1": 4}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
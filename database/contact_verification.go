@@ -0,0 +1,180 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+// contactVerifiedColumns maps contact channels to the identity column recording when they were verified.
+var contactVerifiedColumns = map[string]string{
+	model.ContactChannelEmail: "email_verified_at",
+	model.ContactChannelPhone: "phone_verified_at",
+}
+
+// CreateContactVerification saves a verification code sent to an identity's email address or phone number.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - verification: The verification, with the hashes of its code and destination.
+// Returns:
+// - An error if the insert fails.
+func (d Datasource) CreateContactVerification(ctx context.Context, verification *model.ContactVerification) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Creating contact verification")
+	defer span.End()
+
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.contact_verifications (verification_id, identity_id, channel, destination_hash, code_hash, attempts, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`,
+		verification.VerificationID, verification.IdentityID, verification.Channel, verification.DestinationHash,
+		verification.CodeHash, verification.Attempts, verification.ExpiresAt, verification.CreatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create contact verification", err)
+	}
+	return nil
+}
+
+// GetPendingContactVerification retrieves the latest verification of an identity's channel that has not been
+// confirmed. Codes requested before it are superseded and never pending.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - identityID: The ID of the identity.
+// - channel: The channel verified, email or phone.
+// Returns:
+// - The verification, or an error if none is pending.
+func (d Datasource) GetPendingContactVerification(ctx context.Context, identityID, channel string) (*model.ContactVerification, error) {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Fetching pending contact verification")
+	defer span.End()
+
+	verification := &model.ContactVerification{}
+	err := d.Conn.QueryRowContext(ctx, `
+		SELECT verification_id, identity_id, channel, destination_hash, code_hash, attempts, expires_at, verified_at, created_at
+		FROM (
+			SELECT * FROM blnk.contact_verifications
+			WHERE identity_id = $1 AND channel = $2
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		) latest
+		WHERE verified_at IS NULL
+	`, identityID, channel).Scan(
+		&verification.VerificationID, &verification.IdentityID, &verification.Channel, &verification.DestinationHash,
+		&verification.CodeHash, &verification.Attempts, &verification.ExpiresAt, &verification.VerifiedAt, &verification.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("No pending %s verification for identity '%s'", channel, identityID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve contact verification", err)
+	}
+	return verification, nil
+}
+
+// IncrementContactVerificationAttempts counts a wrong code entered for a verification.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - verificationID: The ID of the verification.
+// Returns:
+// - The attempts made so far, or an error if the update fails.
+func (d Datasource) IncrementContactVerificationAttempts(ctx context.Context, verificationID string) (int, error) {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Counting contact verification attempt")
+	defer span.End()
+
+	var attempts int
+	err := d.Conn.QueryRowContext(ctx, `
+		UPDATE blnk.contact_verifications
+		SET attempts = attempts + 1
+		WHERE verification_id = $1
+		RETURNING attempts
+	`, verificationID).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return 0, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Contact verification with ID '%s' not found", verificationID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return 0, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update contact verification", err)
+	}
+	return attempts, nil
+}
+
+// ConfirmContactVerification marks a verification confirmed and the identity's email address or phone number
+// verified, together. A verification can only be confirmed once.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - verification: The verification, with the time it was confirmed set.
+// Returns:
+// - An error if the verification was already confirmed, the identity is not found or deleted, or the update fails.
+func (d Datasource) ConfirmContactVerification(ctx context.Context, verification *model.ContactVerification) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Confirming contact verification")
+	defer span.End()
+
+	column, ok := contactVerifiedColumns[verification.Channel]
+	if !ok {
+		return apierror.NewAPIError(apierror.ErrBadRequest, fmt.Sprintf("unknown contact channel '%s'", verification.Channel), nil)
+	}
+
+	tx, err := d.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to begin transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE blnk.contact_verifications
+		SET verified_at = $2
+		WHERE verification_id = $1 AND verified_at IS NULL
+	`, verification.VerificationID, verification.VerifiedAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to confirm contact verification", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	} else if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Contact verification with ID '%s' was already confirmed", verification.VerificationID), nil)
+	}
+
+	result, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE blnk.identity
+		SET %s = $2
+		WHERE identity_id = $1 AND deleted_at IS NULL
+	`, column), verification.IdentityID, verification.VerifiedAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to verify identity contact", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	} else if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity with ID '%s' not found", verification.IdentityID), nil)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to commit contact verification", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPendingContactVerification(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	columns := []string{"verification_id", "identity_id", "channel", "destination_hash", "code_hash", "attempts", "expires_at", "verified_at", "created_at"}
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE identity_id = $1 AND channel = $2 ORDER BY created_at DESC, id DESC LIMIT 1 ) latest WHERE verified_at IS NULL")).
+		WithArgs("idt_1", model.ContactChannelEmail).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("cvr_1", "idt_1", model.ContactChannelEmail, "dest", "code", 2, now, nil, now))
+
+	verification, err := ds.GetPendingContactVerification(context.Background(), "idt_1", model.ContactChannelEmail)
+	require.NoError(t, err)
+	assert.Equal(t, "cvr_1", verification.VerificationID)
+	assert.Equal(t, 2, verification.Attempts)
+	assert.Nil(t, verification.VerifiedAt)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.contact_verifications")).
+		WithArgs("idt_1", model.ContactChannelPhone).
+		WillReturnRows(sqlmock.NewRows(columns))
+	_, err = ds.GetPendingContactVerification(context.Background(), "idt_1", model.ContactChannelPhone)
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfirmContactVerification(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	verifiedAt := time.Now()
+	verification := &model.ContactVerification{VerificationID: "cvr_1", IdentityID: "idt_1", Channel: model.ContactChannelPhone, VerifiedAt: &verifiedAt}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.contact_verifications SET verified_at = $2 WHERE verification_id = $1 AND verified_at IS NULL")).
+		WithArgs("cvr_1", &verifiedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.identity SET phone_verified_at = $2 WHERE identity_id = $1 AND deleted_at IS NULL")).
+		WithArgs("idt_1", &verifiedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, ds.ConfirmContactVerification(context.Background(), verification))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.contact_verifications")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	err = ds.ConfirmContactVerification(context.Background(), verification)
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrConflict, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
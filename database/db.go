@@ -17,6 +17,7 @@ limitations under the License.
 package database
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"sync"
@@ -26,6 +27,14 @@ import (
 	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
 )
 
+// dbExecutor runs statements on the connection pool or inside a database transaction, so that a write can be
+// made on its own or as part of a larger unit of work.
+type dbExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 // Declare a package-level variable to hold the singleton instance.
 var (
 	instance *Datasource
@@ -35,6 +44,11 @@ var (
 type Datasource struct {
 	Conn  *sql.DB
 	Cache cache.Cache
+	// HistoryOrder is the time transaction history is ordered by, "created_at" or "transaction_time".
+	HistoryOrder string
+	// replicas serve the read-only queries that can tolerate replication lag. Without replicas every query
+	// goes to Conn.
+	replicas *replicaSet
 }
 
 // NewDataSource initializes a new database connection.
@@ -67,7 +81,12 @@ func GetDBConnection(configuration *config.Configuration) (*Datasource, error) {
 			// Continue without cache instead of failing completely.
 		}
 
-		instance = &Datasource{Conn: con, Cache: cacheInstance}
+		instance = &Datasource{
+			Conn:         con,
+			Cache:        cacheInstance,
+			HistoryOrder: configuration.Transaction.HistoryOrder,
+			replicas:     newReplicaSet(configuration.DataSource),
+		}
 	})
 	if err != nil {
 		return nil, err
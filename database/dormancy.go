@@ -0,0 +1,516 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+const dormantBalanceColumns = `balance_id, ledger_id, identity_id, currency, last_activity_at, dormant_since, frozen, status, batch_id`
+
+const escheatmentBatchColumns = `batch_id, currency, holding_balance, items, total_amount, status, error, created_at, applied_at`
+
+// FindInactiveBalances finds balances that are not yet flagged dormant and had no transactions since a time.
+// Indicator balances such as @World are internal and never become dormant.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - inactiveSince: Balances with a transaction at or after this time are active.
+// - limit: The maximum number of balances to return.
+// Returns:
+// - The inactive balances with the time of their last transaction, or an error if the query fails.
+func (d Datasource) FindInactiveBalances(ctx context.Context, inactiveSince time.Time, limit int) ([]*model.DormantBalance, error) {
+	ctx, span := otel.Tracer("dormancy.database").Start(ctx, "Finding inactive balances")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT b.balance_id, b.ledger_id, COALESCE(b.identity_id, ''), b.currency,
+			COALESCE(GREATEST(
+				(SELECT MAX(t.created_at) FROM blnk.transactions t WHERE t.source = b.balance_id),
+				(SELECT MAX(t.created_at) FROM blnk.transactions t WHERE t.destination = b.balance_id)
+			), b.created_at)
+		FROM blnk.balances b
+		WHERE COALESCE(b.indicator, '') = ''
+			AND b.created_at < $1
+			AND NOT EXISTS (SELECT 1 FROM blnk.dormant_balances d WHERE d.balance_id = b.balance_id)
+			AND NOT EXISTS (SELECT 1 FROM blnk.transactions t WHERE t.source = b.balance_id AND t.created_at >= $1)
+			AND NOT EXISTS (SELECT 1 FROM blnk.transactions t WHERE t.destination = b.balance_id AND t.created_at >= $1)
+		ORDER BY b.created_at ASC
+		LIMIT $2
+	`, inactiveSince, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to find inactive balances", err)
+	}
+	defer rows.Close()
+
+	balances := []*model.DormantBalance{}
+	for rows.Next() {
+		balance := &model.DormantBalance{Status: model.DormantBalanceDormant}
+		if err := rows.Scan(&balance.BalanceID, &balance.LedgerID, &balance.IdentityID, &balance.Currency, &balance.LastActivityAt); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan inactive balance", err)
+		}
+		balances = append(balances, balance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over inactive balances", err)
+	}
+	return balances, nil
+}
+
+// FlagDormantBalance records a balance as dormant. A balance that is already flagged is left unchanged.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - balance: The dormant balance to store.
+// Returns:
+// - Whether the balance was flagged, or an error if it could not be saved.
+func (d Datasource) FlagDormantBalance(ctx context.Context, balance *model.DormantBalance) (bool, error) {
+	ctx, span := otel.Tracer("dormancy.database").Start(ctx, "Flagging dormant balance")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.dormant_balances (`+dormantBalanceColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (balance_id) DO NOTHING
+	`,
+		balance.BalanceID, balance.LedgerID, sql.NullString{String: balance.IdentityID, Valid: balance.IdentityID != ""},
+		balance.Currency, balance.LastActivityAt, balance.DormantSince, balance.Frozen, balance.Status,
+		sql.NullString{String: balance.BatchID, Valid: balance.BatchID != ""},
+	)
+	if err != nil {
+		span.RecordError(err)
+		return false, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to flag dormant balance", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// ClearReactivatedBalances removes the dormancy flag of balances that had a transaction after their last
+// recorded activity. Frozen balances and balances claimed by an escheatment batch keep their flag.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// Returns:
+// - The balances that were cleared, or an error if the update fails.
+func (d Datasource) ClearReactivatedBalances(ctx context.Context) ([]*model.DormantBalance, error) {
+	ctx, span := otel.Tracer("dormancy.database").Start(ctx, "Clearing reactivated balances")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		DELETE FROM blnk.dormant_balances d
+		WHERE d.status = $1 AND NOT d.frozen AND d.batch_id IS NULL
+			AND (
+				EXISTS (SELECT 1 FROM blnk.transactions t WHERE t.source = d.balance_id AND t.created_at > d.last_activity_at)
+				OR EXISTS (SELECT 1 FROM blnk.transactions t WHERE t.destination = d.balance_id AND t.created_at > d.last_activity_at)
+			)
+		RETURNING `+dormantBalanceColumns,
+		model.DormantBalanceDormant,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to clear reactivated balances", err)
+	}
+	return collectDormantBalances(rows)
+}
+
+// GetDormantBalance retrieves the dormancy record of a balance.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - balanceID: The ID of the balance.
+// Returns:
+// - The dormant balance, or an error if the balance is not dormant.
+func (d Datasource) GetDormantBalance(ctx context.Context, balanceID string) (*model.DormantBalance, error) {
+	ctx, span := otel.Tracer("dormancy.database").Start(ctx, "Fetching dormant balance")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `SELECT `+dormantBalanceColumns+` FROM blnk.dormant_balances WHERE balance_id = $1`, balanceID)
+
+	balance, err := scanDormantBalance(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Dormant balance with ID '%s' not found", balanceID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve dormant balance", err)
+	}
+	return balance, nil
+}
+
+// dormantBalanceKeyset is the order dormant balances are listed in, longest dormant first.
+var dormantBalanceKeyset = keyset{timeColumn: "dormant_since", idColumn: "balance_id"}
+
+// escheatmentBatchKeyset is the order escheatment batches are listed in, newest first.
+var escheatmentBatchKeyset = keyset{timeColumn: "created_at", idColumn: "batch_id", descending: true}
+
+// ListDormantBalances lists dormant balances, longest dormant first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - status: Only balances with this status are listed when it is not empty.
+// - page: The page of balances to read.
+// Returns:
+// - The dormant balances, or an error if the query fails.
+func (d Datasource) ListDormantBalances(ctx context.Context, status string, page model.Page) ([]*model.DormantBalance, error) {
+	ctx, span := otel.Tracer("dormancy.database").Start(ctx, "Listing dormant balances")
+	defer span.End()
+
+	condition, suffix, args := dormantBalanceKeyset.page(page, []interface{}{status})
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+dormantBalanceColumns+`
+		FROM blnk.dormant_balances
+		WHERE ($1 = '' OR status = $1) AND `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve dormant balances", err)
+	}
+	return collectDormantBalances(rows)
+}
+
+// ListFrozenBalanceIDs retrieves the IDs of the balances frozen for dormancy.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// Returns:
+// - The balance IDs, or an error if the query fails.
+func (d Datasource) ListFrozenBalanceIDs(ctx context.Context) ([]string, error) {
+	ctx, span := otel.Tracer("dormancy.database").Start(ctx, "Listing frozen balances")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `SELECT balance_id FROM blnk.dormant_balances WHERE frozen`)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve frozen balances", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan frozen balance", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over frozen balances", err)
+	}
+	return ids, nil
+}
+
+// DeleteDormantBalance removes the dormancy record of a balance, reactivating it.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - balanceID: The ID of the balance.
+// Returns:
+// - An error if the balance is not dormant or the record could not be removed.
+func (d Datasource) DeleteDormantBalance(ctx context.Context, balanceID string) error {
+	ctx, span := otel.Tracer("dormancy.database").Start(ctx, "Deleting dormant balance")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `DELETE FROM blnk.dormant_balances WHERE balance_id = $1`, balanceID)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to delete dormant balance", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Dormant balance with ID '%s' not found", balanceID), nil)
+	}
+	return nil
+}
+
+// CreateEscheatmentBatch saves a batch and, in the same database transaction, claims the dormant balances of
+// its currency that hold funds and are not part of another batch. Each claimed balance becomes an item for
+// its current amount. A claimed balance can never be escheated twice.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - batch: The batch to store. Its items and total amount are set from the claimed balances.
+// - dormantBefore: Only balances that became dormant before this time are claimed when it is not nil.
+// Returns:
+// - An error if the batch could not be saved.
+func (d Datasource) CreateEscheatmentBatch(ctx context.Context, batch *model.EscheatmentBatch, dormantBefore *time.Time) error {
+	ctx, span := otel.Tracer("dormancy.database").Start(ctx, "Creating escheatment batch")
+	defer span.End()
+
+	tx, err := d.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to begin escheatment batch", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO blnk.escheatment_batches (`+escheatmentBatchColumns+`)
+		VALUES ($1, $2, $3, '[]', 0, $4, NULL, $5, NULL)
+	`, batch.BatchID, batch.Currency, batch.HoldingBalance, batch.Status, batch.CreatedAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create escheatment batch", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE blnk.dormant_balances d
+		SET batch_id = $1
+		FROM blnk.balances b
+		WHERE b.balance_id = d.balance_id AND b.balance > 0
+			AND d.status = $2 AND d.batch_id IS NULL AND d.currency = $3
+			AND ($4::TIMESTAMPTZ IS NULL OR d.dormant_since < $4)
+		RETURNING d.balance_id, d.ledger_id, COALESCE(d.identity_id, ''), d.last_activity_at, d.dormant_since, b.balance, b.currency_multiplier
+	`, batch.BatchID, model.DormantBalanceDormant, batch.Currency, dormantBefore)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to claim dormant balances", err)
+	}
+	items, err := collectEscheatmentItems(rows)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	batch.Items = items
+	batch.TotalAmount = new(big.Int)
+	for i := range batch.Items {
+		batch.Items[i].Reference = fmt.Sprintf("%s_%d", batch.BatchID, i+1)
+		batch.TotalAmount.Add(batch.TotalAmount, batch.Items[i].PreciseAmount)
+	}
+	encoded, err := json.Marshal(batch.Items)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInvalidInput, "Failed to encode escheatment items", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE blnk.escheatment_batches SET items = $2, total_amount = $3 WHERE batch_id = $1`, batch.BatchID, encoded, batch.TotalAmount.String()); err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update escheatment batch", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to commit escheatment batch", err)
+	}
+	return nil
+}
+
+// UpdateEscheatmentBatch records the items and outcome of an escheatment batch. The balances whose funds
+// were transferred are marked escheated in the same database transaction.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - batch: The batch with its items and status.
+// Returns:
+// - An error if the batch could not be updated.
+func (d Datasource) UpdateEscheatmentBatch(ctx context.Context, batch *model.EscheatmentBatch) error {
+	ctx, span := otel.Tracer("dormancy.database").Start(ctx, "Updating escheatment batch")
+	defer span.End()
+
+	items, err := json.Marshal(batch.Items)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInvalidInput, "Failed to encode escheatment items", err)
+	}
+
+	tx, err := d.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to begin escheatment batch update", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE blnk.escheatment_batches
+		SET items = $2, total_amount = $3, status = $4, error = $5, applied_at = $6
+		WHERE batch_id = $1
+	`, batch.BatchID, items, bigIntString(batch.TotalAmount), batch.Status,
+		sql.NullString{String: batch.Error, Valid: batch.Error != ""}, batch.AppliedAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update escheatment batch", err)
+	}
+
+	var transferred []string
+	for _, item := range batch.Items {
+		if item.TransactionID != "" {
+			transferred = append(transferred, item.BalanceID)
+		}
+	}
+	if len(transferred) > 0 {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE blnk.dormant_balances SET status = $3
+			WHERE batch_id = $1 AND balance_id = ANY($2)
+		`, batch.BatchID, pq.StringArray(transferred), model.DormantBalanceEscheated)
+		if err != nil {
+			span.RecordError(err)
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to mark balances escheated", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to commit escheatment batch update", err)
+	}
+	return nil
+}
+
+// GetEscheatmentBatch retrieves an escheatment batch by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - batchID: The ID of the batch.
+// Returns:
+// - The batch, or an error if it does not exist.
+func (d Datasource) GetEscheatmentBatch(ctx context.Context, batchID string) (*model.EscheatmentBatch, error) {
+	ctx, span := otel.Tracer("dormancy.database").Start(ctx, "Fetching escheatment batch")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `SELECT `+escheatmentBatchColumns+` FROM blnk.escheatment_batches WHERE batch_id = $1`, batchID)
+
+	batch, err := scanEscheatmentBatch(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Escheatment batch with ID '%s' not found", batchID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve escheatment batch", err)
+	}
+	return batch, nil
+}
+
+// ListEscheatmentBatches lists escheatment batches, newest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - page: The page of batches to read.
+// Returns:
+// - The batches, or an error if the query fails.
+func (d Datasource) ListEscheatmentBatches(ctx context.Context, page model.Page) ([]*model.EscheatmentBatch, error) {
+	ctx, span := otel.Tracer("dormancy.database").Start(ctx, "Listing escheatment batches")
+	defer span.End()
+
+	condition, suffix, args := escheatmentBatchKeyset.page(page, nil)
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+escheatmentBatchColumns+`
+		FROM blnk.escheatment_batches
+		WHERE `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve escheatment batches", err)
+	}
+	defer rows.Close()
+
+	batches := []*model.EscheatmentBatch{}
+	for rows.Next() {
+		batch, err := scanEscheatmentBatch(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan escheatment batch", err)
+		}
+		batches = append(batches, batch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over escheatment batches", err)
+	}
+	return batches, nil
+}
+
+func collectDormantBalances(rows *sql.Rows) ([]*model.DormantBalance, error) {
+	defer rows.Close()
+
+	balances := []*model.DormantBalance{}
+	for rows.Next() {
+		balance, err := scanDormantBalance(rows)
+		if err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan dormant balance", err)
+		}
+		balances = append(balances, balance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over dormant balances", err)
+	}
+	return balances, nil
+}
+
+// collectEscheatmentItems reads the claimed balances of a batch, ordered by balance ID so that item
+// references are stable.
+func collectEscheatmentItems(rows *sql.Rows) ([]model.EscheatmentItem, error) {
+	defer rows.Close()
+
+	items := []model.EscheatmentItem{}
+	for rows.Next() {
+		var item model.EscheatmentItem
+		var amount string
+		err := rows.Scan(&item.BalanceID, &item.LedgerID, &item.IdentityID, &item.LastActivityAt, &item.DormantSince, &amount, &item.Precision)
+		if err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan claimed balance", err)
+		}
+		item.PreciseAmount, _ = new(big.Int).SetString(amount, 10)
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over claimed balances", err)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].BalanceID < items[j].BalanceID })
+	return items, nil
+}
+
+func scanDormantBalance(row rowScanner) (*model.DormantBalance, error) {
+	balance := &model.DormantBalance{}
+	var identityID, batchID sql.NullString
+	err := row.Scan(
+		&balance.BalanceID, &balance.LedgerID, &identityID, &balance.Currency, &balance.LastActivityAt,
+		&balance.DormantSince, &balance.Frozen, &balance.Status, &batchID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	balance.IdentityID = identityID.String
+	balance.BatchID = batchID.String
+	return balance, nil
+}
+
+func scanEscheatmentBatch(row rowScanner) (*model.EscheatmentBatch, error) {
+	batch := &model.EscheatmentBatch{}
+	var items []byte
+	var totalAmount string
+	var batchError sql.NullString
+	err := row.Scan(
+		&batch.BatchID, &batch.Currency, &batch.HoldingBalance, &items, &totalAmount,
+		&batch.Status, &batchError, &batch.CreatedAt, &batch.AppliedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	batch.TotalAmount, _ = new(big.Int).SetString(totalAmount, 10)
+	batch.Error = batchError.String
+	if err := json.Unmarshal(items, &batch.Items); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// bigIntString formats an amount for a NUMERIC column, treating nil as zero.
+func bigIntString(amount *big.Int) string {
+	if amount == nil {
+		return "0"
+	}
+	return amount.String()
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const eodRunColumns = `run_id, to_char(business_date, 'YYYY-MM-DD'), status, cutoff_at, steps, started_at, completed_at`
+
+// CreateEODRun saves the end-of-day run of a business date.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - run: The run to store.
+// Returns:
+// - An error if the business date already has a run or the run could not be saved.
+func (d Datasource) CreateEODRun(ctx context.Context, run *model.EODRun) error {
+	ctx, span := otel.Tracer("eod.database").Start(ctx, "Creating EOD run")
+	defer span.End()
+
+	steps, err := json.Marshal(run.Steps)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to encode EOD run steps", err)
+	}
+
+	_, err = d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.eod_runs (run_id, business_date, status, cutoff_at, steps, started_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, run.RunID, run.BusinessDate, run.Status, run.CutoffAt, steps, run.StartedAt, run.CompletedAt)
+	if err != nil {
+		span.RecordError(err)
+		if pgconn.HasCode(err, pgconn.UniqueViolation) {
+			return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Business date '%s' already has an EOD run", run.BusinessDate), err)
+		}
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create EOD run", err)
+	}
+	return nil
+}
+
+// UpdateEODRun saves the status and step progress of an end-of-day run.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - run: The run with its progress.
+// Returns:
+// - An error if the run does not exist or could not be updated.
+func (d Datasource) UpdateEODRun(ctx context.Context, run *model.EODRun) error {
+	ctx, span := otel.Tracer("eod.database").Start(ctx, "Updating EOD run")
+	defer span.End()
+
+	steps, err := json.Marshal(run.Steps)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to encode EOD run steps", err)
+	}
+
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.eod_runs
+		SET status = $2, steps = $3, completed_at = $4
+		WHERE run_id = $1
+	`, run.RunID, run.Status, steps, run.CompletedAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update EOD run", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("EOD run with ID '%s' not found", run.RunID), nil)
+	}
+	return nil
+}
+
+// GetEODRun retrieves the end-of-day run of a business date.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - businessDate: The business date, as YYYY-MM-DD.
+// Returns:
+// - The run, or an error if the business date has no run.
+func (d Datasource) GetEODRun(ctx context.Context, businessDate string) (*model.EODRun, error) {
+	ctx, span := otel.Tracer("eod.database").Start(ctx, "Fetching EOD run")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `
+		SELECT `+eodRunColumns+`
+		FROM blnk.eod_runs
+		WHERE business_date = $1
+	`, businessDate)
+
+	run, err := scanEODRun(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("No EOD run for business date '%s'", businessDate), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve EOD run", err)
+	}
+	return run, nil
+}
+
+// GetLatestEODRun retrieves the end-of-day run of the latest business date.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// Returns:
+// - The run, or an error if no business date has a run.
+func (d Datasource) GetLatestEODRun(ctx context.Context) (*model.EODRun, error) {
+	ctx, span := otel.Tracer("eod.database").Start(ctx, "Fetching latest EOD run")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `
+		SELECT `+eodRunColumns+`
+		FROM blnk.eod_runs
+		ORDER BY business_date DESC
+		LIMIT 1
+	`)
+
+	run, err := scanEODRun(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, "No EOD run found", err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve EOD run", err)
+	}
+	return run, nil
+}
+
+// eodRunKeyset is the order end-of-day runs are listed in, latest business date first.
+var eodRunKeyset = keyset{timeColumn: "business_date", idColumn: "run_id", descending: true}
+
+// ListEODRuns lists end-of-day runs, latest business date first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - page: The page of runs to read.
+// Returns:
+// - The runs, or an error if the query fails.
+func (d Datasource) ListEODRuns(ctx context.Context, page model.Page) ([]*model.EODRun, error) {
+	ctx, span := otel.Tracer("eod.database").Start(ctx, "Listing EOD runs")
+	defer span.End()
+
+	condition, suffix, args := eodRunKeyset.page(page, nil)
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+eodRunColumns+`
+		FROM blnk.eod_runs
+		WHERE `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve EOD runs", err)
+	}
+	defer rows.Close()
+
+	runs := []*model.EODRun{}
+	for rows.Next() {
+		run, err := scanEODRun(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan EOD run", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over EOD runs", err)
+	}
+	return runs, nil
+}
+
+func scanEODRun(row rowScanner) (*model.EODRun, error) {
+	run := &model.EODRun{}
+	var steps []byte
+	err := row.Scan(&run.RunID, &run.BusinessDate, &run.Status, &run.CutoffAt, &steps, &run.StartedAt, &run.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(steps, &run.Steps); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
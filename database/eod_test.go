@@ -0,0 +1,104 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateEODRun(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	run := &model.EODRun{
+		RunID: "eod_1", BusinessDate: "2026-10-15", Status: model.EODRunRunning, CutoffAt: now, StartedAt: now,
+		Steps: []model.EODStepRun{{Name: "cutoff", Kind: model.EODKindCutoff, Status: model.EODStepPending}},
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.eod_runs")).
+		WithArgs("eod_1", "2026-10-15", model.EODRunRunning, now, []byte(`[{"name":"cutoff","kind":"cutoff","status":"pending","attempts":0}]`), now, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	require.NoError(t, ds.CreateEODRun(context.Background(), run))
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.eod_runs")).
+		WillReturnError(&pq.Error{Code: "23505"})
+	err = ds.CreateEODRun(context.Background(), run)
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrConflict, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetEODRun(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.eod_runs")).
+		WithArgs("2026-10-15").
+		WillReturnRows(sqlmock.NewRows([]string{"run_id", "business_date", "status", "cutoff_at", "steps", "started_at", "completed_at"}).
+			AddRow("eod_1", "2026-10-15", model.EODRunFailed, now, []byte(`[{"name":"cutoff","kind":"cutoff","status":"failed","attempts":2,"error":"boom"}]`), now, now))
+
+	run, err := ds.GetEODRun(context.Background(), "2026-10-15")
+	require.NoError(t, err)
+	assert.Equal(t, model.EODRunFailed, run.Status)
+	require.Len(t, run.Steps, 1)
+	assert.Equal(t, 2, run.Steps[0].Attempts)
+	assert.Equal(t, "boom", run.Steps[0].Error)
+	require.NotNil(t, run.CompletedAt)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.eod_runs")).
+		WithArgs("2026-10-16").
+		WillReturnRows(sqlmock.NewRows([]string{"run_id"}))
+	_, err = ds.GetEODRun(context.Background(), "2026-10-16")
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateEODRun_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.eod_runs")).
+		WithArgs("eod_missing", model.EODRunCompleted, []byte("null"), nil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = ds.UpdateEODRun(context.Background(), &model.EODRun{RunID: "eod_missing", Status: model.EODRunCompleted})
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const externalAccountColumns = `external_account_id, COALESCE(identity_id, ''), type, holder_name, currency, COALESCE(country, ''), COALESCE(bank_name, ''), COALESCE(iban, ''), COALESCE(account_number, ''), COALESCE(routing_number, ''), COALESCE(bic, ''), COALESCE(network, ''), COALESCE(wallet_address, ''), verification_status, COALESCE(verification_reason, ''), verified_at, meta_data, created_at`
+
+// CreateExternalAccount saves an external account.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - account: The account to store.
+// Returns:
+// - An error if the insert fails.
+func (d Datasource) CreateExternalAccount(ctx context.Context, account *model.ExternalAccount) error {
+	ctx, span := otel.Tracer("external_account.database").Start(ctx, "Creating external account")
+	defer span.End()
+
+	metaData, err := json.Marshal(account.MetaData)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
+	}
+
+	_, err = d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.external_accounts (external_account_id, identity_id, type, holder_name, currency, country, bank_name, iban, account_number, routing_number, bic, network, wallet_address, verification_status, verification_reason, verified_at, meta_data, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+	`,
+		account.ExternalAccountID, nullString(account.IdentityID), account.Type, account.HolderName, account.Currency,
+		nullString(account.Country), nullString(account.BankName), nullString(account.IBAN), nullString(account.AccountNumber),
+		nullString(account.RoutingNumber), nullString(account.BIC), nullString(account.Network), nullString(account.WalletAddress),
+		account.VerificationStatus, nullString(account.VerificationReason), account.VerifiedAt, metaData, account.CreatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create external account", err)
+	}
+	return nil
+}
+
+// GetExternalAccount retrieves an external account by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - externalAccountID: The ID of the account.
+// Returns:
+// - The account, or an error if it does not exist.
+func (d Datasource) GetExternalAccount(ctx context.Context, externalAccountID string) (*model.ExternalAccount, error) {
+	ctx, span := otel.Tracer("external_account.database").Start(ctx, "Fetching external account")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `
+		SELECT `+externalAccountColumns+`
+		FROM blnk.external_accounts
+		WHERE external_account_id = $1
+	`, externalAccountID)
+
+	account := &model.ExternalAccount{}
+	err := scanExternalAccount(row, account)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("External account with ID '%s' not found", externalAccountID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve external account", err)
+	}
+	return account, nil
+}
+
+// GetExternalAccounts retrieves the external accounts of an identity, newest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - identityID: The ID of the identity.
+// Returns:
+// - The accounts, or an error if the query fails.
+func (d Datasource) GetExternalAccounts(ctx context.Context, identityID string) ([]*model.ExternalAccount, error) {
+	ctx, span := otel.Tracer("external_account.database").Start(ctx, "Fetching external accounts")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+externalAccountColumns+`
+		FROM blnk.external_accounts
+		WHERE identity_id = $1
+		ORDER BY created_at DESC
+	`, identityID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve external accounts", err)
+	}
+	defer rows.Close()
+
+	accounts := []*model.ExternalAccount{}
+	for rows.Next() {
+		account := &model.ExternalAccount{}
+		if err := scanExternalAccount(rows, account); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan external account", err)
+		}
+		accounts = append(accounts, account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over external accounts", err)
+	}
+	return accounts, nil
+}
+
+// UpdateExternalAccountVerification records the review of an external account. The update only applies while
+// the account is still in the status it was reviewed from, so concurrent reviews cannot both succeed.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - externalAccountID: The ID of the account.
+// - from: The status the account was reviewed in.
+// - to: The status the review ended with.
+// - reason: Why the account was verified or rejected, or empty.
+// - at: When the account was reviewed.
+// Returns:
+// - An error if the account is not found or no longer in the status reviewed, or if the update fails.
+func (d Datasource) UpdateExternalAccountVerification(ctx context.Context, externalAccountID, from, to, reason string, at time.Time) error {
+	ctx, span := otel.Tracer("external_account.database").Start(ctx, "Updating external account verification")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.external_accounts
+		SET verification_status = $3, verification_reason = $4, verified_at = $5
+		WHERE external_account_id = $1 AND verification_status = $2
+	`, externalAccountID, from, to, nullString(reason), at)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update external account verification", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("External account with ID '%s' is no longer %s", externalAccountID, from), nil)
+	}
+	return nil
+}
+
+func scanExternalAccount(row rowScanner, account *model.ExternalAccount) error {
+	var metaData []byte
+	err := row.Scan(
+		&account.ExternalAccountID, &account.IdentityID, &account.Type, &account.HolderName, &account.Currency,
+		&account.Country, &account.BankName, &account.IBAN, &account.AccountNumber, &account.RoutingNumber, &account.BIC,
+		&account.Network, &account.WalletAddress, &account.VerificationStatus, &account.VerificationReason,
+		&account.VerifiedAt, &metaData, &account.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if len(metaData) == 0 {
+		return nil
+	}
+	return json.Unmarshal(metaData, &account.MetaData)
+}
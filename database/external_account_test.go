@@ -0,0 +1,89 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateExternalAccount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	account := &model.ExternalAccount{
+		ExternalAccountID: "ext_1", Type: model.ExternalAccountWallet, HolderName: "Ada", Currency: "USDC",
+		Network: "ethereum", WalletAddress: "0x52908400098527886E0F7030069857D2E4169EE7",
+		VerificationStatus: model.VerificationPending, CreatedAt: now,
+	}
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.external_accounts")).
+		WithArgs("ext_1", nil, model.ExternalAccountWallet, "Ada", "USDC", nil, nil, nil, nil, nil, nil, "ethereum",
+			"0x52908400098527886E0F7030069857D2E4169EE7", model.VerificationPending, nil, nil, []byte(`null`), now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	require.NoError(t, ds.CreateExternalAccount(context.Background(), account))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetExternalAccounts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	columns := []string{"external_account_id", "identity_id", "type", "holder_name", "currency", "country", "bank_name", "iban", "account_number", "routing_number", "bic", "network", "wallet_address", "verification_status", "verification_reason", "verified_at", "meta_data", "created_at"}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.external_accounts WHERE identity_id = $1 ORDER BY created_at DESC")).
+		WithArgs("idt_ada").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("ext_1", "idt_ada", model.ExternalAccountBank, "Ada Lovelace", "EUR", "DE", "", "DE89370400440532013000", "", "", "", "", "", model.VerificationVerified, "name matched", now, []byte(`{"label":"main"}`), now))
+
+	accounts, err := ds.GetExternalAccounts(context.Background(), "idt_ada")
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "DE89370400440532013000", accounts[0].IBAN)
+	assert.Equal(t, model.VerificationVerified, accounts[0].VerificationStatus)
+	assert.Equal(t, "main", accounts[0].MetaData["label"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateExternalAccountVerification_Conflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.external_accounts")).
+		WithArgs("ext_1", model.VerificationPending, model.VerificationVerified, nil, now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = ds.UpdateExternalAccountVerification(context.Background(), "ext_1", model.VerificationPending, model.VerificationVerified, "", now)
+	assert.Equal(t, apierror.ErrConflict, err.(apierror.APIError).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
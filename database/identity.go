@@ -26,30 +26,46 @@ import (
 
 	"github.com/blnkfinance/blnk/internal/apierror"
 	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
 )
 
 // CreateIdentity inserts a new identity record into the database.
 // It generates a unique IdentityID, sets the creation timestamp, and stores the identity metadata.
 // Parameters:
+// - ctx: The context for the operation.
 // - identity: The identity object to be inserted.
 // Returns:
 // - The created identity object, or an error if the creation fails.
-func (d Datasource) CreateIdentity(identity model.Identity) (model.Identity, error) {
+func (d Datasource) CreateIdentity(ctx context.Context, identity model.Identity) (model.Identity, error) {
+	if err := validateIdentityType(&identity); err != nil {
+		return identity, err
+	}
+
 	// Marshal metadata into JSON format
 	metaDataJSON, err := json.Marshal(identity.MetaData)
 	if err != nil {
 		return identity, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
 	}
 
-	// Generate a unique identity ID and set the creation timestamp
+	preferencesJSON, err := marshalCommunicationPreferences(identity.CommunicationPreferences)
+	if err != nil {
+		return identity, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal communication preferences", err)
+	}
+
+	// Generate a unique identity ID and set the creation timestamp. New identities start active and unverified.
 	identity.IdentityID = model.GenerateUUIDWithSuffix("idt")
 	identity.CreatedAt = time.Now()
+	identity.Status = model.IdentityStatusActive
+	identity.StatusReason, identity.StatusChangedAt = "", nil
+	identity.Version = 1
+	identity.VerificationStatus = model.VerificationUnverified
+	identity.VerificationReason = ""
+	identity.VerificationSubmittedAt, identity.VerifiedAt, identity.VerificationRejectedAt = nil, nil, nil
+	identity.VerifiedEmail, identity.VerifiedPhone = false, false
+	identity.EmailVerifiedAt, identity.PhoneVerifiedAt = nil, nil
 
 	// Insert the identity record into the database
-	_, err = d.Conn.Exec(`
-		INSERT INTO blnk.identity (identity_id, identity_type, first_name, last_name, other_names, gender, dob, email_address, phone_number, nationality, organization_name, category, street, country, state, post_code, city, created_at, meta_data)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
-	`, identity.IdentityID, identity.IdentityType, identity.FirstName, identity.LastName, identity.OtherNames, identity.Gender, identity.DOB, identity.EmailAddress, identity.PhoneNumber, identity.Nationality, identity.OrganizationName, identity.Category, identity.Street, identity.Country, identity.State, identity.PostCode, identity.City, identity.CreatedAt, metaDataJSON)
+	err = insertIdentity(ctx, d.Conn, &identity, metaDataJSON, preferencesJSON)
 	// Handle any errors that occur during insertion
 	if err != nil {
 		return identity, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create identity", err)
@@ -59,15 +75,41 @@ func (d Datasource) CreateIdentity(identity model.Identity) (model.Identity, err
 	return identity, nil
 }
 
+// insertIdentity inserts an identity whose ID and creation time are already set.
+func insertIdentity(ctx context.Context, db dbExecutor, identity *model.Identity, metaDataJSON []byte, preferencesJSON interface{}) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO blnk.identity (identity_id, identity_type, first_name, last_name, other_names, gender, dob, email_address, phone_number, nationality, organization_name, category, street, country, state, post_code, city, created_at, meta_data, locale, timezone, communication_preferences)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
+	`, identity.IdentityID, identity.IdentityType, identity.FirstName, identity.LastName, identity.OtherNames, identity.Gender, identity.DOB, identity.EmailAddress, identity.PhoneNumber, identity.Nationality, identity.OrganizationName, identity.Category, identity.Street, identity.Country, identity.State, identity.PostCode, identity.City, identity.CreatedAt, metaDataJSON, identity.Locale, identity.Timezone, preferencesJSON)
+	return err
+}
+
 // GetIdentityByID retrieves an identity from the database based on the given identity ID.
-// It starts a transaction, executes a query to fetch the identity details, and commits the transaction upon success.
+// Deleted identities are not found.
+// Parameters:
+// - ctx: The context for the operation.
+// - id: The ID of the identity to be retrieved.
+// Returns:
+// - A pointer to the Identity object if found, or an error if the identity is not found or the query fails.
+func (d Datasource) GetIdentityByID(ctx context.Context, id string) (*model.Identity, error) {
+	return d.getIdentityByID(ctx, id, false)
+}
+
+// GetIdentityByIDIncludingDeleted retrieves an identity by ID whether or not it has been deleted.
 // Parameters:
+// - ctx: The context for the operation.
 // - id: The ID of the identity to be retrieved.
 // Returns:
 // - A pointer to the Identity object if found, or an error if the identity is not found or the query fails.
-func (d Datasource) GetIdentityByID(id string) (*model.Identity, error) {
+func (d Datasource) GetIdentityByIDIncludingDeleted(ctx context.Context, id string) (*model.Identity, error) {
+	return d.getIdentityByID(ctx, id, true)
+}
+
+// getIdentityByID starts a transaction, executes a query to fetch the identity details, and commits the
+// transaction upon success. Deleted identities are only found with includeDeleted.
+func (d Datasource) getIdentityByID(ctx context.Context, id string, includeDeleted bool) (*model.Identity, error) {
 	// Set a timeout for the context and ensure cancellation
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
 	defer cancel()
 
 	// Begin a transaction
@@ -77,14 +119,21 @@ func (d Datasource) GetIdentityByID(id string) (*model.Identity, error) {
 	}
 
 	// Query the database for the identity by ID
-	row := tx.QueryRow(`
-		SELECT identity_id, identity_type, first_name, last_name, other_names, gender, dob, email_address, phone_number, nationality, organization_name, category, street, country, state, post_code, city, created_at, meta_data
+	query := `
+		SELECT identity_id, identity_type, first_name, last_name, other_names, gender, dob, email_address, phone_number, nationality, organization_name, category, street, country, state, post_code, city, created_at, meta_data, locale, timezone, communication_preferences, deleted_at,
+			verification_status, verification_reason, verification_submitted_at, verified_at, verification_rejected_at,
+			risk_score, risk_level, risk_scored_at, email_verified_at, phone_verified_at, tags,
+			status, status_reason, status_changed_at, version
 		FROM blnk.identity
-		WHERE identity_id = $1
-	`, id)
+		WHERE identity_id = $1`
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+	row := tx.QueryRowContext(ctx, query, id)
 
 	identity := &model.Identity{}
-	var metaDataJSON []byte
+	var metaDataJSON, preferencesJSON []byte
+	var verificationReason, riskLevel, statusReason sql.NullString
 
 	// Scan the row into the identity object
 	err = row.Scan(
@@ -92,6 +141,11 @@ func (d Datasource) GetIdentityByID(id string) (*model.Identity, error) {
 		&identity.FirstName, &identity.LastName, &identity.OtherNames, &identity.Gender, &identity.DOB, &identity.EmailAddress, &identity.PhoneNumber, &identity.Nationality,
 		&identity.OrganizationName, &identity.Category,
 		&identity.Street, &identity.Country, &identity.State, &identity.PostCode, &identity.City, &identity.CreatedAt, &metaDataJSON,
+		&identity.Locale, &identity.Timezone, &preferencesJSON, &identity.DeletedAt,
+		&identity.VerificationStatus, &verificationReason, &identity.VerificationSubmittedAt, &identity.VerifiedAt, &identity.VerificationRejectedAt,
+		&identity.RiskScore, &riskLevel, &identity.RiskScoredAt,
+		&identity.EmailVerifiedAt, &identity.PhoneVerifiedAt, pq.Array(&identity.Tags),
+		&identity.Status, &statusReason, &identity.StatusChangedAt, &identity.Version,
 	)
 	// Handle potential errors during the scan
 	if err != nil {
@@ -101,6 +155,11 @@ func (d Datasource) GetIdentityByID(id string) (*model.Identity, error) {
 		}
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve identity", err)
 	}
+	identity.VerificationReason = verificationReason.String
+	identity.RiskLevel = riskLevel.String
+	identity.StatusReason = statusReason.String
+	identity.VerifiedEmail = identity.EmailVerifiedAt != nil
+	identity.VerifiedPhone = identity.PhoneVerifiedAt != nil
 
 	// Unmarshal the metadata JSON into the identity's MetaData field
 	err = json.Unmarshal(metaDataJSON, &identity.MetaData)
@@ -108,6 +167,10 @@ func (d Datasource) GetIdentityByID(id string) (*model.Identity, error) {
 		_ = tx.Rollback()
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal metadata", err)
 	}
+	if err = unmarshalCommunicationPreferences(preferencesJSON, identity); err != nil {
+		_ = tx.Rollback()
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal communication preferences", err)
+	}
 
 	// Commit the transaction
 	err = tx.Commit()
@@ -119,17 +182,121 @@ func (d Datasource) GetIdentityByID(id string) (*model.Identity, error) {
 	return identity, nil
 }
 
-// GetAllIdentities retrieves all identities from the database.
-// It executes a query to fetch all identity records, parses the result into Identity structs, and handles metadata unmarshalling.
+// GetAllIdentities retrieves all identities from the database, newest first.
 // Returns:
 // - A slice of Identity objects if successful, or an error if any operation fails.
-func (d Datasource) GetAllIdentities() ([]model.Identity, error) {
-	// Execute query to retrieve all identities, ordered by creation date
-	rows, err := d.Conn.Query(`
-		SELECT identity_id, identity_type, first_name, last_name, other_names, gender, dob, email_address, phone_number, nationality, organization_name, category, street, country, state, post_code, city, created_at, meta_data
-		FROM blnk.identity
-		ORDER BY created_at DESC
-	`)
+func (d Datasource) GetAllIdentities(ctx context.Context) ([]model.Identity, error) {
+	return d.GetIdentities(ctx, model.IdentityFilter{}, model.Page{})
+}
+
+// identityKeyset is the order identities are listed in, newest first.
+var identityKeyset = keyset{timeColumn: "created_at", idColumn: "identity_id", descending: true}
+
+// GetIdentities retrieves the identities matching a filter, newest first, or riskiest first when the filter sets a
+// minimum risk score. Deleted identities are only included when the filter asks for them. Identities are read
+// from a read replica when one is configured.
+// It builds a WHERE clause from the non-empty fields of the filter, parses the result into Identity structs, and handles metadata unmarshalling.
+// Parameters:
+// - ctx: The context for the operation.
+// - filter: The fields the identities must match. An empty filter matches every identity.
+// - page: The page of identities to read; a limit of 0 reads all of them. Identities filtered by risk score are
+// ordered riskiest first, which a cursor cannot page through, so those pages are read by offset.
+// Returns:
+// - A slice of Identity objects if successful, or an error if any operation fails.
+func (d Datasource) GetIdentities(ctx context.Context, filter model.IdentityFilter, page model.Page) ([]model.Identity, error) {
+	var conditions []string
+	var args []interface{}
+
+	// Helper function to add a condition for a filter field if it has a value
+	addCondition := func(value, condition string) {
+		if value == "" {
+			return
+		}
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	addCondition(filter.EmailAddress, "lower(email_address) = lower($%d)")
+	addCondition(filter.PhoneNumber, "phone_number = $%d")
+	addCondition(filter.Nationality, "nationality = $%d")
+	addCondition(filter.Country, "country = $%d")
+	addCondition(filter.Category, "category = $%d")
+	addCondition(filter.IdentityType, "identity_type = $%d")
+	addCondition(filter.Status, "status = $%d")
+	addCondition(filter.VerificationStatus, "verification_status = $%d")
+	addCondition(filter.RiskLevel, "risk_level = $%d")
+	if tags := model.ParseIdentityTagFilter(filter.Tags); len(tags) > 0 {
+		args = append(args, pq.Array(tags))
+		conditions = append(conditions, fmt.Sprintf("tags @> $%d::text[]", len(args)))
+	}
+	// Metadata filters use the GIN index on meta_data: values by containment and bare keys by a path match
+	values, keys := model.ParseIdentityMetadataFilter(filter.MetaData)
+	if len(values) > 0 {
+		valuesJSON, err := json.Marshal(values)
+		if err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata filter", err)
+		}
+		args = append(args, string(valuesJSON))
+		conditions = append(conditions, fmt.Sprintf("meta_data @> $%d::jsonb", len(args)))
+	}
+	for _, key := range keys {
+		keyJSON, err := json.Marshal(key)
+		if err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata filter", err)
+		}
+		args = append(args, "$."+string(keyJSON))
+		conditions = append(conditions, fmt.Sprintf("meta_data @? $%d::jsonpath", len(args)))
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, "created_after must be before created_before", nil)
+	}
+	if filter.CreatedAfter != nil {
+		args = append(args, *filter.CreatedAfter)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.CreatedBefore != nil {
+		args = append(args, *filter.CreatedBefore)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if filter.MinRiskScore > 0 {
+		args = append(args, filter.MinRiskScore)
+		conditions = append(conditions, fmt.Sprintf("risk_score >= $%d", len(args)))
+	}
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	var suffix string
+	if filter.MinRiskScore > 0 {
+		if page.After != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInvalidInput, "identities filtered by risk score are paged by offset", nil)
+		}
+		// Riskiest identities first, for compliance review
+		suffix = "\n\t\tORDER BY risk_score DESC, created_at DESC"
+		if page.Limit > 0 {
+			args = append(args, page.Limit, page.Offset)
+			suffix += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+		}
+	} else {
+		var condition string
+		condition, suffix, args = identityKeyset.page(page, args)
+		if page.After != nil {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	query := `
+		SELECT identity_id, identity_type, first_name, last_name, other_names, gender, dob, email_address, phone_number, nationality, organization_name, category, street, country, state, post_code, city, created_at, meta_data, locale, timezone, communication_preferences, deleted_at,
+			verification_status, verification_reason, verification_submitted_at, verified_at, verification_rejected_at,
+			risk_score, risk_level, risk_scored_at, email_verified_at, phone_verified_at, tags,
+			status, status_reason, status_changed_at, version
+		FROM blnk.identity`
+	if len(conditions) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+	query += suffix
+
+	rows, err := d.readQuery(ctx, query, args...)
 	if err != nil {
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve identities", err)
 	}
@@ -140,23 +307,8 @@ func (d Datasource) GetAllIdentities() ([]model.Identity, error) {
 	// Iterate through the result set
 	for rows.Next() {
 		identity := model.Identity{}
-		var metaDataJSON []byte
-
-		// Scan the row into the identity object
-		err = rows.Scan(
-			&identity.IdentityID, &identity.IdentityType,
-			&identity.FirstName, &identity.LastName, &identity.OtherNames, &identity.Gender, &identity.DOB, &identity.EmailAddress, &identity.PhoneNumber, &identity.Nationality,
-			&identity.OrganizationName, &identity.Category,
-			&identity.Street, &identity.Country, &identity.State, &identity.PostCode, &identity.City, &identity.CreatedAt, &metaDataJSON,
-		)
-		if err != nil {
-			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan identity data", err)
-		}
-
-		// Unmarshal metadata JSON into the MetaData field
-		err = json.Unmarshal(metaDataJSON, &identity.MetaData)
-		if err != nil {
-			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal metadata", err)
+		if err = scanIdentity(rows, &identity); err != nil {
+			return nil, err
 		}
 
 		// Append the identity to the slice
@@ -172,13 +324,56 @@ func (d Datasource) GetAllIdentities() ([]model.Identity, error) {
 	return identities, nil
 }
 
+// scanIdentity scans a row of the identity columns selected by GetIdentities into an identity, unmarshalling its
+// metadata and communication preferences.
+func scanIdentity(row rowScanner, identity *model.Identity) error {
+	var metaDataJSON, preferencesJSON []byte
+	var verificationReason, riskLevel, statusReason sql.NullString
+
+	err := row.Scan(
+		&identity.IdentityID, &identity.IdentityType,
+		&identity.FirstName, &identity.LastName, &identity.OtherNames, &identity.Gender, &identity.DOB, &identity.EmailAddress, &identity.PhoneNumber, &identity.Nationality,
+		&identity.OrganizationName, &identity.Category,
+		&identity.Street, &identity.Country, &identity.State, &identity.PostCode, &identity.City, &identity.CreatedAt, &metaDataJSON,
+		&identity.Locale, &identity.Timezone, &preferencesJSON, &identity.DeletedAt,
+		&identity.VerificationStatus, &verificationReason, &identity.VerificationSubmittedAt, &identity.VerifiedAt, &identity.VerificationRejectedAt,
+		&identity.RiskScore, &riskLevel, &identity.RiskScoredAt,
+		&identity.EmailVerifiedAt, &identity.PhoneVerifiedAt, pq.Array(&identity.Tags),
+		&identity.Status, &statusReason, &identity.StatusChangedAt, &identity.Version,
+	)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan identity data", err)
+	}
+	identity.VerificationReason = verificationReason.String
+	identity.RiskLevel = riskLevel.String
+	identity.StatusReason = statusReason.String
+	identity.VerifiedEmail = identity.EmailVerifiedAt != nil
+	identity.VerifiedPhone = identity.PhoneVerifiedAt != nil
+
+	if err = json.Unmarshal(metaDataJSON, &identity.MetaData); err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal metadata", err)
+	}
+	if err = unmarshalCommunicationPreferences(preferencesJSON, identity); err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal communication preferences", err)
+	}
+	return nil
+}
+
 // UpdateIdentity updates a specific identity record in the database.
-// It marshals the identity metadata, constructs an SQL update query, and checks the result.
+// It marshals the identity metadata, constructs an SQL update query, and checks the result. Deleted identities
+// must be restored before they can be updated. The identity as it was before the update is written to its
+// history in the same database transaction, with the fields the update changed; updates that change nothing
+// are not recorded. Every update moves the identity on to its next version. An update naming the version it was
+// made against, in identity.Version, is rejected with a conflict when the identity is no longer at that version;
+// one naming no version is applied to the identity as it is. identity.Version is set to the new version. The
+// metadata replaces the identity's, or is merged into it as a JSON merge patch when identity.MergeMetaData is set.
 // Parameters:
+// - ctx: The context for the operation.
 // - identity: A pointer to the Identity object containing the updated details.
+// - changedBy: Who made the update, recorded in the history.
 // Returns:
-// - An error if the update fails, or nil if successful.
-func (d Datasource) UpdateIdentity(identity *model.Identity) error {
+// - An error if the update fails or the identity has been updated since its version, or nil if successful.
+func (d Datasource) UpdateIdentity(ctx context.Context, identity *model.Identity, changedBy string) error {
 	var setFields []string
 	var args []interface{}
 	argPosition := 1
@@ -214,7 +409,9 @@ func (d Datasource) UpdateIdentity(identity *model.Identity) error {
 	addField(identity.OtherNames, "other_names")
 	addField(identity.Gender, "gender")
 	addField(identity.DOB, "dob")
+	emailPosition := argPosition
 	addField(identity.EmailAddress, "email_address")
+	phonePosition := argPosition
 	addField(identity.PhoneNumber, "phone_number")
 	addField(identity.Nationality, "nationality")
 	addField(identity.OrganizationName, "organization_name")
@@ -224,14 +421,38 @@ func (d Datasource) UpdateIdentity(identity *model.Identity) error {
 	addField(identity.State, "state")
 	addField(identity.PostCode, "post_code")
 	addField(identity.City, "city")
+	addField(identity.Locale, "locale")
+	addField(identity.Timezone, "timezone")
+
+	// A changed email address or phone number has not been verified, so its verification is cleared
+	if identity.EmailAddress != "" {
+		setFields = append(setFields, fmt.Sprintf("email_verified_at = CASE WHEN email_address = $%d THEN email_verified_at END", emailPosition))
+	}
+	if identity.PhoneNumber != "" {
+		setFields = append(setFields, fmt.Sprintf("phone_verified_at = CASE WHEN phone_number = $%d THEN phone_verified_at END", phonePosition))
+	}
+
+	// Communication preferences are replaced as a whole when provided
+	if identity.CommunicationPreferences != nil {
+		preferencesJSON, err := marshalCommunicationPreferences(identity.CommunicationPreferences)
+		if err != nil {
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal communication preferences", err)
+		}
+		setFields = append(setFields, fmt.Sprintf("communication_preferences = $%d", argPosition))
+		args = append(args, preferencesJSON)
+		argPosition++
+	}
 
-	// Always update metadata if it exists
+	// Always update metadata if it exists. Metadata merged into the identity's is only known once the identity
+	// is locked, so its argument is filled in then
+	metaDataArg := -1
 	if identity.MetaData != nil {
 		metaDataJSON, err := json.Marshal(identity.MetaData)
 		if err != nil {
 			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
 		}
 		setFields = append(setFields, fmt.Sprintf("meta_data = $%d", argPosition))
+		metaDataArg = len(args)
 		args = append(args, metaDataJSON)
 		argPosition++
 	}
@@ -240,62 +461,219 @@ func (d Datasource) UpdateIdentity(identity *model.Identity) error {
 	if len(setFields) == 0 {
 		return apierror.NewAPIError(apierror.ErrBadRequest, "No fields provided for update", nil)
 	}
+	setFields = append(setFields, "version = version + 1")
 
 	// Build the SQL query
 	query := fmt.Sprintf(`
 		UPDATE blnk.identity
 		SET %s
-		WHERE identity_id = $%d
+		WHERE identity_id = $%d AND deleted_at IS NULL
 	`, strings.Join(setFields, ", "), argPosition)
 
 	// Add identity ID as the last argument
 	args = append(args, identity.IdentityID)
 
-	// Execute the update query
-	result, err := d.Conn.Exec(query, args...)
+	tx, err := d.Conn.BeginTx(ctx, nil)
 	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to begin transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// Lock the identity so that concurrent updates are recorded one after the other
+	previous, err := selectIdentity(ctx, tx, identity.IdentityID, true)
+	if err != nil {
+		return err
+	}
+	if identity.Version > 0 && previous.Version != identity.Version {
+		return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Identity with ID '%s' is at version %d, not %d", identity.IdentityID, previous.Version, identity.Version), nil)
+	}
+	if identity.MergeMetaData && metaDataArg >= 0 {
+		metaDataJSON, err := json.Marshal(model.MergeMetaDataPatch(previous.MetaData, identity.MetaData))
+		if err != nil {
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
+		}
+		args[metaDataArg] = metaDataJSON
+	}
+
+	// Execute the update query
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
 		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update identity", err)
 	}
 
+	updated, err := selectIdentity(ctx, tx, identity.IdentityID, false)
+	if err != nil {
+		return err
+	}
+	// The update is checked against the identity type as it leaves the identity, since it may change the type
+	if err := validateIdentityType(updated); err != nil {
+		return err
+	}
+	if err := recordIdentityVersion(ctx, tx, previous, updated, changedBy); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to commit identity update", err)
+	}
+	identity.Version = updated.Version
+	return nil
+}
+
+// DeleteIdentity soft-deletes a specific identity record by setting its deleted_at timestamp.
+// The row is kept so that transactions and balances referencing the identity remain auditable.
+// Parameters:
+// - ctx: The context for the operation.
+// - id: The ID of the identity to be deleted.
+// Returns:
+// - An error if the deletion fails or the identity is not found or already deleted, or nil if successful.
+func (d Datasource) DeleteIdentity(ctx context.Context, id string) error {
+	// Mark the identity as deleted
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.identity
+		SET deleted_at = $2
+		WHERE identity_id = $1 AND deleted_at IS NULL
+	`, id, time.Now())
+	// Handle any errors that occur during execution
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to delete identity", err)
+	}
+
+	// Check how many rows were affected by the update query
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
 	}
 
+	// If no rows were deleted, return a "not found" error
 	if rowsAffected == 0 {
-		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity with ID '%s' not found", identity.IdentityID), nil)
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity with ID '%s' not found", id), nil)
 	}
 
 	return nil
 }
 
-// DeleteIdentity deletes a specific identity record from the database.
-// It executes the SQL delete query based on the provided identity ID.
+// RestoreIdentity restores a soft-deleted identity by clearing its deleted_at timestamp.
 // Parameters:
-// - id: The ID of the identity to be deleted.
+// - ctx: The context for the operation.
+// - id: The ID of the identity to be restored.
 // Returns:
-// - An error if the deletion fails, or nil if successful.
-func (d Datasource) DeleteIdentity(id string) error {
-	// Execute the SQL delete query
-	result, err := d.Conn.Exec(`
-		DELETE FROM blnk.identity
-		WHERE identity_id = $1
+// - An error if the restore fails or no deleted identity has the ID, or nil if successful.
+func (d Datasource) RestoreIdentity(ctx context.Context, id string) error {
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.identity
+		SET deleted_at = NULL
+		WHERE identity_id = $1 AND deleted_at IS NOT NULL
 	`, id)
-	// Handle any errors that occur during execution
 	if err != nil {
-		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to delete identity", err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to restore identity", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Deleted identity with ID '%s' not found", id), nil)
+	}
+
+	return nil
+}
+
+// verificationTimestampColumns maps each verification status to the column recording when an identity last
+// entered it.
+var verificationTimestampColumns = map[string]string{
+	model.VerificationPending:  "verification_submitted_at",
+	model.VerificationVerified: "verified_at",
+	model.VerificationRejected: "verification_rejected_at",
+}
+
+// UpdateIdentityVerification moves an identity's verification from one status to another, recording the reason
+// and when it entered the new status. The update only applies while the identity is still in the from status,
+// so concurrent changes cannot both succeed.
+// Parameters:
+// - ctx: The context for the operation.
+// - id: The ID of the identity.
+// - from: The status the identity is expected to be in.
+// - to: The status to move the identity to.
+// - reason: Why the status changed, or empty.
+// - at: When the status changed.
+// Returns:
+// - An error if the identity is not found, is deleted, or is no longer in the from status, or if the update fails.
+func (d Datasource) UpdateIdentityVerification(ctx context.Context, id, from, to, reason string, at time.Time) error {
+	column, ok := verificationTimestampColumns[to]
+	if !ok {
+		return apierror.NewAPIError(apierror.ErrBadRequest, fmt.Sprintf("unknown verification status '%s'", to), nil)
+	}
+
+	result, err := d.Conn.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE blnk.identity
+		SET verification_status = $3, verification_reason = $4, %s = $5
+		WHERE identity_id = $1 AND verification_status = $2 AND deleted_at IS NULL
+	`, column), id, from, to, nullString(reason), at)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update identity verification", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Identity with ID '%s' is no longer %s", id, from), nil)
+	}
+
+	return nil
+}
+
+// UpdateIdentityRisk records the risk score and risk level of an identity and when it was scored.
+// Parameters:
+// - ctx: The context for the operation.
+// - id: The ID of the identity.
+// - score: The risk score of the identity.
+// - level: The risk level of the score.
+// - at: When the identity was scored.
+// Returns:
+// - An error if the identity is not found or is deleted, or if the update fails.
+func (d Datasource) UpdateIdentityRisk(ctx context.Context, id string, score float64, level string, at time.Time) error {
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.identity
+		SET risk_score = $2, risk_level = $3, risk_scored_at = $4
+		WHERE identity_id = $1 AND deleted_at IS NULL
+	`, id, score, level, at)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update identity risk", err)
 	}
 
-	// Check how many rows were affected by the delete query
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
 	}
 
-	// If no rows were deleted, return a "not found" error
 	if rowsAffected == 0 {
 		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity with ID '%s' not found", id), nil)
 	}
 
 	return nil
 }
+
+// marshalCommunicationPreferences encodes communication preferences for storage. Identities without
+// preferences store NULL.
+func marshalCommunicationPreferences(preferences *model.CommunicationPreferences) (interface{}, error) {
+	if preferences == nil {
+		return nil, nil
+	}
+	return json.Marshal(preferences)
+}
+
+// unmarshalCommunicationPreferences decodes stored communication preferences into an identity.
+func unmarshalCommunicationPreferences(data []byte, identity *model.Identity) error {
+	if len(data) == 0 {
+		return nil
+	}
+	identity.CommunicationPreferences = &model.CommunicationPreferences{}
+	return json.Unmarshal(data, identity.CommunicationPreferences)
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const identityAddressColumns = `address_id, identity_id, type, COALESCE(street, ''), COALESCE(city, ''), COALESCE(state, ''), COALESCE(post_code, ''), country, meta_data, created_at, updated_at, version`
+
+// CreateIdentityAddress saves an address of an identity.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - address: The address to store.
+// Returns:
+// - An error if the insert fails.
+func (d Datasource) CreateIdentityAddress(ctx context.Context, address *model.IdentityAddress) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Creating identity address")
+	defer span.End()
+
+	metaData, err := json.Marshal(address.MetaData)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
+	}
+
+	_, err = d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.identity_addresses (address_id, identity_id, type, street, city, state, post_code, country, meta_data, created_at, updated_at, version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`,
+		address.AddressID, address.IdentityID, address.Type, nullString(address.Street), nullString(address.City),
+		nullString(address.State), nullString(address.PostCode), address.Country, metaData, address.CreatedAt, address.UpdatedAt,
+		address.Version,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create identity address", err)
+	}
+	return nil
+}
+
+// GetIdentityAddress retrieves an identity address by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - addressID: The ID of the address.
+// Returns:
+// - The address, or an error if it does not exist.
+func (d Datasource) GetIdentityAddress(ctx context.Context, addressID string) (*model.IdentityAddress, error) {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Fetching identity address")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `
+		SELECT `+identityAddressColumns+`
+		FROM blnk.identity_addresses
+		WHERE address_id = $1
+	`, addressID)
+
+	address := &model.IdentityAddress{}
+	err := scanIdentityAddress(row, address)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity address with ID '%s' not found", addressID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve identity address", err)
+	}
+	return address, nil
+}
+
+// GetIdentityAddresses retrieves the addresses of an identity, oldest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - identityID: The ID of the identity.
+// Returns:
+// - The addresses, or an error if the query fails.
+func (d Datasource) GetIdentityAddresses(ctx context.Context, identityID string) ([]*model.IdentityAddress, error) {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Fetching identity addresses")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+identityAddressColumns+`
+		FROM blnk.identity_addresses
+		WHERE identity_id = $1
+		ORDER BY created_at ASC, id ASC
+	`, identityID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve identity addresses", err)
+	}
+	defer rows.Close()
+
+	addresses := []*model.IdentityAddress{}
+	for rows.Next() {
+		address := &model.IdentityAddress{}
+		if err := scanIdentityAddress(rows, address); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan identity address", err)
+		}
+		addresses = append(addresses, address)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over identity addresses", err)
+	}
+	return addresses, nil
+}
+
+// UpdateIdentityAddress replaces the type, fields and metadata of an identity address. The identity an address
+// belongs to cannot change. The address is only updated while it is still at the version it was read at, and
+// moves on to the next version.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - address: The address as read, with its new fields and update time set.
+// Returns:
+// - An error if the address has been updated or deleted since it was read, or the update fails.
+func (d Datasource) UpdateIdentityAddress(ctx context.Context, address *model.IdentityAddress) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Updating identity address")
+	defer span.End()
+
+	metaData, err := json.Marshal(address.MetaData)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
+	}
+
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.identity_addresses
+		SET type = $2, street = $3, city = $4, state = $5, post_code = $6, country = $7, meta_data = $8, updated_at = $9,
+			version = version + 1
+		WHERE address_id = $1 AND version = $10
+	`,
+		address.AddressID, address.Type, nullString(address.Street), nullString(address.City), nullString(address.State),
+		nullString(address.PostCode), address.Country, metaData, address.UpdatedAt, address.Version,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update identity address", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Identity address with ID '%s' is no longer at version %d", address.AddressID, address.Version), nil)
+	}
+	address.Version++
+	return nil
+}
+
+// DeleteIdentityAddress deletes an identity address.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - addressID: The ID of the address.
+// Returns:
+// - An error if the address is not found or the delete fails.
+func (d Datasource) DeleteIdentityAddress(ctx context.Context, addressID string) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Deleting identity address")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `DELETE FROM blnk.identity_addresses WHERE address_id = $1`, addressID)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to delete identity address", err)
+	}
+	return addressRowsAffected(result, addressID)
+}
+
+// addressRowsAffected checks that a delete of an address found it.
+func addressRowsAffected(result sql.Result, addressID string) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity address with ID '%s' not found", addressID), nil)
+	}
+	return nil
+}
+
+func scanIdentityAddress(row rowScanner, address *model.IdentityAddress) error {
+	var metaData []byte
+	err := row.Scan(
+		&address.AddressID, &address.IdentityID, &address.Type, &address.Street, &address.City, &address.State,
+		&address.PostCode, &address.Country, &metaData, &address.CreatedAt, &address.UpdatedAt, &address.Version,
+	)
+	if err != nil {
+		return err
+	}
+	if len(metaData) == 0 {
+		return nil
+	}
+	return json.Unmarshal(metaData, &address.MetaData)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetIdentityAddresses(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	columns := []string{"address_id", "identity_id", "type", "street", "city", "state", "post_code", "country", "meta_data", "created_at", "updated_at", "version"}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity_addresses WHERE identity_id = $1 ORDER BY created_at ASC")).
+		WithArgs("idt_ada").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("addr_1", "idt_ada", model.AddressResidential, "1 Main St", "London", "", "N1 1AA", "GB", []byte(`{"since":"2020"}`), now, now, 1).
+			AddRow("addr_2", "idt_ada", model.AddressMailing, "", "", "", "", "GB", []byte(`null`), now, now, 3))
+
+	addresses, err := ds.GetIdentityAddresses(context.Background(), "idt_ada")
+	require.NoError(t, err)
+	require.Len(t, addresses, 2)
+	assert.Equal(t, model.AddressResidential, addresses[0].Type)
+	assert.Equal(t, "N1 1AA", addresses[0].PostCode)
+	assert.Equal(t, "2020", addresses[0].MetaData["since"])
+	assert.Equal(t, model.AddressMailing, addresses[1].Type)
+	assert.Nil(t, addresses[1].MetaData)
+	assert.Equal(t, 3, addresses[1].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateIdentityAddress(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	address := &model.IdentityAddress{AddressID: "addr_1", IdentityID: "idt_ada", Type: model.AddressBusiness, City: "Lagos", Country: "NG", CreatedAt: now, UpdatedAt: now, Version: 1}
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.identity_addresses")).
+		WithArgs("addr_1", "idt_ada", model.AddressBusiness, nil, "Lagos", nil, nil, "NG", []byte(`null`), now, now, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	require.NoError(t, ds.CreateIdentityAddress(context.Background(), address))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateIdentityAddress_Version(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	address := &model.IdentityAddress{AddressID: "addr_1", IdentityID: "idt_ada", Type: model.AddressBusiness, Country: "NG", UpdatedAt: now, Version: 2}
+	update := regexp.QuoteMeta("UPDATE blnk.identity_addresses")
+	mock.ExpectExec(update).
+		WithArgs("addr_1", model.AddressBusiness, nil, nil, nil, nil, "NG", []byte(`null`), now, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, ds.UpdateIdentityAddress(context.Background(), address))
+	assert.Equal(t, 3, address.Version)
+
+	// An address updated since it was read is not overwritten
+	mock.ExpectExec(update).
+		WithArgs("addr_1", model.AddressBusiness, nil, nil, nil, nil, "NG", []byte(`null`), now, 3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	err = ds.UpdateIdentityAddress(context.Background(), address)
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrConflict, apiErr.Code)
+	assert.Equal(t, 3, address.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteIdentityAddress_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM blnk.identity_addresses")).
+		WithArgs("addr_missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = ds.DeleteIdentityAddress(context.Background(), "addr_missing")
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
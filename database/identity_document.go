@@ -0,0 +1,168 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const identityDocumentColumns = `document_id, identity_id, document_type, file_name, content_type, size, storage_key, expires_at, verification_status, verification_reason, verified_at, created_at`
+
+// CreateIdentityDocument saves the metadata of a document uploaded for an identity.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - document: The document to store.
+// Returns:
+// - An error if the document could not be saved.
+func (d Datasource) CreateIdentityDocument(ctx context.Context, document *model.IdentityDocument) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Creating identity document")
+	defer span.End()
+
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.identity_documents (`+identityDocumentColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`,
+		document.DocumentID, document.IdentityID, document.DocumentType, document.FileName, document.ContentType,
+		document.Size, document.StorageKey, document.ExpiresAt, document.VerificationStatus,
+		nullString(document.VerificationReason), document.VerifiedAt, document.CreatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create identity document", err)
+	}
+	return nil
+}
+
+// GetIdentityDocument retrieves an identity document by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - documentID: The ID of the document.
+// Returns:
+// - The document, or an error if it does not exist.
+func (d Datasource) GetIdentityDocument(ctx context.Context, documentID string) (*model.IdentityDocument, error) {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Fetching identity document")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `
+		SELECT `+identityDocumentColumns+`
+		FROM blnk.identity_documents
+		WHERE document_id = $1
+	`, documentID)
+
+	document, err := scanIdentityDocument(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity document with ID '%s' not found", documentID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve identity document", err)
+	}
+	return document, nil
+}
+
+// GetIdentityDocuments retrieves the documents of an identity, newest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - identityID: The ID of the identity.
+// Returns:
+// - The documents, or an error if the query fails.
+func (d Datasource) GetIdentityDocuments(ctx context.Context, identityID string) ([]*model.IdentityDocument, error) {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Fetching identity documents")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+identityDocumentColumns+`
+		FROM blnk.identity_documents
+		WHERE identity_id = $1
+		ORDER BY created_at DESC
+	`, identityID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve identity documents", err)
+	}
+	defer rows.Close()
+
+	documents := []*model.IdentityDocument{}
+	for rows.Next() {
+		document, err := scanIdentityDocument(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan identity document", err)
+		}
+		documents = append(documents, document)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over identity documents", err)
+	}
+	return documents, nil
+}
+
+// UpdateIdentityDocumentVerification records the review of a document. The update only applies while the
+// document is still pending, so concurrent reviews cannot both succeed.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - documentID: The ID of the document.
+// - status: The status the review ended with.
+// - reason: Why the document was verified or rejected, or empty.
+// - at: When the document was reviewed.
+// Returns:
+// - An error if the document is not found or no longer pending, or if the update fails.
+func (d Datasource) UpdateIdentityDocumentVerification(ctx context.Context, documentID, status, reason string, at time.Time) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Updating identity document verification")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.identity_documents
+		SET verification_status = $2, verification_reason = $3, verified_at = $4
+		WHERE document_id = $1 AND verification_status = $5
+	`, documentID, status, nullString(reason), at, model.VerificationPending)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update identity document verification", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Identity document with ID '%s' is no longer pending", documentID), nil)
+	}
+	return nil
+}
+
+func scanIdentityDocument(row rowScanner) (*model.IdentityDocument, error) {
+	document := &model.IdentityDocument{}
+	var reason sql.NullString
+	err := row.Scan(
+		&document.DocumentID, &document.IdentityID, &document.DocumentType, &document.FileName, &document.ContentType,
+		&document.Size, &document.StorageKey, &document.ExpiresAt, &document.VerificationStatus, &reason,
+		&document.VerifiedAt, &document.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	document.VerificationReason = reason.String
+	return document, nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var identityDocumentTestColumns = []string{"document_id", "identity_id", "document_type", "file_name", "content_type", "size", "storage_key", "expires_at", "verification_status", "verification_reason", "verified_at", "created_at"}
+
+func TestGetIdentityDocuments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity_documents")).
+		WithArgs("idt_1").
+		WillReturnRows(sqlmock.NewRows(identityDocumentTestColumns).
+			AddRow("doc_2", "idt_1", model.DocumentTypeUtilityBill, "bill.png", "image/png", 2048, "identity-documents/idt_1/doc_2.png", nil, model.VerificationPending, nil, nil, now).
+			AddRow("doc_1", "idt_1", model.DocumentTypePassport, "passport.pdf", "application/pdf", 1024, "identity-documents/idt_1/doc_1.pdf", now.AddDate(5, 0, 0), model.VerificationRejected, "blurry", now, now))
+
+	documents, err := ds.GetIdentityDocuments(context.Background(), "idt_1")
+	require.NoError(t, err)
+	require.Len(t, documents, 2)
+	assert.Nil(t, documents[0].ExpiresAt)
+	assert.Equal(t, int64(2048), documents[0].Size)
+	assert.NotNil(t, documents[1].ExpiresAt)
+	assert.Equal(t, "blurry", documents[1].VerificationReason)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetIdentityDocument_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity_documents")).
+		WithArgs("doc_missing").
+		WillReturnRows(sqlmock.NewRows(identityDocumentTestColumns))
+
+	_, err = ds.GetIdentityDocument(context.Background(), "doc_missing")
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateIdentityDocumentVerification_NoLongerPending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.identity_documents")).
+		WithArgs("doc_1", model.VerificationVerified, nil, sqlmock.AnyArg(), model.VerificationPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = ds.UpdateIdentityDocumentVerification(context.Background(), "doc_1", model.VerificationVerified, "", time.Now())
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrConflict, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+// AnonymizeIdentity erases the personal fields of an identity in a single database transaction: the fields are
+// cleared on the identity, its addresses are deleted, and the fields are overwritten in every previous version kept
+// in its history, with the changes recorded of them emptied, and the receipt of the erasure is written. Deleted identities can be erased too.
+// The number of versions scrubbed is set on the erasure.
+// Parameters:
+// - ctx: The context for the operation.
+// - erasure: The receipt to write, with its ID, identity, fields, reason, requester and time set.
+// Returns:
+// - An error if the identity does not exist or was already erased, or if any write fails.
+func (d Datasource) AnonymizeIdentity(ctx context.Context, erasure *model.IdentityErasure) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Anonymizing identity")
+	defer span.End()
+
+	tx, err := d.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to begin transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := anonymizeIdentity(ctx, tx, erasure); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to commit identity erasure", err)
+	}
+	return nil
+}
+
+func anonymizeIdentity(ctx context.Context, tx *sql.Tx, erasure *model.IdentityErasure) error {
+	identity := &model.Identity{}
+	err := scanIdentity(tx.QueryRowContext(ctx, `
+		SELECT identity_id, identity_type, first_name, last_name, other_names, gender, dob, email_address, phone_number, nationality, organization_name, category, street, country, state, post_code, city, created_at, meta_data, locale, timezone, communication_preferences, deleted_at,
+			verification_status, verification_reason, verification_submitted_at, verified_at, verification_rejected_at,
+			risk_score, risk_level, risk_scored_at, email_verified_at, phone_verified_at, tags,
+			status, status_reason, status_changed_at, version
+		FROM blnk.identity
+		WHERE identity_id = $1
+		FOR UPDATE`, erasure.IdentityID), identity)
+	if err != nil {
+		if apiErr, ok := err.(apierror.APIError); ok && apiErr.Details == sql.ErrNoRows {
+			return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity with ID '%s' not found", erasure.IdentityID), sql.ErrNoRows)
+		}
+		return err
+	}
+
+	model.AnonymizeIdentity(identity)
+	metaDataJSON, err := json.Marshal(identity.MetaData)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE blnk.identity
+		SET first_name = $2, last_name = $3, other_names = $4, email_address = $5, phone_number = $6, dob = $7,
+			street = $8, city = $9, state = $10, post_code = $11, country = $12, meta_data = $13,
+			email_verified_at = NULL, phone_verified_at = NULL
+		WHERE identity_id = $1`,
+		identity.IdentityID, identity.FirstName, identity.LastName, identity.OtherNames, identity.EmailAddress, identity.PhoneNumber, identity.DOB,
+		identity.Street, identity.City, identity.State, identity.PostCode, identity.Country, metaDataJSON)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to anonymize identity", err)
+	}
+
+	// Addresses are personal fields held apart from the identity, so they are removed rather than kept blank
+	if _, err := tx.ExecContext(ctx, `DELETE FROM blnk.identity_addresses WHERE identity_id = $1`, erasure.IdentityID); err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to erase identity addresses", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM blnk.contact_verifications WHERE identity_id = $1`, erasure.IdentityID); err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to erase contact verifications", err)
+	}
+
+	// Previous versions keep the erased values too, both in the identity as it was and in the changes recorded
+	values, err := model.ErasedIdentityValues(identity)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal erased fields", err)
+	}
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal erased fields", err)
+	}
+	result, err := tx.ExecContext(ctx, `
+		UPDATE blnk.identity_history
+		SET previous = previous || $2::jsonb,
+			changes = (
+				SELECT COALESCE(jsonb_agg(
+					CASE WHEN change->>'field' = ANY($3) THEN jsonb_build_object('field', change->'field', 'from', NULL, 'to', NULL) ELSE change END
+					ORDER BY position), '[]'::jsonb)
+				FROM jsonb_array_elements(changes) WITH ORDINALITY AS element(change, position)
+			)
+		WHERE identity_id = $1`, erasure.IdentityID, valuesJSON, pq.Array(erasure.Fields))
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scrub identity history", err)
+	}
+	scrubbed, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scrub identity history", err)
+	}
+	erasure.HistoryVersions = int(scrubbed)
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO blnk.identity_erasures (erasure_id, identity_id, fields, history_versions, reason, requested_by, erased_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		erasure.ErasureID, erasure.IdentityID, pq.Array(erasure.Fields), erasure.HistoryVersions, nullString(erasure.Reason), erasure.RequestedBy, erasure.ErasedAt)
+	if err != nil {
+		if pgconn.HasCode(err, pgconn.UniqueViolation) {
+			return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Identity with ID '%s' has already been erased", erasure.IdentityID), err)
+		}
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record identity erasure", err)
+	}
+	return nil
+}
+
+// GetIdentityErasure retrieves the receipt of an identity's erasure.
+// Parameters:
+// - ctx: The context for the operation.
+// - identityID: The ID of the identity.
+// Returns:
+// - The receipt, or an error if the identity was not erased or the query fails.
+func (d Datasource) GetIdentityErasure(ctx context.Context, identityID string) (*model.IdentityErasure, error) {
+	erasure := &model.IdentityErasure{}
+	var reason sql.NullString
+	err := d.Conn.QueryRowContext(ctx, `
+		SELECT erasure_id, identity_id, fields, history_versions, reason, requested_by, erased_at
+		FROM blnk.identity_erasures
+		WHERE identity_id = $1`, identityID).
+		Scan(&erasure.ErasureID, &erasure.IdentityID, pq.Array(&erasure.Fields), &erasure.HistoryVersions, &reason, &erasure.RequestedBy, &erasure.ErasedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Erasure of identity with ID '%s' not found", identityID), err)
+		}
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve identity erasure", err)
+	}
+	erasure.Reason = reason.String
+	return erasure, nil
+}
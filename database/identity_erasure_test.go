@@ -0,0 +1,107 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymizeIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	identity := &model.Identity{IdentityID: "idt_1", FirstName: "Ada", EmailAddress: "ada@example.com", City: "London", MetaData: map[string]interface{}{"tier": "gold"}}
+	erasure := &model.IdentityErasure{ErasureID: "era_1", IdentityID: "idt_1", Fields: model.ErasedIdentityFields, RequestedBy: model.ActorSystem, ErasedAt: time.Now()}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity WHERE identity_id = $1 FOR UPDATE")).
+		WithArgs("idt_1").
+		WillReturnRows(identityRows(t, identity))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.identity SET first_name = $2")).
+		WithArgs("idt_1", "", "", "", "", "", time.Time{}, "", "", "", "", "", []byte(`{"tier":"gold"}`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM blnk.identity_addresses WHERE identity_id = $1")).
+		WithArgs("idt_1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM blnk.contact_verifications WHERE identity_id = $1")).
+		WithArgs("idt_1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.identity_history SET previous = previous || $2::jsonb")).
+		WithArgs("idt_1", sqlmock.AnyArg(), pq.Array(model.ErasedIdentityFields)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.identity_erasures")).
+		WithArgs("era_1", "idt_1", pq.Array(model.ErasedIdentityFields), 3, nil, model.ActorSystem, erasure.ErasedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, ds.AnonymizeIdentity(context.Background(), erasure))
+	assert.Equal(t, 3, erasure.HistoryVersions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnonymizeIdentity_AlreadyErased(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE")).
+		WithArgs("idt_1").
+		WillReturnRows(identityRows(t, &model.Identity{IdentityID: "idt_1"}))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.identity SET")).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM blnk.identity_addresses")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM blnk.contact_verifications")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.identity_history")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.identity_erasures")).WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	err = ds.AnonymizeIdentity(context.Background(), &model.IdentityErasure{ErasureID: "era_2", IdentityID: "idt_1", Fields: model.ErasedIdentityFields, RequestedBy: model.ActorSystem, ErasedAt: time.Now()})
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrConflict, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetIdentityErasure_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity_erasures")).
+		WithArgs("idt_1").
+		WillReturnRows(sqlmock.NewRows([]string{"erasure_id"}))
+
+	_, err = ds.GetIdentityErasure(context.Background(), "idt_1")
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+// selectIdentity reads an identity that is not deleted within a database transaction, locking its row when
+// forUpdate is set.
+func selectIdentity(ctx context.Context, tx *sql.Tx, id string, forUpdate bool) (*model.Identity, error) {
+	query := `
+		SELECT identity_id, identity_type, first_name, last_name, other_names, gender, dob, email_address, phone_number, nationality, organization_name, category, street, country, state, post_code, city, created_at, meta_data, locale, timezone, communication_preferences, deleted_at,
+			verification_status, verification_reason, verification_submitted_at, verified_at, verification_rejected_at,
+			risk_score, risk_level, risk_scored_at, email_verified_at, phone_verified_at, tags,
+			status, status_reason, status_changed_at, version
+		FROM blnk.identity
+		WHERE identity_id = $1 AND deleted_at IS NULL`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	identity := &model.Identity{}
+	err := scanIdentity(tx.QueryRowContext(ctx, query, id), identity)
+	if err != nil {
+		if apiErr, ok := err.(apierror.APIError); ok && apiErr.Details == sql.ErrNoRows {
+			return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity with ID '%s' not found", id), sql.ErrNoRows)
+		}
+		return nil, err
+	}
+	return identity, nil
+}
+
+// recordIdentityVersion writes the version of an identity before an update to its history, numbered after the
+// versions already recorded. The identity must be locked by the caller. Fields tokenized by the update are
+// recorded with their tokens, and nothing is written when the update changed no field.
+func recordIdentityVersion(ctx context.Context, tx *sql.Tx, previous, updated *model.Identity, changedBy string) error {
+	model.MaskTokenizedFields(previous, updated)
+	changes, err := model.DiffIdentities(previous, updated)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to compare identity versions", err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	previousJSON, err := json.Marshal(previous)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal identity", err)
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal identity changes", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO blnk.identity_history (identity_id, version, previous, changes, changed_by, changed_at)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5
+		FROM blnk.identity_history
+		WHERE identity_id = $1
+	`, previous.IdentityID, previousJSON, changesJSON, changedBy, time.Now())
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record identity history", err)
+	}
+	return nil
+}
+
+// GetIdentityHistory retrieves the versions of an identity kept when it was updated, oldest first.
+// Parameters:
+// - ctx: The context for the operation.
+// - identityID: The ID of the identity.
+// Returns:
+// - The versions of the identity, or an error if they cannot be retrieved.
+func (d Datasource) GetIdentityHistory(ctx context.Context, identityID string) ([]*model.IdentityHistoryEntry, error) {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Fetching identity history")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT identity_id, version, previous, changes, changed_by, changed_at
+		FROM blnk.identity_history
+		WHERE identity_id = $1
+		ORDER BY version ASC
+	`, identityID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve identity history", err)
+	}
+	defer rows.Close()
+
+	history := []*model.IdentityHistoryEntry{}
+	for rows.Next() {
+		entry := &model.IdentityHistoryEntry{}
+		var previousJSON, changesJSON []byte
+		if err := rows.Scan(&entry.IdentityID, &entry.Version, &previousJSON, &changesJSON, &entry.ChangedBy, &entry.ChangedAt); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan identity history", err)
+		}
+		if err := json.Unmarshal(previousJSON, &entry.Previous); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal identity version", err)
+		}
+		if err := json.Unmarshal(changesJSON, &entry.Changes); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal identity changes", err)
+		}
+		history = append(history, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over identity history", err)
+	}
+	return history, nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// identityRows returns the rows of the identity columns selected within an identity update.
+func identityRows(t *testing.T, identity *model.Identity) *sqlmock.Rows {
+	metaDataJSON, err := json.Marshal(identity.MetaData)
+	require.NoError(t, err)
+	return sqlmock.NewRows([]string{
+		"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob", "email_address", "phone_number", "nationality", "organization_name", "category", "street", "country", "state", "post_code", "city", "created_at", "meta_data", "locale", "timezone", "communication_preferences", "deleted_at",
+		"verification_status", "verification_reason", "verification_submitted_at", "verified_at", "verification_rejected_at",
+		"risk_score", "risk_level", "risk_scored_at", "email_verified_at", "phone_verified_at", "tags", "status", "status_reason", "status_changed_at", "version",
+	}).AddRow(identity.IdentityID, identity.IdentityType, identity.FirstName, identity.LastName, identity.OtherNames, identity.Gender, identity.DOB, identity.EmailAddress, identity.PhoneNumber, identity.Nationality, identity.OrganizationName, identity.Category, identity.Street, identity.Country, identity.State, identity.PostCode, identity.City, identity.CreatedAt, metaDataJSON, identity.Locale, identity.Timezone, nil, nil, "unverified", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "active", nil, nil, max(identity.Version, 1))
+}
+
+// expectIdentityUpdate expects an identity to be locked and read as before, updated by the expectations that
+// update sets, and read again as after.
+func expectIdentityUpdate(t *testing.T, mock sqlmock.Sqlmock, before, after *model.Identity, update func()) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity WHERE identity_id = $1 AND deleted_at IS NULL FOR UPDATE")).
+		WithArgs(before.IdentityID).
+		WillReturnRows(identityRows(t, before))
+	update()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity WHERE identity_id = $1 AND deleted_at IS NULL")).
+		WithArgs(after.IdentityID).
+		WillReturnRows(identityRows(t, after))
+}
+
+func TestUpdateIdentity_RecordsHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	before := &model.Identity{IdentityID: "idt1", FirstName: "John", LastName: "Doe", EmailAddress: "old@example.com", MetaData: map[string]interface{}{}}
+	after := *before
+	after.EmailAddress = "new@example.com"
+
+	expectIdentityUpdate(t, mock, before, &after, func() {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.identity SET email_address = $1")).
+			WithArgs("new@example.com", "idt1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	})
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.identity_history")).
+		WithArgs("idt1", sqlmock.AnyArg(), []byte(`[{"field":"email_address","from":"old@example.com","to":"new@example.com"}]`), "owner_1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, ds.UpdateIdentity(context.Background(), &model.Identity{IdentityID: "idt1", EmailAddress: "new@example.com"}, "owner_1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateIdentity_UnchangedIsNotRecorded(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	identity := &model.Identity{IdentityID: "idt1", City: "Lagos", MetaData: map[string]interface{}{}}
+	expectIdentityUpdate(t, mock, identity, identity, func() {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.identity SET city = $1")).WithArgs("Lagos", "idt1").WillReturnResult(sqlmock.NewResult(0, 1))
+	})
+	mock.ExpectCommit()
+
+	require.NoError(t, ds.UpdateIdentity(context.Background(), &model.Identity{IdentityID: "idt1", City: "Lagos"}, model.ActorSystem))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateIdentity_TokenizedFieldsAreMasked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	before := &model.Identity{IdentityID: "idt1", FirstName: "John", MetaData: map[string]interface{}{}}
+	after := &model.Identity{IdentityID: "idt1", FirstName: "Tkn", MetaData: map[string]interface{}{"tokenized_fields": map[string]bool{"FirstName": true}}}
+
+	expectIdentityUpdate(t, mock, before, after, func() {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.identity SET first_name = $1")).WillReturnResult(sqlmock.NewResult(0, 1))
+	})
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.identity_history")).
+		WithArgs("idt1", sqlmock.AnyArg(), []byte(`[{"field":"meta_data","from":{},"to":{"tokenized_fields":{"FirstName":true}}}]`), "owner_1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	update := &model.Identity{IdentityID: "idt1", FirstName: "Tkn", MetaData: after.MetaData}
+	require.NoError(t, ds.UpdateIdentity(context.Background(), update, "owner_1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateIdentity_Version(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	before := &model.Identity{IdentityID: "idt1", City: "Lagos", MetaData: map[string]interface{}{}, Version: 2}
+	after := *before
+	after.City = "Abuja"
+	after.Version = 3
+
+	expectIdentityUpdate(t, mock, before, &after, func() {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.identity SET city = $1, version = version + 1")).
+			WithArgs("Abuja", "idt1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	})
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.identity_history")).
+		WithArgs("idt1", sqlmock.AnyArg(), []byte(`[{"field":"city","from":"Lagos","to":"Abuja"}]`), "owner_1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	update := &model.Identity{IdentityID: "idt1", City: "Abuja", Version: 2}
+	require.NoError(t, ds.UpdateIdentity(context.Background(), update, "owner_1"))
+	assert.Equal(t, 3, update.Version)
+
+	// An update made against a version the identity has moved on from is rejected
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE")).WithArgs("idt1").WillReturnRows(identityRows(t, &after))
+	mock.ExpectRollback()
+
+	err = ds.UpdateIdentity(context.Background(), &model.Identity{IdentityID: "idt1", City: "Kano", Version: 2}, "owner_1")
+	require.Error(t, err)
+	assert.Equal(t, apierror.ErrConflict, err.(apierror.APIError).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateIdentity_MergeMetaData(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	before := &model.Identity{IdentityID: "idt1", MetaData: map[string]interface{}{"tier": "gold", "segment": "retail"}}
+	after := *before
+	after.MetaData = map[string]interface{}{"tier": "gold", "vip": true}
+
+	expectIdentityUpdate(t, mock, before, &after, func() {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.identity SET meta_data = $1")).
+			WithArgs([]byte(`{"tier":"gold","vip":true}`), "idt1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	})
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.identity_history")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	update := &model.Identity{IdentityID: "idt1", MetaData: map[string]interface{}{"segment": nil, "vip": true}, MergeMetaData: true}
+	require.NoError(t, ds.UpdateIdentity(context.Background(), update, "owner_1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateIdentity_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE")).WithArgs("idt_missing").WillReturnRows(sqlmock.NewRows([]string{"identity_id"}))
+	mock.ExpectRollback()
+
+	err = ds.UpdateIdentity(context.Background(), &model.Identity{IdentityID: "idt_missing", City: "Lagos"}, "owner_1")
+	require.Error(t, err)
+	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetIdentityHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	changedAt := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity_history")).
+		WithArgs("idt1").
+		WillReturnRows(sqlmock.NewRows([]string{"identity_id", "version", "previous", "changes", "changed_by", "changed_at"}).
+			AddRow("idt1", 1, []byte(`{"identity_id":"idt1","city":"Abuja"}`), []byte(`[{"field":"city","from":"Abuja","to":"Lagos"}]`), "owner_1", changedAt))
+
+	history, err := ds.GetIdentityHistory(context.Background(), "idt1")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, 1, history[0].Version)
+	assert.Equal(t, "Abuja", history[0].Previous.City)
+	assert.Equal(t, []model.IdentityFieldChange{{Field: "city", From: "Abuja", To: "Lagos"}}, history[0].Changes)
+	assert.Equal(t, "owner_1", history[0].ChangedBy)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
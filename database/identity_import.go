@@ -0,0 +1,126 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+// identityCopyColumns are the columns CreateIdentities copies identities into.
+var identityCopyColumns = []string{
+	"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob", "email_address", "phone_number", "nationality",
+	"organization_name", "category", "street", "country", "state", "post_code", "city", "created_at", "meta_data", "locale", "timezone", "communication_preferences",
+}
+
+// CreateIdentities creates a batch of identities with a single COPY in one database transaction, so either all
+// of them are created or none is. Each identity is given its ID and creation time, and starts unverified.
+// Parameters:
+// - ctx: The context for the operation.
+// - identities: The identities to create.
+// Returns:
+// - An error if any identity cannot be encoded or the copy fails.
+func (d Datasource) CreateIdentities(ctx context.Context, identities []*model.Identity) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Creating identities")
+	defer span.End()
+
+	// COPY sends byte slices as bytea, so the JSON columns are sent as text
+	rows := make([][]interface{}, len(identities))
+	now := time.Now()
+	for i, identity := range identities {
+		if err := validateIdentityType(identity); err != nil {
+			return err
+		}
+		metaDataJSON, err := json.Marshal(identity.MetaData)
+		if err != nil {
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
+		}
+		var preferencesJSON interface{}
+		if identity.CommunicationPreferences != nil {
+			data, err := json.Marshal(identity.CommunicationPreferences)
+			if err != nil {
+				return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal communication preferences", err)
+			}
+			preferencesJSON = string(data)
+		}
+
+		identity.IdentityID = model.GenerateUUIDWithSuffix("idt")
+		identity.CreatedAt = now
+		identity.VerificationStatus = model.VerificationUnverified
+		identity.Status = model.IdentityStatusActive
+		identity.Version = 1
+		rows[i] = []interface{}{
+			identity.IdentityID, identity.IdentityType, identity.FirstName, identity.LastName, identity.OtherNames, identity.Gender, identity.DOB, identity.EmailAddress, identity.PhoneNumber, identity.Nationality,
+			identity.OrganizationName, identity.Category, identity.Street, identity.Country, identity.State, identity.PostCode, identity.City, identity.CreatedAt, string(metaDataJSON), identity.Locale, identity.Timezone, preferencesJSON,
+		}
+	}
+
+	// With pgx the identities are copied in binary format
+	if err := d.copyFrom(ctx, "identity", identityCopyColumns, rows); !errors.Is(err, pgconn.ErrNotPgx) {
+		if err != nil {
+			span.RecordError(err)
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to copy identities", err)
+		}
+		return nil
+	}
+
+	tx, err := d.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to begin transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyInSchema("blnk", "identity", identityCopyColumns...))
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to prepare identity copy", err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			span.RecordError(err)
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to copy identities", err)
+		}
+	}
+	// The copy is only sent once flushed by an Exec without arguments
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to copy identities", err)
+	}
+	if err := stmt.Close(); err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to copy identities", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to commit identities", err)
+	}
+	return nil
+}
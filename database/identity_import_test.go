@@ -0,0 +1,74 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateIdentities(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	identities := []*model.Identity{
+		{IdentityType: "individual", FirstName: "Ada", MetaData: map[string]interface{}{"legacy_id": "c-1"}},
+		{IdentityType: "individual", FirstName: "Grace", CommunicationPreferences: &model.CommunicationPreferences{Channel: model.CommunicationChannelEmail}},
+	}
+
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare(regexp.QuoteMeta(`COPY "blnk"."identity" ("identity_id", "identity_type"`))
+	prep.ExpectExec().WithArgs(sqlmock.AnyArg(), "individual", "Ada", "", "", "", sqlmock.AnyArg(), "", "", "", "", "", "", "", "", "", "", sqlmock.AnyArg(), `{"legacy_id":"c-1"}`, "", "", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectExec().WithArgs(sqlmock.AnyArg(), "individual", "Grace", "", "", "", sqlmock.AnyArg(), "", "", "", "", "", "", "", "", "", "", sqlmock.AnyArg(), "null", "", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, ds.CreateIdentities(context.Background(), identities))
+	for _, identity := range identities {
+		assert.NotEmpty(t, identity.IdentityID)
+		assert.Equal(t, model.VerificationUnverified, identity.VerificationStatus)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateIdentities_CopyFails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare(regexp.QuoteMeta(`COPY "blnk"."identity"`))
+	prep.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectExec().WithoutArgs().WillReturnError(errors.New("value too long"))
+	mock.ExpectRollback()
+
+	err = ds.CreateIdentities(context.Background(), []*model.Identity{{IdentityType: "individual"}})
+	assert.ErrorContains(t, err, "Failed to copy identities")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+// FindDuplicateIdentities retrieves the identities that are not deleted and share an email address, phone
+// number, or date of birth and name with an identity, newest first. The identity itself is excluded.
+// Parameters:
+// - ctx: The context for the operation.
+// - identity: The identity to find duplicates of.
+// Returns:
+// - The candidate duplicates, or an error if the query fails.
+func (d Datasource) FindDuplicateIdentities(ctx context.Context, identity *model.Identity) ([]model.Identity, error) {
+	args := []interface{}{identity.IdentityID}
+	var matches []string
+	if identity.EmailAddress != "" {
+		args = append(args, strings.TrimSpace(identity.EmailAddress))
+		matches = append(matches, fmt.Sprintf("lower(email_address) = lower($%d)", len(args)))
+	}
+	if identity.PhoneNumber != "" {
+		args = append(args, strings.TrimSpace(identity.PhoneNumber))
+		matches = append(matches, fmt.Sprintf("phone_number = $%d", len(args)))
+	}
+	if !identity.DOB.IsZero() && identity.FirstName != "" && identity.LastName != "" {
+		args = append(args, identity.DOB, identity.FirstName, identity.LastName)
+		matches = append(matches, fmt.Sprintf("(dob::date = $%d::date AND lower(first_name) = lower($%d) AND lower(last_name) = lower($%d))", len(args)-2, len(args)-1, len(args)))
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT identity_id, identity_type, first_name, last_name, other_names, gender, dob, email_address, phone_number, nationality, organization_name, category, street, country, state, post_code, city, created_at, meta_data, locale, timezone, communication_preferences, deleted_at,
+			verification_status, verification_reason, verification_submitted_at, verified_at, verification_rejected_at,
+			risk_score, risk_level, risk_scored_at, email_verified_at, phone_verified_at, tags,
+			status, status_reason, status_changed_at, version
+		FROM blnk.identity
+		WHERE identity_id <> $1 AND deleted_at IS NULL AND (`+strings.Join(matches, " OR ")+`)
+		ORDER BY created_at DESC`, args...)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to find duplicate identities", err)
+	}
+	defer rows.Close()
+
+	var identities []model.Identity
+	for rows.Next() {
+		var duplicate model.Identity
+		if err := scanIdentity(rows, &duplicate); err != nil {
+			return nil, err
+		}
+		identities = append(identities, duplicate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over identities", err)
+	}
+	return identities, nil
+}
+
+// MergeIdentities merges an identity into the one that survives it in a single database transaction: the
+// balances and accounts of the merged identity are moved to the survivor, the merged identity is deleted and
+// the merge is recorded. The IDs of the balances moved are set on the merge.
+// Parameters:
+// - ctx: The context for the operation.
+// - merge: The merge to apply, with its ID, identities, matched fields, reason and time set.
+// Returns:
+// - An error if either identity does not exist or is deleted, or if any write fails.
+func (d Datasource) MergeIdentities(ctx context.Context, merge *model.IdentityMerge) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Merging identities")
+	defer span.End()
+
+	tx, err := d.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to begin transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := mergeIdentities(ctx, tx, merge); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to commit identity merge", err)
+	}
+	return nil
+}
+
+func mergeIdentities(ctx context.Context, tx *sql.Tx, merge *model.IdentityMerge) error {
+	// Lock both identities so that neither is deleted, merged or restored while their balances move
+	rows, err := tx.QueryContext(ctx, `
+		SELECT identity_id FROM blnk.identity
+		WHERE identity_id IN ($1, $2) AND deleted_at IS NULL
+		ORDER BY identity_id
+		FOR UPDATE`, merge.SurvivorID, merge.MergedID)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to lock identities", err)
+	}
+	locked := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to lock identities", err)
+		}
+		locked[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to lock identities", err)
+	}
+	for _, id := range []string{merge.SurvivorID, merge.MergedID} {
+		if !locked[id] {
+			return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity with ID '%s' not found", id), nil)
+		}
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		UPDATE blnk.balances SET identity_id = $1
+		WHERE identity_id = $2
+		RETURNING balance_id`, merge.SurvivorID, merge.MergedID)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to move balances", err)
+	}
+	merge.BalanceIDs = []string{}
+	for rows.Next() {
+		var balanceID string
+		if err := rows.Scan(&balanceID); err != nil {
+			rows.Close()
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to move balances", err)
+		}
+		merge.BalanceIDs = append(merge.BalanceIDs, balanceID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to move balances", err)
+	}
+	sort.Strings(merge.BalanceIDs)
+
+	if _, err := tx.ExecContext(ctx, `UPDATE blnk.accounts SET identity_id = $1 WHERE identity_id = $2`, merge.SurvivorID, merge.MergedID); err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to move accounts", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE blnk.identity SET deleted_at = $2 WHERE identity_id = $1`, merge.MergedID, merge.MergedAt); err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to delete merged identity", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO blnk.identity_merges (merge_id, survivor_id, merged_id, matched_on, balance_ids, reason, merged_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		merge.MergeID, merge.SurvivorID, merge.MergedID, pq.Array(merge.MatchedOn), pq.Array(merge.BalanceIDs), nullString(merge.Reason), merge.MergedAt)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record identity merge", err)
+	}
+	return nil
+}
+
+// GetIdentityMerges retrieves the merges an identity took part in, as survivor or as the merged identity,
+// newest first.
+// Parameters:
+// - ctx: The context for the operation.
+// - identityID: The ID of the identity.
+// Returns:
+// - The merges, or an error if the query fails.
+func (d Datasource) GetIdentityMerges(ctx context.Context, identityID string) ([]*model.IdentityMerge, error) {
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT merge_id, survivor_id, merged_id, matched_on, balance_ids, reason, merged_at
+		FROM blnk.identity_merges
+		WHERE survivor_id = $1 OR merged_id = $1
+		ORDER BY merged_at DESC`, identityID)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve identity merges", err)
+	}
+	defer rows.Close()
+
+	merges := []*model.IdentityMerge{}
+	for rows.Next() {
+		merge := &model.IdentityMerge{}
+		var reason sql.NullString
+		if err := rows.Scan(&merge.MergeID, &merge.SurvivorID, &merge.MergedID, pq.Array(&merge.MatchedOn), pq.Array(&merge.BalanceIDs), &reason, &merge.MergedAt); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan identity merge", err)
+		}
+		merge.Reason = reason.String
+		merges = append(merges, merge)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over identity merges", err)
+	}
+	return merges, nil
+}
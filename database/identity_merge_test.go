@@ -0,0 +1,106 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeIdentities(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	merge := &model.IdentityMerge{MergeID: "mrg_1", SurvivorID: "idt_keep", MergedID: "idt_dupe", MatchedOn: []string{model.DuplicateOnEmailAddress}, MergedAt: time.Now()}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE")).
+		WithArgs("idt_keep", "idt_dupe").
+		WillReturnRows(sqlmock.NewRows([]string{"identity_id"}).AddRow("idt_dupe").AddRow("idt_keep"))
+	mock.ExpectQuery(regexp.QuoteMeta("UPDATE blnk.balances SET identity_id = $1")).
+		WithArgs("idt_keep", "idt_dupe").
+		WillReturnRows(sqlmock.NewRows([]string{"balance_id"}).AddRow("bln_2").AddRow("bln_1"))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.accounts")).WithArgs("idt_keep", "idt_dupe").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.identity SET deleted_at")).WithArgs("idt_dupe", merge.MergedAt).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.identity_merges")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, ds.MergeIdentities(context.Background(), merge))
+	assert.Equal(t, []string{"bln_1", "bln_2"}, merge.BalanceIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeIdentities_DeletedIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE")).
+		WithArgs("idt_keep", "idt_dupe").
+		WillReturnRows(sqlmock.NewRows([]string{"identity_id"}).AddRow("idt_keep"))
+	mock.ExpectRollback()
+
+	err = ds.MergeIdentities(context.Background(), &model.IdentityMerge{MergeID: "mrg_1", SurvivorID: "idt_keep", MergedID: "idt_dupe", MergedAt: time.Now()})
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindDuplicateIdentities_NothingToMatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	duplicates, err := ds.FindDuplicateIdentities(context.Background(), &model.Identity{IdentityID: "idt_1", FirstName: "Ada"})
+	require.NoError(t, err)
+	assert.Empty(t, duplicates)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetIdentityMerges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity_merges")).
+		WithArgs("idt_keep").
+		WillReturnRows(sqlmock.NewRows([]string{"merge_id", "survivor_id", "merged_id", "matched_on", "balance_ids", "reason", "merged_at"}).
+			AddRow("mrg_1", "idt_keep", "idt_dupe", "{email_address,phone_number}", "{bln_1}", nil, now))
+
+	merges, err := ds.GetIdentityMerges(context.Background(), "idt_keep")
+	require.NoError(t, err)
+	require.Len(t, merges, 1)
+	assert.Equal(t, []string{model.DuplicateOnEmailAddress, model.DuplicateOnPhoneNumber}, merges[0].MatchedOn)
+	assert.Equal(t, []string{"bln_1"}, merges[0].BalanceIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const identityRelationshipColumns = `relationship_id, identity_id, related_identity_id, type, role, meta_data, created_at, updated_at, version`
+
+// CreateIdentityRelationship saves a relationship between two identities.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - relationship: The relationship to store.
+// Returns:
+// - An error if the identities are already related by the relationship's type, or if the insert fails.
+func (d Datasource) CreateIdentityRelationship(ctx context.Context, relationship *model.IdentityRelationship) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Creating identity relationship")
+	defer span.End()
+
+	metaData, err := json.Marshal(relationship.MetaData)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
+	}
+
+	_, err = d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.identity_relationships (`+identityRelationshipColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`,
+		relationship.RelationshipID, relationship.IdentityID, relationship.RelatedIdentityID, relationship.Type,
+		nullString(relationship.Role), metaData, relationship.CreatedAt, relationship.UpdatedAt, relationship.Version,
+	)
+	if err != nil {
+		span.RecordError(err)
+		if pgconn.HasCode(err, pgconn.UniqueViolation) {
+			return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Identity '%s' is already a %s of identity '%s'", relationship.IdentityID, relationship.Type, relationship.RelatedIdentityID), err)
+		}
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create identity relationship", err)
+	}
+	return nil
+}
+
+// GetIdentityRelationship retrieves an identity relationship by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - relationshipID: The ID of the relationship.
+// Returns:
+// - The relationship, or an error if it does not exist.
+func (d Datasource) GetIdentityRelationship(ctx context.Context, relationshipID string) (*model.IdentityRelationship, error) {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Fetching identity relationship")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `
+		SELECT `+identityRelationshipColumns+`
+		FROM blnk.identity_relationships
+		WHERE relationship_id = $1
+	`, relationshipID)
+
+	relationship := &model.IdentityRelationship{}
+	err := scanIdentityRelationship(row, relationship)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity relationship with ID '%s' not found", relationshipID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve identity relationship", err)
+	}
+	return relationship, nil
+}
+
+// UpdateIdentityRelationship updates the role and metadata of an identity relationship. The identities and type
+// of a relationship cannot change; a different relationship is created instead. The relationship is only updated
+// while it is still at the version it was read at, and moves on to the next version.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - relationship: The relationship as read, with its new role, metadata and update time set.
+// Returns:
+// - An error if the relationship has been updated or deleted since it was read, or the update fails.
+func (d Datasource) UpdateIdentityRelationship(ctx context.Context, relationship *model.IdentityRelationship) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Updating identity relationship")
+	defer span.End()
+
+	metaData, err := json.Marshal(relationship.MetaData)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
+	}
+
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.identity_relationships
+		SET role = $2, meta_data = $3, updated_at = $4, version = version + 1
+		WHERE relationship_id = $1 AND version = $5
+	`, relationship.RelationshipID, nullString(relationship.Role), metaData, relationship.UpdatedAt, relationship.Version)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update identity relationship", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Identity relationship with ID '%s' is no longer at version %d", relationship.RelationshipID, relationship.Version), nil)
+	}
+	relationship.Version++
+	return nil
+}
+
+// DeleteIdentityRelationship deletes an identity relationship.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - relationshipID: The ID of the relationship.
+// Returns:
+// - An error if the relationship is not found or the delete fails.
+func (d Datasource) DeleteIdentityRelationship(ctx context.Context, relationshipID string) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Deleting identity relationship")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `DELETE FROM blnk.identity_relationships WHERE relationship_id = $1`, relationshipID)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to delete identity relationship", err)
+	}
+	return relationshipRowsAffected(result, relationshipID)
+}
+
+// GetRelatedIdentities retrieves the identities related to an identity, in either direction, with the
+// relationships between them, oldest relationship first. Deleted identities are left out.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - identityID: The ID of the identity.
+// Returns:
+// - The related identities, or an error if the query fails.
+func (d Datasource) GetRelatedIdentities(ctx context.Context, identityID string) ([]*model.RelatedIdentity, error) {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Fetching related identities")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT r.relationship_id, r.identity_id, r.related_identity_id, r.type, r.role, r.meta_data, r.created_at, r.updated_at, r.version,
+			i.identity_id, i.identity_type, i.first_name, i.last_name, i.other_names, i.gender, i.dob, i.email_address, i.phone_number, i.nationality, i.organization_name, i.category, i.street, i.country, i.state, i.post_code, i.city, i.created_at, i.meta_data, i.locale, i.timezone, i.communication_preferences, i.deleted_at,
+			i.verification_status, i.verification_reason, i.verification_submitted_at, i.verified_at, i.verification_rejected_at,
+			i.risk_score, i.risk_level, i.risk_scored_at, i.email_verified_at, i.phone_verified_at, i.tags,
+			i.status, i.status_reason, i.status_changed_at, i.version
+		FROM blnk.identity_relationships r
+		JOIN blnk.identity i
+			ON i.identity_id = CASE WHEN r.identity_id = $1 THEN r.related_identity_id ELSE r.identity_id END
+		WHERE (r.identity_id = $1 OR r.related_identity_id = $1) AND i.deleted_at IS NULL
+		ORDER BY r.created_at ASC
+	`, identityID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve related identities", err)
+	}
+	defer rows.Close()
+
+	related := []*model.RelatedIdentity{}
+	for rows.Next() {
+		entry := &model.RelatedIdentity{Direction: model.RelationshipOutgoing}
+		var role sql.NullString
+		var metaData []byte
+		row := prefixedRow{row: rows, prefix: identityRelationshipFields(&entry.Relationship, &role, &metaData)}
+		if err := scanIdentity(row, &entry.Identity); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		if err := finishIdentityRelationship(&entry.Relationship, role, metaData); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan related identity", err)
+		}
+		if entry.Relationship.RelatedIdentityID == identityID {
+			entry.Direction = model.RelationshipIncoming
+		}
+		related = append(related, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over related identities", err)
+	}
+	return related, nil
+}
+
+// relationshipRowsAffected checks that a delete of a relationship found it.
+func relationshipRowsAffected(result sql.Result, relationshipID string) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity relationship with ID '%s' not found", relationshipID), nil)
+	}
+	return nil
+}
+
+// identityRelationshipFields returns the scan destinations of the identity relationship columns, in the order of
+// identityRelationshipColumns. The role and metadata are scanned into role and metaData, to be set on the
+// relationship by finishIdentityRelationship.
+func identityRelationshipFields(relationship *model.IdentityRelationship, role *sql.NullString, metaData *[]byte) []interface{} {
+	return []interface{}{
+		&relationship.RelationshipID, &relationship.IdentityID, &relationship.RelatedIdentityID, &relationship.Type,
+		role, metaData, &relationship.CreatedAt, &relationship.UpdatedAt, &relationship.Version,
+	}
+}
+
+// finishIdentityRelationship sets the scanned role and metadata on a relationship.
+func finishIdentityRelationship(relationship *model.IdentityRelationship, role sql.NullString, metaData []byte) error {
+	relationship.Role = role.String
+	if len(metaData) == 0 {
+		return nil
+	}
+	return json.Unmarshal(metaData, &relationship.MetaData)
+}
+
+func scanIdentityRelationship(row rowScanner, relationship *model.IdentityRelationship) error {
+	var role sql.NullString
+	var metaData []byte
+	if err := row.Scan(identityRelationshipFields(relationship, &role, &metaData)...); err != nil {
+		return err
+	}
+	return finishIdentityRelationship(relationship, role, metaData)
+}
+
+// prefixedRow scans a row whose first columns are scanned into prefix and the rest into the destinations
+// given to Scan, so that a scanner of the trailing columns can be reused on a joined row.
+type prefixedRow struct {
+	row    rowScanner
+	prefix []interface{}
+}
+
+func (p prefixedRow) Scan(dest ...interface{}) error {
+	return p.row.Scan(append(p.prefix[:len(p.prefix):len(p.prefix)], dest...)...)
+}
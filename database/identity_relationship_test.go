@@ -0,0 +1,81 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRelatedIdentities(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	columns := append([]string{"relationship_id", "identity_id", "related_identity_id", "type", "role", "meta_data", "created_at", "updated_at", "version"},
+		"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob", "email_address", "phone_number", "nationality", "organization_name", "category", "street", "country", "state", "post_code", "city", "created_at", "meta_data", "locale", "timezone", "communication_preferences", "deleted_at",
+		"verification_status", "verification_reason", "verification_submitted_at", "verified_at", "verification_rejected_at",
+		"risk_score", "risk_level", "risk_scored_at", "email_verified_at", "phone_verified_at", "tags", "status", "status_reason", "status_changed_at", "version")
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity_relationships r")).
+		WithArgs("idt_org").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("rel_1", "idt_ada", "idt_org", model.RelationshipDirector, "CEO", []byte(`{"since":"2020"}`), now, now, 1,
+				"idt_ada", "individual", "Ada", "Lovelace", "", "female", now, "ada@example.com", "", "", "", "", "", "", "", "", "", now, []byte(`{}`), "", "", nil, nil, "unverified", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "active", nil, nil, 1).
+			AddRow("rel_2", "idt_org", "idt_parent", model.RelationshipMember, nil, []byte(`{}`), now, now, 2,
+				"idt_parent", "organization", "", "", "", "", now, "", "", "", "Parent Co", "", "", "", "", "", "", now, []byte(`{}`), "", "", nil, nil, "unverified", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "active", nil, nil, 1))
+
+	related, err := ds.GetRelatedIdentities(context.Background(), "idt_org")
+	require.NoError(t, err)
+	require.Len(t, related, 2)
+	assert.Equal(t, model.RelationshipIncoming, related[0].Direction)
+	assert.Equal(t, "CEO", related[0].Relationship.Role)
+	assert.Equal(t, "2020", related[0].Relationship.MetaData["since"])
+	assert.Equal(t, "idt_ada", related[0].Identity.IdentityID)
+	assert.Equal(t, "Lovelace", related[0].Identity.LastName)
+	assert.Equal(t, model.RelationshipOutgoing, related[1].Direction)
+	assert.Empty(t, related[1].Relationship.Role)
+	assert.Equal(t, 2, related[1].Relationship.Version)
+	assert.Equal(t, "Parent Co", related[1].Identity.OrganizationName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteIdentityRelationship_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM blnk.identity_relationships")).
+		WithArgs("rel_missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = ds.DeleteIdentityRelationship(context.Background(), "rel_missing")
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
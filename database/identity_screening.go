@@ -0,0 +1,138 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const identityScreeningColumns = `screening_id, identity_id, provider, status, matches, screened_at`
+
+// CreateIdentityScreening records the result of screening an identity.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - screening: The screening to record.
+// Returns:
+// - An error if the screening could not be saved.
+func (d Datasource) CreateIdentityScreening(ctx context.Context, screening *model.IdentityScreening) error {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Creating identity screening")
+	defer span.End()
+
+	matches := screening.Matches
+	if matches == nil {
+		matches = []model.ScreeningMatch{}
+	}
+	matchesJSON, err := json.Marshal(matches)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal screening matches", err)
+	}
+
+	_, err = d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.identity_screenings (`+identityScreeningColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, screening.ScreeningID, screening.IdentityID, screening.Provider, screening.Status, matchesJSON, screening.ScreenedAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create identity screening", err)
+	}
+	return nil
+}
+
+// GetIdentityScreenings retrieves the screenings of an identity, newest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - identityID: The ID of the identity.
+// Returns:
+// - The screenings, or an error if the query fails.
+func (d Datasource) GetIdentityScreenings(ctx context.Context, identityID string) ([]*model.IdentityScreening, error) {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Fetching identity screenings")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+identityScreeningColumns+`
+		FROM blnk.identity_screenings
+		WHERE identity_id = $1
+		ORDER BY screened_at DESC, id DESC
+	`, identityID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve identity screenings", err)
+	}
+	defer rows.Close()
+
+	screenings := []*model.IdentityScreening{}
+	for rows.Next() {
+		screening, err := scanIdentityScreening(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan identity screening", err)
+		}
+		screenings = append(screenings, screening)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over identity screenings", err)
+	}
+	return screenings, nil
+}
+
+// GetLatestIdentityScreening retrieves the latest screening of an identity, its current standing.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - identityID: The ID of the identity.
+// Returns:
+// - The screening, or an error if the identity was never screened.
+func (d Datasource) GetLatestIdentityScreening(ctx context.Context, identityID string) (*model.IdentityScreening, error) {
+	ctx, span := otel.Tracer("identity.database").Start(ctx, "Fetching latest identity screening")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `
+		SELECT `+identityScreeningColumns+`
+		FROM blnk.identity_screenings
+		WHERE identity_id = $1
+		ORDER BY screened_at DESC, id DESC
+		LIMIT 1
+	`, identityID)
+
+	screening, err := scanIdentityScreening(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity '%s' has not been screened", identityID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve identity screening", err)
+	}
+	return screening, nil
+}
+
+func scanIdentityScreening(row rowScanner) (*model.IdentityScreening, error) {
+	var screening model.IdentityScreening
+	var matches []byte
+	if err := row.Scan(&screening.ScreeningID, &screening.IdentityID, &screening.Provider, &screening.Status, &matches, &screening.ScreenedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(matches, &screening.Matches); err != nil {
+		return nil, err
+	}
+	return &screening, nil
+}
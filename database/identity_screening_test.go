@@ -0,0 +1,78 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var identityScreeningTestColumns = []string{"screening_id", "identity_id", "provider", "status", "matches", "screened_at"}
+
+func TestCreateIdentityScreening(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.identity_screenings")).
+		WithArgs("scr_1", "idt_1", "rules", model.ScreeningClear, []byte("[]"), now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = ds.CreateIdentityScreening(context.Background(), &model.IdentityScreening{
+		ScreeningID: "scr_1", IdentityID: "idt_1", Provider: "rules", Status: model.ScreeningClear, ScreenedAt: now,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetLatestIdentityScreening(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity_screenings")).
+		WithArgs("idt_1").
+		WillReturnRows(sqlmock.NewRows(identityScreeningTestColumns).
+			AddRow("scr_2", "idt_1", "http", model.ScreeningFlagged, []byte(`[{"list":"sanctions","entry_id":"SDN-1","name":"Jane Roe","score":0.92}]`), time.Now()))
+
+	screening, err := ds.GetLatestIdentityScreening(context.Background(), "idt_1")
+	require.NoError(t, err)
+	assert.True(t, screening.Flagged())
+	require.Len(t, screening.Matches, 1)
+	assert.Equal(t, model.ScreeningListSanctions, screening.Matches[0].List)
+	assert.Equal(t, 0.92, screening.Matches[0].Score)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity_screenings")).
+		WithArgs("idt_2").
+		WillReturnRows(sqlmock.NewRows(identityScreeningTestColumns))
+	_, err = ds.GetLatestIdentityScreening(context.Background(), "idt_2")
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+)
+
+// UpdateIdentityStatus moves an identity from one lifecycle status to another, recording the reason and when it
+// changed. The update only applies while the identity is still in the from status, so concurrent changes cannot
+// both succeed.
+// Parameters:
+// - ctx: The context for the operation.
+// - id: The ID of the identity.
+// - from: The status the identity is expected to be in.
+// - to: The status to move the identity to.
+// - reason: Why the status changed, or empty.
+// - at: When the status changed.
+// Returns:
+// - An error if the identity is not found, is deleted, or is no longer in the from status, or if the update fails.
+func (d Datasource) UpdateIdentityStatus(ctx context.Context, id, from, to, reason string, at time.Time) error {
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.identity
+		SET status = $3, status_reason = $4, status_changed_at = $5
+		WHERE identity_id = $1 AND status = $2 AND deleted_at IS NULL
+	`, id, from, to, nullString(reason), at)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update identity status", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Identity with ID '%s' is no longer %s", id, from), nil)
+	}
+
+	return nil
+}
+
+// ListInactiveIdentityIDs lists the IDs of the identities that are blocked or closed, whose balances cannot
+// transact.
+// Parameters:
+// - ctx: The context for the operation.
+// Returns:
+// - The IDs of the identities, or an error if the query fails.
+func (d Datasource) ListInactiveIdentityIDs(ctx context.Context) ([]string, error) {
+	rows, err := d.Conn.QueryContext(ctx, `SELECT identity_id FROM blnk.identity WHERE status <> 'active'`)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to list inactive identities", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan identity ID", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over identities", err)
+	}
+	return ids, nil
+}
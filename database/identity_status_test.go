@@ -0,0 +1,67 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateIdentityStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	at := time.Now()
+	mock.ExpectExec(`UPDATE blnk.identity\s+SET status = \$3, status_reason = \$4, status_changed_at = \$5\s+WHERE identity_id = \$1 AND status = \$2 AND deleted_at IS NULL`).
+		WithArgs("idt123", model.IdentityStatusActive, model.IdentityStatusBlocked, "chargeback fraud", at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SET status = \$3, status_reason = \$4, status_changed_at = \$5`).
+		WithArgs("idt123", model.IdentityStatusActive, model.IdentityStatusClosed, nil, at).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, ds.UpdateIdentityStatus(context.Background(), "idt123", model.IdentityStatusActive, model.IdentityStatusBlocked, "chargeback fraud", at))
+
+	err = ds.UpdateIdentityStatus(context.Background(), "idt123", model.IdentityStatusActive, model.IdentityStatusClosed, "", at)
+	assert.Equal(t, apierror.ErrConflict, err.(apierror.APIError).Code, "the identity was no longer active")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListInactiveIdentityIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(`SELECT identity_id FROM blnk.identity WHERE status <> 'active'`).
+		WillReturnRows(sqlmock.NewRows([]string{"identity_id"}).AddRow("idt_blocked").AddRow("idt_closed"))
+
+	ids, err := ds.ListInactiveIdentityIDs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"idt_blocked", "idt_closed"}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/lib/pq"
+)
+
+// AddIdentityTags adds tags to an identity, keeping its tags sorted and without duplicates.
+// Parameters:
+// - ctx: The context for the operation.
+// - id: The ID of the identity.
+// - tags: The tags to add.
+// Returns:
+// - The tags of the identity after the update.
+// - An error if the identity is not found or is deleted, or if the update fails.
+func (d Datasource) AddIdentityTags(ctx context.Context, id string, tags []string) ([]string, error) {
+	return d.updateIdentityTags(ctx, id, tags, `
+		UPDATE blnk.identity
+		SET tags = ARRAY(SELECT DISTINCT t FROM unnest(tags || $2::text[]) AS t ORDER BY t)
+		WHERE identity_id = $1 AND deleted_at IS NULL
+		RETURNING tags
+	`)
+}
+
+// RemoveIdentityTags removes tags from an identity. Tags the identity does not have are ignored.
+// Parameters:
+// - ctx: The context for the operation.
+// - id: The ID of the identity.
+// - tags: The tags to remove.
+// Returns:
+// - The tags of the identity after the update.
+// - An error if the identity is not found or is deleted, or if the update fails.
+func (d Datasource) RemoveIdentityTags(ctx context.Context, id string, tags []string) ([]string, error) {
+	return d.updateIdentityTags(ctx, id, tags, `
+		UPDATE blnk.identity
+		SET tags = ARRAY(SELECT t FROM unnest(tags) AS t WHERE NOT (t = ANY($2::text[])) ORDER BY t)
+		WHERE identity_id = $1 AND deleted_at IS NULL
+		RETURNING tags
+	`)
+}
+
+// updateIdentityTags runs an update of an identity's tags returning the tags it is left with.
+func (d Datasource) updateIdentityTags(ctx context.Context, id string, tags []string, query string) ([]string, error) {
+	updated := []string{}
+	err := d.Conn.QueryRowContext(ctx, query, id, pq.Array(tags)).Scan(pq.Array(&updated))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity with ID '%s' not found", id), err)
+		}
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update identity tags", err)
+	}
+	return updated, nil
+}
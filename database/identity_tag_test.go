@@ -0,0 +1,77 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddIdentityTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(`UPDATE blnk.identity\s+SET tags = ARRAY\(SELECT DISTINCT t FROM unnest\(tags \|\| \$2::text\[\]\) AS t ORDER BY t\)\s+WHERE identity_id = \$1 AND deleted_at IS NULL\s+RETURNING tags`).
+		WithArgs("idt123", pq.Array([]string{"kyc:tier-2", "vip"})).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow(`{dormant,kyc:tier-2,vip}`))
+
+	tags, err := ds.AddIdentityTags(context.Background(), "idt123", []string{"kyc:tier-2", "vip"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dormant", "kyc:tier-2", "vip"}, tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveIdentityTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(`UPDATE blnk.identity\s+SET tags = ARRAY\(SELECT t FROM unnest\(tags\) AS t WHERE NOT \(t = ANY\(\$2::text\[\]\)\) ORDER BY t\)`).
+		WithArgs("idt123", pq.Array([]string{"vip"})).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow(`{}`))
+
+	tags, err := ds.RemoveIdentityTags(context.Background(), "idt123", []string{"vip"})
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddIdentityTags_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(`UPDATE blnk.identity\s+SET tags`).
+		WithArgs("idt_missing", pq.Array([]string{"vip"})).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}))
+
+	_, err = ds.AddIdentityTags(context.Background(), "idt_missing", []string{"vip"})
+	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
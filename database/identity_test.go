@@ -17,6 +17,7 @@ limitations under the License.
 package database
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -26,6 +27,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/blnkfinance/blnk/internal/apierror"
 	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -56,16 +58,19 @@ func TestCreateIdentity_Success(t *testing.T) {
 		MetaData: map[string]interface{}{
 			"key": "value",
 		},
+		Locale:                   "en-US",
+		Timezone:                 "America/Los_Angeles",
+		CommunicationPreferences: &model.CommunicationPreferences{Channel: model.CommunicationChannelEmail},
 	}
 
 	metaDataJSON, err := json.Marshal(identity.MetaData)
 	assert.NoError(t, err)
 
 	mock.ExpectExec("INSERT INTO blnk.identity").
-		WithArgs(sqlmock.AnyArg(), identity.IdentityType, identity.FirstName, identity.LastName, identity.OtherNames, identity.Gender, identity.DOB, identity.EmailAddress, identity.PhoneNumber, identity.Nationality, identity.OrganizationName, identity.Category, identity.Street, identity.Country, identity.State, identity.PostCode, identity.City, sqlmock.AnyArg(), metaDataJSON).
+		WithArgs(sqlmock.AnyArg(), identity.IdentityType, identity.FirstName, identity.LastName, identity.OtherNames, identity.Gender, identity.DOB, identity.EmailAddress, identity.PhoneNumber, identity.Nationality, identity.OrganizationName, identity.Category, identity.Street, identity.Country, identity.State, identity.PostCode, identity.City, sqlmock.AnyArg(), metaDataJSON, "en-US", "America/Los_Angeles", []byte(`{"channel":"email"}`)).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	createdIdentity, err := ds.CreateIdentity(identity)
+	createdIdentity, err := ds.CreateIdentity(context.Background(), identity)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, createdIdentity.IdentityID)
 	assert.WithinDuration(t, time.Now(), createdIdentity.CreatedAt, time.Second)
@@ -85,10 +90,10 @@ func TestCreateIdentity_Fail(t *testing.T) {
 	}
 
 	mock.ExpectExec("INSERT INTO blnk.identity").
-		WithArgs(sqlmock.AnyArg(), identity.IdentityType, identity.FirstName, identity.LastName, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), identity.IdentityType, identity.FirstName, identity.LastName, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnError(fmt.Errorf("failed to insert"))
 
-	_, err = ds.CreateIdentity(identity)
+	_, err = ds.CreateIdentity(context.Background(), identity)
 	assert.Error(t, err)
 	assert.Equal(t, apierror.ErrInternalServer, err.(apierror.APIError).Code)
 }
@@ -106,7 +111,7 @@ func TestGetIdentityByID_NotFound(t *testing.T) {
 		WillReturnError(sql.ErrNoRows)
 	mock.ExpectRollback()
 
-	_, err = ds.GetIdentityByID("idt123")
+	_, err = ds.GetIdentityByID(context.Background(), "idt123")
 	assert.Error(t, err)
 	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
 }
@@ -142,21 +147,32 @@ func TestGetIdentityByID_Success(t *testing.T) {
 	}
 
 	metaDataJSON, _ := json.Marshal(expectedIdentity.MetaData)
+	verifiedAt := time.Now()
 
 	mock.ExpectBegin()
 	mock.ExpectQuery("SELECT identity_id, identity_type, first_name, last_name, other_names, gender, dob, email_address, phone_number, nationality, organization_name, category, street, country, state, post_code, city, created_at, meta_data").
 		WithArgs("idt123").
 		WillReturnRows(sqlmock.NewRows([]string{
-			"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob", "email_address", "phone_number", "nationality", "organization_name", "category", "street", "country", "state", "post_code", "city", "created_at", "meta_data",
-		}).AddRow(expectedIdentity.IdentityID, expectedIdentity.IdentityType, expectedIdentity.FirstName, expectedIdentity.LastName, expectedIdentity.OtherNames, expectedIdentity.Gender, expectedIdentity.DOB, expectedIdentity.EmailAddress, expectedIdentity.PhoneNumber, expectedIdentity.Nationality, expectedIdentity.OrganizationName, expectedIdentity.Category, expectedIdentity.Street, expectedIdentity.Country, expectedIdentity.State, expectedIdentity.PostCode, expectedIdentity.City, expectedIdentity.CreatedAt, metaDataJSON))
+			"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob", "email_address", "phone_number", "nationality", "organization_name", "category", "street", "country", "state", "post_code", "city", "created_at", "meta_data", "locale", "timezone", "communication_preferences", "deleted_at",
+			"verification_status", "verification_reason", "verification_submitted_at", "verified_at", "verification_rejected_at",
+			"risk_score", "risk_level", "risk_scored_at", "email_verified_at", "phone_verified_at", "tags", "status", "status_reason", "status_changed_at", "version",
+		}).AddRow(expectedIdentity.IdentityID, expectedIdentity.IdentityType, expectedIdentity.FirstName, expectedIdentity.LastName, expectedIdentity.OtherNames, expectedIdentity.Gender, expectedIdentity.DOB, expectedIdentity.EmailAddress, expectedIdentity.PhoneNumber, expectedIdentity.Nationality, expectedIdentity.OrganizationName, expectedIdentity.Category, expectedIdentity.Street, expectedIdentity.Country, expectedIdentity.State, expectedIdentity.PostCode, expectedIdentity.City, expectedIdentity.CreatedAt, metaDataJSON, "en-GB", "Europe/London", []byte(`{"channel":"sms","opt_outs":["marketing"]}`), nil, "verified", "documents checked", verifiedAt, verifiedAt, nil, nil, nil, nil, nil, nil, nil, "active", nil, nil, 1))
 	mock.ExpectCommit()
 
-	identity, err := ds.GetIdentityByID("idt123")
+	identity, err := ds.GetIdentityByID(context.Background(), "idt123")
 	assert.NoError(t, err)
 	assert.Equal(t, expectedIdentity.IdentityID, identity.IdentityID)
 	assert.Equal(t, expectedIdentity.FirstName, identity.FirstName)
 	assert.Equal(t, expectedIdentity.LastName, identity.LastName)
 	assert.Equal(t, expectedIdentity.MetaData, identity.MetaData)
+	assert.Equal(t, "en-GB", identity.Locale)
+	assert.Equal(t, model.IdentityStatusActive, identity.Status)
+	assert.Equal(t, "Europe/London", identity.Timezone)
+	assert.Equal(t, &model.CommunicationPreferences{Channel: model.CommunicationChannelSMS, OptOuts: []string{model.CommunicationMarketing}}, identity.CommunicationPreferences)
+	assert.Equal(t, model.VerificationVerified, identity.VerificationStatus)
+	assert.Equal(t, "documents checked", identity.VerificationReason)
+	assert.Equal(t, verifiedAt, *identity.VerifiedAt)
+	assert.Nil(t, identity.VerificationRejectedAt)
 }
 
 func TestGetAllIdentities_Success(t *testing.T) {
@@ -217,20 +233,145 @@ func TestGetAllIdentities_Success(t *testing.T) {
 	metaData2, err := json.Marshal(expectedIdentities[1].MetaData)
 	assert.NoError(t, err)
 
-	// Mock the query result to return all 19 columns
+	// Mock the query result to return all 22 columns
 	mock.ExpectQuery("SELECT identity_id, identity_type, first_name, last_name, other_names, gender, dob, email_address, phone_number, nationality, organization_name, category, street, country, state, post_code, city, created_at, meta_data").
 		WillReturnRows(sqlmock.NewRows([]string{
-			"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob", "email_address", "phone_number", "nationality", "organization_name", "category", "street", "country", "state", "post_code", "city", "created_at", "meta_data",
+			"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob", "email_address", "phone_number", "nationality", "organization_name", "category", "street", "country", "state", "post_code", "city", "created_at", "meta_data", "locale", "timezone", "communication_preferences", "deleted_at",
+			"verification_status", "verification_reason", "verification_submitted_at", "verified_at", "verification_rejected_at",
+			"risk_score", "risk_level", "risk_scored_at", "email_verified_at", "phone_verified_at", "tags", "status", "status_reason", "status_changed_at", "version",
 		}).
-			AddRow(expectedIdentities[0].IdentityID, expectedIdentities[0].IdentityType, expectedIdentities[0].FirstName, expectedIdentities[0].LastName, expectedIdentities[0].OtherNames, expectedIdentities[0].Gender, expectedIdentities[0].DOB, expectedIdentities[0].EmailAddress, expectedIdentities[0].PhoneNumber, expectedIdentities[0].Nationality, expectedIdentities[0].OrganizationName, expectedIdentities[0].Category, expectedIdentities[0].Street, expectedIdentities[0].Country, expectedIdentities[0].State, expectedIdentities[0].PostCode, expectedIdentities[0].City, expectedIdentities[0].CreatedAt, metaData1).
-			AddRow(expectedIdentities[1].IdentityID, expectedIdentities[1].IdentityType, expectedIdentities[1].FirstName, expectedIdentities[1].LastName, expectedIdentities[1].OtherNames, expectedIdentities[1].Gender, expectedIdentities[1].DOB, expectedIdentities[1].EmailAddress, expectedIdentities[1].PhoneNumber, expectedIdentities[1].Nationality, expectedIdentities[1].OrganizationName, expectedIdentities[1].Category, expectedIdentities[1].Street, expectedIdentities[1].Country, expectedIdentities[1].State, expectedIdentities[1].PostCode, expectedIdentities[1].City, expectedIdentities[1].CreatedAt, metaData2))
+			AddRow(expectedIdentities[0].IdentityID, expectedIdentities[0].IdentityType, expectedIdentities[0].FirstName, expectedIdentities[0].LastName, expectedIdentities[0].OtherNames, expectedIdentities[0].Gender, expectedIdentities[0].DOB, expectedIdentities[0].EmailAddress, expectedIdentities[0].PhoneNumber, expectedIdentities[0].Nationality, expectedIdentities[0].OrganizationName, expectedIdentities[0].Category, expectedIdentities[0].Street, expectedIdentities[0].Country, expectedIdentities[0].State, expectedIdentities[0].PostCode, expectedIdentities[0].City, expectedIdentities[0].CreatedAt, metaData1, "", "", nil, nil, "unverified", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "active", nil, nil, 1).
+			AddRow(expectedIdentities[1].IdentityID, expectedIdentities[1].IdentityType, expectedIdentities[1].FirstName, expectedIdentities[1].LastName, expectedIdentities[1].OtherNames, expectedIdentities[1].Gender, expectedIdentities[1].DOB, expectedIdentities[1].EmailAddress, expectedIdentities[1].PhoneNumber, expectedIdentities[1].Nationality, expectedIdentities[1].OrganizationName, expectedIdentities[1].Category, expectedIdentities[1].Street, expectedIdentities[1].Country, expectedIdentities[1].State, expectedIdentities[1].PostCode, expectedIdentities[1].City, expectedIdentities[1].CreatedAt, metaData2, "fr-FR", "Europe/Paris", nil, nil, "unverified", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "active", nil, nil, 1))
 
 	// Execute the function under test
-	identities, err := ds.GetAllIdentities()
+	identities, err := ds.GetAllIdentities(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, identities, 2)
 	assert.Equal(t, expectedIdentities[0].IdentityID, identities[0].IdentityID)
 	assert.Equal(t, expectedIdentities[1].IdentityID, identities[1].IdentityID)
+	assert.Nil(t, identities[0].CommunicationPreferences)
+	assert.Equal(t, "Europe/Paris", identities[1].Timezone)
+}
+
+func TestGetIdentities_Filtered(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	filter := model.IdentityFilter{EmailAddress: "John.Doe@Example.com", Country: "NG", IdentityType: "individual"}
+
+	mock.ExpectQuery(`FROM blnk.identity\s+WHERE lower\(email_address\) = lower\(\$1\) AND country = \$2 AND identity_type = \$3 AND deleted_at IS NULL\s+ORDER BY created_at DESC, identity_id DESC LIMIT \$4 OFFSET \$5`).
+		WithArgs("John.Doe@Example.com", "NG", "individual", 20, 40).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob", "email_address", "phone_number", "nationality", "organization_name", "category", "street", "country", "state", "post_code", "city", "created_at", "meta_data", "locale", "timezone", "communication_preferences", "deleted_at",
+			"verification_status", "verification_reason", "verification_submitted_at", "verified_at", "verification_rejected_at",
+			"risk_score", "risk_level", "risk_scored_at", "email_verified_at", "phone_verified_at", "tags", "status", "status_reason", "status_changed_at", "version",
+		}).
+			AddRow("idt1", "individual", "John", "Doe", "", "", time.Now(), "john.doe@example.com", "", "", "", "", "", "NG", "", "", "", time.Now(), []byte(`{}`), "", "", nil, nil, "unverified", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "active", nil, nil, 1))
+
+	identities, err := ds.GetIdentities(context.Background(), filter, model.Page{Limit: 20, Offset: 40})
+	assert.NoError(t, err)
+	assert.Len(t, identities, 1)
+	assert.Equal(t, "idt1", identities[0].IdentityID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetIdentities_MetadataAndCreatedRange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	filter := model.IdentityFilter{
+		Nationality: "NG", Category: "retail", VerificationStatus: model.VerificationVerified,
+		MetaData: "tier:gold,pep", CreatedAfter: &after, CreatedBefore: &before,
+	}
+
+	mock.ExpectQuery(`FROM blnk.identity\s+WHERE nationality = \$1 AND category = \$2 AND verification_status = \$3 AND meta_data @> \$4::jsonb AND meta_data @\? \$5::jsonpath AND created_at >= \$6 AND created_at < \$7 AND deleted_at IS NULL\s+ORDER BY created_at DESC, identity_id DESC$`).
+		WithArgs("NG", "retail", model.VerificationVerified, `{"tier":"gold"}`, `$."pep"`, after, before).
+		WillReturnRows(sqlmock.NewRows([]string{"identity_id"}))
+
+	identities, err := ds.GetIdentities(context.Background(), filter, model.Page{})
+	assert.NoError(t, err)
+	assert.Empty(t, identities)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	filter.CreatedBefore = &after
+	_, err = ds.GetIdentities(context.Background(), filter, model.Page{})
+	var apiErr apierror.APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrInvalidInput, apiErr.Code)
+}
+
+func TestGetIdentities_AboveRiskThreshold(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	scoredAt := time.Now()
+	mock.ExpectQuery(`FROM blnk.identity\s+WHERE risk_level = \$1 AND risk_score >= \$2 AND deleted_at IS NULL\s+ORDER BY risk_score DESC, created_at DESC$`).
+		WithArgs(model.RiskLevelHigh, 75.0).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob", "email_address", "phone_number", "nationality", "organization_name", "category", "street", "country", "state", "post_code", "city", "created_at", "meta_data", "locale", "timezone", "communication_preferences", "deleted_at",
+			"verification_status", "verification_reason", "verification_submitted_at", "verified_at", "verification_rejected_at",
+			"risk_score", "risk_level", "risk_scored_at", "email_verified_at", "phone_verified_at", "tags", "status", "status_reason", "status_changed_at", "version",
+		}).
+			AddRow("idt1", "individual", "John", "Doe", "", "", time.Now(), "", "", "", "", "", "", "NG", "", "", "", time.Now(), []byte(`{}`), "", "", nil, nil, "unverified", nil, nil, nil, nil, 90.5, "high", scoredAt, nil, nil, nil, "active", nil, nil, 1))
+
+	identities, err := ds.GetIdentities(context.Background(), model.IdentityFilter{MinRiskScore: 75, RiskLevel: model.RiskLevelHigh}, model.Page{})
+	assert.NoError(t, err)
+	assert.Len(t, identities, 1)
+	assert.Equal(t, 90.5, *identities[0].RiskScore)
+	assert.Equal(t, model.RiskLevelHigh, identities[0].RiskLevel)
+	assert.Equal(t, scoredAt, *identities[0].RiskScoredAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetIdentities_ByTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(`FROM blnk.identity\s+WHERE tags @> \$1::text\[\] AND deleted_at IS NULL\s+ORDER BY created_at DESC, identity_id DESC$`).
+		WithArgs(pq.Array([]string{"vip", "dormant"})).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob", "email_address", "phone_number", "nationality", "organization_name", "category", "street", "country", "state", "post_code", "city", "created_at", "meta_data", "locale", "timezone", "communication_preferences", "deleted_at",
+			"verification_status", "verification_reason", "verification_submitted_at", "verified_at", "verification_rejected_at",
+			"risk_score", "risk_level", "risk_scored_at", "email_verified_at", "phone_verified_at", "tags", "status", "status_reason", "status_changed_at", "version",
+		}).
+			AddRow("idt1", "individual", "John", "Doe", "", "", time.Now(), "", "", "", "", "", "", "NG", "", "", "", time.Now(), []byte(`{}`), "", "", nil, nil, "unverified", nil, nil, nil, nil, nil, nil, nil, nil, nil, `{dormant,kyc:tier-2,vip}`, "active", nil, nil, 1))
+
+	identities, err := ds.GetIdentities(context.Background(), model.IdentityFilter{Tags: " VIP, dormant,"}, model.Page{})
+	assert.NoError(t, err)
+	assert.Len(t, identities, 1)
+	assert.Equal(t, []string{"dormant", "kyc:tier-2", "vip"}, identities[0].Tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetIdentities_EmptyFilterIsUnpaginated(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(`FROM blnk.identity\s+WHERE deleted_at IS NULL\s+ORDER BY created_at DESC, identity_id DESC$`).
+		WithoutArgs().
+		WillReturnRows(sqlmock.NewRows([]string{"identity_id"}))
+
+	identities, err := ds.GetIdentities(context.Background(), model.IdentityFilter{}, model.Page{})
+	assert.NoError(t, err)
+	assert.Empty(t, identities)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestUpdateIdentity_Success(t *testing.T) {
@@ -254,13 +395,17 @@ func TestUpdateIdentity_Success(t *testing.T) {
 	metaDataJSON, err := json.Marshal(identity.MetaData)
 	assert.NoError(t, err)
 
-	// Match the exact column names and include meta_data
-	mock.ExpectExec("UPDATE blnk\\.identity SET").
-		WithArgs(identity.FirstName, identity.LastName, identity.EmailAddress, metaDataJSON, identity.IdentityID).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	// Match the exact column names and include meta_data. The email address changing clears its verification.
+	expectIdentityUpdate(t, mock, identity, identity, func() {
+		mock.ExpectExec(`UPDATE blnk\.identity SET .*email_verified_at = CASE WHEN email_address = \$3 THEN email_verified_at END`).
+			WithArgs(identity.FirstName, identity.LastName, identity.EmailAddress, metaDataJSON, identity.IdentityID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	})
+	mock.ExpectCommit()
 
-	err = ds.UpdateIdentity(identity)
+	err = ds.UpdateIdentity(context.Background(), identity, model.ActorSystem)
 	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestUpdateIdentity_NoFieldsProvided(t *testing.T) {
@@ -274,7 +419,7 @@ func TestUpdateIdentity_NoFieldsProvided(t *testing.T) {
 		IdentityID: "idt1",
 	}
 
-	err = ds.UpdateIdentity(identity)
+	err = ds.UpdateIdentity(context.Background(), identity, model.ActorSystem)
 	assert.Error(t, err)
 	assert.Equal(t, apierror.ErrBadRequest, err.(apierror.APIError).Code)
 }
@@ -300,12 +445,41 @@ func TestUpdateIdentity_PartialUpdate(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Use a more flexible pattern but account for meta_data
-	mock.ExpectExec("UPDATE blnk\\.identity SET").
-		WithArgs(identity.EmailAddress, identity.PhoneNumber, metaDataJSON, identity.IdentityID).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectIdentityUpdate(t, mock, identity, identity, func() {
+		mock.ExpectExec("UPDATE blnk\\.identity SET").
+			WithArgs(identity.EmailAddress, identity.PhoneNumber, metaDataJSON, identity.IdentityID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	})
+	mock.ExpectCommit()
 
-	err = ds.UpdateIdentity(identity)
+	err = ds.UpdateIdentity(context.Background(), identity, model.ActorSystem)
 	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateIdentity_Preferences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	identity := &model.Identity{
+		IdentityID:               "idt1",
+		Timezone:                 "Asia/Tokyo",
+		CommunicationPreferences: &model.CommunicationPreferences{Channel: model.CommunicationChannelNone},
+	}
+
+	expectIdentityUpdate(t, mock, identity, identity, func() {
+		mock.ExpectExec("UPDATE blnk\\.identity SET timezone = \\$1, communication_preferences = \\$2").
+			WithArgs("Asia/Tokyo", []byte(`{"channel":"none"}`), identity.IdentityID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	})
+	mock.ExpectCommit()
+
+	err = ds.UpdateIdentity(context.Background(), identity, model.ActorSystem)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestDeleteIdentity_Success(t *testing.T) {
@@ -315,11 +489,11 @@ func TestDeleteIdentity_Success(t *testing.T) {
 
 	ds := Datasource{Conn: db}
 
-	mock.ExpectExec("DELETE FROM blnk.identity").
-		WithArgs("idt123").
+	mock.ExpectExec(`UPDATE blnk.identity\s+SET deleted_at = \$2\s+WHERE identity_id = \$1 AND deleted_at IS NULL`).
+		WithArgs("idt123", sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err = ds.DeleteIdentity("idt123")
+	err = ds.DeleteIdentity(context.Background(), "idt123")
 	assert.NoError(t, err)
 }
 
@@ -330,11 +504,148 @@ func TestDeleteIdentity_NotFound(t *testing.T) {
 
 	ds := Datasource{Conn: db}
 
-	mock.ExpectExec("DELETE FROM blnk.identity").
-		WithArgs("idt123").
+	mock.ExpectExec(`UPDATE blnk.identity\s+SET deleted_at = \$2\s+WHERE identity_id = \$1 AND deleted_at IS NULL`).
+		WithArgs("idt123", sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 0))
 
-	err = ds.DeleteIdentity("idt123")
+	err = ds.DeleteIdentity(context.Background(), "idt123")
 	assert.Error(t, err)
 	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
 }
+
+func TestGetIdentityByID_ExcludesDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	deletedAt := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`WHERE identity_id = \$1 AND deleted_at IS NULL$`).
+		WithArgs("idt123").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`WHERE identity_id = \$1$`).
+		WithArgs("idt123").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob", "email_address", "phone_number", "nationality", "organization_name", "category", "street", "country", "state", "post_code", "city", "created_at", "meta_data", "locale", "timezone", "communication_preferences", "deleted_at",
+			"verification_status", "verification_reason", "verification_submitted_at", "verified_at", "verification_rejected_at",
+			"risk_score", "risk_level", "risk_scored_at", "email_verified_at", "phone_verified_at", "tags", "status", "status_reason", "status_changed_at", "version",
+		}).AddRow("idt123", "individual", "John", "Doe", "", "", time.Now(), "", "", "", "", "", "", "", "", "", "", time.Now(), []byte(`{}`), "", "", nil, deletedAt, "unverified", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "active", nil, nil, 1))
+	mock.ExpectCommit()
+
+	_, err = ds.GetIdentityByID(context.Background(), "idt123")
+	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
+
+	identity, err := ds.GetIdentityByIDIncludingDeleted(context.Background(), "idt123")
+	assert.NoError(t, err)
+	assert.Equal(t, deletedAt, *identity.DeletedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetIdentities_AfterCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	after := model.PageCursor{Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), ID: "idt_40"}
+	mock.ExpectQuery(`FROM blnk.identity\s+WHERE country = \$1 AND deleted_at IS NULL AND \(created_at, identity_id\) < \(\$2, \$3\)\s+ORDER BY created_at DESC, identity_id DESC LIMIT \$4$`).
+		WithArgs("NG", after.Time, after.ID, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"identity_id"}))
+
+	_, err = ds.GetIdentities(context.Background(), model.IdentityFilter{Country: "NG"}, model.Page{Limit: 20, After: &after})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Identities ordered by risk score cannot be paged by cursor
+	_, err = ds.GetIdentities(context.Background(), model.IdentityFilter{MinRiskScore: 75}, model.Page{Limit: 20, After: &after})
+	assert.Equal(t, apierror.ErrInvalidInput, err.(apierror.APIError).Code)
+}
+
+func TestGetIdentities_IncludeDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(`FROM blnk.identity\s+WHERE country = \$1\s+ORDER BY created_at DESC, identity_id DESC$`).
+		WithArgs("NG").
+		WillReturnRows(sqlmock.NewRows([]string{"identity_id"}))
+
+	_, err = ds.GetIdentities(context.Background(), model.IdentityFilter{Country: "NG", IncludeDeleted: true}, model.Page{})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectExec(`UPDATE blnk.identity\s+SET deleted_at = NULL\s+WHERE identity_id = \$1 AND deleted_at IS NOT NULL`).
+		WithArgs("idt123").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE blnk.identity\s+SET deleted_at = NULL`).
+		WithArgs("idt456").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, ds.RestoreIdentity(context.Background(), "idt123"))
+
+	err = ds.RestoreIdentity(context.Background(), "idt456")
+	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateIdentityVerification(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	at := time.Now()
+	mock.ExpectExec(`UPDATE blnk.identity\s+SET verification_status = \$3, verification_reason = \$4, verification_rejected_at = \$5\s+WHERE identity_id = \$1 AND verification_status = \$2 AND deleted_at IS NULL`).
+		WithArgs("idt123", model.VerificationPending, model.VerificationRejected, "document expired", at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SET verification_status = \$3, verification_reason = \$4, verified_at = \$5`).
+		WithArgs("idt123", model.VerificationPending, model.VerificationVerified, nil, at).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, ds.UpdateIdentityVerification(context.Background(), "idt123", model.VerificationPending, model.VerificationRejected, "document expired", at))
+
+	err = ds.UpdateIdentityVerification(context.Background(), "idt123", model.VerificationPending, model.VerificationVerified, "", at)
+	assert.Equal(t, apierror.ErrConflict, err.(apierror.APIError).Code, "the identity was no longer pending")
+
+	err = ds.UpdateIdentityVerification(context.Background(), "idt123", model.VerificationPending, model.VerificationUnverified, "", at)
+	assert.Equal(t, apierror.ErrBadRequest, err.(apierror.APIError).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateIdentityRisk(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	at := time.Now()
+	mock.ExpectExec(`UPDATE blnk.identity\s+SET risk_score = \$2, risk_level = \$3, risk_scored_at = \$4\s+WHERE identity_id = \$1 AND deleted_at IS NULL`).
+		WithArgs("idt123", 82.0, model.RiskLevelHigh, at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE blnk.identity\s+SET risk_score`).
+		WithArgs("idt456", 10.0, model.RiskLevelLow, at).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, ds.UpdateIdentityRisk(context.Background(), "idt123", 82, model.RiskLevelHigh, at))
+
+	err = ds.UpdateIdentityRisk(context.Background(), "idt456", 10, model.RiskLevelLow, at)
+	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// ConfiguredIdentityTypes returns the identity types identities can have: the built-in individual and
+// organization types and those defined in the configuration.
+// Returns:
+// - The identity types.
+// - Whether any identity type is defined, without which identity types are not enforced.
+// - An error if a defined identity type is invalid.
+func ConfiguredIdentityTypes() (model.IdentityTypes, bool, error) {
+	cnf, err := config.Fetch()
+	if err != nil || len(cnf.IdentityTypes) == 0 {
+		types, _ := model.NewIdentityTypes(nil)
+		return types, false, nil
+	}
+
+	defined := make(map[string]model.IdentityType, len(cnf.IdentityTypes))
+	for name, identityType := range cnf.IdentityTypes {
+		defined[name] = model.IdentityType{Kind: identityType.Kind, RequiredFields: identityType.RequiredFields}
+	}
+	types, err := model.NewIdentityTypes(defined)
+	if err != nil {
+		return nil, false, err
+	}
+	return types, true, nil
+}
+
+// validateIdentityType checks that an identity has a defined identity type and the fields the type requires,
+// when identity types are defined.
+// Returns:
+// - An error with the *model.IdentityTypeError as its details if the identity does not match its type.
+func validateIdentityType(identity *model.Identity) error {
+	types, defined, err := ConfiguredIdentityTypes()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Invalid identity type configuration", err)
+	}
+	if !defined {
+		return nil
+	}
+	if err := types.Validate(identity); err != nil {
+		return apierror.NewAPIError(apierror.ErrInvalidInput, err.Error(), err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useIdentityTypes configures identity types for the duration of a test.
+func useIdentityTypes(t *testing.T, types map[string]config.IdentityTypeConfig) {
+	previous := config.ConfigStore.Load()
+	config.ConfigStore.Store(&config.Configuration{IdentityTypes: types})
+	t.Cleanup(func() {
+		if previous != nil {
+			config.ConfigStore.Store(previous)
+		} else {
+			config.ConfigStore.Store(&config.Configuration{})
+		}
+	})
+}
+
+func TestCreateIdentity_IdentityType(t *testing.T) {
+	useIdentityTypes(t, map[string]config.IdentityTypeConfig{
+		"trust": {Kind: model.IdentityTypeOrganization, RequiredFields: []string{"organization_name", "country"}},
+	})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	_, err = ds.CreateIdentity(context.Background(), model.Identity{IdentityType: "trust", OrganizationName: "Lovelace Trust"})
+	require.Error(t, err)
+	assert.Equal(t, apierror.ErrInvalidInput, err.(apierror.APIError).Code)
+	var typeErr *model.IdentityTypeError
+	require.ErrorAs(t, err.(apierror.APIError).Details.(error), &typeErr)
+	assert.Equal(t, "country", typeErr.Errors[0].Field)
+
+	_, err = ds.CreateIdentity(context.Background(), model.Identity{IdentityType: "government"})
+	assert.Equal(t, apierror.ErrInvalidInput, err.(apierror.APIError).Code)
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.identity")).WillReturnResult(sqlmock.NewResult(1, 1))
+	_, err = ds.CreateIdentity(context.Background(), model.Identity{IdentityType: "trust", OrganizationName: "Lovelace Trust", Country: "GB"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfiguredIdentityTypes_Undefined(t *testing.T) {
+	useIdentityTypes(t, nil)
+
+	types, defined, err := ConfiguredIdentityTypes()
+	require.NoError(t, err)
+	assert.False(t, defined)
+	assert.Equal(t, model.IdentityTypeOrganization, types.Kind(model.IdentityTypeOrganization))
+	assert.NoError(t, validateIdentityType(&model.Identity{IdentityType: "anything"}))
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+// FindUnbalancedTransactions finds applied transactions whose legs cannot balance: a missing source or
+// destination, both legs on the same balance, a non-positive amount, or a precise amount that disagrees
+// with the amount and precision.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - limit: The maximum number of issues to return.
+// Returns:
+// - []model.IntegrityIssue: The transactions that failed the check.
+// - An error if the query fails.
+func (d Datasource) FindUnbalancedTransactions(ctx context.Context, limit int) ([]model.IntegrityIssue, error) {
+	ctx, span := otel.Tracer("integrity.database").Start(ctx, "Finding unbalanced transactions")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT transaction_id, COALESCE(source, ''), COALESCE(destination, ''),
+			COALESCE(precise_amount::TEXT, ''), COALESCE(amount, 0), COALESCE(precision, 1)
+		FROM blnk.transactions
+		WHERE status = 'APPLIED'
+		AND (source IS NULL OR source = ''
+			OR destination IS NULL OR destination = ''
+			OR source = destination
+			OR precise_amount IS NULL OR precise_amount <= 0
+			OR ABS(amount * precision - precise_amount) >= 1)
+		ORDER BY created_at ASC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to check transaction legs", err)
+	}
+	defer rows.Close()
+
+	issues := []model.IntegrityIssue{}
+	for rows.Next() {
+		var transactionID, source, destination, preciseAmount string
+		var amount, precision float64
+		if err := rows.Scan(&transactionID, &source, &destination, &preciseAmount, &amount, &precision); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan transaction", err)
+		}
+
+		issue := model.IntegrityIssue{
+			Check:    model.IntegrityCheckUnbalancedTransactions,
+			EntityID: transactionID,
+			Repair:   &model.RepairSuggestion{Action: model.RepairActionManualReview, Params: map[string]string{"transaction_id": transactionID}},
+		}
+		switch {
+		case source == "" || destination == "":
+			issue.Detail = "transaction is missing a source or destination leg"
+		case source == destination:
+			issue.Detail = "source and destination legs post to the same balance"
+		case preciseAmount == "" || preciseAmount[0] == '-' || preciseAmount == "0":
+			issue.Detail = "transaction has a non-positive precise amount"
+			issue.Actual = preciseAmount
+		default:
+			issue.Detail = "precise amount does not match amount and precision"
+			issue.Expected = fmt.Sprintf("%.0f", amount*precision)
+			issue.Actual = preciseAmount
+		}
+		issues = append(issues, issue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over transactions", err)
+	}
+
+	return issues, nil
+}
+
+// FindBalanceDrift finds balances whose stored credit and debit totals differ from the sums of their
+// applied transactions, or whose balance is not credit minus debit.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - limit: The maximum number of issues to return.
+// Returns:
+// - []model.IntegrityIssue: The balances that failed the check, with the expected totals.
+// - An error if the query fails.
+func (d Datasource) FindBalanceDrift(ctx context.Context, limit int) ([]model.IntegrityIssue, error) {
+	ctx, span := otel.Tracer("integrity.database").Start(ctx, "Finding balance drift")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		WITH credits AS (
+			SELECT destination AS balance_id, SUM(precise_amount) AS total
+			FROM blnk.transactions WHERE status = 'APPLIED' GROUP BY destination
+		), debits AS (
+			SELECT source AS balance_id, SUM(precise_amount) AS total
+			FROM blnk.transactions WHERE status = 'APPLIED' GROUP BY source
+		)
+		SELECT b.balance_id, b.credit_balance::TEXT, b.debit_balance::TEXT, b.balance::TEXT,
+			COALESCE(c.total, 0)::TEXT, COALESCE(dr.total, 0)::TEXT
+		FROM blnk.balances b
+		LEFT JOIN credits c ON c.balance_id = b.balance_id
+		LEFT JOIN debits dr ON dr.balance_id = b.balance_id
+		WHERE b.credit_balance <> COALESCE(c.total, 0)
+			OR b.debit_balance <> COALESCE(dr.total, 0)
+			OR b.balance <> b.credit_balance - b.debit_balance
+		ORDER BY b.balance_id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to check balance totals", err)
+	}
+	defer rows.Close()
+
+	issues := []model.IntegrityIssue{}
+	for rows.Next() {
+		var balanceID, credit, debit, balance, expectedCredit, expectedDebit string
+		if err := rows.Scan(&balanceID, &credit, &debit, &balance, &expectedCredit, &expectedDebit); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan balance", err)
+		}
+		expectedCreditInt, _ := new(big.Int).SetString(expectedCredit, 10)
+		expectedDebitInt, _ := new(big.Int).SetString(expectedDebit, 10)
+		expectedBalance := new(big.Int).Sub(expectedCreditInt, expectedDebitInt).String()
+
+		issues = append(issues, model.IntegrityIssue{
+			Check:    model.IntegrityCheckBalanceDrift,
+			EntityID: balanceID,
+			Detail:   "balance fields do not equal the sum of applied transactions",
+			Expected: fmt.Sprintf("credit=%s debit=%s balance=%s", expectedCredit, expectedDebit, expectedBalance),
+			Actual:   fmt.Sprintf("credit=%s debit=%s balance=%s", credit, debit, balance),
+			Repair: &model.RepairSuggestion{Action: model.RepairActionRecomputeBalance, Params: map[string]string{
+				"balance_id":     balanceID,
+				"credit_balance": expectedCredit,
+				"debit_balance":  expectedDebit,
+				"balance":        expectedBalance,
+			}},
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over balances", err)
+	}
+
+	return issues, nil
+}
+
+// FindOrphanedInflightTransactions finds inflight records that are out of step with their commits and voids:
+// inflight transactions committed or voided beyond their amount, inflight transactions older than staleBefore
+// that were never committed or voided, and voids whose inflight transaction does not exist.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - staleBefore: Unresolved inflight transactions created before this time are reported.
+// - limit: The maximum number of issues to return.
+// Returns:
+// - []model.IntegrityIssue: The inflight records that failed the check.
+// - An error if the query fails.
+func (d Datasource) FindOrphanedInflightTransactions(ctx context.Context, staleBefore time.Time, limit int) ([]model.IntegrityIssue, error) {
+	ctx, span := otel.Tracer("integrity.database").Start(ctx, "Finding orphaned inflight transactions")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT kind, transaction_id, expected, actual FROM (
+			SELECT 'overcommitted' AS kind, p.transaction_id, p.precise_amount::TEXT AS expected,
+				SUM(c.precise_amount)::TEXT AS actual, p.created_at
+			FROM blnk.transactions p
+			JOIN blnk.transactions c ON c.parent_transaction = p.transaction_id AND c.status IN ('APPLIED', 'VOID')
+			WHERE p.status = 'INFLIGHT'
+			GROUP BY p.transaction_id, p.precise_amount, p.created_at
+			HAVING SUM(c.precise_amount) > p.precise_amount
+			UNION ALL
+			SELECT 'stale', p.transaction_id, '', p.precise_amount::TEXT, p.created_at
+			FROM blnk.transactions p
+			WHERE p.status = 'INFLIGHT' AND p.created_at < $1
+			AND NOT EXISTS (
+				SELECT 1 FROM blnk.transactions c
+				WHERE c.parent_transaction = p.transaction_id AND c.status IN ('APPLIED', 'VOID')
+			)
+			UNION ALL
+			SELECT 'orphaned_void', c.transaction_id, c.parent_transaction, '', c.created_at
+			FROM blnk.transactions c
+			WHERE c.status = 'VOID'
+			AND NOT EXISTS (
+				SELECT 1 FROM blnk.transactions p
+				WHERE p.transaction_id = c.parent_transaction AND p.status = 'INFLIGHT'
+			)
+		) issues
+		ORDER BY created_at ASC
+		LIMIT $2
+	`, staleBefore, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to check inflight transactions", err)
+	}
+	defer rows.Close()
+
+	issues := []model.IntegrityIssue{}
+	for rows.Next() {
+		var kind, transactionID, expected, actual string
+		if err := rows.Scan(&kind, &transactionID, &expected, &actual); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan inflight transaction", err)
+		}
+
+		issue := model.IntegrityIssue{
+			Check:    model.IntegrityCheckOrphanedInflight,
+			EntityID: transactionID,
+			Expected: expected,
+			Actual:   actual,
+			Repair:   &model.RepairSuggestion{Action: model.RepairActionManualReview, Params: map[string]string{"transaction_id": transactionID}},
+		}
+		switch kind {
+		case "overcommitted":
+			issue.Detail = "inflight transaction was committed or voided beyond its amount"
+		case "stale":
+			issue.Detail = "inflight transaction was never committed or voided"
+			issue.Repair.Action = model.RepairActionVoidInflight
+		default:
+			issue.Detail = "void does not reference an existing inflight transaction"
+		}
+		issues = append(issues, issue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over inflight transactions", err)
+	}
+
+	return issues, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindBalanceDrift(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	rows := sqlmock.NewRows([]string{"balance_id", "credit_balance", "debit_balance", "balance", "expected_credit", "expected_debit"}).
+		AddRow("bln_1", "500", "100", "400", "700", "100")
+	mock.ExpectQuery("WITH credits AS").WithArgs(10).WillReturnRows(rows)
+
+	issues, err := ds.FindBalanceDrift(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, issues, 1)
+	assert.Equal(t, model.IntegrityCheckBalanceDrift, issues[0].Check)
+	assert.Equal(t, "bln_1", issues[0].EntityID)
+	assert.Equal(t, model.RepairActionRecomputeBalance, issues[0].Repair.Action)
+	assert.Equal(t, "600", issues[0].Repair.Params["balance"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindOrphanedInflightTransactions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+	staleBefore := time.Now()
+
+	rows := sqlmock.NewRows([]string{"kind", "transaction_id", "expected", "actual"}).
+		AddRow("stale", "txn_1", "", "1000").
+		AddRow("overcommitted", "txn_2", "1000", "1500")
+	mock.ExpectQuery("SELECT kind, transaction_id, expected, actual").WithArgs(staleBefore, 10).WillReturnRows(rows)
+
+	issues, err := ds.FindOrphanedInflightTransactions(context.Background(), staleBefore, 10)
+	assert.NoError(t, err)
+	assert.Len(t, issues, 2)
+	assert.Equal(t, model.RepairActionVoidInflight, issues[0].Repair.Action)
+	assert.Equal(t, model.RepairActionManualReview, issues[1].Repair.Action)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
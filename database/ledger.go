@@ -17,25 +17,27 @@ limitations under the License.
 package database
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"time"
 
 	"github.com/blnkfinance/blnk/internal/apierror"
+	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
 	"github.com/blnkfinance/blnk/model"
-	"github.com/lib/pq"
 )
 
 // CreateLedger inserts a new ledger record into the database, ensuring metadata is properly marshaled into JSON format.
 // It assigns a unique ledger ID with a suffix and captures the current timestamp as the creation time.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - ledger: The ledger data to be inserted into the database.
 //
 // Returns:
 // - model.Ledger: The created ledger object including the generated LedgerID and creation timestamp.
 // - error: An error if the ledger creation fails, including specific database error handling for conflicts.
-func (d Datasource) CreateLedger(ledger model.Ledger) (model.Ledger, error) {
+func (d Datasource) CreateLedger(ctx context.Context, ledger model.Ledger) (model.Ledger, error) {
 	// Marshal the metadata into JSON format
 	metaDataJSON, err := json.Marshal(ledger.MetaData)
 	if err != nil {
@@ -47,16 +49,12 @@ func (d Datasource) CreateLedger(ledger model.Ledger) (model.Ledger, error) {
 	ledger.CreatedAt = time.Now()
 
 	// Insert the ledger into the database
-	_, err = d.Conn.Exec(`
-		INSERT INTO blnk.ledgers (meta_data, name, ledger_id)
-		VALUES ($1, $2, $3)
-	`, metaDataJSON, ledger.Name, ledger.LedgerID)
+	err = insertLedger(ctx, d.Conn, &ledger, metaDataJSON)
 	// Handle database errors, specifically unique constraint violations
 	if err != nil {
-		pqErr, ok := err.(*pq.Error)
-		if ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
+		if pgErr, ok := pgconn.AsError(err); ok {
+			switch pgErr.Code {
+			case pgconn.UniqueViolation:
 				return model.Ledger{}, apierror.NewAPIError(apierror.ErrConflict, "Ledger with this name or ID already exists", err)
 			default:
 				return model.Ledger{}, apierror.NewAPIError(apierror.ErrInternalServer, "Database error occurred", err)
@@ -68,30 +66,43 @@ func (d Datasource) CreateLedger(ledger model.Ledger) (model.Ledger, error) {
 	return ledger, nil
 }
 
-// GetAllLedgers retrieves a paginated list of ledger records from the database, unmarshaling their metadata from JSON format.
-// This method supports pagination and can be used to efficiently retrieve all ledgers over multiple requests.
+// insertLedger inserts a ledger whose ID and creation time are already set.
+func insertLedger(ctx context.Context, db dbExecutor, ledger *model.Ledger, metaDataJSON []byte) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO blnk.ledgers (meta_data, name, ledger_id)
+		VALUES ($1, $2, $3)
+	`, metaDataJSON, ledger.Name, ledger.LedgerID)
+	return err
+}
+
+// ledgerKeyset is the order ledgers are listed in, newest first.
+var ledgerKeyset = keyset{timeColumn: "created_at", idColumn: "ledger_id", descending: true}
+
+// GetAllLedgers retrieves a page of ledger records from the database, unmarshaling their metadata from JSON format.
+// Pages are read by cursor, so later pages are as cheap as the first however many ledgers there are. Ledgers
+// outside the ledger scope of ctx are left out.
 //
 // Parameters:
-// - limit: The maximum number of ledgers to return (e.g., 20).
-// - offset: The offset to start fetching ledgers from (for pagination).
+// - ctx: The context for the operation.
+// - page: The page to read; a limit outside 1 to 100 reads 20 ledgers.
 //
 // Returns:
 // - []model.Ledger: A slice of ledgers retrieved from the database.
 // - error: An error if the query fails or if there's an issue processing the results.
-func (d Datasource) GetAllLedgers(limit, offset int) ([]model.Ledger, error) {
-	if limit <= 0 || limit > 100 {
-		limit = 20 // Default limit to 20 if the provided limit is invalid or too large
+func (d Datasource) GetAllLedgers(ctx context.Context, page model.Page) ([]model.Ledger, error) {
+	if page.Limit <= 0 || page.Limit > 100 {
+		page.Limit = 20 // Default limit to 20 if the provided limit is invalid or too large
 	}
 
 	// Execute a paginated query to select ledgers from the database
+	scope, args := ledgerScopeLedgers(ctx, "ledger_id", nil)
+	condition, suffix, args := ledgerKeyset.page(page, args)
 	query := `
 		SELECT ledger_id, name, created_at, meta_data
 		FROM blnk.ledgers
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
+		WHERE ` + and(scope, condition) + suffix
 
-	rows, err := d.Conn.Query(query, limit, offset)
+	rows, err := d.Conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, err.Error(), err)
 	}
@@ -129,16 +140,17 @@ func (d Datasource) GetAllLedgers(limit, offset int) ([]model.Ledger, error) {
 // It handles cases where the ledger is not found and unmarshals the metadata from JSON format.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - id: The unique ID of the ledger to retrieve.
 //
 // Returns:
 // - *model.Ledger: The ledger object, if found.
 // - error: An error if the ledger is not found or if the query fails.
-func (d Datasource) GetLedgerByID(id string) (*model.Ledger, error) {
+func (d Datasource) GetLedgerByID(ctx context.Context, id string) (*model.Ledger, error) {
 	ledger := model.Ledger{}
 
 	// Query the database to find the ledger by its ID
-	row := d.Conn.QueryRow(`
+	row := d.Conn.QueryRowContext(ctx, `
 		SELECT ledger_id, name, created_at, meta_data
 		FROM blnk.ledgers
 		WHERE ledger_id = $1
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	"github.com/lib/pq"
+)
+
+// Lists apply the ledger scope of the caller in their WHERE clause rather than to the rows they read, so that
+// pages are full and their cursors stay in step with what the caller sees. The conditions are empty for callers
+// without a ledger scope, and are combined with the rest of the clause by and. Placeholders are numbered from
+// len(args)+1, and the returned args extend args with their values, as for keyset.page.
+
+// ledgerScopeLedgers returns the condition that keeps the ledgers in ledgerColumn within the ledger scope of ctx.
+func ledgerScopeLedgers(ctx context.Context, ledgerColumn string, args []interface{}) (string, []interface{}) {
+	scope, ok := ledgerscope.FromContext(ctx)
+	if !ok || !scope.Restricted() {
+		return "", args
+	}
+	args = append(args, pq.Array(scope.LedgerIDs))
+	return fmt.Sprintf("%s = ANY($%d)", ledgerColumn, len(args)), args
+}
+
+// ledgerScopeBalances returns the condition that keeps the balances in balanceColumn, of the ledgers in
+// ledgerColumn, within the ledger scope of ctx: balances of a ledger in scope or whose ID has a prefix in scope.
+func ledgerScopeBalances(ctx context.Context, balanceColumn, ledgerColumn string, args []interface{}) (string, []interface{}) {
+	scope, ok := ledgerscope.FromContext(ctx)
+	if !ok || !scope.Restricted() {
+		return "", args
+	}
+	args = append(args, pq.Array(scope.LedgerIDs), pq.Array(prefixPatterns(scope.BalancePrefixes)))
+	return fmt.Sprintf("(%s = ANY($%d) OR %s LIKE ANY($%d))", ledgerColumn, len(args)-1, balanceColumn, len(args)), args
+}
+
+// ledgerScopeTransactions returns the condition that keeps the transactions of the table aliased t within the
+// ledger scope of ctx: transactions whose source and destination balances are both in scope.
+func ledgerScopeTransactions(ctx context.Context, args []interface{}) (string, []interface{}) {
+	condition, args := ledgerScopeBalances(ctx, "sb.balance_id", "sb.ledger_id", args)
+	if condition == "" {
+		return condition, args
+	}
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM blnk.balances sb WHERE sb.balance_id = t.source AND %[1]s)
+		AND EXISTS (SELECT 1 FROM blnk.balances sb WHERE sb.balance_id = t.destination AND %[1]s)`, condition), args
+}
+
+// and joins the non-empty conditions with AND.
+func and(conditions ...string) string {
+	var parts []string
+	for _, condition := range conditions {
+		if condition != "" {
+			parts = append(parts, condition)
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// prefixPatterns returns LIKE patterns matching the IDs that start with each prefix. Empty prefixes match
+// nothing, as in ledgerscope.Scope.AllowsBalance.
+func prefixPatterns(prefixes []string) []string {
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	patterns := []string{}
+	for _, prefix := range prefixes {
+		if prefix != "" {
+			patterns = append(patterns, escaper.Replace(prefix)+"%")
+		}
+	}
+	return patterns
+}
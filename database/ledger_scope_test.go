@@ -0,0 +1,69 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAllBalances_LedgerScope(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+	ctx := ledgerscope.WithScope(context.Background(), ledgerscope.Scope{LedgerIDs: []string{"ldg_cards"}, BalancePrefixes: []string{"bln_vip_"}})
+
+	rows := sqlmock.NewRows([]string{"balance_id", "indicator", "balance", "credit_balance", "debit_balance", "currency", "currency_multiplier", "ledger_id", "created_at", "meta_data"}).
+		AddRow("bln_1", nil, "100", "100", "0", "USD", 100, "ldg_cards", time.Now(), []byte(`{}`))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE (ledger_id = ANY($1) OR balance_id LIKE ANY($2)) AND TRUE ORDER BY created_at DESC, balance_id DESC LIMIT $3")).
+		WithArgs(`{"ldg_cards"}`, `{"bln\\_vip\\_%"}`, 20).
+		WillReturnRows(rows)
+
+	balances, err := ds.GetAllBalances(ctx, model.Page{Limit: 20})
+	require.NoError(t, err)
+	assert.Len(t, balances, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTransactionsByIdentity_LedgerScope(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+	ctx := ledgerscope.WithScope(context.Background(), ledgerscope.Scope{LedgerIDs: []string{"ldg_cards"}})
+
+	mock.ExpectQuery(regexp.QuoteMeta("EXISTS (SELECT 1 FROM blnk.balances sb WHERE sb.balance_id = t.source AND (sb.ledger_id = ANY($2) OR sb.balance_id LIKE ANY($3)))")).
+		WithArgs("idt_1", `{"ldg_cards"}`, "{}", 20).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id"}))
+
+	_, err = ds.GetTransactionsByIdentity(ctx, "idt_1", model.Page{Limit: 20})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPrefixPatterns(t *testing.T) {
+	assert.Equal(t, []string{`bln\_vip%`, `100\%%`}, prefixPatterns([]string{"bln_vip", "", "100%"}))
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+// recordSequencedTransaction records a transaction and its status like RecordTransaction, and assigns it the
+// next sequence number of the ledgers of its source and destination in the same statement. Incrementing a
+// ledger's counter locks it until the statement commits, so concurrent postings to a ledger are numbered in
+// the order they commit and a failed insert leaves no gap. Counters are locked in ledger order so postings
+// between the same two ledgers cannot deadlock.
+func recordSequencedTransaction(ctx context.Context, db dbExecutor, txn *model.Transaction, metaDataJSON []byte) ([]model.LedgerSequence, error) {
+	rows, err := db.QueryContext(ctx,
+		`WITH recorded AS (
+			INSERT INTO blnk.transactions(transaction_id, parent_transaction, source, reference, amount, precise_amount, precision, rate, currency, destination, description, status, created_at, meta_data, scheduled_for, hash, effective_date, transaction_time, group_id) 
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+			RETURNING transaction_id, parent_transaction, status, created_at
+		), history AS (
+			INSERT INTO blnk.transaction_status_history(transaction_id, parent_transaction, status, reason_code, reason, actor)
+			SELECT transaction_id, parent_transaction, status, $20, $21, $22 FROM recorded
+		), counters AS (
+			INSERT INTO blnk.ledger_sequences(ledger_id, last_sequence)
+			SELECT DISTINCT ledger_id, 1 FROM blnk.balances WHERE balance_id IN ($3, $10) ORDER BY ledger_id
+			ON CONFLICT (ledger_id) DO UPDATE SET last_sequence = blnk.ledger_sequences.last_sequence + 1
+			RETURNING ledger_id, last_sequence
+		)
+		INSERT INTO blnk.transaction_sequences(ledger_id, sequence, transaction_id, created_at)
+		SELECT c.ledger_id, c.last_sequence, r.transaction_id, r.created_at FROM counters c, recorded r
+		RETURNING ledger_id, sequence`,
+		txn.TransactionID, txn.ParentTransaction, txn.Source, txn.Reference, txn.AmountString, txn.PreciseAmount.String(), txn.Precision, txn.Rate, txn.Currency, txn.Destination, txn.Description, txn.Status, txn.CreatedAt, metaDataJSON, txn.ScheduledFor, txn.Hash, txn.EffectiveDate, txn.TransactionTime, nullString(txn.GroupID),
+		txn.StatusChange.ReasonCode, txn.StatusChange.Reason, txn.StatusChange.Actor,
+	)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record transaction", err)
+	}
+	defer rows.Close()
+
+	sequences, err := scanLedgerSequences(rows)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record transaction", err)
+	}
+	return sequences, nil
+}
+
+// GetTransactionSequences retrieves the sequence numbers of a transaction in the ledgers it posted to.
+func (d Datasource) GetTransactionSequences(ctx context.Context, transactionID string) ([]model.LedgerSequence, error) {
+	ctx, span := otel.Tracer("ledger_sequence.database").Start(ctx, "GetTransactionSequences")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT ledger_id, sequence FROM blnk.transaction_sequences
+		WHERE transaction_id = $1
+		ORDER BY ledger_id
+	`, transactionID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve transaction sequences", err)
+	}
+	defer rows.Close()
+
+	sequences, err := scanLedgerSequences(rows)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan transaction sequences", err)
+	}
+	return sequences, nil
+}
+
+// GetLedgerSequence retrieves the numbered transactions of a ledger after a sequence number, in order, along
+// with the ledger's last sequence number.
+func (d Datasource) GetLedgerSequence(ctx context.Context, ledgerID string, after int64, limit int) (*model.LedgerSequencePage, error) {
+	ctx, span := otel.Tracer("ledger_sequence.database").Start(ctx, "GetLedgerSequence")
+	defer span.End()
+
+	page := &model.LedgerSequencePage{LedgerID: ledgerID, Entries: []model.LedgerSequenceEntry{}}
+	err := d.Conn.QueryRowContext(ctx, `
+		SELECT last_sequence FROM blnk.ledger_sequences WHERE ledger_id = $1
+	`, ledgerID).Scan(&page.LastSequence)
+	if err == sql.ErrNoRows {
+		// Nothing has posted to the ledger yet
+		return page, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve ledger sequence", err)
+	}
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT s.sequence, s.transaction_id, COALESCE(t.status, ''), s.created_at
+		FROM blnk.transaction_sequences s
+		LEFT JOIN blnk.transactions t ON t.transaction_id = s.transaction_id
+		WHERE s.ledger_id = $1 AND s.sequence > $2
+		ORDER BY s.sequence ASC
+		LIMIT $3
+	`, ledgerID, after, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve ledger sequence", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry model.LedgerSequenceEntry
+		if err := rows.Scan(&entry.Sequence, &entry.TransactionID, &entry.Status, &entry.CreatedAt); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan ledger sequence", err)
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over ledger sequence", err)
+	}
+	return page, nil
+}
+
+func scanLedgerSequences(rows *sql.Rows) ([]model.LedgerSequence, error) {
+	sequences := []model.LedgerSequence{}
+	for rows.Next() {
+		var sequence model.LedgerSequence
+		if err := rows.Scan(&sequence.LedgerID, &sequence.Sequence); err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, sequence)
+	}
+	return sequences, rows.Err()
+}
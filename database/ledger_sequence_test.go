@@ -0,0 +1,103 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordTransaction_AssignsLedgerSequences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	txn := &model.Transaction{
+		TransactionID: "txn_1",
+		Source:        "bln_source",
+		Destination:   "bln_destination",
+		Reference:     "ref_1",
+		AmountString:  "10",
+		PreciseAmount: model.Int64ToBigInt(1000),
+		Precision:     100,
+		Currency:      "USD",
+		Status:        "APPLIED",
+		CreatedAt:     time.Now(),
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO blnk.ledger_sequences")).
+		WithArgs(txn.TransactionID, "", "bln_source", "ref_1", "10", "1000", float64(100), float64(0), "USD", "bln_destination", "", "APPLIED",
+			txn.CreatedAt, []byte("null"), time.Time{}, "", nil, nil, nil, "", "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"ledger_id", "sequence"}).
+			AddRow("ldg_a", 41).
+			AddRow("ldg_b", 7))
+
+	recorded, err := ds.RecordTransaction(context.Background(), txn)
+	require.NoError(t, err)
+	assert.Equal(t, []model.LedgerSequence{{LedgerID: "ldg_a", Sequence: 41}, {LedgerID: "ldg_b", Sequence: 7}}, recorded.Sequences)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetLedgerSequence(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	createdAt := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT last_sequence FROM blnk.ledger_sequences")).
+		WithArgs("ldg_a").
+		WillReturnRows(sqlmock.NewRows([]string{"last_sequence"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.transaction_sequences s")).
+		WithArgs("ldg_a", int64(1), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"sequence", "transaction_id", "status", "created_at"}).
+			AddRow(2, "txn_2", "APPLIED", createdAt).
+			AddRow(3, "txn_3", "INFLIGHT", createdAt))
+
+	page, err := ds.GetLedgerSequence(context.Background(), "ldg_a", 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), page.LastSequence)
+	require.Len(t, page.Entries, 2)
+	assert.Equal(t, model.LedgerSequenceEntry{Sequence: 2, TransactionID: "txn_2", Status: "APPLIED", CreatedAt: createdAt}, page.Entries[0])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetLedgerSequence_NothingPosted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT last_sequence FROM blnk.ledger_sequences")).
+		WithArgs("ldg_empty").
+		WillReturnError(sql.ErrNoRows)
+
+	page, err := ds.GetLedgerSequence(context.Background(), "ldg_empty", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), page.LastSequence)
+	assert.Empty(t, page.Entries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
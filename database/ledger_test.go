@@ -17,6 +17,7 @@ limitations under the License.
 package database
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"testing"
@@ -51,7 +52,7 @@ func TestCreateLedger_Success(t *testing.T) {
 		WithArgs(metaDataJSON, ledger.Name, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	createdLedger, err := ds.CreateLedger(ledger)
+	createdLedger, err := ds.CreateLedger(context.Background(), ledger)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, createdLedger.LedgerID)
 	assert.WithinDuration(t, time.Now(), createdLedger.CreatedAt, time.Second)
@@ -78,7 +79,7 @@ func TestCreateLedger_UniqueViolation(t *testing.T) {
 		WithArgs(metaDataJSON, ledger.Name, sqlmock.AnyArg()).
 		WillReturnError(&pq.Error{Code: "23505", Message: "unique_violation"})
 
-	_, err = ds.CreateLedger(ledger)
+	_, err = ds.CreateLedger(context.Background(), ledger)
 	assert.Error(t, err)
 	apiErr, ok := err.(apierror.APIError)
 	assert.True(t, ok)
@@ -102,10 +103,10 @@ func TestGetAllLedgers_Success(t *testing.T) {
 		AddRow("ldg1", "Ledger 1", time.Now(), metaDataJSON).
 		AddRow("ldg2", "Ledger 2", time.Now(), metaDataJSON)
 
-	mock.ExpectQuery("SELECT ledger_id, name, created_at, meta_data FROM blnk.ledgers ORDER BY created_at DESC LIMIT \\$1 OFFSET \\$2").
-		WithArgs(2, 0).
+	mock.ExpectQuery("SELECT ledger_id, name, created_at, meta_data FROM blnk.ledgers WHERE TRUE ORDER BY created_at DESC, ledger_id DESC LIMIT \\$1").
+		WithArgs(2).
 		WillReturnRows(rows)
-	ledgers, err := ds.GetAllLedgers(2, 0)
+	ledgers, err := ds.GetAllLedgers(context.Background(), model.Page{Limit: 2})
 	assert.NoError(t, err)
 	assert.Len(t, ledgers, 2)
 	assert.Equal(t, "Ledger 1", ledgers[0].Name)
@@ -131,7 +132,7 @@ func TestGetLedgerByID_Success(t *testing.T) {
 		WithArgs("ldg1").
 		WillReturnRows(row)
 
-	ledger, err := ds.GetLedgerByID("ldg1")
+	ledger, err := ds.GetLedgerByID(context.Background(), "ldg1")
 	assert.NoError(t, err)
 	assert.Equal(t, "Ledger 1", ledger.Name)
 }
@@ -147,7 +148,7 @@ func TestGetLedgerByID_NotFound(t *testing.T) {
 		WithArgs("ldg1").
 		WillReturnError(sql.ErrNoRows)
 
-	_, err = ds.GetLedgerByID("ldg1")
+	_, err = ds.GetLedgerByID(context.Background(), "ldg1")
 	assert.Error(t, err)
 	apiErr, ok := err.(apierror.APIError)
 	assert.True(t, ok)
@@ -2,25 +2,38 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
 )
 
+// metadataCounterTables maps the entity types whose metadata can hold counters to their table and ID column.
+var metadataCounterTables = map[string]struct{ table, idColumn string }{
+	"ledgers":    {"blnk.ledgers", "ledger_id"},
+	"balances":   {"blnk.balances", "balance_id"},
+	"identities": {"blnk.identity", "identity_id"},
+}
+
 // UpdateLedgerMetadata updates the metadata for a specific ledger in the database.
 // It marshals the metadata map to JSON before storing it.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - id: The ID of the ledger to update.
 // - metadata: The new metadata to store.
 //
 // Returns:
 // - error: An error if the update operation fails.
-func (d *Datasource) UpdateLedgerMetadata(id string, metadata map[string]interface{}) error {
+func (d *Datasource) UpdateLedgerMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
 	metadataJSON, err := json.Marshal(metadata)
 	if err != nil {
 		return err
 	}
 
-	_, err = d.Conn.Exec(`
+	_, err = d.Conn.ExecContext(ctx, `
 		UPDATE blnk.ledgers 
 		SET meta_data = $1
 		WHERE ledger_id = $2
@@ -83,21 +96,77 @@ func (d *Datasource) UpdateBalanceMetadata(ctx context.Context, id string, metad
 // It marshals the metadata map to JSON before storing it.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - id: The ID of the identity to update.
 // - metadata: The new metadata to store.
 //
 // Returns:
 // - error: An error if the update operation fails.
-func (d *Datasource) UpdateIdentityMetadata(id string, metadata map[string]interface{}) error {
+func (d *Datasource) UpdateIdentityMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
 	metadataJSON, err := json.Marshal(metadata)
 	if err != nil {
 		return err
 	}
 
-	_, err = d.Conn.Exec(`
+	_, err = d.Conn.ExecContext(ctx, `
 		UPDATE blnk.identity 
 		SET meta_data = $1
 		WHERE identity_id = $2
 	`, metadataJSON, id)
 	return err
 }
+
+// IncrementMetadata adds to a numeric metadata key of a ledger, balance or identity in a single UPDATE, so
+// concurrent increments of the same key are never lost. A missing key starts at 0; a key holding anything but
+// a number is left unchanged.
+//
+// Parameters:
+// - ctx: The context for the database operation.
+// - entityType: The type of the entity: ledgers, balances or identities.
+// - id: The ID of the entity to update.
+// - key: The metadata key to increment.
+// - by: The amount to add to the key, which may be negative or fractional.
+//
+// Returns:
+// - map[string]interface{}: The entity's metadata after the increment.
+// - error: An error if the entity does not exist, the key is not a number, or the update fails.
+func (d *Datasource) IncrementMetadata(ctx context.Context, entityType, id, key string, by float64) (map[string]interface{}, error) {
+	counter, ok := metadataCounterTables[entityType]
+	if !ok {
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, fmt.Sprintf("Metadata of %s cannot be incremented", entityType), nil)
+	}
+
+	var metaDataJSON []byte
+	err := d.Conn.QueryRowContext(ctx, fmt.Sprintf(`
+		UPDATE %[1]s
+		SET meta_data = jsonb_set(
+			CASE WHEN jsonb_typeof(meta_data) = 'object' THEN meta_data ELSE '{}'::jsonb END,
+			ARRAY[$2::text],
+			to_jsonb(COALESCE((meta_data->>$2::text)::numeric, 0) + $3::numeric))
+		WHERE %[2]s = $1
+			AND (jsonb_typeof(meta_data) IS DISTINCT FROM 'object' OR meta_data->$2::text IS NULL OR jsonb_typeof(meta_data->$2::text) = 'number')
+		RETURNING meta_data
+	`, counter.table, counter.idColumn), id, key, by).Scan(&metaDataJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Nothing was updated: the entity is missing, or its key holds something other than a number
+		var valueType sql.NullString
+		lookupErr := d.Conn.QueryRowContext(ctx, fmt.Sprintf(`SELECT jsonb_typeof(meta_data->$2::text) FROM %s WHERE %s = $1`,
+			counter.table, counter.idColumn), id, key).Scan(&valueType)
+		if errors.Is(lookupErr, sql.ErrNoRows) {
+			return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Entity with ID '%s' not found", id), nil)
+		}
+		if lookupErr != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to increment metadata", lookupErr)
+		}
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, fmt.Sprintf("Metadata key '%s' holds a %s, not a number", key, valueType.String), nil)
+	}
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to increment metadata", err)
+	}
+
+	var metaData map[string]interface{}
+	if err := json.Unmarshal(metaDataJSON, &metaData); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to decode metadata", err)
+	}
+	return metaData, nil
+}
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/blnkfinance/blnk/internal/apierror"
+
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUpdateLedgerMetadata(t *testing.T) {
@@ -25,7 +28,7 @@ func TestUpdateLedgerMetadata(t *testing.T) {
 		WithArgs(metadataJSON, "ldg_123").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err = ds.UpdateLedgerMetadata("ldg_123", metadata)
+	err = ds.UpdateLedgerMetadata(context.Background(), "ldg_123", metadata)
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -92,7 +95,7 @@ func TestUpdateIdentityMetadata(t *testing.T) {
 		WithArgs(metadataJSON, "idt_123").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err = ds.UpdateIdentityMetadata("idt_123", metadata)
+	err = ds.UpdateIdentityMetadata(context.Background(), "idt_123", metadata)
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -108,7 +111,7 @@ func TestUpdateMetadata_Error(t *testing.T) {
 	}
 
 	// Test for marshal error
-	err = ds.UpdateLedgerMetadata("ldg_123", metadata)
+	err = ds.UpdateLedgerMetadata(context.Background(), "ldg_123", metadata)
 	assert.Error(t, err)
 
 	// Test for database error
@@ -118,7 +121,46 @@ func TestUpdateMetadata_Error(t *testing.T) {
 		WithArgs(metadataJSON, "ldg_123").
 		WillReturnError(sqlmock.ErrCancelled)
 
-	err = ds.UpdateLedgerMetadata("ldg_123", validMetadata)
+	err = ds.UpdateLedgerMetadata(context.Background(), "ldg_123", validMetadata)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncrementMetadata(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+	ctx := context.Background()
+
+	mock.ExpectQuery(`UPDATE blnk\.balances SET meta_data = jsonb_set\(.+WHERE balance_id = \$1 .+RETURNING meta_data`).
+		WithArgs("bln_123", "login_count", 1.0).
+		WillReturnRows(sqlmock.NewRows([]string{"meta_data"}).AddRow(`{"tier": "gold", "login_count": 3}`))
+
+	metaData, err := ds.IncrementMetadata(ctx, "balances", "bln_123", "login_count", 1)
+	require.NoError(t, err)
+	assert.Equal(t, float64(3), metaData["login_count"])
+	assert.Equal(t, "gold", metaData["tier"])
+
+	// A key that is not a number is left unchanged
+	mock.ExpectQuery(`UPDATE blnk\.identity`).WithArgs("idt_123", "tier", 1.0).WillReturnRows(sqlmock.NewRows([]string{"meta_data"}))
+	mock.ExpectQuery(`SELECT jsonb_typeof\(meta_data->\$2::text\) FROM blnk\.identity WHERE identity_id = \$1`).
+		WithArgs("idt_123", "tier").
+		WillReturnRows(sqlmock.NewRows([]string{"jsonb_typeof"}).AddRow("string"))
+
+	_, err = ds.IncrementMetadata(ctx, "identities", "idt_123", "tier", 1)
+	require.Error(t, err)
+	assert.Equal(t, apierror.ErrInvalidInput, err.(apierror.APIError).Code)
+
+	mock.ExpectQuery(`UPDATE blnk\.ledgers`).WithArgs("ldg_404", "runs", 2.5).WillReturnRows(sqlmock.NewRows([]string{"meta_data"}))
+	mock.ExpectQuery(`SELECT jsonb_typeof`).WithArgs("ldg_404", "runs").WillReturnRows(sqlmock.NewRows([]string{"jsonb_typeof"}))
+
+	_, err = ds.IncrementMetadata(ctx, "ledgers", "ldg_404", "runs", 2.5)
+	require.Error(t, err)
+	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
+
+	_, err = ds.IncrementMetadata(ctx, "transactions", "txn_123", "runs", 1)
 	assert.Error(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,159 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const minimumBalanceColumns = `minimum_balance_id, balance_id, ledger_id, currency, precise_amount, created_at`
+
+// minimumBalanceKeyset is the order minimum balances are listed in, newest first.
+var minimumBalanceKeyset = keyset{timeColumn: "created_at", idColumn: "minimum_balance_id", descending: true}
+
+// SetMinimumBalance saves the minimum balance of a balance, or of a ledger in a currency, replacing any
+// minimum already set for it. The ID and creation time of the saved minimum are set on it.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - minimum: The minimum balance to save.
+// Returns:
+// - An error if the minimum cannot be saved.
+func (d Datasource) SetMinimumBalance(ctx context.Context, minimum *model.MinimumBalance) error {
+	ctx, span := otel.Tracer("minimum_balance.database").Start(ctx, "Setting minimum balance")
+	defer span.End()
+
+	conflict := `(balance_id) WHERE balance_id IS NOT NULL`
+	if minimum.LedgerID != "" {
+		conflict = `(ledger_id, currency) WHERE ledger_id IS NOT NULL`
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO blnk.minimum_balances (minimum_balance_id, balance_id, ledger_id, currency, precise_amount, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT %s DO UPDATE SET precise_amount = EXCLUDED.precise_amount
+		RETURNING minimum_balance_id, created_at
+	`, conflict)
+
+	err := d.Conn.QueryRowContext(ctx, query,
+		minimum.MinimumBalanceID, nullString(minimum.BalanceID), nullString(minimum.LedgerID), nullString(minimum.Currency), minimum.PreciseAmount.String(), minimum.CreatedAt,
+	).Scan(&minimum.MinimumBalanceID, &minimum.CreatedAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to set minimum balance", err)
+	}
+	return nil
+}
+
+// GetMinimumBalance retrieves a minimum balance by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - id: The ID of the minimum balance.
+// Returns:
+// - The minimum balance, or an error if it is not found or the query fails.
+func (d Datasource) GetMinimumBalance(ctx context.Context, id string) (*model.MinimumBalance, error) {
+	ctx, span := otel.Tracer("minimum_balance.database").Start(ctx, "Getting minimum balance")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `SELECT `+minimumBalanceColumns+` FROM blnk.minimum_balances WHERE minimum_balance_id = $1`, id)
+	minimum, err := scanMinimumBalance(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("minimum balance with ID '%s' not found", id), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve minimum balance", err)
+	}
+	return minimum, nil
+}
+
+// ListMinimumBalances retrieves a page of minimum balances, newest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - page: The page of minimum balances to read. A zero page reads them all.
+// Returns:
+// - The minimum balances, or an error if the query fails.
+func (d Datasource) ListMinimumBalances(ctx context.Context, page model.Page) ([]*model.MinimumBalance, error) {
+	ctx, span := otel.Tracer("minimum_balance.database").Start(ctx, "Listing minimum balances")
+	defer span.End()
+
+	condition, suffix, args := minimumBalanceKeyset.page(page, nil)
+	rows, err := d.Conn.QueryContext(ctx, `SELECT `+minimumBalanceColumns+` FROM blnk.minimum_balances WHERE `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve minimum balances", err)
+	}
+	defer rows.Close()
+
+	minimums := []*model.MinimumBalance{}
+	for rows.Next() {
+		minimum, err := scanMinimumBalance(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan minimum balance", err)
+		}
+		minimums = append(minimums, minimum)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over minimum balances", err)
+	}
+	return minimums, nil
+}
+
+// DeleteMinimumBalance removes a minimum balance.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - id: The ID of the minimum balance.
+// Returns:
+// - An error if the minimum balance is not found or cannot be deleted.
+func (d Datasource) DeleteMinimumBalance(ctx context.Context, id string) error {
+	ctx, span := otel.Tracer("minimum_balance.database").Start(ctx, "Deleting minimum balance")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `DELETE FROM blnk.minimum_balances WHERE minimum_balance_id = $1`, id)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to delete minimum balance", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("minimum balance with ID '%s' not found", id), nil)
+	}
+	return nil
+}
+
+func scanMinimumBalance(row rowScanner) (*model.MinimumBalance, error) {
+	minimum := &model.MinimumBalance{}
+	var balanceID, ledgerID, currency sql.NullString
+	var preciseAmount string
+	if err := row.Scan(&minimum.MinimumBalanceID, &balanceID, &ledgerID, &currency, &preciseAmount, &minimum.CreatedAt); err != nil {
+		return nil, err
+	}
+	minimum.BalanceID = balanceID.String
+	minimum.LedgerID = ledgerID.String
+	minimum.Currency = currency.String
+	amount, ok := new(big.Int).SetString(preciseAmount, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid minimum balance amount %q", preciseAmount)
+	}
+	minimum.PreciseAmount = amount
+	return minimum, nil
+}
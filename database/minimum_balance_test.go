@@ -0,0 +1,86 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"math/big"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetMinimumBalance_LedgerUpsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	minimum := &model.MinimumBalance{MinimumBalanceID: "min_new", LedgerID: "ldg_1", Currency: "USD", PreciseAmount: big.NewInt(1000), CreatedAt: time.Now()}
+	mock.ExpectQuery(regexp.QuoteMeta("ON CONFLICT (ledger_id, currency) WHERE ledger_id IS NOT NULL DO UPDATE")).
+		WithArgs("min_new", nil, "ldg_1", "USD", "1000", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"minimum_balance_id", "created_at"}).AddRow("min_existing", createdAt))
+
+	require.NoError(t, ds.SetMinimumBalance(context.Background(), minimum))
+	assert.Equal(t, "min_existing", minimum.MinimumBalanceID)
+	assert.Equal(t, createdAt, minimum.CreatedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListMinimumBalances(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.minimum_balances WHERE TRUE ORDER BY created_at DESC, minimum_balance_id DESC LIMIT $1")).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"minimum_balance_id", "balance_id", "ledger_id", "currency", "precise_amount", "created_at"}).
+			AddRow("min_1", "bln_1", nil, nil, "-500", now).
+			AddRow("min_2", nil, "ldg_1", "USD", "100000000000000000000", now))
+
+	minimums, err := ds.ListMinimumBalances(context.Background(), model.Page{Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, minimums, 2)
+	assert.Equal(t, "bln_1", minimums[0].BalanceID)
+	assert.Equal(t, big.NewInt(-500), minimums[0].PreciseAmount)
+	assert.Equal(t, "USD", minimums[1].Currency)
+	assert.Equal(t, "100000000000000000000", minimums[1].PreciseAmount.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteMinimumBalance_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM blnk.minimum_balances WHERE minimum_balance_id = $1")).
+		WithArgs("min_missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = ds.DeleteMinimumBalance(context.Background(), "min_missing")
+	assert.ErrorContains(t, err, "not found")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
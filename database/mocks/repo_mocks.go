@@ -20,6 +20,7 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
 	"github.com/blnkfinance/blnk/model"
 	"github.com/stretchr/testify/mock"
 )
@@ -61,8 +62,8 @@ func (m *MockDataSource) UpdateTransactionStatus(ctx context.Context, id string,
 	return args.Error(0)
 }
 
-func (m *MockDataSource) GetAllTransactions(ctx context.Context, limit, offset int) ([]model.Transaction, error) {
-	args := m.Called(limit, offset)
+func (m *MockDataSource) GetAllTransactions(ctx context.Context, page model.Page) ([]model.Transaction, error) {
+	args := m.Called(page)
 	return args.Get(0).([]model.Transaction), args.Error(1)
 }
 
@@ -71,51 +72,51 @@ func (m *MockDataSource) GetTotalCommittedTransactions(ctx context.Context, pare
 	return args.Get(0).(*big.Int), args.Error(1)
 }
 
-func (m *MockDataSource) GetTransactionsPaginated(ctx context.Context, id string, batchSize int, offset int64) ([]*model.Transaction, error) {
-	args := m.Called(ctx, id, batchSize, offset)
+func (m *MockDataSource) GetTransactionsPaginated(ctx context.Context, id string, page model.Page) ([]*model.Transaction, error) {
+	args := m.Called(ctx, id, page)
 	return args.Get(0).([]*model.Transaction), args.Error(1)
 }
 
-func (m *MockDataSource) GetInflightTransactionsByParentID(ctx context.Context, parentTransactionID string, batchSize int, offset int64) ([]*model.Transaction, error) {
-	args := m.Called(ctx, parentTransactionID, batchSize, offset)
+func (m *MockDataSource) GetInflightTransactionsByParentID(ctx context.Context, parentTransactionID string, page model.Page) ([]*model.Transaction, error) {
+	args := m.Called(ctx, parentTransactionID, page)
 	return args.Get(0).([]*model.Transaction), args.Error(1)
 }
 
-func (m *MockDataSource) GetRefundableTransactionsByParentID(ctx context.Context, parentTransactionID string, batchSize int, offset int64) ([]*model.Transaction, error) {
-	args := m.Called(ctx, parentTransactionID, batchSize, offset)
+func (m *MockDataSource) GetRefundableTransactionsByParentID(ctx context.Context, parentTransactionID string, page model.Page) ([]*model.Transaction, error) {
+	args := m.Called(ctx, parentTransactionID, page)
 	return args.Get(0).([]*model.Transaction), args.Error(1)
 }
 
-func (m *MockDataSource) GroupTransactions(ctx context.Context, groupCriteria string, batchSize int, offset int64) (map[string][]*model.Transaction, error) {
-	args := m.Called(ctx, groupCriteria, batchSize, offset)
+func (m *MockDataSource) GroupTransactions(ctx context.Context, groupCriteria string, page model.Page) (map[string][]*model.Transaction, error) {
+	args := m.Called(ctx, groupCriteria, page)
 	return args.Get(0).(map[string][]*model.Transaction), args.Error(1)
 }
 
-func (m *MockDataSource) GetTransactionsByParent(ctx context.Context, parentID string, limit int, offset int64) ([]*model.Transaction, error) {
-	args := m.Called(ctx, parentID, limit, offset)
+func (m *MockDataSource) GetTransactionsByParent(ctx context.Context, parentID string, page model.Page) ([]*model.Transaction, error) {
+	args := m.Called(ctx, parentID, page)
 	return args.Get(0).([]*model.Transaction), args.Error(1)
 }
 
 // Ledger methods
 
-func (m *MockDataSource) CreateLedger(ledger model.Ledger) (model.Ledger, error) {
-	args := m.Called(ledger)
+func (m *MockDataSource) CreateLedger(ctx context.Context, ledger model.Ledger) (model.Ledger, error) {
+	args := m.Called(ctx, ledger)
 	return args.Get(0).(model.Ledger), args.Error(1)
 }
 
-func (m *MockDataSource) GetAllLedgers(limit, offset int) ([]model.Ledger, error) {
-	args := m.Called(limit, offset)
+func (m *MockDataSource) GetAllLedgers(ctx context.Context, page model.Page) ([]model.Ledger, error) {
+	args := m.Called(ctx, page)
 	return args.Get(0).([]model.Ledger), args.Error(1)
 }
 
-func (m *MockDataSource) GetLedgerByID(id string) (*model.Ledger, error) {
-	args := m.Called(id)
+func (m *MockDataSource) GetLedgerByID(ctx context.Context, id string) (*model.Ledger, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*model.Ledger), args.Error(1)
 }
 
 // Metadata update methods
-func (m *MockDataSource) UpdateLedgerMetadata(id string, metadata map[string]interface{}) error {
-	args := m.Called(id, metadata)
+func (m *MockDataSource) UpdateLedgerMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	args := m.Called(ctx, id, metadata)
 	return args.Error(0)
 }
 
@@ -129,40 +130,48 @@ func (m *MockDataSource) UpdateBalanceMetadata(ctx context.Context, id string, m
 	return args.Error(0)
 }
 
-func (m *MockDataSource) UpdateIdentityMetadata(id string, metadata map[string]interface{}) error {
-	args := m.Called(id, metadata)
+func (m *MockDataSource) UpdateIdentityMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	args := m.Called(ctx, id, metadata)
 	return args.Error(0)
 }
 
+func (m *MockDataSource) IncrementMetadata(ctx context.Context, entityType, id, key string, by float64) (map[string]interface{}, error) {
+	args := m.Called(ctx, entityType, id, key, by)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]interface{}), args.Error(1)
+}
+
 // Balance methods
 
-func (m *MockDataSource) CreateBalance(balance model.Balance) (model.Balance, error) {
-	args := m.Called(balance)
+func (m *MockDataSource) CreateBalance(ctx context.Context, balance model.Balance) (model.Balance, error) {
+	args := m.Called(ctx, balance)
 	return args.Get(0).(model.Balance), args.Error(1)
 }
 
-func (m *MockDataSource) GetBalanceByID(id string, include []string, withQueued bool) (*model.Balance, error) {
-	args := m.Called(id, include, withQueued)
+func (m *MockDataSource) GetBalanceByID(ctx context.Context, id string, include []string, withQueued bool) (*model.Balance, error) {
+	args := m.Called(ctx, id, include, withQueued)
 	return args.Get(0).(*model.Balance), args.Error(1)
 }
 
-func (m *MockDataSource) GetBalanceByIDLite(id string) (*model.Balance, error) {
-	args := m.Called(id)
+func (m *MockDataSource) GetBalanceByIDLite(ctx context.Context, id string) (*model.Balance, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*model.Balance), args.Error(1)
 }
 
-func (m *MockDataSource) GetAllBalances(limit, offset int) ([]model.Balance, error) {
-	args := m.Called(limit, offset)
+func (m *MockDataSource) GetAllBalances(ctx context.Context, page model.Page) ([]model.Balance, error) {
+	args := m.Called(ctx, page)
 	return args.Get(0).([]model.Balance), args.Error(1)
 }
 
-func (m *MockDataSource) UpdateBalance(balance *model.Balance) error {
-	args := m.Called(balance)
+func (m *MockDataSource) UpdateBalance(ctx context.Context, balance *model.Balance) error {
+	args := m.Called(ctx, balance)
 	return args.Error(0)
 }
 
-func (m *MockDataSource) GetBalanceByIndicator(indicator, currency string) (*model.Balance, error) {
-	args := m.Called(indicator, currency)
+func (m *MockDataSource) GetBalanceByIndicator(ctx context.Context, indicator, currency string) (*model.Balance, error) {
+	args := m.Called(ctx, indicator, currency)
 	return args.Get(0).(*model.Balance), args.Error(1)
 }
 
@@ -171,8 +180,8 @@ func (m *MockDataSource) UpdateBalances(ctx context.Context, sourceBalance, dest
 	return args.Error(0)
 }
 
-func (m *MockDataSource) GetSourceDestination(sourceId, destinationId string) ([]*model.Balance, error) {
-	args := m.Called(sourceId, destinationId)
+func (m *MockDataSource) GetSourceDestination(ctx context.Context, sourceId, destinationId string) ([]*model.Balance, error) {
+	args := m.Called(ctx, sourceId, destinationId)
 	return args.Get(0).([]*model.Balance), args.Error(1)
 }
 
@@ -183,92 +192,330 @@ func (m *MockDataSource) GetBalanceAtTime(ctx context.Context, balanceID string,
 
 // Account methods
 
-func (m *MockDataSource) CreateAccount(account model.Account) (model.Account, error) {
-	args := m.Called(account)
+func (m *MockDataSource) CreateAccount(ctx context.Context, account model.Account) (model.Account, error) {
+	args := m.Called(ctx, account)
 	return args.Get(0).(model.Account), args.Error(1)
 }
 
-func (m *MockDataSource) GetAccountByID(id string, include []string) (*model.Account, error) {
-	args := m.Called(id, include)
+func (m *MockDataSource) GetAccountByID(ctx context.Context, id string, include []string) (*model.Account, error) {
+	args := m.Called(ctx, id, include)
 	return args.Get(0).(*model.Account), args.Error(1)
 }
 
-func (m *MockDataSource) GetAllAccounts() ([]model.Account, error) {
-	args := m.Called()
+func (m *MockDataSource) GetAllAccounts(ctx context.Context) ([]model.Account, error) {
+	args := m.Called(ctx)
 	return args.Get(0).([]model.Account), args.Error(1)
 }
 
-func (m *MockDataSource) GetAccountByNumber(number string) (*model.Account, error) {
-	args := m.Called(number)
+func (m *MockDataSource) GetAccountByNumber(ctx context.Context, number string) (*model.Account, error) {
+	args := m.Called(ctx, number)
 	return args.Get(0).(*model.Account), args.Error(1)
 }
 
-func (m *MockDataSource) UpdateAccount(account *model.Account) error {
-	args := m.Called(account)
+func (m *MockDataSource) UpdateAccount(ctx context.Context, account *model.Account) error {
+	args := m.Called(ctx, account)
 	return args.Error(0)
 }
 
-func (m *MockDataSource) DeleteAccount(id string) error {
-	args := m.Called(id)
+func (m *MockDataSource) DeleteAccount(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
 // BalanceMonitor methods
 
-func (m *MockDataSource) CreateMonitor(monitor model.BalanceMonitor) (model.BalanceMonitor, error) {
-	args := m.Called(monitor)
+func (m *MockDataSource) CreateMonitor(ctx context.Context, monitor model.BalanceMonitor) (model.BalanceMonitor, error) {
+	args := m.Called(ctx, monitor)
 	return args.Get(0).(model.BalanceMonitor), args.Error(1)
 }
 
-func (m *MockDataSource) GetMonitorByID(id string) (*model.BalanceMonitor, error) {
-	args := m.Called(id)
+func (m *MockDataSource) GetMonitorByID(ctx context.Context, id string) (*model.BalanceMonitor, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*model.BalanceMonitor), args.Error(1)
 }
 
-func (m *MockDataSource) GetAllMonitors() ([]model.BalanceMonitor, error) {
-	args := m.Called()
+func (m *MockDataSource) GetAllMonitors(ctx context.Context) ([]model.BalanceMonitor, error) {
+	args := m.Called(ctx)
 	return args.Get(0).([]model.BalanceMonitor), args.Error(1)
 }
 
-func (m *MockDataSource) GetBalanceMonitors(balanceID string) ([]model.BalanceMonitor, error) {
-	args := m.Called(balanceID)
+func (m *MockDataSource) GetBalanceMonitors(ctx context.Context, balanceID string) ([]model.BalanceMonitor, error) {
+	args := m.Called(ctx, balanceID)
 	return args.Get(0).([]model.BalanceMonitor), args.Error(1)
 }
 
-func (m *MockDataSource) UpdateMonitor(monitor *model.BalanceMonitor) error {
-	args := m.Called(monitor)
+func (m *MockDataSource) UpdateMonitor(ctx context.Context, monitor *model.BalanceMonitor) error {
+	args := m.Called(ctx, monitor)
 	return args.Error(0)
 }
 
-func (m *MockDataSource) DeleteMonitor(id string) error {
-	args := m.Called(id)
+func (m *MockDataSource) DeleteMonitor(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
 // Identity methods
 
-func (m *MockDataSource) CreateIdentity(identity model.Identity) (model.Identity, error) {
-	args := m.Called(identity)
+func (m *MockDataSource) CreateIdentity(ctx context.Context, identity model.Identity) (model.Identity, error) {
+	args := m.Called(ctx, identity)
 	return args.Get(0).(model.Identity), args.Error(1)
 }
 
-func (m *MockDataSource) GetIdentityByID(id string) (*model.Identity, error) {
-	args := m.Called(id)
+func (m *MockDataSource) GetIdentityByID(ctx context.Context, id string) (*model.Identity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*model.Identity), args.Error(1)
+}
+
+func (m *MockDataSource) GetIdentityByIDIncludingDeleted(ctx context.Context, id string) (*model.Identity, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).(*model.Identity), args.Error(1)
 }
 
-func (m *MockDataSource) GetAllIdentities() ([]model.Identity, error) {
-	args := m.Called()
+func (m *MockDataSource) GetAllIdentities(ctx context.Context) ([]model.Identity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]model.Identity), args.Error(1)
+}
+
+func (m *MockDataSource) GetIdentities(ctx context.Context, filter model.IdentityFilter, page model.Page) ([]model.Identity, error) {
+	args := m.Called(ctx, filter, page)
+	return args.Get(0).([]model.Identity), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateIdentity(ctx context.Context, identity *model.Identity, changedBy string) error {
+	args := m.Called(ctx, identity, changedBy)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) DeleteIdentity(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) RestoreIdentity(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) FindDuplicateIdentities(ctx context.Context, identity *model.Identity) ([]model.Identity, error) {
+	args := m.Called(ctx, identity)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).([]model.Identity), args.Error(1)
 }
 
-func (m *MockDataSource) UpdateIdentity(identity *model.Identity) error {
-	args := m.Called(identity)
+func (m *MockDataSource) MergeIdentities(ctx context.Context, merge *model.IdentityMerge) error {
+	args := m.Called(ctx, merge)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetIdentityMerges(ctx context.Context, identityID string) ([]*model.IdentityMerge, error) {
+	args := m.Called(ctx, identityID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.IdentityMerge), args.Error(1)
+}
+
+func (m *MockDataSource) GetIdentityHistory(ctx context.Context, identityID string) ([]*model.IdentityHistoryEntry, error) {
+	args := m.Called(ctx, identityID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.IdentityHistoryEntry), args.Error(1)
+}
+
+func (m *MockDataSource) CreateIdentities(ctx context.Context, identities []*model.Identity) error {
+	args := m.Called(ctx, identities)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) AnonymizeIdentity(ctx context.Context, erasure *model.IdentityErasure) error {
+	args := m.Called(ctx, erasure)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetIdentityErasure(ctx context.Context, identityID string) (*model.IdentityErasure, error) {
+	args := m.Called(ctx, identityID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdentityErasure), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateIdentityVerification(ctx context.Context, id, from, to, reason string, at time.Time) error {
+	args := m.Called(ctx, id, from, to, reason, at)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) UpdateIdentityStatus(ctx context.Context, id, from, to, reason string, at time.Time) error {
+	args := m.Called(ctx, id, from, to, reason, at)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) ListInactiveIdentityIDs(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateIdentityRisk(ctx context.Context, id string, score float64, level string, at time.Time) error {
+	args := m.Called(ctx, id, score, level, at)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) CreateIdentityDocument(ctx context.Context, document *model.IdentityDocument) error {
+	args := m.Called(ctx, document)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetIdentityDocument(ctx context.Context, documentID string) (*model.IdentityDocument, error) {
+	args := m.Called(ctx, documentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdentityDocument), args.Error(1)
+}
+
+func (m *MockDataSource) GetIdentityDocuments(ctx context.Context, identityID string) ([]*model.IdentityDocument, error) {
+	args := m.Called(ctx, identityID)
+	return args.Get(0).([]*model.IdentityDocument), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateIdentityDocumentVerification(ctx context.Context, documentID, status, reason string, at time.Time) error {
+	args := m.Called(ctx, documentID, status, reason, at)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) CreateIdentityRelationship(ctx context.Context, relationship *model.IdentityRelationship) error {
+	args := m.Called(ctx, relationship)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetIdentityRelationship(ctx context.Context, relationshipID string) (*model.IdentityRelationship, error) {
+	args := m.Called(ctx, relationshipID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdentityRelationship), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateIdentityRelationship(ctx context.Context, relationship *model.IdentityRelationship) error {
+	args := m.Called(ctx, relationship)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) DeleteIdentityRelationship(ctx context.Context, relationshipID string) error {
+	args := m.Called(ctx, relationshipID)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetRelatedIdentities(ctx context.Context, identityID string) ([]*model.RelatedIdentity, error) {
+	args := m.Called(ctx, identityID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.RelatedIdentity), args.Error(1)
+}
+
+func (m *MockDataSource) CreateIdentityAddress(ctx context.Context, address *model.IdentityAddress) error {
+	args := m.Called(ctx, address)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetIdentityAddress(ctx context.Context, addressID string) (*model.IdentityAddress, error) {
+	args := m.Called(ctx, addressID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdentityAddress), args.Error(1)
+}
+
+func (m *MockDataSource) GetIdentityAddresses(ctx context.Context, identityID string) ([]*model.IdentityAddress, error) {
+	args := m.Called(ctx, identityID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.IdentityAddress), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateIdentityAddress(ctx context.Context, address *model.IdentityAddress) error {
+	args := m.Called(ctx, address)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) DeleteIdentityAddress(ctx context.Context, addressID string) error {
+	args := m.Called(ctx, addressID)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) AddIdentityTags(ctx context.Context, id string, tags []string) ([]string, error) {
+	args := m.Called(ctx, id, tags)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockDataSource) RemoveIdentityTags(ctx context.Context, id string, tags []string) ([]string, error) {
+	args := m.Called(ctx, id, tags)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockDataSource) CreateExternalAccount(ctx context.Context, account *model.ExternalAccount) error {
+	args := m.Called(ctx, account)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetExternalAccount(ctx context.Context, externalAccountID string) (*model.ExternalAccount, error) {
+	args := m.Called(ctx, externalAccountID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ExternalAccount), args.Error(1)
+}
+
+func (m *MockDataSource) GetExternalAccounts(ctx context.Context, identityID string) ([]*model.ExternalAccount, error) {
+	args := m.Called(ctx, identityID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ExternalAccount), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateExternalAccountVerification(ctx context.Context, externalAccountID, from, to, reason string, at time.Time) error {
+	args := m.Called(ctx, externalAccountID, from, to, reason, at)
 	return args.Error(0)
 }
 
-func (m *MockDataSource) DeleteIdentity(id string) error {
-	args := m.Called(id)
+func (m *MockDataSource) CreateContactVerification(ctx context.Context, verification *model.ContactVerification) error {
+	args := m.Called(ctx, verification)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetPendingContactVerification(ctx context.Context, identityID, channel string) (*model.ContactVerification, error) {
+	args := m.Called(ctx, identityID, channel)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ContactVerification), args.Error(1)
+}
+
+func (m *MockDataSource) IncrementContactVerificationAttempts(ctx context.Context, verificationID string) (int, error) {
+	args := m.Called(ctx, verificationID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDataSource) ConfirmContactVerification(ctx context.Context, verification *model.ContactVerification) error {
+	args := m.Called(ctx, verification)
 	return args.Error(0)
 }
 
@@ -314,8 +561,23 @@ func (m *MockDataSource) GetMatchesByReconciliationID(ctx context.Context, recon
 	return args.Get(0).([]*model.Match), args.Error(1)
 }
 
-func (m *MockDataSource) GetExternalTransactionsPaginated(ctx context.Context, uploadID string, batchSize int, offset int64) ([]*model.ExternalTransaction, error) {
-	args := m.Called(ctx, uploadID, batchSize, offset)
+func (m *MockDataSource) GetMatchesByStatus(ctx context.Context, reconciliationID, status string) ([]*model.Match, error) {
+	args := m.Called(ctx, reconciliationID, status)
+	return args.Get(0).([]*model.Match), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateMatchStatus(ctx context.Context, reconciliationID, externalTxnID, internalTxnID, status string) error {
+	args := m.Called(ctx, reconciliationID, externalTxnID, internalTxnID, status)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetMatchScoreDistribution(ctx context.Context, reconciliationID string) (*model.ScoreDistribution, error) {
+	args := m.Called(ctx, reconciliationID)
+	return args.Get(0).(*model.ScoreDistribution), args.Error(1)
+}
+
+func (m *MockDataSource) GetExternalTransactionsPaginated(ctx context.Context, uploadID string, page model.Page) ([]*model.ExternalTransaction, error) {
+	args := m.Called(ctx, uploadID, page)
 	return args.Get(0).([]*model.ExternalTransaction), args.Error(1)
 }
 
@@ -324,6 +586,19 @@ func (m *MockDataSource) RecordExternalTransaction(ctx context.Context, tx *mode
 	return args.Error(0)
 }
 
+func (m *MockDataSource) RecordExternalTransactions(ctx context.Context, txns []*model.ExternalTransaction, uploadID string) ([]string, error) {
+	args := m.Called(ctx, txns, uploadID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockDataSource) GetUnmatchedExternalTransactions(ctx context.Context, reconciliationID string) ([]*model.ExternalTransaction, error) {
+	args := m.Called(ctx, reconciliationID)
+	return args.Get(0).([]*model.ExternalTransaction), args.Error(1)
+}
+
 func (m *MockDataSource) RecordMatchingRule(ctx context.Context, rule *model.MatchingRule) error {
 	args := m.Called(ctx, rule)
 	return args.Error(0)
@@ -359,8 +634,8 @@ func (m *MockDataSource) LoadReconciliationProgress(ctx context.Context, reconci
 	return args.Get(0).(model.ReconciliationProgress), args.Error(1)
 }
 
-func (m *MockDataSource) FetchAndGroupExternalTransactions(ctx context.Context, uploadID string, groupCriteria string, batchSize int, offset int64) (map[string][]*model.Transaction, error) {
-	args := m.Called(ctx, uploadID, groupCriteria, batchSize, offset)
+func (m *MockDataSource) FetchAndGroupExternalTransactions(ctx context.Context, uploadID string, groupCriteria string, page model.Page) (map[string][]*model.Transaction, error) {
+	args := m.Called(ctx, uploadID, groupCriteria, page)
 	return args.Get(0).(map[string][]*model.Transaction), args.Error(1)
 }
 
@@ -374,8 +649,8 @@ func (m *MockDataSource) CreateAPIKey(ctx context.Context, name, ownerID string,
 	return args.Get(0).(*model.APIKey), args.Error(1)
 }
 
-func (m *MockDataSource) ListAPIKeys(ctx context.Context, ownerID string) ([]*model.APIKey, error) {
-	args := m.Called(ctx, ownerID)
+func (m *MockDataSource) ListAPIKeys(ctx context.Context, ownerID string, page model.Page) ([]*model.APIKey, error) {
+	args := m.Called(ctx, ownerID, page)
 	return args.Get(0).([]*model.APIKey), args.Error(1)
 }
 
@@ -404,7 +679,854 @@ func (m *MockDataSource) IsTransactionRefunded(ctx context.Context, transaction
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockDataSource) UpdateBalanceIdentity(balanceID string, identityID string) error {
-	args := m.Called(balanceID, identityID)
+func (m *MockDataSource) UpdateBalanceIdentity(ctx context.Context, balanceID string, identityID string) error {
+	args := m.Called(ctx, balanceID, identityID)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetTransactionsCreatedBetween(ctx context.Context, start, end time.Time, page model.Page) ([]*model.Transaction, error) {
+	args := m.Called(ctx, start, end, page)
+	return args.Get(0).([]*model.Transaction), args.Error(1)
+}
+
+func (m *MockDataSource) RotateAPIKey(ctx context.Context, id, ownerID string, overlap time.Duration) (*model.APIKey, error) {
+	args := m.Called(ctx, id, ownerID, overlap)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.APIKey), args.Error(1)
+}
+
+func (m *MockDataSource) ExpireAPIKeyRotation(ctx context.Context, id, ownerID string) error {
+	args := m.Called(ctx, id, ownerID)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) UpdateAPIKeyNetworkPolicy(ctx context.Context, id, ownerID string, policy model.NetworkPolicy) error {
+	args := m.Called(ctx, id, ownerID, policy)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) UpdateAPIKeyFieldCompatibility(ctx context.Context, id, ownerID, mode string) error {
+	args := m.Called(ctx, id, ownerID, mode)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) UpdateAPIKeyLedgerScope(ctx context.Context, id, ownerID string, scope ledgerscope.Scope) error {
+	args := m.Called(ctx, id, ownerID, scope)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) RecordAPIKeyAccessDenial(ctx context.Context, denial *model.APIKeyAccessDenial) error {
+	args := m.Called(ctx, denial)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) ListAPIKeyAccessDenials(ctx context.Context, id, ownerID string, limit int) ([]*model.APIKeyAccessDenial, error) {
+	args := m.Called(ctx, id, ownerID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.APIKeyAccessDenial), args.Error(1)
+}
+
+func (m *MockDataSource) CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) error {
+	args := m.Called(ctx, account)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetServiceAccount(ctx context.Context, id string) (*model.ServiceAccount, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ServiceAccount), args.Error(1)
+}
+
+func (m *MockDataSource) ListServiceAccounts(ctx context.Context, ownerID string) ([]*model.ServiceAccount, error) {
+	args := m.Called(ctx, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ServiceAccount), args.Error(1)
+}
+
+func (m *MockDataSource) RevokeServiceAccount(ctx context.Context, id, ownerID string) error {
+	args := m.Called(ctx, id, ownerID)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) UpdateServiceAccountTokenIssued(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) CreateNettingGroup(ctx context.Context, group *model.NettingGroup) error {
+	args := m.Called(ctx, group)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetNettingGroup(ctx context.Context, groupID string) (*model.NettingGroup, error) {
+	args := m.Called(ctx, groupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.NettingGroup), args.Error(1)
+}
+
+func (m *MockDataSource) ListNettingGroups(ctx context.Context, page model.Page) ([]*model.NettingGroup, error) {
+	args := m.Called(ctx, page)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.NettingGroup), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateNettingGroupStatus(ctx context.Context, groupID string, active bool) error {
+	args := m.Called(ctx, groupID, active)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) RecordNettingEntry(ctx context.Context, entry *model.NettingEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) ListNettingEntries(ctx context.Context, groupID, status string, page model.Page) ([]*model.NettingEntry, error) {
+	args := m.Called(ctx, groupID, status, page)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.NettingEntry), args.Error(1)
+}
+
+func (m *MockDataSource) GetBalanceNettingEntries(ctx context.Context, balanceID string, start, end time.Time) ([]*model.NettingEntry, error) {
+	args := m.Called(ctx, balanceID, start, end)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.NettingEntry), args.Error(1)
+}
+
+func (m *MockDataSource) CreateNettingSettlement(ctx context.Context, settlement *model.NettingSettlement) ([]*model.NettingEntry, error) {
+	args := m.Called(ctx, settlement)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.NettingEntry), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateNettingSettlement(ctx context.Context, settlement *model.NettingSettlement) error {
+	args := m.Called(ctx, settlement)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetNettingSettlement(ctx context.Context, settlementID string) (*model.NettingSettlement, error) {
+	args := m.Called(ctx, settlementID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.NettingSettlement), args.Error(1)
+}
+
+func (m *MockDataSource) ListNettingSettlements(ctx context.Context, groupID string) ([]*model.NettingSettlement, error) {
+	args := m.Called(ctx, groupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.NettingSettlement), args.Error(1)
+}
+
+func (m *MockDataSource) CreateCardAuthorization(ctx context.Context, auth *model.CardAuthorization) error {
+	args := m.Called(ctx, auth)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetCardAuthorization(ctx context.Context, authorizationID string) (*model.CardAuthorization, error) {
+	args := m.Called(ctx, authorizationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CardAuthorization), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateCardAuthorization(ctx context.Context, auth *model.CardAuthorization) error {
+	args := m.Called(ctx, auth)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetExpiredCardAuthorizations(ctx context.Context, now time.Time, limit int) ([]*model.CardAuthorization, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.CardAuthorization), args.Error(1)
+}
+
+func (m *MockDataSource) RecordScheduledTransactionFailure(ctx context.Context, failure *model.ScheduledTransactionFailure) error {
+	args := m.Called(ctx, failure)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetScheduledTransactionFailures(ctx context.Context, start, end time.Time, page model.Page) ([]*model.ScheduledTransactionFailure, error) {
+	args := m.Called(ctx, start, end, page)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ScheduledTransactionFailure), args.Error(1)
+}
+
+func (m *MockDataSource) UpsertBalanceNotificationPreference(ctx context.Context, preference *model.BalanceNotificationPreference) error {
+	args := m.Called(ctx, preference)
 	return args.Error(0)
 }
+
+func (m *MockDataSource) GetBalanceNotificationPreference(ctx context.Context, balanceID string) (*model.BalanceNotificationPreference, error) {
+	args := m.Called(ctx, balanceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.BalanceNotificationPreference), args.Error(1)
+}
+
+func (m *MockDataSource) ListBalanceNotificationPreferences(ctx context.Context) ([]*model.BalanceNotificationPreference, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.BalanceNotificationPreference), args.Error(1)
+}
+
+func (m *MockDataSource) DeleteBalanceNotificationPreference(ctx context.Context, balanceID string) error {
+	args := m.Called(ctx, balanceID)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) EstimateRowCount(ctx context.Context, table string) (int64, error) {
+	args := m.Called(ctx, table)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockDataSource) FindInactiveBalances(ctx context.Context, inactiveSince time.Time, limit int) ([]*model.DormantBalance, error) {
+	args := m.Called(ctx, inactiveSince, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.DormantBalance), args.Error(1)
+}
+
+func (m *MockDataSource) FlagDormantBalance(ctx context.Context, balance *model.DormantBalance) (bool, error) {
+	args := m.Called(ctx, balance)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDataSource) ClearReactivatedBalances(ctx context.Context) ([]*model.DormantBalance, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.DormantBalance), args.Error(1)
+}
+
+func (m *MockDataSource) GetDormantBalance(ctx context.Context, balanceID string) (*model.DormantBalance, error) {
+	args := m.Called(ctx, balanceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.DormantBalance), args.Error(1)
+}
+
+func (m *MockDataSource) ListDormantBalances(ctx context.Context, status string, page model.Page) ([]*model.DormantBalance, error) {
+	args := m.Called(ctx, status, page)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.DormantBalance), args.Error(1)
+}
+
+func (m *MockDataSource) ListFrozenBalanceIDs(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockDataSource) DeleteDormantBalance(ctx context.Context, balanceID string) error {
+	args := m.Called(ctx, balanceID)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) CreateEscheatmentBatch(ctx context.Context, batch *model.EscheatmentBatch, dormantBefore *time.Time) error {
+	args := m.Called(ctx, batch, dormantBefore)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) UpdateEscheatmentBatch(ctx context.Context, batch *model.EscheatmentBatch) error {
+	args := m.Called(ctx, batch)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetEscheatmentBatch(ctx context.Context, batchID string) (*model.EscheatmentBatch, error) {
+	args := m.Called(ctx, batchID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.EscheatmentBatch), args.Error(1)
+}
+
+func (m *MockDataSource) ListEscheatmentBatches(ctx context.Context, page model.Page) ([]*model.EscheatmentBatch, error) {
+	args := m.Called(ctx, page)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.EscheatmentBatch), args.Error(1)
+}
+
+func (m *MockDataSource) RecordStatementIngestion(ctx context.Context, ingestion *model.StatementIngestion) (bool, error) {
+	args := m.Called(ctx, ingestion)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateStatementIngestion(ctx context.Context, ingestion *model.StatementIngestion) error {
+	args := m.Called(ctx, ingestion)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) ListStatementIngestions(ctx context.Context, page model.Page) ([]*model.StatementIngestion, error) {
+	args := m.Called(ctx, page)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.StatementIngestion), args.Error(1)
+}
+
+func (m *MockDataSource) RecordRequestLog(ctx context.Context, entry *model.RequestLog) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetRequestLog(ctx context.Context, logID, ownerID string) (*model.RequestLog, error) {
+	args := m.Called(ctx, logID, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RequestLog), args.Error(1)
+}
+
+func (m *MockDataSource) ListRequestLogs(ctx context.Context, filter model.RequestLogFilter, page model.Page) ([]*model.RequestLog, error) {
+	args := m.Called(ctx, filter, page)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.RequestLog), args.Error(1)
+}
+
+func (m *MockDataSource) PruneRequestLogs(ctx context.Context, before time.Time, maxEntries int) (int64, error) {
+	args := m.Called(ctx, before, maxEntries)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockDataSource) RecordWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetWebhookAnalytics(ctx context.Context, endpointID string, from, to time.Time) (*model.WebhookAnalytics, error) {
+	args := m.Called(ctx, endpointID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.WebhookAnalytics), args.Error(1)
+}
+
+func (m *MockDataSource) PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockDataSource) FindSlowStatements(ctx context.Context, minMeanTime time.Duration, limit int) ([]model.SchemaRecommendation, error) {
+	args := m.Called(ctx, minMeanTime, limit)
+	return args.Get(0).([]model.SchemaRecommendation), args.Error(1)
+}
+
+func (m *MockDataSource) FindSequentialScanTables(ctx context.Context, minRows int64, limit int) ([]model.SchemaRecommendation, error) {
+	args := m.Called(ctx, minRows, limit)
+	return args.Get(0).([]model.SchemaRecommendation), args.Error(1)
+}
+
+func (m *MockDataSource) FindUnindexedMetadata(ctx context.Context, minRows int64, limit int) ([]model.SchemaRecommendation, error) {
+	args := m.Called(ctx, minRows, limit)
+	return args.Get(0).([]model.SchemaRecommendation), args.Error(1)
+}
+
+func (m *MockDataSource) FindUnusedIndexes(ctx context.Context, limit int) ([]model.SchemaRecommendation, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]model.SchemaRecommendation), args.Error(1)
+}
+
+func (m *MockDataSource) FindTablesNeedingMaintenance(ctx context.Context, limit int) ([]model.SchemaRecommendation, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]model.SchemaRecommendation), args.Error(1)
+}
+
+func (m *MockDataSource) UpsertBalanceSharding(ctx context.Context, sharding *model.BalanceSharding) error {
+	args := m.Called(ctx, sharding)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetBalanceSharding(ctx context.Context, balanceID string) (*model.BalanceSharding, error) {
+	args := m.Called(ctx, balanceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.BalanceSharding), args.Error(1)
+}
+
+func (m *MockDataSource) ListBalanceShardings(ctx context.Context) ([]*model.BalanceSharding, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.BalanceSharding), args.Error(1)
+}
+
+func (m *MockDataSource) GetPendingInflightCredits(ctx context.Context, balanceID string, limit int) ([]*model.PendingInflightCredit, error) {
+	args := m.Called(ctx, balanceID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.PendingInflightCredit), args.Error(1)
+}
+
+func (m *MockDataSource) GetTransactionStatusHistory(ctx context.Context, transactionID string) ([]*model.TransactionStatusEntry, error) {
+	args := m.Called(ctx, transactionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.TransactionStatusEntry), args.Error(1)
+}
+
+func (m *MockDataSource) GetReplayTransactions(ctx context.Context, ledgerID string, from, to time.Time) ([]*model.Transaction, error) {
+	args := m.Called(ctx, ledgerID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Transaction), args.Error(1)
+}
+
+func (m *MockDataSource) GetReplayBalanceAt(ctx context.Context, balanceID string, at time.Time) (*model.Balance, error) {
+	args := m.Called(ctx, balanceID, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Balance), args.Error(1)
+}
+
+func (m *MockDataSource) RestoreLedger(ctx context.Context, ledgerID string) error {
+	args := m.Called(ctx, ledgerID)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) RestoreBalance(ctx context.Context, balance *model.Balance) error {
+	args := m.Called(ctx, balance)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetWarehouseBatch(ctx context.Context, entity string, after model.WarehouseWatermark, until time.Time, limit int) (*model.WarehouseBatch, error) {
+	args := m.Called(ctx, entity, after, until, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.WarehouseBatch), args.Error(1)
+}
+
+func (m *MockDataSource) GetWarehouseSyncState(ctx context.Context, entity string) (*model.WarehouseSyncState, error) {
+	args := m.Called(ctx, entity)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.WarehouseSyncState), args.Error(1)
+}
+
+func (m *MockDataSource) ListWarehouseSyncStates(ctx context.Context) ([]*model.WarehouseSyncState, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.WarehouseSyncState), args.Error(1)
+}
+
+func (m *MockDataSource) SaveWarehouseSyncState(ctx context.Context, state *model.WarehouseSyncState) error {
+	args := m.Called(ctx, state)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetComplianceBacklog(ctx context.Context, limit int) (*model.ComplianceBacklog, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ComplianceBacklog), args.Error(1)
+}
+
+func (m *MockDataSource) GetComplianceExportState(ctx context.Context) (*model.ComplianceExportState, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ComplianceExportState), args.Error(1)
+}
+
+func (m *MockDataSource) SaveComplianceExportState(ctx context.Context, state *model.ComplianceExportState, cursors map[string]int64) error {
+	args := m.Called(ctx, state, cursors)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetTransactionSequences(ctx context.Context, transactionID string) ([]model.LedgerSequence, error) {
+	args := m.Called(ctx, transactionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.LedgerSequence), args.Error(1)
+}
+
+func (m *MockDataSource) GetLedgerSequence(ctx context.Context, ledgerID string, after int64, limit int) (*model.LedgerSequencePage, error) {
+	args := m.Called(ctx, ledgerID, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.LedgerSequencePage), args.Error(1)
+}
+
+func (m *MockDataSource) GetBalanceTransactionsBetween(ctx context.Context, balanceID string, start, end time.Time, page model.Page) ([]*model.Transaction, error) {
+	args := m.Called(ctx, balanceID, start, end, page)
+	return args.Get(0).([]*model.Transaction), args.Error(1)
+}
+
+func (m *MockDataSource) GetTransactionsByGroup(ctx context.Context, groupID string, page model.Page) ([]*model.Transaction, error) {
+	args := m.Called(ctx, groupID, page)
+	return args.Get(0).([]*model.Transaction), args.Error(1)
+}
+
+func (m *MockDataSource) GetTransactionsByIdentity(ctx context.Context, identityID string, page model.Page) ([]*model.Transaction, error) {
+	args := m.Called(ctx, identityID, page)
+	return args.Get(0).([]*model.Transaction), args.Error(1)
+}
+
+func (m *MockDataSource) GetBalancesByIdentity(ctx context.Context, identityID string) ([]model.Balance, error) {
+	args := m.Called(ctx, identityID)
+	return args.Get(0).([]model.Balance), args.Error(1)
+}
+
+func (m *MockDataSource) GetBalancesByLedger(ctx context.Context, ledgerID string) ([]model.Balance, error) {
+	args := m.Called(ctx, ledgerID)
+	return args.Get(0).([]model.Balance), args.Error(1)
+}
+
+func (m *MockDataSource) CreateStatementSchedule(ctx context.Context, schedule *model.StatementSchedule) error {
+	args := m.Called(ctx, schedule)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetStatementSchedule(ctx context.Context, scheduleID string) (*model.StatementSchedule, error) {
+	args := m.Called(ctx, scheduleID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.StatementSchedule), args.Error(1)
+}
+
+func (m *MockDataSource) GetDueStatementSchedules(ctx context.Context, now time.Time, limit int) ([]*model.StatementSchedule, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]*model.StatementSchedule), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateStatementScheduleRun(ctx context.Context, scheduleID string, lastRunAt, nextRunAt time.Time) error {
+	args := m.Called(ctx, scheduleID, lastRunAt, nextRunAt)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) CreateStatement(ctx context.Context, statement *model.Statement) error {
+	args := m.Called(ctx, statement)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetStatement(ctx context.Context, statementID string) (*model.Statement, error) {
+	args := m.Called(ctx, statementID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Statement), args.Error(1)
+}
+
+func (m *MockDataSource) GetStatementsBySchedule(ctx context.Context, scheduleID string) ([]*model.Statement, error) {
+	args := m.Called(ctx, scheduleID)
+	return args.Get(0).([]*model.Statement), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateStatementDelivery(ctx context.Context, statement *model.Statement) error {
+	args := m.Called(ctx, statement)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) FindUnbalancedTransactions(ctx context.Context, limit int) ([]model.IntegrityIssue, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]model.IntegrityIssue), args.Error(1)
+}
+
+func (m *MockDataSource) FindBalanceDrift(ctx context.Context, limit int) ([]model.IntegrityIssue, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]model.IntegrityIssue), args.Error(1)
+}
+
+func (m *MockDataSource) FindOrphanedInflightTransactions(ctx context.Context, staleBefore time.Time, limit int) ([]model.IntegrityIssue, error) {
+	args := m.Called(ctx, staleBefore, limit)
+	return args.Get(0).([]model.IntegrityIssue), args.Error(1)
+}
+
+func (m *MockDataSource) CreateTransactionChallenge(ctx context.Context, challenge *model.TransactionChallenge) error {
+	args := m.Called(ctx, challenge)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetTransactionChallenge(ctx context.Context, challengeID string) (*model.TransactionChallenge, error) {
+	args := m.Called(ctx, challengeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TransactionChallenge), args.Error(1)
+}
+
+func (m *MockDataSource) ListTransactionChallenges(ctx context.Context, status string, page model.Page) ([]*model.TransactionChallenge, error) {
+	args := m.Called(ctx, status, page)
+	return args.Get(0).([]*model.TransactionChallenge), args.Error(1)
+}
+
+func (m *MockDataSource) GetExpiredTransactionChallenges(ctx context.Context, now time.Time, limit int) ([]*model.TransactionChallenge, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]*model.TransactionChallenge), args.Error(1)
+}
+
+func (m *MockDataSource) AppendChallengeEvent(ctx context.Context, challengeID string, event model.ChallengeEvent) error {
+	args := m.Called(ctx, challengeID, event)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) ResolveTransactionChallenge(ctx context.Context, challengeID, status string, event model.ChallengeEvent) (bool, error) {
+	args := m.Called(ctx, challengeID, status, event)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDataSource) CreateReportDefinition(ctx context.Context, definition *model.ReportDefinition) error {
+	args := m.Called(ctx, definition)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetReportDefinition(ctx context.Context, reportID string) (*model.ReportDefinition, error) {
+	args := m.Called(ctx, reportID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ReportDefinition), args.Error(1)
+}
+
+func (m *MockDataSource) ListReportDefinitions(ctx context.Context, page model.Page) ([]*model.ReportDefinition, error) {
+	args := m.Called(ctx, page)
+	return args.Get(0).([]*model.ReportDefinition), args.Error(1)
+}
+
+func (m *MockDataSource) GetDueReportDefinitions(ctx context.Context, now time.Time, limit int) ([]*model.ReportDefinition, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]*model.ReportDefinition), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateReportDefinitionRun(ctx context.Context, reportID string, lastRunAt, nextRunAt time.Time) error {
+	args := m.Called(ctx, reportID, lastRunAt, nextRunAt)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) DeleteReportDefinition(ctx context.Context, reportID string) error {
+	args := m.Called(ctx, reportID)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) RunReportQuery(ctx context.Context, definition *model.ReportDefinition, now time.Time, limit int) ([]string, [][]string, bool, error) {
+	args := m.Called(ctx, definition, now, limit)
+	if args.Get(0) == nil {
+		return nil, nil, false, args.Error(3)
+	}
+	return args.Get(0).([]string), args.Get(1).([][]string), args.Bool(2), args.Error(3)
+}
+
+func (m *MockDataSource) CreateReportRun(ctx context.Context, run *model.ReportRun) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) UpdateReportRun(ctx context.Context, run *model.ReportRun) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetReportRun(ctx context.Context, runID string) (*model.ReportRun, error) {
+	args := m.Called(ctx, runID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ReportRun), args.Error(1)
+}
+
+func (m *MockDataSource) ListReportRuns(ctx context.Context, reportID string, page model.Page) ([]*model.ReportRun, error) {
+	args := m.Called(ctx, reportID, page)
+	return args.Get(0).([]*model.ReportRun), args.Error(1)
+}
+
+func (m *MockDataSource) SetMinimumBalance(ctx context.Context, minimum *model.MinimumBalance) error {
+	args := m.Called(ctx, minimum)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetMinimumBalance(ctx context.Context, id string) (*model.MinimumBalance, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.MinimumBalance), args.Error(1)
+}
+
+func (m *MockDataSource) ListMinimumBalances(ctx context.Context, page model.Page) ([]*model.MinimumBalance, error) {
+	args := m.Called(ctx, page)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.MinimumBalance), args.Error(1)
+}
+
+func (m *MockDataSource) DeleteMinimumBalance(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) CommitUnitOfWork(ctx context.Context, work *model.UnitOfWork) error {
+	args := m.Called(ctx, work)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) CreateEODRun(ctx context.Context, run *model.EODRun) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) UpdateEODRun(ctx context.Context, run *model.EODRun) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetEODRun(ctx context.Context, businessDate string) (*model.EODRun, error) {
+	args := m.Called(ctx, businessDate)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.EODRun), args.Error(1)
+}
+
+func (m *MockDataSource) GetLatestEODRun(ctx context.Context) (*model.EODRun, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.EODRun), args.Error(1)
+}
+
+func (m *MockDataSource) ListEODRuns(ctx context.Context, page model.Page) ([]*model.EODRun, error) {
+	args := m.Called(ctx, page)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.EODRun), args.Error(1)
+}
+
+// Posting rule methods
+
+func (m *MockDataSource) SetPostingRules(ctx context.Context, rules *model.PostingRules) error {
+	args := m.Called(ctx, rules)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetPostingRules(ctx context.Context, ledgerID string) (*model.PostingRules, error) {
+	args := m.Called(ctx, ledgerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PostingRules), args.Error(1)
+}
+
+func (m *MockDataSource) ListPostingRules(ctx context.Context) ([]*model.PostingRules, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.PostingRules), args.Error(1)
+}
+
+func (m *MockDataSource) DeletePostingRules(ctx context.Context, ledgerID string) error {
+	args := m.Called(ctx, ledgerID)
+	return args.Error(0)
+}
+
+// Balance alias methods
+
+func (m *MockDataSource) CreateBalanceAlias(ctx context.Context, alias *model.BalanceAlias) error {
+	args := m.Called(ctx, alias)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetBalanceAlias(ctx context.Context, tenantID, alias string) (*model.BalanceAlias, error) {
+	args := m.Called(ctx, tenantID, alias)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.BalanceAlias), args.Error(1)
+}
+
+func (m *MockDataSource) ListBalanceAliases(ctx context.Context, tenantID, balanceID string) ([]*model.BalanceAlias, error) {
+	args := m.Called(ctx, tenantID, balanceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.BalanceAlias), args.Error(1)
+}
+
+func (m *MockDataSource) UpdateBalanceAlias(ctx context.Context, alias *model.BalanceAlias) error {
+	args := m.Called(ctx, alias)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) DeleteBalanceAlias(ctx context.Context, tenantID, alias string) error {
+	args := m.Called(ctx, tenantID, alias)
+	return args.Error(0)
+}
+
+// Identity screening methods
+
+func (m *MockDataSource) CreateIdentityScreening(ctx context.Context, screening *model.IdentityScreening) error {
+	args := m.Called(ctx, screening)
+	return args.Error(0)
+}
+
+func (m *MockDataSource) GetIdentityScreenings(ctx context.Context, identityID string) ([]*model.IdentityScreening, error) {
+	args := m.Called(ctx, identityID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.IdentityScreening), args.Error(1)
+}
+
+func (m *MockDataSource) GetLatestIdentityScreening(ctx context.Context, identityID string) (*model.IdentityScreening, error) {
+	args := m.Called(ctx, identityID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.IdentityScreening), args.Error(1)
+}
@@ -0,0 +1,462 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+const nettingGroupColumns = `group_id, name, balance_ids, currency, precision, cutoff_time, is_active, last_cutoff_at, created_at`
+
+// nettingGroupKeyset is the order netting groups are listed in, oldest first.
+var nettingGroupKeyset = keyset{timeColumn: "created_at", idColumn: "group_id"}
+
+const nettingEntryColumns = `entry_id, group_id, transaction_id, reference, source, destination, amount, precise_amount, currency, description, meta_data, status, settlement_id, created_at`
+
+// nettingEntryKeyset is the order the entries of a netting group are listed in, newest first.
+var nettingEntryKeyset = keyset{timeColumn: "created_at", idColumn: "entry_id", descending: true}
+
+const nettingSettlementColumns = `settlement_id, group_id, cutoff_at, entry_count, transfers, status, error, created_at`
+
+// CreateNettingGroup saves a new netting group.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - group: The group to store.
+// Returns:
+// - An error if the group could not be saved.
+func (d Datasource) CreateNettingGroup(ctx context.Context, group *model.NettingGroup) error {
+	ctx, span := otel.Tracer("netting.database").Start(ctx, "Creating netting group")
+	defer span.End()
+
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.netting_groups (`+nettingGroupColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`,
+		group.GroupID, group.Name, pq.StringArray(group.BalanceIDs), group.Currency, group.Precision,
+		group.CutoffTime, group.IsActive, group.LastCutoffAt, group.CreatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create netting group", err)
+	}
+	return nil
+}
+
+// GetNettingGroup retrieves a netting group by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - groupID: The ID of the group.
+// Returns:
+// - The group, or an error if it does not exist.
+func (d Datasource) GetNettingGroup(ctx context.Context, groupID string) (*model.NettingGroup, error) {
+	ctx, span := otel.Tracer("netting.database").Start(ctx, "Fetching netting group")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `SELECT `+nettingGroupColumns+` FROM blnk.netting_groups WHERE group_id = $1`, groupID)
+
+	group, err := scanNettingGroup(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Netting group with ID '%s' not found", groupID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve netting group", err)
+	}
+	return group, nil
+}
+
+// ListNettingGroups retrieves a page of netting groups, oldest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - page: The page of groups to read. A zero page reads them all.
+// Returns:
+// - The groups, or an error if the query fails.
+func (d Datasource) ListNettingGroups(ctx context.Context, page model.Page) ([]*model.NettingGroup, error) {
+	ctx, span := otel.Tracer("netting.database").Start(ctx, "Listing netting groups")
+	defer span.End()
+
+	condition, suffix, args := nettingGroupKeyset.page(page, nil)
+	rows, err := d.Conn.QueryContext(ctx, `SELECT `+nettingGroupColumns+` FROM blnk.netting_groups WHERE `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve netting groups", err)
+	}
+	defer rows.Close()
+
+	groups := []*model.NettingGroup{}
+	for rows.Next() {
+		group, err := scanNettingGroup(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan netting group", err)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over netting groups", err)
+	}
+	return groups, nil
+}
+
+// UpdateNettingGroupStatus activates or deactivates a netting group.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - groupID: The ID of the group.
+// - active: Whether postings between the group's balances should be deferred.
+// Returns:
+// - An error if the group does not exist or could not be updated.
+func (d Datasource) UpdateNettingGroupStatus(ctx context.Context, groupID string, active bool) error {
+	ctx, span := otel.Tracer("netting.database").Start(ctx, "Updating netting group status")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `UPDATE blnk.netting_groups SET is_active = $2 WHERE group_id = $1`, groupID, active)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update netting group", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Netting group with ID '%s' not found", groupID), nil)
+	}
+	return nil
+}
+
+// RecordNettingEntry saves the memo record of a deferred posting.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - entry: The entry to store.
+// Returns:
+// - An error if the entry could not be saved, including when its reference was already used.
+func (d Datasource) RecordNettingEntry(ctx context.Context, entry *model.NettingEntry) error {
+	ctx, span := otel.Tracer("netting.database").Start(ctx, "Recording netting entry")
+	defer span.End()
+
+	metaData, err := json.Marshal(entry.MetaData)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInvalidInput, "Failed to encode netting entry metadata", err)
+	}
+
+	_, err = d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.netting_entries (`+nettingEntryColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`,
+		entry.EntryID, entry.GroupID, entry.TransactionID, entry.Reference, entry.Source, entry.Destination,
+		entry.Amount, entry.PreciseAmount.String(), entry.Currency,
+		sql.NullString{String: entry.Description, Valid: entry.Description != ""}, metaData, entry.Status,
+		sql.NullString{String: entry.SettlementID, Valid: entry.SettlementID != ""}, entry.CreatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		if pgconn.HasCode(err, pgconn.UniqueViolation) {
+			return apierror.NewAPIError(apierror.ErrConflict, "Transaction reference has already been used", err)
+		}
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record netting entry", err)
+	}
+	return nil
+}
+
+// ListNettingEntries retrieves the entries of a netting group, newest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - groupID: The ID of the group.
+// - status: Only entries with this status are returned when not empty.
+// - page: The page of entries to read.
+// Returns:
+// - The entries, or an error if the query fails.
+func (d Datasource) ListNettingEntries(ctx context.Context, groupID, status string, page model.Page) ([]*model.NettingEntry, error) {
+	ctx, span := otel.Tracer("netting.database").Start(ctx, "Listing netting entries")
+	defer span.End()
+
+	condition, suffix, args := nettingEntryKeyset.page(page, []interface{}{groupID, status})
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+nettingEntryColumns+`
+		FROM blnk.netting_entries
+		WHERE group_id = $1 AND ($2 = '' OR status = $2) AND `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve netting entries", err)
+	}
+	return collectNettingEntries(rows)
+}
+
+// GetBalanceNettingEntries retrieves the netting entries a balance took part in during a period, oldest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - balanceID: The ID of the balance.
+// - start: The inclusive start of the period.
+// - end: The exclusive end of the period.
+// Returns:
+// - The entries, or an error if the query fails.
+func (d Datasource) GetBalanceNettingEntries(ctx context.Context, balanceID string, start, end time.Time) ([]*model.NettingEntry, error) {
+	ctx, span := otel.Tracer("netting.database").Start(ctx, "Fetching balance netting entries")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+nettingEntryColumns+`
+		FROM blnk.netting_entries
+		WHERE (source = $1 OR destination = $1) AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at ASC
+	`, balanceID, start, end)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve balance netting entries", err)
+	}
+	return collectNettingEntries(rows)
+}
+
+// CreateNettingSettlement saves a settlement and, in the same database transaction, claims every pending
+// entry of its group created before the cutoff and records the cutoff on the group. Claimed entries are
+// marked settled so they can never be netted twice.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - settlement: The settlement to store. EntryCount is set from the claimed entries.
+// Returns:
+// - The claimed entries, or an error if the settlement could not be saved.
+func (d Datasource) CreateNettingSettlement(ctx context.Context, settlement *model.NettingSettlement) ([]*model.NettingEntry, error) {
+	ctx, span := otel.Tracer("netting.database").Start(ctx, "Creating netting settlement")
+	defer span.End()
+
+	tx, err := d.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to begin netting settlement", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	transfers, err := json.Marshal(settlement.Transfers)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, "Failed to encode netting transfers", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO blnk.netting_settlements (`+nettingSettlementColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`,
+		settlement.SettlementID, settlement.GroupID, settlement.CutoffAt, 0, transfers, settlement.Status,
+		sql.NullString{String: settlement.Error, Valid: settlement.Error != ""}, settlement.CreatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		if pgconn.HasCode(err, pgconn.UniqueViolation) {
+			return nil, apierror.NewAPIError(apierror.ErrConflict, "Netting group has already been settled for this cutoff", err)
+		}
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create netting settlement", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE blnk.netting_entries
+		SET status = $3, settlement_id = $4
+		WHERE group_id = $1 AND status = $2 AND created_at < $5
+		RETURNING `+nettingEntryColumns,
+		settlement.GroupID, model.NettingEntryPending, model.NettingEntrySettled, settlement.SettlementID, settlement.CutoffAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to claim netting entries", err)
+	}
+	entries, err := collectNettingEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+	settlement.EntryCount = len(entries)
+
+	if _, err := tx.ExecContext(ctx, `UPDATE blnk.netting_settlements SET entry_count = $2 WHERE settlement_id = $1`, settlement.SettlementID, settlement.EntryCount); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update netting settlement", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE blnk.netting_groups SET last_cutoff_at = $2 WHERE group_id = $1`, settlement.GroupID, settlement.CutoffAt); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update netting group cutoff", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to commit netting settlement", err)
+	}
+	return entries, nil
+}
+
+// UpdateNettingSettlement records the transfers and outcome of a settlement.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - settlement: The settlement with its transfers and status.
+// Returns:
+// - An error if the settlement could not be updated.
+func (d Datasource) UpdateNettingSettlement(ctx context.Context, settlement *model.NettingSettlement) error {
+	ctx, span := otel.Tracer("netting.database").Start(ctx, "Updating netting settlement")
+	defer span.End()
+
+	transfers, err := json.Marshal(settlement.Transfers)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInvalidInput, "Failed to encode netting transfers", err)
+	}
+
+	_, err = d.Conn.ExecContext(ctx, `
+		UPDATE blnk.netting_settlements
+		SET transfers = $2, status = $3, error = $4
+		WHERE settlement_id = $1
+	`, settlement.SettlementID, transfers, settlement.Status, sql.NullString{String: settlement.Error, Valid: settlement.Error != ""})
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update netting settlement", err)
+	}
+	return nil
+}
+
+// GetNettingSettlement retrieves a netting settlement by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - settlementID: The ID of the settlement.
+// Returns:
+// - The settlement, or an error if it does not exist.
+func (d Datasource) GetNettingSettlement(ctx context.Context, settlementID string) (*model.NettingSettlement, error) {
+	ctx, span := otel.Tracer("netting.database").Start(ctx, "Fetching netting settlement")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `SELECT `+nettingSettlementColumns+` FROM blnk.netting_settlements WHERE settlement_id = $1`, settlementID)
+
+	settlement, err := scanNettingSettlement(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Netting settlement with ID '%s' not found", settlementID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve netting settlement", err)
+	}
+	return settlement, nil
+}
+
+// ListNettingSettlements retrieves the settlements of a netting group, newest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - groupID: The ID of the group.
+// Returns:
+// - The settlements, or an error if the query fails.
+func (d Datasource) ListNettingSettlements(ctx context.Context, groupID string) ([]*model.NettingSettlement, error) {
+	ctx, span := otel.Tracer("netting.database").Start(ctx, "Listing netting settlements")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+nettingSettlementColumns+`
+		FROM blnk.netting_settlements
+		WHERE group_id = $1
+		ORDER BY cutoff_at DESC
+	`, groupID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve netting settlements", err)
+	}
+	defer rows.Close()
+
+	settlements := []*model.NettingSettlement{}
+	for rows.Next() {
+		settlement, err := scanNettingSettlement(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan netting settlement", err)
+		}
+		settlements = append(settlements, settlement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over netting settlements", err)
+	}
+	return settlements, nil
+}
+
+func collectNettingEntries(rows *sql.Rows) ([]*model.NettingEntry, error) {
+	defer rows.Close()
+
+	entries := []*model.NettingEntry{}
+	for rows.Next() {
+		entry, err := scanNettingEntry(rows)
+		if err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan netting entry", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over netting entries", err)
+	}
+	return entries, nil
+}
+
+func scanNettingGroup(row rowScanner) (*model.NettingGroup, error) {
+	group := &model.NettingGroup{}
+	var balanceIDs pq.StringArray
+	err := row.Scan(
+		&group.GroupID, &group.Name, &balanceIDs, &group.Currency, &group.Precision,
+		&group.CutoffTime, &group.IsActive, &group.LastCutoffAt, &group.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	group.BalanceIDs = []string(balanceIDs)
+	return group, nil
+}
+
+func scanNettingEntry(row rowScanner) (*model.NettingEntry, error) {
+	entry := &model.NettingEntry{}
+	var preciseAmount string
+	var description, settlementID sql.NullString
+	var metaData []byte
+	err := row.Scan(
+		&entry.EntryID, &entry.GroupID, &entry.TransactionID, &entry.Reference, &entry.Source, &entry.Destination,
+		&entry.Amount, &preciseAmount, &entry.Currency, &description, &metaData, &entry.Status, &settlementID, &entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	entry.PreciseAmount, _ = new(big.Int).SetString(preciseAmount, 10)
+	entry.Description = description.String
+	entry.SettlementID = settlementID.String
+	if len(metaData) > 0 {
+		if err := json.Unmarshal(metaData, &entry.MetaData); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+func scanNettingSettlement(row rowScanner) (*model.NettingSettlement, error) {
+	settlement := &model.NettingSettlement{}
+	var transfers []byte
+	var settlementError sql.NullString
+	err := row.Scan(
+		&settlement.SettlementID, &settlement.GroupID, &settlement.CutoffAt, &settlement.EntryCount,
+		&transfers, &settlement.Status, &settlementError, &settlement.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	settlement.Error = settlementError.String
+	if err := json.Unmarshal(transfers, &settlement.Transfers); err != nil {
+		return nil, err
+	}
+	return settlement, nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"fmt"
+
+	"github.com/blnkfinance/blnk/model"
+)
+
+// keyset describes the order a list is paged in: by timeColumn and then idColumn, newest first when descending.
+// The id column breaks ties between rows created at the same time, so every row has one position.
+type keyset struct {
+	timeColumn string
+	idColumn   string
+	descending bool
+}
+
+// page returns the SQL that selects one page of a list ordered by the keyset. The condition keeps the rows
+// after the page's cursor, or is TRUE without one, and belongs in the query's WHERE clause. The suffix orders,
+// limits and, for pages without a cursor, offsets the rows and ends the query. Placeholders are numbered from
+// len(args)+1, and the returned args extend args with their values.
+//
+// Parameters:
+// - p: The page to select.
+// - args: The arguments of the query so far.
+//
+// Returns:
+// - condition string: The condition to AND into the WHERE clause.
+// - suffix string: The ORDER BY, LIMIT and OFFSET clauses.
+// - []interface{}: The arguments of the whole query.
+func (k keyset) page(p model.Page, args []interface{}) (condition string, suffix string, allArgs []interface{}) {
+	direction, comparison := "ASC", ">"
+	if k.descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	condition = "TRUE"
+	if p.After != nil {
+		args = append(args, p.After.Time, p.After.ID)
+		condition = fmt.Sprintf("(%s, %s) %s ($%d, $%d)", k.timeColumn, k.idColumn, comparison, len(args)-1, len(args))
+	}
+
+	suffix = fmt.Sprintf(" ORDER BY %s %s, %s %s", k.timeColumn, direction, k.idColumn, direction)
+	if p.Limit > 0 {
+		args = append(args, p.Limit)
+		suffix += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if p.After == nil && p.Offset > 0 {
+		args = append(args, p.Offset)
+		suffix += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return condition, suffix, args
+}
+
+// pageKey identifies a page in cache keys: by its cursor, or by its offset without one.
+func pageKey(p model.Page) string {
+	if p.After != nil {
+		return fmt.Sprintf("%d:%s", p.Limit, p.After.Encode())
+	}
+	return fmt.Sprintf("%d:%d", p.Limit, p.Offset)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeysetPage(t *testing.T) {
+	newest := keyset{timeColumn: "created_at", idColumn: "ledger_id", descending: true}
+	after := &model.PageCursor{Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), ID: "ldg_1"}
+
+	tests := []struct {
+		name      string
+		keyset    keyset
+		page      model.Page
+		condition string
+		suffix    string
+		args      []interface{}
+	}{
+		{
+			name:      "whole list",
+			keyset:    newest,
+			condition: "TRUE",
+			suffix:    " ORDER BY created_at DESC, ledger_id DESC",
+			args:      []interface{}{"NG"},
+		},
+		{
+			name:      "offset",
+			keyset:    newest,
+			page:      model.Page{Limit: 20, Offset: 40},
+			condition: "TRUE",
+			suffix:    " ORDER BY created_at DESC, ledger_id DESC LIMIT $2 OFFSET $3",
+			args:      []interface{}{"NG", 20, 40},
+		},
+		{
+			name:      "cursor ignores offset",
+			keyset:    newest,
+			page:      model.Page{Limit: 20, After: after, Offset: 40},
+			condition: "(created_at, ledger_id) < ($2, $3)",
+			suffix:    " ORDER BY created_at DESC, ledger_id DESC LIMIT $4",
+			args:      []interface{}{"NG", after.Time, after.ID, 20},
+		},
+		{
+			name:      "ascending",
+			keyset:    keyset{timeColumn: "dormant_since", idColumn: "balance_id"},
+			page:      model.Page{Limit: 20, After: after},
+			condition: "(dormant_since, balance_id) > ($2, $3)",
+			suffix:    " ORDER BY dormant_since ASC, balance_id ASC LIMIT $4",
+			args:      []interface{}{"NG", after.Time, after.ID, 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, suffix, args := tt.keyset.page(tt.page, []interface{}{"NG"})
+			assert.Equal(t, tt.condition, condition)
+			assert.Equal(t, tt.suffix, suffix)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
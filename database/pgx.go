@@ -0,0 +1,46 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+
+	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
+	"github.com/jackc/pgx/v5"
+)
+
+// execBatch sends the statements of batch to the primary in one round trip and runs them in one transaction,
+// so either all of them apply or none does. It returns pgconn.ErrNotPgx without running anything when the
+// database is not opened with the pgx driver, for the caller to run the statements one by one instead.
+func (d Datasource) execBatch(ctx context.Context, batch *pgx.Batch) error {
+	return pgconn.WithPgxConn(ctx, d.Conn, func(conn *pgx.Conn) error {
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			return tx.SendBatch(ctx, batch).Close()
+		})
+	})
+}
+
+// copyFrom copies rows into the columns of a table of the blnk schema with a binary COPY in one transaction.
+// It returns pgconn.ErrNotPgx without copying anything when the database is not opened with the pgx driver.
+func (d Datasource) copyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) error {
+	return pgconn.WithPgxConn(ctx, d.Conn, func(conn *pgx.Conn) error {
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			_, err := tx.CopyFrom(ctx, pgx.Identifier{"blnk", table}, columns, pgx.CopyFromRows(rows))
+			return err
+		})
+	})
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+)
+
+// poolStats is the state of a connection pool, labelled "primary" or "replica-<n>" in order of the configured
+// replicas.
+type poolStats struct {
+	pool  string
+	stats sql.DBStats
+}
+
+// poolSnapshot returns the state of the connection pools of the datasource.
+func (d *Datasource) poolSnapshot() []poolStats {
+	if d == nil || d.Conn == nil {
+		return nil
+	}
+	pools := []poolStats{{pool: "primary", stats: d.Conn.Stats()}}
+	if d.replicas != nil {
+		for i, r := range d.replicas.replicas {
+			pools = append(pools, poolStats{pool: fmt.Sprintf("replica-%d", i+1), stats: r.conn.Stats()})
+		}
+	}
+	return pools
+}
+
+// WritePoolMetrics writes the state of the database connection pools in the Prometheus text format, so pool
+// exhaustion shows up as time spent waiting for a connection before it shows up as failed requests. Nothing is
+// written before the database is connected.
+//
+// Parameters:
+// - w: The writer the metrics are written to.
+//
+// Returns:
+// - error: An error if the metrics could not be written.
+func WritePoolMetrics(w io.Writer) error {
+	return writePoolMetrics(w, instance.poolSnapshot())
+}
+
+func writePoolMetrics(w io.Writer, pools []poolStats) error {
+	if len(pools) == 0 {
+		return nil
+	}
+	metrics := []struct {
+		name, kind, help string
+		value            func(sql.DBStats) float64
+	}{
+		{"blnk_db_pool_max_open_connections", "gauge", "Most connections the pool opens.", func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+		{"blnk_db_pool_open_connections", "gauge", "Connections open, in use or idle.", func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+		{"blnk_db_pool_in_use_connections", "gauge", "Connections in use.", func(s sql.DBStats) float64 { return float64(s.InUse) }},
+		{"blnk_db_pool_idle_connections", "gauge", "Idle connections.", func(s sql.DBStats) float64 { return float64(s.Idle) }},
+		{"blnk_db_pool_wait_count_total", "counter", "Queries that waited for a connection.", func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+		{"blnk_db_pool_wait_duration_seconds_total", "counter", "Time spent waiting for a connection.", func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+		{"blnk_db_pool_max_idle_closed_total", "counter", "Connections closed because the pool had max_idle_conns idle.", func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+		{"blnk_db_pool_max_idle_time_closed_total", "counter", "Connections closed after conn_max_idle_time idle.", func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+		{"blnk_db_pool_max_lifetime_closed_total", "counter", "Connections closed after conn_max_lifetime.", func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+	}
+
+	for _, metric := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind); err != nil {
+			return err
+		}
+		for _, p := range pools {
+			if _, err := fmt.Fprintf(w, "%s{pool=%q} %g\n", metric.name, p.pool, metric.value(p.stats)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePoolMetrics(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, writePoolMetrics(&out, nil))
+	assert.Empty(t, out.String())
+
+	pools := []poolStats{
+		{pool: "primary", stats: sql.DBStats{MaxOpenConnections: 25, OpenConnections: 12, InUse: 9, Idle: 3, WaitCount: 4, WaitDuration: 1500 * time.Millisecond}},
+		{pool: "replica-1", stats: sql.DBStats{MaxOpenConnections: 25, OpenConnections: 2, Idle: 2}},
+	}
+	require.NoError(t, writePoolMetrics(&out, pools))
+	assert.Contains(t, out.String(), "# TYPE blnk_db_pool_in_use_connections gauge\n")
+	assert.Contains(t, out.String(), `blnk_db_pool_in_use_connections{pool="primary"} 9`)
+	assert.Contains(t, out.String(), `blnk_db_pool_idle_connections{pool="replica-1"} 2`)
+	assert.Contains(t, out.String(), `blnk_db_pool_wait_duration_seconds_total{pool="primary"} 1.5`)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const postingRulesColumns = `ledger_id, allow_zero_amount, allow_same_source_destination, require_description, reference_pattern, updated_at`
+
+// SetPostingRules saves the posting rules of a ledger, replacing any rules already set for it.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - rules: The rules to save, with their ledger and update time set.
+// Returns:
+// - An error if the rules cannot be saved.
+func (d Datasource) SetPostingRules(ctx context.Context, rules *model.PostingRules) error {
+	ctx, span := otel.Tracer("posting_rules.database").Start(ctx, "Setting posting rules")
+	defer span.End()
+
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.ledger_posting_rules (`+postingRulesColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (ledger_id) DO UPDATE SET
+			allow_zero_amount = EXCLUDED.allow_zero_amount,
+			allow_same_source_destination = EXCLUDED.allow_same_source_destination,
+			require_description = EXCLUDED.require_description,
+			reference_pattern = EXCLUDED.reference_pattern,
+			updated_at = EXCLUDED.updated_at
+	`, rules.LedgerID, rules.AllowZeroAmount, rules.AllowSameSourceDestination, rules.RequireDescription, nullString(rules.ReferencePattern), rules.UpdatedAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to set posting rules", err)
+	}
+	return nil
+}
+
+// GetPostingRules retrieves the posting rules of a ledger.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - ledgerID: The ID of the ledger.
+// Returns:
+// - The rules, or an error if the ledger has none or the query fails.
+func (d Datasource) GetPostingRules(ctx context.Context, ledgerID string) (*model.PostingRules, error) {
+	ctx, span := otel.Tracer("posting_rules.database").Start(ctx, "Getting posting rules")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `SELECT `+postingRulesColumns+` FROM blnk.ledger_posting_rules WHERE ledger_id = $1`, ledgerID)
+	rules, err := scanPostingRules(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("posting rules for ledger '%s' not found", ledgerID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve posting rules", err)
+	}
+	return rules, nil
+}
+
+// ListPostingRules retrieves the posting rules of every ledger that has them.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// Returns:
+// - The rules, or an error if the query fails.
+func (d Datasource) ListPostingRules(ctx context.Context) ([]*model.PostingRules, error) {
+	ctx, span := otel.Tracer("posting_rules.database").Start(ctx, "Listing posting rules")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `SELECT `+postingRulesColumns+` FROM blnk.ledger_posting_rules ORDER BY ledger_id`)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve posting rules", err)
+	}
+	defer rows.Close()
+
+	list := []*model.PostingRules{}
+	for rows.Next() {
+		rules, err := scanPostingRules(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan posting rules", err)
+		}
+		list = append(list, rules)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over posting rules", err)
+	}
+	return list, nil
+}
+
+// DeletePostingRules removes the posting rules of a ledger.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - ledgerID: The ID of the ledger.
+// Returns:
+// - An error if the ledger has no rules or they cannot be deleted.
+func (d Datasource) DeletePostingRules(ctx context.Context, ledgerID string) error {
+	ctx, span := otel.Tracer("posting_rules.database").Start(ctx, "Deleting posting rules")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `DELETE FROM blnk.ledger_posting_rules WHERE ledger_id = $1`, ledgerID)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to delete posting rules", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("posting rules for ledger '%s' not found", ledgerID), nil)
+	}
+	return nil
+}
+
+func scanPostingRules(row rowScanner) (*model.PostingRules, error) {
+	rules := &model.PostingRules{}
+	var pattern sql.NullString
+	if err := row.Scan(&rules.LedgerID, &rules.AllowZeroAmount, &rules.AllowSameSourceDestination, &rules.RequireDescription, &pattern, &rules.UpdatedAt); err != nil {
+		return nil, err
+	}
+	rules.ReferencePattern = pattern.String
+	return rules, nil
+}
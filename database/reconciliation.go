@@ -20,17 +20,28 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/blnkfinance/blnk/internal/apierror"
+	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
 	"github.com/blnkfinance/blnk/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/lib/pq"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// externalTransactionKeyset is the order the external transactions of an upload are read in, newest first.
+var externalTransactionKeyset = keyset{timeColumn: "date", idColumn: "id", descending: true}
+
+// groupedExternalTransactionKeyset is the order external transactions are grouped in, oldest first, so that the
+// next batch starts after the latest transaction of the groups already read.
+var groupedExternalTransactionKeyset = keyset{timeColumn: "date", idColumn: "id"}
+
 // RecordReconciliation saves a reconciliation record to the database.
 // Parameters:
 // - ctx: Context for managing request and tracing.
@@ -169,6 +180,14 @@ func (d Datasource) GetReconciliationsByUploadID(ctx context.Context, uploadID s
 	return reconciliations, nil
 }
 
+const insertMatchQuery = `INSERT INTO blnk.matches(
+		external_transaction_id, internal_transaction_id, reconciliation_id, amount, date, confidence, status
+	) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+const insertUnmatchedQuery = `INSERT INTO blnk.unmatched(
+		external_transaction_id, reconciliation_id, date
+	) VALUES ($1, $2, $3)`
+
 // RecordMatches batches the saving of match records associated with a specific reconciliation ID.
 // It uses a database transaction to ensure atomicity and consistency of the batch insert operation.
 // Parameters:
@@ -181,6 +200,20 @@ func (d Datasource) RecordMatches(ctx context.Context, reconciliationID string,
 	ctx, span := otel.Tracer("reconciliation.database").Start(ctx, "Batch saving matches to db")
 	defer span.End()
 
+	// With pgx the inserts are pipelined in one round trip
+	batch := &pgx.Batch{}
+	for _, match := range matches {
+		batch.Queue(insertMatchQuery, match.ExternalTransactionID, match.InternalTransactionID, reconciliationID, match.Amount,
+			match.Date, match.Confidence, match.Status)
+	}
+	if err := d.execBatch(ctx, batch); !errors.Is(err, pgconn.ErrNotPgx) {
+		if err != nil {
+			span.RecordError(err)
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record matches", err)
+		}
+		return nil
+	}
+
 	txn, err := d.Conn.BeginTx(ctx, nil)
 	if err != nil {
 		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to start transaction", err)
@@ -218,6 +251,20 @@ func (d Datasource) RecordUnmatched(ctx context.Context, reconciliationID string
 	ctx, span := otel.Tracer("reconciliation.database").Start(ctx, "Batch saving unmatched to db")
 	defer span.End()
 
+	// With pgx the inserts are pipelined in one round trip
+	batch := &pgx.Batch{}
+	now := time.Now()
+	for _, externalId := range results {
+		batch.Queue(insertUnmatchedQuery, externalId, reconciliationID, now)
+	}
+	if err := d.execBatch(ctx, batch); !errors.Is(err, pgconn.ErrNotPgx) {
+		if err != nil {
+			span.RecordError(err)
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record unmatched match", err)
+		}
+		return nil
+	}
+
 	txn, err := d.Conn.BeginTx(ctx, nil)
 	if err != nil {
 		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to start transaction", err)
@@ -229,11 +276,7 @@ func (d Datasource) RecordUnmatched(ctx context.Context, reconciliationID string
 	}()
 
 	for _, externalId := range results {
-		_, err := txn.ExecContext(ctx,
-			`INSERT INTO blnk.unmatched(
-					external_transaction_id, reconciliation_id, date
-				) VALUES ($1, $2, $3)`,
-			externalId, reconciliationID, time.Now())
+		_, err := txn.ExecContext(ctx, insertUnmatchedQuery, externalId, reconciliationID, time.Now())
 		if err != nil {
 			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record unmatched match", err)
 		}
@@ -255,11 +298,9 @@ func (d Datasource) RecordUnmatched(ctx context.Context, reconciliationID string
 // Returns:
 // - An error if the operation fails, wrapped in an APIError for consistency.
 func (d Datasource) recordMatchInTransaction(ctx context.Context, tx *sql.Tx, match *model.Match) error {
-	_, err := tx.ExecContext(ctx,
-		`INSERT INTO blnk.matches(
-			external_transaction_id, internal_transaction_id, reconciliation_id, amount, date
-		) VALUES ($1, $2, $3, $4, $5)`,
+	_, err := tx.ExecContext(ctx, insertMatchQuery,
 		match.ExternalTransactionID, match.InternalTransactionID, match.ReconciliationID, match.Amount, match.Date,
+		match.Confidence, match.Status,
 	)
 	if err != nil {
 		// For other errors, return the original error
@@ -282,9 +323,10 @@ func (d Datasource) RecordMatch(ctx context.Context, match *model.Match) error {
 
 	_, err := d.Conn.ExecContext(ctx,
 		`INSERT INTO blnk.matches(
-			external_transaction_id, internal_transaction_id, reconciliation_id, amount, date
-		) VALUES ($1, $2, $3, $4, $5)`,
+			external_transaction_id, internal_transaction_id, reconciliation_id, amount, date, confidence, status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
 		match.ExternalTransactionID, match.InternalTransactionID, match.ReconciliationID, match.Amount, match.Date,
+		match.Confidence, match.Status,
 	)
 	if err != nil {
 		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record match", err)
@@ -306,7 +348,7 @@ func (d Datasource) GetMatchesByReconciliationID(ctx context.Context, reconcilia
 	defer span.End()
 
 	rows, err := d.Conn.QueryContext(ctx, `
-		SELECT m.external_transaction_id, m.internal_transaction_id, m.amount, m.date
+		SELECT m.external_transaction_id, m.internal_transaction_id, m.amount, m.date, m.confidence, m.status
 		FROM blnk.matches m
 		JOIN blnk.external_transactions et ON m.external_transaction_id = et.id
 		WHERE et.reconciliation_id = $1
@@ -322,7 +364,7 @@ func (d Datasource) GetMatchesByReconciliationID(ctx context.Context, reconcilia
 		match := &model.Match{}
 		err = rows.Scan(
 			&match.ExternalTransactionID, &match.InternalTransactionID,
-			&match.Amount, &match.Date,
+			&match.Amount, &match.Date, &match.Confidence, &match.Status,
 		)
 		if err != nil {
 			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan match data", err)
@@ -338,6 +380,146 @@ func (d Datasource) GetMatchesByReconciliationID(ctx context.Context, reconcilia
 	return matches, nil
 }
 
+// GetMatchesByStatus retrieves the matches recorded for a reconciliation that are in the given status.
+// It is used to list the review queue of mid-confidence matches.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - reconciliationID: The ID of the reconciliation to filter matches.
+// - status: The match status to filter by (e.g. "pending_review").
+// Returns:
+// - A slice of Match structs ordered by descending confidence.
+// - An error if the operation fails, wrapped in an APIError for consistency.
+func (d Datasource) GetMatchesByStatus(ctx context.Context, reconciliationID, status string) ([]*model.Match, error) {
+	ctx, span := otel.Tracer("reconciliation.database").Start(ctx, "Fetching matches by status")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT external_transaction_id, internal_transaction_id, reconciliation_id, amount, date, confidence, status
+		FROM blnk.matches
+		WHERE reconciliation_id = $1 AND status = $2
+		ORDER BY confidence DESC
+	`, reconciliationID, status)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve matches", err)
+	}
+	defer rows.Close()
+
+	var matches []*model.Match
+
+	for rows.Next() {
+		match := &model.Match{}
+		err = rows.Scan(
+			&match.ExternalTransactionID, &match.InternalTransactionID, &match.ReconciliationID,
+			&match.Amount, &match.Date, &match.Confidence, &match.Status,
+		)
+		if err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan match data", err)
+		}
+
+		matches = append(matches, match)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over matches", err)
+	}
+
+	return matches, nil
+}
+
+// UpdateMatchStatus moves a recorded match to a new status, typically when a reviewer confirms or rejects
+// a match from the review queue.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - reconciliationID: The ID of the reconciliation the match belongs to.
+// - externalTxnID: The external transaction ID of the match.
+// - internalTxnID: The internal transaction ID of the match.
+// - status: The new status of the match.
+// Returns:
+// - An error if the update fails or the match is not found.
+func (d Datasource) UpdateMatchStatus(ctx context.Context, reconciliationID, externalTxnID, internalTxnID, status string) error {
+	ctx, span := otel.Tracer("reconciliation.database").Start(ctx, "Updating match status")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.matches
+		SET status = $4
+		WHERE reconciliation_id = $1 AND external_transaction_id = $2 AND internal_transaction_id = $3
+	`, reconciliationID, externalTxnID, internalTxnID, status)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update match status", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Match between '%s' and '%s' not found", externalTxnID, internalTxnID), nil)
+	}
+
+	return nil
+}
+
+// GetMatchScoreDistribution aggregates the confidence scores of all matches recorded for a reconciliation
+// into ten equal-width buckets, along with per-status totals.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - reconciliationID: The ID of the reconciliation to summarise.
+// Returns:
+// - A pointer to the ScoreDistribution for the run.
+// - An error if the operation fails, wrapped in an APIError for consistency.
+func (d Datasource) GetMatchScoreDistribution(ctx context.Context, reconciliationID string) (*model.ScoreDistribution, error) {
+	ctx, span := otel.Tracer("reconciliation.database").Start(ctx, "Fetching match score distribution")
+	defer span.End()
+
+	dist := &model.ScoreDistribution{ReconciliationID: reconciliationID, Buckets: make([]model.ScoreBucket, 10)}
+	for i := range dist.Buckets {
+		dist.Buckets[i].Lower = float64(i) / 10
+		dist.Buckets[i].Upper = float64(i+1) / 10
+	}
+
+	err := d.Conn.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+			COALESCE(AVG(confidence), 0),
+			COUNT(*) FILTER (WHERE status = 'confirmed'),
+			COUNT(*) FILTER (WHERE status = 'pending_review'),
+			COUNT(*) FILTER (WHERE status = 'rejected')
+		FROM blnk.matches
+		WHERE reconciliation_id = $1
+	`, reconciliationID).Scan(&dist.TotalMatches, &dist.AverageScore, &dist.Confirmed, &dist.PendingReview, &dist.Rejected)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve score distribution", err)
+	}
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT LEAST(width_bucket(confidence, 0, 1, 10), 10) AS bucket, COUNT(*)
+		FROM blnk.matches
+		WHERE reconciliation_id = $1
+		GROUP BY bucket
+	`, reconciliationID)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve score distribution", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan score bucket", err)
+		}
+		if bucket >= 1 && bucket <= len(dist.Buckets) {
+			dist.Buckets[bucket-1].Count = count
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over score buckets", err)
+	}
+
+	return dist, nil
+}
+
 // RecordExternalTransaction inserts a new external transaction into the database.
 // It associates the transaction with an upload ID and records its details.
 // Parameters:
@@ -363,6 +545,59 @@ func (d Datasource) RecordExternalTransaction(ctx context.Context, tx *model.Ext
 	return nil
 }
 
+// RecordExternalTransactions saves a batch of external transactions in one statement, skipping those whose ID
+// was already recorded.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - txns: The external transactions to save.
+// - uploadID: The ID of the upload batch the transactions belong to.
+// Returns:
+// - The IDs of the transactions saved, or an error wrapped in an APIError if the operation fails.
+func (d Datasource) RecordExternalTransactions(ctx context.Context, txns []*model.ExternalTransaction, uploadID string) ([]string, error) {
+	ctx, span := otel.Tracer("reconciliation.database").Start(ctx, "Saving external transactions to db")
+	defer span.End()
+
+	ids := make([]string, len(txns))
+	amounts := make([]float64, len(txns))
+	references := make([]string, len(txns))
+	currencies := make([]string, len(txns))
+	descriptions := make([]string, len(txns))
+	dates := make([]string, len(txns))
+	sources := make([]string, len(txns))
+	for i, tx := range txns {
+		ids[i], amounts[i], references[i], currencies[i] = tx.ID, tx.Amount, tx.Reference, tx.Currency
+		descriptions[i], dates[i], sources[i] = tx.Description, tx.Date.Format(time.RFC3339Nano), tx.Source
+	}
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		INSERT INTO blnk.external_transactions (id, amount, reference, currency, description, date, source, upload_id)
+		SELECT id, amount, reference, currency, description, date, source, $8
+		FROM unnest($1::text[], $2::numeric[], $3::text[], $4::text[], $5::text[], $6::timestamp[], $7::text[])
+			AS t(id, amount, reference, currency, description, date, source)
+		ON CONFLICT (id) DO NOTHING
+		RETURNING id`,
+		pq.Array(ids), pq.Array(amounts), pq.Array(references), pq.Array(currencies), pq.Array(descriptions), pq.Array(dates), pq.Array(sources), uploadID,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record external transactions", err)
+	}
+	defer rows.Close()
+
+	recorded := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan recorded external transaction", err)
+		}
+		recorded = append(recorded, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while recording external transactions", err)
+	}
+	return recorded, nil
+}
+
 // GetExternalTransactionsByReconciliationID fetches all external transactions associated with a given reconciliation ID.
 // Parameters:
 // - ctx: Context for managing request and tracing.
@@ -405,6 +640,50 @@ func (d Datasource) GetExternalTransactionsByReconciliationID(ctx context.Contex
 	return transactions, nil
 }
 
+// GetUnmatchedExternalTransactions fetches the external transactions that a reconciliation recorded as unmatched.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - reconciliationID: The ID of the reconciliation to fetch unmatched transactions for.
+// Returns:
+// - A slice of ExternalTransaction pointers or an error wrapped in an APIError if the operation fails.
+func (d Datasource) GetUnmatchedExternalTransactions(ctx context.Context, reconciliationID string) ([]*model.ExternalTransaction, error) {
+	ctx, span := otel.Tracer("reconciliation.database").Start(ctx, "Fetching unmatched external transactions")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT DISTINCT et.id, et.amount, et.reference, et.currency, et.description, et.date, et.source
+		FROM blnk.unmatched u
+		JOIN blnk.external_transactions et ON u.external_transaction_id = et.id
+		WHERE u.reconciliation_id = $1
+		ORDER BY et.date
+	`, reconciliationID)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve unmatched transactions", err)
+	}
+	defer rows.Close()
+
+	var transactions []*model.ExternalTransaction
+
+	for rows.Next() {
+		tx := &model.ExternalTransaction{}
+		err = rows.Scan(
+			&tx.ID, &tx.Amount, &tx.Reference, &tx.Currency,
+			&tx.Description, &tx.Date, &tx.Source,
+		)
+		if err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan external transaction data", err)
+		}
+
+		transactions = append(transactions, tx)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over unmatched transactions", err)
+	}
+
+	return transactions, nil
+}
+
 // RecordMatchingRule saves a new matching rule to the database.
 // Parameters:
 // - ctx: Context for managing the request and tracing.
@@ -600,21 +879,20 @@ func (d Datasource) GetMatchingRule(ctx context.Context, id string) (*model.Matc
 }
 
 // GetExternalTransactionsPaginated retrieves external transactions based on the provided upload ID
-// with pagination. It first checks the cache, and if the data is not available, it fetches from the
+// with pagination, newest first. It first checks the cache, and if the data is not available, it fetches from the
 // database and caches the result for 5 minutes.
 // Parameters:
 // - ctx: Context for managing the request and tracing.
 // - uploadID: The ID of the upload to filter external transactions.
-// - batchSize: The number of transactions to retrieve per batch.
-// - offset: The starting point to retrieve transactions from.
+// - page: The page of transactions to retrieve.
 // Returns:
 // - A slice of ExternalTransaction objects or an APIError if the operation fails.
-func (d Datasource) GetExternalTransactionsPaginated(ctx context.Context, uploadID string, batchSize int, offset int64) ([]*model.ExternalTransaction, error) {
+func (d Datasource) GetExternalTransactionsPaginated(ctx context.Context, uploadID string, page model.Page) ([]*model.ExternalTransaction, error) {
 	ctx, span := otel.Tracer("reconciliation.database").Start(ctx, "Fetching external transactions with pagination")
 	defer span.End()
 
 	// Create a cache key based on the pagination parameters
-	cacheKey := fmt.Sprintf("transactions:external:paginated:%d:%d", batchSize, offset)
+	cacheKey := fmt.Sprintf("transactions:external:paginated:%s:%s", uploadID, pageKey(page))
 	var transactions []*model.ExternalTransaction
 
 	// Check if the data exists in the cache
@@ -624,13 +902,11 @@ func (d Datasource) GetExternalTransactionsPaginated(ctx context.Context, upload
 	}
 
 	// Query the database for external transactions if not found in cache
+	condition, suffix, args := externalTransactionKeyset.page(page, []interface{}{uploadID})
 	rows, err := d.Conn.QueryContext(ctx, `
 		SELECT id, amount, reference, currency, description, date, source
 		FROM blnk.external_transactions
-		WHERE upload_id = $1
-		ORDER BY date DESC
-		LIMIT $2 OFFSET $3
-	`, uploadID, batchSize, offset)
+		WHERE upload_id = $1 AND `+condition+suffix, args...)
 	if err != nil {
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve external transactions", err)
 	}
@@ -727,11 +1003,10 @@ func (d Datasource) LoadReconciliationProgress(ctx context.Context, reconciliati
 // - ctx: Context for managing the request and tracing.
 // - uploadID: The ID of the upload to filter external transactions.
 // - groupCriteria: The field by which to group the transactions (e.g., "amount", "currency").
-// - batchSize: The number of transactions to retrieve.
-// - offset: The pagination offset.
+// - page: The page of transactions to group, oldest first.
 // Returns:
 // - A map of grouped transactions where the key is the group criterion value and the value is a slice of transactions, or an error wrapped in an APIError if any issues occur.
-func (d Datasource) FetchAndGroupExternalTransactions(ctx context.Context, uploadID string, groupCriteria string, batchSize int, offset int64) (map[string][]*model.Transaction, error) {
+func (d Datasource) FetchAndGroupExternalTransactions(ctx context.Context, uploadID string, groupCriteria string, page model.Page) (map[string][]*model.Transaction, error) {
 	ctx, span := otel.Tracer("reconciliation.database").Start(ctx, "FetchAndGroupExternalTransactions")
 	defer span.End()
 
@@ -745,7 +1020,7 @@ func (d Datasource) FetchAndGroupExternalTransactions(ctx context.Context, uploa
 	}
 
 	// Create a cache key based on the grouping and pagination parameters
-	cacheKey := fmt.Sprintf("external_transactions:grouped:%s:%s:%d:%d", uploadID, groupCriteria, batchSize, offset)
+	cacheKey := fmt.Sprintf("external_transactions:grouped:%s:%s:%s", uploadID, groupCriteria, pageKey(page))
 
 	var groupedTransactions map[string][]*model.Transaction
 	err := d.Cache.Get(ctx, cacheKey, &groupedTransactions)
@@ -757,15 +1032,13 @@ func (d Datasource) FetchAndGroupExternalTransactions(ctx context.Context, uploa
 	}
 
 	// If not in cache or error occurred, fetch from database
+	condition, suffix, args := groupedExternalTransactionKeyset.page(page, []interface{}{groupCriteria, uploadID})
 	query := `
         SELECT $1::text AS group_key, id, amount, reference, currency, description, date, source
         FROM blnk.external_transactions
-        WHERE upload_id = $2 AND $1::text IS NOT NULL AND $1::text != ''
-        ORDER BY $1::text
-        LIMIT $3 OFFSET $4
-    `
+        WHERE upload_id = $2 AND $1::text IS NOT NULL AND $1::text != '' AND ` + condition + suffix
 
-	rows, err := d.Conn.QueryContext(ctx, query, groupCriteria, uploadID, batchSize, offset)
+	rows, err := d.Conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		span.RecordError(err)
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve grouped external transactions", err)
@@ -200,6 +200,8 @@ func TestRecordMatches_Success(t *testing.T) {
 			ReconciliationID:      "rec123",
 			Amount:                1000,
 			Date:                  time.Now(),
+			Confidence:            0.95,
+			Status:                model.MatchStatusConfirmed,
 		},
 		{
 			ExternalTransactionID: "ext2",
@@ -207,6 +209,8 @@ func TestRecordMatches_Success(t *testing.T) {
 			ReconciliationID:      "rec123",
 			Amount:                2000,
 			Date:                  time.Now(),
+			Confidence:            0.7,
+			Status:                model.MatchStatusPendingReview,
 		},
 	}
 
@@ -214,7 +218,7 @@ func TestRecordMatches_Success(t *testing.T) {
 
 	for _, match := range matches {
 		mock.ExpectExec("INSERT INTO blnk.matches").
-			WithArgs(match.ExternalTransactionID, match.InternalTransactionID, match.ReconciliationID, match.Amount, match.Date).
+			WithArgs(match.ExternalTransactionID, match.InternalTransactionID, match.ReconciliationID, match.Amount, match.Date, match.Confidence, match.Status).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 	}
 
@@ -244,7 +248,7 @@ func TestRecordMatches_Fail(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO blnk.matches").
-		WithArgs(matches[0].ExternalTransactionID, matches[0].InternalTransactionID, matches[0].ReconciliationID, matches[0].Amount, matches[0].Date).
+		WithArgs(matches[0].ExternalTransactionID, matches[0].InternalTransactionID, matches[0].ReconciliationID, matches[0].Amount, matches[0].Date, matches[0].Confidence, matches[0].Status).
 		WillReturnError(fmt.Errorf("failed to insert match"))
 	mock.ExpectRollback()
 
@@ -252,3 +256,100 @@ func TestRecordMatches_Fail(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, apierror.ErrInternalServer, err.(apierror.APIError).Code)
 }
+
+func TestUpdateMatchStatus_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectExec("UPDATE blnk.matches").
+		WithArgs("rec123", "ext1", "int1", model.MatchStatusConfirmed).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = ds.UpdateMatchStatus(context.TODO(), "rec123", "ext1", "int1", model.MatchStatusConfirmed)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateMatchStatus_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectExec("UPDATE blnk.matches").
+		WithArgs("rec123", "ext1", "int1", model.MatchStatusRejected).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = ds.UpdateMatchStatus(context.TODO(), "rec123", "ext1", "int1", model.MatchStatusRejected)
+	assert.Error(t, err)
+	assert.Equal(t, apierror.ErrNotFound, err.(apierror.APIError).Code)
+}
+
+func TestGetMatchScoreDistribution(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery("SELECT COUNT").
+		WithArgs("rec123").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "avg", "confirmed", "pending_review", "rejected"}).
+			AddRow(3, 0.8, 2, 1, 0))
+	mock.ExpectQuery("SELECT LEAST").
+		WithArgs("rec123").
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}).
+			AddRow(7, 1).
+			AddRow(10, 2))
+
+	dist, err := ds.GetMatchScoreDistribution(context.TODO(), "rec123")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, dist.TotalMatches)
+	assert.Equal(t, 2, dist.Confirmed)
+	assert.Equal(t, 1, dist.PendingReview)
+	assert.Len(t, dist.Buckets, 10)
+	assert.Equal(t, 1, dist.Buckets[6].Count)
+	assert.Equal(t, 2, dist.Buckets[9].Count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordExternalTransactions_SkipsRecordedIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+	date := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	txns := []*model.ExternalTransaction{
+		{ID: "ext_1", Amount: 10.5, Reference: "ref-1", Currency: "USD", Date: date, Source: "bank"},
+		{ID: "ext_2", Amount: 20, Reference: "ref-2", Currency: "USD", Date: date, Source: "bank"},
+	}
+
+	mock.ExpectQuery(`INSERT INTO blnk.external_transactions .* FROM unnest\(.*\) .* ON CONFLICT \(id\) DO NOTHING\s+RETURNING id`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "upload_1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("ext_2"))
+
+	recorded, err := ds.RecordExternalTransactions(context.Background(), txns, "upload_1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ext_2"}, recorded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordExternalTransactions_Fail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+	mock.ExpectQuery("INSERT INTO blnk.external_transactions").WillReturnError(fmt.Errorf("database error"))
+
+	_, err = ds.RecordExternalTransactions(context.Background(), []*model.ExternalTransaction{{ID: "ext_1"}}, "upload_1")
+	assert.Error(t, err)
+	apiErr, ok := err.(apierror.APIError)
+	assert.True(t, ok)
+	assert.Equal(t, apierror.ErrInternalServer, apiErr.Code)
+}
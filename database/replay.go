@@ -0,0 +1,181 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+// GetReplayTransactions retrieves the applied, inflight and voided transactions created within a window that
+// touch a balance of the ledger, in the order they were recorded. Applied transactions that commit an inflight
+// transaction are returned with the model.ReplayCommit status.
+func (d Datasource) GetReplayTransactions(ctx context.Context, ledgerID string, from, to time.Time) ([]*model.Transaction, error) {
+	ctx, span := otel.Tracer("replay.database").Start(ctx, "GetReplayTransactions")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT t.transaction_id, t.parent_transaction, t.source, t.destination, t.reference, t.amount, t.precise_amount,
+			t.precision, t.rate, t.currency, t.description,
+			CASE WHEN t.status = 'APPLIED' AND p.status = 'INFLIGHT' THEN 'COMMIT' ELSE t.status END,
+			t.created_at, t.meta_data, t.hash
+		FROM blnk.transactions t
+		LEFT JOIN blnk.transactions p ON p.transaction_id = t.parent_transaction
+		WHERE t.status IN ('APPLIED', 'INFLIGHT', 'VOID')
+			AND t.created_at >= $2 AND t.created_at < $3
+			AND (t.source IN (SELECT balance_id FROM blnk.balances WHERE ledger_id = $1)
+				OR t.destination IN (SELECT balance_id FROM blnk.balances WHERE ledger_id = $1))
+		ORDER BY t.created_at ASC, t.transaction_id ASC
+	`, ledgerID, from, to)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve replay transactions", err)
+	}
+	defer rows.Close()
+
+	transactions := []*model.Transaction{}
+	for rows.Next() {
+		txn := &model.Transaction{}
+		var preciseAmount string
+		var metaData []byte
+		if err := rows.Scan(&txn.TransactionID, &txn.ParentTransaction, &txn.Source, &txn.Destination, &txn.Reference,
+			&txn.Amount, &preciseAmount, &txn.Precision, &txn.Rate, &txn.Currency, &txn.Description, &txn.Status,
+			&txn.CreatedAt, &metaData, &txn.Hash); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan replay transaction", err)
+		}
+		if len(metaData) > 0 {
+			if err := json.Unmarshal(metaData, &txn.MetaData); err != nil {
+				span.RecordError(err)
+				return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal metadata", err)
+			}
+		}
+		txn.PreciseAmount, _ = new(big.Int).SetString(preciseAmount, 10)
+		transactions = append(transactions, txn)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over replay transactions", err)
+	}
+	return transactions, nil
+}
+
+// GetReplayBalanceAt computes the amounts of a balance at a point in time from the transactions recorded before
+// then, ignoring its snapshots and current amounts so a balance row that drifted from its transactions shows up
+// when the slice is replayed. Inflight amounts are the part of each inflight transaction that had been neither
+// committed nor voided by then.
+func (d Datasource) GetReplayBalanceAt(ctx context.Context, balanceID string, at time.Time) (*model.Balance, error) {
+	ctx, span := otel.Tracer("replay.database").Start(ctx, "GetReplayBalanceAt")
+	defer span.End()
+
+	var credit, debit, inflightCredit, inflightDebit string
+	err := d.Conn.QueryRowContext(ctx, `
+		WITH applied AS (
+			SELECT
+				COALESCE(SUM(CASE WHEN destination = $1 THEN TRUNC(precise_amount * COALESCE(NULLIF(rate, 0), 1)::NUMERIC) ELSE 0 END), 0) AS credit,
+				COALESCE(SUM(CASE WHEN source = $1 THEN precise_amount ELSE 0 END), 0) AS debit
+			FROM blnk.transactions
+			WHERE (source = $1 OR destination = $1) AND status = 'APPLIED' AND created_at < $2
+		), inflight AS (
+			SELECT
+				COALESCE(SUM(CASE WHEN destination = $1 THEN pending ELSE 0 END), 0) AS credit,
+				COALESCE(SUM(CASE WHEN source = $1 THEN pending ELSE 0 END), 0) AS debit
+			FROM (
+				SELECT t.source, t.destination,
+					t.precise_amount - COALESCE((
+						SELECT SUM(child.precise_amount)
+						FROM blnk.transactions child
+						WHERE child.parent_transaction = t.transaction_id AND child.status IN ('APPLIED', 'VOID')
+							AND child.created_at < $2
+					), 0) AS pending
+				FROM blnk.transactions t
+				WHERE (t.source = $1 OR t.destination = $1) AND t.status = 'INFLIGHT' AND t.created_at < $2
+			) pending
+		)
+		SELECT applied.credit::TEXT, applied.debit::TEXT, inflight.credit::TEXT, inflight.debit::TEXT
+		FROM applied, inflight
+	`, balanceID, at).Scan(&credit, &debit, &inflightCredit, &inflightDebit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to compute balance", err)
+	}
+
+	balance := &model.Balance{BalanceID: balanceID}
+	balance.CreditBalance, _ = new(big.Int).SetString(credit, 10)
+	balance.DebitBalance, _ = new(big.Int).SetString(debit, 10)
+	balance.InflightCreditBalance, _ = new(big.Int).SetString(inflightCredit, 10)
+	balance.InflightDebitBalance, _ = new(big.Int).SetString(inflightDebit, 10)
+	balance.Balance = new(big.Int).Sub(balance.CreditBalance, balance.DebitBalance)
+	balance.InflightBalance = new(big.Int).Sub(balance.InflightCreditBalance, balance.InflightDebitBalance)
+	return balance, nil
+}
+
+// RestoreLedger creates a ledger with a given ID unless it already exists. Replays use it to give the balances
+// they restore a ledger to belong to.
+func (d Datasource) RestoreLedger(ctx context.Context, ledgerID string) error {
+	ctx, span := otel.Tracer("replay.database").Start(ctx, "RestoreLedger")
+	defer span.End()
+
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.ledgers (ledger_id, name, created_at, meta_data)
+		VALUES ($1, $1, NOW(), '{}')
+		ON CONFLICT (ledger_id) DO NOTHING
+	`, ledgerID)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to restore ledger", err)
+	}
+	return nil
+}
+
+// RestoreBalance writes a balance with its ID and amounts as given, replacing the amounts of an existing balance
+// with the same ID. Unlike CreateBalance it keeps the ID, so replays can restore exported balance states. The
+// identity is left out because the identities of an exported slice are not restored with it.
+func (d Datasource) RestoreBalance(ctx context.Context, balance *model.Balance) error {
+	ctx, span := otel.Tracer("replay.database").Start(ctx, "RestoreBalance")
+	defer span.End()
+
+	balance.InitializeBalanceFields()
+	metaData, err := json.Marshal(balance.MetaData)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
+	}
+
+	_, err = d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.balances (balance_id, balance, credit_balance, debit_balance, inflight_balance, inflight_credit_balance,
+			inflight_debit_balance, currency, currency_multiplier, ledger_id, indicator, created_at, meta_data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12, $13)
+		ON CONFLICT (balance_id) DO UPDATE
+		SET balance = EXCLUDED.balance, credit_balance = EXCLUDED.credit_balance, debit_balance = EXCLUDED.debit_balance,
+			inflight_balance = EXCLUDED.inflight_balance, inflight_credit_balance = EXCLUDED.inflight_credit_balance,
+			inflight_debit_balance = EXCLUDED.inflight_debit_balance, version = blnk.balances.version + 1
+	`, balance.BalanceID, balance.Balance.String(), balance.CreditBalance.String(), balance.DebitBalance.String(),
+		balance.InflightBalance.String(), balance.InflightCreditBalance.String(), balance.InflightDebitBalance.String(),
+		balance.Currency, balance.CurrencyMultiplier, balance.LedgerID, balance.Indicator, balance.CreatedAt, metaData)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to restore balance", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"math/big"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetReplayTransactions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	rows := sqlmock.NewRows([]string{"transaction_id", "parent_transaction", "source", "destination", "reference", "amount",
+		"precise_amount", "precision", "rate", "currency", "description", "status", "created_at", "meta_data", "hash"}).
+		AddRow("txn1", "", "bln_a", "bln_b", "ref1", 10.0, "1000", 100, 1.0, "USD", "", "INFLIGHT", from.Add(time.Hour), []byte(`{}`), "h1").
+		AddRow("txn2", "txn1", "bln_a", "bln_b", "ref2", 10.0, "1000", 100, 1.0, "USD", "", model.ReplayCommit, from.Add(2*time.Hour), nil, "h2")
+
+	mock.ExpectQuery(regexp.QuoteMeta("CASE WHEN t.status = 'APPLIED' AND p.status = 'INFLIGHT' THEN 'COMMIT'")).
+		WithArgs("ldg_1", from, to).
+		WillReturnRows(rows)
+
+	transactions, err := ds.GetReplayTransactions(context.Background(), "ldg_1", from, to)
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+	assert.Equal(t, "INFLIGHT", transactions[0].Status)
+	assert.Equal(t, model.ReplayCommit, transactions[1].Status)
+	assert.Equal(t, "txn1", transactions[1].ParentTransaction)
+	assert.Equal(t, big.NewInt(1000), transactions[1].PreciseAmount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetReplayBalanceAt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+	at := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT applied.credit::TEXT, applied.debit::TEXT, inflight.credit::TEXT, inflight.debit::TEXT")).
+		WithArgs("bln_a", at).
+		WillReturnRows(sqlmock.NewRows([]string{"credit", "debit", "inflight_credit", "inflight_debit"}).
+			AddRow("5000", "1500", "0", "700"))
+
+	balance, err := ds.GetReplayBalanceAt(context.Background(), "bln_a", at)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(3500), balance.Balance)
+	assert.Equal(t, big.NewInt(700), balance.InflightDebitBalance)
+	assert.Equal(t, big.NewInt(-700), balance.InflightBalance)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/sirupsen/logrus"
+)
+
+// replicaLagQuery measures how far a replica is behind its primary. A replica that has replayed everything it
+// received is not behind, however long ago the last transaction was, and a server that is not replicating
+// reports no lag.
+const replicaLagQuery = `
+	SELECT CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END`
+
+// replicaCheckTimeout bounds how long measuring the lag of a replica can hold up the read that triggered it.
+const replicaCheckTimeout = 2 * time.Second
+
+// replica is a read replica of the primary database and what was found when its lag was last measured.
+type replica struct {
+	conn     *sql.DB
+	checking atomic.Bool
+
+	mu        sync.RWMutex
+	checkedAt time.Time
+	lag       time.Duration
+	healthy   bool
+}
+
+// replicaSet routes read-only queries across the read replicas, skipping any that are unreachable or further
+// behind the primary than the configured maximum lag.
+type replicaSet struct {
+	replicas      []*replica
+	maxLag        time.Duration
+	checkInterval time.Duration
+	next          atomic.Uint64
+}
+
+// newReplicaSet connects to the configured read replicas. A replica that cannot be reached is left out with a
+// warning rather than stopping Blnk, since reads fall back to the primary.
+func newReplicaSet(dsConfig config.DataSourceConfig) *replicaSet {
+	if len(dsConfig.Replicas) == 0 {
+		return nil
+	}
+
+	set := &replicaSet{maxLag: dsConfig.ReplicaMaxLag, checkInterval: dsConfig.ReplicaCheckInterval}
+	for _, dns := range dsConfig.Replicas {
+		replicaConfig := dsConfig
+		replicaConfig.Dns = dns
+		conn, err := ConnectDB(replicaConfig)
+		if err != nil {
+			logrus.Warnf("read replica is unreachable and will not be used: %v", err)
+			continue
+		}
+		set.replicas = append(set.replicas, &replica{conn: conn})
+	}
+	if len(set.replicas) == 0 {
+		return nil
+	}
+	return set
+}
+
+// pick returns the next replica, in turn, that is reachable and within the maximum lag, or nil when there is
+// none and the read should go to the primary.
+func (s *replicaSet) pick(ctx context.Context) *replica {
+	if s == nil {
+		return nil
+	}
+	start := s.next.Add(1)
+	for i := range s.replicas {
+		r := s.replicas[(int(start)+i)%len(s.replicas)]
+		if r.usable(ctx, s.maxLag, s.checkInterval) {
+			return r
+		}
+	}
+	return nil
+}
+
+// usable reports whether the replica can serve a read, measuring its lag again once the last measurement is
+// older than the check interval. While another read is measuring it, the last measurement is used.
+func (r *replica) usable(ctx context.Context, maxLag, checkInterval time.Duration) bool {
+	r.mu.RLock()
+	stale := time.Since(r.checkedAt) >= checkInterval
+	r.mu.RUnlock()
+
+	if stale && r.checking.CompareAndSwap(false, true) {
+		r.measure(ctx)
+		r.checking.Store(false)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.healthy && r.lag <= maxLag
+}
+
+// measure records how far the replica is behind the primary, or that it cannot be reached.
+func (r *replica) measure(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+	defer cancel()
+
+	var seconds float64
+	err := r.conn.QueryRowContext(ctx, replicaLagQuery).Scan(&seconds)
+	if err != nil {
+		logrus.Warnf("read replica check failed, reading from the primary: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkedAt = time.Now()
+	r.healthy = err == nil
+	r.lag = time.Duration(seconds * float64(time.Second))
+}
+
+// markUnhealthy stops reads going to the replica until its lag is next measured.
+func (r *replica) markUnhealthy() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.healthy = false
+}
+
+// readQuery runs a read-only query on a read replica when one is configured and current enough, and on the
+// primary otherwise. A query that fails on the replica is run again on the primary, and the replica is not used
+// until its next check. Only reads that can tolerate the replica's lag should use it.
+func (d Datasource) readQuery(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if r := d.replicas.pick(ctx); r != nil {
+		rows, err := r.conn.QueryContext(ctx, query, args...)
+		if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return rows, err
+		}
+		logrus.Warnf("read replica query failed, reading from the primary: %v", err)
+		r.markUnhealthy()
+	}
+	return d.Conn.QueryContext(ctx, query, args...)
+}
+
+// beginReadTx starts a read-only transaction on a read replica when one is configured and current enough, and
+// on the primary otherwise, falling back to the primary when the replica cannot start it.
+func (d Datasource) beginReadTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if r := d.replicas.pick(ctx); r != nil {
+		tx, err := r.conn.BeginTx(ctx, opts)
+		if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return tx, err
+		}
+		logrus.Warnf("read replica transaction failed, reading from the primary: %v", err)
+		r.markUnhealthy()
+	}
+	return d.Conn.BeginTx(ctx, opts)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReplicaTestDatasource(t *testing.T) (Datasource, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	primary, primaryMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { primary.Close() })
+	replicaDB, replicaMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { replicaDB.Close() })
+
+	set := &replicaSet{replicas: []*replica{{conn: replicaDB}}, maxLag: 10 * time.Second, checkInterval: time.Minute}
+	return Datasource{Conn: primary, replicas: set}, primaryMock, replicaMock
+}
+
+func TestReadQuery_UsesCurrentReplica(t *testing.T) {
+	ds, primaryMock, replicaMock := newReplicaTestDatasource(t)
+	replicaMock.ExpectQuery("pg_last_wal_replay_lsn").WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(1.5))
+	replicaMock.ExpectQuery("SELECT balance_id FROM blnk.balances").WillReturnRows(sqlmock.NewRows([]string{"balance_id"}).AddRow("bln_1"))
+	replicaMock.ExpectQuery("SELECT balance_id FROM blnk.balances").WillReturnRows(sqlmock.NewRows([]string{"balance_id"}))
+
+	for i := 0; i < 2; i++ {
+		rows, err := ds.readQuery(context.Background(), "SELECT balance_id FROM blnk.balances")
+		require.NoError(t, err)
+		rows.Close()
+	}
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+}
+
+func TestReadQuery_LaggingReplicaFallsBackToPrimary(t *testing.T) {
+	ds, primaryMock, replicaMock := newReplicaTestDatasource(t)
+	replicaMock.ExpectQuery("pg_last_wal_replay_lsn").WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(42.0))
+	primaryMock.ExpectQuery("SELECT balance_id FROM blnk.balances").WillReturnRows(sqlmock.NewRows([]string{"balance_id"}))
+
+	rows, err := ds.readQuery(context.Background(), "SELECT balance_id FROM blnk.balances")
+	require.NoError(t, err)
+	rows.Close()
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+}
+
+func TestReadQuery_FailedReplicaQueryRetriesOnPrimary(t *testing.T) {
+	ds, primaryMock, replicaMock := newReplicaTestDatasource(t)
+	replicaMock.ExpectQuery("pg_last_wal_replay_lsn").WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(0.0))
+	replicaMock.ExpectQuery("SELECT balance_id FROM blnk.balances").WillReturnError(errors.New("connection reset"))
+	primaryMock.ExpectQuery("SELECT balance_id FROM blnk.balances").WillReturnRows(sqlmock.NewRows([]string{"balance_id"}))
+	primaryMock.ExpectQuery("SELECT balance_id FROM blnk.balances").WillReturnRows(sqlmock.NewRows([]string{"balance_id"}))
+
+	// The replica is left out until its next check, so the second read goes straight to the primary
+	for i := 0; i < 2; i++ {
+		rows, err := ds.readQuery(context.Background(), "SELECT balance_id FROM blnk.balances")
+		require.NoError(t, err)
+		rows.Close()
+	}
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+}
+
+func TestReadQuery_WithoutReplicas(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT balance_id FROM blnk.balances").WillReturnRows(sqlmock.NewRows([]string{"balance_id"}))
+	rows, err := Datasource{Conn: db}.readQuery(context.Background(), "SELECT balance_id FROM blnk.balances")
+	require.NoError(t, err)
+	rows.Close()
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,593 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+const reportDefinitionColumns = `report_id, name, source, filters, group_by, aggregates, window_duration, schedule, format, destination, is_active, next_run_at, last_run_at, created_at`
+
+const reportRunColumns = `run_id, report_id, trigger, status, columns, rows, row_count, truncated, storage_key, error, started_at, completed_at`
+
+// reportTables are the tables report sources read from.
+var reportTables = map[string]string{
+	model.ReportSourceTransactions: "blnk.transactions",
+	model.ReportSourceBalances:     "blnk.balances",
+}
+
+// reportExpressions are the SQL expressions of report fields that are not plain columns.
+var reportExpressions = map[string]string{
+	"created_date":  "to_char(created_at, 'YYYY-MM-DD')",
+	"created_month": "to_char(created_at, 'YYYY-MM')",
+}
+
+var reportOperators = map[string]string{
+	"==": "=",
+	"!=": "<>",
+	">":  ">",
+	">=": ">=",
+	"<":  "<",
+	"<=": "<=",
+}
+
+// reportExpression returns the SQL expression of a report field. Only fields listed in model.ReportFields
+// reach the query, so the expressions never contain user input.
+func reportExpression(source, field string) (string, error) {
+	if _, ok := model.ReportFields[source][field]; !ok {
+		return "", fmt.Errorf("unknown report field %q", field)
+	}
+	if expression, ok := reportExpressions[field]; ok {
+		return expression, nil
+	}
+	return field, nil
+}
+
+// buildReportQuery builds the aggregate query of a report. Group values and aggregates are selected as text
+// and rows are ordered by the group values. One row more than limit is selected so that truncation can be
+// detected.
+func buildReportQuery(definition *model.ReportDefinition, now time.Time, limit int) (string, []interface{}, error) {
+	table, ok := reportTables[definition.Source]
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported report source %q", definition.Source)
+	}
+
+	var selects, groups, conditions []string
+	var args []interface{}
+	for i, field := range definition.GroupBy {
+		expression, err := reportExpression(definition.Source, field)
+		if err != nil {
+			return "", nil, err
+		}
+		selects = append(selects, fmt.Sprintf("(%s)::text", expression))
+		groups = append(groups, fmt.Sprintf("%d", i+1))
+	}
+	for _, aggregate := range definition.Aggregates {
+		argument := "*"
+		if aggregate.Field != "" {
+			expression, err := reportExpression(definition.Source, aggregate.Field)
+			if err != nil {
+				return "", nil, err
+			}
+			argument = expression
+		}
+		function := strings.ToUpper(aggregate.Function)
+		if function == "AVG" {
+			// Averages of minor units are kept to two decimal places rather than Postgres' sixteen
+			selects = append(selects, fmt.Sprintf("ROUND(AVG(%s), 2)::text", argument))
+			continue
+		}
+		selects = append(selects, fmt.Sprintf("%s(%s)::text", function, argument))
+	}
+
+	for _, filter := range definition.Filters {
+		expression, err := reportExpression(definition.Source, filter.Field)
+		if err != nil {
+			return "", nil, err
+		}
+		if filter.Operator == "in" {
+			args = append(args, pq.Array(filter.Values))
+			conditions = append(conditions, fmt.Sprintf("%s = ANY($%d)", expression, len(args)))
+			continue
+		}
+		operator, ok := reportOperators[filter.Operator]
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported report operator %q", filter.Operator)
+		}
+		args = append(args, filter.Value)
+		conditions = append(conditions, fmt.Sprintf("%s %s $%d", expression, operator, len(args)))
+	}
+	if window := definition.WindowDuration(); window > 0 {
+		args = append(args, now.Add(-window))
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	query := "SELECT " + strings.Join(selects, ", ") + " FROM " + table
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if len(groups) > 0 {
+		query += " GROUP BY " + strings.Join(groups, ", ") + " ORDER BY " + strings.Join(groups, ", ")
+	}
+	args = append(args, limit+1)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+	return query, args, nil
+}
+
+// RunReportQuery computes the rows of a report.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - definition: The report to compute.
+// - now: The time the report runs at, which its window ends at.
+// - limit: The maximum number of rows to return.
+// Returns:
+// - The columns and rows of the report, whether rows beyond the limit were left out, or an error if the
+// query fails.
+func (d Datasource) RunReportQuery(ctx context.Context, definition *model.ReportDefinition, now time.Time, limit int) ([]string, [][]string, bool, error) {
+	ctx, span := otel.Tracer("report.database").Start(ctx, "Running report query")
+	defer span.End()
+
+	query, args, err := buildReportQuery(definition, now, limit)
+	if err != nil {
+		return nil, nil, false, apierror.NewAPIError(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	columns := append([]string{}, definition.GroupBy...)
+	for _, aggregate := range definition.Aggregates {
+		columns = append(columns, aggregate.Column())
+	}
+
+	rows, err := d.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, nil, false, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to run report", err)
+	}
+	defer rows.Close()
+
+	results := [][]string{}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			span.RecordError(err)
+			return nil, nil, false, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan report row", err)
+		}
+		row := make([]string, len(values))
+		for i, value := range values {
+			row[i] = value.String
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, false, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over report rows", err)
+	}
+
+	truncated := len(results) > limit
+	if truncated {
+		results = results[:limit]
+	}
+	return columns, results, truncated, nil
+}
+
+// CreateReportDefinition saves a new report definition.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - definition: The report to store.
+// Returns:
+// - An error if the report could not be saved.
+func (d Datasource) CreateReportDefinition(ctx context.Context, definition *model.ReportDefinition) error {
+	ctx, span := otel.Tracer("report.database").Start(ctx, "Creating report definition")
+	defer span.End()
+
+	filters, groupBy, aggregates, err := marshalReportDefinition(definition)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to encode report definition", err)
+	}
+
+	_, err = d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.report_definitions (`+reportDefinitionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`,
+		definition.ReportID, definition.Name, definition.Source, filters, groupBy, aggregates,
+		sql.NullString{String: definition.Window, Valid: definition.Window != ""},
+		sql.NullString{String: definition.Schedule, Valid: definition.Schedule != ""},
+		definition.Format, sql.NullString{String: definition.Destination, Valid: definition.Destination != ""},
+		definition.IsActive, definition.NextRunAt, definition.LastRunAt, definition.CreatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create report definition", err)
+	}
+	return nil
+}
+
+// GetReportDefinition retrieves a report definition by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - reportID: The ID of the report.
+// Returns:
+// - The report, or an error if it does not exist.
+func (d Datasource) GetReportDefinition(ctx context.Context, reportID string) (*model.ReportDefinition, error) {
+	ctx, span := otel.Tracer("report.database").Start(ctx, "Fetching report definition")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `
+		SELECT `+reportDefinitionColumns+`
+		FROM blnk.report_definitions
+		WHERE report_id = $1
+	`, reportID)
+
+	definition, err := scanReportDefinition(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Report with ID '%s' not found", reportID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve report definition", err)
+	}
+	return definition, nil
+}
+
+// reportDefinitionKeyset is the order report definitions are listed in, newest first.
+var reportDefinitionKeyset = keyset{timeColumn: "created_at", idColumn: "report_id", descending: true}
+
+// reportRunKeyset is the order the runs of a report are listed in, newest first.
+var reportRunKeyset = keyset{timeColumn: "started_at", idColumn: "run_id", descending: true}
+
+// ListReportDefinitions lists report definitions, newest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - page: The page of reports to read.
+// Returns:
+// - The reports, or an error if the query fails.
+func (d Datasource) ListReportDefinitions(ctx context.Context, page model.Page) ([]*model.ReportDefinition, error) {
+	ctx, span := otel.Tracer("report.database").Start(ctx, "Listing report definitions")
+	defer span.End()
+
+	condition, suffix, args := reportDefinitionKeyset.page(page, nil)
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+reportDefinitionColumns+`
+		FROM blnk.report_definitions
+		WHERE `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve report definitions", err)
+	}
+	return collectReportDefinitions(rows)
+}
+
+// GetDueReportDefinitions retrieves active scheduled reports whose next run is at or before the given time.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - now: The reference time.
+// - limit: The maximum number of reports to return.
+// Returns:
+// - The due reports, or an error if the query fails.
+func (d Datasource) GetDueReportDefinitions(ctx context.Context, now time.Time, limit int) ([]*model.ReportDefinition, error) {
+	ctx, span := otel.Tracer("report.database").Start(ctx, "Fetching due report definitions")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+reportDefinitionColumns+`
+		FROM blnk.report_definitions
+		WHERE is_active AND next_run_at <= $1
+		ORDER BY next_run_at ASC
+		LIMIT $2
+	`, now, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve due report definitions", err)
+	}
+	return collectReportDefinitions(rows)
+}
+
+// UpdateReportDefinitionRun records a scheduled run of a report and moves it to its next run time.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - reportID: The ID of the report.
+// - lastRunAt: When the report ran.
+// - nextRunAt: When the report should run next.
+// Returns:
+// - An error if the report could not be updated.
+func (d Datasource) UpdateReportDefinitionRun(ctx context.Context, reportID string, lastRunAt, nextRunAt time.Time) error {
+	ctx, span := otel.Tracer("report.database").Start(ctx, "Updating report definition run")
+	defer span.End()
+
+	_, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.report_definitions
+		SET last_run_at = $2, next_run_at = $3
+		WHERE report_id = $1
+	`, reportID, lastRunAt, nextRunAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update report definition", err)
+	}
+	return nil
+}
+
+// DeleteReportDefinition deletes a report definition along with its runs.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - reportID: The ID of the report.
+// Returns:
+// - An error if the report does not exist or could not be deleted.
+func (d Datasource) DeleteReportDefinition(ctx context.Context, reportID string) error {
+	ctx, span := otel.Tracer("report.database").Start(ctx, "Deleting report definition")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `DELETE FROM blnk.report_definitions WHERE report_id = $1`, reportID)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to delete report definition", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Report with ID '%s' not found", reportID), nil)
+	}
+	return nil
+}
+
+// CreateReportRun saves a report run.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - run: The run to store.
+// Returns:
+// - An error if the run could not be saved.
+func (d Datasource) CreateReportRun(ctx context.Context, run *model.ReportRun) error {
+	ctx, span := otel.Tracer("report.database").Start(ctx, "Creating report run")
+	defer span.End()
+
+	columns, rows, err := marshalReportRun(run)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to encode report run", err)
+	}
+
+	_, err = d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.report_runs (`+reportRunColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`,
+		run.RunID, run.ReportID, run.Trigger, run.Status, columns, rows, run.RowCount, run.Truncated,
+		sql.NullString{String: run.StorageKey, Valid: run.StorageKey != ""},
+		sql.NullString{String: run.Error, Valid: run.Error != ""},
+		run.StartedAt, run.CompletedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create report run", err)
+	}
+	return nil
+}
+
+// UpdateReportRun saves the outcome of a report run.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - run: The run with its outcome.
+// Returns:
+// - An error if the run does not exist or could not be updated.
+func (d Datasource) UpdateReportRun(ctx context.Context, run *model.ReportRun) error {
+	ctx, span := otel.Tracer("report.database").Start(ctx, "Updating report run")
+	defer span.End()
+
+	columns, rows, err := marshalReportRun(run)
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to encode report run", err)
+	}
+
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.report_runs
+		SET status = $2, columns = $3, rows = $4, row_count = $5, truncated = $6, storage_key = $7, error = $8, completed_at = $9
+		WHERE run_id = $1
+	`,
+		run.RunID, run.Status, columns, rows, run.RowCount, run.Truncated,
+		sql.NullString{String: run.StorageKey, Valid: run.StorageKey != ""},
+		sql.NullString{String: run.Error, Valid: run.Error != ""},
+		run.CompletedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update report run", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Report run with ID '%s' not found", run.RunID), nil)
+	}
+	return nil
+}
+
+// GetReportRun retrieves a report run by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - runID: The ID of the run.
+// Returns:
+// - The run, or an error if it does not exist.
+func (d Datasource) GetReportRun(ctx context.Context, runID string) (*model.ReportRun, error) {
+	ctx, span := otel.Tracer("report.database").Start(ctx, "Fetching report run")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `
+		SELECT `+reportRunColumns+`
+		FROM blnk.report_runs
+		WHERE run_id = $1
+	`, runID)
+
+	run, err := scanReportRun(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Report run with ID '%s' not found", runID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve report run", err)
+	}
+	return run, nil
+}
+
+// ListReportRuns lists the runs of a report, newest first. The rows of the runs are left out.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - reportID: The ID of the report.
+// - page: The page of runs to read.
+// Returns:
+// - The runs, or an error if the query fails.
+func (d Datasource) ListReportRuns(ctx context.Context, reportID string, page model.Page) ([]*model.ReportRun, error) {
+	ctx, span := otel.Tracer("report.database").Start(ctx, "Listing report runs")
+	defer span.End()
+
+	condition, suffix, args := reportRunKeyset.page(page, []interface{}{reportID})
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT run_id, report_id, trigger, status, columns, '[]'::jsonb, row_count, truncated, storage_key, error, started_at, completed_at
+		FROM blnk.report_runs
+		WHERE report_id = $1 AND `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve report runs", err)
+	}
+	defer rows.Close()
+
+	runs := []*model.ReportRun{}
+	for rows.Next() {
+		run, err := scanReportRun(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan report run", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over report runs", err)
+	}
+	return runs, nil
+}
+
+func collectReportDefinitions(rows *sql.Rows) ([]*model.ReportDefinition, error) {
+	defer rows.Close()
+
+	definitions := []*model.ReportDefinition{}
+	for rows.Next() {
+		definition, err := scanReportDefinition(rows)
+		if err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan report definition", err)
+		}
+		definitions = append(definitions, definition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over report definitions", err)
+	}
+	return definitions, nil
+}
+
+func marshalReportDefinition(definition *model.ReportDefinition) ([]byte, []byte, []byte, error) {
+	filters, err := json.Marshal(nonNil(definition.Filters))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	groupBy, err := json.Marshal(nonNil(definition.GroupBy))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	aggregates, err := json.Marshal(nonNil(definition.Aggregates))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return filters, groupBy, aggregates, nil
+}
+
+func marshalReportRun(run *model.ReportRun) ([]byte, []byte, error) {
+	columns, err := json.Marshal(nonNil(run.Columns))
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := json.Marshal(nonNil(run.Rows))
+	if err != nil {
+		return nil, nil, err
+	}
+	return columns, rows, nil
+}
+
+// nonNil stores empty lists as [] rather than null.
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
+
+func scanReportDefinition(row rowScanner) (*model.ReportDefinition, error) {
+	definition := &model.ReportDefinition{}
+	var filters, groupBy, aggregates []byte
+	var window, schedule, destination sql.NullString
+	err := row.Scan(
+		&definition.ReportID, &definition.Name, &definition.Source, &filters, &groupBy, &aggregates,
+		&window, &schedule, &definition.Format, &destination, &definition.IsActive,
+		&definition.NextRunAt, &definition.LastRunAt, &definition.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	definition.Window, definition.Schedule, definition.Destination = window.String, schedule.String, destination.String
+	if err := json.Unmarshal(filters, &definition.Filters); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(groupBy, &definition.GroupBy); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(aggregates, &definition.Aggregates); err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+func scanReportRun(row rowScanner) (*model.ReportRun, error) {
+	run := &model.ReportRun{}
+	var columns, rows []byte
+	var storageKey, runError sql.NullString
+	err := row.Scan(
+		&run.RunID, &run.ReportID, &run.Trigger, &run.Status, &columns, &rows, &run.RowCount, &run.Truncated,
+		&storageKey, &runError, &run.StartedAt, &run.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	run.StorageKey, run.Error = storageKey.String, runError.String
+	if err := json.Unmarshal(columns, &run.Columns); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(rows, &run.Rows); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildReportQuery(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	definition := &model.ReportDefinition{
+		Source: model.ReportSourceTransactions,
+		Filters: []model.ReportFilter{
+			{Field: "status", Operator: "==", Value: "APPLIED"},
+			{Field: "currency", Operator: "in", Values: []string{"USD", "EUR"}},
+		},
+		GroupBy:    []string{"currency", "created_date"},
+		Aggregates: []model.ReportAggregate{{Function: "count"}, {Function: "avg", Field: "precise_amount"}},
+		Window:     "24h",
+	}
+
+	query, args, err := buildReportQuery(definition, now, 100)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT (currency)::text, (to_char(created_at, 'YYYY-MM-DD'))::text, COUNT(*)::text, ROUND(AVG(precise_amount), 2)::text "+
+		"FROM blnk.transactions WHERE status = $1 AND currency = ANY($2) AND created_at >= $3 "+
+		"GROUP BY 1, 2 ORDER BY 1, 2 LIMIT $4", query)
+	assert.Equal(t, []interface{}{"APPLIED", pq.Array([]string{"USD", "EUR"}), now.Add(-24 * time.Hour), 101}, args)
+}
+
+func TestBuildReportQuery_RejectsUnknownFields(t *testing.T) {
+	definition := &model.ReportDefinition{
+		Source:     model.ReportSourceBalances,
+		GroupBy:    []string{"currency; DROP TABLE blnk.balances"},
+		Aggregates: []model.ReportAggregate{{Function: "count"}},
+	}
+	_, _, err := buildReportQuery(definition, time.Now(), 100)
+	assert.Error(t, err)
+}
+
+func TestRunReportQuery_Truncates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	definition := &model.ReportDefinition{
+		Source:     model.ReportSourceBalances,
+		GroupBy:    []string{"ledger_id"},
+		Aggregates: []model.ReportAggregate{{Function: "sum", Field: "balance", Alias: "total"}},
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT (ledger_id)::text, SUM(balance)::text FROM blnk.balances GROUP BY 1 ORDER BY 1 LIMIT $1")).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"ledger_id", "total"}).
+			AddRow("ldg_a", "100").
+			AddRow(nil, "5").
+			AddRow("ldg_c", "7"))
+
+	columns, rows, truncated, err := ds.RunReportQuery(context.Background(), definition, time.Now(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ledger_id", "total"}, columns)
+	assert.Equal(t, [][]string{{"ldg_a", "100"}, {"", "5"}}, rows)
+	assert.True(t, truncated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetReportDefinition(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	created := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.report_definitions")).
+		WithArgs("report_1").
+		WillReturnRows(sqlmock.NewRows([]string{"report_id", "name", "source", "filters", "group_by", "aggregates", "window_duration", "schedule", "format", "destination", "is_active", "next_run_at", "last_run_at", "created_at"}).
+			AddRow("report_1", "Volume", "transactions", []byte(`[{"field":"status","operator":"==","value":"APPLIED"}]`), []byte(`["currency"]`),
+				[]byte(`[{"function":"count"}]`), nil, "daily", "csv", "s3", true, created.AddDate(0, 0, 1), nil, created))
+
+	definition, err := ds.GetReportDefinition(context.Background(), "report_1")
+	require.NoError(t, err)
+	assert.Equal(t, []model.ReportFilter{{Field: "status", Operator: "==", Value: "APPLIED"}}, definition.Filters)
+	assert.Equal(t, []string{"currency"}, definition.GroupBy)
+	assert.Equal(t, "daily", definition.Schedule)
+	assert.Equal(t, "", definition.Window)
+	assert.Equal(t, model.ReportDestinationS3, definition.Destination)
+	require.NotNil(t, definition.NextRunAt)
+	assert.Nil(t, definition.LastRunAt)
+}
+
+func TestGetReportRun_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.report_runs")).
+		WithArgs("report_run_1").
+		WillReturnRows(sqlmock.NewRows([]string{"run_id"}))
+
+	_, err = ds.GetReportRun(context.Background(), "report_run_1")
+	assert.ErrorContains(t, err, "not found")
+}
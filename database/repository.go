@@ -21,121 +21,420 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
 	"github.com/blnkfinance/blnk/model"
 )
 
 // IDataSource defines the interface for data source operations, grouping related functionalities.
 type IDataSource interface {
-	transaction    // Interface for transaction-related operations
-	ledger         // Interface for ledger-related operations
-	balance        // Interface for balance-related operations
-	identity       // Interface for identity-related operations
-	balanceMonitor // Interface for balance monitoring operations
-	account        // Interface for account-related operations
-	reconciliation // Interface for reconciliation-related operations
-	apikey         // Interface for API key operations
+	transaction       // Interface for transaction-related operations
+	ledger            // Interface for ledger-related operations
+	balance           // Interface for balance-related operations
+	identity          // Interface for identity-related operations
+	balanceMonitor    // Interface for balance monitoring operations
+	notificationPrefs // Interface for balance notification preference operations
+	account           // Interface for account-related operations
+	reconciliation    // Interface for reconciliation-related operations
+	apikey            // Interface for API key operations
+	serviceAccount    // Interface for service account operations
+	netting           // Interface for deferred net settlement operations
+	cardAuthorization // Interface for card authorization lifecycle operations
+	statement         // Interface for statement operations
+	integrity         // Interface for ledger integrity checks
+	schemaAdvisor     // Interface for schema and index diagnostics
+	tableStats        // Interface for table statistics
+	dormancy          // Interface for dormancy and escheatment operations
+	requestLog        // Interface for request log operations
+	balanceSharding   // Interface for sharded balance operations
+	replay            // Interface for ledger replay operations
+	warehouseSync     // Interface for warehouse sync operations
+	complianceExport  // Interface for compliance export operations
+	ledgerSequence    // Interface for ledger sequence numbers
+	challenge         // Interface for transaction challenge operations
+	report            // Interface for saved report operations
+	minimumBalance    // Interface for minimum balance operations
+	unitOfWork        // Interface for writing sessions atomically
+	eod               // Interface for end-of-day run operations
+	postingRules      // Interface for ledger posting rule operations
+	balanceAlias      // Interface for balance alias operations
+	identityScreening // Interface for identity screening operations
+	webhookDelivery   // Interface for webhook delivery records
 }
 
 // transaction defines methods for handling transactions.
 type transaction interface {
-	RecordTransaction(cxt context.Context, txn *model.Transaction) (*model.Transaction, error)                                                      // Records a new transaction
-	GetTransaction(cxt context.Context, id string) (*model.Transaction, error)                                                                      // Retrieves a transaction by ID
-	IsParentTransactionVoid(cxt context.Context, parentID string) (bool, error)                                                                     // Checks if a parent transaction is void
-	GetTransactionByRef(cxt context.Context, reference string) (model.Transaction, error)                                                           // Retrieves a transaction by reference
-	TransactionExistsByRef(ctx context.Context, reference string) (bool, error)                                                                     // Checks if a transaction exists by reference
-	UpdateTransactionStatus(cxt context.Context, id string, status string) error                                                                    // Updates the status of a transaction
-	GetAllTransactions(cxt context.Context, limit, offset int) ([]model.Transaction, error)                                                         // Retrieves all transactions
-	GetTotalCommittedTransactions(cxt context.Context, parentID string) (*big.Int, error)                                                           // Gets the total count of committed transactions for a parent
-	GetTransactionsPaginated(ctx context.Context, id string, batchSize int, offset int64) ([]*model.Transaction, error)                             // Retrieves transactions in a paginated manner
-	GetInflightTransactionsByParentID(ctx context.Context, parentTransactionID string, batchSize int, offset int64) ([]*model.Transaction, error)   // Retrieves inflight transactions by parent ID
-	GetRefundableTransactionsByParentID(ctx context.Context, parentTransactionID string, batchSize int, offset int64) ([]*model.Transaction, error) // Retrieves refundable transactions by parent ID
-	GroupTransactions(ctx context.Context, groupCriteria string, batchSize int, offset int64) (map[string][]*model.Transaction, error)              // Groups transactions based on specified criteria
-	UpdateLedgerMetadata(id string, metadata map[string]interface{}) error
+	RecordTransaction(cxt context.Context, txn *model.Transaction) (*model.Transaction, error)                                          // Records a new transaction
+	GetTransaction(cxt context.Context, id string) (*model.Transaction, error)                                                          // Retrieves a transaction by ID
+	IsParentTransactionVoid(cxt context.Context, parentID string) (bool, error)                                                         // Checks if a parent transaction is void
+	GetTransactionByRef(cxt context.Context, reference string) (model.Transaction, error)                                               // Retrieves a transaction by reference
+	TransactionExistsByRef(ctx context.Context, reference string) (bool, error)                                                         // Checks if a transaction exists by reference
+	UpdateTransactionStatus(cxt context.Context, id string, status string) error                                                        // Updates the status of a transaction
+	GetAllTransactions(cxt context.Context, page model.Page) ([]model.Transaction, error)                                               // Retrieves all transactions
+	GetTotalCommittedTransactions(cxt context.Context, parentID string) (*big.Int, error)                                               // Gets the total count of committed transactions for a parent
+	GetTransactionsPaginated(ctx context.Context, id string, page model.Page) ([]*model.Transaction, error)                             // Retrieves transactions in a paginated manner
+	GetInflightTransactionsByParentID(ctx context.Context, parentTransactionID string, page model.Page) ([]*model.Transaction, error)   // Retrieves inflight transactions by parent ID
+	GetRefundableTransactionsByParentID(ctx context.Context, parentTransactionID string, page model.Page) ([]*model.Transaction, error) // Retrieves refundable transactions by parent ID
+	GroupTransactions(ctx context.Context, groupCriteria string, page model.Page) (map[string][]*model.Transaction, error)              // Groups transactions based on specified criteria
+	UpdateLedgerMetadata(ctx context.Context, id string, metadata map[string]interface{}) error
 	UpdateTransactionMetadata(ctx context.Context, id string, metadata map[string]interface{}) error
 	UpdateBalanceMetadata(ctx context.Context, id string, metadata map[string]interface{}) error
-	UpdateIdentityMetadata(id string, metadata map[string]interface{}) error
+	UpdateIdentityMetadata(ctx context.Context, id string, metadata map[string]interface{}) error
+	IncrementMetadata(ctx context.Context, entityType, id, key string, by float64) (map[string]interface{}, error) // Atomically adds to a numeric metadata key
 	TransactionExistsByIDOrParentID(ctx context.Context, id string) (bool, error)
-	GetTransactionsByParent(ctx context.Context, parentID string, limit int, offset int64) ([]*model.Transaction, error) // Retrieves transactions by parent ID with pagination
-	IsTransactionRefunded(ctx context.Context, transaction *model.Transaction) (bool, error)                             // Checks if a transaction has already been refunded
+	GetTransactionsByParent(ctx context.Context, parentID string, page model.Page) ([]*model.Transaction, error)                              // Retrieves a page of transactions by parent ID
+	IsTransactionRefunded(ctx context.Context, transaction *model.Transaction) (bool, error)                                                  // Checks if a transaction has already been refunded
+	GetTransactionsCreatedBetween(ctx context.Context, start, end time.Time, page model.Page) ([]*model.Transaction, error)                   // Retrieves transactions created within a time window
+	GetBalanceTransactionsBetween(ctx context.Context, balanceID string, start, end time.Time, page model.Page) ([]*model.Transaction, error) // Retrieves applied transactions of a balance within a time window
+	GetTransactionsByGroup(ctx context.Context, groupID string, page model.Page) ([]*model.Transaction, error)                                // Retrieves the transactions of a group with pagination
+	GetTransactionsByIdentity(ctx context.Context, identityID string, page model.Page) ([]*model.Transaction, error)                          // Retrieves the transactions of an identity's balances with pagination
+	RecordScheduledTransactionFailure(ctx context.Context, failure *model.ScheduledTransactionFailure) error                                  // Saves a scheduled transaction that failed permanently
+	GetScheduledTransactionFailures(ctx context.Context, start, end time.Time, page model.Page) ([]*model.ScheduledTransactionFailure, error) // Retrieves permanently failed scheduled transactions
+	GetPendingInflightCredits(ctx context.Context, balanceID string, limit int) ([]*model.PendingInflightCredit, error)                       // Retrieves inflight credits of a balance that have not fully landed
+	GetTransactionStatusHistory(ctx context.Context, transactionID string) ([]*model.TransactionStatusEntry, error)                           // Retrieves the statuses a transaction and the transactions it led to went through
 }
 
 // ledger defines methods for handling ledgers.
 type ledger interface {
-	CreateLedger(ledger model.Ledger) (model.Ledger, error) // Creates a new ledger
-	GetAllLedgers(limit, offset int) ([]model.Ledger, error)
-	GetLedgerByID(id string) (*model.Ledger, error) // Retrieves a ledger by ID
+	CreateLedger(ctx context.Context, ledger model.Ledger) (model.Ledger, error) // Creates a new ledger
+	GetAllLedgers(ctx context.Context, page model.Page) ([]model.Ledger, error)
+	GetLedgerByID(ctx context.Context, id string) (*model.Ledger, error) // Retrieves a ledger by ID
 }
 
 // balance defines methods for handling balances.
 type balance interface {
-	CreateBalance(balance model.Balance) (model.Balance, error)                                                            // Creates a new balance
-	GetBalanceByID(id string, include []string, withQueued bool) (*model.Balance, error)                                   // Retrieves a balance by ID with additional data and queued status
-	GetBalanceByIDLite(id string) (*model.Balance, error)                                                                  // Retrieves a balance by ID with minimal data
-	GetAllBalances(limit, offset int) ([]model.Balance, error)                                                             // Retrieves all balances
-	UpdateBalance(balance *model.Balance) error                                                                            // Updates a balance
-	GetBalanceByIndicator(indicator, currency string) (*model.Balance, error)                                              // Retrieves a balance by indicator and currency
+	CreateBalance(ctx context.Context, balance model.Balance) (model.Balance, error)                                       // Creates a new balance
+	GetBalanceByID(ctx context.Context, id string, include []string, withQueued bool) (*model.Balance, error)              // Retrieves a balance by ID with additional data and queued status
+	GetBalanceByIDLite(ctx context.Context, id string) (*model.Balance, error)                                             // Retrieves a balance by ID with minimal data
+	GetAllBalances(ctx context.Context, page model.Page) ([]model.Balance, error)                                          // Retrieves all balances
+	UpdateBalance(ctx context.Context, balance *model.Balance) error                                                       // Updates a balance
+	GetBalanceByIndicator(ctx context.Context, indicator, currency string) (*model.Balance, error)                         // Retrieves a balance by indicator and currency
 	UpdateBalances(ctx context.Context, sourceBalance, destinationBalance *model.Balance) error                            // Updates multiple balances
-	GetSourceDestination(sourceId, destinationId string) ([]*model.Balance, error)                                         // Retrieves balances between source and destination
+	GetSourceDestination(ctx context.Context, sourceId, destinationId string) ([]*model.Balance, error)                    // Retrieves balances between source and destination
 	TakeBalanceSnapshots(ctx context.Context, batchSize int) (int, error)                                                  // Takes balance snapshots
 	GetBalanceAtTime(ctx context.Context, balanceID string, targetTime time.Time, fromSource bool) (*model.Balance, error) // Retrieves a balance at a specific time
-	UpdateBalanceIdentity(balanceID string, identityID string) error                                                       // Updates only the identity_id of a balance
+	UpdateBalanceIdentity(ctx context.Context, balanceID string, identityID string) error                                  // Updates only the identity_id of a balance
+	GetBalancesByIdentity(ctx context.Context, identityID string) ([]model.Balance, error)                                 // Retrieves all balances of an identity
+	GetBalancesByLedger(ctx context.Context, ledgerID string) ([]model.Balance, error)                                     // Retrieves all balances of a ledger
+}
+
+// notificationPrefs defines methods for the notification preferences of balances.
+type notificationPrefs interface {
+	UpsertBalanceNotificationPreference(ctx context.Context, preference *model.BalanceNotificationPreference) error       // Saves the notification preference of a balance
+	GetBalanceNotificationPreference(ctx context.Context, balanceID string) (*model.BalanceNotificationPreference, error) // Retrieves the notification preference of a balance
+	ListBalanceNotificationPreferences(ctx context.Context) ([]*model.BalanceNotificationPreference, error)               // Lists the notification preferences of all balances
+	DeleteBalanceNotificationPreference(ctx context.Context, balanceID string) error                                      // Removes the notification preference of a balance
 }
 
 // account defines methods for handling accounts.
 type account interface {
-	CreateAccount(account model.Account) (model.Account, error)         // Creates a new account
-	GetAccountByID(id string, include []string) (*model.Account, error) // Retrieves an account by ID with additional data
-	GetAllAccounts() ([]model.Account, error)                           // Retrieves all accounts
-	GetAccountByNumber(number string) (*model.Account, error)           // Retrieves an account by its number
-	UpdateAccount(account *model.Account) error                         // Updates an account
-	DeleteAccount(id string) error                                      // Deletes an account
+	CreateAccount(ctx context.Context, account model.Account) (model.Account, error)         // Creates a new account
+	GetAccountByID(ctx context.Context, id string, include []string) (*model.Account, error) // Retrieves an account by ID with additional data
+	GetAllAccounts(ctx context.Context) ([]model.Account, error)                             // Retrieves all accounts
+	GetAccountByNumber(ctx context.Context, number string) (*model.Account, error)           // Retrieves an account by its number
+	UpdateAccount(ctx context.Context, account *model.Account) error                         // Updates an account
+	DeleteAccount(ctx context.Context, id string) error                                      // Deletes an account
 }
 
 // balanceMonitor defines methods for monitoring balances.
 type balanceMonitor interface {
-	CreateMonitor(monitor model.BalanceMonitor) (model.BalanceMonitor, error) // Creates a new balance monitor
-	GetMonitorByID(id string) (*model.BalanceMonitor, error)                  // Retrieves a balance monitor by ID
-	GetAllMonitors() ([]model.BalanceMonitor, error)                          // Retrieves all balance monitors
-	GetBalanceMonitors(balanceID string) ([]model.BalanceMonitor, error)      // Retrieves monitors for a specific balance
-	UpdateMonitor(monitor *model.BalanceMonitor) error                        // Updates a balance monitor
-	DeleteMonitor(id string) error                                            // Deletes a balance monitor
+	CreateMonitor(ctx context.Context, monitor model.BalanceMonitor) (model.BalanceMonitor, error) // Creates a new balance monitor
+	GetMonitorByID(ctx context.Context, id string) (*model.BalanceMonitor, error)                  // Retrieves a balance monitor by ID
+	GetAllMonitors(ctx context.Context) ([]model.BalanceMonitor, error)                            // Retrieves all balance monitors
+	GetBalanceMonitors(ctx context.Context, balanceID string) ([]model.BalanceMonitor, error)      // Retrieves monitors for a specific balance
+	UpdateMonitor(ctx context.Context, monitor *model.BalanceMonitor) error                        // Updates a balance monitor
+	DeleteMonitor(ctx context.Context, id string) error                                            // Deletes a balance monitor
 }
 
 // identity defines methods for handling identities.
 type identity interface {
-	CreateIdentity(identity model.Identity) (model.Identity, error) // Creates a new identity
-	GetIdentityByID(id string) (*model.Identity, error)             // Retrieves an identity by ID
-	GetAllIdentities() ([]model.Identity, error)                    // Retrieves all identities
-	UpdateIdentity(identity *model.Identity) error                  // Updates an identity
-	DeleteIdentity(id string) error                                 // Deletes an identity
+	CreateIdentity(ctx context.Context, identity model.Identity) (model.Identity, error)                                   // Creates a new identity
+	GetIdentityByID(ctx context.Context, id string) (*model.Identity, error)                                               // Retrieves an identity by ID
+	GetIdentityByIDIncludingDeleted(ctx context.Context, id string) (*model.Identity, error)                               // Retrieves an identity by ID, even if deleted
+	GetAllIdentities(ctx context.Context) ([]model.Identity, error)                                                        // Retrieves all identities
+	GetIdentities(ctx context.Context, filter model.IdentityFilter, page model.Page) ([]model.Identity, error)             // Retrieves the identities matching a filter
+	UpdateIdentity(ctx context.Context, identity *model.Identity, changedBy string) error                                  // Updates an identity, recording its previous version
+	DeleteIdentity(ctx context.Context, id string) error                                                                   // Soft-deletes an identity
+	RestoreIdentity(ctx context.Context, id string) error                                                                  // Restores a deleted identity
+	UpdateIdentityVerification(ctx context.Context, id, from, to, reason string, at time.Time) error                       // Moves an identity's verification to a new status
+	UpdateIdentityStatus(ctx context.Context, id, from, to, reason string, at time.Time) error                             // Moves an identity to a new lifecycle status
+	ListInactiveIdentityIDs(ctx context.Context) ([]string, error)                                                         // Retrieves the IDs of blocked and closed identities
+	UpdateIdentityRisk(ctx context.Context, id string, score float64, level string, at time.Time) error                    // Records the risk score and level of an identity
+	CreateIdentityDocument(ctx context.Context, document *model.IdentityDocument) error                                    // Saves the metadata of an identity document
+	GetIdentityDocument(ctx context.Context, documentID string) (*model.IdentityDocument, error)                           // Retrieves an identity document by ID
+	GetIdentityDocuments(ctx context.Context, identityID string) ([]*model.IdentityDocument, error)                        // Retrieves the documents of an identity
+	UpdateIdentityDocumentVerification(ctx context.Context, documentID, status, reason string, at time.Time) error         // Records the review of a pending identity document
+	FindDuplicateIdentities(ctx context.Context, identity *model.Identity) ([]model.Identity, error)                       // Retrieves the likely duplicates of an identity
+	MergeIdentities(ctx context.Context, merge *model.IdentityMerge) error                                                 // Moves an identity's balances to another and deletes it
+	GetIdentityMerges(ctx context.Context, identityID string) ([]*model.IdentityMerge, error)                              // Retrieves the merges an identity took part in
+	GetIdentityHistory(ctx context.Context, identityID string) ([]*model.IdentityHistoryEntry, error)                      // Retrieves the previous versions of an identity
+	CreateIdentities(ctx context.Context, identities []*model.Identity) error                                              // Creates a batch of identities at once
+	AnonymizeIdentity(ctx context.Context, erasure *model.IdentityErasure) error                                           // Erases an identity's personal fields and records the erasure
+	GetIdentityErasure(ctx context.Context, identityID string) (*model.IdentityErasure, error)                             // Retrieves the receipt of an identity's erasure
+	CreateIdentityRelationship(ctx context.Context, relationship *model.IdentityRelationship) error                        // Saves a relationship between two identities
+	GetIdentityRelationship(ctx context.Context, relationshipID string) (*model.IdentityRelationship, error)               // Retrieves an identity relationship by ID
+	UpdateIdentityRelationship(ctx context.Context, relationship *model.IdentityRelationship) error                        // Updates the role and metadata of an identity relationship
+	DeleteIdentityRelationship(ctx context.Context, relationshipID string) error                                           // Deletes an identity relationship
+	GetRelatedIdentities(ctx context.Context, identityID string) ([]*model.RelatedIdentity, error)                         // Retrieves the identities related to an identity
+	CreateIdentityAddress(ctx context.Context, address *model.IdentityAddress) error                                       // Saves an address of an identity
+	GetIdentityAddress(ctx context.Context, addressID string) (*model.IdentityAddress, error)                              // Retrieves an identity address by ID
+	GetIdentityAddresses(ctx context.Context, identityID string) ([]*model.IdentityAddress, error)                         // Retrieves the addresses of an identity
+	UpdateIdentityAddress(ctx context.Context, address *model.IdentityAddress) error                                       // Updates the type, fields and metadata of an identity address
+	DeleteIdentityAddress(ctx context.Context, addressID string) error                                                     // Deletes an identity address
+	AddIdentityTags(ctx context.Context, id string, tags []string) ([]string, error)                                       // Adds tags to an identity, returning its tags
+	RemoveIdentityTags(ctx context.Context, id string, tags []string) ([]string, error)                                    // Removes tags from an identity, returning its tags
+	CreateExternalAccount(ctx context.Context, account *model.ExternalAccount) error                                       // Saves an external account
+	GetExternalAccount(ctx context.Context, externalAccountID string) (*model.ExternalAccount, error)                      // Retrieves an external account by ID
+	GetExternalAccounts(ctx context.Context, identityID string) ([]*model.ExternalAccount, error)                          // Retrieves the external accounts of an identity
+	UpdateExternalAccountVerification(ctx context.Context, externalAccountID, from, to, reason string, at time.Time) error // Records the review of an external account
+	CreateContactVerification(ctx context.Context, verification *model.ContactVerification) error                          // Saves a verification code sent to an identity's email address or phone number
+	GetPendingContactVerification(ctx context.Context, identityID, channel string) (*model.ContactVerification, error)     // Retrieves the latest unconfirmed verification of an identity's channel
+	IncrementContactVerificationAttempts(ctx context.Context, verificationID string) (int, error)                          // Counts a wrong code entered for a verification
+	ConfirmContactVerification(ctx context.Context, verification *model.ContactVerification) error                         // Confirms a verification and marks the identity's contact verified
 }
 
 // reconciliation defines methods for handling reconciliation processes.
 type reconciliation interface {
-	RecordReconciliation(ctx context.Context, rec *model.Reconciliation) error                                                                                          // Records a new reconciliation
-	GetReconciliation(ctx context.Context, id string) (*model.Reconciliation, error)                                                                                    // Retrieves a reconciliation by ID
-	UpdateReconciliationStatus(ctx context.Context, id string, status string, matchedCount, unmatchedCount int) error                                                   // Updates the status of a reconciliation
-	GetReconciliationsByUploadID(ctx context.Context, uploadID string) ([]*model.Reconciliation, error)                                                                 // Retrieves reconciliations by upload ID
-	RecordMatch(ctx context.Context, match *model.Match) error                                                                                                          // Records a match in reconciliation
-	GetMatchesByReconciliationID(ctx context.Context, reconciliationID string) ([]*model.Match, error)                                                                  // Retrieves matches by reconciliation ID
-	GetExternalTransactionsPaginated(ctx context.Context, uploadID string, batchSize int, offset int64) ([]*model.ExternalTransaction, error)                           // Retrieves external transactions in a paginated manner
-	RecordExternalTransaction(ctx context.Context, tx *model.ExternalTransaction, reconciliationID string) error                                                        // Records an external transaction
-	RecordMatchingRule(ctx context.Context, rule *model.MatchingRule) error                                                                                             // Records a matching rule
-	GetMatchingRules(ctx context.Context) ([]*model.MatchingRule, error)                                                                                                // Retrieves all matching rules
-	GetMatchingRule(ctx context.Context, id string) (*model.MatchingRule, error)                                                                                        // Retrieves a matching rule by ID
-	UpdateMatchingRule(ctx context.Context, rule *model.MatchingRule) error                                                                                             // Updates a matching rule
-	DeleteMatchingRule(ctx context.Context, id string) error                                                                                                            // Deletes a matching rule
-	SaveReconciliationProgress(ctx context.Context, reconciliationID string, progress model.ReconciliationProgress) error                                               // Saves reconciliation progress
-	LoadReconciliationProgress(ctx context.Context, reconciliationID string) (model.ReconciliationProgress, error)                                                      // Loads reconciliation progress
-	RecordMatches(ctx context.Context, reconciliationID string, matches []model.Match) error                                                                            // Records matches for a reconciliation
-	RecordUnmatched(ctx context.Context, reconciliationID string, results []string) error                                                                               // Records unmatched results for a reconciliation
-	FetchAndGroupExternalTransactions(ctx context.Context, uploadID string, groupCriteria string, batchSize int, offset int64) (map[string][]*model.Transaction, error) // Fetches and groups external transactions based on criteria
+	RecordReconciliation(ctx context.Context, rec *model.Reconciliation) error                                                                              // Records a new reconciliation
+	GetReconciliation(ctx context.Context, id string) (*model.Reconciliation, error)                                                                        // Retrieves a reconciliation by ID
+	UpdateReconciliationStatus(ctx context.Context, id string, status string, matchedCount, unmatchedCount int) error                                       // Updates the status of a reconciliation
+	GetReconciliationsByUploadID(ctx context.Context, uploadID string) ([]*model.Reconciliation, error)                                                     // Retrieves reconciliations by upload ID
+	RecordMatch(ctx context.Context, match *model.Match) error                                                                                              // Records a match in reconciliation
+	GetMatchesByReconciliationID(ctx context.Context, reconciliationID string) ([]*model.Match, error)                                                      // Retrieves matches by reconciliation ID
+	GetMatchesByStatus(ctx context.Context, reconciliationID, status string) ([]*model.Match, error)                                                        // Retrieves matches of a reconciliation in a given status
+	UpdateMatchStatus(ctx context.Context, reconciliationID, externalTxnID, internalTxnID, status string) error                                             // Updates the status of a recorded match
+	GetMatchScoreDistribution(ctx context.Context, reconciliationID string) (*model.ScoreDistribution, error)                                               // Aggregates match confidence scores for a reconciliation
+	GetExternalTransactionsPaginated(ctx context.Context, uploadID string, page model.Page) ([]*model.ExternalTransaction, error)                           // Retrieves external transactions in a paginated manner
+	RecordExternalTransaction(ctx context.Context, tx *model.ExternalTransaction, reconciliationID string) error                                            // Records an external transaction
+	RecordExternalTransactions(ctx context.Context, txns []*model.ExternalTransaction, uploadID string) ([]string, error)                                   // Records a batch of external transactions, skipping IDs already recorded
+	GetUnmatchedExternalTransactions(ctx context.Context, reconciliationID string) ([]*model.ExternalTransaction, error)                                    // Retrieves the external transactions left unmatched by a reconciliation
+	RecordMatchingRule(ctx context.Context, rule *model.MatchingRule) error                                                                                 // Records a matching rule
+	GetMatchingRules(ctx context.Context) ([]*model.MatchingRule, error)                                                                                    // Retrieves all matching rules
+	GetMatchingRule(ctx context.Context, id string) (*model.MatchingRule, error)                                                                            // Retrieves a matching rule by ID
+	UpdateMatchingRule(ctx context.Context, rule *model.MatchingRule) error                                                                                 // Updates a matching rule
+	DeleteMatchingRule(ctx context.Context, id string) error                                                                                                // Deletes a matching rule
+	SaveReconciliationProgress(ctx context.Context, reconciliationID string, progress model.ReconciliationProgress) error                                   // Saves reconciliation progress
+	LoadReconciliationProgress(ctx context.Context, reconciliationID string) (model.ReconciliationProgress, error)                                          // Loads reconciliation progress
+	RecordMatches(ctx context.Context, reconciliationID string, matches []model.Match) error                                                                // Records matches for a reconciliation
+	RecordUnmatched(ctx context.Context, reconciliationID string, results []string) error                                                                   // Records unmatched results for a reconciliation
+	FetchAndGroupExternalTransactions(ctx context.Context, uploadID string, groupCriteria string, page model.Page) (map[string][]*model.Transaction, error) // Fetches and groups external transactions based on criteria
+	RecordStatementIngestion(ctx context.Context, ingestion *model.StatementIngestion) (bool, error)                                                        // Records a statement file unless it was ingested before
+	UpdateStatementIngestion(ctx context.Context, ingestion *model.StatementIngestion) error                                                                // Saves the outcome of a statement ingestion
+	ListStatementIngestions(ctx context.Context, page model.Page) ([]*model.StatementIngestion, error)                                                      // Lists statement ingestions
 }
 
 type apikey interface {
 	CreateAPIKey(ctx context.Context, name, ownerID string, scopes []string, expiresAt time.Time) (*model.APIKey, error) // Creates a new API key
 	GetAPIKey(ctx context.Context, key string) (*model.APIKey, error)                                                    // Retrieves an API key by its key string
 	RevokeAPIKey(ctx context.Context, id, ownerID string) error                                                          // Revokes an API key
-	ListAPIKeys(ctx context.Context, ownerID string) ([]*model.APIKey, error)                                            // Lists all API keys for a specific owner
+	ListAPIKeys(ctx context.Context, ownerID string, page model.Page) ([]*model.APIKey, error)                           // Lists a page of API keys for a specific owner
 	UpdateLastUsed(ctx context.Context, id string) error                                                                 // Updates the last_used_at timestamp for an API key
+	RotateAPIKey(ctx context.Context, id, ownerID string, overlap time.Duration) (*model.APIKey, error)                  // Issues a replacement key and keeps the old one valid for the overlap period
+	ExpireAPIKeyRotation(ctx context.Context, id, ownerID string) error                                                  // Ends the overlap window of a rotated API key
+	UpdateAPIKeyNetworkPolicy(ctx context.Context, id, ownerID string, policy model.NetworkPolicy) error                 // Replaces the IP allow-list and blocked countries of an API key
+	UpdateAPIKeyLedgerScope(ctx context.Context, id, ownerID string, scope ledgerscope.Scope) error                      // Replaces the ledgers and balance ID prefixes an API key is restricted to
+	UpdateAPIKeyFieldCompatibility(ctx context.Context, id, ownerID, mode string) error                                  // Sets the names renamed JSON fields are emitted under for an API key
+	RecordAPIKeyAccessDenial(ctx context.Context, denial *model.APIKeyAccessDenial) error                                // Saves a request rejected by an API key's network policy
+	ListAPIKeyAccessDenials(ctx context.Context, id, ownerID string, limit int) ([]*model.APIKeyAccessDenial, error)     // Lists the most recent rejected requests for an API key
+}
+
+// serviceAccount defines methods for managing service accounts.
+type serviceAccount interface {
+	CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) error            // Saves a new service account
+	GetServiceAccount(ctx context.Context, id string) (*model.ServiceAccount, error)          // Retrieves a service account by ID
+	ListServiceAccounts(ctx context.Context, ownerID string) ([]*model.ServiceAccount, error) // Lists the service accounts of an owner
+	RevokeServiceAccount(ctx context.Context, id, ownerID string) error                       // Revokes a service account and every token issued to it
+	UpdateServiceAccountTokenIssued(ctx context.Context, id string) error                     // Records when a token was last issued to a service account
+}
+
+// netting defines methods for deferred net settlement between groups of balances.
+type netting interface {
+	CreateNettingGroup(ctx context.Context, group *model.NettingGroup) error                                             // Saves a new netting group
+	GetNettingGroup(ctx context.Context, groupID string) (*model.NettingGroup, error)                                    // Retrieves a netting group by ID
+	ListNettingGroups(ctx context.Context, page model.Page) ([]*model.NettingGroup, error)                               // Lists a page of netting groups
+	UpdateNettingGroupStatus(ctx context.Context, groupID string, active bool) error                                     // Activates or deactivates a netting group
+	RecordNettingEntry(ctx context.Context, entry *model.NettingEntry) error                                             // Saves the memo record of a deferred posting
+	ListNettingEntries(ctx context.Context, groupID, status string, page model.Page) ([]*model.NettingEntry, error)      // Lists the entries of a netting group
+	GetBalanceNettingEntries(ctx context.Context, balanceID string, start, end time.Time) ([]*model.NettingEntry, error) // Retrieves the entries a balance took part in during a period
+	CreateNettingSettlement(ctx context.Context, settlement *model.NettingSettlement) ([]*model.NettingEntry, error)     // Saves a settlement and claims the pending entries before its cutoff
+	UpdateNettingSettlement(ctx context.Context, settlement *model.NettingSettlement) error                              // Records the transfers and outcome of a settlement
+	GetNettingSettlement(ctx context.Context, settlementID string) (*model.NettingSettlement, error)                     // Retrieves a netting settlement by ID
+	ListNettingSettlements(ctx context.Context, groupID string) ([]*model.NettingSettlement, error)                      // Lists the settlements of a netting group
+}
+
+// cardAuthorization defines methods for tracking card authorizations.
+type cardAuthorization interface {
+	CreateCardAuthorization(ctx context.Context, auth *model.CardAuthorization) error                               // Saves a new card authorization
+	GetCardAuthorization(ctx context.Context, authorizationID string) (*model.CardAuthorization, error)             // Retrieves a card authorization by ID
+	UpdateCardAuthorization(ctx context.Context, auth *model.CardAuthorization) error                               // Saves the lifecycle state of a card authorization
+	GetExpiredCardAuthorizations(ctx context.Context, now time.Time, limit int) ([]*model.CardAuthorization, error) // Retrieves authorized authorizations past their expiry
+}
+
+// statement defines methods for scheduled account statements.
+type statement interface {
+	CreateStatementSchedule(ctx context.Context, schedule *model.StatementSchedule) error                       // Creates a statement schedule
+	GetStatementSchedule(ctx context.Context, scheduleID string) (*model.StatementSchedule, error)              // Retrieves a statement schedule by ID
+	GetDueStatementSchedules(ctx context.Context, now time.Time, limit int) ([]*model.StatementSchedule, error) // Retrieves active schedules that are due to run
+	UpdateStatementScheduleRun(ctx context.Context, scheduleID string, lastRunAt, nextRunAt time.Time) error    // Records a schedule run
+	CreateStatement(ctx context.Context, statement *model.Statement) error                                      // Saves a generated statement
+	GetStatement(ctx context.Context, statementID string) (*model.Statement, error)                             // Retrieves a statement by ID
+	GetStatementsBySchedule(ctx context.Context, scheduleID string) ([]*model.Statement, error)                 // Retrieves the statements generated by a schedule
+	UpdateStatementDelivery(ctx context.Context, statement *model.Statement) error                              // Records the outcome of a statement delivery
+}
+
+// integrity defines read-only checks of ledger invariants.
+type integrity interface {
+	FindUnbalancedTransactions(ctx context.Context, limit int) ([]model.IntegrityIssue, error)                              // Finds applied transactions whose legs cannot balance
+	FindBalanceDrift(ctx context.Context, limit int) ([]model.IntegrityIssue, error)                                        // Finds balances that differ from the sums of their transactions
+	FindOrphanedInflightTransactions(ctx context.Context, staleBefore time.Time, limit int) ([]model.IntegrityIssue, error) // Finds inflight records out of step with their commits and voids
+}
+
+// tableStats defines methods reading planner statistics of tables.
+type tableStats interface {
+	EstimateRowCount(ctx context.Context, table string) (int64, error) // Estimates the number of rows in a table
+}
+
+// dormancy defines methods for tracking dormant balances and escheating their funds.
+type dormancy interface {
+	FindInactiveBalances(ctx context.Context, inactiveSince time.Time, limit int) ([]*model.DormantBalance, error) // Finds unflagged balances without transactions since a time
+	FlagDormantBalance(ctx context.Context, balance *model.DormantBalance) (bool, error)                           // Records a balance as dormant
+	ClearReactivatedBalances(ctx context.Context) ([]*model.DormantBalance, error)                                 // Removes the flag of dormant balances that had new transactions
+	GetDormantBalance(ctx context.Context, balanceID string) (*model.DormantBalance, error)                        // Retrieves the dormancy record of a balance
+	ListDormantBalances(ctx context.Context, status string, page model.Page) ([]*model.DormantBalance, error)      // Lists dormant balances
+	ListFrozenBalanceIDs(ctx context.Context) ([]string, error)                                                    // Retrieves the IDs of balances frozen for dormancy
+	DeleteDormantBalance(ctx context.Context, balanceID string) error                                              // Removes the dormancy record of a balance
+	CreateEscheatmentBatch(ctx context.Context, batch *model.EscheatmentBatch, dormantBefore *time.Time) error     // Saves a batch with an item for each dormant balance it claims
+	UpdateEscheatmentBatch(ctx context.Context, batch *model.EscheatmentBatch) error                               // Records the items and outcome of a batch
+	GetEscheatmentBatch(ctx context.Context, batchID string) (*model.EscheatmentBatch, error)                      // Retrieves an escheatment batch by ID
+	ListEscheatmentBatches(ctx context.Context, page model.Page) ([]*model.EscheatmentBatch, error)                // Lists escheatment batches
+}
+
+// requestLog defines methods for recording and searching API requests.
+type requestLog interface {
+	RecordRequestLog(ctx context.Context, entry *model.RequestLog) error                                              // Saves an entry of the request log
+	GetRequestLog(ctx context.Context, logID, ownerID string) (*model.RequestLog, error)                              // Retrieves an entry of the request log
+	ListRequestLogs(ctx context.Context, filter model.RequestLogFilter, page model.Page) ([]*model.RequestLog, error) // Searches the request log
+	PruneRequestLogs(ctx context.Context, before time.Time, maxEntries int) (int64, error)                            // Deletes expired and excess entries of the request log
+}
+
+// webhookDelivery defines methods for recording and summarising webhook deliveries.
+type webhookDelivery interface {
+	RecordWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error                                // Saves the record of a webhook delivery
+	GetWebhookAnalytics(ctx context.Context, endpointID string, from, to time.Time) (*model.WebhookAnalytics, error) // Summarises the deliveries to an endpoint within a time range
+	PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)                                     // Deletes the records of deliveries made before a time
+}
+
+// schemaAdvisor defines read-only diagnostics of the blnk schema's statements, indexes and table health.
+type schemaAdvisor interface {
+	FindSlowStatements(ctx context.Context, minMeanTime time.Duration, limit int) ([]model.SchemaRecommendation, error) // Finds slow statements in pg_stat_statements
+	FindSequentialScanTables(ctx context.Context, minRows int64, limit int) ([]model.SchemaRecommendation, error)       // Finds large tables mostly read by sequential scans
+	FindUnindexedMetadata(ctx context.Context, minRows int64, limit int) ([]model.SchemaRecommendation, error)          // Finds large tables whose metadata has no GIN index
+	FindUnusedIndexes(ctx context.Context, limit int) ([]model.SchemaRecommendation, error)                             // Finds indexes that are never scanned
+	FindTablesNeedingMaintenance(ctx context.Context, limit int) ([]model.SchemaRecommendation, error)                  // Finds tables with many dead rows or no statistics
+}
+
+// balanceSharding defines methods for managing sharded balances.
+type balanceSharding interface {
+	UpsertBalanceSharding(ctx context.Context, sharding *model.BalanceSharding) error         // Saves the sharding of a balance
+	GetBalanceSharding(ctx context.Context, balanceID string) (*model.BalanceSharding, error) // Retrieves the sharding of a balance
+	ListBalanceShardings(ctx context.Context) ([]*model.BalanceSharding, error)               // Retrieves the shardings of all sharded balances
+}
+
+// replay defines methods for exporting ledger slices and restoring them into a scratch database.
+type replay interface {
+	GetReplayTransactions(ctx context.Context, ledgerID string, from, to time.Time) ([]*model.Transaction, error) // Retrieves the transactions touching a ledger within a window
+	GetReplayBalanceAt(ctx context.Context, balanceID string, at time.Time) (*model.Balance, error)               // Computes the amounts of a balance at a point in time from its transactions
+	RestoreLedger(ctx context.Context, ledgerID string) error                                                     // Creates a ledger with a given ID unless it exists
+	RestoreBalance(ctx context.Context, balance *model.Balance) error                                             // Writes a balance with its ID and amounts as given
+}
+
+// warehouseSync defines methods for reading the rows to export to a warehouse and tracking how far each entity was exported.
+type warehouseSync interface {
+	GetWarehouseBatch(ctx context.Context, entity string, after model.WarehouseWatermark, until time.Time, limit int) (*model.WarehouseBatch, error) // Reads the next rows of an entity to export
+	GetWarehouseSyncState(ctx context.Context, entity string) (*model.WarehouseSyncState, error)                                                     // Retrieves how far an entity has been exported
+	ListWarehouseSyncStates(ctx context.Context) ([]*model.WarehouseSyncState, error)                                                                // Lists the sync states of the exported entities
+	SaveWarehouseSyncState(ctx context.Context, state *model.WarehouseSyncState) error                                                               // Saves how far an entity has been exported
+}
+
+// complianceExport defines methods for reading the postings to export to immutable storage and tracking how far they were exported.
+type complianceExport interface {
+	GetComplianceBacklog(ctx context.Context, limit int) (*model.ComplianceBacklog, error)                             // Reads the next postings to export
+	GetComplianceExportState(ctx context.Context) (*model.ComplianceExportState, error)                                // Retrieves how far postings have been exported
+	SaveComplianceExportState(ctx context.Context, state *model.ComplianceExportState, cursors map[string]int64) error // Saves how far postings have been exported
+}
+
+// ledgerSequence defines methods for reading the sequence numbers transactions are assigned in their ledgers.
+type ledgerSequence interface {
+	GetTransactionSequences(ctx context.Context, transactionID string) ([]model.LedgerSequence, error)                 // Retrieves the sequence numbers of a transaction
+	GetLedgerSequence(ctx context.Context, ledgerID string, after int64, limit int) (*model.LedgerSequencePage, error) // Retrieves the numbered transactions of a ledger after a sequence number
+}
+
+// challenge defines methods for storing transactions held back for strong customer authentication and their trails.
+type challenge interface {
+	CreateTransactionChallenge(ctx context.Context, challenge *model.TransactionChallenge) error                           // Saves a new transaction challenge
+	GetTransactionChallenge(ctx context.Context, challengeID string) (*model.TransactionChallenge, error)                  // Retrieves a transaction challenge by ID
+	ListTransactionChallenges(ctx context.Context, status string, page model.Page) ([]*model.TransactionChallenge, error)  // Lists transaction challenges, newest first
+	GetExpiredTransactionChallenges(ctx context.Context, now time.Time, limit int) ([]*model.TransactionChallenge, error)  // Retrieves pending challenges that timed out
+	AppendChallengeEvent(ctx context.Context, challengeID string, event model.ChallengeEvent) error                        // Adds an event to the trail of a challenge
+	ResolveTransactionChallenge(ctx context.Context, challengeID, status string, event model.ChallengeEvent) (bool, error) // Moves a pending challenge to its final status
+}
+
+// report defines methods for saved report definitions, computing them and storing their runs.
+type report interface {
+	CreateReportDefinition(ctx context.Context, definition *model.ReportDefinition) error                                                 // Saves a new report definition
+	GetReportDefinition(ctx context.Context, reportID string) (*model.ReportDefinition, error)                                            // Retrieves a report definition by ID
+	ListReportDefinitions(ctx context.Context, page model.Page) ([]*model.ReportDefinition, error)                                        // Lists report definitions, newest first
+	GetDueReportDefinitions(ctx context.Context, now time.Time, limit int) ([]*model.ReportDefinition, error)                             // Retrieves scheduled reports that are due to run
+	UpdateReportDefinitionRun(ctx context.Context, reportID string, lastRunAt, nextRunAt time.Time) error                                 // Records a scheduled run of a report
+	DeleteReportDefinition(ctx context.Context, reportID string) error                                                                    // Deletes a report definition and its runs
+	RunReportQuery(ctx context.Context, definition *model.ReportDefinition, now time.Time, limit int) ([]string, [][]string, bool, error) // Computes the rows of a report
+	CreateReportRun(ctx context.Context, run *model.ReportRun) error                                                                      // Saves a report run
+	UpdateReportRun(ctx context.Context, run *model.ReportRun) error                                                                      // Saves the outcome of a report run
+	GetReportRun(ctx context.Context, runID string) (*model.ReportRun, error)                                                             // Retrieves a report run by ID
+	ListReportRuns(ctx context.Context, reportID string, page model.Page) ([]*model.ReportRun, error)                                     // Lists the runs of a report, newest first
+}
+
+// minimumBalance defines methods for handling the minimum balances debits cannot breach.
+type minimumBalance interface {
+	SetMinimumBalance(ctx context.Context, minimum *model.MinimumBalance) error                // Saves the minimum of a balance or of a ledger in a currency
+	GetMinimumBalance(ctx context.Context, id string) (*model.MinimumBalance, error)           // Retrieves a minimum balance by ID
+	ListMinimumBalances(ctx context.Context, page model.Page) ([]*model.MinimumBalance, error) // Retrieves a page of minimum balances
+	DeleteMinimumBalance(ctx context.Context, id string) error                                 // Removes a minimum balance
+}
+
+// unitOfWork defines the method for writing a session's staged operations in one database transaction.
+type unitOfWork interface {
+	CommitUnitOfWork(ctx context.Context, work *model.UnitOfWork) error // Writes a unit of work atomically
+}
+
+// eod defines methods for tracking the end-of-day runs of business dates.
+type eod interface {
+	CreateEODRun(ctx context.Context, run *model.EODRun) error                 // Saves the run of a business date
+	UpdateEODRun(ctx context.Context, run *model.EODRun) error                 // Saves the progress of a run
+	GetEODRun(ctx context.Context, businessDate string) (*model.EODRun, error) // Retrieves the run of a business date
+	GetLatestEODRun(ctx context.Context) (*model.EODRun, error)                // Retrieves the run of the latest business date
+	ListEODRuns(ctx context.Context, page model.Page) ([]*model.EODRun, error) // Lists runs, latest business date first
+}
+
+// postingRules defines methods for the posting rules of ledgers.
+type postingRules interface {
+	SetPostingRules(ctx context.Context, rules *model.PostingRules) error              // Saves the posting rules of a ledger
+	GetPostingRules(ctx context.Context, ledgerID string) (*model.PostingRules, error) // Retrieves the posting rules of a ledger
+	ListPostingRules(ctx context.Context) ([]*model.PostingRules, error)               // Retrieves the posting rules of every ledger
+	DeletePostingRules(ctx context.Context, ledgerID string) error                     // Removes the posting rules of a ledger
+}
+
+// balanceAlias defines methods for the aliases of balances.
+type balanceAlias interface {
+	CreateBalanceAlias(ctx context.Context, alias *model.BalanceAlias) error                           // Saves an alias of a balance
+	GetBalanceAlias(ctx context.Context, tenantID, alias string) (*model.BalanceAlias, error)          // Retrieves an alias of a tenant
+	ListBalanceAliases(ctx context.Context, tenantID, balanceID string) ([]*model.BalanceAlias, error) // Lists the aliases of a tenant
+	UpdateBalanceAlias(ctx context.Context, alias *model.BalanceAlias) error                           // Points an alias at another balance
+	DeleteBalanceAlias(ctx context.Context, tenantID, alias string) error                              // Removes an alias of a tenant
+}
+
+// identityScreening defines methods for the sanctions and PEP screenings of identities.
+type identityScreening interface {
+	CreateIdentityScreening(ctx context.Context, screening *model.IdentityScreening) error               // Records the result of screening an identity
+	GetIdentityScreenings(ctx context.Context, identityID string) ([]*model.IdentityScreening, error)    // Retrieves the screenings of an identity, newest first
+	GetLatestIdentityScreening(ctx context.Context, identityID string) (*model.IdentityScreening, error) // Retrieves the latest screening of an identity
 }
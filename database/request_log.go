@@ -0,0 +1,215 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const requestLogColumns = `log_id, method, path, query, key_id, owner_id, ip_address, status_code, latency_ms, request_body, response_body, request_truncated, response_truncated, created_at`
+
+// RecordRequestLog saves an entry of the request log.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - entry: The request to record.
+// Returns:
+// - An error if the insert fails.
+func (d Datasource) RecordRequestLog(ctx context.Context, entry *model.RequestLog) error {
+	ctx, span := otel.Tracer("request_log.database").Start(ctx, "Recording request log")
+	defer span.End()
+
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.request_logs (`+requestLogColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`,
+		entry.LogID, entry.Method, entry.Path, nullString(entry.Query), nullString(entry.KeyID), nullString(entry.OwnerID),
+		nullString(entry.IPAddress), entry.StatusCode, entry.LatencyMs, nullString(entry.RequestBody),
+		nullString(entry.ResponseBody), entry.RequestTruncated, entry.ResponseTruncated, entry.CreatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record request log", err)
+	}
+	return nil
+}
+
+// GetRequestLog retrieves an entry of the request log.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - logID: The ID of the entry.
+// - ownerID: The owner the request must have been made by, or empty to allow any owner.
+// Returns:
+// - The entry, or an error if it does not exist or the query fails.
+func (d Datasource) GetRequestLog(ctx context.Context, logID, ownerID string) (*model.RequestLog, error) {
+	ctx, span := otel.Tracer("request_log.database").Start(ctx, "Getting request log")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `
+		SELECT `+requestLogColumns+`
+		FROM blnk.request_logs
+		WHERE log_id = $1 AND ($2 = '' OR owner_id = $2)
+	`, logID, ownerID)
+	entry, err := scanRequestLog(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Request log with ID '%s' not found", logID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve request log", err)
+	}
+	return entry, nil
+}
+
+// requestLogKeyset is the order the request log is listed in, newest first.
+var requestLogKeyset = keyset{timeColumn: "created_at", idColumn: "log_id", descending: true}
+
+// ListRequestLogs searches the request log, newest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - filter: The conditions entries must meet.
+// - page: The page of entries to read.
+// Returns:
+// - The entries, or an error if the query fails.
+func (d Datasource) ListRequestLogs(ctx context.Context, filter model.RequestLogFilter, page model.Page) ([]*model.RequestLog, error) {
+	ctx, span := otel.Tracer("request_log.database").Start(ctx, "Listing request logs")
+	defer span.End()
+
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", fmt.Sprintf("$%d", len(args))))
+	}
+	if filter.OwnerID != "" {
+		where("owner_id = ?", filter.OwnerID)
+	}
+	if filter.KeyID != "" {
+		where("key_id = ?", filter.KeyID)
+	}
+	if filter.Method != "" {
+		where("method = ?", strings.ToUpper(filter.Method))
+	}
+	if filter.PathPrefix != "" {
+		where(`path LIKE ? ESCAPE '\'`, escapeLike(filter.PathPrefix)+"%")
+	}
+	if filter.StatusCode != 0 {
+		where("status_code = ?", filter.StatusCode)
+	}
+	if filter.MinStatus != 0 {
+		where("status_code >= ?", filter.MinStatus)
+	}
+	if filter.From != nil {
+		where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		where("created_at < ?", *filter.To)
+	}
+	if filter.Contains != "" {
+		where(`(request_body ILIKE ? ESCAPE '\' OR response_body ILIKE ? ESCAPE '\')`, "%"+escapeLike(filter.Contains)+"%")
+	}
+
+	condition, suffix, args := requestLogKeyset.page(page, args)
+	conditions = append(conditions, condition)
+	query := `SELECT ` + requestLogColumns + ` FROM blnk.request_logs WHERE ` + strings.Join(conditions, " AND ") + suffix
+
+	rows, err := d.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve request logs", err)
+	}
+	defer rows.Close()
+
+	entries := []*model.RequestLog{}
+	for rows.Next() {
+		entry, err := scanRequestLog(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan request log", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over request logs", err)
+	}
+	return entries, nil
+}
+
+// PruneRequestLogs deletes the entries of the request log that are older than a time, and then the oldest
+// entries beyond a maximum number.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - before: Entries created before this time are deleted.
+// - maxEntries: The number of newest entries to keep, or 0 to keep any number.
+// Returns:
+// - The number of entries deleted, or an error if a delete fails.
+func (d Datasource) PruneRequestLogs(ctx context.Context, before time.Time, maxEntries int) (int64, error) {
+	ctx, span := otel.Tracer("request_log.database").Start(ctx, "Pruning request logs")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `DELETE FROM blnk.request_logs WHERE created_at < $1`, before)
+	if err != nil {
+		span.RecordError(err)
+		return 0, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to prune request logs", err)
+	}
+	deleted, _ := result.RowsAffected()
+
+	if maxEntries > 0 {
+		result, err = d.Conn.ExecContext(ctx, `
+			DELETE FROM blnk.request_logs
+			WHERE id <= (SELECT id FROM blnk.request_logs ORDER BY id DESC OFFSET $1 LIMIT 1)
+		`, maxEntries)
+		if err != nil {
+			span.RecordError(err)
+			return deleted, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to prune request logs", err)
+		}
+		overflow, _ := result.RowsAffected()
+		deleted += overflow
+	}
+	return deleted, nil
+}
+
+func scanRequestLog(row rowScanner) (*model.RequestLog, error) {
+	entry := &model.RequestLog{}
+	var query, keyID, ownerID, ipAddress, requestBody, responseBody sql.NullString
+	if err := row.Scan(
+		&entry.LogID, &entry.Method, &entry.Path, &query, &keyID, &ownerID, &ipAddress, &entry.StatusCode,
+		&entry.LatencyMs, &requestBody, &responseBody, &entry.RequestTruncated, &entry.ResponseTruncated,
+		&entry.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	entry.Query = query.String
+	entry.KeyID = keyID.String
+	entry.OwnerID = ownerID.String
+	entry.IPAddress = ipAddress.String
+	entry.RequestBody = requestBody.String
+	entry.ResponseBody = responseBody.String
+	return entry, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
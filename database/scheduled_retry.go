@@ -0,0 +1,107 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const scheduledTransactionFailureColumns = `failure_id, transaction_id, reference, source, destination, currency, precise_amount, executed_amount, attempts, reason, scheduled_for, first_failed_at, failed_at`
+
+// scheduledFailureKeyset is the order scheduled transaction failures are listed in, newest first.
+var scheduledFailureKeyset = keyset{timeColumn: "failed_at", idColumn: "failure_id", descending: true}
+
+// RecordScheduledTransactionFailure saves a scheduled transaction that failed permanently.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - failure: The failure to store.
+// Returns:
+// - An error if the failure could not be saved.
+func (d Datasource) RecordScheduledTransactionFailure(ctx context.Context, failure *model.ScheduledTransactionFailure) error {
+	ctx, span := otel.Tracer("scheduled_retry.database").Start(ctx, "Recording scheduled transaction failure")
+	defer span.End()
+
+	executed := failure.ExecutedAmount
+	if executed == nil {
+		executed = big.NewInt(0)
+	}
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.scheduled_transaction_failures (`+scheduledTransactionFailureColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`,
+		failure.FailureID, failure.TransactionID, failure.Reference, failure.Source, failure.Destination, failure.Currency,
+		failure.PreciseAmount.String(), executed.String(), failure.Attempts, failure.Reason,
+		failure.ScheduledFor, failure.FirstFailedAt, failure.FailedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record scheduled transaction failure", err)
+	}
+	return nil
+}
+
+// GetScheduledTransactionFailures retrieves the scheduled transactions that failed permanently during a period, newest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - start: The inclusive start of the period.
+// - end: The exclusive end of the period.
+// - page: The page of failures to read.
+// Returns:
+// - The failures, or an error if the query fails.
+func (d Datasource) GetScheduledTransactionFailures(ctx context.Context, start, end time.Time, page model.Page) ([]*model.ScheduledTransactionFailure, error) {
+	ctx, span := otel.Tracer("scheduled_retry.database").Start(ctx, "Fetching scheduled transaction failures")
+	defer span.End()
+
+	condition, suffix, args := scheduledFailureKeyset.page(page, []interface{}{start, end})
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+scheduledTransactionFailureColumns+`
+		FROM blnk.scheduled_transaction_failures
+		WHERE failed_at >= $1 AND failed_at < $2 AND `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve scheduled transaction failures", err)
+	}
+	defer rows.Close()
+
+	failures := []*model.ScheduledTransactionFailure{}
+	for rows.Next() {
+		failure := &model.ScheduledTransactionFailure{}
+		var preciseAmount, executedAmount string
+		err := rows.Scan(
+			&failure.FailureID, &failure.TransactionID, &failure.Reference, &failure.Source, &failure.Destination, &failure.Currency,
+			&preciseAmount, &executedAmount, &failure.Attempts, &failure.Reason,
+			&failure.ScheduledFor, &failure.FirstFailedAt, &failure.FailedAt,
+		)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan scheduled transaction failure", err)
+		}
+		failure.PreciseAmount, _ = new(big.Int).SetString(preciseAmount, 10)
+		failure.ExecutedAmount, _ = new(big.Int).SetString(executedAmount, 10)
+		failures = append(failures, failure)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over scheduled transaction failures", err)
+	}
+	return failures, nil
+}
@@ -0,0 +1,366 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+// maintenanceDeadTuples is the number of dead rows from which a table is considered for a vacuum.
+const maintenanceDeadTuples = 10000
+
+var blnkTablePattern = regexp.MustCompile(`blnk\.(\w+)`)
+
+// FindSlowStatements reads pg_stat_statements for statements on the blnk schema whose mean execution time
+// reaches minMeanTime, slowest in total first. When the extension is not installed it recommends installing it,
+// since the other checks can only point at tables and not at the statements that scan them.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - minMeanTime: Statements faster than this on average are not reported.
+// - limit: The maximum number of recommendations to return.
+// Returns:
+// - []model.SchemaRecommendation: A recommendation for each slow statement.
+// - An error if a query fails.
+func (d Datasource) FindSlowStatements(ctx context.Context, minMeanTime time.Duration, limit int) ([]model.SchemaRecommendation, error) {
+	ctx, span := otel.Tracer("schema_advisor.database").Start(ctx, "Finding slow statements")
+	defer span.End()
+
+	var installed bool
+	err := d.Conn.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements')
+	`).Scan(&installed)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to check for pg_stat_statements", err)
+	}
+	if !installed {
+		return []model.SchemaRecommendation{{
+			Check:     model.SchemaCheckSlowStatements,
+			Severity:  model.SchemaAdviceInfo,
+			Detail:    "pg_stat_statements is not installed, so slow statements cannot be identified",
+			Action:    "add pg_stat_statements to shared_preload_libraries, restart PostgreSQL and create the extension",
+			Statement: "CREATE EXTENSION IF NOT EXISTS pg_stat_statements",
+		}}, nil
+	}
+
+	minMeanMs := float64(minMeanTime) / float64(time.Millisecond)
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT query, calls, mean_exec_time, total_exec_time
+		FROM pg_stat_statements
+		WHERE query ILIKE '%blnk.%' AND mean_exec_time >= $1
+		ORDER BY total_exec_time DESC
+		LIMIT $2
+	`, minMeanMs, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to read pg_stat_statements", err)
+	}
+	defer rows.Close()
+
+	recommendations := []model.SchemaRecommendation{}
+	for rows.Next() {
+		var query string
+		var calls int64
+		var meanMs, totalMs float64
+		if err := rows.Scan(&query, &calls, &meanMs, &totalMs); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan statement", err)
+		}
+
+		severity := model.SchemaAdviceWarning
+		if meanMs >= 10*minMeanMs {
+			severity = model.SchemaAdviceCritical
+		}
+		table := ""
+		if match := blnkTablePattern.FindStringSubmatch(query); match != nil {
+			table = match[1]
+		}
+		recommendations = append(recommendations, model.SchemaRecommendation{
+			Check:    model.SchemaCheckSlowStatements,
+			Severity: severity,
+			Table:    table,
+			Detail:   fmt.Sprintf("statement averages %.1fms over %d calls", meanMs, calls),
+			Action:   slowStatementAction(query),
+			Evidence: map[string]string{
+				"query":         query,
+				"calls":         strconv.FormatInt(calls, 10),
+				"mean_exec_ms":  strconv.FormatFloat(meanMs, 'f', 1, 64),
+				"total_exec_ms": strconv.FormatFloat(totalMs, 'f', 1, 64),
+			},
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over statements", err)
+	}
+
+	return recommendations, nil
+}
+
+// slowStatementAction suggests what to do about a slow statement from the shape of its query.
+func slowStatementAction(query string) string {
+	lower := strings.ToLower(query)
+	switch {
+	case strings.Contains(lower, "meta_data") && (strings.Contains(lower, "->") || strings.Contains(lower, "@>")):
+		return "the statement filters on metadata; add a GIN index on the table's meta_data or filter by an indexed column"
+	case strings.Contains(lower, "blnk.transactions") && (strings.Contains(lower, "source") || strings.Contains(lower, "destination")) &&
+		(strings.Contains(lower, "created_at") || strings.Contains(lower, "transaction_time")):
+		return "the statement reads balance history; bound it by balance and time so the (source, created_at) and (destination, created_at) indexes are used"
+	case strings.Contains(lower, "offset"):
+		return "the statement pages with OFFSET, which reads every skipped row; page with a cursor instead"
+	default:
+		return "run EXPLAIN (ANALYZE, BUFFERS) on the statement to find the scan that needs an index"
+	}
+}
+
+// FindSequentialScanTables finds tables of the blnk schema with at least minRows rows that are read by
+// sequential scans more often than through an index, most rows read sequentially first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - minRows: Tables with fewer live rows than this are not reported, since scanning them is cheap.
+// - limit: The maximum number of recommendations to return.
+// Returns:
+// - []model.SchemaRecommendation: A recommendation for each table.
+// - An error if the query fails.
+func (d Datasource) FindSequentialScanTables(ctx context.Context, minRows int64, limit int) ([]model.SchemaRecommendation, error) {
+	ctx, span := otel.Tracer("schema_advisor.database").Start(ctx, "Finding sequentially scanned tables")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT relname, seq_scan, COALESCE(idx_scan, 0), seq_tup_read, n_live_tup
+		FROM pg_stat_user_tables
+		WHERE schemaname = 'blnk' AND n_live_tup >= $1 AND seq_scan > COALESCE(idx_scan, 0)
+		ORDER BY seq_tup_read DESC
+		LIMIT $2
+	`, minRows, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to read table scan statistics", err)
+	}
+	defer rows.Close()
+
+	recommendations := []model.SchemaRecommendation{}
+	for rows.Next() {
+		var table string
+		var seqScans, idxScans, seqRows, liveRows int64
+		if err := rows.Scan(&table, &seqScans, &idxScans, &seqRows, &liveRows); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan table statistics", err)
+		}
+		recommendations = append(recommendations, model.SchemaRecommendation{
+			Check:    model.SchemaCheckSequentialScans,
+			Severity: model.SchemaAdviceWarning,
+			Table:    table,
+			Detail:   fmt.Sprintf("table of %d rows was scanned sequentially %d times and through an index %d times", liveRows, seqScans, idxScans),
+			Action:   "find the statements reading the table in pg_stat_statements and index the columns they filter by",
+			Evidence: map[string]string{
+				"seq_scan":     strconv.FormatInt(seqScans, 10),
+				"idx_scan":     strconv.FormatInt(idxScans, 10),
+				"seq_tup_read": strconv.FormatInt(seqRows, 10),
+				"n_live_tup":   strconv.FormatInt(liveRows, 10),
+			},
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over tables", err)
+	}
+
+	return recommendations, nil
+}
+
+// FindUnindexedMetadata finds tables of the blnk schema with at least minRows rows whose meta_data column has
+// no GIN index, so every metadata filter on them scans the whole table.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - minRows: Tables with fewer live rows than this are not reported.
+// - limit: The maximum number of recommendations to return.
+// Returns:
+// - []model.SchemaRecommendation: A recommendation with the index to create for each table.
+// - An error if the query fails.
+func (d Datasource) FindUnindexedMetadata(ctx context.Context, minRows int64, limit int) ([]model.SchemaRecommendation, error) {
+	ctx, span := otel.Tracer("schema_advisor.database").Start(ctx, "Finding unindexed metadata")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT c.relname, s.n_live_tup
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_stat_user_tables s ON s.relid = c.oid
+		WHERE n.nspname = 'blnk' AND c.relkind = 'r'
+		AND a.attname = 'meta_data' AND a.atttypid = 'jsonb'::regtype AND NOT a.attisdropped
+		AND s.n_live_tup >= $1
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_index i
+			JOIN pg_class ic ON ic.oid = i.indexrelid
+			JOIN pg_am am ON am.oid = ic.relam
+			WHERE i.indrelid = c.oid AND am.amname = 'gin' AND a.attnum = ANY(i.indkey)
+		)
+		ORDER BY s.n_live_tup DESC
+		LIMIT $2
+	`, minRows, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to check metadata indexes", err)
+	}
+	defer rows.Close()
+
+	recommendations := []model.SchemaRecommendation{}
+	for rows.Next() {
+		var table string
+		var liveRows int64
+		if err := rows.Scan(&table, &liveRows); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan table", err)
+		}
+		recommendations = append(recommendations, model.SchemaRecommendation{
+			Check:    model.SchemaCheckMetadataIndexes,
+			Severity: model.SchemaAdviceWarning,
+			Table:    table,
+			Detail:   fmt.Sprintf("metadata of %d rows has no GIN index, so metadata filters scan the whole table", liveRows),
+			Action:   "create a GIN index on the metadata if it is filtered by",
+			Statement: fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON blnk.%s USING GIN (meta_data jsonb_path_ops)",
+				pq.QuoteIdentifier("idx_"+table+"_meta_data"), pq.QuoteIdentifier(table)),
+			Evidence: map[string]string{"n_live_tup": strconv.FormatInt(liveRows, 10)},
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over tables", err)
+	}
+
+	return recommendations, nil
+}
+
+// FindUnusedIndexes finds indexes of the blnk schema that have not been scanned since the statistics were last
+// reset, largest first. Unique and primary key indexes enforce constraints and are never reported.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - limit: The maximum number of recommendations to return.
+// Returns:
+// - []model.SchemaRecommendation: A recommendation with the statement dropping each index.
+// - An error if the query fails.
+func (d Datasource) FindUnusedIndexes(ctx context.Context, limit int) ([]model.SchemaRecommendation, error) {
+	ctx, span := otel.Tracer("schema_advisor.database").Start(ctx, "Finding unused indexes")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT s.relname, s.indexrelname, pg_relation_size(s.indexrelid)
+		FROM pg_stat_user_indexes s
+		JOIN pg_index i ON i.indexrelid = s.indexrelid
+		WHERE s.schemaname = 'blnk' AND s.idx_scan = 0 AND NOT i.indisunique AND NOT i.indisprimary
+		ORDER BY pg_relation_size(s.indexrelid) DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to read index usage", err)
+	}
+	defer rows.Close()
+
+	recommendations := []model.SchemaRecommendation{}
+	for rows.Next() {
+		var table, index string
+		var size int64
+		if err := rows.Scan(&table, &index, &size); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan index", err)
+		}
+		recommendations = append(recommendations, model.SchemaRecommendation{
+			Check:     model.SchemaCheckUnusedIndexes,
+			Severity:  model.SchemaAdviceInfo,
+			Table:     table,
+			Detail:    fmt.Sprintf("index %s has not been scanned since statistics were reset but slows down every write", index),
+			Action:    "drop the index if statistics cover a representative period of traffic",
+			Statement: fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS blnk.%s", pq.QuoteIdentifier(index)),
+			Evidence:  map[string]string{"index": index, "size_bytes": strconv.FormatInt(size, 10)},
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over indexes", err)
+	}
+
+	return recommendations, nil
+}
+
+// FindTablesNeedingMaintenance finds tables of the blnk schema whose dead rows exceed a fifth of their live
+// rows, or that hold rows but have never been analyzed, so the planner works from stale statistics.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - limit: The maximum number of recommendations to return.
+// Returns:
+// - []model.SchemaRecommendation: A recommendation with the statement vacuuming each table.
+// - An error if the query fails.
+func (d Datasource) FindTablesNeedingMaintenance(ctx context.Context, limit int) ([]model.SchemaRecommendation, error) {
+	ctx, span := otel.Tracer("schema_advisor.database").Start(ctx, "Finding tables needing maintenance")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT relname, n_live_tup, n_dead_tup, last_analyze IS NULL AND last_autoanalyze IS NULL
+		FROM pg_stat_user_tables
+		WHERE schemaname = 'blnk'
+		AND ((n_dead_tup >= $1 AND n_dead_tup > n_live_tup / 5)
+			OR (n_live_tup >= $1 AND last_analyze IS NULL AND last_autoanalyze IS NULL))
+		ORDER BY n_dead_tup DESC
+		LIMIT $2
+	`, maintenanceDeadTuples, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to read table maintenance statistics", err)
+	}
+	defer rows.Close()
+
+	recommendations := []model.SchemaRecommendation{}
+	for rows.Next() {
+		var table string
+		var liveRows, deadRows int64
+		var neverAnalyzed bool
+		if err := rows.Scan(&table, &liveRows, &deadRows, &neverAnalyzed); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan table statistics", err)
+		}
+		detail := fmt.Sprintf("table has %d dead rows for %d live rows", deadRows, liveRows)
+		if neverAnalyzed {
+			detail = fmt.Sprintf("table of %d rows has never been analyzed", liveRows)
+		}
+		recommendations = append(recommendations, model.SchemaRecommendation{
+			Check:     model.SchemaCheckTableMaintenance,
+			Severity:  model.SchemaAdviceWarning,
+			Table:     table,
+			Detail:    detail,
+			Action:    "vacuum and analyze the table, and tune its autovacuum thresholds if this recurs",
+			Statement: fmt.Sprintf("VACUUM (ANALYZE) blnk.%s", pq.QuoteIdentifier(table)),
+			Evidence: map[string]string{
+				"n_live_tup": strconv.FormatInt(liveRows, 10),
+				"n_dead_tup": strconv.FormatInt(deadRows, 10),
+			},
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over tables", err)
+	}
+
+	return recommendations, nil
+}
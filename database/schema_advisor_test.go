@@ -0,0 +1,86 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSlowStatements_ExtensionMissing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	recommendations, err := ds.FindSlowStatements(context.Background(), 100*time.Millisecond, 10)
+	assert.NoError(t, err)
+	assert.Len(t, recommendations, 1)
+	assert.Equal(t, model.SchemaAdviceInfo, recommendations[0].Severity)
+	assert.Contains(t, recommendations[0].Statement, "pg_stat_statements")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindSlowStatements(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("FROM pg_stat_statements").WithArgs(float64(100), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"query", "calls", "mean_exec_time", "total_exec_time"}).
+			AddRow("SELECT * FROM blnk.balances WHERE meta_data->>'customer' = $1", 40, 1500.0, 60000.0).
+			AddRow("SELECT * FROM blnk.transactions WHERE source = $1 AND created_at <= $2", 900, 120.0, 108000.0))
+
+	recommendations, err := ds.FindSlowStatements(context.Background(), 100*time.Millisecond, 10)
+	assert.NoError(t, err)
+	assert.Len(t, recommendations, 2)
+	assert.Equal(t, "balances", recommendations[0].Table)
+	assert.Equal(t, model.SchemaAdviceCritical, recommendations[0].Severity)
+	assert.Contains(t, recommendations[0].Action, "GIN index")
+	assert.Equal(t, "transactions", recommendations[1].Table)
+	assert.Equal(t, model.SchemaAdviceWarning, recommendations[1].Severity)
+	assert.Contains(t, recommendations[1].Action, "balance history")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindUnindexedMetadata(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery("FROM pg_attribute").WithArgs(int64(10000), 5).
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "n_live_tup"}).AddRow("transactions", 250000))
+
+	recommendations, err := ds.FindUnindexedMetadata(context.Background(), 10000, 5)
+	assert.NoError(t, err)
+	assert.Len(t, recommendations, 1)
+	assert.Equal(t, `CREATE INDEX CONCURRENTLY IF NOT EXISTS "idx_transactions_meta_data" ON blnk."transactions" USING GIN (meta_data jsonb_path_ops)`, recommendations[0].Statement)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/lib/pq"
+)
+
+var ErrServiceAccountNotFound = errors.New("service account not found")
+
+const serviceAccountColumns = `service_account_id, name, owner_id, secret_hash, scopes, created_at, last_token_issued_at, is_revoked, revoked_at`
+
+// CreateServiceAccount saves a new service account
+func (s *Datasource) CreateServiceAccount(ctx context.Context, account *model.ServiceAccount) error {
+	query := `
+		INSERT INTO blnk.service_accounts (service_account_id, name, owner_id, secret_hash, scopes, created_at, is_revoked)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := s.Conn.ExecContext(ctx, query,
+		account.ServiceAccountID,
+		account.Name,
+		account.OwnerID,
+		account.SecretHash,
+		pq.StringArray(account.Scopes),
+		account.CreatedAt,
+		account.IsRevoked,
+	)
+	return err
+}
+
+// GetServiceAccount retrieves a service account by its ID
+func (s *Datasource) GetServiceAccount(ctx context.Context, id string) (*model.ServiceAccount, error) {
+	query := `SELECT ` + serviceAccountColumns + ` FROM blnk.service_accounts WHERE service_account_id = $1`
+
+	account, err := scanServiceAccount(s.Conn.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrServiceAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// ListServiceAccounts lists all service accounts for an owner
+func (s *Datasource) ListServiceAccounts(ctx context.Context, ownerID string) ([]*model.ServiceAccount, error) {
+	query := `SELECT ` + serviceAccountColumns + ` FROM blnk.service_accounts WHERE owner_id = $1 ORDER BY created_at DESC`
+
+	rows, err := s.Conn.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*model.ServiceAccount
+	for rows.Next() {
+		account, err := scanServiceAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+// RevokeServiceAccount revokes a service account
+func (s *Datasource) RevokeServiceAccount(ctx context.Context, id, ownerID string) error {
+	query := `
+		UPDATE blnk.service_accounts
+		SET is_revoked = true, revoked_at = $1
+		WHERE service_account_id = $2 AND owner_id = $3
+	`
+
+	result, err := s.Conn.ExecContext(ctx, query, time.Now(), id, ownerID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrServiceAccountNotFound
+	}
+
+	return nil
+}
+
+// UpdateServiceAccountTokenIssued updates the last_token_issued_at timestamp for a service account
+func (s *Datasource) UpdateServiceAccountTokenIssued(ctx context.Context, id string) error {
+	query := `
+		UPDATE blnk.service_accounts
+		SET last_token_issued_at = $1
+		WHERE service_account_id = $2
+	`
+
+	_, err := s.Conn.ExecContext(ctx, query, time.Now(), id)
+	return err
+}
+
+func scanServiceAccount(row rowScanner) (*model.ServiceAccount, error) {
+	account := &model.ServiceAccount{}
+	var scopes pq.StringArray
+	err := row.Scan(
+		&account.ServiceAccountID,
+		&account.Name,
+		&account.OwnerID,
+		&account.SecretHash,
+		&scopes,
+		&account.CreatedAt,
+		&account.LastTokenIssuedAt,
+		&account.IsRevoked,
+		&account.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	account.Scopes = []string(scopes)
+	return account, nil
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const statementScheduleColumns = `schedule_id, entity_type, entity_id, day_of_month, channel, email, is_active, next_run_at, last_run_at, created_at`
+
+const statementColumns = `statement_id, schedule_id, entity_type, entity_id, period_start, period_end, storage_key, channel, delivery_status, delivery_attempts, last_error, delivered_at, created_at`
+
+// CreateStatementSchedule saves a new statement schedule.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - schedule: The schedule to store.
+// Returns:
+// - An error if the schedule could not be saved.
+func (d Datasource) CreateStatementSchedule(ctx context.Context, schedule *model.StatementSchedule) error {
+	ctx, span := otel.Tracer("statement.database").Start(ctx, "Creating statement schedule")
+	defer span.End()
+
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.statement_schedules (`+statementScheduleColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`,
+		schedule.ScheduleID, schedule.EntityType, schedule.EntityID, schedule.DayOfMonth, schedule.Channel,
+		sql.NullString{String: schedule.Email, Valid: schedule.Email != ""}, schedule.IsActive,
+		schedule.NextRunAt, schedule.LastRunAt, schedule.CreatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create statement schedule", err)
+	}
+	return nil
+}
+
+// GetStatementSchedule retrieves a statement schedule by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - scheduleID: The ID of the schedule.
+// Returns:
+// - The schedule, or an error if it does not exist.
+func (d Datasource) GetStatementSchedule(ctx context.Context, scheduleID string) (*model.StatementSchedule, error) {
+	ctx, span := otel.Tracer("statement.database").Start(ctx, "Fetching statement schedule")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `
+		SELECT `+statementScheduleColumns+`
+		FROM blnk.statement_schedules
+		WHERE schedule_id = $1
+	`, scheduleID)
+
+	schedule, err := scanStatementSchedule(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Statement schedule with ID '%s' not found", scheduleID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve statement schedule", err)
+	}
+	return schedule, nil
+}
+
+// GetDueStatementSchedules retrieves active schedules whose next run is at or before the given time.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - now: The reference time.
+// - limit: The maximum number of schedules to return.
+// Returns:
+// - The due schedules, or an error if the query fails.
+func (d Datasource) GetDueStatementSchedules(ctx context.Context, now time.Time, limit int) ([]*model.StatementSchedule, error) {
+	ctx, span := otel.Tracer("statement.database").Start(ctx, "Fetching due statement schedules")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+statementScheduleColumns+`
+		FROM blnk.statement_schedules
+		WHERE is_active AND next_run_at <= $1
+		ORDER BY next_run_at ASC
+		LIMIT $2
+	`, now, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve due statement schedules", err)
+	}
+	defer rows.Close()
+
+	schedules := []*model.StatementSchedule{}
+	for rows.Next() {
+		schedule, err := scanStatementSchedule(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan statement schedule", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over statement schedules", err)
+	}
+	return schedules, nil
+}
+
+// UpdateStatementScheduleRun records a schedule run and moves it to its next run time.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - scheduleID: The ID of the schedule.
+// - lastRunAt: When the schedule ran.
+// - nextRunAt: When the schedule should run next.
+// Returns:
+// - An error if the schedule could not be updated.
+func (d Datasource) UpdateStatementScheduleRun(ctx context.Context, scheduleID string, lastRunAt, nextRunAt time.Time) error {
+	ctx, span := otel.Tracer("statement.database").Start(ctx, "Updating statement schedule run")
+	defer span.End()
+
+	_, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.statement_schedules
+		SET last_run_at = $2, next_run_at = $3
+		WHERE schedule_id = $1
+	`, scheduleID, lastRunAt, nextRunAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update statement schedule", err)
+	}
+	return nil
+}
+
+// CreateStatement saves a generated statement.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - statement: The statement to store.
+// Returns:
+// - An error if the statement could not be saved.
+func (d Datasource) CreateStatement(ctx context.Context, statement *model.Statement) error {
+	ctx, span := otel.Tracer("statement.database").Start(ctx, "Creating statement")
+	defer span.End()
+
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.statements (`+statementColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`,
+		statement.StatementID, statement.ScheduleID, statement.EntityType, statement.EntityID,
+		statement.PeriodStart, statement.PeriodEnd, statement.StorageKey, statement.Channel,
+		statement.DeliveryStatus, statement.DeliveryAttempts,
+		sql.NullString{String: statement.LastError, Valid: statement.LastError != ""},
+		statement.DeliveredAt, statement.CreatedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to create statement", err)
+	}
+	return nil
+}
+
+// GetStatement retrieves a statement by ID.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - statementID: The ID of the statement.
+// Returns:
+// - The statement, or an error if it does not exist.
+func (d Datasource) GetStatement(ctx context.Context, statementID string) (*model.Statement, error) {
+	ctx, span := otel.Tracer("statement.database").Start(ctx, "Fetching statement")
+	defer span.End()
+
+	row := d.Conn.QueryRowContext(ctx, `
+		SELECT `+statementColumns+`
+		FROM blnk.statements
+		WHERE statement_id = $1
+	`, statementID)
+
+	statement, err := scanStatement(row)
+	if err == sql.ErrNoRows {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Statement with ID '%s' not found", statementID), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve statement", err)
+	}
+	return statement, nil
+}
+
+// GetStatementsBySchedule retrieves the statements generated by a schedule, newest first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - scheduleID: The ID of the schedule.
+// Returns:
+// - The statements, or an error if the query fails.
+func (d Datasource) GetStatementsBySchedule(ctx context.Context, scheduleID string) ([]*model.Statement, error) {
+	ctx, span := otel.Tracer("statement.database").Start(ctx, "Fetching statements by schedule")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+statementColumns+`
+		FROM blnk.statements
+		WHERE schedule_id = $1
+		ORDER BY period_start DESC
+	`, scheduleID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve statements", err)
+	}
+	defer rows.Close()
+
+	statements := []*model.Statement{}
+	for rows.Next() {
+		statement, err := scanStatement(rows)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan statement", err)
+		}
+		statements = append(statements, statement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over statements", err)
+	}
+	return statements, nil
+}
+
+// UpdateStatementDelivery records the outcome of a delivery attempt.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - statement: The statement with its updated delivery fields.
+// Returns:
+// - An error if the statement could not be updated.
+func (d Datasource) UpdateStatementDelivery(ctx context.Context, statement *model.Statement) error {
+	ctx, span := otel.Tracer("statement.database").Start(ctx, "Updating statement delivery")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.statements
+		SET delivery_status = $2, delivery_attempts = $3, last_error = $4, delivered_at = $5
+		WHERE statement_id = $1
+	`, statement.StatementID, statement.DeliveryStatus, statement.DeliveryAttempts,
+		sql.NullString{String: statement.LastError, Valid: statement.LastError != ""}, statement.DeliveredAt)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update statement delivery", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to get rows affected", err)
+	}
+	if rowsAffected == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Statement with ID '%s' not found", statement.StatementID), nil)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanStatementSchedule(row rowScanner) (*model.StatementSchedule, error) {
+	schedule := &model.StatementSchedule{}
+	var email sql.NullString
+	err := row.Scan(
+		&schedule.ScheduleID, &schedule.EntityType, &schedule.EntityID, &schedule.DayOfMonth, &schedule.Channel,
+		&email, &schedule.IsActive, &schedule.NextRunAt, &schedule.LastRunAt, &schedule.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	schedule.Email = email.String
+	return schedule, nil
+}
+
+func scanStatement(row rowScanner) (*model.Statement, error) {
+	statement := &model.Statement{}
+	var lastError sql.NullString
+	err := row.Scan(
+		&statement.StatementID, &statement.ScheduleID, &statement.EntityType, &statement.EntityID,
+		&statement.PeriodStart, &statement.PeriodEnd, &statement.StorageKey, &statement.Channel,
+		&statement.DeliveryStatus, &statement.DeliveryAttempts, &lastError, &statement.DeliveredAt, &statement.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	statement.LastError = lastError.String
+	return statement, nil
+}
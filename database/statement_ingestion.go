@@ -0,0 +1,151 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+const statementIngestionColumns = `ingestion_id, channel, sender, filename, fingerprint, profile, status, upload_id, record_count, reconciliation_id, error, received_at, completed_at`
+
+// RecordStatementIngestion records a statement file unless a file with the same fingerprint was recorded
+// before. A file whose ingestion never got past being received, because the run ingesting it was
+// interrupted, is recorded again so that it is retried.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - ingestion: The ingestion to record.
+// Returns:
+// - True if the ingestion was recorded, false if the file was already ingested, or an error if the insert fails.
+func (d Datasource) RecordStatementIngestion(ctx context.Context, ingestion *model.StatementIngestion) (bool, error) {
+	ctx, span := otel.Tracer("statement_ingestion.database").Start(ctx, "Recording statement ingestion")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.statement_ingestions (`+statementIngestionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (fingerprint) DO UPDATE
+		SET ingestion_id = EXCLUDED.ingestion_id, channel = EXCLUDED.channel, sender = EXCLUDED.sender,
+			filename = EXCLUDED.filename, received_at = EXCLUDED.received_at, created_at = NOW()
+		WHERE blnk.statement_ingestions.status = '`+model.StatementIngestionReceived+`'
+	`, statementIngestionArgs(ingestion)...)
+	if err != nil {
+		span.RecordError(err)
+		return false, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record statement ingestion", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// UpdateStatementIngestion saves the outcome of a statement ingestion.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - ingestion: The ingestion to save.
+// Returns:
+// - An error if the ingestion does not exist or the update fails.
+func (d Datasource) UpdateStatementIngestion(ctx context.Context, ingestion *model.StatementIngestion) error {
+	ctx, span := otel.Tracer("statement_ingestion.database").Start(ctx, "Updating statement ingestion")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `
+		UPDATE blnk.statement_ingestions
+		SET profile = $2, status = $3, upload_id = $4, record_count = $5, reconciliation_id = $6, error = $7, completed_at = $8
+		WHERE ingestion_id = $1
+	`,
+		ingestion.IngestionID, nullString(ingestion.Profile), ingestion.Status, nullString(ingestion.UploadID),
+		ingestion.RecordCount, nullString(ingestion.ReconciliationID), nullString(ingestion.Error), ingestion.CompletedAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to update statement ingestion", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, "Statement ingestion not found", nil)
+	}
+	return nil
+}
+
+// statementIngestionKeyset is the order statement ingestions are listed in, most recently received first.
+var statementIngestionKeyset = keyset{timeColumn: "received_at", idColumn: "ingestion_id", descending: true}
+
+// ListStatementIngestions lists statement ingestions, most recently received first.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - page: The page of ingestions to read.
+// Returns:
+// - The ingestions, or an error if the query fails.
+func (d Datasource) ListStatementIngestions(ctx context.Context, page model.Page) ([]*model.StatementIngestion, error) {
+	ctx, span := otel.Tracer("statement_ingestion.database").Start(ctx, "Listing statement ingestions")
+	defer span.End()
+
+	condition, suffix, args := statementIngestionKeyset.page(page, nil)
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT `+statementIngestionColumns+`
+		FROM blnk.statement_ingestions
+		WHERE `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve statement ingestions", err)
+	}
+	defer rows.Close()
+
+	ingestions := []*model.StatementIngestion{}
+	for rows.Next() {
+		ingestion := &model.StatementIngestion{}
+		var sender, profile, uploadID, reconciliationID, ingestionErr sql.NullString
+		var completedAt sql.NullTime
+		if err := rows.Scan(
+			&ingestion.IngestionID, &ingestion.Channel, &sender, &ingestion.Filename, &ingestion.Fingerprint,
+			&profile, &ingestion.Status, &uploadID, &ingestion.RecordCount, &reconciliationID, &ingestionErr,
+			&ingestion.ReceivedAt, &completedAt,
+		); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan statement ingestion", err)
+		}
+		ingestion.Sender = sender.String
+		ingestion.Profile = profile.String
+		ingestion.UploadID = uploadID.String
+		ingestion.ReconciliationID = reconciliationID.String
+		ingestion.Error = ingestionErr.String
+		if completedAt.Valid {
+			ingestion.CompletedAt = &completedAt.Time
+		}
+		ingestions = append(ingestions, ingestion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over statement ingestions", err)
+	}
+	return ingestions, nil
+}
+
+func statementIngestionArgs(ingestion *model.StatementIngestion) []interface{} {
+	return []interface{}{
+		ingestion.IngestionID, ingestion.Channel, nullString(ingestion.Sender), ingestion.Filename,
+		ingestion.Fingerprint, nullString(ingestion.Profile), ingestion.Status, nullString(ingestion.UploadID),
+		ingestion.RecordCount, nullString(ingestion.ReconciliationID), nullString(ingestion.Error),
+		ingestion.ReceivedAt, ingestion.CompletedAt,
+	}
+}
+
+// nullString stores empty strings as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
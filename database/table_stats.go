@@ -0,0 +1,52 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"go.opentelemetry.io/otel"
+)
+
+// EstimateRowCount returns the planner's estimate of the number of rows in a table. The estimate is as fresh
+// as the table's last vacuum or analyze, which makes it cheap enough to report with every page of a list.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - table: The schema-qualified table name, e.g. blnk.ledgers.
+// Returns:
+// - int64: The estimated row count, or -1 when the table has not been analyzed yet.
+// - An error if the table does not exist or the query fails.
+func (d Datasource) EstimateRowCount(ctx context.Context, table string) (int64, error) {
+	ctx, span := otel.Tracer("table_stats.database").Start(ctx, "Estimating row count")
+	defer span.End()
+
+	var estimate int64
+	err := d.Conn.QueryRowContext(ctx, `
+		SELECT reltuples::BIGINT FROM pg_class WHERE oid = to_regclass($1)
+	`, table).Scan(&estimate)
+	if err == sql.ErrNoRows {
+		return 0, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Table '%s' not found", table), err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return 0, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to estimate row count", err)
+	}
+	return estimate, nil
+}
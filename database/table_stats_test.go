@@ -0,0 +1,47 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateRowCount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	mock.ExpectQuery("SELECT reltuples::BIGINT FROM pg_class").WithArgs("blnk.ledgers").
+		WillReturnRows(sqlmock.NewRows([]string{"reltuples"}).AddRow(1200))
+	mock.ExpectQuery("SELECT reltuples::BIGINT FROM pg_class").WithArgs("blnk.missing").
+		WillReturnRows(sqlmock.NewRows([]string{"reltuples"}))
+
+	estimate, err := ds.EstimateRowCount(context.Background(), "blnk.ledgers")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1200), estimate)
+
+	_, err = ds.EstimateRowCount(context.Background(), "blnk.missing")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -45,6 +45,11 @@ import (
 // Returns:
 // - The recorded transaction if successful, or an error if the recording fails.
 func (d Datasource) RecordTransaction(ctx context.Context, txn *model.Transaction) (*model.Transaction, error) {
+	return recordTransaction(ctx, d.Conn, txn)
+}
+
+// recordTransaction records a transaction on the connection pool or inside a database transaction.
+func recordTransaction(ctx context.Context, db dbExecutor, txn *model.Transaction) (*model.Transaction, error) {
 	// Start a new tracing span for the database operation
 	ctx, span := otel.Tracer("transaction.database").Start(ctx, "RecordTransaction")
 	defer span.End()
@@ -56,11 +61,33 @@ func (d Datasource) RecordTransaction(ctx context.Context, txn *model.Transactio
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
 	}
 
-	// Execute the SQL insert statement to record the transaction
-	_, err = d.Conn.ExecContext(ctx,
-		`INSERT INTO blnk.transactions(transaction_id, parent_transaction, source, reference, amount, precise_amount, precision, rate, currency, destination, description, status, created_at, meta_data, scheduled_for, hash, effective_date) 
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`,
-		txn.TransactionID, txn.ParentTransaction, txn.Source, txn.Reference, txn.AmountString, txn.PreciseAmount.String(), txn.Precision, txn.Rate, txn.Currency, txn.Destination, txn.Description, txn.Status, txn.CreatedAt, metaDataJSON, txn.ScheduledFor, txn.Hash, txn.EffectiveDate,
+	// Transactions that move balances are numbered in their ledgers in the same statement that records them
+	if model.IsSequencedStatus(txn.Status) {
+		sequences, err := recordSequencedTransaction(ctx, db, txn, metaDataJSON)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		txn.Sequences = sequences
+		span.AddEvent("Transaction recorded", trace.WithAttributes(
+			attribute.String("transaction.id", txn.TransactionID),
+			attribute.String("transaction.reference", txn.Reference),
+			attribute.Int("transaction.sequences", len(sequences)),
+		))
+		return txn, nil
+	}
+
+	// Execute the SQL insert statement to record the transaction, and its status in the status history
+	_, err = db.ExecContext(ctx,
+		`WITH recorded AS (
+			INSERT INTO blnk.transactions(transaction_id, parent_transaction, source, reference, amount, precise_amount, precision, rate, currency, destination, description, status, created_at, meta_data, scheduled_for, hash, effective_date, transaction_time, group_id) 
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+			RETURNING transaction_id, parent_transaction, status
+		)
+		INSERT INTO blnk.transaction_status_history(transaction_id, parent_transaction, status, reason_code, reason, actor)
+		SELECT transaction_id, parent_transaction, status, $20, $21, $22 FROM recorded`,
+		txn.TransactionID, txn.ParentTransaction, txn.Source, txn.Reference, txn.AmountString, txn.PreciseAmount.String(), txn.Precision, txn.Rate, txn.Currency, txn.Destination, txn.Description, txn.Status, txn.CreatedAt, metaDataJSON, txn.ScheduledFor, txn.Hash, txn.EffectiveDate, txn.TransactionTime, nullString(txn.GroupID),
+		txn.StatusChange.ReasonCode, txn.StatusChange.Reason, txn.StatusChange.Actor,
 	)
 	// Handle errors that may occur during the execution of the query
 	if err != nil {
@@ -77,6 +104,32 @@ func (d Datasource) RecordTransaction(ctx context.Context, txn *model.Transactio
 	return txn, nil
 }
 
+// historyOrder returns the column expression transaction history is ordered by. Ordering by transaction time
+// falls back to the server's time for transactions recorded without a client time.
+func (d Datasource) historyOrder() string {
+	if d.HistoryOrder == "transaction_time" {
+		return "COALESCE(transaction_time, created_at)"
+	}
+	return "created_at"
+}
+
+// historyKeyset is the order transaction history is listed in, newest first by the configured history order.
+func (d Datasource) historyKeyset() keyset {
+	return keyset{timeColumn: d.historyOrder(), idColumn: "transaction_id", descending: true}
+}
+
+// chronologicalHistoryKeyset is the order statements and transaction groups read transaction history in, oldest
+// first by the configured history order.
+func (d Datasource) chronologicalHistoryKeyset() keyset {
+	return keyset{timeColumn: d.historyOrder(), idColumn: "transaction_id"}
+}
+
+// transactionKeyset is the order batch readers walk all transactions in, oldest first.
+var transactionKeyset = keyset{timeColumn: "created_at", idColumn: "transaction_id"}
+
+// childTransactionKeyset is the order the transactions of a parent are listed in, newest first.
+var childTransactionKeyset = keyset{timeColumn: "created_at", idColumn: "transaction_id", descending: true}
+
 // GetTransaction retrieves a transaction by its ID from the database.
 // It logs the transaction retrieval using OpenTelemetry tracing.
 // Parameters:
@@ -91,7 +144,7 @@ func (d Datasource) GetTransaction(ctx context.Context, id string) (*model.Trans
 
 	// Execute the SQL query to retrieve the transaction by its ID
 	row := d.Conn.QueryRowContext(ctx, `
-		SELECT transaction_id, source, reference, amount, precise_amount, precision, currency, destination, description, status, created_at, meta_data, parent_transaction, hash
+		SELECT transaction_id, source, reference, amount, precise_amount, precision, currency, destination, description, status, created_at, meta_data, parent_transaction, hash, transaction_time, COALESCE(group_id, '')
 		FROM blnk.transactions
 		WHERE transaction_id = $1
 	`, id)
@@ -100,7 +153,7 @@ func (d Datasource) GetTransaction(ctx context.Context, id string) (*model.Trans
 	txn := &model.Transaction{}
 	var metaDataJSON []byte
 	var preciseAmountStr string
-	err := row.Scan(&txn.TransactionID, &txn.Source, &txn.Reference, &txn.Amount, &preciseAmountStr, &txn.Precision, &txn.Currency, &txn.Destination, &txn.Description, &txn.Status, &txn.CreatedAt, &metaDataJSON, &txn.ParentTransaction, &txn.Hash)
+	err := row.Scan(&txn.TransactionID, &txn.Source, &txn.Reference, &txn.Amount, &preciseAmountStr, &txn.Precision, &txn.Currency, &txn.Destination, &txn.Description, &txn.Status, &txn.CreatedAt, &metaDataJSON, &txn.ParentTransaction, &txn.Hash, &txn.TransactionTime, &txn.GroupID)
 	// Handle errors, including no rows found
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -302,24 +355,25 @@ func (d Datasource) UpdateTransactionStatus(ctx context.Context, id string, stat
 	return nil
 }
 
-// GetAllTransactions retrieves all transactions from the database, ordered by creation date in descending order.
+// GetAllTransactions retrieves all transactions from the database, newest first by the configured history order.
 // It traces the operation using OpenTelemetry and returns an error if the retrieval or processing fails.
+// The list is read from a read replica when one is configured.
 // Parameters:
 // - ctx: Context for managing the request and tracing.
+// - page: The page of transactions to read.
 // Returns:
 // - A slice of transactions or an error if the retrieval fails.
-func (d Datasource) GetAllTransactions(ctx context.Context, limit, offset int) ([]model.Transaction, error) {
+func (d Datasource) GetAllTransactions(ctx context.Context, page model.Page) ([]model.Transaction, error) {
 	// Start a new tracing span for the operation
 	ctx, span := otel.Tracer("transaction.database").Start(ctx, "GetAllTransactions")
 	defer span.End()
 
 	// Execute the query to retrieve all transactions
-	rows, err := d.Conn.QueryContext(ctx, `
-		SELECT transaction_id, source, reference, amount, currency, destination, description, status, hash, created_at, meta_data
+	condition, suffix, args := d.historyKeyset().page(page, nil)
+	rows, err := d.readQuery(ctx, `
+		SELECT transaction_id, source, reference, amount, currency, destination, description, status, hash, created_at, meta_data, transaction_time, COALESCE(group_id, '')
 		FROM blnk.transactions
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`, limit, offset)
+		WHERE `+condition+suffix, args...)
 	if err != nil {
 		span.RecordError(err)
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve transactions", err)
@@ -347,6 +401,8 @@ func (d Datasource) GetAllTransactions(ctx context.Context, limit, offset int) (
 			&transaction.Hash,
 			&transaction.CreatedAt,
 			&metaDataJSON,
+			&transaction.TransactionTime,
+			&transaction.GroupID,
 		)
 		if err != nil {
 			span.RecordError(err)
@@ -429,20 +485,19 @@ func (d Datasource) GetTotalCommittedTransactions(ctx context.Context, parentID
 	return total, nil
 }
 
-// GetTransactionsPaginated retrieves a batch of transactions from the database with pagination support and caches the result.
+// GetTransactionsPaginated retrieves a page of transactions, oldest first, and caches the result.
 // If the data is found in cache, it is returned from there; otherwise, it is fetched from the database and then cached.
 // Parameters:
 // - ctx: Context for managing request and tracing.
-// - batchSize: Number of transactions to retrieve in one batch.
-// - offset: Number of transactions to skip before retrieving the batch.
+// - page: The page to retrieve, after the cursor of the previous one.
 // Returns:
 // - A slice of transactions, or an error if the retrieval or caching fails.
-func (d Datasource) GetTransactionsPaginated(ctx context.Context, _ string, batchSize int, offset int64) ([]*model.Transaction, error) {
+func (d Datasource) GetTransactionsPaginated(ctx context.Context, _ string, page model.Page) ([]*model.Transaction, error) {
 	ctx, span := otel.Tracer("transaction.database").Start(ctx, "GetInternalTransactionsPaginated")
 	defer span.End()
 
 	// Create a cache key based on the pagination parameters
-	cacheKey := fmt.Sprintf("transactions:paginated:%d:%d", batchSize, offset)
+	cacheKey := "transactions:paginated:" + pageKey(page)
 
 	var transactions []*model.Transaction
 	// Attempt to retrieve transactions from cache
@@ -455,12 +510,11 @@ func (d Datasource) GetTransactionsPaginated(ctx context.Context, _ string, batc
 	}
 
 	// If not found in cache, fetch from the database
+	condition, suffix, args := transactionKeyset.page(page, nil)
 	rows, err := d.Conn.QueryContext(ctx, `
         SELECT transaction_id, parent_transaction, source, reference, amount, precise_amount, precision, rate, currency, destination, description, status, created_at, meta_data, scheduled_for, hash
         FROM blnk.transactions
-        ORDER BY created_at ASC
-        LIMIT $1 OFFSET $2
-    `, batchSize, offset)
+        WHERE `+condition+suffix, args...)
 	if err != nil {
 		span.RecordError(err)
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve paginated transactions", err)
@@ -530,15 +584,15 @@ func (d Datasource) GetTransactionsPaginated(ctx context.Context, _ string, batc
 }
 
 // GroupTransactions retrieves and groups transactions from the database based on a specified column (groupCriteria).
-// It supports pagination and caches the grouped results for efficiency. If the data is found in the cache, it returns the cached data.
+// It reads the transactions a page at a time, oldest first, and caches the grouped results for efficiency. If the data
+// is found in the cache, it returns the cached data.
 // Parameters:
 // - ctx: Context for managing request and tracing.
 // - groupCriteria: Column to group transactions by (e.g., "currency", "status").
-// - batchSize: Number of transactions to retrieve in one batch.
-// - offset: Number of transactions to skip before retrieving the batch.
+// - page: The page of transactions to group, after the cursor of the previous one.
 // Returns:
 // - A map of grouped transactions, or an error if retrieval or grouping fails.
-func (d Datasource) GroupTransactions(ctx context.Context, groupCriteria string, batchSize int, offset int64) (map[string][]*model.Transaction, error) {
+func (d Datasource) GroupTransactions(ctx context.Context, groupCriteria string, page model.Page) (map[string][]*model.Transaction, error) {
 	ctx, span := otel.Tracer("transaction.database").Start(ctx, "GroupTransactions")
 	defer span.End()
 
@@ -553,7 +607,7 @@ func (d Datasource) GroupTransactions(ctx context.Context, groupCriteria string,
 	}
 
 	// Create a cache key based on the grouping and pagination parameters
-	cacheKey := fmt.Sprintf("transactions:grouped:%s:%d:%d", groupCriteria, batchSize, offset)
+	cacheKey := fmt.Sprintf("transactions:grouped:%s:%s", groupCriteria, pageKey(page))
 
 	var groupedTransactions map[string][]*model.Transaction
 	err := d.Cache.Get(ctx, cacheKey, &groupedTransactions)
@@ -565,17 +619,15 @@ func (d Datasource) GroupTransactions(ctx context.Context, groupCriteria string,
 	}
 
 	// If not in cache or error occurred, fetch from database
+	condition, suffix, args := transactionKeyset.page(page, []interface{}{groupCriteria})
 	query := `
         SELECT $1::text AS group_key, transaction_id, parent_transaction, source, reference, 
                amount, precise_amount, precision, rate, currency, destination, 
                description, status, created_at, meta_data, scheduled_for, hash
         FROM blnk.transactions
-        WHERE $1::text IS NOT NULL AND $1::text != ''
-        ORDER BY $1::text
-        LIMIT $2 OFFSET $3
-    `
+        WHERE $1::text IS NOT NULL AND $1::text != '' AND ` + condition + suffix
 
-	rows, err := d.Conn.QueryContext(ctx, query, groupCriteria, batchSize, offset)
+	rows, err := d.Conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		span.RecordError(err)
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve grouped transactions", err)
@@ -643,22 +695,22 @@ func (d Datasource) GroupTransactions(ctx context.Context, groupCriteria string,
 }
 
 // GetInflightTransactionsByParentID retrieves all inflight transactions associated with a given parent transaction ID.
-// It supports pagination via batchSize and offset. Transactions with status 'INFLIGHT' are fetched.
+// It returns one page, newest first. Transactions with status 'INFLIGHT' are fetched.
 // If no INFLIGHT transactions exist, then transactions with status 'QUEUED' and meta_data.inflight=true are considered.
 // Parameters:
 // - ctx: Context for managing request and tracing.
 // - parentTransactionID: The ID of the parent transaction to filter by.
-// - batchSize: Number of transactions to retrieve in one batch.
-// - offset: Number of transactions to skip before retrieving the batch.
+// - page: The page to retrieve, after the cursor of the previous one.
 // Returns:
 // - A slice of inflight transactions or an error if retrieval fails.
-func (d Datasource) GetInflightTransactionsByParentID(ctx context.Context, parentTransactionID string, batchSize int, offset int64) ([]*model.Transaction, error) {
+func (d Datasource) GetInflightTransactionsByParentID(ctx context.Context, parentTransactionID string, page model.Page) ([]*model.Transaction, error) {
 	ctx, span := otel.Tracer("transaction.database").Start(ctx, "GetInflightTransactionsByParentID")
 	defer span.End()
 
 	// This query first checks if there are any INFLIGHT transactions for this parentTransactionID
 	// If there are, it returns only those. If not, it falls back to QUEUED with inflight=true
 	// It excludes any REJECTED transactions
+	condition, suffix, args := childTransactionKeyset.page(page, []interface{}{parentTransactionID})
 	rows, err := d.Conn.QueryContext(ctx, `
 		WITH inflight_transactions AS (
 			SELECT transaction_id, parent_transaction, source, reference, amount, precise_amount, precision,
@@ -687,15 +739,14 @@ func (d Datasource) GetInflightTransactionsByParentID(ctx context.Context, paren
 			)
 		)
 		
-		SELECT * FROM inflight_transactions
-		UNION ALL
-		-- Only include queued_inflight if there are no inflight transactions
-		SELECT * FROM queued_inflight_transactions 
-		WHERE NOT EXISTS (SELECT 1 FROM inflight_transactions)
-		
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`, parentTransactionID, batchSize, offset)
+		SELECT * FROM (
+			SELECT * FROM inflight_transactions
+			UNION ALL
+			-- Only include queued_inflight if there are no inflight transactions
+			SELECT * FROM queued_inflight_transactions 
+			WHERE NOT EXISTS (SELECT 1 FROM inflight_transactions)
+		) candidates
+		WHERE `+condition+suffix, args...)
 	if err != nil {
 		span.RecordError(err)
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve inflight transactions", err)
@@ -756,18 +807,18 @@ func (d Datasource) GetInflightTransactionsByParentID(ctx context.Context, paren
 }
 
 // GetRefundableTransactionsByParentID retrieves transactions associated with a given parent transaction ID that are eligible for refunds.
-// Refundable transactions are those with status 'APPLIED' or 'VOID'. It supports pagination with batchSize and offset.
+// Refundable transactions are those with status 'APPLIED' or 'VOID'. It returns one page, newest first.
 // Parameters:
 // - ctx: Context for managing request and tracing.
 // - parentTransactionID: The ID of the parent transaction to filter by.
-// - batchSize: Number of transactions to retrieve in one batch.
-// - offset: Number of transactions to skip before retrieving the batch.
+// - page: The page to retrieve, after the cursor of the previous one.
 // Returns:
 // - A slice of refundable transactions or an error if retrieval fails.
-func (d Datasource) GetRefundableTransactionsByParentID(ctx context.Context, parentTransactionID string, batchSize int, offset int64) ([]*model.Transaction, error) {
+func (d Datasource) GetRefundableTransactionsByParentID(ctx context.Context, parentTransactionID string, page model.Page) ([]*model.Transaction, error) {
 	ctx, span := otel.Tracer("transaction.database").Start(ctx, "GetRefundableTransactionsByParentID")
 	defer span.End()
 
+	condition, suffix, args := childTransactionKeyset.page(page, []interface{}{parentTransactionID})
 	rows, err := d.Conn.QueryContext(ctx, `
 		SELECT 
 			t.transaction_id, t.parent_transaction, t.source, t.reference, t.amount, t.precise_amount, 
@@ -775,7 +826,7 @@ func (d Datasource) GetRefundableTransactionsByParentID(ctx context.Context, par
 			t.meta_data, t.scheduled_for, t.hash
 		FROM 
 			blnk.transactions t
-		WHERE 
+		WHERE (
 			-- Case 1: The transaction is the parent itself and is APPLIED
 			(t.transaction_id = $1 AND t.status = 'APPLIED')
 			
@@ -784,11 +835,7 @@ func (d Datasource) GetRefundableTransactionsByParentID(ctx context.Context, par
 
 			-- Case 3: Transaction is APPLIED and linked via metadata QUEUED_PARENT_TRANSACTION
 			OR (t.status = 'APPLIED' AND t.meta_data->>'QUEUED_PARENT_TRANSACTION' = $1)
-
-		ORDER BY 
-			t.created_at DESC
-		LIMIT $2 OFFSET $3
-	`, parentTransactionID, batchSize, offset)
+		) AND `+condition+suffix, args...)
 	if err != nil {
 		span.RecordError(err)
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve refundable transactions", err)
@@ -931,20 +978,19 @@ func (d Datasource) TransactionExistsByIDOrParentID(ctx context.Context, id stri
 }
 
 // GetTransactionsByParent retrieves all transactions associated with a given parent transaction ID.
-// It supports pagination via limit and offset parameters.
+// It returns one page, newest first.
 // Parameters:
 // - ctx: Context for managing request and tracing.
 // - parentID: The ID of the parent transaction to filter by.
-// - limit: Maximum number of transactions to retrieve.
-// - offset: Number of transactions to skip before retrieving the batch.
+// - page: The page to retrieve, after the cursor of the previous one.
 // Returns:
 // - A slice of transactions or an error if retrieval fails.
-func (d Datasource) GetTransactionsByParent(ctx context.Context, parentID string, limit int, offset int64) ([]*model.Transaction, error) {
+func (d Datasource) GetTransactionsByParent(ctx context.Context, parentID string, page model.Page) ([]*model.Transaction, error) {
 	ctx, span := otel.Tracer("transaction.database").Start(ctx, "GetTransactionsByParent")
 	defer span.End()
 
 	// Create a cache key based on the parameters
-	cacheKey := fmt.Sprintf("transactions:parent:%s:%d:%d", parentID, limit, offset)
+	cacheKey := fmt.Sprintf("transactions:parent:%s:%s", parentID, pageKey(page))
 
 	var transactions []*model.Transaction
 	// Attempt to retrieve from cache first
@@ -957,14 +1003,12 @@ func (d Datasource) GetTransactionsByParent(ctx context.Context, parentID string
 	}
 
 	// If not in cache, query the database
+	condition, suffix, args := childTransactionKeyset.page(page, []interface{}{parentID})
 	rows, err := d.Conn.QueryContext(ctx, `
 		SELECT transaction_id, parent_transaction, source, reference, amount, precise_amount, precision, 
 			   rate, currency, destination, description, status, created_at, meta_data, scheduled_for, hash
 		FROM blnk.transactions
-		WHERE parent_transaction = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`, parentID, limit, offset)
+		WHERE parent_transaction = $1 AND `+condition+suffix, args...)
 	if err != nil {
 		span.RecordError(err)
 		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve transactions by parent", err)
@@ -1065,3 +1109,411 @@ func (d Datasource) IsTransactionRefunded(ctx context.Context, transaction *mode
 
 	return exists, nil
 }
+
+// GetTransactionsCreatedBetween retrieves a page of transactions created within a time window, oldest first.
+// Parameters:
+// - ctx: Context for managing the request and tracing.
+// - start: The inclusive start of the window.
+// - end: The inclusive end of the window.
+// - page: The page of transactions to read.
+// Returns:
+// - A slice of transactions, or an error if the retrieval fails.
+func (d Datasource) GetTransactionsCreatedBetween(ctx context.Context, start, end time.Time, page model.Page) ([]*model.Transaction, error) {
+	ctx, span := otel.Tracer("transaction.database").Start(ctx, "GetTransactionsCreatedBetween")
+	defer span.End()
+
+	condition, suffix, args := transactionKeyset.page(page, []interface{}{start, end})
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT transaction_id, parent_transaction, source, reference, amount, precise_amount, precision, rate, currency, destination, description, status, created_at, meta_data, scheduled_for, hash
+		FROM blnk.transactions
+		WHERE created_at >= $1 AND created_at <= $2 AND `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve transactions", err)
+	}
+	defer rows.Close()
+
+	transactions := []*model.Transaction{}
+	for rows.Next() {
+		transaction := &model.Transaction{}
+		var metaDataJSON []byte
+		var preciseAmountStr string
+		err = rows.Scan(
+			&transaction.TransactionID,
+			&transaction.ParentTransaction,
+			&transaction.Source,
+			&transaction.Reference,
+			&transaction.Amount,
+			&preciseAmountStr,
+			&transaction.Precision,
+			&transaction.Rate,
+			&transaction.Currency,
+			&transaction.Destination,
+			&transaction.Description,
+			&transaction.Status,
+			&transaction.CreatedAt,
+			&metaDataJSON,
+			&transaction.ScheduledFor,
+			&transaction.Hash,
+		)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan transaction data", err)
+		}
+
+		if err = json.Unmarshal(metaDataJSON, &transaction.MetaData); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal metadata", err)
+		}
+
+		transaction.PreciseAmount, _ = new(big.Int).SetString(preciseAmountStr, 10)
+		transactions = append(transactions, transaction)
+	}
+
+	if err = rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over transactions", err)
+	}
+
+	span.AddEvent("Transactions in window retrieved", trace.WithAttributes(
+		attribute.Int("transaction.count", len(transactions)),
+	))
+	return transactions, nil
+}
+
+// GetBalanceTransactionsBetween retrieves applied transactions that debit or credit a balance created within
+// [start, end), ordered by the configured history order.
+// Parameters:
+// - ctx: Context for managing the request and tracing.
+// - balanceID: The ID of the balance.
+// - start: The inclusive start of the window.
+// - end: The exclusive end of the window.
+// - page: The page of transactions to read.
+// Returns:
+// - A slice of transactions in the window, or an error if the query fails.
+func (d Datasource) GetBalanceTransactionsBetween(ctx context.Context, balanceID string, start, end time.Time, page model.Page) ([]*model.Transaction, error) {
+	ctx, span := otel.Tracer("transaction.database").Start(ctx, "GetBalanceTransactionsBetween")
+	defer span.End()
+
+	condition, suffix, args := d.chronologicalHistoryKeyset().page(page, []interface{}{balanceID, start, end})
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT transaction_id, parent_transaction, source, reference, amount, precise_amount, precision, rate, currency, destination, description, status, created_at, meta_data, scheduled_for, hash, transaction_time, COALESCE(group_id, '')
+		FROM blnk.transactions
+		WHERE (source = $1 OR destination = $1) AND status = 'APPLIED'
+			AND created_at >= $2 AND created_at < $3 AND `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve transactions", err)
+	}
+	defer rows.Close()
+
+	transactions := []*model.Transaction{}
+	for rows.Next() {
+		transaction := &model.Transaction{}
+		var metaDataJSON []byte
+		var preciseAmountStr string
+		err = rows.Scan(
+			&transaction.TransactionID,
+			&transaction.ParentTransaction,
+			&transaction.Source,
+			&transaction.Reference,
+			&transaction.Amount,
+			&preciseAmountStr,
+			&transaction.Precision,
+			&transaction.Rate,
+			&transaction.Currency,
+			&transaction.Destination,
+			&transaction.Description,
+			&transaction.Status,
+			&transaction.CreatedAt,
+			&metaDataJSON,
+			&transaction.ScheduledFor,
+			&transaction.Hash,
+			&transaction.TransactionTime,
+			&transaction.GroupID,
+		)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan transaction data", err)
+		}
+
+		if err = json.Unmarshal(metaDataJSON, &transaction.MetaData); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal metadata", err)
+		}
+
+		transaction.PreciseAmount, _ = new(big.Int).SetString(preciseAmountStr, 10)
+		transactions = append(transactions, transaction)
+	}
+
+	if err = rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over transactions", err)
+	}
+
+	span.AddEvent("Balance transactions in window retrieved", trace.WithAttributes(
+		attribute.Int("transaction.count", len(transactions)),
+	))
+	return transactions, nil
+}
+
+// GetTransactionsByGroup retrieves the transactions of a group, oldest first by the configured history order.
+// Parameters:
+// - ctx: Context for managing the request and tracing.
+// - groupID: The group ID the transactions were recorded with.
+// - page: The page of transactions to read.
+// Returns:
+// - A slice of the transactions of the group, or an error if the query fails.
+func (d Datasource) GetTransactionsByGroup(ctx context.Context, groupID string, page model.Page) ([]*model.Transaction, error) {
+	ctx, span := otel.Tracer("transaction.database").Start(ctx, "GetTransactionsByGroup")
+	defer span.End()
+
+	condition, suffix, args := d.chronologicalHistoryKeyset().page(page, []interface{}{groupID})
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT transaction_id, parent_transaction, source, reference, amount, precise_amount, precision, rate, currency, destination, description, status, created_at, meta_data, scheduled_for, hash, transaction_time, group_id
+		FROM blnk.transactions
+		WHERE group_id = $1 AND `+condition+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve transactions by group", err)
+	}
+	defer rows.Close()
+
+	transactions := []*model.Transaction{}
+	for rows.Next() {
+		transaction := &model.Transaction{}
+		var metaDataJSON []byte
+		var preciseAmountStr string
+		err = rows.Scan(
+			&transaction.TransactionID,
+			&transaction.ParentTransaction,
+			&transaction.Source,
+			&transaction.Reference,
+			&transaction.Amount,
+			&preciseAmountStr,
+			&transaction.Precision,
+			&transaction.Rate,
+			&transaction.Currency,
+			&transaction.Destination,
+			&transaction.Description,
+			&transaction.Status,
+			&transaction.CreatedAt,
+			&metaDataJSON,
+			&transaction.ScheduledFor,
+			&transaction.Hash,
+			&transaction.TransactionTime,
+			&transaction.GroupID,
+		)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan transaction data", err)
+		}
+
+		if err = json.Unmarshal(metaDataJSON, &transaction.MetaData); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal metadata", err)
+		}
+
+		transaction.PreciseAmount, _ = new(big.Int).SetString(preciseAmountStr, 10)
+		transactions = append(transactions, transaction)
+	}
+
+	if err = rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over transactions", err)
+	}
+
+	span.AddEvent("Transactions by group retrieved", trace.WithAttributes(
+		attribute.String("transaction.group_id", groupID),
+		attribute.Int("transaction.count", len(transactions)),
+	))
+	return transactions, nil
+}
+
+// GetTransactionsByIdentity retrieves the transactions that debit or credit a balance of an identity, newest first
+// by the configured history order. Transactions outside the ledger scope of ctx are left out. The history is read
+// from a read replica when one is configured.
+// Parameters:
+// - ctx: Context for managing the request and tracing.
+// - identityID: The ID of the identity.
+// - page: The page of transactions to read.
+// Returns:
+// - A slice of the identity's transactions, or an error if the query fails.
+func (d Datasource) GetTransactionsByIdentity(ctx context.Context, identityID string, page model.Page) ([]*model.Transaction, error) {
+	ctx, span := otel.Tracer("transaction.database").Start(ctx, "GetTransactionsByIdentity")
+	defer span.End()
+
+	scope, args := ledgerScopeTransactions(ctx, []interface{}{identityID})
+	condition, suffix, args := d.historyKeyset().page(page, args)
+	rows, err := d.readQuery(ctx, `
+		SELECT transaction_id, parent_transaction, source, reference, amount, precise_amount, precision, rate, currency, destination, description, status, created_at, meta_data, scheduled_for, hash, transaction_time, COALESCE(group_id, '')
+		FROM blnk.transactions t
+		WHERE (source IN (SELECT balance_id FROM blnk.balances WHERE identity_id = $1)
+			OR destination IN (SELECT balance_id FROM blnk.balances WHERE identity_id = $1))
+		AND `+and(scope, condition)+suffix, args...)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve transactions by identity", err)
+	}
+	defer rows.Close()
+
+	transactions := []*model.Transaction{}
+	for rows.Next() {
+		transaction := &model.Transaction{}
+		var metaDataJSON []byte
+		var preciseAmountStr string
+		err = rows.Scan(
+			&transaction.TransactionID,
+			&transaction.ParentTransaction,
+			&transaction.Source,
+			&transaction.Reference,
+			&transaction.Amount,
+			&preciseAmountStr,
+			&transaction.Precision,
+			&transaction.Rate,
+			&transaction.Currency,
+			&transaction.Destination,
+			&transaction.Description,
+			&transaction.Status,
+			&transaction.CreatedAt,
+			&metaDataJSON,
+			&transaction.ScheduledFor,
+			&transaction.Hash,
+			&transaction.TransactionTime,
+			&transaction.GroupID,
+		)
+		if err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan transaction data", err)
+		}
+
+		if err = json.Unmarshal(metaDataJSON, &transaction.MetaData); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to unmarshal metadata", err)
+		}
+
+		transaction.PreciseAmount, _ = new(big.Int).SetString(preciseAmountStr, 10)
+		transactions = append(transactions, transaction)
+	}
+
+	if err = rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over transactions", err)
+	}
+
+	span.AddEvent("Transactions by identity retrieved", trace.WithAttributes(
+		attribute.String("identity.id", identityID),
+		attribute.Int("transaction.count", len(transactions)),
+	))
+	return transactions, nil
+}
+
+// GetPendingInflightCredits retrieves the inflight transactions crediting a balance that have not fully landed.
+// The pending amount of each is what has been neither committed nor voided, and transactions with nothing
+// left pending are skipped.
+// Parameters:
+// - ctx: Context for managing the request and tracing.
+// - balanceID: The ID of the destination balance.
+// - limit: The maximum number of transactions to return.
+// Returns:
+// - The pending credits, oldest first, or an error if the query fails.
+func (d Datasource) GetPendingInflightCredits(ctx context.Context, balanceID string, limit int) ([]*model.PendingInflightCredit, error) {
+	ctx, span := otel.Tracer("transaction.database").Start(ctx, "GetPendingInflightCredits")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT transaction_id, reference, source, currency, precision, precise_amount, pending_amount, created_at
+		FROM (
+			SELECT t.transaction_id, t.reference, t.source, t.currency, t.precision, t.precise_amount, t.created_at,
+				t.precise_amount - COALESCE((
+					SELECT SUM(child.precise_amount)
+					FROM blnk.transactions child
+					WHERE child.parent_transaction = t.transaction_id AND child.status IN ('APPLIED', 'VOID')
+				), 0) AS pending_amount
+			FROM blnk.transactions t
+			WHERE t.destination = $1 AND t.status = 'INFLIGHT'
+		) pending
+		WHERE pending_amount > 0
+		ORDER BY created_at ASC
+		LIMIT $2
+	`, balanceID, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve pending inflight credits", err)
+	}
+	defer rows.Close()
+
+	credits := []*model.PendingInflightCredit{}
+	for rows.Next() {
+		credit := &model.PendingInflightCredit{}
+		var preciseAmountStr, pendingAmountStr string
+		if err := rows.Scan(&credit.TransactionID, &credit.Reference, &credit.Source, &credit.Currency, &credit.Precision,
+			&preciseAmountStr, &pendingAmountStr, &credit.CreatedAt); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan pending inflight credit", err)
+		}
+		credit.PreciseAmount, _ = new(big.Int).SetString(preciseAmountStr, 10)
+		credit.PendingAmount, _ = new(big.Int).SetString(pendingAmountStr, 10)
+		credits = append(credits, credit)
+	}
+	if err = rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over pending inflight credits", err)
+	}
+
+	span.AddEvent("Pending inflight credits retrieved", trace.WithAttributes(
+		attribute.Int("transaction.count", len(credits)),
+	))
+	return credits, nil
+}
+
+// GetTransactionStatusHistory retrieves the statuses a transaction went through, including those of the
+// transactions it led to, such as the queued copy that was applied or the commits and voids of an inflight
+// transaction.
+// Parameters:
+// - ctx: Context for managing the request and tracing.
+// - transactionID: The ID of the transaction.
+// Returns:
+// - The status changes, oldest first, or an error if the query fails.
+func (d Datasource) GetTransactionStatusHistory(ctx context.Context, transactionID string) ([]*model.TransactionStatusEntry, error) {
+	ctx, span := otel.Tracer("transaction.database").Start(ctx, "GetTransactionStatusHistory")
+	defer span.End()
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		WITH RECURSIVE lineage(transaction_id) AS (
+			SELECT $1::TEXT
+			UNION
+			SELECT h.transaction_id
+			FROM blnk.transaction_status_history h
+			JOIN lineage l ON h.parent_transaction = l.transaction_id
+		)
+		SELECT h.transaction_id, COALESCE(h.parent_transaction, ''), h.status, COALESCE(h.reason_code, ''), COALESCE(h.reason, ''), COALESCE(h.actor, ''), h.created_at
+		FROM blnk.transaction_status_history h
+		WHERE h.transaction_id IN (SELECT transaction_id FROM lineage)
+		ORDER BY h.created_at ASC, h.id ASC
+	`, transactionID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve transaction status history", err)
+	}
+	defer rows.Close()
+
+	history := []*model.TransactionStatusEntry{}
+	for rows.Next() {
+		entry := &model.TransactionStatusEntry{}
+		if err := rows.Scan(&entry.TransactionID, &entry.ParentTransaction, &entry.Status, &entry.ReasonCode, &entry.Reason, &entry.Actor, &entry.CreatedAt); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan transaction status history", err)
+		}
+		history = append(history, entry)
+	}
+	if err = rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over transaction status history", err)
+	}
+
+	span.AddEvent("Transaction status history retrieved", trace.WithAttributes(
+		attribute.Int("history.count", len(history)),
+	))
+	return history, nil
+}
@@ -69,7 +69,7 @@ func TestRecordTransaction_Success(t *testing.T) {
 	assert.NoError(t, err)
 
 	mock.ExpectExec("INSERT INTO blnk.transactions").
-		WithArgs(transaction.TransactionID, transaction.ParentTransaction, transaction.Source, transaction.Reference, transaction.AmountString, transaction.PreciseAmount.String(), transaction.Precision, transaction.Rate, transaction.Currency, transaction.Destination, transaction.Description, transaction.Status, transaction.CreatedAt, metaDataJSON, transaction.ScheduledFor, transaction.Hash, transaction.EffectiveDate).
+		WithArgs(transaction.TransactionID, transaction.ParentTransaction, transaction.Source, transaction.Reference, transaction.AmountString, transaction.PreciseAmount.String(), transaction.Precision, transaction.Rate, transaction.Currency, transaction.Destination, transaction.Description, transaction.Status, transaction.CreatedAt, metaDataJSON, transaction.ScheduledFor, transaction.Hash, transaction.EffectiveDate, transaction.TransactionTime, nil, "", "", "").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	result, err := ds.RecordTransaction(ctx, transaction)
@@ -112,7 +112,7 @@ func TestRecordTransaction_Failure(t *testing.T) {
 	assert.NoError(t, err)
 
 	mock.ExpectExec("INSERT INTO blnk.transactions").
-		WithArgs(transaction.TransactionID, transaction.ParentTransaction, transaction.Source, transaction.Reference, transaction.Amount, transaction.PreciseAmount.String(), transaction.Precision, transaction.Rate, transaction.Currency, transaction.Destination, transaction.Description, transaction.Status, transaction.CreatedAt, metaDataJSON, transaction.ScheduledFor, transaction.Hash, transaction.EffectiveDate).
+		WithArgs(transaction.TransactionID, transaction.ParentTransaction, transaction.Source, transaction.Reference, transaction.Amount, transaction.PreciseAmount.String(), transaction.Precision, transaction.Rate, transaction.Currency, transaction.Destination, transaction.Description, transaction.Status, transaction.CreatedAt, metaDataJSON, transaction.ScheduledFor, transaction.Hash, transaction.EffectiveDate, transaction.TransactionTime, nil, "", "", "").
 		WillReturnError(errors.New("db error"))
 
 	_, err = ds.RecordTransaction(ctx, transaction)
@@ -135,10 +135,10 @@ func TestGetTransaction_Success(t *testing.T) {
 	metaDataJSON, err := json.Marshal(metaData)
 	assert.NoError(t, err)
 
-	rows := sqlmock.NewRows([]string{"transaction_id", "source", "reference", "amount", "precise_amount", "precision", "currency", "destination", "description", "status", "created_at", "meta_data", "parent_transaction", "hash"}).
-		AddRow("txn123", "src1", "ref123", 1000, 1000, 2, "USD", "dest1", "Test Transaction", "PENDING", time.Now(), metaDataJSON, "parent123", "hash123")
+	rows := sqlmock.NewRows([]string{"transaction_id", "source", "reference", "amount", "precise_amount", "precision", "currency", "destination", "description", "status", "created_at", "meta_data", "parent_transaction", "hash", "transaction_time", "group_id"}).
+		AddRow("txn123", "src1", "ref123", 1000, 1000, 2, "USD", "dest1", "Test Transaction", "PENDING", time.Now(), metaDataJSON, "parent123", "hash123", nil, "order_1")
 
-	mock.ExpectQuery("SELECT transaction_id, source, reference, amount, precise_amount, precision, currency, destination, description, status, created_at, meta_data, parent_transaction, hash FROM blnk.transactions WHERE transaction_id = ?").
+	mock.ExpectQuery("SELECT transaction_id, source, reference, amount, precise_amount, precision, currency, destination, description, status, created_at, meta_data, parent_transaction, hash, transaction_time, COALESCE\\(group_id, ''\\) FROM blnk.transactions WHERE transaction_id = ?").
 		WithArgs("txn123").
 		WillReturnRows(rows)
 
@@ -149,6 +149,7 @@ func TestGetTransaction_Success(t *testing.T) {
 	assert.Equal(t, "dest1", txn.Destination)
 	assert.Equal(t, "parent123", txn.ParentTransaction)
 	assert.Equal(t, "hash123", txn.Hash)
+	assert.Equal(t, "order_1", txn.GroupID)
 }
 
 func TestGetTransaction_NotFound(t *testing.T) {
@@ -162,7 +163,7 @@ func TestGetTransaction_NotFound(t *testing.T) {
 
 	ds := Datasource{Conn: db}
 
-	mock.ExpectQuery("SELECT transaction_id, source, reference, amount, precise_amount, precision, currency, destination, description, status, created_at, meta_data, parent_transaction, hash FROM blnk.transactions WHERE transaction_id = ?").
+	mock.ExpectQuery("SELECT transaction_id, source, reference, amount, precise_amount, precision, currency, destination, description, status, created_at, meta_data, parent_transaction, hash, transaction_time, COALESCE\\(group_id, ''\\) FROM blnk.transactions WHERE transaction_id = ?").
 		WithArgs("txn123").
 		WillReturnError(sql.ErrNoRows)
 
@@ -273,21 +274,20 @@ func TestGetInflightTransactionsByParentID_Success(t *testing.T) {
 			)
 		)
 		
-		SELECT * FROM inflight_transactions
-		UNION ALL
-		-- Only include queued_inflight if there are no inflight transactions
-		SELECT * FROM queued_inflight_transactions 
-		WHERE NOT EXISTS (SELECT 1 FROM inflight_transactions)
-		
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
+		SELECT * FROM (
+			SELECT * FROM inflight_transactions
+			UNION ALL
+			-- Only include queued_inflight if there are no inflight transactions
+			SELECT * FROM queued_inflight_transactions 
+			WHERE NOT EXISTS (SELECT 1 FROM inflight_transactions)
+		) candidates
+		WHERE TRUE ORDER BY created_at DESC, transaction_id DESC LIMIT $2`
 	// Use regexp.QuoteMeta to escape regex special characters for sqlmock
 	mock.ExpectQuery(regexp.QuoteMeta(expectedQuery)).
-		WithArgs(parentID, 10, int64(0)). // Add all three expected arguments
+		WithArgs(parentID, 10).
 		WillReturnRows(mockRows)
 
-	transactions, err := ds.GetInflightTransactionsByParentID(ctx, parentID, 10, 0)
+	transactions, err := ds.GetInflightTransactionsByParentID(ctx, parentID, model.Page{Limit: 10})
 	assert.NoError(t, err)
 	assert.Len(t, transactions, 1)
 	assert.Equal(t, "txn123", transactions[0].TransactionID)
@@ -335,21 +335,20 @@ func TestGetInflightTransactionsByParentID_NoRows(t *testing.T) {
 			)
 		)
 		
-		SELECT * FROM inflight_transactions
-		UNION ALL
-		-- Only include queued_inflight if there are no inflight transactions
-		SELECT * FROM queued_inflight_transactions 
-		WHERE NOT EXISTS (SELECT 1 FROM inflight_transactions)
-		
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
+		SELECT * FROM (
+			SELECT * FROM inflight_transactions
+			UNION ALL
+			-- Only include queued_inflight if there are no inflight transactions
+			SELECT * FROM queued_inflight_transactions 
+			WHERE NOT EXISTS (SELECT 1 FROM inflight_transactions)
+		) candidates
+		WHERE TRUE ORDER BY created_at DESC, transaction_id DESC LIMIT $2`
 	// Use regexp.QuoteMeta to escape regex special characters for sqlmock
 	mock.ExpectQuery(regexp.QuoteMeta(expectedQuery)).
-		WithArgs(parentID, 10, int64(0)). // Add all three expected arguments
+		WithArgs(parentID, 10).
 		WillReturnRows(sqlmock.NewRows([]string{}))
 
-	transactions, err := ds.GetInflightTransactionsByParentID(ctx, parentID, 10, 0)
+	transactions, err := ds.GetInflightTransactionsByParentID(ctx, parentID, model.Page{Limit: 10})
 	assert.NoError(t, err)
 	assert.Empty(t, transactions)
 }
@@ -395,21 +394,20 @@ func TestGetInflightTransactionsByParentID_Error(t *testing.T) {
 			)
 		)
 		
-		SELECT * FROM inflight_transactions
-		UNION ALL
-		-- Only include queued_inflight if there are no inflight transactions
-		SELECT * FROM queued_inflight_transactions 
-		WHERE NOT EXISTS (SELECT 1 FROM inflight_transactions)
-		
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
+		SELECT * FROM (
+			SELECT * FROM inflight_transactions
+			UNION ALL
+			-- Only include queued_inflight if there are no inflight transactions
+			SELECT * FROM queued_inflight_transactions 
+			WHERE NOT EXISTS (SELECT 1 FROM inflight_transactions)
+		) candidates
+		WHERE TRUE ORDER BY created_at DESC, transaction_id DESC LIMIT $2`
 	// Use regexp.QuoteMeta to escape regex special characters for sqlmock
 	mock.ExpectQuery(regexp.QuoteMeta(expectedQuery)).
-		WithArgs(parentID, 10, int64(0)). // Add all three expected arguments
+		WithArgs(parentID, 10).
 		WillReturnError(errors.New("database error"))
 
-	transactions, err := ds.GetInflightTransactionsByParentID(ctx, parentID, 10, 0)
+	transactions, err := ds.GetInflightTransactionsByParentID(ctx, parentID, model.Page{Limit: 10})
 	assert.Error(t, err)
 	assert.Nil(t, transactions)
 	apiErr, ok := err.(apierror.APIError)
@@ -487,3 +485,210 @@ func TestGetTotalCommittedTransactions_Error(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, apierror.ErrInternalServer, apiErr.Code)
 }
+
+func TestGetAllTransactions_HistoryOrder(t *testing.T) {
+	tests := []struct {
+		historyOrder string
+		orderBy      string
+	}{
+		{historyOrder: "", orderBy: "ORDER BY created_at DESC, transaction_id DESC"},
+		{historyOrder: "created_at", orderBy: "ORDER BY created_at DESC, transaction_id DESC"},
+		{historyOrder: "transaction_time", orderBy: "ORDER BY COALESCE(transaction_time, created_at) DESC, transaction_id DESC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.historyOrder, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			ds := Datasource{Conn: db, HistoryOrder: tt.historyOrder}
+
+			clientTime := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+			rows := sqlmock.NewRows([]string{"transaction_id", "source", "reference", "amount", "currency", "destination", "description", "status", "hash", "created_at", "meta_data", "transaction_time", "group_id"}).
+				AddRow("txn1", "src1", "ref1", 100, "USD", "dest1", "Offline sale", "APPLIED", "hash1", clientTime.Add(time.Hour), []byte(`{}`), clientTime, "").
+				AddRow("txn2", "src1", "ref2", 100, "USD", "dest1", "Online sale", "APPLIED", "hash2", clientTime, []byte(`{}`), nil, "")
+
+			mock.ExpectQuery(regexp.QuoteMeta(tt.orderBy)).
+				WithArgs(10).
+				WillReturnRows(rows)
+
+			transactions, err := ds.GetAllTransactions(context.Background(), model.Page{Limit: 10})
+			assert.NoError(t, err)
+			assert.Len(t, transactions, 2)
+			assert.Equal(t, &clientTime, transactions[0].TransactionTime)
+			assert.Nil(t, transactions[1].TransactionTime)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetTransactionsByGroup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	createdAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"transaction_id", "parent_transaction", "source", "reference", "amount", "precise_amount", "precision", "rate", "currency", "destination", "description", "status", "created_at", "meta_data", "scheduled_for", "hash", "transaction_time", "group_id"}).
+		AddRow("txn1", "", "bln_customer", "ref1", 100, "10000", 100, 1, "USD", "bln_merchant", "Payment", "APPLIED", createdAt, []byte(`{}`), time.Time{}, "hash1", nil, "order_1").
+		AddRow("txn2", "", "bln_merchant", "ref2", 3, "300", 100, 1, "USD", "@fees", "Fee", "APPLIED", createdAt.Add(time.Minute), []byte(`{}`), time.Time{}, "hash2", nil, "order_1")
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE group_id = $1 AND TRUE ORDER BY created_at ASC, transaction_id ASC LIMIT $2")).
+		WithArgs("order_1", 50).
+		WillReturnRows(rows)
+
+	transactions, err := ds.GetTransactionsByGroup(context.Background(), "order_1", model.Page{Limit: 50})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+	assert.Equal(t, "order_1", transactions[0].GroupID)
+	assert.Equal(t, "10000", transactions[0].PreciseAmount.String())
+	assert.Equal(t, "txn2", transactions[1].TransactionID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTransactionsByGroup_AfterCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db, HistoryOrder: "transaction_time"}
+
+	createdAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"transaction_id", "parent_transaction", "source", "reference", "amount", "precise_amount", "precision", "rate", "currency", "destination", "description", "status", "created_at", "meta_data", "scheduled_for", "hash", "transaction_time", "group_id"}).
+		AddRow("txn3", "", "bln_merchant", "ref3", 3, "300", 100, 1, "USD", "@fees", "Fee", "APPLIED", createdAt, []byte(`{}`), time.Time{}, "hash3", nil, "order_1")
+
+	after := &model.PageCursor{Time: createdAt.Add(-time.Minute), ID: "txn2"}
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE group_id = $1 AND (COALESCE(transaction_time, created_at), transaction_id) > ($2, $3) ORDER BY COALESCE(transaction_time, created_at) ASC, transaction_id ASC LIMIT $4")).
+		WithArgs("order_1", after.Time, after.ID, 50).
+		WillReturnRows(rows)
+
+	transactions, err := ds.GetTransactionsByGroup(context.Background(), "order_1", model.Page{Limit: 50, After: after})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, "txn3", transactions[0].TransactionID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRefundableTransactionsByParentID_AfterCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	createdAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"transaction_id", "parent_transaction", "source", "reference", "amount", "precise_amount", "precision", "rate", "currency", "destination", "description", "status", "created_at", "meta_data", "scheduled_for", "hash"}).
+		AddRow("txn1", "parent123", "bln_customer", "ref1", 100, "10000", 100, 1, "USD", "bln_merchant", "Payment", "APPLIED", createdAt, []byte(`{}`), time.Time{}, "hash1")
+
+	after := &model.PageCursor{Time: createdAt.Add(time.Minute), ID: "txn2"}
+	mock.ExpectQuery(regexp.QuoteMeta(") AND (created_at, transaction_id) < ($2, $3) ORDER BY created_at DESC, transaction_id DESC LIMIT $4")).
+		WithArgs("parent123", after.Time, after.ID, 50).
+		WillReturnRows(rows)
+
+	transactions, err := ds.GetRefundableTransactionsByParentID(context.Background(), "parent123", model.Page{Limit: 50, After: after})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, "txn1", transactions[0].TransactionID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTransactionsByIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	createdAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"transaction_id", "parent_transaction", "source", "reference", "amount", "precise_amount", "precision", "rate", "currency", "destination", "description", "status", "created_at", "meta_data", "scheduled_for", "hash", "transaction_time", "group_id"}).
+		AddRow("txn2", "", "bln_wallet", "ref2", 5, "500", 100, 1, "USD", "bln_merchant", "Purchase", "APPLIED", createdAt.Add(time.Hour), []byte(`{}`), time.Time{}, "hash2", nil, "").
+		AddRow("txn1", "", "@funding", "ref1", 100, "10000", 100, 1, "USD", "bln_wallet", "Top up", "APPLIED", createdAt, []byte(`{}`), time.Time{}, "hash1", nil, "")
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE (source IN (SELECT balance_id FROM blnk.balances WHERE identity_id = $1)")).
+		WithArgs("idt_1", 20).
+		WillReturnRows(rows)
+
+	transactions, err := ds.GetTransactionsByIdentity(context.Background(), "idt_1", model.Page{Limit: 20})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+	assert.Equal(t, "txn2", transactions[0].TransactionID)
+	assert.Equal(t, "bln_wallet", transactions[1].Destination)
+	assert.Equal(t, "10000", transactions[1].PreciseAmount.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPendingInflightCredits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	createdAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"transaction_id", "reference", "source", "currency", "precision", "precise_amount", "pending_amount", "created_at"}).
+		AddRow("txn1", "ref1", "bln_src", "USD", 100, "10000", "2500", createdAt)
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE t.destination = $1 AND t.status = 'INFLIGHT'")).
+		WithArgs("bln_dest", 100).
+		WillReturnRows(rows)
+
+	credits, err := ds.GetPendingInflightCredits(context.Background(), "bln_dest", 100)
+	assert.NoError(t, err)
+	assert.Len(t, credits, 1)
+	assert.Equal(t, "txn1", credits[0].TransactionID)
+	assert.Equal(t, big.NewInt(10000), credits[0].PreciseAmount)
+	assert.Equal(t, big.NewInt(2500), credits[0].PendingAmount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordTransaction_RecordsStatusChange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	transaction := &model.Transaction{
+		TransactionID: "txn123",
+		Status:        "REJECTED",
+		PreciseAmount: model.Int64ToBigInt(1000),
+		StatusChange:  model.StatusChange{ReasonCode: model.ReasonInsufficientFunds, Reason: "insufficient funds in source balance", Actor: model.ActorSystem},
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.transaction_status_history(transaction_id, parent_transaction, status, reason_code, reason, actor)")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), "REJECTED", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), model.ReasonInsufficientFunds, "insufficient funds in source balance", model.ActorSystem).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	_, err = ds.RecordTransaction(context.Background(), transaction)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTransactionStatusHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+
+	createdAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"transaction_id", "parent_transaction", "status", "reason_code", "reason", "actor", "created_at"}).
+		AddRow("txn1", "", "INFLIGHT", "", "", "owner_1", createdAt).
+		AddRow("txn2", "txn1", "VOID", model.ReasonInflightExpired, "", model.ActorSystem, createdAt.Add(time.Hour))
+
+	mock.ExpectQuery(regexp.QuoteMeta("WITH RECURSIVE lineage(transaction_id)")).
+		WithArgs("txn1").
+		WillReturnRows(rows)
+
+	history, err := ds.GetTransactionStatusHistory(context.Background(), "txn1")
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "INFLIGHT", history[0].Status)
+	assert.Equal(t, "txn1", history[1].ParentTransaction)
+	assert.Equal(t, model.ReasonInflightExpired, history[1].ReasonCode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
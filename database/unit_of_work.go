@@ -0,0 +1,129 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	pgconn "github.com/blnkfinance/blnk/internal/pg-conn"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+// CommitUnitOfWork writes a unit of work in one database transaction: its ledgers, identities and balances are
+// inserted, the existing balances it posts to are updated and its transactions are recorded. Either all of it
+// is written or, if any write fails, none of it.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - work: The unit of work to write.
+// Returns:
+// - An error if any write fails. A conflict is reported when a resource already exists or an existing balance
+// changed since it was read.
+func (d Datasource) CommitUnitOfWork(ctx context.Context, work *model.UnitOfWork) error {
+	ctx, span := otel.Tracer("unit_of_work.database").Start(ctx, "Committing unit of work")
+	defer span.End()
+
+	tx, err := d.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to begin transaction", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := writeUnitOfWork(ctx, tx, work); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to commit unit of work", err)
+	}
+	return nil
+}
+
+func writeUnitOfWork(ctx context.Context, tx *sql.Tx, work *model.UnitOfWork) error {
+	for _, ledger := range work.Ledgers {
+		metaDataJSON, err := json.Marshal(ledger.MetaData)
+		if err != nil {
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
+		}
+		if err := insertLedger(ctx, tx, ledger, metaDataJSON); err != nil {
+			return unitOfWorkError(err, "ledger", ledger.LedgerID)
+		}
+	}
+
+	for _, identity := range work.Identities {
+		metaDataJSON, err := json.Marshal(identity.MetaData)
+		if err != nil {
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
+		}
+		preferencesJSON, err := marshalCommunicationPreferences(identity.CommunicationPreferences)
+		if err != nil {
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal communication preferences", err)
+		}
+		if err := insertIdentity(ctx, tx, identity, metaDataJSON, preferencesJSON); err != nil {
+			return unitOfWorkError(err, "identity", identity.IdentityID)
+		}
+	}
+
+	for _, balance := range work.Balances {
+		metaDataJSON, err := json.Marshal(balance.MetaData)
+		if err != nil {
+			return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to marshal metadata", err)
+		}
+		if err := insertBalance(ctx, tx, balance, metaDataJSON); err != nil {
+			return unitOfWorkError(err, "balance", balance.BalanceID)
+		}
+	}
+
+	for _, balance := range work.UpdatedBalances {
+		if err := updateBalance(ctx, tx, balance); err != nil {
+			return err
+		}
+	}
+
+	for _, txn := range work.Transactions {
+		if _, err := recordTransaction(ctx, tx, txn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unitOfWorkError maps a failed insert of a unit of work's resource to an API error.
+func unitOfWorkError(err error, resource, id string) error {
+	if pgErr, ok := pgconn.AsError(err); ok {
+		message := pgErr.Message
+		if pgErr.Detail != "" {
+			message += ": " + pgErr.Detail
+		}
+		switch pgErr.Code {
+		case pgconn.UniqueViolation:
+			return apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Failed to create %s '%s': %s", resource, id, message), err)
+		case pgconn.ForeignKeyViolation:
+			return apierror.NewAPIError(apierror.ErrBadRequest, fmt.Sprintf("Failed to create %s '%s': %s", resource, id, message), err)
+		}
+	}
+	return apierror.NewAPIError(apierror.ErrInternalServer, fmt.Sprintf("Failed to create %s '%s'", resource, id), err)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"math/big"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	pgxconn "github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unitOfWorkTestBalance(id string) *model.Balance {
+	return &model.Balance{BalanceID: id, LedgerID: "ldg_1", Currency: "USD", Balance: big.NewInt(0), CreditBalance: big.NewInt(0),
+		DebitBalance: big.NewInt(0), InflightBalance: big.NewInt(0), InflightCreditBalance: big.NewInt(0), InflightDebitBalance: big.NewInt(0), CreatedAt: time.Now()}
+}
+
+func TestCommitUnitOfWork(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	existing := unitOfWorkTestBalance("bln_existing")
+	existing.Version = 3
+	work := &model.UnitOfWork{
+		Ledgers:         []*model.Ledger{{LedgerID: "ldg_1", Name: "Customers", CreatedAt: time.Now()}},
+		Balances:        []*model.Balance{unitOfWorkTestBalance("bln_new")},
+		UpdatedBalances: []*model.Balance{existing},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.ledgers")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.balances")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE blnk.balances")).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, ds.CommitUnitOfWork(context.Background(), work))
+	assert.Equal(t, int64(4), existing.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCommitUnitOfWork_RollsBackOnFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	work := &model.UnitOfWork{
+		Ledgers:  []*model.Ledger{{LedgerID: "ldg_1", Name: "Customers", CreatedAt: time.Now()}},
+		Balances: []*model.Balance{unitOfWorkTestBalance("bln_new")},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.ledgers")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.balances")).WillReturnError(&pq.Error{Code: "23505", Message: "duplicate key"})
+	mock.ExpectRollback()
+
+	err = ds.CommitUnitOfWork(context.Background(), work)
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrConflict, apiErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCommitUnitOfWork_PgxErrorDetail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	ds := Datasource{Conn: db}
+
+	work := &model.UnitOfWork{Ledgers: []*model.Ledger{{LedgerID: "ldg_missing", Name: "Customers", CreatedAt: time.Now()}}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO blnk.ledgers")).
+		WillReturnError(&pgxconn.PgError{Code: "23503", Message: "violates foreign key constraint", Detail: "Key (tenant_id)=(acme) is not present."})
+	mock.ExpectRollback()
+
+	err = ds.CommitUnitOfWork(context.Background(), work)
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrBadRequest, apiErr.Code)
+	assert.Contains(t, apiErr.Message, "Key (tenant_id)=(acme) is not present.")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+// warehouseSource describes the table an entity is exported from.
+type warehouseSource struct {
+	table   string
+	key     string
+	columns []string
+}
+
+// warehouseSources lists the columns exported for each entity. updated_at is always last.
+var warehouseSources = map[string]warehouseSource{
+	model.WarehouseTransactions: {
+		table: "blnk.transactions",
+		key:   "transaction_id",
+		columns: []string{"transaction_id", "parent_transaction", "group_id", "source", "destination", "reference",
+			"amount", "precise_amount", "precision", "rate", "currency", "description", "status", "hash", "meta_data",
+			"effective_date", "created_at", "updated_at"},
+	},
+	model.WarehouseBalances: {
+		table: "blnk.balances",
+		key:   "balance_id",
+		columns: []string{"balance_id", "ledger_id", "identity_id", "indicator", "currency", "currency_multiplier",
+			"balance", "credit_balance", "debit_balance", "inflight_balance", "inflight_credit_balance",
+			"inflight_debit_balance", "version", "meta_data", "created_at", "updated_at"},
+	},
+	model.WarehouseIdentities: {
+		table: "blnk.identity",
+		key:   "identity_id",
+		columns: []string{"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob",
+			"email_address", "phone_number", "nationality", "organization_name", "category", "street", "country",
+			"state", "post_code", "city", "meta_data", "verification_status", "verified_at", "created_at", "updated_at", "deleted_at"},
+	},
+}
+
+// GetWarehouseBatch reads the next batch of rows of an entity to export: the rows after the watermark, in
+// updated_at and ID order, that were last written before until. Amounts and metadata are read as text so
+// they reach the warehouse without losing precision.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - entity string: The entity to read, one of model.WarehouseEntities.
+// - after model.WarehouseWatermark: The position the entity was exported up to.
+// - until time.Time: Rows written at or after this time are left for a later batch.
+// - limit int: The maximum number of rows to read.
+//
+// Returns:
+// - *model.WarehouseBatch: The rows read, which may be none.
+// - error: An error if the entity is unknown or the rows could not be read.
+func (d Datasource) GetWarehouseBatch(ctx context.Context, entity string, after model.WarehouseWatermark, until time.Time, limit int) (*model.WarehouseBatch, error) {
+	ctx, span := otel.Tracer("warehouse.database").Start(ctx, "GetWarehouseBatch")
+	defer span.End()
+
+	source, ok := warehouseSources[entity]
+	if !ok {
+		return nil, apierror.NewAPIError(apierror.ErrBadRequest, fmt.Sprintf("unknown warehouse entity %q", entity), nil)
+	}
+
+	rows, err := d.Conn.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE updated_at < $1 AND (updated_at, %s) > ($2, $3)
+		ORDER BY updated_at ASC, %s ASC
+		LIMIT $4
+	`, strings.Join(source.columns, ", "), source.table, source.key, source.key), until, after.At, after.ID, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve warehouse rows", err)
+	}
+	defer rows.Close()
+
+	batch := &model.WarehouseBatch{Entity: entity, Columns: source.columns, Rows: [][]interface{}{}, Last: after}
+	for rows.Next() {
+		values := make([]interface{}, len(source.columns))
+		dest := make([]interface{}, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan warehouse row", err)
+		}
+		for i, value := range values {
+			// Text, numeric and JSON columns are read as bytes
+			if b, ok := value.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+
+		id, _ := values[0].(string)
+		updatedAt, _ := values[len(values)-1].(time.Time)
+		batch.Rows = append(batch.Rows, values)
+		batch.RowIDs = append(batch.RowIDs, fmt.Sprintf("%s@%d", id, updatedAt.UnixMicro()))
+		batch.Last = model.WarehouseWatermark{At: updatedAt, ID: id}
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over warehouse rows", err)
+	}
+	return batch, nil
+}
+
+// GetWarehouseSyncState retrieves how far an entity has been exported. An entity that was never exported
+// has an empty state.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - entity string: The entity.
+//
+// Returns:
+// - *model.WarehouseSyncState: The state of the entity.
+// - error: An error if the state could not be retrieved.
+func (d Datasource) GetWarehouseSyncState(ctx context.Context, entity string) (*model.WarehouseSyncState, error) {
+	ctx, span := otel.Tracer("warehouse.database").Start(ctx, "GetWarehouseSyncState")
+	defer span.End()
+
+	states, err := d.queryWarehouseSyncStates(ctx, "WHERE entity = $1", entity)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if len(states) == 0 {
+		return &model.WarehouseSyncState{Entity: entity}, nil
+	}
+	return states[0], nil
+}
+
+// ListWarehouseSyncStates lists the sync states of the entities that have been exported.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - []*model.WarehouseSyncState: The states, ordered by entity.
+// - error: An error if the states could not be retrieved.
+func (d Datasource) ListWarehouseSyncStates(ctx context.Context) ([]*model.WarehouseSyncState, error) {
+	ctx, span := otel.Tracer("warehouse.database").Start(ctx, "ListWarehouseSyncStates")
+	defer span.End()
+
+	states, err := d.queryWarehouseSyncStates(ctx, "")
+	if err != nil {
+		span.RecordError(err)
+	}
+	return states, err
+}
+
+// SaveWarehouseSyncState saves how far an entity has been exported.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - state *model.WarehouseSyncState: The state to save.
+//
+// Returns:
+// - error: An error if the state could not be saved.
+func (d Datasource) SaveWarehouseSyncState(ctx context.Context, state *model.WarehouseSyncState) error {
+	ctx, span := otel.Tracer("warehouse.database").Start(ctx, "SaveWarehouseSyncState")
+	defer span.End()
+
+	var watermarkAt *time.Time
+	if !state.Watermark.At.IsZero() {
+		watermarkAt = &state.Watermark.At
+	}
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.warehouse_sync_state (entity, watermark_at, watermark_id, rows_synced, last_synced_at, last_error)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		ON CONFLICT (entity) DO UPDATE
+		SET watermark_at = EXCLUDED.watermark_at, watermark_id = EXCLUDED.watermark_id, rows_synced = EXCLUDED.rows_synced,
+			last_synced_at = EXCLUDED.last_synced_at, last_error = EXCLUDED.last_error
+	`, state.Entity, watermarkAt, state.Watermark.ID, state.RowsSynced, state.LastSyncedAt, state.LastError)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to save warehouse sync state", err)
+	}
+	return nil
+}
+
+func (d Datasource) queryWarehouseSyncStates(ctx context.Context, where string, args ...interface{}) ([]*model.WarehouseSyncState, error) {
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT entity, watermark_at, watermark_id, rows_synced, last_synced_at, COALESCE(last_error, '')
+		FROM blnk.warehouse_sync_state
+		`+where+`
+		ORDER BY entity ASC
+	`, args...)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to retrieve warehouse sync state", err)
+	}
+	defer rows.Close()
+
+	states := []*model.WarehouseSyncState{}
+	for rows.Next() {
+		state := &model.WarehouseSyncState{}
+		var watermarkAt, lastSyncedAt sql.NullTime
+		if err := rows.Scan(&state.Entity, &watermarkAt, &state.Watermark.ID, &state.RowsSynced, &lastSyncedAt, &state.LastError); err != nil {
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan warehouse sync state", err)
+		}
+		if watermarkAt.Valid {
+			state.Watermark.At = watermarkAt.Time
+		}
+		if lastSyncedAt.Valid {
+			state.LastSyncedAt = &lastSyncedAt.Time
+		}
+		states = append(states, state)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over warehouse sync states", err)
+	}
+	return states, nil
+}
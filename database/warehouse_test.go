@@ -0,0 +1,77 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetWarehouseBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+	after := model.WarehouseWatermark{At: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), ID: "idt_1"}
+	until := after.At.Add(time.Hour)
+	updatedAt := after.At.Add(time.Minute)
+
+	columns := warehouseSources[model.WarehouseIdentities].columns
+	values := make([]driver.Value, len(columns))
+	values[0] = "idt_2"
+	values[2] = []byte("Ada")
+	values[len(values)-1] = updatedAt
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.identity\n\t\tWHERE updated_at < $1 AND (updated_at, identity_id) > ($2, $3)")).
+		WithArgs(until, after.At, after.ID, 100).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(values...))
+
+	batch, err := ds.GetWarehouseBatch(context.Background(), model.WarehouseIdentities, after, until, 100)
+	require.NoError(t, err)
+	require.Len(t, batch.Rows, 1)
+	assert.Equal(t, "Ada", batch.Rows[0][2])
+	assert.Equal(t, model.WarehouseWatermark{At: updatedAt, ID: "idt_2"}, batch.Last)
+	assert.Equal(t, []string{"idt_2@1772323260000000"}, batch.RowIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = ds.GetWarehouseBatch(context.Background(), "ledgers", after, until, 100)
+	assert.Error(t, err)
+}
+
+func TestGetWarehouseSyncState_NeverSynced(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ds := Datasource{Conn: db}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM blnk.warehouse_sync_state")).
+		WithArgs(model.WarehouseBalances).
+		WillReturnRows(sqlmock.NewRows([]string{"entity", "watermark_at", "watermark_id", "rows_synced", "last_synced_at", "last_error"}))
+
+	state, err := ds.GetWarehouseSyncState(context.Background(), model.WarehouseBalances)
+	require.NoError(t, err)
+	assert.Equal(t, &model.WarehouseSyncState{Entity: model.WarehouseBalances}, state)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
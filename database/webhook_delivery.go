@@ -0,0 +1,165 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"go.opentelemetry.io/otel"
+)
+
+// RecordWebhookDelivery saves the record of a webhook delivery.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - delivery: The delivery to record.
+// Returns:
+// - An error if the insert fails.
+func (d Datasource) RecordWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	ctx, span := otel.Tracer("webhook_delivery.database").Start(ctx, "Recording webhook delivery")
+	defer span.End()
+
+	statusCode := sql.NullInt64{Int64: int64(delivery.StatusCode), Valid: delivery.StatusCode != 0}
+	_, err := d.Conn.ExecContext(ctx, `
+		INSERT INTO blnk.webhook_deliveries (delivery_id, endpoint_id, event, succeeded, status_code, latency_ms, error, delivered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`,
+		delivery.DeliveryID, delivery.EndpointID, delivery.Event, delivery.Succeeded, statusCode, delivery.LatencyMs,
+		nullString(delivery.Error), delivery.DeliveredAt,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return apierror.NewAPIError(apierror.ErrInternalServer, "Failed to record webhook delivery", err)
+	}
+	return nil
+}
+
+// GetWebhookAnalytics summarises the deliveries to a webhook endpoint made within a time range.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - endpointID: The ID of the endpoint.
+// - from: Deliveries made at or after this time are summarised.
+// - to: Deliveries made before this time are summarised.
+// Returns:
+// - The counts, latency percentiles, failures by status and volume by event of the deliveries, or an error if
+// a query fails.
+func (d Datasource) GetWebhookAnalytics(ctx context.Context, endpointID string, from, to time.Time) (*model.WebhookAnalytics, error) {
+	ctx, span := otel.Tracer("webhook_delivery.database").Start(ctx, "Getting webhook analytics")
+	defer span.End()
+
+	analytics := &model.WebhookAnalytics{
+		EndpointID:       endpointID,
+		From:             from,
+		To:               to,
+		FailuresByStatus: map[string]int64{},
+		Events:           []model.WebhookEventVolume{},
+	}
+
+	err := d.Conn.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE succeeded),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY latency_ms), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY latency_ms), 0)
+		FROM blnk.webhook_deliveries
+		WHERE endpoint_id = $1 AND delivered_at >= $2 AND delivered_at < $3
+	`, endpointID, from, to).Scan(&analytics.Deliveries, &analytics.Succeeded, &analytics.LatencyP50Ms, &analytics.LatencyP95Ms)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to summarise webhook deliveries", err)
+	}
+	analytics.Failed = analytics.Deliveries - analytics.Succeeded
+	if analytics.Deliveries > 0 {
+		analytics.SuccessRate = float64(analytics.Succeeded) / float64(analytics.Deliveries)
+	}
+
+	rows, err := d.Conn.QueryContext(ctx, `
+		SELECT status_code, COUNT(*)
+		FROM blnk.webhook_deliveries
+		WHERE endpoint_id = $1 AND delivered_at >= $2 AND delivered_at < $3 AND NOT succeeded
+		GROUP BY status_code
+	`, endpointID, from, to)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to summarise webhook failures", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var statusCode sql.NullInt64
+		var count int64
+		if err := rows.Scan(&statusCode, &count); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan webhook failures", err)
+		}
+		status := model.WebhookFailuresNoResponse
+		if statusCode.Valid {
+			status = strconv.FormatInt(statusCode.Int64, 10)
+		}
+		analytics.FailuresByStatus[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over webhook failures", err)
+	}
+
+	eventRows, err := d.Conn.QueryContext(ctx, `
+		SELECT event, COUNT(*), COUNT(*) FILTER (WHERE succeeded)
+		FROM blnk.webhook_deliveries
+		WHERE endpoint_id = $1 AND delivered_at >= $2 AND delivered_at < $3
+		GROUP BY event
+		ORDER BY COUNT(*) DESC, event
+	`, endpointID, from, to)
+	if err != nil {
+		span.RecordError(err)
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to summarise webhook events", err)
+	}
+	defer eventRows.Close()
+	for eventRows.Next() {
+		var volume model.WebhookEventVolume
+		if err := eventRows.Scan(&volume.Event, &volume.Deliveries, &volume.Succeeded); err != nil {
+			span.RecordError(err)
+			return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to scan webhook events", err)
+		}
+		volume.Failed = volume.Deliveries - volume.Succeeded
+		analytics.Events = append(analytics.Events, volume)
+	}
+	if err := eventRows.Err(); err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInternalServer, "Error occurred while iterating over webhook events", err)
+	}
+	return analytics, nil
+}
+
+// PruneWebhookDeliveries deletes the records of webhook deliveries made before a time.
+// Parameters:
+// - ctx: Context for managing request and tracing.
+// - before: Deliveries made before this time are deleted.
+// Returns:
+// - The number of records deleted, or an error if the delete fails.
+func (d Datasource) PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := otel.Tracer("webhook_delivery.database").Start(ctx, "Pruning webhook deliveries")
+	defer span.End()
+
+	result, err := d.Conn.ExecContext(ctx, `DELETE FROM blnk.webhook_deliveries WHERE delivered_at < $1`, before)
+	if err != nil {
+		span.RecordError(err)
+		return 0, apierror.NewAPIError(apierror.ErrInternalServer, "Failed to prune webhook deliveries", err)
+	}
+	deleted, _ := result.RowsAffected()
+	return deleted, nil
+}
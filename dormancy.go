@@ -0,0 +1,405 @@
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	redlock "github.com/blnkfinance/blnk/internal/lock"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// escheatmentBatchMetaKey marks the transfers of an escheatment batch so they are allowed from frozen balances.
+	escheatmentBatchMetaKey = "BLNK_ESCHEATMENT_BATCH"
+
+	frozenBalanceCacheTTL  = 30 * time.Second
+	dormancyScanBatchSize  = 500
+	escheatmentLockTimeout = 10 * time.Minute
+)
+
+// frozenBalanceCache keeps the IDs of balances frozen for dormancy in memory so that postings do not query
+// them for every transaction. Balances frozen or reactivated on another instance are picked up within
+// frozenBalanceCacheTTL.
+type frozenBalanceCache struct {
+	mu       sync.Mutex
+	ids      map[string]bool
+	loadedAt time.Time
+}
+
+// frozenBalances returns the cached frozen balance IDs, reloading them when the cache is stale. A failed
+// reload keeps the previous IDs.
+func (l *Blnk) frozenBalances(ctx context.Context) map[string]bool {
+	if l.frozen == nil {
+		return nil
+	}
+	l.frozen.mu.Lock()
+	defer l.frozen.mu.Unlock()
+
+	if time.Since(l.frozen.loadedAt) < frozenBalanceCacheTTL {
+		return l.frozen.ids
+	}
+	l.frozen.loadedAt = time.Now()
+
+	ids, err := l.datasource.ListFrozenBalanceIDs(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to load frozen balances")
+		return l.frozen.ids
+	}
+	frozen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		frozen[id] = true
+	}
+	l.frozen.ids = frozen
+	return frozen
+}
+
+// invalidateFrozenBalances forces the next posting to reload the frozen balances.
+func (l *Blnk) invalidateFrozenBalances() {
+	if l.frozen == nil {
+		return
+	}
+	l.frozen.mu.Lock()
+	l.frozen.loadedAt = time.Time{}
+	l.frozen.mu.Unlock()
+}
+
+// checkFrozenBalances rejects a transaction to or from a balance that is frozen for dormancy. Freezing is
+// only enforced while it is enabled, and the transfers of escheatment batches are always allowed.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - transaction *model.Transaction: The transaction with its source and destination balance IDs resolved.
+//
+// Returns:
+// - error: An error if the source or destination is frozen.
+func (l *Blnk) checkFrozenBalances(ctx context.Context, transaction *model.Transaction) error {
+	cnf, err := config.Fetch()
+	if err != nil || !cnf.Dormancy.Freeze {
+		return nil
+	}
+	if _, ok := transaction.MetaData[escheatmentBatchMetaKey]; ok {
+		return nil
+	}
+
+	frozen := l.frozenBalances(ctx)
+	for _, id := range []string{transaction.Source, transaction.Destination} {
+		if frozen[id] {
+			return fmt.Errorf("balance %s is frozen for dormancy and must be reactivated first", id)
+		}
+	}
+	return nil
+}
+
+// RunDormancyScan flags the balances that had no transactions for the configured inactivity period as
+// dormant, freezing them when freezing is enabled, and clears the flag of dormant balances that had
+// transactions since they were flagged. It does nothing when dormancy tracking is disabled.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - *model.DormancyScan: The number of balances flagged and reactivated.
+// - error: An error if the scan could not be completed.
+func (l *Blnk) RunDormancyScan(ctx context.Context) (*model.DormancyScan, error) {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	scan := &model.DormancyScan{}
+	if cnf.Dormancy.InactivityPeriod <= 0 {
+		return scan, nil
+	}
+
+	locker := redlock.NewLocker(l.redis, "dormancy-scan", model.GenerateUUIDWithSuffix("loc"))
+	if err := locker.Lock(ctx, escheatmentLockTimeout); err != nil {
+		return nil, fmt.Errorf("failed to acquire lock for dormancy scan: %w", err)
+	}
+	defer l.releaseLock(ctx, locker)
+
+	reactivated, err := l.datasource.ClearReactivatedBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, balance := range reactivated {
+		l.sendDormancyWebhook("balance.reactivated", balance)
+	}
+	scan.Reactivated = len(reactivated)
+
+	now := time.Now().UTC()
+	inactiveSince := now.Add(-cnf.Dormancy.InactivityPeriod)
+	for {
+		balances, err := l.datasource.FindInactiveBalances(ctx, inactiveSince, dormancyScanBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, balance := range balances {
+			balance.DormantSince = now
+			balance.Frozen = cnf.Dormancy.Freeze
+			flagged, err := l.datasource.FlagDormantBalance(ctx, balance)
+			if err != nil {
+				return nil, err
+			}
+			if flagged {
+				scan.Flagged++
+				l.sendDormancyWebhook("balance.dormant", balance)
+			}
+		}
+		if len(balances) < dormancyScanBatchSize {
+			break
+		}
+	}
+
+	if scan.Flagged > 0 && cnf.Dormancy.Freeze {
+		l.invalidateFrozenBalances()
+	}
+	return scan, nil
+}
+
+// ReactivateBalance clears the dormancy flag of a balance and unfreezes it. A balance that is part of an
+// escheatment batch that has not transferred its funds cannot be reactivated.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - balanceID string: The ID of the dormant balance.
+//
+// Returns:
+// - *model.DormantBalance: The dormancy record that was cleared.
+// - error: An error if the balance is not dormant or is held by a pending escheatment batch.
+func (l *Blnk) ReactivateBalance(ctx context.Context, balanceID string) (*model.DormantBalance, error) {
+	balance, err := l.datasource.GetDormantBalance(ctx, balanceID)
+	if err != nil {
+		return nil, err
+	}
+	if balance.BatchID != "" && balance.Status == model.DormantBalanceDormant {
+		return nil, fmt.Errorf("balance %s is part of escheatment batch %s", balanceID, balance.BatchID)
+	}
+
+	if err := l.datasource.DeleteDormantBalance(ctx, balanceID); err != nil {
+		return nil, err
+	}
+	l.invalidateFrozenBalances()
+	l.sendDormancyWebhook("balance.reactivated", balance)
+	return balance, nil
+}
+
+// ListDormantBalances lists dormant balances, longest dormant first.
+func (l *Blnk) ListDormantBalances(ctx context.Context, status string, page model.Page) ([]*model.DormantBalance, error) {
+	return l.datasource.ListDormantBalances(ctx, status, page)
+}
+
+// CreateEscheatmentBatch creates a pending batch for the dormant balances of a currency that hold funds.
+// The batch's report can be reviewed before it is applied.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - req model.EscheatmentBatchRequest: The currency and dormancy cutoff of the balances to include.
+//
+// Returns:
+// - *model.EscheatmentBatch: The pending batch with an item for each balance it includes.
+// - error: An error if the request is invalid or the batch could not be created.
+func (l *Blnk) CreateEscheatmentBatch(ctx context.Context, req model.EscheatmentBatchRequest) (*model.EscheatmentBatch, error) {
+	if req.Currency == "" {
+		return nil, errors.New("currency is required")
+	}
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &model.EscheatmentBatch{
+		BatchID:        model.GenerateUUIDWithSuffix("esch"),
+		Currency:       req.Currency,
+		HoldingBalance: cnf.Dormancy.HoldingBalance,
+		Items:          []model.EscheatmentItem{},
+		TotalAmount:    big.NewInt(0),
+		Status:         model.EscheatmentBatchPending,
+		CreatedAt:      time.Now().UTC(),
+	}
+	if err := l.datasource.CreateEscheatmentBatch(ctx, batch, req.DormantBefore); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// GetEscheatmentBatch retrieves an escheatment batch by ID.
+func (l *Blnk) GetEscheatmentBatch(ctx context.Context, batchID string) (*model.EscheatmentBatch, error) {
+	return l.datasource.GetEscheatmentBatch(ctx, batchID)
+}
+
+// ListEscheatmentBatches lists escheatment batches, newest first.
+func (l *Blnk) ListEscheatmentBatches(ctx context.Context, page model.Page) ([]*model.EscheatmentBatch, error) {
+	return l.datasource.ListEscheatmentBatches(ctx, page)
+}
+
+// ApplyEscheatmentBatch transfers the funds of each item of a batch to the holding balance. Items that
+// were already transferred are skipped, so a failed batch can be applied again. Each transfer has a
+// reference derived from the batch, so an item can never be transferred twice.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - batchID string: The ID of the batch.
+//
+// Returns:
+// - *model.EscheatmentBatch: The batch. Its status is failed when a transfer could not be made.
+// - error: An error if the batch was already applied or could not be loaded.
+func (l *Blnk) ApplyEscheatmentBatch(ctx context.Context, batchID string) (*model.EscheatmentBatch, error) {
+	locker := redlock.NewLocker(l.redis, "escheatment-batch:"+batchID, model.GenerateUUIDWithSuffix("loc"))
+	if err := locker.Lock(ctx, escheatmentLockTimeout); err != nil {
+		return nil, fmt.Errorf("failed to acquire lock for escheatment batch %s: %w", batchID, err)
+	}
+	defer l.releaseLock(ctx, locker)
+
+	batch, err := l.datasource.GetEscheatmentBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Status == model.EscheatmentBatchApplied {
+		return nil, fmt.Errorf("escheatment batch %s has already been applied", batchID)
+	}
+
+	batch.Status = model.EscheatmentBatchApplied
+	batch.Error = ""
+	for i := range batch.Items {
+		item := &batch.Items[i]
+		if item.TransactionID != "" {
+			continue
+		}
+		txn, err := l.QueueTransaction(ctx, &model.Transaction{
+			Source:        item.BalanceID,
+			Destination:   batch.HoldingBalance,
+			PreciseAmount: new(big.Int).Set(item.PreciseAmount),
+			Precision:     item.Precision,
+			Currency:      batch.Currency,
+			Reference:     item.Reference,
+			Description:   fmt.Sprintf("Escheatment of dormant balance %s", item.BalanceID),
+			SkipQueue:     true,
+			MetaData: map[string]interface{}{
+				escheatmentBatchMetaKey: batch.BatchID,
+			},
+		})
+		if err != nil {
+			batch.Status = model.EscheatmentBatchFailed
+			batch.Error = fmt.Sprintf("item %s: %v", item.Reference, err)
+			break
+		}
+		item.TransactionID = txn.TransactionID
+	}
+	if batch.Status == model.EscheatmentBatchApplied {
+		appliedAt := time.Now().UTC()
+		batch.AppliedAt = &appliedAt
+	}
+
+	if err := l.datasource.UpdateEscheatmentBatch(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	event := "escheatment.applied"
+	if batch.Status == model.EscheatmentBatchFailed {
+		event = "escheatment.failed"
+	}
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: batch}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+	return batch, nil
+}
+
+// EscheatmentReport renders the report of a batch as CSV, with a row per item giving the owner, the amount
+// and the dormancy dates that unclaimed property filings ask for.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - batchID string: The ID of the batch.
+//
+// Returns:
+// - []byte: The CSV report.
+// - error: An error if the batch could not be loaded or the report could not be rendered.
+func (l *Blnk) EscheatmentReport(ctx context.Context, batchID string) ([]byte, error) {
+	batch, err := l.datasource.GetEscheatmentBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string]*model.Identity)
+	for _, item := range batch.Items {
+		if item.IdentityID == "" || owners[item.IdentityID] != nil {
+			continue
+		}
+		identity, err := l.GetDetokenizedIdentity(ctx, item.IdentityID)
+		if err != nil {
+			logrus.WithError(err).WithField("identity_id", item.IdentityID).Warn("failed to load owner for escheatment report")
+			continue
+		}
+		owners[item.IdentityID] = identity
+	}
+	return renderEscheatmentCSV(batch, owners)
+}
+
+// renderEscheatmentCSV writes the items of a batch as CSV rows, filling in the owner details that are known.
+func renderEscheatmentCSV(batch *model.EscheatmentBatch, owners map[string]*model.Identity) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := []string{
+		"batch_id", "balance_id", "ledger_id", "identity_id", "owner_name", "street", "city", "state", "post_code",
+		"country", "email_address", "currency", "amount", "precise_amount", "last_activity_at", "dormant_since",
+		"reference", "transaction_id", "status",
+	}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, item := range batch.Items {
+		owner := owners[item.IdentityID]
+		if owner == nil {
+			owner = &model.Identity{}
+		}
+		name := owner.OrganizationName
+		if name == "" {
+			name = strings.TrimSpace(strings.Join([]string{owner.FirstName, owner.OtherNames, owner.LastName}, " "))
+			name = strings.Join(strings.Fields(name), " ")
+		}
+
+		amount := decimal.NewFromBigInt(item.PreciseAmount, 0)
+		if item.Precision > 0 {
+			amount = amount.Div(decimal.NewFromFloat(item.Precision))
+		}
+		status := "pending"
+		if item.TransactionID != "" {
+			status = "transferred"
+		}
+
+		row := []string{
+			batch.BatchID, item.BalanceID, item.LedgerID, item.IdentityID, name, owner.Street, owner.City, owner.State,
+			owner.PostCode, owner.Country, owner.EmailAddress, batch.Currency, amount.String(), item.PreciseAmount.String(),
+			item.LastActivityAt.UTC().Format(time.RFC3339), item.DormantSince.UTC().Format(time.RFC3339),
+			item.Reference, item.TransactionID, status,
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// sendDormancyWebhook notifies subscribers that a balance became dormant or was reactivated.
+func (l *Blnk) sendDormancyWebhook(event string, balance *model.DormantBalance) {
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: balance}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
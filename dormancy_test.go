@@ -0,0 +1,163 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newDormancyTestBlnk(t *testing.T, dormancy config.DormancyConfig) (*Blnk, *mocks.MockDataSource) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis:    config.RedisConfig{Dns: mr.Addr()},
+		Queue:    config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+		Dormancy: dormancy,
+	})
+
+	mockDS := new(mocks.MockDataSource)
+	b, err := NewBlnk(mockDS)
+	require.NoError(t, err)
+	return b, mockDS
+}
+
+func TestRunDormancyScan_FlagsAndFreezes(t *testing.T) {
+	b, mockDS := newDormancyTestBlnk(t, config.DormancyConfig{InactivityPeriod: 365 * 24 * time.Hour, Freeze: true})
+	ctx := context.Background()
+
+	mockDS.On("ClearReactivatedBalances", mock.Anything).Return([]*model.DormantBalance{}, nil)
+	mockDS.On("FindInactiveBalances", mock.Anything, mock.MatchedBy(func(since time.Time) bool {
+		return time.Since(since) > 364*24*time.Hour
+	}), dormancyScanBatchSize).Return([]*model.DormantBalance{
+		{BalanceID: "bln_a", Currency: "USD", Status: model.DormantBalanceDormant},
+		{BalanceID: "bln_b", Currency: "USD", Status: model.DormantBalanceDormant},
+	}, nil)
+	mockDS.On("FlagDormantBalance", mock.Anything, mock.MatchedBy(func(balance *model.DormantBalance) bool {
+		return balance.BalanceID == "bln_a" && balance.Frozen && !balance.DormantSince.IsZero()
+	})).Return(true, nil)
+	// Flagged by a concurrent scan
+	mockDS.On("FlagDormantBalance", mock.Anything, mock.MatchedBy(func(balance *model.DormantBalance) bool {
+		return balance.BalanceID == "bln_b"
+	})).Return(false, nil)
+
+	scan, err := b.RunDormancyScan(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, scan.Flagged)
+	assert.Equal(t, 0, scan.Reactivated)
+
+	mockDS.On("ListFrozenBalanceIDs", mock.Anything).Return([]string{"bln_a"}, nil)
+	err = b.checkFrozenBalances(ctx, &model.Transaction{Source: "bln_a", Destination: "bln_c"})
+	assert.ErrorContains(t, err, "balance bln_a is frozen")
+	err = b.checkFrozenBalances(ctx, &model.Transaction{Source: "bln_c", Destination: "bln_a"})
+	assert.Error(t, err)
+	assert.NoError(t, b.checkFrozenBalances(ctx, &model.Transaction{Source: "bln_c", Destination: "bln_d"}))
+
+	// Escheatment transfers move the funds of frozen balances
+	assert.NoError(t, b.checkFrozenBalances(ctx, &model.Transaction{
+		Source: "bln_a", Destination: "bln_holding",
+		MetaData: map[string]interface{}{escheatmentBatchMetaKey: "esch_1"},
+	}))
+	mockDS.AssertExpectations(t)
+}
+
+func TestRunDormancyScan_Disabled(t *testing.T) {
+	b, mockDS := newDormancyTestBlnk(t, config.DormancyConfig{})
+
+	scan, err := b.RunDormancyScan(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &model.DormancyScan{}, scan)
+
+	// Frozen balances are not enforced while freezing is disabled
+	assert.NoError(t, b.checkFrozenBalances(context.Background(), &model.Transaction{Source: "bln_a", Destination: "bln_b"}))
+	mockDS.AssertNotCalled(t, "ListFrozenBalanceIDs", mock.Anything)
+}
+
+func TestReactivateBalance(t *testing.T) {
+	b, mockDS := newDormancyTestBlnk(t, config.DormancyConfig{InactivityPeriod: time.Hour})
+	ctx := context.Background()
+
+	mockDS.On("GetDormantBalance", mock.Anything, "bln_claimed").Return(&model.DormantBalance{
+		BalanceID: "bln_claimed", Status: model.DormantBalanceDormant, BatchID: "esch_1",
+	}, nil)
+	_, err := b.ReactivateBalance(ctx, "bln_claimed")
+	assert.ErrorContains(t, err, "is part of escheatment batch esch_1")
+
+	mockDS.On("GetDormantBalance", mock.Anything, "bln_a").Return(&model.DormantBalance{
+		BalanceID: "bln_a", Status: model.DormantBalanceDormant, Frozen: true,
+	}, nil)
+	mockDS.On("DeleteDormantBalance", mock.Anything, "bln_a").Return(nil)
+	balance, err := b.ReactivateBalance(ctx, "bln_a")
+	require.NoError(t, err)
+	assert.Equal(t, "bln_a", balance.BalanceID)
+
+	mockDS.On("GetDormantBalance", mock.Anything, "bln_missing").Return(nil, errors.New("Dormant balance with ID 'bln_missing' not found"))
+	_, err = b.ReactivateBalance(ctx, "bln_missing")
+	assert.Error(t, err)
+	mockDS.AssertNotCalled(t, "DeleteDormantBalance", mock.Anything, "bln_claimed")
+}
+
+func TestRenderEscheatmentCSV(t *testing.T) {
+	lastActivity := time.Date(2023, 3, 1, 9, 0, 0, 0, time.UTC)
+	dormantSince := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	batch := &model.EscheatmentBatch{
+		BatchID:  "esch_1",
+		Currency: "USD",
+		Items: []model.EscheatmentItem{
+			{
+				BalanceID: "bln_a", LedgerID: "ldg_1", IdentityID: "idt_1", PreciseAmount: big.NewInt(12550), Precision: 100,
+				LastActivityAt: lastActivity, DormantSince: dormantSince, Reference: "esch_1_1", TransactionID: "txn_1",
+			},
+			{
+				BalanceID: "bln_b", LedgerID: "ldg_1", PreciseAmount: big.NewInt(7), Precision: 100,
+				LastActivityAt: lastActivity, DormantSince: dormantSince, Reference: "esch_1_2",
+			},
+		},
+	}
+	owners := map[string]*model.Identity{
+		"idt_1": {FirstName: "Ada", LastName: "Obi", Street: "1 Main St", City: "Lagos", Country: "NG", EmailAddress: "ada@example.com"},
+	}
+
+	data, err := renderEscheatmentCSV(batch, owners)
+	require.NoError(t, err)
+
+	rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "owner_name", rows[0][4])
+	assert.Equal(t, []string{
+		"esch_1", "bln_a", "ldg_1", "idt_1", "Ada Obi", "1 Main St", "Lagos", "", "", "NG", "ada@example.com", "USD",
+		"125.5", "12550", "2023-03-01T09:00:00Z", "2026-03-01T00:00:00Z", "esch_1_1", "txn_1", "transferred",
+	}, rows[1])
+	assert.Equal(t, "0.07", rows[2][12])
+	assert.Equal(t, "pending", rows[2][18])
+}
@@ -0,0 +1,461 @@
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	redlock "github.com/blnkfinance/blnk/internal/lock"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/internal/resilience"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Events sent when an end-of-day run ends. Runs that completed with errors send EventEODCompleted.
+const (
+	EventEODCompleted = "eod.completed"
+	EventEODFailed    = "eod.failed"
+)
+
+const (
+	// eodLockTimeout is how long a run holds its business date's lock between steps. The lock is extended by
+	// each step's timeout before the step runs.
+	eodLockTimeout = 5 * time.Minute
+	// eodSnapshotBatchSize is how many balances the snapshots step snapshots at once.
+	eodSnapshotBatchSize = 1000
+)
+
+var (
+	// ErrEODNotConfigured is returned when end-of-day processing has no steps configured.
+	ErrEODNotConfigured = errors.New("end-of-day processing is not configured")
+	// ErrInvalidBusinessDate is returned when a business date is not a date in YYYY-MM-DD.
+	ErrInvalidBusinessDate = errors.New("invalid business date")
+	// ErrEODInProgress is returned when the business date's run is already running elsewhere.
+	ErrEODInProgress = errors.New("end-of-day run already in progress")
+)
+
+// eodStepRequest is what an http step posts to its URL.
+type eodStepRequest struct {
+	RunID        string    `json:"run_id"`
+	BusinessDate string    `json:"business_date"`
+	CutoffAt     time.Time `json:"cutoff_at"`
+	Step         string    `json:"step"`
+}
+
+// RunEOD runs the end-of-day processing of a business date: its configured steps run one at a time, every step
+// after the steps it depends on. Failed attempts are retried as configured; a step that still fails skips the
+// steps that depend on it, while the steps that do not carry on. Progress is saved after every attempt, so the
+// run can be followed while it goes.
+//
+// Each business date has a single run. Running a date whose run completed returns that run, and running a date
+// whose run failed resumes it, keeping the steps that already succeeded and retrying the rest.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - businessDate string: The business date, as YYYY-MM-DD, or empty for the latest date whose cutoff passed.
+//
+// Returns:
+// - *model.EODRun: The run, which may have failed.
+// - error: ErrEODNotConfigured, ErrInvalidBusinessDate or ErrEODInProgress, or an error if the run could not be
+// saved.
+func (l *Blnk) RunEOD(ctx context.Context, businessDate string) (*model.EODRun, error) {
+	ctx, span := tracer.Start(ctx, "RunEOD")
+	defer span.End()
+
+	run, steps, locker, err := l.beginEOD(ctx, businessDate)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if run.Finished() {
+		l.releaseLock(ctx, locker)
+		return run, nil
+	}
+	return l.finishEOD(ctx, run, steps, locker)
+}
+
+// StartEOD starts the end-of-day processing of a business date like RunEOD, but returns as soon as the run has
+// started and leaves its steps running in the background, for callers that follow its progress instead.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - businessDate string: The business date, as YYYY-MM-DD, or empty for the latest date whose cutoff passed.
+//
+// Returns:
+// - *model.EODRun: The run as it started, or the completed run of the business date.
+// - error: ErrEODNotConfigured, ErrInvalidBusinessDate or ErrEODInProgress, or an error if the run could not be
+// saved.
+func (l *Blnk) StartEOD(ctx context.Context, businessDate string) (*model.EODRun, error) {
+	run, steps, locker, err := l.beginEOD(ctx, businessDate)
+	if err != nil {
+		return nil, err
+	}
+	if run.Finished() {
+		l.releaseLock(ctx, locker)
+		return run, nil
+	}
+
+	started := *run
+	started.Steps = append([]model.EODStepRun(nil), run.Steps...)
+	go func() {
+		if _, err := l.finishEOD(context.WithoutCancel(ctx), run, steps, locker); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+	return &started, nil
+}
+
+// beginEOD locks a business date and creates or resumes its run, returning the steps to run in order. The caller
+// releases the lock, which finishEOD does once the steps ran.
+func (l *Blnk) beginEOD(ctx context.Context, businessDate string) (*model.EODRun, []config.EODStepConfig, *redlock.Locker, error) {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(cnf.EOD.Steps) == 0 {
+		return nil, nil, nil, ErrEODNotConfigured
+	}
+	steps, err := cnf.EOD.OrderedSteps()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if businessDate == "" {
+		businessDate, err = DueBusinessDate(cnf.EOD, time.Now())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	cutoffAt, err := eodCutoff(cnf.EOD, businessDate)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	locker := redlock.NewLocker(l.redis, "eod:"+businessDate, model.GenerateUUIDWithSuffix("loc"))
+	if err := locker.Lock(ctx, eodLockTimeout); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w for %s", ErrEODInProgress, businessDate)
+	}
+	run, err := l.startEODRun(ctx, businessDate, cutoffAt, steps)
+	if err != nil {
+		l.releaseLock(ctx, locker)
+		return nil, nil, nil, err
+	}
+	return run, steps, locker, nil
+}
+
+// finishEOD runs the steps of a run in order, saves how the run ended and releases its business date's lock.
+func (l *Blnk) finishEOD(ctx context.Context, run *model.EODRun, steps []config.EODStepConfig, locker *redlock.Locker) (*model.EODRun, error) {
+	defer l.releaseLock(ctx, locker)
+
+	for _, step := range steps {
+		l.runEODStep(ctx, locker, run, step)
+	}
+
+	run.Status = eodRunStatus(run, steps)
+	completedAt := time.Now()
+	run.CompletedAt = &completedAt
+	if err := l.datasource.UpdateEODRun(ctx, run); err != nil {
+		return nil, err
+	}
+	l.sendEODWebhook(run)
+	return run, nil
+}
+
+// RunDueEOD runs the end-of-day processing of the latest business date whose cutoff passed, unless that date
+// was already run or is not a business day of the configured calendar. Failed runs are left to be resumed with RunEOD rather than retried on every call.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - *model.EODRun: The run, or nil if there was nothing to run.
+// - error: An error if the run could not be started or saved.
+func (l *Blnk) RunDueEOD(ctx context.Context) (*model.EODRun, error) {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	if len(cnf.EOD.Steps) == 0 {
+		return nil, nil
+	}
+	businessDate, err := DueBusinessDate(cnf.EOD, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if cnf.EOD.Calendar != "" {
+		cal, err := businessCalendar(cnf.EOD.Calendar)
+		if err != nil {
+			return nil, err
+		}
+		day, err := time.ParseInLocation(time.DateOnly, businessDate, cal.Location())
+		if err != nil {
+			return nil, err
+		}
+		if !cal.IsBusinessDay(day) {
+			return nil, nil
+		}
+	}
+
+	_, err = l.datasource.GetEODRun(ctx, businessDate)
+	if err == nil {
+		return nil, nil
+	}
+	var apiErr apierror.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != apierror.ErrNotFound {
+		return nil, err
+	}
+	run, err := l.RunEOD(ctx, businessDate)
+	if errors.Is(err, ErrEODInProgress) {
+		return nil, nil
+	}
+	return run, err
+}
+
+// GetEODRun retrieves the end-of-day run of a business date.
+func (l *Blnk) GetEODRun(ctx context.Context, businessDate string) (*model.EODRun, error) {
+	return l.datasource.GetEODRun(ctx, businessDate)
+}
+
+// GetCurrentEODRun retrieves the end-of-day run of the latest business date, to follow the progress of the run
+// in flight.
+func (l *Blnk) GetCurrentEODRun(ctx context.Context) (*model.EODRun, error) {
+	return l.datasource.GetLatestEODRun(ctx)
+}
+
+// ListEODRuns lists end-of-day runs, latest business date first.
+func (l *Blnk) ListEODRuns(ctx context.Context, page model.Page) ([]*model.EODRun, error) {
+	return l.datasource.ListEODRuns(ctx, page)
+}
+
+// DueBusinessDate returns the latest business date whose cutoff has passed at the given time: today's in the
+// configured timezone once its cutoff passed, and yesterday's before.
+func DueBusinessDate(cnf config.EODConfig, now time.Time) (string, error) {
+	location, err := time.LoadLocation(cnf.Timezone)
+	if err != nil {
+		return "", err
+	}
+	today := now.In(location).Format(time.DateOnly)
+	cutoffAt, err := eodCutoff(cnf, today)
+	if err != nil {
+		return "", err
+	}
+	if now.Before(cutoffAt) {
+		return now.In(location).AddDate(0, 0, -1).Format(time.DateOnly), nil
+	}
+	return today, nil
+}
+
+// eodCutoff returns the time a business date closes.
+func eodCutoff(cnf config.EODConfig, businessDate string) (time.Time, error) {
+	location, err := time.LoadLocation(cnf.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	cutoffAt, err := time.ParseInLocation(time.DateOnly+" 15:04", businessDate+" "+cnf.CutoffTime, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q, expected YYYY-MM-DD", ErrInvalidBusinessDate, businessDate)
+	}
+	return cutoffAt, nil
+}
+
+// startEODRun creates the run of a business date, or prepares its existing run to be resumed. The steps of a
+// resumed run follow the current configuration, keeping the progress of the steps that succeeded.
+func (l *Blnk) startEODRun(ctx context.Context, businessDate string, cutoffAt time.Time, steps []config.EODStepConfig) (*model.EODRun, error) {
+	existing, err := l.datasource.GetEODRun(ctx, businessDate)
+	var apiErr apierror.APIError
+	if err != nil && !(errors.As(err, &apiErr) && apiErr.Code == apierror.ErrNotFound) {
+		return nil, err
+	}
+	if existing != nil && existing.Status == model.EODRunCompleted {
+		return existing, nil
+	}
+
+	run := existing
+	if run == nil {
+		run = &model.EODRun{
+			RunID:        model.GenerateUUIDWithSuffix("eod"),
+			BusinessDate: businessDate,
+			CutoffAt:     cutoffAt,
+			StartedAt:    time.Now(),
+		}
+	}
+	stepRuns := make([]model.EODStepRun, 0, len(steps))
+	for _, step := range steps {
+		if previous := run.Step(step.Name); previous != nil && previous.Status == model.EODStepSucceeded {
+			stepRuns = append(stepRuns, *previous)
+			continue
+		}
+		stepRuns = append(stepRuns, model.EODStepRun{Name: step.Name, Kind: step.Kind, Status: model.EODStepPending})
+	}
+	run.Steps = stepRuns
+	run.Status = model.EODRunRunning
+	run.CompletedAt = nil
+
+	if existing == nil {
+		return run, l.datasource.CreateEODRun(ctx, run)
+	}
+	return run, l.datasource.UpdateEODRun(ctx, run)
+}
+
+// runEODStep runs a step of a run with its retries, or skips it when a step it depends on did not succeed.
+func (l *Blnk) runEODStep(ctx context.Context, locker *redlock.Locker, run *model.EODRun, step config.EODStepConfig) {
+	stepRun := run.Step(step.Name)
+	if stepRun.Status == model.EODStepSucceeded {
+		return
+	}
+	for _, dependency := range step.DependsOn {
+		if run.Step(dependency).Status != model.EODStepSucceeded {
+			stepRun.Status = model.EODStepSkipped
+			stepRun.Error = fmt.Sprintf("step %s did not succeed", dependency)
+			l.saveEODProgress(ctx, run)
+			return
+		}
+	}
+
+	for attempt := 0; attempt <= step.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				stepRun.Status, stepRun.Error = model.EODStepFailed, ctx.Err().Error()
+				l.saveEODProgress(ctx, run)
+				return
+			case <-time.After(step.RetryBackoff):
+			}
+		}
+		if err := locker.ExtendLock(ctx, step.Timeout+eodLockTimeout); err != nil {
+			logrus.WithError(err).WithField("business_date", run.BusinessDate).Error("failed to extend end-of-day lock")
+		}
+
+		startedAt := time.Now()
+		stepRun.Status, stepRun.Error, stepRun.StartedAt, stepRun.CompletedAt = model.EODStepRunning, "", &startedAt, nil
+		stepRun.Attempts++
+		l.saveEODProgress(ctx, run)
+
+		stepCtx, cancel := context.WithTimeout(ctx, step.Timeout)
+		output, err := l.executeEODStep(stepCtx, run, step)
+		cancel()
+
+		completedAt := time.Now()
+		stepRun.CompletedAt = &completedAt
+		if err == nil {
+			stepRun.Status, stepRun.Output = model.EODStepSucceeded, output
+			l.saveEODProgress(ctx, run)
+			return
+		}
+		stepRun.Status, stepRun.Error = model.EODStepFailed, err.Error()
+		l.saveEODProgress(ctx, run)
+		logrus.WithError(err).WithFields(logrus.Fields{"business_date": run.BusinessDate, "step": step.Name, "attempt": stepRun.Attempts}).Warn("end-of-day step failed")
+	}
+}
+
+// executeEODStep does the work of a step and summarizes what it did.
+func (l *Blnk) executeEODStep(ctx context.Context, run *model.EODRun, step config.EODStepConfig) (string, error) {
+	switch step.Kind {
+	case model.EODKindCutoff:
+		if time.Now().Before(run.CutoffAt) {
+			return "", fmt.Errorf("business date %s has not reached its cutoff at %s", run.BusinessDate, run.CutoffAt.Format(time.RFC3339))
+		}
+		return "cutoff at " + run.CutoffAt.Format(time.RFC3339), nil
+	case model.EODKindSettlements:
+		settled, err := l.RunDueNettingSettlements(ctx)
+		return fmt.Sprintf("settled %d netting groups", settled), err
+	case model.EODKindSnapshots:
+		taken, err := l.datasource.TakeBalanceSnapshots(ctx, eodSnapshotBatchSize)
+		return fmt.Sprintf("took %d balance snapshots", taken), err
+	case model.EODKindReports:
+		completed, err := l.RunDueReports(ctx)
+		return fmt.Sprintf("ran %d reports", completed), err
+	case model.EODKindStatements:
+		generated, err := l.RunDueStatements(ctx)
+		return fmt.Sprintf("generated %d statements", generated), err
+	case model.EODKindUsageExport:
+		day, err := time.Parse(time.DateOnly, run.BusinessDate)
+		if err != nil {
+			return "", err
+		}
+		exported, err := l.ExportDailyUsage(ctx, day)
+		return fmt.Sprintf("exported %d usage records", exported), err
+	case model.EODKindDormancy:
+		scan, err := l.RunDormancyScan(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("flagged %d and reactivated %d balances", scan.Flagged, scan.Reactivated), nil
+	case model.EODKindHTTP:
+		return l.postEODStep(ctx, run, step)
+	}
+	return "", fmt.Errorf("unknown step kind %q", step.Kind)
+}
+
+// postEODStep hands a business date to the service behind an http step, signed like webhook deliveries when the
+// step has a secret. The step succeeds once the service responds with a 2xx status.
+func (l *Blnk) postEODStep(ctx context.Context, run *model.EODRun, step config.EODStepConfig) (string, error) {
+	body, err := json.Marshal(eodStepRequest{RunID: run.RunID, BusinessDate: run.BusinessDate, CutoffAt: run.CutoffAt, Step: step.Name})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, step.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if step.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, signWebhookPayload(body, []string{step.Secret}, time.Now()))
+	}
+
+	resp, err := resilience.Get(resilience.EOD).Wrap(l.httpClient).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s responded with status %d", step.URL, resp.StatusCode)
+	}
+	return fmt.Sprintf("%s responded with status %d", step.URL, resp.StatusCode), nil
+}
+
+// saveEODProgress saves the progress of a run. Failures are logged rather than stopping the run, whose final
+// status is saved when it ends.
+func (l *Blnk) saveEODProgress(ctx context.Context, run *model.EODRun) {
+	if err := l.datasource.UpdateEODRun(ctx, run); err != nil {
+		logrus.WithError(err).WithField("business_date", run.BusinessDate).Error("failed to save end-of-day progress")
+	}
+}
+
+// eodRunStatus returns the status a run ends with: failed when a required step did not succeed, completed with
+// errors when only optional steps did not, and completed otherwise.
+func eodRunStatus(run *model.EODRun, steps []config.EODStepConfig) string {
+	status := model.EODRunCompleted
+	for _, step := range steps {
+		if run.Step(step.Name).Status == model.EODStepSucceeded {
+			continue
+		}
+		if !step.Optional {
+			return model.EODRunFailed
+		}
+		status = model.EODRunCompletedWithErrors
+	}
+	return status
+}
+
+// sendEODWebhook sends an eod.completed or eod.failed webhook with a run that ended.
+func (l *Blnk) sendEODWebhook(run *model.EODRun) {
+	event := EventEODCompleted
+	if run.Status == model.EODRunFailed {
+		event = EventEODFailed
+	}
+	payload := *run
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func useEODSteps(t *testing.T, steps ...config.EODStepConfig) {
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	updated := *cnf
+	updated.EOD = config.EODConfig{Timezone: "UTC", CutoffTime: "18:00", Steps: steps}
+	config.ConfigStore.Store(&updated)
+}
+
+func TestRunEOD_DependenciesRetriesAndFailures(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	var accrualCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/accruals":
+			// The first attempt fails, so the step succeeds on its retry
+			if accrualCalls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	useEODSteps(t,
+		config.EODStepConfig{Name: "snapshots", Kind: model.EODKindSnapshots, DependsOn: []string{"accruals"}, Timeout: time.Second},
+		config.EODStepConfig{Name: "cutoff", Kind: model.EODKindCutoff, Timeout: time.Second},
+		config.EODStepConfig{Name: "accruals", Kind: model.EODKindHTTP, URL: server.URL + "/accruals", DependsOn: []string{"cutoff"}, Retries: 1, RetryBackoff: time.Millisecond, Timeout: time.Second},
+		config.EODStepConfig{Name: "fees", Kind: model.EODKindHTTP, URL: server.URL + "/fees", DependsOn: []string{"cutoff"}, Optional: true, Timeout: time.Second},
+		config.EODStepConfig{Name: "fee-report", Kind: model.EODKindHTTP, URL: server.URL + "/fee-report", DependsOn: []string{"fees"}, Optional: true, Timeout: time.Second},
+	)
+
+	mockDS.On("GetEODRun", mock.Anything, "2026-10-15").Return(nil, apierror.NewAPIError(apierror.ErrNotFound, "not found", nil))
+	mockDS.On("CreateEODRun", mock.Anything, mock.Anything).Return(nil)
+	mockDS.On("UpdateEODRun", mock.Anything, mock.Anything).Return(nil)
+	mockDS.On("TakeBalanceSnapshots", mock.Anything, eodSnapshotBatchSize).Return(4, nil)
+
+	run, err := b.RunEOD(context.Background(), "2026-10-15")
+	require.NoError(t, err)
+
+	var order []string
+	for _, step := range run.Steps {
+		order = append(order, step.Name)
+	}
+	assert.Equal(t, []string{"cutoff", "accruals", "snapshots", "fees", "fee-report"}, order)
+
+	assert.Equal(t, model.EODStepSucceeded, run.Step("cutoff").Status)
+	assert.Equal(t, model.EODStepSucceeded, run.Step("accruals").Status)
+	assert.Equal(t, 2, run.Step("accruals").Attempts)
+	assert.Equal(t, model.EODStepSucceeded, run.Step("snapshots").Status)
+	assert.Equal(t, "took 4 balance snapshots", run.Step("snapshots").Output)
+	assert.Equal(t, model.EODStepFailed, run.Step("fees").Status)
+	assert.Equal(t, model.EODStepSkipped, run.Step("fee-report").Status)
+	assert.Equal(t, model.EODRunCompletedWithErrors, run.Status)
+	assert.NotNil(t, run.CompletedAt)
+	assert.Equal(t, time.Date(2026, 10, 15, 18, 0, 0, 0, time.UTC), run.CutoffAt)
+}
+
+func TestRunEOD_ResumesFailedRun(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	useEODSteps(t,
+		config.EODStepConfig{Name: "cutoff", Kind: model.EODKindCutoff, Timeout: time.Second},
+		config.EODStepConfig{Name: "snapshots", Kind: model.EODKindSnapshots, DependsOn: []string{"cutoff"}, Timeout: time.Second},
+	)
+
+	completedAt := time.Now()
+	failed := &model.EODRun{
+		RunID: "eod_1", BusinessDate: "2026-10-15", Status: model.EODRunFailed, CompletedAt: &completedAt,
+		CutoffAt: time.Date(2026, 10, 15, 18, 0, 0, 0, time.UTC),
+		Steps: []model.EODStepRun{
+			{Name: "cutoff", Kind: model.EODKindCutoff, Status: model.EODStepSucceeded, Attempts: 1, Output: "cutoff"},
+			{Name: "snapshots", Kind: model.EODKindSnapshots, Status: model.EODStepFailed, Attempts: 1, Error: "boom"},
+		},
+	}
+	mockDS.On("GetEODRun", mock.Anything, "2026-10-15").Return(failed, nil)
+	mockDS.On("UpdateEODRun", mock.Anything, mock.Anything).Return(nil)
+	mockDS.On("TakeBalanceSnapshots", mock.Anything, eodSnapshotBatchSize).Return(2, nil).Once()
+
+	run, err := b.RunEOD(context.Background(), "2026-10-15")
+	require.NoError(t, err)
+	assert.Equal(t, "eod_1", run.RunID)
+	assert.Equal(t, model.EODRunCompleted, run.Status)
+	assert.Equal(t, 1, run.Step("cutoff").Attempts)
+	assert.Equal(t, model.EODStepSucceeded, run.Step("snapshots").Status)
+	assert.Empty(t, run.Step("snapshots").Error)
+	mockDS.AssertNotCalled(t, "CreateEODRun", mock.Anything, mock.Anything)
+	mockDS.AssertExpectations(t)
+}
+
+func TestRunEOD_Validation(t *testing.T) {
+	b, _, mr := newBalanceNotificationTestBlnk(t)
+
+	useEODSteps(t)
+	_, err := b.RunEOD(context.Background(), "2026-10-15")
+	assert.True(t, errors.Is(err, ErrEODNotConfigured))
+
+	useEODSteps(t, config.EODStepConfig{Name: "cutoff", Kind: model.EODKindCutoff})
+	_, err = b.RunEOD(context.Background(), "15/10/2026")
+	assert.True(t, errors.Is(err, ErrInvalidBusinessDate))
+
+	require.NoError(t, mr.Set("eod:2026-10-15", "loc_other"))
+	_, err = b.RunEOD(context.Background(), "2026-10-15")
+	assert.True(t, errors.Is(err, ErrEODInProgress))
+}
+
+func TestDueBusinessDate(t *testing.T) {
+	cnf := config.EODConfig{Timezone: "America/New_York", CutoffTime: "17:00"}
+
+	date, err := DueBusinessDate(cnf, time.Date(2026, 10, 15, 20, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10-14", date)
+
+	date, err = DueBusinessDate(cnf, time.Date(2026, 10, 15, 21, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10-15", date)
+}
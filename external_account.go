@@ -0,0 +1,151 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// EventExternalAccountCreated is sent when an external account is added. Reviews send
+// external_account.verified or external_account.rejected.
+const EventExternalAccountCreated = "external_account.created"
+
+var (
+	// ErrInvalidExternalAccount is returned when an external account is missing details or has malformed ones,
+	// such as an IBAN failing its checksum.
+	ErrInvalidExternalAccount = errors.New("invalid external account")
+
+	// ErrExternalAccountUnusable is returned when a transaction pays out to an external account that is not
+	// verified or holds another currency.
+	ErrExternalAccountUnusable = errors.New("external account cannot be paid out to")
+)
+
+// CreateExternalAccount adds a bank account or wallet to the directory of external accounts, attached to an
+// identity when the account names one. Account details are normalized and checked, and the account is pending
+// until reviewed.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - account model.ExternalAccount: The type, holder, currency and details of the account.
+//
+// Returns:
+// - *model.ExternalAccount: The saved account.
+// - error: ErrInvalidExternalAccount if the account is rejected, or an error if its identity does not exist.
+func (l *Blnk) CreateExternalAccount(ctx context.Context, account model.ExternalAccount) (*model.ExternalAccount, error) {
+	ctx, span := tracer.Start(ctx, "CreateExternalAccount")
+	defer span.End()
+
+	account.Normalize()
+	if err := account.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidExternalAccount, err)
+	}
+	if account.IdentityID != "" {
+		if _, err := l.datasource.GetIdentityByID(ctx, account.IdentityID); err != nil {
+			return nil, err
+		}
+	}
+
+	account.ExternalAccountID = model.GenerateUUIDWithSuffix("ext")
+	account.VerificationStatus = model.VerificationPending
+	account.VerificationReason = ""
+	account.VerifiedAt = nil
+	account.CreatedAt = time.Now()
+	if err := l.datasource.CreateExternalAccount(ctx, &account); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	l.sendExternalAccountWebhook(EventExternalAccountCreated, &account)
+	return &account, nil
+}
+
+// GetExternalAccount retrieves an external account by ID.
+func (l *Blnk) GetExternalAccount(ctx context.Context, externalAccountID string) (*model.ExternalAccount, error) {
+	return l.datasource.GetExternalAccount(ctx, externalAccountID)
+}
+
+// GetExternalAccounts lists the external accounts of an identity, newest first.
+func (l *Blnk) GetExternalAccounts(ctx context.Context, identityID string) ([]*model.ExternalAccount, error) {
+	if _, err := l.datasource.GetIdentityByIDIncludingDeleted(ctx, identityID); err != nil {
+		return nil, err
+	}
+	return l.datasource.GetExternalAccounts(ctx, identityID)
+}
+
+// ReviewExternalAccount records the review of an external account, once its owner has been confirmed, for
+// example by a micro-deposit or a name check with the bank. Pending accounts are verified or rejected, and
+// verified accounts can be rejected later to stop payouts to them.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - externalAccountID string: The ID of the account.
+// - status string: verified or rejected.
+// - reason string: Why the account was verified or rejected. May be empty.
+//
+// Returns:
+// - *model.ExternalAccount: The reviewed account.
+// - error: ErrInvalidVerificationTransition if the account cannot move to the status, or an error if it does
+// not exist.
+func (l *Blnk) ReviewExternalAccount(ctx context.Context, externalAccountID, status, reason string) (*model.ExternalAccount, error) {
+	ctx, span := tracer.Start(ctx, "ReviewExternalAccount")
+	defer span.End()
+
+	account, err := l.datasource.GetExternalAccount(ctx, externalAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if !model.CanReviewExternalAccount(account.VerificationStatus, status) {
+		return nil, fmt.Errorf("%w: external account %s is %s and cannot become %s", ErrInvalidVerificationTransition, externalAccountID, account.VerificationStatus, status)
+	}
+
+	now := time.Now()
+	if err := l.datasource.UpdateExternalAccountVerification(ctx, externalAccountID, account.VerificationStatus, status, reason, now); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	account.VerificationStatus = status
+	account.VerificationReason = reason
+	account.VerifiedAt = &now
+	l.sendExternalAccountWebhook("external_account."+status, account)
+	return account, nil
+}
+
+// checkTransactionExternalAccount checks that the external account a transaction pays out to is verified and
+// holds the transaction's currency, and records the account in the transaction's metadata so that the payout
+// can be traced to it.
+func (l *Blnk) checkTransactionExternalAccount(ctx context.Context, txn *model.Transaction) error {
+	if txn.ExternalAccountID == "" {
+		return nil
+	}
+	account, err := l.datasource.GetExternalAccount(ctx, txn.ExternalAccountID)
+	if err != nil {
+		return err
+	}
+	if account.VerificationStatus != model.VerificationVerified {
+		return fmt.Errorf("%w: %s is %s", ErrExternalAccountUnusable, account.ExternalAccountID, account.VerificationStatus)
+	}
+	if account.Currency != txn.Currency {
+		return fmt.Errorf("%w: %s holds %s, not %s", ErrExternalAccountUnusable, account.ExternalAccountID, account.Currency, txn.Currency)
+	}
+
+	if txn.MetaData == nil {
+		txn.MetaData = make(map[string]interface{})
+	}
+	txn.MetaData[model.ExternalAccountMetaKey] = account.ExternalAccountID
+	return nil
+}
+
+// sendExternalAccountWebhook sends a webhook about an external account in the background.
+func (l *Blnk) sendExternalAccountWebhook(event string, account *model.ExternalAccount) {
+	payload := *account
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateExternalAccount(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_ada").Return(&model.Identity{IdentityID: "idt_ada"}, nil)
+	mockDS.On("CreateExternalAccount", mock.Anything, mock.AnythingOfType("*model.ExternalAccount")).Return(nil)
+
+	account, err := b.CreateExternalAccount(context.Background(), model.ExternalAccount{
+		IdentityID:         "idt_ada",
+		Type:               model.ExternalAccountBank,
+		HolderName:         "Ada Lovelace",
+		Currency:           "gbp",
+		IBAN:               "GB82 WEST 1234 5698 7654 32",
+		VerificationStatus: model.VerificationVerified,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, account.ExternalAccountID)
+	assert.Equal(t, "GB82WEST12345698765432", account.IBAN)
+	assert.Equal(t, "GB", account.Country)
+	assert.Equal(t, model.VerificationPending, account.VerificationStatus)
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
+
+func TestCreateExternalAccount_Invalid(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	_, err := b.CreateExternalAccount(context.Background(), model.ExternalAccount{
+		Type: model.ExternalAccountBank, HolderName: "Ada", Currency: "GBP", IBAN: "GB83WEST12345698765432",
+	})
+	assert.True(t, errors.Is(err, ErrInvalidExternalAccount))
+	mockDS.AssertNotCalled(t, "CreateExternalAccount", mock.Anything, mock.Anything)
+}
+
+func TestReviewExternalAccount(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetExternalAccount", mock.Anything, "ext_1").
+		Return(&model.ExternalAccount{ExternalAccountID: "ext_1", VerificationStatus: model.VerificationVerified}, nil)
+	mockDS.On("UpdateExternalAccountVerification", mock.Anything, "ext_1", model.VerificationVerified, model.VerificationRejected, "account closed", mock.Anything).Return(nil)
+
+	account, err := b.ReviewExternalAccount(context.Background(), "ext_1", model.VerificationRejected, "account closed")
+	require.NoError(t, err)
+	assert.Equal(t, model.VerificationRejected, account.VerificationStatus)
+
+	_, err = b.ReviewExternalAccount(context.Background(), "ext_1", model.VerificationPending, "")
+	assert.True(t, errors.Is(err, ErrInvalidVerificationTransition))
+	mockDS.AssertNumberOfCalls(t, "UpdateExternalAccountVerification", 1)
+}
+
+func TestCheckTransactionExternalAccount(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetExternalAccount", mock.Anything, "ext_verified").
+		Return(&model.ExternalAccount{ExternalAccountID: "ext_verified", Currency: "USD", VerificationStatus: model.VerificationVerified}, nil)
+	mockDS.On("GetExternalAccount", mock.Anything, "ext_pending").
+		Return(&model.ExternalAccount{ExternalAccountID: "ext_pending", Currency: "USD", VerificationStatus: model.VerificationPending}, nil)
+
+	txn := &model.Transaction{Currency: "USD", ExternalAccountID: "ext_verified"}
+	require.NoError(t, b.checkTransactionExternalAccount(context.Background(), txn))
+	assert.Equal(t, "ext_verified", txn.MetaData[model.ExternalAccountMetaKey])
+
+	err := b.checkTransactionExternalAccount(context.Background(), &model.Transaction{Currency: "USD", ExternalAccountID: "ext_pending"})
+	assert.True(t, errors.Is(err, ErrExternalAccountUnusable))
+
+	err = b.checkTransactionExternalAccount(context.Background(), &model.Transaction{Currency: "EUR", ExternalAccountID: "ext_verified"})
+	assert.True(t, errors.Is(err, ErrExternalAccountUnusable))
+
+	require.NoError(t, b.checkTransactionExternalAccount(context.Background(), &model.Transaction{Currency: "USD"}))
+	mockDS.AssertNumberOfCalls(t, "GetExternalAccount", 3)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+
+	"github.com/blnkfinance/blnk/internal/featureflags"
+)
+
+// IsFeatureEnabled reports whether a feature flag is enabled for the tenant carried by ctx.
+//
+// Parameters:
+// - ctx context.Context: The context, optionally carrying the tenant.
+// - name string: The name of the feature flag.
+//
+// Returns:
+// - bool: True if the feature is enabled.
+func (l *Blnk) IsFeatureEnabled(ctx context.Context, name string) bool {
+	return l.flags.IsEnabled(ctx, name)
+}
+
+// ListFeatureFlags returns every known feature flag with its current state.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - []*featureflags.Flag: The feature flags.
+// - error: An error if the flags could not be read.
+func (l *Blnk) ListFeatureFlags(ctx context.Context) ([]*featureflags.Flag, error) {
+	return l.flags.List(ctx)
+}
+
+// GetFeatureFlag returns the current state of a single feature flag.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - name string: The name of the feature flag.
+//
+// Returns:
+// - *featureflags.Flag: The feature flag.
+// - error: An error if the flag is unknown or could not be read.
+func (l *Blnk) GetFeatureFlag(ctx context.Context, name string) (*featureflags.Flag, error) {
+	return l.flags.Get(ctx, name)
+}
+
+// SetFeatureFlag stores a runtime override for a feature flag. It applies to the next evaluation.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - flag *featureflags.Flag: The desired flag state.
+//
+// Returns:
+// - error: An error if the flag is invalid or could not be stored.
+func (l *Blnk) SetFeatureFlag(ctx context.Context, flag *featureflags.Flag) error {
+	return l.flags.Set(ctx, flag)
+}
+
+// ResetFeatureFlag removes the runtime override for a feature flag, rolling it back to its configured default.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - name string: The name of the feature flag.
+//
+// Returns:
+// - error: An error if the override could not be removed.
+func (l *Blnk) ResetFeatureFlag(ctx context.Context, name string) error {
+	return l.flags.Delete(ctx, name)
+}
@@ -23,11 +23,21 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/blnkfinance/blnk/config"
 	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/internal/tenant"
 	"github.com/blnkfinance/blnk/internal/tokenization"
 	"github.com/blnkfinance/blnk/model"
 )
 
+// Events sent through the webhook pipeline as identities are created and change.
+const (
+	EventIdentityCreated  = "identity.created"
+	EventIdentityUpdated  = "identity.updated"
+	EventIdentityDeleted  = "identity.deleted"
+	EventIdentityRestored = "identity.restored"
+)
+
 // postIdentityActions performs actions after an identity has been created.
 // It sends the newly created identity to the search index queue and sends a webhook notification.
 func (l *Blnk) postIdentityActions(_ context.Context, identity *model.Identity) {
@@ -37,7 +47,7 @@ func (l *Blnk) postIdentityActions(_ context.Context, identity *model.Identity)
 			notification.NotifyError(err)
 		}
 		err = l.SendWebhook(NewWebhook{
-			Event:   "identity.created",
+			Event:   EventIdentityCreated,
 			Payload: identity,
 		})
 		if err != nil {
@@ -46,33 +56,96 @@ func (l *Blnk) postIdentityActions(_ context.Context, identity *model.Identity)
 	}()
 }
 
-// CreateIdentity creates a new identity in the database.
+// postIdentityChangeActions reindexes an identity after it was updated or restored, and removes it from the
+// index once deleted, so that searches reflect the change, and sends a webhook with the identity as it now is,
+// so that downstream systems stay in sync without polling.
+func (l *Blnk) postIdentityChangeActions(ctx context.Context, event, identityID string) {
+	// The work outlives the request, so it keeps the request's values, such as its tenant, but not its deadline
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		identity, err := l.datasource.GetIdentityByIDIncludingDeleted(ctx, identityID)
+		if err != nil {
+			notification.NotifyError(err)
+			return
+		}
+		if identity.DeletedAt != nil {
+			err = l.queue.queueIndexDeletion(identity.IdentityID, "identities")
+		} else {
+			err = l.queue.queueIndexData(identity.IdentityID, "identities", identity)
+		}
+		if err != nil {
+			notification.NotifyError(err)
+		}
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: identity}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
+
+// identityLocaleFormat returns the format amounts and dates are shown to an identity with. Unknown identities
+// get the default format.
+func (l *Blnk) identityLocaleFormat(ctx context.Context, identityID string) model.LocaleFormat {
+	if identityID != "" {
+		if identity, err := l.datasource.GetIdentityByID(ctx, identityID); err == nil {
+			return identity.LocaleFormat()
+		}
+	}
+	return model.NewLocaleFormat("", "")
+}
+
+// CreateIdentity creates a new identity in the database, scores its risk when risk scoring is enabled and screens
+// it against sanctions and PEP lists when screening is enabled.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - identity model.Identity: The Identity model to be created.
 //
 // Returns:
 // - model.Identity: The created Identity model.
-// - error: An error if the identity could not be created.
-func (l *Blnk) CreateIdentity(identity model.Identity) (model.Identity, error) {
-	identity, err := l.datasource.CreateIdentity(identity)
+// - error: An error if the identity's locale, timezone or communication preferences are invalid, a
+// *model.IdentitySchemaError if its meta_data does not match the schema of its identity type, or an error if it
+// could not be created.
+func (l *Blnk) CreateIdentity(ctx context.Context, identity model.Identity) (model.Identity, error) {
+	if err := identity.ValidatePreferences(); err != nil {
+		return model.Identity{}, err
+	}
+	if err := validateIdentityFields(identity.IdentityType, identity.MetaData); err != nil {
+		return model.Identity{}, err
+	}
+	identity, err := l.datasource.CreateIdentity(ctx, identity)
 	if err != nil {
 		return model.Identity{}, err
 	}
-	l.postIdentityActions(context.Background(), &identity)
+	l.rescoreIdentity(ctx, &identity)
+	l.rescreenIdentity(ctx, &identity)
+	l.postIdentityActions(ctx, &identity)
 	return identity, nil
 }
 
 // GetIdentity retrieves an identity by its ID.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - id string: The ID of the identity to retrieve.
 //
 // Returns:
 // - *model.Identity: A pointer to the Identity model if found.
 // - error: An error if the identity could not be retrieved.
-func (l *Blnk) GetIdentity(id string) (*model.Identity, error) {
-	return l.datasource.GetIdentityByID(id)
+func (l *Blnk) GetIdentity(ctx context.Context, id string) (*model.Identity, error) {
+	return l.datasource.GetIdentityByID(ctx, id)
+}
+
+// GetIdentityIncludingDeleted retrieves an identity by its ID whether or not it has been deleted.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - id string: The ID of the identity to retrieve.
+//
+// Returns:
+// - *model.Identity: A pointer to the Identity model if found.
+// - error: An error if the identity could not be retrieved.
+func (l *Blnk) GetIdentityIncludingDeleted(ctx context.Context, id string) (*model.Identity, error) {
+	return l.datasource.GetIdentityByIDIncludingDeleted(ctx, id)
 }
 
 // GetAllIdentities retrieves all identities from the database.
@@ -80,41 +153,167 @@ func (l *Blnk) GetIdentity(id string) (*model.Identity, error) {
 // Returns:
 // - []model.Identity: A slice of Identity models.
 // - error: An error if the identities could not be retrieved.
-func (l *Blnk) GetAllIdentities() ([]model.Identity, error) {
-	return l.datasource.GetAllIdentities()
+func (l *Blnk) GetAllIdentities(ctx context.Context) ([]model.Identity, error) {
+	return l.datasource.GetAllIdentities(ctx)
+}
+
+// GetIdentities retrieves the identities matching a filter, newest first.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - filter model.IdentityFilter: The fields the identities must match.
+// - page model.Page: The page of identities to read, or the zero page for all of them.
+//
+// Returns:
+// - []model.Identity: A slice of the matching Identity models.
+// - error: An error if the identities could not be retrieved.
+func (l *Blnk) GetIdentities(ctx context.Context, filter model.IdentityFilter, page model.Page) ([]model.Identity, error) {
+	return l.datasource.GetIdentities(ctx, filter, page)
 }
 
-// UpdateIdentity updates an existing identity in the database.
+// UpdateIdentity updates an existing identity in the database. The identity as it was before is kept in its
+// history, recorded as changed by the tenant the request acts for, or by the system, its risk is scored again and it
+// is screened again when those are enabled, and an identity.updated webhook is sent with the identity as updated.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - identity *model.Identity: A pointer to the Identity model to be updated.
 //
 // Returns:
-// - error: An error if the identity could not be updated.
-func (l *Blnk) UpdateIdentity(identity *model.Identity) error {
-	return l.datasource.UpdateIdentity(identity)
+// - error: An error if the identity's locale, timezone or communication preferences are invalid, a
+// *model.IdentitySchemaError if its meta_data does not match the schema of its identity type, a conflict error if
+// identity.Version is set and the identity has been updated since, or an error if it could not be updated.
+func (l *Blnk) UpdateIdentity(ctx context.Context, identity *model.Identity) error {
+	if err := identity.ValidatePreferences(); err != nil {
+		return err
+	}
+	if err := l.validateIdentityUpdateFields(ctx, identity); err != nil {
+		return err
+	}
+	changedBy := tenant.FromContext(ctx)
+	if changedBy == "" {
+		changedBy = model.ActorSystem
+	}
+	if err := l.datasource.UpdateIdentity(ctx, identity, changedBy); err != nil {
+		return err
+	}
+	if l.identityRiskScorer() != nil || l.identityScreener() != nil {
+		// Updates only carry the changed fields, so the identity is scored and screened as it now is
+		if updated, err := l.datasource.GetIdentityByID(ctx, identity.IdentityID); err == nil {
+			l.rescoreIdentity(ctx, updated)
+			l.rescreenIdentity(ctx, updated)
+		} else {
+			notification.NotifyError(err)
+		}
+	}
+	l.postIdentityChangeActions(ctx, EventIdentityUpdated, identity.IdentityID)
+	return nil
+}
+
+// GetIdentityHistory retrieves the previous versions of an identity, oldest first, each with the fields the
+// update that replaced it changed and who made that update. Deleted identities keep their history.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - id string: The ID of the identity.
+//
+// Returns:
+// - []*model.IdentityHistoryEntry: The previous versions of the identity.
+// - error: An error if the identity does not exist or its history could not be retrieved.
+func (l *Blnk) GetIdentityHistory(ctx context.Context, id string) ([]*model.IdentityHistoryEntry, error) {
+	if _, err := l.datasource.GetIdentityByIDIncludingDeleted(ctx, id); err != nil {
+		return nil, err
+	}
+	return l.datasource.GetIdentityHistory(ctx, id)
+}
+
+// GetIdentityBalances retrieves the balances of an identity, oldest first, including those of a deleted identity.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - id string: The ID of the identity.
+//
+// Returns:
+// - []model.Balance: The balances of the identity.
+// - error: An error if the identity does not exist or its balances could not be retrieved.
+func (l *Blnk) GetIdentityBalances(ctx context.Context, id string) ([]model.Balance, error) {
+	if _, err := l.datasource.GetIdentityByIDIncludingDeleted(ctx, id); err != nil {
+		return nil, err
+	}
+	return l.datasource.GetBalancesByIdentity(ctx, id)
+}
+
+// GetIdentityTransactions retrieves the transactions that moved money in or out of the balances of an identity,
+// newest first, including those of a deleted identity.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - id string: The ID of the identity.
+// - page model.Page: The page of transactions to read.
+//
+// Returns:
+// - []*model.Transaction: The transactions of the identity.
+// - error: An error if the identity does not exist or its transactions could not be retrieved.
+func (l *Blnk) GetIdentityTransactions(ctx context.Context, id string, page model.Page) ([]*model.Transaction, error) {
+	if _, err := l.datasource.GetIdentityByIDIncludingDeleted(ctx, id); err != nil {
+		return nil, err
+	}
+	return l.datasource.GetTransactionsByIdentity(ctx, id, page)
 }
 
-// DeleteIdentity deletes an identity by its ID.
+// TransactionHistoryCursor returns the position of a transaction in transaction history, which is ordered by
+// the configured history order.
+func (l *Blnk) TransactionHistoryCursor(txn *model.Transaction) model.PageCursor {
+	order := ""
+	if cfg, err := config.Fetch(); err == nil {
+		order = cfg.Transaction.HistoryOrder
+	}
+	return txn.HistoryCursor(order)
+}
+
+// DeleteIdentity soft-deletes an identity by its ID. The identity is hidden from reads until it is restored, and
+// an identity.deleted webhook is sent with it.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - id string: The ID of the identity to delete.
 //
 // Returns:
 // - error: An error if the identity could not be deleted.
-func (l *Blnk) DeleteIdentity(id string) error {
-	return l.datasource.DeleteIdentity(id)
+func (l *Blnk) DeleteIdentity(ctx context.Context, id string) error {
+	if err := l.datasource.DeleteIdentity(ctx, id); err != nil {
+		return err
+	}
+	l.postIdentityChangeActions(ctx, EventIdentityDeleted, id)
+	return nil
+}
+
+// RestoreIdentity restores a deleted identity by its ID and sends an identity.restored webhook with it.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - id string: The ID of the identity to restore.
+//
+// Returns:
+// - error: An error if no deleted identity has the ID or it could not be restored.
+func (l *Blnk) RestoreIdentity(ctx context.Context, id string) error {
+	if err := l.datasource.RestoreIdentity(ctx, id); err != nil {
+		return err
+	}
+	l.postIdentityChangeActions(ctx, EventIdentityRestored, id)
+	return nil
 }
 
 // TokenizeIdentityField tokenizes a specific field in an identity.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - identityID string: The ID of the identity.
 // - fieldName string: The name of the field to tokenize.
 //
 // Returns:
 // - error: An error if the field could not be tokenized.
-func (l *Blnk) TokenizeIdentityField(identityID, fieldName string) error {
+func (l *Blnk) TokenizeIdentityField(ctx context.Context, identityID, fieldName string) error {
 	// Convert field name to struct field format for reflection
 	structFieldName := convertToStructFieldName(fieldName)
 
@@ -132,7 +331,7 @@ func (l *Blnk) TokenizeIdentityField(identityID, fieldName string) error {
 	}
 
 	// Get the identity
-	identity, err := l.GetIdentity(identityID)
+	identity, err := l.GetIdentity(ctx, identityID)
 	if err != nil {
 		return err
 	}
@@ -168,21 +367,22 @@ func (l *Blnk) TokenizeIdentityField(identityID, fieldName string) error {
 	identity.MarkFieldAsTokenized(fieldName)
 
 	// Update the identity
-	return l.UpdateIdentity(identity)
+	return l.UpdateIdentity(ctx, identity)
 }
 
 // DetokenizeIdentityField detokenizes a specific field in an identity.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - identityID string: The ID of the identity.
 // - fieldName string: The name of the field to detokenize.
 //
 // Returns:
 // - string: The detokenized field value.
 // - error: An error if the field could not be detokenized.
-func (l *Blnk) DetokenizeIdentityField(identityID, fieldName string) (string, error) {
+func (l *Blnk) DetokenizeIdentityField(ctx context.Context, identityID, fieldName string) (string, error) {
 	// Get the identity
-	identity, err := l.GetIdentity(identityID)
+	identity, err := l.GetIdentity(ctx, identityID)
 	if err != nil {
 		return "", err
 	}
@@ -226,14 +426,15 @@ func (l *Blnk) DetokenizeIdentityField(identityID, fieldName string) (string, er
 // TokenizeIdentity tokenizes all specified fields in an identity.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - identityID string: The ID of the identity.
 // - fields []string: The names of the fields to tokenize.
 //
 // Returns:
 // - error: An error if any field could not be tokenized.
-func (l *Blnk) TokenizeIdentity(identityID string, fields []string) error {
+func (l *Blnk) TokenizeIdentity(ctx context.Context, identityID string, fields []string) error {
 	for _, field := range fields {
-		err := l.TokenizeIdentityField(identityID, field)
+		err := l.TokenizeIdentityField(ctx, identityID, field)
 		if err != nil {
 			return err
 		}
@@ -244,14 +445,15 @@ func (l *Blnk) TokenizeIdentity(identityID string, fields []string) error {
 // DetokenizeIdentity detokenizes and returns all tokenized fields in an identity.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - identityID string: The ID of the identity.
 //
 // Returns:
 // - map[string]string: A map of field names to their detokenized values.
 // - error: An error if any field could not be detokenized.
-func (l *Blnk) DetokenizeIdentity(identityID string) (map[string]string, error) {
+func (l *Blnk) DetokenizeIdentity(ctx context.Context, identityID string) (map[string]string, error) {
 	// Get the identity
-	identity, err := l.GetIdentity(identityID)
+	identity, err := l.GetIdentity(ctx, identityID)
 	if err != nil {
 		return nil, err
 	}
@@ -264,7 +466,7 @@ func (l *Blnk) DetokenizeIdentity(identityID string) (map[string]string, error)
 		if ok {
 			for fieldName, isTokenized := range tokenizedFields {
 				if isTokenized {
-					originalValue, err := l.DetokenizeIdentityField(identityID, fieldName)
+					originalValue, err := l.DetokenizeIdentityField(ctx, identityID, fieldName)
 					if err != nil {
 						return nil, err
 					}
@@ -280,14 +482,15 @@ func (l *Blnk) DetokenizeIdentity(identityID string) (map[string]string, error)
 // TokenizeAllPII tokenizes all eligible PII fields in an identity.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - identityID string: The ID of the identity.
 //
 // Returns:
 // - error: An error if any field could not be tokenized.
-func (l *Blnk) TokenizeAllPII(identityID string) error {
+func (l *Blnk) TokenizeAllPII(ctx context.Context, identityID string) error {
 	for _, field := range tokenization.TokenizableFields {
 		// Ignore errors for fields that might already be tokenized
-		_ = l.TokenizeIdentityField(identityID, field)
+		_ = l.TokenizeIdentityField(ctx, identityID, field)
 	}
 	return nil
 }
@@ -296,14 +499,15 @@ func (l *Blnk) TokenizeAllPII(identityID string) error {
 // Note: This does not modify the stored identity.
 //
 // Parameters:
+// - ctx context.Context: The context for the operation.
 // - identityID string: The ID of the identity.
 //
 // Returns:
 // - *model.Identity: A pointer to the detokenized Identity model.
 // - error: An error if the identity could not be detokenized.
-func (l *Blnk) GetDetokenizedIdentity(identityID string) (*model.Identity, error) {
+func (l *Blnk) GetDetokenizedIdentity(ctx context.Context, identityID string) (*model.Identity, error) {
 	// Get the identity
-	identity, err := l.GetIdentity(identityID)
+	identity, err := l.GetIdentity(ctx, identityID)
 	if err != nil {
 		return nil, err
 	}
@@ -317,7 +521,7 @@ func (l *Blnk) GetDetokenizedIdentity(identityID string) (*model.Identity, error
 		if ok {
 			for field, isTokenized := range tokenizedFields {
 				if isTokenized {
-					originalValue, err := l.DetokenizeIdentityField(identityID, field)
+					originalValue, err := l.DetokenizeIdentityField(ctx, identityID, field)
 					if err != nil {
 						return nil, err
 					}
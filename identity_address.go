@@ -0,0 +1,140 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// Events sent when identity addresses change.
+const (
+	EventIdentityAddressCreated = "identity.address.created"
+	EventIdentityAddressUpdated = "identity.address.updated"
+	EventIdentityAddressDeleted = "identity.address.deleted"
+)
+
+// ErrInvalidIdentityAddress is returned when an address of an identity is rejected.
+var ErrInvalidIdentityAddress = errors.New("invalid identity address")
+
+// CreateIdentityAddress adds an address to an identity, such as a mailing address besides where an individual
+// lives, or another branch of an organization.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - address model.IdentityAddress: The identity, type, fields and metadata of the address.
+//
+// Returns:
+// - *model.IdentityAddress: The saved address.
+// - error: ErrInvalidIdentityAddress if the address is rejected, or an error if the identity does not exist.
+func (l *Blnk) CreateIdentityAddress(ctx context.Context, address model.IdentityAddress) (*model.IdentityAddress, error) {
+	ctx, span := tracer.Start(ctx, "CreateIdentityAddress")
+	defer span.End()
+
+	if err := address.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIdentityAddress, err)
+	}
+	if _, err := l.datasource.GetIdentityByID(ctx, address.IdentityID); err != nil {
+		return nil, err
+	}
+
+	address.AddressID = model.GenerateUUIDWithSuffix("addr")
+	address.CreatedAt = time.Now()
+	address.UpdatedAt = address.CreatedAt
+	address.Version = 1
+	if err := l.datasource.CreateIdentityAddress(ctx, &address); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	l.sendIdentityAddressWebhook(EventIdentityAddressCreated, &address)
+	return &address, nil
+}
+
+// GetIdentityAddress retrieves an address of an identity. Addresses of other identities are not found.
+func (l *Blnk) GetIdentityAddress(ctx context.Context, identityID, addressID string) (*model.IdentityAddress, error) {
+	address, err := l.datasource.GetIdentityAddress(ctx, addressID)
+	if err != nil {
+		return nil, err
+	}
+	if address.IdentityID != identityID {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity address with ID '%s' not found", addressID), nil)
+	}
+	return address, nil
+}
+
+// GetIdentityAddresses lists the addresses of an identity, oldest first.
+func (l *Blnk) GetIdentityAddresses(ctx context.Context, identityID string) ([]*model.IdentityAddress, error) {
+	if _, err := l.datasource.GetIdentityByIDIncludingDeleted(ctx, identityID); err != nil {
+		return nil, err
+	}
+	return l.datasource.GetIdentityAddresses(ctx, identityID)
+}
+
+// UpdateIdentityAddress replaces the type, fields and metadata of an address of an identity.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - identityID string: The ID of the identity the address belongs to.
+// - addressID string: The ID of the address.
+// - update model.IdentityAddress: The new type, fields and metadata of the address, and the version of the
+// address the update was made against, or 0 to update it as it is.
+//
+// Returns:
+// - *model.IdentityAddress: The updated address.
+// - error: ErrInvalidIdentityAddress if the update is rejected, a conflict error if the address is no longer at
+// the version, or an error if the address is not found or cannot be updated.
+func (l *Blnk) UpdateIdentityAddress(ctx context.Context, identityID, addressID string, update model.IdentityAddress) (*model.IdentityAddress, error) {
+	if err := update.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIdentityAddress, err)
+	}
+	address, err := l.GetIdentityAddress(ctx, identityID, addressID)
+	if err != nil {
+		return nil, err
+	}
+	if update.Version > 0 && update.Version != address.Version {
+		return nil, apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Identity address with ID '%s' is at version %d, not %d", addressID, address.Version, update.Version), nil)
+	}
+	address.Type = update.Type
+	address.Street = update.Street
+	address.City = update.City
+	address.State = update.State
+	address.PostCode = update.PostCode
+	address.Country = update.Country
+	address.MetaData = update.MetaData
+	address.UpdatedAt = time.Now()
+	if err := l.datasource.UpdateIdentityAddress(ctx, address); err != nil {
+		return nil, err
+	}
+
+	l.sendIdentityAddressWebhook(EventIdentityAddressUpdated, address)
+	return address, nil
+}
+
+// DeleteIdentityAddress removes an address of an identity.
+func (l *Blnk) DeleteIdentityAddress(ctx context.Context, identityID, addressID string) error {
+	address, err := l.GetIdentityAddress(ctx, identityID, addressID)
+	if err != nil {
+		return err
+	}
+	if err := l.datasource.DeleteIdentityAddress(ctx, addressID); err != nil {
+		return err
+	}
+
+	l.sendIdentityAddressWebhook(EventIdentityAddressDeleted, address)
+	return nil
+}
+
+// sendIdentityAddressWebhook sends a webhook about an identity address in the background.
+func (l *Blnk) sendIdentityAddressWebhook(event string, address *model.IdentityAddress) {
+	payload := *address
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateIdentityAddress(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_ada").Return(&model.Identity{IdentityID: "idt_ada"}, nil)
+	mockDS.On("CreateIdentityAddress", mock.Anything, mock.AnythingOfType("*model.IdentityAddress")).Return(nil)
+
+	address, err := b.CreateIdentityAddress(context.Background(), model.IdentityAddress{
+		IdentityID: "idt_ada",
+		Type:       model.AddressMailing,
+		Street:     "PO Box 12",
+		Country:    "GB",
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, address.AddressID)
+	assert.False(t, address.CreatedAt.IsZero())
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
+
+func TestCreateIdentityAddress_Invalid(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	_, err := b.CreateIdentityAddress(context.Background(), model.IdentityAddress{IdentityID: "idt_ada", Type: "holiday", Country: "GB"})
+	assert.True(t, errors.Is(err, ErrInvalidIdentityAddress))
+
+	_, err = b.CreateIdentityAddress(context.Background(), model.IdentityAddress{IdentityID: "idt_ada", Type: model.AddressResidential})
+	assert.True(t, errors.Is(err, ErrInvalidIdentityAddress))
+	mockDS.AssertNotCalled(t, "CreateIdentityAddress", mock.Anything, mock.Anything)
+}
+
+func TestUpdateIdentityAddress(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityAddress", mock.Anything, "addr_1").
+		Return(&model.IdentityAddress{AddressID: "addr_1", IdentityID: "idt_ada", Type: model.AddressResidential, Country: "GB", Version: 1}, nil)
+	mockDS.On("UpdateIdentityAddress", mock.Anything, mock.AnythingOfType("*model.IdentityAddress")).Return(nil)
+
+	address, err := b.UpdateIdentityAddress(context.Background(), "idt_ada", "addr_1", model.IdentityAddress{Type: model.AddressBusiness, City: "Paris", Country: "FR"})
+	require.NoError(t, err)
+	assert.Equal(t, "idt_ada", address.IdentityID)
+	assert.Equal(t, model.AddressBusiness, address.Type)
+	assert.Equal(t, "FR", address.Country)
+
+	_, err = b.UpdateIdentityAddress(context.Background(), "idt_bob", "addr_1", model.IdentityAddress{Type: model.AddressBusiness, Country: "FR"})
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+
+	// An update made against another version of the address is rejected before it is written
+	_, err = b.UpdateIdentityAddress(context.Background(), "idt_ada", "addr_1", model.IdentityAddress{Type: model.AddressBusiness, Country: "FR", Version: 4})
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrConflict, apiErr.Code)
+	mockDS.AssertNumberOfCalls(t, "UpdateIdentityAddress", 1)
+}
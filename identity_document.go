@@ -0,0 +1,189 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+)
+
+const (
+	// maxIdentityDocumentSize is the largest document file that can be uploaded.
+	maxIdentityDocumentSize   = 10 << 20
+	identityDocumentKeyPrefix = "identity-documents"
+)
+
+// ErrInvalidIdentityDocument is returned when an uploaded document or its review is rejected.
+var ErrInvalidIdentityDocument = errors.New("invalid identity document")
+
+// identityDocumentExtensions lists the file types accepted for identity documents, by content type, with the
+// extension their objects are stored under.
+var identityDocumentExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+// UploadIdentityDocument stores a verification document for an identity, such as a passport scan or a utility
+// bill. The file is kept in the S3 bucket used for statements and reports, and the document starts pending review.
+// Its content type is detected from the file rather than trusted from the upload, and only PDFs, JPEGs and PNGs
+// are accepted.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - document model.IdentityDocument: The document's identity, type, file name and expiry date.
+// - file io.Reader: The contents of the document.
+//
+// Returns:
+// - *model.IdentityDocument: The saved document.
+// - error: ErrInvalidIdentityDocument if the document is rejected, or an error if the identity does not exist or
+// the document cannot be stored.
+func (l *Blnk) UploadIdentityDocument(ctx context.Context, document model.IdentityDocument, file io.Reader) (*model.IdentityDocument, error) {
+	ctx, span := tracer.Start(ctx, "UploadIdentityDocument")
+	defer span.End()
+
+	if err := document.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIdentityDocument, err)
+	}
+	if _, err := l.datasource.GetIdentityByID(ctx, document.IdentityID); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxIdentityDocumentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidIdentityDocument)
+	}
+	if len(data) > maxIdentityDocumentSize {
+		return nil, fmt.Errorf("%w: the file is larger than %d bytes", ErrInvalidIdentityDocument, maxIdentityDocumentSize)
+	}
+	contentType := http.DetectContentType(data)
+	extension, ok := identityDocumentExtensions[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported file type %s", ErrInvalidIdentityDocument, contentType)
+	}
+
+	store, err := l.getStatementStore()
+	if err != nil {
+		return nil, err
+	}
+	document.DocumentID = model.GenerateUUIDWithSuffix("doc")
+	document.ContentType = contentType
+	document.Size = int64(len(data))
+	document.StorageKey = fmt.Sprintf("%s/%s/%s%s", identityDocumentKeyPrefix, document.IdentityID, document.DocumentID, extension)
+	document.VerificationStatus = model.VerificationPending
+	document.CreatedAt = time.Now()
+	if err := store.Put(ctx, document.StorageKey, data); err != nil {
+		return nil, fmt.Errorf("failed to store identity document: %w", err)
+	}
+	if err := l.datasource.CreateIdentityDocument(ctx, &document); err != nil {
+		return nil, err
+	}
+
+	l.sendIdentityDocumentWebhook("identity.document.uploaded", &document)
+	return &document, nil
+}
+
+// GetIdentityDocument retrieves a document of an identity. Documents of other identities are not found.
+func (l *Blnk) GetIdentityDocument(ctx context.Context, identityID, documentID string) (*model.IdentityDocument, error) {
+	document, err := l.datasource.GetIdentityDocument(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document.IdentityID != identityID {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity document with ID '%s' not found", documentID), nil)
+	}
+	return document, nil
+}
+
+// ListIdentityDocuments lists the documents of an identity, newest first.
+func (l *Blnk) ListIdentityDocuments(ctx context.Context, identityID string) ([]*model.IdentityDocument, error) {
+	if _, err := l.datasource.GetIdentityByID(ctx, identityID); err != nil {
+		return nil, err
+	}
+	return l.datasource.GetIdentityDocuments(ctx, identityID)
+}
+
+// DownloadIdentityDocument retrieves a document of an identity along with its file.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - identityID string: The ID of the identity.
+// - documentID string: The ID of the document.
+//
+// Returns:
+// - *model.IdentityDocument: The document.
+// - []byte: The contents of its file.
+// - error: An error if the document is not found or its file cannot be loaded.
+func (l *Blnk) DownloadIdentityDocument(ctx context.Context, identityID, documentID string) (*model.IdentityDocument, []byte, error) {
+	document, err := l.GetIdentityDocument(ctx, identityID, documentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := l.getStatementStore()
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := store.Get(ctx, document.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load identity document: %w", err)
+	}
+	return document, data, nil
+}
+
+// ReviewIdentityDocument records the review of a pending document, which ends verified or rejected, and sends
+// an identity.document.<status> webhook. Expired documents cannot be verified.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - identityID string: The ID of the identity.
+// - documentID string: The ID of the document.
+// - status string: The status the review ended with.
+// - reason string: Why the document was verified or rejected. Optional.
+//
+// Returns:
+// - *model.IdentityDocument: The reviewed document.
+// - error: ErrInvalidVerificationTransition if the document is not pending or the status is not a review
+// outcome, ErrInvalidIdentityDocument if an expired document is verified, or an error if the document is not
+// found or was reviewed concurrently.
+func (l *Blnk) ReviewIdentityDocument(ctx context.Context, identityID, documentID, status, reason string) (*model.IdentityDocument, error) {
+	document, err := l.GetIdentityDocument(ctx, identityID, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if !model.CanReviewDocument(document.VerificationStatus, status) {
+		return nil, fmt.Errorf("%w: document %s is %s and cannot become %s", ErrInvalidVerificationTransition, documentID, document.VerificationStatus, status)
+	}
+
+	now := time.Now()
+	if status == model.VerificationVerified && document.Expired(now) {
+		return nil, fmt.Errorf("%w: document %s expired on %s", ErrInvalidIdentityDocument, documentID, document.ExpiresAt.Format(time.DateOnly))
+	}
+	if err := l.datasource.UpdateIdentityDocumentVerification(ctx, documentID, status, reason, now); err != nil {
+		return nil, err
+	}
+
+	document.VerificationStatus = status
+	document.VerificationReason = reason
+	document.VerifiedAt = &now
+	l.sendIdentityDocumentWebhook("identity.document."+status, document)
+	return document, nil
+}
+
+// sendIdentityDocumentWebhook sends a webhook about an identity document in the background.
+func (l *Blnk) sendIdentityDocumentWebhook(event string, document *model.IdentityDocument) {
+	payload := *document
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
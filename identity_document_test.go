@@ -0,0 +1,123 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testPDF = []byte("%PDF-1.4\n1 0 obj\n<<>>\nendobj\n")
+
+func TestUploadIdentityDocument(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	store := &memoryStatementStore{objects: map[string][]byte{}}
+	b.statements = store
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil)
+	mockDS.On("CreateIdentityDocument", mock.Anything, mock.AnythingOfType("*model.IdentityDocument")).Return(nil)
+
+	document, err := b.UploadIdentityDocument(context.Background(), model.IdentityDocument{
+		IdentityID:   "idt_1",
+		DocumentType: model.DocumentTypePassport,
+		FileName:     "passport.pdf",
+	}, bytes.NewReader(testPDF))
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", document.ContentType)
+	assert.Equal(t, int64(len(testPDF)), document.Size)
+	assert.Equal(t, model.VerificationPending, document.VerificationStatus)
+	assert.Equal(t, "identity-documents/idt_1/"+document.DocumentID+".pdf", document.StorageKey)
+	assert.Equal(t, testPDF, store.objects[document.StorageKey])
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
+
+func TestUploadIdentityDocument_RejectsUnsupportedFiles(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	store := &memoryStatementStore{objects: map[string][]byte{}}
+	b.statements = store
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil)
+
+	_, err := b.UploadIdentityDocument(context.Background(), model.IdentityDocument{IdentityID: "idt_1", DocumentType: model.DocumentTypeUtilityBill},
+		bytes.NewReader([]byte("#!/bin/sh\necho hello\n")))
+	assert.True(t, errors.Is(err, ErrInvalidIdentityDocument))
+
+	_, err = b.UploadIdentityDocument(context.Background(), model.IdentityDocument{IdentityID: "idt_1", DocumentType: model.DocumentTypeUtilityBill},
+		bytes.NewReader(nil))
+	assert.True(t, errors.Is(err, ErrInvalidIdentityDocument))
+
+	_, err = b.UploadIdentityDocument(context.Background(), model.IdentityDocument{IdentityID: "idt_1", DocumentType: "selfie"},
+		bytes.NewReader(testPDF))
+	assert.True(t, errors.Is(err, ErrInvalidIdentityDocument))
+
+	assert.Empty(t, store.objects)
+	mockDS.AssertNotCalled(t, "CreateIdentityDocument", mock.Anything, mock.Anything)
+}
+
+func TestDownloadIdentityDocument(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	b.statements = &memoryStatementStore{objects: map[string][]byte{"identity-documents/idt_1/doc_1.pdf": testPDF}}
+	mockDS.On("GetIdentityDocument", mock.Anything, "doc_1").
+		Return(&model.IdentityDocument{DocumentID: "doc_1", IdentityID: "idt_1", StorageKey: "identity-documents/idt_1/doc_1.pdf"}, nil)
+
+	document, data, err := b.DownloadIdentityDocument(context.Background(), "idt_1", "doc_1")
+	require.NoError(t, err)
+	assert.Equal(t, "doc_1", document.DocumentID)
+	assert.Equal(t, testPDF, data)
+
+	// Documents are only found through the identity they belong to
+	_, _, err = b.DownloadIdentityDocument(context.Background(), "idt_2", "doc_1")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestReviewIdentityDocument(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityDocument", mock.Anything, "doc_1").
+		Return(&model.IdentityDocument{DocumentID: "doc_1", IdentityID: "idt_1", VerificationStatus: model.VerificationPending}, nil)
+	mockDS.On("UpdateIdentityDocumentVerification", mock.Anything, "doc_1", model.VerificationVerified, "matches", mock.AnythingOfType("time.Time")).Return(nil)
+
+	document, err := b.ReviewIdentityDocument(context.Background(), "idt_1", "doc_1", model.VerificationVerified, "matches")
+	require.NoError(t, err)
+	assert.Equal(t, model.VerificationVerified, document.VerificationStatus)
+	assert.NotNil(t, document.VerifiedAt)
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
+
+func TestReviewIdentityDocument_Rejections(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	expired := time.Now().AddDate(0, -1, 0)
+	mockDS.On("GetIdentityDocument", mock.Anything, "doc_reviewed").
+		Return(&model.IdentityDocument{DocumentID: "doc_reviewed", IdentityID: "idt_1", VerificationStatus: model.VerificationRejected}, nil)
+	mockDS.On("GetIdentityDocument", mock.Anything, "doc_expired").
+		Return(&model.IdentityDocument{DocumentID: "doc_expired", IdentityID: "idt_1", VerificationStatus: model.VerificationPending, ExpiresAt: &expired}, nil)
+
+	_, err := b.ReviewIdentityDocument(context.Background(), "idt_1", "doc_reviewed", model.VerificationVerified, "")
+	assert.True(t, errors.Is(err, ErrInvalidVerificationTransition))
+
+	_, err = b.ReviewIdentityDocument(context.Background(), "idt_1", "doc_expired", model.VerificationVerified, "")
+	assert.True(t, errors.Is(err, ErrInvalidIdentityDocument))
+
+	mockDS.AssertNotCalled(t, "UpdateIdentityDocumentVerification", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,75 @@
+package blnk
+
+import (
+	"context"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// EventIdentityAnonymized is sent when an identity's personal fields are erased.
+const EventIdentityAnonymized = "identity.anonymized"
+
+// AnonymizeIdentity irreversibly erases the personal fields of an identity for a right-to-erasure request: its
+// names, email address, phone number, date of birth and address are cleared, along with the tokens of any of
+// them that were tokenized, and the same fields are overwritten in every previous version kept in its history.
+// The identity keeps its ID, so the balances, accounts and transactions referencing it are untouched, and a
+// receipt of the erasure is recorded in the same database transaction. Deleted identities can be erased too,
+// but each identity only once.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - identityID string: The ID of the identity to erase.
+// - reason string: Why the identity was erased, such as the reference of the request. Optional.
+//
+// Returns:
+// - *model.IdentityErasure: The receipt of the erasure.
+// - error: An error if the identity does not exist, was already erased, or could not be erased.
+func (l *Blnk) AnonymizeIdentity(ctx context.Context, identityID, reason string) (*model.IdentityErasure, error) {
+	ctx, span := tracer.Start(ctx, "AnonymizeIdentity")
+	defer span.End()
+
+	requestedBy := tenant.FromContext(ctx)
+	if requestedBy == "" {
+		requestedBy = model.ActorSystem
+	}
+	erasure := &model.IdentityErasure{
+		ErasureID:   model.GenerateUUIDWithSuffix("era"),
+		IdentityID:  identityID,
+		Fields:      model.ErasedIdentityFields,
+		Reason:      reason,
+		RequestedBy: requestedBy,
+		ErasedAt:    time.Now(),
+	}
+	if err := l.datasource.AnonymizeIdentity(ctx, erasure); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	l.postIdentityErasureActions(ctx, erasure)
+	return erasure, nil
+}
+
+// GetIdentityErasure retrieves the receipt of an identity's erasure.
+func (l *Blnk) GetIdentityErasure(ctx context.Context, identityID string) (*model.IdentityErasure, error) {
+	return l.datasource.GetIdentityErasure(ctx, identityID)
+}
+
+// postIdentityErasureActions reindexes an erased identity, so that its erased fields no longer appear in
+// searches, and sends an identity.anonymized webhook with the receipt.
+func (l *Blnk) postIdentityErasureActions(_ context.Context, erasure *model.IdentityErasure) {
+	payload := *erasure
+	go func() {
+		identity, err := l.datasource.GetIdentityByIDIncludingDeleted(context.Background(), payload.IdentityID)
+		if err != nil {
+			notification.NotifyError(err)
+		} else if err := l.queue.queueIndexData(identity.IdentityID, "identities", identity); err != nil {
+			notification.NotifyError(err)
+		}
+		if err := l.SendWebhook(NewWebhook{Event: EventIdentityAnonymized, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
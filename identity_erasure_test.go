@@ -0,0 +1,46 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymizeIdentity(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("AnonymizeIdentity", mock.Anything, mock.AnythingOfType("*model.IdentityErasure")).Run(func(args mock.Arguments) {
+		args.Get(1).(*model.IdentityErasure).HistoryVersions = 2
+	}).Return(nil)
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil)
+
+	erasure, err := b.AnonymizeIdentity(tenant.WithTenant(context.Background(), "tnt_1"), "idt_1", "erasure request 42")
+	require.NoError(t, err)
+	assert.Equal(t, "idt_1", erasure.IdentityID)
+	assert.Equal(t, model.ErasedIdentityFields, erasure.Fields)
+	assert.Equal(t, 2, erasure.HistoryVersions)
+	assert.Equal(t, "erasure request 42", erasure.Reason)
+	assert.Equal(t, "tnt_1", erasure.RequestedBy)
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+}
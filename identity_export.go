@@ -0,0 +1,298 @@
+package blnk
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/parquet"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/redis/go-redis/v9"
+	"github.com/wacul/ptr"
+)
+
+const (
+	identityExportKeyPrefix = "identity-exports"
+	identityExportTTL       = 7 * 24 * time.Hour
+	// identityExportPageSize is how many identities an export reads at once.
+	identityExportPageSize = 1000
+	// identityExportS3Prefix is where exports are written in the S3 bucket when no key is given.
+	identityExportS3Prefix = "exports/identities"
+)
+
+// ErrInvalidIdentityExport is returned when an export is requested in an unknown format or to an unknown
+// destination.
+var ErrInvalidIdentityExport = errors.New("invalid identity export")
+
+// StartIdentityExport starts writing the identities matching the export's filter to a CSV or Parquet file in
+// the background, in the export directory or the S3 bucket. Identities are read a page at a time and streamed
+// to the file, so exports of any size run in bounded memory.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - export model.IdentityExport: The format, destination and filter of the export.
+//
+// Returns:
+// - *model.IdentityExport: The export, whose progress can be polled with GetIdentityExport.
+// - error: ErrInvalidIdentityExport if the format or destination is unknown, or an error if the export cannot be
+// recorded.
+func (l *Blnk) StartIdentityExport(ctx context.Context, export model.IdentityExport) (*model.IdentityExport, error) {
+	if err := prepareIdentityExport(&export); err != nil {
+		return nil, err
+	}
+	if err := l.saveIdentityExport(ctx, &export); err != nil {
+		return nil, err
+	}
+
+	// The export outlives the request but keeps its tenant
+	ctx = context.WithoutCancel(ctx)
+	go func(export model.IdentityExport) {
+		if err := l.RunIdentityExport(ctx, &export, func() {
+			if err := l.saveIdentityExport(ctx, &export); err != nil {
+				log.Printf("Identity export %s: failed to save progress: %v", export.ExportID, err)
+			}
+		}); err != nil {
+			log.Printf("Identity export %s: %v", export.ExportID, err)
+		}
+		if err := l.saveIdentityExport(ctx, &export); err != nil {
+			log.Printf("Identity export %s: failed to save result: %v", export.ExportID, err)
+		}
+	}(export)
+
+	return &export, nil
+}
+
+// GetIdentityExport retrieves the current state of an identity export.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - exportID string: The ID of the export.
+//
+// Returns:
+// - *model.IdentityExport: The export and the number of identities written.
+// - error: An error if the export does not exist or cannot be read.
+func (l *Blnk) GetIdentityExport(ctx context.Context, exportID string) (*model.IdentityExport, error) {
+	data, err := l.redis.Get(ctx, fmt.Sprintf("%s:%s", identityExportKeyPrefix, exportID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("identity export not found: %s", exportID)
+		}
+		return nil, err
+	}
+
+	var export model.IdentityExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal identity export: %w", err)
+	}
+	return &export, nil
+}
+
+// RunIdentityExport writes the identities matching the export's filter to its destination and records the
+// outcome on the export. A local export is written to its Location, or to the export directory when it has
+// none, and only appears there once complete; an S3 export is uploaded to the key in its Location, or under
+// exports/identities when it has none.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - export *model.IdentityExport: The export, updated with its location, row count and status.
+// - onPage func(): Called after every page of identities written, to report progress. May be nil.
+//
+// Returns:
+// - error: An error if the export is invalid or the file could not be written.
+func (l *Blnk) RunIdentityExport(ctx context.Context, export *model.IdentityExport, onPage func()) error {
+	ctx, span := tracer.Start(ctx, "RunIdentityExport")
+	defer span.End()
+
+	err := prepareIdentityExport(export)
+	if err == nil {
+		if export.Destination == model.IdentityExportS3 {
+			err = l.uploadIdentityExport(ctx, export, onPage)
+		} else {
+			err = l.writeLocalIdentityExport(ctx, export, onPage)
+		}
+	}
+
+	export.CompletedAt = ptr.Time(time.Now())
+	export.Status = model.IdentityExportCompleted
+	if err != nil {
+		span.RecordError(err)
+		export.Status = model.IdentityExportFailed
+		export.Error = err.Error()
+	}
+	return err
+}
+
+// prepareIdentityExport checks an export's format and destination and gives a new export its ID and status.
+func prepareIdentityExport(export *model.IdentityExport) error {
+	switch export.Format {
+	case model.IdentityExportCSV, model.IdentityExportParquet:
+	default:
+		return fmt.Errorf("%w: unknown format %q, expected csv or parquet", ErrInvalidIdentityExport, export.Format)
+	}
+	if export.Destination == "" {
+		export.Destination = model.IdentityExportLocal
+	}
+	switch export.Destination {
+	case model.IdentityExportLocal, model.IdentityExportS3:
+	default:
+		return fmt.Errorf("%w: unknown destination %q, expected local or s3", ErrInvalidIdentityExport, export.Destination)
+	}
+
+	if export.ExportID == "" {
+		export.ExportID = model.GenerateUUIDWithSuffix("export")
+		export.CreatedAt = time.Now()
+	}
+	export.Status = model.IdentityExportRunning
+	return nil
+}
+
+// writeLocalIdentityExport writes an export to a file on disk, writing to a temporary file renamed into place
+// once complete.
+func (l *Blnk) writeLocalIdentityExport(ctx context.Context, export *model.IdentityExport, onPage func()) error {
+	if export.Location == "" {
+		cnf, err := config.Fetch()
+		if err != nil {
+			return err
+		}
+		export.Location = filepath.Join(cnf.ExportDir, export.ExportID+"."+export.Format)
+	}
+	if err := os.MkdirAll(filepath.Dir(export.Location), 0o755); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	partial := export.Location + ".partial"
+	file, err := os.Create(partial)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	err = l.writeIdentityExport(ctx, file, export, onPage)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(partial)
+		return err
+	}
+	return os.Rename(partial, export.Location)
+}
+
+// uploadIdentityExport streams an export to the S3 bucket as it is written, in a multipart upload.
+func (l *Blnk) uploadIdentityExport(ctx context.Context, export *model.IdentityExport, onPage func()) error {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return err
+	}
+	if cnf.S3BucketName == "" {
+		return errors.New("s3 bucket is not configured for identity exports")
+	}
+	client, err := newS3Client(cnf)
+	if err != nil {
+		return err
+	}
+	key := export.Location
+	if key == "" {
+		key = path.Join(identityExportS3Prefix, export.ExportID+"."+export.Format)
+	}
+	export.Location = fmt.Sprintf("s3://%s/%s", cnf.S3BucketName, key)
+
+	reader, writer := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		err := l.writeIdentityExport(ctx, writer, export, onPage)
+		_ = writer.CloseWithError(err)
+		written <- err
+	}()
+
+	_, err = s3manager.NewUploaderWithClient(client).UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(cnf.S3BucketName),
+		Key:    aws.String(key),
+		Body:   reader,
+	})
+	// Unblock the writer if the upload stopped reading early.
+	_ = reader.CloseWithError(err)
+	if writeErr := <-written; writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// writeIdentityExport writes the identities matching an export's filter to w in its format, a page at a time,
+// counting them on the export.
+func (l *Blnk) writeIdentityExport(ctx context.Context, w io.Writer, export *model.IdentityExport, onPage func()) error {
+	write, finish, err := newIdentityExportWriter(w, export.Format)
+	if err != nil {
+		return err
+	}
+
+	page := model.Page{Limit: identityExportPageSize}
+	for {
+		identities, err := l.datasource.GetIdentities(ctx, export.Filter, page)
+		if err != nil {
+			return err
+		}
+		for i := range identities {
+			if err := write(identities[i].ExportRecord()); err != nil {
+				return fmt.Errorf("failed to write identity %s: %w", identities[i].IdentityID, err)
+			}
+		}
+		export.Rows += int64(len(identities))
+		if onPage != nil {
+			onPage()
+		}
+		if len(identities) < identityExportPageSize {
+			return finish()
+		}
+		// Identities filtered by risk score are ordered riskiest first, which a cursor cannot page.
+		if export.Filter.MinRiskScore > 0 {
+			page.Offset += identityExportPageSize
+		} else {
+			page.After = model.NextPageCursor(identities, identityExportPageSize, model.Identity.PageCursor)
+		}
+	}
+}
+
+// newIdentityExportWriter returns functions writing the rows of an export in a format to w, and finishing the
+// file once every row is written.
+func newIdentityExportWriter(w io.Writer, format string) (func([]string) error, func() error, error) {
+	switch format {
+	case model.IdentityExportCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(model.IdentityExportColumns); err != nil {
+			return nil, nil, err
+		}
+		finish := func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+		return writer.Write, finish, nil
+	case model.IdentityExportParquet:
+		writer, err := parquet.NewWriter(w, model.IdentityExportColumns, parquet.DefaultRowGroupSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		return writer.Write, writer.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown format %q, expected csv or parquet", ErrInvalidIdentityExport, format)
+	}
+}
+
+// saveIdentityExport persists the export state so that progress survives across requests.
+func (l *Blnk) saveIdentityExport(ctx context.Context, export *model.IdentityExport) error {
+	data, err := json.Marshal(export)
+	if err != nil {
+		return fmt.Errorf("failed to marshal identity export: %w", err)
+	}
+	key := fmt.Sprintf("%s:%s", identityExportKeyPrefix, export.ExportID)
+	return l.redis.Set(ctx, key, data, identityExportTTL).Err()
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func identityExportTestIdentities(n int) []model.Identity {
+	identities := make([]model.Identity, n)
+	for i := range identities {
+		identities[i] = model.Identity{
+			IdentityID:   model.GenerateUUIDWithSuffix("idt"),
+			IdentityType: "individual",
+			FirstName:    "Ada",
+			EmailAddress: "ada@example.com",
+			MetaData:     map[string]interface{}{"tier": "gold"},
+			CreatedAt:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+	}
+	return identities
+}
+
+func TestRunIdentityExport_CSVPagesThroughIdentities(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	filter := model.IdentityFilter{Country: "NG"}
+	firstPage := identityExportTestIdentities(identityExportPageSize)
+	after := firstPage[len(firstPage)-1].PageCursor()
+	mockDS.On("GetIdentities", mock.Anything, filter, model.Page{Limit: identityExportPageSize}).Return(firstPage, nil).Once()
+	mockDS.On("GetIdentities", mock.Anything, filter, model.Page{Limit: identityExportPageSize, After: &after}).Return(identityExportTestIdentities(2), nil).Once()
+
+	location := filepath.Join(t.TempDir(), "identities.csv")
+	export := &model.IdentityExport{Format: model.IdentityExportCSV, Location: location, Filter: filter}
+	pages := 0
+	require.NoError(t, b.RunIdentityExport(context.Background(), export, func() { pages++ }))
+
+	assert.Equal(t, model.IdentityExportCompleted, export.Status)
+	assert.Equal(t, int64(identityExportPageSize+2), export.Rows)
+	assert.Equal(t, 2, pages)
+	assert.NoFileExists(t, location+".partial")
+
+	file, err := os.Open(location)
+	require.NoError(t, err)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, identityExportPageSize+3)
+	assert.Equal(t, model.IdentityExportColumns, records[0])
+	assert.Equal(t, "ada@example.com", records[1][9])
+	assert.Equal(t, `{"tier":"gold"}`, records[1][17])
+	mockDS.AssertExpectations(t)
+}
+
+func TestRunIdentityExport_ParquetInExportDirectory(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.ExportDir = t.TempDir()
+	mockDS.On("GetIdentities", mock.Anything, model.IdentityFilter{}, model.Page{Limit: identityExportPageSize}).Return(identityExportTestIdentities(3), nil).Once()
+
+	export := &model.IdentityExport{Format: model.IdentityExportParquet}
+	require.NoError(t, b.RunIdentityExport(context.Background(), export, nil))
+
+	assert.Equal(t, filepath.Join(cnf.ExportDir, export.ExportID+".parquet"), export.Location)
+	data, err := os.ReadFile(export.Location)
+	require.NoError(t, err)
+	assert.Equal(t, "PAR1", string(data[:4]))
+	assert.Equal(t, "PAR1", string(data[len(data)-4:]))
+}
+
+func TestRunIdentityExport_FailedPageRemovesPartialFile(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentities", mock.Anything, model.IdentityFilter{}, model.Page{Limit: identityExportPageSize}).Return([]model.Identity{}, assert.AnError).Once()
+
+	location := filepath.Join(t.TempDir(), "identities.csv")
+	export := &model.IdentityExport{Format: model.IdentityExportCSV, Location: location}
+	require.ErrorIs(t, b.RunIdentityExport(context.Background(), export, nil), assert.AnError)
+
+	assert.Equal(t, model.IdentityExportFailed, export.Status)
+	assert.NotEmpty(t, export.Error)
+	assert.NoFileExists(t, location)
+	assert.NoFileExists(t, location+".partial")
+}
+
+func TestStartIdentityExport(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.ExportDir = t.TempDir()
+	mockDS.On("GetIdentities", mock.Anything, model.IdentityFilter{}, model.Page{Limit: identityExportPageSize}).Return(identityExportTestIdentities(1), nil).Once()
+
+	_, err = b.StartIdentityExport(context.Background(), model.IdentityExport{Format: "xlsx"})
+	assert.ErrorIs(t, err, ErrInvalidIdentityExport)
+	_, err = b.StartIdentityExport(context.Background(), model.IdentityExport{Format: model.IdentityExportCSV, Destination: "ftp"})
+	assert.ErrorIs(t, err, ErrInvalidIdentityExport)
+
+	started, err := b.StartIdentityExport(context.Background(), model.IdentityExport{Format: model.IdentityExportCSV})
+	require.NoError(t, err)
+	assert.Equal(t, model.IdentityExportRunning, started.Status)
+	assert.Equal(t, model.IdentityExportLocal, started.Destination)
+
+	require.Eventually(t, func() bool {
+		export, err := b.GetIdentityExport(context.Background(), started.ExportID)
+		return err == nil && export.Status == model.IdentityExportCompleted && export.Rows == 1
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = b.GetIdentityExport(context.Background(), "export_missing")
+	assert.ErrorContains(t, err, "not found")
+}
@@ -0,0 +1,210 @@
+package blnk
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+)
+
+const (
+	// identityImportBatchSize is how many identities an import creates at once.
+	identityImportBatchSize = 1000
+	// maxIdentityImportLine is the longest NDJSON line an import reads.
+	maxIdentityImportLine = 1 << 20
+)
+
+// ErrInvalidIdentityImport is returned when an import cannot be read at all, such as when its format is unknown
+// or its CSV header names unknown columns. Invalid rows are reported rather than failing the import.
+var ErrInvalidIdentityImport = errors.New("invalid identity import")
+
+// identityImport collects the identities of an import into batches and records the outcome of every row.
+type identityImport struct {
+	blnk    *Blnk
+	report  *model.IdentityImportReport
+	batch   []*model.Identity
+	indexes []int // Index in the report's rows of each identity in the batch
+}
+
+// ImportIdentities creates identities in bulk from a CSV or NDJSON file, for onboarding customers migrated from
+// another system. Rows are validated as they are read and the valid ones created in batches, so that a file of
+// any size is imported in a single pass; rows that are invalid, or whose batch cannot be created, are reported
+// with their error without stopping the import. The identities created are indexed for search, but no
+// identity.created webhooks are sent for them.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - format string: The format of the file, csv or ndjson.
+// - r io.Reader: The file.
+//
+// Returns:
+// - *model.IdentityImportReport: The outcome of every row.
+// - error: ErrInvalidIdentityImport if the file cannot be read as the format.
+func (l *Blnk) ImportIdentities(ctx context.Context, format string, r io.Reader) (*model.IdentityImportReport, error) {
+	ctx, span := tracer.Start(ctx, "ImportIdentities")
+	defer span.End()
+
+	imp := &identityImport{blnk: l, report: &model.IdentityImportReport{Format: format, Rows: []model.IdentityImportResult{}}}
+	var err error
+	switch format {
+	case model.IdentityImportCSV:
+		err = imp.readCSV(ctx, r)
+	case model.IdentityImportNDJSON:
+		err = imp.readNDJSON(ctx, r)
+	default:
+		err = fmt.Errorf("%w: unknown format %q, expected csv or ndjson", ErrInvalidIdentityImport, format)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	imp.flush(ctx)
+	return imp.report, nil
+}
+
+// readCSV reads identities from a CSV file whose header row names the fields of its columns. Empty cells leave
+// their fields unset.
+func (imp *identityImport) readCSV(ctx context.Context, r io.Reader) error {
+	reader := csv.NewReader(bufio.NewReader(r))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%w: failed to read CSV header: %v", ErrInvalidIdentityImport, err)
+	}
+	for i, column := range header {
+		header[i] = strings.TrimSpace(column)
+		if !model.IsImportableIdentityField(header[i]) {
+			return fmt.Errorf("%w: unknown column %q", ErrInvalidIdentityImport, header[i])
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return fmt.Errorf("%w: %v", ErrInvalidIdentityImport, err)
+			}
+			imp.fail(err)
+			continue
+		}
+		if len(record) != len(header) {
+			imp.fail(fmt.Errorf("expected %d columns, got %d", len(header), len(record)))
+			continue
+		}
+
+		fields := make(map[string]interface{}, len(header))
+		var cellErr error
+		for i, value := range record {
+			if value == "" {
+				continue
+			}
+			switch header[i] {
+			case "meta_data", "communication_preferences":
+				var object map[string]interface{}
+				if err := json.Unmarshal([]byte(value), &object); err != nil {
+					cellErr = fmt.Errorf("%s must be a JSON object: %v", header[i], err)
+				}
+				fields[header[i]] = object
+			default:
+				fields[header[i]] = value
+			}
+		}
+		if cellErr != nil {
+			imp.fail(cellErr)
+			continue
+		}
+		imp.add(ctx, fields)
+	}
+}
+
+// readNDJSON reads identities from an NDJSON file, one object per line. Blank lines are skipped.
+func (imp *identityImport) readNDJSON(ctx context.Context, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxIdentityImportLine)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			imp.fail(fmt.Errorf("invalid JSON: %v", err))
+			continue
+		}
+		imp.add(ctx, fields)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidIdentityImport, err)
+	}
+	return nil
+}
+
+// add validates a row and queues its identity for creation, creating the batch once it is full.
+func (imp *identityImport) add(ctx context.Context, fields map[string]interface{}) {
+	identity, err := model.ParseImportedIdentity(fields)
+	if err == nil {
+		err = checkIdentityType(identity)
+	}
+	if err == nil {
+		err = validateIdentityFields(identity.IdentityType, identity.MetaData)
+	}
+	if err != nil {
+		imp.fail(err)
+		return
+	}
+	imp.report.Total++
+	imp.report.Rows = append(imp.report.Rows, model.IdentityImportResult{Row: imp.report.Total})
+	imp.batch = append(imp.batch, identity)
+	imp.indexes = append(imp.indexes, len(imp.report.Rows)-1)
+	if len(imp.batch) >= identityImportBatchSize {
+		imp.flush(ctx)
+	}
+}
+
+// fail records a row that was not imported.
+func (imp *identityImport) fail(err error) {
+	imp.report.Total++
+	imp.report.Failed++
+	imp.report.Rows = append(imp.report.Rows, model.IdentityImportResult{Row: imp.report.Total, Error: err.Error()})
+}
+
+// flush creates the identities of the current batch, and indexes them in the background once created. If the
+// batch cannot be created every row in it is reported with the error.
+func (imp *identityImport) flush(ctx context.Context) {
+	if len(imp.batch) == 0 {
+		return
+	}
+	batch, indexes := imp.batch, imp.indexes
+	imp.batch, imp.indexes = nil, nil
+
+	if err := imp.blnk.datasource.CreateIdentities(ctx, batch); err != nil {
+		for _, index := range indexes {
+			imp.report.Rows[index].Error = err.Error()
+		}
+		imp.report.Failed += len(batch)
+		return
+	}
+	for i, index := range indexes {
+		imp.report.Rows[index].IdentityID = batch[i].IdentityID
+	}
+	imp.report.Imported += len(batch)
+
+	go func() {
+		for _, identity := range batch {
+			if err := imp.blnk.queue.queueIndexData(identity.IdentityID, "identities", identity); err != nil {
+				notification.NotifyError(err)
+			}
+		}
+	}()
+}
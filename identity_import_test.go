@@ -0,0 +1,85 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportIdentities_CSV(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("CreateIdentities", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		for i, identity := range args.Get(1).([]*model.Identity) {
+			identity.IdentityID = []string{"idt_1", "idt_2"}[i]
+		}
+	}).Return(nil)
+
+	file := strings.Join([]string{
+		"identity_type,first_name,email_address,dob,meta_data",
+		`individual,Ada,ada@example.com,1815-12-10,"{""legacy_id"":""c-1""}"`,
+		",Nobody,,,",
+		`individual,Grace,grace@example.com,,`,
+		`individual,Broken,,,{not json}`,
+	}, "\n")
+
+	report, err := b.ImportIdentities(context.Background(), model.IdentityImportCSV, strings.NewReader(file))
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, model.IdentityImportResult{Row: 1, IdentityID: "idt_1"}, report.Rows[0])
+	assert.Equal(t, model.IdentityImportResult{Row: 2, Error: "identity_type is required"}, report.Rows[1])
+	assert.Equal(t, model.IdentityImportResult{Row: 3, IdentityID: "idt_2"}, report.Rows[2])
+	assert.Contains(t, report.Rows[3].Error, "meta_data must be a JSON object")
+
+	batch := mockDS.Calls[0].Arguments.Get(1).([]*model.Identity)
+	assert.Equal(t, "c-1", batch[0].MetaData["legacy_id"])
+}
+
+func TestImportIdentities_NDJSONBatchFailure(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("CreateIdentities", mock.Anything, mock.Anything).Return(errors.New("Failed to copy identities"))
+
+	file := "{\"identity_type\":\"individual\",\"first_name\":\"Ada\"}\n\n{not json}\n{\"identity_type\":\"organization\",\"organization_name\":\"Acme\"}\n"
+	report, err := b.ImportIdentities(context.Background(), model.IdentityImportNDJSON, strings.NewReader(file))
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 0, report.Imported)
+	assert.Equal(t, 3, report.Failed)
+	assert.Equal(t, "Failed to copy identities", report.Rows[0].Error)
+	assert.Contains(t, report.Rows[1].Error, "invalid JSON")
+	assert.Equal(t, "Failed to copy identities", report.Rows[2].Error)
+}
+
+func TestImportIdentities_InvalidFile(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	_, err := b.ImportIdentities(context.Background(), "xlsx", strings.NewReader(""))
+	assert.ErrorIs(t, err, ErrInvalidIdentityImport)
+
+	_, err = b.ImportIdentities(context.Background(), model.IdentityImportCSV, strings.NewReader("identity_type,balance\nindividual,10\n"))
+	assert.ErrorIs(t, err, ErrInvalidIdentityImport)
+	mockDS.AssertNotCalled(t, "CreateIdentities", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,129 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// EventIdentityMerged is sent when a duplicate identity is merged into another.
+const EventIdentityMerged = "identity.merged"
+
+// ErrInvalidIdentityMerge is returned when two identities cannot be merged, such as when they are not duplicates.
+var ErrInvalidIdentityMerge = errors.New("invalid identity merge")
+
+// FindDuplicateIdentities finds the identities that are likely duplicates of an identity: those sharing its
+// email address, its phone number, or its date of birth and name. Deleted identities are not considered.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - identityID string: The ID of the identity.
+//
+// Returns:
+// - []model.DuplicateIdentity: The duplicates with the fields each matched on, newest first.
+// - error: An error if the identity does not exist or the duplicates cannot be retrieved.
+func (l *Blnk) FindDuplicateIdentities(ctx context.Context, identityID string) ([]model.DuplicateIdentity, error) {
+	identity, err := l.datasource.GetIdentityByID(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := l.datasource.FindDuplicateIdentities(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := []model.DuplicateIdentity{}
+	for _, candidate := range candidates {
+		if matched := model.MatchDuplicateIdentity(*identity, candidate); len(matched) > 0 {
+			duplicates = append(duplicates, model.DuplicateIdentity{Identity: candidate, MatchedOn: matched})
+		}
+	}
+	return duplicates, nil
+}
+
+// MergeIdentities merges a duplicate identity into the identity that survives it. The balances and accounts of
+// the merged identity are moved to the survivor and the merged identity is deleted, all in one database
+// transaction along with a record of the merge. Only identities that are duplicates of each other can be merged.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - survivorID string: The ID of the identity to keep.
+// - mergedID string: The ID of the duplicate to merge into it.
+// - reason string: Why the identities were merged. Optional.
+//
+// Returns:
+// - *model.IdentityMerge: The record of the merge, with the balances moved.
+// - error: ErrInvalidIdentityMerge if the identities are the same or not duplicates, or an error if either does
+// not exist or the merge fails.
+func (l *Blnk) MergeIdentities(ctx context.Context, survivorID, mergedID, reason string) (*model.IdentityMerge, error) {
+	ctx, span := tracer.Start(ctx, "MergeIdentities")
+	defer span.End()
+
+	if survivorID == mergedID {
+		return nil, fmt.Errorf("%w: an identity cannot be merged into itself", ErrInvalidIdentityMerge)
+	}
+	survivor, err := l.datasource.GetIdentityByID(ctx, survivorID)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := l.datasource.GetIdentityByID(ctx, mergedID)
+	if err != nil {
+		return nil, err
+	}
+	matched := model.MatchDuplicateIdentity(*survivor, *merged)
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("%w: identities %s and %s share no email address, phone number, or date of birth and name", ErrInvalidIdentityMerge, survivorID, mergedID)
+	}
+
+	merge := &model.IdentityMerge{
+		MergeID:    model.GenerateUUIDWithSuffix("mrg"),
+		SurvivorID: survivorID,
+		MergedID:   mergedID,
+		MatchedOn:  matched,
+		Reason:     reason,
+		MergedAt:   time.Now(),
+	}
+	if err := l.datasource.MergeIdentities(ctx, merge); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	l.postIdentityMergeActions(ctx, merge)
+	return merge, nil
+}
+
+// GetIdentityMerges lists the merges an identity took part in, as survivor or as the merged identity, newest
+// first. Merged identities are deleted, so their merges are listed even if they no longer exist.
+func (l *Blnk) GetIdentityMerges(ctx context.Context, identityID string) ([]*model.IdentityMerge, error) {
+	return l.datasource.GetIdentityMerges(ctx, identityID)
+}
+
+// postIdentityMergeActions reindexes the balances a merge moved, so that searches by identity find them under
+// the survivor, removes the merged identity from the index and sends an identity.merged webhook.
+func (l *Blnk) postIdentityMergeActions(ctx context.Context, merge *model.IdentityMerge) {
+	// The work outlives the request, so it keeps the request's values, such as its tenant, but not its deadline
+	ctx = context.WithoutCancel(ctx)
+	payload := *merge
+	go func() {
+		for _, balanceID := range payload.BalanceIDs {
+			balance, err := l.datasource.GetBalanceByIDLite(ctx, balanceID)
+			if err != nil {
+				notification.NotifyError(err)
+				continue
+			}
+			if err := l.queue.queueIndexData(balance.BalanceID, "balances", balance); err != nil {
+				notification.NotifyError(err)
+			}
+		}
+		if err := l.queue.queueIndexDeletion(payload.MergedID, "identities"); err != nil {
+			notification.NotifyError(err)
+		}
+		if err := l.SendWebhook(NewWebhook{Event: EventIdentityMerged, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
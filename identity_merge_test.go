@@ -0,0 +1,74 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeIdentities(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_keep").Return(&model.Identity{IdentityID: "idt_keep", EmailAddress: "ada@example.com"}, nil)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_dupe").Return(&model.Identity{IdentityID: "idt_dupe", EmailAddress: "Ada@Example.com"}, nil)
+	mockDS.On("MergeIdentities", mock.Anything, mock.AnythingOfType("*model.IdentityMerge")).Run(func(args mock.Arguments) {
+		args.Get(1).(*model.IdentityMerge).BalanceIDs = []string{}
+	}).Return(nil)
+
+	merge, err := b.MergeIdentities(context.Background(), "idt_keep", "idt_dupe", "signed up twice")
+	require.NoError(t, err)
+	assert.Equal(t, "idt_keep", merge.SurvivorID)
+	assert.Equal(t, "idt_dupe", merge.MergedID)
+	assert.Equal(t, []string{model.DuplicateOnEmailAddress}, merge.MatchedOn)
+	assert.Equal(t, "signed up twice", merge.Reason)
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+}
+
+func TestMergeIdentities_NotDuplicates(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_keep").Return(&model.Identity{IdentityID: "idt_keep", EmailAddress: "ada@example.com"}, nil)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_other").Return(&model.Identity{IdentityID: "idt_other", EmailAddress: "grace@example.com"}, nil)
+
+	_, err := b.MergeIdentities(context.Background(), "idt_keep", "idt_other", "")
+	assert.ErrorIs(t, err, ErrInvalidIdentityMerge)
+
+	_, err = b.MergeIdentities(context.Background(), "idt_keep", "idt_keep", "")
+	assert.ErrorIs(t, err, ErrInvalidIdentityMerge)
+	mockDS.AssertNotCalled(t, "MergeIdentities", mock.Anything, mock.Anything)
+}
+
+func TestFindDuplicateIdentities(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	identity := &model.Identity{IdentityID: "idt_1", EmailAddress: "ada@example.com", PhoneNumber: "+2348000000000"}
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(identity, nil)
+	mockDS.On("FindDuplicateIdentities", mock.Anything, identity).Return([]model.Identity{
+		{IdentityID: "idt_2", EmailAddress: "ADA@example.com", PhoneNumber: "+2348000000000"},
+		{IdentityID: "idt_3", PhoneNumber: "+2348000000000"},
+	}, nil)
+
+	duplicates, err := b.FindDuplicateIdentities(context.Background(), "idt_1")
+	require.NoError(t, err)
+	require.Len(t, duplicates, 2)
+	assert.Equal(t, []string{model.DuplicateOnEmailAddress, model.DuplicateOnPhoneNumber}, duplicates[0].MatchedOn)
+	assert.Equal(t, []string{model.DuplicateOnPhoneNumber}, duplicates[1].MatchedOn)
+}
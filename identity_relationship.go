@@ -0,0 +1,144 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// Events sent when identity relationships change.
+const (
+	EventIdentityRelationshipCreated = "identity.relationship.created"
+	EventIdentityRelationshipUpdated = "identity.relationship.updated"
+	EventIdentityRelationshipDeleted = "identity.relationship.deleted"
+)
+
+// ErrInvalidIdentityRelationship is returned when a relationship between identities is rejected.
+var ErrInvalidIdentityRelationship = errors.New("invalid identity relationship")
+
+// CreateIdentityRelationship relates two identities, such as an individual being a member of an organization or
+// the guardian of a minor. Both identities must exist, and members, directors and beneficial owners can only be
+// related to organizations.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - relationship model.IdentityRelationship: The identities, type, role and metadata of the relationship.
+//
+// Returns:
+// - *model.IdentityRelationship: The saved relationship.
+// - error: ErrInvalidIdentityRelationship if the relationship is rejected, or an error if either identity does
+// not exist or the identities are already related by the type.
+func (l *Blnk) CreateIdentityRelationship(ctx context.Context, relationship model.IdentityRelationship) (*model.IdentityRelationship, error) {
+	ctx, span := tracer.Start(ctx, "CreateIdentityRelationship")
+	defer span.End()
+
+	if err := relationship.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIdentityRelationship, err)
+	}
+	if _, err := l.datasource.GetIdentityByID(ctx, relationship.IdentityID); err != nil {
+		return nil, err
+	}
+	related, err := l.datasource.GetIdentityByID(ctx, relationship.RelatedIdentityID)
+	if err != nil {
+		return nil, err
+	}
+	if relationship.RequiresOrganization() && identityKind(related.IdentityType) != model.IdentityTypeOrganization {
+		return nil, fmt.Errorf("%w: a %s must be related to an organization, and identity %s is not one", ErrInvalidIdentityRelationship, relationship.Type, related.IdentityID)
+	}
+
+	relationship.RelationshipID = model.GenerateUUIDWithSuffix("rel")
+	relationship.CreatedAt = time.Now()
+	relationship.UpdatedAt = relationship.CreatedAt
+	relationship.Version = 1
+	if err := l.datasource.CreateIdentityRelationship(ctx, &relationship); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	l.sendIdentityRelationshipWebhook(EventIdentityRelationshipCreated, &relationship)
+	return &relationship, nil
+}
+
+// GetIdentityRelationship retrieves a relationship of an identity, in either direction. Relationships of other
+// identities are not found.
+func (l *Blnk) GetIdentityRelationship(ctx context.Context, identityID, relationshipID string) (*model.IdentityRelationship, error) {
+	relationship, err := l.datasource.GetIdentityRelationship(ctx, relationshipID)
+	if err != nil {
+		return nil, err
+	}
+	if relationship.IdentityID != identityID && relationship.RelatedIdentityID != identityID {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Identity relationship with ID '%s' not found", relationshipID), nil)
+	}
+	return relationship, nil
+}
+
+// UpdateIdentityRelationship replaces the role and metadata of a relationship of an identity.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - identityID string: The ID of either identity of the relationship.
+// - relationshipID string: The ID of the relationship.
+// - role string: The new role of the relationship.
+// - metaData map[string]interface{}: The new metadata of the relationship.
+// - version int: The version of the relationship the update was made against, or 0 to update it as it is.
+//
+// Returns:
+// - *model.IdentityRelationship: The updated relationship.
+// - error: A conflict error if the relationship is no longer at the version, or an error if it is not found or
+// cannot be updated.
+func (l *Blnk) UpdateIdentityRelationship(ctx context.Context, identityID, relationshipID, role string, metaData map[string]interface{}, version int) (*model.IdentityRelationship, error) {
+	relationship, err := l.GetIdentityRelationship(ctx, identityID, relationshipID)
+	if err != nil {
+		return nil, err
+	}
+	if version > 0 && version != relationship.Version {
+		return nil, apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("Identity relationship with ID '%s' is at version %d, not %d", relationshipID, relationship.Version, version), nil)
+	}
+	relationship.Role = role
+	relationship.MetaData = metaData
+	relationship.UpdatedAt = time.Now()
+	if err := l.datasource.UpdateIdentityRelationship(ctx, relationship); err != nil {
+		return nil, err
+	}
+
+	l.sendIdentityRelationshipWebhook(EventIdentityRelationshipUpdated, relationship)
+	return relationship, nil
+}
+
+// DeleteIdentityRelationship removes a relationship of an identity.
+func (l *Blnk) DeleteIdentityRelationship(ctx context.Context, identityID, relationshipID string) error {
+	relationship, err := l.GetIdentityRelationship(ctx, identityID, relationshipID)
+	if err != nil {
+		return err
+	}
+	if err := l.datasource.DeleteIdentityRelationship(ctx, relationshipID); err != nil {
+		return err
+	}
+
+	l.sendIdentityRelationshipWebhook(EventIdentityRelationshipDeleted, relationship)
+	return nil
+}
+
+// GetRelatedIdentities lists the identities related to an identity, in either direction, with the relationship
+// to each: an organization lists its members, and a member lists its organizations.
+func (l *Blnk) GetRelatedIdentities(ctx context.Context, identityID string) ([]*model.RelatedIdentity, error) {
+	if _, err := l.datasource.GetIdentityByID(ctx, identityID); err != nil {
+		return nil, err
+	}
+	return l.datasource.GetRelatedIdentities(ctx, identityID)
+}
+
+// sendIdentityRelationshipWebhook sends a webhook about an identity relationship in the background.
+func (l *Blnk) sendIdentityRelationshipWebhook(event string, relationship *model.IdentityRelationship) {
+	payload := *relationship
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
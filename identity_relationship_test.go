@@ -0,0 +1,81 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateIdentityRelationship(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_ada").Return(&model.Identity{IdentityID: "idt_ada", IdentityType: "individual"}, nil)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_org").Return(&model.Identity{IdentityID: "idt_org", IdentityType: "organization"}, nil)
+	mockDS.On("CreateIdentityRelationship", mock.Anything, mock.AnythingOfType("*model.IdentityRelationship")).Return(nil)
+
+	relationship, err := b.CreateIdentityRelationship(context.Background(), model.IdentityRelationship{
+		IdentityID:        "idt_ada",
+		RelatedIdentityID: "idt_org",
+		Type:              model.RelationshipDirector,
+		Role:              "CEO",
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, relationship.RelationshipID)
+	assert.False(t, relationship.CreatedAt.IsZero())
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
+
+func TestCreateIdentityRelationship_RequiresOrganization(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_ada").Return(&model.Identity{IdentityID: "idt_ada", IdentityType: "individual"}, nil)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_bob").Return(&model.Identity{IdentityID: "idt_bob", IdentityType: "individual"}, nil)
+
+	_, err := b.CreateIdentityRelationship(context.Background(), model.IdentityRelationship{
+		IdentityID: "idt_ada", RelatedIdentityID: "idt_bob", Type: model.RelationshipMember,
+	})
+	assert.True(t, errors.Is(err, ErrInvalidIdentityRelationship))
+
+	_, err = b.CreateIdentityRelationship(context.Background(), model.IdentityRelationship{
+		IdentityID: "idt_ada", RelatedIdentityID: "idt_ada", Type: model.RelationshipGuardian,
+	})
+	assert.True(t, errors.Is(err, ErrInvalidIdentityRelationship))
+	mockDS.AssertNotCalled(t, "CreateIdentityRelationship", mock.Anything, mock.Anything)
+}
+
+func TestGetIdentityRelationship_OtherIdentity(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityRelationship", mock.Anything, "rel_1").
+		Return(&model.IdentityRelationship{RelationshipID: "rel_1", IdentityID: "idt_ada", RelatedIdentityID: "idt_org"}, nil)
+
+	relationship, err := b.GetIdentityRelationship(context.Background(), "idt_org", "rel_1")
+	require.NoError(t, err)
+	assert.Equal(t, "rel_1", relationship.RelationshipID)
+
+	_, err = b.GetIdentityRelationship(context.Background(), "idt_bob", "rel_1")
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+}
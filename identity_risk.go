@@ -0,0 +1,157 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// ErrIdentityRiskScoringDisabled is returned when an identity is scored while no risk rules are configured and no
+// scorer is plugged in.
+var ErrIdentityRiskScoringDisabled = errors.New("identity risk scoring is not enabled")
+
+// IdentityRiskScorer scores how risky an identity is as a customer, from 0 to 100. Scores outside the range are
+// clamped to it. Plug a scorer in with SetIdentityRiskScorer to score identities with a model or an external
+// service instead of the configured risk rules.
+type IdentityRiskScorer interface {
+	ScoreIdentity(ctx context.Context, identity *model.Identity) (float64, error)
+}
+
+// ruleRiskScorer scores identities with the risk rules of the configuration.
+type ruleRiskScorer struct {
+	rules []config.RiskRule
+}
+
+// ScoreIdentity adds up the scores of the rules the identity matches.
+func (s ruleRiskScorer) ScoreIdentity(_ context.Context, identity *model.Identity) (float64, error) {
+	var score float64
+	for _, rule := range s.rules {
+		if riskRuleMatches(rule, identity) {
+			score += rule.Score
+		}
+	}
+	return score, nil
+}
+
+// riskRuleMatches reports whether an identity's value for the rule's field is one of the rule's values, or
+// whether it has no value when the rule has none.
+func riskRuleMatches(rule config.RiskRule, identity *model.Identity) bool {
+	var value string
+	switch rule.Field {
+	case "identity_type":
+		value = identity.IdentityType
+	case "category":
+		value = identity.Category
+	case "nationality":
+		value = identity.Nationality
+	case "country":
+		value = identity.Country
+	case "state":
+		value = identity.State
+	case "city":
+		value = identity.City
+	case "gender":
+		value = identity.Gender
+	case "verification_status":
+		value = identity.VerificationStatus
+	}
+	value = strings.TrimSpace(value)
+	if len(rule.Values) == 0 {
+		return value == ""
+	}
+	for _, candidate := range rule.Values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetIdentityRiskScorer plugs in the scorer identities are scored with as they are created and updated, in place
+// of the configured risk rules.
+func (l *Blnk) SetIdentityRiskScorer(scorer IdentityRiskScorer) {
+	l.riskScorer = scorer
+}
+
+// identityRiskScorer returns the scorer plugged in, or a scorer of the configured risk rules, or nil when
+// identities are not scored.
+func (l *Blnk) identityRiskScorer() IdentityRiskScorer {
+	if l.riskScorer != nil {
+		return l.riskScorer
+	}
+	cnf, err := config.Fetch()
+	if err != nil || len(cnf.RiskScoring.Rules) == 0 {
+		return nil
+	}
+	return ruleRiskScorer{rules: cnf.RiskScoring.Rules}
+}
+
+// ScoreIdentityRisk scores an identity now and records its risk score and level, such as after the risk rules
+// change or for identities imported before scoring was enabled.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - id string: The ID of the identity to score.
+//
+// Returns:
+// - *model.Identity: The identity with its new risk score and level.
+// - error: ErrIdentityRiskScoringDisabled if identities are not scored, or an error if the identity does not
+// exist or cannot be scored.
+func (l *Blnk) ScoreIdentityRisk(ctx context.Context, id string) (*model.Identity, error) {
+	ctx, span := tracer.Start(ctx, "ScoreIdentityRisk")
+	defer span.End()
+
+	scorer := l.identityRiskScorer()
+	if scorer == nil {
+		return nil, ErrIdentityRiskScoringDisabled
+	}
+	identity, err := l.datasource.GetIdentityByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.scoreIdentity(ctx, scorer, identity); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return identity, nil
+}
+
+// rescoreIdentity scores an identity after it was created or updated. Identities are not scored when no scorer is
+// configured, and a failure to score one is reported rather than failing the change that triggered it.
+func (l *Blnk) rescoreIdentity(ctx context.Context, identity *model.Identity) {
+	scorer := l.identityRiskScorer()
+	if scorer == nil {
+		return
+	}
+	if err := l.scoreIdentity(ctx, scorer, identity); err != nil {
+		notification.NotifyError(err)
+	}
+}
+
+// scoreIdentity scores an identity with a scorer, sets its risk score, level and scoring time, and records them.
+func (l *Blnk) scoreIdentity(ctx context.Context, scorer IdentityRiskScorer, identity *model.Identity) error {
+	score, err := scorer.ScoreIdentity(ctx, identity)
+	if err != nil {
+		return err
+	}
+	score = math.Max(0, math.Min(100, score))
+
+	// Thresholds are only unset when the configuration was not loaded through its defaults
+	medium, high := 40.0, 70.0
+	if cnf, err := config.Fetch(); err == nil && cnf.RiskScoring.HighThreshold > 0 {
+		medium, high = cnf.RiskScoring.MediumThreshold, cnf.RiskScoring.HighThreshold
+	}
+	level := model.RiskLevelFor(score, medium, high)
+	now := time.Now()
+	if err := l.datasource.UpdateIdentityRisk(ctx, identity.IdentityID, score, level, now); err != nil {
+		return err
+	}
+	identity.RiskScore, identity.RiskLevel, identity.RiskScoredAt = &score, level, &now
+	return nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedRiskScorer struct {
+	score float64
+	err   error
+}
+
+func (s fixedRiskScorer) ScoreIdentity(context.Context, *model.Identity) (float64, error) {
+	return s.score, s.err
+}
+
+func TestRuleRiskScorer(t *testing.T) {
+	scorer := ruleRiskScorer{rules: []config.RiskRule{
+		{Field: "nationality", Values: []string{"XX", "YY"}, Score: 50},
+		{Field: "category", Values: []string{"pep"}, Score: 30},
+		{Field: "country", Score: 10}, // Identities without a country
+	}}
+
+	score, err := scorer.ScoreIdentity(context.Background(), &model.Identity{Nationality: "xx", Category: "PEP"})
+	require.NoError(t, err)
+	assert.Equal(t, 90.0, score)
+
+	score, err = scorer.ScoreIdentity(context.Background(), &model.Identity{Nationality: "NG", Country: "NG"})
+	require.NoError(t, err)
+	assert.Zero(t, score)
+}
+
+func TestCreateIdentity_ScoresRisk(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.RiskScoring = config.RiskScoringConfig{
+		MediumThreshold: 40,
+		HighThreshold:   70,
+		Rules:           []config.RiskRule{{Field: "nationality", Values: []string{"XX"}, Score: 45}},
+	}
+	t.Cleanup(func() { cnf.RiskScoring = config.RiskScoringConfig{} })
+
+	mockDS.On("CreateIdentity", mock.Anything, mock.Anything).Return(model.Identity{IdentityID: "idt_1", Nationality: "XX"}, nil)
+	mockDS.On("UpdateIdentityRisk", mock.Anything, "idt_1", 45.0, model.RiskLevelMedium, mock.AnythingOfType("time.Time")).Return(nil)
+
+	identity, err := b.CreateIdentity(context.Background(), model.Identity{Nationality: "XX"})
+	require.NoError(t, err)
+	require.NotNil(t, identity.RiskScore)
+	assert.Equal(t, 45.0, *identity.RiskScore)
+	assert.Equal(t, model.RiskLevelMedium, identity.RiskLevel)
+	assert.NotNil(t, identity.RiskScoredAt)
+	mockDS.AssertExpectations(t)
+}
+
+func TestCreateIdentity_NotScoredWithoutScorer(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("CreateIdentity", mock.Anything, mock.Anything).Return(model.Identity{IdentityID: "idt_1"}, nil)
+
+	identity, err := b.CreateIdentity(context.Background(), model.Identity{})
+	require.NoError(t, err)
+	assert.Nil(t, identity.RiskScore)
+	mockDS.AssertNotCalled(t, "UpdateIdentityRisk", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateIdentity_RescoresWithPluggedScorer(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	b.SetIdentityRiskScorer(fixedRiskScorer{score: 140})
+
+	updated := &model.Identity{IdentityID: "idt_1", FirstName: "Ada", Country: "XX"}
+	mockDS.On("UpdateIdentity", mock.Anything, mock.Anything, model.ActorSystem).Return(nil)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(updated, nil)
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_1").Return(updated, nil).Maybe()
+	mockDS.On("UpdateIdentityRisk", mock.Anything, "idt_1", 100.0, model.RiskLevelHigh, mock.AnythingOfType("time.Time")).Return(nil)
+
+	require.NoError(t, b.UpdateIdentity(context.Background(), &model.Identity{IdentityID: "idt_1", Country: "XX"}))
+	assert.Equal(t, 100.0, *updated.RiskScore, "scores are clamped to 100")
+	mockDS.AssertExpectations(t)
+}
+
+func TestUpdateIdentity_ScoringFailureDoesNotFailUpdate(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	b.SetIdentityRiskScorer(fixedRiskScorer{err: errors.New("scoring service unavailable")})
+
+	mockDS.On("UpdateIdentity", mock.Anything, mock.Anything, model.ActorSystem).Return(nil)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil)
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil).Maybe()
+
+	assert.NoError(t, b.UpdateIdentity(context.Background(), &model.Identity{IdentityID: "idt_1", City: "Lagos"}))
+	mockDS.AssertNotCalled(t, "UpdateIdentityRisk", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestScoreIdentityRisk(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	_, err := b.ScoreIdentityRisk(context.Background(), "idt_1")
+	assert.True(t, errors.Is(err, ErrIdentityRiskScoringDisabled))
+
+	b.SetIdentityRiskScorer(fixedRiskScorer{score: 12})
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil)
+	mockDS.On("UpdateIdentityRisk", mock.Anything, "idt_1", 12.0, model.RiskLevelLow, mock.AnythingOfType("time.Time")).Return(nil)
+
+	identity, err := b.ScoreIdentityRisk(context.Background(), "idt_1")
+	require.NoError(t, err)
+	assert.Equal(t, 12.0, *identity.RiskScore)
+	assert.Equal(t, model.RiskLevelLow, identity.RiskLevel)
+	mockDS.AssertExpectations(t)
+}
@@ -0,0 +1,90 @@
+package blnk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// parsedIdentitySchemas caches parsed identity schemas by their text, since the configuration holds them as JSON
+// and they are checked for every identity created or updated.
+var parsedIdentitySchemas sync.Map
+
+// identitySchema returns the schema of the custom fields of an identity type, or nil when the type has none.
+func identitySchema(identityType string) (*model.JSONSchema, error) {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, nil
+	}
+	raw, ok := cnf.IdentitySchemas[identityType]
+	if !ok {
+		return nil, nil
+	}
+	if cached, ok := parsedIdentitySchemas.Load(string(raw)); ok {
+		return cached.(*model.JSONSchema), nil
+	}
+	schema, err := model.ParseJSONSchema(raw)
+	if err != nil {
+		return nil, fmt.Errorf("identity schema of %s: %w", identityType, err)
+	}
+	parsedIdentitySchemas.Store(string(raw), schema)
+	return schema, nil
+}
+
+// validateIdentityFields checks the meta_data of an identity against the schema of its identity type. Identities
+// without meta_data are checked as having none, so the fields the schema requires are reported.
+//
+// Parameters:
+// - identityType string: The identity type of the identity.
+// - metaData map[string]interface{}: The meta_data of the identity.
+//
+// Returns:
+// - error: A *model.IdentitySchemaError listing the fields that do not match the schema, or an error if the schema
+// is invalid, or nil.
+func validateIdentityFields(identityType string, metaData map[string]interface{}) error {
+	schema, err := identitySchema(identityType)
+	if err != nil || schema == nil {
+		return err
+	}
+	if metaData == nil {
+		metaData = map[string]interface{}{}
+	}
+	if errs := schema.Validate("meta_data", metaData); len(errs) > 0 {
+		return &model.IdentitySchemaError{IdentityType: identityType, Errors: errs}
+	}
+	return nil
+}
+
+// validateIdentityUpdateFields checks an update of an identity against the schema of its identity type. Updates
+// only carry the changed fields, so the identity is checked as the update would leave it: the update's meta_data
+// replaces the stored one, or is merged into it when the update merges metadata, and a change of identity type
+// checks the stored meta_data against the new type.
+func (l *Blnk) validateIdentityUpdateFields(ctx context.Context, identity *model.Identity) error {
+	if identity.MetaData == nil && identity.IdentityType == "" {
+		return nil
+	}
+	cnf, err := config.Fetch()
+	if err != nil || len(cnf.IdentitySchemas) == 0 {
+		return nil
+	}
+
+	identityType, metaData := identity.IdentityType, identity.MetaData
+	if identityType == "" || metaData == nil || identity.MergeMetaData {
+		current, err := l.datasource.GetIdentityByID(ctx, identity.IdentityID)
+		if err != nil {
+			return err
+		}
+		if identityType == "" {
+			identityType = current.IdentityType
+		}
+		if metaData == nil {
+			metaData = current.MetaData
+		} else if identity.MergeMetaData {
+			metaData = model.MergeMetaDataPatch(current.MetaData, metaData)
+		}
+	}
+	return validateIdentityFields(identityType, metaData)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setIdentitySchemas(t *testing.T, schemas map[string]string) {
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.IdentitySchemas = map[string]json.RawMessage{}
+	for identityType, schema := range schemas {
+		cnf.IdentitySchemas[identityType] = json.RawMessage(schema)
+	}
+	t.Cleanup(func() { cnf.IdentitySchemas = nil })
+}
+
+const organizationSchema = `{"type": "object", "required": ["tax_id"], "properties": {"tax_id": {"type": "string", "pattern": "^TIN-[0-9]+$"}}}`
+
+func TestCreateIdentity_ValidatesSchema(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	setIdentitySchemas(t, map[string]string{"organization": organizationSchema})
+
+	_, err := b.CreateIdentity(context.Background(), model.Identity{IdentityType: "organization", MetaData: map[string]interface{}{"tax_id": "123"}})
+	var schemaErr *model.IdentitySchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	assert.Equal(t, []model.FieldError{{Field: "meta_data.tax_id", Message: "must match ^TIN-[0-9]+$"}}, schemaErr.Errors)
+
+	_, err = b.CreateIdentity(context.Background(), model.Identity{IdentityType: "organization"})
+	require.ErrorAs(t, err, &schemaErr)
+	assert.Equal(t, "meta_data.tax_id", schemaErr.Errors[0].Field)
+	mockDS.AssertNotCalled(t, "CreateIdentity", mock.Anything, mock.Anything)
+
+	// Types without a schema keep free-form meta_data
+	mockDS.On("CreateIdentity", mock.Anything, mock.Anything).Return(model.Identity{IdentityID: "idt_1", IdentityType: "individual"}, nil)
+	_, err = b.CreateIdentity(context.Background(), model.Identity{IdentityType: "individual", MetaData: map[string]interface{}{"anything": 1}})
+	require.NoError(t, err)
+}
+
+func TestUpdateIdentity_ValidatesSchemaOfUpdatedIdentity(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	setIdentitySchemas(t, map[string]string{"organization": organizationSchema})
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1", IdentityType: "individual", MetaData: map[string]interface{}{"nickname": "ada"}}, nil)
+
+	// Becoming an organization checks the stored meta_data against the organization schema
+	err := b.UpdateIdentity(context.Background(), &model.Identity{IdentityID: "idt_1", IdentityType: "organization"})
+	var schemaErr *model.IdentitySchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	assert.Equal(t, "organization", schemaErr.IdentityType)
+	mockDS.AssertNotCalled(t, "UpdateIdentity", mock.Anything, mock.Anything, mock.Anything)
+
+	// Changing only meta_data checks it against the stored identity type
+	mockDS.On("UpdateIdentity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil).Maybe()
+	err = b.UpdateIdentity(context.Background(), &model.Identity{IdentityID: "idt_1", MetaData: map[string]interface{}{"nickname": "ade"}})
+	require.NoError(t, err)
+}
+
+func TestUpdateIdentity_ValidatesMergedMetaData(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	setIdentitySchemas(t, map[string]string{"organization": organizationSchema})
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1", IdentityType: "organization", MetaData: map[string]interface{}{"tax_id": "TIN-1"}}, nil)
+
+	// Removing a required key is rejected, though the patch alone names no required key
+	err := b.UpdateIdentity(context.Background(), &model.Identity{IdentityID: "idt_1", MetaData: map[string]interface{}{"tax_id": nil}, MergeMetaData: true})
+	var schemaErr *model.IdentitySchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	assert.Equal(t, "meta_data.tax_id", schemaErr.Errors[0].Field)
+	mockDS.AssertNotCalled(t, "UpdateIdentity", mock.Anything, mock.Anything, mock.Anything)
+
+	// Setting another key keeps the stored required key
+	mockDS.On("UpdateIdentity", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil).Maybe()
+	err = b.UpdateIdentity(context.Background(), &model.Identity{IdentityID: "idt_1", MetaData: map[string]interface{}{"sector": "retail"}, MergeMetaData: true})
+	require.NoError(t, err)
+}
+
+func TestValidateIdentityFields_InvalidSchema(t *testing.T) {
+	newBalanceNotificationTestBlnk(t)
+	setIdentitySchemas(t, map[string]string{"organization": `{"type": "record"}`})
+
+	err := validateIdentityFields("organization", nil)
+	assert.ErrorContains(t, err, "identity schema of organization")
+}
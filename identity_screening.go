@@ -0,0 +1,205 @@
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/internal/resilience"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// EventIdentityScreeningFlagged is sent when a screening matches an identity to a sanctions or politically exposed
+// person list entry, so compliance can review the match.
+const EventIdentityScreeningFlagged = "identity.screening.flagged"
+
+var (
+	// ErrIdentityScreeningDisabled is returned when an identity is screened while no screening provider is
+	// configured and no screener is plugged in.
+	ErrIdentityScreeningDisabled = errors.New("identity screening is not enabled")
+
+	// ErrIdentityFlagged is returned when a balance is created for an identity whose latest screening flagged it
+	// while balances of flagged identities are blocked.
+	ErrIdentityFlagged = errors.New("identity is flagged by sanctions screening")
+)
+
+// IdentityScreener screens identities against sanctions and politically exposed person lists, returning the
+// status of the screening and the list entries the identity matched. Plug a screener in with SetIdentityScreener
+// to screen identities with a provider's SDK instead of the configured provider URL.
+type IdentityScreener interface {
+	ScreenIdentity(ctx context.Context, identity *model.Identity) (*model.IdentityScreening, error)
+}
+
+// httpScreener screens identities with the provider configured by URL.
+type httpScreener struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// ScreenIdentity posts the identity to the provider and reads the status and matches it answers with.
+func (s httpScreener) ScreenIdentity(ctx context.Context, identity *model.Identity) (*model.IdentityScreening, error) {
+	body, err := json.Marshal(identity)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := resilience.Get(resilience.Screening).Wrap(s.client).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("screening request failed with status %d", resp.StatusCode)
+	}
+
+	var screening model.IdentityScreening
+	if err := json.NewDecoder(resp.Body).Decode(&screening); err != nil {
+		return nil, fmt.Errorf("invalid screening response: %w", err)
+	}
+	screening.Provider = "http"
+	return &screening, nil
+}
+
+// SetIdentityScreener plugs in the screener identities are screened with as they are created and updated, in
+// place of the configured provider URL.
+func (l *Blnk) SetIdentityScreener(screener IdentityScreener) {
+	l.screener = screener
+}
+
+// identityScreener returns the screener plugged in, or a screener of the configured provider, or nil when
+// identities are not screened.
+func (l *Blnk) identityScreener() IdentityScreener {
+	if l.screener != nil {
+		return l.screener
+	}
+	cnf, err := config.Fetch()
+	if err != nil || cnf.Screening.URL == "" {
+		return nil
+	}
+	return httpScreener{url: cnf.Screening.URL, headers: cnf.Screening.Headers, client: l.httpClient}
+}
+
+// ScreenIdentity screens an identity now and records the result, such as after the lists were updated or for
+// identities created before screening was enabled.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - id string: The ID of the identity to screen.
+//
+// Returns:
+// - *model.IdentityScreening: The recorded screening.
+// - error: ErrIdentityScreeningDisabled if identities are not screened, or an error if the identity does not exist
+// or cannot be screened.
+func (l *Blnk) ScreenIdentity(ctx context.Context, id string) (*model.IdentityScreening, error) {
+	ctx, span := tracer.Start(ctx, "ScreenIdentity")
+	defer span.End()
+
+	screener := l.identityScreener()
+	if screener == nil {
+		return nil, ErrIdentityScreeningDisabled
+	}
+	identity, err := l.datasource.GetIdentityByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	screening, err := l.screenIdentity(ctx, screener, identity)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return screening, nil
+}
+
+// GetIdentityScreenings retrieves the screenings of an identity, newest first.
+func (l *Blnk) GetIdentityScreenings(ctx context.Context, id string) ([]*model.IdentityScreening, error) {
+	if _, err := l.datasource.GetIdentityByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return l.datasource.GetIdentityScreenings(ctx, id)
+}
+
+// rescreenIdentity screens an identity after it was created or updated. Identities are not screened when no
+// screener is configured, and a failure to screen one is reported rather than failing the change that triggered
+// it; the identity keeps the standing of its previous screening.
+func (l *Blnk) rescreenIdentity(ctx context.Context, identity *model.Identity) {
+	screener := l.identityScreener()
+	if screener == nil {
+		return
+	}
+	if _, err := l.screenIdentity(ctx, screener, identity); err != nil {
+		notification.NotifyError(err)
+	}
+}
+
+// screenIdentity screens an identity with a screener and records the result, sending an
+// identity.screening.flagged webhook when the identity matched a list entry.
+func (l *Blnk) screenIdentity(ctx context.Context, screener IdentityScreener, identity *model.Identity) (*model.IdentityScreening, error) {
+	screening, err := screener.ScreenIdentity(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	if screening.Status != model.ScreeningClear && screening.Status != model.ScreeningFlagged {
+		return nil, fmt.Errorf("unknown screening status '%s'", screening.Status)
+	}
+	if screening.Provider == "" {
+		screening.Provider = "custom"
+	}
+	screening.ScreeningID = model.GenerateUUIDWithSuffix("scr")
+	screening.IdentityID = identity.IdentityID
+	screening.ScreenedAt = time.Now()
+	if err := l.datasource.CreateIdentityScreening(ctx, screening); err != nil {
+		return nil, err
+	}
+
+	if screening.Flagged() {
+		go func() {
+			if err := l.SendWebhook(NewWebhook{Event: EventIdentityScreeningFlagged, Payload: screening}); err != nil {
+				notification.NotifyError(err)
+			}
+		}()
+	}
+	return screening, nil
+}
+
+// checkIdentityScreening rejects a balance for an identity whose latest screening flagged it, when balances of
+// flagged identities are blocked. Identities never screened are not blocked.
+func (l *Blnk) checkIdentityScreening(ctx context.Context, identityID string) error {
+	if identityID == "" {
+		return nil
+	}
+	cnf, err := config.Fetch()
+	if err != nil || !cnf.Screening.BlockFlaggedBalances {
+		return nil
+	}
+
+	screening, err := l.datasource.GetLatestIdentityScreening(ctx, identityID)
+	var apiErr apierror.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == apierror.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if screening.Flagged() {
+		return fmt.Errorf("%w: identity %s", ErrIdentityFlagged, identityID)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedScreener struct {
+	status string
+}
+
+func (s fixedScreener) ScreenIdentity(context.Context, *model.Identity) (*model.IdentityScreening, error) {
+	return &model.IdentityScreening{Status: s.status}, nil
+}
+
+func TestCreateIdentity_ScreensWithConfiguredProvider(t *testing.T) {
+	var screened model.Identity
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key_1", r.Header.Get("X-Api-Key"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&screened))
+		_, _ = w.Write([]byte(`{"status":"flagged","matches":[{"list":"sanctions","entry_id":"SDN-1","name":"Jane Roe","score":0.93}]}`))
+	}))
+	defer server.Close()
+
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	b.httpClient = server.Client()
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.Screening = config.ScreeningConfig{URL: server.URL, Headers: map[string]string{"X-Api-Key": "key_1"}}
+	t.Cleanup(func() { cnf.Screening = config.ScreeningConfig{} })
+
+	mockDS.On("CreateIdentity", mock.Anything, mock.Anything).Return(model.Identity{IdentityID: "idt_1", FirstName: "Jane", LastName: "Roe"}, nil)
+	mockDS.On("CreateIdentityScreening", mock.Anything, mock.MatchedBy(func(screening *model.IdentityScreening) bool {
+		return screening.IdentityID == "idt_1" && screening.Provider == "http" && screening.Flagged() &&
+			len(screening.Matches) == 1 && screening.Matches[0].EntryID == "SDN-1" && !screening.ScreenedAt.IsZero()
+	})).Return(nil)
+
+	_, err = b.CreateIdentity(context.Background(), model.Identity{FirstName: "Jane", LastName: "Roe"})
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", screened.FirstName)
+	mockDS.AssertExpectations(t)
+}
+
+func TestScreenIdentity(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	_, err := b.ScreenIdentity(context.Background(), "idt_1")
+	assert.ErrorIs(t, err, ErrIdentityScreeningDisabled)
+
+	b.SetIdentityScreener(fixedScreener{status: model.ScreeningClear})
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil)
+	mockDS.On("CreateIdentityScreening", mock.Anything, mock.Anything).Return(nil)
+
+	screening, err := b.ScreenIdentity(context.Background(), "idt_1")
+	require.NoError(t, err)
+	assert.Equal(t, model.ScreeningClear, screening.Status)
+	assert.Equal(t, "custom", screening.Provider)
+	assert.NotEmpty(t, screening.ScreeningID)
+
+	b.SetIdentityScreener(fixedScreener{status: "maybe"})
+	_, err = b.ScreenIdentity(context.Background(), "idt_1")
+	assert.Error(t, err, "results with an unknown status are not recorded")
+	mockDS.AssertNumberOfCalls(t, "CreateIdentityScreening", 1)
+}
+
+func TestCreateBalance_BlockedForFlaggedIdentity(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.Screening = config.ScreeningConfig{BlockFlaggedBalances: true}
+	t.Cleanup(func() { cnf.Screening = config.ScreeningConfig{} })
+
+	mockDS.On("GetLatestIdentityScreening", mock.Anything, "idt_flagged").Return(&model.IdentityScreening{Status: model.ScreeningFlagged}, nil)
+	mockDS.On("GetLatestIdentityScreening", mock.Anything, "idt_clear").Return(&model.IdentityScreening{Status: model.ScreeningClear}, nil)
+	mockDS.On("GetLatestIdentityScreening", mock.Anything, "idt_new").Return(nil, apierror.NewAPIError(apierror.ErrNotFound, "Identity 'idt_new' has not been screened", nil))
+
+	_, err = b.CreateBalance(context.Background(), model.Balance{LedgerID: "ldg_1", Currency: "USD", IdentityID: "idt_flagged"})
+	assert.ErrorIs(t, err, ErrIdentityFlagged)
+	mockDS.AssertNotCalled(t, "CreateBalance", mock.Anything, mock.Anything)
+
+	assert.NoError(t, b.checkIdentityScreening(context.Background(), "idt_clear"))
+	assert.NoError(t, b.checkIdentityScreening(context.Background(), "idt_new"), "identities never screened are not blocked")
+	assert.NoError(t, b.checkIdentityScreening(context.Background(), ""))
+
+	cnf.Screening.BlockFlaggedBalances = false
+	assert.NoError(t, b.checkIdentityScreening(context.Background(), "idt_flagged"))
+}
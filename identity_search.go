@@ -0,0 +1,72 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/typesense/typesense-go/typesense/api"
+)
+
+const (
+	// identitySearchFields are the fields identities are searched by, and identitySearchTypos the typos tolerated
+	// in each of them: names are tolerant, email addresses less so and phone numbers not at all, since a digit
+	// off is another customer.
+	identitySearchFields = "first_name,last_name,other_names,organization_name,email_address,phone_digits"
+	identitySearchTypos  = "2,2,2,2,1,0"
+
+	// maxIdentitySearchPerPage is the most identities a page of search results holds.
+	maxIdentitySearchPerPage = 100
+)
+
+// phoneNumberQuery matches queries that are phone numbers, written with spaces, dashes, dots or brackets.
+var phoneNumberQuery = regexp.MustCompile(`^\+?[\d\s\-.()]{4,}$`)
+
+// SearchIdentities finds identities by partial name, email address or phone number, tolerating typos, so support
+// can find a customer from what they are told over the phone. Every word of the query matches the start of a
+// word in one of the fields, and phone numbers match however their digits are grouped. Deleted identities are
+// not found, and neither are identities by fields that are tokenized.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - query string: What to search for.
+// - page int: The page of results, from 1.
+// - perPage int: The number of identities per page, at most 100.
+//
+// Returns:
+// - *api.SearchResult: The identities found, best matches first.
+// - error: An error if the query is empty or the search fails.
+func (l *Blnk) SearchIdentities(ctx context.Context, query string, page, perPage int) (*api.SearchResult, error) {
+	ctx, span := tracer.Start(ctx, "SearchIdentities")
+	defer span.End()
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("query is required")
+	}
+	if phoneNumberQuery.MatchString(query) {
+		query = phoneDigits(query)
+	}
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > maxIdentitySearchPerPage {
+		perPage = maxIdentitySearchPerPage
+	}
+
+	typos, prefix := identitySearchTypos, "true"
+	result, err := l.search.Search(ctx, "identities", &api.SearchCollectionParams{
+		Q:        query,
+		QueryBy:  identitySearchFields,
+		NumTypos: &typos,
+		Prefix:   &prefix,
+		Page:     &page,
+		PerPage:  &perPage,
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return result, nil
+}
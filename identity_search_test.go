@@ -0,0 +1,106 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchIdentities(t *testing.T) {
+	var query map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/collections/identities/documents/search", r.URL.Path)
+		query = map[string]string{}
+		for key := range r.URL.Query() {
+			query[key] = r.URL.Query().Get(key)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"found": 1, "hits": [{"document": {"identity_id": "idt_ada"}}]}`))
+	}))
+	defer server.Close()
+	b := &Blnk{search: NewTypesenseClient("key", []string{server.URL})}
+
+	result, err := b.SearchIdentities(context.Background(), " +44 (7700) 900-123 ", 0, 500)
+	require.NoError(t, err)
+	assert.Equal(t, 1, *result.Found)
+	assert.Equal(t, "+447700900123", query["q"])
+	assert.Equal(t, identitySearchFields, query["query_by"])
+	assert.Equal(t, identitySearchTypos, query["num_typos"])
+	assert.Equal(t, "true", query["prefix"])
+	assert.Equal(t, "1", query["page"])
+	assert.Equal(t, "100", query["per_page"])
+
+	_, err = b.SearchIdentities(context.Background(), "ada lovelace", 2, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "ada lovelace", query["q"])
+	assert.Equal(t, "2", query["page"])
+
+	_, err = b.SearchIdentities(context.Background(), "  ", 1, 10)
+	assert.Error(t, err)
+}
+
+func TestLiftPhoneDigits(t *testing.T) {
+	client := &TypesenseClient{}
+	data := map[string]interface{}{"phone_number": "+44 7700-900 123"}
+	client.liftPhoneDigits("identities", data)
+	assert.Equal(t, "+447700900123", data["phone_digits"])
+
+	data = map[string]interface{}{"phone_number": "+44 7700-900 123"}
+	client.liftPhoneDigits("balances", data)
+	assert.NotContains(t, data, "phone_digits")
+}
+
+func TestDeleteIdentity_RemovesFromIndex(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	config.ConfigStore.Store(&config.Configuration{
+		Redis:     config.RedisConfig{Dns: mr.Addr()},
+		TypeSense: config.TypeSenseConfig{Dns: "http://typesense:8108"},
+		Queue:     config.QueueConfig{WebhookQueue: "webhook_queue", IndexQueue: "index_queue", NumberOfQueues: 1},
+	})
+	mockDS := new(mocks.MockDataSource)
+	b, err := NewBlnk(mockDS)
+	require.NoError(t, err)
+
+	deletedAt := time.Now()
+	mockDS.On("DeleteIdentity", mock.Anything, "idt_ada").Return(nil)
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_ada").Return(&model.Identity{IdentityID: "idt_ada", DeletedAt: &deletedAt}, nil)
+	require.NoError(t, b.DeleteIdentity(context.Background(), "idt_ada"))
+
+	var tasks []string
+	require.Eventually(t, func() bool {
+		tasks, _ = mr.List("asynq:{index_queue}:pending")
+		return len(tasks) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The task message embeds its JSON payload
+	msg := mr.HGet("asynq:{index_queue}:t:"+tasks[0], "msg")
+	assert.Contains(t, msg, `"delete_id":"idt_ada"`)
+}
@@ -0,0 +1,155 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+const inactiveIdentityCacheTTL = 30 * time.Second
+
+var (
+	// ErrInvalidIdentityStatusTransition is returned when an identity cannot move to the requested lifecycle status
+	// from its current one.
+	ErrInvalidIdentityStatusTransition = errors.New("invalid identity status transition")
+
+	// ErrIdentityInactive is returned when a transaction is made to or from a balance of a blocked or closed
+	// identity.
+	ErrIdentityInactive = errors.New("identity is not active")
+)
+
+// inactiveIdentityCache keeps the IDs of blocked and closed identities in memory so that postings do not query
+// the status of their balances' identities for every transaction. Identities blocked or activated on another
+// instance are picked up within inactiveIdentityCacheTTL.
+type inactiveIdentityCache struct {
+	mu       sync.Mutex
+	ids      map[string]bool
+	loadedAt time.Time
+}
+
+// ChangeIdentityStatus moves an identity to a new lifecycle status and sends an identity.status.<status> webhook.
+// Transactions on the balances of the identity are rejected while it is blocked or closed, and allowed again once
+// a blocked identity is activated.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - id string: The ID of the identity.
+// - status string: The status to move the identity to.
+// - reason string: Why the status changed, such as why the identity was blocked. Optional.
+//
+// Returns:
+// - *model.Identity: The identity with its new status.
+// - error: ErrInvalidIdentityStatusTransition if the identity cannot move to the status from its current one, or
+// an error if the identity is not found or its status changed concurrently.
+func (l *Blnk) ChangeIdentityStatus(ctx context.Context, id, status, reason string) (*model.Identity, error) {
+	ctx, span := tracer.Start(ctx, "ChangeIdentityStatus")
+	defer span.End()
+
+	identity, err := l.datasource.GetIdentityByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := identity.Status
+	if previous == "" {
+		previous = model.IdentityStatusActive
+	}
+	if !model.CanChangeIdentityStatus(previous, status) {
+		return nil, fmt.Errorf("%w: identity %s is %s and cannot become %s", ErrInvalidIdentityStatusTransition, id, previous, status)
+	}
+
+	now := time.Now()
+	if err := l.datasource.UpdateIdentityStatus(ctx, id, previous, status, reason, now); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	l.invalidateInactiveIdentities()
+
+	identity.Status = status
+	identity.StatusReason = reason
+	identity.StatusChangedAt = &now
+
+	change := model.IdentityStatusChange{
+		IdentityID:     id,
+		PreviousStatus: previous,
+		Status:         status,
+		Reason:         reason,
+		ChangedAt:      now,
+	}
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: "identity.status." + status, Payload: change}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+
+	return identity, nil
+}
+
+// inactiveIdentities returns the cached IDs of blocked and closed identities, reloading them when the cache is
+// stale. A failed reload keeps the previous IDs.
+func (l *Blnk) inactiveIdentities(ctx context.Context) map[string]bool {
+	if l.inactive == nil {
+		return nil
+	}
+	l.inactive.mu.Lock()
+	defer l.inactive.mu.Unlock()
+
+	if time.Since(l.inactive.loadedAt) < inactiveIdentityCacheTTL {
+		return l.inactive.ids
+	}
+	l.inactive.loadedAt = time.Now()
+
+	ids, err := l.datasource.ListInactiveIdentityIDs(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to load inactive identities")
+		return l.inactive.ids
+	}
+	inactive := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		inactive[id] = true
+	}
+	l.inactive.ids = inactive
+	return inactive
+}
+
+// invalidateInactiveIdentities forces the next posting to reload the inactive identities.
+func (l *Blnk) invalidateInactiveIdentities() {
+	if l.inactive == nil {
+		return
+	}
+	l.inactive.mu.Lock()
+	l.inactive.loadedAt = time.Time{}
+	l.inactive.mu.Unlock()
+}
+
+// checkIdentityStatus rejects a transaction to or from a balance whose identity is blocked or closed.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - balances ...*model.Balance: The source and destination balances of the transaction.
+//
+// Returns:
+// - error: ErrIdentityInactive if the identity of a balance is not active.
+func (l *Blnk) checkIdentityStatus(ctx context.Context, balances ...*model.Balance) error {
+	var inactive map[string]bool
+	for _, balance := range balances {
+		if balance == nil || balance.IdentityID == "" {
+			continue
+		}
+		if inactive == nil {
+			if inactive = l.inactiveIdentities(ctx); len(inactive) == 0 {
+				return nil
+			}
+		}
+		if inactive[balance.IdentityID] {
+			return fmt.Errorf("%w: identity %s of balance %s is blocked or closed", ErrIdentityInactive, balance.IdentityID, balance.BalanceID)
+		}
+	}
+	return nil
+}
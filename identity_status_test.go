@@ -0,0 +1,75 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeIdentityStatus(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil)
+	mockDS.On("UpdateIdentityStatus", mock.Anything, "idt_1", model.IdentityStatusActive, model.IdentityStatusBlocked, "fraud investigation", mock.AnythingOfType("time.Time")).Return(nil)
+
+	identity, err := b.ChangeIdentityStatus(context.Background(), "idt_1", model.IdentityStatusBlocked, "fraud investigation")
+	require.NoError(t, err)
+	assert.Equal(t, model.IdentityStatusBlocked, identity.Status)
+	assert.Equal(t, "fraud investigation", identity.StatusReason)
+	assert.NotNil(t, identity.StatusChangedAt)
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
+
+func TestChangeIdentityStatus_InvalidTransition(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1", Status: model.IdentityStatusClosed}, nil)
+
+	_, err := b.ChangeIdentityStatus(context.Background(), "idt_1", model.IdentityStatusActive, "")
+	assert.True(t, errors.Is(err, ErrInvalidIdentityStatusTransition))
+	mockDS.AssertNotCalled(t, "UpdateIdentityStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCheckIdentityStatus(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+	customer := &model.Balance{BalanceID: "bln_customer", IdentityID: "idt_1"}
+	internal := &model.Balance{BalanceID: "bln_internal"}
+
+	mockDS.On("ListInactiveIdentityIDs", mock.Anything).Return([]string{"idt_1"}, nil).Once()
+	err := b.checkIdentityStatus(ctx, internal, customer)
+	assert.ErrorIs(t, err, ErrIdentityInactive)
+	assert.ErrorIs(t, b.checkIdentityStatus(ctx, customer, internal), ErrIdentityInactive, "blocked identities are cached")
+	assert.NoError(t, b.checkIdentityStatus(ctx, internal, internal), "balances without identities are not checked")
+
+	// Activating the identity reloads the inactive identities on the next posting
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1", Status: model.IdentityStatusBlocked}, nil)
+	mockDS.On("UpdateIdentityStatus", mock.Anything, "idt_1", model.IdentityStatusBlocked, model.IdentityStatusActive, "", mock.AnythingOfType("time.Time")).Return(nil)
+	_, err = b.ChangeIdentityStatus(ctx, "idt_1", model.IdentityStatusActive, "")
+	require.NoError(t, err)
+
+	mockDS.On("ListInactiveIdentityIDs", mock.Anything).Return([]string{}, nil).Once()
+	assert.NoError(t, b.checkIdentityStatus(ctx, internal, customer))
+	mockDS.AssertExpectations(t)
+}
@@ -0,0 +1,62 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/model"
+)
+
+// ErrInvalidIdentityTags is returned when tags added to or removed from an identity are missing or malformed.
+var ErrInvalidIdentityTags = errors.New("invalid identity tags")
+
+// AddIdentityTags labels an identity with tags, such as "vip" or "dormant", that identities can then be listed
+// by. Tags are lowercased, and tags the identity already has are kept once.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - identityID string: The ID of the identity.
+// - tags []string: The tags to add.
+//
+// Returns:
+// - *model.Identity: The identity with its tags.
+// - error: ErrInvalidIdentityTags if a tag is malformed, or an error if the identity does not exist.
+func (l *Blnk) AddIdentityTags(ctx context.Context, identityID string, tags []string) (*model.Identity, error) {
+	ctx, span := tracer.Start(ctx, "AddIdentityTags")
+	defer span.End()
+
+	return l.updateIdentityTags(ctx, identityID, tags, l.datasource.AddIdentityTags)
+}
+
+// RemoveIdentityTags removes tags from an identity. Tags the identity does not have are ignored.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - identityID string: The ID of the identity.
+// - tags []string: The tags to remove.
+//
+// Returns:
+// - *model.Identity: The identity with its remaining tags.
+// - error: ErrInvalidIdentityTags if a tag is malformed, or an error if the identity does not exist.
+func (l *Blnk) RemoveIdentityTags(ctx context.Context, identityID string, tags []string) (*model.Identity, error) {
+	ctx, span := tracer.Start(ctx, "RemoveIdentityTags")
+	defer span.End()
+
+	return l.updateIdentityTags(ctx, identityID, tags, l.datasource.RemoveIdentityTags)
+}
+
+// updateIdentityTags normalizes tags and applies an update of an identity's tags with them, reindexing the
+// identity and notifying of the change.
+func (l *Blnk) updateIdentityTags(ctx context.Context, identityID string, tags []string, update func(context.Context, string, []string) ([]string, error)) (*model.Identity, error) {
+	normalized, err := model.NormalizeIdentityTags(tags)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIdentityTags, err)
+	}
+	if _, err := update(ctx, identityID, normalized); err != nil {
+		return nil, err
+	}
+
+	l.postIdentityChangeActions(ctx, EventIdentityUpdated, identityID)
+	return l.datasource.GetIdentityByID(ctx, identityID)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddIdentityTags(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	tagged := &model.Identity{IdentityID: "idt_1", Tags: []string{"kyc:tier-2", "vip"}}
+	mockDS.On("AddIdentityTags", mock.Anything, "idt_1", []string{"kyc:tier-2", "vip"}).Return(tagged.Tags, nil)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(tagged, nil)
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_1").Return(tagged, nil).Maybe()
+
+	identity, err := b.AddIdentityTags(context.Background(), "idt_1", []string{" VIP", "kyc:tier-2", "vip"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kyc:tier-2", "vip"}, identity.Tags)
+	mockDS.AssertCalled(t, "AddIdentityTags", mock.Anything, "idt_1", []string{"kyc:tier-2", "vip"})
+}
+
+func TestAddIdentityTags_Invalid(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	_, err := b.AddIdentityTags(context.Background(), "idt_1", []string{"high value"})
+	assert.True(t, errors.Is(err, ErrInvalidIdentityTags))
+
+	_, err = b.AddIdentityTags(context.Background(), "idt_1", nil)
+	assert.True(t, errors.Is(err, ErrInvalidIdentityTags))
+	mockDS.AssertNotCalled(t, "AddIdentityTags", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRemoveIdentityTags_NotFound(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("RemoveIdentityTags", mock.Anything, "idt_missing", []string{"vip"}).
+		Return(nil, apierror.NewAPIError(apierror.ErrNotFound, "Identity with ID 'idt_missing' not found", nil))
+
+	_, err := b.RemoveIdentityTags(context.Background(), "idt_missing", []string{"vip"})
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+	mockDS.AssertNotCalled(t, "GetIdentityByID", mock.Anything, mock.Anything)
+}
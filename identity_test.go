@@ -17,10 +17,15 @@ limitations under the License.
 package blnk
 
 import (
+	"context"
 	"encoding/json"
+	"github.com/stretchr/testify/mock"
 	"testing"
 	"time"
 
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/tenant"
 	"github.com/blnkfinance/blnk/model"
 
 	"github.com/brianvoe/gofakeit/v6"
@@ -62,10 +67,10 @@ func TestCreateIdentity(t *testing.T) {
 	metaDataJSON, _ := json.Marshal(identity.MetaData)
 
 	mock.ExpectExec("INSERT INTO blnk.identity").
-		WithArgs(sqlmock.AnyArg(), identity.IdentityType, identity.FirstName, identity.LastName, identity.OtherNames, identity.Gender, identity.DOB, identity.EmailAddress, identity.PhoneNumber, identity.Nationality, identity.OrganizationName, identity.Category, identity.Street, identity.Country, identity.State, identity.PostCode, identity.City, sqlmock.AnyArg(), metaDataJSON).
+		WithArgs(sqlmock.AnyArg(), identity.IdentityType, identity.FirstName, identity.LastName, identity.OtherNames, identity.Gender, identity.DOB, identity.EmailAddress, identity.PhoneNumber, identity.Nationality, identity.OrganizationName, identity.Category, identity.Street, identity.Country, identity.State, identity.PostCode, identity.City, sqlmock.AnyArg(), metaDataJSON, identity.Locale, identity.Timezone, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	result, err := d.CreateIdentity(identity)
+	result, err := d.CreateIdentity(context.Background(), identity)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, result.IdentityID)
 	assert.Equal(t, identity.FirstName, result.FirstName)
@@ -75,6 +80,29 @@ func TestCreateIdentity(t *testing.T) {
 	}
 }
 
+func TestCreateIdentity_InvalidPreferences(t *testing.T) {
+	datasource, mock, err := newTestDataSource()
+	if err != nil {
+		t.Fatalf("Error creating test data source: %s", err)
+	}
+
+	d, err := NewBlnk(datasource)
+	if err != nil {
+		t.Fatalf("Error creating Blnk instance: %s", err)
+	}
+
+	_, err = d.CreateIdentity(context.Background(), model.Identity{IdentityType: "individual", Locale: "not a locale!"})
+	assert.ErrorContains(t, err, "invalid locale")
+
+	_, err = d.CreateIdentity(context.Background(), model.Identity{IdentityType: "individual", Timezone: "Mars/Olympus_Mons"})
+	assert.ErrorContains(t, err, "invalid timezone")
+
+	_, err = d.CreateIdentity(context.Background(), model.Identity{IdentityType: "individual", CommunicationPreferences: &model.CommunicationPreferences{Channel: "pigeon"}})
+	assert.ErrorContains(t, err, "unknown communication channel")
+
+	assert.NoError(t, mock.ExpectationsWereMet(), "invalid identities are not stored")
+}
+
 func TestGetIdentity(t *testing.T) {
 	datasource, mock, err := newTestDataSource()
 	if err != nil {
@@ -96,10 +124,14 @@ func TestGetIdentity(t *testing.T) {
 		"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob",
 		"email_address", "phone_number", "nationality", "organization_name", "category",
 		"street", "country", "state", "post_code", "city", "created_at", "meta_data",
+		"locale", "timezone", "communication_preferences", "deleted_at",
+		"verification_status", "verification_reason", "verification_submitted_at", "verified_at", "verification_rejected_at",
+		"risk_score", "risk_level", "risk_scored_at", "email_verified_at", "phone_verified_at", "tags", "status", "status_reason", "status_changed_at", "version",
 	}).AddRow(
 		testID, "Individual", "John", "Doe", "Other Names", "Male", time.Now(),
 		"john@example.com", "1234567890", "Nationality", "Organization", "Category",
 		"Street", "Country", "State", "PostCode", "City", time.Now(), `{"key":"value"}`,
+		"", "", nil, nil, "unverified", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "active", nil, nil, 1,
 	)
 
 	// Updated query to match the actual method's query
@@ -110,7 +142,7 @@ func TestGetIdentity(t *testing.T) {
 	// Expect transaction to commit
 	mock.ExpectCommit()
 
-	result, err := d.GetIdentity(testID)
+	result, err := d.GetIdentity(context.Background(), testID)
 
 	// Updated assertions for all fields
 	assert.NoError(t, err)
@@ -140,19 +172,24 @@ func TestGetAllIdentities(t *testing.T) {
 		"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob",
 		"email_address", "phone_number", "nationality", "organization_name", "category",
 		"street", "country", "state", "post_code", "city", "created_at", "meta_data",
+		"locale", "timezone", "communication_preferences", "deleted_at",
+		"verification_status", "verification_reason", "verification_submitted_at", "verified_at", "verification_rejected_at",
+		"risk_score", "risk_level", "risk_scored_at", "email_verified_at", "phone_verified_at", "tags", "status", "status_reason", "status_changed_at", "version",
 	}).AddRow(
 		"idt_12345", "individual", "John", "Doe", "Other Names", "Male", time.Now(),
 		"john@example.com", "1234567890", "Nationality", "Organization", "Category",
 		"Street", "Country", "State", "PostCode", "City", time.Now(), `{"key":"value"}`,
+		"", "", nil, nil, "unverified", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "active", nil, nil, 1,
 	).AddRow(
 		"idt_4442345", "individual", "John", "Doe", "Other Names", "Male", time.Now(),
 		"john@example.com", "1234567890", "Nationality", "Organization", "Category",
 		"Street", "Country", "State", "PostCode", "City", time.Now(), `{"key":"value"}`,
+		"", "", nil, nil, "unverified", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "active", nil, nil, 1,
 	)
 
 	mock.ExpectQuery("SELECT .* FROM blnk.identity").WillReturnRows(rows)
 
-	result, err := d.GetAllIdentities()
+	result, err := d.GetAllIdentities(context.Background())
 
 	assert.NoError(t, err)
 	assert.Len(t, result, 2)
@@ -198,8 +235,22 @@ func TestUpdateIdentity(t *testing.T) {
 	metaDataJSON, err := json.Marshal(identity.MetaData)
 	assert.NoError(t, err)
 
+	// The identity is locked and read before the update and read again after it
+	identityColumns := []string{
+		"identity_id", "identity_type", "first_name", "last_name", "other_names", "gender", "dob", "email_address", "phone_number", "nationality", "organization_name", "category", "street", "country", "state", "post_code", "city", "created_at", "meta_data", "locale", "timezone", "communication_preferences", "deleted_at",
+		"verification_status", "verification_reason", "verification_submitted_at", "verified_at", "verification_rejected_at",
+		"risk_score", "risk_level", "risk_scored_at", "email_verified_at", "phone_verified_at", "tags", "status", "status_reason", "status_changed_at", "version",
+	}
+	identityRow := func(email string) *sqlmock.Rows {
+		return sqlmock.NewRows(identityColumns).AddRow(identity.IdentityID, identity.IdentityType, identity.FirstName, identity.LastName, identity.OtherNames, identity.Gender, identity.DOB, email, identity.PhoneNumber, identity.Nationality, identity.OrganizationName, identity.Category, identity.Street, identity.Country, identity.State, identity.PostCode, identity.City, time.Time{}, metaDataJSON, "", "", nil, nil, "unverified", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "active", nil, nil, 1)
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM blnk\.identity WHERE identity_id = \$1 AND deleted_at IS NULL FOR UPDATE`).
+		WithArgs(identity.IdentityID).
+		WillReturnRows(identityRow("old.email@example.com"))
+
 	// Update the SQL pattern to include meta_data field
-	mock.ExpectExec(`UPDATE blnk\.identity SET identity_type = \$1, first_name = \$2, last_name = \$3, other_names = \$4, gender = \$5, dob = \$6, email_address = \$7, phone_number = \$8, nationality = \$9, organization_name = \$10, category = \$11, street = \$12, country = \$13, state = \$14, post_code = \$15, city = \$16, meta_data = \$17 WHERE identity_id = \$18`).
+	mock.ExpectExec(`UPDATE blnk\.identity SET identity_type = \$1, first_name = \$2, last_name = \$3, other_names = \$4, gender = \$5, dob = \$6, email_address = \$7, phone_number = \$8, nationality = \$9, organization_name = \$10, category = \$11, street = \$12, country = \$13, state = \$14, post_code = \$15, city = \$16, email_verified_at = CASE WHEN email_address = \$7 THEN email_verified_at END, phone_verified_at = CASE WHEN phone_number = \$8 THEN phone_verified_at END, meta_data = \$17, version = version \+ 1 WHERE identity_id = \$18`).
 		WithArgs(
 			identity.IdentityType,
 			identity.FirstName,
@@ -221,8 +272,17 @@ func TestUpdateIdentity(t *testing.T) {
 			identity.IdentityID, // Update parameter index to $18
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`FROM blnk\.identity WHERE identity_id = \$1 AND deleted_at IS NULL`).
+		WithArgs(identity.IdentityID).
+		WillReturnRows(identityRow(identity.EmailAddress))
 
-	err = d.UpdateIdentity(identity)
+	// The previous version is recorded as changed by the tenant of the request
+	mock.ExpectExec(`INSERT INTO blnk\.identity_history`).
+		WithArgs(identity.IdentityID, sqlmock.AnyArg(), []byte(`[{"field":"email_address","from":"old.email@example.com","to":"john.doe@example.com"}]`), "owner_1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = d.UpdateIdentity(tenant.WithTenant(context.Background(), "owner_1"), identity)
 	assert.NoError(t, err)
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -243,11 +303,11 @@ func TestDeleteIdentity(t *testing.T) {
 
 	testID := "idt_123"
 
-	mock.ExpectExec("DELETE FROM blnk.identity WHERE identity_id =").
-		WithArgs(testID).
+	mock.ExpectExec("UPDATE blnk.identity SET deleted_at = \\$2 WHERE identity_id = \\$1 AND deleted_at IS NULL").
+		WithArgs(testID, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err = d.DeleteIdentity(testID)
+	err = d.DeleteIdentity(context.Background(), testID)
 
 	assert.NoError(t, err)
 
@@ -255,3 +315,69 @@ func TestDeleteIdentity(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestDeleteIdentity_SendsWebhook(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	deletedAt := time.Now()
+	mockDS.On("DeleteIdentity", mock.Anything, "idt_1").Return(nil)
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1", DeletedAt: &deletedAt}, nil)
+
+	assert.NoError(t, b.DeleteIdentity(context.Background(), "idt_1"))
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
+
+func TestRestoreIdentity_SendsWebhook(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("RestoreIdentity", mock.Anything, "idt_1").Return(nil)
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil)
+
+	assert.NoError(t, b.RestoreIdentity(context.Background(), "idt_1"))
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
+
+func TestUpdateIdentity_SendsWebhookWithUpdatedIdentity(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	update := &model.Identity{IdentityID: "idt_1", EmailAddress: "new@example.com"}
+	mockDS.On("UpdateIdentity", context.Background(), update, model.ActorSystem).Return(nil)
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1", FirstName: "Ada", EmailAddress: "new@example.com"}, nil)
+
+	assert.NoError(t, b.UpdateIdentity(context.Background(), update))
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
+
+func TestGetIdentityBalancesAndTransactions(t *testing.T) {
+	mockDS := new(mocks.MockDataSource)
+	b := &Blnk{datasource: mockDS}
+	ctx := context.Background()
+
+	balances := []model.Balance{{BalanceID: "bln_wallet", IdentityID: "idt_1"}, {BalanceID: "bln_savings", IdentityID: "idt_1"}}
+	transactions := []*model.Transaction{{TransactionID: "txn_2", Source: "bln_wallet"}, {TransactionID: "txn_1", Destination: "bln_wallet"}}
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil)
+	mockDS.On("GetBalancesByIdentity", ctx, "idt_1").Return(balances, nil)
+	mockDS.On("GetTransactionsByIdentity", ctx, "idt_1", model.Page{Limit: 20, Offset: 40}).Return(transactions, nil)
+
+	gotBalances, err := b.GetIdentityBalances(ctx, "idt_1")
+	assert.NoError(t, err)
+	assert.Equal(t, balances, gotBalances)
+
+	gotTransactions, err := b.GetIdentityTransactions(ctx, "idt_1", model.Page{Limit: 20, Offset: 40})
+	assert.NoError(t, err)
+	assert.Equal(t, transactions, gotTransactions)
+	mockDS.AssertExpectations(t)
+}
+
+func TestGetIdentityTransactions_UnknownIdentity(t *testing.T) {
+	mockDS := new(mocks.MockDataSource)
+	b := &Blnk{datasource: mockDS}
+	notFound := apierror.NewAPIError(apierror.ErrNotFound, "Identity with ID 'idt_missing' not found", nil)
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_missing").Return((*model.Identity)(nil), notFound)
+
+	_, err := b.GetIdentityTransactions(context.Background(), "idt_missing", model.Page{Limit: 20})
+	assert.Equal(t, notFound, err)
+	_, err = b.GetIdentityBalances(context.Background(), "idt_missing")
+	assert.Equal(t, notFound, err)
+	mockDS.AssertExpectations(t)
+}
@@ -0,0 +1,26 @@
+package blnk
+
+import (
+	"github.com/blnkfinance/blnk/database"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// identityKind returns whether identities of a type are individuals or organizations, by the built-in and defined
+// identity types.
+func identityKind(identityType string) string {
+	types, _, err := database.ConfiguredIdentityTypes()
+	if err != nil {
+		types, _ = model.NewIdentityTypes(nil)
+	}
+	return types.Kind(identityType)
+}
+
+// checkIdentityType checks an identity against the defined identity types ahead of the datasource, which enforces
+// them on every write, so that the rows of an import are reported one by one rather than failing their batch.
+func checkIdentityType(identity *model.Identity) error {
+	types, defined, err := database.ConfiguredIdentityTypes()
+	if err != nil || !defined {
+		return err
+	}
+	return types.Validate(identity)
+}
@@ -0,0 +1,74 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// ErrInvalidVerificationTransition is returned when an identity's verification cannot move to the requested status
+// from its current one.
+var ErrInvalidVerificationTransition = errors.New("invalid verification status transition")
+
+// UpdateIdentityVerification moves an identity's KYC verification to a new status and sends an
+// identity.verification.<status> webhook so that downstream KYC tooling can react.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - id string: The ID of the identity.
+// - status string: The status to move the identity to.
+// - reason string: Why the status changed, such as why the identity was rejected. Optional.
+//
+// Returns:
+// - *model.Identity: The identity with its new verification status.
+// - error: ErrInvalidVerificationTransition if the identity cannot move to the status from its current one, or an
+// error if the identity is not found or its status changed concurrently.
+func (l *Blnk) UpdateIdentityVerification(ctx context.Context, id, status, reason string) (*model.Identity, error) {
+	identity, err := l.datasource.GetIdentityByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := identity.VerificationStatus
+	if previous == "" {
+		previous = model.VerificationUnverified
+	}
+	if !model.CanTransitionVerification(previous, status) {
+		return nil, fmt.Errorf("%w: identity %s is %s and cannot become %s", ErrInvalidVerificationTransition, id, previous, status)
+	}
+
+	now := time.Now()
+	if err := l.datasource.UpdateIdentityVerification(ctx, id, previous, status, reason, now); err != nil {
+		return nil, err
+	}
+
+	identity.VerificationStatus = status
+	identity.VerificationReason = reason
+	switch status {
+	case model.VerificationPending:
+		identity.VerificationSubmittedAt = &now
+	case model.VerificationVerified:
+		identity.VerifiedAt = &now
+	case model.VerificationRejected:
+		identity.VerificationRejectedAt = &now
+	}
+
+	change := model.IdentityVerificationChange{
+		IdentityID:     id,
+		PreviousStatus: previous,
+		Status:         status,
+		Reason:         reason,
+		ChangedAt:      now,
+	}
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: "identity.verification." + status, Payload: change}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+
+	return identity, nil
+}
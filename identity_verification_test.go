@@ -0,0 +1,57 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateIdentityVerification(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1", VerificationStatus: model.VerificationPending}, nil)
+	mockDS.On("UpdateIdentityVerification", mock.Anything, "idt_1", model.VerificationPending, model.VerificationRejected, "document expired", mock.AnythingOfType("time.Time")).Return(nil)
+
+	identity, err := b.UpdateIdentityVerification(context.Background(), "idt_1", model.VerificationRejected, "document expired")
+	require.NoError(t, err)
+	assert.Equal(t, model.VerificationRejected, identity.VerificationStatus)
+	assert.Equal(t, "document expired", identity.VerificationReason)
+	assert.NotNil(t, identity.VerificationRejectedAt)
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
+
+func TestUpdateIdentityVerification_InvalidTransition(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil)
+
+	// Identities without a status are unverified and must be submitted before they can be verified
+	_, err := b.UpdateIdentityVerification(context.Background(), "idt_1", model.VerificationVerified, "")
+	assert.True(t, errors.Is(err, ErrInvalidVerificationTransition))
+	mockDS.AssertNotCalled(t, "UpdateIdentityVerification", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	time.Sleep(50 * time.Millisecond)
+	tasks, _ := mr.List("asynq:{webhook_queue}:pending")
+	assert.Empty(t, tasks, "no webhook is sent for a rejected transition")
+}
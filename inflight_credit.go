@@ -0,0 +1,112 @@
+package blnk
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// pendingCreditLimit caps the pending inflight credits listed in a balance breakdown.
+const pendingCreditLimit = 100
+
+// GetBalanceBreakdown splits a balance into its settled funds, the inflight credits still on their way in and
+// the inflight debits still on their way out. The available balance is what can be spent right now.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - id string: The ID of the balance.
+// - withQueued bool: Whether queued transactions also count towards the breakdown.
+//
+// Returns:
+// - *model.BalanceBreakdown: The breakdown, with the oldest pending credits first.
+// - error: An error if the balance or its pending credits could not be retrieved.
+func (l *Blnk) GetBalanceBreakdown(ctx context.Context, id string, withQueued bool) (*model.BalanceBreakdown, error) {
+	ctx, span := balanceTracer.Start(ctx, "GetBalanceBreakdown")
+	defer span.End()
+
+	balance, err := l.GetBalanceByID(ctx, id, nil, withQueued)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	credits, err := l.datasource.GetPendingInflightCredits(ctx, id, pendingCreditLimit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	available := new(big.Int).Sub(orZero(balance.Balance), orZero(balance.InflightDebitBalance))
+	if withQueued {
+		available.Sub(available, orZero(balance.QueuedDebitBalance))
+	}
+
+	breakdown := &model.BalanceBreakdown{
+		BalanceID:             balance.BalanceID,
+		Currency:              balance.Currency,
+		Balance:               orZero(balance.Balance),
+		CreditBalance:         orZero(balance.CreditBalance),
+		DebitBalance:          orZero(balance.DebitBalance),
+		AvailableBalance:      available,
+		InflightCreditBalance: orZero(balance.InflightCreditBalance),
+		InflightDebitBalance:  orZero(balance.InflightDebitBalance),
+		PendingCredits:        credits,
+	}
+	if withQueued {
+		breakdown.QueuedCreditBalance = orZero(balance.QueuedCreditBalance)
+		breakdown.QueuedDebitBalance = orZero(balance.QueuedDebitBalance)
+	}
+	return breakdown, nil
+}
+
+// inflightCreditEvent builds the webhook sent when a commit or void settles an inflight credit on its destination.
+// It reports false for every other transaction and for commits or voids of nothing, which are never persisted.
+func inflightCreditEvent(status string, transaction *model.Transaction, destination *model.Balance) (string, *model.InflightCreditEvent, bool) {
+	var event string
+	switch status {
+	case StatusCommit:
+		event = "balance.inflight_credit.landed"
+	case StatusVoid:
+		event = "balance.inflight_credit.voided"
+	default:
+		return "", nil, false
+	}
+	if transaction.PreciseAmount == nil || transaction.PreciseAmount.Sign() == 0 {
+		return "", nil, false
+	}
+
+	payload := &model.InflightCreditEvent{
+		BalanceID:         destination.PostingID(),
+		TransactionID:     transaction.TransactionID,
+		ParentTransaction: transaction.ParentTransaction,
+		Currency:          transaction.Currency,
+		Amount:            transaction.Amount,
+		PreciseAmount:     transaction.PreciseAmount,
+	}
+	if destination.ShardOf == "" && destination.InflightCreditBalance != nil {
+		payload.InflightCreditBalance = new(big.Int).Set(destination.InflightCreditBalance)
+	}
+	return event, payload, true
+}
+
+// sendInflightCreditWebhook notifies subscribers that an inflight credit landed on or was voided from its
+// destination.
+func (l *Blnk) sendInflightCreditWebhook(status string, transaction *model.Transaction, destination *model.Balance) {
+	event, payload, ok := inflightCreditEvent(status, transaction, destination)
+	if !ok {
+		return
+	}
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
+
+func orZero(value *big.Int) *big.Int {
+	if value == nil {
+		return new(big.Int)
+	}
+	return value
+}
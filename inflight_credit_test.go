@@ -0,0 +1,85 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBalanceBreakdown(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	ctx := context.Background()
+
+	mockDS.On("GetBalanceByID", mock.Anything, "bln_dest", []string(nil), true).Return(&model.Balance{
+		BalanceID:             "bln_dest",
+		Currency:              "USD",
+		Balance:               big.NewInt(1000),
+		CreditBalance:         big.NewInt(1500),
+		DebitBalance:          big.NewInt(500),
+		InflightCreditBalance: big.NewInt(2500),
+		InflightDebitBalance:  big.NewInt(200),
+		QueuedDebitBalance:    big.NewInt(100),
+	}, nil)
+	mockDS.On("ListBalanceShardings", mock.Anything).Return([]*model.BalanceSharding{}, nil)
+	mockDS.On("GetPendingInflightCredits", mock.Anything, "bln_dest", pendingCreditLimit).Return([]*model.PendingInflightCredit{
+		{TransactionID: "txn_1", PreciseAmount: big.NewInt(4000), PendingAmount: big.NewInt(2500)},
+	}, nil)
+
+	breakdown, err := b.GetBalanceBreakdown(ctx, "bln_dest", true)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(700), breakdown.AvailableBalance)
+	assert.Equal(t, big.NewInt(2500), breakdown.InflightCreditBalance)
+	assert.Equal(t, big.NewInt(0), breakdown.QueuedCreditBalance)
+	assert.Equal(t, big.NewInt(100), breakdown.QueuedDebitBalance)
+	require.Len(t, breakdown.PendingCredits, 1)
+	assert.Equal(t, "txn_1", breakdown.PendingCredits[0].TransactionID)
+}
+
+func TestInflightCreditEvent(t *testing.T) {
+	transaction := &model.Transaction{TransactionID: "txn_2", ParentTransaction: "txn_1", Currency: "USD", Amount: 10, PreciseAmount: big.NewInt(1000)}
+	destination := &model.Balance{BalanceID: "bln_dest", InflightCreditBalance: big.NewInt(500)}
+
+	event, payload, ok := inflightCreditEvent(StatusCommit, transaction, destination)
+	require.True(t, ok)
+	assert.Equal(t, "balance.inflight_credit.landed", event)
+	assert.Equal(t, "bln_dest", payload.BalanceID)
+	assert.Equal(t, "txn_1", payload.ParentTransaction)
+	assert.Equal(t, big.NewInt(500), payload.InflightCreditBalance)
+
+	event, _, ok = inflightCreditEvent(StatusVoid, transaction, destination)
+	require.True(t, ok)
+	assert.Equal(t, "balance.inflight_credit.voided", event)
+
+	_, _, ok = inflightCreditEvent(StatusApplied, transaction, destination)
+	assert.False(t, ok)
+
+	_, _, ok = inflightCreditEvent(StatusCommit, &model.Transaction{PreciseAmount: big.NewInt(0)}, destination)
+	assert.False(t, ok)
+
+	shard := &model.Balance{BalanceID: "bln_shard", ShardOf: "bln_dest", InflightCreditBalance: big.NewInt(500)}
+	_, payload, ok = inflightCreditEvent(StatusCommit, transaction, shard)
+	require.True(t, ok)
+	assert.Equal(t, "bln_dest", payload.BalanceID)
+	assert.Nil(t, payload.InflightCreditBalance)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+)
+
+const (
+	defaultIntegrityIssueLimit = 1000
+	defaultStaleInflightAge    = 30 * 24 * time.Hour
+	integrityHashBatchSize     = 1000
+)
+
+// VerifyOptions controls a ledger integrity verification.
+type VerifyOptions struct {
+	Limit            int           // Maximum number of issues reported per check.
+	StaleInflightAge time.Duration // Inflight transactions older than this with no commit or void are reported.
+}
+
+// VerifyLedgerIntegrity checks ledger invariants across the database and returns a report of every violation
+// with a suggested repair. Checks are independent: a failing check is recorded in the report and the others still run.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - opts VerifyOptions: Limits for the verification; zero values use the defaults.
+//
+// Returns:
+// - *model.IntegrityReport: The results of every check and the issues found.
+func (l *Blnk) VerifyLedgerIntegrity(ctx context.Context, opts VerifyOptions) *model.IntegrityReport {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultIntegrityIssueLimit
+	}
+	if opts.StaleInflightAge <= 0 {
+		opts.StaleInflightAge = defaultStaleInflightAge
+	}
+
+	report := &model.IntegrityReport{GeneratedAt: time.Now(), Healthy: true, Issues: []model.IntegrityIssue{}}
+	checks := []struct {
+		name string
+		run  func() ([]model.IntegrityIssue, error)
+	}{
+		{model.IntegrityCheckUnbalancedTransactions, func() ([]model.IntegrityIssue, error) {
+			return l.datasource.FindUnbalancedTransactions(ctx, opts.Limit)
+		}},
+		{model.IntegrityCheckBalanceDrift, func() ([]model.IntegrityIssue, error) {
+			return l.datasource.FindBalanceDrift(ctx, opts.Limit)
+		}},
+		{model.IntegrityCheckOrphanedInflight, func() ([]model.IntegrityIssue, error) {
+			return l.datasource.FindOrphanedInflightTransactions(ctx, time.Now().Add(-opts.StaleInflightAge), opts.Limit)
+		}},
+		{model.IntegrityCheckTransactionHashes, func() ([]model.IntegrityIssue, error) {
+			return l.findTransactionHashMismatches(ctx, opts.Limit)
+		}},
+	}
+
+	for _, check := range checks {
+		result := model.IntegrityCheckResult{Name: check.name}
+		issues, err := check.run()
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.IssueCount = len(issues)
+		result.Passed = err == nil && len(issues) == 0
+		if !result.Passed {
+			report.Healthy = false
+		}
+		report.Checks = append(report.Checks, result)
+		report.Issues = append(report.Issues, issues...)
+	}
+
+	return report
+}
+
+// findTransactionHashMismatches recomputes the hash of every stored transaction and reports those that differ
+// from the hash recorded when the transaction was created, which indicates the record was altered afterwards.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - limit int: The maximum number of issues to return.
+//
+// Returns:
+// - []model.IntegrityIssue: The transactions whose hashes do not match.
+// - error: An error if the transactions could not be read.
+func (l *Blnk) findTransactionHashMismatches(ctx context.Context, limit int) ([]model.IntegrityIssue, error) {
+	issues := []model.IntegrityIssue{}
+	page := model.Page{Limit: integrityHashBatchSize}
+	for len(issues) < limit {
+		txns, err := l.datasource.GetTransactionsPaginated(ctx, "", page)
+		if err != nil {
+			return issues, err
+		}
+		if len(txns) == 0 {
+			break
+		}
+		page.After = model.NextPageCursor(txns, len(txns), (*model.Transaction).PageCursor)
+
+		for _, txn := range txns {
+			if txn.Hash == "" {
+				continue
+			}
+			if computed := txn.HashTxn(); computed != txn.Hash {
+				issues = append(issues, model.IntegrityIssue{
+					Check:    model.IntegrityCheckTransactionHashes,
+					EntityID: txn.TransactionID,
+					Detail:   "stored hash does not match the transaction's fields",
+					Expected: computed,
+					Actual:   txn.Hash,
+					Repair:   &model.RepairSuggestion{Action: model.RepairActionManualReview, Params: map[string]string{"transaction_id": txn.TransactionID}},
+				})
+				if len(issues) >= limit {
+					break
+				}
+			}
+		}
+	}
+	return issues, nil
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVerifyLedgerIntegrity(t *testing.T) {
+	mockDS := new(mocks.MockDataSource)
+	b := &Blnk{datasource: mockDS}
+
+	intact := &model.Transaction{TransactionID: "txn_1", Amount: 100, Reference: "ref_1", Currency: "USD", Source: "bln_a", Destination: "bln_b"}
+	intact.Hash = intact.HashTxn()
+	tampered := &model.Transaction{TransactionID: "txn_2", Amount: 100, Reference: "ref_2", Currency: "USD", Source: "bln_a", Destination: "bln_b"}
+	tampered.Hash = tampered.HashTxn()
+	tampered.Amount = 1000
+
+	mockDS.On("FindUnbalancedTransactions", mock.Anything, 50).Return([]model.IntegrityIssue{}, nil)
+	mockDS.On("FindBalanceDrift", mock.Anything, 50).Return([]model.IntegrityIssue{}, errors.New("connection lost"))
+	mockDS.On("FindOrphanedInflightTransactions", mock.Anything, mock.Anything, 50).Return([]model.IntegrityIssue{}, nil)
+	mockDS.On("GetTransactionsPaginated", mock.Anything, "", model.Page{Limit: integrityHashBatchSize}).Return([]*model.Transaction{intact, tampered}, nil)
+	next := model.Page{Limit: integrityHashBatchSize, After: &model.PageCursor{ID: tampered.TransactionID}}
+	mockDS.On("GetTransactionsPaginated", mock.Anything, "", next).Return([]*model.Transaction{}, nil)
+
+	report := b.VerifyLedgerIntegrity(context.Background(), VerifyOptions{Limit: 50})
+
+	assert.False(t, report.Healthy)
+	assert.Len(t, report.Checks, 4)
+	assert.True(t, report.Checks[0].Passed)
+	assert.False(t, report.Checks[1].Passed)
+	assert.Equal(t, "connection lost", report.Checks[1].Error)
+	assert.True(t, report.Checks[2].Passed)
+	assert.Equal(t, 1, report.Checks[3].IssueCount)
+	assert.Len(t, report.Issues, 1)
+	assert.Equal(t, "txn_2", report.Issues[0].EntityID)
+	assert.Equal(t, model.RepairActionManualReview, report.Issues[0].Repair.Action)
+}
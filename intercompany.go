@@ -0,0 +1,195 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/resilience"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// intercompanyPageSize is the number of transactions requested from the remote instance per search page.
+const intercompanyPageSize = 250
+
+// remoteSearchResponse is the subset of a Blnk search response needed to read remote transactions.
+type remoteSearchResponse struct {
+	Found int `json:"found"`
+	Hits  []struct {
+		Document struct {
+			TransactionID string  `json:"transaction_id"`
+			Amount        float64 `json:"amount"`
+			Reference     string  `json:"reference"`
+			Currency      string  `json:"currency"`
+			Description   string  `json:"description"`
+			CreatedAt     int64   `json:"created_at"`
+		} `json:"document"`
+	} `json:"hits"`
+}
+
+// StartIntercompanyReconciliation reconciles the local ledger against the applied transactions of another Blnk
+// instance. The remote transactions are pulled through the remote search API, stored as external transactions and
+// then reconciled like any other upload. Unmatched remote transactions are reported as breaks.
+// Parameters:
+// - ctx: The context controlling the reconciliation process.
+// - source: The remote instance and time window to pull transactions from.
+// - strategy: The reconciliation strategy to be used (e.g., "one_to_one").
+// - groupCriteria: Criteria to group transactions (optional).
+// - matchingRuleIDs: The IDs of the rules used for matching transactions.
+// - isDryRun: If true, the reconciliation will not commit changes.
+// Returns:
+// - string: The ID of the reconciliation process.
+// - error: If the remote transactions cannot be fetched or the reconciliation fails to start.
+func (s *Blnk) StartIntercompanyReconciliation(ctx context.Context, source model.IntercompanySource, strategy string, groupCriteria string, matchingRuleIDs []string, isDryRun bool) (string, error) {
+	if err := validateIntercompanySource(source); err != nil {
+		return "", err
+	}
+
+	uploadID := model.GenerateUUIDWithSuffix("intercompany")
+	externalTransactions, err := s.fetchRemoteTransactions(ctx, uploadID, source)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch transactions from remote instance: %w", err)
+	}
+
+	return s.startReconciliationWithTransactions(ctx, uploadID, externalTransactions, strategy, groupCriteria, matchingRuleIDs, isDryRun)
+}
+
+// GetReconciliationBreaks returns the external transactions a reconciliation could not match.
+// For intercompany reconciliations these are the remote transfers with no counterpart in this ledger.
+// Parameters:
+// - ctx: The context controlling the request.
+// - reconciliationID: The ID of the reconciliation.
+// Returns:
+// - []*model.ExternalTransaction: The unmatched external transactions.
+// - error: If the reconciliation cannot be found or the breaks cannot be retrieved.
+func (s *Blnk) GetReconciliationBreaks(ctx context.Context, reconciliationID string) ([]*model.ExternalTransaction, error) {
+	if _, err := s.GetReconciliation(ctx, reconciliationID); err != nil {
+		return nil, err
+	}
+	return s.datasource.GetUnmatchedExternalTransactions(ctx, reconciliationID)
+}
+
+// validateIntercompanySource checks that the remote instance and time window are usable.
+func validateIntercompanySource(source model.IntercompanySource) error {
+	if source.BaseURL == "" {
+		return errors.New("base_url is required")
+	}
+	if _, err := url.ParseRequestURI(source.BaseURL); err != nil {
+		return fmt.Errorf("invalid base_url: %w", err)
+	}
+	if source.StartDate.IsZero() || source.EndDate.IsZero() {
+		return errors.New("start_date and end_date are required")
+	}
+	if source.EndDate.Before(source.StartDate) {
+		return errors.New("end_date must be after start_date")
+	}
+	return nil
+}
+
+// fetchRemoteTransactions pages through the applied transactions of the remote instance within the source's time
+// window and converts them to external transactions. IDs are namespaced with the upload ID so that the same remote
+// transactions can be pulled again by later runs.
+// Parameters:
+// - ctx: The context controlling the request.
+// - uploadID: The upload ID the transactions will be stored under.
+// - source: The remote instance and time window.
+// Returns:
+// - []model.ExternalTransaction: The remote transactions.
+// - error: If any page cannot be retrieved or decoded.
+func (s *Blnk) fetchRemoteTransactions(ctx context.Context, uploadID string, source model.IntercompanySource) ([]model.ExternalTransaction, error) {
+	sourceName := "intercompany:" + source.BaseURL
+	var transactions []model.ExternalTransaction
+
+	for page := 1; ; page++ {
+		result, err := s.searchRemoteTransactions(ctx, source, page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hit := range result.Hits {
+			doc := hit.Document
+			transactions = append(transactions, model.ExternalTransaction{
+				ID:          fmt.Sprintf("%s:%s", uploadID, doc.TransactionID),
+				Amount:      doc.Amount,
+				Reference:   doc.Reference,
+				Currency:    doc.Currency,
+				Description: doc.Description,
+				Date:        time.Unix(doc.CreatedAt, 0),
+				Source:      sourceName,
+			})
+		}
+
+		if len(result.Hits) < intercompanyPageSize || len(transactions) >= result.Found {
+			return transactions, nil
+		}
+	}
+}
+
+// searchRemoteTransactions requests a single page of applied transactions from the remote instance's search API.
+func (s *Blnk) searchRemoteTransactions(ctx context.Context, source model.IntercompanySource, page int) (*remoteSearchResponse, error) {
+	filter := fmt.Sprintf("status:=APPLIED && created_at:[%d..%d]", source.StartDate.Unix(), source.EndDate.Unix())
+	if source.Currency != "" {
+		filter += " && currency:=" + source.Currency
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"q":         "*",
+		"filter_by": filter,
+		"sort_by":   "created_at:asc",
+		"per_page":  intercompanyPageSize,
+		"page":      page,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := strings.TrimRight(source.BaseURL, "/") + "/search/transactions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if source.APIKey != "" {
+		req.Header.Set("X-Blnk-Key", source.APIKey)
+	}
+
+	resp, err := resilience.Get(resilience.Intercompany).Wrap(s.httpClient).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("remote search failed with status %d: %s", resp.StatusCode, string(msg))
+	}
+
+	var result remoteSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode remote search response: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateIntercompanySource(t *testing.T) {
+	now := time.Now()
+
+	assert.Error(t, validateIntercompanySource(model.IntercompanySource{}))
+	assert.Error(t, validateIntercompanySource(model.IntercompanySource{BaseURL: "http://blnk.internal"}))
+	assert.Error(t, validateIntercompanySource(model.IntercompanySource{
+		BaseURL: "http://blnk.internal", StartDate: now, EndDate: now.Add(-time.Hour),
+	}))
+	assert.NoError(t, validateIntercompanySource(model.IntercompanySource{
+		BaseURL: "http://blnk.internal", StartDate: now.Add(-time.Hour), EndDate: now,
+	}))
+}
+
+func TestFetchRemoteTransactions(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search/transactions", r.URL.Path)
+		assert.Equal(t, "remote-key", r.Header.Get("X-Blnk-Key"))
+
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Contains(t, body["filter_by"], "currency:=USD")
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"found": 1,
+			"hits": []map[string]interface{}{
+				{"document": map[string]interface{}{
+					"transaction_id": "txn_remote",
+					"amount":         250.5,
+					"reference":      "ic-ref-1",
+					"currency":       "USD",
+					"description":    "intercompany transfer",
+					"created_at":     created.Unix(),
+				}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	blnk := &Blnk{httpClient: server.Client()}
+	txns, err := blnk.fetchRemoteTransactions(context.Background(), "upload_1", model.IntercompanySource{
+		BaseURL:   server.URL,
+		APIKey:    "remote-key",
+		StartDate: created.Add(-time.Hour),
+		EndDate:   created.Add(time.Hour),
+		Currency:  "USD",
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, txns, 1)
+	assert.Equal(t, "upload_1:txn_remote", txns[0].ID)
+	assert.Equal(t, "ic-ref-1", txns[0].Reference)
+	assert.Equal(t, 250.5, txns[0].Amount)
+	assert.True(t, created.Equal(txns[0].Date))
+}
+
+func TestFetchRemoteTransactions_RemoteError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	blnk := &Blnk{httpClient: server.Client()}
+	_, err := blnk.fetchRemoteTransactions(context.Background(), "upload_1", model.IntercompanySource{
+		BaseURL:   server.URL,
+		StartDate: time.Now().Add(-time.Hour),
+		EndDate:   time.Now(),
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package calendar decides which dates are business days: a calendar, usually a country's, has weekend days and
+// holidays that are not, and custom dates that are business days regardless, such as a working Saturday. Dates
+// that fall on other days can be rolled to a business day by a roll convention.
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// maxRollDays bounds the search for a business day, so a calendar without any cannot loop forever.
+const maxRollDays = 366
+
+// ReasonWeekend is the reason weekend days are not business days.
+const ReasonWeekend = "weekend"
+
+// ErrNoBusinessDay is returned when no business day is found within a year of a date.
+var ErrNoBusinessDay = errors.New("no business day within a year")
+
+// Calendar is a business calendar in its own timezone.
+type Calendar struct {
+	name         string
+	location     *time.Location
+	weekend      map[time.Weekday]bool
+	holidays     map[string]string // Name of each holiday, keyed by YYYY-MM-DD
+	annual       map[string]string // Name of each holiday that falls every year, keyed by MM-DD
+	businessDays map[string]bool
+}
+
+// New builds the calendar of a configuration.
+func New(name string, cfg config.CalendarConfig) (*Calendar, error) {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", cfg.Timezone)
+	}
+	c := &Calendar{
+		name:         name,
+		location:     location,
+		weekend:      make(map[time.Weekday]bool, len(cfg.Weekend)),
+		holidays:     make(map[string]string),
+		annual:       make(map[string]string),
+		businessDays: make(map[string]bool, len(cfg.BusinessDays)),
+	}
+	for _, day := range cfg.Weekend {
+		weekday, err := ParseWeekday(day)
+		if err != nil {
+			return nil, err
+		}
+		c.weekend[weekday] = true
+	}
+	if len(c.weekend) == 7 {
+		return nil, errors.New("weekend cannot include every day of the week")
+	}
+	for date, holiday := range cfg.Holidays {
+		if _, err := time.Parse(time.DateOnly, date); err == nil {
+			c.holidays[date] = holiday
+			continue
+		}
+		if _, err := time.Parse("01-02", date); err == nil {
+			c.annual[date] = holiday
+			continue
+		}
+		return nil, fmt.Errorf("holiday %q must be a date in YYYY-MM-DD, or MM-DD for every year", date)
+	}
+	for _, date := range cfg.BusinessDays {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("business day %q must be a date in YYYY-MM-DD", date)
+		}
+		c.businessDays[date] = true
+	}
+	return c, nil
+}
+
+// ParseWeekday parses the English name of a day of the week, in any case.
+func ParseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), strings.TrimSpace(name)) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
+
+// Name returns the name of the calendar.
+func (c *Calendar) Name() string {
+	return c.name
+}
+
+// Location returns the timezone of the calendar.
+func (c *Calendar) Location() *time.Location {
+	return c.location
+}
+
+// IsBusinessDay reports whether the date of a time, in the calendar's timezone, is a business day.
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	return c.Reason(t) == ""
+}
+
+// Reason returns why the date of a time is not a business day: ReasonWeekend or the name of its holiday. It is
+// empty for business days.
+func (c *Calendar) Reason(t time.Time) string {
+	t = t.In(c.location)
+	date := t.Format(time.DateOnly)
+	if c.businessDays[date] {
+		return ""
+	}
+	if holiday, ok := c.holidays[date]; ok {
+		return holiday
+	}
+	if holiday, ok := c.annual[t.Format("01-02")]; ok {
+		return holiday
+	}
+	if c.weekend[t.Weekday()] {
+		return ReasonWeekend
+	}
+	return ""
+}
+
+// Next returns the first business day after the date of a time, at the same time of day.
+func (c *Calendar) Next(t time.Time) (time.Time, error) {
+	return c.step(t.In(c.location), 1)
+}
+
+// Previous returns the last business day before the date of a time, at the same time of day.
+func (c *Calendar) Previous(t time.Time) (time.Time, error) {
+	return c.step(t.In(c.location), -1)
+}
+
+// Roll moves a time that falls on a non-business day to a business day by a roll convention, keeping its time of
+// day. Times on business days are returned as they are.
+func (c *Calendar) Roll(t time.Time, roll string) (time.Time, error) {
+	if roll == model.RollNone || c.IsBusinessDay(t) {
+		return t, nil
+	}
+	switch roll {
+	case model.RollFollowing:
+		return c.Next(t)
+	case model.RollPreceding:
+		return c.Previous(t)
+	case model.RollModifiedFollowing:
+		next, err := c.Next(t)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if next.Month() != t.In(c.location).Month() {
+			return c.Previous(t)
+		}
+		return next, nil
+	}
+	return time.Time{}, fmt.Errorf("unknown roll convention %q", roll)
+}
+
+func (c *Calendar) step(t time.Time, days int) (time.Time, error) {
+	for i := 1; i <= maxRollDays; i++ {
+		candidate := t.AddDate(0, 0, i*days)
+		if c.IsBusinessDay(candidate) {
+			return candidate, nil
+		}
+	}
+	return time.Time{}, ErrNoBusinessDay
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCalendar(t *testing.T) *Calendar {
+	cal, err := New("US", config.CalendarConfig{
+		Timezone:     "America/New_York",
+		Weekend:      []string{"Saturday", "sunday"},
+		Holidays:     map[string]string{"2026-11-26": "Thanksgiving", "12-25": "Christmas Day"},
+		BusinessDays: []string{"2026-10-03"},
+	})
+	require.NoError(t, err)
+	return cal
+}
+
+func date(t *testing.T, cal *Calendar, value string) time.Time {
+	day, err := time.ParseInLocation("2006-01-02 15:04", value, cal.Location())
+	require.NoError(t, err)
+	return day
+}
+
+func TestCalendar_Reason(t *testing.T) {
+	cal := testCalendar(t)
+
+	assert.Equal(t, "", cal.Reason(date(t, cal, "2026-10-16 09:00")))
+	assert.Equal(t, ReasonWeekend, cal.Reason(date(t, cal, "2026-10-17 09:00")))
+	assert.Equal(t, "Thanksgiving", cal.Reason(date(t, cal, "2026-11-26 09:00")))
+	assert.Equal(t, "Christmas Day", cal.Reason(date(t, cal, "2027-12-25 09:00")))
+	// A custom business day overrides the weekend
+	assert.True(t, cal.IsBusinessDay(date(t, cal, "2026-10-03 09:00")))
+	// Dates are taken in the calendar's timezone: Friday evening in New York is Saturday in UTC
+	assert.True(t, cal.IsBusinessDay(time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC)))
+}
+
+func TestCalendar_Roll(t *testing.T) {
+	cal := testCalendar(t)
+
+	tests := []struct {
+		name     string
+		at       string
+		roll     string
+		expected string
+	}{
+		{name: "business day is kept", at: "2026-10-16 09:00", roll: model.RollFollowing, expected: "2026-10-16 09:00"},
+		{name: "following skips the weekend", at: "2026-10-17 09:00", roll: model.RollFollowing, expected: "2026-10-19 09:00"},
+		{name: "preceding goes back to friday", at: "2026-10-18 09:00", roll: model.RollPreceding, expected: "2026-10-16 09:00"},
+		{name: "following skips a holiday", at: "2026-11-26 09:00", roll: model.RollFollowing, expected: "2026-11-27 09:00"},
+		{name: "modified following stays in the month", at: "2026-10-31 09:00", roll: model.RollModifiedFollowing, expected: "2026-10-30 09:00"},
+		{name: "modified following within the month", at: "2026-10-24 09:00", roll: model.RollModifiedFollowing, expected: "2026-10-26 09:00"},
+		{name: "none leaves the date", at: "2026-10-17 09:00", roll: model.RollNone, expected: "2026-10-17 09:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rolled, err := cal.Roll(date(t, cal, tt.at), tt.roll)
+			require.NoError(t, err)
+			assert.True(t, rolled.Equal(date(t, cal, tt.expected)), "rolled to %s", rolled)
+		})
+	}
+
+	_, err := cal.Roll(date(t, cal, "2026-10-17 09:00"), "sideways")
+	assert.Error(t, err)
+}
+
+func TestNew_InvalidCalendar(t *testing.T) {
+	_, err := New("X", config.CalendarConfig{Timezone: "UTC", Weekend: []string{"funday"}})
+	assert.EqualError(t, err, `unknown weekday "funday"`)
+
+	_, err = New("X", config.CalendarConfig{Timezone: "UTC", Holidays: map[string]string{"25/12": "Christmas"}})
+	assert.Error(t, err)
+
+	_, err = New("X", config.CalendarConfig{Timezone: "Nowhere/City"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package egress
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+)
+
+// NewHTTPClient builds the HTTP client used for outbound webhook delivery and notifications.
+// All requests go through the configured proxy (or the proxy from the environment when none is set),
+// trust the optional CA bundle in addition to the system roots, and use per-host TLS settings
+// when an endpoint override exists.
+//
+// Timeouts are used as configured; their defaults are filled in when the configuration is loaded.
+//
+// Parameters:
+// - cfg config.EgressConfig: The egress configuration.
+//
+// Returns:
+// - *http.Client: The configured HTTP client.
+// - error: An error if the proxy URL, CA bundle or client certificates are invalid.
+func NewHTTPClient(cfg config.EgressConfig) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid webhook proxy url: %s", cfg.ProxyURL)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	defaultTLS, err := buildTLSConfig(cfg.CACertFile, config.EndpointTLSConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	fallback := newTransport(cfg, proxy, defaultTLS)
+	if len(cfg.Endpoints) == 0 {
+		return &http.Client{
+			Timeout:   time.Duration(cfg.RequestTimeout) * time.Second,
+			Transport: fallback,
+		}, nil
+	}
+
+	rt := &endpointTransport{
+		fallback:  fallback,
+		endpoints: make(map[string]*http.Transport, len(cfg.Endpoints)),
+	}
+	for host, endpoint := range cfg.Endpoints {
+		tlsConfig, err := buildTLSConfig(cfg.CACertFile, endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %s: %w", host, err)
+		}
+		rt.endpoints[host] = newTransport(cfg, proxy, tlsConfig)
+	}
+
+	return &http.Client{
+		Timeout:   time.Duration(cfg.RequestTimeout) * time.Second,
+		Transport: rt,
+	}, nil
+}
+
+// endpointTransport routes each request to the transport configured for its host.
+type endpointTransport struct {
+	fallback  *http.Transport
+	endpoints map[string]*http.Transport
+}
+
+// RoundTrip implements http.RoundTripper. Overrides are looked up by host:port first and then by hostname.
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if transport, ok := t.endpoints[req.URL.Host]; ok {
+		return transport.RoundTrip(req)
+	}
+	if transport, ok := t.endpoints[req.URL.Hostname()]; ok {
+		return transport.RoundTrip(req)
+	}
+	return t.fallback.RoundTrip(req)
+}
+
+// newTransport creates a pooled transport with the configured proxy and timeouts.
+func newTransport(cfg config.EgressConfig, proxy func(*http.Request) (*url.URL, error), tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   time.Duration(cfg.ConnectTimeout) * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: time.Duration(cfg.TLSHandshakeTimeout) * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// buildTLSConfig combines the global CA bundle with an endpoint's overrides.
+func buildTLSConfig(globalCAFile string, endpoint config.EndpointTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         endpoint.ServerName,
+		InsecureSkipVerify: endpoint.InsecureSkipVerify, // #nosec G402 -- explicit per-endpoint opt-in
+	}
+
+	if globalCAFile != "" || endpoint.CACertFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		for _, file := range []string{globalCAFile, endpoint.CACertFile} {
+			if file == "" {
+				continue
+			}
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle %s: %w", file, err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA bundle %s", file)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if endpoint.ClientCertFile != "" || endpoint.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(endpoint.ClientCertFile, endpoint.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package egress
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHTTPClient_UsesProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	client, err := NewHTTPClient(config.EgressConfig{ProxyURL: proxy.URL, RequestTimeout: 5})
+	assert.NoError(t, err)
+
+	resp, err := client.Get("http://partner.example.com/webhooks")
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "http://partner.example.com/webhooks", proxied)
+}
+
+func TestNewHTTPClient_EndpointOverrides(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	host := server.Listener.Addr().String()
+
+	client, err := NewHTTPClient(config.EgressConfig{RequestTimeout: 5})
+	assert.NoError(t, err)
+	_, err = client.Get(server.URL)
+	assert.Error(t, err, "self-signed endpoint should be rejected without an override")
+
+	client, err = NewHTTPClient(config.EgressConfig{
+		RequestTimeout: 5,
+		Endpoints: map[string]config.EndpointTLSConfig{
+			host: {InsecureSkipVerify: true},
+		},
+	})
+	assert.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+}
+
+func TestNewHTTPClient_InvalidConfig(t *testing.T) {
+	_, err := NewHTTPClient(config.EgressConfig{ProxyURL: "::not-a-url"})
+	assert.Error(t, err)
+
+	_, err = NewHTTPClient(config.EgressConfig{CACertFile: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	assert.NoError(t, os.WriteFile(empty, []byte("not a certificate"), 0o600))
+	_, err = NewHTTPClient(config.EgressConfig{CACertFile: empty})
+	assert.Error(t, err)
+
+	_, err = NewHTTPClient(config.EgressConfig{
+		Endpoints: map[string]config.EndpointTLSConfig{
+			"partner.example.com": {ClientCertFile: "missing.crt", ClientKeyFile: "missing.key"},
+		},
+	})
+	assert.Error(t, err)
+}
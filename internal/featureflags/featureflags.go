@@ -0,0 +1,189 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package featureflags decides which ledger behaviors are enabled, globally or per tenant.
+// Defaults come from configuration; overrides are stored in Redis so a rollout can be widened
+// or rolled back at runtime without a deploy.
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	flagKeyPrefix = "feature-flags"
+	flagIndexKey  = "feature-flags:index"
+
+	SourceConfig   = "config"
+	SourceOverride = "override"
+)
+
+// ReconciliationConfidenceScoring gates confidence-based routing of reconciliation matches.
+// When it is off, every match produced by the matcher is confirmed.
+const ReconciliationConfidenceScoring = "reconciliation.confidence_scoring"
+
+// Defaults are the built-in states of every known flag. Configuration overrides them.
+var Defaults = map[string]bool{
+	ReconciliationConfidenceScoring: true,
+}
+
+// Flag is the rollout state of a single feature.
+// Evaluation order: a tenant override wins, then the percentage rollout, then Enabled.
+type Flag struct {
+	Name           string          `json:"name"`
+	Enabled        bool            `json:"enabled"`
+	RolloutPercent int             `json:"rollout_percent,omitempty"`
+	Tenants        map[string]bool `json:"tenants,omitempty"`
+	Source         string          `json:"source"`
+	UpdatedAt      time.Time       `json:"updated_at,omitempty"`
+}
+
+// Store evaluates and manages feature flags.
+type Store struct {
+	client   redis.UniversalClient
+	defaults map[string]bool
+}
+
+// NewStore creates a flag store. Configured values take precedence over the built-in Defaults.
+func NewStore(client redis.UniversalClient, configured map[string]bool) *Store {
+	defaults := make(map[string]bool, len(Defaults)+len(configured))
+	for name, enabled := range Defaults {
+		defaults[name] = enabled
+	}
+	for name, enabled := range configured {
+		defaults[name] = enabled
+	}
+	return &Store{client: client, defaults: defaults}
+}
+
+// IsEnabled reports whether a feature is enabled for the tenant in ctx.
+// If the override store cannot be read the configured default is used. A nil store uses Defaults.
+func (s *Store) IsEnabled(ctx context.Context, name string) bool {
+	if s == nil {
+		return Defaults[name]
+	}
+	flag, err := s.Get(ctx, name)
+	if err != nil {
+		return s.defaults[name]
+	}
+	return flag.EnabledFor(tenant.FromContext(ctx))
+}
+
+// EnabledFor evaluates the flag for a tenant.
+func (f *Flag) EnabledFor(tenant string) bool {
+	if tenant != "" {
+		if enabled, ok := f.Tenants[tenant]; ok {
+			return enabled
+		}
+		if f.RolloutPercent > 0 {
+			return bucket(f.Name, tenant) < f.RolloutPercent
+		}
+	}
+	return f.Enabled
+}
+
+// bucket maps a tenant to a stable value in [0, 100) for a flag.
+func bucket(name, tenant string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name + ":" + tenant))
+	return int(h.Sum32() % 100)
+}
+
+// Get returns a flag, falling back to its configured default when there is no override.
+func (s *Store) Get(ctx context.Context, name string) (*Flag, error) {
+	data, err := s.client.Get(ctx, fmt.Sprintf("%s:%s", flagKeyPrefix, name)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		enabled, ok := s.defaults[name]
+		if !ok {
+			return nil, fmt.Errorf("feature flag %s not found", name)
+		}
+		return &Flag{Name: name, Enabled: enabled, Source: SourceConfig}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var flag Flag
+	if err := json.Unmarshal(data, &flag); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal feature flag: %w", err)
+	}
+	flag.Source = SourceOverride
+	return &flag, nil
+}
+
+// List returns all known flags sorted by name.
+func (s *Store) List(ctx context.Context) ([]*Flag, error) {
+	names, err := s.client.SMembers(ctx, flagIndexKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(names)+len(s.defaults))
+	for _, name := range names {
+		seen[name] = true
+	}
+	for name := range s.defaults {
+		seen[name] = true
+	}
+
+	flags := make([]*Flag, 0, len(seen))
+	for name := range seen {
+		flag, err := s.Get(ctx, name)
+		if err != nil {
+			continue
+		}
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags, nil
+}
+
+// Set stores an override for a flag. It takes effect on the next evaluation.
+func (s *Store) Set(ctx context.Context, flag *Flag) error {
+	if flag.Name == "" {
+		return errors.New("flag name is required")
+	}
+	if flag.RolloutPercent < 0 || flag.RolloutPercent > 100 {
+		return errors.New("rollout_percent must be between 0 and 100")
+	}
+	flag.UpdatedAt = time.Now()
+	flag.Source = SourceOverride
+
+	data, err := json.Marshal(flag)
+	if err != nil {
+		return fmt.Errorf("failed to marshal feature flag: %w", err)
+	}
+	if err := s.client.Set(ctx, fmt.Sprintf("%s:%s", flagKeyPrefix, flag.Name), data, 0).Err(); err != nil {
+		return err
+	}
+	return s.client.SAdd(ctx, flagIndexKey, flag.Name).Err()
+}
+
+// Delete removes the override for a flag, returning it to its configured default.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	if err := s.client.Del(ctx, fmt.Sprintf("%s:%s", flagKeyPrefix, name)).Err(); err != nil {
+		return err
+	}
+	return s.client.SRem(ctx, flagIndexKey, name).Err()
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package featureflags
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T, configured map[string]bool) *Store {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return NewStore(client, configured)
+}
+
+func TestStore_DefaultsAndConfiguration(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, map[string]bool{"ledger.new_behavior": true})
+
+	assert.True(t, store.IsEnabled(ctx, ReconciliationConfidenceScoring))
+	assert.True(t, store.IsEnabled(ctx, "ledger.new_behavior"))
+	assert.False(t, store.IsEnabled(ctx, "unknown"))
+
+	store = newTestStore(t, map[string]bool{ReconciliationConfidenceScoring: false})
+	assert.False(t, store.IsEnabled(ctx, ReconciliationConfidenceScoring))
+
+	var nilStore *Store
+	assert.True(t, nilStore.IsEnabled(ctx, ReconciliationConfidenceScoring))
+}
+
+func TestStore_TenantOverrideAndRollback(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, nil)
+
+	err := store.Set(ctx, &Flag{
+		Name:    ReconciliationConfidenceScoring,
+		Enabled: true,
+		Tenants: map[string]bool{"tenant_a": false},
+	})
+	require.NoError(t, err)
+
+	assert.False(t, store.IsEnabled(tenant.WithTenant(ctx, "tenant_a"), ReconciliationConfidenceScoring))
+	assert.True(t, store.IsEnabled(tenant.WithTenant(ctx, "tenant_b"), ReconciliationConfidenceScoring))
+
+	flag, err := store.Get(ctx, ReconciliationConfidenceScoring)
+	require.NoError(t, err)
+	assert.Equal(t, SourceOverride, flag.Source)
+
+	require.NoError(t, store.Delete(ctx, ReconciliationConfidenceScoring))
+	assert.True(t, store.IsEnabled(tenant.WithTenant(ctx, "tenant_a"), ReconciliationConfidenceScoring))
+
+	flag, err = store.Get(ctx, ReconciliationConfidenceScoring)
+	require.NoError(t, err)
+	assert.Equal(t, SourceConfig, flag.Source)
+}
+
+func TestStore_PercentageRollout(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, nil)
+
+	require.NoError(t, store.Set(ctx, &Flag{Name: "ledger.partial", RolloutPercent: 50}))
+
+	enabled := 0
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p"} {
+		tenantCtx := tenant.WithTenant(ctx, name)
+		first := store.IsEnabled(tenantCtx, "ledger.partial")
+		assert.Equal(t, first, store.IsEnabled(tenantCtx, "ledger.partial"), "rollout must be stable per tenant")
+		if first {
+			enabled++
+		}
+	}
+	assert.Greater(t, enabled, 0)
+	assert.Less(t, enabled, 16)
+
+	assert.Error(t, store.Set(ctx, &Flag{Name: "ledger.partial", RolloutPercent: 101}))
+}
+
+func TestStore_List(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, nil)
+	require.NoError(t, store.Set(ctx, &Flag{Name: "a.flag", Enabled: true}))
+
+	flags, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, flags, 2)
+	assert.Equal(t, "a.flag", flags[0].Name)
+	assert.Equal(t, ReconciliationConfidenceScoring, flags[1].Name)
+}
@@ -9,15 +9,16 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/blnkfinance/blnk/internal/resilience"
 	"github.com/cenkalti/backoff/v4"
 	"github.com/sirupsen/logrus"
 )
 
 func (m *redisHookManager) executeHook(ctx context.Context, hook *Hook, payload HookPayload) error {
 	// Create HTTP client with timeout
-	client := &http.Client{
+	client := resilience.Get(resilience.Hooks).Wrap(&http.Client{
 		Timeout: time.Duration(hook.Timeout) * time.Second,
-	}
+	})
 
 	// Marshal payload with explicit handling
 	payloadBytes, err := json.Marshal(payload)
@@ -0,0 +1,303 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingestion
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+)
+
+// maxMessagesPerFetch bounds the number of emails read in one fetch. The rest are read by the next one.
+const maxMessagesPerFetch = 50
+
+// IMAPSource collects the attachments of unseen emails in a mailbox. Emails are marked seen when their files
+// are acknowledged, and emails without attachments are marked seen as soon as they are read.
+type IMAPSource struct {
+	cfg    config.IMAPConfig
+	dial   func(ctx context.Context) (net.Conn, error)
+	client *imapClient
+}
+
+// NewIMAPSource returns a source reading the mailbox described by cfg over TLS.
+func NewIMAPSource(cfg config.IMAPConfig) *IMAPSource {
+	return &IMAPSource{
+		cfg: cfg,
+		dial: func(ctx context.Context) (net.Conn, error) {
+			dialer := tls.Dialer{Config: &tls.Config{ServerName: cfg.Host, MinVersion: tls.VersionTLS12}}
+			return dialer.DialContext(ctx, "tcp", net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)))
+		},
+	}
+}
+
+// Name returns "imap".
+func (s *IMAPSource) Name() string {
+	return "imap"
+}
+
+// Fetch logs in to the mailbox and returns the attachments of its unseen emails.
+func (s *IMAPSource) Fetch(ctx context.Context) ([]File, error) {
+	if s.client != nil {
+		return nil, errors.New("imap source is already open")
+	}
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to imap server: %w", err)
+	}
+	deadline(ctx, conn.SetDeadline)
+	client, err := newIMAPClient(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	s.client = client
+
+	if err := client.login(s.cfg.Username, s.cfg.Password); err != nil {
+		return nil, err
+	}
+	if err := client.selectMailbox(s.cfg.Mailbox); err != nil {
+		return nil, err
+	}
+	uids, err := client.searchUnseen()
+	if err != nil {
+		return nil, err
+	}
+	if len(uids) > maxMessagesPerFetch {
+		uids = uids[:maxMessagesPerFetch]
+	}
+
+	var files []File
+	for _, uid := range uids {
+		raw, err := client.fetch(uid)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := ParseMessage(raw)
+		if err != nil {
+			// The email is left unseen for someone to look at
+			continue
+		}
+		if len(msg.Attachments) == 0 {
+			if err := client.markSeen(uid); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		receivedAt := msg.ReceivedAt
+		if receivedAt.IsZero() {
+			receivedAt = time.Now()
+		}
+		for _, attachment := range msg.Attachments {
+			files = append(files, File{
+				Source:     s.Name(),
+				Ref:        strconv.FormatUint(uint64(uid), 10),
+				Name:       attachment.Name,
+				Sender:     msg.Sender,
+				Data:       attachment.Data,
+				ReceivedAt: receivedAt,
+			})
+		}
+	}
+	return files, nil
+}
+
+// Ack marks the email a file was attached to as seen.
+func (s *IMAPSource) Ack(_ context.Context, file File) error {
+	if s.client == nil {
+		return errors.New("imap source is not open")
+	}
+	uid, err := strconv.ParseUint(file.Ref, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid imap file reference %q", file.Ref)
+	}
+	return s.client.markSeen(uint32(uid))
+}
+
+// Close logs out of the mailbox.
+func (s *IMAPSource) Close() error {
+	if s.client == nil {
+		return nil
+	}
+	client := s.client
+	s.client = nil
+	_, _ = client.command("LOGOUT")
+	return client.conn.Close()
+}
+
+// imapClient speaks the subset of IMAP4rev1 (RFC 3501) needed to read a mailbox.
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapResponse is a response line, with the literals that were sent inline with it.
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+func newIMAPClient(conn net.Conn) (*imapClient, error) {
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readResponse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting.line, "* OK") && !strings.HasPrefix(greeting.line, "* PREAUTH") {
+		return nil, fmt.Errorf("unexpected imap greeting: %s", greeting.line)
+	}
+	return c, nil
+}
+
+// command sends a command and returns the untagged responses to it. It fails unless the server completes the
+// command with OK.
+func (c *imapClient) command(format string, args ...interface{}) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("A%03d", c.tag)
+	command := fmt.Sprintf(format, args...)
+	if _, err := io.WriteString(c.conn, tag+" "+command+"\r\n"); err != nil {
+		return nil, fmt.Errorf("failed to send imap command: %w", err)
+	}
+
+	verb, _, _ := strings.Cut(command, " ")
+	var untagged []imapResponse
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read imap response: %w", err)
+		}
+		if status, ok := strings.CutPrefix(resp.line, tag+" "); ok {
+			if !strings.HasPrefix(status, "OK") {
+				return nil, fmt.Errorf("imap %s failed: %s", verb, status)
+			}
+			return untagged, nil
+		}
+		untagged = append(untagged, resp)
+	}
+}
+
+// readResponse reads a response line. A line ending in {n} is followed by a literal of n bytes and then the
+// rest of the line.
+func (c *imapClient) readResponse() (imapResponse, error) {
+	var resp imapResponse
+	var line strings.Builder
+	for {
+		text, err := c.r.ReadString('\n')
+		if err != nil {
+			return resp, err
+		}
+		text = strings.TrimRight(text, "\r\n")
+		line.WriteString(text)
+
+		size, ok := literalSize(text)
+		if !ok {
+			resp.line = line.String()
+			return resp, nil
+		}
+		if size > maxFileSize {
+			return resp, fmt.Errorf("imap literal of %d bytes exceeds %d bytes", size, maxFileSize)
+		}
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return resp, err
+		}
+		resp.literals = append(resp.literals, literal)
+	}
+}
+
+// literalSize returns the size of the literal announced at the end of a line.
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	start := strings.LastIndexByte(line, '{')
+	if start < 0 {
+		return 0, false
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(line[start+1:len(line)-1], "+"))
+	if err != nil || size < 0 {
+		return 0, false
+	}
+	return size, true
+}
+
+func (c *imapClient) login(username, password string) error {
+	_, err := c.command("LOGIN %s %s", quoteIMAP(username), quoteIMAP(password))
+	return err
+}
+
+func (c *imapClient) selectMailbox(mailbox string) error {
+	_, err := c.command("SELECT %s", quoteIMAP(mailbox))
+	return err
+}
+
+// searchUnseen returns the UIDs of the unseen emails in the selected mailbox.
+func (c *imapClient) searchUnseen() ([]uint32, error) {
+	responses, err := c.command("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, resp := range responses {
+		rest, ok := strings.CutPrefix(resp.line, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Fields(rest) {
+			uid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid uid %q in imap search response", field)
+			}
+			uids = append(uids, uint32(uid))
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns the raw email with a UID without marking it seen.
+func (c *imapClient) fetch(uid uint32) ([]byte, error) {
+	responses, err := c.command("UID FETCH %d BODY.PEEK[]", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, resp := range responses {
+		if strings.Contains(resp.line, "FETCH") && len(resp.literals) > 0 {
+			return resp.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap server returned no body for uid %d", uid)
+}
+
+func (c *imapClient) markSeen(uid uint32) error {
+	_, err := c.command(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid)
+	return err
+}
+
+// quoteIMAP quotes a string for use as an IMAP quoted string.
+func quoteIMAP(s string) string {
+	s = strings.NewReplacer("\r", "", "\n", "").Replace(s)
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ingestion collects statement files from the dead drops counterparties deliver them to: a mailbox
+// read over IMAP and a folder read over SFTP.
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// maxFileSize bounds the size of a single statement file, so that a misdirected upload cannot exhaust memory.
+const maxFileSize = 32 << 20
+
+// File is a statement file collected from a source.
+type File struct {
+	Source     string    // The name of the source the file was collected from
+	Ref        string    // Identifies the file within its source, used to acknowledge it
+	Name       string    // The name of the file
+	Sender     string    // The address the file was emailed from, empty for files not received by email
+	Data       []byte    // The contents of the file
+	ReceivedAt time.Time // When the source received the file
+}
+
+// Source is a dead drop statement files are collected from. A source is opened by Fetch and stays open until
+// Close, so that the files it returned can be acknowledged.
+type Source interface {
+	// Name returns the name of the source, e.g. "imap".
+	Name() string
+	// Fetch returns the files that have not been acknowledged yet.
+	Fetch(ctx context.Context) ([]File, error)
+	// Ack marks a file as processed so that it is not returned again.
+	Ack(ctx context.Context, file File) error
+	// Close releases the connection to the source.
+	Close() error
+}
+
+// Message is an email parsed by ParseMessage.
+type Message struct {
+	Sender      string
+	ReceivedAt  time.Time
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to an email.
+type Attachment struct {
+	Name string
+	Data []byte
+}
+
+// ParseMessage parses a raw email and extracts its sender and attachments. Parts without a filename, such as
+// the text of the email, are skipped.
+//
+// Parameters:
+// - raw: The email in RFC 5322 format.
+//
+// Returns:
+// - *Message: The sender, date and attachments of the email.
+// - error: An error if the email cannot be parsed.
+func ParseMessage(raw []byte) (*Message, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email: %w", err)
+	}
+
+	parsed := &Message{}
+	if from, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+		parsed.Sender = strings.ToLower(from.Address)
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		parsed.ReceivedAt = date
+	}
+
+	parsed.Attachments, err = extractAttachments(msg.Header, msg.Body, 0)
+	if err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// partHeader is satisfied by both the header of an email and the headers of its parts.
+type partHeader interface {
+	Get(key string) string
+}
+
+// extractAttachments walks a MIME entity and returns the files attached to it. Nested multiparts are walked
+// up to a small depth.
+func extractAttachments(header partHeader, body io.Reader, depth int) ([]Attachment, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth > 4 {
+			return nil, errors.New("email is nested too deeply")
+		}
+		reader := multipart.NewReader(body, params["boundary"])
+		var attachments []Attachment
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return attachments, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read email part: %w", err)
+			}
+			nested, err := extractAttachments(part.Header, part, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			attachments = append(attachments, nested...)
+		}
+	}
+
+	name := attachmentName(header, params)
+	if name == "" {
+		return nil, nil
+	}
+	// Parts of a multipart are decoded from quoted-printable by the multipart reader, but not a message
+	// that is a single part
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment %s: %w", name, err)
+	}
+	if len(data) > maxFileSize {
+		return nil, fmt.Errorf("attachment %s exceeds %d bytes", name, maxFileSize)
+	}
+	return []Attachment{{Name: name, Data: data}}, nil
+}
+
+// attachmentName returns the filename of a part from its Content-Disposition, falling back to the name
+// parameter of its Content-Type.
+func attachmentName(header partHeader, contentTypeParams map[string]string) string {
+	name := ""
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	if name == "" {
+		name = contentTypeParams["name"]
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(name); err == nil {
+		name = decoded
+	}
+	// Only the base name is kept, as the sender chooses the name
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSpace(name)
+}
+
+// deadline applies the deadline of ctx, if any, to a connection.
+func deadline(ctx context.Context, set func(time.Time) error) {
+	if d, ok := ctx.Deadline(); ok {
+		_ = set(d)
+	}
+}
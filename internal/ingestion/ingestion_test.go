@@ -0,0 +1,336 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingestion
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStatement = "ID,Amount,Date\r\n1,10.50,2026-01-02T00:00:00Z\r\n"
+
+func testEmail(from string) string {
+	return "From: Bank Statements <" + from + ">\r\n" +
+		"Date: Fri, 02 Jan 2026 08:00:00 +0000\r\n" +
+		"Subject: Daily statement\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=outer\r\n" +
+		"\r\n" +
+		"--outer\r\n" +
+		"Content-Type: multipart/alternative; boundary=inner\r\n" +
+		"\r\n" +
+		"--inner\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"Your statement is attached.\r\n" +
+		"--inner--\r\n" +
+		"--outer\r\n" +
+		"Content-Type: text/csv; name=\"ignored.csv\"\r\n" +
+		"Content-Disposition: attachment; filename=\"statement-2026-01-02.csv\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"SUQsQW1vdW50LERhdGUNCjEsMTAuNTAsMjAy\r\n" +
+		"Ni0wMS0wMlQwMDowMDowMFoNCg==\r\n" +
+		"--outer\r\n" +
+		"Content-Type: text/csv\r\n" +
+		"Content-Disposition: attachment; filename=\"=?utf-8?q?fees_=C3=A9.csv?=\"\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"\r\n" +
+		"ID,Amount,Date=\r\n" +
+		"\r\n" +
+		"--outer--\r\n"
+}
+
+func TestParseMessage_ExtractsAttachments(t *testing.T) {
+	msg, err := ParseMessage([]byte(testEmail("Statements@Bank.example")))
+	require.NoError(t, err)
+
+	assert.Equal(t, "statements@bank.example", msg.Sender)
+	assert.Equal(t, 2026, msg.ReceivedAt.Year())
+	require.Len(t, msg.Attachments, 2)
+	assert.Equal(t, "statement-2026-01-02.csv", msg.Attachments[0].Name)
+	assert.Equal(t, testStatement, string(msg.Attachments[0].Data))
+	assert.Equal(t, "fees é.csv", msg.Attachments[1].Name)
+	assert.Equal(t, "ID,Amount,Date", string(msg.Attachments[1].Data))
+}
+
+func TestParseMessage_StripsDirectoriesFromNames(t *testing.T) {
+	raw := "From: a@bank.example\r\n" +
+		"Content-Type: text/csv\r\n" +
+		"Content-Disposition: attachment; filename=\"../../etc/statement.csv\"\r\n" +
+		"\r\n" +
+		testStatement
+	msg, err := ParseMessage([]byte(raw))
+	require.NoError(t, err)
+	require.Len(t, msg.Attachments, 1)
+	assert.Equal(t, "statement.csv", msg.Attachments[0].Name)
+}
+
+// fakeIMAPServer serves a mailbox of unseen emails keyed by UID and records the UIDs marked seen.
+func fakeIMAPServer(conn net.Conn, emails map[uint32]string, seen chan<- uint32) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK IMAP4rev1 ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, command, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		switch {
+		case strings.HasPrefix(command, "LOGIN"):
+			if command != `LOGIN "reader" "p\"ss"` {
+				fmt.Fprintf(conn, "%s NO invalid credentials\r\n", tag)
+				continue
+			}
+		case strings.HasPrefix(command, "UID SEARCH UNSEEN"):
+			var uids []string
+			for uid := range emails {
+				uids = append(uids, fmt.Sprint(uid))
+			}
+			sort.Strings(uids)
+			fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(uids, " "))
+		case strings.HasPrefix(command, "UID FETCH"):
+			var uid uint32
+			fmt.Sscanf(command, "UID FETCH %d", &uid)
+			body := emails[uid]
+			fmt.Fprintf(conn, "* %d FETCH (UID %d BODY[] {%d}\r\n%s)\r\n", uid, uid, len(body), body)
+		case strings.HasPrefix(command, "UID STORE"):
+			var uid uint32
+			fmt.Sscanf(command, "UID STORE %d", &uid)
+			seen <- uid
+		case command == "LOGOUT":
+			fmt.Fprintf(conn, "* BYE\r\n%s OK LOGOUT completed\r\n", tag)
+			return
+		}
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+	}
+}
+
+func TestIMAPSource_FetchesAttachmentsAndMarksSeen(t *testing.T) {
+	emails := map[uint32]string{
+		7: testEmail("statements@bank.example"),
+		9: "From: someone@example.com\r\nSubject: hello\r\n\r\nNo attachments here.\r\n",
+	}
+	seen := make(chan uint32, 10)
+
+	source := NewIMAPSource(config.IMAPConfig{Username: "reader", Password: `p"ss`, Mailbox: "INBOX"})
+	source.dial = func(ctx context.Context) (net.Conn, error) {
+		client, server := net.Pipe()
+		go fakeIMAPServer(server, emails, seen)
+		return client, nil
+	}
+
+	files, err := source.Fetch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "imap", files[0].Source)
+	assert.Equal(t, "7", files[0].Ref)
+	assert.Equal(t, "statements@bank.example", files[0].Sender)
+	assert.Equal(t, testStatement, string(files[0].Data))
+	// The email without attachments is marked seen straight away
+	assert.Equal(t, uint32(9), <-seen)
+
+	require.NoError(t, source.Ack(context.Background(), files[0]))
+	assert.Equal(t, uint32(7), <-seen)
+	require.NoError(t, source.Close())
+}
+
+func TestIMAPSource_FailedLogin(t *testing.T) {
+	source := NewIMAPSource(config.IMAPConfig{Username: "reader", Password: "wrong", Mailbox: "INBOX"})
+	source.dial = func(ctx context.Context) (net.Conn, error) {
+		client, server := net.Pipe()
+		go fakeIMAPServer(server, nil, nil)
+		return client, nil
+	}
+
+	_, err := source.Fetch(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "imap LOGIN failed")
+	assert.NotContains(t, err.Error(), "wrong")
+	require.NoError(t, source.Close())
+}
+
+// fakeSFTPServer serves an in-memory file system over version 3 of the SFTP protocol.
+type fakeSFTPServer struct {
+	files   map[string][]byte
+	dirs    map[string]bool
+	handles map[string]string
+	listed  map[string]bool
+}
+
+func (s *fakeSFTPServer) serve(r io.Reader, w io.Writer) {
+	client := &sftpClient{r: r, w: w}
+	for {
+		typ, payload, err := client.readPacket()
+		if err != nil {
+			return
+		}
+		buf := sftpBuffer(payload)
+		if typ == sftpInit {
+			_ = client.writePacket(sftpVersion, binary.BigEndian.AppendUint32(nil, 3))
+			continue
+		}
+		id, _ := buf.uint32()
+		respond := func(typ byte, body []byte) {
+			_ = client.writePacket(typ, append(binary.BigEndian.AppendUint32(nil, id), body...))
+		}
+		status := func(code uint32) {
+			respond(sftpStatus, appendSFTPString(appendSFTPString(binary.BigEndian.AppendUint32(nil, code), "status"), ""))
+		}
+
+		switch typ {
+		case sftpOpendir, sftpOpen:
+			name, _ := buf.string()
+			handle := fmt.Sprintf("h%d", len(s.handles))
+			s.handles[handle] = name
+			respond(sftpHandle, appendSFTPString(nil, handle))
+		case sftpReaddir:
+			handle, _ := buf.string()
+			dir := s.handles[handle]
+			if s.listed[handle] {
+				status(sftpEOF)
+				continue
+			}
+			s.listed[handle] = true
+			var names []string
+			for name := range s.files {
+				if path.Dir(name) == dir {
+					names = append(names, name)
+				}
+			}
+			for name := range s.dirs {
+				if path.Dir(name) == dir {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			body := binary.BigEndian.AppendUint32(nil, uint32(len(names)))
+			for _, name := range names {
+				mode := uint32(0o100644)
+				if s.dirs[name] {
+					mode = 0o40755
+				}
+				body = appendSFTPString(body, path.Base(name))
+				body = appendSFTPString(body, "")
+				body = binary.BigEndian.AppendUint32(body, sftpAttrSize|sftpAttrPermissions|sftpAttrACModTime)
+				body = binary.BigEndian.AppendUint64(body, uint64(len(s.files[name])))
+				body = binary.BigEndian.AppendUint32(body, mode)
+				body = binary.BigEndian.AppendUint32(body, 1767312000)
+				body = binary.BigEndian.AppendUint32(body, 1767312000)
+			}
+			respond(sftpName, body)
+		case sftpRead:
+			handle, _ := buf.string()
+			offset, _ := buf.uint64()
+			length, _ := buf.uint32()
+			data := s.files[s.handles[handle]]
+			if offset >= uint64(len(data)) {
+				status(sftpEOF)
+				continue
+			}
+			end := min(offset+uint64(length), uint64(len(data)))
+			respond(sftpData, appendSFTPString(nil, string(data[offset:end])))
+		case sftpStat:
+			name, _ := buf.string()
+			if !s.dirs[name] {
+				status(sftpNoSuch)
+				continue
+			}
+			respond(sftpAttrs, binary.BigEndian.AppendUint32(nil, 0))
+		case sftpMkdir:
+			name, _ := buf.string()
+			s.dirs[name] = true
+			status(sftpOK)
+		case sftpRename:
+			from, _ := buf.string()
+			to, _ := buf.string()
+			s.files[to] = s.files[from]
+			delete(s.files, from)
+			status(sftpOK)
+		default:
+			status(sftpOK)
+		}
+	}
+}
+
+func TestSFTPClient_ReadsAndMovesFiles(t *testing.T) {
+	large := strings.Repeat("x", sftpChunkSize+10)
+	server := &fakeSFTPServer{
+		files: map[string][]byte{
+			"/drop/statement.csv": []byte(testStatement),
+			"/drop/large.csv":     []byte(large),
+			"/drop/.partial":      []byte("incomplete"),
+		},
+		dirs:    map[string]bool{"/drop/archive": true},
+		handles: map[string]string{},
+		listed:  map[string]bool{},
+	}
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	go server.serve(serverReader, serverWriter)
+
+	client, err := newSFTPClient(clientReader, clientWriter)
+	require.NoError(t, err)
+	source := &SFTPSource{cfg: config.SFTPConfig{Directory: "/drop", ProcessedDirectory: "/drop/processed/2026"}, client: client}
+
+	entries, err := client.readDir("/drop")
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	for _, entry := range entries {
+		assert.Equal(t, entry.name != "archive", entry.regular, entry.name)
+	}
+
+	data, err := client.readFile("/drop/large.csv")
+	require.NoError(t, err)
+	assert.Equal(t, large, string(data))
+
+	data, err = client.readFile("/drop/statement.csv")
+	require.NoError(t, err)
+	assert.Equal(t, testStatement, string(data))
+
+	require.NoError(t, source.Ack(context.Background(), File{Ref: "/drop/statement.csv"}))
+	assert.True(t, server.dirs["/drop/processed"])
+	assert.True(t, server.dirs["/drop/processed/2026"])
+	assert.NotContains(t, server.files, "/drop/statement.csv")
+	var moved []string
+	for name := range server.files {
+		if path.Dir(name) == "/drop/processed/2026" {
+			moved = append(moved, path.Base(name))
+		}
+	}
+	require.Len(t, moved, 1)
+	assert.True(t, strings.HasSuffix(moved[0], "-statement.csv"))
+}
+
+func TestSFTPSource_RequiresHostKey(t *testing.T) {
+	_, err := NewSFTPSource(config.SFTPConfig{Host: "localhost", Port: 22}).Fetch(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "host key is not configured")
+}
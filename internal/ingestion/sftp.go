@@ -0,0 +1,566 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingestion
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTP packet types and status codes, from version 3 of the protocol
+// (draft-ietf-secsh-filexfer-02).
+const (
+	sftpInit     = 1
+	sftpVersion  = 2
+	sftpOpen     = 3
+	sftpClose    = 4
+	sftpRead     = 5
+	sftpOpendir  = 11
+	sftpReaddir  = 12
+	sftpMkdir    = 14
+	sftpStat     = 17
+	sftpRename   = 18
+	sftpStatus   = 101
+	sftpHandle   = 102
+	sftpData     = 103
+	sftpName     = 104
+	sftpAttrs    = 105
+	sftpOK       = 0
+	sftpEOF      = 1
+	sftpNoSuch   = 2
+	sftpReadFlag = 0x1
+
+	sftpAttrSize        = 0x1
+	sftpAttrUIDGID      = 0x2
+	sftpAttrPermissions = 0x4
+	sftpAttrACModTime   = 0x8
+	sftpAttrExtended    = 0x80000000
+
+	sftpChunkSize = 32 << 10
+)
+
+// SFTPSource collects the files in a directory of an SFTP server. Files are moved to the processed directory
+// when they are acknowledged, or left in place when there is none.
+type SFTPSource struct {
+	cfg     config.SFTPConfig
+	conn    *ssh.Client
+	session *ssh.Session
+	client  *sftpClient
+}
+
+// NewSFTPSource returns a source reading the directory described by cfg.
+func NewSFTPSource(cfg config.SFTPConfig) *SFTPSource {
+	return &SFTPSource{cfg: cfg}
+}
+
+// Name returns "sftp".
+func (s *SFTPSource) Name() string {
+	return "sftp"
+}
+
+// Fetch connects to the server and returns the regular files in the directory, skipping hidden files.
+func (s *SFTPSource) Fetch(ctx context.Context) ([]File, error) {
+	if s.client != nil {
+		return nil, errors.New("sftp source is already open")
+	}
+	if err := s.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.client.readDir(s.cfg.Directory)
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	for _, entry := range entries {
+		if !entry.regular || strings.HasPrefix(entry.name, ".") {
+			continue
+		}
+		if entry.size > maxFileSize {
+			return nil, fmt.Errorf("file %s exceeds %d bytes", entry.name, maxFileSize)
+		}
+		filePath := path.Join(s.cfg.Directory, entry.name)
+		data, err := s.client.readFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		receivedAt := entry.modTime
+		if receivedAt.IsZero() {
+			receivedAt = time.Now()
+		}
+		files = append(files, File{
+			Source:     s.Name(),
+			Ref:        filePath,
+			Name:       entry.name,
+			Data:       data,
+			ReceivedAt: receivedAt,
+		})
+	}
+	return files, nil
+}
+
+// Ack moves a file to the processed directory. The name is prefixed with the time so that counterparties
+// can reuse file names.
+func (s *SFTPSource) Ack(_ context.Context, file File) error {
+	if s.client == nil {
+		return errors.New("sftp source is not open")
+	}
+	if s.cfg.ProcessedDirectory == "" {
+		return nil
+	}
+	if err := s.client.mkdirAll(s.cfg.ProcessedDirectory); err != nil {
+		return err
+	}
+	target := path.Join(s.cfg.ProcessedDirectory, time.Now().UTC().Format("20060102T150405")+"-"+path.Base(file.Ref))
+	return s.client.rename(file.Ref, target)
+}
+
+// Close disconnects from the server.
+func (s *SFTPSource) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	_ = s.session.Close()
+	err := s.conn.Close()
+	s.conn, s.session, s.client = nil, nil, nil
+	return err
+}
+
+// connect opens an SSH connection and starts the sftp subsystem on it. The server must present the
+// configured host key.
+func (s *SFTPSource) connect(ctx context.Context) error {
+	if s.cfg.HostKey == "" {
+		return errors.New("sftp host key is not configured")
+	}
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(s.cfg.HostKey))
+	if err != nil {
+		return fmt.Errorf("invalid sftp host key: %w", err)
+	}
+
+	var auth []ssh.AuthMethod
+	if s.cfg.PrivateKeyFile != "" {
+		key, err := os.ReadFile(s.cfg.PrivateKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read sftp private key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return fmt.Errorf("failed to parse sftp private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if s.cfg.Password != "" {
+		auth = append(auth, ssh.Password(s.cfg.Password))
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to sftp server: %w", err)
+	}
+	deadline(ctx, netConn.SetDeadline)
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, &ssh.ClientConfig{
+		User:            s.cfg.Username,
+		Auth:            auth,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         30 * time.Second,
+	})
+	if err != nil {
+		_ = netConn.Close()
+		return fmt.Errorf("failed to connect to sftp server: %w", err)
+	}
+	conn := ssh.NewClient(sshConn, chans, reqs)
+
+	session, err := conn.NewSession()
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to open sftp session: %w", err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to start sftp subsystem: %w", err)
+	}
+	client, err := newSFTPClient(stdout, stdin)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	s.conn, s.session, s.client = conn, session, client
+	return nil
+}
+
+// sftpClient speaks the subset of version 3 of the SFTP protocol needed to read and move files. Requests are
+// sent one at a time.
+type sftpClient struct {
+	r      io.Reader
+	w      io.Writer
+	nextID uint32
+}
+
+// sftpEntry is a directory entry returned by readDir.
+type sftpEntry struct {
+	name    string
+	regular bool
+	size    uint64
+	modTime time.Time
+}
+
+func newSFTPClient(r io.Reader, w io.Writer) (*sftpClient, error) {
+	c := &sftpClient{r: r, w: w}
+	if err := c.writePacket(sftpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+		return nil, err
+	}
+	typ, _, err := c.readPacket()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise sftp: %w", err)
+	}
+	if typ != sftpVersion {
+		return nil, fmt.Errorf("unexpected sftp packet type %d during initialisation", typ)
+	}
+	return c, nil
+}
+
+func (c *sftpClient) writePacket(typ byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	packet = append(packet, typ)
+	packet = append(packet, payload...)
+	_, err := c.w.Write(packet)
+	return err
+}
+
+func (c *sftpClient) readPacket() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length == 0 || length > maxFileSize {
+		return 0, nil, fmt.Errorf("invalid sftp packet length %d", length)
+	}
+	payload := make([]byte, length-1)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[4], payload, nil
+}
+
+// request sends a request and returns the type and payload of the response, without the request ID.
+func (c *sftpClient) request(typ byte, payload []byte) (byte, []byte, error) {
+	c.nextID++
+	id := c.nextID
+	if err := c.writePacket(typ, append(binary.BigEndian.AppendUint32(nil, id), payload...)); err != nil {
+		return 0, nil, fmt.Errorf("failed to send sftp request: %w", err)
+	}
+	respType, resp, err := c.readPacket()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read sftp response: %w", err)
+	}
+	buf := sftpBuffer(resp)
+	respID, err := buf.uint32()
+	if err != nil {
+		return 0, nil, err
+	}
+	if respID != id {
+		return 0, nil, fmt.Errorf("sftp response %d does not match request %d", respID, id)
+	}
+	return respType, buf, nil
+}
+
+// statusError turns a status response into an error, returning nil for OK.
+func statusError(typ byte, payload []byte, op, target string) error {
+	if typ != sftpStatus {
+		return fmt.Errorf("sftp %s %s: unexpected response type %d", op, target, typ)
+	}
+	buf := sftpBuffer(payload)
+	code, err := buf.uint32()
+	if err != nil {
+		return err
+	}
+	if code == sftpOK {
+		return nil
+	}
+	message, _ := buf.string()
+	return &sftpStatusError{op: op, target: target, code: code, message: message}
+}
+
+type sftpStatusError struct {
+	op      string
+	target  string
+	code    uint32
+	message string
+}
+
+func (e *sftpStatusError) Error() string {
+	return fmt.Sprintf("sftp %s %s failed with status %d: %s", e.op, e.target, e.code, e.message)
+}
+
+func hasStatus(err error, code uint32) bool {
+	var statusErr *sftpStatusError
+	return errors.As(err, &statusErr) && statusErr.code == code
+}
+
+// openHandle sends a request that returns a handle.
+func (c *sftpClient) openHandle(typ byte, payload []byte, op, target string) (string, error) {
+	respType, resp, err := c.request(typ, payload)
+	if err != nil {
+		return "", err
+	}
+	if respType != sftpHandle {
+		return "", statusError(respType, resp, op, target)
+	}
+	buf := sftpBuffer(resp)
+	return buf.string()
+}
+
+func (c *sftpClient) closeHandle(handle string) {
+	_, _, _ = c.request(sftpClose, appendSFTPString(nil, handle))
+}
+
+func (c *sftpClient) readDir(dir string) ([]sftpEntry, error) {
+	handle, err := c.openHandle(sftpOpendir, appendSFTPString(nil, dir), "opendir", dir)
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeHandle(handle)
+
+	var entries []sftpEntry
+	for {
+		respType, resp, err := c.request(sftpReaddir, appendSFTPString(nil, handle))
+		if err != nil {
+			return nil, err
+		}
+		if respType != sftpName {
+			if err := statusError(respType, resp, "readdir", dir); err != nil && !hasStatus(err, sftpEOF) {
+				return nil, err
+			}
+			return entries, nil
+		}
+
+		buf := sftpBuffer(resp)
+		count, err := buf.uint32()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < count; i++ {
+			name, err := buf.string()
+			if err != nil {
+				return nil, err
+			}
+			longName, err := buf.string()
+			if err != nil {
+				return nil, err
+			}
+			entry, err := buf.attrs()
+			if err != nil {
+				return nil, err
+			}
+			entry.name = name
+			if !entry.hasMode {
+				entry.regular = strings.HasPrefix(longName, "-")
+			}
+			entries = append(entries, entry.sftpEntry)
+		}
+	}
+}
+
+func (c *sftpClient) readFile(filePath string) ([]byte, error) {
+	payload := appendSFTPString(nil, filePath)
+	payload = binary.BigEndian.AppendUint32(payload, sftpReadFlag)
+	payload = binary.BigEndian.AppendUint32(payload, 0) // No attributes
+	handle, err := c.openHandle(sftpOpen, payload, "open", filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeHandle(handle)
+
+	var data []byte
+	for {
+		payload := appendSFTPString(nil, handle)
+		payload = binary.BigEndian.AppendUint64(payload, uint64(len(data)))
+		payload = binary.BigEndian.AppendUint32(payload, sftpChunkSize)
+		respType, resp, err := c.request(sftpRead, payload)
+		if err != nil {
+			return nil, err
+		}
+		if respType != sftpData {
+			if err := statusError(respType, resp, "read", filePath); err != nil && !hasStatus(err, sftpEOF) {
+				return nil, err
+			}
+			return data, nil
+		}
+		buf := sftpBuffer(resp)
+		chunk, err := buf.string()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+		if len(data) > maxFileSize {
+			return nil, fmt.Errorf("file %s exceeds %d bytes", filePath, maxFileSize)
+		}
+	}
+}
+
+func (c *sftpClient) rename(from, to string) error {
+	respType, resp, err := c.request(sftpRename, appendSFTPString(appendSFTPString(nil, from), to))
+	if err != nil {
+		return err
+	}
+	return statusError(respType, resp, "rename", from)
+}
+
+// mkdirAll creates a directory and its parents unless they exist.
+func (c *sftpClient) mkdirAll(dir string) error {
+	respType, resp, err := c.request(sftpStat, appendSFTPString(nil, dir))
+	if err != nil {
+		return err
+	}
+	if respType == sftpAttrs {
+		return nil
+	}
+	if err := statusError(respType, resp, "stat", dir); !hasStatus(err, sftpNoSuch) {
+		return err
+	}
+	if parent := path.Dir(dir); parent != dir && parent != "." && parent != "/" {
+		if err := c.mkdirAll(parent); err != nil {
+			return err
+		}
+	}
+	respType, resp, err = c.request(sftpMkdir, binary.BigEndian.AppendUint32(appendSFTPString(nil, dir), 0))
+	if err != nil {
+		return err
+	}
+	return statusError(respType, resp, "mkdir", dir)
+}
+
+func appendSFTPString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// sftpBuffer reads the fields of an SFTP packet.
+type sftpBuffer []byte
+
+var errShortSFTPPacket = errors.New("sftp packet is too short")
+
+func (b *sftpBuffer) uint32() (uint32, error) {
+	if len(*b) < 4 {
+		return 0, errShortSFTPPacket
+	}
+	v := binary.BigEndian.Uint32(*b)
+	*b = (*b)[4:]
+	return v, nil
+}
+
+func (b *sftpBuffer) uint64() (uint64, error) {
+	if len(*b) < 8 {
+		return 0, errShortSFTPPacket
+	}
+	v := binary.BigEndian.Uint64(*b)
+	*b = (*b)[8:]
+	return v, nil
+}
+
+func (b *sftpBuffer) string() (string, error) {
+	n, err := b.uint32()
+	if err != nil {
+		return "", err
+	}
+	if uint32(len(*b)) < n {
+		return "", errShortSFTPPacket
+	}
+	s := string((*b)[:n])
+	*b = (*b)[n:]
+	return s, nil
+}
+
+// parsedAttrs is a directory entry parsed from file attributes.
+type parsedAttrs struct {
+	sftpEntry
+	hasMode bool
+}
+
+// attrs reads a file attributes structure.
+func (b *sftpBuffer) attrs() (parsedAttrs, error) {
+	var parsed parsedAttrs
+	flags, err := b.uint32()
+	if err != nil {
+		return parsed, err
+	}
+	if flags&sftpAttrSize != 0 {
+		if parsed.size, err = b.uint64(); err != nil {
+			return parsed, err
+		}
+	}
+	if flags&sftpAttrUIDGID != 0 {
+		if _, err := b.uint64(); err != nil {
+			return parsed, err
+		}
+	}
+	if flags&sftpAttrPermissions != 0 {
+		mode, err := b.uint32()
+		if err != nil {
+			return parsed, err
+		}
+		parsed.hasMode = true
+		parsed.regular = mode&0o170000 == 0o100000
+	}
+	if flags&sftpAttrACModTime != 0 {
+		if _, err := b.uint32(); err != nil {
+			return parsed, err
+		}
+		mtime, err := b.uint32()
+		if err != nil {
+			return parsed, err
+		}
+		parsed.modTime = time.Unix(int64(mtime), 0).UTC()
+	}
+	if flags&sftpAttrExtended != 0 {
+		count, err := b.uint32()
+		if err != nil {
+			return parsed, err
+		}
+		for i := uint32(0); i < 2*count; i++ {
+			if _, err := b.string(); err != nil {
+				return parsed, err
+			}
+		}
+	}
+	return parsed, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ledgerscope carries the ledgers and balances a request may touch through a context.
+// Requests authenticated with an API key restricted to specific ledgers or balance ID prefixes
+// carry a Scope; unrestricted requests carry none.
+package ledgerscope
+
+import (
+	"context"
+	"strings"
+)
+
+// Scope lists the ledger IDs and balance ID prefixes a caller may access. A balance is in scope
+// when its ledger is allowed or its ID starts with an allowed prefix.
+type Scope struct {
+	LedgerIDs       []string `json:"ledger_ids"`
+	BalancePrefixes []string `json:"balance_prefixes"`
+}
+
+type contextKey struct{}
+
+// WithScope returns a context that carries the scope. Unrestricted scopes are not stored.
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	if !scope.Restricted() {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, scope)
+}
+
+// FromContext returns the scope carried by the context and whether there is one.
+func FromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(contextKey{}).(Scope)
+	return scope, ok
+}
+
+// Restricted reports whether the scope limits access at all.
+func (s Scope) Restricted() bool {
+	return len(s.LedgerIDs) > 0 || len(s.BalancePrefixes) > 0
+}
+
+// AllowsLedger reports whether the ledger is in scope.
+func (s Scope) AllowsLedger(ledgerID string) bool {
+	if !s.Restricted() {
+		return true
+	}
+	for _, id := range s.LedgerIDs {
+		if id == ledgerID {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsBalance reports whether a balance in the given ledger is in scope.
+func (s Scope) AllowsBalance(balanceID, ledgerID string) bool {
+	if s.AllowsLedger(ledgerID) {
+		return true
+	}
+	for _, prefix := range s.BalancePrefixes {
+		if prefix != "" && strings.HasPrefix(balanceID, prefix) {
+			return true
+		}
+	}
+	return false
+}
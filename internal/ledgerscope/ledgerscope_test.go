@@ -0,0 +1,46 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledgerscope
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScope(t *testing.T) {
+	scope := Scope{LedgerIDs: []string{"ldg_cards"}, BalancePrefixes: []string{"bln_wallet_"}}
+
+	assert.True(t, scope.Restricted())
+	assert.True(t, scope.AllowsLedger("ldg_cards"))
+	assert.False(t, scope.AllowsLedger("ldg_loans"))
+	assert.True(t, scope.AllowsBalance("bln_123", "ldg_cards"))
+	assert.True(t, scope.AllowsBalance("bln_wallet_9", "ldg_loans"))
+	assert.False(t, scope.AllowsBalance("bln_123", "ldg_loans"))
+
+	assert.True(t, Scope{}.AllowsBalance("bln_123", "ldg_loans"))
+}
+
+func TestWithScope(t *testing.T) {
+	_, ok := FromContext(WithScope(context.Background(), Scope{}))
+	assert.False(t, ok)
+
+	scope, ok := FromContext(WithScope(context.Background(), Scope{LedgerIDs: []string{"ldg_cards"}}))
+	assert.True(t, ok)
+	assert.Equal(t, []string{"ldg_cards"}, scope.LedgerIDs)
+}
@@ -23,7 +23,9 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/blnkfinance/blnk/internal/egress"
 	"github.com/blnkfinance/blnk/internal/request"
+	"github.com/blnkfinance/blnk/internal/resilience"
 	"github.com/sirupsen/logrus"
 
 	"github.com/blnkfinance/blnk/config"
@@ -91,9 +93,17 @@ func SlackNotification(err error) {
 		return
 	}
 
+	// Slack is reached through the egress client, so notifications leave through the same proxy and trust the
+	// same CA bundle as webhooks
+	client, err := egress.NewHTTPClient(conf.Notification.Webhook.Egress)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
 	// Send the request and handle the response
 	var response map[string]interface{}
-	_, err = request.Call(req, &response)
+	_, err = request.CallWith(resilience.Get(resilience.Slack).Wrap(client), req, &response)
 	if err != nil {
 		log.Println(err)
 	}
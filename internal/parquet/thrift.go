@@ -0,0 +1,122 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Types of the Thrift compact protocol used by the structures written.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftEncoder writes structures in the Thrift compact protocol, which Parquet page headers and file metadata
+// are encoded with. Fields must be written in increasing order of their IDs within each structure.
+type thriftEncoder struct {
+	buf     bytes.Buffer
+	lastIDs []int16
+	lastID  int16
+}
+
+func (e *thriftEncoder) fieldHeader(id int16, typ byte) {
+	if delta := id - e.lastID; delta > 0 && delta <= 15 {
+		e.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		e.buf.WriteByte(typ)
+		e.varint(int64(id))
+	}
+	e.lastID = id
+}
+
+func (e *thriftEncoder) uvarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	e.buf.Write(tmp[:n])
+}
+
+// varint writes a zigzag encoded integer.
+func (e *thriftEncoder) varint(v int64) {
+	e.uvarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (e *thriftEncoder) i32(id int16, v int32) {
+	e.fieldHeader(id, thriftI32)
+	e.varint(int64(v))
+}
+
+func (e *thriftEncoder) i64(id int16, v int64) {
+	e.fieldHeader(id, thriftI64)
+	e.varint(v)
+}
+
+func (e *thriftEncoder) string(id int16, v string) {
+	e.fieldHeader(id, thriftBinary)
+	e.uvarint(uint64(len(v)))
+	e.buf.WriteString(v)
+}
+
+// beginStruct starts a structure in field id. A field ID of 0 starts a structure that is an element of a list.
+func (e *thriftEncoder) beginStruct(id int16) {
+	if id != 0 {
+		e.fieldHeader(id, thriftStruct)
+	}
+	e.lastIDs = append(e.lastIDs, e.lastID)
+	e.lastID = 0
+}
+
+func (e *thriftEncoder) endStruct() {
+	e.buf.WriteByte(0)
+	e.lastID = e.lastIDs[len(e.lastIDs)-1]
+	e.lastIDs = e.lastIDs[:len(e.lastIDs)-1]
+}
+
+func (e *thriftEncoder) listHeader(id int16, elemType byte, size int) {
+	e.fieldHeader(id, thriftList)
+	if size < 15 {
+		e.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	e.buf.WriteByte(0xf0 | elemType)
+	e.uvarint(uint64(size))
+}
+
+func (e *thriftEncoder) i32List(id int16, values []int32) {
+	e.listHeader(id, thriftI32, len(values))
+	for _, v := range values {
+		e.varint(int64(v))
+	}
+}
+
+func (e *thriftEncoder) stringList(id int16, values []string) {
+	e.listHeader(id, thriftBinary, len(values))
+	for _, v := range values {
+		e.uvarint(uint64(len(v)))
+		e.buf.WriteString(v)
+	}
+}
+
+// bytes returns the encoded structure, ending it.
+func (e *thriftEncoder) bytes() []byte {
+	e.buf.WriteByte(0)
+	return e.buf.Bytes()
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package parquet writes flat tables of optional string columns as Parquet files. Rows are buffered into row
+// groups of a fixed number of rows, each written out as it fills, so tables of any size are written in bounded
+// memory. Pages are PLAIN encoded and uncompressed, which every Parquet reader supports.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	magic = "PAR1"
+
+	// DefaultRowGroupSize is the number of rows of a row group when none is given.
+	DefaultRowGroupSize = 10000
+
+	// Values of the Parquet format enums used.
+	typeByteArray      = 6
+	repetitionOptional = 1
+	convertedTypeUTF8  = 0
+	encodingPlain      = 0
+	encodingRLE        = 3
+	codecUncompressed  = 0
+	pageTypeDataPage   = 0
+	fileFormatVersion  = 1
+	createdBy          = "blnk"
+)
+
+// ErrClosed is returned when a row is written after the writer was closed.
+var ErrClosed = errors.New("parquet writer is closed")
+
+type column struct {
+	defined []bool
+	values  bytes.Buffer
+}
+
+type columnChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+type rowGroup struct {
+	chunks  []columnChunk
+	numRows int64
+	size    int64
+}
+
+// Writer writes rows of string columns to a Parquet file.
+type Writer struct {
+	w            io.Writer
+	offset       int64
+	names        []string
+	columns      []column
+	rows         int
+	rowGroupSize int
+	rowGroups    []rowGroup
+	closed       bool
+}
+
+// NewWriter starts a Parquet file with the named columns on w, writing a row group every rowGroupSize rows, or
+// DefaultRowGroupSize when it is not positive.
+func NewWriter(w io.Writer, names []string, rowGroupSize int) (*Writer, error) {
+	if len(names) == 0 {
+		return nil, errors.New("parquet file needs at least one column")
+	}
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+	pw := &Writer{w: w, names: names, columns: make([]column, len(names)), rowGroupSize: rowGroupSize}
+	if err := pw.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// Write adds a row with a value for every column. Empty values are written as nulls.
+func (pw *Writer) Write(row []string) error {
+	if pw.closed {
+		return ErrClosed
+	}
+	if len(row) != len(pw.columns) {
+		return fmt.Errorf("expected %d values, got %d", len(pw.columns), len(row))
+	}
+	for i, value := range row {
+		col := &pw.columns[i]
+		col.defined = append(col.defined, value != "")
+		if value != "" {
+			var length [4]byte
+			binary.LittleEndian.PutUint32(length[:], uint32(len(value)))
+			col.values.Write(length[:])
+			col.values.WriteString(value)
+		}
+	}
+	pw.rows++
+	if pw.rows >= pw.rowGroupSize {
+		return pw.flush()
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the file's metadata. It does not close the underlying writer.
+func (pw *Writer) Close() error {
+	if pw.closed {
+		return nil
+	}
+	if err := pw.flush(); err != nil {
+		return err
+	}
+	pw.closed = true
+
+	footer := pw.fileMetadata()
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	if err := pw.write(footer); err != nil {
+		return err
+	}
+	if err := pw.write(length[:]); err != nil {
+		return err
+	}
+	return pw.write([]byte(magic))
+}
+
+// flush writes the buffered rows as a row group with a single data page per column.
+func (pw *Writer) flush() error {
+	if pw.rows == 0 {
+		return nil
+	}
+	group := rowGroup{numRows: int64(pw.rows)}
+	for i := range pw.columns {
+		col := &pw.columns[i]
+
+		levels := encodeDefinitionLevels(col.defined)
+		body := make([]byte, 0, 4+len(levels)+col.values.Len())
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(levels)))
+		body = append(body, levels...)
+		body = append(body, col.values.Bytes()...)
+
+		header := pageHeader(len(col.defined), len(body))
+		chunk := columnChunk{offset: pw.offset, size: int64(len(header) + len(body)), numValues: int64(len(col.defined))}
+		if err := pw.write(header); err != nil {
+			return err
+		}
+		if err := pw.write(body); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.size
+
+		col.defined = col.defined[:0]
+		col.values.Reset()
+	}
+	pw.rowGroups = append(pw.rowGroups, group)
+	pw.rows = 0
+	return nil
+}
+
+func (pw *Writer) write(p []byte) error {
+	n, err := pw.w.Write(p)
+	pw.offset += int64(n)
+	return err
+}
+
+// encodeDefinitionLevels encodes whether each value is set as runs of the RLE/bit-packing hybrid encoding.
+func encodeDefinitionLevels(defined []bool) []byte {
+	var out []byte
+	for start := 0; start < len(defined); {
+		end := start + 1
+		for end < len(defined) && defined[end] == defined[start] {
+			end++
+		}
+		out = binary.AppendUvarint(out, uint64(end-start)<<1)
+		if defined[start] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		start = end
+	}
+	return out
+}
+
+func pageHeader(numValues, size int) []byte {
+	e := &thriftEncoder{}
+	e.i32(1, pageTypeDataPage)
+	e.i32(2, int32(size))
+	e.i32(3, int32(size))
+	e.beginStruct(5)
+	e.i32(1, int32(numValues))
+	e.i32(2, encodingPlain)
+	e.i32(3, encodingRLE)
+	e.i32(4, encodingRLE)
+	e.endStruct()
+	return e.bytes()
+}
+
+func (pw *Writer) fileMetadata() []byte {
+	var numRows int64
+	for _, group := range pw.rowGroups {
+		numRows += group.numRows
+	}
+
+	e := &thriftEncoder{}
+	e.i32(1, fileFormatVersion)
+
+	e.listHeader(2, thriftStruct, len(pw.names)+1)
+	e.beginStruct(0)
+	e.string(4, "schema")
+	e.i32(5, int32(len(pw.names)))
+	e.endStruct()
+	for _, name := range pw.names {
+		e.beginStruct(0)
+		e.i32(1, typeByteArray)
+		e.i32(3, repetitionOptional)
+		e.string(4, name)
+		e.i32(6, convertedTypeUTF8)
+		e.endStruct()
+	}
+
+	e.i64(3, numRows)
+
+	e.listHeader(4, thriftStruct, len(pw.rowGroups))
+	for _, group := range pw.rowGroups {
+		e.beginStruct(0)
+		e.listHeader(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			e.beginStruct(0)
+			e.i64(2, chunk.offset)
+			e.beginStruct(3)
+			e.i32(1, typeByteArray)
+			e.i32List(2, []int32{encodingPlain, encodingRLE})
+			e.stringList(3, []string{pw.names[i]})
+			e.i32(4, codecUncompressed)
+			e.i64(5, chunk.numValues)
+			e.i64(6, chunk.size)
+			e.i64(7, chunk.size)
+			e.i64(9, chunk.offset)
+			e.endStruct()
+			e.endStruct()
+		}
+		e.i64(2, group.size)
+		e.i64(3, group.numRows)
+		e.endStruct()
+	}
+
+	e.string(6, createdBy)
+	return e.bytes()
+}
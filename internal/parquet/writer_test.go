@@ -0,0 +1,72 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter_WritesFramedFile(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []string{"identity_id", "email_address"}, 2)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write([]string{"idt_1", "one@example.com"}))
+	require.NoError(t, w.Write([]string{"idt_2", ""}))
+	require.NoError(t, w.Write([]string{"idt_3", "three@example.com"}))
+	require.NoError(t, w.Close())
+
+	data := buf.Bytes()
+	assert.Equal(t, magic, string(data[:4]))
+	assert.Equal(t, magic, string(data[len(data)-4:]))
+
+	footerLength := int(binary.LittleEndian.Uint32(data[len(data)-8 : len(data)-4]))
+	require.Less(t, footerLength, len(data)-12)
+	footer := data[len(data)-8-footerLength : len(data)-8]
+	assert.Contains(t, string(footer), "identity_id")
+	assert.Contains(t, string(footer), "email_address")
+
+	// Three rows in groups of two make two row groups, each with a chunk per column.
+	assert.Len(t, w.rowGroups, 2)
+	assert.Equal(t, int64(2), w.rowGroups[0].numRows)
+	assert.Equal(t, int64(1), w.rowGroups[1].numRows)
+	assert.Equal(t, int64(4), w.rowGroups[0].chunks[0].offset)
+	assert.Contains(t, string(data[:len(data)-8-footerLength]), "three@example.com")
+}
+
+func TestWriter_RejectsInvalidRows(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []string{"a", "b"}, 0)
+	require.NoError(t, err)
+
+	assert.Error(t, w.Write([]string{"only one"}))
+	require.NoError(t, w.Close())
+	assert.ErrorIs(t, w.Write([]string{"a", "b"}), ErrClosed)
+
+	_, err = NewWriter(&buf, nil, 0)
+	assert.Error(t, err)
+}
+
+func TestEncodeDefinitionLevels(t *testing.T) {
+	// Runs of two set values, one null and one set value, each a header of the run length shifted left once.
+	assert.Equal(t, []byte{4, 1, 2, 0, 2, 1}, encodeDefinitionLevels([]bool{true, true, false, true}))
+}
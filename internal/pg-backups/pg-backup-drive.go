@@ -34,6 +34,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/resilience"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -66,6 +67,7 @@ func NewBackupManager() (*BackupManager, error) {
 		Region:           aws.String(cfg.S3Region),
 		DisableSSL:       aws.Bool(true), // Disable SSL to use HTTP
 		S3ForcePathStyle: aws.Bool(true), // Force path style for certain S3-compatible services
+		HTTPClient:       resilience.Get(resilience.S3).Client(),
 	}
 
 	// Create a new AWS session.
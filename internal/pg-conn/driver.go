@@ -0,0 +1,85 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgconn
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
+)
+
+// ErrNotPgx is returned by WithPgxConn when the database was not opened with the pgx driver, so the caller
+// falls back to database/sql.
+var ErrNotPgx = errors.New("database is not opened with the pgx driver")
+
+// newConnector returns the connector of the named driver for dsn. An empty name selects lib/pq.
+func newConnector(driverName, dsn string) (driver.Connector, error) {
+	switch driverName {
+	case "", config.DriverPostgres:
+		return pq.NewConnector(dsn)
+	case config.DriverPgx:
+		connConfig, err := pgx.ParseConfig(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return stdlib.GetConnector(*connConfig), nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driverName)
+	}
+}
+
+// IsPgx reports whether db was opened with the pgx driver.
+func IsPgx(db *sql.DB) bool {
+	_, ok := db.Driver().(*stdlib.Driver)
+	return ok
+}
+
+// WithPgxConn runs fn with a pgx connection taken from db, for work database/sql cannot express, such as
+// pipelined batches and binary COPY. The connection is held until fn returns, and the tenant of ctx is set on
+// its session first when row-level security is enabled. It returns ErrNotPgx without calling fn when db uses
+// another driver.
+func WithPgxConn(ctx context.Context, db *sql.DB, fn func(conn *pgx.Conn) error) error {
+	if !IsPgx(db) {
+		return ErrNotPgx
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		if tc, ok := driverConn.(*tenantConn); ok {
+			if err := tc.useTenant(ctx); err != nil {
+				return err
+			}
+			driverConn = tc.Conn
+		}
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return ErrNotPgx
+		}
+		return fn(stdConn.Conn())
+	})
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgconn
+
+import (
+	"errors"
+
+	pgxconn "github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+)
+
+// SQLSTATE codes of the errors Blnk tells apart.
+const (
+	UniqueViolation     = "23505"
+	ForeignKeyViolation = "23503"
+)
+
+// Error is an error reported by the Postgres server, whichever driver received it.
+type Error struct {
+	Code       string
+	Message    string
+	Detail     string
+	Hint       string
+	Table      string
+	Column     string
+	Constraint string
+}
+
+// AsError finds the Postgres server error in err's chain, as reported by lib/pq or pgx.
+func AsError(err error) (*Error, bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return &Error{
+			Code: string(pqErr.Code), Message: pqErr.Message, Detail: pqErr.Detail, Hint: pqErr.Hint,
+			Table: pqErr.Table, Column: pqErr.Column, Constraint: pqErr.Constraint,
+		}, true
+	}
+	var pgErr *pgxconn.PgError
+	if errors.As(err, &pgErr) {
+		return &Error{
+			Code: pgErr.Code, Message: pgErr.Message, Detail: pgErr.Detail, Hint: pgErr.Hint,
+			Table: pgErr.TableName, Column: pgErr.ColumnName, Constraint: pgErr.ConstraintName,
+		}, true
+	}
+	return nil, false
+}
+
+// HasCode reports whether err is a Postgres server error with the given SQLSTATE code.
+func HasCode(err error, code string) bool {
+	pgErr, ok := AsError(err)
+	return ok && pgErr.Code == code
+}
@@ -2,12 +2,12 @@ package pgconn
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"log"
 	"sync"
 
 	"github.com/blnkfinance/blnk/config"
 	"github.com/blnkfinance/blnk/internal/cache"
-	_ "github.com/lib/pq" // Import the postgres driver
 )
 
 // Declare a package-level variable to hold the singleton instance.
@@ -44,12 +44,20 @@ func GetDBConnection(configuration *config.Configuration) (*Datasource, error) {
 	return instance, nil
 }
 
-// ConnectDB establishes a database connection with pooling.
+// ConnectDB establishes a database connection with pooling, using the driver set in the configuration.
 func ConnectDB(dsConfig config.DataSourceConfig) (*sql.DB, error) {
-	db, err := sql.Open("postgres", dsConfig.Dns)
+	var connector driver.Connector
+	var err error
+	if dsConfig.EnableRLS {
+		// Row-level security needs the tenant of each statement set on its session
+		connector, err = newTenantConnector(dsConfig.Driver, dsConfig.Dns)
+	} else {
+		connector, err = newConnector(dsConfig.Driver, dsConfig.Dns)
+	}
 	if err != nil {
 		return nil, err
 	}
+	db := sql.OpenDB(connector)
 
 	// Apply connection pooling settings from configuration
 	db.SetMaxOpenConns(dsConfig.MaxOpenConns)
@@ -0,0 +1,135 @@
+package pgconn
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/blnkfinance/blnk/internal/tenant"
+)
+
+// TenantSetting is the Postgres setting that row-level security policies compare each row's tenant_id with.
+// An empty value means the session is not acting for a tenant and sees every row.
+const TenantSetting = "blnk.tenant_id"
+
+const setTenantQuery = "SELECT set_config('" + TenantSetting + "', $1, false)"
+
+// tenantConnector opens Postgres connections that set TenantSetting to the tenant carried by the context of
+// each statement before running it. Statements run without a request context carry no tenant and are not
+// restricted by the policies.
+type tenantConnector struct {
+	base driver.Connector
+}
+
+// newTenantConnector wraps the connector of the named driver for dsn with tenant propagation.
+func newTenantConnector(driverName, dsn string) (driver.Connector, error) {
+	base, err := newConnector(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantConnector{base: base}, nil
+}
+
+func (c *tenantConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantConn{Conn: conn}, nil
+}
+
+func (c *tenantConnector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+// tenantConn remembers the tenant last set on the session so the setting is only sent when the tenant changes.
+// database/sql never uses a connection from two goroutines at once, so the fields need no locking.
+type tenantConn struct {
+	driver.Conn
+	tenant string
+	known  bool
+}
+
+// useTenant sets TenantSetting on the session to the tenant carried by ctx.
+func (c *tenantConn) useTenant(ctx context.Context) error {
+	current := tenant.FromContext(ctx)
+	if c.known && c.tenant == current {
+		return nil
+	}
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return errors.New("postgres driver does not support ExecContext")
+	}
+	c.known = false
+	if _, err := execer.ExecContext(ctx, setTenantQuery, []driver.NamedValue{{Ordinal: 1, Value: current}}); err != nil {
+		return err
+	}
+	c.tenant, c.known = current, true
+	return nil
+}
+
+func (c *tenantConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.useTenant(ctx); err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *tenantConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.useTenant(ctx); err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *tenantConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.useTenant(ctx); err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+// BeginTx sets the tenant before the transaction starts, so every statement in it runs for that tenant.
+func (c *tenantConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.useTenant(ctx); err != nil {
+		return nil, err
+	}
+	tx, err := c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantTx{Tx: tx, conn: c}, nil
+}
+
+func (c *tenantConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tenantConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tenantConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// tenantTx forgets the session's tenant on rollback, since Postgres undoes a set_config made inside the
+// rolled back transaction.
+type tenantTx struct {
+	driver.Tx
+	conn *tenantConn
+}
+
+func (t *tenantTx) Rollback() error {
+	t.conn.known = false
+	return t.Tx.Rollback()
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pgconn
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingConn is a driver connection that records the statements it runs.
+type recordingConn struct {
+	statements []string
+	tenants    []interface{}
+}
+
+func (c *recordingConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *recordingConn) Close() error                        { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *recordingConn) Commit() error                       { return nil }
+func (c *recordingConn) Rollback() error                     { return nil }
+
+func (c *recordingConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.statements = append(c.statements, query)
+	if query == setTenantQuery {
+		c.tenants = append(c.tenants, args[0].Value)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *recordingConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.statements = append(c.statements, query)
+	return nil, nil
+}
+
+func TestTenantConn_SetsTenantWhenItChanges(t *testing.T) {
+	inner := &recordingConn{}
+	conn := &tenantConn{Conn: inner}
+	acme := tenant.WithTenant(context.Background(), "acme")
+	globex := tenant.WithTenant(context.Background(), "globex")
+
+	_, err := conn.QueryContext(acme, "SELECT 1", nil)
+	require.NoError(t, err)
+	_, err = conn.ExecContext(acme, "UPDATE x", nil)
+	require.NoError(t, err)
+	_, err = conn.QueryContext(globex, "SELECT 2", nil)
+	require.NoError(t, err)
+	_, err = conn.QueryContext(context.Background(), "SELECT 3", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{"acme", "globex", ""}, inner.tenants)
+	assert.Equal(t, []string{setTenantQuery, "SELECT 1", "UPDATE x", setTenantQuery, "SELECT 2", setTenantQuery, "SELECT 3"}, inner.statements)
+}
+
+func TestTenantConn_RollbackForgetsTenant(t *testing.T) {
+	inner := &recordingConn{}
+	conn := &tenantConn{Conn: inner}
+	acme := tenant.WithTenant(context.Background(), "acme")
+
+	tx, err := conn.BeginTx(acme, driver.TxOptions{})
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	_, err = conn.QueryContext(acme, "SELECT 1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"acme", "acme"}, inner.tenants, "the setting is sent again after a rollback undid it")
+}
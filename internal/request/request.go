@@ -55,9 +55,22 @@ func ToJsonReq(payload interface{}) (*bytes.Buffer, error) {
 // - *http.Response: The raw HTTP response object.
 // - error: An error if the HTTP request or JSON decoding fails.
 func Call(req *http.Request, response interface{}) (*http.Response, error) {
+	return CallWith(&http.Client{}, req, response)
+}
+
+// CallWith makes an HTTP request like Call, sending it with the provided client.
+//
+// Parameters:
+// - client *http.Client: The HTTP client to send the request with.
+// - req *http.Request: The prepared HTTP request to send.
+// - response interface{}: The target structure to hold the decoded JSON response.
+//
+// Returns:
+// - *http.Response: The raw HTTP response object.
+// - error: An error if the HTTP request or JSON decoding fails.
+func CallWith(client *http.Client, req *http.Request, response interface{}) (*http.Response, error) {
 	// Set request content type to JSON
 	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
 
 	// Send the HTTP request and capture the response
 	resp, err := client.Do(req)
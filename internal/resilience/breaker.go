@@ -0,0 +1,91 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resilience
+
+import (
+	"sync"
+	"time"
+)
+
+// States of a circuit breaker.
+const (
+	stateClosed   = "closed"
+	stateOpen     = "open"
+	stateHalfOpen = "half_open"
+)
+
+// breaker is the circuit breaker of a host. It opens after threshold consecutive failures and stays open for
+// openDuration, after which a single call is let through as a probe: its success closes the circuit and its
+// failure opens it again. A negative threshold disables the breaker.
+type breaker struct {
+	threshold    int
+	openDuration time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow reports whether a call may be made now. A call allowed while the circuit is half open is the probe,
+// and no other call is allowed until its outcome is recorded.
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.stateLocked(now) {
+	case stateOpen:
+		return false
+	case stateHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// record records the outcome of a call.
+func (b *breaker) record(now time.Time, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if success {
+		b.failures = 0
+		b.openedAt = time.Time{}
+		return
+	}
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openedAt = now
+	}
+}
+
+func (b *breaker) state(now time.Time) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked(now)
+}
+
+func (b *breaker) stateLocked(now time.Time) string {
+	if b.threshold <= 0 || b.openedAt.IsZero() {
+		return stateClosed
+	}
+	if now.Sub(b.openedAt) < b.openDuration {
+		return stateOpen
+	}
+	return stateHalfOpen
+}
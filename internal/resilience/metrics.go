@@ -0,0 +1,75 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resilience
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WriteMetrics writes the health of the dependencies in use in the Prometheus text format. Hosts are left out
+// of the labels so that the endpoints of partners are not exposed.
+//
+// Parameters:
+// - w: The writer the metrics are written to.
+//
+// Returns:
+// - error: An error if the metrics could not be written.
+func WriteMetrics(w io.Writer) error {
+	health := Snapshot()
+	metrics := []struct {
+		name, kind, help string
+		value            func(Health) float64
+	}{
+		{"blnk_dependency_up", "gauge", "Whether no host of the dependency has an open circuit.", func(h Health) float64 {
+			if h.Healthy() {
+				return 1
+			}
+			return 0
+		}},
+		{"blnk_dependency_open_circuits", "gauge", "Number of hosts of the dependency whose circuit is open.", func(h Health) float64 { return float64(len(h.OpenHosts)) }},
+		{"blnk_dependency_requests_total", "counter", "Requests made to the dependency, including retries.", func(h Health) float64 { return float64(h.Stats.Requests) }},
+		{"blnk_dependency_failures_total", "counter", "Requests to the dependency that failed.", func(h Health) float64 { return float64(h.Stats.Failures) }},
+		{"blnk_dependency_timeouts_total", "counter", "Requests to the dependency that timed out.", func(h Health) float64 { return float64(h.Stats.Timeouts) }},
+		{"blnk_dependency_retries_total", "counter", "Requests to the dependency that were retried.", func(h Health) float64 { return float64(h.Stats.Retries) }},
+		{"blnk_dependency_rejected_total", "counter", "Requests to the dependency rejected by an open circuit.", func(h Health) float64 { return float64(h.Stats.Rejected) }},
+		{"blnk_dependency_request_duration_seconds_total", "counter", "Time spent on requests to the dependency.", func(h Health) float64 { return h.Stats.LatencySeconds }},
+	}
+
+	for _, metric := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind); err != nil {
+			return err
+		}
+		for _, h := range health {
+			if _, err := fmt.Fprintf(w, "%s{dependency=%q} %g\n", metric.name, h.Dependency, metric.value(h)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Handler serves the metrics written by WriteMetrics.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := WriteMetrics(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resilience puts the calls Blnk makes to external dependencies behind a common policy: a timeout per
+// attempt, retries with exponential backoff for idempotent requests, and a circuit breaker per host. Each
+// dependency is configured in the dependencies section of the configuration, and its health is exported as
+// metrics.
+package resilience
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+)
+
+// External dependencies.
+const (
+	Webhooks       = "webhooks"
+	Typesense      = "typesense"
+	S3             = "s3"
+	Hooks          = "hooks"
+	AccountNumbers = "account_numbers"
+	Slack          = "slack"
+	Warehouse      = "warehouse"
+	Intercompany   = "intercompany"
+	Challenge      = "challenge"
+	Screening      = "screening"
+	Enrichment     = "enrichment"
+	EOD            = "eod"
+)
+
+// defaultPolicy applies to dependencies and fields that are not configured.
+var defaultPolicy = config.DependencyPolicy{
+	Timeout:          30 * time.Second,
+	Retries:          2,
+	RetryBackoff:     200 * time.Millisecond,
+	FailureThreshold: 5,
+	OpenDuration:     30 * time.Second,
+}
+
+// dependencyDefaults override the default policy for dependencies with needs of their own. Webhook deliveries
+// are retried by the queue and have a circuit per endpoint of their own, so they are neither retried nor
+// broken here. Transactions wait on enrichment as they are recorded, so it is given little time. End-of-day steps
+// are bounded by their own timeout and retried by the run.
+var dependencyDefaults = map[string]config.DependencyPolicy{
+	Webhooks:   {Retries: -1, FailureThreshold: -1},
+	Typesense:  {Timeout: 5 * time.Second},
+	Warehouse:  {Timeout: 2 * time.Minute},
+	Enrichment: {Timeout: 2 * time.Second, Retries: -1},
+	EOD:        {Timeout: -1, Retries: -1},
+}
+
+// Dependency is an external dependency calls are made to. It keeps a circuit breaker per host and counts the
+// outcome of every call.
+type Dependency struct {
+	name      string
+	policy    config.DependencyPolicy
+	endpoints map[string]config.DependencyPolicy
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+	stats    Stats
+}
+
+// registry holds the dependencies in use, created on first use from the configuration.
+var registry = struct {
+	sync.Mutex
+	dependencies map[string]*Dependency
+}{dependencies: map[string]*Dependency{}}
+
+// Get returns the named dependency, creating it from the current configuration on first use.
+//
+// Parameters:
+// - name: The name of the dependency, e.g. Typesense.
+//
+// Returns:
+// - *Dependency: The dependency.
+func Get(name string) *Dependency {
+	registry.Lock()
+	defer registry.Unlock()
+	if dep, ok := registry.dependencies[name]; ok {
+		return dep
+	}
+
+	var cfg config.DependencyConfig
+	if cnf, err := config.Fetch(); err == nil {
+		cfg = cnf.Dependencies[name]
+		// Webhooks keep honouring the request timeout of the egress configuration
+		if name == Webhooks && cfg.Timeout == 0 && cnf.Notification.Webhook.Egress.RequestTimeout > 0 {
+			cfg.Timeout = time.Duration(cnf.Notification.Webhook.Egress.RequestTimeout) * time.Second
+		}
+	}
+	dep := newDependency(name, cfg)
+	registry.dependencies[name] = dep
+	return dep
+}
+
+// Reset forgets the dependencies in use, so they are created again from the configuration. Their breakers and
+// counts are lost.
+func Reset() {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.dependencies = map[string]*Dependency{}
+}
+
+func newDependency(name string, cfg config.DependencyConfig) *Dependency {
+	policy := mergePolicy(mergePolicy(defaultPolicy, dependencyDefaults[name]), cfg.DependencyPolicy)
+	endpoints := make(map[string]config.DependencyPolicy, len(cfg.Endpoints))
+	for host, override := range cfg.Endpoints {
+		endpoints[host] = mergePolicy(policy, override)
+	}
+	return &Dependency{name: name, policy: policy, endpoints: endpoints, breakers: map[string]*breaker{}}
+}
+
+// mergePolicy returns base with the fields set in override replacing its own.
+func mergePolicy(base, override config.DependencyPolicy) config.DependencyPolicy {
+	if override.Timeout != 0 {
+		base.Timeout = override.Timeout
+	}
+	if override.Retries != 0 {
+		base.Retries = override.Retries
+	}
+	if override.RetryBackoff != 0 {
+		base.RetryBackoff = override.RetryBackoff
+	}
+	if override.FailureThreshold != 0 {
+		base.FailureThreshold = override.FailureThreshold
+	}
+	if override.OpenDuration != 0 {
+		base.OpenDuration = override.OpenDuration
+	}
+	return base
+}
+
+// Name returns the name of the dependency.
+func (d *Dependency) Name() string { return d.name }
+
+// Policy returns the policy calls to a host are made with. Hosts are looked up by host:port first and then
+// by hostname.
+func (d *Dependency) Policy(host string) config.DependencyPolicy {
+	if policy, ok := d.endpoints[host]; ok {
+		return policy
+	}
+	if policy, ok := d.endpoints[hostname(host)]; ok {
+		return policy
+	}
+	return d.policy
+}
+
+// Client returns an HTTP client whose requests follow the dependency's policy.
+func (d *Dependency) Client() *http.Client {
+	return &http.Client{Transport: d.Transport(nil)}
+}
+
+// Wrap returns a copy of client whose requests follow the dependency's policy, keeping its transport, timeout
+// and redirect policy.
+func (d *Dependency) Wrap(client *http.Client) *http.Client {
+	wrapped := *client
+	wrapped.Transport = d.Transport(client.Transport)
+	return &wrapped
+}
+
+// breaker returns the circuit breaker of a host.
+func (d *Dependency) breaker(host string) *breaker {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	b, ok := d.breakers[host]
+	if !ok {
+		policy := d.Policy(host)
+		b = &breaker{threshold: policy.FailureThreshold, openDuration: policy.OpenDuration}
+		d.breakers[host] = b
+	}
+	return b
+}
+
+// Health reports the state of a dependency: how its calls went and which of its hosts have an open circuit.
+type Health struct {
+	Dependency string   `json:"dependency"`
+	Stats      Stats    `json:"stats"`
+	OpenHosts  []string `json:"open_hosts"`
+}
+
+// Healthy reports whether no host of the dependency has an open circuit.
+func (h Health) Healthy() bool { return len(h.OpenHosts) == 0 }
+
+// Snapshot returns the health of the dependencies in use, ordered by name.
+func Snapshot() []Health {
+	registry.Lock()
+	dependencies := make([]*Dependency, 0, len(registry.dependencies))
+	for _, dep := range registry.dependencies {
+		dependencies = append(dependencies, dep)
+	}
+	registry.Unlock()
+	sort.Slice(dependencies, func(i, j int) bool { return dependencies[i].name < dependencies[j].name })
+
+	now := time.Now()
+	health := make([]Health, 0, len(dependencies))
+	for _, dep := range dependencies {
+		dep.mu.Lock()
+		h := Health{Dependency: dep.name, Stats: dep.stats, OpenHosts: []string{}}
+		for host, b := range dep.breakers {
+			if b.state(now) == stateOpen {
+				h.OpenHosts = append(h.OpenHosts, host)
+			}
+		}
+		dep.mu.Unlock()
+		sort.Strings(h.OpenHosts)
+		health = append(health, h)
+	}
+	return health
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resilience
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDependency(policy config.DependencyPolicy) *Dependency {
+	return newDependency("test", config.DependencyConfig{DependencyPolicy: policy})
+}
+
+func statusServer(t *testing.T, calls *int32, statuses ...int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(calls, 1)
+		status := statuses[len(statuses)-1]
+		if int(n) <= len(statuses) {
+			status = statuses[n-1]
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMergePolicy(t *testing.T) {
+	dep := newDependency(Typesense, config.DependencyConfig{
+		DependencyPolicy: config.DependencyPolicy{Retries: 4},
+		Endpoints: map[string]config.DependencyPolicy{
+			"search.internal": {Timeout: time.Second},
+		},
+	})
+
+	assert.Equal(t, 5*time.Second, dep.Policy("other:8108").Timeout)
+	assert.Equal(t, 4, dep.Policy("other:8108").Retries)
+	assert.Equal(t, defaultPolicy.OpenDuration, dep.Policy("other:8108").OpenDuration)
+
+	override := dep.Policy("search.internal:8108")
+	assert.Equal(t, time.Second, override.Timeout)
+	assert.Equal(t, 4, override.Retries)
+}
+
+func TestTransport_RetriesIdempotentRequests(t *testing.T) {
+	var calls int32
+	server := statusServer(t, &calls, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+	dep := testDependency(config.DependencyPolicy{Retries: 2, RetryBackoff: time.Millisecond})
+
+	resp, err := dep.Client().Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, int32(3), calls)
+	assert.Equal(t, int64(3), dep.stats.Requests)
+	assert.Equal(t, int64(2), dep.stats.Retries)
+	assert.Equal(t, int64(2), dep.stats.Failures)
+}
+
+func TestTransport_DoesNotRetryPosts(t *testing.T) {
+	var calls int32
+	server := statusServer(t, &calls, http.StatusServiceUnavailable, http.StatusOK)
+	dep := testDependency(config.DependencyPolicy{Retries: 2, RetryBackoff: time.Millisecond})
+
+	resp, err := dep.Client().Post(server.URL, "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), calls)
+
+	// An idempotency key makes the post safe to send again
+	req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader([]byte(`{}`)))
+	require.NoError(t, err)
+	req.Header.Set("Idempotency-Key", "key_1")
+	atomic.StoreInt32(&calls, 0)
+	resp, err = dep.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestTransport_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+	dep := testDependency(config.DependencyPolicy{Timeout: 20 * time.Millisecond, Retries: -1})
+
+	_, err := dep.Client().Get(server.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out after 20ms")
+	assert.Equal(t, int64(1), dep.stats.Timeouts)
+}
+
+func TestTransport_NegativeTimeoutLeavesCallerDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	dep := testDependency(config.DependencyPolicy{Timeout: -1, Retries: -1})
+
+	resp, err := dep.Client().Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int64(0), dep.stats.Timeouts)
+}
+
+func TestTransport_CircuitBreaker(t *testing.T) {
+	var calls int32
+	server := statusServer(t, &calls, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK)
+	dep := testDependency(config.DependencyPolicy{Retries: -1, FailureThreshold: 2, OpenDuration: 50 * time.Millisecond})
+	client := dep.Client()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	_, err := client.Get(server.URL)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(2), calls)
+	assert.Equal(t, int64(1), dep.stats.Rejected)
+	assert.Equal(t, stateOpen, dep.breaker(host).state(time.Now()))
+
+	// Once the circuit has been open long enough a probe is let through, and its success closes the circuit
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, stateHalfOpen, dep.breaker(host).state(time.Now()))
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, stateClosed, dep.breaker(host).state(time.Now()))
+}
+
+func TestBreaker_HalfOpenAllowsOneProbe(t *testing.T) {
+	b := &breaker{threshold: 1, openDuration: time.Minute}
+	now := time.Now()
+	b.record(now, false)
+	assert.False(t, b.allow(now))
+
+	later := now.Add(2 * time.Minute)
+	assert.True(t, b.allow(later))
+	assert.False(t, b.allow(later))
+
+	// A failed probe opens the circuit again
+	b.record(later, false)
+	assert.Equal(t, stateOpen, b.state(later))
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	b := &breaker{threshold: -1, openDuration: time.Minute}
+	for i := 0; i < 10; i++ {
+		b.record(time.Now(), false)
+	}
+	assert.True(t, b.allow(time.Now()))
+	assert.Equal(t, stateClosed, b.state(time.Now()))
+}
+
+func TestRetryable(t *testing.T) {
+	get, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	assert.True(t, retryable(get))
+
+	post, _ := http.NewRequest(http.MethodPost, "http://example.com", strings.NewReader("{}"))
+	assert.False(t, retryable(post))
+
+	put := &http.Request{Method: http.MethodPut, URL: &url.URL{}, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}
+	assert.False(t, retryable(put), "a body that cannot be read again is not retried")
+}
+
+func TestWriteMetrics(t *testing.T) {
+	Reset()
+	t.Cleanup(Reset)
+	var calls int32
+	server := statusServer(t, &calls, http.StatusOK)
+
+	resp, err := Get(Hooks).Client().Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteMetrics(&buf))
+	out := buf.String()
+	assert.Contains(t, out, "# TYPE blnk_dependency_requests_total counter")
+	assert.Contains(t, out, `blnk_dependency_requests_total{dependency="hooks"} 1`)
+	assert.Contains(t, out, `blnk_dependency_up{dependency="hooks"} 1`)
+	assert.NotContains(t, out, "127.0.0.1")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "blnk_dependency_open_circuits")
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrCircuitOpen is returned for calls to a host whose circuit is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Stats counts the calls made to a dependency. Requests counts every attempt, including retries.
+type Stats struct {
+	Requests       int64   `json:"requests"`
+	Failures       int64   `json:"failures"`
+	Timeouts       int64   `json:"timeouts"`
+	Retries        int64   `json:"retries"`
+	Rejected       int64   `json:"rejected"`
+	LatencySeconds float64 `json:"latency_seconds"`
+}
+
+// transport makes the requests of a dependency through its policy.
+type transport struct {
+	dep  *Dependency
+	base http.RoundTripper
+}
+
+// Transport returns a round tripper making requests through base, or the default transport when base is nil,
+// with the dependency's policy.
+func (d *Dependency) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{dep: d, base: base}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	policy := t.dep.Policy(host)
+	b := t.dep.breaker(host)
+	retries := 0
+	if retryable(req) && policy.Retries > 0 {
+		retries = policy.Retries
+	}
+	backoff := policy.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		if !b.allow(time.Now()) {
+			t.dep.count(func(s *Stats) { s.Rejected++ })
+			return nil, fmt.Errorf("%s %s: %w", t.dep.name, host, ErrCircuitOpen)
+		}
+
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+		ctx, cancel := context.WithCancel(req.Context())
+		if policy.Timeout > 0 {
+			ctx, cancel = context.WithTimeout(req.Context(), policy.Timeout)
+		}
+		start := time.Now()
+		resp, err := t.base.RoundTrip(attemptReq.WithContext(ctx))
+		elapsed := time.Since(start)
+
+		failed := err != nil || resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+		timedOut := err != nil && ctx.Err() == context.DeadlineExceeded && req.Context().Err() == nil
+		// Calls abandoned by the caller say nothing about the health of the host
+		if req.Context().Err() == nil {
+			b.record(time.Now(), !failed)
+		}
+		t.dep.count(func(s *Stats) {
+			s.Requests++
+			s.LatencySeconds += elapsed.Seconds()
+			if failed {
+				s.Failures++
+			}
+			if timedOut {
+				s.Timeouts++
+			}
+		})
+
+		if !failed || attempt >= retries || req.Context().Err() != nil {
+			if err != nil {
+				cancel()
+				if timedOut {
+					return nil, fmt.Errorf("%s %s: timed out after %s: %w", t.dep.name, host, policy.Timeout, err)
+				}
+				return nil, err
+			}
+			// The timeout covers reading the body, so it is released once the body is closed
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		cancel()
+		t.dep.count(func(s *Stats) { s.Retries++ })
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// retryable reports whether a request can be sent again: it is idempotent, either by its method or because it
+// carries an idempotency key, and its body can be read again.
+func retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// count updates the stats of the dependency.
+func (d *Dependency) count(update func(*Stats)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	update(&d.stats)
+}
+
+// cancelBody releases the timeout of a request once its response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// hostname strips the port from a host.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package servicetoken signs and verifies the short-lived tokens issued to service accounts.
+// A token is "bst_" followed by the base64url-encoded JSON claims, a dot, and the base64url-encoded
+// HMAC-SHA256 of the encoded claims.
+package servicetoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Prefix identifies service tokens among the credentials accepted in the X-Blnk-Key header.
+const Prefix = "bst_"
+
+var (
+	ErrMalformed        = errors.New("malformed service token")
+	ErrInvalidSignature = errors.New("invalid service token signature")
+	ErrExpired          = errors.New("service token has expired")
+)
+
+// Claims are the contents of a service token.
+type Claims struct {
+	ServiceAccountID string   `json:"sub"`
+	OwnerID          string   `json:"owner"`
+	Scopes           []string `json:"scopes"`
+	IssuedAt         int64    `json:"iat"`
+	ExpiresAt        int64    `json:"exp"`
+}
+
+// IsToken reports whether a credential is a service token.
+func IsToken(credential string) bool {
+	return strings.HasPrefix(credential, Prefix)
+}
+
+// Sign encodes the claims and signs them with key.
+func Sign(claims Claims, key []byte) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return Prefix + encoded + "." + base64.RawURLEncoding.EncodeToString(sign(encoded, key)), nil
+}
+
+// Verify checks the signature and expiry of a token and returns its claims.
+func Verify(token string, key []byte, now time.Time) (*Claims, error) {
+	if !IsToken(token) {
+		return nil, ErrMalformed
+	}
+	encoded, signature, found := strings.Cut(strings.TrimPrefix(token, Prefix), ".")
+	if !found {
+		return nil, ErrMalformed
+	}
+
+	given, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if !hmac.Equal(given, sign(encoded, key)) {
+		return nil, ErrInvalidSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrMalformed
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrExpired
+	}
+	return &claims, nil
+}
+
+func sign(encoded string, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
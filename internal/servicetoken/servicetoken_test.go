@@ -0,0 +1,71 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicetoken
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Now()
+	key := []byte("signing-key")
+	claims := Claims{
+		ServiceAccountID: "svc_1",
+		OwnerID:          "owner_1",
+		Scopes:           []string{"transactions:write"},
+		IssuedAt:         now.Unix(),
+		ExpiresAt:        now.Add(time.Minute).Unix(),
+	}
+
+	token, err := Sign(claims, key)
+	require.NoError(t, err)
+	assert.True(t, IsToken(token))
+
+	verified, err := Verify(token, key, now)
+	require.NoError(t, err)
+	assert.Equal(t, claims, *verified)
+
+	_, err = Verify(token, []byte("other-key"), now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = Verify(token, key, now.Add(2*time.Minute))
+	assert.ErrorIs(t, err, ErrExpired)
+
+	_, err = Verify("bst_not-a-token", key, now)
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestVerifyRejectsTamperedClaims(t *testing.T) {
+	now := time.Now()
+	key := []byte("signing-key")
+
+	token, err := Sign(Claims{ServiceAccountID: "svc_1", Scopes: []string{"ledgers:read"}, ExpiresAt: now.Add(time.Minute).Unix()}, key)
+	require.NoError(t, err)
+	elevated, err := Sign(Claims{ServiceAccountID: "svc_1", Scopes: []string{"*:*"}, ExpiresAt: now.Add(time.Minute).Unix()}, []byte("attacker"))
+	require.NoError(t, err)
+
+	// Pair the elevated claims with the genuine signature
+	_, genuineSig, _ := strings.Cut(strings.TrimPrefix(token, Prefix), ".")
+	elevatedClaims, _, _ := strings.Cut(strings.TrimPrefix(elevated, Prefix), ".")
+	_, err = Verify(Prefix+elevatedClaims+"."+genuineSig, key, now)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
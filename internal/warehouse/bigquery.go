@@ -0,0 +1,244 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+)
+
+const (
+	bigQueryBaseURL  = "https://bigquery.googleapis.com/bigquery/v2"
+	bigQueryTokenURL = "https://oauth2.googleapis.com/token"
+	bigQueryScope    = "https://www.googleapis.com/auth/bigquery.insertdata"
+
+	// bigQueryMaxRows is the number of rows sent in each streaming insert, the size BigQuery recommends.
+	bigQueryMaxRows = 500
+)
+
+// BigQuerySink writes batches to BigQuery with streaming inserts. Each row is sent with its ID as insert ID,
+// so BigQuery drops rows sent twice within its deduplication window.
+type BigQuerySink struct {
+	cfg      config.BigQueryConfig
+	client   *http.Client
+	baseURL  string
+	tokenURL string
+	email    string
+	key      *rsa.PrivateKey
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// serviceAccountKey is the part of a service account's JSON key used to authenticate.
+type serviceAccountKey struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// NewBigQuerySink returns a sink writing to the dataset in cfg, authenticated with the service account key
+// in its credentials file.
+//
+// Parameters:
+// - cfg: The project, dataset and credentials file.
+//
+// Returns:
+// - *BigQuerySink: The sink.
+// - error: An error if the configuration is incomplete or the key cannot be read.
+func NewBigQuerySink(cfg config.BigQueryConfig) (*BigQuerySink, error) {
+	if cfg.ProjectID == "" || cfg.Dataset == "" || cfg.CredentialsFile == "" {
+		return nil, errors.New("bigquery requires a project ID, dataset and credentials file")
+	}
+	data, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bigquery credentials: %w", err)
+	}
+	var account serviceAccountKey
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse bigquery credentials: %w", err)
+	}
+	key, err := parsePrivateKey([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	sink := &BigQuerySink{
+		cfg:      cfg,
+		client:   newHTTPClient(),
+		baseURL:  bigQueryBaseURL,
+		tokenURL: bigQueryTokenURL,
+		email:    account.ClientEmail,
+		key:      key,
+	}
+	if account.TokenURI != "" {
+		sink.tokenURL = account.TokenURI
+	}
+	return sink, nil
+}
+
+// Name returns "bigquery".
+func (s *BigQuerySink) Name() string { return "bigquery" }
+
+// Close does nothing, as streaming inserts hold no connection.
+func (s *BigQuerySink) Close() error { return nil }
+
+// Write streams the rows of a batch into its table, in requests of up to 500 rows. Rows BigQuery rejects fail
+// the write with the reason of the first of them.
+func (s *BigQuerySink) Write(ctx context.Context, batch Batch) error {
+	if err := batch.validate(); err != nil {
+		return err
+	}
+	records := batch.records()
+	for start := 0; start < len(records); start += bigQueryMaxRows {
+		end := min(start+bigQueryMaxRows, len(records))
+		if err := s.insert(ctx, batch, records, start, end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type bigQueryInsertRow struct {
+	InsertID string                 `json:"insertId,omitempty"`
+	JSON     map[string]interface{} `json:"json"`
+}
+
+type bigQueryInsertResponse struct {
+	InsertErrors []struct {
+		Index  int `json:"index"`
+		Errors []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"insertErrors"`
+}
+
+func (s *BigQuerySink) insert(ctx context.Context, batch Batch, records []map[string]interface{}, start, end int) error {
+	rows := make([]bigQueryInsertRow, 0, end-start)
+	for i := start; i < end; i++ {
+		row := bigQueryInsertRow{JSON: records[i]}
+		if i < len(batch.RowIDs) {
+			row.InsertID = batch.RowIDs[i]
+		}
+		rows = append(rows, row)
+	}
+	body, err := json.Marshal(map[string]interface{}{"kind": "bigquery#tableDataInsertAllRequest", "rows": rows})
+	if err != nil {
+		return err
+	}
+
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s/insertAll", s.baseURL,
+		url.PathEscape(s.cfg.ProjectID), url.PathEscape(s.cfg.Dataset), url.PathEscape(batch.Table))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to insert into bigquery: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readError(resp)
+	}
+
+	var result bigQueryInsertResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode bigquery response: %w", err)
+	}
+	if len(result.InsertErrors) > 0 {
+		rejected := result.InsertErrors[0]
+		reason := "unknown"
+		if len(rejected.Errors) > 0 {
+			reason = rejected.Errors[0].Reason + ": " + rejected.Errors[0].Message
+		}
+		return fmt.Errorf("bigquery rejected %d rows, row %d: %s", len(result.InsertErrors), start+rejected.Index, reason)
+	}
+	return nil
+}
+
+// accessToken returns an OAuth access token for the service account, exchanging a signed assertion for a new
+// one shortly before the current one expires.
+func (s *BigQuerySink) accessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Before(s.tokenExpiry) {
+		return s.token, nil
+	}
+
+	now := time.Now()
+	assertion, err := signJWT(s.key, map[string]interface{}{
+		"iss":   s.email,
+		"scope": bigQueryScope,
+		"aud":   s.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate with bigquery: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", readError(resp)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode bigquery token: %w", err)
+	}
+	s.token = token.AccessToken
+	s.tokenExpiry = now.Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return s.token, nil
+}
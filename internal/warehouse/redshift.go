@@ -0,0 +1,185 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package warehouse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/resilience"
+	_ "github.com/lib/pq" // Redshift speaks the postgres protocol
+)
+
+// stager stores the files Redshift loads batches from.
+type stager interface {
+	// Stage stores a file and returns the URI Redshift reads it from.
+	Stage(ctx context.Context, key string, data []byte) (string, error)
+	// Remove deletes a staged file.
+	Remove(ctx context.Context, key string) error
+}
+
+// RedshiftSink writes batches to Redshift. Each batch is staged in S3 as gzipped JSON lines and loaded with
+// COPY, which is how Redshift prefers to ingest rows.
+type RedshiftSink struct {
+	db     *sql.DB
+	cfg    config.RedshiftConfig
+	stager stager
+	now    func() time.Time
+}
+
+// NewRedshiftSink returns a sink writing to the Redshift cluster in cnf, staging batches in S3 with the
+// credentials backups use.
+//
+// Parameters:
+// - cnf: The configuration, whose Warehouse.Redshift section configures the cluster and staging bucket.
+//
+// Returns:
+// - *RedshiftSink: The sink.
+// - error: An error if the configuration is incomplete or the cluster cannot be reached.
+func NewRedshiftSink(cnf *config.Configuration) (*RedshiftSink, error) {
+	cfg := cnf.Warehouse.Redshift
+	if cfg.Dns == "" || cfg.S3Bucket == "" || cfg.IAMRole == "" {
+		return nil, errors.New("redshift requires a DNS, S3 bucket and IAM role")
+	}
+
+	awsConfig := &aws.Config{Region: aws.String(cnf.S3Region), HTTPClient: resilience.Get(resilience.S3).Client()}
+	if cnf.AwsAccessKeyId != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(cnf.AwsAccessKeyId, cnf.AwsSecretAccessKey, "")
+	}
+	if cnf.S3Endpoint != "" {
+		awsConfig.Endpoint = aws.String(cnf.S3Endpoint)
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	}
+	awsSession, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 session: %w", err)
+	}
+
+	db, err := sql.Open("postgres", cfg.Dns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open redshift connection: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	return newRedshiftSink(db, cfg, &s3Stager{client: s3.New(awsSession), bucket: cfg.S3Bucket}), nil
+}
+
+func newRedshiftSink(db *sql.DB, cfg config.RedshiftConfig, stager stager) *RedshiftSink {
+	return &RedshiftSink{db: db, cfg: cfg, stager: stager, now: time.Now}
+}
+
+// Name returns "redshift".
+func (s *RedshiftSink) Name() string { return "redshift" }
+
+// Close closes the connection to the cluster.
+func (s *RedshiftSink) Close() error { return s.db.Close() }
+
+// Write stages the rows of a batch and copies them into its table. Keys are matched to columns regardless of
+// case, and the staged file is removed once it is loaded.
+func (s *RedshiftSink) Write(ctx context.Context, batch Batch) error {
+	if err := batch.validate(); err != nil {
+		return err
+	}
+	if len(batch.Rows) == 0 {
+		return nil
+	}
+
+	data, err := gzipJSONLines(batch.records())
+	if err != nil {
+		return err
+	}
+	key := path.Join(s.cfg.S3Prefix, batch.Table, fmt.Sprintf("%d.json.gz", s.now().UnixNano()))
+	uri, err := s.stager.Stage(ctx, key, data)
+	if err != nil {
+		return fmt.Errorf("failed to stage batch: %w", err)
+	}
+	defer func() {
+		_ = s.stager.Remove(context.Background(), key)
+	}()
+
+	table := batch.Table
+	if s.cfg.Schema != "" && !strings.Contains(table, ".") {
+		table = s.cfg.Schema + "." + table
+	}
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf(
+		"COPY %s (%s) FROM %s IAM_ROLE %s FORMAT AS JSON 'auto ignorecase' GZIP TIMEFORMAT 'auto'",
+		table, strings.Join(batch.Columns, ", "), quoteLiteral(uri), quoteLiteral(s.cfg.IAMRole)))
+	if err != nil {
+		return fmt.Errorf("failed to copy into redshift: %w", err)
+	}
+	return nil
+}
+
+// gzipJSONLines encodes records as gzipped JSON, one record per line.
+func gzipJSONLines(records []map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(zw)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// quoteLiteral quotes a string as an SQL literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// s3Stager stages files in an S3 bucket.
+type s3Stager struct {
+	client *s3.S3
+	bucket string
+}
+
+func (s *s3Stager) Stage(ctx context.Context, key string, data []byte) (string, error) {
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return "", err
+	}
+	return "s3://" + s.bucket + "/" + key, nil
+}
+
+func (s *s3Stager) Remove(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
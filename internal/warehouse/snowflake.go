@@ -0,0 +1,224 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+)
+
+// snowflakePollInterval is how often a statement Snowflake runs asynchronously is checked for completion.
+const snowflakePollInterval = time.Second
+
+// SnowflakeSink writes batches to Snowflake through its SQL API. Each batch is inserted by a single INSERT
+// statement with the values of each column bound as an array.
+type SnowflakeSink struct {
+	cfg         config.SnowflakeConfig
+	client      *http.Client
+	baseURL     string
+	key         *rsa.PrivateKey
+	fingerprint string
+}
+
+// NewSnowflakeSink returns a sink writing to the database and schema in cfg, authenticated with the private
+// key of the user.
+//
+// Parameters:
+// - cfg: The account, user, private key file and where to write.
+//
+// Returns:
+// - *SnowflakeSink: The sink.
+// - error: An error if the configuration is incomplete or the key cannot be read.
+func NewSnowflakeSink(cfg config.SnowflakeConfig) (*SnowflakeSink, error) {
+	if cfg.Account == "" || cfg.User == "" || cfg.PrivateKeyFile == "" {
+		return nil, errors.New("snowflake requires an account, user and private key file")
+	}
+	data, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snowflake private key: %w", err)
+	}
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(publicKey)
+
+	return &SnowflakeSink{
+		cfg:         cfg,
+		client:      newHTTPClient(),
+		baseURL:     "https://" + strings.ToLower(cfg.Account) + ".snowflakecomputing.com",
+		key:         key,
+		fingerprint: "SHA256:" + base64.StdEncoding.EncodeToString(digest[:]),
+	}, nil
+}
+
+// Name returns "snowflake".
+func (s *SnowflakeSink) Name() string { return "snowflake" }
+
+// Close does nothing, as the SQL API holds no connection.
+func (s *SnowflakeSink) Close() error { return nil }
+
+type snowflakeBinding struct {
+	Type  string        `json:"type"`
+	Value []interface{} `json:"value"`
+}
+
+type snowflakeStatement struct {
+	Statement string                      `json:"statement"`
+	Timeout   int                         `json:"timeout"`
+	Database  string                      `json:"database,omitempty"`
+	Schema    string                      `json:"schema,omitempty"`
+	Warehouse string                      `json:"warehouse,omitempty"`
+	Role      string                      `json:"role,omitempty"`
+	Bindings  map[string]snowflakeBinding `json:"bindings"`
+}
+
+type snowflakeResponse struct {
+	Code            string `json:"code"`
+	Message         string `json:"message"`
+	StatementHandle string `json:"statementHandle"`
+}
+
+// Write inserts the rows of a batch into its table. Values are bound as text, which Snowflake converts to the
+// types of the columns.
+func (s *SnowflakeSink) Write(ctx context.Context, batch Batch) error {
+	if err := batch.validate(); err != nil {
+		return err
+	}
+	if len(batch.Rows) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(batch.Columns))
+	bindings := make(map[string]snowflakeBinding, len(batch.Columns))
+	for j := range batch.Columns {
+		placeholders[j] = "?"
+		values := make([]interface{}, len(batch.Rows))
+		for i, row := range batch.Rows {
+			values[i] = snowflakeValue(row[j])
+		}
+		bindings[strconv.Itoa(j+1)] = snowflakeBinding{Type: "TEXT", Value: values}
+	}
+
+	body, err := json.Marshal(snowflakeStatement{
+		Statement: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", batch.Table, strings.Join(batch.Columns, ", "), strings.Join(placeholders, ", ")),
+		Timeout:   int(requestTimeout / time.Second),
+		Database:  s.cfg.Database,
+		Schema:    s.cfg.Schema,
+		Warehouse: s.cfg.Warehouse,
+		Role:      s.cfg.Role,
+		Bindings:  bindings,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodPost, "/api/v2/statements", body)
+	if err != nil {
+		return err
+	}
+	// Statements that outlast the request are finished asynchronously and polled for
+	for resp.StatusCode == http.StatusAccepted {
+		var pending snowflakeResponse
+		err := json.NewDecoder(resp.Body).Decode(&pending)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode snowflake response: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(snowflakePollInterval):
+		}
+		if resp, err = s.do(ctx, http.MethodGet, "/api/v2/statements/"+pending.StatementHandle, nil); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readError(resp)
+	}
+	return nil
+}
+
+// do sends a request to the SQL API, authenticated with a freshly signed token.
+func (s *SnowflakeSink) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	token, err := s.token()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Snowflake-Authorization-Token-Type", "KEYPAIR_JWT")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach snowflake: %w", err)
+	}
+	return resp, nil
+}
+
+// token signs the token key-pair authentication expects. Its issuer is the qualified user followed by the
+// fingerprint of their public key.
+func (s *SnowflakeSink) token() (string, error) {
+	account := strings.ToUpper(strings.SplitN(s.cfg.Account, ".", 2)[0])
+	user := account + "." + strings.ToUpper(s.cfg.User)
+	now := time.Now()
+	return signJWT(s.key, map[string]interface{}{
+		"iss": user + "." + s.fingerprint,
+		"sub": user,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour - time.Minute).Unix(),
+	})
+}
+
+// snowflakeValue returns a value as the text it is bound as. Nulls stay null.
+func snowflakeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	case time.Time:
+		return jsonValue(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package warehouse writes rows exported from the ledger to an analytics warehouse: BigQuery through
+// streaming inserts, Snowflake through its SQL API and Redshift through COPY from files staged in S3.
+package warehouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/resilience"
+)
+
+// requestTimeout bounds each request to a warehouse.
+const requestTimeout = 2 * time.Minute
+
+// Batch is a batch of rows written to a warehouse table.
+type Batch struct {
+	Table   string          // The table the rows are written to
+	Columns []string        // The column of each value of a row
+	Rows    [][]interface{} // The rows, with their values in the order of Columns
+	RowIDs  []string        // Identifies each row, so warehouses that can drop rows sent twice do
+}
+
+// Sink is a warehouse batches are written to. Rows are appended, so a row written again after it changed is
+// a new row, and a batch written twice after a failure may be appended twice by warehouses that cannot drop
+// repeated rows.
+type Sink interface {
+	// Name returns the name of the warehouse, e.g. "bigquery".
+	Name() string
+	// Write appends a batch of rows to its table.
+	Write(ctx context.Context, batch Batch) error
+	// Close releases the connection to the warehouse.
+	Close() error
+}
+
+// New returns the sink of the warehouse configured in cnf.
+//
+// Parameters:
+// - cnf: The configuration, whose Warehouse section selects and configures the warehouse.
+//
+// Returns:
+// - Sink: The sink.
+// - error: An error if no warehouse is configured or its configuration is incomplete.
+func New(cnf *config.Configuration) (Sink, error) {
+	switch cnf.Warehouse.Provider {
+	case "bigquery":
+		return NewBigQuerySink(cnf.Warehouse.BigQuery)
+	case "snowflake":
+		return NewSnowflakeSink(cnf.Warehouse.Snowflake)
+	case "redshift":
+		return NewRedshiftSink(cnf)
+	case "":
+		return nil, errors.New("warehouse sync is not configured")
+	}
+	return nil, fmt.Errorf("unknown warehouse provider %q", cnf.Warehouse.Provider)
+}
+
+// identifierPattern matches the table and column names that may be interpolated into SQL, optionally
+// qualified by a schema.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)*$`)
+
+// validate checks that a batch is well formed and that its names are safe to write into SQL.
+func (b Batch) validate() error {
+	if !identifierPattern.MatchString(b.Table) {
+		return fmt.Errorf("invalid table name %q", b.Table)
+	}
+	for _, column := range b.Columns {
+		if !identifierPattern.MatchString(column) {
+			return fmt.Errorf("invalid column name %q", column)
+		}
+	}
+	for i, row := range b.Rows {
+		if len(row) != len(b.Columns) {
+			return fmt.Errorf("row %d has %d values for %d columns", i, len(row), len(b.Columns))
+		}
+	}
+	return nil
+}
+
+// records returns the rows of the batch as objects keyed by column.
+func (b Batch) records() []map[string]interface{} {
+	records := make([]map[string]interface{}, len(b.Rows))
+	for i, row := range b.Rows {
+		record := make(map[string]interface{}, len(b.Columns))
+		for j, column := range b.Columns {
+			record[column] = jsonValue(row[j])
+		}
+		records[i] = record
+	}
+	return records
+}
+
+// jsonValue returns a value as it is sent to warehouses that take JSON. Times are written in RFC 3339 with
+// microseconds, the precision warehouses keep.
+func jsonValue(value interface{}) interface{} {
+	if t, ok := value.(time.Time); ok {
+		return t.UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+	return value
+}
+
+// newHTTPClient returns the client requests to warehouses are sent with.
+func newHTTPClient() *http.Client {
+	return resilience.Get(resilience.Warehouse).Client()
+}
+
+// readError returns the error of a failed response, including its body so the warehouse's reason is kept.
+func readError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return fmt.Errorf("warehouse responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package warehouse
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBatch() Batch {
+	return Batch{
+		Table:   "ledger_transactions",
+		Columns: []string{"transaction_id", "precise_amount", "created_at"},
+		Rows: [][]interface{}{
+			{"txn_1", "1000", time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)},
+			{"txn_2", nil, time.Date(2026, 3, 1, 9, 1, 0, 0, time.UTC)},
+		},
+		RowIDs: []string{"txn_1@1", "txn_2@2"},
+	}
+}
+
+func writeTestKey(t *testing.T) (string, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path, key
+}
+
+func jwtClaims(t *testing.T, token string) map[string]interface{} {
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	return claims
+}
+
+func TestBatchValidate(t *testing.T) {
+	assert.NoError(t, testBatch().validate())
+
+	batch := testBatch()
+	batch.Table = "transactions; DROP TABLE x"
+	assert.Error(t, batch.validate())
+
+	batch = testBatch()
+	batch.Rows[1] = batch.Rows[1][:2]
+	assert.Error(t, batch.validate())
+}
+
+func TestBigQuerySink_Write(t *testing.T) {
+	_, key := writeTestKey(t)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	tokenRequests := 0
+	var inserted []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			tokenRequests++
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "svc@project.iam.gserviceaccount.com", jwtClaims(t, r.Form.Get("assertion"))["iss"])
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "expires_in": 3600})
+		case r.URL.Path == "/projects/proj/datasets/analytics/tables/ledger_transactions/insertAll":
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			var body struct {
+				Rows []map[string]interface{} `json:"rows"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			inserted = append(inserted, body.Rows...)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	credentials, err := json.Marshal(map[string]string{
+		"client_email": "svc@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL + "/token",
+	})
+	require.NoError(t, err)
+	credentialsPath := filepath.Join(t.TempDir(), "credentials.json")
+	require.NoError(t, os.WriteFile(credentialsPath, credentials, 0o600))
+
+	sink, err := NewBigQuerySink(config.BigQueryConfig{ProjectID: "proj", Dataset: "analytics", CredentialsFile: credentialsPath})
+	require.NoError(t, err)
+	sink.baseURL = server.URL
+
+	require.NoError(t, sink.Write(context.Background(), testBatch()))
+	require.NoError(t, sink.Write(context.Background(), testBatch()))
+	assert.Equal(t, 1, tokenRequests)
+	require.Len(t, inserted, 4)
+	assert.Equal(t, "txn_1@1", inserted[0]["insertId"])
+	assert.Equal(t, map[string]interface{}{
+		"transaction_id": "txn_1", "precise_amount": "1000", "created_at": "2026-03-01T09:00:00.000000Z",
+	}, inserted[0]["json"])
+}
+
+func TestBigQuerySink_InsertErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"insertErrors":[{"index":1,"errors":[{"reason":"invalid","message":"no such field"}]}]}`))
+	}))
+	defer server.Close()
+
+	_, key := writeTestKey(t)
+	sink := &BigQuerySink{cfg: config.BigQueryConfig{ProjectID: "proj", Dataset: "analytics"}, client: server.Client(),
+		baseURL: server.URL, key: key, token: "token", tokenExpiry: time.Now().Add(time.Hour)}
+
+	err := sink.Write(context.Background(), testBatch())
+	assert.ErrorContains(t, err, "row 1: invalid: no such field")
+}
+
+func TestSnowflakeSink_Write(t *testing.T) {
+	keyPath, _ := writeTestKey(t)
+
+	var statement snowflakeStatement
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "KEYPAIR_JWT", r.Header.Get("X-Snowflake-Authorization-Token-Type"))
+		claims := jwtClaims(t, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		assert.Equal(t, "ACME.LOADER", claims["sub"])
+		assert.True(t, strings.HasPrefix(claims["iss"].(string), "ACME.LOADER.SHA256:"))
+
+		if r.Method == http.MethodPost {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&statement))
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{"statementHandle":"h1"}`))
+			return
+		}
+		assert.Equal(t, "/api/v2/statements/h1", r.URL.Path)
+		_, _ = w.Write([]byte(`{"code":"090001"}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewSnowflakeSink(config.SnowflakeConfig{Account: "acme.eu-west-1", User: "loader", PrivateKeyFile: keyPath, Database: "ANALYTICS"})
+	require.NoError(t, err)
+	sink.baseURL = server.URL
+
+	require.NoError(t, sink.Write(context.Background(), testBatch()))
+	assert.Equal(t, "INSERT INTO ledger_transactions (transaction_id, precise_amount, created_at) VALUES (?, ?, ?)", statement.Statement)
+	assert.Equal(t, "ANALYTICS", statement.Database)
+	assert.Equal(t, []interface{}{"1000", nil}, statement.Bindings["2"].Value)
+	assert.Equal(t, []interface{}{"2026-03-01T09:00:00.000000Z", "2026-03-01T09:01:00.000000Z"}, statement.Bindings["3"].Value)
+}
+
+type fakeStager struct {
+	staged  map[string][]byte
+	removed []string
+}
+
+func (s *fakeStager) Stage(_ context.Context, key string, data []byte) (string, error) {
+	s.staged[key] = data
+	return "s3://bucket/" + key, nil
+}
+
+func (s *fakeStager) Remove(_ context.Context, key string) error {
+	s.removed = append(s.removed, key)
+	return nil
+}
+
+func TestRedshiftSink_Write(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	stager := &fakeStager{staged: map[string][]byte{}}
+	sink := newRedshiftSink(db, config.RedshiftConfig{Schema: "analytics", S3Prefix: "blnk", IAMRole: "arn:aws:iam::1:role/load"}, stager)
+	sink.now = func() time.Time { return time.Unix(0, 42) }
+
+	mock.ExpectExec(regexp.QuoteMeta("COPY analytics.ledger_transactions (transaction_id, precise_amount, created_at) FROM 's3://bucket/blnk/ledger_transactions/42.json.gz' IAM_ROLE 'arn:aws:iam::1:role/load' FORMAT AS JSON 'auto ignorecase' GZIP")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	require.NoError(t, sink.Write(context.Background(), testBatch()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, []string{"blnk/ledger_transactions/42.json.gz"}, stager.removed)
+
+	zr, err := gzip.NewReader(strings.NewReader(string(stager.staged["blnk/ledger_transactions/42.json.gz"])))
+	require.NoError(t, err)
+	lines, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, `{"created_at":"2026-03-01T09:00:00.000000Z","precise_amount":"1000","transaction_id":"txn_1"}`+"\n"+
+		`{"created_at":"2026-03-01T09:01:00.000000Z","precise_amount":null,"transaction_id":"txn_2"}`+"\n", string(lines))
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workerpool limits how many tasks a worker processes at once and scales that limit between the bounds
+// of the queue configuration: up while the transaction queues back up and transactions apply quickly, down when
+// the queues drain or applying slows, which usually means the balances or the database are contended.
+package workerpool
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Pool is a resizable limit on the tasks a worker processes at once. Tasks acquire a slot before they run and
+// release it when they finish; the Pool's concurrency is the number of slots.
+type Pool struct {
+	min, max      int
+	interval      time.Duration
+	targetLatency time.Duration
+	depth         func(context.Context) (int, error)
+
+	mu          sync.Mutex
+	concurrency int
+	active      int
+	changed     chan struct{} // Closed and replaced when a slot frees up or the concurrency changes
+	applied     int           // Transactions applied since the last scaling
+	applyTime   time.Duration // Time they took to apply
+	lastDepth   int
+	lastLatency time.Duration
+}
+
+// New returns a Pool starting at the minimum concurrency of the queue configuration. depth reports how many
+// transactions are waiting in the transaction queues.
+func New(cfg config.QueueConfig, depth func(context.Context) (int, error)) *Pool {
+	return &Pool{
+		min:           cfg.MinConcurrency,
+		max:           cfg.MaxConcurrency,
+		interval:      cfg.ScaleInterval,
+		targetLatency: cfg.TargetApplyLatency,
+		depth:         depth,
+		concurrency:   cfg.MinConcurrency,
+		changed:       make(chan struct{}),
+	}
+}
+
+// Max returns the most tasks the Pool can let run at once, which the worker must be able to run.
+func (p *Pool) Max() int {
+	return p.max
+}
+
+// Concurrency returns the number of tasks the Pool currently lets run at once.
+func (p *Pool) Concurrency() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.concurrency
+}
+
+// Acquire waits for a slot to run a task in, or for ctx to be done.
+func (p *Pool) Acquire(ctx context.Context) error {
+	for {
+		p.mu.Lock()
+		if p.active < p.concurrency {
+			p.active++
+			p.mu.Unlock()
+			return nil
+		}
+		changed := p.changed
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Release frees the slot of a finished task.
+func (p *Pool) Release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active--
+	p.notify()
+}
+
+// ObserveApply records how long a transaction took to apply, which scaling weighs against the target latency.
+func (p *Pool) ObserveApply(elapsed time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.applied++
+	p.applyTime += elapsed
+}
+
+// notify wakes the tasks waiting for a slot. The caller must hold p.mu.
+func (p *Pool) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// Run scales the Pool every scale interval until ctx is done. It does nothing when the bounds fix the
+// concurrency.
+func (p *Pool) Run(ctx context.Context) {
+	if p.min == p.max {
+		return
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		depth, err := p.depth(ctx)
+		if err != nil {
+			logrus.Errorf("Error reading transaction queue depth: %v", err)
+			continue
+		}
+		before := p.Concurrency()
+		if after := p.Scale(depth); after != before {
+			logrus.Infof(" [*] Scaled worker concurrency from %d to %d (queue depth %d)", before, after, depth)
+		}
+	}
+}
+
+// Scale sets the Pool's concurrency for the current queue depth and the average time transactions took to
+// apply since the last scaling, and returns it.
+func (p *Pool) Scale(depth int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var latency time.Duration
+	if p.applied > 0 {
+		latency = p.applyTime / time.Duration(p.applied)
+	}
+	p.applied, p.applyTime = 0, 0
+	p.lastDepth, p.lastLatency = depth, latency
+
+	if next := nextConcurrency(p.concurrency, depth, latency, p.targetLatency, p.min, p.max); next != p.concurrency {
+		p.concurrency = next
+		p.notify()
+	}
+	return p.concurrency
+}
+
+// nextConcurrency decides the concurrency after current. Applying slower than the target halves it, since more
+// tasks at once would only contend further; a backlog larger than the current concurrency doubles it; and
+// empty queues shrink it by one, so that a lull does not drop it all at once. The result stays within min and
+// max.
+func nextConcurrency(current, depth int, latency, target time.Duration, min, max int) int {
+	next := current
+	switch {
+	case target > 0 && latency > target:
+		next = current / 2
+	case depth > current:
+		next = current * 2
+	case depth == 0:
+		next = current - 1
+	}
+	if next < min {
+		next = min
+	}
+	if next > max {
+		next = max
+	}
+	return next
+}
+
+// WriteMetrics writes the state of the Pool in the Prometheus text format: its concurrency and bounds, the tasks
+// running, and the queue depth and apply latency it last scaled by.
+//
+// Parameters:
+// - w: The writer the metrics are written to.
+//
+// Returns:
+// - error: An error if the metrics could not be written.
+func (p *Pool) WriteMetrics(w io.Writer) error {
+	p.mu.Lock()
+	metrics := []struct {
+		name, help string
+		value      float64
+	}{
+		{"blnk_worker_concurrency", "Tasks the worker currently processes at once.", float64(p.concurrency)},
+		{"blnk_worker_min_concurrency", "Lower bound of the worker's concurrency.", float64(p.min)},
+		{"blnk_worker_max_concurrency", "Upper bound of the worker's concurrency.", float64(p.max)},
+		{"blnk_worker_active_tasks", "Tasks the worker is processing.", float64(p.active)},
+		{"blnk_worker_queue_depth", "Transactions waiting in the transaction queues when the worker last scaled.", float64(p.lastDepth)},
+		{"blnk_worker_apply_latency_seconds", "Average time transactions took to apply before the worker last scaled.", p.lastLatency.Seconds()},
+	}
+	p.mu.Unlock()
+
+	for _, metric := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", metric.name, metric.help, metric.name, metric.name, metric.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
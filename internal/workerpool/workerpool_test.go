@@ -0,0 +1,105 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workerpool
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPool(min, max int) *Pool {
+	return New(config.QueueConfig{MinConcurrency: min, MaxConcurrency: max, ScaleInterval: time.Second, TargetApplyLatency: 100 * time.Millisecond}, func(context.Context) (int, error) {
+		return 0, nil
+	})
+}
+
+func TestNextConcurrency(t *testing.T) {
+	target := 100 * time.Millisecond
+	tests := []struct {
+		name           string
+		current, depth int
+		latency        time.Duration
+		expected       int
+	}{
+		{"backlog doubles", 2, 10, 50 * time.Millisecond, 4},
+		{"backlog capped at max", 6, 100, 50 * time.Millisecond, 8},
+		{"slow applies halve despite backlog", 8, 100, 300 * time.Millisecond, 4},
+		{"slow applies stop at min", 1, 100, 300 * time.Millisecond, 1},
+		{"empty queues shrink by one", 4, 0, 0, 3},
+		{"steady queues keep concurrency", 4, 3, 50 * time.Millisecond, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, nextConcurrency(tt.current, tt.depth, tt.latency, target, 1, 8))
+		})
+	}
+}
+
+func TestPool_ScaleUsesApplyLatency(t *testing.T) {
+	pool := testPool(1, 8)
+	assert.Equal(t, 2, pool.Scale(10))
+	assert.Equal(t, 4, pool.Scale(10))
+
+	pool.ObserveApply(200 * time.Millisecond)
+	pool.ObserveApply(400 * time.Millisecond)
+	assert.Equal(t, 2, pool.Scale(10))
+
+	// The latency observed is reset by each scaling
+	assert.Equal(t, 4, pool.Scale(10))
+}
+
+func TestPool_AcquireWaitsForSlot(t *testing.T) {
+	pool := testPool(1, 2)
+	ctx := context.Background()
+	require.NoError(t, pool.Acquire(ctx))
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Acquire(timeout), context.DeadlineExceeded)
+
+	// Scaling up lets a waiting task run
+	acquired := make(chan error, 1)
+	go func() { acquired <- pool.Acquire(ctx) }()
+	pool.Scale(5)
+	select {
+	case err := <-acquired:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("task did not acquire a slot after scaling up")
+	}
+
+	pool.Release()
+	pool.Release()
+	require.NoError(t, pool.Acquire(ctx))
+}
+
+func TestPool_WriteMetrics(t *testing.T) {
+	pool := testPool(2, 6)
+	pool.Scale(10)
+
+	var buf bytes.Buffer
+	require.NoError(t, pool.WriteMetrics(&buf))
+	assert.Contains(t, buf.String(), "blnk_worker_concurrency 4\n")
+	assert.Contains(t, buf.String(), "blnk_worker_max_concurrency 6\n")
+	assert.Contains(t, buf.String(), "blnk_worker_queue_depth 10\n")
+}
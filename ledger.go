@@ -50,39 +50,45 @@ func (l *Blnk) postLedgerActions(_ context.Context, ledger *model.Ledger) {
 // It calls postLedgerActions after a successful creation.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - ledger: A Ledger model representing the ledger to be created.
 //
 // Returns:
 // - model.Ledger: The created Ledger model.
 // - error: An error if the ledger could not be created.
-func (l *Blnk) CreateLedger(ledger model.Ledger) (model.Ledger, error) {
-	ledger, err := l.datasource.CreateLedger(ledger)
+func (l *Blnk) CreateLedger(ctx context.Context, ledger model.Ledger) (model.Ledger, error) {
+	ledger, err := l.datasource.CreateLedger(ctx, ledger)
 	if err != nil {
 		return model.Ledger{}, err
 	}
-	l.postLedgerActions(context.Background(), &ledger)
+	l.postLedgerActions(ctx, &ledger)
 	return ledger, nil
 }
 
-// GetAllLedgers retrieves all ledgers from the datasource.
+// GetAllLedgers retrieves a page of ledgers from the datasource, newest first.
 // It returns a slice of Ledger models and an error if the operation fails.
 //
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - page model.Page: The page of ledgers to read.
+//
 // Returns:
 // - []model.Ledger: A slice of Ledger models.
 // - error: An error if the ledgers could not be retrieved.
-func (l *Blnk) GetAllLedgers(limit, offset int) ([]model.Ledger, error) {
-	return l.datasource.GetAllLedgers(limit, offset)
+func (l *Blnk) GetAllLedgers(ctx context.Context, page model.Page) ([]model.Ledger, error) {
+	return l.datasource.GetAllLedgers(ctx, page)
 }
 
 // GetLedgerByID retrieves a ledger by its ID from the datasource.
 // It returns a pointer to the Ledger model and an error if the operation fails.
 //
 // Parameters:
+// - ctx: The context for the operation.
 // - id: A string representing the ID of the ledger to retrieve.
 //
 // Returns:
 // - *model.Ledger: A pointer to the Ledger model if found.
 // - error: An error if the ledger could not be retrieved.
-func (l *Blnk) GetLedgerByID(id string) (*model.Ledger, error) {
-	return l.datasource.GetLedgerByID(id)
+func (l *Blnk) GetLedgerByID(ctx context.Context, id string) (*model.Ledger, error) {
+	return l.datasource.GetLedgerByID(ctx, id)
 }
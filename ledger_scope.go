@@ -0,0 +1,197 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// ErrOutsideLedgerScope is returned when a request touches a ledger or balance outside the ledger scope
+// of the API key that made it.
+var ErrOutsideLedgerScope = errors.New("access denied: outside the ledger scope of this API key")
+
+// CheckLedgerAccess reports whether the ledger is within the ledger scope carried by ctx.
+//
+// Parameters:
+// - ctx: The context carrying the caller's ledger scope.
+// - ledgerID: The ledger being accessed.
+//
+// Returns:
+// - error: ErrOutsideLedgerScope if the ledger is not in scope.
+func (l *Blnk) CheckLedgerAccess(ctx context.Context, ledgerID string) error {
+	scope, ok := ledgerscope.FromContext(ctx)
+	if ok && !scope.AllowsLedger(ledgerID) {
+		return ErrOutsideLedgerScope
+	}
+	return nil
+}
+
+// CheckBalanceAccess reports whether the balance is within the ledger scope carried by ctx.
+//
+// Parameters:
+// - ctx: The context carrying the caller's ledger scope.
+// - balanceID: The balance being accessed.
+//
+// Returns:
+// - error: ErrOutsideLedgerScope if the balance is not in scope, or an error if it cannot be found.
+func (l *Blnk) CheckBalanceAccess(ctx context.Context, balanceID string) error {
+	scope, ok := ledgerscope.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	balance, err := l.datasource.GetBalanceByIDLite(ctx, balanceID)
+	if err != nil {
+		return err
+	}
+	if !scope.AllowsBalance(balance.BalanceID, balance.LedgerID) {
+		return ErrOutsideLedgerScope
+	}
+	return nil
+}
+
+// CheckTransactionAccess reports whether every balance a transaction moves money between is within
+// the ledger scope carried by ctx. Aliases are checked as the balances they name. Indicators that do
+// not have a balance yet are created in the general ledger, so they are checked against it.
+//
+// Parameters:
+// - ctx: The context carrying the caller's ledger scope.
+// - txn: The transaction being created or accessed.
+//
+// Returns:
+// - error: ErrOutsideLedgerScope if any balance is not in scope.
+func (l *Blnk) CheckTransactionAccess(ctx context.Context, txn *model.Transaction) error {
+	scope, ok := ledgerscope.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	balances, err := l.transactionBalances(ctx, txn)
+	if err != nil {
+		return err
+	}
+	return checkBalancesInScope(scope, balances...)
+}
+
+// checkBalancesInScope reports whether every balance is within scope.
+func checkBalancesInScope(scope ledgerscope.Scope, balances ...*model.Balance) error {
+	for _, balance := range balances {
+		if !scope.AllowsBalance(balance.BalanceID, balance.LedgerID) {
+			return ErrOutsideLedgerScope
+		}
+	}
+	return nil
+}
+
+// transactionBalances returns the balances a transaction moves money between, with aliases resolved to the
+// balances they name. Indicators that do not have a balance yet are returned as a balance of the general
+// ledger, where they will be created.
+func (l *Blnk) transactionBalances(ctx context.Context, txn *model.Transaction) ([]*model.Balance, error) {
+	parties := []string{txn.Source, txn.Destination}
+	for _, distribution := range txn.Sources {
+		parties = append(parties, distribution.Identifier)
+	}
+	for _, distribution := range txn.Destinations {
+		parties = append(parties, distribution.Identifier)
+	}
+
+	var balances []*model.Balance
+	for _, party := range parties {
+		if party == "" {
+			continue
+		}
+
+		party, err := l.resolveBalanceAlias(ctx, party)
+		if err != nil {
+			return nil, err
+		}
+
+		var balance *model.Balance
+		if strings.HasPrefix(party, "@") {
+			balance, err = l.datasource.GetBalanceByIndicator(ctx, party, txn.Currency)
+			if err != nil {
+				balance = &model.Balance{LedgerID: GeneralLedgerID}
+			}
+		} else {
+			balance, err = l.datasource.GetBalanceByIDLite(ctx, party)
+			if err != nil {
+				return nil, err
+			}
+		}
+		balances = append(balances, balance)
+	}
+	return balances, nil
+}
+
+// CheckTransactionIDAccess reports whether an existing transaction is within the ledger scope carried by ctx.
+//
+// Parameters:
+// - ctx: The context carrying the caller's ledger scope.
+// - transactionID: The transaction being accessed.
+//
+// Returns:
+// - error: ErrOutsideLedgerScope if the transaction is not in scope or cannot be verified.
+func (l *Blnk) CheckTransactionIDAccess(ctx context.Context, transactionID string) error {
+	if _, ok := ledgerscope.FromContext(ctx); !ok {
+		return nil
+	}
+
+	txn, err := l.datasource.GetTransaction(ctx, transactionID)
+	if err != nil {
+		// Batches and other records that cannot be resolved to balances are not accessible to scoped keys
+		return ErrOutsideLedgerScope
+	}
+	return l.CheckTransactionAccess(ctx, txn)
+}
+
+// CheckEntityAccess reports whether the ledger, balance or transaction identified by entityID is within
+// the ledger scope carried by ctx. Identities are not tied to a ledger and are always in scope.
+//
+// Parameters:
+// - ctx: The context carrying the caller's ledger scope.
+// - entityID: The ID of the entity being accessed.
+//
+// Returns:
+// - error: ErrOutsideLedgerScope if the entity is not in scope.
+func (l *Blnk) CheckEntityAccess(ctx context.Context, entityID string) error {
+	if _, ok := ledgerscope.FromContext(ctx); !ok {
+		return nil
+	}
+
+	entityType, err := getEntityTypeFromID(entityID)
+	if err != nil {
+		return err
+	}
+
+	switch entityType {
+	case "ledgers":
+		return l.CheckLedgerAccess(ctx, entityID)
+	case "balances":
+		return l.CheckBalanceAccess(ctx, entityID)
+	case "transactions":
+		return l.CheckTransactionIDAccess(ctx, entityID)
+	default:
+		return nil
+	}
+}
+
+// EstimateRowCount returns an estimate of the number of rows in a table, for reporting the total of a list
+// without counting it. Callers restricted to specific ledgers get no estimate, since it counts rows outside
+// their scope.
+//
+// Parameters:
+// - ctx: The context carrying the caller's ledger scope.
+// - table: The schema-qualified table name.
+//
+// Returns:
+// - int64: The estimated row count, or -1 when there is no estimate.
+// - error: An error if the estimate could not be read.
+func (l *Blnk) EstimateRowCount(ctx context.Context, table string) (int64, error) {
+	if scope, ok := ledgerscope.FromContext(ctx); ok && scope.Restricted() {
+		return -1, nil
+	}
+	return l.datasource.EstimateRowCount(ctx, table)
+}
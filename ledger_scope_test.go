@@ -0,0 +1,58 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"testing"
+
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckTransactionAccess(t *testing.T) {
+	mockDS := new(mocks.MockDataSource)
+	b := &Blnk{datasource: mockDS}
+
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_cards_1").Return(&model.Balance{BalanceID: "bln_cards_1", LedgerID: "ldg_cards"}, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_loans_1").Return(&model.Balance{BalanceID: "bln_loans_1", LedgerID: "ldg_loans"}, nil)
+	mockDS.On("GetBalanceByIndicator", mock.Anything, "@Settlement", "USD").Return((*model.Balance)(nil), errors.New("balance not found"))
+
+	txn := &model.Transaction{Source: "bln_cards_1", Destination: "bln_loans_1", Currency: "USD"}
+
+	// Unscoped requests are not checked
+	assert.NoError(t, b.CheckTransactionAccess(context.Background(), txn))
+
+	ctx := ledgerscope.WithScope(context.Background(), ledgerscope.Scope{LedgerIDs: []string{"ldg_cards"}})
+	assert.ErrorIs(t, b.CheckTransactionAccess(ctx, txn), ErrOutsideLedgerScope)
+
+	// Indicators without a balance are created in the general ledger
+	txn = &model.Transaction{Source: "bln_cards_1", Destination: "@Settlement", Currency: "USD"}
+	assert.ErrorIs(t, b.CheckTransactionAccess(ctx, txn), ErrOutsideLedgerScope)
+
+	ctx = ledgerscope.WithScope(context.Background(), ledgerscope.Scope{LedgerIDs: []string{"ldg_cards", GeneralLedgerID}})
+	assert.NoError(t, b.CheckTransactionAccess(ctx, txn))
+
+	ctx = ledgerscope.WithScope(context.Background(), ledgerscope.Scope{BalancePrefixes: []string{"bln_cards_", "bln_loans_"}})
+	txn = &model.Transaction{Sources: []model.Distribution{{Identifier: "bln_cards_1"}}, Destination: "bln_loans_1", Currency: "USD"}
+	assert.NoError(t, b.CheckTransactionAccess(ctx, txn))
+	assert.ErrorIs(t, b.CheckLedgerAccess(ctx, "ldg_cards"), ErrOutsideLedgerScope)
+}
@@ -0,0 +1,57 @@
+package blnk
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blnkfinance/blnk/model"
+)
+
+// GetLedgerSequence retrieves the numbered transactions of a ledger after a sequence number, in order. Sequence
+// numbers have no gaps, so a consumer reading the sequence page by page can tell a record is missing when a
+// number is skipped, and is caught up once it has read LastSequence.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - ledgerID: The ID of the ledger.
+// - after: The last sequence number already read, 0 to start from the beginning.
+// - limit: The maximum number of transactions to return.
+//
+// Returns:
+// - *model.LedgerSequencePage: The transactions and the last sequence number of the ledger.
+// - error: An error if the ledger cannot be found or the sequence cannot be read.
+func (l *Blnk) GetLedgerSequence(ctx context.Context, ledgerID string, after int64, limit int) (*model.LedgerSequencePage, error) {
+	ctx, span := tracer.Start(ctx, "GetLedgerSequence")
+	defer span.End()
+
+	if after < 0 {
+		return nil, fmt.Errorf("after cannot be negative")
+	}
+	if _, err := l.datasource.GetLedgerByID(ctx, ledgerID); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	page, err := l.datasource.GetLedgerSequence(ctx, ledgerID, after, limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return page, nil
+}
+
+// attachTransactionSequences adds the sequence numbers of a transaction that moved balances, which are not read
+// with the transaction itself.
+func (l *Blnk) attachTransactionSequences(ctx context.Context, transaction *model.Transaction) error {
+	if !model.IsSequencedStatus(transaction.Status) || len(transaction.Sequences) > 0 {
+		return nil
+	}
+	sequences, err := l.datasource.GetTransactionSequences(ctx, transaction.TransactionID)
+	if err != nil {
+		return err
+	}
+	if len(sequences) > 0 {
+		transaction.Sequences = sequences
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTransaction_AttachesSequences(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	mockDS.On("GetTransaction", mock.Anything, "txn_applied").
+		Return(&model.Transaction{TransactionID: "txn_applied", Status: StatusApplied}, nil)
+	mockDS.On("GetTransactionSequences", mock.Anything, "txn_applied").
+		Return([]model.LedgerSequence{{LedgerID: "ldg_a", Sequence: 12}}, nil)
+	mockDS.On("GetTransaction", mock.Anything, "txn_queued").
+		Return(&model.Transaction{TransactionID: "txn_queued", Status: StatusQueued}, nil)
+
+	applied, err := b.GetTransaction(context.Background(), "txn_applied")
+	require.NoError(t, err)
+	assert.Equal(t, []model.LedgerSequence{{LedgerID: "ldg_a", Sequence: 12}}, applied.Sequences)
+
+	// Queued transactions have not moved balances and are not numbered
+	queued, err := b.GetTransaction(context.Background(), "txn_queued")
+	require.NoError(t, err)
+	assert.Empty(t, queued.Sequences)
+	mockDS.AssertNotCalled(t, "GetTransactionSequences", mock.Anything, "txn_queued")
+}
+
+func TestGetLedgerSequence(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	page := &model.LedgerSequencePage{LedgerID: "ldg_a", LastSequence: 2, Entries: []model.LedgerSequenceEntry{{Sequence: 2, TransactionID: "txn_2"}}}
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_a").Return(&model.Ledger{LedgerID: "ldg_a"}, nil)
+	mockDS.On("GetLedgerSequence", mock.Anything, "ldg_a", int64(1), 50).Return(page, nil)
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_missing").Return((*model.Ledger)(nil), errors.New("Ledger not found"))
+
+	got, err := b.GetLedgerSequence(context.Background(), "ldg_a", 1, 50)
+	require.NoError(t, err)
+	assert.Equal(t, page, got)
+
+	_, err = b.GetLedgerSequence(context.Background(), "ldg_missing", 0, 50)
+	assert.ErrorContains(t, err, "not found")
+
+	_, err = b.GetLedgerSequence(context.Background(), "ldg_a", -1, 50)
+	assert.Error(t, err)
+}
@@ -0,0 +1,199 @@
+package blnk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+)
+
+// ExportLedgerTemplate exports the structure of a ledger without its transaction data: its balances, their
+// monitors and minimums, the ledger's minimums by currency and the currencies of its balances. The shards of
+// sharded balances are left out, as sharding is tuned per environment.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - ledgerID string: The ID of the ledger to export.
+//
+// Returns:
+// - *model.LedgerTemplate: The template of the ledger.
+// - error: An error if the ledger is not found or its structure could not be read.
+func (l *Blnk) ExportLedgerTemplate(ctx context.Context, ledgerID string) (*model.LedgerTemplate, error) {
+	ctx, span := tracer.Start(ctx, "ExportLedgerTemplate")
+	defer span.End()
+
+	ledger, err := l.datasource.GetLedgerByID(ctx, ledgerID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	balances, err := l.datasource.GetBalancesByLedger(ctx, ledgerID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	minimums, err := l.datasource.ListMinimumBalances(ctx, model.Page{})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	template := &model.LedgerTemplate{
+		Version:        model.LedgerTemplateVersion,
+		SourceLedgerID: ledger.LedgerID,
+		Name:           ledger.Name,
+		MetaData:       ledger.MetaData,
+		Currencies:     []string{},
+		Balances:       []model.LedgerTemplateBalance{},
+		ExportedAt:     time.Now().UTC(),
+	}
+
+	balanceMinimums := make(map[string]*model.MinimumBalance)
+	for _, minimum := range minimums {
+		switch {
+		case minimum.BalanceID != "":
+			balanceMinimums[minimum.BalanceID] = minimum
+		case minimum.LedgerID == ledgerID:
+			template.MinimumBalances = append(template.MinimumBalances, model.LedgerTemplateMinimum{Currency: minimum.Currency, PreciseAmount: minimum.PreciseAmount})
+		}
+	}
+
+	currencies := make(map[string]bool)
+	for _, balance := range balances {
+		if _, ok := balance.MetaData[model.ShardOfMetaKey]; ok {
+			continue
+		}
+		monitors, err := l.datasource.GetBalanceMonitors(ctx, balance.BalanceID)
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("failed to get monitors of balance %s: %w", balance.BalanceID, err)
+		}
+
+		skeleton := model.LedgerTemplateBalance{
+			SourceBalanceID:    balance.BalanceID,
+			Indicator:          balance.Indicator,
+			Currency:           balance.Currency,
+			CurrencyMultiplier: balance.CurrencyMultiplier,
+			MetaData:           balance.MetaData,
+		}
+		for _, monitor := range monitors {
+			skeleton.Monitors = append(skeleton.Monitors, model.LedgerTemplateMonitor{Description: monitor.Description, Condition: monitor.Condition})
+		}
+		if minimum, ok := balanceMinimums[balance.BalanceID]; ok {
+			skeleton.MinimumBalance = minimum.PreciseAmount
+		}
+		template.Balances = append(template.Balances, skeleton)
+		currencies[balance.Currency] = true
+	}
+	for _, minimum := range template.MinimumBalances {
+		currencies[minimum.Currency] = true
+	}
+	for currency := range currencies {
+		template.Currencies = append(template.Currencies, currency)
+	}
+	sort.Strings(template.Currencies)
+	return template, nil
+}
+
+// ImportLedgerTemplate creates a new ledger from a template, with a balance of zero amounts for each balance
+// of the template along with its monitors and minimums. Every currency of the template must be registered in
+// this environment; nothing is created otherwise. Balances whose indicator is already in use are skipped.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - template *model.LedgerTemplate: The template to import.
+// - name string: The name of the new ledger. Defaults to the name in the template.
+//
+// Returns:
+// - *model.LedgerTemplateImport: The new ledger and the balances created for the template.
+// - error: An error if the template is invalid or the ledger, a monitor or a minimum could not be created.
+func (l *Blnk) ImportLedgerTemplate(ctx context.Context, template *model.LedgerTemplate, name string) (*model.LedgerTemplateImport, error) {
+	ctx, span := tracer.Start(ctx, "ImportLedgerTemplate")
+	defer span.End()
+
+	if template.Version != model.LedgerTemplateVersion {
+		return nil, fmt.Errorf("unsupported ledger template version %d", template.Version)
+	}
+	if name == "" {
+		name = template.Name
+	}
+	for _, currency := range template.Currencies {
+		if _, err := LookupCurrency(currency); err != nil {
+			return nil, fmt.Errorf("the template's currencies must be registered: %w", err)
+		}
+	}
+
+	ledger, err := l.CreateLedger(ctx, model.Ledger{Name: name, MetaData: template.MetaData})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	result := &model.LedgerTemplateImport{Ledger: ledger, Balances: make(map[string]string), Skipped: []model.LedgerTemplateSkipped{}}
+
+	for _, skeleton := range template.Balances {
+		balance, err := l.CreateBalance(ctx, model.Balance{
+			LedgerID:           ledger.LedgerID,
+			Indicator:          skeleton.Indicator,
+			Currency:           skeleton.Currency,
+			CurrencyMultiplier: skeleton.CurrencyMultiplier,
+			MetaData:           skeleton.MetaData,
+		})
+		if err != nil {
+			result.Skipped = append(result.Skipped, model.LedgerTemplateSkipped{SourceBalanceID: skeleton.SourceBalanceID, Reason: err.Error()})
+			continue
+		}
+		if balance.BalanceID == "" {
+			result.Skipped = append(result.Skipped, model.LedgerTemplateSkipped{SourceBalanceID: skeleton.SourceBalanceID, Reason: fmt.Sprintf("indicator %s is already in use for %s", skeleton.Indicator, skeleton.Currency)})
+			continue
+		}
+		result.Balances[skeleton.SourceBalanceID] = balance.BalanceID
+
+		for _, monitor := range skeleton.Monitors {
+			if _, err := l.CreateMonitor(ctx, model.BalanceMonitor{BalanceID: balance.BalanceID, Description: monitor.Description, Condition: monitor.Condition}); err != nil {
+				span.RecordError(err)
+				return result, fmt.Errorf("failed to create a monitor of balance %s in ledger %s: %w", balance.BalanceID, ledger.LedgerID, err)
+			}
+			result.Monitors++
+		}
+		if skeleton.MinimumBalance != nil {
+			if _, err := l.SetMinimumBalance(ctx, model.MinimumBalance{BalanceID: balance.BalanceID, PreciseAmount: skeleton.MinimumBalance}); err != nil {
+				span.RecordError(err)
+				return result, fmt.Errorf("failed to set the minimum of balance %s in ledger %s: %w", balance.BalanceID, ledger.LedgerID, err)
+			}
+			result.MinimumBalances++
+		}
+	}
+
+	for _, minimum := range template.MinimumBalances {
+		if _, err := l.SetMinimumBalance(ctx, model.MinimumBalance{LedgerID: ledger.LedgerID, Currency: minimum.Currency, PreciseAmount: minimum.PreciseAmount}); err != nil {
+			span.RecordError(err)
+			return result, fmt.Errorf("failed to set the %s minimum of ledger %s: %w", minimum.Currency, ledger.LedgerID, err)
+		}
+		result.MinimumBalances++
+	}
+	return result, nil
+}
+
+// CloneLedger creates a copy of a ledger's structure in this environment, as if its template were exported
+// and imported.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - ledgerID string: The ID of the ledger to clone.
+// - name string: The name of the new ledger. Defaults to the name of the ledger with " (copy)" appended.
+//
+// Returns:
+// - *model.LedgerTemplateImport: The new ledger and the balances created for the ledger's balances.
+// - error: An error if the ledger could not be exported or its copy could not be created.
+func (l *Blnk) CloneLedger(ctx context.Context, ledgerID, name string) (*model.LedgerTemplateImport, error) {
+	template, err := l.ExportLedgerTemplate(ctx, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = template.Name + " (copy)"
+	}
+	return l.ImportLedgerTemplate(ctx, template, name)
+}
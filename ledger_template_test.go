@@ -0,0 +1,108 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportLedgerTemplate(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_1").Return(&model.Ledger{LedgerID: "ldg_1", Name: "Wallets", MetaData: map[string]interface{}{"team": "payments"}}, nil)
+	mockDS.On("GetBalancesByLedger", mock.Anything, "ldg_1").Return([]model.Balance{
+		{BalanceID: "bln_fees", Indicator: "@fees", Currency: "USD", CurrencyMultiplier: 100, LedgerID: "ldg_1", Balance: big.NewInt(5000)},
+		{BalanceID: "bln_eur", Currency: "EUR", CurrencyMultiplier: 100, LedgerID: "ldg_1"},
+		{BalanceID: "bln_shard", Currency: "USD", LedgerID: "ldg_1", MetaData: map[string]interface{}{model.ShardOfMetaKey: "bln_fees"}},
+	}, nil)
+	mockDS.On("ListMinimumBalances", mock.Anything, model.Page{}).Return([]*model.MinimumBalance{
+		{BalanceID: "bln_fees", PreciseAmount: big.NewInt(100)},
+		{LedgerID: "ldg_1", Currency: "NGN", PreciseAmount: big.NewInt(0)},
+		{LedgerID: "ldg_2", Currency: "USD", PreciseAmount: big.NewInt(7)},
+	}, nil)
+	mockDS.On("GetBalanceMonitors", mock.Anything, "bln_fees").Return([]model.BalanceMonitor{
+		{MonitorID: "mon_1", BalanceID: "bln_fees", Description: "low fees", CallBackURL: "https://prod.example.com", Condition: model.AlertCondition{Field: "balance", Operator: "<", Value: 10, Precision: 100}},
+	}, nil)
+	mockDS.On("GetBalanceMonitors", mock.Anything, "bln_eur").Return([]model.BalanceMonitor{}, nil)
+
+	template, err := b.ExportLedgerTemplate(context.Background(), "ldg_1")
+	require.NoError(t, err)
+	assert.Equal(t, model.LedgerTemplateVersion, template.Version)
+	assert.Equal(t, "Wallets", template.Name)
+	assert.Equal(t, []string{"EUR", "NGN", "USD"}, template.Currencies)
+	require.Len(t, template.Balances, 2, "shards are not exported")
+	assert.Equal(t, "@fees", template.Balances[0].Indicator)
+	assert.Equal(t, big.NewInt(100), template.Balances[0].MinimumBalance)
+	require.Len(t, template.Balances[0].Monitors, 1)
+	assert.Equal(t, "low fees", template.Balances[0].Monitors[0].Description)
+	assert.Equal(t, []model.LedgerTemplateMinimum{{Currency: "NGN", PreciseAmount: big.NewInt(0)}}, template.MinimumBalances)
+	mockDS.AssertNotCalled(t, "GetBalanceMonitors", mock.Anything, "bln_shard")
+}
+
+func TestImportLedgerTemplate(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	template := &model.LedgerTemplate{
+		Version:    model.LedgerTemplateVersion,
+		Name:       "Wallets",
+		Currencies: []string{"NGN", "USD"},
+		Balances: []model.LedgerTemplateBalance{
+			{SourceBalanceID: "bln_fees", Indicator: "@fees", Currency: "USD", CurrencyMultiplier: 100, MinimumBalance: big.NewInt(100),
+				Monitors: []model.LedgerTemplateMonitor{{Description: "low fees", Condition: model.AlertCondition{Field: "balance", Operator: "<", Value: 10, Precision: 100}}}},
+			{SourceBalanceID: "bln_ops", Indicator: "@ops", Currency: "USD", CurrencyMultiplier: 100},
+		},
+		MinimumBalances: []model.LedgerTemplateMinimum{{Currency: "NGN", PreciseAmount: big.NewInt(0)}},
+	}
+
+	mockDS.On("CreateLedger", mock.Anything, model.Ledger{Name: "Staging wallets"}).Return(model.Ledger{LedgerID: "ldg_new", Name: "Staging wallets"}, nil)
+	mockDS.On("CreateBalance", mock.Anything, mock.MatchedBy(func(balance model.Balance) bool { return balance.Indicator == "@fees" && balance.LedgerID == "ldg_new" })).
+		Return(model.Balance{BalanceID: "bln_new", LedgerID: "ldg_new", Currency: "USD"}, nil)
+	// The indicator of the second balance is taken, so no balance is returned
+	mockDS.On("CreateBalance", mock.Anything, mock.MatchedBy(func(balance model.Balance) bool { return balance.Indicator == "@ops" })).Return(model.Balance{}, nil)
+	mockDS.On("CreateMonitor", mock.Anything, mock.MatchedBy(func(monitor model.BalanceMonitor) bool {
+		return monitor.BalanceID == "bln_new" && monitor.CallBackURL == "" && monitor.Condition.PreciseValue.Cmp(big.NewInt(1000)) == 0
+	})).Return(model.BalanceMonitor{MonitorID: "mon_new"}, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_new").Return(&model.Balance{BalanceID: "bln_new"}, nil)
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_new").Return(&model.Ledger{LedgerID: "ldg_new"}, nil)
+	mockDS.On("SetMinimumBalance", mock.Anything, mock.Anything).Return(nil)
+
+	result, err := b.ImportLedgerTemplate(context.Background(), template, "Staging wallets")
+	require.NoError(t, err)
+	assert.Equal(t, "ldg_new", result.Ledger.LedgerID)
+	assert.Equal(t, map[string]string{"bln_fees": "bln_new"}, result.Balances)
+	assert.Equal(t, 1, result.Monitors)
+	assert.Equal(t, 2, result.MinimumBalances)
+	require.Len(t, result.Skipped, 1)
+	assert.Equal(t, "bln_ops", result.Skipped[0].SourceBalanceID)
+	mockDS.AssertNumberOfCalls(t, "SetMinimumBalance", 2)
+}
+
+func TestImportLedgerTemplate_Invalid(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	_, err := b.ImportLedgerTemplate(context.Background(), &model.LedgerTemplate{Version: 2}, "")
+	assert.ErrorContains(t, err, "unsupported ledger template version")
+
+	_, err = b.ImportLedgerTemplate(context.Background(), &model.LedgerTemplate{Version: model.LedgerTemplateVersion, Currencies: []string{"XYZ"}}, "")
+	assert.ErrorContains(t, err, "XYZ")
+	mockDS.AssertNotCalled(t, "CreateLedger", mock.Anything, mock.Anything)
+}
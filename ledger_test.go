@@ -17,6 +17,7 @@ limitations under the License.
 package blnk
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"testing"
@@ -86,7 +87,7 @@ func TestCreateLedger(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Execute the test function
-	result, err := d.CreateLedger(ledger)
+	result, err := d.CreateLedger(context.Background(), ledger)
 	// Assertions
 	assert.NoError(t, err)
 	assert.NotEmpty(t, result.LedgerID)
@@ -113,11 +114,11 @@ func TestGetAllLedgers(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"ledger_id", "name", "created_at", "meta_data"}).
 		AddRow("ldg_1234567", "general ledger", time.Now(), `{"key":"value"}`)
 
-	mock.ExpectQuery("SELECT ledger_id, name, created_at, meta_data FROM blnk.ledgers ORDER BY created_at DESC LIMIT \\$1 OFFSET \\$2").
+	mock.ExpectQuery("SELECT ledger_id, name, created_at, meta_data FROM blnk.ledgers WHERE TRUE ORDER BY created_at DESC, ledger_id DESC LIMIT \\$1 OFFSET \\$2").
 		WithArgs(1, 1).
 		WillReturnRows(rows)
 
-	result, err := d.GetAllLedgers(1, 1)
+	result, err := d.GetAllLedgers(context.Background(), model.Page{Limit: 1, Offset: 1})
 
 	assert.NoError(t, err)
 	assert.Len(t, result, 1)
@@ -146,7 +147,7 @@ func TestGetLedgerByID(t *testing.T) {
 		WithArgs(testID).
 		WillReturnRows(row)
 
-	result, err := d.GetLedgerByID(testID)
+	result, err := d.GetLedgerByID(context.Background(), testID)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -0,0 +1,220 @@
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maintenanceKey         = "maintenance"
+	maintenanceGlobalField = "global"
+
+	// defaultMaintenanceRetryAfter is how many seconds clients are asked to wait when maintenance is enabled
+	// without saying.
+	defaultMaintenanceRetryAfter = 60
+)
+
+var (
+	// ErrMaintenanceMode is wrapped by the errors of operations rejected because of maintenance mode.
+	ErrMaintenanceMode = errors.New("maintenance mode")
+
+	// ErrInvalidMaintenanceMode is returned when maintenance mode is set with a negative retry_after.
+	ErrInvalidMaintenanceMode = errors.New("retry_after cannot be negative")
+)
+
+// MaintenanceError is returned when an operation is rejected because Blnk, or a ledger it changes, is in
+// maintenance mode. It wraps ErrMaintenanceMode.
+type MaintenanceError struct {
+	Mode model.MaintenanceMode
+}
+
+func (e *MaintenanceError) Error() string {
+	scope := "Blnk is"
+	if e.Mode.LedgerID != "" {
+		scope = fmt.Sprintf("ledger %s is", e.Mode.LedgerID)
+	}
+	message := scope + " in maintenance mode and only serving reads"
+	if e.Mode.Reason != "" {
+		message += ": " + e.Mode.Reason
+	}
+	return message
+}
+
+func (e *MaintenanceError) Unwrap() error {
+	return ErrMaintenanceMode
+}
+
+// GetMaintenanceStatus retrieves the global maintenance mode and the ledgers in maintenance, ordered by ledger ID.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - *model.MaintenanceStatus: The maintenance status.
+// - error: An error if the status could not be read.
+func (l *Blnk) GetMaintenanceStatus(ctx context.Context) (*model.MaintenanceStatus, error) {
+	values, err := l.redis.HGetAll(ctx, maintenanceKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &model.MaintenanceStatus{Ledgers: []model.MaintenanceMode{}}
+	for field, value := range values {
+		var mode model.MaintenanceMode
+		if err := json.Unmarshal([]byte(value), &mode); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal maintenance mode: %w", err)
+		}
+		if field == maintenanceGlobalField {
+			status.Global = mode
+		} else {
+			status.Ledgers = append(status.Ledgers, mode)
+		}
+	}
+	sort.Slice(status.Ledgers, func(i, j int) bool { return status.Ledgers[i].LedgerID < status.Ledgers[j].LedgerID })
+	return status, nil
+}
+
+// SetMaintenanceMode enables or disables maintenance mode globally, or for the ledger of mode.LedgerID. It
+// applies to every server sharing the Redis instance from their next request, and to the workers from their next
+// queued transaction or scheduled run. Jobs that only read or export data keep running.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - mode model.MaintenanceMode: Whether maintenance is enabled, why, and how long clients should wait.
+//
+// Returns:
+// - *model.MaintenanceMode: The maintenance mode set.
+// - error: ErrInvalidMaintenanceMode if the mode is invalid, or an error if the ledger does not exist or the
+// mode could not be stored.
+func (l *Blnk) SetMaintenanceMode(ctx context.Context, mode model.MaintenanceMode) (*model.MaintenanceMode, error) {
+	if mode.RetryAfter < 0 {
+		return nil, ErrInvalidMaintenanceMode
+	}
+	if mode.LedgerID != "" {
+		if _, err := l.datasource.GetLedgerByID(ctx, mode.LedgerID); err != nil {
+			return nil, err
+		}
+	}
+	if mode.RetryAfter == 0 {
+		mode.RetryAfter = defaultMaintenanceRetryAfter
+	}
+	mode.UpdatedBy = tenant.FromContext(ctx)
+	if mode.UpdatedBy == "" {
+		mode.UpdatedBy = model.ActorSystem
+	}
+	mode.UpdatedAt = time.Now()
+
+	field := maintenanceField(mode.LedgerID)
+	if !mode.Enabled {
+		if err := l.redis.HDel(ctx, maintenanceKey, field).Err(); err != nil {
+			return nil, err
+		}
+		logrus.Warnf("maintenance mode disabled for %s by %s", field, mode.UpdatedBy)
+		return &mode, nil
+	}
+
+	data, err := json.Marshal(mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal maintenance mode: %w", err)
+	}
+	if err := l.redis.HSet(ctx, maintenanceKey, field, data).Err(); err != nil {
+		return nil, err
+	}
+	logrus.Warnf("maintenance mode enabled for %s by %s: %s", field, mode.UpdatedBy, mode.Reason)
+	return &mode, nil
+}
+
+// CheckMaintenance reports whether Blnk is in global maintenance mode, returning a *MaintenanceError if it is.
+// Operations are not rejected when the maintenance status cannot be read.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - error: A *MaintenanceError if Blnk is in maintenance mode.
+func (l *Blnk) CheckMaintenance(ctx context.Context) error {
+	status, ok := l.maintenanceStatus(ctx)
+	if ok && status.Global.Enabled {
+		return &MaintenanceError{Mode: status.Global}
+	}
+	return nil
+}
+
+// checkLedgerMaintenance returns a *MaintenanceError when Blnk or one of the ledgers is in maintenance mode.
+func (l *Blnk) checkLedgerMaintenance(ctx context.Context, ledgerIDs ...string) error {
+	status, ok := l.maintenanceStatus(ctx)
+	if !ok {
+		return nil
+	}
+	if status.Global.Enabled {
+		return &MaintenanceError{Mode: status.Global}
+	}
+	for _, ledgerID := range ledgerIDs {
+		if mode, ok := status.LedgerMode(ledgerID); ok {
+			return &MaintenanceError{Mode: mode}
+		}
+	}
+	return nil
+}
+
+// CheckTransactionMaintenance reports whether Blnk or a ledger of a balance the transaction moves money between is
+// in maintenance mode. Balances are only looked up while some ledger is in maintenance.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - txn *model.Transaction: The transaction to check.
+//
+// Returns:
+// - error: A *MaintenanceError if Blnk or a ledger of the transaction is in maintenance mode.
+func (l *Blnk) CheckTransactionMaintenance(ctx context.Context, txn *model.Transaction) error {
+	status, ok := l.maintenanceStatus(ctx)
+	if !ok {
+		return nil
+	}
+	if status.Global.Enabled {
+		return &MaintenanceError{Mode: status.Global}
+	}
+	if len(status.Ledgers) == 0 {
+		return nil
+	}
+
+	balances, err := l.transactionBalances(ctx, txn)
+	if err != nil {
+		return err
+	}
+	for _, balance := range balances {
+		if mode, ok := status.LedgerMode(balance.LedgerID); ok {
+			return &MaintenanceError{Mode: mode}
+		}
+	}
+	return nil
+}
+
+// maintenanceStatus reads the maintenance status, reporting false when it cannot be read so that an outage of
+// Redis does not by itself stop writes.
+func (l *Blnk) maintenanceStatus(ctx context.Context) (*model.MaintenanceStatus, bool) {
+	if l.redis == nil {
+		return nil, false
+	}
+	status, err := l.GetMaintenanceStatus(ctx)
+	if err != nil {
+		logrus.Errorf("failed to read maintenance status: %v", err)
+		return nil, false
+	}
+	return status, true
+}
+
+func maintenanceField(ledgerID string) string {
+	if ledgerID == "" {
+		return maintenanceGlobalField
+	}
+	return "ledger:" + ledgerID
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetMaintenanceMode(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_payouts").Return(&model.Ledger{LedgerID: "ldg_payouts"}, nil)
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_missing").Return((*model.Ledger)(nil), apierror.NewAPIError(apierror.ErrNotFound, "Ledger with ID 'ldg_missing' not found", nil))
+
+	mode, err := b.SetMaintenanceMode(ctx, model.MaintenanceMode{LedgerID: "ldg_payouts", Enabled: true, Reason: "migrating"})
+	require.NoError(t, err)
+	assert.Equal(t, defaultMaintenanceRetryAfter, mode.RetryAfter)
+	assert.Equal(t, model.ActorSystem, mode.UpdatedBy)
+
+	status, err := b.GetMaintenanceStatus(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.Global.Enabled)
+	require.Len(t, status.Ledgers, 1)
+	assert.Equal(t, "migrating", status.Ledgers[0].Reason)
+	assert.NoError(t, b.CheckMaintenance(ctx), "a ledger in maintenance leaves the others writable")
+
+	_, err = b.SetMaintenanceMode(ctx, model.MaintenanceMode{LedgerID: "ldg_missing", Enabled: true})
+	assert.Error(t, err)
+	_, err = b.SetMaintenanceMode(ctx, model.MaintenanceMode{Enabled: true, RetryAfter: -1})
+	assert.ErrorIs(t, err, ErrInvalidMaintenanceMode)
+
+	_, err = b.SetMaintenanceMode(ctx, model.MaintenanceMode{LedgerID: "ldg_payouts", Enabled: false})
+	require.NoError(t, err)
+	status, err = b.GetMaintenanceStatus(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, status.Ledgers)
+}
+
+func TestLedgerMaintenance_RejectsWrites(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_payouts").Return(&model.Ledger{LedgerID: "ldg_payouts"}, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_payout").Return(&model.Balance{BalanceID: "bln_payout", LedgerID: "ldg_payouts"}, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_customer").Return(&model.Balance{BalanceID: "bln_customer", LedgerID: "ldg_customers"}, nil)
+
+	_, err := b.SetMaintenanceMode(ctx, model.MaintenanceMode{LedgerID: "ldg_payouts", Enabled: true, RetryAfter: 300})
+	require.NoError(t, err)
+
+	_, err = b.CreateBalance(ctx, model.Balance{LedgerID: "ldg_payouts", Currency: "USD"})
+	var maintenance *MaintenanceError
+	require.True(t, errors.As(err, &maintenance))
+	assert.Equal(t, "ldg_payouts", maintenance.Mode.LedgerID)
+	assert.Equal(t, 300, maintenance.Mode.RetryAfter)
+	mockDS.AssertNotCalled(t, "CreateBalance", mock.Anything, mock.Anything)
+
+	_, err = b.QueueTransaction(ctx, &model.Transaction{
+		Source: "bln_customer", Destination: "bln_payout", Currency: "USD", PreciseAmount: big.NewInt(100), Precision: 100, Reference: "ref_payout",
+	})
+	assert.ErrorIs(t, err, ErrMaintenanceMode)
+
+	assert.NoError(t, b.checkLedgerMaintenance(ctx, "ldg_customers"))
+	assert.NoError(t, b.CheckTransactionMaintenance(ctx, &model.Transaction{Source: "bln_customer", Destination: "bln_customer", Currency: "USD"}))
+
+	_, err = b.SetMaintenanceMode(ctx, model.MaintenanceMode{Enabled: true, Reason: "failover"})
+	require.NoError(t, err)
+	err = b.checkLedgerMaintenance(ctx, "ldg_customers")
+	require.True(t, errors.As(err, &maintenance))
+	assert.Empty(t, maintenance.Mode.LedgerID)
+	assert.Equal(t, "Blnk is in maintenance mode and only serving reads: failover", err.Error())
+}
+
+func TestLedgerMaintenance_RejectsSessionCommitAndInflightUpdates(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	expectSessionPostActions(mockDS)
+	ctx := context.Background()
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_payouts").Return(&model.Ledger{LedgerID: "ldg_payouts"}, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_payout").Return(&model.Balance{BalanceID: "bln_payout", LedgerID: "ldg_payouts"}, nil)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_customer").Return(&model.Balance{BalanceID: "bln_customer", LedgerID: "ldg_customers"}, nil)
+
+	session, err := b.CreateSession(ctx)
+	require.NoError(t, err)
+	_, err = b.StageBalance(ctx, session.SessionID, model.Balance{LedgerID: "ldg_payouts", Currency: "USD"})
+	require.NoError(t, err)
+
+	_, err = b.SetMaintenanceMode(ctx, model.MaintenanceMode{LedgerID: "ldg_payouts", Enabled: true})
+	require.NoError(t, err)
+
+	_, err = b.CommitSession(ctx, session.SessionID)
+	var maintenance *MaintenanceError
+	require.True(t, errors.As(err, &maintenance))
+	assert.Equal(t, "ldg_payouts", maintenance.Mode.LedgerID)
+	mockDS.AssertNotCalled(t, "CommitUnitOfWork", mock.Anything, mock.Anything)
+
+	mockDS.On("GetTransaction", mock.Anything, "txn_hold").Return(&model.Transaction{
+		TransactionID: "txn_hold", Source: "bln_customer", Destination: "bln_payout", Currency: "USD", Status: StatusInflight,
+	}, nil)
+	_, err = b.CommitInflightTransaction(ctx, "txn_hold", big.NewInt(100))
+	assert.ErrorIs(t, err, ErrMaintenanceMode)
+	_, err = b.VoidInflightTransaction(ctx, "txn_hold")
+	assert.ErrorIs(t, err, ErrMaintenanceMode)
+	mockDS.AssertNotCalled(t, "IsParentTransactionVoid", mock.Anything, mock.Anything)
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
 )
 
 // getEntityTypeFromID determines the entity type from the ID prefix.
@@ -54,7 +58,7 @@ func (l *Blnk) UpdateMetadata(ctx context.Context, entityID string, newMetadata
 	// Check if entity exists first
 	switch entityType {
 	case "ledgers":
-		ledger, err := l.GetLedgerByID(entityID)
+		ledger, err := l.GetLedgerByID(ctx, entityID)
 		if err != nil {
 			return nil, errors.New("entity not found")
 		}
@@ -99,7 +103,7 @@ func (l *Blnk) UpdateMetadata(ctx context.Context, entityID string, newMetadata
 		return mergedMetadata, nil
 
 	case "identities":
-		identity, err := l.GetIdentity(entityID)
+		identity, err := l.GetIdentity(ctx, entityID)
 		if err != nil {
 			return nil, errors.New("entity not found")
 		}
@@ -116,6 +120,45 @@ func (l *Blnk) UpdateMetadata(ctx context.Context, entityID string, newMetadata
 	}
 }
 
+// IncrementMetadata atomically adds to a numeric metadata key of a ledger, balance or identity, such as a login
+// count, so clients need not read, change and write back metadata to keep a counter and lose increments when
+// they race. A missing key starts at 0. Transaction metadata and encrypted metadata keys cannot be incremented.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - entityID: The ID of the ledger, balance or identity.
+// - key: The metadata key to increment.
+// - by: The amount to add, which may be negative to decrement.
+//
+// Returns:
+// - map[string]interface{}: The entity's metadata after the increment.
+// - error: An error if the key cannot be incremented or the entity does not exist.
+func (l *Blnk) IncrementMetadata(ctx context.Context, entityID, key string, by float64) (map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "IncrementMetadata")
+	defer span.End()
+
+	entityType, err := getEntityTypeFromID(entityID)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, err.Error(), nil)
+	}
+	if entityType == "transactions" {
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, "transaction metadata cannot be incremented", nil)
+	}
+	if strings.TrimSpace(key) == "" {
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, "key is required", nil)
+	}
+	if conf, err := config.Fetch(); err == nil && slices.Contains(conf.EncryptedMetadata.Keys, key) {
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, fmt.Sprintf("encrypted metadata key %s cannot be incremented", key), nil)
+	}
+
+	metaData, err := l.datasource.IncrementMetadata(ctx, entityType, entityID, key, by)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return metaData, nil
+}
+
 // mergeMetadata merges new metadata with existing metadata.
 // If the current metadata is nil, it initializes a new map.
 //
@@ -151,7 +194,7 @@ func mergeMetadata(current, new map[string]interface{}) map[string]interface{} {
 func (l *Blnk) updateEntityMetadata(ctx context.Context, entityType, entityID string, metadata map[string]interface{}) error {
 	switch entityType {
 	case "ledgers":
-		return l.datasource.UpdateLedgerMetadata(entityID, metadata)
+		return l.datasource.UpdateLedgerMetadata(ctx, entityID, metadata)
 
 	case "transactions":
 		return l.datasource.UpdateTransactionMetadata(ctx, entityID, metadata)
@@ -160,7 +203,7 @@ func (l *Blnk) updateEntityMetadata(ctx context.Context, entityType, entityID st
 		return l.datasource.UpdateBalanceMetadata(ctx, entityID, metadata)
 
 	case "identities":
-		return l.datasource.UpdateIdentityMetadata(entityID, metadata)
+		return l.datasource.UpdateIdentityMetadata(ctx, entityID, metadata)
 
 	default:
 		return fmt.Errorf("unsupported entity type: %s", entityType)
@@ -0,0 +1,150 @@
+package blnk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/sirupsen/logrus"
+)
+
+// EncryptedMetadataPrefix marks a metadata value that was encrypted before storage. Values keep the
+// prefix at rest so they can be recognised even after a key is removed from the configuration.
+const EncryptedMetadataPrefix = "enc:v1:"
+
+// metadataFieldNames are the JSON keys that hold entity metadata.
+var metadataFieldNames = []string{"meta_data", "metadata"}
+
+// EncryptMetadata encrypts, in place, the values of the metadata keys configured in
+// encrypted_metadata.keys. Values that are already encrypted are left unchanged.
+//
+// Parameters:
+// - meta: The metadata to encrypt.
+//
+// Returns:
+// - error: An error if a value cannot be encrypted.
+func (l *Blnk) EncryptMetadata(meta map[string]interface{}) error {
+	conf, err := config.Fetch()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range conf.EncryptedMetadata.Keys {
+		value, ok := meta[key]
+		if !ok || value == nil || isEncryptedMetadataValue(value) {
+			continue
+		}
+
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode metadata key %s: %w", key, err)
+		}
+		token, err := l.tokenizer.Tokenize(string(raw))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt metadata key %s: %w", key, err)
+		}
+		meta[key] = EncryptedMetadataPrefix + token
+	}
+	return nil
+}
+
+// RevealMetadata replaces, in place, every encrypted metadata value with its decrypted value when
+// decrypt is true, or with the configured mask otherwise. Values that cannot be decrypted are masked.
+//
+// Parameters:
+// - meta: The metadata to reveal.
+// - decrypt: Whether the caller is allowed to see encrypted values.
+func (l *Blnk) RevealMetadata(meta map[string]interface{}, decrypt bool) {
+	mask := "********"
+	if conf, err := config.Fetch(); err == nil && conf.EncryptedMetadata.Mask != "" {
+		mask = conf.EncryptedMetadata.Mask
+	}
+
+	for key, value := range meta {
+		token, ok := value.(string)
+		if !ok || !strings.HasPrefix(token, EncryptedMetadataPrefix) {
+			continue
+		}
+		if !decrypt {
+			meta[key] = mask
+			continue
+		}
+
+		decrypted, err := l.decryptMetadataValue(strings.TrimPrefix(token, EncryptedMetadataPrefix))
+		if err != nil {
+			logrus.Errorf("failed to decrypt metadata key %s: %v", key, err)
+			meta[key] = mask
+			continue
+		}
+		meta[key] = decrypted
+	}
+}
+
+// EncryptMetadataFields walks a decoded JSON document and encrypts every metadata object in it.
+//
+// Parameters:
+// - document: A value decoded from JSON, such as a request body.
+//
+// Returns:
+// - error: An error if a value cannot be encrypted.
+func (l *Blnk) EncryptMetadataFields(document interface{}) error {
+	var err error
+	walkMetadataFields(document, func(meta map[string]interface{}) {
+		if err == nil {
+			err = l.EncryptMetadata(meta)
+		}
+	})
+	return err
+}
+
+// RevealMetadataFields walks a decoded JSON document and reveals every metadata object in it.
+//
+// Parameters:
+// - document: A value decoded from JSON, such as a response body.
+// - decrypt: Whether the caller is allowed to see encrypted values.
+func (l *Blnk) RevealMetadataFields(document interface{}, decrypt bool) {
+	walkMetadataFields(document, func(meta map[string]interface{}) {
+		l.RevealMetadata(meta, decrypt)
+	})
+}
+
+// decryptMetadataValue decrypts a token and decodes the original JSON value.
+func (l *Blnk) decryptMetadataValue(token string) (interface{}, error) {
+	raw, err := l.tokenizer.Detokenize(token)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// walkMetadataFields calls fn for every metadata object found in a decoded JSON document.
+func walkMetadataFields(document interface{}, fn func(map[string]interface{})) {
+	switch v := document.(type) {
+	case map[string]interface{}:
+		for _, name := range metadataFieldNames {
+			if meta, ok := v[name].(map[string]interface{}); ok {
+				fn(meta)
+			}
+		}
+		for _, child := range v {
+			walkMetadataFields(child, fn)
+		}
+	case []interface{}:
+		for _, child := range v {
+			walkMetadataFields(child, fn)
+		}
+	}
+}
+
+func isEncryptedMetadataValue(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.HasPrefix(s, EncryptedMetadataPrefix)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/tokenization"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptAndRevealMetadataFields(t *testing.T) {
+	config.ConfigStore.Store(&config.Configuration{
+		EncryptedMetadata: config.EncryptedMetadataConfig{Keys: []string{"bvn", "bank_account"}, Mask: "****"},
+	})
+	b := &Blnk{tokenizer: tokenization.NewTokenizationService([]byte("0123456789abcdef0123456789abcdef"))}
+
+	body := `{"transactions":[{"amount":100,"meta_data":{"bvn":"22233344455","bank_account":{"number":"0123456789"},"note":"rent"}}]}`
+	var document interface{}
+	require.NoError(t, json.Unmarshal([]byte(body), &document))
+	require.NoError(t, b.EncryptMetadataFields(document))
+
+	meta := document.(map[string]interface{})["transactions"].([]interface{})[0].(map[string]interface{})["meta_data"].(map[string]interface{})
+	assert.True(t, strings.HasPrefix(meta["bvn"].(string), EncryptedMetadataPrefix))
+	assert.True(t, strings.HasPrefix(meta["bank_account"].(string), EncryptedMetadataPrefix))
+	assert.Equal(t, "rent", meta["note"])
+
+	// Encrypting again leaves the stored values unchanged
+	encrypted := meta["bvn"]
+	require.NoError(t, b.EncryptMetadata(meta))
+	assert.Equal(t, encrypted, meta["bvn"])
+
+	masked := map[string]interface{}{"bvn": meta["bvn"], "note": "rent"}
+	b.RevealMetadata(masked, false)
+	assert.Equal(t, "****", masked["bvn"])
+	assert.Equal(t, "rent", masked["note"])
+
+	b.RevealMetadataFields(document, true)
+	assert.Equal(t, "22233344455", meta["bvn"])
+	assert.Equal(t, map[string]interface{}{"number": "0123456789"}, meta["bank_account"])
+
+	corrupt := map[string]interface{}{"bvn": EncryptedMetadataPrefix + "not-a-token"}
+	b.RevealMetadata(corrupt, true)
+	assert.Equal(t, "****", corrupt["bvn"])
+}
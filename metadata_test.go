@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/blnkfinance/blnk/config"
 	"github.com/blnkfinance/blnk/database/mocks"
 	"github.com/blnkfinance/blnk/model"
 	"github.com/stretchr/testify/assert"
@@ -48,8 +49,8 @@ func TestUpdateMetadata(t *testing.T) {
 	t.Run("Update Ledger Metadata", func(t *testing.T) {
 		existingMetadata := map[string]interface{}{"existing": "value"}
 		ledger := &model.Ledger{MetaData: existingMetadata}
-		mockDS.On("GetLedgerByID", "ldg_123").Return(ledger, nil)
-		mockDS.On("UpdateLedgerMetadata", "ldg_123", mock.Anything).Return(nil)
+		mockDS.On("GetLedgerByID", mock.Anything, "ldg_123").Return(ledger, nil)
+		mockDS.On("UpdateLedgerMetadata", mock.Anything, "ldg_123", mock.Anything).Return(nil)
 
 		newMetadata := map[string]interface{}{"new": "value"}
 		result, err := blnk.UpdateMetadata(ctx, "ldg_123", newMetadata)
@@ -78,7 +79,7 @@ func TestUpdateMetadata(t *testing.T) {
 		existingMetadata := map[string]interface{}{"existing": "value"}
 		balance := &model.Balance{MetaData: existingMetadata}
 
-		mockDS.On("GetBalanceByID", "bln_123", mock.Anything, false).Return(balance, nil)
+		mockDS.On("GetBalanceByID", mock.Anything, "bln_123", mock.Anything, false).Return(balance, nil)
 		mockDS.On("UpdateBalanceMetadata", mock.Anything, "bln_123", mock.Anything).Return(nil)
 
 		newMetadata := map[string]interface{}{"new": "value"}
@@ -94,8 +95,8 @@ func TestUpdateMetadata(t *testing.T) {
 		existingMetadata := map[string]interface{}{"existing": "value"}
 		identity := &model.Identity{MetaData: existingMetadata}
 
-		mockDS.On("GetIdentityByID", "idt_123").Return(identity, nil)
-		mockDS.On("UpdateIdentityMetadata", "idt_123", mock.Anything).Return(nil)
+		mockDS.On("GetIdentityByID", mock.Anything, "idt_123").Return(identity, nil)
+		mockDS.On("UpdateIdentityMetadata", mock.Anything, "idt_123", mock.Anything).Return(nil)
 
 		newMetadata := map[string]interface{}{"new": "value"}
 		result, err := blnk.UpdateMetadata(ctx, "idt_123", newMetadata)
@@ -148,3 +149,27 @@ func TestMergeMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestIncrementMetadata(t *testing.T) {
+	config.ConfigStore.Store(&config.Configuration{EncryptedMetadata: config.EncryptedMetadataConfig{Keys: []string{"ssn"}}})
+	mockDS := new(mocks.MockDataSource)
+	blnk := &Blnk{datasource: mockDS}
+	ctx := context.Background()
+
+	mockDS.On("IncrementMetadata", mock.Anything, "balances", "bln_123", "login_count", 1.0).
+		Return(map[string]interface{}{"login_count": float64(3)}, nil)
+	result, err := blnk.IncrementMetadata(ctx, "bln_123", "login_count", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), result["login_count"])
+
+	for _, tc := range []struct{ id, key string }{
+		{"txn_123", "login_count"},
+		{"bln_123", " "},
+		{"bln_123", "ssn"},
+		{"invalid_123", "login_count"},
+	} {
+		_, err := blnk.IncrementMetadata(ctx, tc.id, tc.key, 1)
+		assert.Error(t, err, "%s %q", tc.id, tc.key)
+	}
+	mockDS.AssertNumberOfCalls(t, "IncrementMetadata", 1)
+}
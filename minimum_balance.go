@@ -0,0 +1,171 @@
+package blnk
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	minimumBalanceCacheTTL = 30 * time.Second
+
+	// EventMinimumBalanceBreach is sent when a debit would take a balance below its minimum, whether it was
+	// rejected or allowed by an override.
+	EventMinimumBalanceBreach = "balance.minimum_breach_attempted"
+)
+
+// minimumBalanceCache keeps the minimum balances in memory so that postings do not query them for every
+// transaction. Minimums set or removed on another instance are picked up within minimumBalanceCacheTTL.
+type minimumBalanceCache struct {
+	mu       sync.Mutex
+	balances map[string]*big.Int // Keyed by balance ID
+	ledgers  map[string]*big.Int // Keyed by ledger ID and currency
+	loadedAt time.Time
+}
+
+func ledgerMinimumKey(ledgerID, currency string) string {
+	return ledgerID + "/" + strings.ToUpper(currency)
+}
+
+// SetMinimumBalance sets the minimum balance of a balance, or of every balance of a ledger in a currency,
+// replacing any minimum already set for it.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - minimum model.MinimumBalance: The minimum to set.
+//
+// Returns:
+// - *model.MinimumBalance: The saved minimum.
+// - error: An error if the minimum is invalid, its balance or ledger does not exist or it cannot be saved.
+func (l *Blnk) SetMinimumBalance(ctx context.Context, minimum model.MinimumBalance) (*model.MinimumBalance, error) {
+	if err := minimum.Validate(); err != nil {
+		return nil, err
+	}
+	if minimum.BalanceID != "" {
+		if _, err := l.datasource.GetBalanceByIDLite(ctx, minimum.BalanceID); err != nil {
+			return nil, err
+		}
+	} else if _, err := l.datasource.GetLedgerByID(ctx, minimum.LedgerID); err != nil {
+		return nil, err
+	}
+
+	minimum.MinimumBalanceID = model.GenerateUUIDWithSuffix("min")
+	minimum.CreatedAt = time.Now()
+	if err := l.datasource.SetMinimumBalance(ctx, &minimum); err != nil {
+		return nil, err
+	}
+	l.invalidateMinimumBalances()
+	return &minimum, nil
+}
+
+// GetMinimumBalance retrieves a minimum balance by ID.
+func (l *Blnk) GetMinimumBalance(ctx context.Context, id string) (*model.MinimumBalance, error) {
+	return l.datasource.GetMinimumBalance(ctx, id)
+}
+
+// ListMinimumBalances lists a page of minimum balances, newest first.
+func (l *Blnk) ListMinimumBalances(ctx context.Context, page model.Page) ([]*model.MinimumBalance, error) {
+	return l.datasource.ListMinimumBalances(ctx, page)
+}
+
+// DeleteMinimumBalance removes a minimum balance, so debits are only limited by the funds of the balance again.
+func (l *Blnk) DeleteMinimumBalance(ctx context.Context, id string) error {
+	if err := l.datasource.DeleteMinimumBalance(ctx, id); err != nil {
+		return err
+	}
+	l.invalidateMinimumBalances()
+	return nil
+}
+
+// minimumBalanceFor returns the minimum of a balance: its own, or else its ledger's in its currency. It
+// returns nil when the balance has no minimum. The minimums are reloaded when the cache is stale, and a
+// failed reload keeps the previous ones.
+func (l *Blnk) minimumBalanceFor(ctx context.Context, balance *model.Balance) *big.Int {
+	if l.minimums == nil {
+		return nil
+	}
+	l.minimums.mu.Lock()
+	defer l.minimums.mu.Unlock()
+
+	if time.Since(l.minimums.loadedAt) >= minimumBalanceCacheTTL {
+		l.minimums.loadedAt = time.Now()
+		minimums, err := l.datasource.ListMinimumBalances(ctx, model.Page{})
+		if err != nil {
+			logrus.WithError(err).Warn("failed to load minimum balances")
+		} else {
+			l.minimums.balances = make(map[string]*big.Int)
+			l.minimums.ledgers = make(map[string]*big.Int)
+			for _, minimum := range minimums {
+				if minimum.BalanceID != "" {
+					l.minimums.balances[minimum.BalanceID] = minimum.PreciseAmount
+				} else {
+					l.minimums.ledgers[ledgerMinimumKey(minimum.LedgerID, minimum.Currency)] = minimum.PreciseAmount
+				}
+			}
+		}
+	}
+
+	if minimum, ok := l.minimums.balances[balance.BalanceID]; ok {
+		return minimum
+	}
+	return l.minimums.ledgers[ledgerMinimumKey(balance.LedgerID, balance.Currency)]
+}
+
+// invalidateMinimumBalances forces the next posting to reload the minimum balances.
+func (l *Blnk) invalidateMinimumBalances() {
+	if l.minimums == nil {
+		return
+	}
+	l.minimums.mu.Lock()
+	l.minimums.loadedAt = time.Time{}
+	l.minimums.mu.Unlock()
+}
+
+// enforceMinimumBalance checks the source of a transaction, with the debit applied, against its minimum
+// balance. A breach is reported as a webhook and rejects the transaction unless the transaction overrides
+// minimums, which only callers with the minimum-balances:override scope may ask for.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - transaction *model.Transaction: The transaction being applied.
+// - source *model.Balance: The source balance with the debit applied.
+//
+// Returns:
+// - error: A *model.MinimumBalanceError if the debit breaches the minimum and is not overridden.
+func (l *Blnk) enforceMinimumBalance(ctx context.Context, transaction *model.Transaction, source *model.Balance) error {
+	minimum := l.minimumBalanceFor(ctx, source)
+	if minimum == nil {
+		return nil
+	}
+	breachErr := model.CheckMinimumBalance(source, minimum)
+	if breachErr == nil {
+		return nil
+	}
+
+	breach := model.MinimumBalanceBreach{
+		BalanceID:        source.BalanceID,
+		TransactionID:    transaction.TransactionID,
+		Reference:        transaction.Reference,
+		PreciseAmount:    transaction.PreciseAmount,
+		Minimum:          minimum,
+		ResultingBalance: breachErr.ResultingBalance,
+		Overridden:       transaction.OverrideMinimumBalance,
+		AttemptedAt:      time.Now(),
+	}
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: EventMinimumBalanceBreach, Payload: breach}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+
+	if transaction.OverrideMinimumBalance {
+		return nil
+	}
+	return breachErr
+}
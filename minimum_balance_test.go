@@ -0,0 +1,121 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func minimumTestBalance(id, ledgerID string, amount int64) *model.Balance {
+	return &model.Balance{
+		BalanceID:     id,
+		LedgerID:      ledgerID,
+		Currency:      "USD",
+		Balance:       big.NewInt(amount),
+		CreditBalance: big.NewInt(amount),
+		DebitBalance:  big.NewInt(0),
+	}
+}
+
+func minimumTestTransaction(amount int64) *model.Transaction {
+	return &model.Transaction{TransactionID: "txn_1", Reference: "ref_1", PreciseAmount: big.NewInt(amount), Precision: 1, Currency: "USD", Status: StatusApplied}
+}
+
+func TestApplyTransactionToBalances_MinimumBalance(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("ListMinimumBalances", mock.Anything, model.Page{}).Return([]*model.MinimumBalance{
+		{MinimumBalanceID: "min_1", BalanceID: "bln_src", PreciseAmount: big.NewInt(1000)},
+	}, nil).Once()
+
+	// 1500 less 600 leaves 900, below the minimum of 1000, even though overdrafts are allowed
+	transaction := minimumTestTransaction(600)
+	transaction.AllowOverdraft = true
+	err := b.applyTransactionToBalances(context.Background(), []*model.Balance{minimumTestBalance("bln_src", "ldg_1", 1500), minimumTestBalance("bln_dst", "ldg_1", 0)}, transaction)
+
+	var breach *model.MinimumBalanceError
+	require.True(t, errors.As(err, &breach))
+	assert.Equal(t, big.NewInt(900), breach.ResultingBalance)
+	assert.Equal(t, model.ReasonMinimumBalance, model.RejectionReasonCode(err.Error()))
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+
+	// Debits that stay at or above the minimum are applied, using the cached minimums
+	err = b.applyTransactionToBalances(context.Background(), []*model.Balance{minimumTestBalance("bln_src", "ldg_1", 1500), minimumTestBalance("bln_dst", "ldg_1", 0)}, minimumTestTransaction(500))
+	assert.NoError(t, err)
+	mockDS.AssertExpectations(t)
+}
+
+func TestApplyTransactionToBalances_MinimumBalanceOverride(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("ListMinimumBalances", mock.Anything, model.Page{}).Return([]*model.MinimumBalance{
+		{MinimumBalanceID: "min_1", BalanceID: "bln_src", PreciseAmount: big.NewInt(1000)},
+	}, nil)
+
+	transaction := minimumTestTransaction(600)
+	transaction.OverrideMinimumBalance = true
+	source := minimumTestBalance("bln_src", "ldg_1", 1500)
+	require.NoError(t, b.applyTransactionToBalances(context.Background(), []*model.Balance{source, minimumTestBalance("bln_dst", "ldg_1", 0)}, transaction))
+	assert.Equal(t, big.NewInt(900), source.Balance)
+
+	// The breach is still reported for monitoring
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+}
+
+func TestApplyTransactionToBalances_LedgerMinimumBalance(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("ListMinimumBalances", mock.Anything, model.Page{}).Return([]*model.MinimumBalance{
+		{MinimumBalanceID: "min_1", LedgerID: "ldg_1", Currency: "USD", PreciseAmount: big.NewInt(100)},
+		{MinimumBalanceID: "min_2", BalanceID: "bln_exempt", PreciseAmount: big.NewInt(-500)},
+	}, nil)
+
+	err := b.applyTransactionToBalances(context.Background(), []*model.Balance{minimumTestBalance("bln_src", "ldg_1", 500), minimumTestBalance("bln_dst", "ldg_1", 0)}, minimumTestTransaction(450))
+	assert.Error(t, err)
+
+	// A balance's own minimum takes precedence over its ledger's
+	transaction := minimumTestTransaction(700)
+	transaction.AllowOverdraft = true
+	err = b.applyTransactionToBalances(context.Background(), []*model.Balance{minimumTestBalance("bln_exempt", "ldg_1", 500), minimumTestBalance("bln_dst", "ldg_1", 0)}, transaction)
+	assert.NoError(t, err)
+
+	// Balances of other ledgers are not affected
+	err = b.applyTransactionToBalances(context.Background(), []*model.Balance{minimumTestBalance("bln_other", "ldg_2", 500), minimumTestBalance("bln_dst", "ldg_1", 0)}, minimumTestTransaction(450))
+	assert.NoError(t, err)
+}
+
+func TestSetMinimumBalance(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_1").Return(minimumTestBalance("bln_1", "ldg_1", 0), nil)
+	mockDS.On("SetMinimumBalance", mock.Anything, mock.MatchedBy(func(m *model.MinimumBalance) bool {
+		return m.BalanceID == "bln_1" && m.PreciseAmount.Cmp(big.NewInt(5000)) == 0 && m.MinimumBalanceID != ""
+	})).Return(nil)
+
+	minimum, err := b.SetMinimumBalance(context.Background(), model.MinimumBalance{BalanceID: "bln_1", Amount: 50, Precision: 100})
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(5000), minimum.PreciseAmount)
+
+	_, err = b.SetMinimumBalance(context.Background(), model.MinimumBalance{LedgerID: "ldg_1"})
+	assert.Error(t, err)
+	mockDS.AssertExpectations(t)
+}
@@ -3,7 +3,12 @@ package model
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
 	"time"
+
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
 )
 
 type APIKey struct {
@@ -17,6 +22,33 @@ type APIKey struct {
 	LastUsedAt time.Time  `json:"last_used_at" db:"last_used_at"`
 	IsRevoked  bool       `json:"is_revoked" db:"is_revoked"`
 	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+
+	// RotatedTo and RotationExpiresAt are set once the key has been rotated. The key keeps
+	// working until RotationExpiresAt so that clients can switch over without downtime.
+	RotatedTo         *string    `json:"rotated_to,omitempty" db:"rotated_to"`
+	RotationExpiresAt *time.Time `json:"rotation_expires_at,omitempty" db:"rotation_expires_at"`
+
+	NetworkPolicy NetworkPolicy `json:"network_policy"`
+
+	// LedgerScope restricts the key to specific ledgers or balance ID prefixes. An empty scope allows all ledgers.
+	LedgerScope ledgerscope.Scope `json:"ledger_scope"`
+
+	// FieldCompatibility chooses the names renamed JSON fields are emitted under for the key, one of the
+	// FieldCompatibility modes. An empty setting uses the deployment's default.
+	FieldCompatibility string `json:"field_compatibility,omitempty"`
+}
+
+// Field compatibility modes. Renamed fields are accepted under either name in every mode; legacy callers
+// receive the deprecated names, current callers the current names, and callers migrating between them both.
+const (
+	FieldCompatibilityLegacy  = "legacy"
+	FieldCompatibilityBoth    = "both"
+	FieldCompatibilityCurrent = "current"
+)
+
+// ValidFieldCompatibility reports whether mode is a field compatibility mode.
+func ValidFieldCompatibility(mode string) bool {
+	return mode == FieldCompatibilityLegacy || mode == FieldCompatibilityBoth || mode == FieldCompatibilityCurrent
 }
 
 // GenerateKey creates a new secure API key
@@ -49,9 +81,17 @@ func NewAPIKey(name, ownerID string, scopes []string, expiresAt time.Time) (*API
 // IsValid checks if the API key is valid
 func (k *APIKey) IsValid() bool {
 	now := time.Now()
+	if k.RotationExpiresAt != nil && !now.Before(*k.RotationExpiresAt) {
+		return false
+	}
 	return !k.IsRevoked && now.Before(k.ExpiresAt)
 }
 
+// IsRotating reports whether the key has been replaced but is still inside its overlap window
+func (k *APIKey) IsRotating() bool {
+	return k.RotatedTo != nil && k.RotationExpiresAt != nil && time.Now().Before(*k.RotationExpiresAt)
+}
+
 // HasScope checks if the API key has the required scope
 func (k *APIKey) HasScope(scope string) bool {
 	for _, s := range k.Scopes {
@@ -61,3 +101,88 @@ func (k *APIKey) HasScope(scope string) bool {
 	}
 	return false
 }
+
+// Reasons recorded when a request is rejected by an API key's network policy.
+const (
+	AccessDenialIPNotAllowed      = "ip_not_allowed"
+	AccessDenialCountryBlocked    = "country_blocked"
+	AccessDenialCountryUnresolved = "country_unresolved"
+)
+
+// NetworkPolicy restricts where an API key can be used from. An empty policy allows every request.
+// AllowedCIDRs accepts CIDR ranges or single IP addresses; BlockedCountries holds ISO 3166-1 alpha-2 codes.
+type NetworkPolicy struct {
+	AllowedCIDRs     []string `json:"allowed_cidrs"`
+	BlockedCountries []string `json:"blocked_countries"`
+}
+
+// Normalize validates the policy and returns it with single IPs expanded to host ranges and
+// country codes upper-cased.
+func (p NetworkPolicy) Normalize() (NetworkPolicy, error) {
+	normalized := NetworkPolicy{AllowedCIDRs: []string{}, BlockedCountries: []string{}}
+	for _, entry := range p.AllowedCIDRs {
+		entry = strings.TrimSpace(entry)
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			entry = fmt.Sprintf("%s/%d", ip.String(), bits)
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return NetworkPolicy{}, fmt.Errorf("invalid CIDR %q", entry)
+		}
+		normalized.AllowedCIDRs = append(normalized.AllowedCIDRs, network.String())
+	}
+	for _, country := range p.BlockedCountries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if len(country) != 2 {
+			return NetworkPolicy{}, fmt.Errorf("invalid country code %q", country)
+		}
+		normalized.BlockedCountries = append(normalized.BlockedCountries, country)
+	}
+	return normalized, nil
+}
+
+// Check returns the reason a request from ip and country is rejected, or an empty string if it is allowed.
+// When countries are blocked, requests whose country cannot be determined are rejected.
+func (p NetworkPolicy) Check(ip net.IP, country string) string {
+	if len(p.AllowedCIDRs) > 0 {
+		allowed := false
+		for _, entry := range p.AllowedCIDRs {
+			_, network, err := net.ParseCIDR(entry)
+			if err == nil && ip != nil && network.Contains(ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return AccessDenialIPNotAllowed
+		}
+	}
+
+	if len(p.BlockedCountries) > 0 {
+		if country == "" {
+			return AccessDenialCountryUnresolved
+		}
+		for _, blocked := range p.BlockedCountries {
+			if strings.EqualFold(blocked, country) {
+				return AccessDenialCountryBlocked
+			}
+		}
+	}
+
+	return ""
+}
+
+// APIKeyAccessDenial records a request that was rejected by an API key's network policy.
+type APIKeyAccessDenial struct {
+	APIKeyID  string    `json:"api_key_id"`
+	IPAddress string    `json:"ip_address"`
+	Country   string    `json:"country,omitempty"`
+	Reason    string    `json:"reason"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkPolicy_Normalize(t *testing.T) {
+	policy, err := NetworkPolicy{
+		AllowedCIDRs:     []string{"10.0.0.0/8", " 203.0.113.7 ", "2001:db8::1"},
+		BlockedCountries: []string{"ru", "KP"},
+	}.Normalize()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "203.0.113.7/32", "2001:db8::1/128"}, policy.AllowedCIDRs)
+	assert.Equal(t, []string{"RU", "KP"}, policy.BlockedCountries)
+
+	_, err = NetworkPolicy{AllowedCIDRs: []string{"not-an-ip"}}.Normalize()
+	assert.Error(t, err)
+
+	_, err = NetworkPolicy{BlockedCountries: []string{"RUS"}}.Normalize()
+	assert.Error(t, err)
+}
+
+func TestNetworkPolicy_Check(t *testing.T) {
+	policy := NetworkPolicy{
+		AllowedCIDRs:     []string{"10.0.0.0/8"},
+		BlockedCountries: []string{"KP"},
+	}
+
+	assert.Equal(t, "", NetworkPolicy{}.Check(net.ParseIP("198.51.100.1"), ""))
+	assert.Equal(t, "", policy.Check(net.ParseIP("10.1.2.3"), "NG"))
+	assert.Equal(t, AccessDenialIPNotAllowed, policy.Check(net.ParseIP("198.51.100.1"), "NG"))
+	assert.Equal(t, AccessDenialIPNotAllowed, policy.Check(nil, "NG"))
+	assert.Equal(t, AccessDenialCountryBlocked, policy.Check(net.ParseIP("10.1.2.3"), "kp"))
+	assert.Equal(t, AccessDenialCountryUnresolved, policy.Check(net.ParseIP("10.1.2.3"), ""))
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+// Codes a balance authorization is declined with, besides the reason codes of rejected transactions such as
+// ReasonInsufficientFunds.
+const (
+	DeclineAmountLimit   = "amount_limit_exceeded"
+	DeclineVelocityLimit = "velocity_limit_exceeded"
+	DeclineBalanceFrozen = "balance_frozen"
+	DeclineTimeout       = "latency_budget_exceeded"
+)
+
+// LimitWarningsMetaKey is the metadata key the hold of an authorization that exceeded soft limits lists the
+// warnings under.
+const LimitWarningsMetaKey = "BLNK_LIMIT_WARNINGS"
+
+// LimitWarning records a soft limit an authorization exceeded and was approved regardless, with the decline code
+// and reason it would have been declined with were the limit enforced.
+type LimitWarning struct {
+	Rule   string `json:"rule"`
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// BalanceAuthorization is the decision on a request to authorize a payment against a balance. An approved
+// authorization holds the amount with the inflight transaction TransactionID until it is committed, voided or
+// expires at ExpiresAt. A declined one holds nothing and says why in DeclineCode and DeclineReason, with Rule
+// naming the limit that declined it, if any. Warnings list the soft limits the authorization exceeded.
+type BalanceAuthorization struct {
+	Approved      bool           `json:"approved"`
+	TransactionID string         `json:"transaction_id,omitempty"`
+	Reference     string         `json:"reference"`
+	Source        string         `json:"source"`
+	Destination   string         `json:"destination"`
+	Currency      string         `json:"currency"`
+	PreciseAmount *big.Int       `json:"precise_amount"`
+	DeclineCode   string         `json:"decline_code,omitempty"`
+	DeclineReason string         `json:"decline_reason,omitempty"`
+	Rule          string         `json:"rule,omitempty"`
+	ExpiresAt     time.Time      `json:"expires_at,omitempty"`
+	Warnings      []LimitWarning `json:"warnings,omitempty"`
+	ElapsedMs     float64        `json:"elapsed_ms"`
+}
@@ -43,6 +43,8 @@ type Balance struct {
 	CreatedAt             time.Time              `json:"created_at"`
 	InflightExpiresAt     time.Time              `json:"inflight_expires_at"`
 	MetaData              map[string]interface{} `json:"meta_data"`
+	ShardCount            int                    `json:"shard_count,omitempty"`
+	ShardOf               string                 `json:"-"`
 }
 
 type BalanceMonitor struct {
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// balanceAliasPattern is the form of balance aliases: "@" followed by up to 64 letters, digits, underscores,
+// dots or hyphens, starting with a letter or digit.
+var balanceAliasPattern = regexp.MustCompile(`^@[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// BalanceAlias is a human-readable name of a balance, such as @main_usd_float, that transactions can use in place
+// of the balance's ID. Aliases belong to the tenant of their balance and are unique within it, and an alias takes
+// precedence over a balance indicator of the same name.
+type BalanceAlias struct {
+	Alias     string    `json:"alias"`
+	BalanceID string    `json:"balance_id"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the form of the alias and that it names a balance.
+func (a *BalanceAlias) Validate() error {
+	if !IsBalanceAlias(a.Alias) {
+		return fmt.Errorf("invalid alias '%s': aliases start with @ followed by up to 64 letters, digits, underscores, dots or hyphens", a.Alias)
+	}
+	if strings.TrimSpace(a.BalanceID) == "" {
+		return fmt.Errorf("balance_id is required")
+	}
+	return nil
+}
+
+// IsBalanceAlias reports whether a source or destination has the form of a balance alias.
+func IsBalanceAlias(identifier string) bool {
+	return balanceAliasPattern.MatchString(identifier)
+}
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalanceAliasValidate(t *testing.T) {
+	assert.NoError(t, (&BalanceAlias{Alias: "@main_usd_float", BalanceID: "bln_1"}).Validate())
+	assert.NoError(t, (&BalanceAlias{Alias: "@fees.ng-2026", BalanceID: "bln_1"}).Validate())
+
+	for _, alias := range []string{"main_usd_float", "@", "@_float", "@main usd", "@" + string(make([]byte, 65))} {
+		assert.Error(t, (&BalanceAlias{Alias: alias, BalanceID: "bln_1"}).Validate(), alias)
+	}
+	assert.Error(t, (&BalanceAlias{Alias: "@main_usd_float"}).Validate())
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"errors"
+	"math/big"
+	"time"
+)
+
+const (
+	// NotificationModeRealtime delivers an event for every posting on the balance.
+	NotificationModeRealtime = "realtime"
+	// NotificationModeDigest replaces the per-posting events with one daily digest.
+	NotificationModeDigest = "digest"
+)
+
+// BalanceNotificationPreference controls which transaction events are delivered for postings on a balance.
+// Postings of at least MinDebitAmount or MinCreditAmount are always delivered immediately. Smaller postings
+// are dropped in realtime mode and summarised in the daily digest in digest mode. Amounts are in the
+// balance's precise (minor) units.
+type BalanceNotificationPreference struct {
+	BalanceID       string    `json:"balance_id"`
+	Mode            string    `json:"mode"`
+	MinDebitAmount  *big.Int  `json:"min_debit_amount,omitempty"`
+	MinCreditAmount *big.Int  `json:"min_credit_amount,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// BalanceNotificationDigest summarises a day of postings on a balance in digest mode. The display totals are
+// formatted in the locale of the balance's identity, which Locale and Timezone echo.
+type BalanceNotificationDigest struct {
+	BalanceID          string   `json:"balance_id"`
+	Date               string   `json:"date"`
+	Currency           string   `json:"currency,omitempty"`
+	DebitCount         int      `json:"debit_count"`
+	DebitTotal         *big.Int `json:"debit_total"`
+	CreditCount        int      `json:"credit_count"`
+	CreditTotal        *big.Int `json:"credit_total"`
+	DisplayDebitTotal  string   `json:"display_debit_total,omitempty"`
+	DisplayCreditTotal string   `json:"display_credit_total,omitempty"`
+	Locale             string   `json:"locale,omitempty"`
+	Timezone           string   `json:"timezone,omitempty"`
+}
+
+// Validate checks the mode and thresholds of the preference.
+func (p *BalanceNotificationPreference) Validate() error {
+	if p.Mode != NotificationModeRealtime && p.Mode != NotificationModeDigest {
+		return errors.New("mode must be realtime or digest")
+	}
+	if p.MinDebitAmount != nil && p.MinDebitAmount.Sign() < 0 {
+		return errors.New("min_debit_amount must not be negative")
+	}
+	if p.MinCreditAmount != nil && p.MinCreditAmount.Sign() < 0 {
+		return errors.New("min_credit_amount must not be negative")
+	}
+	return nil
+}
+
+// NotifyImmediately reports whether a posting of amount on the balance should be delivered as its own event.
+//
+// Parameters:
+// - debit: Whether the posting debits the balance.
+// - amount: The precise amount of the posting.
+func (p *BalanceNotificationPreference) NotifyImmediately(debit bool, amount *big.Int) bool {
+	threshold := p.MinCreditAmount
+	if debit {
+		threshold = p.MinDebitAmount
+	}
+	if threshold != nil {
+		return amount != nil && amount.Cmp(threshold) >= 0
+	}
+	return p.Mode == NotificationModeRealtime
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalanceNotificationPreference_Validate(t *testing.T) {
+	assert.NoError(t, (&BalanceNotificationPreference{Mode: NotificationModeDigest}).Validate())
+	assert.ErrorContains(t, (&BalanceNotificationPreference{Mode: "weekly"}).Validate(), "mode")
+	assert.ErrorContains(t, (&BalanceNotificationPreference{Mode: NotificationModeRealtime, MinDebitAmount: big.NewInt(-1)}).Validate(), "min_debit_amount")
+}
+
+func TestBalanceNotificationPreference_NotifyImmediately(t *testing.T) {
+	realtime := &BalanceNotificationPreference{Mode: NotificationModeRealtime, MinDebitAmount: big.NewInt(1000)}
+	assert.True(t, realtime.NotifyImmediately(true, big.NewInt(1000)))
+	assert.False(t, realtime.NotifyImmediately(true, big.NewInt(999)))
+	assert.True(t, realtime.NotifyImmediately(false, big.NewInt(1)), "credits without a threshold follow the mode")
+
+	digest := &BalanceNotificationPreference{Mode: NotificationModeDigest, MinDebitAmount: big.NewInt(1000)}
+	assert.True(t, digest.NotifyImmediately(true, big.NewInt(5000)))
+	assert.False(t, digest.NotifyImmediately(true, big.NewInt(10)))
+	assert.False(t, digest.NotifyImmediately(false, big.NewInt(5000)))
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"time"
+)
+
+const (
+	// ShardRoundRobin spreads postings over the shards in turn.
+	ShardRoundRobin = "round_robin"
+	// ShardHash sends every posting with the same reference to the same shard, so retries land on one shard.
+	ShardHash = "hash"
+
+	// MaxBalanceShards is the largest number of shards a balance can be split into.
+	MaxBalanceShards = 256
+
+	// ShardOfMetaKey marks the shard balances of a sharded balance with the ID of that balance.
+	ShardOfMetaKey = "BLNK_SHARD_OF"
+)
+
+// BalanceSharding splits a balance that receives too many postings for a single row into shards. The shards
+// are balances of their own, the first being the sharded balance itself. Postings are applied to one shard,
+// chosen with Strategy, but are still recorded against the sharded balance, and reading the sharded balance
+// returns the sum of its shards.
+type BalanceSharding struct {
+	BalanceID string     `json:"balance_id"`
+	Strategy  string     `json:"strategy"`
+	ShardIDs  []string   `json:"shard_ids"`
+	Shards    []*Balance `json:"shards,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// BalanceShardRequest asks for a balance to be split into Shards shards.
+type BalanceShardRequest struct {
+	Shards   int    `json:"shards"`
+	Strategy string `json:"strategy"`
+}
+
+// Validate checks the number of shards and the strategy of the request.
+func (r *BalanceShardRequest) Validate() error {
+	if r.Shards < 2 || r.Shards > MaxBalanceShards {
+		return fmt.Errorf("shards must be between 2 and %d", MaxBalanceShards)
+	}
+	if r.Strategy != ShardRoundRobin && r.Strategy != ShardHash {
+		return errors.New("strategy must be round_robin or hash")
+	}
+	return nil
+}
+
+// Shard picks the shard a posting is applied to.
+//
+// Parameters:
+// - reference: The reference of the transaction, used by the hash strategy.
+// - turn: The number of postings routed so far, used by the round robin strategy.
+func (s *BalanceSharding) Shard(reference string, turn uint64) string {
+	count := uint64(len(s.ShardIDs))
+	if count == 0 {
+		return s.BalanceID
+	}
+	if s.Strategy == ShardHash {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(reference))
+		return s.ShardIDs[h.Sum64()%count]
+	}
+	return s.ShardIDs[turn%count]
+}
+
+// PostingID returns the ID transactions on the balance are recorded against: the sharded balance for a shard,
+// and the balance itself otherwise.
+func (b *Balance) PostingID() string {
+	if b.ShardOf != "" {
+		return b.ShardOf
+	}
+	return b.BalanceID
+}
+
+// AddShard adds the amounts of a shard to the balance, which is the sum of the shards read so far.
+func (b *Balance) AddShard(shard *Balance) {
+	add := func(total **big.Int, amount *big.Int) {
+		if amount == nil {
+			return
+		}
+		if *total == nil {
+			*total = new(big.Int)
+		}
+		*total = new(big.Int).Add(*total, amount)
+	}
+	add(&b.Balance, shard.Balance)
+	add(&b.CreditBalance, shard.CreditBalance)
+	add(&b.DebitBalance, shard.DebitBalance)
+	add(&b.InflightBalance, shard.InflightBalance)
+	add(&b.InflightCreditBalance, shard.InflightCreditBalance)
+	add(&b.InflightDebitBalance, shard.InflightDebitBalance)
+	add(&b.QueuedCreditBalance, shard.QueuedCreditBalance)
+	add(&b.QueuedDebitBalance, shard.QueuedDebitBalance)
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalanceShardRequest_Validate(t *testing.T) {
+	assert.NoError(t, (&BalanceShardRequest{Shards: 4, Strategy: ShardHash}).Validate())
+	assert.Error(t, (&BalanceShardRequest{Shards: 1, Strategy: ShardHash}).Validate())
+	assert.Error(t, (&BalanceShardRequest{Shards: MaxBalanceShards + 1, Strategy: ShardRoundRobin}).Validate())
+	assert.Error(t, (&BalanceShardRequest{Shards: 4, Strategy: "random"}).Validate())
+}
+
+func TestBalanceSharding_Shard(t *testing.T) {
+	sharding := &BalanceSharding{Strategy: ShardRoundRobin, ShardIDs: []string{"a", "b", "c"}}
+	assert.Equal(t, "a", sharding.Shard("ref", 0))
+	assert.Equal(t, "b", sharding.Shard("ref", 1))
+	assert.Equal(t, "a", sharding.Shard("ref", 3))
+
+	sharding.Strategy = ShardHash
+	first := sharding.Shard("ref_1", 0)
+	for turn := uint64(1); turn < 5; turn++ {
+		assert.Equal(t, first, sharding.Shard("ref_1", turn))
+	}
+	assert.Contains(t, sharding.ShardIDs, first)
+}
+
+func TestBalance_AddShard(t *testing.T) {
+	balance := &Balance{Balance: big.NewInt(10), CreditBalance: big.NewInt(10), DebitBalance: big.NewInt(0)}
+	balance.AddShard(&Balance{Balance: big.NewInt(-5), CreditBalance: big.NewInt(5), DebitBalance: big.NewInt(10), InflightBalance: big.NewInt(3)})
+
+	assert.Equal(t, big.NewInt(5), balance.Balance)
+	assert.Equal(t, big.NewInt(15), balance.CreditBalance)
+	assert.Equal(t, big.NewInt(10), balance.DebitBalance)
+	assert.Equal(t, big.NewInt(3), balance.InflightBalance)
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"errors"
+	"math/big"
+)
+
+// CalculationRequest asks for a quote on an amount: the fees charged on it, the interest it earns and what it
+// converts to in another currency. The amount is either the current balance of BalanceID or a hypothetical
+// PreciseAmount, or Amount in major units, of Currency.
+type CalculationRequest struct {
+	BalanceID     string   `json:"balance_id,omitempty"`
+	Amount        float64  `json:"amount,omitempty"`
+	PreciseAmount *big.Int `json:"precise_amount,omitempty"`
+	Currency      string   `json:"currency,omitempty"`
+	Precision     float64  `json:"precision,omitempty"` // Defaults to the currency's multiplier
+	ToCurrency    string   `json:"to_currency,omitempty"`
+	Days          int      `json:"days,omitempty"` // The period interest is projected over, a year by default
+}
+
+// Validate checks that the request names a balance or an amount, but not both.
+func (r CalculationRequest) Validate() error {
+	hypothetical := r.PreciseAmount != nil || r.Amount != 0 || r.Currency != ""
+	if r.BalanceID != "" && hypothetical {
+		return errors.New("provide either balance_id or an amount and currency, not both")
+	}
+	if r.BalanceID == "" {
+		if r.Currency == "" {
+			return errors.New("currency is required without a balance_id")
+		}
+		if r.PreciseAmount == nil && r.Amount == 0 {
+			return errors.New("amount or precise_amount is required without a balance_id")
+		}
+		if (r.PreciseAmount != nil && r.PreciseAmount.Sign() < 0) || r.Amount < 0 {
+			return errors.New("amount cannot be negative")
+		}
+	}
+	if r.Precision < 0 {
+		return errors.New("precision cannot be negative")
+	}
+	if r.Days < 0 {
+		return errors.New("days cannot be negative")
+	}
+	return nil
+}
+
+// CalculatedAmount is an amount in a quote, both in units of precision and in major units.
+type CalculatedAmount struct {
+	PreciseAmount *big.Int `json:"precise_amount"`
+	Amount        float64  `json:"amount"`
+}
+
+// NewCalculatedAmount returns a precise amount along with its value in major units.
+func NewCalculatedAmount(preciseAmount *big.Int, precision float64) CalculatedAmount {
+	amount, _ := new(big.Float).Quo(new(big.Float).SetInt(preciseAmount), new(big.Float).SetFloat64(precision)).Float64()
+	return CalculatedAmount{PreciseAmount: preciseAmount, Amount: amount}
+}
+
+// CalculatedFee is a fee charged on the amount of a quote.
+type CalculatedFee struct {
+	Name string `json:"name"`
+	CalculatedAmount
+}
+
+// CalculatedInterest is the simple interest the amount of a quote earns over Days at AnnualRate percent.
+type CalculatedInterest struct {
+	AnnualRate float64 `json:"annual_rate"`
+	Days       int     `json:"days"`
+	CalculatedAmount
+}
+
+// CalculatedConversion is the net amount of a quote converted to ToCurrency at Rate, in the minor units of
+// ToCurrency.
+type CalculatedConversion struct {
+	ToCurrency string  `json:"to_currency"`
+	Rate       float64 `json:"rate"`
+	Precision  float64 `json:"precision"`
+	CalculatedAmount
+}
+
+// Calculation is a quote on an amount. Nothing is posted when it is made. NetAmount is the amount less its
+// fees, and Interest and Conversion are only set when an interest rate is configured for the currency or a
+// target currency was asked for.
+type Calculation struct {
+	BalanceID    string                `json:"balance_id,omitempty"`
+	Currency     string                `json:"currency"`
+	Precision    float64               `json:"precision"`
+	RoundingMode RoundingMode          `json:"rounding_mode,omitempty"`
+	Amount       CalculatedAmount      `json:"amount"`
+	Fees         []CalculatedFee       `json:"fees"`
+	TotalFees    CalculatedAmount      `json:"total_fees"`
+	NetAmount    CalculatedAmount      `json:"net_amount"`
+	Interest     *CalculatedInterest   `json:"interest,omitempty"`
+	Conversion   *CalculatedConversion `json:"conversion,omitempty"`
+}
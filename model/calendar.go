@@ -0,0 +1,51 @@
+package model
+
+import "fmt"
+
+// Roll conventions for dates that fall on a non-business day. Following moves the date to the next business day
+// and preceding to the previous one; modified following moves it to the next business day unless that is in the
+// next month, in which case it moves to the previous one. None leaves the date as it is.
+const (
+	RollNone              = "none"
+	RollFollowing         = "following"
+	RollModifiedFollowing = "modified_following"
+	RollPreceding         = "preceding"
+)
+
+// BusinessDayRule keeps the date a scheduled transaction runs on to the business days of a calendar, rolling
+// dates that fall on weekends or holidays by the rule's convention.
+type BusinessDayRule struct {
+	Calendar string `json:"calendar"`
+	Roll     string `json:"roll"`
+}
+
+// CalendarDay describes a date of a business calendar. Reason is why the date is not a business day, weekend or
+// the name of its holiday, and the nearest business days on either side of it are given.
+type CalendarDay struct {
+	Calendar            string `json:"calendar"`
+	Date                string `json:"date"`
+	BusinessDay         bool   `json:"business_day"`
+	Reason              string `json:"reason,omitempty"`
+	NextBusinessDay     string `json:"next_business_day"`
+	PreviousBusinessDay string `json:"previous_business_day"`
+}
+
+// Validate checks that the rule names a calendar and a known roll convention.
+func (r *BusinessDayRule) Validate() error {
+	if r.Calendar == "" {
+		return fmt.Errorf("business_day_rule.calendar is required")
+	}
+	if !IsRollConvention(r.Roll) {
+		return fmt.Errorf("business_day_rule.roll %q is unknown, expected following, modified_following, preceding or none", r.Roll)
+	}
+	return nil
+}
+
+// IsRollConvention reports whether a name is a known roll convention.
+func IsRollConvention(roll string) bool {
+	switch roll {
+	case RollNone, RollFollowing, RollModifiedFollowing, RollPreceding:
+		return true
+	}
+	return false
+}
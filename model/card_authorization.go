@@ -0,0 +1,93 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+// Statuses of a card authorization.
+const (
+	CardAuthorizationAuthorized = "authorized"
+	CardAuthorizationCleared    = "cleared"
+	CardAuthorizationReversed   = "reversed"
+	CardAuthorizationExpired    = "expired"
+)
+
+// Lifecycle events recorded on a card authorization, named after the ISO 8583 messages they stand for.
+const (
+	CardEventAuthorization            = "authorization"
+	CardEventIncrementalAuthorization = "incremental_authorization"
+	CardEventReversal                 = "reversal"
+	CardEventClearing                 = "clearing"
+	CardEventExpiry                   = "expiry"
+)
+
+// Statuses of a hold placed for a card authorization.
+const (
+	CardHoldHeld     = "held"
+	CardHoldCleared  = "cleared"
+	CardHoldReleased = "released"
+)
+
+// CardAuthorization tracks a card payment from authorization to clearing, reversal or expiry. Each
+// authorized amount is held by an inflight transaction from Source to Destination.
+type CardAuthorization struct {
+	AuthorizationID  string                   `json:"authorization_id"`
+	Scheme           string                   `json:"scheme"`
+	Reference        string                   `json:"reference"`
+	Source           string                   `json:"source"`
+	Destination      string                   `json:"destination"`
+	Currency         string                   `json:"currency"`
+	Precision        float64                  `json:"precision"`
+	AuthorizedAmount *big.Int                 `json:"authorized_amount"`
+	ReversedAmount   *big.Int                 `json:"reversed_amount"`
+	ClearedAmount    *big.Int                 `json:"cleared_amount"`
+	Status           string                   `json:"status"`
+	Holds            []CardAuthorizationHold  `json:"holds"`
+	Events           []CardAuthorizationEvent `json:"events"`
+	MetaData         map[string]interface{}   `json:"meta_data,omitempty"`
+	ExpiresAt        time.Time                `json:"expires_at"`
+	CreatedAt        time.Time                `json:"created_at"`
+	UpdatedAt        time.Time                `json:"updated_at"`
+}
+
+// CardAuthorizationHold is one inflight transaction holding part of the authorized amount.
+type CardAuthorizationHold struct {
+	TransactionID string   `json:"transaction_id"`
+	Amount        *big.Int `json:"amount"`
+	Status        string   `json:"status"`
+}
+
+// CardAuthorizationEvent records one step of the authorization lifecycle.
+type CardAuthorizationEvent struct {
+	Type           string    `json:"type"`
+	Amount         *big.Int  `json:"amount"`
+	TransactionIDs []string  `json:"transaction_ids,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// HeldAmount returns the amount still held for the authorization.
+func (a *CardAuthorization) HeldAmount() *big.Int {
+	held := big.NewInt(0)
+	for _, hold := range a.Holds {
+		if hold.Status == CardHoldHeld {
+			held.Add(held, hold.Amount)
+		}
+	}
+	return held
+}
+
+// ToPrecise converts an amount in major units to the authorization's precise units.
+func (a *CardAuthorization) ToPrecise(amount float64) *big.Int {
+	return convertDecimalToPrecise(&Transaction{Amount: amount, Precision: a.Precision})
+}
+
+// RecordEvent appends a lifecycle event to the authorization.
+func (a *CardAuthorization) RecordEvent(eventType string, amount *big.Int, transactionIDs ...string) {
+	a.Events = append(a.Events, CardAuthorizationEvent{
+		Type:           eventType,
+		Amount:         new(big.Int).Set(amount),
+		TransactionIDs: transactionIDs,
+		CreatedAt:      time.Now(),
+	})
+}
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+// Statuses of transaction challenges.
+const (
+	ChallengePending   = "pending"   // Waiting for the authentication system to call back
+	ChallengeConfirmed = "confirmed" // The customer passed the challenge and the transaction was queued
+	ChallengeFailed    = "failed"    // The customer failed or declined the challenge
+	ChallengeExpired   = "expired"   // No callback arrived before the challenge timed out
+)
+
+// Events of a challenge's trail.
+const (
+	ChallengeEventCreated          = "created"
+	ChallengeEventSent             = "sent"
+	ChallengeEventSendFailed       = "send_failed"
+	ChallengeEventCallback         = "callback"
+	ChallengeEventInvalidSignature = "invalid_signature"
+	ChallengeEventConfirmed        = "confirmed"
+	ChallengeEventReleaseFailed    = "release_failed" // The transaction of a confirmed challenge could not be queued
+	ChallengeEventFailed           = "failed"
+	ChallengeEventExpired          = "expired"
+)
+
+// TransactionChallenge is a transaction held back until the customer passes a strong customer authentication
+// challenge run by an external system. Transaction is the request as it was submitted, which is queued when
+// the challenge is confirmed and recorded as rejected when it fails or expires.
+type TransactionChallenge struct {
+	ChallengeID   string           `json:"challenge_id"`
+	TransactionID string           `json:"transaction_id"`
+	Reference     string           `json:"reference"`
+	Rule          string           `json:"rule"`
+	Status        string           `json:"status"`
+	Transaction   *Transaction     `json:"transaction"`
+	Trail         []ChallengeEvent `json:"trail"`
+	ExpiresAt     time.Time        `json:"expires_at"`
+	CreatedAt     time.Time        `json:"created_at"`
+	ResolvedAt    *time.Time       `json:"resolved_at,omitempty"`
+}
+
+// ChallengeEvent is an entry of the trail of a challenge.
+type ChallengeEvent struct {
+	Event  string    `json:"event"`
+	Detail string    `json:"detail,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// ChallengeCallback is the result of a challenge reported by the authentication system. ChallengeID names the
+// challenge the result is for, so a signed callback cannot be replayed against another challenge.
+type ChallengeCallback struct {
+	ChallengeID string `json:"challenge_id"`
+	Status      string `json:"status"` // ChallengeConfirmed or ChallengeFailed
+	Reason      string `json:"reason,omitempty"`
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ComplianceRecord is a posting in the compliance export: a transaction at its position in the sequence of a
+// ledger it posted to. A transaction between two ledgers is recorded once for each. Amounts are kept as text so
+// they are exported without losing precision.
+type ComplianceRecord struct {
+	LedgerID          string          `json:"ledger_id"`
+	Sequence          int64           `json:"sequence"`
+	TransactionID     string          `json:"transaction_id"`
+	ParentTransaction string          `json:"parent_transaction,omitempty"`
+	Reference         string          `json:"reference"`
+	Source            string          `json:"source"`
+	Destination       string          `json:"destination"`
+	PreciseAmount     string          `json:"precise_amount"`
+	Precision         float64         `json:"precision"`
+	Currency          string          `json:"currency"`
+	Status            string          `json:"status"`
+	Description       string          `json:"description,omitempty"`
+	Hash              string          `json:"hash"`
+	MetaData          json.RawMessage `json:"meta_data,omitempty"`
+	EffectiveDate     *time.Time      `json:"effective_date,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+// ComplianceBacklog is the postings read for the next batch of the compliance export, in ledger and sequence
+// order, with the last sequence number of each of their ledgers that was already exported.
+type ComplianceBacklog struct {
+	Records  []ComplianceRecord
+	Exported map[string]int64
+}
+
+// ComplianceSequenceRange is the run of consecutive sequence numbers of a ledger in a compliance batch.
+type ComplianceSequenceRange struct {
+	LedgerID      string `json:"ledger_id"`
+	FirstSequence int64  `json:"first_sequence"`
+	LastSequence  int64  `json:"last_sequence"`
+}
+
+// ComplianceBatch is an object of the compliance export. Batches are numbered from 1 without gaps, and each
+// carries the SHA-256 hash of the batch before it, so removing or altering a batch breaks the chain. The
+// batch's own hash and signature are stored with the object rather than in it.
+type ComplianceBatch struct {
+	BatchNumber  int64                     `json:"batch_number"`
+	PreviousHash string                    `json:"previous_hash"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	Ranges       []ComplianceSequenceRange `json:"ranges"`
+	Records      []ComplianceRecord        `json:"records"`
+}
+
+// ComplianceExportState records how far postings have been exported: the number and hash of the last batch
+// written, and how the last export went.
+type ComplianceExportState struct {
+	LastBatch       int64      `json:"last_batch"`
+	LastHash        string     `json:"last_hash"`
+	RecordsExported int64      `json:"records_exported"`
+	LastExportedAt  *time.Time `json:"last_exported_at,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+}
+
+// ComplianceExportRun reports a run of the compliance export.
+type ComplianceExportRun struct {
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	Batches     int       `json:"batches"`
+	Records     int       `json:"records"`
+	LastBatch   int64     `json:"last_batch"`
+}
+
+// ComplianceVerification reports a check of the batches in the compliance bucket: that they are numbered
+// without gaps, their signatures and hash chain are intact, and the sequence of every ledger continues from
+// one batch to the next. Issues lists what was wrong; the export is intact when there are none.
+type ComplianceVerification struct {
+	VerifiedAt time.Time `json:"verified_at"`
+	Batches    int64     `json:"batches"`
+	Records    int64     `json:"records"`
+	Ledgers    int       `json:"ledgers"`
+	LastHash   string    `json:"last_hash"`
+	Valid      bool      `json:"valid"`
+	Issues     []string  `json:"issues"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Contact channels an identity can verify.
+const (
+	ContactChannelEmail = "email"
+	ContactChannelPhone = "phone"
+)
+
+// ContactVerification is a code sent to an identity's email address or phone number to confirm it belongs to
+// the identity. Only hashes of the code and of the destination it was sent to are kept; Code is set only when
+// the verification is requested, for the caller to deliver it.
+type ContactVerification struct {
+	VerificationID  string     `json:"verification_id"`
+	IdentityID      string     `json:"identity_id"`
+	Channel         string     `json:"channel"`
+	Destination     string     `json:"destination,omitempty"`
+	Code            string     `json:"code,omitempty"`
+	DestinationHash string     `json:"-"`
+	CodeHash        string     `json:"-"`
+	Attempts        int        `json:"attempts"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	VerifiedAt      *time.Time `json:"verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// ContactDestination returns the email address or phone number of an identity verified on a channel, or false
+// for an unknown channel.
+func (i *Identity) ContactDestination(channel string) (string, bool) {
+	switch channel {
+	case ContactChannelEmail:
+		return i.EmailAddress, true
+	case ContactChannelPhone:
+		return i.PhoneNumber, true
+	default:
+		return "", false
+	}
+}
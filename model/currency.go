@@ -0,0 +1,202 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Currency is an entry of the currency registry: how many minor units a currency has and how it is written.
+// Besides money, the registry holds assets such as loyalty points, gift card units and commodities, which can
+// be valued in a fiat currency.
+type Currency struct {
+	Code           string  `json:"code"`
+	Name           string  `json:"name,omitempty"`
+	Type           string  `json:"type"`
+	MinorUnits     int     `json:"minor_units"`
+	Multiplier     float64 `json:"multiplier"` // The precision of an amount in minor units, 10^MinorUnits
+	Symbol         string  `json:"symbol,omitempty"`
+	SymbolPosition string  `json:"symbol_position,omitempty"` // "before" or "after" the amount; empty follows the locale
+	ValueCurrency  string  `json:"value_currency,omitempty"`  // The fiat currency the asset is valued in
+	ISO            bool    `json:"iso"`
+}
+
+// The kinds of asset in the currency registry.
+const (
+	CurrencyTypeFiat      = "fiat"
+	CurrencyTypeCrypto    = "crypto"
+	CurrencyTypePoints    = "points"    // Loyalty or reward points
+	CurrencyTypeVoucher   = "voucher"   // Gift card or voucher units
+	CurrencyTypeCommodity = "commodity" // A physical commodity, e.g. grams of gold
+	CurrencyTypeOther     = "other"
+)
+
+// Where a registered symbol is placed, regardless of the locale.
+const (
+	SymbolBeforeAmount = "before"
+	SymbolAfterAmount  = "after"
+)
+
+// ISOCurrency returns the ISO 4217 definition of a currency.
+func ISOCurrency(code string) (Currency, bool) {
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return Currency{}, false
+	}
+	scale, _ := currency.Standard.Rounding(unit)
+	return Currency{Code: unit.String(), Type: CurrencyTypeFiat, MinorUnits: scale, Multiplier: math.Pow10(scale), ISO: true}, true
+}
+
+// How the currency of a formatted amount is shown.
+const (
+	CurrencyDisplaySymbol = "symbol" // The locale's symbol, e.g. "$" in en-US and "US$" in en-NG
+	CurrencyDisplayNarrow = "narrow" // The shortest symbol, e.g. "$"
+	CurrencyDisplayCode   = "code"   // The currency code, e.g. "USD"
+)
+
+// FormattedAmount is an amount rendered for display.
+type FormattedAmount struct {
+	Currency      string   `json:"currency"`
+	PreciseAmount *big.Int `json:"precise_amount"`
+	Precision     float64  `json:"precision"`
+	Amount        string   `json:"amount"`    // In major units, rounded to the currency's minor units
+	Formatted     string   `json:"formatted"` // With the locale's digits, separators and symbol
+	Symbol        string   `json:"symbol"`
+	Locale        string   `json:"locale"`
+}
+
+// ValidateCurrencyDisplay checks that a currency display is known. The empty display shows the symbol.
+func ValidateCurrencyDisplay(display string) error {
+	switch display {
+	case "", CurrencyDisplaySymbol, CurrencyDisplayNarrow, CurrencyDisplayCode:
+		return nil
+	}
+	return fmt.Errorf("unknown currency display %q, expected symbol, narrow or code", display)
+}
+
+// ValidateCurrencyType checks that a currency type is known.
+func ValidateCurrencyType(kind string) error {
+	switch kind {
+	case CurrencyTypeFiat, CurrencyTypeCrypto, CurrencyTypePoints, CurrencyTypeVoucher, CurrencyTypeCommodity, CurrencyTypeOther:
+		return nil
+	}
+	return fmt.Errorf("unknown currency type %q, expected fiat, crypto, points, voucher, commodity or other", kind)
+}
+
+// FormatMoney renders an amount of a currency for display in the format's locale. The amount is converted
+// from its precision to the currency's minor units, rounding half to even when the precision is finer, and
+// written with the locale's digits, separators and symbol placement, e.g. "$1,234.50" in en-US and
+// "1.234,50 $" in de-DE.
+//
+// Parameters:
+// - preciseAmount: The amount in units of precision.
+// - precision: The precision of the amount, e.g. 100 for cents. Zero uses the currency's multiplier.
+// - cur: The currency of the amount.
+// - display: How the currency is shown, one of the CurrencyDisplay values.
+func (f LocaleFormat) FormatMoney(preciseAmount *big.Int, precision float64, cur Currency, display string) FormattedAmount {
+	if precision <= 0 {
+		precision = cur.Multiplier
+	}
+	if precision <= 0 {
+		precision = 1
+	}
+	if preciseAmount == nil {
+		preciseAmount = new(big.Int)
+	}
+	value := decimal.NewFromBigInt(preciseAmount, 0).Div(decimal.NewFromFloat(precision)).RoundBank(int32(cur.MinorUnits))
+	minor := value.Shift(int32(cur.MinorUnits)).BigInt()
+
+	symbol := f.currencySymbol(cur, display)
+	number := f.FormatAmount(new(big.Int).Abs(minor), math.Pow10(cur.MinorUnits))
+	sign := ""
+	if minor.Sign() < 0 {
+		sign = "-"
+	}
+
+	after := symbolAfterAmount(f.tag)
+	if cur.SymbolPosition != "" && display != CurrencyDisplayCode {
+		after = cur.SymbolPosition == SymbolAfterAmount
+	}
+
+	var formatted string
+	switch {
+	case symbol == "":
+		formatted = sign + number
+	case after:
+		formatted = sign + number + "\u00a0" + symbol
+	default:
+		last, _ := utf8.DecodeLastRuneInString(symbol)
+		if unicode.IsLetter(last) {
+			// Symbols ending in a letter, like "CHF", are kept apart from the digits by a no-break space
+			symbol += "\u00a0"
+		}
+		formatted = sign + symbol + number
+	}
+
+	return FormattedAmount{
+		Currency:      cur.Code,
+		PreciseAmount: preciseAmount,
+		Precision:     precision,
+		Amount:        value.StringFixed(int32(cur.MinorUnits)),
+		Formatted:     formatted,
+		Symbol:        strings.TrimSuffix(symbol, "\u00a0"),
+		Locale:        f.Locale,
+	}
+}
+
+// currencySymbol returns the symbol of a currency in the format's locale. Registered symbols take precedence,
+// and currencies outside ISO 4217 without one are shown by their code.
+func (f LocaleFormat) currencySymbol(cur Currency, display string) string {
+	if display == CurrencyDisplayCode {
+		return cur.Code
+	}
+	if cur.Symbol != "" {
+		return cur.Symbol
+	}
+	unit, err := currency.ParseISO(cur.Code)
+	if !cur.ISO || err != nil {
+		return cur.Code
+	}
+	printer := message.NewPrinter(f.tag)
+	if display == CurrencyDisplayNarrow {
+		return printer.Sprint(currency.NarrowSymbol(unit))
+	}
+	return printer.Sprint(currency.Symbol(unit))
+}
+
+// symbolAfterAmountLanguages write the currency symbol after the amount in their CLDR standard currency
+// pattern, e.g. "1.234,50 €" in German.
+var symbolAfterAmountLanguages = map[string]bool{
+	"be": true, "bg": true, "cs": true, "da": true, "de": true, "el": true, "es": true, "et": true,
+	"fi": true, "fr": true, "hr": true, "hu": true, "it": true, "lt": true, "lv": true, "nb": true,
+	"nn": true, "no": true, "pl": true, "ro": true, "ru": true, "sk": true, "sl": true, "sv": true,
+	"uk": true, "vi": true,
+}
+
+// symbolBeforeAmountRegions are the regional exceptions to symbolAfterAmountLanguages, and symbolAfterAmount
+// regions are those of other languages that write the symbol after the amount.
+var (
+	symbolBeforeAmountRegions = map[string]bool{
+		"de-AT": true, "de-CH": true, "de-LI": true, "it-CH": true,
+		"es-MX": true, "es-US": true, "es-419": true,
+	}
+	symbolAfterAmountRegions = map[string]bool{"pt-PT": true}
+)
+
+func symbolAfterAmount(tag language.Tag) bool {
+	base, _ := tag.Base()
+	region, _ := tag.Region()
+	regional := base.String() + "-" + region.String()
+	if symbolBeforeAmountRegions[regional] {
+		return false
+	}
+	return symbolAfterAmountRegions[regional] || symbolAfterAmountLanguages[base.String()]
+}
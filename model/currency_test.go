@@ -0,0 +1,79 @@
+package model
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestISOCurrency(t *testing.T) {
+	usd, ok := ISOCurrency("usd")
+	require.True(t, ok)
+	assert.Equal(t, Currency{Code: "USD", Type: CurrencyTypeFiat, MinorUnits: 2, Multiplier: 100, ISO: true}, usd)
+
+	jpy, ok := ISOCurrency("JPY")
+	require.True(t, ok)
+	assert.Equal(t, 0, jpy.MinorUnits)
+	assert.Equal(t, float64(1), jpy.Multiplier)
+
+	_, ok = ISOCurrency("BTC")
+	assert.False(t, ok)
+}
+
+func TestFormatMoney(t *testing.T) {
+	usd, _ := ISOCurrency("USD")
+	eur, _ := ISOCurrency("EUR")
+	jpy, _ := ISOCurrency("JPY")
+	chf, _ := ISOCurrency("CHF")
+	btc := Currency{Code: "BTC", MinorUnits: 8, Multiplier: 1e8, Symbol: "₿"}
+	points := Currency{Code: "PTS", MinorUnits: 0, Multiplier: 1}
+	rewards := Currency{Code: "POINTS", Type: CurrencyTypePoints, Multiplier: 1, Symbol: "pts", SymbolPosition: SymbolAfterAmount}
+	gold := Currency{Code: "XAU_G", Type: CurrencyTypeCommodity, MinorUnits: 3, Multiplier: 1000, Symbol: "g", SymbolPosition: SymbolAfterAmount}
+
+	tests := []struct {
+		name      string
+		locale    string
+		amount    int64
+		precision float64
+		currency  Currency
+		display   string
+		formatted string
+		plain     string
+	}{
+		{"en-US dollars", "en-US", 123450, 100, usd, "", "$1,234.50", "1234.50"},
+		{"de-DE euros", "de-DE", 123450, 100, eur, "", "1.234,50\u00a0€", "1234.50"},
+		{"de-CH francs", "de-CH", 123450, 100, chf, "code", "CHF\u00a01’234.50", "1234.50"},
+		{"negative", "en-US", -5, 100, usd, "", "-$0.05", "-0.05"},
+		{"finer precision rounds half to even", "en-US", 1234565, 10000, usd, "", "$123.46", "123.46"},
+		{"default precision", "en-US", 1050, 0, usd, "", "$10.50", "10.50"},
+		{"yen has no minor units", "ja-JP", 123456, 1, jpy, "", "￥123,456", "123456"},
+		{"configured symbol", "en", 150000000, 1e8, btc, "", "₿1.50000000", "1.50000000"},
+		{"currency without symbol", "en", 2500, 1, points, "", "PTS\u00a02,500", "2500"},
+		{"code display", "en-US", 100, 100, usd, CurrencyDisplayCode, "USD\u00a01.00", "1.00"},
+		{"symbol placed after the amount", "en-US", 1250, 1, rewards, "", "1,250\u00a0pts", "1250"},
+		{"grams of gold", "de-DE", 12500, 1000, gold, "", "12,500\u00a0g", "12.500"},
+		{"code display ignores symbol position", "en-US", 1250, 1, rewards, CurrencyDisplayCode, "POINTS\u00a01,250", "1250"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewLocaleFormat(tt.locale, "").FormatMoney(big.NewInt(tt.amount), tt.precision, tt.currency, tt.display)
+			assert.Equal(t, tt.formatted, got.Formatted)
+			assert.Equal(t, tt.plain, got.Amount)
+			assert.Equal(t, tt.currency.Code, got.Currency)
+		})
+	}
+}
+
+func TestValidateCurrencyType(t *testing.T) {
+	assert.NoError(t, ValidateCurrencyType(CurrencyTypePoints))
+	assert.Error(t, ValidateCurrencyType(""))
+	assert.Error(t, ValidateCurrencyType("stock"))
+}
+
+func TestValidateCurrencyDisplay(t *testing.T) {
+	assert.NoError(t, ValidateCurrencyDisplay(""))
+	assert.NoError(t, ValidateCurrencyDisplay(CurrencyDisplayNarrow))
+	assert.Error(t, ValidateCurrencyDisplay("long"))
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+// Statuses of dormant balances and escheatment batches.
+const (
+	DormantBalanceDormant   = "dormant"
+	DormantBalanceEscheated = "escheated"
+
+	EscheatmentBatchPending = "pending"
+	EscheatmentBatchApplied = "applied"
+	EscheatmentBatchFailed  = "failed"
+)
+
+// DormantBalance records a balance that had no transactions for the configured inactivity period.
+type DormantBalance struct {
+	BalanceID      string    `json:"balance_id"`
+	LedgerID       string    `json:"ledger_id"`
+	IdentityID     string    `json:"identity_id,omitempty"`
+	Currency       string    `json:"currency"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	DormantSince   time.Time `json:"dormant_since"`
+	Frozen         bool      `json:"frozen"`
+	Status         string    `json:"status"`
+	BatchID        string    `json:"batch_id,omitempty"`
+}
+
+// DormancyScan summarises a run of the dormancy scan.
+type DormancyScan struct {
+	Flagged     int `json:"flagged"`
+	Reactivated int `json:"reactivated"`
+}
+
+// EscheatmentBatchRequest selects the dormant balances an escheatment batch transfers. Only balances that
+// became dormant before DormantBefore are included when it is set.
+type EscheatmentBatchRequest struct {
+	Currency      string     `json:"currency"`
+	DormantBefore *time.Time `json:"dormant_before,omitempty"`
+}
+
+// EscheatmentItem is the transfer of one dormant balance's funds to the holding balance.
+type EscheatmentItem struct {
+	BalanceID      string    `json:"balance_id"`
+	LedgerID       string    `json:"ledger_id"`
+	IdentityID     string    `json:"identity_id,omitempty"`
+	PreciseAmount  *big.Int  `json:"precise_amount"`
+	Precision      float64   `json:"precision"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	DormantSince   time.Time `json:"dormant_since"`
+	Reference      string    `json:"reference"`
+	TransactionID  string    `json:"transaction_id,omitempty"`
+}
+
+// EscheatmentBatch transfers the funds of dormant balances in one currency to the holding balance. A batch is
+// created pending so its report can be reviewed, and its transfers are made when it is applied.
+type EscheatmentBatch struct {
+	BatchID        string            `json:"batch_id"`
+	Currency       string            `json:"currency"`
+	HoldingBalance string            `json:"holding_balance"`
+	Items          []EscheatmentItem `json:"items"`
+	TotalAmount    *big.Int          `json:"total_amount"`
+	Status         string            `json:"status"`
+	Error          string            `json:"error,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	AppliedAt      *time.Time        `json:"applied_at,omitempty"`
+}
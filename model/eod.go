@@ -0,0 +1,74 @@
+package model
+
+import "time"
+
+// Kinds of end-of-day steps. Cutoff records the business date's cutoff, HTTP hands the business date to another
+// service, and the rest run the ledger's own jobs.
+const (
+	EODKindCutoff      = "cutoff"
+	EODKindSettlements = "settlements"
+	EODKindSnapshots   = "snapshots"
+	EODKindReports     = "reports"
+	EODKindStatements  = "statements"
+	EODKindUsageExport = "usage_export"
+	EODKindDormancy    = "dormancy"
+	EODKindHTTP        = "http"
+)
+
+// Statuses of end-of-day runs. A run completes with errors when only optional steps failed, and fails when any
+// other step did.
+const (
+	EODRunRunning             = "running"
+	EODRunCompleted           = "completed"
+	EODRunCompletedWithErrors = "completed_with_errors"
+	EODRunFailed              = "failed"
+)
+
+// Statuses of the steps of an end-of-day run. Steps are skipped when a step they depend on failed.
+const (
+	EODStepPending   = "pending"
+	EODStepRunning   = "running"
+	EODStepSucceeded = "succeeded"
+	EODStepFailed    = "failed"
+	EODStepSkipped   = "skipped"
+)
+
+// EODRun is the end-of-day processing of a business date, with the progress of each of its steps in the order
+// they run. Each business date has one run, which is resumed when started again after it failed.
+type EODRun struct {
+	RunID        string       `json:"run_id"`
+	BusinessDate string       `json:"business_date"`
+	Status       string       `json:"status"`
+	CutoffAt     time.Time    `json:"cutoff_at"`
+	Steps        []EODStepRun `json:"steps"`
+	StartedAt    time.Time    `json:"started_at"`
+	CompletedAt  *time.Time   `json:"completed_at,omitempty"`
+}
+
+// EODStepRun is the progress of a step of an end-of-day run. Output summarizes what a succeeded step did, and
+// Error is the error of its last failed attempt.
+type EODStepRun struct {
+	Name        string     `json:"name"`
+	Kind        string     `json:"kind"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	Output      string     `json:"output,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Step returns the step of the run with the given name, or nil if the run has no such step.
+func (r *EODRun) Step(name string) *EODStepRun {
+	for i := range r.Steps {
+		if r.Steps[i].Name == name {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+// Finished reports whether the run has ended, successfully or not.
+func (r *EODRun) Finished() bool {
+	return r.Status != EODRunRunning
+}
@@ -0,0 +1,218 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Types of external accounts: accounts at banks, and wallets on blockchain networks.
+const (
+	ExternalAccountBank   = "bank_account"
+	ExternalAccountWallet = "wallet"
+)
+
+// ExternalAccountMetaKey is the metadata key a transaction paying out to an external account records the
+// account's ID under.
+const ExternalAccountMetaKey = "BLNK_EXTERNAL_ACCOUNT"
+
+var (
+	ibanPattern          = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+	bicPattern           = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+	accountNumberPattern = regexp.MustCompile(`^[A-Z0-9]{4,34}$`)
+
+	// routingNumberPatterns are the formats of the routing numbers of banks in a country: ABA routing numbers in
+	// the US, sort codes in the UK, BSB numbers in Australia, institution and transit numbers in Canada and IFSC
+	// codes in India. Routing numbers of other countries are only checked to be alphanumeric.
+	routingNumberPatterns = map[string]*regexp.Regexp{
+		"US": regexp.MustCompile(`^[0-9]{9}$`),
+		"GB": regexp.MustCompile(`^[0-9]{6}$`),
+		"AU": regexp.MustCompile(`^[0-9]{6}$`),
+		"CA": regexp.MustCompile(`^0?[0-9]{8}$`),
+		"IN": regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`),
+	}
+	defaultRoutingNumberPattern = regexp.MustCompile(`^[A-Z0-9]{3,20}$`)
+
+	// walletAddressPatterns are the formats of wallet addresses on the networks whose addresses are checked.
+	// Addresses on other networks are only checked to have no spaces.
+	walletAddressPatterns = map[string]*regexp.Regexp{
+		"ethereum": regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`),
+		"polygon":  regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`),
+		"bitcoin":  regexp.MustCompile(`^(bc1[02-9ac-hj-np-z]{11,71}|[13][1-9A-HJ-NP-Za-km-z]{25,34})$`),
+		"tron":     regexp.MustCompile(`^T[1-9A-HJ-NP-Za-km-z]{33}$`),
+		"solana":   regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]{32,44}$`),
+	}
+)
+
+// ExternalAccount is a destination outside Blnk that money is paid out to, such as a customer's bank account or
+// crypto wallet, optionally attached to an identity. Accounts are pending when added and reviewed once, ending
+// verified or rejected; only verified accounts can be paid out to.
+type ExternalAccount struct {
+	ExternalAccountID  string                 `json:"external_account_id"`
+	IdentityID         string                 `json:"identity_id,omitempty"`
+	Type               string                 `json:"type"`
+	HolderName         string                 `json:"holder_name"`
+	Currency           string                 `json:"currency"`
+	Country            string                 `json:"country,omitempty"`
+	BankName           string                 `json:"bank_name,omitempty"`
+	IBAN               string                 `json:"iban,omitempty"`
+	AccountNumber      string                 `json:"account_number,omitempty"`
+	RoutingNumber      string                 `json:"routing_number,omitempty"`
+	BIC                string                 `json:"bic,omitempty"`
+	Network            string                 `json:"network,omitempty"`
+	WalletAddress      string                 `json:"wallet_address,omitempty"`
+	VerificationStatus string                 `json:"verification_status"`
+	VerificationReason string                 `json:"verification_reason,omitempty"`
+	VerifiedAt         *time.Time             `json:"verified_at,omitempty"`
+	MetaData           map[string]interface{} `json:"meta_data,omitempty"`
+	CreatedAt          time.Time              `json:"created_at"`
+}
+
+// Normalize puts the fields of the account in the form they are stored and compared in: codes uppercase and
+// bank details without the spaces and dashes they are often written with.
+func (a *ExternalAccount) Normalize() {
+	a.Type = strings.ToLower(strings.TrimSpace(a.Type))
+	a.HolderName = strings.TrimSpace(a.HolderName)
+	a.Currency = strings.ToUpper(strings.TrimSpace(a.Currency))
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	a.IBAN = compactBankDetail(a.IBAN)
+	a.AccountNumber = compactBankDetail(a.AccountNumber)
+	a.RoutingNumber = compactBankDetail(a.RoutingNumber)
+	a.BIC = compactBankDetail(a.BIC)
+	a.Network = strings.ToLower(strings.TrimSpace(a.Network))
+	a.WalletAddress = strings.TrimSpace(a.WalletAddress)
+}
+
+// Validate checks the account has the details of its type, and that they are well formed: IBANs must pass their
+// checksum, routing numbers must match the format of their country, and wallet addresses the format of their
+// network. The account is expected to be normalized.
+func (a *ExternalAccount) Validate() error {
+	if a.HolderName == "" {
+		return errors.New("holder_name is required")
+	}
+	if a.Currency == "" {
+		return errors.New("currency is required")
+	}
+	switch a.Type {
+	case ExternalAccountBank:
+		return a.validateBankAccount()
+	case ExternalAccountWallet:
+		return a.validateWallet()
+	default:
+		return fmt.Errorf("unknown external account type '%s'", a.Type)
+	}
+}
+
+func (a *ExternalAccount) validateBankAccount() error {
+	if a.IBAN == "" && a.AccountNumber == "" {
+		return errors.New("iban or account_number is required")
+	}
+	if a.IBAN != "" {
+		if err := ValidateIBAN(a.IBAN); err != nil {
+			return err
+		}
+		if a.Country == "" {
+			a.Country = a.IBAN[:2]
+		} else if a.Country != a.IBAN[:2] {
+			return fmt.Errorf("iban is for country '%s', not '%s'", a.IBAN[:2], a.Country)
+		}
+	}
+	if a.AccountNumber != "" && !accountNumberPattern.MatchString(a.AccountNumber) {
+		return errors.New("account_number must be 4 to 34 letters and digits")
+	}
+	if a.RoutingNumber != "" {
+		if err := ValidateRoutingNumber(a.Country, a.RoutingNumber); err != nil {
+			return err
+		}
+	} else if a.IBAN == "" && a.Country == "US" {
+		return errors.New("routing_number is required for US bank accounts")
+	}
+	if a.BIC != "" && !bicPattern.MatchString(a.BIC) {
+		return fmt.Errorf("invalid bic '%s'", a.BIC)
+	}
+	if a.Network != "" || a.WalletAddress != "" {
+		return errors.New("bank accounts have no network or wallet_address")
+	}
+	return nil
+}
+
+func (a *ExternalAccount) validateWallet() error {
+	if a.Network == "" || a.WalletAddress == "" {
+		return errors.New("network and wallet_address are required")
+	}
+	pattern, ok := walletAddressPatterns[a.Network]
+	if ok && !pattern.MatchString(a.WalletAddress) || !ok && strings.ContainsAny(a.WalletAddress, " \t\n") {
+		return fmt.Errorf("invalid %s wallet address '%s'", a.Network, a.WalletAddress)
+	}
+	if a.IBAN != "" || a.AccountNumber != "" || a.RoutingNumber != "" || a.BIC != "" {
+		return errors.New("wallets have no bank details")
+	}
+	return nil
+}
+
+// ValidateIBAN checks the format of an IBAN, without spaces, and its ISO 13616 check digits.
+func ValidateIBAN(iban string) error {
+	if !ibanPattern.MatchString(iban) {
+		return fmt.Errorf("invalid iban '%s'", iban)
+	}
+	// The check digits make the number formed by moving the first four characters to the end, with letters
+	// replaced by 10 to 35, equal to 1 modulo 97.
+	var digits strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			fmt.Fprintf(&digits, "%d", r-'A'+10)
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	if new(big.Int).Mod(n, big.NewInt(97)).Int64() != 1 {
+		return fmt.Errorf("invalid iban '%s': check digits do not match", iban)
+	}
+	return nil
+}
+
+// ValidateRoutingNumber checks a routing number matches the format of the country's routing numbers. US routing
+// numbers must also pass the ABA checksum.
+func ValidateRoutingNumber(country, routingNumber string) error {
+	pattern, ok := routingNumberPatterns[country]
+	if !ok {
+		pattern = defaultRoutingNumberPattern
+	}
+	if !pattern.MatchString(routingNumber) {
+		return fmt.Errorf("invalid routing number '%s' for country '%s'", routingNumber, country)
+	}
+	if country == "US" {
+		weights := [3]int{3, 7, 1}
+		sum := 0
+		for i, r := range routingNumber {
+			sum += int(r-'0') * weights[i%3]
+		}
+		if sum%10 != 0 {
+			return fmt.Errorf("invalid routing number '%s': checksum does not match", routingNumber)
+		}
+	}
+	return nil
+}
+
+// CanReviewExternalAccount reports whether an external account can move from one verification status to
+// another. Pending accounts are verified or rejected, and verified accounts can later be rejected to stop
+// payouts to them.
+func CanReviewExternalAccount(from, to string) bool {
+	switch from {
+	case VerificationPending:
+		return to == VerificationVerified || to == VerificationRejected
+	case VerificationVerified:
+		return to == VerificationRejected
+	default:
+		return false
+	}
+}
+
+// compactBankDetail uppercases a bank detail and removes the spaces and dashes it is written with.
+func compactBankDetail(s string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(s)))
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateIBAN(t *testing.T) {
+	assert.NoError(t, ValidateIBAN("GB82WEST12345698765432"))
+	assert.NoError(t, ValidateIBAN("DE89370400440532013000"))
+	assert.Error(t, ValidateIBAN("GB83WEST12345698765432"))
+	assert.Error(t, ValidateIBAN("GB82 WEST 1234 5698 7654 32"))
+	assert.Error(t, ValidateIBAN("1234"))
+}
+
+func TestValidateRoutingNumber(t *testing.T) {
+	assert.NoError(t, ValidateRoutingNumber("US", "021000021"))
+	assert.Error(t, ValidateRoutingNumber("US", "021000022"))
+	assert.Error(t, ValidateRoutingNumber("US", "02100002"))
+	assert.NoError(t, ValidateRoutingNumber("GB", "402715"))
+	assert.Error(t, ValidateRoutingNumber("GB", "40271"))
+	assert.NoError(t, ValidateRoutingNumber("IN", "HDFC0001234"))
+	assert.NoError(t, ValidateRoutingNumber("NG", "058"))
+}
+
+func TestExternalAccountValidate(t *testing.T) {
+	account := ExternalAccount{Type: " Bank_Account", HolderName: "Ada Lovelace", Currency: "eur", IBAN: "de89 3704 0044 0532 0130 00"}
+	account.Normalize()
+	assert.NoError(t, account.Validate())
+	assert.Equal(t, "DE89370400440532013000", account.IBAN)
+	assert.Equal(t, "DE", account.Country)
+	assert.Equal(t, "EUR", account.Currency)
+
+	account = ExternalAccount{Type: ExternalAccountBank, HolderName: "Ada", Currency: "USD", Country: "US", AccountNumber: "000123456789", RoutingNumber: "021-000-021"}
+	account.Normalize()
+	assert.NoError(t, account.Validate())
+
+	account = ExternalAccount{Type: ExternalAccountWallet, HolderName: "Ada", Currency: "USDC", Network: "Ethereum", WalletAddress: "0x52908400098527886E0F7030069857D2E4169EE7"}
+	account.Normalize()
+	assert.NoError(t, account.Validate())
+
+	invalid := []ExternalAccount{
+		{Type: ExternalAccountBank, HolderName: "Ada", Currency: "USD", Country: "US", AccountNumber: "000123456789"},
+		{Type: ExternalAccountBank, HolderName: "Ada", Currency: "EUR", Country: "FR", IBAN: "DE89370400440532013000"},
+		{Type: ExternalAccountBank, HolderName: "Ada", Currency: "EUR"},
+		{Type: ExternalAccountBank, HolderName: "Ada", Currency: "EUR", IBAN: "DE89370400440532013000", BIC: "DEUT"},
+		{Type: ExternalAccountWallet, HolderName: "Ada", Currency: "USDC", Network: "ethereum", WalletAddress: "0x1234"},
+		{Type: ExternalAccountWallet, HolderName: "Ada", Currency: "USDC", Network: "ethereum"},
+		{Type: "card", HolderName: "Ada", Currency: "USD"},
+		{Type: ExternalAccountBank, Currency: "USD", AccountNumber: "000123456789"},
+	}
+	for _, account := range invalid {
+		account.Normalize()
+		assert.Error(t, account.Validate(), account)
+	}
+}
+
+func TestCanReviewExternalAccount(t *testing.T) {
+	assert.True(t, CanReviewExternalAccount(VerificationPending, VerificationVerified))
+	assert.True(t, CanReviewExternalAccount(VerificationPending, VerificationRejected))
+	assert.True(t, CanReviewExternalAccount(VerificationVerified, VerificationRejected))
+	assert.False(t, CanReviewExternalAccount(VerificationRejected, VerificationVerified))
+	assert.False(t, CanReviewExternalAccount(VerificationPending, VerificationPending))
+}
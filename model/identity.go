@@ -16,6 +16,7 @@ limitations under the License.
 package model
 
 import (
+	"fmt"
 	"strings"
 	"time"
 )
@@ -40,6 +41,253 @@ type Identity struct {
 	DOB              time.Time              `json:"dob" form:"dob"`
 	CreatedAt        time.Time              `json:"created_at" form:"createdAt"`
 	MetaData         map[string]interface{} `json:"meta_data" form:"metaData"`
+
+	// Addresses are the addresses the identity holds besides the one above. They are managed on their own and
+	// only set when a single identity is retrieved.
+	Addresses []*IdentityAddress `json:"addresses,omitempty" form:"-"`
+
+	// Locale is a BCP 47 language tag and Timezone an IANA timezone name. Statements and notifications about
+	// the identity format amounts and dates with them.
+	Locale                   string                    `json:"locale" form:"locale"`
+	Timezone                 string                    `json:"timezone" form:"timezone"`
+	CommunicationPreferences *CommunicationPreferences `json:"communication_preferences,omitempty" form:"communication_preferences"`
+
+	// VerificationStatus is the stage of the identity's KYC verification, one of the Verification statuses.
+	// The timestamps record when the identity last entered each stage, and VerificationReason explains the
+	// last change, such as why the identity was rejected.
+	VerificationStatus      string     `json:"verification_status" form:"-"`
+	VerificationReason      string     `json:"verification_reason,omitempty" form:"-"`
+	VerificationSubmittedAt *time.Time `json:"verification_submitted_at,omitempty" form:"-"`
+	VerifiedAt              *time.Time `json:"verified_at,omitempty" form:"-"`
+	VerificationRejectedAt  *time.Time `json:"verification_rejected_at,omitempty" form:"-"`
+
+	// RiskScore is how risky the identity is as a customer, from 0 to 100, and RiskLevel the band of risk
+	// levels the score falls in. Identities are scored as they are created and updated when a risk scorer is
+	// configured; identities never scored have no score.
+	RiskScore    *float64   `json:"risk_score,omitempty" form:"-"`
+	RiskLevel    string     `json:"risk_level,omitempty" form:"-"`
+	RiskScoredAt *time.Time `json:"risk_scored_at,omitempty" form:"-"`
+
+	// VerifiedEmail and VerifiedPhone report whether the identity confirmed its email address and phone number
+	// with a verification code, and the timestamps when. Changing either clears its verification, since the new
+	// value has not been confirmed.
+	VerifiedEmail   bool       `json:"verified_email" form:"-"`
+	VerifiedPhone   bool       `json:"verified_phone" form:"-"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" form:"-"`
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty" form:"-"`
+
+	// Tags are labels operators segment identities with, such as "vip" or "dormant". They are managed on their
+	// own and listed in order.
+	Tags []string `json:"tags,omitempty" form:"-"`
+
+	// Status is where the identity is in its lifecycle, one of the IdentityStatus statuses. Transactions on the
+	// balances of blocked and closed identities are rejected. StatusReason explains the last change.
+	Status          string     `json:"status" form:"-"`
+	StatusReason    string     `json:"status_reason,omitempty" form:"-"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty" form:"-"`
+
+	// Version counts the updates of the identity's details, starting at 1. An update that names the version it
+	// was made against is rejected once the identity has been updated since.
+	Version int `json:"version" form:"-"`
+
+	// MergeMetaData makes an update apply MetaData to the identity's metadata as a JSON merge patch, setting and
+	// removing individual keys, instead of replacing it.
+	MergeMetaData bool `json:"-" form:"-"`
+
+	// DeletedAt is set when the identity is deleted. Deleted identities are kept so that the transactions and
+	// balances referencing them stay auditable, and can be restored.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" form:"-"`
+}
+
+// IdentityFilter narrows a listing of identities. Empty fields match every identity. Email addresses match
+// regardless of case; the other fields must match exactly. Tokenized fields hold tokens rather than their
+// values, so identities whose filtered field is tokenized are not found by its value. Deleted identities are
+// only listed with IncludeDeleted. Identities at or above MinRiskScore are listed riskiest first, for compliance
+// review. VerificationStatus filters on the identity's KYC status.
+type IdentityFilter struct {
+	EmailAddress string `json:"email_address" form:"email_address"`
+	PhoneNumber  string `json:"phone_number" form:"phone_number"`
+	Nationality  string `json:"nationality" form:"nationality"`
+	Country      string `json:"country" form:"country"`
+	Category     string `json:"category" form:"category"`
+	IdentityType string `json:"identity_type" form:"identity_type"`
+
+	// Tags is a comma-separated list of tags identities must all have.
+	Tags string `json:"tags" form:"tags"`
+
+	// MetaData is a comma-separated list of key:value pairs the metadata of identities must all contain, compared
+	// as strings. A key without a value matches identities whose metadata has the key, whatever its value.
+	MetaData string `json:"meta_data" form:"meta_data"`
+
+	// CreatedAfter and CreatedBefore bound when identities were created, from CreatedAfter inclusive to
+	// CreatedBefore exclusive. Both are RFC 3339 times in query strings.
+	CreatedAfter  *time.Time `json:"created_after,omitempty" form:"created_after"`
+	CreatedBefore *time.Time `json:"created_before,omitempty" form:"created_before"`
+
+	Status             string  `json:"status" form:"status"`
+	VerificationStatus string  `json:"verification_status" form:"verification_status"`
+	RiskLevel          string  `json:"risk_level" form:"risk_level"`
+	MinRiskScore       float64 `json:"min_risk_score" form:"min_risk_score"`
+	IncludeDeleted     bool    `json:"include_deleted" form:"include_deleted"`
+}
+
+// IsEmpty reports whether the filter matches every identity that has not been deleted.
+func (f IdentityFilter) IsEmpty() bool {
+	return f == IdentityFilter{}
+}
+
+// Verification statuses of an identity. Identities start unverified and are pending while their KYC checks
+// run, which end with the identity verified or rejected. Rejected identities can be submitted again once
+// corrected, and verified ones when they are due for review.
+const (
+	VerificationUnverified = "unverified"
+	VerificationPending    = "pending"
+	VerificationVerified   = "verified"
+	VerificationRejected   = "rejected"
+)
+
+// Lifecycle statuses of an identity. Identities start active. Blocked identities, such as those under
+// investigation, cannot transact until they are activated again, and closed identities can never transact again.
+const (
+	IdentityStatusActive  = "active"
+	IdentityStatusBlocked = "blocked"
+	IdentityStatusClosed  = "closed"
+)
+
+// identityStatusTransitions lists the statuses each lifecycle status can move to.
+var identityStatusTransitions = map[string][]string{
+	IdentityStatusActive:  {IdentityStatusBlocked, IdentityStatusClosed},
+	IdentityStatusBlocked: {IdentityStatusActive, IdentityStatusClosed},
+}
+
+// CanChangeIdentityStatus reports whether an identity can move from one lifecycle status to another. Identities
+// stored before statuses were tracked have no status and count as active.
+func CanChangeIdentityStatus(from, to string) bool {
+	if from == "" {
+		from = IdentityStatusActive
+	}
+	for _, next := range identityStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IdentityStatusChange describes a change of an identity's lifecycle status.
+type IdentityStatusChange struct {
+	IdentityID     string    `json:"identity_id"`
+	PreviousStatus string    `json:"previous_status"`
+	Status         string    `json:"status"`
+	Reason         string    `json:"reason,omitempty"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+// Risk levels of an identity, by its risk score.
+const (
+	RiskLevelLow    = "low"
+	RiskLevelMedium = "medium"
+	RiskLevelHigh   = "high"
+)
+
+// RiskLevelFor returns the risk level of a score: high at or above the high threshold, medium at or above the
+// medium threshold, and low below it.
+func RiskLevelFor(score, medium, high float64) string {
+	switch {
+	case score >= high:
+		return RiskLevelHigh
+	case score >= medium:
+		return RiskLevelMedium
+	default:
+		return RiskLevelLow
+	}
+}
+
+// verificationTransitions lists the statuses each verification status can move to.
+var verificationTransitions = map[string][]string{
+	VerificationUnverified: {VerificationPending},
+	VerificationPending:    {VerificationVerified, VerificationRejected},
+	VerificationVerified:   {VerificationPending},
+	VerificationRejected:   {VerificationPending},
+}
+
+// CanTransitionVerification reports whether an identity's verification can move from one status to another.
+// Identities stored before verification was tracked have no status and count as unverified.
+func CanTransitionVerification(from, to string) bool {
+	if from == "" {
+		from = VerificationUnverified
+	}
+	for _, next := range verificationTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IdentityVerificationChange describes a change of an identity's verification status.
+type IdentityVerificationChange struct {
+	IdentityID     string    `json:"identity_id"`
+	PreviousStatus string    `json:"previous_status"`
+	Status         string    `json:"status"`
+	Reason         string    `json:"reason,omitempty"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+// Communication channels and the communications an identity can opt out of.
+const (
+	CommunicationChannelEmail   = "email"
+	CommunicationChannelSMS     = "sms"
+	CommunicationChannelWebhook = "webhook"
+	CommunicationChannelNone    = "none"
+
+	CommunicationStatements    = "statements"
+	CommunicationNotifications = "notifications"
+	CommunicationMarketing     = "marketing"
+)
+
+// CommunicationPreferences records how an identity wants to be contacted: its preferred channel and the
+// communications it opted out of.
+type CommunicationPreferences struct {
+	Channel string   `json:"channel,omitempty"`
+	OptOuts []string `json:"opt_outs,omitempty"`
+}
+
+// ValidatePreferences checks the locale, timezone and communication preferences of the identity, replacing
+// the locale with its canonical form. Empty values are left unset.
+func (i *Identity) ValidatePreferences() error {
+	if i.Locale != "" {
+		locale, err := CanonicalLocale(i.Locale)
+		if err != nil {
+			return err
+		}
+		i.Locale = locale
+	}
+	if i.Timezone != "" {
+		if err := ValidateTimezone(i.Timezone); err != nil {
+			return err
+		}
+	}
+	if p := i.CommunicationPreferences; p != nil {
+		switch p.Channel {
+		case "", CommunicationChannelEmail, CommunicationChannelSMS, CommunicationChannelWebhook, CommunicationChannelNone:
+		default:
+			return fmt.Errorf("unknown communication channel %q, expected email, sms, webhook or none", p.Channel)
+		}
+		for _, optOut := range p.OptOuts {
+			switch optOut {
+			case CommunicationStatements, CommunicationNotifications, CommunicationMarketing:
+			default:
+				return fmt.Errorf("unknown communication %q, expected statements, notifications or marketing", optOut)
+			}
+		}
+	}
+	return nil
+}
+
+// LocaleFormat returns the format amounts and dates are shown to the identity with.
+func (i *Identity) LocaleFormat() LocaleFormat {
+	return NewLocaleFormat(i.Locale, i.Timezone)
 }
 
 // convertToStructFieldName ensures consistent field name format by returning
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Types of identity addresses. An individual lives at a residential address and may receive mail at another; an
+// organization trades from its business addresses.
+const (
+	AddressResidential = "residential"
+	AddressMailing     = "mailing"
+	AddressBusiness    = "business"
+)
+
+var addressTypes = map[string]bool{
+	AddressResidential: true,
+	AddressMailing:     true,
+	AddressBusiness:    true,
+}
+
+// IdentityAddress is one of the addresses of an identity. An identity can hold any number of addresses of each
+// type, alongside the single address kept on the identity itself. Version counts the updates of the address,
+// starting at 1.
+type IdentityAddress struct {
+	AddressID  string                 `json:"address_id"`
+	IdentityID string                 `json:"identity_id"`
+	Type       string                 `json:"type"`
+	Street     string                 `json:"street"`
+	City       string                 `json:"city"`
+	State      string                 `json:"state"`
+	PostCode   string                 `json:"post_code"`
+	Country    string                 `json:"country"`
+	MetaData   map[string]interface{} `json:"meta_data,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+	Version    int                    `json:"version"`
+}
+
+// Validate checks the type of the address and that it names a country.
+func (a *IdentityAddress) Validate() error {
+	if !addressTypes[a.Type] {
+		return fmt.Errorf("unknown address type '%s'", a.Type)
+	}
+	if a.Country == "" {
+		return errors.New("country is required")
+	}
+	return nil
+}
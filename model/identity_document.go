@@ -0,0 +1,65 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Types of identity documents.
+const (
+	DocumentTypePassport        = "passport"
+	DocumentTypeNationalID      = "national_id"
+	DocumentTypeDriversLicense  = "drivers_license"
+	DocumentTypeResidencePermit = "residence_permit"
+	DocumentTypeUtilityBill     = "utility_bill"
+	DocumentTypeBankStatement   = "bank_statement"
+	DocumentTypeOther           = "other"
+)
+
+var documentTypes = map[string]bool{
+	DocumentTypePassport:        true,
+	DocumentTypeNationalID:      true,
+	DocumentTypeDriversLicense:  true,
+	DocumentTypeResidencePermit: true,
+	DocumentTypeUtilityBill:     true,
+	DocumentTypeBankStatement:   true,
+	DocumentTypeOther:           true,
+}
+
+// IdentityDocument is a verification document attached to an identity, such as a passport scan or a utility
+// bill. The file itself is kept in object storage under StorageKey. Documents are pending when uploaded and
+// are reviewed once, ending verified or rejected; a corrected document is uploaded as a new one.
+type IdentityDocument struct {
+	DocumentID         string     `json:"document_id"`
+	IdentityID         string     `json:"identity_id"`
+	DocumentType       string     `json:"document_type"`
+	FileName           string     `json:"file_name"`
+	ContentType        string     `json:"content_type"`
+	Size               int64      `json:"size"`
+	StorageKey         string     `json:"-"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	VerificationStatus string     `json:"verification_status"`
+	VerificationReason string     `json:"verification_reason,omitempty"`
+	VerifiedAt         *time.Time `json:"verified_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// Validate checks the type of the document.
+func (d *IdentityDocument) Validate() error {
+	if !documentTypes[d.DocumentType] {
+		return fmt.Errorf("unknown document type '%s'", d.DocumentType)
+	}
+	return nil
+}
+
+// Expired reports whether the document has expired at the given time. Documents without an expiry date never
+// expire.
+func (d *IdentityDocument) Expired(at time.Time) bool {
+	return d.ExpiresAt != nil && !d.ExpiresAt.After(at)
+}
+
+// CanReviewDocument reports whether a document can move from one verification status to another. Only pending
+// documents are reviewed, and a review ends with the document verified or rejected.
+func CanReviewDocument(from, to string) bool {
+	return from == VerificationPending && (to == VerificationVerified || to == VerificationRejected)
+}
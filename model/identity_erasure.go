@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+// ErasedIdentityFields are the JSON names of the personal fields an identity's erasure scrubs: its names, contact
+// details, date of birth and address. The identity's ID, type, category and the references to it from balances
+// and accounts are kept, so that its ledger history stays intact.
+var ErasedIdentityFields = []string{
+	"first_name", "last_name", "other_names",
+	"email_address", "phone_number",
+	"dob",
+	"street", "city", "state", "post_code", "country",
+}
+
+// erasedIdentityStructFields are the struct field names of ErasedIdentityFields, which tokenized fields are
+// marked with in the metadata.
+var erasedIdentityStructFields = []string{
+	"FirstName", "LastName", "OtherNames",
+	"EmailAddress", "PhoneNumber",
+	"DOB",
+	"Street", "City", "State", "PostCode", "Country",
+}
+
+// IdentityErasure is the receipt of an identity's erasure under a right-to-erasure request. It records which
+// fields were scrubbed, how many previous versions of the identity were scrubbed with them, and who asked for
+// the erasure and when, but none of the values erased.
+type IdentityErasure struct {
+	ErasureID       string    `json:"erasure_id"`
+	IdentityID      string    `json:"identity_id"`
+	Fields          []string  `json:"fields"`
+	HistoryVersions int       `json:"history_versions"`
+	Reason          string    `json:"reason,omitempty"`
+	RequestedBy     string    `json:"requested_by"`
+	ErasedAt        time.Time `json:"erased_at"`
+}
+
+// AnonymizeIdentity clears the ErasedIdentityFields of an identity and drops the tokenized markers of those
+// fields from its metadata, since the tokens are erased with them.
+func AnonymizeIdentity(identity *Identity) {
+	identity.FirstName, identity.LastName, identity.OtherNames = "", "", ""
+	identity.EmailAddress, identity.PhoneNumber = "", ""
+	identity.DOB = time.Time{}
+	identity.Street, identity.City, identity.State, identity.PostCode, identity.Country = "", "", "", "", ""
+
+	switch tokenized := identity.MetaData["tokenized_fields"].(type) {
+	case map[string]bool:
+		for _, field := range erasedIdentityStructFields {
+			delete(tokenized, field)
+		}
+		for _, field := range ErasedIdentityFields {
+			delete(tokenized, field)
+		}
+	case map[string]interface{}:
+		for _, field := range erasedIdentityStructFields {
+			delete(tokenized, field)
+		}
+		for _, field := range ErasedIdentityFields {
+			delete(tokenized, field)
+		}
+	}
+}
+
+// ErasedIdentityValues returns the ErasedIdentityFields of an anonymized identity with their JSON values, to
+// overwrite the same fields in the identity's previous versions.
+func ErasedIdentityValues(identity *Identity) (map[string]interface{}, error) {
+	fields, err := identityFields(identity)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{}, len(ErasedIdentityFields))
+	for _, field := range ErasedIdentityFields {
+		values[field] = fields[field]
+	}
+	return values, nil
+}
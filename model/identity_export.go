@@ -0,0 +1,78 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Formats identities can be exported to, and where export files are written: a directory on the server's disk
+// or the configured S3 bucket.
+const (
+	IdentityExportCSV     = "csv"
+	IdentityExportParquet = "parquet"
+
+	IdentityExportLocal = "local"
+	IdentityExportS3    = "s3"
+)
+
+// Statuses of an identity export.
+const (
+	IdentityExportRunning   = "running"
+	IdentityExportCompleted = "completed"
+	IdentityExportFailed    = "failed"
+)
+
+// IdentityExportColumns are the columns of an identity export, in order. The columns an import accepts keep
+// their names, so an export with the identity_id and later columns removed can be imported again.
+var IdentityExportColumns = append(append([]string{"identity_id"}, ImportableIdentityFields...),
+	"created_at", "verification_status", "risk_score", "risk_level", "verified_email", "verified_phone", "deleted_at")
+
+// IdentityExport is a job writing the identities matching Filter to a CSV or Parquet file, on the server's disk
+// or in S3. Location is the path or S3 URL of the file, and Rows counts the identities written so far.
+type IdentityExport struct {
+	ExportID    string         `json:"export_id"`
+	Format      string         `json:"format"`
+	Destination string         `json:"destination"`
+	Location    string         `json:"location"`
+	Filter      IdentityFilter `json:"filter"`
+	Status      string         `json:"status"`
+	Rows        int64          `json:"rows"`
+	Error       string         `json:"error,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+}
+
+// ExportRecord returns the values of an identity for the IdentityExportColumns. Unset values are empty, times
+// are RFC 3339 and the metadata and communication preferences are JSON objects. Tokenized fields are exported
+// as their tokens.
+func (i *Identity) ExportRecord() []string {
+	riskScore := ""
+	if i.RiskScore != nil {
+		riskScore = strconv.FormatFloat(*i.RiskScore, 'f', -1, 64)
+	}
+	return []string{
+		i.IdentityID, i.IdentityType, i.OrganizationName, i.Category,
+		i.FirstName, i.LastName, i.OtherNames, i.Gender, exportTime(&i.DOB),
+		i.EmailAddress, i.PhoneNumber, i.Nationality,
+		i.Street, i.Country, i.State, i.PostCode, i.City,
+		exportJSON(i.MetaData), i.Locale, i.Timezone, exportJSON(i.CommunicationPreferences),
+		exportTime(&i.CreatedAt), i.VerificationStatus, riskScore, i.RiskLevel,
+		strconv.FormatBool(i.VerifiedEmail), strconv.FormatBool(i.VerifiedPhone), exportTime(i.DeletedAt),
+	}
+}
+
+func exportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func exportJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// IdentityFieldChange is a field an update of an identity changed, with its values before and after, as they
+// appear in the identity's JSON.
+type IdentityFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// IdentityHistoryEntry is a version of an identity kept when it was updated: the identity as it was before the
+// update, the fields the update changed, and who made it and when. Versions are numbered from 1 in the order the
+// updates were made.
+type IdentityHistoryEntry struct {
+	IdentityID string                `json:"identity_id"`
+	Version    int                   `json:"version"`
+	Previous   Identity              `json:"previous"`
+	Changes    []IdentityFieldChange `json:"changes"`
+	ChangedBy  string                `json:"changed_by"`
+	ChangedAt  time.Time             `json:"changed_at"`
+}
+
+// DiffIdentities returns the fields that differ between two versions of an identity, sorted by field name.
+// Fields are compared by their JSON values, so nested fields such as the metadata are compared as a whole. The
+// version of the identity, which every update moves on, is not a change of its own.
+func DiffIdentities(before, after *Identity) ([]IdentityFieldChange, error) {
+	from, err := identityFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := identityFields(after)
+	if err != nil {
+		return nil, err
+	}
+	delete(from, "version")
+	delete(to, "version")
+
+	fields := make(map[string]bool, len(from))
+	for field := range from {
+		fields[field] = true
+	}
+	for field := range to {
+		fields[field] = true
+	}
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	var changes []IdentityFieldChange
+	for _, field := range names {
+		if !reflect.DeepEqual(from[field], to[field]) {
+			changes = append(changes, IdentityFieldChange{Field: field, From: from[field], To: to[field]})
+		}
+	}
+	return changes, nil
+}
+
+// MaskTokenizedFields copies into a previous version of an identity the values of the fields tokenized in its
+// updated version, so that the history of an identity never keeps the values its tokens replaced.
+func MaskTokenizedFields(previous, updated *Identity) {
+	from := reflect.ValueOf(previous).Elem()
+	to := reflect.ValueOf(updated).Elem()
+	for i := 0; i < to.NumField(); i++ {
+		field := to.Type().Field(i)
+		if field.Type.Kind() == reflect.String && updated.IsFieldTokenized(field.Name) {
+			from.Field(i).SetString(to.Field(i).String())
+		}
+	}
+}
+
+func identityFields(identity *Identity) (map[string]interface{}, error) {
+	data, err := json.Marshal(identity)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Formats identities can be imported from. CSV files have a header row naming the columns; NDJSON files have
+// an identity object per line.
+const (
+	IdentityImportCSV    = "csv"
+	IdentityImportNDJSON = "ndjson"
+)
+
+// ImportableIdentityFields are the JSON names of the fields an imported identity can set, which are the CSV
+// columns an import accepts. Identities are given their ID, creation time and verification status on import.
+var ImportableIdentityFields = []string{
+	"identity_type", "organization_name", "category",
+	"first_name", "last_name", "other_names", "gender", "dob",
+	"email_address", "phone_number", "nationality",
+	"street", "country", "state", "post_code", "city",
+	"meta_data", "locale", "timezone", "communication_preferences",
+}
+
+// IdentityImportResult is the outcome of importing one row: the ID of the identity it created, or why it was
+// not imported. Rows are numbered from 1, not counting a CSV file's header row.
+type IdentityImportResult struct {
+	Row        int    `json:"row"`
+	IdentityID string `json:"identity_id,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// IdentityImportReport is the outcome of an import, with the result of every row in order.
+type IdentityImportReport struct {
+	Format   string                 `json:"format"`
+	Total    int                    `json:"total"`
+	Imported int                    `json:"imported"`
+	Failed   int                    `json:"failed"`
+	Rows     []IdentityImportResult `json:"rows"`
+}
+
+// ParseImportedIdentity builds an identity from the fields of an imported row, keyed by their JSON names. Dates
+// of birth may be given as dates or RFC 3339 timestamps, and in CSV files the metadata and communication
+// preferences are JSON objects. Rows must have an identity type, and their locale, timezone and communication
+// preferences must be valid.
+func ParseImportedIdentity(fields map[string]interface{}) (*Identity, error) {
+	var unknown []string
+	for field := range fields {
+		if !IsImportableIdentityField(field) {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown fields: %s", strings.Join(unknown, ", "))
+	}
+
+	if dob, ok := fields["dob"].(string); ok && dob != "" {
+		if date, err := time.Parse(time.DateOnly, dob); err == nil {
+			fields["dob"] = date.Format(time.RFC3339)
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	identity := &Identity{}
+	if err := json.Unmarshal(data, identity); err != nil {
+		return nil, fmt.Errorf("invalid identity: %w", err)
+	}
+
+	if strings.TrimSpace(identity.IdentityType) == "" {
+		return nil, errors.New("identity_type is required")
+	}
+	if err := identity.ValidatePreferences(); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// IsImportableIdentityField reports whether an import can set the field with the given JSON name.
+func IsImportableIdentityField(field string) bool {
+	for _, importable := range ImportableIdentityFields {
+		if field == importable {
+			return true
+		}
+	}
+	return false
+}
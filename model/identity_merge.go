@@ -0,0 +1,55 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Fields two identities can be matched on as duplicates.
+const (
+	DuplicateOnEmailAddress = "email_address"
+	DuplicateOnPhoneNumber  = "phone_number"
+	DuplicateOnDOB          = "dob"
+)
+
+// DuplicateIdentity is an identity found to be a likely duplicate of another, with the fields they share.
+type DuplicateIdentity struct {
+	Identity  Identity `json:"identity"`
+	MatchedOn []string `json:"matched_on"`
+}
+
+// IdentityMerge records the merge of a duplicate identity into the identity that survives it. The balances
+// and accounts of the merged identity are moved to the survivor and the merged identity is deleted.
+type IdentityMerge struct {
+	MergeID    string    `json:"merge_id"`
+	SurvivorID string    `json:"survivor_id"`
+	MergedID   string    `json:"merged_id"`
+	MatchedOn  []string  `json:"matched_on"`
+	BalanceIDs []string  `json:"balance_ids"`
+	Reason     string    `json:"reason,omitempty"`
+	MergedAt   time.Time `json:"merged_at"`
+}
+
+// MatchDuplicateIdentity returns the fields two identities share that mark them as duplicates: the same email
+// address, regardless of case, the same phone number, or the same date of birth along with the same name. It
+// returns nil if they are not duplicates. Empty fields never match.
+func MatchDuplicateIdentity(a, b Identity) []string {
+	var matched []string
+	if email := strings.TrimSpace(a.EmailAddress); email != "" && strings.EqualFold(email, strings.TrimSpace(b.EmailAddress)) {
+		matched = append(matched, DuplicateOnEmailAddress)
+	}
+	if phone := strings.TrimSpace(a.PhoneNumber); phone != "" && phone == strings.TrimSpace(b.PhoneNumber) {
+		matched = append(matched, DuplicateOnPhoneNumber)
+	}
+	if !a.DOB.IsZero() && sameDate(a.DOB, b.DOB) && a.FirstName != "" && a.LastName != "" &&
+		strings.EqualFold(a.FirstName, b.FirstName) && strings.EqualFold(a.LastName, b.LastName) {
+		matched = append(matched, DuplicateOnDOB)
+	}
+	return matched
+}
+
+func sameDate(a, b time.Time) bool {
+	ay, am, ad := a.UTC().Date()
+	by, bm, bd := b.UTC().Date()
+	return ay == by && am == bm && ad == bd
+}
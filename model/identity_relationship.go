@@ -0,0 +1,81 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Types of relationships between identities. Members, directors and beneficial owners are related to an
+// organization; a guardian is related to the identity they are the guardian of.
+const (
+	RelationshipMember          = "member"
+	RelationshipDirector        = "director"
+	RelationshipBeneficialOwner = "beneficial_owner"
+	RelationshipGuardian        = "guardian"
+)
+
+// Directions of a relationship as seen from one of its identities. An outgoing relationship is one the identity
+// holds, such as being a member of an organization; an incoming one is held by the related identity, such as the
+// organization's member.
+const (
+	RelationshipOutgoing = "outgoing"
+	RelationshipIncoming = "incoming"
+)
+
+var relationshipTypes = map[string]bool{
+	RelationshipMember:          true,
+	RelationshipDirector:        true,
+	RelationshipBeneficialOwner: true,
+	RelationshipGuardian:        true,
+}
+
+// organizationRelationshipTypes are the relationship types whose related identity must be an organization.
+var organizationRelationshipTypes = map[string]bool{
+	RelationshipMember:          true,
+	RelationshipDirector:        true,
+	RelationshipBeneficialOwner: true,
+}
+
+// IdentityRelationship links two identities: IdentityID is the Type of RelatedIdentityID, such as an individual
+// being a member of an organization or the guardian of a minor. Role describes the relationship further, such as
+// a job title, and two identities can only be related once by each type. Version counts the updates of the
+// relationship, starting at 1.
+type IdentityRelationship struct {
+	RelationshipID    string                 `json:"relationship_id"`
+	IdentityID        string                 `json:"identity_id"`
+	RelatedIdentityID string                 `json:"related_identity_id"`
+	Type              string                 `json:"type"`
+	Role              string                 `json:"role,omitempty"`
+	MetaData          map[string]interface{} `json:"meta_data,omitempty"`
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+	Version           int                    `json:"version"`
+}
+
+// RelatedIdentity is an identity related to another, with the relationship between them and its direction as
+// seen from the other identity.
+type RelatedIdentity struct {
+	Relationship IdentityRelationship `json:"relationship"`
+	Direction    string               `json:"direction"`
+	Identity     Identity             `json:"identity"`
+}
+
+// Validate checks the type of the relationship and that it relates two different identities.
+func (r *IdentityRelationship) Validate() error {
+	if !relationshipTypes[r.Type] {
+		return fmt.Errorf("unknown relationship type '%s'", r.Type)
+	}
+	if r.RelatedIdentityID == "" {
+		return errors.New("related_identity_id is required")
+	}
+	if r.IdentityID == r.RelatedIdentityID {
+		return errors.New("an identity cannot be related to itself")
+	}
+	return nil
+}
+
+// RequiresOrganization reports whether the related identity of the relationship must be an organization.
+func (r *IdentityRelationship) RequiresOrganization() bool {
+	return organizationRelationshipTypes[r.Type]
+}
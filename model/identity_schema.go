@@ -0,0 +1,265 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JSONSchema describes the custom fields an identity type keeps in its meta_data, with the subset of JSON Schema
+// keywords that describe flat records: types, required and known properties, enums, lengths and patterns of
+// strings, ranges of numbers, the formats date, date-time and email, and the items of arrays.
+type JSONSchema struct {
+	Type                 string                 `json:"type,omitempty"` // object, string, number, integer, boolean or array
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+var schemaTypes = map[string]bool{"": true, "object": true, "string": true, "number": true, "integer": true, "boolean": true, "array": true}
+
+var schemaFormats = map[string]bool{"": true, "date": true, "date-time": true, "email": true}
+
+// ParseJSONSchema parses a schema and checks that it only uses the types and formats Blnk validates and that its
+// patterns are valid regular expressions.
+func ParseJSONSchema(data []byte) (*JSONSchema, error) {
+	var schema JSONSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := schema.compile("$"); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func (s *JSONSchema) compile(path string) error {
+	if !schemaTypes[s.Type] {
+		return fmt.Errorf("%s: unsupported type %q", path, s.Type)
+	}
+	if !schemaFormats[s.Format] {
+		return fmt.Errorf("%s: unsupported format %q", path, s.Format)
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", path, err)
+		}
+		s.pattern = pattern
+	}
+	for name, property := range s.Properties {
+		if property == nil {
+			return fmt.Errorf("%s.%s: schema is required", path, name)
+		}
+		if err := property.compile(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// FieldError is a field that does not match its schema, named by its path such as meta_data.tax_id.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// IdentitySchemaError is returned when the meta_data of an identity does not match the schema of its identity
+// type, listing every field that does not.
+type IdentitySchemaError struct {
+	IdentityType string       `json:"identity_type"`
+	Errors       []FieldError `json:"errors"`
+}
+
+func (e *IdentitySchemaError) Error() string {
+	fields := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		fields[i] = fieldErr.Field + " " + fieldErr.Message
+	}
+	return fmt.Sprintf("meta_data does not match the %s schema: %s", e.IdentityType, strings.Join(fields, "; "))
+}
+
+// Validate checks a value against the schema, returning an error for every field that does not match it, in
+// the order of their paths. Fields are named from path.
+func (s *JSONSchema) Validate(path string, value interface{}) []FieldError {
+	var errs []FieldError
+	s.validate(path, value, &errs)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+func (s *JSONSchema) validate(path string, value interface{}, errs *[]FieldError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Enum) > 0 && !schemaEnumContains(s.Enum, value) {
+		fail("must be one of %s", schemaEnumList(s.Enum))
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if field, present := object[name]; !present || field == nil {
+				*errs = append(*errs, FieldError{Field: path + "." + name, Message: "is required"})
+			}
+		}
+		for name, field := range object {
+			property, known := s.Properties[name]
+			if !known {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*errs = append(*errs, FieldError{Field: path + "." + name, Message: "is not allowed"})
+				}
+				continue
+			}
+			if field != nil {
+				property.validate(path+"."+name, field, errs)
+			}
+		}
+
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		length := len([]rune(text))
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(text) {
+			fail("must match %s", s.Pattern)
+		}
+		if !schemaFormatMatches(s.Format, text) {
+			fail("must be a valid %s", s.Format)
+		}
+
+	case "number", "integer":
+		number, ok := schemaNumber(value)
+		if !ok {
+			fail("must be a number")
+			return
+		}
+		if s.Type == "integer" && number != float64(int64(number)) {
+			fail("must be an integer")
+		}
+		if s.Minimum != nil && number < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+		}
+
+	case "array":
+		items := reflect.ValueOf(value)
+		if value == nil || items.Kind() != reflect.Slice {
+			fail("must be an array")
+			return
+		}
+		if s.MinItems != nil && items.Len() < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && items.Len() > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i := 0; i < items.Len(); i++ {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), items.Index(i).Interface(), errs)
+			}
+		}
+	}
+}
+
+// schemaNumber reads a number decoded from JSON or set by Go code.
+func schemaNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func schemaFormatMatches(format, text string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse("2006-01-02", text)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, text)
+		return err == nil
+	case "email":
+		address, err := mail.ParseAddress(text)
+		return err == nil && address.Address == text
+	}
+	return true
+}
+
+func schemaEnumContains(enum []interface{}, value interface{}) bool {
+	number, isNumber := schemaNumber(value)
+	for _, candidate := range enum {
+		if isNumber {
+			if n, ok := schemaNumber(candidate); ok && n == number {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaEnumList(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		encoded, _ := json.Marshal(value)
+		values[i] = string(encoded)
+	}
+	return strings.Join(values, ", ")
+}
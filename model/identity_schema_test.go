@@ -0,0 +1,64 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const businessSchema = `{
+	"type": "object",
+	"required": ["registration_number", "incorporated_on"],
+	"additionalProperties": false,
+	"properties": {
+		"registration_number": {"type": "string", "pattern": "^RC[0-9]{6}$"},
+		"incorporated_on": {"type": "string", "format": "date"},
+		"employees": {"type": "integer", "minimum": 1},
+		"sector": {"enum": ["retail", "fintech"]},
+		"directors": {"type": "array", "minItems": 1, "items": {"type": "string", "minLength": 2}}
+	}
+}`
+
+func TestJSONSchemaValidate(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(businessSchema))
+	require.NoError(t, err)
+
+	valid := map[string]interface{}{
+		"registration_number": "RC123456",
+		"incorporated_on":     "2019-04-01",
+		"employees":           float64(12),
+		"sector":              "fintech",
+		"directors":           []interface{}{"Ada Obi"},
+	}
+	assert.Empty(t, schema.Validate("meta_data", valid))
+
+	errs := schema.Validate("meta_data", map[string]interface{}{
+		"registration_number": "123",
+		"employees":           2.5,
+		"sector":              "mining",
+		"directors":           []interface{}{"A"},
+		"nickname":            "acme",
+	})
+	assert.Equal(t, []FieldError{
+		{Field: "meta_data.directors[0]", Message: "must be at least 2 characters"},
+		{Field: "meta_data.employees", Message: "must be an integer"},
+		{Field: "meta_data.incorporated_on", Message: "is required"},
+		{Field: "meta_data.nickname", Message: "is not allowed"},
+		{Field: "meta_data.registration_number", Message: "must match ^RC[0-9]{6}$"},
+		{Field: "meta_data.sector", Message: `must be one of "retail", "fintech"`},
+	}, errs)
+}
+
+func TestParseJSONSchema_Invalid(t *testing.T) {
+	_, err := ParseJSONSchema([]byte(`{"type": "object", "properties": {"code": {"type": "string", "pattern": "("}}}`))
+	assert.ErrorContains(t, err, "$.code: invalid pattern")
+
+	_, err = ParseJSONSchema([]byte(`{"type": "tuple"}`))
+	assert.ErrorContains(t, err, `unsupported type "tuple"`)
+}
+
+func TestIdentitySchemaError(t *testing.T) {
+	err := &IdentitySchemaError{IdentityType: "organization", Errors: []FieldError{{Field: "meta_data.tax_id", Message: "is required"}}}
+	assert.Equal(t, "meta_data does not match the organization schema: meta_data.tax_id is required", err.Error())
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Statuses of an identity screening. Identities are clear when no list entry matches them and flagged when one
+// does, until the matches are reviewed.
+const (
+	ScreeningClear   = "clear"
+	ScreeningFlagged = "flagged"
+)
+
+// Lists identities are screened against.
+const (
+	ScreeningListSanctions = "sanctions" // Such as the OFAC Specially Designated Nationals list
+	ScreeningListPEP       = "pep"       // Politically exposed persons
+)
+
+// IdentityScreening is the result of screening an identity against sanctions and politically exposed person
+// lists. Identities are screened as they are created and updated, and the latest screening of an identity is its
+// current standing.
+type IdentityScreening struct {
+	ScreeningID string           `json:"screening_id"`
+	IdentityID  string           `json:"identity_id"`
+	Provider    string           `json:"provider"`
+	Status      string           `json:"status"`
+	Matches     []ScreeningMatch `json:"matches"`
+	ScreenedAt  time.Time        `json:"screened_at"`
+}
+
+// Flagged reports whether the screening matched the identity to a list entry.
+func (s *IdentityScreening) Flagged() bool {
+	return s.Status == ScreeningFlagged
+}
+
+// ScreeningMatch is a list entry an identity matched, with how closely it matched from 0 to 1 as the provider
+// reports it.
+type ScreeningMatch struct {
+	List    string                 `json:"list"`
+	EntryID string                 `json:"entry_id,omitempty"`
+	Name    string                 `json:"name"`
+	Score   float64                `json:"score,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
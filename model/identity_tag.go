@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxIdentityTagLength is the longest an identity tag can be.
+const maxIdentityTagLength = 50
+
+// identityTagPattern matches the characters tags are made of, so they can be listed in a filter separated by
+// commas.
+var identityTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]*$`)
+
+// NormalizeIdentityTags lowercases and trims tags and checks they are made of letters, digits, '_', '.', ':' and
+// '-', returning them sorted without duplicates.
+func NormalizeIdentityTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, errors.New("at least one tag is required")
+	}
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if len(tag) > maxIdentityTagLength {
+			return nil, fmt.Errorf("tag '%s' is longer than %d characters", tag, maxIdentityTagLength)
+		}
+		if !identityTagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag '%s': tags are letters, digits, '_', '.', ':' and '-'", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// ParseIdentityTagFilter splits a comma-separated list of tags filtered on, lowercasing them and dropping empty
+// entries.
+func ParseIdentityTagFilter(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ParseIdentityMetadataFilter splits a comma-separated list of metadata filters into the values metadata keys
+// must have, from key:value entries, and the keys metadata must have with any value, from entries without a
+// value. Keys and values are trimmed and entries without a key are dropped.
+func ParseIdentityMetadataFilter(s string) (values map[string]string, keys []string) {
+	for _, entry := range strings.Split(s, ",") {
+		key, value, hasValue := strings.Cut(entry, ":")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if !hasValue {
+			keys = append(keys, key)
+			continue
+		}
+		if values == nil {
+			values = map[string]string{}
+		}
+		values[key] = strings.TrimSpace(value)
+	}
+	return values, keys
+}
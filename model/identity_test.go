@@ -0,0 +1,257 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionVerification(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{VerificationUnverified, VerificationPending, true},
+		{"", VerificationPending, true},
+		{VerificationPending, VerificationVerified, true},
+		{VerificationPending, VerificationRejected, true},
+		{VerificationRejected, VerificationPending, true},
+		{VerificationVerified, VerificationPending, true},
+		{VerificationUnverified, VerificationVerified, false},
+		{VerificationRejected, VerificationVerified, false},
+		{VerificationVerified, VerificationRejected, false},
+		{VerificationPending, VerificationPending, false},
+		{VerificationPending, "approved", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, CanTransitionVerification(tt.from, tt.to), "%q -> %q", tt.from, tt.to)
+	}
+}
+
+func TestCanChangeIdentityStatus(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{IdentityStatusActive, IdentityStatusBlocked, true},
+		{"", IdentityStatusBlocked, true},
+		{IdentityStatusActive, IdentityStatusClosed, true},
+		{IdentityStatusBlocked, IdentityStatusActive, true},
+		{IdentityStatusBlocked, IdentityStatusClosed, true},
+		{IdentityStatusActive, IdentityStatusActive, false},
+		{IdentityStatusClosed, IdentityStatusActive, false},
+		{IdentityStatusClosed, IdentityStatusBlocked, false},
+		{IdentityStatusActive, "suspended", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, CanChangeIdentityStatus(tt.from, tt.to), "%q -> %q", tt.from, tt.to)
+	}
+}
+
+func TestIdentityDocumentValidate(t *testing.T) {
+	doc := &IdentityDocument{DocumentType: DocumentTypePassport}
+	assert.NoError(t, doc.Validate())
+
+	doc.DocumentType = "selfie"
+	assert.EqualError(t, doc.Validate(), "unknown document type 'selfie'")
+}
+
+func TestIdentityDocumentExpired(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	assert.False(t, (&IdentityDocument{}).Expired(now))
+	assert.True(t, (&IdentityDocument{ExpiresAt: &past}).Expired(now))
+	assert.False(t, (&IdentityDocument{ExpiresAt: &future}).Expired(now))
+}
+
+func TestCanReviewDocument(t *testing.T) {
+	assert.True(t, CanReviewDocument(VerificationPending, VerificationVerified))
+	assert.True(t, CanReviewDocument(VerificationPending, VerificationRejected))
+	assert.False(t, CanReviewDocument(VerificationVerified, VerificationRejected))
+	assert.False(t, CanReviewDocument(VerificationPending, VerificationPending))
+}
+
+func TestMatchDuplicateIdentity(t *testing.T) {
+	dob := time.Date(1990, 4, 2, 0, 0, 0, 0, time.UTC)
+	ada := Identity{FirstName: "Ada", LastName: "Lovelace", EmailAddress: "ada@example.com", PhoneNumber: "+2348000000000", DOB: dob}
+
+	assert.Equal(t, []string{DuplicateOnEmailAddress}, MatchDuplicateIdentity(ada, Identity{EmailAddress: " ADA@example.com"}))
+	assert.Equal(t, []string{DuplicateOnPhoneNumber}, MatchDuplicateIdentity(ada, Identity{PhoneNumber: "+2348000000000"}))
+	assert.Equal(t, []string{DuplicateOnDOB}, MatchDuplicateIdentity(ada, Identity{FirstName: "ada", LastName: "LOVELACE", DOB: dob.Add(3 * time.Hour)}))
+	assert.Nil(t, MatchDuplicateIdentity(ada, Identity{FirstName: "Grace", LastName: "Hopper", DOB: dob}))
+	assert.Nil(t, MatchDuplicateIdentity(Identity{}, Identity{}))
+}
+
+func TestDiffIdentities(t *testing.T) {
+	before := &Identity{IdentityID: "idt1", City: "Abuja", MetaData: map[string]interface{}{"tier": "silver"}}
+	after := &Identity{IdentityID: "idt1", City: "Lagos", MetaData: map[string]interface{}{"tier": "gold"}}
+
+	changes, err := DiffIdentities(before, after)
+	assert.NoError(t, err)
+	assert.Equal(t, []IdentityFieldChange{
+		{Field: "city", From: "Abuja", To: "Lagos"},
+		{Field: "meta_data", From: map[string]interface{}{"tier": "silver"}, To: map[string]interface{}{"tier": "gold"}},
+	}, changes)
+
+	changes, err = DiffIdentities(before, before)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestMaskTokenizedFields(t *testing.T) {
+	previous := &Identity{FirstName: "Ada", LastName: "Lovelace"}
+	updated := &Identity{FirstName: "Xqz", LastName: "Lovelace", MetaData: map[string]interface{}{"tokenized_fields": map[string]interface{}{"FirstName": true}}}
+
+	MaskTokenizedFields(previous, updated)
+	assert.Equal(t, "Xqz", previous.FirstName)
+	assert.Equal(t, "Lovelace", previous.LastName)
+}
+
+func TestAnonymizeIdentity(t *testing.T) {
+	identity := &Identity{
+		IdentityID: "idt1", IdentityType: "individual", Category: "retail", Nationality: "NG",
+		FirstName: "Ada", LastName: "Lovelace", OtherNames: "King", EmailAddress: "ada@example.com", PhoneNumber: "+2348000000000",
+		DOB: time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC), Street: "12 St James's Square", City: "London", State: "London", PostCode: "SW1Y", Country: "UK",
+		MetaData: map[string]interface{}{"tier": "gold", "tokenized_fields": map[string]interface{}{"FirstName": true, "Gender": true}},
+	}
+
+	AnonymizeIdentity(identity)
+	assert.Equal(t, &Identity{
+		IdentityID: "idt1", IdentityType: "individual", Category: "retail", Nationality: "NG",
+		MetaData: map[string]interface{}{"tier": "gold", "tokenized_fields": map[string]interface{}{"Gender": true}},
+	}, identity)
+
+	values, err := ErasedIdentityValues(identity)
+	assert.NoError(t, err)
+	assert.Len(t, values, len(ErasedIdentityFields))
+	assert.Equal(t, "", values["first_name"])
+	assert.Equal(t, "0001-01-01T00:00:00Z", values["dob"])
+}
+
+func TestParseImportedIdentity(t *testing.T) {
+	identity, err := ParseImportedIdentity(map[string]interface{}{
+		"identity_type": "individual",
+		"first_name":    "Ada",
+		"dob":           "1815-12-10",
+		"meta_data":     map[string]interface{}{"legacy_id": "c-1"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Ada", identity.FirstName)
+	assert.Equal(t, time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC), identity.DOB.UTC())
+	assert.Equal(t, "c-1", identity.MetaData["legacy_id"])
+
+	_, err = ParseImportedIdentity(map[string]interface{}{"first_name": "Ada"})
+	assert.EqualError(t, err, "identity_type is required")
+
+	_, err = ParseImportedIdentity(map[string]interface{}{"identity_type": "individual", "identity_id": "idt_1", "balance": 1})
+	assert.EqualError(t, err, "unknown fields: balance, identity_id")
+
+	_, err = ParseImportedIdentity(map[string]interface{}{"identity_type": "individual", "dob": "10/12/1815"})
+	assert.Error(t, err)
+
+	_, err = ParseImportedIdentity(map[string]interface{}{"identity_type": "individual", "timezone": "Mars/Olympus"})
+	assert.Error(t, err)
+}
+
+func TestIdentityRelationshipValidate(t *testing.T) {
+	assert.NoError(t, (&IdentityRelationship{IdentityID: "idt_1", RelatedIdentityID: "idt_2", Type: RelationshipGuardian}).Validate())
+	assert.Error(t, (&IdentityRelationship{IdentityID: "idt_1", RelatedIdentityID: "idt_2", Type: "parent"}).Validate())
+	assert.Error(t, (&IdentityRelationship{IdentityID: "idt_1", Type: RelationshipMember}).Validate())
+	assert.Error(t, (&IdentityRelationship{IdentityID: "idt_1", RelatedIdentityID: "idt_1", Type: RelationshipMember}).Validate())
+
+	assert.True(t, (&IdentityRelationship{Type: RelationshipDirector}).RequiresOrganization())
+	assert.False(t, (&IdentityRelationship{Type: RelationshipGuardian}).RequiresOrganization())
+}
+
+func TestRiskLevelFor(t *testing.T) {
+	assert.Equal(t, RiskLevelLow, RiskLevelFor(39.9, 40, 70))
+	assert.Equal(t, RiskLevelMedium, RiskLevelFor(40, 40, 70))
+	assert.Equal(t, RiskLevelHigh, RiskLevelFor(70, 40, 70))
+}
+
+func TestIdentityExportRecord(t *testing.T) {
+	score := 42.5
+	deletedAt := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	identity := &Identity{
+		IdentityID:   "idt_1",
+		IdentityType: "individual",
+		EmailAddress: "ada@example.com",
+		CreatedAt:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		RiskScore:    &score,
+		DeletedAt:    &deletedAt,
+	}
+
+	record := identity.ExportRecord()
+	assert.Len(t, record, len(IdentityExportColumns))
+	values := make(map[string]string, len(record))
+	for i, column := range IdentityExportColumns {
+		values[column] = record[i]
+	}
+	assert.Equal(t, "idt_1", values["identity_id"])
+	assert.Equal(t, "ada@example.com", values["email_address"])
+	assert.Equal(t, "", values["dob"])
+	assert.Equal(t, "", values["meta_data"])
+	assert.Equal(t, "", values["communication_preferences"])
+	assert.Equal(t, "2026-01-02T03:04:05Z", values["created_at"])
+	assert.Equal(t, "42.5", values["risk_score"])
+	assert.Equal(t, "false", values["verified_email"])
+	assert.Equal(t, "2026-02-01T00:00:00Z", values["deleted_at"])
+}
+
+func TestNormalizeIdentityTags(t *testing.T) {
+	tags, err := NormalizeIdentityTags([]string{" VIP ", "kyc:tier-2", "vip", "segment.retail_uk"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kyc:tier-2", "segment.retail_uk", "vip"}, tags)
+
+	for _, invalid := range [][]string{nil, {""}, {"high value"}, {"vip,dormant"}, {"-vip"}, {strings.Repeat("a", 51)}} {
+		_, err := NormalizeIdentityTags(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseIdentityTagFilter(t *testing.T) {
+	assert.Equal(t, []string{"vip", "dormant"}, ParseIdentityTagFilter(" VIP,, dormant "))
+	assert.Empty(t, ParseIdentityTagFilter(""))
+}
+
+func TestParseIdentityMetadataFilter(t *testing.T) {
+	values, keys := ParseIdentityMetadataFilter(" tier: gold ,pep, ref:a:b,,:orphan")
+	assert.Equal(t, map[string]string{"tier": "gold", "ref": "a:b"}, values)
+	assert.Equal(t, []string{"pep"}, keys)
+
+	values, keys = ParseIdentityMetadataFilter("")
+	assert.Empty(t, values)
+	assert.Empty(t, keys)
+}
+
+func TestIdentityTypes(t *testing.T) {
+	types, err := NewIdentityTypes(map[string]IdentityType{
+		"trust":      {Kind: IdentityTypeOrganization, RequiredFields: []string{"organization_name", "country"}},
+		"individual": {RequiredFields: []string{"first_name", "last_name"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, IdentityTypeOrganization, types.Kind("trust"))
+	assert.Equal(t, IdentityTypeOrganization, types.Kind(IdentityTypeOrganization))
+	assert.Equal(t, IdentityTypeIndividual, types.Kind("unknown"))
+
+	assert.NoError(t, types.Validate(&Identity{IdentityType: "trust", OrganizationName: "Lovelace Trust", Country: "GB"}))
+	assert.NoError(t, types.Validate(&Identity{IdentityType: IdentityTypeOrganization}))
+
+	err = types.Validate(&Identity{IdentityType: "trust", OrganizationName: "Lovelace Trust"})
+	var typeErr *IdentityTypeError
+	assert.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, []FieldError{{Field: "country", Message: "is required"}}, typeErr.Errors)
+
+	err = types.Validate(&Identity{IdentityType: "government"})
+	assert.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "identity_type", typeErr.Errors[0].Field)
+	assert.Contains(t, err.Error(), "must be one of individual, organization, trust")
+
+	_, err = NewIdentityTypes(map[string]IdentityType{"trust": {RequiredFields: []string{"meta_data"}}})
+	assert.Error(t, err)
+	_, err = NewIdentityTypes(map[string]IdentityType{"trust": {Kind: "agency"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The built-in identity types, which are also the kinds of identity every identity type is one of: people, named
+// by their first and last names, and organizations such as companies and trusts, named by their organization
+// name.
+const (
+	IdentityTypeIndividual   = "individual"
+	IdentityTypeOrganization = "organization"
+)
+
+// identityTypeFields report whether an identity has a value for each field identity types can require, by the
+// field names in the API.
+var identityTypeFields = map[string]func(i *Identity) bool{
+	"first_name":        func(i *Identity) bool { return i.FirstName != "" },
+	"last_name":         func(i *Identity) bool { return i.LastName != "" },
+	"other_names":       func(i *Identity) bool { return i.OtherNames != "" },
+	"gender":            func(i *Identity) bool { return i.Gender != "" },
+	"dob":               func(i *Identity) bool { return !i.DOB.IsZero() },
+	"email_address":     func(i *Identity) bool { return i.EmailAddress != "" },
+	"phone_number":      func(i *Identity) bool { return i.PhoneNumber != "" },
+	"nationality":       func(i *Identity) bool { return i.Nationality != "" },
+	"organization_name": func(i *Identity) bool { return i.OrganizationName != "" },
+	"category":          func(i *Identity) bool { return i.Category != "" },
+	"street":            func(i *Identity) bool { return i.Street != "" },
+	"country":           func(i *Identity) bool { return i.Country != "" },
+	"state":             func(i *Identity) bool { return i.State != "" },
+	"post_code":         func(i *Identity) bool { return i.PostCode != "" },
+	"city":              func(i *Identity) bool { return i.City != "" },
+	"locale":            func(i *Identity) bool { return i.Locale != "" },
+	"timezone":          func(i *Identity) bool { return i.Timezone != "" },
+}
+
+// IdentityType is a type identities can have, such as trust or joint-account, with the kind of identity it is and
+// the fields identities of the type must have.
+type IdentityType struct {
+	Kind           string   `json:"kind"`
+	RequiredFields []string `json:"required_fields"`
+}
+
+// IdentityTypes are the identity types identities can have, keyed by name.
+type IdentityTypes map[string]IdentityType
+
+// NewIdentityTypes returns the built-in identity types, which require no fields, together with the defined types.
+// Defined types are individuals unless their kind says otherwise, and can redefine the built-in types to make
+// them require fields.
+func NewIdentityTypes(defined map[string]IdentityType) (IdentityTypes, error) {
+	types := IdentityTypes{
+		IdentityTypeIndividual:   {Kind: IdentityTypeIndividual},
+		IdentityTypeOrganization: {Kind: IdentityTypeOrganization},
+	}
+	for name, identityType := range defined {
+		if strings.TrimSpace(name) == "" {
+			return nil, errors.New("identity types cannot have an empty name")
+		}
+		switch identityType.Kind {
+		case "":
+			identityType.Kind = IdentityTypeIndividual
+		case IdentityTypeIndividual, IdentityTypeOrganization:
+		default:
+			return nil, fmt.Errorf("identity type %s: unknown kind %q, expected individual or organization", name, identityType.Kind)
+		}
+		for _, field := range identityType.RequiredFields {
+			if identityTypeFields[field] == nil {
+				return nil, fmt.Errorf("identity type %s: field %q cannot be required", name, field)
+			}
+		}
+		types[name] = identityType
+	}
+	return types, nil
+}
+
+// Kind returns whether identities of a type are individuals or organizations. Unknown types are individuals.
+func (t IdentityTypes) Kind(name string) string {
+	if identityType, ok := t[name]; ok {
+		return identityType.Kind
+	}
+	return IdentityTypeIndividual
+}
+
+// Validate checks that the identity has one of the types and every field its type requires.
+func (t IdentityTypes) Validate(identity *Identity) error {
+	identityType, ok := t[identity.IdentityType]
+	if !ok {
+		names := make([]string, 0, len(t))
+		for name := range t {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return &IdentityTypeError{IdentityType: identity.IdentityType, Errors: []FieldError{
+			{Field: "identity_type", Message: "must be one of " + strings.Join(names, ", ")},
+		}}
+	}
+
+	var errs []FieldError
+	for _, field := range identityType.RequiredFields {
+		if !identityTypeFields[field](identity) {
+			errs = append(errs, FieldError{Field: field, Message: "is required"})
+		}
+	}
+	if len(errs) > 0 {
+		return &IdentityTypeError{IdentityType: identity.IdentityType, Errors: errs}
+	}
+	return nil
+}
+
+// IdentityTypeError is returned when an identity has an unknown identity type or lacks fields its type requires,
+// listing them.
+type IdentityTypeError struct {
+	IdentityType string       `json:"identity_type"`
+	Errors       []FieldError `json:"errors"`
+}
+
+func (e *IdentityTypeError) Error() string {
+	fields := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		fields[i] = fieldErr.Field + " " + fieldErr.Message
+	}
+	return fmt.Sprintf("identity does not match the %q identity type: %s", e.IdentityType, strings.Join(fields, "; "))
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+// PendingInflightCredit is an inflight transaction that has not fully landed on its destination yet.
+type PendingInflightCredit struct {
+	TransactionID string    `json:"transaction_id"`
+	Reference     string    `json:"reference"`
+	Source        string    `json:"source"`
+	Currency      string    `json:"currency"`
+	Precision     float64   `json:"precision"`
+	PreciseAmount *big.Int  `json:"precise_amount"`
+	PendingAmount *big.Int  `json:"pending_amount"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// BalanceBreakdown splits a balance into what has settled, what is on its way in and what is on its way out.
+type BalanceBreakdown struct {
+	BalanceID             string                   `json:"balance_id"`
+	Currency              string                   `json:"currency"`
+	Balance               *big.Int                 `json:"balance"`
+	CreditBalance         *big.Int                 `json:"credit_balance"`
+	DebitBalance          *big.Int                 `json:"debit_balance"`
+	AvailableBalance      *big.Int                 `json:"available_balance"`
+	InflightCreditBalance *big.Int                 `json:"inflight_credit_balance"`
+	InflightDebitBalance  *big.Int                 `json:"inflight_debit_balance"`
+	QueuedCreditBalance   *big.Int                 `json:"queued_credit_balance,omitempty"`
+	QueuedDebitBalance    *big.Int                 `json:"queued_debit_balance,omitempty"`
+	PendingCredits        []*PendingInflightCredit `json:"pending_credits"`
+}
+
+// InflightCreditEvent is the payload of the webhooks sent when an inflight credit lands on its destination or
+// is voided. InflightCreditBalance is what is still pending on the destination afterwards and is left out for
+// sharded balances, whose pending credits are spread over several rows.
+type InflightCreditEvent struct {
+	BalanceID             string   `json:"balance_id"`
+	TransactionID         string   `json:"transaction_id"`
+	ParentTransaction     string   `json:"parent_transaction"`
+	Currency              string   `json:"currency"`
+	Amount                float64  `json:"amount"`
+	PreciseAmount         *big.Int `json:"precise_amount"`
+	InflightCreditBalance *big.Int `json:"inflight_credit_balance,omitempty"`
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import "time"
+
+const (
+	IntegrityCheckUnbalancedTransactions = "unbalanced_transactions"
+	IntegrityCheckBalanceDrift           = "balance_drift"
+	IntegrityCheckOrphanedInflight       = "orphaned_inflight"
+	IntegrityCheckTransactionHashes      = "transaction_hashes"
+
+	RepairActionRecomputeBalance = "recompute_balance" // Rewrite the balance fields from the sums of its applied transactions.
+	RepairActionVoidInflight     = "void_inflight"     // Void an inflight transaction that was never resolved.
+	RepairActionManualReview     = "manual_review"     // The issue cannot be repaired automatically.
+)
+
+// IntegrityIssue is a single invariant violation found by a ledger integrity check.
+type IntegrityIssue struct {
+	Check    string            `json:"check"`
+	EntityID string            `json:"entity_id"`
+	Detail   string            `json:"detail"`
+	Expected string            `json:"expected,omitempty"`
+	Actual   string            `json:"actual,omitempty"`
+	Repair   *RepairSuggestion `json:"repair,omitempty"`
+}
+
+// RepairSuggestion describes the job an operator can run to resolve an integrity issue.
+type RepairSuggestion struct {
+	Action string            `json:"action"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// IntegrityCheckResult summarises the outcome of one integrity check.
+type IntegrityCheckResult struct {
+	Name       string `json:"name"`
+	Passed     bool   `json:"passed"`
+	IssueCount int    `json:"issue_count"`
+	Error      string `json:"error,omitempty"`
+}
+
+// IntegrityReport is the machine-readable output of a ledger integrity verification.
+type IntegrityReport struct {
+	GeneratedAt time.Time              `json:"generated_at"`
+	Healthy     bool                   `json:"healthy"`
+	Checks      []IntegrityCheckResult `json:"checks"`
+	Issues      []IntegrityIssue       `json:"issues"`
+}
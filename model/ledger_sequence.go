@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// SequencedStatuses are the statuses of the transactions that move balances, which are numbered in the
+// sequence of every ledger they post to.
+var SequencedStatuses = []string{"APPLIED", "INFLIGHT", "VOID"}
+
+// IsSequencedStatus reports whether transactions recorded with a status are numbered in their ledgers.
+func IsSequencedStatus(status string) bool {
+	for _, sequenced := range SequencedStatuses {
+		if status == sequenced {
+			return true
+		}
+	}
+	return false
+}
+
+// LedgerSequence is the position of a transaction in the sequence of a ledger it posted to. Sequence numbers
+// start at 1 and are assigned without gaps, so a reader that has seen sequence n of a ledger knows the next
+// transaction to expect is n+1.
+type LedgerSequence struct {
+	LedgerID string `json:"ledger_id"`
+	Sequence int64  `json:"sequence"`
+}
+
+// LedgerSequenceEntry is a numbered transaction of a ledger.
+type LedgerSequenceEntry struct {
+	Sequence      int64     `json:"sequence"`
+	TransactionID string    `json:"transaction_id"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// LedgerSequencePage is a run of consecutive sequence numbers of a ledger.
+type LedgerSequencePage struct {
+	LedgerID     string                `json:"ledger_id"`
+	LastSequence int64                 `json:"last_sequence"` // The sequence number of the latest transaction of the ledger
+	Entries      []LedgerSequenceEntry `json:"entries"`
+}
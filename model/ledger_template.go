@@ -0,0 +1,67 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+// LedgerTemplateVersion is the version of the ledger template format written by exports. Imports reject
+// templates of other versions.
+const LedgerTemplateVersion = 1
+
+// LedgerTemplate is the structure of a ledger without its transaction data: its balances with zero amounts,
+// their monitors and minimums, the ledger's minimums by currency and the currencies its balances are held in.
+// It is exported from one environment and imported into another, or cloned within one, so that staging can
+// mirror production's configuration. Identities and monitor callback URLs belong to an environment and are not
+// exported.
+type LedgerTemplate struct {
+	Version         int                     `json:"version"`
+	SourceLedgerID  string                  `json:"source_ledger_id"`
+	Name            string                  `json:"name"`
+	MetaData        map[string]interface{}  `json:"meta_data,omitempty"`
+	Currencies      []string                `json:"currencies"`
+	Balances        []LedgerTemplateBalance `json:"balances"`
+	MinimumBalances []LedgerTemplateMinimum `json:"minimum_balances,omitempty"`
+	ExportedAt      time.Time               `json:"exported_at"`
+}
+
+// LedgerTemplateBalance is a balance of a ledger template. SourceBalanceID is the ID of the balance the
+// template was exported from, which the import reports alongside the ID of the balance it creates.
+type LedgerTemplateBalance struct {
+	SourceBalanceID    string                  `json:"source_balance_id"`
+	Indicator          string                  `json:"indicator,omitempty"`
+	Currency           string                  `json:"currency"`
+	CurrencyMultiplier float64                 `json:"currency_multiplier"`
+	MetaData           map[string]interface{}  `json:"meta_data,omitempty"`
+	Monitors           []LedgerTemplateMonitor `json:"monitors,omitempty"`
+	MinimumBalance     *big.Int                `json:"minimum_balance,omitempty"`
+}
+
+// LedgerTemplateMonitor is a monitor of a balance in a ledger template.
+type LedgerTemplateMonitor struct {
+	Description string         `json:"description,omitempty"`
+	Condition   AlertCondition `json:"condition"`
+}
+
+// LedgerTemplateMinimum is the minimum of every balance of a ledger template in a currency.
+type LedgerTemplateMinimum struct {
+	Currency      string   `json:"currency"`
+	PreciseAmount *big.Int `json:"precise_amount"`
+}
+
+// LedgerTemplateImport is the outcome of importing a ledger template. Balances maps the source balance IDs of
+// the template to the IDs of the balances created for them, and Skipped lists the template balances that
+// could not be created, such as those whose indicator is already in use.
+type LedgerTemplateImport struct {
+	Ledger          Ledger                  `json:"ledger"`
+	Balances        map[string]string       `json:"balances"`
+	Monitors        int                     `json:"monitors"`
+	MinimumBalances int                     `json:"minimum_balances"`
+	Skipped         []LedgerTemplateSkipped `json:"skipped"`
+}
+
+// LedgerTemplateSkipped is a balance of a ledger template that was not created.
+type LedgerTemplateSkipped struct {
+	SourceBalanceID string `json:"source_balance_id"`
+	Reason          string `json:"reason"`
+}
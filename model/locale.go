@@ -0,0 +1,206 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Timezones are validated the same way whether or not the host has a zoneinfo database
+	"unicode"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Defaults used to format amounts and dates for readers without a locale or timezone.
+const (
+	DefaultLocale   = "en"
+	DefaultTimezone = "UTC"
+)
+
+// CanonicalLocale checks that a locale is a well-formed BCP 47 language tag, such as "en-GB" or "pt-BR", and
+// returns its canonical form.
+func CanonicalLocale(locale string) (string, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", fmt.Errorf("invalid locale %q, expected a BCP 47 language tag such as en-GB", locale)
+	}
+	return tag.String(), nil
+}
+
+// ValidateTimezone checks that a timezone is an IANA timezone name, such as "Europe/London".
+func ValidateTimezone(timezone string) error {
+	if timezone == "" || timezone == "Local" {
+		return errors.New("timezone must be an IANA timezone name such as Europe/London")
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q, expected an IANA timezone name such as Europe/London", timezone)
+	}
+	return nil
+}
+
+// LocaleFormat formats amounts and dates for a reader's locale and timezone.
+type LocaleFormat struct {
+	Locale   string `json:"locale"`
+	Timezone string `json:"timezone"`
+
+	tag      language.Tag
+	location *time.Location
+	symbols  numberSymbols
+}
+
+// NewLocaleFormat returns the format of a locale and timezone. Empty or invalid values fall back to
+// DefaultLocale and DefaultTimezone.
+func NewLocaleFormat(locale, timezone string) LocaleFormat {
+	tag, err := language.Parse(locale)
+	if locale == "" || err != nil {
+		tag = language.MustParse(DefaultLocale)
+	}
+	location, err := time.LoadLocation(timezone)
+	if timezone == "" || timezone == "Local" || err != nil {
+		timezone, location = DefaultTimezone, time.UTC
+	}
+	return LocaleFormat{Locale: tag.String(), Timezone: timezone, tag: tag, location: location, symbols: symbolsFor(tag)}
+}
+
+// FormatTime formats a time as RFC 3339 in the format's timezone.
+func (f LocaleFormat) FormatTime(t time.Time) string {
+	return t.In(f.loc()).Format(time.RFC3339)
+}
+
+// FormatDate formats the calendar date of a time in the format's timezone.
+func (f LocaleFormat) FormatDate(t time.Time) string {
+	return t.In(f.loc()).Format("2006-01-02")
+}
+
+// FormatAmount formats a precise amount in major units with the digits, grouping and decimal separator of
+// the format's locale, e.g. 123456789 with a multiplier of 100 is "1,234,567.89" in en and "1.234.567,89"
+// in de.
+//
+// Parameters:
+// - preciseAmount: The amount in minor units.
+// - multiplier: The currency multiplier of the amount, e.g. 100 for cents.
+func (f LocaleFormat) FormatAmount(preciseAmount *big.Int, multiplier float64) string {
+	if preciseAmount == nil {
+		return ""
+	}
+	symbols := f.symbols
+	if symbols.digits == nil {
+		symbols = symbolsFor(language.MustParse(DefaultLocale))
+	}
+
+	places := 0
+	if multiplier > 1 {
+		places = int(math.Round(math.Log10(multiplier)))
+	}
+	digits := new(big.Int).Abs(preciseAmount).String()
+	if len(digits) <= places {
+		digits = strings.Repeat("0", places-len(digits)+1) + digits
+	}
+	integer, fraction := digits[:len(digits)-places], digits[len(digits)-places:]
+
+	var out strings.Builder
+	if preciseAmount.Sign() < 0 {
+		out.WriteByte('-')
+	}
+	for i, d := range integer {
+		if i > 0 && symbols.group != "" && symbols.groupBefore(len(integer)-i) {
+			out.WriteString(symbols.group)
+		}
+		out.WriteRune(symbols.digits[d-'0'])
+	}
+	if fraction != "" {
+		out.WriteString(symbols.decimal)
+		for _, d := range fraction {
+			out.WriteRune(symbols.digits[d-'0'])
+		}
+	}
+	return out.String()
+}
+
+func (f LocaleFormat) loc() *time.Location {
+	if f.location == nil {
+		return time.UTC
+	}
+	return f.location
+}
+
+// numberSymbols are the digits and separators a locale writes numbers with.
+type numberSymbols struct {
+	digits    []rune // digits[i] is how the digit i is written
+	group     string
+	decimal   string
+	primary   int // size of the group nearest the decimal separator
+	secondary int // size of the other groups, which differs in e.g. en-IN
+}
+
+// groupBefore reports whether a group separator goes before the digit with remaining digits left, itself
+// included, in the integer part.
+func (s numberSymbols) groupBefore(remaining int) bool {
+	if remaining == s.primary {
+		return true
+	}
+	return remaining > s.primary && s.secondary > 0 && (remaining-s.primary)%s.secondary == 0
+}
+
+var numberSymbolCache sync.Map
+
+// symbolsFor learns the number symbols of a locale from how it formats reference numbers, since the
+// symbols themselves are not exported by x/text.
+func symbolsFor(tag language.Tag) numberSymbols {
+	if cached, ok := numberSymbolCache.Load(tag); ok {
+		return cached.(numberSymbols)
+	}
+	printer := message.NewPrinter(tag)
+	symbols := numberSymbols{decimal: ".", primary: 3, secondary: 3}
+
+	digits := []rune{}
+	for _, r := range printer.Sprint(number.Decimal(1234567890, number.NoSeparator())) {
+		if unicode.IsDigit(r) {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) == 10 {
+		// The reference lists the digits 1 to 9 and then 0
+		symbols.digits = append([]rune{digits[9]}, digits[:9]...)
+	} else {
+		symbols.digits = []rune("0123456789")
+	}
+
+	// Split 1234567.5 into runs of digits and the separators between them
+	var groups []int
+	var separators []string
+	run, sep := 0, ""
+	for _, r := range printer.Sprint(number.Decimal(1234567.5, number.Scale(1))) {
+		if unicode.IsDigit(r) {
+			if sep != "" {
+				separators = append(separators, sep)
+				sep = ""
+			}
+			run++
+			continue
+		}
+		if run > 0 {
+			groups = append(groups, run)
+			run = 0
+		}
+		sep += string(r)
+	}
+	if len(separators) > 0 {
+		symbols.decimal = separators[len(separators)-1]
+		if len(separators) > 1 {
+			symbols.group = separators[0]
+			symbols.primary = groups[len(groups)-1]
+			if len(groups) > 2 {
+				symbols.secondary = groups[len(groups)-2]
+			}
+		}
+	}
+
+	numberSymbolCache.Store(tag, symbols)
+	return symbols
+}
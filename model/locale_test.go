@@ -0,0 +1,75 @@
+package model
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalLocale(t *testing.T) {
+	locale, err := CanonicalLocale("en-gb")
+	require.NoError(t, err)
+	assert.Equal(t, "en-GB", locale)
+
+	_, err = CanonicalLocale("english please")
+	assert.Error(t, err)
+}
+
+func TestValidateTimezone(t *testing.T) {
+	assert.NoError(t, ValidateTimezone("America/New_York"))
+	assert.Error(t, ValidateTimezone("Local"))
+	assert.Error(t, ValidateTimezone("Nowhere/Special"))
+}
+
+func TestLocaleFormat_FormatAmount(t *testing.T) {
+	tests := []struct {
+		locale     string
+		amount     int64
+		multiplier float64
+		expected   string
+	}{
+		{"en-US", 123456789, 100, "1,234,567.89"},
+		{"de-DE", 123456789, 100, "1.234.567,89"},
+		{"fr-FR", 123456789, 100, "1\u00a0234\u00a0567,89"},
+		{"en-IN", 123456789, 100, "12,34,567.89"},
+		{"en-US", -5, 100, "-0.05"},
+		{"en-US", 1500, 1, "1,500"},
+		{"ja-JP", 999, 1, "999"},
+		{"", 100000, 1000, "100.000"},
+	}
+	for _, tt := range tests {
+		format := NewLocaleFormat(tt.locale, "")
+		assert.Equal(t, tt.expected, format.FormatAmount(big.NewInt(tt.amount), tt.multiplier), tt.locale)
+	}
+
+	assert.Equal(t, "", NewLocaleFormat("en", "").FormatAmount(nil, 100))
+}
+
+func TestLocaleFormat_FormatTime(t *testing.T) {
+	at := time.Date(2026, 3, 31, 23, 30, 0, 0, time.UTC)
+
+	tokyo := NewLocaleFormat("ja-JP", "Asia/Tokyo")
+	assert.Equal(t, "2026-04-01T08:30:00+09:00", tokyo.FormatTime(at))
+	assert.Equal(t, "2026-04-01", tokyo.FormatDate(at))
+
+	// Unknown values fall back to the defaults
+	fallback := NewLocaleFormat("???", "Nowhere/Special")
+	assert.Equal(t, DefaultLocale, fallback.Locale)
+	assert.Equal(t, DefaultTimezone, fallback.Timezone)
+	assert.Equal(t, "2026-03-31T23:30:00Z", fallback.FormatTime(at))
+}
+
+func TestIdentity_ValidatePreferences(t *testing.T) {
+	identity := Identity{Locale: "pt-br", Timezone: "America/Sao_Paulo", CommunicationPreferences: &CommunicationPreferences{
+		Channel: CommunicationChannelEmail,
+		OptOuts: []string{CommunicationMarketing},
+	}}
+	require.NoError(t, identity.ValidatePreferences())
+	assert.Equal(t, "pt-BR", identity.Locale)
+
+	identity.CommunicationPreferences.OptOuts = []string{"everything"}
+	assert.Error(t, identity.ValidatePreferences())
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// MaintenanceMode is the maintenance state of Blnk as a whole, or of a single ledger when LedgerID is set. While
+// it is enabled, operations that change data are rejected and reads keep being served, so that data can be
+// migrated or a failover completed without writes landing midway. Rejected clients are asked to retry after
+// RetryAfter seconds.
+type MaintenanceMode struct {
+	LedgerID   string    `json:"ledger_id,omitempty"`
+	Enabled    bool      `json:"enabled"`
+	Reason     string    `json:"reason,omitempty"`
+	RetryAfter int       `json:"retry_after"`
+	UpdatedBy  string    `json:"updated_by,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// MaintenanceStatus is the global maintenance mode and the ledgers in maintenance.
+type MaintenanceStatus struct {
+	Global  MaintenanceMode   `json:"global"`
+	Ledgers []MaintenanceMode `json:"ledgers"`
+}
+
+// LedgerMode returns the maintenance mode of a ledger, or false when the ledger is not in maintenance.
+func (s *MaintenanceStatus) LedgerMode(ledgerID string) (MaintenanceMode, bool) {
+	for _, mode := range s.Ledgers {
+		if mode.LedgerID == ledgerID {
+			return mode, true
+		}
+	}
+	return MaintenanceMode{}, false
+}
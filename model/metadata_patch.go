@@ -0,0 +1,24 @@
+package model
+
+// MergeMetaDataPatch applies a JSON merge patch (RFC 7386) to metadata and returns the result, leaving both
+// arguments as they were: keys the patch sets to null are removed, objects are merged key by key, and any other
+// value replaces the one it patches.
+func MergeMetaDataPatch(target, patch map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(target)+len(patch))
+	for key, value := range target {
+		merged[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		if object, ok := value.(map[string]interface{}); ok {
+			current, _ := merged[key].(map[string]interface{})
+			merged[key] = MergeMetaDataPatch(current, object)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
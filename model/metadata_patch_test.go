@@ -0,0 +1,41 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeMetaDataPatch(t *testing.T) {
+	target := map[string]interface{}{
+		"tier":    "gold",
+		"segment": "retail",
+		"address": map[string]interface{}{"city": "Lagos", "zip": "100001"},
+		"tags":    []interface{}{"a", "b"},
+	}
+	patch := map[string]interface{}{
+		"segment": nil,
+		"address": map[string]interface{}{"zip": nil, "street": "1 Marina"},
+		"tags":    []interface{}{"c"},
+		"missing": nil,
+		"limits":  map[string]interface{}{"daily": float64(500), "weekly": nil},
+	}
+
+	merged := MergeMetaDataPatch(target, patch)
+	assert.Equal(t, map[string]interface{}{
+		"tier":    "gold",
+		"address": map[string]interface{}{"city": "Lagos", "street": "1 Marina"},
+		"tags":    []interface{}{"c"},
+		"limits":  map[string]interface{}{"daily": float64(500)},
+	}, merged)
+
+	// The metadata patched is left as it was
+	assert.Equal(t, "retail", target["segment"])
+	assert.Equal(t, "100001", target["address"].(map[string]interface{})["zip"])
+
+	// Objects replace values that are not objects
+	merged = MergeMetaDataPatch(map[string]interface{}{"tier": "gold"}, map[string]interface{}{"tier": map[string]interface{}{"name": "gold"}})
+	assert.Equal(t, map[string]interface{}{"name": "gold"}, merged["tier"])
+
+	assert.Empty(t, MergeMetaDataPatch(nil, map[string]interface{}{"tier": nil}))
+}
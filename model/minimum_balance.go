@@ -0,0 +1,91 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// MinimumBalance is a floor that debits cannot take a balance below. Unlike an overdraft limit, which each
+// transaction asks for, a minimum belongs to the balance and holds whether or not the transaction allows an
+// overdraft. It applies to one balance, or to every balance of a ledger in a currency; a balance's own
+// minimum takes precedence over its ledger's.
+type MinimumBalance struct {
+	MinimumBalanceID string    `json:"minimum_balance_id"`
+	BalanceID        string    `json:"balance_id,omitempty"`
+	LedgerID         string    `json:"ledger_id,omitempty"`
+	Currency         string    `json:"currency,omitempty"`
+	Amount           float64   `json:"amount,omitempty"`
+	Precision        float64   `json:"precision,omitempty"`
+	PreciseAmount    *big.Int  `json:"precise_amount"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// Validate checks that the minimum applies to a balance or to a ledger in a currency, and fills in its
+// precise amount from its amount and precision when it is not given.
+func (m *MinimumBalance) Validate() error {
+	if (m.BalanceID == "") == (m.LedgerID == "") {
+		return errors.New("provide either balance_id or ledger_id")
+	}
+	m.Currency = strings.ToUpper(strings.TrimSpace(m.Currency))
+	if m.LedgerID != "" && m.Currency == "" {
+		return errors.New("currency is required for a ledger minimum balance")
+	}
+	if m.BalanceID != "" {
+		m.Currency = ""
+	}
+	if m.Precision < 0 {
+		return errors.New("precision cannot be negative")
+	}
+	if m.PreciseAmount == nil {
+		precision := m.Precision
+		if precision == 0 {
+			precision = 1
+		}
+		m.PreciseAmount = decimal.NewFromFloat(m.Amount).Mul(decimal.NewFromFloat(precision)).BigInt()
+	}
+	return nil
+}
+
+// MinimumBalanceError is returned when a debit would take a balance below its minimum.
+type MinimumBalanceError struct {
+	BalanceID        string
+	Minimum          *big.Int
+	ResultingBalance *big.Int
+}
+
+func (e *MinimumBalanceError) Error() string {
+	return fmt.Sprintf("transaction would take balance %s to %s, below its minimum balance of %s", e.BalanceID, e.ResultingBalance, e.Minimum)
+}
+
+// MinimumBalanceBreach describes an attempt to debit a balance below its minimum. Overridden is set when the
+// debit was allowed anyway by a caller permitted to override minimums.
+type MinimumBalanceBreach struct {
+	BalanceID        string    `json:"balance_id"`
+	TransactionID    string    `json:"transaction_id"`
+	Reference        string    `json:"reference"`
+	PreciseAmount    *big.Int  `json:"precise_amount"`
+	Minimum          *big.Int  `json:"minimum"`
+	ResultingBalance *big.Int  `json:"resulting_balance"`
+	Overridden       bool      `json:"overridden"`
+	AttemptedAt      time.Time `json:"attempted_at"`
+}
+
+// CheckMinimumBalance checks a balance that a debit has been applied to against a minimum, returning nil
+// when the balance is at or above it. The balance available after the debit is its balance less its
+// inflight and queued debits, as when funds are checked.
+func CheckMinimumBalance(balance *Balance, minimum *big.Int) *MinimumBalanceError {
+	balance.InitializeBalanceFields()
+	available := new(big.Int).Sub(balance.Balance, balance.InflightDebitBalance)
+	if balance.QueuedDebitBalance != nil {
+		available.Sub(available, balance.QueuedDebitBalance)
+	}
+	if available.Cmp(minimum) >= 0 {
+		return nil
+	}
+	return &MinimumBalanceError{BalanceID: balance.BalanceID, Minimum: minimum, ResultingBalance: available}
+}
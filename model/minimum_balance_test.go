@@ -0,0 +1,37 @@
+package model
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinimumBalance_Validate(t *testing.T) {
+	minimum := &MinimumBalance{BalanceID: "bln_1", Amount: 25.5, Precision: 100, Currency: "usd"}
+	require.NoError(t, minimum.Validate())
+	assert.Equal(t, big.NewInt(2550), minimum.PreciseAmount)
+	assert.Empty(t, minimum.Currency, "a balance minimum is in the balance's currency")
+
+	ledger := &MinimumBalance{LedgerID: "ldg_1", Currency: "ngn", PreciseAmount: big.NewInt(1000)}
+	require.NoError(t, ledger.Validate())
+	assert.Equal(t, "NGN", ledger.Currency)
+
+	assert.Error(t, (&MinimumBalance{}).Validate())
+	assert.Error(t, (&MinimumBalance{BalanceID: "bln_1", LedgerID: "ldg_1"}).Validate())
+	assert.Error(t, (&MinimumBalance{LedgerID: "ldg_1"}).Validate())
+}
+
+func TestCheckMinimumBalance(t *testing.T) {
+	balance := &Balance{BalanceID: "bln_1", Balance: big.NewInt(1500), InflightDebitBalance: big.NewInt(300)}
+	assert.Nil(t, CheckMinimumBalance(balance, big.NewInt(1200)))
+
+	breach := CheckMinimumBalance(balance, big.NewInt(1201))
+	require.NotNil(t, breach)
+	assert.Equal(t, big.NewInt(1200), breach.ResultingBalance)
+	assert.Contains(t, breach.Error(), "minimum balance")
+
+	balance.QueuedDebitBalance = big.NewInt(200)
+	assert.NotNil(t, CheckMinimumBalance(balance, big.NewInt(1200)))
+}
@@ -162,12 +162,8 @@ func canProcessTransaction(transaction *Transaction, sourceBalance *Balance) err
 		// Calculate the resulting balance after transaction, considering inflight and queued debits
 		resultingBalance := new(big.Int).Sub(availableBalance, transactionAmount)
 
-		// Convert overdraft limit to big.Int with precision applied
-		overdraftLimitPrecise := int64(transaction.OverdraftLimit * transaction.Precision)
-		overdraftLimitBigInt := new(big.Int).SetInt64(overdraftLimitPrecise)
-
-		// Negative of overdraft limit (as balance will be negative)
-		negativeOverdraftLimit := new(big.Int).Neg(overdraftLimitBigInt)
+		// Negative of overdraft limit (as balance will be negative), in the transaction's precision
+		negativeOverdraftLimit := transaction.OverdraftLimitMoney().Neg().Amount
 
 		// Check if resulting balance is within overdraft limit
 		if resultingBalance.Cmp(negativeOverdraftLimit) >= 0 {
@@ -426,7 +422,7 @@ func UpdateBalances(transaction *Transaction, source, destination *Balance) erro
 	source.computeBalance(transaction.Inflight)
 
 	// Calculate destination amount with rate
-	destinationAmount := ApplyRate(transaction.PreciseAmount, transaction.Rate)
+	destinationAmount := ApplyRateWithRounding(transaction.PreciseAmount, transaction.Rate, transaction.RoundingMode)
 
 	// Update destination balance with rate-adjusted amount
 	destination.addCredit(destinationAmount, transaction.Inflight)
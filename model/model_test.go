@@ -148,6 +148,19 @@ func TestCanProcessTransaction(t *testing.T) {
 		assert.EqualError(t, err, "transaction exceeds overdraft limit")
 	})
 
+	t.Run("Overdraft limit with decimal places", func(t *testing.T) {
+		sourceBalance := &Balance{
+			Balance: big.NewInt(0),
+		}
+		txn := &Transaction{
+			PreciseAmount:  Int64ToBigInt(29), // 0.29 * 100 is 28.999... as floats
+			Precision:      100,
+			OverdraftLimit: 0.29,
+		}
+		err := canProcessTransaction(txn, sourceBalance)
+		assert.NoError(t, err)
+	})
+
 	t.Run("Sufficient funds with no inflight debits", func(t *testing.T) {
 		sourceBalance := &Balance{
 			Balance:         big.NewInt(1000),
@@ -583,3 +596,17 @@ func TestApplyPrecisionWithEmptyAmount(t *testing.T) {
 		}
 	})
 }
+
+func TestAPIKey_IsValidDuringRotation(t *testing.T) {
+	replacement := "api_key_new"
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Minute)
+
+	key := &APIKey{ExpiresAt: time.Now().Add(24 * time.Hour), RotatedTo: &replacement, RotationExpiresAt: &future}
+	assert.True(t, key.IsValid())
+	assert.True(t, key.IsRotating())
+
+	key.RotationExpiresAt = &past
+	assert.False(t, key.IsValid())
+	assert.False(t, key.IsRotating())
+}
@@ -0,0 +1,241 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrCurrencyMismatch is returned when amounts of different currencies are added, subtracted or compared.
+	ErrCurrencyMismatch = errors.New("amounts are in different currencies")
+
+	// ErrPrecisionMismatch is returned when amounts of the same currency are combined at precisions that cannot
+	// be converted to one another exactly, such as 100 and 3.
+	ErrPrecisionMismatch = errors.New("amounts have precisions that cannot be combined exactly")
+)
+
+// Money is an amount of a currency at a precision: Amount is counted in units of 1/Precision of the major unit,
+// so 1234 USD at precision 100 is $12.34. Amounts only combine with amounts of their currency, and amounts at
+// different precisions are converted to the finer one, so that cents are never added to dollars.
+type Money struct {
+	Amount    *big.Int
+	Currency  string
+	Precision float64
+}
+
+// NewMoney returns an amount in units of precision. A nil amount is zero and a precision of zero or less is 1.
+// The amount is copied, so later changes to it do not change the Money.
+func NewMoney(amount *big.Int, currency string, precision float64) Money {
+	if precision <= 0 {
+		precision = 1
+	}
+	value := new(big.Int)
+	if amount != nil {
+		value.Set(amount)
+	}
+	return Money{Amount: value, Currency: currency, Precision: precision}
+}
+
+// ParseMoney parses an amount in major units, such as "12.34", at a precision. Amounts with more decimal places
+// than the precision holds are rejected rather than rounded.
+func ParseMoney(amount, currency string, precision float64) (Money, error) {
+	value, err := decimal.NewFromString(amount)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+	if precision <= 0 {
+		precision = 1
+	}
+	units := value.Mul(decimal.NewFromFloat(precision))
+	if !units.IsInteger() {
+		return Money{}, fmt.Errorf("amount %s has more decimal places than precision %v holds", amount, precision)
+	}
+	return Money{Amount: units.BigInt(), Currency: currency, Precision: precision}, nil
+}
+
+// MoneyFromFloat returns an amount given in major units as a float, such as an overdraft limit, at a precision.
+// The float is read as the shortest decimal that represents it, so 0.29 at precision 100 is 29 rather than the
+// 28 that multiplying the floats gives. Units finer than the precision are truncated.
+func MoneyFromFloat(amount float64, currency string, precision float64) Money {
+	if precision <= 0 {
+		precision = 1
+	}
+	units := decimal.NewFromFloat(amount).Mul(decimal.NewFromFloat(precision))
+	return Money{Amount: units.BigInt(), Currency: currency, Precision: precision}
+}
+
+// Decimal returns the amount in major units.
+func (m Money) Decimal() decimal.Decimal {
+	if m.Amount == nil {
+		return decimal.Zero
+	}
+	precision := m.Precision
+	if precision <= 0 {
+		precision = 1
+	}
+	return decimal.NewFromBigInt(m.Amount, 0).Div(decimal.NewFromFloat(precision))
+}
+
+// String returns the amount in major units with its currency, such as "12.34 USD".
+func (m Money) String() string {
+	return m.Decimal().String() + " " + m.Currency
+}
+
+// Sign returns -1, 0 or 1 as the amount is negative, zero or positive.
+func (m Money) Sign() int {
+	if m.Amount == nil {
+		return 0
+	}
+	return m.Amount.Sign()
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Sign() == 0
+}
+
+// Neg returns the amount with its sign flipped.
+func (m Money) Neg() Money {
+	result := NewMoney(m.Amount, m.Currency, m.Precision)
+	result.Amount.Neg(result.Amount)
+	return result
+}
+
+// Add returns the sum of two amounts of a currency, at the finer of their precisions.
+func (m Money) Add(other Money) (Money, error) {
+	a, b, err := AlignMoney(m, other)
+	if err != nil {
+		return Money{}, err
+	}
+	a.Amount.Add(a.Amount, b.Amount)
+	return a, nil
+}
+
+// Sub returns the difference of two amounts of a currency, at the finer of their precisions.
+func (m Money) Sub(other Money) (Money, error) {
+	a, b, err := AlignMoney(m, other)
+	if err != nil {
+		return Money{}, err
+	}
+	a.Amount.Sub(a.Amount, b.Amount)
+	return a, nil
+}
+
+// Cmp compares two amounts of a currency, returning -1, 0 or 1 as m is less than, equal to or greater than other.
+func (m Money) Cmp(other Money) (int, error) {
+	a, b, err := AlignMoney(m, other)
+	if err != nil {
+		return 0, err
+	}
+	return a.Amount.Cmp(b.Amount), nil
+}
+
+// Rescale converts the amount to another precision. Amounts that fall between two units of the new precision
+// are rounded with mode, and truncated without one. An unknown mode is an error rather than a truncation.
+func (m Money) Rescale(precision float64, mode RoundingMode) (Money, error) {
+	if err := mode.Validate(); err != nil {
+		return Money{}, err
+	}
+	if precision <= 0 {
+		precision = 1
+	}
+	// The part rounded away is not kept: a rescaled amount has no leg to post it to
+	rounded, _ := mode.Round(m.Decimal().Mul(decimal.NewFromFloat(precision)))
+	return Money{Amount: rounded, Currency: m.Currency, Precision: precision}, nil
+}
+
+// AlignMoney returns copies of two amounts of a currency at the finer of their precisions, or an error if they
+// are in different currencies or at precisions that cannot be converted exactly.
+func AlignMoney(a, b Money) (Money, Money, error) {
+	if a.Currency != b.Currency {
+		return Money{}, Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, a.Currency, b.Currency)
+	}
+	a = NewMoney(a.Amount, a.Currency, a.Precision)
+	b = NewMoney(b.Amount, b.Currency, b.Precision)
+	if a.Precision == b.Precision {
+		return a, b, nil
+	}
+
+	precision := a.Precision
+	if b.Precision > precision {
+		precision = b.Precision
+	}
+	for _, m := range []*Money{&a, &b} {
+		units := decimal.NewFromBigInt(m.Amount, 0).Mul(decimal.NewFromFloat(precision)).Div(decimal.NewFromFloat(m.Precision))
+		if !units.IsInteger() {
+			return Money{}, Money{}, fmt.Errorf("%w: %v and %v", ErrPrecisionMismatch, a.Precision, b.Precision)
+		}
+		m.Amount, m.Precision = units.BigInt(), precision
+	}
+	return a, b, nil
+}
+
+// moneyJSON is the JSON form of Money. The amount in major units is written as a string so that it reaches
+// clients without being rounded to a float.
+type moneyJSON struct {
+	Amount        string   `json:"amount"`
+	PreciseAmount *big.Int `json:"precise_amount"`
+	Currency      string   `json:"currency"`
+	Precision     float64  `json:"precision"`
+}
+
+// MarshalJSON writes the amount with both its precise amount and its amount in major units.
+func (m Money) MarshalJSON() ([]byte, error) {
+	money := NewMoney(m.Amount, m.Currency, m.Precision)
+	return json.Marshal(moneyJSON{
+		Amount:        money.Decimal().String(),
+		PreciseAmount: money.Amount,
+		Currency:      money.Currency,
+		Precision:     money.Precision,
+	})
+}
+
+// UnmarshalJSON reads an amount from its precise amount, or from its amount in major units when it has none.
+// The amount may be written as a string or a number.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		Amount        json.Number `json:"amount"`
+		PreciseAmount *big.Int    `json:"precise_amount"`
+		Currency      string      `json:"currency"`
+		Precision     float64     `json:"precision"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if fields.PreciseAmount != nil {
+		*m = NewMoney(fields.PreciseAmount, fields.Currency, fields.Precision)
+		return nil
+	}
+	if fields.Amount == "" {
+		return errors.New("money requires an amount or a precise_amount")
+	}
+	money, err := ParseMoney(fields.Amount.String(), fields.Currency, fields.Precision)
+	if err != nil {
+		return err
+	}
+	*m = money
+	return nil
+}
+
+// Transaction and Balance keep their amount fields, which are the JSON and database contract of the API, and
+// expose their amounts as Money through the accessors below. Replacing the fields themselves is a breaking change
+// to that contract and is tracked separately from the Money type.
+
+// Money returns the amount of the transaction in its currency and precision.
+func (transaction *Transaction) Money() Money {
+	return NewMoney(transaction.PreciseAmount, transaction.Currency, transaction.Precision)
+}
+
+// OverdraftLimitMoney returns the overdraft limit of the transaction in its currency and precision.
+func (transaction *Transaction) OverdraftLimitMoney() Money {
+	return MoneyFromFloat(transaction.OverdraftLimit, transaction.Currency, transaction.Precision)
+}
+
+// Money returns the balance in its currency, at the precision of its currency multiplier.
+func (balance *Balance) Money() Money {
+	return NewMoney(balance.Balance, balance.Currency, balance.CurrencyMultiplier)
+}
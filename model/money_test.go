@@ -0,0 +1,102 @@
+package model
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoney_Arithmetic(t *testing.T) {
+	dollars := NewMoney(big.NewInt(12), "USD", 1)
+	cents := NewMoney(big.NewInt(1234), "USD", 100)
+
+	sum, err := dollars.Add(cents)
+	require.NoError(t, err)
+	assert.Equal(t, "2434", sum.Amount.String())
+	assert.Equal(t, float64(100), sum.Precision)
+	assert.Equal(t, "24.34 USD", sum.String())
+
+	difference, err := dollars.Sub(cents)
+	require.NoError(t, err)
+	assert.Equal(t, "-34", difference.Amount.String())
+	assert.Equal(t, -1, difference.Sign())
+	assert.Equal(t, "34", difference.Neg().Amount.String())
+
+	cmp, err := cents.Cmp(NewMoney(big.NewInt(12340), "USD", 1000))
+	require.NoError(t, err)
+	assert.Equal(t, 0, cmp)
+
+	// The operands are not changed
+	assert.Equal(t, "12", dollars.Amount.String())
+	assert.Equal(t, float64(1), dollars.Precision)
+}
+
+func TestMoney_Mismatches(t *testing.T) {
+	_, err := NewMoney(big.NewInt(1), "USD", 100).Add(NewMoney(big.NewInt(1), "EUR", 100))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = NewMoney(big.NewInt(1), "USD", 100).Add(NewMoney(big.NewInt(1), "USD", 3))
+	assert.ErrorIs(t, err, ErrPrecisionMismatch)
+}
+
+func TestParseMoneyAndRescale(t *testing.T) {
+	money, err := ParseMoney("12.345", "USD", 1000)
+	require.NoError(t, err)
+	assert.Equal(t, "12345", money.Amount.String())
+
+	_, err = ParseMoney("12.345", "USD", 100)
+	assert.Error(t, err)
+	_, err = ParseMoney("twelve", "USD", 100)
+	assert.Error(t, err)
+
+	for mode, want := range map[RoundingMode]string{RoundHalfEven: "1234", RoundHalfUp: "1235", "": "1234"} {
+		rescaled, err := money.Rescale(100, mode)
+		require.NoError(t, err)
+		assert.Equal(t, want, rescaled.Amount.String(), mode)
+	}
+
+	_, err = money.Rescale(100, "nearest")
+	assert.Error(t, err)
+}
+
+func TestMoney_JSON(t *testing.T) {
+	encoded, err := json.Marshal(NewMoney(big.NewInt(123456789012345678), "USD", 100))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"1234567890123456.78","precise_amount":123456789012345678,"currency":"USD","precision":100}`, string(encoded))
+
+	var decoded Money
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, "123456789012345678", decoded.Amount.String())
+
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"10.5","currency":"EUR","precision":100}`), &decoded))
+	assert.Equal(t, "1050", decoded.Amount.String())
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":10.5,"currency":"EUR","precision":100}`), &decoded))
+	assert.Equal(t, "1050", decoded.Amount.String())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"currency":"EUR"}`), &decoded))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"10.555","currency":"EUR","precision":100}`), &decoded))
+}
+
+func TestTransactionAndBalanceMoney(t *testing.T) {
+	txn := &Transaction{PreciseAmount: big.NewInt(500), Currency: "NGN", Precision: 100}
+	money := txn.Money()
+	money.Amount.SetInt64(1)
+	assert.Equal(t, "500", txn.PreciseAmount.String())
+
+	balance := &Balance{Balance: big.NewInt(250), Currency: "NGN", CurrencyMultiplier: 100}
+	total, err := balance.Money().Add(txn.Money())
+	require.NoError(t, err)
+	assert.Equal(t, "7.5 NGN", total.String())
+}
+
+func TestMoneyFromFloat(t *testing.T) {
+	assert.Equal(t, "29", MoneyFromFloat(0.29, "USD", 100).Amount.String())
+	assert.Equal(t, "1005", MoneyFromFloat(10.057, "USD", 100).Amount.String())
+	assert.Equal(t, "600", MoneyFromFloat(600, "USD", 0).Amount.String())
+
+	txn := &Transaction{Currency: "USD", Precision: 100, OverdraftLimit: 0.29}
+	assert.Equal(t, "0.29 USD", txn.OverdraftLimitMoney().String())
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// NetWorth is what an identity holds across all its balances, converted to a base currency at the configured
+// exchange rates. Currencies without a rate to the base currency are listed unconverted and left out of Total.
+type NetWorth struct {
+	IdentityID   string             `json:"identity_id"`
+	BaseCurrency string             `json:"base_currency"`
+	Precision    float64            `json:"precision"`
+	Total        CalculatedAmount   `json:"total"`
+	Currencies   []NetWorthCurrency `json:"currencies"`
+	Unconverted  []string           `json:"unconverted,omitempty"`
+	AsOf         time.Time          `json:"as_of"`
+}
+
+// NetWorthCurrency is the part of a net worth held in one currency: the sum of the identity's balances in the
+// currency, and that sum converted to the base currency when a rate is configured.
+type NetWorthCurrency struct {
+	Currency  string                `json:"currency"`
+	Balances  int                   `json:"balances"`
+	Precision float64               `json:"precision"`
+	Balance   CalculatedAmount      `json:"balance"`
+	Converted *CalculatedConversion `json:"converted,omitempty"`
+}
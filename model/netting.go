@@ -0,0 +1,157 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Statuses of netting entries and settlements.
+const (
+	NettingEntryPending = "pending"
+	NettingEntrySettled = "settled"
+
+	NettingSettlementProcessing = "processing"
+	NettingSettlementApplied    = "applied"
+	NettingSettlementFailed     = "failed"
+)
+
+// NettingGroup is a set of balances whose postings to each other are deferred and settled as net
+// transfers once a day at CutoffTime (HH:MM, UTC).
+type NettingGroup struct {
+	GroupID      string     `json:"group_id"`
+	Name         string     `json:"name"`
+	BalanceIDs   []string   `json:"balance_ids"`
+	Currency     string     `json:"currency"`
+	Precision    float64    `json:"precision"`
+	CutoffTime   string     `json:"cutoff_time"`
+	IsActive     bool       `json:"is_active"`
+	LastCutoffAt *time.Time `json:"last_cutoff_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// NettingEntry is the memo record of a gross posting that was deferred to a netting settlement.
+type NettingEntry struct {
+	EntryID       string                 `json:"entry_id"`
+	GroupID       string                 `json:"group_id"`
+	TransactionID string                 `json:"transaction_id"`
+	Reference     string                 `json:"reference"`
+	Source        string                 `json:"source"`
+	Destination   string                 `json:"destination"`
+	Amount        float64                `json:"amount"`
+	PreciseAmount *big.Int               `json:"precise_amount"`
+	Currency      string                 `json:"currency"`
+	Description   string                 `json:"description,omitempty"`
+	MetaData      map[string]interface{} `json:"meta_data,omitempty"`
+	Status        string                 `json:"status"`
+	SettlementID  string                 `json:"settlement_id,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+// NettingTransfer is one net transfer produced by a settlement.
+type NettingTransfer struct {
+	Source        string   `json:"source"`
+	Destination   string   `json:"destination"`
+	PreciseAmount *big.Int `json:"precise_amount"`
+	Reference     string   `json:"reference"`
+	TransactionID string   `json:"transaction_id,omitempty"`
+}
+
+// NettingSettlement records the net transfers applied for a group at a cutoff.
+type NettingSettlement struct {
+	SettlementID string            `json:"settlement_id"`
+	GroupID      string            `json:"group_id"`
+	CutoffAt     time.Time         `json:"cutoff_at"`
+	EntryCount   int               `json:"entry_count"`
+	Transfers    []NettingTransfer `json:"transfers"`
+	Status       string            `json:"status"`
+	Error        string            `json:"error,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// Contains reports whether the balance belongs to the group.
+func (g *NettingGroup) Contains(balanceID string) bool {
+	for _, id := range g.BalanceIDs {
+		if id == balanceID {
+			return true
+		}
+	}
+	return false
+}
+
+// CutoffOn returns the group's cutoff on the UTC day of t.
+func (g *NettingGroup) CutoffOn(t time.Time) (time.Time, error) {
+	clock, err := time.Parse("15:04", g.CutoffTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cutoff time %q, expected HH:MM", g.CutoffTime)
+	}
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC), nil
+}
+
+// NetTransfers reduces gross entries to the net transfers that leave every balance in the same position.
+// A pair of balances always nets to at most one transfer. Larger groups are settled by matching the
+// largest debtors with the largest creditors, which keeps the number of transfers below the group size.
+func NetTransfers(entries []*NettingEntry) []NettingTransfer {
+	positions := map[string]*big.Int{}
+	position := func(id string) *big.Int {
+		if positions[id] == nil {
+			positions[id] = big.NewInt(0)
+		}
+		return positions[id]
+	}
+	for _, entry := range entries {
+		if entry.PreciseAmount == nil {
+			continue
+		}
+		position(entry.Source).Sub(position(entry.Source), entry.PreciseAmount)
+		position(entry.Destination).Add(position(entry.Destination), entry.PreciseAmount)
+	}
+
+	type party struct {
+		id     string
+		amount *big.Int
+	}
+	var debtors, creditors []party
+	for id, amount := range positions {
+		switch amount.Sign() {
+		case -1:
+			debtors = append(debtors, party{id, new(big.Int).Neg(amount)})
+		case 1:
+			creditors = append(creditors, party{id, new(big.Int).Set(amount)})
+		}
+	}
+	byAmount := func(parties []party) {
+		sort.Slice(parties, func(i, j int) bool {
+			if c := parties[i].amount.Cmp(parties[j].amount); c != 0 {
+				return c > 0
+			}
+			return parties[i].id < parties[j].id
+		})
+	}
+	byAmount(debtors)
+	byAmount(creditors)
+
+	transfers := []NettingTransfer{}
+	for i, j := 0, 0; i < len(debtors) && j < len(creditors); {
+		amount := debtors[i].amount
+		if creditors[j].amount.Cmp(amount) < 0 {
+			amount = creditors[j].amount
+		}
+		transfers = append(transfers, NettingTransfer{
+			Source:        debtors[i].id,
+			Destination:   creditors[j].id,
+			PreciseAmount: new(big.Int).Set(amount),
+		})
+		debtors[i].amount = new(big.Int).Sub(debtors[i].amount, amount)
+		creditors[j].amount = new(big.Int).Sub(creditors[j].amount, amount)
+		if debtors[i].amount.Sign() == 0 {
+			i++
+		}
+		if creditors[j].amount.Sign() == 0 {
+			j++
+		}
+	}
+	return transfers
+}
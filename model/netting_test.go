@@ -0,0 +1,73 @@
+package model
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nettingEntry(source, destination string, amount int64) *NettingEntry {
+	return &NettingEntry{Source: source, Destination: destination, PreciseAmount: big.NewInt(amount)}
+}
+
+func TestNetTransfers_Pair(t *testing.T) {
+	transfers := NetTransfers([]*NettingEntry{
+		nettingEntry("bln_a", "bln_b", 1000),
+		nettingEntry("bln_b", "bln_a", 300),
+		nettingEntry("bln_a", "bln_b", 50),
+	})
+	require.Len(t, transfers, 1)
+	assert.Equal(t, "bln_a", transfers[0].Source)
+	assert.Equal(t, "bln_b", transfers[0].Destination)
+	assert.Equal(t, "750", transfers[0].PreciseAmount.String())
+}
+
+func TestNetTransfers_OffsettingEntriesProduceNoTransfer(t *testing.T) {
+	transfers := NetTransfers([]*NettingEntry{
+		nettingEntry("bln_a", "bln_b", 500),
+		nettingEntry("bln_b", "bln_a", 500),
+	})
+	assert.Empty(t, transfers)
+}
+
+func TestNetTransfers_GroupPreservesPositions(t *testing.T) {
+	entries := []*NettingEntry{
+		nettingEntry("bln_a", "bln_b", 400),
+		nettingEntry("bln_b", "bln_c", 900),
+		nettingEntry("bln_c", "bln_a", 100),
+		nettingEntry("bln_d", "bln_a", 250),
+	}
+	transfers := NetTransfers(entries)
+	assert.Less(t, len(transfers), 4)
+
+	net := func(items []NettingTransfer) map[string]int64 {
+		positions := map[string]int64{}
+		for _, item := range items {
+			positions[item.Source] -= item.PreciseAmount.Int64()
+			positions[item.Destination] += item.PreciseAmount.Int64()
+		}
+		return positions
+	}
+	gross := map[string]int64{}
+	for _, entry := range entries {
+		gross[entry.Source] -= entry.PreciseAmount.Int64()
+		gross[entry.Destination] += entry.PreciseAmount.Int64()
+	}
+	for id, amount := range net(transfers) {
+		assert.Equal(t, gross[id], amount, id)
+	}
+}
+
+func TestNettingGroup_CutoffOn(t *testing.T) {
+	group := NettingGroup{CutoffTime: "17:30"}
+	cutoff, err := group.CutoffOn(time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 5, 17, 30, 0, 0, time.UTC), cutoff)
+
+	invalid := NettingGroup{CutoffTime: "5pm"}
+	_, err = invalid.CutoffOn(time.Now())
+	assert.Error(t, err)
+}
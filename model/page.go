@@ -0,0 +1,215 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	pageCursorPrefix   = "keyset:"
+	offsetCursorPrefix = "offset:"
+)
+
+// ErrInvalidPageCursor is returned when a page cursor cannot be decoded.
+var ErrInvalidPageCursor = errors.New("invalid cursor")
+
+// PageCursor is the position of a row in a list ordered by a time and then by ID, such as the creation time
+// and ID of a ledger. The next page of the list holds the rows after the cursor in that order.
+type PageCursor struct {
+	Time time.Time
+	ID   string
+}
+
+// Page selects one page of a list: up to Limit rows after the After cursor. Without a cursor the page starts
+// after skipping Offset rows, which clients that still page by offset rely on; a cursor reads only the rows
+// it returns however deep into the list the page is. A Limit of 0 returns the rest of the list.
+type Page struct {
+	Limit  int
+	After  *PageCursor
+	Offset int
+}
+
+// Encode returns the opaque token of the cursor, as handed to API clients.
+func (c PageCursor) Encode() string {
+	raw := pageCursorPrefix + c.Time.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// EncodeOffsetCursor returns the opaque token of the page starting at offset, for lists that cannot be
+// paged by a cursor.
+func EncodeOffsetCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(offsetCursorPrefix + strconv.Itoa(offset)))
+}
+
+// ParsePageToken returns the page a cursor token points at. Tokens from EncodeOffsetCursor, including those
+// issued before lists were paged by cursor, point at an offset.
+//
+// Parameters:
+// - token string: The token of a previous page.
+// - limit int: The size of the page.
+//
+// Returns:
+// - Page: The page after the token.
+// - error: ErrInvalidPageCursor if the token is malformed.
+func ParsePageToken(token string, limit int) (Page, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Page{}, ErrInvalidPageCursor
+	}
+	raw := string(decoded)
+
+	if offset, ok := strings.CutPrefix(raw, offsetCursorPrefix); ok {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return Page{}, ErrInvalidPageCursor
+		}
+		return Page{Limit: limit, Offset: n}, nil
+	}
+
+	position, ok := strings.CutPrefix(raw, pageCursorPrefix)
+	if !ok {
+		return Page{}, ErrInvalidPageCursor
+	}
+	at, id, ok := strings.Cut(position, "|")
+	if !ok || id == "" {
+		return Page{}, ErrInvalidPageCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return Page{}, ErrInvalidPageCursor
+	}
+	return Page{Limit: limit, After: &PageCursor{Time: t, ID: id}}, nil
+}
+
+// NextPageCursor returns the cursor of the page after items: the position of the last item when the page is
+// full, or nil when the list has no more rows.
+//
+// Parameters:
+// - items []T: The rows of the page, as read from the list.
+// - limit int: The size of the page.
+// - position func(T) PageCursor: The position of a row in the list's order.
+//
+// Returns:
+// - *PageCursor: The cursor of the next page, or nil.
+func NextPageCursor[T any](items []T, limit int, position func(T) PageCursor) *PageCursor {
+	if limit <= 0 || len(items) < limit {
+		return nil
+	}
+	cursor := position(items[len(items)-1])
+	return &cursor
+}
+
+// PageCursor is the position of the ledger in the ledger list.
+func (l Ledger) PageCursor() PageCursor {
+	return PageCursor{Time: l.CreatedAt, ID: l.LedgerID}
+}
+
+// PageCursor is the position of the balance in the balance list.
+func (b Balance) PageCursor() PageCursor {
+	return PageCursor{Time: b.CreatedAt, ID: b.BalanceID}
+}
+
+// PageCursor is the position of the identity in the identity list.
+func (i Identity) PageCursor() PageCursor {
+	return PageCursor{Time: i.CreatedAt, ID: i.IdentityID}
+}
+
+// PageCursor is the position of the transaction in lists of transactions ordered by creation time.
+func (t Transaction) PageCursor() PageCursor {
+	return PageCursor{Time: t.CreatedAt, ID: t.TransactionID}
+}
+
+// HistoryCursor is the position of the transaction in transaction history listed by order, the configured
+// history order: "transaction_time" lists transactions by their transaction time where they have one.
+func (t Transaction) HistoryCursor(order string) PageCursor {
+	at := t.CreatedAt
+	if order == "transaction_time" && t.TransactionTime != nil {
+		at = *t.TransactionTime
+	}
+	return PageCursor{Time: at, ID: t.TransactionID}
+}
+
+// PageCursor is the position of the request log in the request log list.
+func (r RequestLog) PageCursor() PageCursor {
+	return PageCursor{Time: r.CreatedAt, ID: r.LogID}
+}
+
+// PageCursor is the position of the challenge in the challenge list.
+func (c TransactionChallenge) PageCursor() PageCursor {
+	return PageCursor{Time: c.CreatedAt, ID: c.ChallengeID}
+}
+
+// PageCursor is the position of the balance in the dormant balance list, which lists the longest dormant first.
+func (d DormantBalance) PageCursor() PageCursor {
+	return PageCursor{Time: d.DormantSince, ID: d.BalanceID}
+}
+
+// PageCursor is the position of the batch in the escheatment batch list.
+func (b EscheatmentBatch) PageCursor() PageCursor {
+	return PageCursor{Time: b.CreatedAt, ID: b.BatchID}
+}
+
+// PageCursor is the position of the group in the netting group list.
+func (g NettingGroup) PageCursor() PageCursor {
+	return PageCursor{Time: g.CreatedAt, ID: g.GroupID}
+}
+
+// PageCursor is the position of the minimum balance in the minimum balance list.
+func (m MinimumBalance) PageCursor() PageCursor {
+	return PageCursor{Time: m.CreatedAt, ID: m.MinimumBalanceID}
+}
+
+// PageCursor is the position of the API key in the API key list.
+func (k APIKey) PageCursor() PageCursor {
+	return PageCursor{Time: k.CreatedAt, ID: k.APIKeyID}
+}
+
+// PageCursor is the position of the entry in the netting entry list.
+func (e NettingEntry) PageCursor() PageCursor {
+	return PageCursor{Time: e.CreatedAt, ID: e.EntryID}
+}
+
+// PageCursor is the position of the ingestion in the statement ingestion list, which lists the most recently
+// received first.
+func (s StatementIngestion) PageCursor() PageCursor {
+	return PageCursor{Time: s.ReceivedAt, ID: s.IngestionID}
+}
+
+// PageCursor is the position of the definition in the report definition list.
+func (r ReportDefinition) PageCursor() PageCursor {
+	return PageCursor{Time: r.CreatedAt, ID: r.ReportID}
+}
+
+// PageCursor is the position of the run in the report run list.
+func (r ReportRun) PageCursor() PageCursor {
+	return PageCursor{Time: r.StartedAt, ID: r.RunID}
+}
+
+// PageCursor is the position of the failure in the scheduled transaction failure list.
+func (f ScheduledTransactionFailure) PageCursor() PageCursor {
+	return PageCursor{Time: f.FailedAt, ID: f.FailureID}
+}
+
+// PageCursor is the position of the run in the end-of-day run list, which is ordered by business date.
+func (r EODRun) PageCursor() PageCursor {
+	date, _ := time.Parse(time.DateOnly, r.BusinessDate)
+	return PageCursor{Time: date, ID: r.RunID}
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePageToken(t *testing.T) {
+	cursor := PageCursor{Time: time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC), ID: "ldg_1"}
+	page, err := ParsePageToken(cursor.Encode(), 20)
+	require.NoError(t, err)
+	assert.Equal(t, Page{Limit: 20, After: &cursor}, page)
+
+	page, err = ParsePageToken(EncodeOffsetCursor(40), 20)
+	require.NoError(t, err)
+	assert.Equal(t, Page{Limit: 20, Offset: 40}, page)
+
+	for _, token := range []string{"not-a-cursor", EncodeOffsetCursor(-1), "a2V5c2V0OmxkZ18x"} {
+		_, err := ParsePageToken(token, 20)
+		assert.ErrorIs(t, err, ErrInvalidPageCursor, token)
+	}
+}
+
+func TestNextPageCursor(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	ledgers := []Ledger{{LedgerID: "ldg_2", CreatedAt: created.Add(time.Second)}, {LedgerID: "ldg_1", CreatedAt: created}}
+
+	assert.Equal(t, &PageCursor{Time: created, ID: "ldg_1"}, NextPageCursor(ledgers, 2, Ledger.PageCursor))
+	assert.Nil(t, NextPageCursor(ledgers, 3, Ledger.PageCursor))
+	assert.Nil(t, NextPageCursor(ledgers, 0, Ledger.PageCursor))
+}
+
+func TestTransaction_HistoryCursor(t *testing.T) {
+	created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	clientTime := created.Add(-time.Hour)
+	txn := Transaction{TransactionID: "txn_1", CreatedAt: created, TransactionTime: &clientTime}
+
+	assert.Equal(t, PageCursor{Time: created, ID: "txn_1"}, txn.HistoryCursor("created_at"))
+	assert.Equal(t, PageCursor{Time: clientTime, ID: "txn_1"}, txn.HistoryCursor("transaction_time"))
+
+	txn.TransactionTime = nil
+	assert.Equal(t, PageCursor{Time: created, ID: "txn_1"}, txn.HistoryCursor("transaction_time"))
+}
@@ -0,0 +1,40 @@
+package model
+
+// Stages of the posting pipeline timed for each transaction. A queued transaction is enqueued, then validated,
+// waits for the lock of its source balance, updates its balances and is committed; it is then indexed for search
+// and webhooks are sent about it.
+const (
+	PipelineStageValidation    = "validation"
+	PipelineStageEnqueue       = "enqueue"
+	PipelineStageLockWait      = "lock_wait"
+	PipelineStageBalanceUpdate = "balance_update"
+	PipelineStageCommit        = "commit"
+	PipelineStageIndex         = "index"
+	PipelineStageNotify        = "notify"
+)
+
+// PipelineStages lists the stages of the posting pipeline in the order a transaction goes through them.
+var PipelineStages = []string{
+	PipelineStageEnqueue,
+	PipelineStageValidation,
+	PipelineStageLockWait,
+	PipelineStageBalanceUpdate,
+	PipelineStageCommit,
+	PipelineStageIndex,
+	PipelineStageNotify,
+}
+
+// TransactionTiming is the time a transaction spent in each stage of the posting pipeline, in pipeline order.
+// Stages the transaction did not go through, such as the enqueue of a transaction recorded without the queue,
+// are left out.
+type TransactionTiming struct {
+	TransactionID string        `json:"transaction_id"`
+	Stages        []StageTiming `json:"stages"`
+	TotalMs       float64       `json:"total_ms"`
+}
+
+// StageTiming is the time spent in a stage of the posting pipeline.
+type StageTiming struct {
+	Stage      string  `json:"stage"`
+	DurationMs float64 `json:"duration_ms"`
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PostingRules set how strictly the postings of a ledger are validated, so that a test ledger can be
+// permissive while production ledgers stay strict. Their zero value is strict: zero-amount transactions and
+// transactions from a balance to itself are rejected unless allowed. A description or a reference format are
+// only enforced when asked for. Ledgers without posting rules are not checked beyond the usual validation.
+type PostingRules struct {
+	LedgerID                   string    `json:"ledger_id"`
+	AllowZeroAmount            bool      `json:"allow_zero_amount"`
+	AllowSameSourceDestination bool      `json:"allow_same_source_destination"`
+	RequireDescription         bool      `json:"require_description"`
+	ReferencePattern           string    `json:"reference_pattern,omitempty"` // Regular expression the whole reference must match
+	UpdatedAt                  time.Time `json:"updated_at"`
+}
+
+// Validate checks that the reference pattern is a valid regular expression.
+func (r *PostingRules) Validate() error {
+	if _, err := r.referenceRegexp(); err != nil {
+		return fmt.Errorf("invalid reference_pattern: %w", err)
+	}
+	return nil
+}
+
+// referenceRegexp compiles the reference pattern anchored to the whole reference, or returns nil when there
+// is none.
+func (r *PostingRules) referenceRegexp() (*regexp.Regexp, error) {
+	if r.ReferencePattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(`^(?:` + r.ReferencePattern + `)$`)
+}
+
+// Check validates a transaction against the rules, returning a PostingRuleError naming the rule it breaks.
+func (r *PostingRules) Check(transaction *Transaction) error {
+	if !r.AllowZeroAmount && isZeroAmount(transaction) {
+		return &PostingRuleError{LedgerID: r.LedgerID, Rule: "allow_zero_amount", Reason: "zero-amount transactions are not allowed"}
+	}
+	if !r.AllowSameSourceDestination && transaction.Source != "" && transaction.Source == transaction.Destination {
+		return &PostingRuleError{LedgerID: r.LedgerID, Rule: "allow_same_source_destination", Reason: "the source and destination must be different balances"}
+	}
+	if r.RequireDescription && strings.TrimSpace(transaction.Description) == "" {
+		return &PostingRuleError{LedgerID: r.LedgerID, Rule: "require_description", Reason: "a description is required"}
+	}
+	re, err := r.referenceRegexp()
+	if err != nil {
+		return err
+	}
+	if re != nil && !re.MatchString(transaction.Reference) {
+		return &PostingRuleError{LedgerID: r.LedgerID, Rule: "reference_pattern", Reason: fmt.Sprintf("reference %q does not match %s", transaction.Reference, r.ReferencePattern)}
+	}
+	return nil
+}
+
+// isZeroAmount reports whether a transaction moves no funds.
+func isZeroAmount(transaction *Transaction) bool {
+	if transaction.PreciseAmount != nil {
+		return transaction.PreciseAmount.Sign() == 0
+	}
+	return transaction.Amount == 0
+}
+
+// ErrPostingRule is matched by every PostingRuleError.
+var ErrPostingRule = errors.New("posting rule violated")
+
+// PostingRuleError is returned when a transaction breaks a posting rule of a ledger it posts to.
+type PostingRuleError struct {
+	LedgerID string
+	Rule     string
+	Reason   string
+}
+
+func (e *PostingRuleError) Error() string {
+	return fmt.Sprintf("ledger %s rejects the transaction: %s", e.LedgerID, e.Reason)
+}
+
+// Is reports whether target is ErrPostingRule.
+func (e *PostingRuleError) Is(target error) bool {
+	return target == ErrPostingRule
+}
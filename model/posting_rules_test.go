@@ -0,0 +1,54 @@
+package model
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostingRules_Check(t *testing.T) {
+	valid := func() *Transaction {
+		return &Transaction{Source: "bln_1", Destination: "bln_2", PreciseAmount: big.NewInt(100), Description: "rent", Reference: "INV-0001"}
+	}
+	strict := &PostingRules{LedgerID: "ldg_1", RequireDescription: true, ReferencePattern: `INV-\d{4}`}
+	require.NoError(t, strict.Validate())
+	assert.NoError(t, strict.Check(valid()))
+
+	zero := valid()
+	zero.PreciseAmount = big.NewInt(0)
+	same := valid()
+	same.Destination = "bln_1"
+	undescribed := valid()
+	undescribed.Description = " "
+	unformatted := valid()
+	unformatted.Reference = "xINV-0001"
+
+	for name, tc := range map[string]struct {
+		txn  *Transaction
+		rule string
+	}{
+		"zero amount":    {zero, "allow_zero_amount"},
+		"same balance":   {same, "allow_same_source_destination"},
+		"no description": {undescribed, "require_description"},
+		"bad reference":  {unformatted, "reference_pattern"},
+	} {
+		err := strict.Check(tc.txn)
+		var ruleErr *PostingRuleError
+		require.ErrorAs(t, err, &ruleErr, name)
+		assert.Equal(t, tc.rule, ruleErr.Rule, name)
+		assert.True(t, errors.Is(err, ErrPostingRule), name)
+		assert.Equal(t, ReasonPostingRule, RejectionReasonCode(err.Error()), name)
+	}
+
+	permissive := &PostingRules{LedgerID: "ldg_test", AllowZeroAmount: true, AllowSameSourceDestination: true}
+	for _, txn := range []*Transaction{zero, same, undescribed, unformatted} {
+		assert.NoError(t, permissive.Check(txn))
+	}
+}
+
+func TestPostingRules_Validate(t *testing.T) {
+	assert.Error(t, (&PostingRules{ReferencePattern: "INV-("}).Validate())
+}
@@ -17,12 +17,50 @@ package model
 
 import "time"
 
+// Match statuses describe how a scored match was routed once the run finished.
+const (
+	MatchStatusConfirmed     = "confirmed"      // Confidence met the auto-confirm threshold, or a reviewer accepted it.
+	MatchStatusPendingReview = "pending_review" // Confidence fell between the review and auto-confirm thresholds.
+	MatchStatusRejected      = "rejected"       // A reviewer declined the match.
+)
+
 type Match struct {
-	ExternalTransactionID string
-	InternalTransactionID string
-	ReconciliationID      string
-	Amount                float64
-	Date                  time.Time
+	ExternalTransactionID string    `json:"external_transaction_id"`
+	InternalTransactionID string    `json:"internal_transaction_id"`
+	ReconciliationID      string    `json:"reconciliation_id"`
+	Amount                float64   `json:"amount"`
+	Date                  time.Time `json:"date"`
+	Confidence            float64   `json:"confidence"`
+	Status                string    `json:"status"`
+}
+
+// ScoreBucket counts the matches of a reconciliation run whose confidence falls within [Lower, Upper).
+type ScoreBucket struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int     `json:"count"`
+}
+
+// ScoreDistribution summarises the confidence scores produced by a reconciliation run so that
+// matching rules and thresholds can be tuned against real data.
+type ScoreDistribution struct {
+	ReconciliationID string        `json:"reconciliation_id"`
+	TotalMatches     int           `json:"total_matches"`
+	AverageScore     float64       `json:"average_score"`
+	Confirmed        int           `json:"confirmed"`
+	PendingReview    int           `json:"pending_review"`
+	Rejected         int           `json:"rejected"`
+	Buckets          []ScoreBucket `json:"buckets"`
+}
+
+// DryRunReport is the outcome of a dry-run reconciliation. It lists every match the rules produced,
+// including low-confidence ones, without any of them having been recorded against the ledger.
+type DryRunReport struct {
+	ReconciliationID string            `json:"reconciliation_id"`
+	Matches          []Match           `json:"matches"`
+	Exceptions       []string          `json:"exceptions"`
+	Summary          ScoreDistribution `json:"summary"`
+	GeneratedAt      time.Time         `json:"generated_at"`
 }
 
 type ExternalTransaction struct {
@@ -35,6 +73,25 @@ type ExternalTransaction struct {
 	Source      string    `json:"source"`
 }
 
+// ExternalRecordBatch is a batch of external transaction records pushed by a partner as they happen, rather than
+// uploaded in a file. Batches pushed with the same UploadID are stored, and reconciled, together; a batch without
+// one starts a new upload. Records without a source take the batch's.
+type ExternalRecordBatch struct {
+	UploadID string                `json:"upload_id"`
+	Source   string                `json:"source"`
+	Records  []ExternalTransaction `json:"records"`
+}
+
+// ExternalRecordBatchResult is the outcome of pushing a batch: how many records were received and recorded, and
+// the IDs of those skipped because a record with the same ID was already pushed or uploaded.
+type ExternalRecordBatchResult struct {
+	UploadID   string   `json:"upload_id"`
+	Source     string   `json:"source"`
+	Received   int      `json:"received"`
+	Recorded   int      `json:"recorded"`
+	Duplicates []string `json:"duplicates"`
+}
+
 type Reconciliation struct {
 	ID                    int64      `json:"-"`
 	ReconciliationID      string     `json:"reconciliation_id"`
@@ -47,6 +104,26 @@ type Reconciliation struct {
 	CompletedAt           *time.Time `json:"completed_at"`
 }
 
+// ReconciliationEvent is the payload of the webhooks announcing that a reconciliation run started, completed
+// or failed. The match statistics are only meaningful once the run has completed.
+type ReconciliationEvent struct {
+	Reconciliation
+	TotalTransactions int     `json:"total_transactions"`
+	PendingReview     int     `json:"pending_review"`
+	MatchRate         float64 `json:"match_rate"`
+	Error             string  `json:"error,omitempty"`
+}
+
+// ExceptionResolution is the payload of the webhook announcing that a reviewer resolved a match held for
+// review, either by confirming or by rejecting it.
+type ExceptionResolution struct {
+	ReconciliationID      string    `json:"reconciliation_id"`
+	ExternalTransactionID string    `json:"external_transaction_id"`
+	InternalTransactionID string    `json:"internal_transaction_id"`
+	Status                string    `json:"status"`
+	ResolvedAt            time.Time `json:"resolved_at"`
+}
+
 type ReconciliationProgress struct {
 	LastProcessedExternalTxnID string `json:"last_processed_external_txn_id"`
 	ProcessedCount             int    `json:"processed_count"`
@@ -61,6 +138,16 @@ type ReconciliationResults struct {
 	UnmatchedTransactions []string   `json:"unmatched_transactions"`
 }
 
+// IntercompanySource describes another Blnk deployment whose applied transactions are pulled in as the
+// external side of an intercompany reconciliation.
+type IntercompanySource struct {
+	BaseURL   string    `json:"base_url"`
+	APIKey    string    `json:"api_key"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Currency  string    `json:"currency"`
+}
+
 type MatchingRule struct {
 	ID          int64              `json:"-"`
 	RuleID      string             `json:"rule_id"`
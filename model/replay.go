@@ -0,0 +1,58 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+// ReplayCommit is the status a replay slice gives to the applied transactions that commit an inflight
+// transaction, so they are replayed as commits rather than as new postings.
+const ReplayCommit = "COMMIT"
+
+// ReplaySlice is a time slice of a ledger exported for replay: the state of every balance the slice touches
+// when it starts, the transactions recorded within it and the state production reached when it ends.
+type ReplaySlice struct {
+	LedgerID     string         `json:"ledger_id"`
+	From         time.Time      `json:"from"`
+	To           time.Time      `json:"to"`
+	ExportedAt   time.Time      `json:"exported_at"`
+	Balances     []*Balance     `json:"balances"`
+	Transactions []*Transaction `json:"transactions"`
+	Production   []*Balance     `json:"production"`
+}
+
+// ReplayResult is the outcome of replaying a slice.
+type ReplayResult struct {
+	Applied    int             `json:"applied"`
+	Skipped    []string        `json:"skipped"`
+	Duplicates [][]string      `json:"duplicates"`
+	Balances   []*Balance      `json:"balances"`
+	Diffs      []ReplayDiff    `json:"diffs"`
+	Failures   []ReplayFailure `json:"failures"`
+	Matches    bool            `json:"matches"`
+}
+
+// ReplayFailure is a transaction of a slice that could not be replayed.
+type ReplayFailure struct {
+	TransactionID string `json:"transaction_id"`
+	Error         string `json:"error"`
+}
+
+// ReplayDiff is a balance field whose replayed value differs from the value production reached.
+type ReplayDiff struct {
+	BalanceID  string   `json:"balance_id"`
+	Field      string   `json:"field"`
+	Replayed   *big.Int `json:"replayed"`
+	Production *big.Int `json:"production"`
+	Difference *big.Int `json:"difference"`
+}
+
+// AnonymizedSlice is a replay slice with the personal data in it replaced by realistic fakes, for sharing with
+// vendors, training environments and debugging away from production. IDs are replaced consistently, so the
+// slice keeps its structure and can still be replayed. AmountsPerturbed is how far amounts may have been moved,
+// as a fraction of each amount, or 0 when amounts are as recorded.
+type AnonymizedSlice struct {
+	ReplaySlice
+	Identities       []*Identity `json:"identities"`
+	AmountsPerturbed float64     `json:"amounts_perturbed"`
+}
@@ -0,0 +1,326 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sources a report can be built from.
+const (
+	ReportSourceTransactions = "transactions"
+	ReportSourceBalances     = "balances"
+)
+
+// Schedules a report can run on. A report without a schedule only runs when asked to.
+const (
+	ReportScheduleHourly  = "hourly"
+	ReportScheduleDaily   = "daily"
+	ReportScheduleWeekly  = "weekly"
+	ReportScheduleMonthly = "monthly"
+)
+
+// Output formats and destinations of report runs. Runs are always kept and can be fetched by run ID; a
+// destination additionally pushes each run's output to S3 or as a webhook.
+const (
+	ReportFormatCSV  = "csv"
+	ReportFormatJSON = "json"
+
+	ReportDestinationS3      = "s3"
+	ReportDestinationWebhook = "webhook"
+)
+
+// Statuses and triggers of report runs.
+const (
+	ReportRunRunning   = "running"
+	ReportRunCompleted = "completed"
+	ReportRunFailed    = "failed"
+
+	ReportTriggerSchedule = "schedule"
+	ReportTriggerManual   = "manual"
+)
+
+// Kinds of report fields. Text fields can be grouped by, number fields can be aggregated and time fields
+// can be filtered on. Buckets are times truncated to a day or month, which can only be grouped by.
+const (
+	reportFieldText   = "text"
+	reportFieldNumber = "number"
+	reportFieldTime   = "time"
+	reportFieldBucket = "bucket"
+)
+
+// ReportFields lists the fields of each report source and their kinds.
+var ReportFields = map[string]map[string]string{
+	ReportSourceTransactions: {
+		"transaction_id": reportFieldText,
+		"source":         reportFieldText,
+		"destination":    reportFieldText,
+		"reference":      reportFieldText,
+		"group_id":       reportFieldText,
+		"currency":       reportFieldText,
+		"status":         reportFieldText,
+		"precise_amount": reportFieldNumber,
+		"created_at":     reportFieldTime,
+		"effective_date": reportFieldTime,
+		"created_date":   reportFieldBucket,
+		"created_month":  reportFieldBucket,
+	},
+	ReportSourceBalances: {
+		"balance_id":       reportFieldText,
+		"ledger_id":        reportFieldText,
+		"identity_id":      reportFieldText,
+		"indicator":        reportFieldText,
+		"currency":         reportFieldText,
+		"balance":          reportFieldNumber,
+		"credit_balance":   reportFieldNumber,
+		"debit_balance":    reportFieldNumber,
+		"inflight_balance": reportFieldNumber,
+		"created_at":       reportFieldTime,
+		"created_date":     reportFieldBucket,
+		"created_month":    reportFieldBucket,
+	},
+}
+
+// ReportAggregateFunctions are the aggregates a report can compute. Count needs no field; the others take a
+// number field.
+var ReportAggregateFunctions = []string{"count", "sum", "avg", "min", "max"}
+
+// ReportFilterOperators are the comparisons a report filter can make, as in balance monitor conditions.
+var ReportFilterOperators = []string{"==", "!=", ">", ">=", "<", "<=", "in"}
+
+var reportAliasPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// ReportFilter keeps the rows of a report whose field compares to a value. The "in" operator compares to
+// Values instead of Value.
+type ReportFilter struct {
+	Field    string   `json:"field"`
+	Operator string   `json:"operator"`
+	Value    string   `json:"value,omitempty"`
+	Values   []string `json:"values,omitempty"`
+}
+
+// ReportAggregate computes a value over the rows of each group of a report. Alias names its column and
+// defaults to the function and field, e.g. sum_precise_amount.
+type ReportAggregate struct {
+	Function string `json:"function"`
+	Field    string `json:"field,omitempty"`
+	Alias    string `json:"alias,omitempty"`
+}
+
+// Column returns the name of the aggregate's column.
+func (a ReportAggregate) Column() string {
+	if a.Alias != "" {
+		return a.Alias
+	}
+	if a.Field == "" {
+		return a.Function
+	}
+	return a.Function + "_" + a.Field
+}
+
+// ReportDefinition is a saved report: the rows of a source that pass its filters, grouped by its group-bys
+// and summarised by its aggregates. Window limits the rows to those created within a duration before each
+// run, so that a scheduled report covers the period since its previous run.
+type ReportDefinition struct {
+	ReportID    string            `json:"report_id"`
+	Name        string            `json:"name"`
+	Source      string            `json:"source"`
+	Filters     []ReportFilter    `json:"filters"`
+	GroupBy     []string          `json:"group_by"`
+	Aggregates  []ReportAggregate `json:"aggregates"`
+	Window      string            `json:"window,omitempty"`
+	Schedule    string            `json:"schedule,omitempty"`
+	Format      string            `json:"format"`
+	Destination string            `json:"destination,omitempty"`
+	IsActive    bool              `json:"is_active"`
+	NextRunAt   *time.Time        `json:"next_run_at,omitempty"`
+	LastRunAt   *time.Time        `json:"last_run_at,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// ReportRun is one execution of a report. Rows hold the values of Columns as text, so that sums of precise
+// amounts are never rounded.
+type ReportRun struct {
+	RunID       string     `json:"run_id"`
+	ReportID    string     `json:"report_id"`
+	Trigger     string     `json:"trigger"`
+	Status      string     `json:"status"`
+	Columns     []string   `json:"columns"`
+	Rows        [][]string `json:"rows"`
+	RowCount    int        `json:"row_count"`
+	Truncated   bool       `json:"truncated"`
+	StorageKey  string     `json:"storage_key,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Records returns the rows of the run as objects keyed by column.
+func (r *ReportRun) Records() []map[string]string {
+	records := make([]map[string]string, 0, len(r.Rows))
+	for _, row := range r.Rows {
+		record := make(map[string]string, len(r.Columns))
+		for i, column := range r.Columns {
+			if i < len(row) {
+				record[column] = row[i]
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// WindowDuration returns the window of the report, or zero if it has none.
+func (d *ReportDefinition) WindowDuration() time.Duration {
+	window, err := time.ParseDuration(d.Window)
+	if err != nil {
+		return 0
+	}
+	return window
+}
+
+// Validate checks the report against the fields of its source and fills in the default format.
+func (d *ReportDefinition) Validate() error {
+	if strings.TrimSpace(d.Name) == "" {
+		return errors.New("name is required")
+	}
+	fields, ok := ReportFields[d.Source]
+	if !ok {
+		return fmt.Errorf("unsupported source %q, expected %s", d.Source, joinSorted(ReportFields))
+	}
+
+	for _, filter := range d.Filters {
+		if err := filter.validate(fields); err != nil {
+			return err
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, field := range d.GroupBy {
+		kind, ok := fields[field]
+		if !ok || kind == reportFieldNumber || kind == reportFieldTime {
+			return fmt.Errorf("cannot group %s by %q", d.Source, field)
+		}
+		if seen[field] {
+			return fmt.Errorf("group_by lists %q more than once", field)
+		}
+		seen[field] = true
+	}
+
+	if len(d.Aggregates) == 0 {
+		return errors.New("at least one aggregate is required")
+	}
+	for _, aggregate := range d.Aggregates {
+		if err := aggregate.validate(fields); err != nil {
+			return err
+		}
+		column := aggregate.Column()
+		if seen[column] {
+			return fmt.Errorf("column %q is defined more than once", column)
+		}
+		seen[column] = true
+	}
+
+	if d.Window != "" {
+		if window, err := time.ParseDuration(d.Window); err != nil || window <= 0 {
+			return fmt.Errorf("invalid window %q, expected a positive duration such as 24h", d.Window)
+		}
+	}
+
+	switch d.Schedule {
+	case "", ReportScheduleHourly, ReportScheduleDaily, ReportScheduleWeekly, ReportScheduleMonthly:
+	default:
+		return fmt.Errorf("unsupported schedule %q, expected hourly, daily, weekly or monthly", d.Schedule)
+	}
+
+	if d.Format == "" {
+		d.Format = ReportFormatCSV
+	}
+	if d.Format != ReportFormatCSV && d.Format != ReportFormatJSON {
+		return fmt.Errorf("unsupported format %q, expected csv or json", d.Format)
+	}
+	switch d.Destination {
+	case "", ReportDestinationS3, ReportDestinationWebhook:
+	default:
+		return fmt.Errorf("unsupported destination %q, expected s3 or webhook", d.Destination)
+	}
+	return nil
+}
+
+func (f ReportFilter) validate(fields map[string]string) error {
+	kind, ok := fields[f.Field]
+	if !ok || kind == reportFieldBucket {
+		return fmt.Errorf("cannot filter on %q", f.Field)
+	}
+	if !containsReportString(ReportFilterOperators, f.Operator) {
+		return fmt.Errorf("unsupported operator %q for %s", f.Operator, f.Field)
+	}
+
+	values := []string{f.Value}
+	if f.Operator == "in" {
+		if len(f.Values) == 0 {
+			return fmt.Errorf("the in filter on %s needs values", f.Field)
+		}
+		values = f.Values
+	}
+	for _, value := range values {
+		switch kind {
+		case reportFieldNumber:
+			if _, ok := new(big.Int).SetString(value, 10); !ok {
+				return fmt.Errorf("filter on %s needs a whole number in minor units, got %q", f.Field, value)
+			}
+		case reportFieldTime:
+			if _, err := ParseReportTime(value); err != nil {
+				return fmt.Errorf("filter on %s needs an RFC 3339 time or a date, got %q", f.Field, value)
+			}
+		}
+	}
+	return nil
+}
+
+func (a ReportAggregate) validate(fields map[string]string) error {
+	if !containsReportString(ReportAggregateFunctions, a.Function) {
+		return fmt.Errorf("unsupported aggregate %q, expected one of %s", a.Function, strings.Join(ReportAggregateFunctions, ", "))
+	}
+	if a.Field == "" {
+		if a.Function != "count" {
+			return fmt.Errorf("%s needs a field", a.Function)
+		}
+	} else if fields[a.Field] != reportFieldNumber {
+		return fmt.Errorf("cannot %s %q, it is not a number", a.Function, a.Field)
+	}
+	if a.Alias != "" && !reportAliasPattern.MatchString(a.Alias) {
+		return fmt.Errorf("invalid alias %q, expected lower case letters, digits and underscores", a.Alias)
+	}
+	return nil
+}
+
+// ParseReportTime parses a time in a report filter, either RFC 3339 or a date at midnight UTC.
+func ParseReportTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func containsReportString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func joinSorted(m map[string]map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, " or ")
+}
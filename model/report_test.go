@@ -0,0 +1,79 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validReportDefinition() ReportDefinition {
+	return ReportDefinition{
+		Name:       "Daily volume",
+		Source:     ReportSourceTransactions,
+		Filters:    []ReportFilter{{Field: "status", Operator: "==", Value: "APPLIED"}},
+		GroupBy:    []string{"currency", "created_date"},
+		Aggregates: []ReportAggregate{{Function: "count"}, {Function: "sum", Field: "precise_amount"}},
+	}
+}
+
+func TestReportDefinitionValidate(t *testing.T) {
+	definition := validReportDefinition()
+	require.NoError(t, definition.Validate())
+	assert.Equal(t, ReportFormatCSV, definition.Format)
+
+	tests := []struct {
+		name   string
+		change func(*ReportDefinition)
+		err    string
+	}{
+		{"missing name", func(d *ReportDefinition) { d.Name = " " }, "name"},
+		{"unknown source", func(d *ReportDefinition) { d.Source = "ledgers" }, "source"},
+		{"unknown filter field", func(d *ReportDefinition) { d.Filters[0].Field = "meta_data" }, "filter"},
+		{"unknown operator", func(d *ReportDefinition) { d.Filters[0].Operator = "like" }, "operator"},
+		{"non numeric amount", func(d *ReportDefinition) {
+			d.Filters = []ReportFilter{{Field: "precise_amount", Operator: ">", Value: "1.5"}}
+		}, "whole number"},
+		{"bad time", func(d *ReportDefinition) {
+			d.Filters = []ReportFilter{{Field: "created_at", Operator: ">=", Value: "yesterday"}}
+		}, "RFC 3339"},
+		{"in without values", func(d *ReportDefinition) {
+			d.Filters = []ReportFilter{{Field: "currency", Operator: "in"}}
+		}, "needs values"},
+		{"group by number", func(d *ReportDefinition) { d.GroupBy = []string{"precise_amount"} }, "cannot group"},
+		{"duplicate group", func(d *ReportDefinition) { d.GroupBy = []string{"currency", "currency"} }, "more than once"},
+		{"no aggregates", func(d *ReportDefinition) { d.Aggregates = nil }, "aggregate"},
+		{"sum of text", func(d *ReportDefinition) {
+			d.Aggregates = []ReportAggregate{{Function: "sum", Field: "currency"}}
+		}, "not a number"},
+		{"sum without field", func(d *ReportDefinition) { d.Aggregates = []ReportAggregate{{Function: "sum"}} }, "needs a field"},
+		{"duplicate column", func(d *ReportDefinition) {
+			d.Aggregates = []ReportAggregate{{Function: "count", Alias: "currency"}}
+		}, "more than once"},
+		{"bad alias", func(d *ReportDefinition) {
+			d.Aggregates = []ReportAggregate{{Function: "count", Alias: "Total; DROP"}}
+		}, "alias"},
+		{"bad window", func(d *ReportDefinition) { d.Window = "-1h" }, "window"},
+		{"bad schedule", func(d *ReportDefinition) { d.Schedule = "yearly" }, "schedule"},
+		{"bad format", func(d *ReportDefinition) { d.Format = "xlsx" }, "format"},
+		{"bad destination", func(d *ReportDefinition) { d.Destination = "ftp" }, "destination"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			definition := validReportDefinition()
+			tt.change(&definition)
+			assert.ErrorContains(t, definition.Validate(), tt.err)
+		})
+	}
+}
+
+func TestReportAggregateColumn(t *testing.T) {
+	assert.Equal(t, "count", ReportAggregate{Function: "count"}.Column())
+	assert.Equal(t, "sum_precise_amount", ReportAggregate{Function: "sum", Field: "precise_amount"}.Column())
+	assert.Equal(t, "volume", ReportAggregate{Function: "sum", Field: "precise_amount", Alias: "volume"}.Column())
+}
+
+func TestReportRunRecords(t *testing.T) {
+	run := ReportRun{Columns: []string{"currency", "count"}, Rows: [][]string{{"USD", "3"}, {"EUR", "1"}}}
+	assert.Equal(t, []map[string]string{{"currency": "USD", "count": "3"}, {"currency": "EUR", "count": "1"}}, run.Records())
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// RequestLog records an API request and the response it got. Credentials and the configured fields are
+// redacted from the bodies, which are cut to the configured size.
+type RequestLog struct {
+	LogID             string    `json:"log_id"`
+	Method            string    `json:"method"`
+	Path              string    `json:"path"`
+	Query             string    `json:"query,omitempty"`
+	KeyID             string    `json:"key_id,omitempty"`
+	OwnerID           string    `json:"owner_id,omitempty"`
+	IPAddress         string    `json:"ip_address"`
+	StatusCode        int       `json:"status_code"`
+	LatencyMs         int64     `json:"latency_ms"`
+	RequestBody       string    `json:"request_body,omitempty"`
+	ResponseBody      string    `json:"response_body,omitempty"`
+	RequestTruncated  bool      `json:"request_truncated"`
+	ResponseTruncated bool      `json:"response_truncated"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// RequestLogFilter narrows a search of the request log. Empty fields do not filter.
+type RequestLogFilter struct {
+	OwnerID    string     // Only requests made by keys of this owner
+	KeyID      string     // Only requests made with this API key or service account
+	Method     string     // Only requests with this HTTP method
+	PathPrefix string     // Only requests whose path starts with this prefix
+	StatusCode int        // Only responses with this status code
+	MinStatus  int        // Only responses with at least this status code
+	From       *time.Time // Only requests made at or after this time
+	To         *time.Time // Only requests made before this time
+	Contains   string     // Only requests whose request or response body contains this text
+}
@@ -0,0 +1,146 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// RoundingMode decides which minor unit an amount that falls between two minor units is rounded to.
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half_up"   // Halves round away from zero
+	RoundHalfEven RoundingMode = "half_even" // Halves round to the even neighbour
+	RoundFloor    RoundingMode = "floor"     // Amounts round toward negative infinity
+)
+
+// RoundingRemainderKey marks the metadata of the leg that posts the remainder of a rounded split.
+const RoundingRemainderKey = "BLNK_ROUNDING_REMAINDER"
+
+// Validate checks that the mode is known. The empty mode keeps the legacy behaviour.
+func (m RoundingMode) Validate() error {
+	switch m {
+	case "", RoundHalfUp, RoundHalfEven, RoundFloor:
+		return nil
+	}
+	return fmt.Errorf("unknown rounding mode %q, expected half_up, half_even or floor", string(m))
+}
+
+// Round rounds an amount in minor units to a whole number of minor units. It returns the rounded amount and
+// the part that was rounded away. Without a mode the amount is truncated.
+func (m RoundingMode) Round(value decimal.Decimal) (*big.Int, decimal.Decimal) {
+	var rounded decimal.Decimal
+	switch m {
+	case RoundHalfUp:
+		rounded = value.Round(0)
+	case RoundHalfEven:
+		rounded = value.RoundBank(0)
+	case RoundFloor:
+		rounded = value.Floor()
+	default:
+		rounded = value.Truncate(0)
+	}
+	return rounded.BigInt(), value.Sub(rounded)
+}
+
+// PercentageOf returns percent percent of a precise amount, rounded with mode. Fees charged as a percentage
+// of an amount are calculated with it.
+func PercentageOf(preciseAmount *big.Int, percent decimal.Decimal, mode RoundingMode) *big.Int {
+	rounded, _ := mode.Round(decimal.NewFromBigInt(preciseAmount, 0).Mul(percent).Div(decimal.NewFromInt(100)))
+	return rounded
+}
+
+// ApplyRateWithRounding converts a precise amount at an exchange rate and rounds the result with mode.
+// Without a mode it behaves like ApplyRate.
+func ApplyRateWithRounding(preciseAmount *big.Int, rate float64, mode RoundingMode) *big.Int {
+	if mode == "" {
+		return ApplyRate(preciseAmount, rate)
+	}
+	if rate == 0 {
+		rate = 1
+	}
+	rounded, _ := mode.Round(decimal.NewFromBigInt(preciseAmount, 0).Mul(decimal.NewFromFloat(rate)))
+	return rounded
+}
+
+// CalculateDistributionsRounded splits a precise amount like CalculateDistributionsPrecise, but rounds each
+// fixed and percentage share with mode instead of truncating it. A "left" share takes whatever the other
+// shares leave. Without one, the difference between the total and the sum of the shares is returned as the
+// remainder rather than added to the largest share, so it can be posted on its own. The remainder is
+// negative when rounding up made the shares exceed the total.
+func CalculateDistributionsRounded(ctx context.Context, totalPreciseAmount *big.Int, distributions []Distribution, precision int64, mode RoundingMode) (map[string]*big.Int, *big.Int, error) {
+	_, span := tracer.Start(ctx, "CalculateDistributionsRounded")
+	defer span.End()
+
+	total := decimal.NewFromBigInt(totalPreciseAmount, 0)
+	precisionDec := decimal.NewFromInt(precision)
+	hundred := decimal.NewFromInt(100)
+
+	shares := make(map[string]*big.Int)
+	allocated := new(big.Int)
+	var fixedTotal, totalPercentage decimal.Decimal
+	leftIdentifier := ""
+
+	for _, dist := range distributions {
+		var share *big.Int
+		switch {
+		case dist.Distribution == "left":
+			if leftIdentifier != "" {
+				err := errors.New("multiple identifiers with 'left' distribution")
+				span.RecordError(err)
+				return nil, nil, err
+			}
+			leftIdentifier = dist.Identifier
+			continue
+		case strings.HasSuffix(dist.Distribution, "%"):
+			percentage, err := decimal.NewFromString(strings.TrimSuffix(dist.Distribution, "%"))
+			if err != nil {
+				span.RecordError(err)
+				return nil, nil, errors.New("invalid percentage format")
+			}
+			totalPercentage = totalPercentage.Add(percentage)
+			share = PercentageOf(totalPreciseAmount, percentage, mode)
+		default:
+			fixedAmount, err := strconv.ParseFloat(dist.Distribution, 64)
+			if err != nil {
+				span.RecordError(err)
+				return nil, nil, errors.New("invalid fixed amount format")
+			}
+			fixedDec := decimal.NewFromFloat(fixedAmount).Mul(precisionDec)
+			fixedTotal = fixedTotal.Add(fixedDec)
+			share, _ = mode.Round(fixedDec)
+		}
+		shares[dist.Identifier] = share
+		allocated.Add(allocated, share)
+	}
+
+	if totalPercentage.Cmp(hundred) > 0 || fixedTotal.Cmp(total) > 0 {
+		err := errors.New("total distributions exceed 100% or total amount")
+		span.RecordError(err)
+		return nil, nil, err
+	}
+
+	// Rounding moves each share by less than one minor unit, so shares exceeding the total by more than that
+	// were never going to fit
+	remainder := new(big.Int).Sub(totalPreciseAmount, allocated)
+	if new(big.Int).Neg(remainder).Cmp(big.NewInt(int64(len(shares)))) > 0 {
+		err := errors.New("total distributions exceed 100% or total amount")
+		span.RecordError(err)
+		return nil, nil, err
+	}
+	if leftIdentifier != "" {
+		left := remainder
+		remainder = new(big.Int)
+		if left.Sign() < 0 {
+			left, remainder = new(big.Int), left
+		}
+		shares[leftIdentifier] = left
+	}
+	return shares, remainder, nil
+}
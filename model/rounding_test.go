@@ -0,0 +1,133 @@
+package model
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundingMode_Round(t *testing.T) {
+	tests := []struct {
+		mode     RoundingMode
+		value    string
+		expected int64
+	}{
+		{RoundHalfUp, "2.5", 3},
+		{RoundHalfUp, "2.4", 2},
+		{RoundHalfEven, "2.5", 2},
+		{RoundHalfEven, "3.5", 4},
+		{RoundFloor, "2.9", 2},
+		{RoundFloor, "-2.5", -3},
+		{"", "2.7", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode)+"_"+tt.value, func(t *testing.T) {
+			rounded, diff := tt.mode.Round(decimal.RequireFromString(tt.value))
+			assert.Equal(t, tt.expected, rounded.Int64())
+			assert.True(t, decimal.NewFromInt(tt.expected).Add(diff).Equal(decimal.RequireFromString(tt.value)))
+		})
+	}
+}
+
+func TestRoundingMode_Validate(t *testing.T) {
+	assert.NoError(t, RoundingMode("").Validate())
+	assert.NoError(t, RoundHalfEven.Validate())
+	assert.Error(t, RoundingMode("ceiling").Validate())
+}
+
+func TestPercentageOfAndApplyRateWithRounding(t *testing.T) {
+	assert.Equal(t, int64(501), PercentageOf(big.NewInt(1001), decimal.NewFromInt(50), RoundHalfUp).Int64())
+	assert.Equal(t, int64(500), PercentageOf(big.NewInt(1001), decimal.NewFromInt(50), RoundHalfEven).Int64())
+
+	assert.Equal(t, int64(1235), ApplyRateWithRounding(big.NewInt(1000), 1.2345, RoundHalfUp).Int64())
+	assert.Equal(t, int64(1234), ApplyRateWithRounding(big.NewInt(1000), 1.2345, RoundFloor).Int64())
+	assert.Equal(t, ApplyRate(big.NewInt(1000), 1.2345), ApplyRateWithRounding(big.NewInt(1000), 1.2345, ""))
+}
+
+func TestCalculateDistributionsRounded(t *testing.T) {
+	distributions := []Distribution{
+		{Identifier: "a", Distribution: "33.5%"},
+		{Identifier: "b", Distribution: "33.5%"},
+		{Identifier: "c", Distribution: "33%"},
+	}
+
+	shares, remainder, err := CalculateDistributionsRounded(context.Background(), big.NewInt(100), distributions, 1, RoundFloor)
+	require.NoError(t, err)
+	assert.Equal(t, int64(33), shares["a"].Int64())
+	assert.Equal(t, int64(1), remainder.Int64())
+
+	shares, remainder, err = CalculateDistributionsRounded(context.Background(), big.NewInt(100), distributions, 1, RoundHalfUp)
+	require.NoError(t, err)
+	assert.Equal(t, int64(34), shares["a"].Int64())
+	assert.Equal(t, int64(-1), remainder.Int64())
+
+	// A left share absorbs the remainder
+	shares, remainder, err = CalculateDistributionsRounded(context.Background(), big.NewInt(100), []Distribution{
+		{Identifier: "a", Distribution: "33.5%"},
+		{Identifier: "b", Distribution: "left"},
+	}, 1, RoundHalfUp)
+	require.NoError(t, err)
+	assert.Equal(t, int64(34), shares["a"].Int64())
+	assert.Equal(t, int64(66), shares["b"].Int64())
+	assert.Zero(t, remainder.Sign())
+
+	_, _, err = CalculateDistributionsRounded(context.Background(), big.NewInt(100), []Distribution{
+		{Identifier: "a", Distribution: "80"},
+		{Identifier: "b", Distribution: "50%"},
+	}, 1, RoundHalfUp)
+	assert.Error(t, err)
+}
+
+func TestSplitTransactionPrecise_RoundingRemainder(t *testing.T) {
+	newTransaction := func(mode RoundingMode) *Transaction {
+		return &Transaction{
+			TransactionID:   "txn_parent",
+			Reference:       "ref",
+			Source:          "bln_source",
+			Currency:        "USD",
+			Precision:       1,
+			PreciseAmount:   big.NewInt(100),
+			Rate:            1,
+			RoundingMode:    mode,
+			RoundingBalance: "@RoundingDifference",
+			Destinations: []Distribution{
+				{Identifier: "bln_a", Distribution: "33.5%"},
+				{Identifier: "bln_b", Distribution: "33.5%"},
+				{Identifier: "bln_c", Distribution: "33%"},
+			},
+		}
+	}
+
+	// Rounding down leaves one unit for the source to pay to the rounding balance
+	legs, err := newTransaction(RoundFloor).SplitTransactionPrecise(context.Background())
+	require.NoError(t, err)
+	require.Len(t, legs, 4)
+	remainderLeg := legs[3]
+	assert.Equal(t, "bln_source", remainderLeg.Source)
+	assert.Equal(t, "@RoundingDifference", remainderLeg.Destination)
+	assert.Equal(t, int64(1), remainderLeg.PreciseAmount.Int64())
+	assert.Equal(t, "ref-4", remainderLeg.Reference)
+	assert.Equal(t, true, remainderLeg.MetaData[RoundingRemainderKey])
+	assert.False(t, remainderLeg.AllowOverdraft)
+
+	// Rounding up pays one unit too many, which the rounding balance returns to the source
+	legs, err = newTransaction(RoundHalfUp).SplitTransactionPrecise(context.Background())
+	require.NoError(t, err)
+	require.Len(t, legs, 4)
+	remainderLeg = legs[3]
+	assert.Equal(t, "@RoundingDifference", remainderLeg.Source)
+	assert.Equal(t, "bln_source", remainderLeg.Destination)
+	assert.True(t, remainderLeg.AllowOverdraft)
+
+	// Without a rounding balance the legacy split is used
+	transaction := newTransaction(RoundHalfUp)
+	transaction.RoundingBalance = ""
+	legs, err = transaction.SplitTransactionPrecise(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, legs, 3)
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"errors"
+	"math/big"
+	"time"
+)
+
+// RetryPolicy controls how a scheduled transaction is retried when its source has insufficient funds.
+// Attempts are spread evenly over WindowDays. With AllowPartial, each attempt moves whatever the source
+// can cover and only the remainder is retried.
+type RetryPolicy struct {
+	MaxAttempts  int  `json:"max_attempts"`
+	WindowDays   int  `json:"window_days"`
+	AllowPartial bool `json:"allow_partial"`
+
+	// Progress of the retries, carried with the queued transaction.
+	Attempts       int        `json:"attempts,omitempty"`
+	FirstFailedAt  *time.Time `json:"first_failed_at,omitempty"`
+	ExecutedAmount *big.Int   `json:"executed_amount,omitempty"`
+}
+
+// ScheduledTransactionFailure records a scheduled transaction that failed permanently after its retries ran out.
+type ScheduledTransactionFailure struct {
+	FailureID      string    `json:"failure_id"`
+	TransactionID  string    `json:"transaction_id"`
+	Reference      string    `json:"reference"`
+	Source         string    `json:"source"`
+	Destination    string    `json:"destination"`
+	Currency       string    `json:"currency"`
+	PreciseAmount  *big.Int  `json:"precise_amount"`
+	ExecutedAmount *big.Int  `json:"executed_amount"`
+	Attempts       int       `json:"attempts"`
+	Reason         string    `json:"reason"`
+	ScheduledFor   time.Time `json:"scheduled_for"`
+	FirstFailedAt  time.Time `json:"first_failed_at"`
+	FailedAt       time.Time `json:"failed_at"`
+}
+
+// Validate checks that the policy can be applied.
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 1 {
+		return errors.New("retry_policy.max_attempts must be at least 1")
+	}
+	if p.WindowDays < 0 {
+		return errors.New("retry_policy.window_days must not be negative")
+	}
+	return nil
+}
+
+// Interval returns the delay between two attempts.
+func (p *RetryPolicy) Interval() time.Duration {
+	if p.MaxAttempts < 1 {
+		return 0
+	}
+	return time.Duration(p.WindowDays) * 24 * time.Hour / time.Duration(p.MaxAttempts)
+}
+
+// Exhausted reports whether every allowed attempt has been made.
+func (p *RetryPolicy) Exhausted() bool {
+	return p.Attempts >= p.MaxAttempts
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Interval(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3, WindowDays: 6}
+	assert.Equal(t, 48*time.Hour, policy.Interval())
+
+	policy = &RetryPolicy{MaxAttempts: 0, WindowDays: 6}
+	assert.Equal(t, time.Duration(0), policy.Interval())
+}
+
+func TestRetryPolicy_Exhausted(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 2}
+	assert.False(t, policy.Exhausted())
+	policy.Attempts = 2
+	assert.True(t, policy.Exhausted())
+}
+
+func TestRetryPolicy_Validate(t *testing.T) {
+	assert.NoError(t, (&RetryPolicy{MaxAttempts: 3, WindowDays: 5}).Validate())
+	assert.ErrorContains(t, (&RetryPolicy{MaxAttempts: 0, WindowDays: 5}).Validate(), "max_attempts")
+	assert.ErrorContains(t, (&RetryPolicy{MaxAttempts: 3, WindowDays: -1}).Validate(), "window_days")
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import "time"
+
+const (
+	SchemaCheckSlowStatements   = "slow_statements"
+	SchemaCheckSequentialScans  = "sequential_scans"
+	SchemaCheckMetadataIndexes  = "metadata_indexes"
+	SchemaCheckUnusedIndexes    = "unused_indexes"
+	SchemaCheckTableMaintenance = "table_maintenance"
+
+	SchemaAdviceInfo     = "info"     // Worth knowing, no action needed yet.
+	SchemaAdviceWarning  = "warning"  // Likely to slow the ledger down as data grows.
+	SchemaAdviceCritical = "critical" // Already slowing the ledger down.
+)
+
+// SchemaRecommendation is a single finding of the schema advisor with the change an operator can make to act on it.
+// Statement is the SQL to run, when the recommendation can be applied with one.
+type SchemaRecommendation struct {
+	Check     string            `json:"check"`
+	Severity  string            `json:"severity"`
+	Table     string            `json:"table,omitempty"`
+	Detail    string            `json:"detail"`
+	Action    string            `json:"action"`
+	Statement string            `json:"statement,omitempty"`
+	Evidence  map[string]string `json:"evidence,omitempty"`
+}
+
+// SchemaCheckResult summarises the outcome of one schema advisor check.
+type SchemaCheckResult struct {
+	Name                string `json:"name"`
+	RecommendationCount int    `json:"recommendation_count"`
+	Error               string `json:"error,omitempty"`
+}
+
+// SchemaAdvisorReport is the output of the schema advisor: the checks that ran and what they recommend.
+type SchemaAdvisorReport struct {
+	GeneratedAt     time.Time              `json:"generated_at"`
+	Checks          []SchemaCheckResult    `json:"checks"`
+	Recommendations []SchemaRecommendation `json:"recommendations"`
+}
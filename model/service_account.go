@@ -0,0 +1,58 @@
+package model
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+)
+
+// ServiceAccount is a non-human credential that exchanges its secret for short-lived tokens.
+// Only a hash of the secret is stored; the secret itself is returned once, at creation.
+type ServiceAccount struct {
+	ServiceAccountID  string     `json:"service_account_id" db:"service_account_id"`
+	Name              string     `json:"name" db:"name"`
+	OwnerID           string     `json:"owner_id" db:"owner_id"`
+	SecretHash        string     `json:"-" db:"secret_hash"`
+	Scopes            []string   `json:"scopes" db:"scopes"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	LastTokenIssuedAt *time.Time `json:"last_token_issued_at,omitempty" db:"last_token_issued_at"`
+	IsRevoked         bool       `json:"is_revoked" db:"is_revoked"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// ServiceToken is a short-lived bearer token issued to a service account.
+type ServiceToken struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// HashServiceAccountSecret returns the stored form of a service account secret.
+func HashServiceAccountSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewServiceAccount creates a service account and its secret
+func NewServiceAccount(name, ownerID string, scopes []string) (*ServiceAccount, string, error) {
+	secret, err := GenerateKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &ServiceAccount{
+		ServiceAccountID: GenerateUUIDWithSuffix("svc"),
+		Name:             name,
+		OwnerID:          ownerID,
+		SecretHash:       HashServiceAccountSecret(secret),
+		Scopes:           scopes,
+		CreatedAt:        time.Now(),
+	}, secret, nil
+}
+
+// VerifySecret reports whether secret belongs to the service account
+func (s *ServiceAccount) VerifySecret(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashServiceAccountSecret(secret)), []byte(s.SecretHash)) == 1
+}
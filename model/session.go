@@ -0,0 +1,59 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// Statuses of a session.
+const (
+	SessionOpen       = "open"
+	SessionCommitted  = "committed"
+	SessionRolledBack = "rolled_back"
+)
+
+// Operations a session can stage.
+const (
+	SessionCreateLedger      = "create_ledger"
+	SessionCreateIdentity    = "create_identity"
+	SessionCreateBalance     = "create_balance"
+	SessionRecordTransaction = "record_transaction"
+)
+
+// Session is a unit of work: ledgers, identities, balances and transactions are staged in it one request at a
+// time and written together when it is committed, or not at all. Staged resources are given their IDs when they
+// are staged, so that later operations in the session can refer to them, but they do not exist until the
+// session is committed. Sessions that are neither committed nor rolled back expire at ExpiresAt.
+type Session struct {
+	SessionID   string             `json:"session_id"`
+	Status      string             `json:"status"`
+	Operations  []SessionOperation `json:"operations"`
+	CreatedAt   time.Time          `json:"created_at"`
+	ExpiresAt   time.Time          `json:"expires_at"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty"`
+}
+
+// SessionOperation is an operation staged in a session. The resource matching its Type is set; once the session
+// is committed it holds the resource as written.
+type SessionOperation struct {
+	Type        string       `json:"type"`
+	Ledger      *Ledger      `json:"ledger,omitempty"`
+	Identity    *Identity    `json:"identity,omitempty"`
+	Balance     *Balance     `json:"balance,omitempty"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+}
+
+// ErrSessionNotOpen is returned when operations are staged in, or a commit or rollback is asked of, a session
+// that was already committed or rolled back.
+var ErrSessionNotOpen = errors.New("session is no longer open")
+
+// UnitOfWork is what a session's commit writes in one database transaction: the ledgers, identities and
+// balances it creates, the existing balances its transactions post to and the transactions themselves. The
+// balances it creates are written with its transactions applied.
+type UnitOfWork struct {
+	Ledgers         []*Ledger
+	Identities      []*Identity
+	Balances        []*Balance
+	UpdatedBalances []*Balance
+	Transactions    []*Transaction
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+// Statement delivery channels and statuses.
+const (
+	StatementChannelWebhook = "webhook"
+	StatementChannelEmail   = "email"
+
+	StatementDeliveryPending   = "pending"
+	StatementDeliveryDelivered = "delivered"
+	StatementDeliveryFailed    = "failed"
+)
+
+// StatementSchedule generates a monthly statement for a balance or an identity.
+type StatementSchedule struct {
+	ScheduleID string     `json:"schedule_id"`
+	EntityType string     `json:"entity_type"` // "balance" or "identity"
+	EntityID   string     `json:"entity_id"`
+	DayOfMonth int        `json:"day_of_month"`
+	Channel    string     `json:"channel"`
+	Email      string     `json:"email,omitempty"`
+	IsActive   bool       `json:"is_active"`
+	NextRunAt  time.Time  `json:"next_run_at"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Statement is a generated statement and the state of its delivery.
+type Statement struct {
+	StatementID      string     `json:"statement_id"`
+	ScheduleID       string     `json:"schedule_id"`
+	EntityType       string     `json:"entity_type"`
+	EntityID         string     `json:"entity_id"`
+	PeriodStart      time.Time  `json:"period_start"`
+	PeriodEnd        time.Time  `json:"period_end"`
+	StorageKey       string     `json:"storage_key"`
+	Channel          string     `json:"channel"`
+	DeliveryStatus   string     `json:"delivery_status"`
+	DeliveryAttempts int        `json:"delivery_attempts"`
+	LastError        string     `json:"last_error,omitempty"`
+	DeliveredAt      *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// StatementBalance summarises one balance over a statement period.
+type StatementBalance struct {
+	BalanceID          string         `json:"balance_id"`
+	Currency           string         `json:"currency"`
+	CurrencyMultiplier float64        `json:"currency_multiplier"`
+	OpeningBalance     *big.Int       `json:"opening_balance"`
+	ClosingBalance     *big.Int       `json:"closing_balance"`
+	Transactions       []*Transaction `json:"transactions"`
+	// MemoEntries are gross postings deferred to a netting settlement. They are listed for audit and do
+	// not count towards the closing balance; the net settlement transactions do.
+	MemoEntries []*NettingEntry `json:"memo_entries,omitempty"`
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// Statuses of statement ingestions. An ingestion is received when the file is first seen, uploaded once its
+// rows are staged for reconciliation, and reconciling once a reconciliation of them has started.
+const (
+	StatementIngestionReceived    = "received"
+	StatementIngestionUploaded    = "uploaded"
+	StatementIngestionReconciling = "reconciling"
+	StatementIngestionUnmatched   = "unmatched"
+	StatementIngestionFailed      = "failed"
+)
+
+// StatementIngestion records a statement file collected from a dead drop and what became of it. Files are
+// identified by the SHA-256 fingerprint of their contents, so a file delivered twice is ingested once.
+type StatementIngestion struct {
+	IngestionID      string     `json:"ingestion_id"`
+	Channel          string     `json:"channel"`
+	Sender           string     `json:"sender,omitempty"`
+	Filename         string     `json:"filename"`
+	Fingerprint      string     `json:"fingerprint"`
+	Profile          string     `json:"profile,omitempty"`
+	Status           string     `json:"status"`
+	UploadID         string     `json:"upload_id,omitempty"`
+	RecordCount      int        `json:"record_count"`
+	ReconciliationID string     `json:"reconciliation_id,omitempty"`
+	Error            string     `json:"error,omitempty"`
+	ReceivedAt       time.Time  `json:"received_at"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+}
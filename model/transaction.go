@@ -41,35 +41,47 @@ type Distribution struct {
 }
 
 type Transaction struct {
-	ID                 int64                  `json:"-"`
-	PreciseAmount      *big.Int               `json:"precise_amount,omitempty"`
-	Amount             float64                `json:"amount"`
-	AmountString       string                 `json:"amount_string,omitempty"`
-	Rate               float64                `json:"rate"`
-	Precision          float64                `json:"precision"`
-	OverdraftLimit     float64                `json:"overdraft_limit"`
-	TransactionID      string                 `json:"transaction_id"`
-	ParentTransaction  string                 `json:"parent_transaction"`
-	Source             string                 `json:"source,omitempty"`
-	Destination        string                 `json:"destination,omitempty"`
-	Reference          string                 `json:"reference"`
-	Currency           string                 `json:"currency"`
-	Description        string                 `json:"description,omitempty"`
-	Status             string                 `json:"status"`
-	Hash               string                 `json:"hash"`
-	AllowOverdraft     bool                   `json:"allow_overdraft"`
-	Inflight           bool                   `json:"inflight"`
-	SkipBalanceUpdate  bool                   `json:"-"`
-	SkipQueue          bool                   `json:"skip_queue"`
-	Atomic             bool                   `json:"atomic"`
-	GroupIds           []string               `json:"-"`
-	Sources            []Distribution         `json:"sources,omitempty"`
-	Destinations       []Distribution         `json:"destinations,omitempty"`
-	CreatedAt          time.Time              `json:"created_at"`
-	EffectiveDate      *time.Time             `json:"effective_date,omitempty"`
-	ScheduledFor       time.Time              `json:"scheduled_for,omitempty"`
-	InflightExpiryDate time.Time              `json:"inflight_expiry_date,omitempty"`
-	MetaData           map[string]interface{} `json:"meta_data,omitempty"`
+	ID                     int64                  `json:"-"`
+	PreciseAmount          *big.Int               `json:"precise_amount,omitempty"`
+	Amount                 float64                `json:"amount"`
+	AmountString           string                 `json:"amount_string,omitempty"`
+	Rate                   float64                `json:"rate"`
+	Precision              float64                `json:"precision"`
+	OverdraftLimit         float64                `json:"overdraft_limit"`
+	TransactionID          string                 `json:"transaction_id"`
+	ParentTransaction      string                 `json:"parent_transaction"`
+	Source                 string                 `json:"source,omitempty"`
+	Destination            string                 `json:"destination,omitempty"`
+	Reference              string                 `json:"reference"`
+	GroupID                string                 `json:"group_id,omitempty"`            // Links the transactions of one operation, such as an order
+	ExternalAccountID      string                 `json:"external_account_id,omitempty"` // The verified external account a payout is sent to
+	Currency               string                 `json:"currency"`
+	Description            string                 `json:"description,omitempty"`
+	Status                 string                 `json:"status"`
+	Hash                   string                 `json:"hash"`
+	AllowOverdraft         bool                   `json:"allow_overdraft"`
+	Inflight               bool                   `json:"inflight"`
+	SkipBalanceUpdate      bool                   `json:"-"`
+	SkipQueue              bool                   `json:"skip_queue"`
+	Atomic                 bool                   `json:"atomic"`
+	OverrideMinimumBalance bool                   `json:"override_minimum_balance,omitempty"`
+	GroupIds               []string               `json:"-"`
+	Sources                []Distribution         `json:"sources,omitempty"`
+	Destinations           []Distribution         `json:"destinations,omitempty"`
+	CreatedAt              time.Time              `json:"created_at"`
+	EffectiveDate          *time.Time             `json:"effective_date,omitempty"`
+	TransactionTime        *time.Time             `json:"transaction_time,omitempty"`
+	ScheduledFor           time.Time              `json:"scheduled_for,omitempty"`
+	InflightExpiryDate     time.Time              `json:"inflight_expiry_date,omitempty"`
+	MetaData               map[string]interface{} `json:"meta_data,omitempty"`
+	RetryPolicy            *RetryPolicy           `json:"retry_policy,omitempty"`
+	BusinessDayRule        *BusinessDayRule       `json:"business_day_rule,omitempty"`
+	RoundingMode           RoundingMode           `json:"rounding_mode,omitempty"`
+	Sequences              []LedgerSequence       `json:"sequences,omitempty"`
+	RoundingBalance        string                 `json:"-"`
+	SourceShard            string                 `json:"-"`
+	DestinationShard       string                 `json:"-"`
+	StatusChange           StatusChange           `json:"-"`
 }
 
 func (transaction *Transaction) ToJSON() ([]byte, error) {
@@ -327,7 +339,14 @@ func (transaction *Transaction) SplitTransactionPrecise(ctx context.Context) ([]
 
 	// Use PreciseAmount for distribution calculation
 	precisionInt := int64(transaction.Precision)
-	distributions, err := CalculateDistributionsPrecise(ctx, transaction.PreciseAmount, ds, precisionInt)
+	var distributions map[string]*big.Int
+	var remainder *big.Int
+	var err error
+	if transaction.RoundingMode != "" && transaction.RoundingBalance != "" {
+		distributions, remainder, err = CalculateDistributionsRounded(ctx, transaction.PreciseAmount, ds, precisionInt, transaction.RoundingMode)
+	} else {
+		distributions, err = CalculateDistributionsPrecise(ctx, transaction.PreciseAmount, ds, precisionInt)
+	}
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -368,12 +387,55 @@ func (transaction *Transaction) SplitTransactionPrecise(ctx context.Context) ([]
 		))
 	}
 
+	if remainder != nil && remainder.Sign() != 0 {
+		transactions = append(transactions, transaction.roundingRemainderLeg(remainder, counter))
+	}
+
 	span.AddEvent("Transaction split completed", trace.WithAttributes(
 		attribute.Int("new_transactions.count", len(transactions)),
 	))
 	return transactions, nil
 }
 
+// roundingRemainderLeg returns the leg that posts the remainder of a rounded split to the rounding balance.
+// The leg settles with the side that was not split, so that side still moves exactly the transaction's amount:
+// a source pays any unallocated remainder to the rounding balance and a destination is topped up from it, and
+// a negative remainder flows the other way.
+func (transaction *Transaction) roundingRemainderLeg(remainder *big.Int, counter int) *Transaction {
+	leg := *transaction
+	leg.TransactionID = GenerateUUIDWithSuffix("txn")
+	leg.ParentTransaction = transaction.TransactionID
+	leg.Reference = fmt.Sprintf("%s-%d", transaction.Reference, counter)
+	leg.Sources = nil
+	leg.Destinations = nil
+	leg.PreciseAmount = new(big.Int).Abs(remainder)
+
+	fromRounding := remainder.Sign() < 0
+	if len(transaction.Sources) > 0 {
+		fromRounding = !fromRounding
+	}
+	counterpart := transaction.Source
+	if len(transaction.Sources) > 0 {
+		counterpart = transaction.Destination
+	}
+	if fromRounding {
+		leg.Source, leg.Destination = transaction.RoundingBalance, counterpart
+		leg.AllowOverdraft = true
+	} else {
+		leg.Source, leg.Destination = counterpart, transaction.RoundingBalance
+	}
+
+	leg.MetaData = make(map[string]interface{}, len(transaction.MetaData)+1)
+	for key, value := range transaction.MetaData {
+		leg.MetaData[key] = value
+	}
+	leg.MetaData[RoundingRemainderKey] = true
+
+	convertPreciseToDecimal(&leg)
+	leg.Hash = leg.HashTxn()
+	return &leg
+}
+
 // BulkTransactionRequest encapsulates the data needed for a bulk transaction request.
 type BulkTransactionRequest struct {
 	Transactions []*Transaction `json:"transactions"`
@@ -0,0 +1,22 @@
+package model
+
+import "math/big"
+
+// TransactionGroup is a logical operation, such as an order or an invoice, and the transactions recorded for it:
+// its payment, fees, refunds and chargebacks, linked by their group ID.
+type TransactionGroup struct {
+	GroupID      string           `json:"group_id"`
+	Positions    []*GroupPosition `json:"positions"`
+	Transactions []*Transaction   `json:"transactions"`
+}
+
+// GroupPosition is where a balance stands after the applied transactions of a group: what they credited, what
+// they debited, and the net of the two. Amounts are precise amounts of the currency at its precision.
+type GroupPosition struct {
+	BalanceID string   `json:"balance_id"`
+	Currency  string   `json:"currency"`
+	Precision float64  `json:"precision"`
+	Credits   *big.Int `json:"credits"`
+	Debits    *big.Int `json:"debits"`
+	Net       *big.Int `json:"net"`
+}
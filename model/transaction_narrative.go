@@ -0,0 +1,85 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// NarrativeMetaKey is the metadata key the enriched narrative of a transaction is stored under. The transaction's
+// description stays as it was sent.
+const NarrativeMetaKey = "BLNK_NARRATIVE"
+
+// Sources of an enriched narrative: the cleanup of the description alone, a configured rule, or the enrichment API.
+const (
+	NarrativeSourceCleanup = "cleanup"
+	NarrativeSourceRules   = "rules"
+	NarrativeSourceAPI     = "api"
+)
+
+var (
+	// narrativeProcessorPrefix matches the prefixes card processors and banks put before the merchant in card
+	// narratives, such as "SQ *" for Square, "TST* " for Toast or "POS PURCHASE ".
+	narrativeProcessorPrefix = regexp.MustCompile(`(?i)^((SQ|TST|SP|PP|PY|PAYPAL|IZ|SUMUP|ZETTLE)\s?\*\s*|(DEBIT CARD |CARD |POS )?(PURCHASE|POS)\s+)`)
+
+	// narrativeMerchantEnd matches where the merchant ends in a card narrative: at a store number or at a '*'
+	// before an order reference. In narratives written in capitals, a run of four or more digits is a store or
+	// terminal number too.
+	narrativeMerchantEnd         = regexp.MustCompile(`\s*(#\s?\d+|\*).*$`)
+	narrativeCapitalsMerchantEnd = regexp.MustCompile(`\s*(#\s?\d+|\*|\b\d{4,}\b).*$`)
+)
+
+// TransactionNarrative is the enriched narrative of a transaction: its description as sent and cleaned up, the
+// merchant it was paid to and the category it falls in, when known.
+type TransactionNarrative struct {
+	RawDescription string    `json:"raw_description"`
+	Description    string    `json:"description"`
+	MerchantName   string    `json:"merchant_name,omitempty"`
+	Category       string    `json:"category,omitempty"`
+	Source         string    `json:"source"`
+	EnrichedAt     time.Time `json:"enriched_at"`
+}
+
+// NewTransactionNarrative cleans up a transaction description: whitespace is collapsed and card processor
+// prefixes are removed. Descriptions that are card narratives, with such a prefix or a store number, also name
+// their merchant, in title case when the narrative is in capitals.
+func NewTransactionNarrative(description string) *TransactionNarrative {
+	narrative := &TransactionNarrative{RawDescription: description, Source: NarrativeSourceCleanup}
+	cleaned := strings.Join(strings.Fields(description), " ")
+
+	isCardNarrative := false
+	if prefix := narrativeProcessorPrefix.FindString(cleaned); prefix != "" && prefix != cleaned {
+		cleaned = cleaned[len(prefix):]
+		isCardNarrative = true
+	}
+	merchantEnd := narrativeMerchantEnd
+	if cleaned == strings.ToUpper(cleaned) {
+		merchantEnd = narrativeCapitalsMerchantEnd
+	}
+	merchant := merchantEnd.ReplaceAllString(cleaned, "")
+	if merchant != cleaned {
+		isCardNarrative = true
+	}
+
+	narrative.Description = cleaned
+	if isCardNarrative && merchant != "" {
+		narrative.MerchantName = titleCaseCapitals(merchant)
+	}
+	return narrative
+}
+
+// titleCaseCapitals title cases text written in capitals, such as "BLUE BOTTLE COFFEE", and leaves text in mixed
+// case as it is.
+func titleCaseCapitals(s string) string {
+	if s != strings.ToUpper(s) {
+		return s
+	}
+	words := strings.Fields(strings.ToLower(s))
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
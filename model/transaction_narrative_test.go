@@ -0,0 +1,27 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTransactionNarrative(t *testing.T) {
+	tests := []struct {
+		raw, description, merchant string
+	}{
+		{"SQ *BLUE BOTTLE COFFEE  #1234 OAKLAND CA", "BLUE BOTTLE COFFEE #1234 OAKLAND CA", "Blue Bottle Coffee"},
+		{"POS PURCHASE STARBUCKS 04512 SEATTLE", "STARBUCKS 04512 SEATTLE", "Starbucks"},
+		{"TST* Joe's Pizza", "Joe's Pizza", "Joe's Pizza"},
+		{"AMZN Mktp US*2A3B4C5D6", "AMZN Mktp US*2A3B4C5D6", "AMZN Mktp US"},
+		{"Salary for March 2026", "Salary for March 2026", ""},
+		{"  Rent   payment ", "Rent payment", ""},
+	}
+	for _, tt := range tests {
+		narrative := NewTransactionNarrative(tt.raw)
+		assert.Equal(t, tt.raw, narrative.RawDescription)
+		assert.Equal(t, tt.description, narrative.Description, tt.raw)
+		assert.Equal(t, tt.merchant, narrative.MerchantName, tt.raw)
+		assert.Equal(t, NarrativeSourceCleanup, narrative.Source)
+	}
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Reason codes explain why a transaction moved to a status.
+const (
+	ReasonInsufficientFunds = "insufficient_funds"
+	ReasonOverdraftLimit    = "overdraft_limit_exceeded"
+	ReasonMinimumBalance    = "minimum_balance_breached"
+	ReasonPostingRule       = "posting_rule_violated"
+	ReasonRetriesExhausted  = "retries_exhausted"
+	ReasonInflightCommitted = "inflight_committed"
+	ReasonInflightVoided    = "inflight_voided"
+	ReasonInflightExpired   = "inflight_expired"
+	ReasonChallengeFailed   = "challenge_failed"
+	ReasonChallengeExpired  = "challenge_expired"
+	ReasonRejected          = "rejected" // Rejected for a reason without a code of its own
+)
+
+// ActorSystem is the actor of status changes made by workers rather than by a request.
+const ActorSystem = "system"
+
+// StatusChange describes why and by whom a transaction is recorded with its status. It is saved to the status
+// history alongside the transaction.
+type StatusChange struct {
+	ReasonCode string
+	Reason     string
+	Actor      string
+}
+
+// TransactionStatusEntry is one status a transaction, or one of the transactions it led to, went through.
+type TransactionStatusEntry struct {
+	TransactionID     string    `json:"transaction_id"`
+	ParentTransaction string    `json:"parent_transaction,omitempty"`
+	Status            string    `json:"status"`
+	ReasonCode        string    `json:"reason_code,omitempty"`
+	Reason            string    `json:"reason,omitempty"`
+	Actor             string    `json:"actor,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// RejectionReasonCode classifies the reason a transaction was rejected for.
+func RejectionReasonCode(reason string) string {
+	reason = strings.ToLower(reason)
+	switch {
+	case strings.Contains(reason, "retries exhausted"), strings.Contains(reason, "max retry attempts"):
+		return ReasonRetriesExhausted
+	case strings.Contains(reason, "insufficient funds"):
+		return ReasonInsufficientFunds
+	case strings.Contains(reason, "overdraft limit"):
+		return ReasonOverdraftLimit
+	case strings.Contains(reason, "minimum balance"):
+		return ReasonMinimumBalance
+	case strings.Contains(reason, "rejects the transaction"):
+		return ReasonPostingRule
+	default:
+		return ReasonRejected
+	}
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+// UsageRecord holds the metered usage of one tenant on one ledger for one day.
+// API calls that do not target a ledger are recorded with an empty LedgerID.
+type UsageRecord struct {
+	Date               string `json:"date"`
+	Tenant             string `json:"tenant"`
+	LedgerID           string `json:"ledger_id,omitempty"`
+	APICalls           int64  `json:"api_calls"`
+	TransactionsPosted int64  `json:"transactions_posted"`
+	StorageBytes       int64  `json:"storage_bytes"`
+}
+
+// UsageFilter selects the usage records returned by a usage query. Dates are inclusive and formatted as YYYY-MM-DD.
+type UsageFilter struct {
+	Tenant   string `json:"tenant"`
+	LedgerID string `json:"ledger_id"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// Entities exported by the warehouse sync.
+const (
+	WarehouseTransactions = "transactions"
+	WarehouseBalances     = "balances"
+	WarehouseIdentities   = "identities"
+)
+
+// WarehouseEntities lists the entities exported by the warehouse sync, in the order they are synced.
+var WarehouseEntities = []string{WarehouseTransactions, WarehouseBalances, WarehouseIdentities}
+
+// WarehouseWatermark is the position an entity was exported up to: the updated_at and ID of the last row
+// exported. Rows are exported in updated_at and ID order, so the rows after the watermark are the ones
+// written since.
+type WarehouseWatermark struct {
+	At time.Time `json:"at"`
+	ID string    `json:"id"`
+}
+
+// WarehouseBatch is a batch of rows of an entity read for export. Columns names the values of each row. Last
+// is the watermark of the last row.
+type WarehouseBatch struct {
+	Entity  string
+	Columns []string
+	Rows    [][]interface{}
+	RowIDs  []string // Identifies each version of a row, so the warehouse can drop rows sent twice
+	Last    WarehouseWatermark
+}
+
+// WarehouseSyncState records how far an entity has been exported and how its last sync went.
+type WarehouseSyncState struct {
+	Entity       string             `json:"entity"`
+	Watermark    WarehouseWatermark `json:"watermark"`
+	RowsSynced   int64              `json:"rows_synced"`
+	LastSyncedAt *time.Time         `json:"last_synced_at,omitempty"`
+	LastError    string             `json:"last_error,omitempty"`
+}
+
+// WarehouseSyncRun reports a run of the warehouse sync.
+type WarehouseSyncRun struct {
+	Provider    string                `json:"provider"`
+	StartedAt   time.Time             `json:"started_at"`
+	CompletedAt time.Time             `json:"completed_at"`
+	Entities    []WarehouseEntitySync `json:"entities"`
+}
+
+// WarehouseEntitySync reports the export of one entity in a sync run.
+type WarehouseEntitySync struct {
+	Entity  string `json:"entity"`
+	Table   string `json:"table"`
+	Rows    int    `json:"rows"`
+	Batches int    `json:"batches"`
+	Error   string `json:"error,omitempty"`
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+import "time"
+
+// Webhook replay statuses.
+const (
+	ReplayStatusRunning   = "running"
+	ReplayStatusCompleted = "completed"
+	ReplayStatusFailed    = "failed"
+)
+
+// WebhookReplay describes a rate-limited re-delivery of historical events for one resource type
+// within a time window to a chosen endpoint.
+type WebhookReplay struct {
+	ReplayID      string            `json:"replay_id"`
+	ResourceType  string            `json:"resource_type"`
+	StartDate     time.Time         `json:"start_date"`
+	EndDate       time.Time         `json:"end_date"`
+	URL           string            `json:"url"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	RatePerSecond int               `json:"rate_per_second"`
+	Status        string            `json:"status"`
+	Delivered     int               `json:"delivered"`
+	Failed        int               `json:"failed"`
+	Error         string            `json:"error,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	CompletedAt   *time.Time        `json:"completed_at,omitempty"`
+}
+
+// WebhookSigningSecret holds the secret used to sign outbound webhooks. After a rotation the
+// previous secret is still used to sign deliveries until PreviousExpiresAt, so receivers can
+// roll their verification over without dropping events.
+type WebhookSigningSecret struct {
+	Current           string     `json:"current"`
+	Previous          string     `json:"previous,omitempty"`
+	RotatedAt         *time.Time `json:"rotated_at,omitempty"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+}
+
+// ActiveSecrets returns the secrets that deliveries should currently be signed with.
+func (s *WebhookSigningSecret) ActiveSecrets() []string {
+	secrets := []string{}
+	if s.Current != "" {
+		secrets = append(secrets, s.Current)
+	}
+	if s.IsRotating() {
+		secrets = append(secrets, s.Previous)
+	}
+	return secrets
+}
+
+// IsRotating reports whether the previous secret is still inside its overlap window.
+func (s *WebhookSigningSecret) IsRotating() bool {
+	return s.Previous != "" && s.PreviousExpiresAt != nil && time.Now().Before(*s.PreviousExpiresAt)
+}
+
+// WebhookSigningSecretState is the rotation state of the signing secret without the secret values.
+type WebhookSigningSecretState struct {
+	Configured        bool       `json:"configured"`
+	Rotating          bool       `json:"rotating"`
+	RotatedAt         *time.Time `json:"rotated_at,omitempty"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+}
+
+// Webhook circuit states. A disabled circuit is one that stayed open so long the endpoint was disabled; it is
+// no longer probed and only closes when the endpoint is enabled again.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+	CircuitDisabled = "disabled"
+)
+
+// WebhookCircuit is the circuit breaker state of a webhook endpoint. While the circuit is not closed,
+// deliveries to the endpoint are parked instead of sent, and the oldest of them is sent as a probe at
+// NextProbeAt.
+type WebhookCircuit struct {
+	EndpointID          string        `json:"endpoint_id"`
+	Endpoint            string        `json:"endpoint"`
+	State               string        `json:"state"`
+	ConsecutiveFailures int64         `json:"consecutive_failures"`
+	Parked              int64         `json:"parked"`
+	ProbeInterval       time.Duration `json:"probe_interval"`
+	LastError           string        `json:"last_error,omitempty"`
+	OpenedAt            *time.Time    `json:"opened_at,omitempty"`
+	NextProbeAt         *time.Time    `json:"next_probe_at,omitempty"`
+	DisabledAt          *time.Time    `json:"disabled_at,omitempty"`
+	UpdatedAt           time.Time     `json:"updated_at"`
+}
+
+// WebhookDelivery is the record of one attempt to deliver a webhook to an endpoint. StatusCode is the status a
+// failed delivery was answered with, and zero when the endpoint could not be reached.
+type WebhookDelivery struct {
+	DeliveryID  string    `json:"delivery_id"`
+	EndpointID  string    `json:"endpoint_id"`
+	Event       string    `json:"event"`
+	Succeeded   bool      `json:"succeeded"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	LatencyMs   int64     `json:"latency_ms"`
+	Error       string    `json:"error,omitempty"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
+
+// WebhookFailuresNoResponse is the key failures the endpoint never answered are counted under in
+// WebhookAnalytics.FailuresByStatus.
+const WebhookFailuresNoResponse = "no_response"
+
+// WebhookAnalytics summarises the deliveries to a webhook endpoint within a window: how many succeeded, how
+// long they took, the statuses failed deliveries were answered with and the volume of each event type.
+type WebhookAnalytics struct {
+	EndpointID       string               `json:"endpoint_id"`
+	Window           string               `json:"window"`
+	From             time.Time            `json:"from"`
+	To               time.Time            `json:"to"`
+	Deliveries       int64                `json:"deliveries"`
+	Succeeded        int64                `json:"succeeded"`
+	Failed           int64                `json:"failed"`
+	SuccessRate      float64              `json:"success_rate"`
+	LatencyP50Ms     float64              `json:"latency_p50_ms"`
+	LatencyP95Ms     float64              `json:"latency_p95_ms"`
+	FailuresByStatus map[string]int64     `json:"failures_by_status"`
+	Events           []WebhookEventVolume `json:"events"`
+}
+
+// WebhookEventVolume is the number of deliveries of one event type within a window.
+type WebhookEventVolume struct {
+	Event      string `json:"event"`
+	Deliveries int64  `json:"deliveries"`
+	Succeeded  int64  `json:"succeeded"`
+	Failed     int64  `json:"failed"`
+}
@@ -0,0 +1,181 @@
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	netWorthKeyPrefix = "net_worth"
+
+	// netWorthCacheTTL is how long a net worth is served from cache. Dashboards poll it, so it only needs to be
+	// fresh to within a few seconds.
+	netWorthCacheTTL = 30 * time.Second
+)
+
+// GetIdentityNetWorth sums the balances of an identity by currency and converts each currency to a base currency
+// at the configured exchange rates, for a total of everything the identity holds. Currencies without a rate to
+// the base currency are listed unconverted and left out of the total. Balances outside the ledger scope carried
+// by ctx are left out, and net worths are cached briefly for callers without a ledger scope.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - identityID string: The ID of the identity.
+// - base string: The code of the currency the total is in.
+//
+// Returns:
+// - *model.NetWorth: The net worth of the identity.
+// - error: An error if the base currency is unknown, the identity does not exist or its balances could not be
+// retrieved.
+func (l *Blnk) GetIdentityNetWorth(ctx context.Context, identityID, base string) (*model.NetWorth, error) {
+	ctx, span := tracer.Start(ctx, "GetIdentityNetWorth")
+	defer span.End()
+
+	target, err := LookupCurrency(base)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, err.Error(), err)
+	}
+
+	_, scoped := ledgerscope.FromContext(ctx)
+	key := fmt.Sprintf("%s:%s:%s", netWorthKeyPrefix, identityID, target.Code)
+	if !scoped {
+		if cached, ok := l.cachedNetWorth(ctx, key); ok {
+			return cached, nil
+		}
+	}
+
+	balances, err := l.GetIdentityBalances(ctx, identityID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	netWorth := calculateNetWorth(cnf, identityID, target, balances)
+	if !scoped {
+		l.cacheNetWorth(ctx, key, netWorth)
+	}
+	return netWorth, nil
+}
+
+// calculateNetWorth sums balances by currency, each at the currency's registered precision or, for currencies
+// not in the registry, the highest precision of its balances, and converts the sums to the target currency.
+func calculateNetWorth(cnf *config.Configuration, identityID string, target model.Currency, balances []model.Balance) *model.NetWorth {
+	byCurrency := make(map[string][]model.Balance)
+	for _, balance := range balances {
+		code := strings.ToUpper(balance.Currency)
+		byCurrency[code] = append(byCurrency[code], balance)
+	}
+	codes := make([]string, 0, len(byCurrency))
+	for code := range byCurrency {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	total := new(big.Int)
+	netWorth := &model.NetWorth{
+		IdentityID:   identityID,
+		BaseCurrency: target.Code,
+		Precision:    target.Multiplier,
+		Currencies:   []model.NetWorthCurrency{},
+		AsOf:         time.Now(),
+	}
+	for _, code := range codes {
+		precision := currencyPrecision(code, byCurrency[code])
+		sum := decimal.Zero
+		for _, balance := range byCurrency[code] {
+			if balance.Balance == nil {
+				continue
+			}
+			sum = sum.Add(decimal.NewFromBigInt(balance.Balance, 0).
+				Mul(decimal.NewFromFloat(precision)).
+				Div(decimal.NewFromFloat(balanceMultiplier(balance))))
+		}
+		amount := sum.Round(0).BigInt()
+
+		holding := model.NetWorthCurrency{
+			Currency:  code,
+			Balances:  len(byCurrency[code]),
+			Precision: precision,
+			Balance:   model.NewCalculatedAmount(amount, precision),
+		}
+		converted, err := convertCalculation(cnf, amount, code, precision, target.Code)
+		if err != nil {
+			netWorth.Unconverted = append(netWorth.Unconverted, code)
+		} else {
+			holding.Converted = converted
+			total.Add(total, converted.PreciseAmount)
+		}
+		netWorth.Currencies = append(netWorth.Currencies, holding)
+	}
+	netWorth.Total = model.NewCalculatedAmount(total, target.Multiplier)
+	return netWorth
+}
+
+// currencyPrecision returns the precision a currency's balances are summed at.
+func currencyPrecision(code string, balances []model.Balance) float64 {
+	if currency, err := LookupCurrency(code); err == nil {
+		return currency.Multiplier
+	}
+	precision := 1.0
+	for _, balance := range balances {
+		if multiplier := balanceMultiplier(balance); multiplier > precision {
+			precision = multiplier
+		}
+	}
+	return precision
+}
+
+func balanceMultiplier(balance model.Balance) float64 {
+	if balance.CurrencyMultiplier <= 0 {
+		return 1
+	}
+	return balance.CurrencyMultiplier
+}
+
+// cachedNetWorth returns a net worth cached under key, if there is one.
+func (l *Blnk) cachedNetWorth(ctx context.Context, key string) (*model.NetWorth, bool) {
+	if l.redis == nil {
+		return nil, false
+	}
+	data, err := l.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	var netWorth model.NetWorth
+	if err := json.Unmarshal(data, &netWorth); err != nil {
+		return nil, false
+	}
+	return &netWorth, true
+}
+
+// cacheNetWorth caches a net worth under key for netWorthCacheTTL. Caching never fails the read, and errors are
+// logged.
+func (l *Blnk) cacheNetWorth(ctx context.Context, key string, netWorth *model.NetWorth) {
+	if l.redis == nil {
+		return
+	}
+	data, err := json.Marshal(netWorth)
+	if err != nil {
+		logrus.Errorf("failed to marshal net worth: %v", err)
+		return
+	}
+	if err := l.redis.Set(ctx, key, data, netWorthCacheTTL).Err(); err != nil {
+		logrus.Errorf("failed to cache net worth: %v", err)
+	}
+}
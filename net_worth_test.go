@@ -0,0 +1,99 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func netWorthTestBalances() []model.Balance {
+	return []model.Balance{
+		{BalanceID: "bln_usd_1", LedgerID: "ldg_main", Currency: "USD", CurrencyMultiplier: 100, Balance: big.NewInt(1000)},
+		{BalanceID: "bln_usd_2", LedgerID: "ldg_savings", Currency: "usd", CurrencyMultiplier: 100, Balance: big.NewInt(550)},
+		{BalanceID: "bln_eur", LedgerID: "ldg_main", Currency: "EUR", CurrencyMultiplier: 100, Balance: big.NewInt(1000)},
+		{BalanceID: "bln_pts", LedgerID: "ldg_main", Currency: "POINTS", CurrencyMultiplier: 1, Balance: big.NewInt(5)},
+	}
+}
+
+func TestGetIdentityNetWorth_ConvertsToBaseCurrency(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.Pricing.FXRates = map[string]float64{"EUR/USD": 1.1}
+
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil).Once()
+	mockDS.On("GetBalancesByIdentity", mock.Anything, "idt_1").Return(netWorthTestBalances(), nil).Once()
+
+	netWorth, err := b.GetIdentityNetWorth(context.Background(), "idt_1", "usd")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", netWorth.BaseCurrency)
+	assert.Equal(t, big.NewInt(2650), netWorth.Total.PreciseAmount)
+	assert.Equal(t, 26.5, netWorth.Total.Amount)
+	assert.Equal(t, []string{"POINTS"}, netWorth.Unconverted)
+
+	require.Len(t, netWorth.Currencies, 3)
+	eur := netWorth.Currencies[0]
+	assert.Equal(t, "EUR", eur.Currency)
+	require.NotNil(t, eur.Converted)
+	assert.Equal(t, big.NewInt(1100), eur.Converted.PreciseAmount)
+	assert.Equal(t, "POINTS", netWorth.Currencies[1].Currency)
+	assert.Nil(t, netWorth.Currencies[1].Converted)
+	usd := netWorth.Currencies[2]
+	assert.Equal(t, 2, usd.Balances)
+	assert.Equal(t, big.NewInt(1550), usd.Balance.PreciseAmount)
+
+	// The second read is served from cache.
+	cached, err := b.GetIdentityNetWorth(context.Background(), "idt_1", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(2650), cached.Total.PreciseAmount)
+	mockDS.AssertExpectations(t)
+}
+
+func TestGetIdentityNetWorth_LedgerScopeBypassesCache(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+
+	mockDS.On("GetIdentityByIDIncludingDeleted", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1"}, nil)
+	// The datasource applies the ledger scope, so only the balance of ldg_savings comes back
+	mockDS.On("GetBalancesByIdentity", mock.Anything, "idt_1").Return(netWorthTestBalances()[1:2], nil)
+
+	ctx := ledgerscope.WithScope(context.Background(), ledgerscope.Scope{LedgerIDs: []string{"ldg_savings"}})
+	netWorth, err := b.GetIdentityNetWorth(ctx, "idt_1", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(550), netWorth.Total.PreciseAmount)
+	require.Len(t, netWorth.Currencies, 1)
+	assert.Empty(t, netWorth.Unconverted)
+	assert.False(t, mr.Exists("net_worth:idt_1:USD"))
+}
+
+func TestGetIdentityNetWorth_UnknownBaseCurrency(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	_, err := b.GetIdentityNetWorth(context.Background(), "idt_1", "NOPE")
+	require.Error(t, err)
+	assert.Equal(t, 400, apierror.MapErrorToHTTPStatus(err))
+	mockDS.AssertNotCalled(t, "GetBalancesByIdentity", mock.Anything, mock.Anything)
+}
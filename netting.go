@@ -0,0 +1,428 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	redlock "github.com/blnkfinance/blnk/internal/lock"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+// StatusDeferred is the status of a posting that was recorded as a netting entry instead of being
+// applied. Its effect reaches the balances through the group's next net settlement.
+const StatusDeferred = "DEFERRED"
+
+const (
+	// nettingSettlementMetaKey marks the transactions created by a settlement so they are never deferred again.
+	nettingSettlementMetaKey = "BLNK_NETTING_SETTLEMENT"
+	// nettingGroupMetaKey records on a deferred transaction the group it was deferred to.
+	nettingGroupMetaKey = "BLNK_NETTING_GROUP"
+
+	nettingGroupCacheTTL = 30 * time.Second
+	nettingLockTimeout   = 10 * time.Minute
+)
+
+// nettingGroupCache keeps the active netting groups in memory so that QueueTransaction does not query
+// them for every posting. Groups created on another instance are picked up within nettingGroupCacheTTL.
+type nettingGroupCache struct {
+	mu       sync.Mutex
+	groups   []*model.NettingGroup
+	loadedAt time.Time
+}
+
+// activeNettingGroups returns the cached active netting groups, reloading them when the cache is stale.
+// A failed reload keeps the previous groups so that a database hiccup does not block postings.
+func (l *Blnk) activeNettingGroups(ctx context.Context) []*model.NettingGroup {
+	if l.netting == nil {
+		return nil
+	}
+	l.netting.mu.Lock()
+	defer l.netting.mu.Unlock()
+
+	if time.Since(l.netting.loadedAt) < nettingGroupCacheTTL {
+		return l.netting.groups
+	}
+	l.netting.loadedAt = time.Now()
+
+	groups, err := l.datasource.ListNettingGroups(ctx, model.Page{})
+	if err != nil {
+		logrus.WithError(err).Warn("failed to load netting groups")
+		return l.netting.groups
+	}
+	active := make([]*model.NettingGroup, 0, len(groups))
+	for _, group := range groups {
+		if group.IsActive {
+			active = append(active, group)
+		}
+	}
+	l.netting.groups = active
+	return active
+}
+
+// invalidateNettingGroups forces the next posting to reload the netting groups.
+func (l *Blnk) invalidateNettingGroups() {
+	if l.netting == nil {
+		return
+	}
+	l.netting.mu.Lock()
+	l.netting.loadedAt = time.Time{}
+	l.netting.mu.Unlock()
+}
+
+// nettingGroupFor returns the active group a transaction should be deferred to, or nil when it must be
+// applied immediately. Only plain postings between two members of the same group, in the group's currency,
+// are deferred; inflight, scheduled, split and settlement transactions are always applied.
+func (l *Blnk) nettingGroupFor(ctx context.Context, transaction *model.Transaction) *model.NettingGroup {
+	if transaction.Inflight || !transaction.ScheduledFor.IsZero() || transaction.Status != StatusQueued {
+		return nil
+	}
+	if len(transaction.Sources) > 0 || len(transaction.Destinations) > 0 || transaction.Source == transaction.Destination {
+		return nil
+	}
+	if _, ok := transaction.MetaData[nettingSettlementMetaKey]; ok {
+		return nil
+	}
+
+	for _, group := range l.activeNettingGroups(ctx) {
+		if group.Contains(transaction.Source) && group.Contains(transaction.Destination) && strings.EqualFold(group.Currency, transaction.Currency) {
+			return group
+		}
+	}
+	return nil
+}
+
+// deferForNetting records a transaction as a pending entry of a netting group instead of applying it.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - transaction: The transaction to defer. Its metadata and precise amount must already be set.
+// - group: The group the transaction is deferred to.
+//
+// Returns:
+// - *model.Transaction: The transaction with status DEFERRED.
+// - error: An error if the precision does not match the group or the entry could not be recorded.
+func (l *Blnk) deferForNetting(ctx context.Context, transaction *model.Transaction, group *model.NettingGroup) (*model.Transaction, error) {
+	if transaction.Precision != group.Precision {
+		return nil, fmt.Errorf("transaction precision %v does not match netting group %s precision %v", transaction.Precision, group.GroupID, group.Precision)
+	}
+
+	exists, err := l.datasource.TransactionExistsByRef(ctx, transaction.Reference)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("reference %s has already been used", transaction.Reference)
+	}
+
+	transaction.Status = StatusDeferred
+	transaction.MetaData[nettingGroupMetaKey] = group.GroupID
+
+	entry := &model.NettingEntry{
+		EntryID:       model.GenerateUUIDWithSuffix("net"),
+		GroupID:       group.GroupID,
+		TransactionID: transaction.TransactionID,
+		Reference:     transaction.Reference,
+		Source:        transaction.Source,
+		Destination:   transaction.Destination,
+		Amount:        transaction.Amount,
+		PreciseAmount: transaction.PreciseAmount,
+		Currency:      transaction.Currency,
+		Description:   transaction.Description,
+		MetaData:      transaction.MetaData,
+		Status:        model.NettingEntryPending,
+		CreatedAt:     transaction.CreatedAt,
+	}
+	if err := l.datasource.RecordNettingEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: getEventFromStatus(StatusDeferred), Payload: transaction}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+	return transaction, nil
+}
+
+// CreateNettingGroup creates a netting group. Postings between its balances are deferred from then on and
+// settled as net transfers at the daily cutoff. A balance can belong to only one active group.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - group: The group to create. Name, currency, cutoff time and at least two balances are required.
+//
+// Returns:
+// - *model.NettingGroup: The created group.
+// - error: An error if the group is invalid or could not be saved.
+func (l *Blnk) CreateNettingGroup(ctx context.Context, group model.NettingGroup) (*model.NettingGroup, error) {
+	if group.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if group.Currency == "" {
+		return nil, errors.New("currency is required")
+	}
+	if _, err := group.CutoffOn(time.Now()); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	balanceIDs := make([]string, 0, len(group.BalanceIDs))
+	for _, id := range group.BalanceIDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		balanceIDs = append(balanceIDs, id)
+	}
+	if len(balanceIDs) < 2 {
+		return nil, errors.New("a netting group needs at least two balances")
+	}
+	for _, id := range balanceIDs {
+		balance, err := l.datasource.GetBalanceByIDLite(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("balance %s not found: %w", id, err)
+		}
+		if !strings.EqualFold(balance.Currency, group.Currency) {
+			return nil, fmt.Errorf("balance %s has currency %s, expected %s", id, balance.Currency, group.Currency)
+		}
+	}
+
+	existing, err := l.datasource.ListNettingGroups(ctx, model.Page{})
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range existing {
+		if !other.IsActive {
+			continue
+		}
+		for _, id := range balanceIDs {
+			if other.Contains(id) {
+				return nil, fmt.Errorf("balance %s already belongs to netting group %s", id, other.GroupID)
+			}
+		}
+	}
+
+	if group.Precision == 0 {
+		group.Precision = 1
+	}
+	group.GroupID = model.GenerateUUIDWithSuffix("netg")
+	group.BalanceIDs = balanceIDs
+	group.IsActive = true
+	group.LastCutoffAt = nil
+	group.CreatedAt = time.Now()
+
+	if err := l.datasource.CreateNettingGroup(ctx, &group); err != nil {
+		return nil, err
+	}
+	l.invalidateNettingGroups()
+	return &group, nil
+}
+
+// GetNettingGroup retrieves a netting group by ID.
+func (l *Blnk) GetNettingGroup(ctx context.Context, groupID string) (*model.NettingGroup, error) {
+	return l.datasource.GetNettingGroup(ctx, groupID)
+}
+
+// ListNettingGroups retrieves a page of netting groups, oldest first.
+func (l *Blnk) ListNettingGroups(ctx context.Context, page model.Page) ([]*model.NettingGroup, error) {
+	return l.datasource.ListNettingGroups(ctx, page)
+}
+
+// ListNettingEntries retrieves the memo entries of a netting group, optionally filtered by status.
+func (l *Blnk) ListNettingEntries(ctx context.Context, groupID, status string, page model.Page) ([]*model.NettingEntry, error) {
+	return l.datasource.ListNettingEntries(ctx, groupID, status, page)
+}
+
+// GetNettingSettlement retrieves a netting settlement by ID.
+func (l *Blnk) GetNettingSettlement(ctx context.Context, settlementID string) (*model.NettingSettlement, error) {
+	return l.datasource.GetNettingSettlement(ctx, settlementID)
+}
+
+// ListNettingSettlements retrieves the settlements of a netting group.
+func (l *Blnk) ListNettingSettlements(ctx context.Context, groupID string) ([]*model.NettingSettlement, error) {
+	return l.datasource.ListNettingSettlements(ctx, groupID)
+}
+
+// DeactivateNettingGroup stops deferring postings between the group's balances and settles its pending entries.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - groupID: The ID of the group.
+//
+// Returns:
+// - *model.NettingSettlement: The settlement of the pending entries.
+// - error: An error if the group could not be deactivated or settled.
+func (l *Blnk) DeactivateNettingGroup(ctx context.Context, groupID string) (*model.NettingSettlement, error) {
+	if err := l.datasource.UpdateNettingGroupStatus(ctx, groupID, false); err != nil {
+		return nil, err
+	}
+	l.invalidateNettingGroups()
+	return l.SettleNettingGroup(ctx, groupID, time.Now().UTC())
+}
+
+// RunDueNettingSettlements settles every active group whose cutoff for today has passed and has not been settled yet.
+// Entries from earlier days that were missed, for example while the worker was down, are included in the settlement.
+//
+// Parameters:
+// - ctx: The context for the operation.
+//
+// Returns:
+// - int: The number of groups settled.
+// - error: An error if the groups could not be loaded.
+func (l *Blnk) RunDueNettingSettlements(ctx context.Context) (int, error) {
+	groups, err := l.datasource.ListNettingGroups(ctx, model.Page{})
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	settled := 0
+	for _, group := range groups {
+		if !group.IsActive {
+			continue
+		}
+		cutoff, err := group.CutoffOn(now)
+		if err != nil || now.Before(cutoff) {
+			continue
+		}
+		if group.LastCutoffAt != nil && !group.LastCutoffAt.Before(cutoff) {
+			continue
+		}
+		if _, err := l.SettleNettingGroup(ctx, group.GroupID, cutoff); err != nil {
+			logrus.WithError(err).WithField("group_id", group.GroupID).Error("failed to settle netting group")
+			continue
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+// SettleNettingGroup nets the pending entries a group recorded before the cutoff and applies the net transfers.
+// The gross entries are kept as settled memo records that point at the settlement.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - groupID: The ID of the group.
+// - cutoff: Entries created before this time are settled.
+//
+// Returns:
+// - *model.NettingSettlement: The settlement. Its status is failed when a net transfer could not be applied.
+// - error: An error if the settlement could not be created.
+func (l *Blnk) SettleNettingGroup(ctx context.Context, groupID string, cutoff time.Time) (*model.NettingSettlement, error) {
+	locker := redlock.NewLocker(l.redis, "netting-group:"+groupID, model.GenerateUUIDWithSuffix("loc"))
+	if err := locker.Lock(ctx, nettingLockTimeout); err != nil {
+		return nil, fmt.Errorf("failed to acquire lock for netting group %s: %w", groupID, err)
+	}
+	defer l.releaseLock(ctx, locker)
+
+	group, err := l.datasource.GetNettingGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	settlement := &model.NettingSettlement{
+		SettlementID: model.GenerateUUIDWithSuffix("nset"),
+		GroupID:      group.GroupID,
+		CutoffAt:     cutoff,
+		Transfers:    []model.NettingTransfer{},
+		Status:       model.NettingSettlementProcessing,
+		CreatedAt:    time.Now(),
+	}
+	entries, err := l.datasource.CreateNettingSettlement(ctx, settlement)
+	if err != nil {
+		return nil, err
+	}
+
+	settlement.Transfers = model.NetTransfers(entries)
+	for i := range settlement.Transfers {
+		settlement.Transfers[i].Reference = fmt.Sprintf("%s_%d", settlement.SettlementID, i+1)
+	}
+	return l.applyNettingTransfers(ctx, group, settlement)
+}
+
+// RetryNettingSettlement applies the transfers of a failed settlement that have not been applied yet.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - settlementID: The ID of the settlement.
+//
+// Returns:
+// - *model.NettingSettlement: The settlement with its updated status.
+// - error: An error if the settlement is not failed or could not be loaded.
+func (l *Blnk) RetryNettingSettlement(ctx context.Context, settlementID string) (*model.NettingSettlement, error) {
+	settlement, err := l.datasource.GetNettingSettlement(ctx, settlementID)
+	if err != nil {
+		return nil, err
+	}
+
+	locker := redlock.NewLocker(l.redis, "netting-group:"+settlement.GroupID, model.GenerateUUIDWithSuffix("loc"))
+	if err := locker.Lock(ctx, nettingLockTimeout); err != nil {
+		return nil, fmt.Errorf("failed to acquire lock for netting group %s: %w", settlement.GroupID, err)
+	}
+	defer l.releaseLock(ctx, locker)
+
+	if settlement.Status != model.NettingSettlementFailed {
+		return nil, fmt.Errorf("netting settlement %s is %s, only failed settlements can be retried", settlementID, settlement.Status)
+	}
+	group, err := l.datasource.GetNettingGroup(ctx, settlement.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	return l.applyNettingTransfers(ctx, group, settlement)
+}
+
+// applyNettingTransfers queues the transfers of a settlement that have no transaction yet and records the outcome.
+// Transfers are applied with overdraft allowed because the gross postings behind them were already accepted.
+// Each transfer has a reference derived from the settlement, so a transfer can never be applied twice.
+func (l *Blnk) applyNettingTransfers(ctx context.Context, group *model.NettingGroup, settlement *model.NettingSettlement) (*model.NettingSettlement, error) {
+	settlement.Status = model.NettingSettlementApplied
+	settlement.Error = ""
+	for i := range settlement.Transfers {
+		transfer := &settlement.Transfers[i]
+		if transfer.TransactionID != "" {
+			continue
+		}
+		txn, err := l.QueueTransaction(ctx, &model.Transaction{
+			Source:         transfer.Source,
+			Destination:    transfer.Destination,
+			PreciseAmount:  transfer.PreciseAmount,
+			Precision:      group.Precision,
+			Currency:       group.Currency,
+			Reference:      transfer.Reference,
+			Description:    fmt.Sprintf("Net settlement for %s", group.Name),
+			AllowOverdraft: true,
+			SkipQueue:      true,
+			MetaData: map[string]interface{}{
+				nettingSettlementMetaKey: settlement.SettlementID,
+				nettingGroupMetaKey:      group.GroupID,
+			},
+		})
+		if err != nil {
+			settlement.Status = model.NettingSettlementFailed
+			settlement.Error = fmt.Sprintf("transfer %s: %v", transfer.Reference, err)
+			break
+		}
+		transfer.TransactionID = txn.TransactionID
+	}
+
+	if err := l.datasource.UpdateNettingSettlement(ctx, settlement); err != nil {
+		return nil, err
+	}
+
+	event := "netting.settled"
+	if settlement.Status == model.NettingSettlementFailed {
+		event = "netting.failed"
+	}
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: settlement}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+	return settlement, nil
+}
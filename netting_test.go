@@ -0,0 +1,102 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newNettingTestBlnk(groups []*model.NettingGroup) (*Blnk, *mocks.MockDataSource) {
+	config.ConfigStore.Store(&config.Configuration{})
+	mockDS := new(mocks.MockDataSource)
+	mockDS.On("ListNettingGroups", mock.Anything, model.Page{}).Return(groups, nil)
+	return &Blnk{datasource: mockDS, netting: &nettingGroupCache{}}, mockDS
+}
+
+func TestQueueTransaction_DefersNettingPosting(t *testing.T) {
+	group := &model.NettingGroup{GroupID: "netg_1", BalanceIDs: []string{"bln_a", "bln_b"}, Currency: "USD", Precision: 100, IsActive: true}
+	b, mockDS := newNettingTestBlnk([]*model.NettingGroup{group})
+
+	mockDS.On("TransactionExistsByRef", mock.Anything, "ref_1").Return(false, nil)
+	mockDS.On("RecordNettingEntry", mock.Anything, mock.MatchedBy(func(entry *model.NettingEntry) bool {
+		return entry.GroupID == "netg_1" && entry.Status == model.NettingEntryPending && entry.PreciseAmount.Cmp(big.NewInt(1250)) == 0
+	})).Return(nil)
+
+	txn, err := b.QueueTransaction(context.Background(), &model.Transaction{
+		Source: "bln_a", Destination: "bln_b", Amount: 12.5, Precision: 100, Currency: "USD", Reference: "ref_1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusDeferred, txn.Status)
+	assert.Equal(t, "netg_1", txn.MetaData[nettingGroupMetaKey])
+	mockDS.AssertExpectations(t)
+}
+
+func TestQueueTransaction_DefersOnlyMatchingPostings(t *testing.T) {
+	group := &model.NettingGroup{GroupID: "netg_1", BalanceIDs: []string{"bln_a", "bln_b"}, Currency: "USD", Precision: 100, IsActive: true}
+	b, _ := newNettingTestBlnk([]*model.NettingGroup{group})
+	ctx := context.Background()
+
+	queued := func(txn model.Transaction) *model.Transaction {
+		txn.Status = StatusQueued
+		return &txn
+	}
+
+	assert.NotNil(t, b.nettingGroupFor(ctx, queued(model.Transaction{Source: "bln_a", Destination: "bln_b", Currency: "USD"})))
+	assert.Nil(t, b.nettingGroupFor(ctx, queued(model.Transaction{Source: "bln_a", Destination: "bln_c", Currency: "USD"})))
+	assert.Nil(t, b.nettingGroupFor(ctx, queued(model.Transaction{Source: "bln_a", Destination: "bln_b", Currency: "EUR"})))
+	assert.Nil(t, b.nettingGroupFor(ctx, queued(model.Transaction{Source: "bln_a", Destination: "bln_b", Currency: "USD", Inflight: true})))
+	assert.Nil(t, b.nettingGroupFor(ctx, queued(model.Transaction{
+		Source: "bln_a", Destination: "bln_b", Currency: "USD",
+		MetaData: map[string]interface{}{nettingSettlementMetaKey: "nset_1"},
+	})))
+}
+
+func TestQueueTransaction_NettingPrecisionMismatch(t *testing.T) {
+	group := &model.NettingGroup{GroupID: "netg_1", BalanceIDs: []string{"bln_a", "bln_b"}, Currency: "USD", Precision: 100, IsActive: true}
+	b, mockDS := newNettingTestBlnk([]*model.NettingGroup{group})
+
+	_, err := b.QueueTransaction(context.Background(), &model.Transaction{
+		Source: "bln_a", Destination: "bln_b", Amount: 12.5, Precision: 1000, Currency: "USD", Reference: "ref_2",
+	})
+	assert.ErrorContains(t, err, "precision")
+	mockDS.AssertNotCalled(t, "RecordNettingEntry", mock.Anything, mock.Anything)
+}
+
+func TestCreateNettingGroup_RejectsOverlappingGroups(t *testing.T) {
+	existing := &model.NettingGroup{GroupID: "netg_1", BalanceIDs: []string{"bln_a", "bln_b"}, Currency: "USD", IsActive: true}
+	b, mockDS := newNettingTestBlnk([]*model.NettingGroup{existing})
+	mockDS.On("GetBalanceByIDLite", mock.Anything, mock.Anything).Return(&model.Balance{Currency: "USD"}, nil)
+
+	_, err := b.CreateNettingGroup(context.Background(), model.NettingGroup{
+		Name: "treasury", BalanceIDs: []string{"bln_b", "bln_c"}, Currency: "USD", CutoffTime: "18:00",
+	})
+	assert.ErrorContains(t, err, "already belongs")
+
+	_, err = b.CreateNettingGroup(context.Background(), model.NettingGroup{
+		Name: "treasury", BalanceIDs: []string{"bln_c", "bln_c"}, Currency: "USD", CutoffTime: "18:00",
+	})
+	assert.ErrorContains(t, err, "at least two balances")
+}
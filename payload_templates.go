@@ -0,0 +1,236 @@
+package blnk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// Content types of rendered payloads whose templates do not set one.
+const (
+	defaultWebhookContentType = "application/json"
+	defaultReceiptContentType = "text/plain; charset=utf-8"
+)
+
+// ErrNoReceiptTemplate is returned when a receipt is requested for an event without a receipt template.
+var ErrNoReceiptTemplate = errors.New("no receipt template is configured for the event")
+
+// RenderedPayload is a payload rendered with a configured template.
+type RenderedPayload struct {
+	ContentType string
+	Body        []byte
+}
+
+// parsedPayloadTemplates caches parsed templates by their text, since the configuration holds them as text and
+// they are rendered for every delivery.
+var parsedPayloadTemplates sync.Map
+
+// RenderTransactionReceipt renders the receipt of a transaction with the receipt template of the event its
+// status is sent as, such as transaction.applied.
+//
+// Parameters:
+// - transaction *model.Transaction: The transaction to render the receipt of.
+//
+// Returns:
+// - *RenderedPayload: The receipt and its content type.
+// - error: ErrNoReceiptTemplate if the event has no receipt template, or an error if the receipt cannot be
+// rendered.
+func (l *Blnk) RenderTransactionReceipt(transaction *model.Transaction) (*RenderedPayload, error) {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := renderPayload(cnf.Templates.Receipts, defaultReceiptContentType, NewWebhook{
+		Event:   getEventFromStatus(transaction.Status),
+		Payload: transaction,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rendered == nil {
+		return nil, ErrNoReceiptTemplate
+	}
+	return rendered, nil
+}
+
+// webhookBody encodes a webhook for an endpoint, with the endpoint's template for the event when it has one.
+//
+// Parameters:
+// - endpoint string: The URL the webhook is delivered to.
+// - data NewWebhook: The webhook to encode.
+//
+// Returns:
+// - []byte: The body of the delivery.
+// - string: The content type of a templated body, or an empty string for the usual JSON body.
+// - error: An error if the webhook cannot be encoded or rendered.
+func webhookBody(endpoint string, data NewWebhook) ([]byte, string, error) {
+	if cnf, err := config.Fetch(); err == nil {
+		rendered, err := renderPayload(cnf.Templates.Webhooks[endpoint], defaultWebhookContentType, data)
+		if err != nil {
+			return nil, "", err
+		}
+		if rendered != nil {
+			return rendered.Body, rendered.ContentType, nil
+		}
+	}
+	body, err := json.Marshal(data)
+	return body, "", err
+}
+
+// renderPayload renders an event with the template for it, or returns nil when there is none. Templates see the
+// event with the field names of its JSON payload, and numbers keep their exact digits so precise amounts are not
+// rounded.
+func renderPayload(templates config.PayloadTemplates, defaultContentType string, data NewWebhook) (*RenderedPayload, error) {
+	text, ok := templates.Events[data.Event]
+	if !ok {
+		text, ok = templates.Events["*"]
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	tmpl, err := parsePayloadTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	format := model.NewLocaleFormat(templates.Locale, templates.Timezone)
+	if err := tmpl.Funcs(payloadTemplateFuncs(format)).Execute(&body, fields); err != nil {
+		return nil, fmt.Errorf("failed to render %s template: %w", data.Event, err)
+	}
+
+	contentType := templates.ContentType
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+	return &RenderedPayload{ContentType: contentType, Body: body.Bytes()}, nil
+}
+
+// parsePayloadTemplate parses a template, or returns it from the cache. The returned template is a clone so that
+// its helpers can be bound to the format of one rendering.
+func parsePayloadTemplate(text string) (*template.Template, error) {
+	if cached, ok := parsedPayloadTemplates.Load(text); ok {
+		return cached.(*template.Template).Clone()
+	}
+	tmpl, err := template.New("payload").Funcs(payloadTemplateFuncs(model.LocaleFormat{})).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	parsedPayloadTemplates.Store(text, tmpl)
+	return tmpl.Clone()
+}
+
+// payloadTemplateFuncs returns the helpers templates are rendered with, formatting for a locale and timezone:
+//
+//   - money formats a precise amount, its precision and currency for display, e.g. "$1,234.50".
+//   - amount formats a precise amount and its precision as a plain decimal, e.g. "1234.50".
+//   - date formats a time with a Go layout, e.g. {{date "02/01/2006" .data.created_at}}.
+//   - json encodes a value as JSON.
+//   - upper and lower change the case of a value.
+//   - default returns its first argument when the second is empty.
+//
+// The helpers are also listed in the configuration so that templates can be checked when it is loaded.
+func payloadTemplateFuncs(format model.LocaleFormat) template.FuncMap {
+	return template.FuncMap{
+		"money": func(preciseAmount, precision, code interface{}) (string, error) {
+			money, err := templateMoney(preciseAmount, precision)
+			if err != nil {
+				return "", err
+			}
+			currency, err := LookupCurrency(fmt.Sprint(code))
+			if err != nil {
+				return format.FormatAmount(money.Amount, money.Precision) + " " + fmt.Sprint(code), nil
+			}
+			return format.FormatMoney(money.Amount, money.Precision, currency, "").Formatted, nil
+		},
+		"amount": func(preciseAmount, precision interface{}) (string, error) {
+			money, err := templateMoney(preciseAmount, precision)
+			if err != nil {
+				return "", err
+			}
+			places := int32(math.Round(math.Log10(money.Precision)))
+			return money.Decimal().StringFixed(places), nil
+		},
+		"date": func(layout string, value interface{}) (string, error) {
+			var t time.Time
+			switch v := value.(type) {
+			case nil:
+				return "", nil
+			case time.Time:
+				t = v
+			case string:
+				if v == "" {
+					return "", nil
+				}
+				parsed, err := time.Parse(time.RFC3339Nano, v)
+				if err != nil {
+					return "", err
+				}
+				t = parsed
+			default:
+				return "", fmt.Errorf("date: unsupported value %v", value)
+			}
+			location, err := time.LoadLocation(format.Timezone)
+			if err != nil {
+				location = time.UTC
+			}
+			return t.In(location).Format(layout), nil
+		},
+		"json": func(value interface{}) (string, error) {
+			encoded, err := json.Marshal(value)
+			return string(encoded), err
+		},
+		"upper": func(value interface{}) string { return strings.ToUpper(templateString(value)) },
+		"lower": func(value interface{}) string { return strings.ToLower(templateString(value)) },
+		"default": func(fallback, value interface{}) interface{} {
+			if templateString(value) == "" {
+				return fallback
+			}
+			return value
+		},
+	}
+}
+
+// templateMoney reads a precise amount and its precision from template values. A precision of zero or less is
+// taken as 1.
+func templateMoney(preciseAmount, precision interface{}) (model.Money, error) {
+	amount, ok := new(big.Int).SetString(templateString(preciseAmount), 10)
+	if !ok {
+		return model.Money{}, fmt.Errorf("invalid precise amount %v", preciseAmount)
+	}
+	multiplier, err := strconv.ParseFloat(templateString(precision), 64)
+	if err != nil && templateString(precision) != "" {
+		return model.Money{}, fmt.Errorf("invalid precision %v", precision)
+	}
+	return model.NewMoney(amount, "", multiplier), nil
+}
+
+// templateString returns a template value as text, with nothing for missing values.
+func templateString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func templateTestTransaction() *model.Transaction {
+	return &model.Transaction{
+		TransactionID: "txn_1",
+		Reference:     "ref_1",
+		Currency:      "USD",
+		Amount:        1234.5,
+		PreciseAmount: big.NewInt(123450),
+		Precision:     100,
+		Status:        StatusApplied,
+		CreatedAt:     time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestRenderPayload(t *testing.T) {
+	templates := config.PayloadTemplates{
+		Timezone: "Africa/Lagos",
+		Events: map[string]string{
+			"transaction.applied": `{{.data.reference}}|{{money .data.precise_amount .data.precision .data.currency}}|{{amount .data.precise_amount .data.precision}}|{{date "2006-01-02 15:04" .data.created_at}}|{{lower .data.status}}|{{default "none" .data.description}}`,
+			"*":                   `{"type":{{json .event}}}`,
+		},
+	}
+
+	rendered, err := renderPayload(templates, defaultWebhookContentType, NewWebhook{Event: "transaction.applied", Payload: templateTestTransaction()})
+	require.NoError(t, err)
+	assert.Equal(t, "ref_1|$1,234.50|1234.50|2026-03-02 00:30|applied|none", string(rendered.Body))
+	assert.Equal(t, defaultWebhookContentType, rendered.ContentType)
+
+	rendered, err = renderPayload(templates, defaultWebhookContentType, NewWebhook{Event: "ledger.created", Payload: model.Ledger{LedgerID: "ldg_1"}})
+	require.NoError(t, err)
+	assert.Equal(t, `{"type":"ledger.created"}`, string(rendered.Body), "events without their own template use the * template")
+
+	rendered, err = renderPayload(config.PayloadTemplates{}, defaultWebhookContentType, NewWebhook{Event: "ledger.created"})
+	require.NoError(t, err)
+	assert.Nil(t, rendered, "events without a template keep their usual payload")
+}
+
+func TestRenderPayload_Locale(t *testing.T) {
+	templates := config.PayloadTemplates{
+		Locale: "de-DE",
+		Events: map[string]string{"*": `{{money .data.precise_amount .data.precision .data.currency}}`},
+	}
+	rendered, err := renderPayload(templates, defaultWebhookContentType, NewWebhook{Event: "transaction.applied", Payload: templateTestTransaction()})
+	require.NoError(t, err)
+	assert.Equal(t, "1.234,50 $", string(rendered.Body))
+}
+
+func TestProcessHTTP_Template(t *testing.T) {
+	var body []byte
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		contentType = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config.ConfigStore.Store(&config.Configuration{
+		Notification: config.Notification{Webhook: config.WebhookConfig{Url: server.URL}},
+		Templates: config.TemplatesConfig{Webhooks: map[string]config.PayloadTemplates{
+			server.URL: {ContentType: "application/xml", Events: map[string]string{
+				"transaction.applied": `<txn ref="{{.data.reference}}" amount="{{amount .data.precise_amount .data.precision}}"/>`,
+			}},
+		}},
+	})
+
+	require.NoError(t, processHTTP(NewWebhook{Event: "transaction.applied", Payload: templateTestTransaction()}, server.Client(), nil))
+	assert.Equal(t, `<txn ref="ref_1" amount="1234.50"/>`, string(body))
+	assert.Equal(t, "application/xml", contentType)
+
+	require.NoError(t, processHTTP(NewWebhook{Event: "ledger.created", Payload: model.Ledger{LedgerID: "ldg_1"}}, server.Client(), nil))
+	assert.JSONEq(t, `{"event":"ledger.created","data":{"ledger_id":"ldg_1","name":"","created_at":"0001-01-01T00:00:00Z","meta_data":null}}`, string(body))
+}
+
+func TestRenderTransactionReceipt(t *testing.T) {
+	config.ConfigStore.Store(&config.Configuration{
+		Templates: config.TemplatesConfig{Receipts: config.PayloadTemplates{Events: map[string]string{
+			"transaction.applied": "Receipt {{.data.transaction_id}}\nPaid {{money .data.precise_amount .data.precision .data.currency}}",
+		}}},
+	})
+	b := &Blnk{}
+
+	receipt, err := b.RenderTransactionReceipt(templateTestTransaction())
+	require.NoError(t, err)
+	assert.Equal(t, "Receipt txn_1\nPaid $1,234.50", string(receipt.Body))
+	assert.Equal(t, defaultReceiptContentType, receipt.ContentType)
+
+	inflight := templateTestTransaction()
+	inflight.Status = StatusInflight
+	_, err = b.RenderTransactionReceipt(inflight)
+	assert.ErrorIs(t, err, ErrNoReceiptTemplate)
+}
@@ -0,0 +1,216 @@
+package blnk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pipelineTimingKeyPrefix = "pipeline_timing"
+	pipelineTimingRetention = 7 * 24 * time.Hour
+)
+
+// pipelineStageBuckets are the upper bounds, in seconds, of the buckets of the stage duration histograms.
+var pipelineStageBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// stageHistogram counts the durations of a pipeline stage by bucket. Counts are not cumulative; they are summed
+// as the histogram is written.
+type stageHistogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// pipelineHistograms holds the duration histograms of the stages timed by this process since it started.
+var pipelineHistograms = struct {
+	sync.Mutex
+	stages map[string]*stageHistogram
+}{stages: make(map[string]*stageHistogram)}
+
+// observePipelineStage adds the duration of a stage to its histogram.
+func observePipelineStage(stage string, duration time.Duration) {
+	seconds := duration.Seconds()
+	pipelineHistograms.Lock()
+	defer pipelineHistograms.Unlock()
+
+	h, ok := pipelineHistograms.stages[stage]
+	if !ok {
+		h = &stageHistogram{counts: make([]uint64, len(pipelineStageBuckets))}
+		pipelineHistograms.stages[stage] = h
+	}
+	for i, bound := range pipelineStageBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// WritePipelineMetrics writes the duration histograms of the stages of the posting pipeline in the Prometheus
+// text format, for the transactions this process posted.
+//
+// Parameters:
+// - w io.Writer: The writer the metrics are written to.
+//
+// Returns:
+// - error: An error if the metrics could not be written.
+func WritePipelineMetrics(w io.Writer) error {
+	const name = "blnk_transaction_stage_duration_seconds"
+	pipelineHistograms.Lock()
+	defer pipelineHistograms.Unlock()
+
+	if _, err := fmt.Fprintf(w, "# HELP %s Time transactions spent in each stage of the posting pipeline.\n# TYPE %s histogram\n", name, name); err != nil {
+		return err
+	}
+	for _, stage := range model.PipelineStages {
+		h, ok := pipelineHistograms.stages[stage]
+		if !ok {
+			continue
+		}
+		var cumulative uint64
+		for i, bound := range pipelineStageBuckets {
+			cumulative += h.counts[i]
+			if _, err := fmt.Fprintf(w, "%s_bucket{stage=%q,le=%q} %d\n", name, stage, strconv.FormatFloat(bound, 'g', -1, 64), cumulative); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket{stage=%q,le=\"+Inf\"} %d\n%s_sum{stage=%q} %g\n%s_count{stage=%q} %d\n",
+			name, stage, h.count, name, stage, h.sum, name, stage, h.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type pipelineTimingContextKey struct{}
+
+// pipelineTiming collects the time a transaction spends in the stages of the pipeline it goes through while
+// it is recorded, so they can be saved together once its ID is final.
+type pipelineTiming struct {
+	mu     sync.Mutex
+	stages map[string]time.Duration
+}
+
+// withPipelineTiming returns a context in which the stages timed are collected for one transaction.
+func withPipelineTiming(ctx context.Context) (context.Context, *pipelineTiming) {
+	timing := &pipelineTiming{stages: make(map[string]time.Duration)}
+	return context.WithValue(ctx, pipelineTimingContextKey{}, timing), timing
+}
+
+// timePipelineStage starts timing a stage of the pipeline. The returned function ends it, adding its duration
+// to the stage's histogram and to the transaction timed in ctx, if any.
+func timePipelineStage(ctx context.Context, stage string) func() {
+	start := time.Now()
+	return func() {
+		duration := time.Since(start)
+		observePipelineStage(stage, duration)
+		if timing, ok := ctx.Value(pipelineTimingContextKey{}).(*pipelineTiming); ok {
+			timing.mu.Lock()
+			timing.stages[stage] += duration
+			timing.mu.Unlock()
+		}
+	}
+}
+
+// savePipelineTiming saves the time a transaction spent in stages of the pipeline, in the background. Timings
+// are kept for pipelineTimingRetention; saving them never fails the posting, and errors are logged.
+func (l *Blnk) savePipelineTiming(transactionID string, stages map[string]time.Duration) {
+	if l.redis == nil || transactionID == "" || len(stages) == 0 {
+		return
+	}
+	values := make(map[string]interface{}, len(stages))
+	for stage, duration := range stages {
+		values[stage] = duration.Microseconds()
+	}
+	go func() {
+		ctx := context.Background()
+		key := pipelineTimingKey(transactionID)
+		pipe := l.redis.TxPipeline()
+		pipe.HSet(ctx, key, values)
+		pipe.Expire(ctx, key, pipelineTimingRetention)
+		if _, err := pipe.Exec(ctx); err != nil {
+			logrus.Errorf("failed to save pipeline timing of transaction %s: %v", transactionID, err)
+		}
+	}()
+}
+
+// saveCollectedPipelineTiming saves the stages collected in timing for a transaction.
+func (l *Blnk) saveCollectedPipelineTiming(transactionID string, timing *pipelineTiming) {
+	timing.mu.Lock()
+	stages := make(map[string]time.Duration, len(timing.stages))
+	for stage, duration := range timing.stages {
+		stages[stage] = duration
+	}
+	timing.mu.Unlock()
+	l.savePipelineTiming(transactionID, stages)
+}
+
+func pipelineTimingKey(transactionID string) string {
+	return fmt.Sprintf("%s:%s", pipelineTimingKeyPrefix, transactionID)
+}
+
+// GetTransactionTimings retrieves the time a transaction spent in each stage of the posting pipeline, with the
+// timings of the transactions it led to, such as the queued copy that was applied. Transactions are listed in
+// the order of their status history, and those without timings, such as ones posted before timings were kept
+// or longer ago than they are kept for, are left out.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - transactionID string: The ID of the transaction.
+//
+// Returns:
+// - []*model.TransactionTiming: The timings of the transaction and the transactions it led to.
+// - error: An error if the transaction does not exist or its timings could not be retrieved.
+func (l *Blnk) GetTransactionTimings(ctx context.Context, transactionID string) ([]*model.TransactionTiming, error) {
+	ctx, span := tracer.Start(ctx, "GetTransactionTimings")
+	defer span.End()
+
+	history, err := l.GetTransactionStatusHistory(ctx, transactionID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	timings := []*model.TransactionTiming{}
+	seen := make(map[string]bool)
+	for _, entry := range history {
+		if seen[entry.TransactionID] {
+			continue
+		}
+		seen[entry.TransactionID] = true
+
+		values, err := l.redis.HGetAll(ctx, pipelineTimingKey(entry.TransactionID)).Result()
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		if len(values) == 0 {
+			continue
+		}
+		timing := &model.TransactionTiming{TransactionID: entry.TransactionID, Stages: []model.StageTiming{}}
+		for _, stage := range model.PipelineStages {
+			value, ok := values[stage]
+			if !ok {
+				continue
+			}
+			micros, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			ms := float64(micros) / 1000
+			timing.Stages = append(timing.Stages, model.StageTiming{Stage: stage, DurationMs: ms})
+			timing.TotalMs += ms
+		}
+		timings = append(timings, timing)
+	}
+	return timings, nil
+}
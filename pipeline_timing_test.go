@@ -0,0 +1,94 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePipelineMetrics(t *testing.T) {
+	pipelineHistograms.Lock()
+	saved := pipelineHistograms.stages
+	pipelineHistograms.stages = make(map[string]*stageHistogram)
+	pipelineHistograms.Unlock()
+	t.Cleanup(func() {
+		pipelineHistograms.Lock()
+		pipelineHistograms.stages = saved
+		pipelineHistograms.Unlock()
+	})
+
+	observePipelineStage(model.PipelineStageLockWait, 2*time.Millisecond)
+	observePipelineStage(model.PipelineStageLockWait, 40*time.Millisecond)
+	observePipelineStage(model.PipelineStageLockWait, time.Minute)
+
+	var out bytes.Buffer
+	require.NoError(t, WritePipelineMetrics(&out))
+	metrics := out.String()
+	assert.Contains(t, metrics, "# TYPE blnk_transaction_stage_duration_seconds histogram")
+	assert.Contains(t, metrics, `blnk_transaction_stage_duration_seconds_bucket{stage="lock_wait",le="0.001"} 0`)
+	assert.Contains(t, metrics, `blnk_transaction_stage_duration_seconds_bucket{stage="lock_wait",le="0.0025"} 1`)
+	assert.Contains(t, metrics, `blnk_transaction_stage_duration_seconds_bucket{stage="lock_wait",le="0.05"} 2`)
+	assert.Contains(t, metrics, `blnk_transaction_stage_duration_seconds_bucket{stage="lock_wait",le="10"} 2`)
+	assert.Contains(t, metrics, `blnk_transaction_stage_duration_seconds_bucket{stage="lock_wait",le="+Inf"} 3`)
+	assert.Contains(t, metrics, `blnk_transaction_stage_duration_seconds_count{stage="lock_wait"} 3`)
+	assert.False(t, strings.Contains(metrics, `stage="commit"`), "stages never timed are left out")
+}
+
+func TestSavePipelineTiming(t *testing.T) {
+	b, _, mr := newBalanceNotificationTestBlnk(t)
+
+	ctx, timing := withPipelineTiming(context.Background())
+	timePipelineStage(ctx, model.PipelineStageValidation)()
+	timePipelineStage(ctx, model.PipelineStageCommit)()
+	b.saveCollectedPipelineTiming("txn_1", timing)
+
+	assert.Eventually(t, func() bool { return mr.Exists("pipeline_timing:txn_1") }, time.Second, 10*time.Millisecond)
+	stages, err := mr.HKeys("pipeline_timing:txn_1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{model.PipelineStageValidation, model.PipelineStageCommit}, stages)
+	assert.Equal(t, pipelineTimingRetention, mr.TTL("pipeline_timing:txn_1"))
+}
+
+func TestGetTransactionTimings(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetTransaction", mock.Anything, "txn_queued").Return(&model.Transaction{TransactionID: "txn_queued"}, nil)
+	mockDS.On("GetTransactionStatusHistory", mock.Anything, "txn_queued").Return([]*model.TransactionStatusEntry{
+		{TransactionID: "txn_queued", Status: StatusQueued},
+		{TransactionID: "txn_applied", ParentTransaction: "txn_queued", Status: StatusApplied},
+	}, nil)
+	mr.HSet("pipeline_timing:txn_applied", model.PipelineStageCommit, "1500", model.PipelineStageEnqueue, "250", model.PipelineStageLockWait, "3000")
+
+	timings, err := b.GetTransactionTimings(context.Background(), "txn_queued")
+	require.NoError(t, err)
+	require.Len(t, timings, 1, "transactions without timings are left out")
+	assert.Equal(t, "txn_applied", timings[0].TransactionID)
+	assert.Equal(t, []model.StageTiming{
+		{Stage: model.PipelineStageEnqueue, DurationMs: 0.25},
+		{Stage: model.PipelineStageLockWait, DurationMs: 3},
+		{Stage: model.PipelineStageCommit, DurationMs: 1.5},
+	}, timings[0].Stages)
+	assert.Equal(t, 4.75, timings[0].TotalMs)
+}
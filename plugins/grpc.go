@@ -0,0 +1,140 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// ProcessMethod is the full gRPC method external plugins must serve. Messages are JSON encoded
+// (content-subtype "json"), so plugins can be written without generated protobuf stubs.
+const ProcessMethod = "/blnk.plugins.v1.TransactionProcessor/Process"
+
+// ProcessRequest is sent to external plugins.
+type ProcessRequest struct {
+	Stage       Stage              `json:"stage"`
+	Transaction *model.Transaction `json:"transaction"`
+}
+
+// ProcessResponse is returned by external plugins. A non-empty Error rejects the transaction
+// (subject to the failure policy). During enrichment, a returned Transaction replaces the original.
+type ProcessResponse struct {
+	Error       string             `json:"error,omitempty"`
+	Transaction *model.Transaction `json:"transaction,omitempty"`
+}
+
+// jsonCodec encodes gRPC messages as JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return "json" }
+
+// GRPCProcessor forwards pipeline stages to an external plugin over gRPC.
+type GRPCProcessor struct {
+	name string
+	conn *grpc.ClientConn
+}
+
+// NewGRPCProcessor connects to an external plugin. The connection is established lazily on first use.
+//
+// Parameters:
+// - name string: The plugin name.
+// - address string: The plugin's gRPC address (host:port).
+//
+// Returns:
+// - *GRPCProcessor: The processor.
+// - error: An error if the address is invalid.
+func NewGRPCProcessor(name, address string) (*GRPCProcessor, error) {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for plugin %s: %w", name, err)
+	}
+	return &GRPCProcessor{name: name, conn: conn}, nil
+}
+
+// Name returns the plugin name.
+func (p *GRPCProcessor) Name() string {
+	return p.name
+}
+
+// Process sends the transaction to the plugin and applies its response.
+func (p *GRPCProcessor) Process(ctx context.Context, stage Stage, txn *model.Transaction) error {
+	var resp ProcessResponse
+	err := p.conn.Invoke(ctx, ProcessMethod, &ProcessRequest{Stage: stage, Transaction: txn}, &resp, grpc.ForceCodec(jsonCodec{}))
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	if stage == StageEnrichment && resp.Transaction != nil {
+		*txn = *resp.Transaction
+	}
+	return nil
+}
+
+// Close closes the connection to the plugin.
+func (p *GRPCProcessor) Close() error {
+	return p.conn.Close()
+}
+
+// LoadFromConfig registers the external plugins listed in the configuration.
+//
+// Parameters:
+// - registry *Registry: The registry to add the plugins to.
+// - plugins []config.PluginConfig: The configured plugins.
+//
+// Returns:
+// - error: An error if a plugin cannot be created or registered.
+func LoadFromConfig(registry *Registry, plugins []config.PluginConfig) error {
+	for _, pc := range plugins {
+		if pc.Name == "" || pc.Address == "" {
+			return errors.New("plugin name and address are required")
+		}
+		processor, err := NewGRPCProcessor(pc.Name, pc.Address)
+		if err != nil {
+			return err
+		}
+
+		stages := make([]Stage, 0, len(pc.Stages))
+		for _, s := range pc.Stages {
+			stages = append(stages, Stage(s))
+		}
+
+		err = registry.Register(Registration{
+			Processor:     processor,
+			Stages:        stages,
+			Timeout:       time.Duration(pc.TimeoutMs) * time.Millisecond,
+			FailurePolicy: FailurePolicy(pc.FailurePolicy),
+		})
+		if err != nil {
+			_ = processor.Close()
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package plugins lets deployments insert custom processors into the transaction posting pipeline.
+//
+// Processors run at one or more stages:
+//   - validation: before balances are loaded. Returning an error rejects the transaction.
+//   - enrichment: before the transaction is applied. Processors may modify the transaction (e.g. add metadata).
+//   - post_commit: after the transaction has been persisted. Errors are logged and never undo the posting.
+//
+// In-process processors implement the Processor interface and are registered on the Registry.
+// External processors are reached over gRPC (see NewGRPCProcessor) and are discovered from configuration.
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Stage identifies where in the posting pipeline a processor runs.
+type Stage string
+
+const (
+	StageValidation Stage = "validation"
+	StageEnrichment Stage = "enrichment"
+	StagePostCommit Stage = "post_commit"
+)
+
+// FailurePolicy decides what happens to the transaction when a processor fails or times out.
+type FailurePolicy string
+
+const (
+	// FailClosed rejects the transaction when the processor fails. This is the default.
+	FailClosed FailurePolicy = "fail_closed"
+	// FailOpen logs the failure and lets the transaction continue.
+	FailOpen FailurePolicy = "fail_open"
+)
+
+// DefaultTimeout is used for processors registered without a timeout.
+const DefaultTimeout = 5 * time.Second
+
+// Processor is a custom step in the posting pipeline.
+type Processor interface {
+	// Name returns a unique name for the processor.
+	Name() string
+	// Process handles the transaction at the given stage. During the enrichment stage the processor
+	// may modify the transaction; changes made at other stages are discarded.
+	Process(ctx context.Context, stage Stage, txn *model.Transaction) error
+}
+
+// Registration describes how a processor is attached to the pipeline.
+type Registration struct {
+	Processor     Processor
+	Stages        []Stage
+	Timeout       time.Duration
+	FailurePolicy FailurePolicy
+}
+
+// Registry holds the registered processors in registration order.
+type Registry struct {
+	mu      sync.RWMutex
+	entries []Registration
+}
+
+// NewRegistry creates an empty processor registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a processor to the pipeline.
+//
+// Parameters:
+// - reg Registration: The processor and the stages it runs at.
+//
+// Returns:
+// - error: An error if the registration is invalid or the name is already registered.
+func (r *Registry) Register(reg Registration) error {
+	if reg.Processor == nil {
+		return errors.New("processor is required")
+	}
+	if len(reg.Stages) == 0 {
+		return fmt.Errorf("processor %s must run at one or more stages", reg.Processor.Name())
+	}
+	for _, stage := range reg.Stages {
+		switch stage {
+		case StageValidation, StageEnrichment, StagePostCommit:
+		default:
+			return fmt.Errorf("processor %s: unknown stage %q", reg.Processor.Name(), stage)
+		}
+	}
+	switch reg.FailurePolicy {
+	case "":
+		reg.FailurePolicy = FailClosed
+	case FailClosed, FailOpen:
+	default:
+		return fmt.Errorf("processor %s: unknown failure policy %q", reg.Processor.Name(), reg.FailurePolicy)
+	}
+	if reg.Timeout <= 0 {
+		reg.Timeout = DefaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.entries {
+		if existing.Processor.Name() == reg.Processor.Name() {
+			return fmt.Errorf("processor %s is already registered", reg.Processor.Name())
+		}
+	}
+	r.entries = append(r.entries, reg)
+	return nil
+}
+
+// Unregister removes a processor by name. It reports whether a processor was removed.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, entry := range r.entries {
+		if entry.Processor.Name() == name {
+			r.entries = append(r.entries[:i], r.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// forStage returns the registrations that run at a stage.
+func (r *Registry) forStage(stage Stage) []Registration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var regs []Registration
+	for _, entry := range r.entries {
+		for _, s := range entry.Stages {
+			if s == stage {
+				regs = append(regs, entry)
+				break
+			}
+		}
+	}
+	return regs
+}
+
+// Run executes every processor registered for a stage, in registration order.
+// Each processor works on a copy of the transaction and is bounded by its timeout. Enrichment
+// changes are copied back only when the processor succeeds.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - stage Stage: The pipeline stage being executed.
+// - txn *model.Transaction: The transaction being posted.
+//
+// Returns:
+// - error: The first error from a fail-closed processor. Always nil for the post-commit stage.
+func (r *Registry) Run(ctx context.Context, stage Stage, txn *model.Transaction) error {
+	if r == nil {
+		return nil
+	}
+
+	for _, reg := range r.forStage(stage) {
+		working := cloneTransaction(txn)
+		err := runWithTimeout(ctx, reg, stage, working)
+		if err == nil {
+			if stage == StageEnrichment {
+				*txn = *working
+			}
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"plugin": reg.Processor.Name(),
+			"stage":  stage,
+			"policy": reg.FailurePolicy,
+		}).WithError(err).Warn("transaction processor failed")
+
+		if stage != StagePostCommit && reg.FailurePolicy == FailClosed {
+			return fmt.Errorf("processor %s rejected transaction at %s: %w", reg.Processor.Name(), stage, err)
+		}
+	}
+	return nil
+}
+
+// runWithTimeout runs one processor and stops waiting for it once its timeout has passed.
+func runWithTimeout(ctx context.Context, reg Registration, stage Stage, txn *model.Transaction) error {
+	ctx, cancel := context.WithTimeout(ctx, reg.Timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("processor panicked: %v", p)
+			}
+		}()
+		done <- reg.Processor.Process(ctx, stage, txn)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("processor timed out after %s", reg.Timeout)
+	}
+}
+
+// cloneTransaction copies a transaction so that a processor cannot modify the original
+// after its timeout has expired.
+func cloneTransaction(txn *model.Transaction) *model.Transaction {
+	clone := *txn
+	if txn.MetaData != nil {
+		clone.MetaData = make(map[string]interface{}, len(txn.MetaData))
+		for k, v := range txn.MetaData {
+			clone.MetaData[k] = v
+		}
+	}
+	return &clone
+}
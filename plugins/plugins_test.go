@@ -0,0 +1,145 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+type funcProcessor struct {
+	name string
+	fn   func(ctx context.Context, stage Stage, txn *model.Transaction) error
+}
+
+func (p funcProcessor) Name() string { return p.name }
+
+func (p funcProcessor) Process(ctx context.Context, stage Stage, txn *model.Transaction) error {
+	return p.fn(ctx, stage, txn)
+}
+
+func TestRegister_Validation(t *testing.T) {
+	r := NewRegistry()
+	noop := funcProcessor{name: "noop", fn: func(context.Context, Stage, *model.Transaction) error { return nil }}
+
+	assert.Error(t, r.Register(Registration{}))
+	assert.Error(t, r.Register(Registration{Processor: noop}))
+	assert.Error(t, r.Register(Registration{Processor: noop, Stages: []Stage{"unknown"}}))
+	assert.Error(t, r.Register(Registration{Processor: noop, Stages: []Stage{StageValidation}, FailurePolicy: "maybe"}))
+	assert.NoError(t, r.Register(Registration{Processor: noop, Stages: []Stage{StageValidation}}))
+	assert.Error(t, r.Register(Registration{Processor: noop, Stages: []Stage{StageEnrichment}}), "duplicate names are rejected")
+
+	assert.True(t, r.Unregister("noop"))
+	assert.False(t, r.Unregister("noop"))
+}
+
+func TestRun_EnrichmentAndPolicies(t *testing.T) {
+	r := NewRegistry()
+	assert.NoError(t, r.Register(Registration{
+		Processor: funcProcessor{name: "tagger", fn: func(_ context.Context, _ Stage, txn *model.Transaction) error {
+			txn.MetaData["risk"] = "low"
+			return nil
+		}},
+		Stages: []Stage{StageEnrichment, StageValidation},
+	}))
+
+	txn := &model.Transaction{MetaData: map[string]interface{}{}}
+	assert.NoError(t, r.Run(context.Background(), StageValidation, txn))
+	assert.NotContains(t, txn.MetaData, "risk", "changes outside enrichment are discarded")
+
+	assert.NoError(t, r.Run(context.Background(), StageEnrichment, txn))
+	assert.Equal(t, "low", txn.MetaData["risk"])
+
+	failing := func(context.Context, Stage, *model.Transaction) error { return errors.New("blocked") }
+	assert.NoError(t, r.Register(Registration{
+		Processor:     funcProcessor{name: "soft", fn: failing},
+		Stages:        []Stage{StageValidation},
+		FailurePolicy: FailOpen,
+	}))
+	assert.NoError(t, r.Run(context.Background(), StageValidation, txn))
+
+	assert.NoError(t, r.Register(Registration{
+		Processor: funcProcessor{name: "hard", fn: failing},
+		Stages:    []Stage{StageValidation, StagePostCommit},
+	}))
+	assert.ErrorContains(t, r.Run(context.Background(), StageValidation, txn), "blocked")
+	assert.NoError(t, r.Run(context.Background(), StagePostCommit, txn), "post-commit failures are never returned")
+}
+
+func TestRun_Timeout(t *testing.T) {
+	r := NewRegistry()
+	assert.NoError(t, r.Register(Registration{
+		Processor: funcProcessor{name: "slow", fn: func(ctx context.Context, _ Stage, _ *model.Transaction) error {
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}},
+		Stages:  []Stage{StageValidation},
+		Timeout: 20 * time.Millisecond,
+	}))
+
+	err := r.Run(context.Background(), StageValidation, &model.Transaction{})
+	assert.ErrorContains(t, err, "timed out")
+}
+
+func TestGRPCProcessor(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := grpc.NewServer(
+		grpc.ForceServerCodec(jsonCodec{}),
+		grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			assert.Equal(t, ProcessMethod, method)
+
+			var req ProcessRequest
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			if req.Transaction.Amount > 1000 {
+				return stream.SendMsg(&ProcessResponse{Error: "amount too large"})
+			}
+			req.Transaction.MetaData = map[string]interface{}{"enriched_by": "remote"}
+			return stream.SendMsg(&ProcessResponse{Transaction: req.Transaction})
+		}),
+	)
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	r := NewRegistry()
+	assert.NoError(t, LoadFromConfig(r, []config.PluginConfig{{
+		Name:      "remote",
+		Address:   lis.Addr().String(),
+		Stages:    []string{"validation", "enrichment"},
+		TimeoutMs: 2000,
+	}}))
+
+	txn := &model.Transaction{Amount: 10}
+	assert.NoError(t, r.Run(context.Background(), StageEnrichment, txn))
+	assert.Equal(t, "remote", txn.MetaData["enriched_by"])
+
+	err = r.Run(context.Background(), StageValidation, &model.Transaction{Amount: 5000})
+	assert.ErrorContains(t, err, "amount too large")
+}
@@ -0,0 +1,123 @@
+package blnk
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+const postingRulesCacheTTL = 30 * time.Second
+
+// postingRulesCache keeps the posting rules of ledgers in memory so that postings do not query them for every
+// transaction. Rules set or removed on another instance are picked up within postingRulesCacheTTL.
+type postingRulesCache struct {
+	mu       sync.Mutex
+	ledgers  map[string]*model.PostingRules // Keyed by ledger ID
+	loadedAt time.Time
+}
+
+// SetPostingRules sets how strictly the postings of a ledger are validated, replacing any rules already set
+// for it. The rules apply to every transaction to or from a balance of the ledger.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - rules model.PostingRules: The rules to set.
+//
+// Returns:
+// - *model.PostingRules: The saved rules.
+// - error: An error if the rules are invalid, the ledger does not exist or the rules cannot be saved.
+func (l *Blnk) SetPostingRules(ctx context.Context, rules model.PostingRules) (*model.PostingRules, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := l.datasource.GetLedgerByID(ctx, rules.LedgerID); err != nil {
+		return nil, err
+	}
+
+	rules.UpdatedAt = time.Now()
+	if err := l.datasource.SetPostingRules(ctx, &rules); err != nil {
+		return nil, err
+	}
+	l.invalidatePostingRules()
+	return &rules, nil
+}
+
+// GetPostingRules retrieves the posting rules of a ledger.
+func (l *Blnk) GetPostingRules(ctx context.Context, ledgerID string) (*model.PostingRules, error) {
+	return l.datasource.GetPostingRules(ctx, ledgerID)
+}
+
+// DeletePostingRules removes the posting rules of a ledger, so its postings are only checked by the usual
+// validation again.
+func (l *Blnk) DeletePostingRules(ctx context.Context, ledgerID string) error {
+	if err := l.datasource.DeletePostingRules(ctx, ledgerID); err != nil {
+		return err
+	}
+	l.invalidatePostingRules()
+	return nil
+}
+
+// postingRulesFor returns the posting rules of a ledger, or nil when it has none. The rules are reloaded when
+// the cache is stale, and a failed reload keeps the previous ones.
+func (l *Blnk) postingRulesFor(ctx context.Context, ledgerID string) *model.PostingRules {
+	if l.postingRules == nil {
+		return nil
+	}
+	l.postingRules.mu.Lock()
+	defer l.postingRules.mu.Unlock()
+
+	if time.Since(l.postingRules.loadedAt) >= postingRulesCacheTTL {
+		l.postingRules.loadedAt = time.Now()
+		list, err := l.datasource.ListPostingRules(ctx)
+		if err != nil {
+			logrus.WithError(err).Warn("failed to load posting rules")
+		} else {
+			l.postingRules.ledgers = make(map[string]*model.PostingRules, len(list))
+			for _, rules := range list {
+				l.postingRules.ledgers[rules.LedgerID] = rules
+			}
+		}
+	}
+	return l.postingRules.ledgers[ledgerID]
+}
+
+// invalidatePostingRules forces the next posting to reload the posting rules.
+func (l *Blnk) invalidatePostingRules() {
+	if l.postingRules == nil {
+		return
+	}
+	l.postingRules.mu.Lock()
+	l.postingRules.loadedAt = time.Time{}
+	l.postingRules.mu.Unlock()
+}
+
+// checkPostingRules validates a transaction against the posting rules of the ledgers of its source and
+// destination. A transaction between two ledgers must satisfy the rules of both.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - transaction *model.Transaction: The transaction being posted.
+// - source *model.Balance: The source balance of the transaction.
+// - destination *model.Balance: The destination balance of the transaction.
+//
+// Returns:
+// - error: A *model.PostingRuleError if the transaction breaks a rule, or nil.
+func (l *Blnk) checkPostingRules(ctx context.Context, transaction *model.Transaction, source, destination *model.Balance) error {
+	ledgers := []string{source.LedgerID}
+	if destination.LedgerID != source.LedgerID {
+		ledgers = append(ledgers, destination.LedgerID)
+	}
+	for _, ledgerID := range ledgers {
+		rules := l.postingRulesFor(ctx, ledgerID)
+		if rules == nil {
+			continue
+		}
+		if err := rules.Check(transaction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
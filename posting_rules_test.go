@@ -0,0 +1,68 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPostingRules(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("ListPostingRules", mock.Anything).Return([]*model.PostingRules{
+		{LedgerID: "ldg_prod", RequireDescription: true},
+		{LedgerID: "ldg_test", AllowZeroAmount: true, AllowSameSourceDestination: true},
+	}, nil).Once()
+
+	prod := &model.Balance{BalanceID: "bln_prod", LedgerID: "ldg_prod"}
+	test := &model.Balance{BalanceID: "bln_test", LedgerID: "ldg_test"}
+	other := &model.Balance{BalanceID: "bln_other", LedgerID: "ldg_other"}
+	zero := &model.Transaction{Source: "bln_test", Destination: "bln_test", PreciseAmount: big.NewInt(0)}
+
+	// The test ledger allows zero-amount transfers to the same balance, and ledgers without rules are not checked
+	assert.NoError(t, b.checkPostingRules(context.Background(), zero, test, test))
+	assert.NoError(t, b.checkPostingRules(context.Background(), &model.Transaction{PreciseAmount: big.NewInt(0)}, other, other))
+
+	// A transfer between ledgers must satisfy the rules of both
+	err := b.checkPostingRules(context.Background(), &model.Transaction{Source: "bln_test", Destination: "bln_prod", PreciseAmount: big.NewInt(100)}, test, prod)
+	var ruleErr *model.PostingRuleError
+	require.True(t, errors.As(err, &ruleErr))
+	assert.Equal(t, "ldg_prod", ruleErr.LedgerID)
+	assert.Equal(t, "require_description", ruleErr.Rule)
+	mockDS.AssertExpectations(t)
+}
+
+func TestSetPostingRules(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_1").Return(&model.Ledger{LedgerID: "ldg_1"}, nil)
+	mockDS.On("SetPostingRules", mock.Anything, mock.AnythingOfType("*model.PostingRules")).Return(nil)
+
+	rules, err := b.SetPostingRules(context.Background(), model.PostingRules{LedgerID: "ldg_1", ReferencePattern: `INV-\d+`})
+	require.NoError(t, err)
+	assert.False(t, rules.UpdatedAt.IsZero())
+
+	_, err = b.SetPostingRules(context.Background(), model.PostingRules{LedgerID: "ldg_1", ReferencePattern: "INV-("})
+	assert.Error(t, err)
+	mockDS.AssertNumberOfCalls(t, "SetPostingRules", 1)
+}
@@ -137,6 +137,40 @@ func (q *Queue) queueIndexData(id string, collection string, data interface{}) e
 	return nil
 }
 
+// queueIndexDeletion enqueues a task to remove a document from a collection, such as a deleted identity.
+//
+// Parameters:
+// - id string: The ID of the document to remove.
+// - collection string: The name of the collection to remove it from.
+//
+// Returns:
+// - error: An error if the task could not be enqueued.
+func (q *Queue) queueIndexDeletion(id string, collection string) error {
+	cfg, err := config.Fetch()
+	if err != nil {
+		return err
+	}
+
+	if cfg.TypeSense.Dns == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"collection": collection,
+		"delete_id":  id,
+	})
+	if err != nil {
+		return err
+	}
+
+	task := asynq.NewTask(cfg.Queue.IndexQueue, payload, asynq.Queue(cfg.Queue.IndexQueue))
+	if _, err := q.Client.Enqueue(task); err != nil {
+		return err
+	}
+	log.Printf(" [*] Successfully enqueued index deletion: %+v", id)
+	return nil
+}
+
 // Enqueue enqueues a transaction to the Redis queue.
 //
 // Parameters:
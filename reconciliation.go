@@ -39,7 +39,9 @@ import (
 
 	"github.com/blnkfinance/blnk/config"
 	"github.com/blnkfinance/blnk/database"
+	"github.com/blnkfinance/blnk/internal/featureflags"
 	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/internal/tenant"
 	"github.com/blnkfinance/blnk/model"
 	"github.com/texttheater/golang-levenshtein/levenshtein"
 	"github.com/wacul/ptr"
@@ -62,17 +64,25 @@ const (
 // - reconciler: A function that handles the reconciliation logic for a batch of transactions.
 // - matches: Counter for transactions that have been successfully matched.
 // - unmatched: Counter for transactions that couldn't be matched.
+// - pendingReview: Counter for matches held for review.
 // - datasource: The interface for database operations, enabling interaction with the data source.
 // - progressSaveCount: The number of transactions processed before saving progress.
+// - autoConfirmThreshold: The confidence at or above which a match is confirmed without review.
+// - reviewThreshold: The confidence at or above which a match is sent to the review queue; anything lower is unmatched.
+// - dryRun: Collects the report of a dry run in place of recording results; nil for regular runs.
 type transactionProcessor struct {
-	reconciliation    model.Reconciliation
-	progress          model.ReconciliationProgress
-	reconciler        reconciler
-	matches           int
-	unmatched         int
-	datasource        database.IDataSource
-	progressSaveCount int
-	blnk              *Blnk
+	reconciliation       model.Reconciliation
+	progress             model.ReconciliationProgress
+	reconciler           reconciler
+	matches              int
+	unmatched            int
+	pendingReview        int
+	datasource           database.IDataSource
+	progressSaveCount    int
+	autoConfirmThreshold float64
+	reviewThreshold      float64
+	dryRun               *dryRunCollector
+	blnk                 *Blnk
 }
 
 // reconciler defines the function type for reconciling a batch of transactions.
@@ -635,9 +645,10 @@ func (s *Blnk) StartReconciliation(ctx context.Context, uploadID string, strateg
 	if err := s.datasource.RecordReconciliation(ctx, &reconciliation); err != nil {
 		return "", err
 	}
+	s.notifyReconciliation(EventReconciliationStarted, reconciliation, 0, nil)
 
 	// Detach the context to allow the reconciliation process to run in the background.
-	detachedCtx := context.Background()
+	detachedCtx := tenant.WithTenant(context.Background(), tenant.FromContext(ctx))
 	ctxWithTrace := trace.ContextWithSpan(detachedCtx, trace.SpanFromContext(ctx))
 
 	// Start the reconciliation process asynchronously.
@@ -646,10 +657,7 @@ func (s *Blnk) StartReconciliation(ctx context.Context, uploadID string, strateg
 		if err != nil {
 			// If an error occurs during the reconciliation, log it and update the reconciliation status to "failed".
 			log.Printf("Error in reconciliation process: %v", err)
-			err := s.datasource.UpdateReconciliationStatus(ctxWithTrace, reconciliationID, StatusFailed, 0, 0)
-			if err != nil {
-				log.Printf("Error updating reconciliation status: %v", err)
-			}
+			_ = s.failReconciliation(ctxWithTrace, reconciliation, err)
 		}
 	}()
 
@@ -671,16 +679,36 @@ func (s *Blnk) StartReconciliation(ctx context.Context, uploadID string, strateg
 func (s *Blnk) StartInstantReconciliation(ctx context.Context, externalTransactions []model.ExternalTransaction,
 	strategy string, groupCriteria string, matchingRuleIDs []string, isDryRun bool,
 ) (string, error) {
-	// Generate a unique ID for the reconciliation
-	reconciliationID := model.GenerateUUIDWithSuffix("recon")
-
 	// Use a temporary ID for the transactions
 	tempID := model.GenerateUUIDWithSuffix("instant")
 
+	return s.startReconciliationWithTransactions(ctx, tempID, externalTransactions, strategy, groupCriteria, matchingRuleIDs, isDryRun)
+}
+
+// startReconciliationWithTransactions records the provided external transactions under the given upload ID and
+// starts a reconciliation over them in the background. It backs every reconciliation whose external records are
+// not loaded from an uploaded file.
+// Parameters:
+// - ctx: The context controlling the reconciliation process.
+// - uploadID: The ID under which the external transactions are stored.
+// - externalTransactions: The external transactions to reconcile.
+// - strategy: The reconciliation strategy to be used (e.g., "one_to_one").
+// - groupCriteria: Criteria to group transactions (optional).
+// - matchingRuleIDs: The IDs of the rules used for matching transactions.
+// - isDryRun: If true, the reconciliation will not commit changes (useful for testing).
+// Returns:
+// - string: The ID of the reconciliation process.
+// - error: If the reconciliation fails to start.
+func (s *Blnk) startReconciliationWithTransactions(ctx context.Context, uploadID string, externalTransactions []model.ExternalTransaction,
+	strategy string, groupCriteria string, matchingRuleIDs []string, isDryRun bool,
+) (string, error) {
+	// Generate a unique ID for the reconciliation
+	reconciliationID := model.GenerateUUIDWithSuffix("recon")
+
 	// Initialize a new reconciliation object with the provided parameters
 	reconciliation := model.Reconciliation{
 		ReconciliationID: reconciliationID,
-		UploadID:         tempID,
+		UploadID:         uploadID,
 		Status:           StatusStarted,
 		StartedAt:        time.Now(),
 		IsDryRun:         isDryRun,
@@ -690,23 +718,23 @@ func (s *Blnk) StartInstantReconciliation(ctx context.Context, externalTransacti
 	if err := s.datasource.RecordReconciliation(ctx, &reconciliation); err != nil {
 		return "", err
 	}
+	s.notifyReconciliation(EventReconciliationStarted, reconciliation, 0, nil)
 
-	// Store the provided transactions in the database with the temporary ID
+	// Store the provided transactions in the database with the upload ID
 	for _, txn := range externalTransactions {
-		if err := s.storeExternalTransaction(ctx, tempID, txn); err != nil {
+		if err := s.storeExternalTransaction(ctx, uploadID, txn); err != nil {
 			// Log error and update reconciliation status
 			log.Printf("Error storing transaction: %v", err)
-			err := s.datasource.UpdateReconciliationStatus(ctx, reconciliationID, StatusFailed, 0, 0)
-			if err != nil {
-				log.Printf("Error updating reconciliation status: %v", err)
-				return "", fmt.Errorf("failed to store external transaction: %w", err)
+			err = fmt.Errorf("failed to store external transaction: %w", err)
+			if failErr := s.failReconciliation(ctx, reconciliation, err); failErr != nil {
+				return "", fmt.Errorf("failed to store external transaction: %w", failErr)
 			}
-			return "", fmt.Errorf("failed to store external transaction: %w", err)
+			return "", err
 		}
 	}
 
 	// Detach the context to allow the reconciliation process to run in the background
-	detachedCtx := context.Background()
+	detachedCtx := tenant.WithTenant(context.Background(), tenant.FromContext(ctx))
 	ctxWithTrace := trace.ContextWithSpan(detachedCtx, trace.SpanFromContext(ctx))
 
 	// Start the reconciliation process asynchronously
@@ -715,10 +743,7 @@ func (s *Blnk) StartInstantReconciliation(ctx context.Context, externalTransacti
 		if err != nil {
 			// If an error occurs during the reconciliation, log it and update the reconciliation status to "failed"
 			log.Printf("Error in instant reconciliation process: %v", err)
-			err := s.datasource.UpdateReconciliationStatus(ctxWithTrace, reconciliationID, StatusFailed, 0, 0)
-			if err != nil {
-				log.Printf("Error updating reconciliation status: %v", err)
-			}
+			_ = s.failReconciliation(ctxWithTrace, reconciliation, err)
 		}
 	}()
 
@@ -741,6 +766,68 @@ func (s *Blnk) GetReconciliation(ctx context.Context, reconciliationID string) (
 	return reconciliation, nil
 }
 
+// GetReconciliationReviewQueue lists the matches of a reconciliation whose confidence fell between the review
+// and auto-confirm thresholds and are waiting for a reviewer.
+// Parameters:
+// - ctx: The context controlling the request.
+// - reconciliationID: The ID of the reconciliation.
+// Returns:
+// - []*model.Match: The matches pending review, highest confidence first.
+// - error: If the matches cannot be retrieved.
+func (s *Blnk) GetReconciliationReviewQueue(ctx context.Context, reconciliationID string) ([]*model.Match, error) {
+	return s.datasource.GetMatchesByStatus(ctx, reconciliationID, model.MatchStatusPendingReview)
+}
+
+// ReviewMatch resolves a match from the review queue. Approved matches are confirmed and the internal
+// transaction is tagged as reconciled; declined matches are marked as rejected.
+// Parameters:
+// - ctx: The context controlling the request.
+// - reconciliationID: The ID of the reconciliation the match belongs to.
+// - externalTxnID: The external transaction ID of the match.
+// - internalTxnID: The internal transaction ID of the match.
+// - approve: True to confirm the match, false to reject it.
+// Returns:
+// - error: If the match cannot be found or updated.
+func (s *Blnk) ReviewMatch(ctx context.Context, reconciliationID, externalTxnID, internalTxnID string, approve bool) error {
+	status := model.MatchStatusRejected
+	if approve {
+		status = model.MatchStatusConfirmed
+	}
+
+	if err := s.datasource.UpdateMatchStatus(ctx, reconciliationID, externalTxnID, internalTxnID, status); err != nil {
+		return err
+	}
+	s.notifyExceptionResolved(reconciliationID, externalTxnID, internalTxnID, status)
+
+	if approve {
+		metadata := map[string]interface{}{
+			"reconciled":        true,
+			"reconciliation_id": reconciliationID,
+			"reconciled_at":     time.Now().Format(time.RFC3339),
+			"external_txn_id":   externalTxnID,
+		}
+		if err := s.updateEntityMetadata(ctx, "transactions", internalTxnID, metadata); err != nil {
+			log.Printf("Error updating metadata for transaction %s: %v", internalTxnID, err)
+		}
+	}
+
+	return nil
+}
+
+// GetMatchScoreDistribution returns the distribution of match confidence scores for a reconciliation run.
+// Parameters:
+// - ctx: The context controlling the request.
+// - reconciliationID: The ID of the reconciliation.
+// Returns:
+// - *model.ScoreDistribution: The score buckets and per-status totals for the run.
+// - error: If the distribution cannot be computed.
+func (s *Blnk) GetMatchScoreDistribution(ctx context.Context, reconciliationID string) (*model.ScoreDistribution, error) {
+	if _, err := s.GetReconciliation(ctx, reconciliationID); err != nil {
+		return nil, err
+	}
+	return s.datasource.GetMatchScoreDistribution(ctx, reconciliationID)
+}
+
 // matchesRules checks whether an external transaction matches a group transaction based on specified matching rules.
 // It iterates through the rules and criteria, evaluating whether the transactions meet the conditions for a match.
 // Parameters:
@@ -787,6 +874,98 @@ func (s *Blnk) matchesRules(externalTxn *model.Transaction, groupTxn model.Trans
 	return false
 }
 
+// scoreMatch computes a confidence score for a candidate match. Every rule whose criteria are all met is
+// scored as the average of its per-criterion scores, and the best scoring rule wins.
+// Parameters:
+// - externalTxn: The external transaction to match.
+// - groupTxn: The internal or group transaction to compare against.
+// - rules: A list of matching rules containing criteria to apply.
+// Returns:
+// - float64: A score between 0 and 1, where 0 means no rule matched.
+func (s *Blnk) scoreMatch(externalTxn *model.Transaction, groupTxn model.Transaction, rules []model.MatchingRule) float64 {
+	best := 0.0
+	for _, rule := range rules {
+		if len(rule.Criteria) == 0 || !s.matchesRules(externalTxn, groupTxn, []model.MatchingRule{rule}) {
+			continue
+		}
+
+		total := 0.0
+		for _, criteria := range rule.Criteria {
+			total += s.scoreCriterion(externalTxn, groupTxn, criteria)
+		}
+		if score := total / float64(len(rule.Criteria)); score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// scoreCriterion scores how closely a single criterion was met, assuming it has already been satisfied.
+// Exact matches score 1; matches that rely on the allowable drift score lower the more drift they consume.
+// Parameters:
+// - externalTxn: The external transaction being matched.
+// - groupTxn: The internal or group transaction being compared against.
+// - criteria: The criterion to score.
+// Returns:
+// - float64: A score between 0.5 and 1.
+func (s *Blnk) scoreCriterion(externalTxn *model.Transaction, groupTxn model.Transaction, criteria model.MatchingCriteria) float64 {
+	if criteria.Operator != "equals" && criteria.Operator != "contains" {
+		// Ordering comparisons are either met or not, so they carry full weight once satisfied.
+		return 1
+	}
+
+	switch criteria.Field {
+	case "amount":
+		return driftScore(math.Abs(externalTxn.Amount-groupTxn.Amount), math.Abs(groupTxn.Amount*criteria.AllowableDrift))
+	case "date":
+		return driftScore(math.Abs(externalTxn.CreatedAt.Sub(groupTxn.CreatedAt).Seconds()), criteria.AllowableDrift)
+	case "description":
+		return s.scoreString(externalTxn.Description, groupTxn.Description)
+	case "reference":
+		return s.scoreString(externalTxn.Reference, groupTxn.Reference)
+	case "currency":
+		if groupTxn.Currency == "MIXED" {
+			return 0.5
+		}
+		return 1
+	}
+	return 1
+}
+
+// driftScore converts the deviation of a matched value into a score, where no deviation scores 1 and
+// a deviation equal to the full allowable drift scores 0.5.
+func driftScore(deviation, allowed float64) float64 {
+	if deviation == 0 || allowed <= 0 {
+		return 1
+	}
+	return 1 - 0.5*math.Min(deviation/allowed, 1)
+}
+
+// scoreString scores the similarity between an external value and the best matching part of an internal value.
+// Case-insensitive equality scores 1, containment scores at least 0.8, and anything else is scored by
+// normalised Levenshtein similarity.
+func (s *Blnk) scoreString(externalValue, internalValue string) float64 {
+	best := 0.0
+	external := strings.ToLower(externalValue)
+	for _, part := range strings.Split(internalValue, " | ") {
+		part = strings.ToLower(part)
+		maxLength := float64(max(len(external), len(part)))
+		if maxLength == 0 || external == part {
+			return 1
+		}
+
+		distance := levenshtein.DistanceForStrings([]rune(external), []rune(part), levenshtein.DefaultOptions)
+		score := 1 - float64(distance)/maxLength
+		if strings.Contains(external, part) || strings.Contains(part, external) {
+			score = 0.8 + 0.2*score
+		}
+		if score > best {
+			best = score
+		}
+	}
+	return math.Max(best, 0.5)
+}
+
 // loadReconciliationProgress retrieves the progress of a reconciliation process.
 // Parameters:
 // - ctx: The context controlling the request.
@@ -830,7 +1009,7 @@ func (s *Blnk) processReconciliation(ctx context.Context, reconciliation model.R
 	reconciler := s.createReconciler(strategy, groupCriteria, matchingRules)
 
 	// Create a transaction processor to handle the reconciliation logic.
-	processor := s.createTransactionProcessor(reconciliation, progress, reconciler)
+	processor := s.createTransactionProcessor(ctx, reconciliation, progress, reconciler)
 
 	// Process the transactions for reconciliation based on the chosen strategy.
 	err = s.processTransactions(ctx, reconciliation.UploadID, processor, strategy)
@@ -841,8 +1020,15 @@ func (s *Blnk) processReconciliation(ctx context.Context, reconciliation model.R
 	// After processing, retrieve the results (matched and unmatched counts).
 	matched, unmatched := processor.getResults()
 
+	// A dry run leaves no match records behind, so its report is stored for later retrieval.
+	if processor.dryRun != nil {
+		if err := s.saveDryRunReport(ctx, processor.dryRun.report(reconciliation.ReconciliationID)); err != nil {
+			return fmt.Errorf("failed to save dry run report: %w", err)
+		}
+	}
+
 	// Finalize the reconciliation by updating the status and recording the results.
-	return s.finalizeReconciliation(ctx, reconciliation, matched, unmatched)
+	return s.finalizeReconciliation(ctx, reconciliation, matched, unmatched, processor.pendingReview)
 }
 
 // updateReconciliationStatus updates the status of a reconciliation process in the database.
@@ -901,24 +1087,38 @@ func (s *Blnk) createReconciler(strategy string, groupCriteria string, matchingR
 }
 
 // createTransactionProcessor creates a new transaction processor for the reconciliation.
+// Confidence thresholds only apply when the confidence scoring feature is enabled for the tenant;
+// otherwise every match is confirmed.
 // Parameters:
+// - ctx: The context, carrying the tenant used to evaluate feature flags.
 // - reconciliation: The reconciliation object representing the current process.
 // - progress: The current progress of the reconciliation.
 // - reconciler: The reconciler function to apply.
 // Returns:
 // - *transactionProcessor: The created transaction processor.
-func (s *Blnk) createTransactionProcessor(reconciliation model.Reconciliation, progress model.ReconciliationProgress, reconciler func(ctx context.Context, txns []*model.Transaction) ([]model.Match, []string)) *transactionProcessor {
+func (s *Blnk) createTransactionProcessor(ctx context.Context, reconciliation model.Reconciliation, progress model.ReconciliationProgress, reconciler func(ctx context.Context, txns []*model.Transaction) ([]model.Match, []string)) *transactionProcessor {
 	conf, err := config.Fetch()
 	if err != nil {
 		log.Printf("Error fetching configuration: %v", err)
 	}
+	autoConfirmThreshold, reviewThreshold := conf.Reconciliation.Thresholds()
+	if !s.IsFeatureEnabled(ctx, featureflags.ReconciliationConfidenceScoring) {
+		autoConfirmThreshold, reviewThreshold = 0, 0
+	}
+	var dryRun *dryRunCollector
+	if reconciliation.IsDryRun {
+		dryRun = &dryRunCollector{}
+	}
 	return &transactionProcessor{
-		reconciliation:    reconciliation,
-		progress:          progress,
-		reconciler:        reconciler,
-		datasource:        s.datasource,
-		progressSaveCount: conf.Reconciliation.ProgressInterval,
-		blnk:              s,
+		reconciliation:       reconciliation,
+		progress:             progress,
+		reconciler:           reconciler,
+		datasource:           s.datasource,
+		progressSaveCount:    conf.Reconciliation.ProgressInterval,
+		autoConfirmThreshold: autoConfirmThreshold,
+		reviewThreshold:      reviewThreshold,
+		dryRun:               dryRun,
+		blnk:                 s,
 	}
 }
 
@@ -933,20 +1133,32 @@ func (tp *transactionProcessor) process(ctx context.Context, txn *model.Transact
 	// Reconcile the batch of transactions and get matches and unmatched transactions.
 	batchMatches, batchUnmatched := tp.reconciler(ctx, []*model.Transaction{txn})
 
+	// Route each match by its confidence score; low-confidence matches are treated as unmatched.
+	scoredMatches := batchMatches
+	batchMatches, lowConfidence := tp.classifyMatches(batchMatches)
+	batchUnmatched = append(batchUnmatched, lowConfidence...)
+
 	// Increment the counters for matched and unmatched transactions.
 	tp.matches += len(batchMatches)
 	tp.unmatched += len(batchUnmatched)
+	for _, match := range batchMatches {
+		if match.Status == model.MatchStatusPendingReview {
+			tp.pendingReview++
+		}
+	}
 
-	// If the reconciliation is not a dry run, record the matches and unmatched transactions.
-	if !tp.reconciliation.IsDryRun {
+	// A dry run only collects the results for its report; otherwise record the matches and unmatched transactions.
+	if tp.dryRun != nil {
+		tp.dryRun.record(scoredMatches, batchMatches, batchUnmatched)
+	} else if !tp.reconciliation.IsDryRun {
 		if len(batchMatches) > 0 {
 			// Record the matched transactions.
 			if err := tp.datasource.RecordMatches(ctx, tp.reconciliation.ReconciliationID, batchMatches); err != nil {
 				return err
 			}
 
-			// Asynchronously update the metadata for each matched internal transaction
-			go tp.updateMatchedTransactionsMetadata(context.Background(), batchMatches)
+			// Asynchronously update the metadata for each confirmed internal transaction
+			go tp.updateMatchedTransactionsMetadata(context.Background(), confirmedMatches(batchMatches))
 		}
 
 		if len(batchUnmatched) > 0 {
@@ -971,6 +1183,49 @@ func (tp *transactionProcessor) process(ctx context.Context, txn *model.Transact
 	return nil
 }
 
+// classifyMatches assigns a status to each match based on its confidence score and the processor's thresholds.
+// Matches at or above the auto-confirm threshold are confirmed, matches at or above the review threshold are
+// held for review, and the external transactions of all remaining matches are reported as unmatched.
+// Parameters:
+// - matches: The scored matches produced by the reconciler.
+// Returns:
+// - []model.Match: The matches to record, with their status set.
+// - []string: The external transaction IDs whose matches fell below the review threshold.
+func (tp *transactionProcessor) classifyMatches(matches []model.Match) ([]model.Match, []string) {
+	kept := make([]model.Match, 0, len(matches))
+	var rejected []string
+	seen := make(map[string]bool)
+
+	for _, match := range matches {
+		switch {
+		case match.Confidence >= tp.autoConfirmThreshold:
+			match.Status = model.MatchStatusConfirmed
+		case match.Confidence >= tp.reviewThreshold:
+			match.Status = model.MatchStatusPendingReview
+		default:
+			if !seen[match.ExternalTransactionID] {
+				seen[match.ExternalTransactionID] = true
+				rejected = append(rejected, match.ExternalTransactionID)
+			}
+			continue
+		}
+		kept = append(kept, match)
+	}
+
+	return kept, rejected
+}
+
+// confirmedMatches filters a slice of matches down to those that have been confirmed.
+func confirmedMatches(matches []model.Match) []model.Match {
+	var confirmed []model.Match
+	for _, match := range matches {
+		if match.Status == model.MatchStatusConfirmed {
+			confirmed = append(confirmed, match)
+		}
+	}
+	return confirmed
+}
+
 // updateMatchedTransactionsMetadata updates the metadata for internal transactions that were matched.
 // This function adds reconciliation information to the internal transaction's metadata.
 // Parameters:
@@ -985,6 +1240,7 @@ func (tp *transactionProcessor) updateMatchedTransactionsMetadata(ctx context.Co
 			"reconciled_at":         time.Now().Format(time.RFC3339),
 			"external_txn_id":       match.ExternalTransactionID,
 			"reconciliation_amount": match.Amount,
+			"match_confidence":      match.Confidence,
 		}
 
 		// Update the internal transaction's metadata
@@ -1059,9 +1315,10 @@ func (s *Blnk) processTransactions(ctx context.Context, uploadID string, process
 // - reconciliation: The reconciliation object representing the current process.
 // - matchCount: The number of matched transactions.
 // - unmatchedCount: The number of unmatched transactions.
+// - pendingReview: The number of matches held for review.
 // Returns:
 // - error: If any error occurs during finalization.
-func (s *Blnk) finalizeReconciliation(ctx context.Context, reconciliation model.Reconciliation, matchCount, unmatchedCount int) error {
+func (s *Blnk) finalizeReconciliation(ctx context.Context, reconciliation model.Reconciliation, matchCount, unmatchedCount, pendingReview int) error {
 	// Update the reconciliation status to "completed".
 	reconciliation.Status = StatusCompleted
 	reconciliation.UnmatchedTransactions = unmatchedCount
@@ -1084,6 +1341,7 @@ func (s *Blnk) finalizeReconciliation(ctx context.Context, reconciliation model.
 	}
 
 	log.Printf("Reconciliation %s completed. Total matches: %d, Total unmatched: %d", reconciliation.ReconciliationID, matchCount, unmatchedCount)
+	s.notifyReconciliation(EventReconciliationCompleted, reconciliation, pendingReview, nil)
 
 	return nil
 }
@@ -1235,13 +1493,13 @@ func (s *Blnk) manyToOneReconciliation(ctx context.Context, internalTxns []*mode
 // Returns:
 // - error: If any error occurs during processing.
 func (s *Blnk) manyToOne(ctx context.Context, internalTxns []*model.Transaction, matchingRules []model.MatchingRule, isExternalGrouped bool, wg *sync.WaitGroup, groupingCriteria string, batchSize int, matchChan chan model.Match, unMatchChan chan string) error {
-	offset := int64(0)
+	page := model.Page{Limit: batchSize}
 	ctx, span := otel.Tracer("blnk.reconciliation").Start(ctx, "ProcessManyToOne")
 	defer span.End()
 
 	// Loop to process transactions in batches.
 	for {
-		groupedExternalTxns, err := s.groupExternalTransactions(ctx, groupingCriteria, batchSize, offset)
+		groupedExternalTxns, err := s.groupExternalTransactions(ctx, groupingCriteria, page)
 		if err != nil {
 			log.Printf("Error grouping external transactions: %v", err)
 			break
@@ -1258,7 +1516,7 @@ func (s *Blnk) manyToOne(ctx context.Context, internalTxns []*model.Transaction,
 		if len(groupMap) == 0 {
 			break
 		}
-		offset += int64(batchSize)
+		page.After = lastGroupedPosition(groupedExternalTxns)
 	}
 	return nil
 }
@@ -1268,13 +1526,12 @@ func (s *Blnk) manyToOne(ctx context.Context, internalTxns []*model.Transaction,
 // Parameters:
 // - ctx: The context controlling the operation.
 // - groupingCriteria: The criteria to group transactions.
-// - batchSize: The number of transactions to process in each batch.
-// - offset: The offset for pagination.
+// - page: The page of transactions to group, after the cursor of the previous one.
 // Returns:
 // - map[string][]*model.Transaction: A map of grouped transactions.
 // - error: If there is an error retrieving the transactions.
-func (s *Blnk) groupExternalTransactions(ctx context.Context, groupingCriteria string, batchSize int, offset int64) (map[string][]*model.Transaction, error) {
-	return s.datasource.FetchAndGroupExternalTransactions(ctx, "", groupingCriteria, batchSize, offset)
+func (s *Blnk) groupExternalTransactions(ctx context.Context, groupingCriteria string, page model.Page) (map[string][]*model.Transaction, error) {
+	return s.datasource.FetchAndGroupExternalTransactions(ctx, "", groupingCriteria, page)
 }
 
 // processGroupedTransactions handles the matching of transactions for both one-to-many and many-to-one reconciliations.
@@ -1318,7 +1575,7 @@ func (s *Blnk) processGroupedTransactions(singleTxns []*model.Transaction, group
 // - bool: True if a match is found, false otherwise.
 func (s *Blnk) matchSingleTransaction(singleTxn *model.Transaction, groupedTxns map[string][]*model.Transaction, groupMap map[string]bool, matchingRules []model.MatchingRule, isExternalGrouped bool, matchChan chan model.Match) bool {
 	for groupKey := range groupMap {
-		if s.matchesGroup(singleTxn, groupedTxns[groupKey], matchingRules) {
+		if confidence := s.scoreGroup(singleTxn, groupedTxns[groupKey], matchingRules); confidence > 0 {
 			for _, groupedTxn := range groupedTxns[groupKey] {
 				var externalID, internalID string
 				if isExternalGrouped {
@@ -1334,6 +1591,7 @@ func (s *Blnk) matchSingleTransaction(singleTxn *model.Transaction, groupedTxns
 					InternalTransactionID: internalID,
 					Amount:                groupedTxn.Amount,
 					Date:                  groupedTxn.CreatedAt,
+					Confidence:            confidence,
 				}
 			}
 			delete(groupMap, groupKey) // Mark the group as processed.
@@ -1348,12 +1606,12 @@ func (s *Blnk) matchSingleTransaction(singleTxn *model.Transaction, groupedTxns
 // Returns:
 // - error: If any error occurs during processing.
 func (s *Blnk) oneToMany(ctx context.Context, singleTxn []*model.Transaction, matchingRules []model.MatchingRule, isExternalGrouped bool, wg *sync.WaitGroup, groupingCriteria string, batchSize int, matchChan chan model.Match, unMatchChan chan string) error {
-	offset := int64(0)
+	page := model.Page{Limit: batchSize}
 	ctx, span := otel.Tracer("blnk.reconciliation").Start(ctx, "ProcessOneToMany")
 	defer span.End()
 
 	for {
-		txns, err := s.groupInternalTransactions(ctx, groupingCriteria, batchSize, offset)
+		txns, err := s.groupInternalTransactions(ctx, groupingCriteria, page)
 		if err != nil {
 			log.Printf("Error grouping internal transactions: %v", err)
 			break
@@ -1370,7 +1628,7 @@ func (s *Blnk) oneToMany(ctx context.Context, singleTxn []*model.Transaction, ma
 		if len(groupMap) == 0 {
 			break
 		}
-		offset += int64(batchSize)
+		page.After = lastGroupedPosition(txns)
 	}
 	return nil
 }
@@ -1389,12 +1647,34 @@ func (s *Blnk) buildGroupMap(groupedTxns map[string][]*model.Transaction) map[st
 }
 
 // groupInternalTransactions groups internal transactions based on the specified criteria for reconciliation.
-// Parameters are the same as `groupExternalTransactions`.
+// Parameters:
+// - ctx: The context controlling the request.
+// - groupingCriteria: The field to group transactions by.
+// - page: The page of transactions to group, after the cursor of the previous one.
 // Returns:
 // - map[string][]*model.Transaction: A map of grouped internal transactions.
 // - error: If any error occurs during grouping.
-func (s *Blnk) groupInternalTransactions(ctx context.Context, groupingCriteria string, batchSize int, offset int64) (map[string][]*model.Transaction, error) {
-	return s.datasource.GroupTransactions(ctx, groupingCriteria, batchSize, offset)
+func (s *Blnk) groupInternalTransactions(ctx context.Context, groupingCriteria string, page model.Page) (map[string][]*model.Transaction, error) {
+	return s.datasource.GroupTransactions(ctx, groupingCriteria, page)
+}
+
+// lastGroupedPosition returns the position of the last transaction read into a page of grouped transactions,
+// which are read oldest first, for the next page to start after it.
+// Parameters:
+// - groupedTxns: The grouped transactions of the page.
+// Returns:
+// - *model.PageCursor: The position of the newest transaction of the page, or nil if it is empty.
+func lastGroupedPosition(groupedTxns map[string][]*model.Transaction) *model.PageCursor {
+	var last *model.PageCursor
+	for _, group := range groupedTxns {
+		for _, txn := range group {
+			position := txn.PageCursor()
+			if last == nil || position.Time.After(last.Time) || (position.Time.Equal(last.Time) && position.ID > last.ID) {
+				last = &position
+			}
+		}
+	}
+	return last
 }
 
 // findMatchingInternalTransaction attempts to find an internal transaction that matches the given external transaction.
@@ -1429,12 +1709,13 @@ func (s *Blnk) findMatchingInternalTransaction(ctx context.Context, externalTxn
 				case <-ctx.Done():
 					return
 				default:
-					if s.matchesRules(externalTxn, *internalTxn, matchingRules) {
+					if confidence := s.scoreMatch(externalTxn, *internalTxn, matchingRules); confidence > 0 {
 						matchChan <- model.Match{
 							ExternalTransactionID: externalTxn.TransactionID,
 							InternalTransactionID: internalTxn.TransactionID,
 							Amount:                externalTxn.Amount,
 							Date:                  externalTxn.CreatedAt,
+							Confidence:            confidence,
 						}
 						matchFound = true
 						return
@@ -1462,14 +1743,13 @@ func (s *Blnk) findMatchingInternalTransaction(ctx context.Context, externalTxn
 // Parameters:
 // - ctx: The context controlling the request.
 // - uploadID: The ID of the upload to retrieve transactions for.
-// - limit: The maximum number of transactions to retrieve.
-// - offset: The offset for pagination.
+// - page: The batch to retrieve, after the cursor of the previous one.
 // Returns:
 // - []*model.Transaction: A list of external transactions converted to internal transactions.
 // - error: If any error occurs during retrieval.
-func (s *Blnk) getExternalTransactionsPaginated(ctx context.Context, uploadID string, limit int, offset int64) ([]*model.Transaction, error) {
-	log.Printf("Fetching external transactions: uploadID=%s, limit=%d, offset=%d", uploadID, limit, offset)
-	externalTransaction, err := s.datasource.GetExternalTransactionsPaginated(ctx, uploadID, limit, int64(offset))
+func (s *Blnk) getExternalTransactionsPaginated(ctx context.Context, uploadID string, page model.Page) ([]*model.Transaction, error) {
+	log.Printf("Fetching external transactions: uploadID=%s, limit=%d", uploadID, page.Limit)
+	externalTransaction, err := s.datasource.GetExternalTransactionsPaginated(ctx, uploadID, page)
 	if err != nil {
 		log.Printf("Error fetching external transactions: %v", err)
 		return nil, err
@@ -1487,13 +1767,12 @@ func (s *Blnk) getExternalTransactionsPaginated(ctx context.Context, uploadID st
 // Parameters:
 // - ctx: The context controlling the request.
 // - id: The ID of the internal transactions to retrieve.
-// - limit: The maximum number of transactions to retrieve.
-// - offset: The offset for pagination.
+// - page: The batch to retrieve, after the cursor of the previous one.
 // Returns:
 // - []*model.Transaction: A list of internal transactions.
 // - error: If any error occurs during retrieval.
-func (s *Blnk) getInternalTransactionsPaginated(ctx context.Context, id string, limit int, offset int64) ([]*model.Transaction, error) {
-	return s.datasource.GetTransactionsPaginated(ctx, "", limit, offset)
+func (s *Blnk) getInternalTransactionsPaginated(ctx context.Context, id string, page model.Page) ([]*model.Transaction, error) {
+	return s.datasource.GetTransactionsPaginated(ctx, "", page)
 }
 
 // matchesGroup compares a group of internal transactions with a single external transaction using matching rules.
@@ -1505,6 +1784,28 @@ func (s *Blnk) getInternalTransactionsPaginated(ctx context.Context, id string,
 // Returns:
 // - bool: True if the group matches the external transaction, false otherwise.
 func (s *Blnk) matchesGroup(externalTxn *model.Transaction, group []*model.Transaction, matchingRules []model.MatchingRule) bool {
+	return s.scoreGroup(externalTxn, group, matchingRules) > 0
+}
+
+// scoreGroup scores how well a group of transactions matches a single transaction using the matching rules.
+// The group is collapsed into a virtual transaction before scoring.
+// Parameters:
+// - externalTxn: The external transaction to compare.
+// - group: The group of internal transactions to compare against.
+// - matchingRules: The rules for matching transactions.
+// Returns:
+// - float64: The match confidence between 0 and 1, where 0 means the group does not match.
+func (s *Blnk) scoreGroup(externalTxn *model.Transaction, group []*model.Transaction, matchingRules []model.MatchingRule) float64 {
+	return s.scoreMatch(externalTxn, s.buildGroupTransaction(group), matchingRules)
+}
+
+// buildGroupTransaction collapses a group of transactions into a single virtual transaction whose amount is
+// the group total, whose date is the earliest in the group, and whose text fields are the joined values.
+// Parameters:
+// - group: The group of transactions to collapse.
+// Returns:
+// - model.Transaction: The virtual transaction representing the group.
+func (s *Blnk) buildGroupTransaction(group []*model.Transaction) model.Transaction {
 	var totalAmount float64
 	var minDate, maxDate time.Time
 	descriptions := make([]string, 0, len(group))
@@ -1528,16 +1829,13 @@ func (s *Blnk) matchesGroup(externalTxn *model.Transaction, group []*model.Trans
 	}
 
 	// Create a virtual transaction representing the group for comparison.
-	groupTxn := model.Transaction{
+	return model.Transaction{
 		Amount:      totalAmount,
 		CreatedAt:   minDate, // Use the earliest date in the group.
 		Description: strings.Join(descriptions, " | "),
 		Reference:   strings.Join(references, " | "),
 		Currency:    s.dominantCurrency(currencies), // Determine the dominant currency in the group.
 	}
-
-	// Use the matching rules to compare the group with the external transaction.
-	return s.matchesRules(externalTxn, groupTxn, matchingRules)
 }
 
 // dominantCurrency returns the dominant currency in a group of transactions.
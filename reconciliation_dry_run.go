@@ -0,0 +1,185 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	dryRunReportKeyPrefix = "reconciliation-dry-run"
+	dryRunReportTTL       = 7 * 24 * time.Hour
+	scoreBucketCount      = 10
+)
+
+// dryRunCollector accumulates the results of a dry-run reconciliation in memory instead of recording them.
+// It is shared by the batch workers, so all access goes through the mutex.
+type dryRunCollector struct {
+	mu         sync.Mutex
+	matches    []model.Match
+	exceptions []string
+}
+
+// record adds the outcome of one batch to the collector.
+// Scored matches that did not survive classification are kept in the report with a rejected status.
+//
+// Parameters:
+// - scored: Every match the reconciler produced for the batch.
+// - kept: The matches that met the review threshold, with their status set.
+// - unmatched: The external transaction IDs left without an acceptable match.
+func (c *dryRunCollector) record(scored, kept []model.Match, unmatched []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keptPairs := make(map[string]bool, len(kept))
+	for _, match := range kept {
+		keptPairs[match.ExternalTransactionID+":"+match.InternalTransactionID] = true
+	}
+	c.matches = append(c.matches, kept...)
+	for _, match := range scored {
+		if !keptPairs[match.ExternalTransactionID+":"+match.InternalTransactionID] {
+			match.Status = model.MatchStatusRejected
+			c.matches = append(c.matches, match)
+		}
+	}
+	c.exceptions = append(c.exceptions, unmatched...)
+}
+
+// report builds the dry-run report from everything collected so far.
+//
+// Parameters:
+// - reconciliationID: The ID of the dry-run reconciliation.
+//
+// Returns:
+// - *model.DryRunReport: The report.
+func (c *dryRunCollector) report(reconciliationID string) *model.DryRunReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	matches := make([]model.Match, len(c.matches))
+	for i, match := range c.matches {
+		match.ReconciliationID = reconciliationID
+		matches[i] = match
+	}
+	exceptions := append([]string{}, c.exceptions...)
+
+	return &model.DryRunReport{
+		ReconciliationID: reconciliationID,
+		Matches:          matches,
+		Exceptions:       exceptions,
+		Summary:          summarizeMatches(reconciliationID, matches),
+		GeneratedAt:      time.Now(),
+	}
+}
+
+// summarizeMatches computes a score distribution for matches held in memory.
+// Buckets and totals mirror the distribution computed for persisted runs.
+//
+// Parameters:
+// - reconciliationID: The ID of the reconciliation.
+// - matches: The matches to summarise.
+//
+// Returns:
+// - model.ScoreDistribution: The score buckets and per-status totals.
+func summarizeMatches(reconciliationID string, matches []model.Match) model.ScoreDistribution {
+	dist := model.ScoreDistribution{ReconciliationID: reconciliationID, Buckets: make([]model.ScoreBucket, scoreBucketCount)}
+	for i := range dist.Buckets {
+		dist.Buckets[i].Lower = float64(i) / scoreBucketCount
+		dist.Buckets[i].Upper = float64(i+1) / scoreBucketCount
+	}
+
+	var total float64
+	for _, match := range matches {
+		dist.TotalMatches++
+		total += match.Confidence
+		switch match.Status {
+		case model.MatchStatusConfirmed:
+			dist.Confirmed++
+		case model.MatchStatusPendingReview:
+			dist.PendingReview++
+		case model.MatchStatusRejected:
+			dist.Rejected++
+		}
+		if match.Confidence < 0 {
+			continue
+		}
+		bucket := int(match.Confidence * scoreBucketCount)
+		if bucket >= scoreBucketCount {
+			bucket = scoreBucketCount - 1
+		}
+		dist.Buckets[bucket].Count++
+	}
+	if dist.TotalMatches > 0 {
+		dist.AverageScore = total / float64(dist.TotalMatches)
+	}
+	return dist
+}
+
+// saveDryRunReport stores a dry-run report so it can be retrieved after the run completes.
+func (s *Blnk) saveDryRunReport(ctx context.Context, report *model.DryRunReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dry run report: %w", err)
+	}
+	key := fmt.Sprintf("%s:%s", dryRunReportKeyPrefix, report.ReconciliationID)
+	return s.redis.Set(ctx, key, data, dryRunReportTTL).Err()
+}
+
+// GetDryRunReport retrieves the report of a completed dry-run reconciliation.
+// Reports are kept for seven days after the run completes.
+//
+// Parameters:
+// - ctx: The context controlling the request.
+// - reconciliationID: The ID of the dry-run reconciliation.
+//
+// Returns:
+// - *model.DryRunReport: The matches, confidence scores and exceptions produced by the run.
+// - error: If the reconciliation is not a dry run, has not completed, or the report has expired.
+func (s *Blnk) GetDryRunReport(ctx context.Context, reconciliationID string) (*model.DryRunReport, error) {
+	reconciliation, err := s.GetReconciliation(ctx, reconciliationID)
+	if err != nil {
+		return nil, err
+	}
+	if !reconciliation.IsDryRun {
+		return nil, fmt.Errorf("reconciliation %s is not a dry run", reconciliationID)
+	}
+	if reconciliation.Status != StatusCompleted {
+		return nil, fmt.Errorf("reconciliation %s has not completed: status is %s", reconciliationID, reconciliation.Status)
+	}
+
+	data, err := s.redis.Get(ctx, fmt.Sprintf("%s:%s", dryRunReportKeyPrefix, reconciliationID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("dry run report for reconciliation %s not found", reconciliationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var report model.DryRunReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dry run report: %w", err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,89 @@
+package blnk
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// Webhook events announcing the lifecycle of a reconciliation run and the resolution of its exceptions.
+const (
+	EventReconciliationStarted   = "reconciliation.started"
+	EventReconciliationCompleted = "reconciliation.completed"
+	EventReconciliationFailed    = "reconciliation.failed"
+	EventExceptionResolved       = "reconciliation.exception_resolved"
+)
+
+// reconciliationEvent builds the payload announcing a reconciliation run. The match rate is the share of the
+// run's transactions that were matched.
+//
+// Parameters:
+// - reconciliation model.Reconciliation: The reconciliation run.
+// - pendingReview int: The number of matches held for review.
+// - runErr error: The error that failed the run, or nil.
+//
+// Returns:
+// - model.ReconciliationEvent: The payload of the webhook.
+func reconciliationEvent(reconciliation model.Reconciliation, pendingReview int, runErr error) model.ReconciliationEvent {
+	event := model.ReconciliationEvent{
+		Reconciliation:    reconciliation,
+		TotalTransactions: reconciliation.MatchedTransactions + reconciliation.UnmatchedTransactions,
+		PendingReview:     pendingReview,
+	}
+	if event.TotalTransactions > 0 {
+		event.MatchRate = float64(reconciliation.MatchedTransactions) / float64(event.TotalTransactions)
+	}
+	if runErr != nil {
+		event.Error = runErr.Error()
+	}
+	return event
+}
+
+// notifyReconciliation sends a webhook announcing a reconciliation run in the background.
+func (s *Blnk) notifyReconciliation(event string, reconciliation model.Reconciliation, pendingReview int, runErr error) {
+	payload := reconciliationEvent(reconciliation, pendingReview, runErr)
+	go func() {
+		if err := s.SendWebhook(NewWebhook{Event: event, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
+
+// failReconciliation marks a reconciliation run as failed and announces the failure.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - reconciliation model.Reconciliation: The reconciliation run that failed.
+// - runErr error: The error that failed the run.
+//
+// Returns:
+// - error: An error if the status of the run could not be updated.
+func (s *Blnk) failReconciliation(ctx context.Context, reconciliation model.Reconciliation, runErr error) error {
+	if err := s.datasource.UpdateReconciliationStatus(ctx, reconciliation.ReconciliationID, StatusFailed, 0, 0); err != nil {
+		log.Printf("Error updating reconciliation status: %v", err)
+		return err
+	}
+
+	reconciliation.Status = StatusFailed
+	s.notifyReconciliation(EventReconciliationFailed, reconciliation, 0, runErr)
+	return nil
+}
+
+// notifyExceptionResolved sends a webhook announcing that a reviewer resolved a match in the background.
+func (s *Blnk) notifyExceptionResolved(reconciliationID, externalTxnID, internalTxnID, status string) {
+	payload := model.ExceptionResolution{
+		ReconciliationID:      reconciliationID,
+		ExternalTransactionID: externalTxnID,
+		InternalTransactionID: internalTxnID,
+		Status:                status,
+		ResolvedAt:            time.Now().UTC(),
+	}
+	go func() {
+		if err := s.SendWebhook(NewWebhook{Event: EventExceptionResolved, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func pendingWebhooks(mr *miniredis.Miniredis) func() bool {
+	return func() bool {
+		tasks, err := mr.List("asynq:{webhook_queue}:pending")
+		return err == nil && len(tasks) == 1
+	}
+}
+
+func TestReconciliationEvent_MatchStats(t *testing.T) {
+	reconciliation := model.Reconciliation{ReconciliationID: "recon_1", Status: StatusCompleted, MatchedTransactions: 3, UnmatchedTransactions: 1}
+
+	event := reconciliationEvent(reconciliation, 2, nil)
+	assert.Equal(t, 4, event.TotalTransactions)
+	assert.Equal(t, 2, event.PendingReview)
+	assert.InDelta(t, 0.75, event.MatchRate, 1e-9)
+	assert.Empty(t, event.Error)
+
+	event = reconciliationEvent(model.Reconciliation{ReconciliationID: "recon_2", Status: StatusFailed}, 0, errors.New("no matching rules"))
+	assert.Zero(t, event.TotalTransactions)
+	assert.Zero(t, event.MatchRate)
+	assert.Equal(t, "no matching rules", event.Error)
+}
+
+func TestFinalizeReconciliation_SendsCompletedWebhook(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+
+	mockDS.On("UpdateReconciliationStatus", mock.Anything, "recon_1", StatusCompleted, 3, 1).Return(nil)
+
+	reconciliation := model.Reconciliation{ReconciliationID: "recon_1", Status: StatusInProgress, IsDryRun: true, StartedAt: time.Now()}
+	require.NoError(t, b.finalizeReconciliation(context.Background(), reconciliation, 3, 1, 2))
+
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
+
+func TestFailReconciliation_SendsFailedWebhook(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+
+	mockDS.On("UpdateReconciliationStatus", mock.Anything, "recon_1", StatusFailed, 0, 0).Return(nil)
+
+	reconciliation := model.Reconciliation{ReconciliationID: "recon_1", Status: StatusInProgress}
+	require.NoError(t, b.failReconciliation(context.Background(), reconciliation, errors.New("upload not found")))
+
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
+
+func TestFailReconciliation_StatusUpdateFails(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+
+	mockDS.On("UpdateReconciliationStatus", mock.Anything, "recon_1", StatusFailed, 0, 0).Return(errors.New("connection refused"))
+
+	err := b.failReconciliation(context.Background(), model.Reconciliation{ReconciliationID: "recon_1"}, errors.New("upload not found"))
+	assert.Error(t, err)
+
+	// The failure was not recorded, so it is not announced either
+	time.Sleep(50 * time.Millisecond)
+	tasks, _ := mr.List("asynq:{webhook_queue}:pending")
+	assert.Empty(t, tasks)
+}
+
+func TestReviewMatch_SendsExceptionResolvedWebhook(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+
+	mockDS.On("UpdateMatchStatus", mock.Anything, "recon_1", "ext_1", "txn_1", model.MatchStatusRejected).Return(nil)
+
+	require.NoError(t, b.ReviewMatch(context.Background(), "recon_1", "ext_1", "txn_1", false))
+
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+	mockDS.AssertExpectations(t)
+}
@@ -0,0 +1,101 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blnkfinance/blnk/model"
+)
+
+// maxExternalRecordBatch is the most records a pushed batch can hold.
+const maxExternalRecordBatch = 1000
+
+// ErrInvalidExternalRecords is returned when a pushed batch of external records is empty, too large, or has a
+// record missing a required field.
+var ErrInvalidExternalRecords = errors.New("invalid external records")
+
+// RecordExternalRecords stores a batch of external transaction records pushed by a partner, so they can be
+// reconciled as they arrive instead of in a daily file. Records are deduplicated on their IDs: those already
+// pushed or uploaded, or repeated within the batch, are reported as duplicates and not stored again, so batches
+// can be safely retried.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - batch model.ExternalRecordBatch: The records, the upload they are added to and their source.
+//
+// Returns:
+// - *model.ExternalRecordBatchResult: The upload the records were added to, and which were duplicates.
+// - error: ErrInvalidExternalRecords if the batch is invalid, or an error if the records could not be stored.
+func (s *Blnk) RecordExternalRecords(ctx context.Context, batch model.ExternalRecordBatch) (*model.ExternalRecordBatchResult, error) {
+	ctx, span := tracer.Start(ctx, "RecordExternalRecords")
+	defer span.End()
+
+	if len(batch.Records) == 0 {
+		return nil, fmt.Errorf("%w: records are required", ErrInvalidExternalRecords)
+	}
+	if len(batch.Records) > maxExternalRecordBatch {
+		return nil, fmt.Errorf("%w: a batch can hold at most %d records", ErrInvalidExternalRecords, maxExternalRecordBatch)
+	}
+	if batch.UploadID == "" {
+		batch.UploadID = model.GenerateUUIDWithSuffix("upload")
+	}
+
+	result := &model.ExternalRecordBatchResult{
+		UploadID:   batch.UploadID,
+		Source:     batch.Source,
+		Received:   len(batch.Records),
+		Duplicates: []string{},
+	}
+	seen := make(map[string]bool, len(batch.Records))
+	records := make([]*model.ExternalTransaction, 0, len(batch.Records))
+	for i := range batch.Records {
+		record := &batch.Records[i]
+		if err := validateExternalRecord(record); err != nil {
+			return nil, fmt.Errorf("%w: record %d: %v", ErrInvalidExternalRecords, i, err)
+		}
+		if record.Source == "" {
+			record.Source = batch.Source
+		}
+		if seen[record.ID] {
+			result.Duplicates = append(result.Duplicates, record.ID)
+			continue
+		}
+		seen[record.ID] = true
+		records = append(records, record)
+	}
+
+	recorded, err := s.datasource.RecordExternalTransactions(ctx, records, batch.UploadID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	stored := make(map[string]bool, len(recorded))
+	for _, id := range recorded {
+		stored[id] = true
+	}
+	for _, record := range records {
+		if !stored[record.ID] {
+			result.Duplicates = append(result.Duplicates, record.ID)
+		}
+	}
+	result.Recorded = len(recorded)
+	return result, nil
+}
+
+// validateExternalRecord checks that a pushed record has the fields an uploaded file's rows require.
+func validateExternalRecord(record *model.ExternalTransaction) error {
+	record.ID = strings.TrimSpace(record.ID)
+	switch {
+	case record.ID == "":
+		return errors.New("id is required")
+	case strings.TrimSpace(record.Reference) == "":
+		return errors.New("reference is required")
+	case strings.TrimSpace(record.Currency) == "":
+		return errors.New("currency is required")
+	case record.Date.IsZero():
+		return errors.New("date is required")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func externalRecord(id string) model.ExternalTransaction {
+	return model.ExternalTransaction{
+		ID:        id,
+		Amount:    25,
+		Reference: "ref-" + id,
+		Currency:  "USD",
+		Date:      time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestRecordExternalRecords_DeduplicatesOnExternalIDs(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	var stored []*model.ExternalTransaction
+	mockDS.On("RecordExternalTransactions", mock.Anything, mock.Anything, "upload_stream").
+		Run(func(args mock.Arguments) { stored = args.Get(1).([]*model.ExternalTransaction) }).
+		Return([]string{"ext_1"}, nil).Once()
+
+	result, err := b.RecordExternalRecords(context.Background(), model.ExternalRecordBatch{
+		UploadID: "upload_stream",
+		Source:   "partner_bank",
+		Records:  []model.ExternalTransaction{externalRecord("ext_1"), externalRecord("ext_2"), externalRecord("ext_1")},
+	})
+	require.NoError(t, err)
+
+	// The repeat within the batch is never sent, and ext_2 was recorded by an earlier batch.
+	require.Len(t, stored, 2)
+	assert.Equal(t, "partner_bank", stored[0].Source)
+	assert.Equal(t, "upload_stream", result.UploadID)
+	assert.Equal(t, 3, result.Received)
+	assert.Equal(t, 1, result.Recorded)
+	assert.ElementsMatch(t, []string{"ext_1", "ext_2"}, result.Duplicates)
+	mockDS.AssertExpectations(t)
+}
+
+func TestRecordExternalRecords_StartsUploadWhenNoneGiven(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("RecordExternalTransactions", mock.Anything, mock.Anything, mock.AnythingOfType("string")).Return([]string{"ext_1"}, nil).Once()
+
+	result, err := b.RecordExternalRecords(context.Background(), model.ExternalRecordBatch{Records: []model.ExternalTransaction{externalRecord("ext_1")}})
+	require.NoError(t, err)
+	assert.Contains(t, result.UploadID, "upload")
+	assert.Empty(t, result.Duplicates)
+}
+
+func TestRecordExternalRecords_RejectsInvalidBatches(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	_, err := b.RecordExternalRecords(context.Background(), model.ExternalRecordBatch{})
+	assert.ErrorIs(t, err, ErrInvalidExternalRecords)
+
+	missingDate := externalRecord("ext_1")
+	missingDate.Date = time.Time{}
+	_, err = b.RecordExternalRecords(context.Background(), model.ExternalRecordBatch{Records: []model.ExternalTransaction{missingDate}})
+	assert.ErrorIs(t, err, ErrInvalidExternalRecords)
+	assert.ErrorContains(t, err, "date is required")
+
+	_, err = b.RecordExternalRecords(context.Background(), model.ExternalRecordBatch{Records: make([]model.ExternalTransaction, maxExternalRecordBatch+1)})
+	assert.ErrorIs(t, err, ErrInvalidExternalRecords)
+	mockDS.AssertNotCalled(t, "RecordExternalTransactions", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -52,7 +52,7 @@ import (
 // 		{TransactionID: "int2", Amount: 200, CreatedAt: time.Now()},
 // 	}
 
-// 	mockDS.On("GetTransactionsPaginated", mock.Anything, "", 100000, int64(0)).Return(internalTxns, nil)
+// 	mockDS.On("GetTransactionsPaginated", mock.Anything, "", model.Page{Limit: 100000}).Return(internalTxns, nil)
 // 	mockDS.On("GetTransactionsPaginated", mock.Anything, "", 100000, int64(2)).Return([]*model.Transaction{}, nil)
 
 // 	matchingRules := []model.MatchingRule{
@@ -109,8 +109,9 @@ func TestOneToManyReconciliation(t *testing.T) {
 
 	emptyGroup := map[string][]*model.Transaction{}
 
-	mockDS.On("GroupTransactions", mock.Anything, mock.Anything, 100000, int64(0)).Return(groupedInternalTxns, nil)
-	mockDS.On("GroupTransactions", mock.Anything, mock.Anything, 100000, int64(100000)).Return(emptyGroup, nil)
+	mockDS.On("GroupTransactions", mock.Anything, mock.Anything, model.Page{Limit: 100000}).Return(groupedInternalTxns, nil)
+	next := model.Page{Limit: 100000, After: &model.PageCursor{Time: internalTxns[2].CreatedAt, ID: "int3"}}
+	mockDS.On("GroupTransactions", mock.Anything, mock.Anything, next).Return(emptyGroup, nil)
 
 	matchingRules := []model.MatchingRule{
 		{
@@ -172,8 +173,9 @@ func TestOneToManyReconciliationNoMatches(t *testing.T) {
 
 	emptyGroup := map[string][]*model.Transaction{}
 
-	mockDS.On("GroupTransactions", mock.Anything, mock.Anything, 100000, int64(0)).Return(groupedInternalTxns, nil)
-	mockDS.On("GroupTransactions", mock.Anything, mock.Anything, 100000, int64(100000)).Return(emptyGroup, nil)
+	mockDS.On("GroupTransactions", mock.Anything, mock.Anything, model.Page{Limit: 100000}).Return(groupedInternalTxns, nil)
+	next := model.Page{Limit: 100000, After: &model.PageCursor{Time: internalTxns[2].CreatedAt, ID: "int3"}}
+	mockDS.On("GroupTransactions", mock.Anything, mock.Anything, next).Return(emptyGroup, nil)
 
 	matchingRules := []model.MatchingRule{
 		{
@@ -420,7 +422,7 @@ func TestReconciliationEdgeCases(t *testing.T) {
 			{TransactionID: "ext1", Amount: 100, CreatedAt: time.Now()},
 		}
 
-		mockDS.On("GetTransactionsPaginated", mock.Anything, "", 100000, int64(0)).Return([]*model.Transaction{}, nil).Once()
+		mockDS.On("GetTransactionsPaginated", mock.Anything, "", model.Page{Limit: 100000}).Return([]*model.Transaction{}, nil).Once()
 
 		matchingRules := []model.MatchingRule{
 			{
@@ -445,7 +447,7 @@ func TestReconciliationEdgeCases(t *testing.T) {
 		}
 		groupedInternalTxns := map[string][]*model.Transaction{}
 
-		mockDS.On("GroupTransactions", mock.Anything, mock.Anything, 100000, int64(0)).Return(groupedInternalTxns, nil)
+		mockDS.On("GroupTransactions", mock.Anything, mock.Anything, model.Page{Limit: 100000}).Return(groupedInternalTxns, nil)
 
 		matchingRules := []model.MatchingRule{
 			{
@@ -464,3 +466,106 @@ func TestReconciliationEdgeCases(t *testing.T) {
 		mockDS.AssertExpectations(t)
 	})
 }
+
+func TestScoreMatch(t *testing.T) {
+	blnk := &Blnk{}
+	now := time.Now()
+
+	externalTxn := &model.Transaction{
+		TransactionID: "ext1",
+		Amount:        100,
+		CreatedAt:     now,
+		Reference:     "REF123",
+		Currency:      "USD",
+	}
+
+	exactRule := []model.MatchingRule{
+		{
+			RuleID: "rule1",
+			Criteria: []model.MatchingCriteria{
+				{Field: "amount", Operator: "equals", AllowableDrift: 0.02},
+				{Field: "reference", Operator: "equals"},
+				{Field: "currency", Operator: "equals"},
+			},
+		},
+	}
+
+	t.Run("Exact match scores 1", func(t *testing.T) {
+		internalTxn := model.Transaction{Amount: 100, CreatedAt: now, Reference: "REF123", Currency: "USD"}
+		assert.Equal(t, 1.0, blnk.scoreMatch(externalTxn, internalTxn, exactRule))
+	})
+
+	t.Run("Drift lowers the score", func(t *testing.T) {
+		internalTxn := model.Transaction{Amount: 101, CreatedAt: now, Reference: "REF123", Currency: "USD"}
+		score := blnk.scoreMatch(externalTxn, internalTxn, exactRule)
+		assert.Greater(t, score, 0.5)
+		assert.Less(t, score, 1.0)
+	})
+
+	t.Run("No matching rule scores 0", func(t *testing.T) {
+		internalTxn := model.Transaction{Amount: 150, CreatedAt: now, Reference: "REF123", Currency: "USD"}
+		assert.Equal(t, 0.0, blnk.scoreMatch(externalTxn, internalTxn, exactRule))
+	})
+
+	t.Run("Best rule wins", func(t *testing.T) {
+		internalTxn := model.Transaction{Amount: 100, CreatedAt: now, Reference: "REF-123-X", Currency: "USD"}
+		rules := []model.MatchingRule{
+			{RuleID: "fuzzy", Criteria: []model.MatchingCriteria{{Field: "reference", Operator: "contains", AllowableDrift: 50}}},
+			{RuleID: "amount", Criteria: []model.MatchingCriteria{{Field: "amount", Operator: "equals"}}},
+		}
+		assert.Equal(t, 1.0, blnk.scoreMatch(externalTxn, internalTxn, rules))
+	})
+}
+
+func TestClassifyMatches(t *testing.T) {
+	tp := &transactionProcessor{autoConfirmThreshold: 0.9, reviewThreshold: 0.6}
+
+	matches := []model.Match{
+		{ExternalTransactionID: "ext1", InternalTransactionID: "int1", Confidence: 0.95},
+		{ExternalTransactionID: "ext2", InternalTransactionID: "int2", Confidence: 0.75},
+		{ExternalTransactionID: "ext3", InternalTransactionID: "int3", Confidence: 0.4},
+		{ExternalTransactionID: "ext3", InternalTransactionID: "int4", Confidence: 0.3},
+	}
+
+	kept, rejected := tp.classifyMatches(matches)
+
+	assert.Len(t, kept, 2)
+	assert.Equal(t, model.MatchStatusConfirmed, kept[0].Status)
+	assert.Equal(t, model.MatchStatusPendingReview, kept[1].Status)
+	assert.Equal(t, []string{"ext3"}, rejected)
+	assert.Len(t, confirmedMatches(kept), 1)
+}
+
+func TestDryRunProcessCollectsReportWithoutRecording(t *testing.T) {
+	mockDS := new(mocks.MockDataSource)
+	tp := &transactionProcessor{
+		reconciliation:       model.Reconciliation{ReconciliationID: "rec1", IsDryRun: true},
+		datasource:           mockDS,
+		progressSaveCount:    100,
+		autoConfirmThreshold: 0.9,
+		reviewThreshold:      0.6,
+		dryRun:               &dryRunCollector{},
+		reconciler: func(_ context.Context, txns []*model.Transaction) ([]model.Match, []string) {
+			return []model.Match{
+				{ExternalTransactionID: txns[0].TransactionID, InternalTransactionID: "int1", Confidence: 0.95},
+				{ExternalTransactionID: "ext2", InternalTransactionID: "int2", Confidence: 0.3},
+			}, nil
+		},
+	}
+
+	err := tp.process(context.Background(), &model.Transaction{TransactionID: "ext1"})
+	assert.NoError(t, err)
+	mockDS.AssertNotCalled(t, "RecordMatches", mock.Anything, mock.Anything, mock.Anything)
+	mockDS.AssertNotCalled(t, "RecordUnmatched", mock.Anything, mock.Anything, mock.Anything)
+
+	report := tp.dryRun.report("rec1")
+	assert.Len(t, report.Matches, 2)
+	assert.Equal(t, model.MatchStatusConfirmed, report.Matches[0].Status)
+	assert.Equal(t, model.MatchStatusRejected, report.Matches[1].Status)
+	assert.Equal(t, "rec1", report.Matches[1].ReconciliationID)
+	assert.Equal(t, 1, report.Summary.Confirmed)
+	assert.Equal(t, 1, report.Summary.Rejected)
+	assert.Equal(t, 1, report.Summary.Buckets[9].Count)
+	assert.Equal(t, 1, report.Summary.Buckets[3].Count)
+	assert.Equal(t, []string{"ext2"}, report.Exceptions)
+}
@@ -0,0 +1,363 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+)
+
+// replayActor is the actor recorded on the transactions a replay writes to a scratch database.
+const replayActor = "replay"
+
+// ReplayExportOptions selects the time slice of a ledger to export for replay.
+type ReplayExportOptions struct {
+	LedgerID string
+	From     time.Time
+	// To defaults to now, in which case the production states of the slice are the live balances rather than
+	// the states computed from the transactions recorded before To.
+	To time.Time
+}
+
+// ReplayOptions controls how a slice is replayed.
+type ReplayOptions struct {
+	// SkipDuplicates replays only the first of the transactions that share a hash, showing what the balances
+	// would have reached had the duplicates not been applied.
+	SkipDuplicates bool
+}
+
+// ExportReplaySlice exports a time slice of a ledger for replay: the transactions recorded within it that touch
+// a balance of the ledger, the state every balance they touch started the slice in and the state production
+// reached at its end. Starting states are computed from the transaction log, not from balance rows or
+// snapshots, so balances whose rows drifted from their transactions show up as differences when replayed.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - opts ReplayExportOptions: The ledger and window to export.
+//
+// Returns:
+// - *model.ReplaySlice: The exported slice.
+// - error: An error if the options are invalid or the slice could not be read.
+func (l *Blnk) ExportReplaySlice(ctx context.Context, opts ReplayExportOptions) (*model.ReplaySlice, error) {
+	ctx, span := tracer.Start(ctx, "ExportReplaySlice")
+	defer span.End()
+
+	now := time.Now().UTC()
+	live := opts.To.IsZero()
+	if live {
+		opts.To = now
+	}
+	if opts.LedgerID == "" {
+		return nil, errors.New("ledger is required")
+	}
+	if opts.From.IsZero() || !opts.From.Before(opts.To) {
+		return nil, errors.New("from must be set and before to")
+	}
+
+	transactions, err := l.datasource.GetReplayTransactions(ctx, opts.LedgerID, opts.From, opts.To)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	slice := &model.ReplaySlice{
+		LedgerID:     opts.LedgerID,
+		From:         opts.From,
+		To:           opts.To,
+		ExportedAt:   now,
+		Balances:     []*model.Balance{},
+		Transactions: transactions,
+		Production:   []*model.Balance{},
+	}
+	for _, id := range replayBalanceIDs(transactions) {
+		details, err := l.datasource.GetBalanceByIDLite(ctx, id)
+		if err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("failed to get balance %s: %w", id, err)
+		}
+
+		initial, err := l.replayBalanceAt(ctx, details, opts.From)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		slice.Balances = append(slice.Balances, initial)
+
+		var production *model.Balance
+		if live {
+			production, err = l.GetBalanceByID(ctx, id, nil, false)
+		} else {
+			production, err = l.replayBalanceAt(ctx, details, opts.To)
+		}
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		slice.Production = append(slice.Production, production)
+	}
+	return slice, nil
+}
+
+// replayBalanceAt returns the state of a balance at a point in time, computed from its transactions.
+func (l *Blnk) replayBalanceAt(ctx context.Context, details *model.Balance, at time.Time) (*model.Balance, error) {
+	state, err := l.datasource.GetReplayBalanceAt(ctx, details.BalanceID, at)
+	if err != nil {
+		return nil, err
+	}
+	state.LedgerID = details.LedgerID
+	state.Currency = details.Currency
+	state.CurrencyMultiplier = details.CurrencyMultiplier
+	state.Indicator = details.Indicator
+	state.CreatedAt = details.CreatedAt
+	return state, nil
+}
+
+// replayBalanceIDs returns the IDs of the balances the transactions touch, sorted.
+func replayBalanceIDs(transactions []*model.Transaction) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, txn := range transactions {
+		for _, id := range []string{txn.Source, txn.Destination} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Replay applies the transactions of a slice to the states its balances started in, in the order they were
+// recorded, and diffs the resulting balances against the states production reached. It uses the balance
+// arithmetic transactions are recorded with and reads nothing but the slice, so replaying a slice always gives
+// the same result. Transactions sharing a hash are reported as duplicates, the usual sign of a double-apply.
+//
+// Parameters:
+// - slice *model.ReplaySlice: The slice to replay.
+// - opts ReplayOptions: How to replay it.
+//
+// Returns:
+// - *model.ReplayResult: The replayed balances and their differences from production.
+func Replay(slice *model.ReplaySlice, opts ReplayOptions) *model.ReplayResult {
+	result := &model.ReplayResult{Skipped: []string{}, Duplicates: [][]string{}, Diffs: []model.ReplayDiff{}, Failures: []model.ReplayFailure{}}
+
+	balances := make(map[string]*model.Balance, len(slice.Balances))
+	for _, balance := range slice.Balances {
+		balances[balance.BalanceID] = cloneReplayBalance(balance)
+	}
+
+	transactions := replayOrder(slice.Transactions)
+	skip := make(map[string]bool)
+	for _, group := range replayDuplicates(transactions) {
+		result.Duplicates = append(result.Duplicates, group)
+		if opts.SkipDuplicates {
+			for _, id := range group[1:] {
+				skip[id] = true
+				result.Skipped = append(result.Skipped, id)
+			}
+		}
+	}
+
+	for _, txn := range transactions {
+		if skip[txn.TransactionID] {
+			continue
+		}
+		if err := applyReplayTransaction(txn, balances); err != nil {
+			result.Failures = append(result.Failures, model.ReplayFailure{TransactionID: txn.TransactionID, Error: err.Error()})
+			continue
+		}
+		result.Applied++
+	}
+
+	ids := make([]string, 0, len(balances))
+	for id := range balances {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		result.Balances = append(result.Balances, balances[id])
+	}
+
+	for _, production := range slice.Production {
+		if replayed, ok := balances[production.BalanceID]; ok {
+			result.Diffs = append(result.Diffs, diffReplayBalance(replayed, production)...)
+		}
+	}
+	result.Matches = len(result.Diffs) == 0 && len(result.Failures) == 0
+	return result
+}
+
+// replayOrder returns the transactions in the order they were recorded. Transactions recorded at the same
+// instant are ordered by ID so the order never depends on how the slice was read.
+func replayOrder(transactions []*model.Transaction) []*model.Transaction {
+	ordered := append([]*model.Transaction(nil), transactions...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if !ordered[i].CreatedAt.Equal(ordered[j].CreatedAt) {
+			return ordered[i].CreatedAt.Before(ordered[j].CreatedAt)
+		}
+		return ordered[i].TransactionID < ordered[j].TransactionID
+	})
+	return ordered
+}
+
+// replayDuplicates groups the IDs of the applied and inflight transactions that share a hash. Commits and voids
+// are left out because they are hashed with generated references.
+func replayDuplicates(transactions []*model.Transaction) [][]string {
+	groups := make(map[string][]string)
+	var hashes []string
+	for _, txn := range transactions {
+		if txn.Hash == "" || (txn.Status != StatusApplied && txn.Status != StatusInflight) {
+			continue
+		}
+		key := txn.Status + ":" + txn.Hash
+		if _, ok := groups[key]; !ok {
+			hashes = append(hashes, key)
+		}
+		groups[key] = append(groups[key], txn.TransactionID)
+	}
+
+	var duplicates [][]string
+	for _, key := range hashes {
+		if len(groups[key]) > 1 {
+			duplicates = append(duplicates, groups[key])
+		}
+	}
+	return duplicates
+}
+
+// applyReplayTransaction applies a transaction to the balances it moves funds between. Transactions are applied
+// even if they overdraw their source, since production already accepted them.
+func applyReplayTransaction(txn *model.Transaction, balances map[string]*model.Balance) error {
+	source, ok := balances[txn.Source]
+	if !ok {
+		return fmt.Errorf("source balance %s is not in the slice", txn.Source)
+	}
+	destination, ok := balances[txn.Destination]
+	if !ok {
+		return fmt.Errorf("destination balance %s is not in the slice", txn.Destination)
+	}
+	if txn.PreciseAmount == nil {
+		return errors.New("transaction has no precise amount")
+	}
+
+	replayed := *txn
+	replayed.PreciseAmount = new(big.Int).Set(txn.PreciseAmount)
+	replayed.AllowOverdraft = true
+	replayed.OverdraftLimit = 0
+
+	switch txn.Status {
+	case StatusApplied, StatusInflight:
+		replayed.Inflight = txn.Status == StatusInflight
+		return model.UpdateBalances(&replayed, source, destination)
+	case model.ReplayCommit:
+		source.CommitInflightDebit(&replayed)
+		destination.CommitInflightCredit(&replayed)
+	case StatusVoid:
+		source.RollbackInflightDebit(replayed.PreciseAmount)
+		destination.RollbackInflightCredit(replayed.PreciseAmount)
+	default:
+		return fmt.Errorf("transactions with status %s are not replayed", txn.Status)
+	}
+	return nil
+}
+
+// diffReplayBalance lists the amounts of a replayed balance that differ from production.
+func diffReplayBalance(replayed, production *model.Balance) []model.ReplayDiff {
+	fields := []struct {
+		name                 string
+		replayed, production *big.Int
+	}{
+		{"balance", replayed.Balance, production.Balance},
+		{"credit_balance", replayed.CreditBalance, production.CreditBalance},
+		{"debit_balance", replayed.DebitBalance, production.DebitBalance},
+		{"inflight_credit_balance", replayed.InflightCreditBalance, production.InflightCreditBalance},
+		{"inflight_debit_balance", replayed.InflightDebitBalance, production.InflightDebitBalance},
+	}
+
+	var diffs []model.ReplayDiff
+	for _, field := range fields {
+		r, p := orZero(field.replayed), orZero(field.production)
+		if r.Cmp(p) != 0 {
+			diffs = append(diffs, model.ReplayDiff{
+				BalanceID:  replayed.BalanceID,
+				Field:      field.name,
+				Replayed:   r,
+				Production: p,
+				Difference: new(big.Int).Sub(r, p),
+			})
+		}
+	}
+	return diffs
+}
+
+// cloneReplayBalance copies a balance so replaying never changes the slice it came from.
+func cloneReplayBalance(balance *model.Balance) *model.Balance {
+	clone := *balance
+	clone.InitializeBalanceFields()
+	clone.Balance = new(big.Int).Set(clone.Balance)
+	clone.CreditBalance = new(big.Int).Set(clone.CreditBalance)
+	clone.DebitBalance = new(big.Int).Set(clone.DebitBalance)
+	clone.InflightBalance = new(big.Int).Set(clone.InflightBalance)
+	clone.InflightCreditBalance = new(big.Int).Set(clone.InflightCreditBalance)
+	clone.InflightDebitBalance = new(big.Int).Set(clone.InflightDebitBalance)
+	return &clone
+}
+
+// RestoreReplay writes a replayed slice to the database of l, which must be a scratch database and never
+// production: the ledgers of its balances, the transactions replayed and the balances they were replayed to.
+// Transactions keep their IDs, so a slice can only be restored into a database once.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - slice *model.ReplaySlice: The slice that was replayed.
+// - result *model.ReplayResult: The result of replaying it.
+//
+// Returns:
+// - error: An error if anything could not be written.
+func (l *Blnk) RestoreReplay(ctx context.Context, slice *model.ReplaySlice, result *model.ReplayResult) error {
+	ctx, span := tracer.Start(ctx, "RestoreReplay")
+	defer span.End()
+
+	restored := make(map[string]bool)
+	for _, balance := range result.Balances {
+		if restored[balance.LedgerID] {
+			continue
+		}
+		if err := l.datasource.RestoreLedger(ctx, balance.LedgerID); err != nil {
+			span.RecordError(err)
+			return err
+		}
+		restored[balance.LedgerID] = true
+	}
+	for _, balance := range result.Balances {
+		if err := l.datasource.RestoreBalance(ctx, balance); err != nil {
+			span.RecordError(err)
+			return fmt.Errorf("failed to restore balance %s: %w", balance.BalanceID, err)
+		}
+	}
+
+	skipped := make(map[string]bool, len(result.Skipped))
+	for _, id := range result.Skipped {
+		skipped[id] = true
+	}
+	for _, txn := range replayOrder(slice.Transactions) {
+		if skipped[txn.TransactionID] {
+			continue
+		}
+		record := *txn
+		if record.Status == model.ReplayCommit {
+			record.Status = StatusApplied
+		}
+		record.StatusChange = model.StatusChange{Actor: replayActor}
+		if _, err := l.datasource.RecordTransaction(ctx, &record); err != nil {
+			span.RecordError(err)
+			return fmt.Errorf("failed to restore transaction %s: %w", txn.TransactionID, err)
+		}
+	}
+	return nil
+}
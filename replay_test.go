@@ -0,0 +1,123 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func replayTestSlice() *model.ReplaySlice {
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	txn := func(id, status, hash string, amount int64, offset time.Duration) *model.Transaction {
+		return &model.Transaction{
+			TransactionID: id, Source: "bln_a", Destination: "bln_b", Status: status, Hash: hash, Currency: "USD",
+			PreciseAmount: big.NewInt(amount), Precision: 100, Rate: 1, CreatedAt: at.Add(offset),
+		}
+	}
+	return &model.ReplaySlice{
+		LedgerID: "ldg_1",
+		Balances: []*model.Balance{
+			{BalanceID: "bln_b", Balance: big.NewInt(0), CreditBalance: big.NewInt(0), DebitBalance: big.NewInt(0)},
+			{BalanceID: "bln_a", Balance: big.NewInt(10000), CreditBalance: big.NewInt(10000), DebitBalance: big.NewInt(0)},
+		},
+		Transactions: []*model.Transaction{
+			txn("txn_dup", StatusApplied, "h1", 1000, 2*time.Minute),
+			txn("txn_1", StatusApplied, "h1", 1000, time.Minute),
+			txn("txn_hold", StatusInflight, "h2", 500, 3*time.Minute),
+			txn("txn_commit", model.ReplayCommit, "h3", 300, 4*time.Minute),
+		},
+		Production: []*model.Balance{
+			{BalanceID: "bln_a", Balance: big.NewInt(7700), CreditBalance: big.NewInt(10000), DebitBalance: big.NewInt(2300), InflightDebitBalance: big.NewInt(200)},
+			{BalanceID: "bln_b", Balance: big.NewInt(2300), CreditBalance: big.NewInt(2300), DebitBalance: big.NewInt(0), InflightCreditBalance: big.NewInt(200)},
+		},
+	}
+}
+
+func TestReplay_MatchesProduction(t *testing.T) {
+	slice := replayTestSlice()
+
+	result := Replay(slice, ReplayOptions{})
+	assert.True(t, result.Matches, "diffs: %+v failures: %+v", result.Diffs, result.Failures)
+	assert.Equal(t, 4, result.Applied)
+	assert.Equal(t, [][]string{{"txn_1", "txn_dup"}}, result.Duplicates)
+	require.Len(t, result.Balances, 2)
+	assert.Equal(t, "bln_a", result.Balances[0].BalanceID)
+	assert.Equal(t, big.NewInt(200), result.Balances[0].InflightDebitBalance)
+
+	// The slice is left untouched, so replaying it again gives the same result
+	assert.Equal(t, big.NewInt(10000), slice.Balances[1].Balance)
+	assert.Equal(t, result, Replay(slice, ReplayOptions{}))
+}
+
+func TestReplay_SkipDuplicatesReportsDiffs(t *testing.T) {
+	result := Replay(replayTestSlice(), ReplayOptions{SkipDuplicates: true})
+
+	assert.False(t, result.Matches)
+	assert.Equal(t, []string{"txn_dup"}, result.Skipped)
+	assert.Equal(t, 3, result.Applied)
+	assert.Contains(t, result.Diffs, model.ReplayDiff{
+		BalanceID: "bln_a", Field: "balance", Replayed: big.NewInt(8700), Production: big.NewInt(7700), Difference: big.NewInt(1000),
+	})
+}
+
+func TestReplay_BalanceMissingFromSlice(t *testing.T) {
+	slice := replayTestSlice()
+	slice.Transactions = append(slice.Transactions, &model.Transaction{
+		TransactionID: "txn_other", Source: "bln_a", Destination: "bln_c", Status: StatusApplied,
+		PreciseAmount: big.NewInt(1), CreatedAt: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+	})
+
+	result := Replay(slice, ReplayOptions{})
+	assert.False(t, result.Matches)
+	require.Len(t, result.Failures, 1)
+	assert.Equal(t, "txn_other", result.Failures[0].TransactionID)
+}
+
+func TestExportReplaySlice(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	ctx := context.Background()
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	mockDS.On("GetReplayTransactions", mock.Anything, "ldg_1", from, to).Return([]*model.Transaction{
+		{TransactionID: "txn_1", Source: "bln_b", Destination: "bln_a", Status: StatusApplied, PreciseAmount: big.NewInt(100)},
+	}, nil)
+	for _, id := range []string{"bln_a", "bln_b"} {
+		mockDS.On("GetBalanceByIDLite", mock.Anything, id).Return(&model.Balance{BalanceID: id, LedgerID: "ldg_1", Currency: "USD"}, nil)
+		mockDS.On("GetReplayBalanceAt", mock.Anything, id, from).Return(&model.Balance{BalanceID: id, Balance: big.NewInt(0)}, nil)
+		mockDS.On("GetReplayBalanceAt", mock.Anything, id, to).Return(&model.Balance{BalanceID: id, Balance: big.NewInt(100)}, nil)
+	}
+
+	slice, err := b.ExportReplaySlice(ctx, ReplayExportOptions{LedgerID: "ldg_1", From: from, To: to})
+	require.NoError(t, err)
+	require.Len(t, slice.Balances, 2)
+	assert.Equal(t, "bln_a", slice.Balances[0].BalanceID)
+	assert.Equal(t, "USD", slice.Balances[0].Currency)
+	assert.Equal(t, big.NewInt(100), slice.Production[1].Balance)
+	mockDS.AssertExpectations(t)
+
+	_, err = b.ExportReplaySlice(ctx, ReplayExportOptions{LedgerID: "ldg_1", From: to, To: from})
+	assert.Error(t, err)
+}
@@ -0,0 +1,285 @@
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	redlock "github.com/blnkfinance/blnk/internal/lock"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+	"github.com/wacul/ptr"
+)
+
+const (
+	reportScheduleBatch = 50
+	reportLockTimeout   = 10 * time.Minute
+	reportKeyPrefix     = "reports"
+
+	// reportMaxRows caps the rows a run keeps. Runs with more groups are marked as truncated.
+	reportMaxRows = 10000
+)
+
+// nextReportRun returns the first run time of a schedule strictly after t. Runs fall on the hour, at midnight
+// UTC, on Monday at midnight UTC or on the first of the month at midnight UTC.
+func nextReportRun(t time.Time, schedule string) time.Time {
+	t = t.UTC()
+	var next time.Time
+	switch schedule {
+	case model.ReportScheduleHourly:
+		next = t.Truncate(time.Hour).Add(time.Hour)
+	case model.ReportScheduleDaily:
+		next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+	case model.ReportScheduleWeekly:
+		daysUntilMonday := (8 - int(t.Weekday())) % 7
+		if daysUntilMonday == 0 {
+			daysUntilMonday = 7
+		}
+		next = time.Date(t.Year(), t.Month(), t.Day()+daysUntilMonday, 0, 0, 0, 0, time.UTC)
+	default:
+		next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return next
+}
+
+// CreateReportDefinition saves a report definition. A scheduled report first runs at the next boundary of its
+// schedule.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - definition: The report to create.
+//
+// Returns:
+// - *model.ReportDefinition: The created report.
+// - error: An error if the report is invalid or cannot be saved.
+func (l *Blnk) CreateReportDefinition(ctx context.Context, definition model.ReportDefinition) (*model.ReportDefinition, error) {
+	if err := definition.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	definition.ReportID = model.GenerateUUIDWithSuffix("report")
+	definition.IsActive = true
+	definition.NextRunAt = nil
+	definition.LastRunAt = nil
+	if definition.Schedule != "" {
+		definition.NextRunAt = ptr.Time(nextReportRun(now, definition.Schedule))
+	}
+	definition.CreatedAt = now
+
+	if err := l.datasource.CreateReportDefinition(ctx, &definition); err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+// GetReportDefinition retrieves a report definition by ID.
+func (l *Blnk) GetReportDefinition(ctx context.Context, reportID string) (*model.ReportDefinition, error) {
+	return l.datasource.GetReportDefinition(ctx, reportID)
+}
+
+// ListReportDefinitions lists report definitions, newest first.
+func (l *Blnk) ListReportDefinitions(ctx context.Context, page model.Page) ([]*model.ReportDefinition, error) {
+	return l.datasource.ListReportDefinitions(ctx, page)
+}
+
+// DeleteReportDefinition deletes a report definition along with its runs.
+func (l *Blnk) DeleteReportDefinition(ctx context.Context, reportID string) error {
+	return l.datasource.DeleteReportDefinition(ctx, reportID)
+}
+
+// GetReportRun retrieves a report run by ID.
+func (l *Blnk) GetReportRun(ctx context.Context, runID string) (*model.ReportRun, error) {
+	return l.datasource.GetReportRun(ctx, runID)
+}
+
+// ListReportRuns lists the runs of a report, newest first, without their rows.
+func (l *Blnk) ListReportRuns(ctx context.Context, reportID string, page model.Page) ([]*model.ReportRun, error) {
+	if _, err := l.datasource.GetReportDefinition(ctx, reportID); err != nil {
+		return nil, err
+	}
+	return l.datasource.ListReportRuns(ctx, reportID, page)
+}
+
+// RunReport runs a report immediately, outside its schedule.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - reportID: The ID of the report.
+//
+// Returns:
+// - *model.ReportRun: The run, which may have failed.
+// - error: An error if the report cannot be found or the run cannot be recorded.
+func (l *Blnk) RunReport(ctx context.Context, reportID string) (*model.ReportRun, error) {
+	definition, err := l.datasource.GetReportDefinition(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	return l.executeReport(ctx, definition, model.ReportTriggerManual)
+}
+
+// RunDueReports runs every scheduled report that is due. Each report is locked while it runs so that several
+// workers can call this safely.
+//
+// Parameters:
+// - ctx: The context for the operation.
+//
+// Returns:
+// - int: The number of reports that completed.
+// - error: An error if the due reports cannot be loaded.
+func (l *Blnk) RunDueReports(ctx context.Context) (int, error) {
+	definitions, err := l.datasource.GetDueReportDefinitions(ctx, time.Now(), reportScheduleBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for _, definition := range definitions {
+		locker := redlock.NewLocker(l.redis, "report:"+definition.ReportID, model.GenerateUUIDWithSuffix("loc"))
+		if err := locker.Lock(ctx, reportLockTimeout); err != nil {
+			continue
+		}
+
+		run, err := l.executeReport(ctx, definition, model.ReportTriggerSchedule)
+		if err != nil {
+			logrus.WithError(err).WithField("report_id", definition.ReportID).Error("failed to run report")
+		} else if run.Status == model.ReportRunCompleted {
+			completed++
+		}
+
+		// Advance the report even on failure so that one bad run does not block later ones.
+		now := time.Now()
+		if err := l.datasource.UpdateReportDefinitionRun(ctx, definition.ReportID, now, nextReportRun(now, definition.Schedule)); err != nil {
+			logrus.WithError(err).WithField("report_id", definition.ReportID).Error("failed to advance report schedule")
+		}
+		_ = locker.Unlock(ctx)
+	}
+	return completed, nil
+}
+
+// RenderReportRun renders the rows of a run in a format.
+//
+// Parameters:
+// - run: The run to render.
+// - format: csv, or json for a list of objects keyed by column.
+//
+// Returns:
+// - []byte: The rendered rows.
+// - string: The content type of the rendered rows.
+// - error: An error if the format is unsupported or the rows cannot be rendered.
+func RenderReportRun(run *model.ReportRun, format string) ([]byte, string, error) {
+	switch format {
+	case model.ReportFormatCSV:
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		if err := w.Write(run.Columns); err != nil {
+			return nil, "", err
+		}
+		if err := w.WriteAll(run.Rows); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "text/csv", nil
+	case model.ReportFormatJSON:
+		data, err := json.Marshal(run.Records())
+		if err != nil {
+			return nil, "", err
+		}
+		return data, "application/json", nil
+	}
+	return nil, "", fmt.Errorf("unsupported format %q, expected csv or json", format)
+}
+
+// executeReport computes a report, records the run and pushes it to the report's destination. A run that
+// cannot be computed or delivered is recorded as failed rather than returned as an error.
+func (l *Blnk) executeReport(ctx context.Context, definition *model.ReportDefinition, trigger string) (*model.ReportRun, error) {
+	now := time.Now()
+	run := &model.ReportRun{
+		RunID:     model.GenerateUUIDWithSuffix("report_run"),
+		ReportID:  definition.ReportID,
+		Trigger:   trigger,
+		Status:    model.ReportRunRunning,
+		StartedAt: now,
+	}
+	if err := l.datasource.CreateReportRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	err := l.computeReport(ctx, definition, run, now)
+	if err == nil {
+		err = l.deliverReport(ctx, definition, run)
+	}
+	run.Status = model.ReportRunCompleted
+	if err != nil {
+		run.Status = model.ReportRunFailed
+		run.Error = err.Error()
+	}
+	run.CompletedAt = ptr.Time(time.Now())
+
+	if err := l.datasource.UpdateReportRun(ctx, run); err != nil {
+		return nil, err
+	}
+	if run.Status == model.ReportRunFailed && definition.Destination == model.ReportDestinationWebhook {
+		l.notifyReportRun("report.failed", run)
+	}
+	return run, nil
+}
+
+// computeReport fills in the columns and rows of a run.
+func (l *Blnk) computeReport(ctx context.Context, definition *model.ReportDefinition, run *model.ReportRun, now time.Time) error {
+	columns, rows, truncated, err := l.datasource.RunReportQuery(ctx, definition, now, reportMaxRows)
+	if err != nil {
+		return err
+	}
+	run.Columns = columns
+	run.Rows = rows
+	run.RowCount = len(rows)
+	run.Truncated = truncated
+	return nil
+}
+
+// deliverReport pushes a run to the report's destination: its output is stored in S3, or the run and its
+// rows are sent as a webhook.
+func (l *Blnk) deliverReport(ctx context.Context, definition *model.ReportDefinition, run *model.ReportRun) error {
+	switch definition.Destination {
+	case model.ReportDestinationS3:
+		data, _, err := RenderReportRun(run, definition.Format)
+		if err != nil {
+			return err
+		}
+		store, err := l.getStatementStore()
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s/%s/%s-%s.%s", reportKeyPrefix, definition.ReportID, run.StartedAt.UTC().Format("2006-01-02T150405"), run.RunID, definition.Format)
+		if err := store.Put(ctx, key, data); err != nil {
+			return fmt.Errorf("failed to store report: %w", err)
+		}
+		run.StorageKey = key
+	case model.ReportDestinationWebhook:
+		// Report the run as completed in the webhook; it is recorded as such right after delivery
+		delivered := *run
+		delivered.Status = model.ReportRunCompleted
+		if err := l.SendWebhook(NewWebhook{Event: "report.completed", Payload: delivered}); err != nil {
+			return fmt.Errorf("failed to send report webhook: %w", err)
+		}
+	case "":
+	default:
+		return errors.New("unsupported report destination " + definition.Destination)
+	}
+	return nil
+}
+
+// notifyReportRun sends a webhook about a report run in the background.
+func (l *Blnk) notifyReportRun(event string, run *model.ReportRun) {
+	payload := *run
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
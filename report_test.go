@@ -0,0 +1,178 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextReportRun(t *testing.T) {
+	// A Wednesday afternoon
+	at := time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 4, 16, 0, 0, 0, time.UTC), nextReportRun(at, model.ReportScheduleHourly))
+	assert.Equal(t, time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), nextReportRun(at, model.ReportScheduleDaily))
+	assert.Equal(t, time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), nextReportRun(at, model.ReportScheduleWeekly))
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), nextReportRun(at, model.ReportScheduleMonthly))
+
+	// Runs are strictly after the time they are computed from
+	monday := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC), nextReportRun(monday, model.ReportScheduleWeekly))
+	assert.Equal(t, time.Date(2026, 3, 9, 1, 0, 0, 0, time.UTC), nextReportRun(monday, model.ReportScheduleHourly))
+}
+
+func TestCreateReportDefinition(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("CreateReportDefinition", mock.Anything, mock.AnythingOfType("*model.ReportDefinition")).Return(nil)
+
+	definition, err := b.CreateReportDefinition(context.Background(), model.ReportDefinition{
+		Name:       "Daily volume",
+		Source:     model.ReportSourceTransactions,
+		GroupBy:    []string{"currency"},
+		Aggregates: []model.ReportAggregate{{Function: "sum", Field: "precise_amount"}},
+		Schedule:   model.ReportScheduleDaily,
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(definition.ReportID, "report_"))
+	assert.True(t, definition.IsActive)
+	assert.Equal(t, model.ReportFormatCSV, definition.Format)
+	require.NotNil(t, definition.NextRunAt)
+	assert.True(t, definition.NextRunAt.After(time.Now()))
+
+	_, err = b.CreateReportDefinition(context.Background(), model.ReportDefinition{Name: "No aggregates", Source: model.ReportSourceBalances})
+	assert.Error(t, err)
+	mockDS.AssertNumberOfCalls(t, "CreateReportDefinition", 1)
+}
+
+func TestRunReport_StoresOutputInS3(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	store := &memoryStatementStore{objects: map[string][]byte{}}
+	b.statements = store
+	ctx := context.Background()
+
+	definition := &model.ReportDefinition{
+		ReportID:    "report_1",
+		Source:      model.ReportSourceTransactions,
+		GroupBy:     []string{"currency"},
+		Aggregates:  []model.ReportAggregate{{Function: "count"}},
+		Format:      model.ReportFormatJSON,
+		Destination: model.ReportDestinationS3,
+	}
+	mockDS.On("GetReportDefinition", ctx, "report_1").Return(definition, nil)
+	mockDS.On("CreateReportRun", ctx, mock.AnythingOfType("*model.ReportRun")).Return(nil)
+	mockDS.On("RunReportQuery", ctx, definition, mock.AnythingOfType("time.Time"), reportMaxRows).
+		Return([]string{"currency", "count"}, [][]string{{"EUR", "2"}, {"USD", "5"}}, false, nil)
+	mockDS.On("UpdateReportRun", ctx, mock.AnythingOfType("*model.ReportRun")).Return(nil)
+
+	run, err := b.RunReport(ctx, "report_1")
+	require.NoError(t, err)
+	assert.Equal(t, model.ReportRunCompleted, run.Status)
+	assert.Equal(t, model.ReportTriggerManual, run.Trigger)
+	assert.Equal(t, 2, run.RowCount)
+	assert.NotNil(t, run.CompletedAt)
+	require.NotEmpty(t, run.StorageKey)
+	assert.True(t, strings.HasSuffix(run.StorageKey, run.RunID+".json"))
+
+	var records []map[string]string
+	require.NoError(t, json.Unmarshal(store.objects[run.StorageKey], &records))
+	assert.Equal(t, []map[string]string{{"currency": "EUR", "count": "2"}, {"currency": "USD", "count": "5"}}, records)
+}
+
+func TestRunReport_RecordsQueryFailure(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+
+	definition := &model.ReportDefinition{
+		ReportID:    "report_1",
+		Source:      model.ReportSourceBalances,
+		Aggregates:  []model.ReportAggregate{{Function: "count"}},
+		Format:      model.ReportFormatCSV,
+		Destination: model.ReportDestinationWebhook,
+	}
+	mockDS.On("GetReportDefinition", ctx, "report_1").Return(definition, nil)
+	mockDS.On("CreateReportRun", ctx, mock.AnythingOfType("*model.ReportRun")).Return(nil)
+	mockDS.On("RunReportQuery", ctx, definition, mock.AnythingOfType("time.Time"), reportMaxRows).
+		Return(nil, nil, false, errors.New("statement timeout"))
+	mockDS.On("UpdateReportRun", ctx, mock.AnythingOfType("*model.ReportRun")).Return(nil)
+
+	run, err := b.RunReport(ctx, "report_1")
+	require.NoError(t, err)
+	assert.Equal(t, model.ReportRunFailed, run.Status)
+	assert.Equal(t, "statement timeout", run.Error)
+
+	// The failure is announced since the report is delivered by webhook
+	assert.Eventually(t, pendingWebhooks(mr), time.Second, 10*time.Millisecond)
+}
+
+func TestRunDueReports_AdvancesSchedule(t *testing.T) {
+	b, mockDS, mr := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+
+	definition := &model.ReportDefinition{
+		ReportID:    "report_1",
+		Source:      model.ReportSourceTransactions,
+		Aggregates:  []model.ReportAggregate{{Function: "count"}},
+		Schedule:    model.ReportScheduleHourly,
+		Format:      model.ReportFormatCSV,
+		Destination: model.ReportDestinationWebhook,
+	}
+	mockDS.On("GetDueReportDefinitions", ctx, mock.AnythingOfType("time.Time"), reportScheduleBatch).Return([]*model.ReportDefinition{definition}, nil)
+	mockDS.On("CreateReportRun", ctx, mock.AnythingOfType("*model.ReportRun")).Return(nil)
+	mockDS.On("RunReportQuery", ctx, definition, mock.AnythingOfType("time.Time"), reportMaxRows).
+		Return([]string{"count"}, [][]string{{"12"}}, false, nil)
+	mockDS.On("UpdateReportRun", ctx, mock.MatchedBy(func(run *model.ReportRun) bool {
+		return run.Status == model.ReportRunCompleted && run.Trigger == model.ReportTriggerSchedule
+	})).Return(nil)
+	mockDS.On("UpdateReportDefinitionRun", ctx, "report_1", mock.AnythingOfType("time.Time"), mock.MatchedBy(func(next time.Time) bool {
+		return next.After(time.Now()) && next.Minute() == 0
+	})).Return(nil)
+
+	completed, err := b.RunDueReports(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, completed)
+	mockDS.AssertExpectations(t)
+
+	tasks, err := mr.List("asynq:{webhook_queue}:pending")
+	require.NoError(t, err)
+	assert.Len(t, tasks, 1)
+}
+
+func TestRenderReportRun(t *testing.T) {
+	run := &model.ReportRun{Columns: []string{"currency", "sum_precise_amount"}, Rows: [][]string{{"USD", "123456789012345678901234"}}}
+
+	data, contentType, err := RenderReportRun(run, model.ReportFormatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, "text/csv", contentType)
+	assert.Equal(t, "currency,sum_precise_amount\nUSD,123456789012345678901234\n", string(data))
+
+	data, contentType, err = RenderReportRun(run, model.ReportFormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+	assert.JSONEq(t, `[{"currency":"USD","sum_precise_amount":"123456789012345678901234"}]`, string(data))
+
+	_, _, err = RenderReportRun(run, "xlsx")
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package blnk
+
+import (
+	"context"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// RecordRequestLog stores an entry of the API request log.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - entry *model.RequestLog: The request to record, with its bodies already truncated and redacted.
+//
+// Returns:
+// - error: An error if the entry could not be stored.
+func (l *Blnk) RecordRequestLog(ctx context.Context, entry *model.RequestLog) error {
+	return l.datasource.RecordRequestLog(ctx, entry)
+}
+
+// GetRequestLog retrieves an entry of the request log. An owner limits the lookup to that owner's requests.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - logID string: The ID of the entry.
+// - ownerID string: The owner the entry must belong to, or empty for any owner.
+//
+// Returns:
+// - *model.RequestLog: The entry.
+// - error: An error if the entry could not be found.
+func (l *Blnk) GetRequestLog(ctx context.Context, logID, ownerID string) (*model.RequestLog, error) {
+	return l.datasource.GetRequestLog(ctx, logID, ownerID)
+}
+
+// ListRequestLogs searches the request log, newest first.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - filter model.RequestLogFilter: The criteria the entries must match.
+// - page model.Page: The page of entries to read.
+//
+// Returns:
+// - []*model.RequestLog: The matching entries.
+// - error: An error if the entries could not be retrieved.
+func (l *Blnk) ListRequestLogs(ctx context.Context, filter model.RequestLogFilter, page model.Page) ([]*model.RequestLog, error) {
+	return l.datasource.ListRequestLogs(ctx, filter, page)
+}
+
+// PruneRequestLogs deletes the request log entries older than the configured retention and, beyond the
+// configured maximum number of entries, the oldest ones. A zero retention keeps entries of any age. It prunes
+// even when request logging is disabled, so that turning it off does not keep old entries forever.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - int64: The number of entries deleted.
+// - error: An error if the entries could not be deleted.
+func (l *Blnk) PruneRequestLogs(ctx context.Context) (int64, error) {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return 0, err
+	}
+	var before time.Time
+	if cnf.RequestLog.Retention > 0 {
+		before = time.Now().UTC().Add(-cnf.RequestLog.Retention)
+	}
+	return l.datasource.PruneRequestLogs(ctx, before, cnf.RequestLog.MaxEntries)
+}
@@ -0,0 +1,38 @@
+package blnk
+
+import (
+	"strings"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// applyRoundingPolicy sets the rounding mode of a transaction that does not name one from the mode configured
+// for its currency, falling back to the default mode, and sets the balance that split remainders are posted to.
+//
+// Parameters:
+// - transaction *model.Transaction: The transaction to apply the policy to.
+//
+// Returns:
+// - error: An error if the transaction's or the configured rounding mode is unknown.
+func applyRoundingPolicy(transaction *model.Transaction) error {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return transaction.RoundingMode.Validate()
+	}
+
+	if transaction.RoundingMode == "" {
+		transaction.RoundingMode = roundingModeFor(cnf, transaction.Currency)
+	}
+	transaction.RoundingBalance = cnf.Rounding.DifferenceBalance
+	return transaction.RoundingMode.Validate()
+}
+
+// roundingModeFor returns the rounding mode configured for a currency, falling back to the default mode.
+func roundingModeFor(cnf *config.Configuration, currency string) model.RoundingMode {
+	mode, ok := cnf.Rounding.Currencies[strings.ToUpper(currency)]
+	if !ok {
+		mode = cnf.Rounding.DefaultMode
+	}
+	return model.RoundingMode(strings.ToLower(mode))
+}
@@ -0,0 +1,212 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+// scheduledRetryPolicy returns the retry policy of a transaction. Scheduled transactions without their own
+// policy use the configured default; nil means the transaction is not retried under a policy.
+func scheduledRetryPolicy(transaction *model.Transaction) *model.RetryPolicy {
+	if transaction.RetryPolicy != nil {
+		return transaction.RetryPolicy
+	}
+	if transaction.ScheduledFor.IsZero() {
+		return nil
+	}
+	cnf, err := config.Fetch()
+	if err != nil || cnf.ScheduledRetry.MaxAttempts <= 0 {
+		return nil
+	}
+	transaction.RetryPolicy = &model.RetryPolicy{
+		MaxAttempts:  cnf.ScheduledRetry.MaxAttempts,
+		WindowDays:   cnf.ScheduledRetry.WindowDays,
+		AllowPartial: cnf.ScheduledRetry.AllowPartial,
+	}
+	return transaction.RetryPolicy
+}
+
+// HandleInsufficientFunds applies the retry policy of a transaction that failed for insufficient funds.
+// With partial execution allowed, whatever the source can cover is moved right away. The remainder is
+// requeued for the next attempt until the policy is exhausted, after which the transaction is rejected
+// and recorded as a permanently failed occurrence.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - transaction: The queued transaction that failed.
+// - cause: The error the transaction failed with.
+//
+// Returns:
+// - bool: Whether the transaction has a retry policy. When false the caller handles the failure.
+// - error: An error if the retry could not be queued or the failure could not be recorded.
+func (l *Blnk) HandleInsufficientFunds(ctx context.Context, transaction *model.Transaction, cause error) (bool, error) {
+	policy := scheduledRetryPolicy(transaction)
+	if policy == nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	if policy.FirstFailedAt == nil {
+		policy.FirstFailedAt = &now
+	}
+	if policy.ExecutedAmount == nil {
+		policy.ExecutedAmount = big.NewInt(0)
+	}
+
+	if policy.AllowPartial {
+		if err := l.executePartialScheduledTransaction(ctx, transaction, policy); err != nil {
+			logrus.WithError(err).WithField("transaction_id", transaction.TransactionID).Warn("partial execution of scheduled transaction failed")
+		}
+		if transaction.PreciseAmount.Sign() == 0 {
+			l.sendScheduledRetryWebhook("scheduled_transaction.completed", transaction, "")
+			return true, nil
+		}
+	}
+
+	if policy.Exhausted() {
+		return true, l.failScheduledTransaction(ctx, transaction, cause)
+	}
+
+	policy.Attempts++
+	transaction.ScheduledFor = now.Add(policy.Interval())
+	// Retries keep to the business days of the transaction's rule, but are never rolled back before now.
+	if err := rollScheduledFor(transaction, now); err != nil {
+		return true, fmt.Errorf("failed to roll retry of scheduled transaction: %w", err)
+	}
+	// A new ID keeps the retry from colliding with the task of the attempt that just failed.
+	transaction.TransactionID = model.GenerateUUIDWithSuffix("txn")
+	if err := l.queue.Enqueue(ctx, transaction); err != nil {
+		return true, fmt.Errorf("failed to queue retry of scheduled transaction: %w", err)
+	}
+	l.sendScheduledRetryWebhook("scheduled_transaction.retry_scheduled", transaction, cause.Error())
+	return true, nil
+}
+
+// executePartialScheduledTransaction moves the part of a scheduled transaction the source can cover and
+// reduces the transaction to the remainder.
+func (l *Blnk) executePartialScheduledTransaction(ctx context.Context, transaction *model.Transaction, policy *model.RetryPolicy) error {
+	source, err := l.datasource.GetBalanceByIDLite(ctx, transaction.Source)
+	if err != nil {
+		return err
+	}
+	source.InitializeBalanceFields()
+	available := new(big.Int).Sub(source.Balance, source.InflightDebitBalance)
+	if available.Sign() <= 0 {
+		return nil
+	}
+	if available.Cmp(transaction.PreciseAmount) > 0 {
+		available.Set(transaction.PreciseAmount)
+	}
+
+	partial := *transaction
+	partial.TransactionID = model.GenerateUUIDWithSuffix("txn")
+	partial.Reference = fmt.Sprintf("%s_partial_%d", transaction.Reference, policy.Attempts+1)
+	partial.PreciseAmount = new(big.Int).Set(available)
+	partial.Amount = 0
+	partial.RetryPolicy = nil
+	partial.MetaData = mergeMetadata(map[string]interface{}{}, transaction.MetaData)
+	partial.MetaData["BLNK_PARTIAL_OF"] = transaction.ParentTransaction
+	model.ApplyPrecision(&partial)
+	if _, err := l.RecordTransaction(ctx, &partial); err != nil {
+		return err
+	}
+
+	policy.ExecutedAmount.Add(policy.ExecutedAmount, available)
+	transaction.PreciseAmount = new(big.Int).Sub(transaction.PreciseAmount, available)
+	transaction.Amount = 0
+	if transaction.PreciseAmount.Sign() > 0 {
+		model.ApplyPrecision(transaction)
+	}
+	return nil
+}
+
+// failScheduledTransaction rejects a scheduled transaction whose retries ran out and records it for the failure report.
+func (l *Blnk) failScheduledTransaction(ctx context.Context, transaction *model.Transaction, cause error) error {
+	policy := transaction.RetryPolicy
+	failure := &model.ScheduledTransactionFailure{
+		FailureID:      model.GenerateUUIDWithSuffix("stf"),
+		TransactionID:  transaction.ParentTransaction,
+		Reference:      transaction.Reference,
+		Source:         transaction.Source,
+		Destination:    transaction.Destination,
+		Currency:       transaction.Currency,
+		PreciseAmount:  new(big.Int).Set(transaction.PreciseAmount),
+		ExecutedAmount: policy.ExecutedAmount,
+		Attempts:       policy.Attempts + 1,
+		Reason:         cause.Error(),
+		ScheduledFor:   transaction.ScheduledFor,
+		FirstFailedAt:  *policy.FirstFailedAt,
+		FailedAt:       time.Now(),
+	}
+	if failure.TransactionID == "" {
+		failure.TransactionID = transaction.TransactionID
+	}
+	if err := l.datasource.RecordScheduledTransactionFailure(ctx, failure); err != nil {
+		return err
+	}
+
+	reason := fmt.Sprintf("retries exhausted after %d attempts: %v", failure.Attempts, cause)
+	if _, err := l.RejectTransaction(ctx, transaction, reason); err != nil {
+		return err
+	}
+	l.sendScheduledRetryWebhook("scheduled_transaction.failed", transaction, reason)
+	return nil
+}
+
+// GetScheduledTransactionFailures returns the report of scheduled transactions that failed permanently during a period.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - start: The inclusive start of the period.
+// - end: The exclusive end of the period.
+// - page: The page of failures to read.
+//
+// Returns:
+// - []*model.ScheduledTransactionFailure: The failures, newest first.
+// - error: An error if the period is invalid or the failures could not be retrieved.
+func (l *Blnk) GetScheduledTransactionFailures(ctx context.Context, start, end time.Time, page model.Page) ([]*model.ScheduledTransactionFailure, error) {
+	if !end.After(start) {
+		return nil, errors.New("end must be after start")
+	}
+	failures, err := l.datasource.GetScheduledTransactionFailures(ctx, start, end, page)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ledgerscope.FromContext(ctx); !ok {
+		return failures, nil
+	}
+
+	// Keys restricted to specific ledgers only see failures between balances they can access
+	filtered := []*model.ScheduledTransactionFailure{}
+	for _, failure := range failures {
+		if l.CheckBalanceAccess(ctx, failure.Source) == nil && l.CheckBalanceAccess(ctx, failure.Destination) == nil {
+			filtered = append(filtered, failure)
+		}
+	}
+	return filtered, nil
+}
+
+// sendScheduledRetryWebhook notifies subscribers about the progress of a scheduled transaction's retries.
+func (l *Blnk) sendScheduledRetryWebhook(event string, transaction *model.Transaction, reason string) {
+	payload := map[string]interface{}{
+		"transaction":  *transaction,
+		"retry_policy": transaction.RetryPolicy,
+	}
+	if reason != "" {
+		payload["reason"] = reason
+	}
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newScheduledRetryTestBlnk(t *testing.T, retry config.ScheduledRetryConfig) (*Blnk, *mocks.MockDataSource) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis:          config.RedisConfig{Dns: mr.Addr()},
+		Queue:          config.QueueConfig{WebhookQueue: "webhook_queue", TransactionQueue: "transactions", NumberOfQueues: 1},
+		ScheduledRetry: retry,
+	})
+
+	mockDS := new(mocks.MockDataSource)
+	b, err := NewBlnk(mockDS)
+	require.NoError(t, err)
+	return b, mockDS
+}
+
+func scheduledRetryTransaction() *model.Transaction {
+	return &model.Transaction{
+		TransactionID:     "txn_1",
+		ParentTransaction: "txn_parent",
+		Reference:         "ref_standing_order",
+		Source:            "bln_source",
+		Destination:       "bln_destination",
+		Currency:          "USD",
+		Amount:            100,
+		Precision:         100,
+		PreciseAmount:     big.NewInt(10000),
+		ScheduledFor:      time.Now().Add(-time.Minute),
+	}
+}
+
+func TestHandleInsufficientFunds_NoPolicy(t *testing.T) {
+	b, mockDS := newScheduledRetryTestBlnk(t, config.ScheduledRetryConfig{})
+
+	txn := scheduledRetryTransaction()
+	handled, err := b.HandleInsufficientFunds(context.Background(), txn, errors.New("insufficient funds"))
+	require.NoError(t, err)
+	assert.False(t, handled)
+	assert.Nil(t, txn.RetryPolicy)
+	mockDS.AssertNotCalled(t, "RecordScheduledTransactionFailure", mock.Anything, mock.Anything)
+}
+
+func TestHandleInsufficientFunds_SchedulesRetry(t *testing.T) {
+	b, mockDS := newScheduledRetryTestBlnk(t, config.ScheduledRetryConfig{MaxAttempts: 3, WindowDays: 3})
+
+	txn := scheduledRetryTransaction()
+	handled, err := b.HandleInsufficientFunds(context.Background(), txn, errors.New("insufficient funds"))
+	require.NoError(t, err)
+	assert.True(t, handled)
+
+	require.NotNil(t, txn.RetryPolicy)
+	assert.Equal(t, 1, txn.RetryPolicy.Attempts)
+	assert.NotNil(t, txn.RetryPolicy.FirstFailedAt)
+	assert.NotEqual(t, "txn_1", txn.TransactionID)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), txn.ScheduledFor, time.Minute)
+	mockDS.AssertNotCalled(t, "RecordScheduledTransactionFailure", mock.Anything, mock.Anything)
+}
+
+func TestHandleInsufficientFunds_ExhaustedPolicyRecordsFailure(t *testing.T) {
+	b, mockDS := newScheduledRetryTestBlnk(t, config.ScheduledRetryConfig{})
+
+	firstFailedAt := time.Now().Add(-72 * time.Hour)
+	txn := scheduledRetryTransaction()
+	txn.RetryPolicy = &model.RetryPolicy{MaxAttempts: 2, WindowDays: 3, Attempts: 2, FirstFailedAt: &firstFailedAt}
+
+	mockDS.On("RecordScheduledTransactionFailure", mock.Anything, mock.MatchedBy(func(f *model.ScheduledTransactionFailure) bool {
+		return f.TransactionID == "txn_parent" && f.Attempts == 3 && f.PreciseAmount.Cmp(big.NewInt(10000)) == 0 &&
+			f.ExecutedAmount.Sign() == 0 && f.FirstFailedAt.Equal(firstFailedAt)
+	})).Return(nil)
+	mockDS.On("RecordTransaction", mock.Anything, mock.MatchedBy(func(t *model.Transaction) bool {
+		return t.Status == StatusRejected
+	})).Return(txn, nil)
+
+	handled, err := b.HandleInsufficientFunds(context.Background(), txn, errors.New("insufficient funds"))
+	require.NoError(t, err)
+	assert.True(t, handled)
+	assert.Contains(t, txn.MetaData["blnk_rejection_reason"], "retries exhausted after 3 attempts")
+	mockDS.AssertExpectations(t)
+}
+
+func TestGetScheduledTransactionFailures_InvalidPeriod(t *testing.T) {
+	b, mockDS := newScheduledRetryTestBlnk(t, config.ScheduledRetryConfig{})
+
+	now := time.Now()
+	_, err := b.GetScheduledTransactionFailures(context.Background(), now, now.Add(-time.Hour), model.Page{Limit: 10})
+	assert.ErrorContains(t, err, "end must be after start")
+	mockDS.AssertNotCalled(t, "GetScheduledTransactionFailures", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
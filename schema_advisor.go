@@ -0,0 +1,98 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+)
+
+const (
+	defaultSchemaAdviceLimit    = 20
+	defaultSlowStatementTime    = 100 * time.Millisecond
+	defaultSchemaAdvisorMinRows = 10000
+)
+
+// SchemaAdvisorOptions controls a schema advisor run.
+type SchemaAdvisorOptions struct {
+	Limit             int           // Maximum number of recommendations per check.
+	SlowStatementTime time.Duration // Statements averaging at least this long are reported.
+	MinRows           int64         // Tables smaller than this are not reported by the scan and metadata checks.
+}
+
+// AdviseSchema inspects the statement statistics, indexes and table health of the blnk schema and returns
+// recommendations that keep the ledger fast as it grows. Checks are independent: a failing check is recorded
+// in the report and the others still run. Recommendations are never applied automatically.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - opts SchemaAdvisorOptions: Thresholds for the checks; zero values use the defaults.
+//
+// Returns:
+// - *model.SchemaAdvisorReport: The results of every check and their recommendations.
+func (l *Blnk) AdviseSchema(ctx context.Context, opts SchemaAdvisorOptions) *model.SchemaAdvisorReport {
+	ctx, span := tracer.Start(ctx, "AdviseSchema")
+	defer span.End()
+
+	if opts.Limit <= 0 {
+		opts.Limit = defaultSchemaAdviceLimit
+	}
+	if opts.SlowStatementTime <= 0 {
+		opts.SlowStatementTime = defaultSlowStatementTime
+	}
+	if opts.MinRows <= 0 {
+		opts.MinRows = defaultSchemaAdvisorMinRows
+	}
+
+	report := &model.SchemaAdvisorReport{GeneratedAt: time.Now(), Recommendations: []model.SchemaRecommendation{}}
+	checks := []struct {
+		name string
+		run  func() ([]model.SchemaRecommendation, error)
+	}{
+		{model.SchemaCheckSlowStatements, func() ([]model.SchemaRecommendation, error) {
+			return l.datasource.FindSlowStatements(ctx, opts.SlowStatementTime, opts.Limit)
+		}},
+		{model.SchemaCheckSequentialScans, func() ([]model.SchemaRecommendation, error) {
+			return l.datasource.FindSequentialScanTables(ctx, opts.MinRows, opts.Limit)
+		}},
+		{model.SchemaCheckMetadataIndexes, func() ([]model.SchemaRecommendation, error) {
+			return l.datasource.FindUnindexedMetadata(ctx, opts.MinRows, opts.Limit)
+		}},
+		{model.SchemaCheckUnusedIndexes, func() ([]model.SchemaRecommendation, error) {
+			return l.datasource.FindUnusedIndexes(ctx, opts.Limit)
+		}},
+		{model.SchemaCheckTableMaintenance, func() ([]model.SchemaRecommendation, error) {
+			return l.datasource.FindTablesNeedingMaintenance(ctx, opts.Limit)
+		}},
+	}
+
+	for _, check := range checks {
+		result := model.SchemaCheckResult{Name: check.name}
+		recommendations, err := check.run()
+		if err != nil {
+			span.RecordError(err)
+			result.Error = err.Error()
+		}
+		result.RecommendationCount = len(recommendations)
+		report.Checks = append(report.Checks, result)
+		report.Recommendations = append(report.Recommendations, recommendations...)
+	}
+
+	return report
+}
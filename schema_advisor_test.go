@@ -0,0 +1,51 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAdviseSchema(t *testing.T) {
+	mockDS := new(mocks.MockDataSource)
+	b := &Blnk{datasource: mockDS}
+
+	none := []model.SchemaRecommendation{}
+	mockDS.On("FindSlowStatements", mock.Anything, defaultSlowStatementTime, defaultSchemaAdviceLimit).Return(none, errors.New("permission denied"))
+	mockDS.On("FindSequentialScanTables", mock.Anything, int64(defaultSchemaAdvisorMinRows), defaultSchemaAdviceLimit).Return(none, nil)
+	mockDS.On("FindUnindexedMetadata", mock.Anything, int64(defaultSchemaAdvisorMinRows), defaultSchemaAdviceLimit).Return([]model.SchemaRecommendation{
+		{Check: model.SchemaCheckMetadataIndexes, Table: "transactions", Severity: model.SchemaAdviceWarning},
+	}, nil)
+	mockDS.On("FindUnusedIndexes", mock.Anything, defaultSchemaAdviceLimit).Return(none, nil)
+	mockDS.On("FindTablesNeedingMaintenance", mock.Anything, defaultSchemaAdviceLimit).Return(none, nil)
+
+	report := b.AdviseSchema(context.Background(), SchemaAdvisorOptions{})
+
+	assert.Len(t, report.Checks, 5)
+	assert.Equal(t, "permission denied", report.Checks[0].Error)
+	assert.Equal(t, 1, report.Checks[2].RecommendationCount)
+	assert.Len(t, report.Recommendations, 1)
+	assert.Equal(t, "transactions", report.Recommendations[0].Table)
+	mockDS.AssertExpectations(t)
+}
@@ -19,11 +19,15 @@ package blnk
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
+	"net/http"
 	"strings"
 	"time"
 
+	"github.com/blnkfinance/blnk/internal/resilience"
+	"github.com/blnkfinance/blnk/model"
 	"github.com/sirupsen/logrus"
 	"github.com/typesense/typesense-go/typesense"
 	"github.com/typesense/typesense-go/typesense/api"
@@ -42,15 +46,22 @@ type NotificationPayload struct {
 
 // NewTypesenseClient initializes and returns a new Typesense client instance.
 func NewTypesenseClient(apiKey string, hosts []string) *TypesenseClient {
-	client := typesense.NewClient(
+	opts := []typesense.ClientOption{
 		typesense.WithServer(hosts[0]),
 		typesense.WithAPIKey(apiKey),
-		typesense.WithConnectionTimeout(5*time.Second),
+		typesense.WithConnectionTimeout(5 * time.Second),
 		typesense.WithCircuitBreakerMaxRequests(50),
-		typesense.WithCircuitBreakerInterval(2*time.Minute),
-		typesense.WithCircuitBreakerTimeout(1*time.Minute),
-	)
-	return &TypesenseClient{Client: client}
+		typesense.WithCircuitBreakerInterval(2 * time.Minute),
+		typesense.WithCircuitBreakerTimeout(1 * time.Minute),
+	}
+	// Requests go through the Typesense dependency policy, which replaces the client's own timeout and breaker
+	apiClient, err := api.NewClientWithResponses(hosts[0],
+		api.WithAPIKey(apiKey),
+		api.WithHTTPClient(resilience.Get(resilience.Typesense).Client()))
+	if err == nil {
+		opts = append(opts, typesense.WithAPIClient(apiClient))
+	}
+	return &TypesenseClient{Client: typesense.NewClient(opts...)}
 }
 
 // EnsureCollectionsExist ensures that all the necessary collections exist in the Typesense schema.
@@ -90,6 +101,20 @@ type MultiSearchRequest struct {
 	Searches []api.MultiSearchSearchesParameter `json:"searches"`
 }
 
+// DeleteDocument removes a document from a collection, so it no longer shows up in searches. Documents that were
+// never indexed are ignored.
+func (t *TypesenseClient) DeleteDocument(ctx context.Context, collection, id string) error {
+	_, err := t.Client.Collection(collection).Document(id).Delete(ctx)
+	var httpErr *typesense.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Status == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete document from Typesense: %w", err)
+	}
+	return nil
+}
+
 // Remove the incorrect MultiSearch method and replace with this:
 func (t *TypesenseClient) MultiSearch(ctx context.Context, searchRequests api.MultiSearchSearchesParameter) (*api.MultiSearchResult, error) {
 	return t.Client.MultiSearch.Perform(ctx, &api.MultiSearchParams{}, searchRequests)
@@ -108,6 +133,8 @@ func (t *TypesenseClient) HandleNotification(table string, data map[string]inter
 		return err
 	}
 	t.convertLargeNumbers(table, data)
+	t.liftNarrative(table, data)
+	t.liftPhoneDigits(table, data)
 	t.ensureSchemaFields(table, data)
 	t.normalizeTimeFields(data)
 
@@ -161,6 +188,45 @@ func (t *TypesenseClient) convertNumberField(data map[string]interface{}, field
 	}
 }
 
+// liftNarrative copies the enriched narrative of a transaction out of its metadata into fields of its own, so
+// transactions can be searched and faceted by merchant and category.
+func (t *TypesenseClient) liftNarrative(table string, data map[string]interface{}) {
+	if table != "transactions" {
+		return
+	}
+	metaData, _ := data["meta_data"].(map[string]interface{})
+	narrative, ok := metaData[model.NarrativeMetaKey].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for field, key := range map[string]string{"narrative": "description", "merchant_name": "merchant_name", "category": "category"} {
+		if value, ok := narrative[key].(string); ok && value != "" {
+			data[field] = value
+		}
+	}
+}
+
+// liftPhoneDigits indexes the phone number of an identity without the spaces, dashes and brackets it was written
+// with, so it is found however the digits are grouped.
+func (t *TypesenseClient) liftPhoneDigits(table string, data map[string]interface{}) {
+	if table != "identities" {
+		return
+	}
+	if phone, ok := data["phone_number"].(string); ok && phone != "" {
+		data["phone_digits"] = phoneDigits(phone)
+	}
+}
+
+// phoneDigits strips a phone number down to its digits and leading plus sign.
+func phoneDigits(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '+' || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, phone)
+}
+
 // ensureSchemaFields ensures all required schema fields are present with default values
 func (t *TypesenseClient) ensureSchemaFields(table string, data map[string]interface{}) {
 	latestSchema := getLatestSchema(table)
@@ -386,6 +452,7 @@ func getTransactionSchema() *api.CollectionSchema {
 			{Name: "precision", Type: "float", Facet: &facet},
 			{Name: "transaction_id", Type: "string", Facet: &facet},
 			{Name: "parent_transaction", Type: "string", Facet: &facet},
+			{Name: "group_id", Type: "string", Facet: &facet, Optional: &enableNested},
 			{Name: "source", Type: "string", Facet: &facet},
 			{Name: "destination", Type: "string", Facet: &facet},
 			{Name: "reference", Type: "string", Facet: &facet},
@@ -401,6 +468,9 @@ func getTransactionSchema() *api.CollectionSchema {
 			{Name: "scheduled_for", Type: "int64", Facet: &facet},
 			{Name: "inflight_expiry_date", Type: "int64", Facet: &facet},
 			{Name: "meta_data", Type: "object", Facet: &facet, Optional: &enableNested},
+			{Name: "narrative", Type: "string", Optional: &enableNested},
+			{Name: "merchant_name", Type: "string", Facet: &facet, Optional: &enableNested},
+			{Name: "category", Type: "string", Facet: &facet, Optional: &enableNested},
 		},
 		DefaultSortingField: &sortBy,
 		EnableNestedFields:  &enableNested,
@@ -453,6 +523,10 @@ func getIdentitySchema() *api.CollectionSchema {
 			{Name: "dob", Type: "int64", Facet: &facet},
 			{Name: "created_at", Type: "int64", Facet: &facet},
 			{Name: "meta_data", Type: "object", Facet: &facet, Optional: &enableNested},
+			{Name: "locale", Type: "string", Facet: &facet, Optional: &enableNested},
+			{Name: "timezone", Type: "string", Facet: &facet, Optional: &enableNested},
+			{Name: "tags", Type: "string[]", Facet: &facet, Optional: &enableNested},
+			{Name: "phone_digits", Type: "string", Optional: &enableNested},
 		},
 		DefaultSortingField: &sortBy,
 		EnableNestedFields:  &enableNested,
@@ -0,0 +1,401 @@
+package blnk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+// SeedOptions controls the demo data generated by Seed. The same profile, seed and sizes always produce the
+// same plan, so demos and load tests are reproducible; only the generated IDs differ between runs.
+type SeedOptions struct {
+	Profile string    `json:"profile"`
+	Users   int       `json:"users"`
+	Months  int       `json:"months"`
+	Seed    int64     `json:"seed"`
+	Now     time.Time `json:"-"`
+}
+
+// SeedReport summarises the data provisioned by Seed.
+type SeedReport struct {
+	Profile         string            `json:"profile"`
+	Seed            int64             `json:"seed"`
+	RunID           string            `json:"run_id"`
+	Ledgers         map[string]string `json:"ledgers"`
+	Identities      int               `json:"identities"`
+	Balances        int               `json:"balances"`
+	Transactions    int               `json:"transactions"`
+	FailedPostings  int               `json:"failed_postings"`
+	TransactionsBy  map[string]int    `json:"transactions_by_category"`
+	PeriodStart     time.Time         `json:"period_start"`
+	PeriodEnd       time.Time         `json:"period_end"`
+	DurationSeconds float64           `json:"duration_seconds"`
+}
+
+// seedProfile describes the shape of the data provisioned for a profile.
+type seedProfile struct {
+	currency     string
+	precision    float64
+	customerName string
+	internalName string
+	plan         func(rng *rand.Rand, opts SeedOptions) seedPlan
+}
+
+// seedProfiles are the profiles accepted by Seed.
+var seedProfiles = map[string]seedProfile{
+	"fintech-wallet": {
+		currency:     "USD",
+		precision:    100,
+		customerName: "Customer Wallets",
+		internalName: "Wallet Operations",
+		plan:         planFintechWallet,
+	},
+}
+
+// SeedProfiles returns the names of the available seed profiles.
+func SeedProfiles() []string {
+	names := make([]string, 0, len(seedProfiles))
+	for name := range seedProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// seedUser is a customer provisioned by the plan.
+type seedUser struct {
+	FirstName string
+	LastName  string
+	Email     string
+	City      string
+	Salary    float64
+	PayDay    int
+}
+
+// seedPosting is a transaction provisioned by the plan. User fields index into seedPlan.Users; -1 means
+// the internal account named in Source or Destination.
+type seedPosting struct {
+	At          time.Time
+	Category    string
+	Description string
+	Amount      float64
+	FromUser    int
+	ToUser      int
+	Source      string
+	Destination string
+	Merchant    string
+}
+
+// seedMerchantCategory is a kind of card spend with its relative frequency and median amount.
+type seedMerchantCategory struct {
+	name      string
+	weight    int
+	median    float64
+	merchants []string
+}
+
+// seedPlan is the full set of users and postings for a seed run, ordered by time.
+type seedPlan struct {
+	Users    []seedUser
+	Postings []seedPosting
+	Start    time.Time
+	End      time.Time
+}
+
+var (
+	seedFirstNames = []string{"Amara", "Ben", "Chen", "Dara", "Elena", "Femi", "Grace", "Hiro", "Ines", "Jamal", "Kofi", "Lena", "Mateo", "Nia", "Omar", "Priya", "Quinn", "Rosa", "Sam", "Tomi", "Uma", "Victor", "Wen", "Yara", "Zane"}
+	seedLastNames  = []string{"Adeyemi", "Brown", "Castillo", "Dubois", "Eriksen", "Fischer", "Garcia", "Hassan", "Ito", "Johnson", "Kowalski", "Lopez", "Mensah", "Nguyen", "Okafor", "Patel", "Rossi", "Schmidt", "Tanaka", "Williams"}
+	seedCities     = []string{"Austin", "Boston", "Chicago", "Denver", "Miami", "New York", "Portland", "San Francisco", "Seattle"}
+
+	// seedMerchantCategories are the card spend categories of the fintech-wallet profile.
+	seedMerchantCategories = []seedMerchantCategory{
+		{"groceries", 30, 45, []string{"FreshMart", "Green Basket", "City Grocer"}},
+		{"dining", 22, 24, []string{"Corner Bistro", "Noodle House", "Taco Stand"}},
+		{"transport", 18, 14, []string{"Metro Transit", "RideNow", "FuelStop"}},
+		{"shopping", 12, 60, []string{"Urban Threads", "HomeGoods Depot", "TechHub"}},
+		{"subscriptions", 10, 12, []string{"StreamFlix", "CloudDrive", "MusicBox"}},
+		{"travel", 3, 320, []string{"SkyWays", "StayInn"}},
+		{"utilities", 5, 85, []string{"PowerGrid", "AquaWorks"}},
+	}
+)
+
+// seedInternalAccounts are the internal balances of the fintech-wallet profile.
+var seedInternalAccounts = []string{seedPayrollAccount, seedCardAccount, seedFeeAccount}
+
+const (
+	seedPayrollAccount    = "payroll-funding"
+	seedCardAccount       = "card-settlement"
+	seedFeeAccount        = "fee-revenue"
+	seedMonthlyFee        = 2.99
+	seedCardSpendPerMonth = 18
+	seedTransfersPerMonth = 3
+)
+
+// planFintechWallet plans a consumer wallet product: monthly salary deposits, card spend with a long-tailed
+// amount distribution, peer-to-peer transfers and a monthly account fee. Spending never exceeds the planned
+// balance of a wallet, so every posting applies without overdraft.
+func planFintechWallet(rng *rand.Rand, opts SeedOptions) seedPlan {
+	end := opts.Now.UTC().Truncate(24 * time.Hour)
+	start := end.AddDate(0, -opts.Months, 0)
+	plan := seedPlan{Start: start, End: end}
+
+	for i := 0; i < opts.Users; i++ {
+		first := seedFirstNames[rng.Intn(len(seedFirstNames))]
+		last := seedLastNames[rng.Intn(len(seedLastNames))]
+		plan.Users = append(plan.Users, seedUser{
+			FirstName: first,
+			LastName:  last,
+			Email:     fmt.Sprintf("%s.%s.%d@example.com", strings.ToLower(first), strings.ToLower(last), i+1),
+			City:      seedCities[rng.Intn(len(seedCities))],
+			Salary:    roundCents(math.Max(1200, rng.NormFloat64()*900+3600)),
+			PayDay:    []int{1, 15, 25, 28}[rng.Intn(4)],
+		})
+	}
+
+	postings := []seedPosting{}
+	for month := 0; month < opts.Months; month++ {
+		monthStart := start.AddDate(0, month, 0)
+		days := monthStart.AddDate(0, 1, 0).Sub(monthStart).Hours() / 24
+
+		for u, user := range plan.Users {
+			payDay := time.Date(monthStart.Year(), monthStart.Month(), 1, 9, 0, 0, 0, time.UTC).AddDate(0, 0, user.PayDay-1)
+			if payDay.Before(monthStart) {
+				payDay = payDay.AddDate(0, 1, 0)
+			}
+			postings = append(postings, seedPosting{
+				At: payDay, Category: "salary", Description: "Salary deposit", Amount: user.Salary,
+				FromUser: -1, ToUser: u, Source: seedPayrollAccount,
+			})
+
+			postings = append(postings, seedPosting{
+				At: monthStart.Add(6 * time.Hour), Category: "fee", Description: "Monthly account fee", Amount: seedMonthlyFee,
+				FromUser: u, ToUser: -1, Destination: seedFeeAccount,
+			})
+
+			for n := poisson(rng, seedCardSpendPerMonth); n > 0; n-- {
+				category := pickMerchantCategory(rng)
+				amount := roundCents(math.Min(category.median*math.Exp(rng.NormFloat64()*0.7), category.median*12))
+				merchant := category.merchants[rng.Intn(len(category.merchants))]
+				postings = append(postings, seedPosting{
+					At: seedTimeInMonth(rng, monthStart, days), Category: category.name, Description: "Card purchase at " + merchant,
+					Amount: math.Max(amount, 0.5), FromUser: u, ToUser: -1, Destination: seedCardAccount, Merchant: merchant,
+				})
+			}
+
+			if len(plan.Users) > 1 {
+				for n := poisson(rng, seedTransfersPerMonth); n > 0; n-- {
+					to := rng.Intn(len(plan.Users) - 1)
+					if to >= u {
+						to++
+					}
+					postings = append(postings, seedPosting{
+						At: seedTimeInMonth(rng, monthStart, days), Category: "p2p", Description: "Transfer to " + plan.Users[to].FirstName,
+						Amount: roundCents(10 + rng.ExpFloat64()*60), FromUser: u, ToUser: to,
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(postings, func(i, j int) bool { return postings[i].At.Before(postings[j].At) })
+
+	// Drop spending a wallet could not cover at that point in time, as a real wallet would decline it.
+	balances := make([]float64, len(plan.Users))
+	for _, posting := range postings {
+		if !posting.At.Before(end) {
+			continue
+		}
+		if posting.FromUser >= 0 {
+			if balances[posting.FromUser] < posting.Amount {
+				continue
+			}
+			balances[posting.FromUser] = roundCents(balances[posting.FromUser] - posting.Amount)
+		}
+		if posting.ToUser >= 0 {
+			balances[posting.ToUser] = roundCents(balances[posting.ToUser] + posting.Amount)
+		}
+		plan.Postings = append(plan.Postings, posting)
+	}
+	return plan
+}
+
+// pickMerchantCategory picks a card spend category according to the category weights.
+func pickMerchantCategory(rng *rand.Rand) seedMerchantCategory {
+	total := 0
+	for _, category := range seedMerchantCategories {
+		total += category.weight
+	}
+	pick := rng.Intn(total)
+	for _, category := range seedMerchantCategories {
+		if pick < category.weight {
+			return category
+		}
+		pick -= category.weight
+	}
+	return seedMerchantCategories[0]
+}
+
+// seedTimeInMonth returns a random time in the month, weighted towards waking hours.
+func seedTimeInMonth(rng *rand.Rand, monthStart time.Time, days float64) time.Time {
+	day := rng.Intn(int(days))
+	hour := 7 + rng.Intn(16)
+	return monthStart.AddDate(0, 0, day).Add(time.Duration(hour)*time.Hour + time.Duration(rng.Intn(3600))*time.Second)
+}
+
+// poisson draws from a Poisson distribution with the given mean.
+func poisson(rng *rand.Rand, mean float64) int {
+	limit := math.Exp(-mean)
+	k, p := 0, 1.0
+	for {
+		p *= rng.Float64()
+		if p <= limit {
+			return k
+		}
+		k++
+	}
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// Seed provisions demo data for a profile: ledgers, identities, balances and months of back-dated
+// transactions. Transactions are posted synchronously so the balances are final when Seed returns.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - opts: The profile, sizes and random seed to use.
+//
+// Returns:
+// - *SeedReport: A summary of the provisioned data.
+// - error: An error if the profile is unknown or the ledgers, identities or balances could not be created.
+func (l *Blnk) Seed(ctx context.Context, opts SeedOptions) (*SeedReport, error) {
+	profile, ok := seedProfiles[opts.Profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown seed profile %q, available profiles: %s", opts.Profile, strings.Join(SeedProfiles(), ", "))
+	}
+	if opts.Users < 1 {
+		return nil, fmt.Errorf("users must be at least 1")
+	}
+	if opts.Months < 1 {
+		return nil, fmt.Errorf("months must be at least 1")
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	began := time.Now()
+	plan := profile.plan(rand.New(rand.NewSource(opts.Seed)), opts)
+	runID := model.GenerateUUIDWithSuffix("seed")
+	report := &SeedReport{
+		Profile:        opts.Profile,
+		Seed:           opts.Seed,
+		RunID:          runID,
+		Ledgers:        map[string]string{},
+		TransactionsBy: map[string]int{},
+		PeriodStart:    plan.Start,
+		PeriodEnd:      plan.End,
+	}
+	seedMeta := map[string]interface{}{"BLNK_SEED_RUN": runID, "BLNK_SEED_PROFILE": opts.Profile}
+
+	customerLedger, err := l.CreateLedger(ctx, model.Ledger{Name: profile.customerName, MetaData: seedMeta})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ledger: %w", err)
+	}
+	report.Ledgers[profile.customerName] = customerLedger.LedgerID
+	internalLedger, err := l.CreateLedger(ctx, model.Ledger{Name: profile.internalName, MetaData: seedMeta})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ledger: %w", err)
+	}
+	report.Ledgers[profile.internalName] = internalLedger.LedgerID
+
+	internal := map[string]string{}
+	for _, account := range seedInternalAccounts {
+		balance, err := l.CreateBalance(ctx, model.Balance{
+			LedgerID: internalLedger.LedgerID,
+			Currency: profile.currency,
+			MetaData: mergeMetadata(map[string]interface{}{"account": account}, seedMeta),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create balance: %w", err)
+		}
+		internal[account] = balance.BalanceID
+		report.Balances++
+	}
+
+	wallets := make([]string, len(plan.Users))
+	for i, user := range plan.Users {
+		identity, err := l.CreateIdentity(ctx, model.Identity{
+			IdentityType: model.IdentityTypeIndividual,
+			FirstName:    user.FirstName,
+			LastName:     user.LastName,
+			EmailAddress: user.Email,
+			City:         user.City,
+			Country:      "US",
+			Category:     "customer",
+			MetaData:     seedMeta,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create identity: %w", err)
+		}
+		report.Identities++
+
+		balance, err := l.CreateBalance(ctx, model.Balance{
+			LedgerID:   customerLedger.LedgerID,
+			IdentityID: identity.IdentityID,
+			Currency:   profile.currency,
+			MetaData:   seedMeta,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create balance: %w", err)
+		}
+		wallets[i] = balance.BalanceID
+		report.Balances++
+	}
+
+	for i, posting := range plan.Postings {
+		source, destination := internal[posting.Source], internal[posting.Destination]
+		if posting.FromUser >= 0 {
+			source = wallets[posting.FromUser]
+		}
+		if posting.ToUser >= 0 {
+			destination = wallets[posting.ToUser]
+		}
+		effective := posting.At
+		meta := map[string]interface{}{"BLNK_SEED_RUN": runID, "category": posting.Category}
+		if posting.Merchant != "" {
+			meta["merchant"] = posting.Merchant
+		}
+
+		_, err := l.RecordTransaction(ctx, &model.Transaction{
+			Source:         source,
+			Destination:    destination,
+			Amount:         posting.Amount,
+			Precision:      profile.precision,
+			Currency:       profile.currency,
+			Reference:      fmt.Sprintf("%s_%d", runID, i+1),
+			Description:    posting.Description,
+			EffectiveDate:  &effective,
+			AllowOverdraft: posting.FromUser < 0,
+			SkipQueue:      true,
+			MetaData:       meta,
+		})
+		if err != nil {
+			report.FailedPostings++
+			logrus.WithError(err).WithField("reference", fmt.Sprintf("%s_%d", runID, i+1)).Warn("failed to post seed transaction")
+			continue
+		}
+		report.Transactions++
+		report.TransactionsBy[posting.Category]++
+	}
+
+	report.DurationSeconds = time.Since(began).Seconds()
+	return report, nil
+}
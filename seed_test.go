@@ -0,0 +1,94 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seedTestOptions(seed int64) SeedOptions {
+	return SeedOptions{Profile: "fintech-wallet", Users: 20, Months: 3, Seed: seed, Now: time.Date(2026, 6, 15, 10, 0, 0, 0, time.UTC)}
+}
+
+func TestPlanFintechWallet_Reproducible(t *testing.T) {
+	opts := seedTestOptions(42)
+	first := planFintechWallet(rand.New(rand.NewSource(opts.Seed)), opts)
+	second := planFintechWallet(rand.New(rand.NewSource(opts.Seed)), opts)
+	assert.Equal(t, first, second)
+
+	other := seedTestOptions(7)
+	third := planFintechWallet(rand.New(rand.NewSource(other.Seed)), other)
+	assert.NotEqual(t, first.Postings, third.Postings)
+}
+
+func TestPlanFintechWallet_Shape(t *testing.T) {
+	opts := seedTestOptions(42)
+	plan := planFintechWallet(rand.New(rand.NewSource(opts.Seed)), opts)
+
+	require.Len(t, plan.Users, 20)
+	assert.Equal(t, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), plan.Start)
+	assert.Equal(t, time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC), plan.End)
+
+	categories := map[string]int{}
+	balances := make([]float64, len(plan.Users))
+	for i, posting := range plan.Postings {
+		categories[posting.Category]++
+		assert.Greater(t, posting.Amount, 0.0)
+		assert.False(t, posting.At.Before(plan.Start))
+		assert.True(t, posting.At.Before(plan.End))
+		if i > 0 {
+			assert.False(t, posting.At.Before(plan.Postings[i-1].At), "postings are ordered by time")
+		}
+
+		// No wallet is ever overdrawn
+		if posting.FromUser >= 0 {
+			balances[posting.FromUser] = roundCents(balances[posting.FromUser] - posting.Amount)
+			assert.GreaterOrEqual(t, balances[posting.FromUser], 0.0)
+		} else {
+			assert.NotEmpty(t, posting.Source)
+		}
+		if posting.ToUser >= 0 {
+			balances[posting.ToUser] = roundCents(balances[posting.ToUser] + posting.Amount)
+		} else {
+			assert.NotEmpty(t, posting.Destination)
+		}
+	}
+
+	assert.Equal(t, 60, categories["salary"])
+	assert.Greater(t, categories["groceries"], categories["travel"])
+	assert.Greater(t, categories["p2p"], 0)
+}
+
+func TestSeed_Validation(t *testing.T) {
+	b, _ := newCardAuthorizationTestBlnk(t)
+	ctx := context.Background()
+
+	_, err := b.Seed(ctx, SeedOptions{Profile: "bank", Users: 1, Months: 1})
+	assert.ErrorContains(t, err, "fintech-wallet")
+
+	_, err = b.Seed(ctx, SeedOptions{Profile: "fintech-wallet", Users: 0, Months: 1})
+	assert.ErrorContains(t, err, "users")
+
+	_, err = b.Seed(ctx, SeedOptions{Profile: "fintech-wallet", Users: 1, Months: 0})
+	assert.ErrorContains(t, err, "months")
+}
@@ -0,0 +1,195 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/servicetoken"
+	"github.com/blnkfinance/blnk/model"
+)
+
+var (
+	ErrInvalidServiceAccountCredentials = errors.New("invalid service account credentials")
+	ErrServiceAccountRevoked            = errors.New("service account has been revoked")
+	ErrScopeNotGranted                  = errors.New("requested scope is not granted to the service account")
+)
+
+// CreateServiceAccount creates a service account for the specified owner
+//
+// Parameters:
+// - ctx: The context for the operation
+// - name: Name of the service account
+// - ownerID: ID of the account owner
+// - scopes: List of permission scopes the account may request tokens for
+//
+// Returns:
+// - *model.ServiceAccount: The created service account
+// - string: The account secret. It is only returned here and cannot be retrieved later.
+// - error: An error if the operation fails
+func (l *Blnk) CreateServiceAccount(ctx context.Context, name, ownerID string, scopes []string) (*model.ServiceAccount, string, error) {
+	account, secret, err := model.NewServiceAccount(name, ownerID, scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := l.datasource.CreateServiceAccount(ctx, account); err != nil {
+		return nil, "", err
+	}
+	return account, secret, nil
+}
+
+// ListServiceAccounts retrieves all service accounts for a specific owner
+//
+// Parameters:
+// - ctx: The context for the operation
+// - ownerID: ID of the account owner
+//
+// Returns:
+// - []*model.ServiceAccount: List of service accounts
+// - error: An error if the operation fails
+func (l *Blnk) ListServiceAccounts(ctx context.Context, ownerID string) ([]*model.ServiceAccount, error) {
+	return l.datasource.ListServiceAccounts(ctx, ownerID)
+}
+
+// RevokeServiceAccount revokes a service account. Tokens already issued to it stop working immediately.
+//
+// Parameters:
+// - ctx: The context for the operation
+// - id: ID of the service account to revoke
+// - ownerID: ID of the account owner
+//
+// Returns:
+// - error: An error if the operation fails
+func (l *Blnk) RevokeServiceAccount(ctx context.Context, id, ownerID string) error {
+	return l.datasource.RevokeServiceAccount(ctx, id, ownerID)
+}
+
+// IssueServiceToken exchanges service account credentials for a short-lived signed token.
+// The token carries the requested scopes, which must be a subset of the account's scopes;
+// all of the account's scopes are granted when none are requested.
+//
+// Parameters:
+// - ctx: The context for the operation
+// - id: ID of the service account
+// - secret: The service account secret
+// - scopes: The scopes requested for the token
+//
+// Returns:
+// - *model.ServiceToken: The issued token and its expiry
+// - error: An error if the credentials are invalid or a scope is not granted
+func (l *Blnk) IssueServiceToken(ctx context.Context, id, secret string, scopes []string) (*model.ServiceToken, error) {
+	account, err := l.datasource.GetServiceAccount(ctx, id)
+	if err != nil || !account.VerifySecret(secret) {
+		return nil, ErrInvalidServiceAccountCredentials
+	}
+	if account.IsRevoked {
+		return nil, ErrServiceAccountRevoked
+	}
+
+	granted, err := grantScopes(account.Scopes, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ttl, err := serviceTokenSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	token, err := servicetoken.Sign(servicetoken.Claims{
+		ServiceAccountID: account.ServiceAccountID,
+		OwnerID:          account.OwnerID,
+		Scopes:           granted,
+		IssuedAt:         now.Unix(),
+		ExpiresAt:        expiresAt.Unix(),
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = l.datasource.UpdateServiceAccountTokenIssued(ctx, account.ServiceAccountID)
+
+	return &model.ServiceToken{
+		Token:     token,
+		TokenType: "Bearer",
+		Scopes:    granted,
+		ExpiresAt: time.Unix(expiresAt.Unix(), 0),
+	}, nil
+}
+
+// ValidateServiceToken checks a service token's signature and expiry and that its service account
+// has not been revoked since the token was issued.
+//
+// Parameters:
+// - ctx: The context for the operation
+// - token: The service token
+//
+// Returns:
+// - *servicetoken.Claims: The claims carried by the token
+// - error: An error if the token is not valid
+func (l *Blnk) ValidateServiceToken(ctx context.Context, token string) (*servicetoken.Claims, error) {
+	key, _, err := serviceTokenSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := servicetoken.Verify(token, key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := l.datasource.GetServiceAccount(ctx, claims.ServiceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.IsRevoked {
+		return nil, ErrServiceAccountRevoked
+	}
+
+	return claims, nil
+}
+
+// grantScopes returns the requested scopes if the account holds all of them, or every account scope
+// when none are requested.
+func grantScopes(accountScopes, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return accountScopes, nil
+	}
+
+	held := make(map[string]bool, len(accountScopes))
+	for _, scope := range accountScopes {
+		held[scope] = true
+	}
+	for _, scope := range requested {
+		if !held[scope] {
+			return nil, fmt.Errorf("%w: %s", ErrScopeNotGranted, scope)
+		}
+	}
+	return requested, nil
+}
+
+// serviceTokenSettings returns the key used to sign service tokens and their lifetime.
+func serviceTokenSettings() ([]byte, time.Duration, error) {
+	conf, err := config.Fetch()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	key := conf.ServiceAccounts.TokenSigningKey
+	if key == "" {
+		key = conf.Server.SecretKey
+	}
+	if key == "" {
+		return nil, 0, errors.New("no service account token signing key is configured")
+	}
+
+	ttl := conf.ServiceAccounts.TokenTTL
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+	return []byte(key), ttl, nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueAndValidateServiceToken(t *testing.T) {
+	config.ConfigStore.Store(&config.Configuration{
+		Server: config.ServerConfig{SecretKey: "master-key"},
+	})
+
+	mockDS := new(mocks.MockDataSource)
+	b := &Blnk{datasource: mockDS}
+
+	account, secret, err := model.NewServiceAccount("worker", "owner_1", []string{"transactions:write", "balances:read"})
+	require.NoError(t, err)
+
+	mockDS.On("GetServiceAccount", mock.Anything, account.ServiceAccountID).Return(account, nil)
+	mockDS.On("UpdateServiceAccountTokenIssued", mock.Anything, account.ServiceAccountID).Return(nil)
+
+	_, err = b.IssueServiceToken(context.Background(), account.ServiceAccountID, "wrong-secret", nil)
+	assert.ErrorIs(t, err, ErrInvalidServiceAccountCredentials)
+
+	_, err = b.IssueServiceToken(context.Background(), account.ServiceAccountID, secret, []string{"ledgers:write"})
+	assert.ErrorIs(t, err, ErrScopeNotGranted)
+
+	token, err := b.IssueServiceToken(context.Background(), account.ServiceAccountID, secret, []string{"balances:read"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"balances:read"}, token.Scopes)
+	assert.Equal(t, "Bearer", token.TokenType)
+
+	claims, err := b.ValidateServiceToken(context.Background(), token.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "owner_1", claims.OwnerID)
+	assert.Equal(t, []string{"balances:read"}, claims.Scopes)
+
+	account.IsRevoked = true
+	_, err = b.ValidateServiceToken(context.Background(), token.Token)
+	assert.ErrorIs(t, err, ErrServiceAccountRevoked)
+}
@@ -0,0 +1,554 @@
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	redlock "github.com/blnkfinance/blnk/internal/lock"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/blnkfinance/blnk/plugins"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// sessionTTL is how long a session stays open, and how long a completed one can still be read.
+	sessionTTL         = 15 * time.Minute
+	sessionKeyPrefix   = "sessions"
+	sessionLockTimeout = time.Minute
+	sessionLockWait    = 5 * time.Second
+)
+
+// sessionKey returns the Redis key a session is stored under.
+func sessionKey(sessionID string) string {
+	return fmt.Sprintf("%s:%s", sessionKeyPrefix, sessionID)
+}
+
+// CreateSession opens a session that ledgers, identities, balances and transactions can be staged in and then
+// committed together. The session expires, with everything staged in it, if it is not committed within
+// sessionTTL.
+func (l *Blnk) CreateSession(ctx context.Context) (*model.Session, error) {
+	now := time.Now()
+	session := &model.Session{
+		SessionID:  model.GenerateUUIDWithSuffix("ses"),
+		Status:     model.SessionOpen,
+		Operations: []model.SessionOperation{},
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(sessionTTL),
+	}
+	if err := l.saveSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetSession retrieves a session with the operations staged in it. Expired sessions are not found.
+func (l *Blnk) GetSession(ctx context.Context, sessionID string) (*model.Session, error) {
+	data, err := l.redis.Get(ctx, sessionKey(sessionID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Session with ID '%s' not found", sessionID), err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var session model.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	return &session, nil
+}
+
+// saveSession stores a session until it expires. Completed sessions are kept for another sessionTTL so that
+// their outcome can be read.
+func (l *Blnk) saveSession(ctx context.Context, session *model.Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	ttl := time.Until(session.ExpiresAt)
+	if session.Status != model.SessionOpen {
+		ttl = sessionTTL
+	}
+	if ttl <= 0 {
+		return apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Session with ID '%s' has expired", session.SessionID), nil)
+	}
+	return l.redis.Set(ctx, sessionKey(session.SessionID), data, ttl).Err()
+}
+
+// withOpenSession runs fn on an open session while holding the session's lock, and saves the session if fn
+// succeeds, so that operations staged concurrently in one session are not lost.
+func (l *Blnk) withOpenSession(ctx context.Context, sessionID string, fn func(*model.Session) error) (*model.Session, error) {
+	locker := redlock.NewLocker(l.redis, "session:"+sessionID, model.GenerateUUIDWithSuffix("loc"))
+	if err := locker.WaitLock(ctx, sessionLockTimeout, sessionLockWait); err != nil {
+		return nil, fmt.Errorf("failed to acquire session lock: %w", err)
+	}
+	defer func() {
+		if err := locker.Unlock(ctx); err != nil {
+			logrus.Errorf("failed to release lock of session %s: %v", sessionID, err)
+		}
+	}()
+
+	session, err := l.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != model.SessionOpen {
+		return nil, fmt.Errorf("%w: session %s is %s", model.ErrSessionNotOpen, sessionID, session.Status)
+	}
+	if err := fn(session); err != nil {
+		return nil, err
+	}
+	if err := l.saveSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// StageLedger stages the creation of a ledger in a session and returns the ledger with the ID it will be
+// created with.
+func (l *Blnk) StageLedger(ctx context.Context, sessionID string, ledger model.Ledger) (*model.Ledger, error) {
+	if err := l.CheckLedgerAccess(ctx, ""); err != nil {
+		return nil, err
+	}
+	ledger.LedgerID = model.GenerateUUIDWithSuffix("ldg")
+	ledger.CreatedAt = time.Now()
+	_, err := l.withOpenSession(ctx, sessionID, func(session *model.Session) error {
+		session.Operations = append(session.Operations, model.SessionOperation{Type: model.SessionCreateLedger, Ledger: &ledger})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ledger, nil
+}
+
+// StageIdentity stages the creation of an identity in a session and returns the identity with the ID it will
+// be created with.
+func (l *Blnk) StageIdentity(ctx context.Context, sessionID string, identity model.Identity) (*model.Identity, error) {
+	if err := identity.ValidatePreferences(); err != nil {
+		return nil, err
+	}
+	if err := validateIdentityFields(identity.IdentityType, identity.MetaData); err != nil {
+		return nil, err
+	}
+	identity.IdentityID = model.GenerateUUIDWithSuffix("idt")
+	identity.CreatedAt = time.Now()
+	identity.VerificationStatus = model.VerificationUnverified
+	identity.VerificationReason = ""
+	identity.VerificationSubmittedAt, identity.VerifiedAt, identity.VerificationRejectedAt, identity.DeletedAt = nil, nil, nil, nil
+	_, err := l.withOpenSession(ctx, sessionID, func(session *model.Session) error {
+		session.Operations = append(session.Operations, model.SessionOperation{Type: model.SessionCreateIdentity, Identity: &identity})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// StageBalance stages the creation of a balance in a session and returns the balance with the ID it will be
+// created with. Its ledger and identity may be existing ones or ones staged earlier in the session.
+func (l *Blnk) StageBalance(ctx context.Context, sessionID string, balance model.Balance) (*model.Balance, error) {
+	if err := l.CheckLedgerAccess(ctx, balance.LedgerID); err != nil {
+		return nil, err
+	}
+	balance.BalanceID = model.GenerateUUIDWithSuffix("bln")
+	balance.CreatedAt = time.Now()
+	balance.Balance, balance.CreditBalance, balance.DebitBalance = big.NewInt(0), big.NewInt(0), big.NewInt(0)
+	balance.InflightBalance, balance.InflightCreditBalance, balance.InflightDebitBalance = big.NewInt(0), big.NewInt(0), big.NewInt(0)
+	_, err := l.withOpenSession(ctx, sessionID, func(session *model.Session) error {
+		if !sessionStagesLedger(session, balance.LedgerID) {
+			if _, err := l.datasource.GetLedgerByID(ctx, balance.LedgerID); err != nil {
+				return err
+			}
+		}
+		if balance.IdentityID != "" && !sessionStagesIdentity(session, balance.IdentityID) {
+			if _, err := l.datasource.GetIdentityByID(ctx, balance.IdentityID); err != nil {
+				return err
+			}
+		}
+		session.Operations = append(session.Operations, model.SessionOperation{Type: model.SessionCreateBalance, Balance: &balance})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+// StageTransaction stages a transaction in a session and returns it with the ID it will be recorded with. Its
+// source and destination may be existing balances, balances staged earlier in the session or @indicators.
+// Only immediate transfers between two balances can be staged: inflight, scheduled and split transactions are
+// not supported. Funds are checked when the session is committed.
+func (l *Blnk) StageTransaction(ctx context.Context, sessionID string, transaction *model.Transaction) (*model.Transaction, error) {
+	switch {
+	case transaction.Inflight:
+		return nil, errors.New("inflight transactions cannot be staged in a session")
+	case !transaction.ScheduledFor.IsZero():
+		return nil, errors.New("scheduled transactions cannot be staged in a session")
+	case len(transaction.Sources) > 0 || len(transaction.Destinations) > 0:
+		return nil, errors.New("split transactions cannot be staged in a session")
+	case transaction.Source == "" || transaction.Destination == "":
+		return nil, errors.New("source and destination are required")
+	}
+
+	transaction.Status = StatusApplied
+	setTransactionMetadata(transaction)
+	if transaction.PreciseAmount == nil || transaction.PreciseAmount.Sign() <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+
+	_, err := l.withOpenSession(ctx, sessionID, func(session *model.Session) error {
+		for _, operation := range session.Operations {
+			if operation.Transaction != nil && operation.Transaction.Reference == transaction.Reference {
+				return fmt.Errorf("reference %s has already been used", transaction.Reference)
+			}
+		}
+		if err := l.validateTxn(ctx, transaction); err != nil {
+			return err
+		}
+		for _, ref := range []string{transaction.Source, transaction.Destination} {
+			if strings.HasPrefix(ref, "@") || sessionStagesBalance(session, ref) {
+				continue
+			}
+			balance, err := l.datasource.GetBalanceByIDLite(ctx, ref)
+			if err != nil {
+				return err
+			}
+			if scope, ok := ledgerscope.FromContext(ctx); ok {
+				if err := checkBalancesInScope(scope, balance); err != nil {
+					return err
+				}
+			}
+		}
+		session.Operations = append(session.Operations, model.SessionOperation{Type: model.SessionRecordTransaction, Transaction: transaction})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// RollbackSession discards everything staged in a session. Nothing staged in it was written, so nothing has to
+// be undone.
+func (l *Blnk) RollbackSession(ctx context.Context, sessionID string) (*model.Session, error) {
+	return l.withOpenSession(ctx, sessionID, func(session *model.Session) error {
+		now := time.Now()
+		session.Status = model.SessionRolledBack
+		session.CompletedAt = &now
+		return nil
+	})
+}
+
+// CommitSession writes everything staged in a session in one database transaction: its ledgers, identities
+// and balances are created and its transactions are applied in the order they were staged. If any of them
+// fails, such as a transaction without enough funds, nothing is written and the session stays open so that it
+// can be corrected or rolled back. The existing balances the transactions post to are locked while the
+// session is committed.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - sessionID string: The ID of the session.
+//
+// Returns:
+// - *model.Session: The committed session, with the resources as written.
+// - error: An error if the session is not open or any of its operations fails.
+func (l *Blnk) CommitSession(ctx context.Context, sessionID string) (*model.Session, error) {
+	ctx, span := tracer.Start(ctx, "CommitSession")
+	defer span.End()
+
+	var work *model.UnitOfWork
+	session, err := l.withOpenSession(ctx, sessionID, func(session *model.Session) error {
+		var err error
+		work, err = l.commitSession(ctx, session)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		session.Status = model.SessionCommitted
+		session.CompletedAt = &now
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	l.postSessionActions(ctx, work)
+	return session, nil
+}
+
+// sessionWork collects the balances a session's transactions post to while they are applied.
+type sessionWork struct {
+	created  map[string]*model.Balance // Balances the session creates, by ID
+	existing map[string]*model.Balance // Existing balances the session posts to, by ID
+	work     *model.UnitOfWork
+}
+
+// commitSession applies a session's operations and writes them, returning what was written.
+func (l *Blnk) commitSession(ctx context.Context, session *model.Session) (*model.UnitOfWork, error) {
+	state := &sessionWork{
+		created:  make(map[string]*model.Balance),
+		existing: make(map[string]*model.Balance),
+		work:     &model.UnitOfWork{},
+	}
+	for _, operation := range session.Operations {
+		switch operation.Type {
+		case model.SessionCreateLedger:
+			state.work.Ledgers = append(state.work.Ledgers, operation.Ledger)
+		case model.SessionCreateIdentity:
+			state.work.Identities = append(state.work.Identities, operation.Identity)
+		case model.SessionCreateBalance:
+			state.created[operation.Balance.BalanceID] = operation.Balance
+			state.work.Balances = append(state.work.Balances, operation.Balance)
+		}
+	}
+
+	// Resolve the balances of every transaction first, so that the existing ones can be locked before they are read
+	var transactions []*model.Transaction
+	for _, operation := range session.Operations {
+		if operation.Type != model.SessionRecordTransaction {
+			continue
+		}
+		transaction := operation.Transaction
+		for _, ref := range []*string{&transaction.Source, &transaction.Destination} {
+			id, err := l.resolveSessionBalance(ctx, state, *ref, transaction.Currency)
+			if err != nil {
+				return nil, err
+			}
+			*ref = id
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	lockers, err := l.lockSessionBalances(ctx, state)
+	defer func() {
+		for _, locker := range lockers {
+			l.releaseLock(ctx, locker)
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	for id := range state.existing {
+		balance, err := l.getPostingBalance(ctx, id, "", cfg.Transaction.EnableQueuedChecks)
+		if err != nil {
+			return nil, err
+		}
+		if balance.ShardCount > 0 {
+			return nil, fmt.Errorf("balance %s is sharded and cannot be posted to in a session", id)
+		}
+		state.existing[id] = balance
+		state.work.UpdatedBalances = append(state.work.UpdatedBalances, balance)
+	}
+	if err := l.checkLedgerMaintenance(ctx, state.ledgerIDs()...); err != nil {
+		return nil, err
+	}
+
+	for i, transaction := range transactions {
+		applied, err := l.applySessionTransaction(ctx, state, transaction)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (reference %s): %w", i+1, transaction.Reference, err)
+		}
+		state.work.Transactions = append(state.work.Transactions, applied)
+	}
+
+	if err := l.datasource.CommitUnitOfWork(ctx, state.work); err != nil {
+		return nil, err
+	}
+
+	// Report the transactions as they were recorded
+	applied := 0
+	for i := range session.Operations {
+		if session.Operations[i].Type == model.SessionRecordTransaction {
+			session.Operations[i].Transaction = state.work.Transactions[applied]
+			applied++
+		}
+	}
+	return state.work, nil
+}
+
+// resolveSessionBalance returns the ID of the balance a transaction in a session names: a balance the session
+// creates, an existing balance or the balance of an @indicator, which the session creates in the general
+// ledger if it does not exist yet.
+func (l *Blnk) resolveSessionBalance(ctx context.Context, state *sessionWork, ref, currency string) (string, error) {
+	if _, ok := state.created[ref]; ok {
+		return ref, nil
+	}
+	if !strings.HasPrefix(ref, "@") {
+		state.existing[ref] = nil
+		return ref, nil
+	}
+
+	for _, balance := range state.created {
+		if balance.Indicator == ref && balance.Currency == currency {
+			return balance.BalanceID, nil
+		}
+	}
+	if balance, err := l.datasource.GetBalanceByIndicator(ctx, ref, currency); err == nil {
+		state.existing[balance.BalanceID] = nil
+		return balance.BalanceID, nil
+	}
+	balance := &model.Balance{
+		BalanceID:             model.GenerateUUIDWithSuffix("bln"),
+		Indicator:             ref,
+		LedgerID:              GeneralLedgerID,
+		Currency:              currency,
+		Balance:               big.NewInt(0),
+		CreditBalance:         big.NewInt(0),
+		DebitBalance:          big.NewInt(0),
+		InflightBalance:       big.NewInt(0),
+		InflightCreditBalance: big.NewInt(0),
+		InflightDebitBalance:  big.NewInt(0),
+		CreatedAt:             time.Now(),
+	}
+	state.created[balance.BalanceID] = balance
+	state.work.Balances = append(state.work.Balances, balance)
+	return balance.BalanceID, nil
+}
+
+// lockSessionBalances locks the existing balances a session posts to, in order of their IDs so that sessions
+// posting to the same balances do not deadlock. It returns the locks acquired, even if locking fails.
+func (l *Blnk) lockSessionBalances(ctx context.Context, state *sessionWork) ([]*redlock.Locker, error) {
+	cfg, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(state.existing))
+	for id := range state.existing {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var lockers []*redlock.Locker
+	for _, id := range ids {
+		locker := redlock.NewLocker(l.redis, id, model.GenerateUUIDWithSuffix("loc"))
+		if err := locker.WaitLock(ctx, cfg.Transaction.LockDuration, sessionLockWait); err != nil {
+			return lockers, fmt.Errorf("failed to acquire lock on balance %s: %w", id, err)
+		}
+		lockers = append(lockers, locker)
+	}
+	return lockers, nil
+}
+
+// applySessionTransaction runs a session's transaction through the pre-transaction hooks, validation plugins
+// and the checks every posted transaction passes, including the caller's ledger scope, and applies it to its
+// balances in memory.
+func (l *Blnk) applySessionTransaction(ctx context.Context, state *sessionWork, transaction *model.Transaction) (*model.Transaction, error) {
+	if err := l.Hooks.ExecutePreHooks(ctx, transaction.TransactionID, transaction); err != nil {
+		return nil, err
+	}
+	if err := l.Plugins.Run(ctx, plugins.StageValidation, transaction); err != nil {
+		return nil, err
+	}
+	if err := l.Plugins.Run(ctx, plugins.StageEnrichment, transaction); err != nil {
+		return nil, err
+	}
+	l.enrichTransactionNarrative(ctx, transaction)
+
+	source, destination := state.balance(transaction.Source), state.balance(transaction.Destination)
+	if scope, ok := ledgerscope.FromContext(ctx); ok {
+		if err := checkBalancesInScope(scope, source, destination); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.checkTransactionRules(ctx, transaction, source, destination); err != nil {
+		return nil, err
+	}
+	if err := l.applyTransactionToBalances(ctx, []*model.Balance{source, destination}, transaction); err != nil {
+		return nil, err
+	}
+
+	applied := l.updateTransactionDetails(ctx, transaction, source, destination)
+	stampStatusChange(ctx, applied, "", "")
+	return applied, nil
+}
+
+// ledgerIDs returns the ledgers of the balances a session creates or posts to.
+func (s *sessionWork) ledgerIDs() []string {
+	var ids []string
+	for _, balance := range s.created {
+		ids = append(ids, balance.LedgerID)
+	}
+	for _, balance := range s.existing {
+		ids = append(ids, balance.LedgerID)
+	}
+	return ids
+}
+
+// balance returns a balance a session posts to.
+func (s *sessionWork) balance(id string) *model.Balance {
+	if balance, ok := s.created[id]; ok {
+		return balance
+	}
+	return s.existing[id]
+}
+
+// postSessionActions indexes what a committed session wrote and sends the webhooks of its resources, as when
+// they are created one at a time.
+func (l *Blnk) postSessionActions(ctx context.Context, work *model.UnitOfWork) {
+	for _, ledger := range work.Ledgers {
+		l.postLedgerActions(ctx, ledger)
+	}
+	for _, identity := range work.Identities {
+		l.postIdentityActions(ctx, identity)
+	}
+	for _, balance := range work.Balances {
+		l.postBalanceActions(ctx, balance)
+	}
+	for _, balance := range work.UpdatedBalances {
+		l.checkBalanceMonitors(ctx, balance)
+	}
+	for _, transaction := range work.Transactions {
+		if err := l.Hooks.ExecutePostHooks(ctx, transaction.TransactionID, transaction); err != nil {
+			logrus.Errorf("post-transaction hooks failed: %v", err)
+		}
+		_ = l.Plugins.Run(ctx, plugins.StagePostCommit, transaction)
+		l.postTransactionActions(ctx, transaction)
+	}
+}
+
+// sessionStagesLedger reports whether a session creates the ledger with the given ID.
+func sessionStagesLedger(session *model.Session, ledgerID string) bool {
+	for _, operation := range session.Operations {
+		if operation.Ledger != nil && operation.Ledger.LedgerID == ledgerID {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionStagesIdentity reports whether a session creates the identity with the given ID.
+func sessionStagesIdentity(session *model.Session, identityID string) bool {
+	for _, operation := range session.Operations {
+		if operation.Identity != nil && operation.Identity.IdentityID == identityID {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionStagesBalance reports whether a session creates the balance with the given ID.
+func sessionStagesBalance(session *model.Session, balanceID string) bool {
+	for _, operation := range session.Operations {
+		if operation.Balance != nil && operation.Balance.BalanceID == balanceID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/internal/ledgerscope"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// expectSessionPostActions stubs the lookups done in the background once a session is committed.
+func expectSessionPostActions(mockDS *mocks.MockDataSource) {
+	mockDS.On("ListMinimumBalances", mock.Anything, model.Page{}).Return([]*model.MinimumBalance{}, nil).Maybe()
+	mockDS.On("GetBalanceMonitors", mock.Anything, mock.Anything).Return([]model.BalanceMonitor{}, nil).Maybe()
+	mockDS.On("ListBalanceNotificationPreferences", mock.Anything).Return([]*model.BalanceNotificationPreference{}, nil).Maybe()
+	mockDS.On("ListPostingRules", mock.Anything).Return([]*model.PostingRules{}, nil).Maybe()
+}
+
+func TestCommitSession_ProvisionsIdentityBalanceAndDeposit(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	expectSessionPostActions(mockDS)
+	ctx := context.Background()
+
+	session, err := b.CreateSession(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, model.SessionOpen, session.Status)
+
+	identity, err := b.StageIdentity(ctx, session.SessionID, model.Identity{FirstName: "Ada"})
+	require.NoError(t, err)
+
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_customers").Return(&model.Ledger{LedgerID: "ldg_customers"}, nil)
+	mockDS.On("ListInactiveIdentityIDs", mock.Anything).Return([]string{}, nil)
+	balance, err := b.StageBalance(ctx, session.SessionID, model.Balance{LedgerID: "ldg_customers", IdentityID: identity.IdentityID, Currency: "USD"})
+	require.NoError(t, err)
+
+	mockDS.On("TransactionExistsByRef", mock.Anything, "deposit_1").Return(false, nil)
+	_, err = b.StageTransaction(ctx, session.SessionID, &model.Transaction{
+		Reference: "deposit_1", Source: "@World", Destination: balance.BalanceID, Amount: 100, Precision: 100, Currency: "USD", AllowOverdraft: true,
+	})
+	require.NoError(t, err)
+
+	var work *model.UnitOfWork
+	mockDS.On("GetBalanceByIndicator", mock.Anything, "@World", "USD").Return((*model.Balance)(nil), errors.New("not found"))
+	mockDS.On("CommitUnitOfWork", mock.Anything, mock.AnythingOfType("*model.UnitOfWork")).Run(func(args mock.Arguments) {
+		work = args.Get(1).(*model.UnitOfWork)
+	}).Return(nil)
+
+	committed, err := b.CommitSession(ctx, session.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SessionCommitted, committed.Status)
+	assert.NotNil(t, committed.CompletedAt)
+
+	require.NotNil(t, work)
+	require.Len(t, work.Identities, 1)
+	require.Len(t, work.Balances, 2)
+	require.Len(t, work.Transactions, 1)
+	assert.Empty(t, work.UpdatedBalances)
+	assert.Equal(t, big.NewInt(10000), work.Balances[0].Balance)
+	assert.Equal(t, "@World", work.Balances[1].Indicator)
+	assert.Equal(t, big.NewInt(-10000), work.Balances[1].Balance)
+	assert.Equal(t, work.Balances[1].BalanceID, work.Transactions[0].Source)
+	assert.Equal(t, StatusApplied, work.Transactions[0].Status)
+
+	_, err = b.StageIdentity(ctx, session.SessionID, model.Identity{FirstName: "Grace"})
+	assert.ErrorIs(t, err, model.ErrSessionNotOpen)
+}
+
+func TestCommitSession_InsufficientFundsWritesNothing(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	expectSessionPostActions(mockDS)
+	ctx := context.Background()
+
+	session, err := b.CreateSession(ctx)
+	require.NoError(t, err)
+
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_customers").Return(&model.Ledger{LedgerID: "ldg_customers"}, nil)
+	balance, err := b.StageBalance(ctx, session.SessionID, model.Balance{LedgerID: "ldg_customers", Currency: "USD"})
+	require.NoError(t, err)
+
+	empty := &model.Balance{BalanceID: "bln_empty", LedgerID: "ldg_customers", Currency: "USD", Balance: big.NewInt(0), CreditBalance: big.NewInt(0), DebitBalance: big.NewInt(0),
+		InflightBalance: big.NewInt(0), InflightCreditBalance: big.NewInt(0), InflightDebitBalance: big.NewInt(0)}
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_empty").Return(empty, nil)
+	mockDS.On("TransactionExistsByRef", mock.Anything, "transfer_1").Return(false, nil)
+	_, err = b.StageTransaction(ctx, session.SessionID, &model.Transaction{
+		Reference: "transfer_1", Source: "bln_empty", Destination: balance.BalanceID, Amount: 50, Precision: 100, Currency: "USD",
+	})
+	require.NoError(t, err)
+
+	_, err = b.CommitSession(ctx, session.SessionID)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transfer_1")
+	mockDS.AssertNotCalled(t, "CommitUnitOfWork", mock.Anything, mock.Anything)
+
+	stored, err := b.GetSession(ctx, session.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SessionOpen, stored.Status)
+	assert.Equal(t, "bln_empty", stored.Operations[1].Transaction.Source)
+}
+
+func TestCommitSession_EnforcesPostingRules(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("ListPostingRules", mock.Anything).Return([]*model.PostingRules{{LedgerID: "ldg_customers", RequireDescription: true}}, nil)
+	ctx := context.Background()
+
+	session, err := b.CreateSession(ctx)
+	require.NoError(t, err)
+
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_customers").Return(&model.Ledger{LedgerID: "ldg_customers"}, nil)
+	balance, err := b.StageBalance(ctx, session.SessionID, model.Balance{LedgerID: "ldg_customers", Currency: "USD"})
+	require.NoError(t, err)
+
+	mockDS.On("TransactionExistsByRef", mock.Anything, "deposit_1").Return(false, nil)
+	mockDS.On("GetBalanceByIndicator", mock.Anything, "@World", "USD").Return((*model.Balance)(nil), errors.New("not found"))
+	_, err = b.StageTransaction(ctx, session.SessionID, &model.Transaction{
+		Reference: "deposit_1", Source: "@World", Destination: balance.BalanceID, Amount: 100, Precision: 100, Currency: "USD", AllowOverdraft: true,
+	})
+	require.NoError(t, err)
+
+	_, err = b.CommitSession(ctx, session.SessionID)
+	var ruleErr *model.PostingRuleError
+	require.ErrorAs(t, err, &ruleErr)
+	assert.Equal(t, "require_description", ruleErr.Rule)
+	mockDS.AssertNotCalled(t, "CommitUnitOfWork", mock.Anything, mock.Anything)
+}
+
+func TestSession_RejectsOperationsOutsideLedgerScope(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	expectSessionPostActions(mockDS)
+	ctx := ledgerscope.WithScope(context.Background(), ledgerscope.Scope{LedgerIDs: []string{"ldg_customers"}})
+
+	session, err := b.CreateSession(ctx)
+	require.NoError(t, err)
+
+	_, err = b.StageLedger(ctx, session.SessionID, model.Ledger{Name: "Treasury"})
+	assert.ErrorIs(t, err, ErrOutsideLedgerScope)
+	_, err = b.StageBalance(ctx, session.SessionID, model.Balance{LedgerID: "ldg_treasury", Currency: "USD"})
+	assert.ErrorIs(t, err, ErrOutsideLedgerScope)
+
+	mockDS.On("GetLedgerByID", mock.Anything, "ldg_customers").Return(&model.Ledger{LedgerID: "ldg_customers"}, nil)
+	balance, err := b.StageBalance(ctx, session.SessionID, model.Balance{LedgerID: "ldg_customers", Currency: "USD"})
+	require.NoError(t, err)
+
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_treasury").Return(&model.Balance{BalanceID: "bln_treasury", LedgerID: "ldg_treasury", Currency: "USD"}, nil)
+	mockDS.On("TransactionExistsByRef", mock.Anything, mock.Anything).Return(false, nil)
+	_, err = b.StageTransaction(ctx, session.SessionID, &model.Transaction{
+		Reference: "transfer_1", Source: "bln_treasury", Destination: balance.BalanceID, Amount: 100, Precision: 100, Currency: "USD",
+	})
+	assert.ErrorIs(t, err, ErrOutsideLedgerScope)
+
+	// @World resolves to the general ledger only when the session is committed
+	mockDS.On("GetBalanceByIndicator", mock.Anything, "@World", "USD").Return((*model.Balance)(nil), errors.New("not found"))
+	_, err = b.StageTransaction(ctx, session.SessionID, &model.Transaction{
+		Reference: "deposit_1", Source: "@World", Destination: balance.BalanceID, Amount: 100, Precision: 100, Currency: "USD", AllowOverdraft: true,
+	})
+	require.NoError(t, err)
+
+	_, err = b.CommitSession(ctx, session.SessionID)
+	assert.ErrorIs(t, err, ErrOutsideLedgerScope)
+	mockDS.AssertNotCalled(t, "CommitUnitOfWork", mock.Anything, mock.Anything)
+}
+
+func TestCommitSession_FailedWriteKeepsSessionOpen(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+
+	session, err := b.CreateSession(ctx)
+	require.NoError(t, err)
+	_, err = b.StageLedger(ctx, session.SessionID, model.Ledger{Name: "Customers"})
+	require.NoError(t, err)
+
+	mockDS.On("CommitUnitOfWork", mock.Anything, mock.Anything).Return(errors.New("connection reset"))
+	_, err = b.CommitSession(ctx, session.SessionID)
+	assert.EqualError(t, err, "connection reset")
+
+	stored, err := b.GetSession(ctx, session.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SessionOpen, stored.Status)
+}
+
+func TestRollbackSession(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+
+	session, err := b.CreateSession(ctx)
+	require.NoError(t, err)
+	_, err = b.StageLedger(ctx, session.SessionID, model.Ledger{Name: "Customers"})
+	require.NoError(t, err)
+
+	rolledBack, err := b.RollbackSession(ctx, session.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SessionRolledBack, rolledBack.Status)
+
+	_, err = b.CommitSession(ctx, session.SessionID)
+	assert.ErrorIs(t, err, model.ErrSessionNotOpen)
+	mockDS.AssertNotCalled(t, "CommitUnitOfWork", mock.Anything, mock.Anything)
+}
+
+func TestStageTransaction_RejectsUnsupportedTransactions(t *testing.T) {
+	b, _, _ := newBalanceNotificationTestBlnk(t)
+	ctx := context.Background()
+
+	session, err := b.CreateSession(ctx)
+	require.NoError(t, err)
+
+	_, err = b.StageTransaction(ctx, session.SessionID, &model.Transaction{Reference: "r1", Source: "bln_a", Destination: "bln_b", Amount: 10, Inflight: true})
+	assert.EqualError(t, err, "inflight transactions cannot be staged in a session")
+	_, err = b.StageTransaction(ctx, session.SessionID, &model.Transaction{Reference: "r2", Sources: []model.Distribution{{Identifier: "bln_a"}}, Destination: "bln_b", Amount: 10})
+	assert.EqualError(t, err, "split transactions cannot be staged in a session")
+}
+
+func TestGetSession_NotFound(t *testing.T) {
+	b, _, _ := newBalanceNotificationTestBlnk(t)
+	_, err := b.GetSession(context.Background(), "ses_missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,23 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+ALTER TABLE blnk.matches ADD COLUMN IF NOT EXISTS confidence NUMERIC(5, 4) NOT NULL DEFAULT 1;
+ALTER TABLE blnk.matches ADD COLUMN IF NOT EXISTS status VARCHAR(50) NOT NULL DEFAULT 'confirmed';
+CREATE INDEX IF NOT EXISTS idx_matches_reconciliation_status ON blnk.matches (reconciliation_id, status);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_matches_reconciliation_status;
+ALTER TABLE blnk.matches DROP COLUMN IF EXISTS status;
+ALTER TABLE blnk.matches DROP COLUMN IF EXISTS confidence;
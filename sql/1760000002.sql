@@ -0,0 +1,21 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+ALTER TABLE blnk.api_keys ADD COLUMN IF NOT EXISTS rotated_to TEXT;
+ALTER TABLE blnk.api_keys ADD COLUMN IF NOT EXISTS rotation_expires_at TIMESTAMP WITH TIME ZONE;
+
+-- +migrate Down
+ALTER TABLE blnk.api_keys DROP COLUMN IF EXISTS rotation_expires_at;
+ALTER TABLE blnk.api_keys DROP COLUMN IF EXISTS rotated_to;
@@ -0,0 +1,55 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.statement_schedules (
+    id SERIAL PRIMARY KEY,
+    schedule_id TEXT NOT NULL UNIQUE,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    day_of_month INTEGER NOT NULL DEFAULT 1,
+    channel TEXT NOT NULL,
+    email TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_statement_schedules_next_run ON blnk.statement_schedules(next_run_at) WHERE is_active;
+
+CREATE TABLE IF NOT EXISTS blnk.statements (
+    id SERIAL PRIMARY KEY,
+    statement_id TEXT NOT NULL UNIQUE,
+    schedule_id TEXT NOT NULL REFERENCES blnk.statement_schedules(schedule_id),
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    storage_key TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    delivery_status TEXT NOT NULL DEFAULT 'pending',
+    delivery_attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_statements_schedule_id ON blnk.statements(schedule_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_statements_schedule_id;
+DROP TABLE IF EXISTS blnk.statements;
+DROP INDEX IF EXISTS idx_statement_schedules_next_run;
+DROP TABLE IF EXISTS blnk.statement_schedules;
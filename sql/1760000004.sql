@@ -0,0 +1,33 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.service_accounts (
+   id                   SERIAL PRIMARY KEY,
+   service_account_id   TEXT NOT NULL UNIQUE,
+   name                 TEXT NOT NULL,
+   owner_id             TEXT NOT NULL,
+   secret_hash          TEXT NOT NULL,
+   scopes               TEXT[] NOT NULL DEFAULT '{}',
+   created_at           TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+   last_token_issued_at TIMESTAMP WITH TIME ZONE,
+   is_revoked           BOOLEAN NOT NULL DEFAULT FALSE,
+   revoked_at           TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_accounts_owner_id ON blnk.service_accounts(owner_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_service_accounts_owner_id;
+DROP TABLE IF EXISTS blnk.service_accounts;
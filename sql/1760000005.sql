@@ -0,0 +1,36 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+ALTER TABLE blnk.api_keys ADD COLUMN IF NOT EXISTS allowed_cidrs TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE blnk.api_keys ADD COLUMN IF NOT EXISTS blocked_countries TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS blnk.api_key_access_denials (
+   id          SERIAL PRIMARY KEY,
+   api_key_id  TEXT NOT NULL,
+   ip_address  TEXT NOT NULL,
+   country     TEXT,
+   reason      TEXT NOT NULL,
+   method      TEXT NOT NULL,
+   path        TEXT NOT NULL,
+   created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_key_access_denials_key_created ON blnk.api_key_access_denials(api_key_id, created_at DESC);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_api_key_access_denials_key_created;
+DROP TABLE IF EXISTS blnk.api_key_access_denials;
+ALTER TABLE blnk.api_keys DROP COLUMN IF EXISTS blocked_countries;
+ALTER TABLE blnk.api_keys DROP COLUMN IF EXISTS allowed_cidrs;
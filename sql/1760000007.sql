@@ -0,0 +1,70 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.netting_groups (
+    id              SERIAL PRIMARY KEY,
+    group_id        TEXT NOT NULL UNIQUE,
+    name            TEXT NOT NULL,
+    balance_ids     TEXT[] NOT NULL,
+    currency        TEXT NOT NULL,
+    precision       DOUBLE PRECISION NOT NULL DEFAULT 1,
+    cutoff_time     TEXT NOT NULL,
+    is_active       BOOLEAN NOT NULL DEFAULT TRUE,
+    last_cutoff_at  TIMESTAMP WITH TIME ZONE,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS blnk.netting_settlements (
+    id              SERIAL PRIMARY KEY,
+    settlement_id   TEXT NOT NULL UNIQUE,
+    group_id        TEXT NOT NULL REFERENCES blnk.netting_groups(group_id),
+    cutoff_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+    entry_count     INTEGER NOT NULL DEFAULT 0,
+    transfers       JSONB NOT NULL DEFAULT '[]',
+    status          TEXT NOT NULL,
+    error           TEXT,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (group_id, cutoff_at)
+);
+
+CREATE TABLE IF NOT EXISTS blnk.netting_entries (
+    id              SERIAL PRIMARY KEY,
+    entry_id        TEXT NOT NULL UNIQUE,
+    group_id        TEXT NOT NULL REFERENCES blnk.netting_groups(group_id),
+    transaction_id  TEXT NOT NULL,
+    reference       TEXT NOT NULL UNIQUE,
+    source          TEXT NOT NULL,
+    destination     TEXT NOT NULL,
+    amount          DOUBLE PRECISION NOT NULL,
+    precise_amount  NUMERIC NOT NULL,
+    currency        TEXT NOT NULL,
+    description     TEXT,
+    meta_data       JSONB,
+    status          TEXT NOT NULL DEFAULT 'pending',
+    settlement_id   TEXT REFERENCES blnk.netting_settlements(settlement_id),
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_netting_entries_group_status ON blnk.netting_entries(group_id, status, created_at);
+CREATE INDEX IF NOT EXISTS idx_netting_entries_source ON blnk.netting_entries(source, created_at);
+CREATE INDEX IF NOT EXISTS idx_netting_entries_destination ON blnk.netting_entries(destination, created_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_netting_entries_destination;
+DROP INDEX IF EXISTS blnk.idx_netting_entries_source;
+DROP INDEX IF EXISTS blnk.idx_netting_entries_group_status;
+DROP TABLE IF EXISTS blnk.netting_entries;
+DROP TABLE IF EXISTS blnk.netting_settlements;
+DROP TABLE IF EXISTS blnk.netting_groups;
@@ -0,0 +1,43 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.card_authorizations (
+    id                SERIAL PRIMARY KEY,
+    authorization_id  TEXT NOT NULL UNIQUE,
+    scheme            TEXT NOT NULL,
+    reference         TEXT NOT NULL UNIQUE,
+    source            TEXT NOT NULL,
+    destination       TEXT NOT NULL,
+    currency          TEXT NOT NULL,
+    precision         DOUBLE PRECISION NOT NULL DEFAULT 1,
+    authorized_amount NUMERIC NOT NULL DEFAULT 0,
+    reversed_amount   NUMERIC NOT NULL DEFAULT 0,
+    cleared_amount    NUMERIC NOT NULL DEFAULT 0,
+    status            TEXT NOT NULL,
+    holds             JSONB NOT NULL DEFAULT '[]',
+    events            JSONB NOT NULL DEFAULT '[]',
+    meta_data         JSONB,
+    expires_at        TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_authorizations_status_expiry ON blnk.card_authorizations(status, expires_at);
+CREATE INDEX IF NOT EXISTS idx_card_authorizations_source ON blnk.card_authorizations(source, created_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_card_authorizations_source;
+DROP INDEX IF EXISTS blnk.idx_card_authorizations_status_expiry;
+DROP TABLE IF EXISTS blnk.card_authorizations;
@@ -0,0 +1,39 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.scheduled_transaction_failures (
+    id              SERIAL PRIMARY KEY,
+    failure_id      TEXT NOT NULL UNIQUE,
+    transaction_id  TEXT NOT NULL,
+    reference       TEXT NOT NULL,
+    source          TEXT NOT NULL,
+    destination     TEXT NOT NULL,
+    currency        TEXT NOT NULL,
+    precise_amount  NUMERIC NOT NULL,
+    executed_amount NUMERIC NOT NULL DEFAULT 0,
+    attempts        INTEGER NOT NULL,
+    reason          TEXT NOT NULL,
+    scheduled_for   TIMESTAMP WITH TIME ZONE,
+    first_failed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    failed_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_transaction_failures_failed_at ON blnk.scheduled_transaction_failures(failed_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_transaction_failures_source ON blnk.scheduled_transaction_failures(source, failed_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_scheduled_transaction_failures_source;
+DROP INDEX IF EXISTS blnk.idx_scheduled_transaction_failures_failed_at;
+DROP TABLE IF EXISTS blnk.scheduled_transaction_failures;
@@ -0,0 +1,68 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+ALTER TABLE blnk.ledgers ADD COLUMN IF NOT EXISTS tenant_id TEXT;
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS tenant_id TEXT;
+ALTER TABLE blnk.balances ADD COLUMN IF NOT EXISTS tenant_id TEXT;
+ALTER TABLE blnk.transactions ADD COLUMN IF NOT EXISTS tenant_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_ledgers_tenant_id ON blnk.ledgers(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_identity_tenant_id ON blnk.identity(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_balances_tenant_id ON blnk.balances(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_tenant_id ON blnk.transactions(tenant_id);
+
+-- New rows belong to the tenant of the session that inserts them. Balances and transactions created
+-- outside a tenant session, such as by the queue workers, inherit the tenant of their ledger or source balance.
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION blnk.assign_tenant_id()
+    RETURNS TRIGGER
+AS
+$$
+BEGIN
+    IF NEW.tenant_id IS NULL THEN
+        NEW.tenant_id := NULLIF(current_setting('blnk.tenant_id', true), '');
+    END IF;
+    IF NEW.tenant_id IS NULL AND TG_TABLE_NAME = 'balances' THEN
+        NEW.tenant_id := (SELECT l.tenant_id FROM blnk.ledgers l WHERE l.ledger_id = NEW.ledger_id);
+    ELSIF NEW.tenant_id IS NULL AND TG_TABLE_NAME = 'transactions' THEN
+        NEW.tenant_id := (SELECT b.tenant_id FROM blnk.balances b WHERE b.balance_id = NEW.source);
+    END IF;
+    RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+CREATE TRIGGER ledgers_assign_tenant BEFORE INSERT ON blnk.ledgers FOR EACH ROW EXECUTE FUNCTION blnk.assign_tenant_id();
+CREATE TRIGGER identity_assign_tenant BEFORE INSERT ON blnk.identity FOR EACH ROW EXECUTE FUNCTION blnk.assign_tenant_id();
+CREATE TRIGGER balances_assign_tenant BEFORE INSERT ON blnk.balances FOR EACH ROW EXECUTE FUNCTION blnk.assign_tenant_id();
+CREATE TRIGGER transactions_assign_tenant BEFORE INSERT ON blnk.transactions FOR EACH ROW EXECUTE FUNCTION blnk.assign_tenant_id();
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS transactions_assign_tenant ON blnk.transactions;
+DROP TRIGGER IF EXISTS balances_assign_tenant ON blnk.balances;
+DROP TRIGGER IF EXISTS identity_assign_tenant ON blnk.identity;
+DROP TRIGGER IF EXISTS ledgers_assign_tenant ON blnk.ledgers;
+DROP FUNCTION IF EXISTS blnk.assign_tenant_id();
+
+DROP INDEX IF EXISTS blnk.idx_transactions_tenant_id;
+DROP INDEX IF EXISTS blnk.idx_balances_tenant_id;
+DROP INDEX IF EXISTS blnk.idx_identity_tenant_id;
+DROP INDEX IF EXISTS blnk.idx_ledgers_tenant_id;
+
+ALTER TABLE blnk.transactions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE blnk.balances DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE blnk.ledgers DROP COLUMN IF EXISTS tenant_id;
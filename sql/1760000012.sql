@@ -0,0 +1,55 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.escheatment_batches (
+    id              SERIAL PRIMARY KEY,
+    batch_id        TEXT NOT NULL UNIQUE,
+    currency        TEXT NOT NULL,
+    holding_balance TEXT NOT NULL,
+    items           JSONB NOT NULL DEFAULT '[]',
+    total_amount    NUMERIC NOT NULL DEFAULT 0,
+    status          TEXT NOT NULL,
+    error           TEXT,
+    created_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    applied_at      TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS blnk.dormant_balances (
+    id               SERIAL PRIMARY KEY,
+    balance_id       TEXT NOT NULL UNIQUE REFERENCES blnk.balances(balance_id),
+    ledger_id        TEXT NOT NULL,
+    identity_id      TEXT,
+    currency         TEXT NOT NULL,
+    last_activity_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    dormant_since    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    frozen           BOOLEAN NOT NULL DEFAULT FALSE,
+    status           TEXT NOT NULL DEFAULT 'dormant',
+    batch_id         TEXT REFERENCES blnk.escheatment_batches(batch_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_dormant_balances_status ON blnk.dormant_balances(status, currency, dormant_since);
+CREATE INDEX IF NOT EXISTS idx_dormant_balances_batch_id ON blnk.dormant_balances(batch_id);
+
+-- The dormancy scan looks up the latest transaction of each balance
+CREATE INDEX IF NOT EXISTS idx_transactions_source_created_at ON blnk.transactions(source, created_at);
+CREATE INDEX IF NOT EXISTS idx_transactions_destination_created_at ON blnk.transactions(destination, created_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_transactions_destination_created_at;
+DROP INDEX IF EXISTS blnk.idx_transactions_source_created_at;
+DROP INDEX IF EXISTS blnk.idx_dormant_balances_batch_id;
+DROP INDEX IF EXISTS blnk.idx_dormant_balances_status;
+DROP TABLE IF EXISTS blnk.dormant_balances;
+DROP TABLE IF EXISTS blnk.escheatment_batches;
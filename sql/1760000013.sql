@@ -0,0 +1,38 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.statement_ingestions (
+    id                SERIAL PRIMARY KEY,
+    ingestion_id      TEXT NOT NULL UNIQUE,
+    channel           TEXT NOT NULL,
+    sender            TEXT,
+    filename          TEXT NOT NULL,
+    fingerprint       TEXT NOT NULL UNIQUE,
+    profile           TEXT,
+    status            TEXT NOT NULL,
+    upload_id         TEXT,
+    record_count      INTEGER NOT NULL DEFAULT 0,
+    reconciliation_id TEXT,
+    error             TEXT,
+    received_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at      TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_statement_ingestions_created_at ON blnk.statement_ingestions(created_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_statement_ingestions_created_at;
+DROP TABLE IF EXISTS blnk.statement_ingestions;
@@ -0,0 +1,42 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.request_logs (
+    id                 BIGSERIAL PRIMARY KEY,
+    log_id             TEXT NOT NULL UNIQUE,
+    method             TEXT NOT NULL,
+    path               TEXT NOT NULL,
+    query              TEXT,
+    key_id             TEXT,
+    owner_id           TEXT,
+    ip_address         TEXT,
+    status_code        INTEGER NOT NULL,
+    latency_ms         BIGINT NOT NULL,
+    request_body       TEXT,
+    response_body      TEXT,
+    request_truncated  BOOLEAN NOT NULL DEFAULT FALSE,
+    response_truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at         TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_logs_created_at ON blnk.request_logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_request_logs_key_id_created_at ON blnk.request_logs(key_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_request_logs_owner_id_created_at ON blnk.request_logs(owner_id, created_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_request_logs_owner_id_created_at;
+DROP INDEX IF EXISTS blnk.idx_request_logs_key_id_created_at;
+DROP INDEX IF EXISTS blnk.idx_request_logs_created_at;
+DROP TABLE IF EXISTS blnk.request_logs;
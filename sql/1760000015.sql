@@ -0,0 +1,24 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+ALTER TABLE blnk.transactions ADD COLUMN IF NOT EXISTS transaction_time TIMESTAMP;
+
+-- History can be ordered by the client's time, falling back to the server's for transactions without one
+CREATE INDEX IF NOT EXISTS idx_transactions_history_time ON blnk.transactions((COALESCE(transaction_time, created_at)));
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_transactions_history_time;
+ALTER TABLE blnk.transactions DROP COLUMN IF EXISTS transaction_time;
@@ -0,0 +1,33 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.transaction_status_history (
+    id                 BIGSERIAL PRIMARY KEY,
+    transaction_id     TEXT NOT NULL,
+    parent_transaction TEXT,
+    status             TEXT NOT NULL,
+    reason_code        TEXT,
+    reason             TEXT,
+    actor              TEXT,
+    created_at         TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_status_history_transaction_id ON blnk.transaction_status_history(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_transaction_status_history_parent_transaction ON blnk.transaction_status_history(parent_transaction);
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.transaction_status_history;
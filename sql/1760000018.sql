@@ -0,0 +1,69 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+
+-- +migrate Up
+-- Rows record when they were last written so the warehouse sync can export what changed since its last run.
+-- Existing rows are stamped with the time of the migration and are exported by the first sync.
+ALTER TABLE blnk.transactions ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+ALTER TABLE blnk.balances ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_transactions_updated_at ON blnk.transactions(updated_at, transaction_id);
+CREATE INDEX IF NOT EXISTS idx_balances_updated_at ON blnk.balances(updated_at, balance_id);
+CREATE INDEX IF NOT EXISTS idx_identity_updated_at ON blnk.identity(updated_at, identity_id);
+
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION blnk.touch_updated_at()
+    RETURNS TRIGGER
+AS
+$$
+BEGIN
+    NEW.updated_at := NOW();
+    RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+CREATE TRIGGER transactions_touch_updated_at BEFORE UPDATE ON blnk.transactions FOR EACH ROW EXECUTE FUNCTION blnk.touch_updated_at();
+CREATE TRIGGER balances_touch_updated_at BEFORE UPDATE ON blnk.balances FOR EACH ROW EXECUTE FUNCTION blnk.touch_updated_at();
+CREATE TRIGGER identity_touch_updated_at BEFORE UPDATE ON blnk.identity FOR EACH ROW EXECUTE FUNCTION blnk.touch_updated_at();
+
+-- The position each entity was exported up to: the updated_at and ID of the last row exported.
+CREATE TABLE IF NOT EXISTS blnk.warehouse_sync_state (
+    entity         TEXT PRIMARY KEY,
+    watermark_at   TIMESTAMP WITH TIME ZONE,
+    watermark_id   TEXT NOT NULL DEFAULT '',
+    rows_synced    BIGINT NOT NULL DEFAULT 0,
+    last_synced_at TIMESTAMP WITH TIME ZONE,
+    last_error     TEXT
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.warehouse_sync_state;
+
+DROP TRIGGER IF EXISTS identity_touch_updated_at ON blnk.identity;
+DROP TRIGGER IF EXISTS balances_touch_updated_at ON blnk.balances;
+DROP TRIGGER IF EXISTS transactions_touch_updated_at ON blnk.transactions;
+DROP FUNCTION IF EXISTS blnk.touch_updated_at();
+
+DROP INDEX IF EXISTS blnk.idx_identity_updated_at;
+DROP INDEX IF EXISTS blnk.idx_balances_updated_at;
+DROP INDEX IF EXISTS blnk.idx_transactions_updated_at;
+
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE blnk.balances DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE blnk.transactions DROP COLUMN IF EXISTS updated_at;
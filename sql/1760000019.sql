@@ -0,0 +1,27 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+
+-- +migrate Up
+-- The locale (BCP 47) and timezone (IANA) statements and notifications about an identity are formatted with,
+-- and how the identity wants to be contacted.
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS communication_preferences JSONB;
+
+-- +migrate Down
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS communication_preferences;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS timezone;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS locale;
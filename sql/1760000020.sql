@@ -0,0 +1,54 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+
+-- +migrate Up
+-- The last sequence number assigned in each ledger. Incrementing it locks the row until the transaction that
+-- recorded the posting commits, so sequence numbers are assigned without gaps.
+CREATE TABLE IF NOT EXISTS blnk.ledger_sequences (
+    ledger_id     TEXT PRIMARY KEY,
+    last_sequence BIGINT NOT NULL DEFAULT 0
+);
+
+-- The position of each applied, inflight or voided transaction in the ledgers of its source and destination.
+CREATE TABLE IF NOT EXISTS blnk.transaction_sequences (
+    ledger_id      TEXT NOT NULL,
+    sequence       BIGINT NOT NULL,
+    transaction_id TEXT NOT NULL,
+    created_at     TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ledger_id, sequence),
+    UNIQUE (ledger_id, transaction_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_sequences_transaction_id ON blnk.transaction_sequences(transaction_id);
+
+-- Number the transactions recorded before sequencing in the order they were created
+INSERT INTO blnk.transaction_sequences (ledger_id, sequence, transaction_id, created_at)
+SELECT ledger_id, ROW_NUMBER() OVER (PARTITION BY ledger_id ORDER BY created_at, transaction_id), transaction_id, created_at
+FROM (
+    SELECT DISTINCT b.ledger_id, t.transaction_id, t.created_at
+    FROM blnk.transactions t
+    JOIN blnk.balances b ON b.balance_id IN (t.source, t.destination)
+    WHERE t.status IN ('APPLIED', 'INFLIGHT', 'VOID')
+) posted
+ON CONFLICT DO NOTHING;
+
+INSERT INTO blnk.ledger_sequences (ledger_id, last_sequence)
+SELECT ledger_id, MAX(sequence) FROM blnk.transaction_sequences GROUP BY ledger_id
+ON CONFLICT (ledger_id) DO UPDATE SET last_sequence = GREATEST(blnk.ledger_sequences.last_sequence, EXCLUDED.last_sequence);
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.transaction_sequences;
+DROP TABLE IF EXISTS blnk.ledger_sequences;
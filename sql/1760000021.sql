@@ -0,0 +1,36 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+
+-- +migrate Up
+-- Transactions held back until the customer passes a strong customer authentication challenge. The submitted
+-- transaction is kept with the challenge, together with the trail of what happened to it.
+CREATE TABLE IF NOT EXISTS blnk.transaction_challenges (
+    challenge_id   TEXT PRIMARY KEY,
+    transaction_id TEXT NOT NULL UNIQUE,
+    reference      TEXT NOT NULL UNIQUE,
+    rule           TEXT NOT NULL,
+    status         TEXT NOT NULL,
+    transaction    JSONB NOT NULL,
+    trail          JSONB NOT NULL DEFAULT '[]',
+    expires_at     TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at     TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    resolved_at    TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_challenges_pending ON blnk.transaction_challenges(expires_at) WHERE status = 'pending';
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.transaction_challenges;
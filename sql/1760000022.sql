@@ -0,0 +1,60 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.report_definitions (
+    id SERIAL PRIMARY KEY,
+    report_id TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    source TEXT NOT NULL,
+    filters JSONB NOT NULL DEFAULT '[]'::jsonb,
+    group_by JSONB NOT NULL DEFAULT '[]'::jsonb,
+    aggregates JSONB NOT NULL DEFAULT '[]'::jsonb,
+    window_duration TEXT,
+    schedule TEXT,
+    format TEXT NOT NULL DEFAULT 'csv',
+    destination TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_definitions_next_run ON blnk.report_definitions(next_run_at) WHERE is_active AND next_run_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS blnk.report_runs (
+    id SERIAL PRIMARY KEY,
+    run_id TEXT NOT NULL UNIQUE,
+    report_id TEXT NOT NULL REFERENCES blnk.report_definitions(report_id) ON DELETE CASCADE,
+    trigger TEXT NOT NULL,
+    status TEXT NOT NULL,
+    columns JSONB NOT NULL DEFAULT '[]'::jsonb,
+    rows JSONB NOT NULL DEFAULT '[]'::jsonb,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    storage_key TEXT,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_runs_report_id ON blnk.report_runs(report_id, started_at DESC);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_report_runs_report_id;
+DROP TABLE IF EXISTS blnk.report_runs;
+DROP INDEX IF EXISTS idx_report_definitions_next_run;
+DROP TABLE IF EXISTS blnk.report_definitions;
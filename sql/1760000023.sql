@@ -0,0 +1,30 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+CREATE INDEX IF NOT EXISTS idx_identity_email_address ON blnk.identity(lower(email_address));
+CREATE INDEX IF NOT EXISTS idx_identity_phone_number ON blnk.identity(phone_number);
+CREATE INDEX IF NOT EXISTS idx_identity_nationality ON blnk.identity(nationality);
+CREATE INDEX IF NOT EXISTS idx_identity_country ON blnk.identity(country);
+CREATE INDEX IF NOT EXISTS idx_identity_category ON blnk.identity(category);
+CREATE INDEX IF NOT EXISTS idx_identity_identity_type ON blnk.identity(identity_type);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_identity_identity_type;
+DROP INDEX IF EXISTS blnk.idx_identity_category;
+DROP INDEX IF EXISTS blnk.idx_identity_country;
+DROP INDEX IF EXISTS blnk.idx_identity_nationality;
+DROP INDEX IF EXISTS blnk.idx_identity_phone_number;
+DROP INDEX IF EXISTS blnk.idx_identity_email_address;
@@ -0,0 +1,32 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.minimum_balances (
+    id                 SERIAL PRIMARY KEY,
+    minimum_balance_id TEXT NOT NULL UNIQUE,
+    balance_id         TEXT REFERENCES blnk.balances (balance_id) ON DELETE CASCADE,
+    ledger_id          TEXT REFERENCES blnk.ledgers (ledger_id) ON DELETE CASCADE,
+    currency           TEXT,
+    precise_amount     NUMERIC NOT NULL,
+    created_at         TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK ((balance_id IS NOT NULL AND ledger_id IS NULL) OR (balance_id IS NULL AND ledger_id IS NOT NULL AND currency IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_minimum_balances_balance_id ON blnk.minimum_balances(balance_id) WHERE balance_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_minimum_balances_ledger_currency ON blnk.minimum_balances(ledger_id, currency) WHERE ledger_id IS NOT NULL;
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.minimum_balances;
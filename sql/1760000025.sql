@@ -0,0 +1,22 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_identity_deleted_at ON blnk.identity(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_identity_deleted_at;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS deleted_at;
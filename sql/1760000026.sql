@@ -0,0 +1,30 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS verification_status TEXT NOT NULL DEFAULT 'unverified';
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS verification_reason TEXT;
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS verification_submitted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS verification_rejected_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_identity_verification_status ON blnk.identity(verification_status);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_identity_verification_status;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS verification_rejected_at;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS verified_at;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS verification_submitted_at;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS verification_reason;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS verification_status;
@@ -0,0 +1,32 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.identity_merges (
+    id          SERIAL PRIMARY KEY,
+    merge_id    TEXT NOT NULL UNIQUE,
+    survivor_id TEXT NOT NULL REFERENCES blnk.identity (identity_id),
+    merged_id   TEXT NOT NULL REFERENCES blnk.identity (identity_id),
+    matched_on  TEXT[] NOT NULL DEFAULT '{}',
+    balance_ids TEXT[] NOT NULL DEFAULT '{}',
+    reason      TEXT,
+    merged_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_identity_merges_survivor_id ON blnk.identity_merges(survivor_id);
+CREATE INDEX IF NOT EXISTS idx_identity_merges_merged_id ON blnk.identity_merges(merged_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.identity_merges;
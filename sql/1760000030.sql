@@ -0,0 +1,29 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.identity_history (
+    id          BIGSERIAL PRIMARY KEY,
+    identity_id TEXT NOT NULL,
+    version     INTEGER NOT NULL,
+    previous    JSONB NOT NULL,
+    changes     JSONB NOT NULL,
+    changed_by  TEXT NOT NULL,
+    changed_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (identity_id, version)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.identity_history;
@@ -0,0 +1,28 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+-- How strictly the postings of each ledger are validated. Ledgers without a row are not checked.
+CREATE TABLE IF NOT EXISTS blnk.ledger_posting_rules (
+    ledger_id                     TEXT PRIMARY KEY REFERENCES blnk.ledgers (ledger_id) ON DELETE CASCADE,
+    allow_zero_amount             BOOLEAN NOT NULL DEFAULT FALSE,
+    allow_same_source_destination BOOLEAN NOT NULL DEFAULT FALSE,
+    require_description           BOOLEAN NOT NULL DEFAULT FALSE,
+    reference_pattern             TEXT,
+    updated_at                    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.ledger_posting_rules;
@@ -0,0 +1,31 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+-- Human-readable names of balances, usable in place of their IDs in transactions. Aliases belong to the tenant
+-- of their balance and are unique within it.
+CREATE TABLE IF NOT EXISTS blnk.balance_aliases (
+    alias      TEXT NOT NULL,
+    balance_id TEXT NOT NULL REFERENCES blnk.balances (balance_id) ON DELETE CASCADE,
+    tenant_id  TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balance_aliases_tenant_alias ON blnk.balance_aliases (COALESCE(tenant_id, ''), alias);
+CREATE INDEX IF NOT EXISTS idx_balance_aliases_balance_id ON blnk.balance_aliases (balance_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.balance_aliases;
@@ -0,0 +1,32 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+-- Results of screening identities against sanctions and politically exposed person lists, newest last. The latest
+-- screening of an identity is its current standing.
+CREATE TABLE IF NOT EXISTS blnk.identity_screenings (
+    id           SERIAL PRIMARY KEY,
+    screening_id TEXT NOT NULL UNIQUE,
+    identity_id  TEXT NOT NULL REFERENCES blnk.identity (identity_id) ON DELETE CASCADE,
+    provider     TEXT NOT NULL,
+    status       TEXT NOT NULL,
+    matches      JSONB NOT NULL DEFAULT '[]',
+    screened_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_identity_screenings_identity_id ON blnk.identity_screenings(identity_id, screened_at DESC);
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.identity_screenings;
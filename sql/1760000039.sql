@@ -0,0 +1,35 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.identity_addresses (
+    id          SERIAL PRIMARY KEY,
+    address_id  TEXT NOT NULL UNIQUE,
+    identity_id TEXT NOT NULL REFERENCES blnk.identity (identity_id) ON DELETE CASCADE,
+    type        TEXT NOT NULL,
+    street      TEXT,
+    city        TEXT,
+    state       TEXT,
+    post_code   TEXT,
+    country     TEXT NOT NULL,
+    meta_data   JSONB NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_identity_addresses_identity_id ON blnk.identity_addresses(identity_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.identity_addresses;
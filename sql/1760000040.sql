@@ -0,0 +1,38 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS blnk.contact_verifications (
+    id               SERIAL PRIMARY KEY,
+    verification_id  TEXT NOT NULL UNIQUE,
+    identity_id      TEXT NOT NULL REFERENCES blnk.identity (identity_id) ON DELETE CASCADE,
+    channel          TEXT NOT NULL,
+    destination_hash TEXT NOT NULL,
+    code_hash        TEXT NOT NULL,
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    expires_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+    verified_at      TIMESTAMP WITH TIME ZONE,
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_verifications_identity_channel ON blnk.contact_verifications(identity_id, channel, created_at DESC);
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.contact_verifications;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS phone_verified_at;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,43 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.external_accounts (
+    id                  SERIAL PRIMARY KEY,
+    external_account_id TEXT NOT NULL UNIQUE,
+    identity_id         TEXT REFERENCES blnk.identity (identity_id) ON DELETE CASCADE,
+    type                TEXT NOT NULL,
+    holder_name         TEXT NOT NULL,
+    currency            TEXT NOT NULL,
+    country             TEXT,
+    bank_name           TEXT,
+    iban                TEXT,
+    account_number      TEXT,
+    routing_number      TEXT,
+    bic                 TEXT,
+    network             TEXT,
+    wallet_address      TEXT,
+    verification_status TEXT NOT NULL DEFAULT 'pending',
+    verification_reason TEXT,
+    verified_at         TIMESTAMP WITH TIME ZONE,
+    meta_data           JSONB,
+    created_at          TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_accounts_identity_id ON blnk.external_accounts(identity_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.external_accounts;
@@ -0,0 +1,29 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+
+
+-- +migrate Up
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS status_reason TEXT;
+ALTER TABLE blnk.identity ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_identity_inactive_status ON blnk.identity(status) WHERE status <> 'active';
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_identity_inactive_status;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS status_changed_at;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS status_reason;
+ALTER TABLE blnk.identity DROP COLUMN IF EXISTS status;
@@ -0,0 +1,36 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS blnk.webhook_deliveries (
+    id           BIGSERIAL PRIMARY KEY,
+    delivery_id  TEXT NOT NULL UNIQUE,
+    endpoint_id  TEXT NOT NULL,
+    event        TEXT NOT NULL,
+    succeeded    BOOLEAN NOT NULL,
+    status_code  INTEGER,
+    latency_ms   BIGINT NOT NULL,
+    error        TEXT,
+    delivered_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint_id_delivered_at ON blnk.webhook_deliveries(endpoint_id, delivered_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_delivered_at ON blnk.webhook_deliveries(delivered_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_webhook_deliveries_delivered_at;
+DROP INDEX IF EXISTS blnk.idx_webhook_deliveries_endpoint_id_delivered_at;
+DROP TABLE IF EXISTS blnk.webhook_deliveries;
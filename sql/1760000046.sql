@@ -0,0 +1,36 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+
+-- +migrate Up
+-- How far postings have been exported to the compliance bucket. The table has a single row.
+CREATE TABLE IF NOT EXISTS blnk.compliance_export_state (
+    id               INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    last_batch       BIGINT NOT NULL DEFAULT 0,
+    last_hash        TEXT NOT NULL DEFAULT '',
+    records_exported BIGINT NOT NULL DEFAULT 0,
+    last_exported_at TIMESTAMP WITH TIME ZONE,
+    last_error       TEXT
+);
+
+-- The last sequence number of each ledger that has been exported.
+CREATE TABLE IF NOT EXISTS blnk.compliance_export_cursors (
+    ledger_id     TEXT PRIMARY KEY,
+    last_sequence BIGINT NOT NULL
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS blnk.compliance_export_cursors;
+DROP TABLE IF EXISTS blnk.compliance_export_state;
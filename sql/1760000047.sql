@@ -0,0 +1,23 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+
+-- +migrate Up
+-- Identity lists are filtered by creation time and metadata, and paged newest first.
+CREATE INDEX IF NOT EXISTS idx_identity_created_at ON blnk.identity(created_at DESC, identity_id DESC);
+CREATE INDEX IF NOT EXISTS idx_identity_meta_data ON blnk.identity USING GIN (meta_data jsonb_path_ops);
+
+-- +migrate Down
+DROP INDEX IF EXISTS blnk.idx_identity_meta_data;
+DROP INDEX IF EXISTS blnk.idx_identity_created_at;
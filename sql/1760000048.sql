@@ -0,0 +1,146 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+-- Every table holding a tenant's data records its tenant, so the row-level security policies can cover it.
+-- API keys, service accounts and request logs belong to their owner, who is the tenant.
+ALTER TABLE blnk.api_keys ADD COLUMN IF NOT EXISTS tenant_id TEXT GENERATED ALWAYS AS (owner_id) STORED;
+ALTER TABLE blnk.service_accounts ADD COLUMN IF NOT EXISTS tenant_id TEXT GENERATED ALWAYS AS (owner_id) STORED;
+ALTER TABLE blnk.request_logs ADD COLUMN IF NOT EXISTS tenant_id TEXT GENERATED ALWAYS AS (owner_id) STORED;
+
+-- +migrate StatementBegin
+DO
+$$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'accounts', 'api_key_access_denials', 'balance_monitors', 'balance_notification_preferences',
+        'balance_shardings', 'balance_snapshots', 'card_authorizations', 'contact_verifications', 'dormant_balances',
+        'escheatment_batches', 'external_accounts', 'external_transactions', 'identity_addresses',
+        'identity_documents', 'identity_erasures', 'identity_history', 'identity_merges', 'identity_relationships',
+        'identity_screenings', 'ledger_posting_rules', 'ledger_sequences', 'matches', 'matching_rules',
+        'minimum_balances', 'netting_entries', 'netting_groups', 'netting_settlements', 'reconciliation_progress',
+        'reconciliations', 'report_definitions', 'report_runs', 'scheduled_transaction_failures',
+        'statement_ingestions', 'statement_schedules', 'statements', 'transaction_challenges', 'transaction_journal',
+        'transaction_sequences', 'transaction_status_history', 'unmatched'
+    ] LOOP
+        EXECUTE format('ALTER TABLE blnk.%I ADD COLUMN IF NOT EXISTS tenant_id TEXT', t);
+        EXECUTE format('CREATE INDEX IF NOT EXISTS %I ON blnk.%I(tenant_id)', 'idx_' || t || '_tenant_id', t);
+    END LOOP;
+END;
+$$;
+-- +migrate StatementEnd
+
+-- New rows belong to the tenant of the session that inserts them. Rows inserted outside a tenant session, such
+-- as by the queue workers and scheduled jobs, inherit the tenant of the row they refer to. The trigger arguments
+-- are triples of a column of the new row, the table it refers to and the key of that table, tried in order.
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION blnk.inherit_tenant_id()
+    RETURNS TRIGGER
+AS
+$$
+DECLARE
+    parent_key TEXT;
+    i          INT := 0;
+BEGIN
+    IF NEW.tenant_id IS NULL THEN
+        NEW.tenant_id := NULLIF(current_setting('blnk.tenant_id', true), '');
+    END IF;
+    WHILE NEW.tenant_id IS NULL AND i + 2 < TG_NARGS LOOP
+        parent_key := to_jsonb(NEW) ->> TG_ARGV[i];
+        IF parent_key IS NOT NULL THEN
+            EXECUTE format('SELECT tenant_id FROM blnk.%I WHERE %I = $1', TG_ARGV[i + 1], TG_ARGV[i + 2])
+                INTO NEW.tenant_id
+                USING parent_key;
+        END IF;
+        i := i + 3;
+    END LOOP;
+    RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+CREATE TRIGGER accounts_inherit_tenant BEFORE INSERT ON blnk.accounts FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('ledger_id', 'ledgers', 'ledger_id');
+CREATE TRIGGER api_key_access_denials_inherit_tenant BEFORE INSERT ON blnk.api_key_access_denials FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('api_key_id', 'api_keys', 'api_key_id');
+CREATE TRIGGER balance_monitors_inherit_tenant BEFORE INSERT ON blnk.balance_monitors FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('balance_id', 'balances', 'balance_id');
+CREATE TRIGGER balance_notification_preferences_inherit_tenant BEFORE INSERT ON blnk.balance_notification_preferences FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('balance_id', 'balances', 'balance_id');
+CREATE TRIGGER balance_shardings_inherit_tenant BEFORE INSERT ON blnk.balance_shardings FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('balance_id', 'balances', 'balance_id');
+CREATE TRIGGER balance_snapshots_inherit_tenant BEFORE INSERT ON blnk.balance_snapshots FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('balance_id', 'balances', 'balance_id');
+CREATE TRIGGER card_authorizations_inherit_tenant BEFORE INSERT ON blnk.card_authorizations FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('source', 'balances', 'balance_id');
+CREATE TRIGGER contact_verifications_inherit_tenant BEFORE INSERT ON blnk.contact_verifications FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('identity_id', 'identity', 'identity_id');
+CREATE TRIGGER dormant_balances_inherit_tenant BEFORE INSERT ON blnk.dormant_balances FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('balance_id', 'balances', 'balance_id');
+CREATE TRIGGER escheatment_batches_inherit_tenant BEFORE INSERT ON blnk.escheatment_batches FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('holding_balance', 'balances', 'balance_id');
+CREATE TRIGGER external_accounts_inherit_tenant BEFORE INSERT ON blnk.external_accounts FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('identity_id', 'identity', 'identity_id');
+CREATE TRIGGER external_transactions_inherit_tenant BEFORE INSERT ON blnk.external_transactions FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id();
+CREATE TRIGGER identity_addresses_inherit_tenant BEFORE INSERT ON blnk.identity_addresses FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('identity_id', 'identity', 'identity_id');
+CREATE TRIGGER identity_documents_inherit_tenant BEFORE INSERT ON blnk.identity_documents FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('identity_id', 'identity', 'identity_id');
+CREATE TRIGGER identity_erasures_inherit_tenant BEFORE INSERT ON blnk.identity_erasures FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('identity_id', 'identity', 'identity_id');
+CREATE TRIGGER identity_history_inherit_tenant BEFORE INSERT ON blnk.identity_history FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('identity_id', 'identity', 'identity_id');
+CREATE TRIGGER identity_merges_inherit_tenant BEFORE INSERT ON blnk.identity_merges FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('survivor_id', 'identity', 'identity_id');
+CREATE TRIGGER identity_relationships_inherit_tenant BEFORE INSERT ON blnk.identity_relationships FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('identity_id', 'identity', 'identity_id');
+CREATE TRIGGER identity_screenings_inherit_tenant BEFORE INSERT ON blnk.identity_screenings FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('identity_id', 'identity', 'identity_id');
+CREATE TRIGGER ledger_posting_rules_inherit_tenant BEFORE INSERT ON blnk.ledger_posting_rules FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('ledger_id', 'ledgers', 'ledger_id');
+CREATE TRIGGER ledger_sequences_inherit_tenant BEFORE INSERT ON blnk.ledger_sequences FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('ledger_id', 'ledgers', 'ledger_id');
+CREATE TRIGGER matches_inherit_tenant BEFORE INSERT ON blnk.matches FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('reconciliation_id', 'reconciliations', 'reconciliation_id');
+CREATE TRIGGER matching_rules_inherit_tenant BEFORE INSERT ON blnk.matching_rules FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id();
+CREATE TRIGGER minimum_balances_inherit_tenant BEFORE INSERT ON blnk.minimum_balances FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('balance_id', 'balances', 'balance_id', 'ledger_id', 'ledgers', 'ledger_id');
+CREATE TRIGGER netting_entries_inherit_tenant BEFORE INSERT ON blnk.netting_entries FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('group_id', 'netting_groups', 'group_id');
+CREATE TRIGGER netting_groups_inherit_tenant BEFORE INSERT ON blnk.netting_groups FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id();
+CREATE TRIGGER netting_settlements_inherit_tenant BEFORE INSERT ON blnk.netting_settlements FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('group_id', 'netting_groups', 'group_id');
+CREATE TRIGGER reconciliation_progress_inherit_tenant BEFORE INSERT ON blnk.reconciliation_progress FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('reconciliation_id', 'reconciliations', 'reconciliation_id');
+CREATE TRIGGER reconciliations_inherit_tenant BEFORE INSERT ON blnk.reconciliations FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id();
+CREATE TRIGGER report_definitions_inherit_tenant BEFORE INSERT ON blnk.report_definitions FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id();
+CREATE TRIGGER report_runs_inherit_tenant BEFORE INSERT ON blnk.report_runs FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('report_id', 'report_definitions', 'report_id');
+CREATE TRIGGER scheduled_transaction_failures_inherit_tenant BEFORE INSERT ON blnk.scheduled_transaction_failures FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('source', 'balances', 'balance_id');
+CREATE TRIGGER statement_ingestions_inherit_tenant BEFORE INSERT ON blnk.statement_ingestions FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id();
+CREATE TRIGGER statement_schedules_inherit_tenant BEFORE INSERT ON blnk.statement_schedules FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id();
+CREATE TRIGGER statements_inherit_tenant BEFORE INSERT ON blnk.statements FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('schedule_id', 'statement_schedules', 'schedule_id');
+CREATE TRIGGER transaction_challenges_inherit_tenant BEFORE INSERT ON blnk.transaction_challenges FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id();
+CREATE TRIGGER transaction_journal_inherit_tenant BEFORE INSERT ON blnk.transaction_journal FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('transaction_id', 'transactions', 'transaction_id');
+CREATE TRIGGER transaction_sequences_inherit_tenant BEFORE INSERT ON blnk.transaction_sequences FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('ledger_id', 'ledgers', 'ledger_id');
+CREATE TRIGGER transaction_status_history_inherit_tenant BEFORE INSERT ON blnk.transaction_status_history FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('transaction_id', 'transactions', 'transaction_id');
+CREATE TRIGGER unmatched_inherit_tenant BEFORE INSERT ON blnk.unmatched FOR EACH ROW EXECUTE FUNCTION blnk.inherit_tenant_id('reconciliation_id', 'reconciliations', 'reconciliation_id');
+
+-- +migrate Down
+-- +migrate StatementBegin
+DO
+$$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'accounts', 'api_key_access_denials', 'balance_monitors', 'balance_notification_preferences',
+        'balance_shardings', 'balance_snapshots', 'card_authorizations', 'contact_verifications', 'dormant_balances',
+        'escheatment_batches', 'external_accounts', 'external_transactions', 'identity_addresses',
+        'identity_documents', 'identity_erasures', 'identity_history', 'identity_merges', 'identity_relationships',
+        'identity_screenings', 'ledger_posting_rules', 'ledger_sequences', 'matches', 'matching_rules',
+        'minimum_balances', 'netting_entries', 'netting_groups', 'netting_settlements', 'reconciliation_progress',
+        'reconciliations', 'report_definitions', 'report_runs', 'scheduled_transaction_failures',
+        'statement_ingestions', 'statement_schedules', 'statements', 'transaction_challenges', 'transaction_journal',
+        'transaction_sequences', 'transaction_status_history', 'unmatched'
+    ] LOOP
+        EXECUTE format('DROP TRIGGER IF EXISTS %I ON blnk.%I', t || '_inherit_tenant', t);
+        EXECUTE format('DROP INDEX IF EXISTS blnk.%I', 'idx_' || t || '_tenant_id');
+        EXECUTE format('ALTER TABLE blnk.%I DROP COLUMN IF EXISTS tenant_id', t);
+    END LOOP;
+END;
+$$;
+-- +migrate StatementEnd
+DROP FUNCTION IF EXISTS blnk.inherit_tenant_id();
+
+ALTER TABLE blnk.request_logs DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE blnk.service_accounts DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE blnk.api_keys DROP COLUMN IF EXISTS tenant_id;
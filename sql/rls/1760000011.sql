@@ -0,0 +1,54 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+-- Sessions acting for a tenant see and write only that tenant's rows. Sessions without a tenant, such as the
+-- master key and the queue workers, are unrestricted. FORCE applies the policies to the table owner as well.
+ALTER TABLE blnk.ledgers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE blnk.ledgers FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON blnk.ledgers
+    USING (COALESCE(current_setting('blnk.tenant_id', true), '') = '' OR tenant_id = current_setting('blnk.tenant_id', true))
+    WITH CHECK (COALESCE(current_setting('blnk.tenant_id', true), '') = '' OR tenant_id = current_setting('blnk.tenant_id', true));
+
+ALTER TABLE blnk.identity ENABLE ROW LEVEL SECURITY;
+ALTER TABLE blnk.identity FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON blnk.identity
+    USING (COALESCE(current_setting('blnk.tenant_id', true), '') = '' OR tenant_id = current_setting('blnk.tenant_id', true))
+    WITH CHECK (COALESCE(current_setting('blnk.tenant_id', true), '') = '' OR tenant_id = current_setting('blnk.tenant_id', true));
+
+ALTER TABLE blnk.balances ENABLE ROW LEVEL SECURITY;
+ALTER TABLE blnk.balances FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON blnk.balances
+    USING (COALESCE(current_setting('blnk.tenant_id', true), '') = '' OR tenant_id = current_setting('blnk.tenant_id', true))
+    WITH CHECK (COALESCE(current_setting('blnk.tenant_id', true), '') = '' OR tenant_id = current_setting('blnk.tenant_id', true));
+
+ALTER TABLE blnk.transactions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE blnk.transactions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON blnk.transactions
+    USING (COALESCE(current_setting('blnk.tenant_id', true), '') = '' OR tenant_id = current_setting('blnk.tenant_id', true))
+    WITH CHECK (COALESCE(current_setting('blnk.tenant_id', true), '') = '' OR tenant_id = current_setting('blnk.tenant_id', true));
+
+-- +migrate Down
+DROP POLICY IF EXISTS tenant_isolation ON blnk.transactions;
+ALTER TABLE blnk.transactions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE blnk.transactions DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON blnk.balances;
+ALTER TABLE blnk.balances NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE blnk.balances DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON blnk.identity;
+ALTER TABLE blnk.identity NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE blnk.identity DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON blnk.ledgers;
+ALTER TABLE blnk.ledgers NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE blnk.ledgers DISABLE ROW LEVEL SECURITY;
@@ -0,0 +1,72 @@
+-- Copyright 2024 Blnk Finance Authors.
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--     http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- +migrate Up
+-- The same tenant isolation as the ledger tables, for every other table that holds a tenant's data.
+-- +migrate StatementBegin
+DO
+$$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'accounts', 'api_key_access_denials', 'api_keys', 'balance_aliases', 'balance_monitors',
+        'balance_notification_preferences', 'balance_shardings', 'balance_snapshots', 'card_authorizations',
+        'contact_verifications', 'dormant_balances', 'escheatment_batches', 'external_accounts',
+        'external_transactions', 'identity_addresses', 'identity_documents', 'identity_erasures', 'identity_history',
+        'identity_merges', 'identity_relationships', 'identity_screenings', 'ledger_posting_rules',
+        'ledger_sequences', 'matches', 'matching_rules', 'minimum_balances', 'netting_entries', 'netting_groups',
+        'netting_settlements', 'reconciliation_progress', 'reconciliations', 'report_definitions', 'report_runs',
+        'request_logs', 'scheduled_transaction_failures', 'service_accounts', 'statement_ingestions',
+        'statement_schedules', 'statements', 'transaction_challenges', 'transaction_journal',
+        'transaction_sequences', 'transaction_status_history', 'unmatched'
+    ] LOOP
+        EXECUTE format('ALTER TABLE blnk.%I ENABLE ROW LEVEL SECURITY', t);
+        EXECUTE format('ALTER TABLE blnk.%I FORCE ROW LEVEL SECURITY', t);
+        EXECUTE format($policy$
+            CREATE POLICY tenant_isolation ON blnk.%I
+                USING (COALESCE(current_setting('blnk.tenant_id', true), '') = '' OR tenant_id = current_setting('blnk.tenant_id', true))
+                WITH CHECK (COALESCE(current_setting('blnk.tenant_id', true), '') = '' OR tenant_id = current_setting('blnk.tenant_id', true))
+        $policy$, t);
+    END LOOP;
+END;
+$$;
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DO
+$$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'accounts', 'api_key_access_denials', 'api_keys', 'balance_aliases', 'balance_monitors',
+        'balance_notification_preferences', 'balance_shardings', 'balance_snapshots', 'card_authorizations',
+        'contact_verifications', 'dormant_balances', 'escheatment_batches', 'external_accounts',
+        'external_transactions', 'identity_addresses', 'identity_documents', 'identity_erasures', 'identity_history',
+        'identity_merges', 'identity_relationships', 'identity_screenings', 'ledger_posting_rules',
+        'ledger_sequences', 'matches', 'matching_rules', 'minimum_balances', 'netting_entries', 'netting_groups',
+        'netting_settlements', 'reconciliation_progress', 'reconciliations', 'report_definitions', 'report_runs',
+        'request_logs', 'scheduled_transaction_failures', 'service_accounts', 'statement_ingestions',
+        'statement_schedules', 'statements', 'transaction_challenges', 'transaction_journal',
+        'transaction_sequences', 'transaction_status_history', 'unmatched'
+    ] LOOP
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON blnk.%I', t);
+        EXECUTE format('ALTER TABLE blnk.%I NO FORCE ROW LEVEL SECURITY', t);
+        EXECUTE format('ALTER TABLE blnk.%I DISABLE ROW LEVEL SECURITY', t);
+    END LOOP;
+END;
+$$;
+-- +migrate StatementEnd
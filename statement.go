@@ -0,0 +1,581 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/blnkfinance/blnk/config"
+	redlock "github.com/blnkfinance/blnk/internal/lock"
+	"github.com/blnkfinance/blnk/internal/resilience"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+	"github.com/wacul/ptr"
+)
+
+const (
+	statementPageSize      = 500
+	statementScheduleBatch = 50
+	statementLockTimeout   = 10 * time.Minute
+	statementKeyPrefix     = "statements"
+)
+
+// statementStore persists generated statement files.
+type statementStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// s3StatementStore stores statements in the configured S3 bucket.
+type s3StatementStore struct {
+	client *s3.S3
+	bucket string
+}
+
+// newS3StatementStore creates a statement store backed by the S3 settings used for backups.
+func newS3StatementStore(cfg *config.Configuration) (*s3StatementStore, error) {
+	if cfg.S3BucketName == "" {
+		return nil, errors.New("s3 bucket is not configured for statement storage")
+	}
+	client, err := newS3Client(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &s3StatementStore{client: client, bucket: cfg.S3BucketName}, nil
+}
+
+// newS3Client creates a client for the S3 settings used for backups.
+func newS3Client(cfg *config.Configuration) (*s3.S3, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials(cfg.AwsAccessKeyId, cfg.AwsSecretAccessKey, ""),
+		Endpoint:         aws.String(cfg.S3Endpoint),
+		Region:           aws.String(cfg.S3Region),
+		S3ForcePathStyle: aws.Bool(true),
+		HTTPClient:       resilience.Get(resilience.S3).Client(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(sess), nil
+}
+
+// Put stores an object, with the content type of its extension. Statements are CSV; reports may be JSON.
+func (s *s3StatementStore) Put(ctx context.Context, key string, data []byte) error {
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "text/csv"
+	}
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+func (s *s3StatementStore) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+// getStatementStore returns the statement store, creating the S3 store on first use.
+func (l *Blnk) getStatementStore() (statementStore, error) {
+	if l.statements != nil {
+		return l.statements, nil
+	}
+	cfg, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	store, err := newS3StatementStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	l.statements = store
+	return store, nil
+}
+
+// nextStatementRun returns the first midnight (UTC) on the given day of the month strictly after t.
+func nextStatementRun(t time.Time, dayOfMonth int) time.Time {
+	t = t.UTC()
+	candidate := time.Date(t.Year(), t.Month(), dayOfMonth, 0, 0, 0, 0, time.UTC)
+	if !candidate.After(t) {
+		candidate = candidate.AddDate(0, 1, 0)
+	}
+	return candidate
+}
+
+// CreateStatementSchedule registers a monthly statement for a balance or an identity.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - schedule: The schedule to create. DayOfMonth defaults to 1 and must be between 1 and 28.
+//
+// Returns:
+// - *model.StatementSchedule: The created schedule with its first run time.
+// - error: An error if the schedule is invalid or cannot be saved.
+func (l *Blnk) CreateStatementSchedule(ctx context.Context, schedule model.StatementSchedule) (*model.StatementSchedule, error) {
+	switch schedule.EntityType {
+	case "balance":
+		if _, err := l.datasource.GetBalanceByIDLite(ctx, schedule.EntityID); err != nil {
+			return nil, fmt.Errorf("balance %s not found: %w", schedule.EntityID, err)
+		}
+	case "identity":
+		if _, err := l.datasource.GetIdentityByID(ctx, schedule.EntityID); err != nil {
+			return nil, fmt.Errorf("identity %s not found: %w", schedule.EntityID, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported entity type: %s", schedule.EntityType)
+	}
+
+	if schedule.DayOfMonth == 0 {
+		schedule.DayOfMonth = 1
+	}
+	if schedule.DayOfMonth < 1 || schedule.DayOfMonth > 28 {
+		return nil, errors.New("day_of_month must be between 1 and 28")
+	}
+
+	switch schedule.Channel {
+	case model.StatementChannelWebhook:
+	case model.StatementChannelEmail:
+		if schedule.Email == "" {
+			return nil, errors.New("email is required for email delivery")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported delivery channel: %s", schedule.Channel)
+	}
+
+	now := time.Now()
+	schedule.ScheduleID = model.GenerateUUIDWithSuffix("stmt_sched")
+	schedule.IsActive = true
+	schedule.NextRunAt = nextStatementRun(now, schedule.DayOfMonth)
+	schedule.CreatedAt = now
+
+	if err := l.datasource.CreateStatementSchedule(ctx, &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// GetStatementSchedule retrieves a statement schedule by ID.
+func (l *Blnk) GetStatementSchedule(ctx context.Context, scheduleID string) (*model.StatementSchedule, error) {
+	return l.datasource.GetStatementSchedule(ctx, scheduleID)
+}
+
+// GetStatement retrieves a generated statement by ID.
+func (l *Blnk) GetStatement(ctx context.Context, statementID string) (*model.Statement, error) {
+	return l.datasource.GetStatement(ctx, statementID)
+}
+
+// ListStatements retrieves the statements generated by a schedule, newest first.
+func (l *Blnk) ListStatements(ctx context.Context, scheduleID string) ([]*model.Statement, error) {
+	return l.datasource.GetStatementsBySchedule(ctx, scheduleID)
+}
+
+// RunDueStatements generates and delivers statements for every schedule that is due.
+// Each schedule is locked while it runs so that several workers can call this safely.
+//
+// Parameters:
+// - ctx: The context for the operation.
+//
+// Returns:
+// - int: The number of statements generated.
+// - error: An error if the due schedules cannot be loaded.
+func (l *Blnk) RunDueStatements(ctx context.Context) (int, error) {
+	schedules, err := l.datasource.GetDueStatementSchedules(ctx, time.Now(), statementScheduleBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	generated := 0
+	for _, schedule := range schedules {
+		locker := redlock.NewLocker(l.redis, "statement-schedule:"+schedule.ScheduleID, model.GenerateUUIDWithSuffix("loc"))
+		if err := locker.Lock(ctx, statementLockTimeout); err != nil {
+			continue
+		}
+
+		periodEnd := schedule.NextRunAt
+		periodStart := periodEnd.AddDate(0, -1, 0)
+		if _, err := l.GenerateStatement(ctx, schedule, periodStart, periodEnd); err != nil {
+			logrus.WithError(err).WithField("schedule_id", schedule.ScheduleID).Error("failed to generate statement")
+		} else {
+			generated++
+		}
+
+		// Advance the schedule even on failure so that one bad period does not block later ones.
+		if err := l.datasource.UpdateStatementScheduleRun(ctx, schedule.ScheduleID, time.Now(), nextStatementRun(periodEnd, schedule.DayOfMonth)); err != nil {
+			logrus.WithError(err).WithField("schedule_id", schedule.ScheduleID).Error("failed to advance statement schedule")
+		}
+		_ = locker.Unlock(ctx)
+	}
+	return generated, nil
+}
+
+// GenerateStatement builds the statement for a schedule and period, stores it and delivers it.
+// A delivery failure is recorded on the statement and does not fail generation; use ResendStatement to retry.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - schedule: The schedule the statement belongs to.
+// - periodStart: The inclusive start of the statement period.
+// - periodEnd: The exclusive end of the statement period.
+//
+// Returns:
+// - *model.Statement: The generated statement.
+// - error: An error if the statement could not be built or stored.
+func (l *Blnk) GenerateStatement(ctx context.Context, schedule *model.StatementSchedule, periodStart, periodEnd time.Time) (*model.Statement, error) {
+	balanceIDs := []string{schedule.EntityID}
+	if schedule.EntityType == "identity" {
+		balances, err := l.datasource.GetBalancesByIdentity(ctx, schedule.EntityID)
+		if err != nil {
+			return nil, err
+		}
+		balanceIDs = balanceIDs[:0]
+		for _, balance := range balances {
+			balanceIDs = append(balanceIDs, balance.BalanceID)
+		}
+	}
+
+	sections := make([]model.StatementBalance, 0, len(balanceIDs))
+	for _, balanceID := range balanceIDs {
+		section, err := l.buildStatementBalance(ctx, balanceID, periodStart, periodEnd)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+
+	data, err := renderStatementCSV(sections, l.statementLocaleFormat(ctx, schedule))
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &model.Statement{
+		StatementID:    model.GenerateUUIDWithSuffix("stmt"),
+		ScheduleID:     schedule.ScheduleID,
+		EntityType:     schedule.EntityType,
+		EntityID:       schedule.EntityID,
+		PeriodStart:    periodStart,
+		PeriodEnd:      periodEnd,
+		Channel:        schedule.Channel,
+		DeliveryStatus: model.StatementDeliveryPending,
+		CreatedAt:      time.Now(),
+	}
+	statement.StorageKey = fmt.Sprintf("%s/%s/%s-%s.csv", statementKeyPrefix, schedule.EntityID, periodStart.Format("2006-01-02"), statement.StatementID)
+
+	store, err := l.getStatementStore()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Put(ctx, statement.StorageKey, data); err != nil {
+		return nil, fmt.Errorf("failed to store statement: %w", err)
+	}
+
+	if err := l.datasource.CreateStatement(ctx, statement); err != nil {
+		return nil, err
+	}
+
+	l.deliverStatement(ctx, statement, schedule.Email, data)
+	return statement, nil
+}
+
+// ResendStatement delivers a previously generated statement again.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - statementID: The ID of the statement.
+//
+// Returns:
+// - *model.Statement: The statement with its updated delivery state.
+// - error: An error if the statement or its file cannot be loaded.
+func (l *Blnk) ResendStatement(ctx context.Context, statementID string) (*model.Statement, error) {
+	statement, err := l.datasource.GetStatement(ctx, statementID)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := l.datasource.GetStatementSchedule(ctx, statement.ScheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := l.getStatementStore()
+	if err != nil {
+		return nil, err
+	}
+	data, err := store.Get(ctx, statement.StorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load statement: %w", err)
+	}
+
+	l.deliverStatement(ctx, statement, schedule.Email, data)
+	return statement, nil
+}
+
+// buildStatementBalance collects the opening balance and the applied transactions of a balance for a period.
+// The closing balance is derived from the listed transactions so that the statement always adds up.
+func (l *Blnk) buildStatementBalance(ctx context.Context, balanceID string, periodStart, periodEnd time.Time) (model.StatementBalance, error) {
+	section := model.StatementBalance{BalanceID: balanceID, OpeningBalance: big.NewInt(0)}
+
+	balance, err := l.datasource.GetBalanceByIDLite(ctx, balanceID)
+	if err != nil {
+		return section, err
+	}
+	section.Currency = balance.Currency
+	section.CurrencyMultiplier = balance.CurrencyMultiplier
+
+	if opening, err := l.datasource.GetBalanceAtTime(ctx, balanceID, periodStart, false); err == nil && opening != nil && opening.Balance != nil {
+		section.OpeningBalance = new(big.Int).Set(opening.Balance)
+	}
+
+	closing := new(big.Int).Set(section.OpeningBalance)
+	page := model.Page{Limit: statementPageSize}
+	for {
+		txns, err := l.datasource.GetBalanceTransactionsBetween(ctx, balanceID, periodStart, periodEnd, page)
+		if err != nil {
+			return section, err
+		}
+		for _, txn := range txns {
+			amount := txn.PreciseAmount
+			if amount == nil {
+				amount = big.NewInt(0)
+			}
+			if txn.Destination == balanceID {
+				closing.Add(closing, amount)
+			}
+			if txn.Source == balanceID {
+				closing.Sub(closing, amount)
+			}
+		}
+		section.Transactions = append(section.Transactions, txns...)
+		if len(txns) < statementPageSize {
+			break
+		}
+		page.After = model.NextPageCursor(txns, statementPageSize, l.TransactionHistoryCursor)
+	}
+	section.ClosingBalance = closing
+
+	memo, err := l.datasource.GetBalanceNettingEntries(ctx, balanceID, periodStart, periodEnd)
+	if err != nil {
+		return section, err
+	}
+	section.MemoEntries = memo
+	return section, nil
+}
+
+// statementLocaleFormat returns the format of the identity a statement is for: the scheduled identity, or the
+// identity of the scheduled balance.
+func (l *Blnk) statementLocaleFormat(ctx context.Context, schedule *model.StatementSchedule) model.LocaleFormat {
+	identityID := schedule.EntityID
+	if schedule.EntityType != "identity" {
+		identityID = ""
+		if balance, err := l.datasource.GetBalanceByIDLite(ctx, schedule.EntityID); err == nil {
+			identityID = balance.IdentityID
+		}
+	}
+	return l.identityLocaleFormat(ctx, identityID)
+}
+
+// renderStatementCSV renders statement sections as CSV. Each balance starts with an opening row and ends with a closing row.
+// Netting memo entries follow the transactions with a memo direction so they are not mistaken for postings.
+// Dates are written in the reader's timezone, and display_amount repeats the amount in the reader's locale.
+func renderStatementCSV(sections []model.StatementBalance, format model.LocaleFormat) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"balance_id", "date", "transaction_id", "reference", "description", "direction", "amount", "precise_amount", "currency", "display_amount"}); err != nil {
+		return nil, err
+	}
+
+	for _, section := range sections {
+		rows := [][]string{{section.BalanceID, "", "", "", "Opening balance", "", "", section.OpeningBalance.String(), section.Currency,
+			format.FormatAmount(section.OpeningBalance, section.CurrencyMultiplier)}}
+		for _, txn := range section.Transactions {
+			direction := "debit"
+			if txn.Destination == section.BalanceID {
+				direction = "credit"
+			}
+			precise := ""
+			if txn.PreciseAmount != nil {
+				precise = txn.PreciseAmount.String()
+			}
+			rows = append(rows, []string{
+				section.BalanceID,
+				format.FormatTime(txn.CreatedAt),
+				txn.TransactionID,
+				txn.Reference,
+				txn.Description,
+				direction,
+				strconv.FormatFloat(txn.Amount, 'f', -1, 64),
+				precise,
+				txn.Currency,
+				format.FormatAmount(txn.PreciseAmount, txn.Precision),
+			})
+		}
+		for _, entry := range section.MemoEntries {
+			direction := "memo debit"
+			if entry.Destination == section.BalanceID {
+				direction = "memo credit"
+			}
+			precise := ""
+			if entry.PreciseAmount != nil {
+				precise = entry.PreciseAmount.String()
+			}
+			rows = append(rows, []string{
+				section.BalanceID,
+				format.FormatTime(entry.CreatedAt),
+				entry.TransactionID,
+				entry.Reference,
+				entry.Description,
+				direction,
+				strconv.FormatFloat(entry.Amount, 'f', -1, 64),
+				precise,
+				entry.Currency,
+				format.FormatAmount(entry.PreciseAmount, section.CurrencyMultiplier),
+			})
+		}
+		rows = append(rows, []string{section.BalanceID, "", "", "", "Closing balance", "", "", section.ClosingBalance.String(), section.Currency,
+			format.FormatAmount(section.ClosingBalance, section.CurrencyMultiplier)})
+		if err := w.WriteAll(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// deliverStatement sends a statement over its channel and records the attempt.
+func (l *Blnk) deliverStatement(ctx context.Context, statement *model.Statement, email string, data []byte) {
+	var err error
+	switch statement.Channel {
+	case model.StatementChannelEmail:
+		err = sendStatementEmail(email, statement, data)
+	default:
+		err = l.SendWebhook(NewWebhook{Event: "statement.generated", Payload: statement})
+	}
+
+	statement.DeliveryAttempts++
+	if err != nil {
+		statement.DeliveryStatus = model.StatementDeliveryFailed
+		statement.LastError = err.Error()
+	} else {
+		statement.DeliveryStatus = model.StatementDeliveryDelivered
+		statement.LastError = ""
+		statement.DeliveredAt = ptr.Time(time.Now())
+	}
+
+	if err := l.datasource.UpdateStatementDelivery(ctx, statement); err != nil {
+		logrus.WithError(err).WithField("statement_id", statement.StatementID).Error("failed to record statement delivery")
+	}
+}
+
+// sendStatementEmail emails a statement with the CSV attached using the configured SMTP server.
+func sendStatementEmail(to string, statement *model.Statement, data []byte) error {
+	cfg, err := config.Fetch()
+	if err != nil {
+		return err
+	}
+	smtpCfg := cfg.Notification.SMTP
+	if smtpCfg.Host == "" {
+		return errors.New("smtp is not configured")
+	}
+
+	msg, err := buildStatementEmail(smtpCfg.From, to, statement, data)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if smtpCfg.Username != "" {
+		auth = smtp.PlainAuth("", smtpCfg.Username, smtpCfg.Password, smtpCfg.Host)
+	}
+	addr := fmt.Sprintf("%s:%d", smtpCfg.Host, smtpCfg.Port)
+	return smtp.SendMail(addr, auth, smtpCfg.From, []string{to}, msg)
+}
+
+// buildStatementEmail builds a multipart MIME message with the statement attached.
+func buildStatementEmail(from, to string, statement *model.Statement, data []byte) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	period := fmt.Sprintf("%s to %s", statement.PeriodStart.Format("2006-01-02"), statement.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"))
+
+	textPart, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(textPart, "Your statement for %s is attached.\r\n", period); err != nil {
+		return nil, err
+	}
+
+	filename := fmt.Sprintf("statement-%s.csv", statement.PeriodStart.Format("2006-01"))
+	attachment, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/csv; name=\"" + filename + "\""},
+		"Content-Disposition":       {"attachment; filename=\"" + filename + "\""},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(attachment, encoded[:76]+"\r\n"); err != nil {
+			return nil, err
+		}
+		encoded = encoded[76:]
+	}
+	if _, err := io.WriteString(attachment, encoded+"\r\n"); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: Account statement %s\r\n", period)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
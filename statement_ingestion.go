@@ -0,0 +1,368 @@
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/ingestion"
+	redlock "github.com/blnkfinance/blnk/internal/lock"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+const statementIngestionLockTimeout = 10 * time.Minute
+
+// newStatementSources returns the dead drops configured in cfg. It is a variable so tests can replace the
+// sources.
+var newStatementSources = func(cfg config.StatementIngestionConfig) []ingestion.Source {
+	var sources []ingestion.Source
+	if cfg.IMAP.Host != "" {
+		sources = append(sources, ingestion.NewIMAPSource(cfg.IMAP))
+	}
+	if cfg.SFTP.Host != "" {
+		sources = append(sources, ingestion.NewSFTPSource(cfg.SFTP))
+	}
+	return sources
+}
+
+// RunStatementIngestion collects new statement files from the configured mailbox and SFTP folder and ingests
+// each of them: the file is matched to a profile, its columns are renamed to the ones reconciliation reads,
+// its rows are uploaded and, if the profile says so, a reconciliation of them is started. The outcome of each
+// file is recorded and announced with a webhook, and emailed when the profile has a notification address.
+// Files are acknowledged once their outcome is recorded, and a file ingested before is acknowledged
+// without being ingested again.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - []*model.StatementIngestion: The ingestions of the files collected.
+// - error: An error if the run could not start or a source could not be read.
+func (l *Blnk) RunStatementIngestion(ctx context.Context) ([]*model.StatementIngestion, error) {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	sources := newStatementSources(cnf.StatementIngestion)
+	if len(sources) == 0 {
+		return nil, errors.New("statement ingestion is not configured")
+	}
+
+	locker := redlock.NewLocker(l.redis, "statement-ingestion", model.GenerateUUIDWithSuffix("loc"))
+	if err := locker.Lock(ctx, statementIngestionLockTimeout); err != nil {
+		return nil, fmt.Errorf("failed to acquire lock for statement ingestion: %w", err)
+	}
+	defer l.releaseLock(ctx, locker)
+
+	ingestions := []*model.StatementIngestion{}
+	var errs []error
+	for _, source := range sources {
+		ingested, err := l.ingestFromSource(ctx, cnf, source)
+		ingestions = append(ingestions, ingested...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+		}
+	}
+	return ingestions, errors.Join(errs...)
+}
+
+// ListStatementIngestions lists the statement files collected from the dead drops, most recently received first.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - page model.Page: The page of ingestions to read.
+//
+// Returns:
+// - []*model.StatementIngestion: The ingestions.
+// - error: An error if the ingestions could not be retrieved.
+func (l *Blnk) ListStatementIngestions(ctx context.Context, page model.Page) ([]*model.StatementIngestion, error) {
+	return l.datasource.ListStatementIngestions(ctx, page)
+}
+
+// ingestFromSource ingests the files of one source. A file that cannot be recorded is left unacknowledged so
+// that the next run retries it.
+func (l *Blnk) ingestFromSource(ctx context.Context, cnf *config.Configuration, source ingestion.Source) ([]*model.StatementIngestion, error) {
+	defer func() {
+		if err := source.Close(); err != nil {
+			logrus.Warnf("failed to close %s statement source: %v", source.Name(), err)
+		}
+	}()
+
+	files, err := source.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ingestions []*model.StatementIngestion
+	for _, file := range files {
+		ingested, err := l.ingestStatementFile(ctx, cnf, file)
+		if err != nil {
+			logrus.Errorf("failed to ingest statement %s from %s: %v", file.Name, source.Name(), err)
+			continue
+		}
+		if err := source.Ack(ctx, file); err != nil {
+			return ingestions, fmt.Errorf("failed to acknowledge %s: %w", file.Name, err)
+		}
+		if ingested != nil {
+			ingestions = append(ingestions, ingested)
+		}
+	}
+	return ingestions, nil
+}
+
+// ingestStatementFile records a file and takes it through upload and reconciliation. It returns nil without
+// an error when the file was ingested before, and an error only when the outcome could not be recorded.
+func (l *Blnk) ingestStatementFile(ctx context.Context, cnf *config.Configuration, file ingestion.File) (*model.StatementIngestion, error) {
+	fingerprint := sha256.Sum256(file.Data)
+	record := &model.StatementIngestion{
+		IngestionID: model.GenerateUUIDWithSuffix("ingestion"),
+		Channel:     file.Source,
+		Sender:      file.Sender,
+		Filename:    file.Name,
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+		Status:      model.StatementIngestionReceived,
+		ReceivedAt:  file.ReceivedAt.UTC(),
+	}
+	recorded, err := l.datasource.RecordStatementIngestion(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	if !recorded {
+		return nil, nil
+	}
+
+	profile := matchIngestionProfile(cnf.StatementIngestion.Profiles, file)
+	if profile == nil {
+		record.Status = model.StatementIngestionUnmatched
+		record.Error = "no ingestion profile accepts the file"
+	} else {
+		record.Profile = profile.Name
+		l.stageStatement(ctx, cnf, profile, file, record)
+	}
+
+	completedAt := time.Now().UTC()
+	record.CompletedAt = &completedAt
+	if err := l.datasource.UpdateStatementIngestion(ctx, record); err != nil {
+		return nil, err
+	}
+	l.notifyStatementIngestion(record, profile)
+	return record, nil
+}
+
+// stageStatement uploads the rows of a file and starts its reconciliation, recording how far it got.
+func (l *Blnk) stageStatement(ctx context.Context, cnf *config.Configuration, profile *config.IngestionProfile, file ingestion.File, record *model.StatementIngestion) {
+	fail := func(err error) {
+		record.Status = model.StatementIngestionFailed
+		record.Error = err.Error()
+	}
+
+	data, rows, err := remapStatementColumns(file.Data, file.Name, profile.Columns)
+	if err != nil {
+		fail(err)
+		return
+	}
+	source := profile.Source
+	if source == "" {
+		source = profile.Name
+	}
+	uploadID, total, err := l.UploadExternalData(ctx, source, bytes.NewReader(data), file.Name)
+	if err != nil {
+		fail(fmt.Errorf("failed to upload statement: %w", err))
+		return
+	}
+	if total == 0 {
+		total = rows
+	}
+	record.Status = model.StatementIngestionUploaded
+	record.UploadID = uploadID
+	record.RecordCount = total
+
+	if !profile.AutoReconcile {
+		return
+	}
+	strategy := profile.Strategy
+	if strategy == "" {
+		strategy = cnf.Reconciliation.DefaultStrategy
+	}
+	reconciliationID, err := l.StartReconciliation(ctx, uploadID, strategy, profile.GroupCriteria, profile.MatchingRuleIDs, profile.DryRun)
+	if err != nil {
+		fail(fmt.Errorf("failed to start reconciliation: %w", err))
+		return
+	}
+	record.Status = model.StatementIngestionReconciling
+	record.ReconciliationID = reconciliationID
+}
+
+// matchIngestionProfile returns the first profile that accepts a file, or nil if none does.
+func matchIngestionProfile(profiles []config.IngestionProfile, file ingestion.File) *config.IngestionProfile {
+	for i := range profiles {
+		profile := &profiles[i]
+		if len(profile.Senders) == 0 && profile.FilenamePattern == "" {
+			continue
+		}
+		if len(profile.Senders) > 0 && !senderMatches(profile.Senders, file.Sender) {
+			continue
+		}
+		if profile.FilenamePattern != "" {
+			matched, err := path.Match(strings.ToLower(profile.FilenamePattern), strings.ToLower(file.Name))
+			if err != nil || !matched {
+				continue
+			}
+		}
+		return profile
+	}
+	return nil
+}
+
+// senderMatches reports whether an email address is one of senders. A sender starting with "@" matches
+// every address of its domain.
+func senderMatches(senders []string, address string) bool {
+	if address == "" {
+		return false
+	}
+	address = strings.ToLower(address)
+	for _, sender := range senders {
+		sender = strings.ToLower(strings.TrimSpace(sender))
+		if strings.HasPrefix(sender, "@") && strings.HasSuffix(address, sender) || sender == address {
+			return true
+		}
+	}
+	return false
+}
+
+// remapStatementColumns renames the headers of a CSV statement to the columns reconciliation reads. A header
+// that already has the name of a mapped column is renamed out of its way. Statements that are not CSV are
+// returned unchanged.
+//
+// Parameters:
+// - data []byte: The contents of the statement.
+// - filename string: The name of the statement file.
+// - columns map[string]string: The header of each column, keyed by column name.
+//
+// Returns:
+// - []byte: The statement with its headers renamed.
+// - int: The number of rows in the statement, or 0 if it is not CSV.
+// - error: An error if the statement cannot be read as CSV.
+func remapStatementColumns(data []byte, filename string, columns map[string]string) ([]byte, int, error) {
+	fileType, err := detectFileType(data, filename)
+	if err != nil || !strings.HasPrefix(fileType, "text/csv") {
+		return data, 0, nil
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read statement: %w", err)
+	}
+	if len(records) == 0 {
+		return data, 0, nil
+	}
+	if len(columns) == 0 {
+		return data, len(records) - 1, nil
+	}
+
+	renames := make(map[string]string, len(columns))
+	mapped := make(map[string]bool, len(columns))
+	for column, header := range columns {
+		column = strings.ToLower(strings.TrimSpace(column))
+		renames[strings.ToLower(strings.TrimSpace(header))] = column
+		mapped[column] = true
+	}
+	for i, header := range records[0] {
+		key := strings.ToLower(strings.TrimSpace(header))
+		if column, ok := renames[key]; ok {
+			records[0][i] = column
+		} else if mapped[key] {
+			records[0][i] = "original_" + header
+		}
+	}
+
+	var out bytes.Buffer
+	writer := csv.NewWriter(&out)
+	if err := writer.WriteAll(records); err != nil {
+		return nil, 0, fmt.Errorf("failed to rewrite statement: %w", err)
+	}
+	return out.Bytes(), len(records) - 1, nil
+}
+
+// notifyStatementIngestion announces the outcome of an ingestion with a webhook, and emails it to the
+// profile's notification address.
+func (l *Blnk) notifyStatementIngestion(record *model.StatementIngestion, profile *config.IngestionProfile) {
+	event := "statement_ingestion.completed"
+	switch record.Status {
+	case model.StatementIngestionFailed:
+		event = "statement_ingestion.failed"
+	case model.StatementIngestionUnmatched:
+		event = "statement_ingestion.unmatched"
+	}
+
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: record}); err != nil {
+			notification.NotifyError(err)
+		}
+		if profile != nil && profile.NotifyEmail != "" {
+			if err := sendIngestionEmail(profile.NotifyEmail, record); err != nil {
+				logrus.Errorf("failed to email statement ingestion %s: %v", record.IngestionID, err)
+			}
+		}
+	}()
+}
+
+// sendIngestionEmail emails a summary of an ingestion using the configured SMTP server.
+func sendIngestionEmail(to string, record *model.StatementIngestion) error {
+	cfg, err := config.Fetch()
+	if err != nil {
+		return err
+	}
+	smtpCfg := cfg.Notification.SMTP
+	if smtpCfg.Host == "" {
+		return errors.New("smtp is not configured")
+	}
+
+	var auth smtp.Auth
+	if smtpCfg.Username != "" {
+		auth = smtp.PlainAuth("", smtpCfg.Username, smtpCfg.Password, smtpCfg.Host)
+	}
+	addr := fmt.Sprintf("%s:%d", smtpCfg.Host, smtpCfg.Port)
+	return smtp.SendMail(addr, auth, smtpCfg.From, []string{to}, buildIngestionEmail(smtpCfg.From, to, record))
+}
+
+// buildIngestionEmail builds a plain text email summarising an ingestion.
+func buildIngestionEmail(from, to string, record *model.StatementIngestion) []byte {
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "Statement: %s\r\n", record.Filename)
+	fmt.Fprintf(&body, "Received via: %s\r\n", record.Channel)
+	if record.Profile != "" {
+		fmt.Fprintf(&body, "Profile: %s\r\n", record.Profile)
+	}
+	fmt.Fprintf(&body, "Status: %s\r\n", record.Status)
+	if record.UploadID != "" {
+		fmt.Fprintf(&body, "Upload: %s (%d records)\r\n", record.UploadID, record.RecordCount)
+	}
+	if record.ReconciliationID != "" {
+		fmt.Fprintf(&body, "Reconciliation: %s\r\n", record.ReconciliationID)
+	}
+	if record.Error != "" {
+		fmt.Fprintf(&body, "Error: %s\r\n", record.Error)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	// The filename is chosen by whoever sent the statement, so it must not be able to add headers
+	filename := strings.NewReplacer("\r", " ", "\n", " ").Replace(record.Filename)
+	fmt.Fprintf(&msg, "Subject: Statement %s %s\r\n", filename, record.Status)
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes()
+}
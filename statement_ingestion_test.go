@@ -0,0 +1,213 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/internal/ingestion"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStatementSource is a dead drop holding a fixed set of files.
+type fakeStatementSource struct {
+	files  []ingestion.File
+	acked  []string
+	closed bool
+}
+
+func (s *fakeStatementSource) Name() string { return "imap" }
+
+func (s *fakeStatementSource) Fetch(context.Context) ([]ingestion.File, error) { return s.files, nil }
+
+func (s *fakeStatementSource) Ack(_ context.Context, file ingestion.File) error {
+	s.acked = append(s.acked, file.Name)
+	return nil
+}
+
+func (s *fakeStatementSource) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestMatchIngestionProfile(t *testing.T) {
+	profiles := []config.IngestionProfile{
+		{Name: "unrestricted"},
+		{Name: "acme", Senders: []string{"@acme-bank.example"}, FilenamePattern: "*.csv"},
+		{Name: "globex", Senders: []string{"Recon@Globex.example"}},
+		{Name: "sftp", FilenamePattern: "settlement_*.CSV"},
+	}
+
+	tests := []struct {
+		file    ingestion.File
+		profile string
+	}{
+		{ingestion.File{Sender: "statements@acme-bank.example", Name: "daily.csv"}, "acme"},
+		{ingestion.File{Sender: "statements@acme-bank.example", Name: "logo.png"}, ""},
+		{ingestion.File{Sender: "statements@notacme-bank.example.com", Name: "daily.csv"}, ""},
+		{ingestion.File{Sender: "recon@globex.example", Name: "anything.json"}, "globex"},
+		{ingestion.File{Name: "settlement_20260102.csv"}, "sftp"},
+		{ingestion.File{Name: "daily.csv"}, ""},
+	}
+	for _, tt := range tests {
+		profile := matchIngestionProfile(profiles, tt.file)
+		if tt.profile == "" {
+			assert.Nil(t, profile, tt.file.Name)
+			continue
+		}
+		require.NotNil(t, profile, tt.file.Name)
+		assert.Equal(t, tt.profile, profile.Name)
+	}
+}
+
+func TestRemapStatementColumns(t *testing.T) {
+	data := []byte("Transaction Ref,Value,Date,Booking Date,Currency,Reference,Narrative\n" +
+		"T1,10.50,2026-01-01,2026-01-02T00:00:00Z,USD,INV-1,Invoice 1\n")
+
+	remapped, rows, err := remapStatementColumns(data, "statement.csv", map[string]string{
+		"id":          "transaction ref",
+		"Amount":      "Value",
+		"date":        "Booking Date",
+		"description": "Narrative",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, rows)
+	assert.Equal(t, "id,amount,original_Date,date,Currency,Reference,description\n"+
+		"T1,10.50,2026-01-01,2026-01-02T00:00:00Z,USD,INV-1,Invoice 1\n", string(remapped))
+
+	columnMap, err := createColumnMap([]string{"id", "amount", "original_Date", "date", "Currency", "Reference", "description"})
+	require.NoError(t, err)
+	assert.Len(t, columnMap, 7, "renamed headers must stay unique")
+
+	jsonData := []byte(`[{"id":"T1"}]`)
+	unchanged, rows, err := remapStatementColumns(jsonData, "statement.json", map[string]string{"id": "ref"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, rows)
+	assert.Equal(t, jsonData, unchanged)
+}
+
+func TestRunStatementIngestion(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis: config.RedisConfig{Dns: mr.Addr()},
+		Queue: config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+		StatementIngestion: config.StatementIngestionConfig{
+			Profiles: []config.IngestionProfile{{
+				Name:    "acme",
+				Source:  "acme-bank",
+				Senders: []string{"@acme-bank.example"},
+				Columns: map[string]string{"id": "Ref", "amount": "Value"},
+			}},
+		},
+	})
+	mockDS := new(mocks.MockDataSource)
+	b, err := NewBlnk(mockDS)
+	require.NoError(t, err)
+
+	receivedAt := time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)
+	source := &fakeStatementSource{files: []ingestion.File{
+		{Source: "imap", Ref: "1", Name: "statement.csv", Sender: "ops@acme-bank.example", ReceivedAt: receivedAt,
+			Data: []byte("Ref,Value,Currency,Reference,Description,Date\nT1,10.50,USD,INV-1,Invoice 1,2026-01-02T00:00:00Z\nT2,4.25,USD,INV-2,Invoice 2,2026-01-02T00:00:00Z\n")},
+		{Source: "imap", Ref: "1", Name: "resent.csv", Sender: "ops@acme-bank.example", ReceivedAt: receivedAt, Data: []byte("already seen")},
+		{Source: "imap", Ref: "2", Name: "logo.png", Sender: "ops@unknown.example", ReceivedAt: receivedAt, Data: []byte("png")},
+		{Source: "imap", Ref: "3", Name: "broken.csv", Sender: "ops@acme-bank.example", ReceivedAt: receivedAt, Data: []byte("db down")},
+	}}
+	original := newStatementSources
+	newStatementSources = func(config.StatementIngestionConfig) []ingestion.Source { return []ingestion.Source{source} }
+	defer func() { newStatementSources = original }()
+
+	byName := func(name string) interface{} {
+		return mock.MatchedBy(func(record *model.StatementIngestion) bool { return record.Filename == name })
+	}
+	mockDS.On("RecordStatementIngestion", mock.Anything, byName("statement.csv")).Return(true, nil)
+	mockDS.On("RecordStatementIngestion", mock.Anything, byName("resent.csv")).Return(false, nil)
+	mockDS.On("RecordStatementIngestion", mock.Anything, byName("logo.png")).Return(true, nil)
+	mockDS.On("RecordStatementIngestion", mock.Anything, byName("broken.csv")).Return(false, errors.New("connection refused"))
+	mockDS.On("RecordExternalTransaction", mock.Anything, mock.MatchedBy(func(txn *model.ExternalTransaction) bool {
+		return txn.Source == "acme-bank" && (txn.ID == "T1" && txn.Amount == 10.50 || txn.ID == "T2" && txn.Amount == 4.25)
+	}), mock.Anything).Return(nil).Twice()
+	mockDS.On("UpdateStatementIngestion", mock.Anything, mock.Anything).Return(nil)
+
+	ingestions, err := b.RunStatementIngestion(context.Background())
+	require.NoError(t, err)
+	require.Len(t, ingestions, 2)
+
+	uploaded := ingestions[0]
+	assert.Equal(t, "acme", uploaded.Profile)
+	assert.Equal(t, model.StatementIngestionUploaded, uploaded.Status)
+	assert.NotEmpty(t, uploaded.UploadID)
+	assert.Equal(t, 2, uploaded.RecordCount)
+	assert.Equal(t, receivedAt, uploaded.ReceivedAt)
+	assert.Len(t, uploaded.Fingerprint, 64)
+	assert.NotNil(t, uploaded.CompletedAt)
+
+	assert.Equal(t, model.StatementIngestionUnmatched, ingestions[1].Status)
+
+	// The file that could not be recorded is left for the next run
+	assert.Equal(t, []string{"statement.csv", "resent.csv", "logo.png"}, source.acked)
+	assert.True(t, source.closed)
+	mockDS.AssertExpectations(t)
+}
+
+func TestRunStatementIngestion_RecordsUploadFailures(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis: config.RedisConfig{Dns: mr.Addr()},
+		Queue: config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+		StatementIngestion: config.StatementIngestionConfig{
+			Profiles: []config.IngestionProfile{{Name: "sftp", FilenamePattern: "*.csv", AutoReconcile: true}},
+		},
+	})
+	mockDS := new(mocks.MockDataSource)
+	b, err := NewBlnk(mockDS)
+	require.NoError(t, err)
+
+	source := &fakeStatementSource{files: []ingestion.File{
+		{Source: "sftp", Ref: "/drop/missing.csv", Name: "missing.csv", Data: []byte("Ref,Value\nT1,10\n")},
+	}}
+	original := newStatementSources
+	newStatementSources = func(config.StatementIngestionConfig) []ingestion.Source { return []ingestion.Source{source} }
+	defer func() { newStatementSources = original }()
+
+	mockDS.On("RecordStatementIngestion", mock.Anything, mock.Anything).Return(true, nil)
+	mockDS.On("UpdateStatementIngestion", mock.Anything, mock.MatchedBy(func(record *model.StatementIngestion) bool {
+		return record.Status == model.StatementIngestionFailed && record.UploadID == ""
+	})).Return(nil)
+
+	ingestions, err := b.RunStatementIngestion(context.Background())
+	require.NoError(t, err)
+	require.Len(t, ingestions, 1)
+	assert.Equal(t, model.StatementIngestionFailed, ingestions[0].Status)
+	assert.Contains(t, ingestions[0].Error, "required column 'ID' not found")
+	assert.Equal(t, []string{"missing.csv"}, source.acked)
+	mockDS.AssertExpectations(t)
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type memoryStatementStore struct {
+	objects map[string][]byte
+}
+
+func (s *memoryStatementStore) Put(_ context.Context, key string, data []byte) error {
+	s.objects[key] = data
+	return nil
+}
+
+func (s *memoryStatementStore) Get(_ context.Context, key string) ([]byte, error) {
+	return s.objects[key], nil
+}
+
+func TestNextStatementRun(t *testing.T) {
+	assert.Equal(t,
+		time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		nextStatementRun(time.Date(2024, 2, 10, 8, 0, 0, 0, time.UTC), 5))
+	assert.Equal(t,
+		time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC),
+		nextStatementRun(time.Date(2024, 2, 10, 8, 0, 0, 0, time.UTC), 15))
+	assert.Equal(t,
+		time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+		nextStatementRun(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 1))
+}
+
+func TestGenerateStatement_Balance(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis: config.RedisConfig{Dns: mr.Addr()},
+		Queue: config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+	})
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	ctx := context.Background()
+
+	mockDS := new(mocks.MockDataSource)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_1").Return(&model.Balance{BalanceID: "bln_1", Currency: "USD"}, nil)
+	mockDS.On("GetBalanceAtTime", ctx, "bln_1", start, false).Return(&model.Balance{Balance: big.NewInt(1000)}, nil)
+	mockDS.On("GetBalanceTransactionsBetween", ctx, "bln_1", start, end, model.Page{Limit: statementPageSize}).Return([]*model.Transaction{
+		{TransactionID: "txn_in", Source: "bln_2", Destination: "bln_1", Amount: 5, PreciseAmount: big.NewInt(500), Currency: "USD", CreatedAt: start.Add(time.Hour)},
+		{TransactionID: "txn_out", Source: "bln_1", Destination: "bln_3", Amount: 2, PreciseAmount: big.NewInt(200), Currency: "USD", CreatedAt: start.Add(2 * time.Hour)},
+	}, nil)
+	mockDS.On("GetBalanceNettingEntries", ctx, "bln_1", start, end).Return([]*model.NettingEntry{}, nil)
+	mockDS.On("CreateStatement", ctx, mock.AnythingOfType("*model.Statement")).Return(nil)
+	mockDS.On("UpdateStatementDelivery", ctx, mock.AnythingOfType("*model.Statement")).Return(nil)
+
+	b, err := NewBlnk(mockDS)
+	assert.NoError(t, err)
+	store := &memoryStatementStore{objects: map[string][]byte{}}
+	b.statements = store
+
+	schedule := &model.StatementSchedule{ScheduleID: "stmt_sched_1", EntityType: "balance", EntityID: "bln_1", Channel: model.StatementChannelWebhook}
+	statement, err := b.GenerateStatement(ctx, schedule, start, end)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StatementDeliveryDelivered, statement.DeliveryStatus)
+	assert.Equal(t, 1, statement.DeliveryAttempts)
+
+	csvData := string(store.objects[statement.StorageKey])
+	assert.Contains(t, csvData, "txn_in")
+	assert.Contains(t, csvData, "credit")
+	assert.Contains(t, csvData, "debit")
+	lines := strings.Split(strings.TrimSpace(csvData), "\n")
+	assert.Len(t, lines, 5)
+	assert.Contains(t, lines[len(lines)-1], "Closing balance,,,1300,USD")
+
+	mockDS.AssertExpectations(t)
+}
+
+func TestRenderStatementCSV_IdentityFormat(t *testing.T) {
+	mockDS := new(mocks.MockDataSource)
+	mockDS.On("GetBalanceByIDLite", mock.Anything, "bln_1").Return(&model.Balance{BalanceID: "bln_1", IdentityID: "idt_1"}, nil)
+	mockDS.On("GetIdentityByID", mock.Anything, "idt_1").Return(&model.Identity{IdentityID: "idt_1", Locale: "de-DE", Timezone: "Europe/Berlin"}, nil)
+	b := &Blnk{datasource: mockDS}
+
+	format := b.statementLocaleFormat(context.Background(), &model.StatementSchedule{EntityType: "balance", EntityID: "bln_1"})
+	assert.Equal(t, "de-DE", format.Locale)
+
+	at := time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC)
+	data, err := renderStatementCSV([]model.StatementBalance{{
+		BalanceID:          "bln_1",
+		Currency:           "EUR",
+		CurrencyMultiplier: 100,
+		OpeningBalance:     big.NewInt(123456),
+		ClosingBalance:     big.NewInt(173456),
+		Transactions: []*model.Transaction{
+			{TransactionID: "txn_in", Destination: "bln_1", Amount: 500, PreciseAmount: big.NewInt(50000), Precision: 100, Currency: "EUR", CreatedAt: at},
+		},
+	}}, format)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 4)
+	assert.True(t, strings.HasSuffix(lines[0], ",display_amount"))
+	assert.Contains(t, lines[1], `"1.234,56"`)
+	assert.Contains(t, lines[2], "2024-02-01T00:00:00+01:00")
+	assert.Contains(t, lines[2], `"500,00"`)
+	assert.Contains(t, lines[3], `"1.734,56"`)
+}
+
+func TestCreateStatementSchedule_Validation(t *testing.T) {
+	b := &Blnk{datasource: new(mocks.MockDataSource)}
+
+	_, err := b.CreateStatementSchedule(context.Background(), model.StatementSchedule{EntityType: "ledger", EntityID: "ldg_1"})
+	assert.Error(t, err)
+}
@@ -37,6 +37,7 @@ import (
 	"go.opentelemetry.io/otel"
 
 	"github.com/blnkfinance/blnk/model"
+	"github.com/blnkfinance/blnk/plugins"
 )
 
 var tracer = otel.Tracer("blnk.transactions")
@@ -51,18 +52,19 @@ const (
 	StatusRejected  = "REJECTED"
 )
 
-// getTxns is a function type that retrieves a batch of transactions based on the parent transaction ID, batch size, and offset.
+// getTxns is a function type that retrieves a batch of transactions based on the parent transaction ID and a page.
+// The page of each batch carries the position of the last transaction read, and readers return the transactions
+// after page.After.
 //
 // Parameters:
 // - ctx context.Context: The context for the operation.
 // - parentTransactionID string: The ID of the parent transaction.
-// - batchSize int: The number of transactions to retrieve in a batch.
-// - offset int64: The offset for pagination.
+// - page model.Page: The batch to retrieve.
 //
 // Returns:
 // - []*model.Transaction: A slice of pointers to the retrieved Transaction models.
 // - error: An error if the transactions could not be retrieved.
-type getTxns func(ctx context.Context, parentTransactionID string, batchSize int, offset int64) ([]*model.Transaction, error)
+type getTxns func(ctx context.Context, parentTransactionID string, page model.Page) ([]*model.Transaction, error)
 
 // transactionWorker is a function type that processes transactions from a job channel and sends the results to a results channel.
 //
@@ -123,12 +125,7 @@ func (l *Blnk) getSourceAndDestination(ctx context.Context, transaction *model.T
 		transaction.Source = sourceBalance.BalanceID
 		span.SetAttributes(attribute.String("source.balance_id", sourceBalance.BalanceID))
 	} else {
-		// Use GetBalanceByID with queued checks if enabled, otherwise use lite version
-		if cfg.Transaction.EnableQueuedChecks {
-			sourceBalance, err = l.datasource.GetBalanceByID(transaction.Source, []string{}, true)
-		} else {
-			sourceBalance, err = l.datasource.GetBalanceByIDLite(transaction.Source)
-		}
+		sourceBalance, err = l.getPostingBalance(ctx, transaction.Source, transaction.SourceShard, cfg.Transaction.EnableQueuedChecks)
 		if err != nil {
 			span.RecordError(err)
 			logrus.Errorf("source error %v", err)
@@ -148,12 +145,7 @@ func (l *Blnk) getSourceAndDestination(ctx context.Context, transaction *model.T
 		transaction.Destination = destinationBalance.BalanceID
 		span.SetAttributes(attribute.String("destination.balance_id", destinationBalance.BalanceID))
 	} else {
-		// Use GetBalanceByID with queued checks if enabled, otherwise use lite version
-		if cfg.Transaction.EnableQueuedChecks {
-			destinationBalance, err = l.datasource.GetBalanceByID(transaction.Destination, []string{}, true)
-		} else {
-			destinationBalance, err = l.datasource.GetBalanceByIDLite(transaction.Destination)
-		}
+		destinationBalance, err = l.getPostingBalance(ctx, transaction.Destination, transaction.DestinationShard, cfg.Transaction.EnableQueuedChecks)
 		if err != nil {
 			span.RecordError(err)
 			logrus.Errorf("destination error %v", err)
@@ -164,6 +156,41 @@ func (l *Blnk) getSourceAndDestination(ctx context.Context, transaction *model.T
 	return sourceBalance, destinationBalance, nil
 }
 
+// getPostingBalance retrieves the balance a posting is applied to: the shard it was routed to for a sharded
+// balance, and the balance itself otherwise.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - balanceID string: The ID of the balance the transaction names.
+// - shardID string: The shard the posting was routed to, or empty if the balance is not sharded.
+// - withQueued bool: Whether to include the amounts of queued transactions.
+//
+// Returns:
+// - *model.Balance: The balance to apply the posting to.
+// - error: An error if the balance could not be retrieved.
+func (l *Blnk) getPostingBalance(ctx context.Context, balanceID, shardID string, withQueued bool) (*model.Balance, error) {
+	id := balanceID
+	if shardID != "" {
+		id = shardID
+	}
+
+	// Use GetBalanceByID with queued checks if enabled, otherwise use lite version
+	var balance *model.Balance
+	var err error
+	if withQueued {
+		balance, err = l.datasource.GetBalanceByID(ctx, id, []string{}, true)
+	} else {
+		balance, err = l.datasource.GetBalanceByIDLite(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if id != balanceID {
+		balance.ShardOf = balanceID
+	}
+	return balance, nil
+}
+
 // acquireLock acquires a distributed lock for a transaction to ensure exclusive access to the source balance.
 // It starts a tracing span, attempts to acquire the lock, and records relevant events and errors.
 //
@@ -183,8 +210,15 @@ func (l *Blnk) acquireLock(ctx context.Context, transaction *model.Transaction)
 		return nil, err
 	}
 
-	locker := redlock.NewLocker(l.redis, transaction.Source, model.GenerateUUIDWithSuffix("loc"))
+	// Postings on different shards of a sharded balance do not need to wait for each other
+	lockKey := transaction.Source
+	if transaction.SourceShard != "" {
+		lockKey = transaction.SourceShard
+	}
+	locker := redlock.NewLocker(l.redis, lockKey, model.GenerateUUIDWithSuffix("loc"))
+	endLockWait := timePipelineStage(ctx, model.PipelineStageLockWait)
 	err = locker.Lock(ctx, config.Transaction.LockDuration)
+	endLockWait()
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -210,8 +244,8 @@ func (l *Blnk) updateTransactionDetails(ctx context.Context, transaction *model.
 
 	// Create a new transaction object with updated details (immutable pattern)
 	newTransaction := *transaction // Copy the original transaction
-	newTransaction.Source = sourceBalance.BalanceID
-	newTransaction.Destination = destinationBalance.BalanceID
+	newTransaction.Source = sourceBalance.PostingID()
+	newTransaction.Destination = destinationBalance.PostingID()
 
 	// Update the status based on the current status and inflight flag
 	applicableStatus := map[string]string{
@@ -280,19 +314,30 @@ func (l *Blnk) postTransactionActions(ctx context.Context, transaction *model.Tr
 	}
 
 	go func() {
+		stages := make(map[string]time.Duration, 2)
+		start := time.Now()
 		err := l.queue.queueIndexData(transaction.TransactionID, config.Transaction.IndexQueuePrefix, transaction)
 		if err != nil {
 			span.RecordError(err)
 			notification.NotifyError(err)
 		}
-		err = l.SendWebhook(NewWebhook{
-			Event:   getEventFromStatus(transaction.Status),
-			Payload: transaction,
-		})
-		if err != nil {
-			span.RecordError(err)
-			notification.NotifyError(err)
+		stages[model.PipelineStageIndex] = time.Since(start)
+		if l.shouldSendTransactionWebhook(context.Background(), transaction) {
+			start = time.Now()
+			err = l.SendWebhook(NewWebhook{
+				Event:   getEventFromStatus(transaction.Status),
+				Payload: transaction,
+			})
+			if err != nil {
+				span.RecordError(err)
+				notification.NotifyError(err)
+			}
+			stages[model.PipelineStageNotify] = time.Since(start)
+		}
+		for stage, duration := range stages {
+			observePipelineStage(stage, duration)
 		}
+		l.savePipelineTiming(transaction.TransactionID, stages)
 		span.AddEvent("Post-transaction actions completed")
 	}()
 }
@@ -353,7 +398,7 @@ func (l *Blnk) updateBalances(ctx context.Context, sourceBalance, destinationBal
 // - span trace.Span: The tracing span for error recording.
 func (l *Blnk) queueBalanceForIndexing(ctx context.Context, balanceID string, span trace.Span) {
 	// Fetch the complete balance data including metadata
-	completeBalance, err := l.datasource.GetBalanceByID(balanceID, []string{}, false)
+	completeBalance, err := l.datasource.GetBalanceByID(ctx, balanceID, []string{}, false)
 	if err != nil {
 		span.RecordError(err)
 		notification.NotifyError(err)
@@ -408,7 +453,7 @@ func (l *Blnk) validateTxn(ctx context.Context, transaction *model.Transaction)
 // Returns:
 // - error: An error if the balances could not be updated.
 func (l *Blnk) applyTransactionToBalances(ctx context.Context, balances []*model.Balance, transaction *model.Transaction) error {
-	_, span := tracer.Start(ctx, "Applying Transaction to Balances")
+	ctx, span := tracer.Start(ctx, "Applying Transaction to Balances")
 	defer span.End()
 
 	span.AddEvent("Calculating new balances")
@@ -437,6 +482,10 @@ func (l *Blnk) applyTransactionToBalances(ctx context.Context, balances []*model
 		span.RecordError(err)
 		return err
 	}
+	if err := l.enforceMinimumBalance(ctx, transaction, balances[0]); err != nil {
+		span.RecordError(err)
+		return err
+	}
 
 	span.AddEvent("Balances updated")
 	return nil
@@ -448,17 +497,16 @@ func (l *Blnk) applyTransactionToBalances(ctx context.Context, balances []*model
 // Parameters:
 // - ctx context.Context: The context for the operation.
 // - parentTransactionID string: The ID of the parent transaction.
-// - batchSize int: The number of transactions to retrieve in a batch.
-// - offset int64: The offset for pagination.
+// - page model.Page: The page to retrieve, after the cursor of the previous one.
 //
 // Returns:
 // - []*model.Transaction: A slice of pointers to the retrieved Transaction models.
 // - error: An error if the transactions could not be retrieved.
-func (l *Blnk) GetInflightTransactionsByParentID(ctx context.Context, parentTransactionID string, batchSize int, offset int64) ([]*model.Transaction, error) {
+func (l *Blnk) GetInflightTransactionsByParentID(ctx context.Context, parentTransactionID string, page model.Page) ([]*model.Transaction, error) {
 	ctx, span := tracer.Start(ctx, "GetInflightTransactionsByParentID")
 	defer span.End()
 
-	transactions, err := l.datasource.GetInflightTransactionsByParentID(ctx, parentTransactionID, batchSize, offset)
+	transactions, err := l.datasource.GetInflightTransactionsByParentID(ctx, parentTransactionID, page)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -474,17 +522,16 @@ func (l *Blnk) GetInflightTransactionsByParentID(ctx context.Context, parentTran
 // Parameters:
 // - ctx context.Context: The context for the operation.
 // - parentTransactionID string: The ID of the parent transaction.
-// - batchSize int: The number of transactions to retrieve in a batch.
-// - offset int64: The offset for pagination.
+// - page model.Page: The page to retrieve, after the cursor of the previous one.
 //
 // Returns:
 // - []*model.Transaction: A slice of pointers to the retrieved Transaction models.
 // - error: An error if the transactions could not be retrieved.
-func (l *Blnk) GetRefundableTransactionsByParentID(ctx context.Context, parentTransactionID string, batchSize int, offset int64) ([]*model.Transaction, error) {
+func (l *Blnk) GetRefundableTransactionsByParentID(ctx context.Context, parentTransactionID string, page model.Page) ([]*model.Transaction, error) {
 	ctx, span := tracer.Start(ctx, "GetRefundableTransactionsByParentID")
 	defer span.End()
 
-	transactions, err := l.datasource.GetRefundableTransactionsByParentID(ctx, parentTransactionID, batchSize, offset)
+	transactions, err := l.datasource.GetRefundableTransactionsByParentID(ctx, parentTransactionID, page)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -568,7 +615,7 @@ func (l *Blnk) ProcessTransactionInBatches(ctx context.Context, parentTransactio
 				log.Printf("Error during processing: %v", err)
 				span.RecordError(err)
 			}
-			return allTxns, fmt.Errorf("error occurred during processing: %v", allErrors)
+			return allTxns, fmt.Errorf("error occurred during processing: %w", errors.Join(allErrors...))
 		}
 
 		span.AddEvent("Processed all transactions in batches")
@@ -632,7 +679,7 @@ func fetchTransactions(ctx context.Context, parentTransactionID string, batchSiz
 	defer span.End()
 	defer close(jobs) // Ensure the jobs channel is closed in all cases to avoid deadlocks
 
-	var offset int64 = 0
+	page := model.Page{Limit: batchSize}
 	for {
 		select {
 		case <-ctx.Done():
@@ -645,7 +692,7 @@ func fetchTransactions(ctx context.Context, parentTransactionID string, batchSiz
 			return
 		default:
 			// Fetch the transactions in batches
-			txns, err := gt(newCtx, parentTransactionID, batchSize, offset)
+			txns, err := gt(newCtx, parentTransactionID, page)
 			if err != nil {
 				// Log and send error if fetching transactions fails
 				log.Printf("Error fetching transactions: %v", err)
@@ -674,8 +721,8 @@ func fetchTransactions(ctx context.Context, parentTransactionID string, batchSiz
 				}
 			}
 
-			// Increment offset to fetch the next batch
-			offset += int64(len(txns))
+			// Move past the batch to fetch the next one
+			page.After = model.NextPageCursor(txns, len(txns), (*model.Transaction).PageCursor)
 		}
 	}
 }
@@ -790,7 +837,7 @@ func (l *Blnk) VoidWorker(ctx context.Context, jobs <-chan *model.Transaction, r
 }
 
 // RecordTransaction records a transaction by validating, processing balances, and finalizing the transaction.
-// It starts a tracing span, acquires a lock, and performs the necessary steps to record the transaction.
+// It starts a tracing span, resolves balance aliases, acquires a lock, and performs the necessary steps to record the transaction.
 //
 // Parameters:
 // - ctx context.Context: The context for the operation.
@@ -803,28 +850,55 @@ func (l *Blnk) RecordTransaction(ctx context.Context, transaction *model.Transac
 	ctx, span := tracer.Start(ctx, "RecordTransaction")
 	defer span.End()
 
-	return l.executeWithLock(ctx, transaction, func(ctx context.Context) (*model.Transaction, error) {
+	if err := l.resolveTransactionAliases(ctx, transaction); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	l.routeToShards(ctx, transaction)
+
+	// Time the stages the transaction goes through, saving them once its ID is final
+	ctx, timing := withPipelineTiming(ctx)
+	recorded, err := l.executeWithLock(ctx, transaction, func(ctx context.Context) (*model.Transaction, error) {
+		endValidation := timePipelineStage(ctx, model.PipelineStageValidation)
+
 		// Execute pre-transaction hooks
 		if err := l.Hooks.ExecutePreHooks(ctx, transaction.TransactionID, transaction); err != nil {
 			span.RecordError(err)
 			return nil, err
 		}
 
+		// Run custom validation and enrichment processors
+		if err := l.Plugins.Run(ctx, plugins.StageValidation, transaction); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		if err := l.Plugins.Run(ctx, plugins.StageEnrichment, transaction); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		l.enrichTransactionNarrative(ctx, transaction)
+
 		// Validate and prepare the transaction, including retrieving source and destination balances
 		transaction, sourceBalance, destinationBalance, err := l.validateAndPrepareTransaction(ctx, transaction)
+		endValidation()
 		if err != nil {
 			span.RecordError(err)
 			return nil, err
 		}
 
 		// Process the balances by applying the transaction
-		if err := l.processBalances(ctx, transaction, sourceBalance, destinationBalance); err != nil {
+		endBalanceUpdate := timePipelineStage(ctx, model.PipelineStageBalanceUpdate)
+		err = l.processBalances(ctx, transaction, sourceBalance, destinationBalance)
+		endBalanceUpdate()
+		if err != nil {
 			span.RecordError(err)
 			return nil, err
 		}
 
 		// Finalize the transaction by persisting it and updating the balances
+		endCommit := timePipelineStage(ctx, model.PipelineStageCommit)
 		transaction, err = l.finalizeTransaction(ctx, transaction, sourceBalance, destinationBalance)
+		endCommit()
 		if err != nil {
 			span.RecordError(err)
 			return nil, err
@@ -837,12 +911,22 @@ func (l *Blnk) RecordTransaction(ctx context.Context, transaction *model.Transac
 			logrus.Errorf("post-transaction hooks failed: %v", err)
 		}
 
+		// Run post-commit processors; their failures never undo the posting
+		_ = l.Plugins.Run(ctx, plugins.StagePostCommit, transaction)
+
 		// Perform post-transaction actions such as indexing and sending webhooks
 		l.postTransactionActions(ctx, transaction)
 
 		span.AddEvent("Transaction processed", trace.WithAttributes(attribute.String("transaction.id", transaction.TransactionID)))
 		return transaction, nil
 	})
+
+	transactionID := transaction.TransactionID
+	if recorded != nil {
+		transactionID = recorded.TransactionID
+	}
+	l.saveCollectedPipelineTiming(transactionID, timing)
+	return recorded, err
 }
 
 // executeWithLock executes a function with a distributed lock to ensure exclusive access to the transaction.
@@ -903,8 +987,12 @@ func (l *Blnk) validateAndPrepareTransaction(ctx context.Context, transaction *m
 
 	// Create a copy of the transaction and update it (immutable)
 	newTransaction := *transaction // Copy the original transaction
-	newTransaction.Source = sourceBalance.BalanceID
-	newTransaction.Destination = destinationBalance.BalanceID
+	newTransaction.Source = sourceBalance.PostingID()
+	newTransaction.Destination = destinationBalance.PostingID()
+	if err := l.checkTransactionRules(ctx, &newTransaction, sourceBalance, destinationBalance); err != nil {
+		span.RecordError(err)
+		return nil, nil, nil, l.logAndRecordError(span, "transaction validation failed", err)
+	}
 
 	span.AddEvent("Transaction validated and prepared", trace.WithAttributes(
 		attribute.String("source.balance_id", sourceBalance.BalanceID),
@@ -914,6 +1002,31 @@ func (l *Blnk) validateAndPrepareTransaction(ctx context.Context, transaction *m
 	return &newTransaction, sourceBalance, destinationBalance, nil
 }
 
+// checkTransactionRules applies the rounding policy to a transaction whose source and destination have been
+// resolved, and checks it against the frozen balances, the status of the balances' identities and the
+// posting rules of their ledgers. Every path that posts a transaction runs these checks.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - transaction *model.Transaction: The transaction to check, with its source and destination resolved.
+// - sourceBalance *model.Balance: The source balance.
+// - destinationBalance *model.Balance: The destination balance.
+//
+// Returns:
+// - error: An error if any of the checks fails.
+func (l *Blnk) checkTransactionRules(ctx context.Context, transaction *model.Transaction, sourceBalance, destinationBalance *model.Balance) error {
+	if err := applyRoundingPolicy(transaction); err != nil {
+		return err
+	}
+	if err := l.checkFrozenBalances(ctx, transaction); err != nil {
+		return err
+	}
+	if err := l.checkIdentityStatus(ctx, sourceBalance, destinationBalance); err != nil {
+		return err
+	}
+	return l.checkPostingRules(ctx, transaction, sourceBalance, destinationBalance)
+}
+
 // processBalances processes the source and destination balances by applying the transaction and updating the balances.
 // It starts a tracing span, applies the transaction to the balances, updates the balances, and records relevant events and errors.
 //
@@ -962,7 +1075,9 @@ func (l *Blnk) finalizeTransaction(ctx context.Context, transaction *model.Trans
 	defer span.End()
 
 	// Update the transaction details with the source and destination balances
+	status := transaction.Status
 	transaction = l.updateTransactionDetails(ctx, transaction, sourceBalance, destinationBalance)
+	stampStatusChange(ctx, transaction, statusChangeReasonCode(status), "")
 
 	// Persist the transaction to the database
 	transaction, err := l.persistTransaction(ctx, transaction)
@@ -970,6 +1085,7 @@ func (l *Blnk) finalizeTransaction(ctx context.Context, transaction *model.Trans
 		span.RecordError(err)
 		return nil, l.logAndRecordError(span, "failed to persist transaction", err)
 	}
+	l.sendInflightCreditWebhook(status, transaction, destinationBalance)
 
 	span.AddEvent("Transaction processed", trace.WithAttributes(attribute.String("transaction.id", transaction.TransactionID)))
 
@@ -1033,6 +1149,7 @@ func (l *Blnk) RejectTransaction(ctx context.Context, transaction *model.Transac
 		transaction.MetaData = make(map[string]interface{})
 	}
 	transaction.MetaData["blnk_rejection_reason"] = reason
+	stampStatusChange(ctx, transaction, model.RejectionReasonCode(reason), reason)
 
 	// Persist the transaction with the updated status and metadata
 	transaction, err := l.datasource.RecordTransaction(ctx, transaction)
@@ -1421,6 +1538,12 @@ func (l *Blnk) fetchAndValidateInflightTransaction(ctx context.Context, transact
 		return nil, err
 	}
 
+	// Committing or voiding moves money on the transaction's ledgers, which may be in maintenance
+	if err := l.CheckTransactionMaintenance(ctx, transaction); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
 	// Check if the parent transaction has been voided
 	parentVoided, err := l.datasource.IsParentTransactionVoid(ctx, transactionID)
 	if err != nil {
@@ -1527,7 +1650,10 @@ func (l *Blnk) prepareTransactionForQueue(ctx context.Context, transaction *mode
 
 // QueueTransaction processes and queues a transaction for execution.
 // It handles both single transactions and split transactions, preparing them for processing
-// by setting metadata, status, and managing their persistence and queueing.
+// by setting metadata, status, and managing their persistence and queueing. Balances named by
+// alias are replaced with their IDs before anything else. Transactions touching a ledger in maintenance
+// mode are rejected with a *MaintenanceError, and payouts to an external account that is not verified with
+// ErrExternalAccountUnusable.
 //
 // Parameters:
 // - ctx context.Context: The context for the operation.
@@ -1545,6 +1671,51 @@ func (l *Blnk) QueueTransaction(ctx context.Context, transaction *model.Transact
 	setTransactionMetadata(transaction)
 	setTransactionStatus(transaction)
 	originalTxnID := transaction.TransactionID
+	if err := l.resolveTransactionAliases(ctx, transaction); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := l.CheckTransactionMaintenance(ctx, transaction); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := l.checkTransactionExternalAccount(ctx, transaction); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := applyRoundingPolicy(transaction); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := validateTransactionTime(transaction); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := rollScheduledFor(transaction, time.Time{}); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// Postings between members of a netting group are recorded and settled at the group's cutoff
+	if group := l.nettingGroupFor(ctx, transaction); group != nil {
+		deferred, err := l.deferForNetting(ctx, transaction, group)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		l.recordTransactionUsage(ctx, deferred)
+		return deferred, nil
+	}
+
+	// Transactions that need strong customer authentication wait for the challenge to be confirmed
+	if rule := challengeRuleFor(transaction); rule != nil {
+		challenged, err := l.challengeTransaction(ctx, transaction, rule)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		return challenged, nil
+	}
 
 	// Handle split transactions if needed
 	transactions, err := l.handleSplitTransactions(ctx, transaction)
@@ -1585,6 +1756,8 @@ func (l *Blnk) QueueTransaction(ctx context.Context, transaction *model.Transact
 		return nil, l.logAndRecordError(span, "failed to queue inflight expiry", err)
 	}
 
+	l.recordTransactionUsage(ctx, transaction)
+
 	return transaction, nil
 }
 
@@ -1593,6 +1766,7 @@ func processTransactionAsync(ctx context.Context, l *Blnk, transaction *model.Tr
 		ctx, span := tracer.Start(ctx, "ProcessTransactionAsync")
 		defer span.End()
 
+		start := time.Now()
 		queueTransactions, err := l.processTxns(ctx, transaction, transactions, originalTxnID, originalRef)
 		if err != nil {
 			span.RecordError(err)
@@ -1603,6 +1777,13 @@ func processTransactionAsync(ctx context.Context, l *Blnk, transaction *model.Tr
 			if err := enqueueTransactions(ctx, l.queue, transaction, queueTransactions); err != nil {
 				span.RecordError(err)
 				// return nil, err
+			} else {
+				// The copies enqueued are the transactions the workers apply, so the enqueue is timed with them
+				enqueue := time.Since(start)
+				observePipelineStage(model.PipelineStageEnqueue, enqueue)
+				for _, queued := range queueTransactions {
+					l.savePipelineTiming(queued.TransactionID, map[string]time.Duration{model.PipelineStageEnqueue: enqueue})
+				}
 			}
 		}
 	}()
@@ -1691,6 +1872,7 @@ func (l *Blnk) processSingleTransaction(ctx context.Context, transaction *model.
 		transaction = preparedTxn
 	}
 
+	stampStatusChange(ctx, transaction, "", "")
 	persistedTxn, err := l.datasource.RecordTransaction(ctx, transaction)
 	if err != nil {
 		return nil, fmt.Errorf("failed to persist original transaction: %w", err)
@@ -1723,6 +1905,7 @@ func (l *Blnk) processSplitTransactions(ctx context.Context, transactions []*mod
 		}
 
 		// Persist the original transaction
+		stampStatusChange(ctx, preparedSplitTxn, "", "")
 		persistedTxn, err := l.datasource.RecordTransaction(ctx, preparedSplitTxn)
 		if err != nil {
 			return nil, fmt.Errorf("failed to persist original transaction: %w", err)
@@ -1857,23 +2040,31 @@ func (l *Blnk) GetTransaction(ctx context.Context, TransactionID string) (*model
 		span.RecordError(err)
 		return nil, err
 	}
+	if err := l.attachTransactionSequences(ctx, transaction); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
 
 	span.AddEvent("Transaction retrieved", trace.WithAttributes(attribute.String("transaction.id", TransactionID)))
 	return transaction, nil
 }
 
-// GetAllTransactions retrieves all transactions from the datasource.
-// It starts a tracing span, fetches all transactions, and records relevant events and errors.
+// GetAllTransactions retrieves a page of transactions from the datasource.
+// It starts a tracing span, fetches the transactions, and records relevant events and errors.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - page model.Page: The page of transactions to read.
 //
 // Returns:
 // - []model.Transaction: A slice of all retrieved Transaction models.
 // - error: An error if the transactions could not be retrieved.
-func (l *Blnk) GetAllTransactions(limit, offset int) ([]model.Transaction, error) {
-	ctx, span := tracer.Start(context.Background(), "GetAllTransactions")
+func (l *Blnk) GetAllTransactions(ctx context.Context, page model.Page) ([]model.Transaction, error) {
+	ctx, span := tracer.Start(ctx, "GetAllTransactions")
 	defer span.End()
 
 	// Fetch all transactions from the datasource
-	transactions, err := l.datasource.GetAllTransactions(ctx, limit, offset)
+	transactions, err := l.datasource.GetAllTransactions(ctx, page)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -2202,6 +2393,14 @@ func (l *Blnk) CreateBulkTransactions(ctx context.Context, req *model.BulkTransa
 	batchID := model.GenerateUUIDWithSuffix("bulk")
 	span.SetAttributes(attribute.String("batch.id", batchID))
 
+	// Batches touching a ledger in maintenance are rejected as a whole rather than failing partway
+	for _, txn := range req.Transactions {
+		if err := l.CheckTransactionMaintenance(ctx, txn); err != nil {
+			span.RecordError(err)
+			return &model.BulkTransactionResult{BatchID: batchID, Status: "failed", Error: err.Error()}, err
+		}
+	}
+
 	// Check if this should be run asynchronously
 	if req.RunAsync {
 		// Start processing in background
@@ -0,0 +1,121 @@
+package blnk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// transactionGroupPageSize is the number of transactions of a group read at a time.
+const transactionGroupPageSize = 500
+
+// GetTransactionGroup retrieves the transactions recorded with a group ID, such as the payment, fees, refunds and
+// chargebacks of an order, and the net position of each balance they moved. Positions only count applied
+// transactions; members that are still queued, inflight or were rejected are listed without moving them.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - groupID string: The group ID.
+//
+// Returns:
+// - *model.TransactionGroup: The members of the group, oldest first, and the positions of their balances.
+// - error: An error if the group has no transactions or they could not be retrieved.
+func (l *Blnk) GetTransactionGroup(ctx context.Context, groupID string) (*model.TransactionGroup, error) {
+	ctx, span := tracer.Start(ctx, "GetTransactionGroup")
+	defer span.End()
+
+	group := &model.TransactionGroup{GroupID: groupID, Transactions: []*model.Transaction{}}
+	page := model.Page{Limit: transactionGroupPageSize}
+	for {
+		transactions, err := l.datasource.GetTransactionsByGroup(ctx, groupID, page)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		group.Transactions = append(group.Transactions, transactions...)
+		if len(transactions) < transactionGroupPageSize {
+			break
+		}
+		page.After = model.NextPageCursor(transactions, transactionGroupPageSize, l.TransactionHistoryCursor)
+	}
+	if len(group.Transactions) == 0 {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, fmt.Sprintf("Transaction group '%s' not found", groupID), nil)
+	}
+
+	positions, err := groupPositions(group.Transactions)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	group.Positions = positions
+	return group, nil
+}
+
+// groupPositions nets the applied transactions of a group by balance and currency, in balance order. Members
+// recorded at different precisions are netted at the finest of them.
+func groupPositions(transactions []*model.Transaction) ([]*model.GroupPosition, error) {
+	type position struct {
+		balanceID       string
+		credits, debits model.Money
+	}
+	positions := make(map[string]*position)
+	post := func(balanceID string, amount model.Money, credit bool) error {
+		key := balanceID + "/" + amount.Currency
+		p, ok := positions[key]
+		if !ok {
+			zero := model.NewMoney(nil, amount.Currency, amount.Precision)
+			p = &position{balanceID: balanceID, credits: zero, debits: zero}
+			positions[key] = p
+		}
+		var err error
+		if credit {
+			p.credits, err = p.credits.Add(amount)
+		} else {
+			p.debits, err = p.debits.Add(amount)
+		}
+		return err
+	}
+
+	for _, transaction := range transactions {
+		if transaction.Status != StatusApplied || transaction.PreciseAmount == nil {
+			continue
+		}
+		amount := transaction.Money()
+		if err := post(transaction.Source, amount, false); err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", transaction.TransactionID, err)
+		}
+		if err := post(transaction.Destination, amount, true); err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", transaction.TransactionID, err)
+		}
+	}
+
+	result := make([]*model.GroupPosition, 0, len(positions))
+	for _, p := range positions {
+		credits, debits, err := model.AlignMoney(p.credits, p.debits)
+		if err != nil {
+			return nil, err
+		}
+		net, err := credits.Sub(debits)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &model.GroupPosition{
+			BalanceID: p.balanceID,
+			Currency:  net.Currency,
+			Precision: net.Precision,
+			Credits:   credits.Amount,
+			Debits:    debits.Amount,
+			Net:       net.Amount,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].BalanceID != result[j].BalanceID {
+			return result[i].BalanceID < result[j].BalanceID
+		}
+		return result[i].Currency < result[j].Currency
+	})
+	return result, nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func groupTestTransaction(id, source, destination string, amount int64, status string) *model.Transaction {
+	return &model.Transaction{
+		TransactionID: id,
+		GroupID:       "order_1",
+		Source:        source,
+		Destination:   destination,
+		Currency:      "USD",
+		Precision:     100,
+		PreciseAmount: big.NewInt(amount),
+		Status:        status,
+	}
+}
+
+func TestGetTransactionGroup(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	members := []*model.Transaction{
+		groupTestTransaction("txn_payment", "bln_customer", "bln_merchant", 10000, StatusApplied),
+		groupTestTransaction("txn_fee", "bln_merchant", "@fees", 300, StatusApplied),
+		groupTestTransaction("txn_refund", "bln_merchant", "bln_customer", 2500, StatusApplied),
+		groupTestTransaction("txn_chargeback", "bln_merchant", "bln_customer", 7500, StatusQueued),
+	}
+	mockDS.On("GetTransactionsByGroup", mock.Anything, "order_1", model.Page{Limit: transactionGroupPageSize}).Return(members, nil)
+
+	group, err := b.GetTransactionGroup(context.Background(), "order_1")
+	require.NoError(t, err)
+	assert.Equal(t, "order_1", group.GroupID)
+	assert.Len(t, group.Transactions, 4)
+
+	require.Len(t, group.Positions, 3)
+	fees, customer, merchant := group.Positions[0], group.Positions[1], group.Positions[2]
+	assert.Equal(t, "@fees", fees.BalanceID)
+	assert.Equal(t, "300", fees.Net.String())
+	assert.Equal(t, "bln_customer", customer.BalanceID)
+	assert.Equal(t, "2500", customer.Credits.String())
+	assert.Equal(t, "10000", customer.Debits.String())
+	assert.Equal(t, "-7500", customer.Net.String())
+	assert.Equal(t, "bln_merchant", merchant.BalanceID)
+	assert.Equal(t, "7200", merchant.Net.String())
+	assert.Equal(t, "USD", merchant.Currency)
+	assert.Equal(t, float64(100), merchant.Precision)
+}
+
+func TestGetTransactionGroup_Pages(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	firstPage := make([]*model.Transaction, transactionGroupPageSize)
+	for i := range firstPage {
+		firstPage[i] = groupTestTransaction("txn_page", "bln_customer", "bln_merchant", 1, StatusApplied)
+	}
+	firstPage[len(firstPage)-1].TransactionID = "txn_page_end"
+	lastPage := []*model.Transaction{groupTestTransaction("txn_last", "bln_customer", "bln_merchant", 1, StatusApplied)}
+	mockDS.On("GetTransactionsByGroup", mock.Anything, "order_1", model.Page{Limit: transactionGroupPageSize}).Return(firstPage, nil)
+	mockDS.On("GetTransactionsByGroup", mock.Anything, "order_1", model.Page{Limit: transactionGroupPageSize, After: &model.PageCursor{ID: "txn_page_end"}}).Return(lastPage, nil)
+
+	group, err := b.GetTransactionGroup(context.Background(), "order_1")
+	require.NoError(t, err)
+	assert.Len(t, group.Transactions, transactionGroupPageSize+1)
+	require.Len(t, group.Positions, 2)
+	assert.Equal(t, big.NewInt(transactionGroupPageSize+1), group.Positions[1].Net)
+}
+
+func TestGetTransactionGroup_NotFound(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+	mockDS.On("GetTransactionsByGroup", mock.Anything, "order_missing", model.Page{Limit: transactionGroupPageSize}).Return([]*model.Transaction{}, nil)
+
+	_, err := b.GetTransactionGroup(context.Background(), "order_missing")
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrNotFound, apiErr.Code)
+}
+
+func TestGetTransactionGroup_MixedPrecisions(t *testing.T) {
+	b, mockDS, _ := newBalanceNotificationTestBlnk(t)
+
+	payment := groupTestTransaction("txn_payment", "bln_customer", "bln_merchant", 10000, StatusApplied)
+	fee := groupTestTransaction("txn_fee", "bln_merchant", "@fees", 2500, StatusApplied)
+	fee.Precision = 1000
+	mockDS.On("GetTransactionsByGroup", mock.Anything, "order_1", model.Page{Limit: transactionGroupPageSize}).Return([]*model.Transaction{payment, fee}, nil)
+
+	group, err := b.GetTransactionGroup(context.Background(), "order_1")
+	require.NoError(t, err)
+	merchant := group.Positions[2]
+	assert.Equal(t, "bln_merchant", merchant.BalanceID)
+	assert.Equal(t, float64(1000), merchant.Precision)
+	assert.Equal(t, "100000", merchant.Credits.String())
+	assert.Equal(t, "97500", merchant.Net.String())
+
+	fee.Precision = 3
+	_, err = b.GetTransactionGroup(context.Background(), "order_1")
+	assert.ErrorIs(t, err, model.ErrPrecisionMismatch)
+}
@@ -0,0 +1,179 @@
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/resilience"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+// narrativePatterns caches the compiled patterns of narrative rules by pattern, so that rules are not compiled
+// for every transaction.
+var narrativePatterns sync.Map
+
+// NarrativeEnricher enriches the narratives of transactions, such as with a merchant database or a categorization
+// model, answering with the description, merchant and category it found. Empty fields keep what the cleanup and
+// rules found. Plug an enricher in with SetNarrativeEnricher to enrich transactions with a provider's SDK instead
+// of the configured enrichment API.
+type NarrativeEnricher interface {
+	EnrichNarrative(ctx context.Context, txn *model.Transaction, narrative *model.TransactionNarrative) (*model.TransactionNarrative, error)
+}
+
+// httpNarrativeEnricher enriches narratives with the enrichment API configured by URL.
+type httpNarrativeEnricher struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// narrativeEnrichmentRequest is the body posted to the enrichment API: the transaction, and its narrative as
+// cleaned up and matched against the rules.
+type narrativeEnrichmentRequest struct {
+	Transaction *model.Transaction          `json:"transaction"`
+	Narrative   *model.TransactionNarrative `json:"narrative"`
+}
+
+// EnrichNarrative posts the transaction to the enrichment API and reads the narrative it answers with.
+func (e httpNarrativeEnricher) EnrichNarrative(ctx context.Context, txn *model.Transaction, narrative *model.TransactionNarrative) (*model.TransactionNarrative, error) {
+	body, err := json.Marshal(narrativeEnrichmentRequest{Transaction: txn, Narrative: narrative})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := resilience.Get(resilience.Enrichment).Wrap(e.client).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("enrichment request failed with status %d", resp.StatusCode)
+	}
+
+	var enriched model.TransactionNarrative
+	if err := json.NewDecoder(resp.Body).Decode(&enriched); err != nil {
+		return nil, fmt.Errorf("invalid enrichment response: %w", err)
+	}
+	return &enriched, nil
+}
+
+// SetNarrativeEnricher plugs in the enricher transaction narratives are enriched with as transactions are
+// recorded, in place of the configured enrichment API.
+func (l *Blnk) SetNarrativeEnricher(enricher NarrativeEnricher) {
+	l.enricher = enricher
+}
+
+// narrativeEnricher returns the enricher plugged in, or an enricher of the configured enrichment API, or nil when
+// narratives are only enriched by rules.
+func (l *Blnk) narrativeEnricher(cnf *config.Configuration) NarrativeEnricher {
+	if l.enricher != nil {
+		return l.enricher
+	}
+	if cnf.Enrichment.URL == "" {
+		return nil
+	}
+	return httpNarrativeEnricher{url: cnf.Enrichment.URL, headers: cnf.Enrichment.Headers, client: l.httpClient}
+}
+
+// enrichTransactionNarrative stores the enriched narrative of a transaction in its metadata when enrichment is
+// enabled: its description cleaned up, then matched against the rules, then enriched by the enricher. A failure
+// of the enricher is logged and the transaction keeps what the rules found, so enrichment never holds up a
+// posting. Transactions without a description, or whose narrative was already enriched, are left as they are.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - txn *model.Transaction: The transaction being recorded.
+func (l *Blnk) enrichTransactionNarrative(ctx context.Context, txn *model.Transaction) {
+	cnf, err := config.Fetch()
+	if err != nil || !cnf.Enrichment.Enabled || strings.TrimSpace(txn.Description) == "" {
+		return
+	}
+	if _, ok := txn.MetaData[model.NarrativeMetaKey]; ok {
+		return
+	}
+
+	narrative := model.NewTransactionNarrative(txn.Description)
+	applyNarrativeRules(narrative, cnf.Enrichment.Rules)
+	if enricher := l.narrativeEnricher(cnf); enricher != nil {
+		enriched, err := enricher.EnrichNarrative(ctx, txn, narrative)
+		if err != nil {
+			logrus.WithError(err).WithField("transaction_id", txn.TransactionID).Warn("failed to enrich transaction narrative")
+		} else if enriched != nil {
+			mergeNarrative(narrative, enriched)
+		}
+	}
+	narrative.EnrichedAt = time.Now()
+
+	if txn.MetaData == nil {
+		txn.MetaData = make(map[string]interface{})
+	}
+	txn.MetaData[model.NarrativeMetaKey] = narrative
+}
+
+// applyNarrativeRules names the merchant of and categorizes a narrative by the first rule matching its cleaned up
+// description. Rules that cannot be compiled are skipped; configured rules are checked as the configuration loads.
+func applyNarrativeRules(narrative *model.TransactionNarrative, rules []config.NarrativeRule) {
+	for _, rule := range rules {
+		pattern, err := narrativePattern(rule.Pattern)
+		if err != nil || !pattern.MatchString(narrative.Description) {
+			continue
+		}
+		if rule.Merchant != "" {
+			narrative.MerchantName = rule.Merchant
+		}
+		if rule.Category != "" {
+			narrative.Category = rule.Category
+		}
+		narrative.Source = model.NarrativeSourceRules
+		return
+	}
+}
+
+// narrativePattern compiles the pattern of a narrative rule, matching regardless of case.
+func narrativePattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := narrativePatterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	compiled, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, err
+	}
+	narrativePatterns.Store(pattern, compiled)
+	return compiled, nil
+}
+
+// mergeNarrative overrides a narrative with the fields an enricher found.
+func mergeNarrative(narrative, enriched *model.TransactionNarrative) {
+	if enriched.Description == "" && enriched.MerchantName == "" && enriched.Category == "" {
+		return
+	}
+	if enriched.Description != "" {
+		narrative.Description = enriched.Description
+	}
+	if enriched.MerchantName != "" {
+		narrative.MerchantName = enriched.MerchantName
+	}
+	if enriched.Category != "" {
+		narrative.Category = enriched.Category
+	}
+	narrative.Source = model.NarrativeSourceAPI
+}
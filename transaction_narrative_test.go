@@ -0,0 +1,160 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedNarrativeEnricher struct {
+	narrative *model.TransactionNarrative
+	err       error
+}
+
+func (e fixedNarrativeEnricher) EnrichNarrative(context.Context, *model.Transaction, *model.TransactionNarrative) (*model.TransactionNarrative, error) {
+	return e.narrative, e.err
+}
+
+// useEnrichment enables narrative enrichment with rules for the duration of a test.
+func useEnrichment(t *testing.T, enrichment config.EnrichmentConfig) {
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.Enrichment = enrichment
+	t.Cleanup(func() { cnf.Enrichment = config.EnrichmentConfig{} })
+}
+
+func narrativeOf(t *testing.T, txn *model.Transaction) *model.TransactionNarrative {
+	narrative, ok := txn.MetaData[model.NarrativeMetaKey].(*model.TransactionNarrative)
+	require.True(t, ok, "the transaction has an enriched narrative")
+	return narrative
+}
+
+func TestEnrichTransactionNarrative_Rules(t *testing.T) {
+	b, _, _ := newBalanceNotificationTestBlnk(t)
+	useEnrichment(t, config.EnrichmentConfig{
+		Enabled: true,
+		Rules: []config.NarrativeRule{
+			{Pattern: `^uber\s*(trip|eats)`, Merchant: "Uber", Category: "transport"},
+			{Pattern: `coffee`, Category: "eating_out"},
+		},
+	})
+
+	txn := &model.Transaction{Description: "UBER   TRIP HELP.UBER.COM"}
+	b.enrichTransactionNarrative(context.Background(), txn)
+	narrative := narrativeOf(t, txn)
+	assert.Equal(t, "UBER   TRIP HELP.UBER.COM", narrative.RawDescription)
+	assert.Equal(t, "UBER TRIP HELP.UBER.COM", narrative.Description)
+	assert.Equal(t, "Uber", narrative.MerchantName)
+	assert.Equal(t, "transport", narrative.Category)
+	assert.Equal(t, model.NarrativeSourceRules, narrative.Source)
+	assert.Equal(t, "UBER   TRIP HELP.UBER.COM", txn.Description, "the description is kept as sent")
+
+	txn = &model.Transaction{Description: "SQ *BLUE BOTTLE COFFEE #12 OAKLAND"}
+	b.enrichTransactionNarrative(context.Background(), txn)
+	narrative = narrativeOf(t, txn)
+	assert.Equal(t, "Blue Bottle Coffee", narrative.MerchantName, "rules without a merchant keep the cleaned up merchant")
+	assert.Equal(t, "eating_out", narrative.Category)
+
+	txn = &model.Transaction{Description: "Rent"}
+	b.enrichTransactionNarrative(context.Background(), txn)
+	narrative = narrativeOf(t, txn)
+	assert.Empty(t, narrative.Category)
+	assert.Equal(t, model.NarrativeSourceCleanup, narrative.Source)
+
+	txn = &model.Transaction{}
+	b.enrichTransactionNarrative(context.Background(), txn)
+	assert.Nil(t, txn.MetaData, "transactions without a description are not enriched")
+}
+
+func TestEnrichTransactionNarrative_Enricher(t *testing.T) {
+	b, _, _ := newBalanceNotificationTestBlnk(t)
+	useEnrichment(t, config.EnrichmentConfig{
+		Enabled: true,
+		Rules:   []config.NarrativeRule{{Pattern: `netflix`, Category: "entertainment"}},
+	})
+
+	b.SetNarrativeEnricher(fixedNarrativeEnricher{narrative: &model.TransactionNarrative{MerchantName: "Netflix", Category: "subscriptions"}})
+	txn := &model.Transaction{Description: "NETFLIX.COM 866-579-7172"}
+	b.enrichTransactionNarrative(context.Background(), txn)
+	narrative := narrativeOf(t, txn)
+	assert.Equal(t, "Netflix", narrative.MerchantName)
+	assert.Equal(t, "subscriptions", narrative.Category)
+	assert.Equal(t, model.NarrativeSourceAPI, narrative.Source)
+
+	// A failing enricher leaves what the rules found rather than failing the posting
+	b.SetNarrativeEnricher(fixedNarrativeEnricher{err: errors.New("enrichment unavailable")})
+	txn = &model.Transaction{Description: "NETFLIX.COM 866-579-7172"}
+	b.enrichTransactionNarrative(context.Background(), txn)
+	narrative = narrativeOf(t, txn)
+	assert.Equal(t, "entertainment", narrative.Category)
+	assert.Equal(t, model.NarrativeSourceRules, narrative.Source)
+
+	// Narratives already enriched, such as of transactions recorded again once queued, are kept
+	enriched := &model.TransactionNarrative{Category: "subscriptions"}
+	txn = &model.Transaction{Description: "NETFLIX.COM", MetaData: map[string]interface{}{model.NarrativeMetaKey: enriched}}
+	b.enrichTransactionNarrative(context.Background(), txn)
+	assert.Same(t, enriched, narrativeOf(t, txn))
+}
+
+func TestEnrichTransactionNarrative_ConfiguredAPI(t *testing.T) {
+	var request narrativeEnrichmentRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key_1", r.Header.Get("X-Api-Key"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		_, _ = w.Write([]byte(`{"merchant_name":"Blue Bottle Coffee","category":"eating_out"}`))
+	}))
+	defer server.Close()
+
+	b, _, _ := newBalanceNotificationTestBlnk(t)
+	b.httpClient = server.Client()
+	useEnrichment(t, config.EnrichmentConfig{Enabled: true, URL: server.URL, Headers: map[string]string{"X-Api-Key": "key_1"}})
+
+	txn := &model.Transaction{TransactionID: "txn_1", Description: "SQ *BLUE BOTTLE #12"}
+	b.enrichTransactionNarrative(context.Background(), txn)
+	narrative := narrativeOf(t, txn)
+	assert.Equal(t, "txn_1", request.Transaction.TransactionID)
+	assert.Equal(t, "BLUE BOTTLE #12", request.Narrative.Description)
+	assert.Equal(t, "Blue Bottle Coffee", narrative.MerchantName)
+	assert.Equal(t, "eating_out", narrative.Category)
+	assert.Equal(t, model.NarrativeSourceAPI, narrative.Source)
+}
+
+func TestLiftNarrative(t *testing.T) {
+	data := map[string]interface{}{
+		"meta_data": map[string]interface{}{
+			model.NarrativeMetaKey: map[string]interface{}{"description": "BLUE BOTTLE #12", "merchant_name": "Blue Bottle", "category": "eating_out"},
+		},
+	}
+	(&TypesenseClient{}).liftNarrative("transactions", data)
+	assert.Equal(t, "BLUE BOTTLE #12", data["narrative"])
+	assert.Equal(t, "Blue Bottle", data["merchant_name"])
+	assert.Equal(t, "eating_out", data["category"])
+
+	unenriched := map[string]interface{}{"meta_data": map[string]interface{}{}}
+	(&TypesenseClient{}).liftNarrative("transactions", unenriched)
+	assert.NotContains(t, unenriched, "category")
+}
@@ -0,0 +1,83 @@
+package blnk
+
+import (
+	"context"
+
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/blnkfinance/blnk/model"
+)
+
+type statusReasonKey struct{}
+
+// WithStatusReason returns a context whose transaction status changes are recorded with the reason code and
+// reason instead of the default ones, such as a void made because an inflight transaction expired.
+func WithStatusReason(ctx context.Context, code, reason string) context.Context {
+	return context.WithValue(ctx, statusReasonKey{}, model.StatusChange{ReasonCode: code, Reason: reason})
+}
+
+// stampStatusChange records on the transaction why and by whom it is being saved with its status. The actor is
+// the tenant the request acts for, or the system when a worker makes the change.
+func stampStatusChange(ctx context.Context, transaction *model.Transaction, code, reason string) {
+	change := model.StatusChange{ReasonCode: code, Reason: reason, Actor: tenant.FromContext(ctx)}
+	if override, ok := ctx.Value(statusReasonKey{}).(model.StatusChange); ok {
+		change.ReasonCode, change.Reason = override.ReasonCode, override.Reason
+	}
+	if change.Actor == "" {
+		change.Actor = model.ActorSystem
+	}
+	transaction.StatusChange = change
+}
+
+// statusChangeReasonCode returns the default reason code of a transaction recorded with the given status.
+func statusChangeReasonCode(status string) string {
+	switch status {
+	case StatusCommit:
+		return model.ReasonInflightCommitted
+	case StatusVoid:
+		return model.ReasonInflightVoided
+	default:
+		return ""
+	}
+}
+
+// GetTransactionStatusHistory retrieves the statuses a transaction went through, oldest first, including those
+// of the transactions it led to, such as the queued copy that was applied or the commits and voids of an
+// inflight transaction. Transactions recorded before the history was kept report their current status only.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - transactionID string: The ID of the transaction.
+//
+// Returns:
+// - []*model.TransactionStatusEntry: The status changes.
+// - error: An error if the transaction does not exist or its history could not be retrieved.
+func (l *Blnk) GetTransactionStatusHistory(ctx context.Context, transactionID string) ([]*model.TransactionStatusEntry, error) {
+	ctx, span := tracer.Start(ctx, "GetTransactionStatusHistory")
+	defer span.End()
+
+	transaction, err := l.datasource.GetTransaction(ctx, transactionID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	history, err := l.datasource.GetTransactionStatusHistory(ctx, transactionID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if len(history) > 0 {
+		return history, nil
+	}
+
+	entry := &model.TransactionStatusEntry{
+		TransactionID:     transaction.TransactionID,
+		ParentTransaction: transaction.ParentTransaction,
+		Status:            transaction.Status,
+		CreatedAt:         transaction.CreatedAt,
+	}
+	if reason, ok := transaction.MetaData["blnk_rejection_reason"].(string); ok {
+		entry.Reason = reason
+		entry.ReasonCode = model.RejectionReasonCode(reason)
+	}
+	return []*model.TransactionStatusEntry{entry}, nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStampStatusChange(t *testing.T) {
+	txn := &model.Transaction{}
+	stampStatusChange(context.Background(), txn, model.ReasonInflightVoided, "")
+	assert.Equal(t, model.StatusChange{ReasonCode: model.ReasonInflightVoided, Actor: model.ActorSystem}, txn.StatusChange)
+
+	ctx := tenant.WithTenant(context.Background(), "owner_1")
+	stampStatusChange(ctx, txn, "", "")
+	assert.Equal(t, model.StatusChange{Actor: "owner_1"}, txn.StatusChange)
+
+	ctx = WithStatusReason(ctx, model.ReasonInflightExpired, "inflight expiry date passed")
+	stampStatusChange(ctx, txn, model.ReasonInflightVoided, "")
+	assert.Equal(t, model.StatusChange{ReasonCode: model.ReasonInflightExpired, Reason: "inflight expiry date passed", Actor: "owner_1"}, txn.StatusChange)
+}
+
+func TestRejectionReasonCode(t *testing.T) {
+	assert.Equal(t, model.ReasonInsufficientFunds, model.RejectionReasonCode("insufficient funds in source balance"))
+	assert.Equal(t, model.ReasonOverdraftLimit, model.RejectionReasonCode("transaction exceeds overdraft limit"))
+	assert.Equal(t, model.ReasonMinimumBalance, model.RejectionReasonCode("transaction would take balance bln_1 to -5, below its minimum balance of 0"))
+	assert.Equal(t, model.ReasonRetriesExhausted, model.RejectionReasonCode("retries exhausted after 3 attempts: insufficient funds in source balance"))
+	assert.Equal(t, model.ReasonRetriesExhausted, model.RejectionReasonCode("max retry attempts reached after insufficient funds"))
+	assert.Equal(t, model.ReasonRejected, model.RejectionReasonCode("balance is frozen"))
+}
+
+func TestRejectTransaction_RecordsReasonCode(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+
+	mockDS.On("RecordTransaction", mock.Anything, mock.MatchedBy(func(txn *model.Transaction) bool {
+		return txn.Status == StatusRejected && txn.StatusChange.ReasonCode == model.ReasonInsufficientFunds &&
+			txn.StatusChange.Reason == "insufficient funds in source balance" && txn.StatusChange.Actor == model.ActorSystem
+	})).Return(&model.Transaction{TransactionID: "txn_1", Status: StatusRejected}, nil)
+
+	_, err := b.RejectTransaction(context.Background(), &model.Transaction{TransactionID: "txn_1"}, "insufficient funds in source balance")
+	require.NoError(t, err)
+	mockDS.AssertExpectations(t)
+}
+
+func TestGetTransactionStatusHistory(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	createdAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	mockDS.On("GetTransaction", mock.Anything, "txn_1").Return(&model.Transaction{TransactionID: "txn_1", Status: StatusInflight, CreatedAt: createdAt}, nil)
+	mockDS.On("GetTransactionStatusHistory", mock.Anything, "txn_1").Return([]*model.TransactionStatusEntry{
+		{TransactionID: "txn_1", Status: StatusInflight, Actor: "owner_1", CreatedAt: createdAt},
+		{TransactionID: "txn_2", ParentTransaction: "txn_1", Status: StatusVoid, ReasonCode: model.ReasonInflightExpired, Actor: model.ActorSystem, CreatedAt: createdAt.Add(time.Hour)},
+	}, nil)
+
+	history, err := b.GetTransactionStatusHistory(context.Background(), "txn_1")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, model.ReasonInflightExpired, history[1].ReasonCode)
+}
+
+func TestGetTransactionStatusHistory_RecordedBeforeHistory(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	createdAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	mockDS.On("GetTransaction", mock.Anything, "txn_1").Return(&model.Transaction{
+		TransactionID: "txn_1", Status: StatusRejected, CreatedAt: createdAt,
+		MetaData: map[string]interface{}{"blnk_rejection_reason": "transaction exceeds overdraft limit"},
+	}, nil)
+	mockDS.On("GetTransactionStatusHistory", mock.Anything, "txn_1").Return([]*model.TransactionStatusEntry{}, nil)
+
+	history, err := b.GetTransactionStatusHistory(context.Background(), "txn_1")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, StatusRejected, history[0].Status)
+	assert.Equal(t, model.ReasonOverdraftLimit, history[0].ReasonCode)
+	assert.Equal(t, "transaction exceeds overdraft limit", history[0].Reason)
+	assert.Equal(t, createdAt, history[0].CreatedAt)
+}
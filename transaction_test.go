@@ -488,10 +488,10 @@ func TestQueueTransactionFlow(t *testing.T) {
 	}
 
 	// Create balances in database
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	sourceID := source.BalanceID
@@ -535,10 +535,10 @@ func TestQueueTransactionFlow(t *testing.T) {
 	require.Equal(t, txn.Amount, appliedTxn.Amount, "Amount should match")
 
 	// Verify final balance states
-	updatedSource, err := ds.GetBalanceByIDLite(sourceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, sourceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(destID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, destID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	fmt.Println("Updated Source Balance: ", updatedSource.Balance)
@@ -600,10 +600,10 @@ func TestQueueTransactionFlowWithSkipQueue(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction with skip_queue set to true
@@ -627,10 +627,10 @@ func TestQueueTransactionFlowWithSkipQueue(t *testing.T) {
 	require.Equal(t, StatusApplied, queuedTxn.Status, "Transaction should be APPLIED immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes
@@ -699,10 +699,10 @@ func TestInflightTransactionFlowWithSkipQueue(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction with skip_queue set to true
@@ -727,10 +727,10 @@ func TestInflightTransactionFlowWithSkipQueue(t *testing.T) {
 	require.Equal(t, StatusInflight, queuedTxn.Status, "Transaction should be INFLIGHT immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes
@@ -799,10 +799,10 @@ func TestInflightTransactionFlowWithSkipQueueThenCommit(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction with skip_queue set to true
@@ -827,10 +827,10 @@ func TestInflightTransactionFlowWithSkipQueueThenCommit(t *testing.T) {
 	require.Equal(t, StatusInflight, queuedTxn.Status, "Transaction should be INFLIGHT immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes
@@ -853,10 +853,10 @@ func TestInflightTransactionFlowWithSkipQueueThenCommit(t *testing.T) {
 	require.NoError(t, err, "Failed to commit transaction")
 
 	// Verify balances were updated immediately
-	updatedSourceAfterCommit, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSourceAfterCommit, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDestAfterCommit, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDestAfterCommit, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Verify balance changes
@@ -916,10 +916,10 @@ func TestInflightTransactionFlowWithSkipQueueThenPartialCommit(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction with skip_queue set to true
@@ -945,10 +945,10 @@ func TestInflightTransactionFlowWithSkipQueueThenPartialCommit(t *testing.T) {
 	require.Equal(t, StatusInflight, queuedTxn.Status, "Transaction should be INFLIGHT immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes for the full amount
@@ -975,10 +975,10 @@ func TestInflightTransactionFlowWithSkipQueueThenPartialCommit(t *testing.T) {
 	require.Equal(t, partialAmount, partialCommitTxn.Amount, "Partial commit amount should match specified amount")
 
 	// Verify balances were updated after partial commit
-	updatedSourceAfterPartialCommit, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSourceAfterPartialCommit, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDestAfterPartialCommit, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDestAfterPartialCommit, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes for the partial commit
@@ -1010,10 +1010,10 @@ func TestInflightTransactionFlowWithSkipQueueThenPartialCommit(t *testing.T) {
 	require.InDelta(t, remainingAmount, remainingCommitTxn.Amount, 0.01, "Remaining commit amount should match expected remaining amount")
 
 	// Verify final balances
-	updatedSourceAfterFullCommit, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSourceAfterFullCommit, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get final source balance")
 
-	updatedDestAfterFullCommit, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDestAfterFullCommit, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get final destination balance")
 
 	// Verify final balance changes
@@ -1034,7 +1034,7 @@ func TestInflightTransactionFlowWithSkipQueueThenPartialCommit(t *testing.T) {
 		"Error message should indicate transaction is already committed")
 
 	// Verify commit history
-	commitHistory, err := ds.GetTransactionsByParent(ctx, inflightEntry.TransactionID, 2, 0)
+	commitHistory, err := ds.GetTransactionsByParent(ctx, inflightEntry.TransactionID, model.Page{Limit: 2})
 	require.NoError(t, err, "Failed to get commit history")
 	require.Equal(t, 2, len(commitHistory), "Should have exactly two commit transactions")
 
@@ -1115,10 +1115,10 @@ func TestInflightTransactionFlowWithSkipQueueThenPartialCommitThenVoid(t *testin
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction with skip_queue set to true
@@ -1144,10 +1144,10 @@ func TestInflightTransactionFlowWithSkipQueueThenPartialCommitThenVoid(t *testin
 	require.Equal(t, StatusInflight, queuedTxn.Status, "Transaction should be INFLIGHT immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes for the full amount
@@ -1174,10 +1174,10 @@ func TestInflightTransactionFlowWithSkipQueueThenPartialCommitThenVoid(t *testin
 	require.Equal(t, partialAmount, partialCommitTxn.Amount, "Partial commit amount should match specified amount")
 
 	// Verify balances were updated after partial commit
-	updatedSourceAfterPartialCommit, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSourceAfterPartialCommit, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDestAfterPartialCommit, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDestAfterPartialCommit, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes for the partial commit
@@ -1205,10 +1205,10 @@ func TestInflightTransactionFlowWithSkipQueueThenPartialCommitThenVoid(t *testin
 	require.Equal(t, StatusVoid, voidTxn.Status, "Void transaction should have VOID status")
 
 	// Verify balances after void
-	updatedSourceAfterVoid, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSourceAfterVoid, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after void")
 
-	updatedDestAfterVoid, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDestAfterVoid, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after void")
 
 	// Verify that permanent balances remain at the partially committed amount
@@ -1236,7 +1236,7 @@ func TestInflightTransactionFlowWithSkipQueueThenPartialCommitThenVoid(t *testin
 		"Error message should indicate transaction is already voided")
 
 	// Verify transaction history
-	txnHistory, err := ds.GetTransactionsByParent(ctx, inflightEntry.TransactionID, 10, 0)
+	txnHistory, err := ds.GetTransactionsByParent(ctx, inflightEntry.TransactionID, model.Page{Limit: 10})
 	require.NoError(t, err, "Failed to get transaction history")
 	require.Equal(t, 2, len(txnHistory), "Should have exactly two transactions: one commit and one void")
 
@@ -1308,10 +1308,10 @@ func TestTwoInflightTransactionsCommitOneVoidCommitted(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create first inflight transaction with HIGHER amount
@@ -1365,10 +1365,10 @@ func TestTwoInflightTransactionsCommitOneVoidCommitted(t *testing.T) {
 	require.Equal(t, StatusInflight, inflightEntry2.Status, "Second transaction should be INFLIGHT")
 
 	// Check balances after both inflight transactions
-	afterInflightSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterInflightSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance")
 
-	afterInflightDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterInflightDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance")
 
 	// Calculate expected inflight balances (both transactions are inflight)
@@ -1389,10 +1389,10 @@ func TestTwoInflightTransactionsCommitOneVoidCommitted(t *testing.T) {
 	require.Equal(t, StatusApplied, commitTxn.Status, "Second transaction should have APPLIED status")
 
 	// Check balances after committing second transaction
-	afterCommitSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterCommitSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after commit")
 
-	afterCommitDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterCommitDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after commit")
 
 	// Calculate expected balances
@@ -1427,10 +1427,10 @@ func TestTwoInflightTransactionsCommitOneVoidCommitted(t *testing.T) {
 		"Error message should indicate transaction is already committed")
 
 	// Check balances again to ensure they weren't affected by the failed void attempt
-	afterVoidAttemptSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterVoidAttemptSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after void attempt")
 
-	afterVoidAttemptDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterVoidAttemptDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after void attempt")
 
 	// Verify permanent balances remain unchanged
@@ -1451,10 +1451,10 @@ func TestTwoInflightTransactionsCommitOneVoidCommitted(t *testing.T) {
 	require.Equal(t, StatusVoid, voidTxn.Status, "First transaction should have VOID status")
 
 	// Check balances after voiding first transaction
-	afterVoidFirstSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterVoidFirstSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after voiding first transaction")
 
-	afterVoidFirstDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterVoidFirstDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after voiding first transaction")
 
 	// Permanent balances should still reflect only the second committed transaction
@@ -1470,12 +1470,12 @@ func TestTwoInflightTransactionsCommitOneVoidCommitted(t *testing.T) {
 		"Destination inflight balance should be zero after voiding first transaction")
 
 	// Verify transaction history for both transactions
-	txnHistory1, err := ds.GetTransactionsByParent(ctx, inflightEntry1.TransactionID, 10, 0)
+	txnHistory1, err := ds.GetTransactionsByParent(ctx, inflightEntry1.TransactionID, model.Page{Limit: 10})
 	require.NoError(t, err, "Failed to get first transaction history")
 	require.Equal(t, 1, len(txnHistory1), "Should have exactly one transaction for the first parent: the void")
 	require.Equal(t, StatusVoid, txnHistory1[0].Status, "First transaction history should include a VOID transaction")
 
-	txnHistory2, err := ds.GetTransactionsByParent(ctx, inflightEntry2.TransactionID, 10, 0)
+	txnHistory2, err := ds.GetTransactionsByParent(ctx, inflightEntry2.TransactionID, model.Page{Limit: 10})
 	require.NoError(t, err, "Failed to get second transaction history")
 	require.Equal(t, 1, len(txnHistory2), "Should have exactly one transaction for the second parent: the commit")
 	require.Equal(t, StatusApplied, txnHistory2[0].Status, "Second transaction history should include an APPLIED transaction")
@@ -1529,10 +1529,10 @@ func TestInflightTransactionWithOvercommitValidation(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create an initial transaction to transfer from source to destination
@@ -1556,10 +1556,10 @@ func TestInflightTransactionWithOvercommitValidation(t *testing.T) {
 	require.Equal(t, StatusApplied, depositResult.Status, "Initial transaction should be APPLIED")
 
 	// Verify balances after initial transfer
-	sourceAfterTransfer, err := ds.GetBalanceByIDLite(source.BalanceID)
+	sourceAfterTransfer, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after transfer")
 
-	destAfterTransfer, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	destAfterTransfer, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after transfer")
 
 	// Source should be -1000, destination should be 1000
@@ -1593,7 +1593,7 @@ func TestInflightTransactionWithOvercommitValidation(t *testing.T) {
 	require.Equal(t, StatusInflight, queuedTxn.Status, "Transaction should be INFLIGHT")
 
 	// Verify inflight balance is updated correctly
-	afterInflightSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterInflightSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after inflight")
 
 	expectedInflightBalance := big.NewInt(int64(-inflightAmount * 100))
@@ -1616,10 +1616,10 @@ func TestInflightTransactionWithOvercommitValidation(t *testing.T) {
 		"Error should mention that overcommit is not allowed")
 
 	// Verify balances remain unchanged after failed overcommit
-	afterFailedOvercommitSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterFailedOvercommitSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after failed overcommit")
 
-	afterFailedOvercommitDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterFailedOvercommitDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after failed overcommit")
 
 	// Source balance should still be -1000
@@ -1646,10 +1646,10 @@ func TestInflightTransactionWithOvercommitValidation(t *testing.T) {
 	require.Equal(t, StatusApplied, partialCommitTxn.Status, "Partial commit transaction should have APPLIED status")
 
 	// Verify balances after partial commit
-	afterPartialCommitSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterPartialCommitSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after partial commit")
 
-	afterPartialCommitDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterPartialCommitDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after partial commit")
 
 	// Source balance should be -1000 - 50 = -1050
@@ -1678,10 +1678,10 @@ func TestInflightTransactionWithOvercommitValidation(t *testing.T) {
 	require.Equal(t, StatusApplied, fullCommitTxn.Status, "Full commit transaction should have APPLIED status")
 
 	// Verify balances after full commit
-	afterFullCommitSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterFullCommitSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after full commit")
 
-	afterFullCommitDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterFullCommitDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after full commit")
 
 	// Source balance should be -1050 - 50 = -1100
@@ -1707,10 +1707,10 @@ func TestInflightTransactionWithOvercommitValidation(t *testing.T) {
 		"Error should mention that transaction is already committed")
 
 	// Verify balances remain unchanged after failed second commit
-	afterFailedSecondCommitSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterFailedSecondCommitSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after failed second commit")
 
-	afterFailedSecondCommitDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterFailedSecondCommitDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after failed second commit")
 
 	require.Equal(t, 0, afterFailedSecondCommitSource.Balance.Cmp(expectedSourceBalanceAfterFull),
@@ -1729,10 +1729,10 @@ func TestInflightTransactionWithOvercommitValidation(t *testing.T) {
 		"Error should mention that transaction is already committed")
 
 	// Verify balances remain unchanged after failed void
-	afterFailedVoidSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterFailedVoidSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after failed void")
 
-	afterFailedVoidDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterFailedVoidDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after failed void")
 
 	require.Equal(t, 0, afterFailedVoidSource.Balance.Cmp(expectedSourceBalanceAfterFull),
@@ -1745,7 +1745,7 @@ func TestInflightTransactionWithOvercommitValidation(t *testing.T) {
 		"Destination inflight balance should remain zero after failed void")
 
 	// Verify transaction history
-	txnHistory, err := ds.GetTransactionsByParent(ctx, inflightEntry.TransactionID, 10, 0)
+	txnHistory, err := ds.GetTransactionsByParent(ctx, inflightEntry.TransactionID, model.Page{Limit: 10})
 	require.NoError(t, err, "Failed to get transaction history")
 	require.Equal(t, 2, len(txnHistory), "Should have exactly two transactions: partial commit and full commit")
 
@@ -1804,10 +1804,10 @@ func TestInflightTransactionWithPartialOvercommitValidation(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create an initial transaction to transfer from source to destination
@@ -1831,10 +1831,10 @@ func TestInflightTransactionWithPartialOvercommitValidation(t *testing.T) {
 	require.Equal(t, StatusApplied, depositResult.Status, "Initial transaction should be APPLIED")
 
 	// Verify balances after initial transfer
-	sourceAfterTransfer, err := ds.GetBalanceByIDLite(source.BalanceID)
+	sourceAfterTransfer, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after transfer")
 
-	destAfterTransfer, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	destAfterTransfer, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after transfer")
 
 	// Source should be -1000, destination should be 1000
@@ -1868,7 +1868,7 @@ func TestInflightTransactionWithPartialOvercommitValidation(t *testing.T) {
 	require.Equal(t, StatusInflight, queuedTxn.Status, "Transaction should be INFLIGHT")
 
 	// Verify inflight balance is updated correctly
-	afterInflightSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterInflightSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after inflight")
 
 	expectedInflightBalance := big.NewInt(int64(-inflightAmount * 100))
@@ -1888,10 +1888,10 @@ func TestInflightTransactionWithPartialOvercommitValidation(t *testing.T) {
 	require.Equal(t, StatusApplied, partialCommitTxn.Status, "Partial commit transaction should have APPLIED status")
 
 	// Verify balances after partial commit
-	afterPartialCommitSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterPartialCommitSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after partial commit")
 
-	afterPartialCommitDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterPartialCommitDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after partial commit")
 
 	// Source balance should be -1000 - 50 = -1050
@@ -1926,10 +1926,10 @@ func TestInflightTransactionWithPartialOvercommitValidation(t *testing.T) {
 		"Error should mention that overcommit of the remaining amount is not allowed")
 
 	// Verify balances remain unchanged after failed partial overcommit
-	afterFailedOvercommitSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterFailedOvercommitSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after failed partial overcommit")
 
-	afterFailedOvercommitDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterFailedOvercommitDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after failed partial overcommit")
 
 	// Source balance should still be -1050
@@ -1954,10 +1954,10 @@ func TestInflightTransactionWithPartialOvercommitValidation(t *testing.T) {
 	require.Equal(t, StatusApplied, fullCommitTxn.Status, "Full commit transaction should have APPLIED status")
 
 	// Verify balances after full commit
-	afterFullCommitSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterFullCommitSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after full commit")
 
-	afterFullCommitDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterFullCommitDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after full commit")
 
 	// Source balance should be -1050 - 50 = -1100
@@ -1983,10 +1983,10 @@ func TestInflightTransactionWithPartialOvercommitValidation(t *testing.T) {
 		"Error should mention that transaction is already committed")
 
 	// Verify balances remain unchanged after failed second commit
-	afterFailedSecondCommitSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterFailedSecondCommitSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after failed second commit")
 
-	afterFailedSecondCommitDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterFailedSecondCommitDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after failed second commit")
 
 	require.Equal(t, 0, afterFailedSecondCommitSource.Balance.Cmp(expectedSourceBalanceAfterFull),
@@ -2005,10 +2005,10 @@ func TestInflightTransactionWithPartialOvercommitValidation(t *testing.T) {
 		"Error should mention that transaction is already committed")
 
 	// Verify balances remain unchanged after failed void
-	afterFailedVoidSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	afterFailedVoidSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after failed void")
 
-	afterFailedVoidDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	afterFailedVoidDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after failed void")
 
 	require.Equal(t, 0, afterFailedVoidSource.Balance.Cmp(expectedSourceBalanceAfterFull),
@@ -2021,7 +2021,7 @@ func TestInflightTransactionWithPartialOvercommitValidation(t *testing.T) {
 		"Destination inflight balance should remain zero after failed void")
 
 	// Verify transaction history
-	txnHistory, err := ds.GetTransactionsByParent(ctx, inflightEntry.TransactionID, 10, 0)
+	txnHistory, err := ds.GetTransactionsByParent(ctx, inflightEntry.TransactionID, model.Page{Limit: 10})
 	require.NoError(t, err, "Failed to get transaction history")
 	require.Equal(t, 2, len(txnHistory), "Should have exactly two transactions: partial commit and full commit")
 
@@ -2082,10 +2082,10 @@ func TestInflightTransactionFlowWithSkipQueueThenVoid(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction with skip_queue set to true
@@ -2110,10 +2110,10 @@ func TestInflightTransactionFlowWithSkipQueueThenVoid(t *testing.T) {
 	require.Equal(t, StatusInflight, queuedTxn.Status, "Transaction should be INFLIGHT immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes
@@ -2136,10 +2136,10 @@ func TestInflightTransactionFlowWithSkipQueueThenVoid(t *testing.T) {
 	require.NoError(t, err, "Failed to void transaction")
 
 	// Verify balances were reset after void
-	updatedSourceAfterVoid, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSourceAfterVoid, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance after void")
 
-	updatedDestAfterVoid, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDestAfterVoid, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance after void")
 
 	// Verify balance changes were reversed
@@ -2214,13 +2214,13 @@ func TestMultipleSourcesTransactionFlowWithSkipQueue(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	sourceOne, err := ds.CreateBalance(*sourceBalanceOne)
+	sourceOne, err := ds.CreateBalance(ctx, *sourceBalanceOne)
 	require.NoError(t, err, "Failed to create source balance one")
 
-	sourceTwo, err := ds.CreateBalance(*sourceBalanceTwo)
+	sourceTwo, err := ds.CreateBalance(ctx, *sourceBalanceTwo)
 	require.NoError(t, err, "Failed to create source balance two")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction with skip_queue set to true
@@ -2248,13 +2248,13 @@ func TestMultipleSourcesTransactionFlowWithSkipQueue(t *testing.T) {
 	require.Equal(t, StatusApplied, queuedTxn.Status, "Transaction should be APPLIED immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedSourceOne, err := ds.GetBalanceByIDLite(sourceOne.BalanceID)
+	updatedSourceOne, err := ds.GetBalanceByIDLite(ctx, sourceOne.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedSourceTwo, err := ds.GetBalanceByIDLite(sourceTwo.BalanceID)
+	updatedSourceTwo, err := ds.GetBalanceByIDLite(ctx, sourceTwo.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes
@@ -2335,13 +2335,13 @@ func TestMultipleSourcesInflightTransactionFlowWithSkipQueueAndCommit(t *testing
 		LedgerID: "general_ledger_id",
 	}
 
-	sourceOne, err := ds.CreateBalance(*sourceBalanceOne)
+	sourceOne, err := ds.CreateBalance(ctx, *sourceBalanceOne)
 	require.NoError(t, err, "Failed to create source balance one")
 
-	sourceTwo, err := ds.CreateBalance(*sourceBalanceTwo)
+	sourceTwo, err := ds.CreateBalance(ctx, *sourceBalanceTwo)
 	require.NoError(t, err, "Failed to create source balance two")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction with skip_queue set to true
@@ -2370,13 +2370,13 @@ func TestMultipleSourcesInflightTransactionFlowWithSkipQueueAndCommit(t *testing
 	require.Equal(t, StatusInflight, queuedTxn.Status, "Transaction should be INFLIGHT immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedSourceOne, err := ds.GetBalanceByIDLite(sourceOne.BalanceID)
+	updatedSourceOne, err := ds.GetBalanceByIDLite(ctx, sourceOne.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance one")
 
-	updatedSourceTwo, err := ds.GetBalanceByIDLite(sourceTwo.BalanceID)
+	updatedSourceTwo, err := ds.GetBalanceByIDLite(ctx, sourceTwo.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance two")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes
@@ -2438,13 +2438,13 @@ func TestMultipleSourcesInflightTransactionFlowWithSkipQueueAndCommit(t *testing
 	require.Equal(t, sourceTwoPartialAmount, partialCommitTxnTwo.Amount, "Partial commit amount should match specified amount")
 
 	// Verify balances were updated after partial commit
-	updatedSourceOneAfterPartialCommit, err := ds.GetBalanceByIDLite(sourceOne.BalanceID)
+	updatedSourceOneAfterPartialCommit, err := ds.GetBalanceByIDLite(ctx, sourceOne.BalanceID)
 	require.NoError(t, err, "Failed to get updated source one balance")
 
-	updatedSourceTwoAfterPartialCommit, err := ds.GetBalanceByIDLite(sourceTwo.BalanceID)
+	updatedSourceTwoAfterPartialCommit, err := ds.GetBalanceByIDLite(ctx, sourceTwo.BalanceID)
 	require.NoError(t, err, "Failed to get updated source two balance")
 
-	updatedDestAfterPartialCommit, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDestAfterPartialCommit, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	expectedSourceOnePartialDebit := big.NewInt(int64(-sourceOnePartialAmount) * 100)
@@ -2491,13 +2491,13 @@ func TestMultipleSourcesInflightTransactionFlowWithSkipQueueAndCommit(t *testing
 		"Remaining commit amount for source two should match expected remaining amount")
 
 	// Verify final balances
-	updatedSourceOneAfterFullCommit, err := ds.GetBalanceByIDLite(sourceOne.BalanceID)
+	updatedSourceOneAfterFullCommit, err := ds.GetBalanceByIDLite(ctx, sourceOne.BalanceID)
 	require.NoError(t, err, "Failed to get final source one balance")
 
-	updatedSourceTwoAfterFullCommit, err := ds.GetBalanceByIDLite(sourceTwo.BalanceID)
+	updatedSourceTwoAfterFullCommit, err := ds.GetBalanceByIDLite(ctx, sourceTwo.BalanceID)
 	require.NoError(t, err, "Failed to get final source two balance")
 
-	updatedDestAfterFullCommit, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDestAfterFullCommit, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get final destination balance")
 
 	// Calculate expected final balances
@@ -2533,11 +2533,11 @@ func TestMultipleSourcesInflightTransactionFlowWithSkipQueueAndCommit(t *testing
 		"Error message should indicate transaction is already committed")
 
 	// Verify commit history for both sources
-	commitHistoryOne, err := ds.GetTransactionsByParent(ctx, inflightEntryOne.TransactionID, 5, 0)
+	commitHistoryOne, err := ds.GetTransactionsByParent(ctx, inflightEntryOne.TransactionID, model.Page{Limit: 5})
 	require.NoError(t, err, "Failed to get commit history for source one")
 	require.Equal(t, 2, len(commitHistoryOne), "Should have exactly two commit transactions for source one")
 
-	commitHistoryTwo, err := ds.GetTransactionsByParent(ctx, inflightEntryTwo.TransactionID, 5, 0)
+	commitHistoryTwo, err := ds.GetTransactionsByParent(ctx, inflightEntryTwo.TransactionID, model.Page{Limit: 5})
 	require.NoError(t, err, "Failed to get commit history for source two")
 	require.Equal(t, 2, len(commitHistoryTwo), "Should have exactly two commit transactions for source two")
 
@@ -2646,13 +2646,13 @@ func TestMultipleDestinationTransactionFlowWithSkipQueue(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	destinationOne, err := ds.CreateBalance(*destinationBalanceOne)
+	destinationOne, err := ds.CreateBalance(ctx, *destinationBalanceOne)
 	require.NoError(t, err, "Failed to create destination balance one")
 
-	destinationTwo, err := ds.CreateBalance(*destinationBalanceTwo)
+	destinationTwo, err := ds.CreateBalance(ctx, *destinationBalanceTwo)
 	require.NoError(t, err, "Failed to create destination balance two")
 
-	source, err := ds.CreateBalance(*destBalance)
+	source, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction with skip_queue set to true
@@ -2680,13 +2680,13 @@ func TestMultipleDestinationTransactionFlowWithSkipQueue(t *testing.T) {
 	require.Equal(t, StatusApplied, queuedTxn.Status, "Transaction should be APPLIED immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedDestinationOne, err := ds.GetBalanceByIDLite(destinationOne.BalanceID)
+	updatedDestinationOne, err := ds.GetBalanceByIDLite(ctx, destinationOne.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
-	updatedDestinationTwo, err := ds.GetBalanceByIDLite(destinationTwo.BalanceID)
+	updatedDestinationTwo, err := ds.GetBalanceByIDLite(ctx, destinationTwo.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
 	// Calculate expected balance changes
@@ -2767,13 +2767,13 @@ func TestMultipleDestinationTransactionFlowWithTwoDistributions(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	destinationOne, err := ds.CreateBalance(*destinationBalanceOne)
+	destinationOne, err := ds.CreateBalance(ctx, *destinationBalanceOne)
 	require.NoError(t, err, "Failed to create destination balance one")
 
-	destinationTwo, err := ds.CreateBalance(*destinationBalanceTwo)
+	destinationTwo, err := ds.CreateBalance(ctx, *destinationBalanceTwo)
 	require.NoError(t, err, "Failed to create destination balance two")
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
 	// Transaction amount
@@ -2804,13 +2804,13 @@ func TestMultipleDestinationTransactionFlowWithTwoDistributions(t *testing.T) {
 	require.Equal(t, StatusApplied, queuedTxn.Status, "Transaction should be APPLIED immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedDestinationOne, err := ds.GetBalanceByIDLite(destinationOne.BalanceID)
+	updatedDestinationOne, err := ds.GetBalanceByIDLite(ctx, destinationOne.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance one")
 
-	updatedDestinationTwo, err := ds.GetBalanceByIDLite(destinationTwo.BalanceID)
+	updatedDestinationTwo, err := ds.GetBalanceByIDLite(ctx, destinationTwo.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance two")
 
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
 	// Calculate expected balance changes
@@ -2897,10 +2897,10 @@ func TestQueueTransactionFlowWithSkipQueueAndPreciseAmount(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction with skip_queue set to true
@@ -2924,10 +2924,10 @@ func TestQueueTransactionFlowWithSkipQueueAndPreciseAmount(t *testing.T) {
 	require.Equal(t, StatusApplied, queuedTxn.Status, "Transaction should be APPLIED immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes
@@ -3000,13 +3000,13 @@ func TestMultipleSourcesTransactionFlowWithSkipQueueAndPreciseAmount(t *testing.
 		LedgerID: "general_ledger_id",
 	}
 
-	sourceOne, err := ds.CreateBalance(*sourceBalanceOne)
+	sourceOne, err := ds.CreateBalance(ctx, *sourceBalanceOne)
 	require.NoError(t, err, "Failed to create source balance one")
 
-	sourceTwo, err := ds.CreateBalance(*sourceBalanceTwo)
+	sourceTwo, err := ds.CreateBalance(ctx, *sourceBalanceTwo)
 	require.NoError(t, err, "Failed to create source balance two")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction with skip_queue set to true
@@ -3034,13 +3034,13 @@ func TestMultipleSourcesTransactionFlowWithSkipQueueAndPreciseAmount(t *testing.
 	require.Equal(t, StatusApplied, queuedTxn.Status, "Transaction should be APPLIED immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedSourceOne, err := ds.GetBalanceByIDLite(sourceOne.BalanceID)
+	updatedSourceOne, err := ds.GetBalanceByIDLite(ctx, sourceOne.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedSourceTwo, err := ds.GetBalanceByIDLite(sourceTwo.BalanceID)
+	updatedSourceTwo, err := ds.GetBalanceByIDLite(ctx, sourceTwo.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes
@@ -3121,13 +3121,13 @@ func TestMultipleDestinationTransactionFlowWithSkipQueueWithPreciseAmount(t *tes
 		LedgerID: "general_ledger_id",
 	}
 
-	destinationOne, err := ds.CreateBalance(*destinationBalanceOne)
+	destinationOne, err := ds.CreateBalance(ctx, *destinationBalanceOne)
 	require.NoError(t, err, "Failed to create destination balance one")
 
-	destinationTwo, err := ds.CreateBalance(*destinationBalanceTwo)
+	destinationTwo, err := ds.CreateBalance(ctx, *destinationBalanceTwo)
 	require.NoError(t, err, "Failed to create destination balance two")
 
-	source, err := ds.CreateBalance(*destBalance)
+	source, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction with skip_queue set to true
@@ -3155,13 +3155,13 @@ func TestMultipleDestinationTransactionFlowWithSkipQueueWithPreciseAmount(t *tes
 	require.Equal(t, StatusApplied, queuedTxn.Status, "Transaction should be APPLIED immediately when skip_queue is true")
 
 	// Verify balances were updated immediately
-	updatedDestinationOne, err := ds.GetBalanceByIDLite(destinationOne.BalanceID)
+	updatedDestinationOne, err := ds.GetBalanceByIDLite(ctx, destinationOne.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
-	updatedDestinationTwo, err := ds.GetBalanceByIDLite(destinationTwo.BalanceID)
+	updatedDestinationTwo, err := ds.GetBalanceByIDLite(ctx, destinationTwo.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
 	// Calculate expected balance changes
@@ -3225,9 +3225,9 @@ func TestQueueTransactionStatus(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(context.Background(), *sourceBalance)
 	require.NoError(t, err)
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(context.Background(), *destBalance)
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -3334,10 +3334,10 @@ func TestStandardTransactionRefund(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create a standard transaction with skip_queue set to true
@@ -3361,10 +3361,10 @@ func TestStandardTransactionRefund(t *testing.T) {
 	require.Equal(t, StatusApplied, originalTxn.Status, "Transaction should be APPLIED immediately with skip_queue enabled")
 
 	// Verify balances after initial transaction
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Calculate expected balance changes
@@ -3397,10 +3397,10 @@ func TestStandardTransactionRefund(t *testing.T) {
 	require.Equal(t, txnEntry.Source, refundTxn.Destination, "Refund destination should be original source")
 
 	// Verify balances after refund
-	sourceAfterRefund, err := ds.GetBalanceByIDLite(source.BalanceID)
+	sourceAfterRefund, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after refund")
 
-	destAfterRefund, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	destAfterRefund, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after refund")
 
 	// Balances should be back to where they started (zero)
@@ -3414,7 +3414,7 @@ func TestStandardTransactionRefund(t *testing.T) {
 		"Destination inflight balance should remain zero after refund")
 
 	// Verify refund transaction in transaction history
-	transactions, err := ds.GetTransactionsByParent(ctx, txnEntry.TransactionID, 10, 0)
+	transactions, err := ds.GetTransactionsByParent(ctx, txnEntry.TransactionID, model.Page{Limit: 10})
 	require.NoError(t, err, "Failed to get transaction history")
 	require.Equal(t, 1, len(transactions), "Should have exactly one child transaction (the refund)")
 	require.Equal(t, StatusApplied, transactions[0].Status, "Refund transaction should have APPLIED status")
@@ -3427,10 +3427,10 @@ func TestStandardTransactionRefund(t *testing.T) {
 	require.Equal(t, refundTxn.TransactionID, refundOfRefundTxn.ParentTransaction, "Refund of refund should reference refund as parent")
 
 	// Verify balances after refund of refund (should be back to the state after original transaction)
-	sourceAfterRefundOfRefund, err := ds.GetBalanceByIDLite(source.BalanceID)
+	sourceAfterRefundOfRefund, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after refund of refund")
 
-	destAfterRefundOfRefund, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	destAfterRefundOfRefund, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after refund of refund")
 
 	// Verify the balances are back to the post-original transaction state
@@ -3491,10 +3491,10 @@ func TestRejectTransaction(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create transaction to reject
@@ -3533,10 +3533,10 @@ func TestRejectTransaction(t *testing.T) {
 	require.Equal(t, rejectionReason, persistedTxn.MetaData["blnk_rejection_reason"], "Persisted rejection reason should match")
 
 	// Verify balances should be unaffected by rejected transaction
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Balances should be zero
@@ -3609,10 +3609,10 @@ func TestCommitWorkerFullFlow(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Step 1: Create an inflight transaction with skip_queue set to true
@@ -3636,10 +3636,10 @@ func TestCommitWorkerFullFlow(t *testing.T) {
 	require.Equal(t, StatusInflight, queuedTxn.Status, "Transaction should be INFLIGHT immediately when skip_queue is true")
 
 	// Step 3: Verify balances were updated immediately with inflight amounts
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Verify inflight balance changes
@@ -3703,10 +3703,10 @@ func TestCommitWorkerFullFlow(t *testing.T) {
 	require.Equal(t, StatusApplied, polledTxn.Status, "Committed transaction should have APPLIED status")
 
 	// Step 8: Verify final balances
-	finalSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	finalSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get final source balance")
 
-	finalDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	finalDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get final destination balance")
 
 	// Verify balance changes - amounts should move from inflight to main balances
@@ -3771,10 +3771,10 @@ func TestVoidWorkerFullFlow(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Step 1: Create an inflight transaction with skip_queue set to true
@@ -3798,10 +3798,10 @@ func TestVoidWorkerFullFlow(t *testing.T) {
 	require.Equal(t, StatusInflight, queuedTxn.Status, "Transaction should be INFLIGHT immediately when skip_queue is true")
 
 	// Step 3: Verify balances were updated immediately with inflight amounts
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Verify inflight balance changes
@@ -3861,10 +3861,10 @@ func TestVoidWorkerFullFlow(t *testing.T) {
 		"Voided transaction should reference the original as parent")
 
 	// Step 8: Verify final balances
-	finalSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	finalSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get final source balance")
 
-	finalDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	finalDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get final destination balance")
 
 	// Verify balance changes - inflight balances should be zeroed and main balances should remain unchanged
@@ -3952,10 +3952,10 @@ func TestRefundWorkerFullFlow(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Step 1: Create a standard (non-inflight) transaction with skip_queue set to true
@@ -3979,10 +3979,10 @@ func TestRefundWorkerFullFlow(t *testing.T) {
 	require.Equal(t, StatusApplied, queuedTxn.Status, "Transaction should be APPLIED immediately when skip_queue is true")
 
 	// Step 3: Verify balances were updated immediately with the transaction amounts
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
 
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Verify main balance changes
@@ -4049,10 +4049,10 @@ func TestRefundWorkerFullFlow(t *testing.T) {
 		"Refund transaction should swap source and destination")
 
 	// Step 8: Verify final balances
-	finalSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	finalSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get final source balance")
 
-	finalDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	finalDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get final destination balance")
 
 	// Verify balances are now back to zero (original amount - refund = 0)
@@ -4165,10 +4165,10 @@ func TestRefundAlreadyRefundedTransaction(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Create a standard transaction
@@ -4191,9 +4191,9 @@ func TestRefundAlreadyRefundedTransaction(t *testing.T) {
 	require.Equal(t, StatusApplied, originalTxn.Status, "Transaction should be APPLIED")
 
 	// Verify balances after initial transaction
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 	expectedDebit := big.NewInt(int64(-originalAmount) * 100)
 	expectedCredit := big.NewInt(int64(originalAmount) * 100)
@@ -4210,9 +4210,9 @@ func TestRefundAlreadyRefundedTransaction(t *testing.T) {
 	require.Equal(t, StatusApplied, refundTxn.Status, "First refund transaction should be APPLIED")
 
 	// Verify balances are back to zero after the first refund
-	sourceAfterFirstRefund, err := ds.GetBalanceByIDLite(source.BalanceID)
+	sourceAfterFirstRefund, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after first refund")
-	destAfterFirstRefund, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	destAfterFirstRefund, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after first refund")
 	require.Equal(t, 0, sourceAfterFirstRefund.Balance.Cmp(big.NewInt(0)), "Source balance should be zero after first refund")
 	require.Equal(t, 0, destAfterFirstRefund.Balance.Cmp(big.NewInt(0)), "Destination balance should be zero after first refund")
@@ -4224,9 +4224,9 @@ func TestRefundAlreadyRefundedTransaction(t *testing.T) {
 	require.Contains(t, err.Error(), "has already been refunded", "Error message should indicate the transaction was already refunded")
 
 	// Verify balances remain unchanged after the failed second refund attempt
-	sourceAfterSecondAttempt, err := ds.GetBalanceByIDLite(source.BalanceID)
+	sourceAfterSecondAttempt, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after second refund attempt")
-	destAfterSecondAttempt, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	destAfterSecondAttempt, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get destination balance after second refund attempt")
 	require.Equal(t, 0, sourceAfterSecondAttempt.Balance.Cmp(big.NewInt(0)), "Source balance should still be zero after failed second refund")
 	require.Equal(t, 0, destAfterSecondAttempt.Balance.Cmp(big.NewInt(0)), "Destination balance should still be zero after failed second refund")
@@ -4289,9 +4289,9 @@ func TestQueueTransactionFlowAsyncPolled(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	txn := &model.Transaction{
@@ -4345,9 +4345,9 @@ func TestQueueTransactionFlowAsyncPolled(t *testing.T) {
 	require.Equal(t, StatusApplied, appliedTxn.Status, "Processed transaction status should be APPLIED")
 	require.Equal(t, queuedTxn.TransactionID, appliedTxn.ParentTransaction, "Applied transaction's parent ID should match original queued transaction ID")
 
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get updated source balance")
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	expectedDebit := big.NewInt(int64(-500) * 100)
@@ -4505,9 +4505,9 @@ func TestInflightTransactionFlowAsyncPolled(t *testing.T) {
 	sourceBalance := &model.Balance{Currency: "USD", LedgerID: "general_ledger_id"}
 	destBalance := &model.Balance{Currency: "USD", LedgerID: "general_ledger_id"}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err)
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err)
 
 	txn := &model.Transaction{
@@ -4550,9 +4550,9 @@ func TestInflightTransactionFlowAsyncPolled(t *testing.T) {
 	require.Equal(t, queuedTxn.TransactionID, inflightTxn.ParentTransaction, "INFLIGHT transaction's parent ID should match original queued transaction ID")
 
 	// Verify inflight balances
-	updatedSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	updatedSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err)
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err)
 
 	expectedInflightDebit := big.NewInt(int64(-500) * 100)
@@ -4583,8 +4583,8 @@ func TestInflightTransactionFlowAsyncPolled(t *testing.T) {
 	require.Equal(t, inflightTxn.TransactionID, appliedCommitTxn1.ParentTransaction, "First applied commit parent ID mismatch")
 
 	// Verify balances after first partial commit
-	sourceAfterPartial1, _ := ds.GetBalanceByIDLite(source.BalanceID)
-	destAfterPartial1, _ := ds.GetBalanceByIDLite(dest.BalanceID)
+	sourceAfterPartial1, _ := ds.GetBalanceByIDLite(ctx, source.BalanceID)
+	destAfterPartial1, _ := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 
 	expectedSourceBalanceAfter1 := big.NewInt(0).Sub(big.NewInt(0), partialAmount1Precise)          // -10000
 	expectedDestBalanceAfter1 := partialAmount1Precise                                              //  10000
@@ -4611,8 +4611,8 @@ func TestInflightTransactionFlowAsyncPolled(t *testing.T) {
 	require.Equal(t, inflightTxn.TransactionID, appliedCommitTxn2.ParentTransaction, "Second applied commit parent ID mismatch")
 
 	// Verify balances after second partial commit
-	sourceAfterPartial2, _ := ds.GetBalanceByIDLite(source.BalanceID)
-	destAfterPartial2, _ := ds.GetBalanceByIDLite(dest.BalanceID)
+	sourceAfterPartial2, _ := ds.GetBalanceByIDLite(ctx, source.BalanceID)
+	destAfterPartial2, _ := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 
 	totalCommittedAfter2 := big.NewInt(0).Add(partialAmount1Precise, partialAmount2Precise)        // 10000 + 15000 = 25000
 	expectedSourceBalanceAfter2 := big.NewInt(0).Sub(big.NewInt(0), totalCommittedAfter2)          // -25000
@@ -4641,8 +4641,8 @@ func TestInflightTransactionFlowAsyncPolled(t *testing.T) {
 	require.Equal(t, inflightTxn.TransactionID, appliedCommitTxn3.ParentTransaction, "Third applied commit parent ID mismatch")
 
 	// Verify final balances after all commits
-	finalSource, _ := ds.GetBalanceByIDLite(source.BalanceID)
-	finalDest, _ := ds.GetBalanceByIDLite(dest.BalanceID)
+	finalSource, _ := ds.GetBalanceByIDLite(ctx, source.BalanceID)
+	finalDest, _ := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 
 	// Balances should now reflect the full originalAmount, and inflight should be zero.
 	// originalAmountPrecise = 50000
@@ -4715,11 +4715,11 @@ func TestMultipleSourcesTransactionFlowAsyncPolled(t *testing.T) {
 	sourceBalanceTwo := &model.Balance{Currency: "USD", LedgerID: "general_ledger_id"}
 	destBalance := &model.Balance{Currency: "USD", LedgerID: "general_ledger_id"}
 
-	sourceOne, err := ds.CreateBalance(*sourceBalanceOne)
+	sourceOne, err := ds.CreateBalance(ctx, *sourceBalanceOne)
 	require.NoError(t, err)
-	sourceTwo, err := ds.CreateBalance(*sourceBalanceTwo)
+	sourceTwo, err := ds.CreateBalance(ctx, *sourceBalanceTwo)
 	require.NoError(t, err)
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err)
 
 	txn := &model.Transaction{
@@ -4761,9 +4761,9 @@ func TestMultipleSourcesTransactionFlowAsyncPolled(t *testing.T) {
 	require.Equal(t, StatusApplied, appliedChild2.Status)
 
 	// Verify balances
-	updatedSourceOne, _ := ds.GetBalanceByIDLite(sourceOne.BalanceID)
-	updatedSourceTwo, _ := ds.GetBalanceByIDLite(sourceTwo.BalanceID)
-	updatedDest, _ := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedSourceOne, _ := ds.GetBalanceByIDLite(ctx, sourceOne.BalanceID)
+	updatedSourceTwo, _ := ds.GetBalanceByIDLite(ctx, sourceTwo.BalanceID)
+	updatedDest, _ := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 
 	expectedDebitEachSource := big.NewInt(int64(-250) * 100) // 50% of 500
 	expectedCreditDest := big.NewInt(int64(500) * 100)
@@ -4824,11 +4824,11 @@ func TestMultipleDestinationsTransactionFlowAsyncPolled(t *testing.T) {
 	destBalanceOne := &model.Balance{Currency: "USD", LedgerID: "general_ledger_id"}
 	destBalanceTwo := &model.Balance{Currency: "USD", LedgerID: "general_ledger_id"}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err)
-	destOne, err := ds.CreateBalance(*destBalanceOne)
+	destOne, err := ds.CreateBalance(ctx, *destBalanceOne)
 	require.NoError(t, err)
-	destTwo, err := ds.CreateBalance(*destBalanceTwo)
+	destTwo, err := ds.CreateBalance(ctx, *destBalanceTwo)
 	require.NoError(t, err)
 
 	txn := &model.Transaction{
@@ -4870,9 +4870,9 @@ func TestMultipleDestinationsTransactionFlowAsyncPolled(t *testing.T) {
 	require.Equal(t, StatusApplied, appliedChild2.Status)
 
 	// Verify balances
-	updatedSource, _ := ds.GetBalanceByIDLite(source.BalanceID)
-	updatedDestOne, _ := ds.GetBalanceByIDLite(destOne.BalanceID)
-	updatedDestTwo, _ := ds.GetBalanceByIDLite(destTwo.BalanceID)
+	updatedSource, _ := ds.GetBalanceByIDLite(ctx, source.BalanceID)
+	updatedDestOne, _ := ds.GetBalanceByIDLite(ctx, destOne.BalanceID)
+	updatedDestTwo, _ := ds.GetBalanceByIDLite(ctx, destTwo.BalanceID)
 
 	expectedDebitSource := big.NewInt(int64(-500) * 100)
 	expectedCreditEachDest := big.NewInt(int64(250) * 100) // 50% of 500
@@ -4927,11 +4927,11 @@ func TestDiscardZeroAmountTransaction_MultiSource_SkipQueue(t *testing.T) {
 	sourceBalanceTwo := &model.Balance{Currency: "USD", LedgerID: "general_ledger_id"}
 	destBalance := &model.Balance{Currency: "USD", LedgerID: "general_ledger_id"}
 
-	sourceOne, err := ds.CreateBalance(*sourceBalanceOne)
+	sourceOne, err := ds.CreateBalance(ctx, *sourceBalanceOne)
 	require.NoError(t, err, "Failed to create source balance one")
-	sourceTwo, err := ds.CreateBalance(*sourceBalanceTwo)
+	sourceTwo, err := ds.CreateBalance(ctx, *sourceBalanceTwo)
 	require.NoError(t, err, "Failed to create source balance two")
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Define transaction: Amount 1, SourceOne takes 100% (Amount 1), SourceTwo takes "left" (Amount 0)
@@ -4958,11 +4958,11 @@ func TestDiscardZeroAmountTransaction_MultiSource_SkipQueue(t *testing.T) {
 	require.Equal(t, StatusApplied, queuedTxn.Status, "Overall transaction status should be APPLIED")
 
 	// Verify balances
-	updatedSourceOne, err := ds.GetBalanceByIDLite(sourceOne.BalanceID)
+	updatedSourceOne, err := ds.GetBalanceByIDLite(ctx, sourceOne.BalanceID)
 	require.NoError(t, err, "Failed to get updated source one balance")
-	updatedSourceTwo, err := ds.GetBalanceByIDLite(sourceTwo.BalanceID)
+	updatedSourceTwo, err := ds.GetBalanceByIDLite(ctx, sourceTwo.BalanceID)
 	require.NoError(t, err, "Failed to get updated source two balance")
-	updatedDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	updatedDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get updated destination balance")
 
 	// Expected balance changes
@@ -5060,14 +5060,14 @@ func TestInflightTransactionWithOverdraftOnCommit(t *testing.T) {
 		LedgerID: "general_ledger_id",
 	}
 
-	source, err := ds.CreateBalance(*sourceBalance)
+	source, err := ds.CreateBalance(ctx, *sourceBalance)
 	require.NoError(t, err, "Failed to create source balance")
 
-	dest, err := ds.CreateBalance(*destBalance)
+	dest, err := ds.CreateBalance(ctx, *destBalance)
 	require.NoError(t, err, "Failed to create destination balance")
 
 	// Verify source balance after deposit
-	sourceAfterDeposit, err := ds.GetBalanceByIDLite(source.BalanceID)
+	sourceAfterDeposit, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get source balance after deposit")
 
 	expectedSourceBalance := big.NewInt(0)
@@ -5122,10 +5122,10 @@ func TestInflightTransactionWithOverdraftOnCommit(t *testing.T) {
 	}
 
 	// Step 5: Verify balances remain unchanged after attempting to commit rejected transaction
-	finalSource, err := ds.GetBalanceByIDLite(source.BalanceID)
+	finalSource, err := ds.GetBalanceByIDLite(ctx, source.BalanceID)
 	require.NoError(t, err, "Failed to get final source balance")
 
-	finalDest, err := ds.GetBalanceByIDLite(dest.BalanceID)
+	finalDest, err := ds.GetBalanceByIDLite(ctx, dest.BalanceID)
 	require.NoError(t, err, "Failed to get final destination balance")
 
 	// Source should still have its initial balance of 500
@@ -0,0 +1,39 @@
+package blnk
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+)
+
+// validateTransactionTime checks that the time a client recorded a transaction at is within the configured
+// skew of the server's time, which is the transaction's creation time. Transactions without a client time
+// are not checked.
+//
+// Parameters:
+// - transaction *model.Transaction: The transaction to check. Its creation time must already be set.
+//
+// Returns:
+// - error: An error if the client time is further from the server's time than the configured skew allows.
+func validateTransactionTime(transaction *model.Transaction) error {
+	if transaction.TransactionTime == nil {
+		return nil
+	}
+	cnf, err := config.Fetch()
+	if err != nil {
+		return err
+	}
+
+	clientTime := transaction.TransactionTime.UTC()
+	transaction.TransactionTime = &clientTime
+	skew := transaction.CreatedAt.Sub(clientTime)
+	if skew > cnf.Transaction.MaxTimeSkewPast {
+		return fmt.Errorf("transaction_time %s is more than %s before the server time", clientTime.Format(time.RFC3339), cnf.Transaction.MaxTimeSkewPast)
+	}
+	if -skew > cnf.Transaction.MaxTimeSkewFuture {
+		return fmt.Errorf("transaction_time %s is more than %s after the server time", clientTime.Format(time.RFC3339), cnf.Transaction.MaxTimeSkewFuture)
+	}
+	return nil
+}
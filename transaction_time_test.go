@@ -0,0 +1,74 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTransactionTime(t *testing.T) {
+	config.ConfigStore.Store(&config.Configuration{
+		Transaction: config.TransactionConfig{MaxTimeSkewPast: 24 * time.Hour, MaxTimeSkewFuture: 5 * time.Minute},
+	})
+	serverTime := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		clientTime *time.Time
+		wantErr    string
+	}{
+		{name: "no client time"},
+		{name: "captured offline within the window", clientTime: timePtr(serverTime.Add(-23 * time.Hour))},
+		{name: "client clock slightly ahead", clientTime: timePtr(serverTime.Add(4 * time.Minute))},
+		{name: "too old", clientTime: timePtr(serverTime.Add(-25 * time.Hour)), wantErr: "before the server time"},
+		{name: "too far ahead", clientTime: timePtr(serverTime.Add(10 * time.Minute)), wantErr: "after the server time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn := &model.Transaction{CreatedAt: serverTime, TransactionTime: tt.clientTime}
+			err := validateTransactionTime(txn)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateTransactionTime_NormalizesToUTC(t *testing.T) {
+	config.ConfigStore.Store(&config.Configuration{
+		Transaction: config.TransactionConfig{MaxTimeSkewPast: time.Hour, MaxTimeSkewFuture: time.Minute},
+	})
+	serverTime := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	clientTime := time.Date(2026, 3, 2, 12, 30, 0, 0, time.FixedZone("WAT", 3600))
+
+	txn := &model.Transaction{CreatedAt: serverTime, TransactionTime: &clientTime}
+	assert.NoError(t, validateTransactionTime(txn))
+	assert.Equal(t, time.UTC, txn.TransactionTime.Location())
+	assert.True(t, txn.TransactionTime.Equal(clientTime))
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
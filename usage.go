@@ -0,0 +1,247 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	usageKeyPrefix      = "usage"
+	usageDateLayout     = "2006-01-02"
+	usageRetention      = 400 * 24 * time.Hour
+	usageMaxQueryDays   = 366
+	usageDefaultTenant  = "default"
+	usageExportedMarker = "usage:exported"
+
+	usageFieldAPICalls     = "api_calls"
+	usageFieldTransactions = "transactions_posted"
+	usageFieldStorage      = "storage_bytes"
+)
+
+// usageTenant returns the tenant the usage in ctx is attributed to. Requests made with the master key,
+// or with authentication disabled, are attributed to the default tenant.
+func usageTenant(ctx context.Context) string {
+	if t := tenant.FromContext(ctx); t != "" {
+		return t
+	}
+	return usageDefaultTenant
+}
+
+// incrementUsage adds to the usage counters of a tenant and ledger for the day of at.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - tenantID string: The tenant the usage belongs to.
+// - ledgerID string: The ledger the usage belongs to, or empty.
+// - at time.Time: When the usage occurred.
+// - counters map[string]int64: The amounts to add, keyed by usage field.
+//
+// Returns:
+// - error: An error if the counters could not be updated.
+func (l *Blnk) incrementUsage(ctx context.Context, tenantID, ledgerID string, at time.Time, counters map[string]int64) error {
+	date := at.UTC().Format(usageDateLayout)
+	key := fmt.Sprintf("%s:%s:%s:%s", usageKeyPrefix, date, tenantID, ledgerID)
+	indexKey := fmt.Sprintf("%s:index:%s", usageKeyPrefix, date)
+
+	pipe := l.redis.TxPipeline()
+	for field, value := range counters {
+		pipe.HIncrBy(ctx, key, field, value)
+	}
+	pipe.Expire(ctx, key, usageRetention)
+	pipe.SAdd(ctx, indexKey, tenantID+"|"+ledgerID)
+	pipe.Expire(ctx, indexKey, usageRetention)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RecordAPICall meters one API call for the tenant in ctx.
+//
+// Parameters:
+// - ctx context.Context: The request context, carrying the tenant.
+//
+// Returns:
+// - error: An error if the call could not be recorded.
+func (l *Blnk) RecordAPICall(ctx context.Context) error {
+	return l.incrementUsage(ctx, usageTenant(ctx), "", time.Now(), map[string]int64{usageFieldAPICalls: 1})
+}
+
+// recordTransactionUsage meters a posted transaction against the tenant in ctx and the ledger of its source balance.
+// Storage is measured as the encoded size of the transaction record. Metering never fails the posting; errors are logged.
+//
+// Parameters:
+// - ctx context.Context: The context carrying the tenant.
+// - transaction *model.Transaction: The posted transaction.
+func (l *Blnk) recordTransactionUsage(ctx context.Context, transaction *model.Transaction) {
+	if l.redis == nil {
+		return
+	}
+	tenantID := usageTenant(ctx)
+	txn := *transaction
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ledgerID := l.ledgerOfBalance(ctx, txn.Source)
+		if ledgerID == "" {
+			ledgerID = l.ledgerOfBalance(ctx, txn.Destination)
+		}
+
+		size := 0
+		if data, err := json.Marshal(txn); err == nil {
+			size = len(data)
+		}
+
+		err := l.incrementUsage(ctx, tenantID, ledgerID, time.Now(), map[string]int64{
+			usageFieldTransactions: 1,
+			usageFieldStorage:      int64(size),
+		})
+		if err != nil {
+			logrus.Errorf("failed to record transaction usage: %v", err)
+		}
+	}()
+}
+
+// ledgerOfBalance returns the ledger a balance belongs to, or an empty string if it cannot be resolved.
+func (l *Blnk) ledgerOfBalance(ctx context.Context, balanceID string) string {
+	if balanceID == "" || strings.HasPrefix(balanceID, "@") {
+		return ""
+	}
+	balance, err := l.datasource.GetBalanceByIDLite(ctx, balanceID)
+	if err != nil || balance == nil {
+		return ""
+	}
+	return balance.LedgerID
+}
+
+// GetUsage returns the daily usage records matching the filter, ordered by date, tenant and ledger.
+// When From or To is empty the range defaults to the current day.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - filter model.UsageFilter: The tenant, ledger and date range to return.
+//
+// Returns:
+// - []model.UsageRecord: The matching usage records.
+// - error: An error if the range is invalid or the records could not be read.
+func (l *Blnk) GetUsage(ctx context.Context, filter model.UsageFilter) ([]model.UsageRecord, error) {
+	today := time.Now().UTC().Format(usageDateLayout)
+	if filter.From == "" {
+		filter.From = today
+	}
+	if filter.To == "" {
+		filter.To = today
+	}
+	from, err := time.Parse(usageDateLayout, filter.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date: %w", err)
+	}
+	to, err := time.Parse(usageDateLayout, filter.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date: %w", err)
+	}
+	if to.Before(from) {
+		return nil, errors.New("to date must not be before from date")
+	}
+	if to.Sub(from) > usageMaxQueryDays*24*time.Hour {
+		return nil, fmt.Errorf("date range must not exceed %d days", usageMaxQueryDays)
+	}
+
+	records := []model.UsageRecord{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		dayRecords, err := l.usageForDay(ctx, day.Format(usageDateLayout), filter)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, dayRecords...)
+	}
+	return records, nil
+}
+
+// usageForDay reads the usage records of a single day that match the filter's tenant and ledger.
+func (l *Blnk) usageForDay(ctx context.Context, date string, filter model.UsageFilter) ([]model.UsageRecord, error) {
+	members, err := l.redis.SMembers(ctx, fmt.Sprintf("%s:index:%s", usageKeyPrefix, date)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(members)
+
+	records := []model.UsageRecord{}
+	for _, member := range members {
+		tenantID, ledgerID, _ := strings.Cut(member, "|")
+		if filter.Tenant != "" && filter.Tenant != tenantID {
+			continue
+		}
+		if filter.LedgerID != "" && filter.LedgerID != ledgerID {
+			continue
+		}
+
+		values, err := l.redis.HGetAll(ctx, fmt.Sprintf("%s:%s:%s:%s", usageKeyPrefix, date, tenantID, ledgerID)).Result()
+		if err != nil {
+			return nil, err
+		}
+		record := model.UsageRecord{Date: date, Tenant: tenantID, LedgerID: ledgerID}
+		_, _ = fmt.Sscan(values[usageFieldAPICalls], &record.APICalls)
+		_, _ = fmt.Sscan(values[usageFieldTransactions], &record.TransactionsPosted)
+		_, _ = fmt.Sscan(values[usageFieldStorage], &record.StorageBytes)
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// ExportDailyUsage publishes the usage records of a completed day as "usage.daily" events on the webhook stream.
+// Each day is exported at most once; repeated calls for the same day are no-ops.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - day time.Time: The day to export.
+//
+// Returns:
+// - int: The number of usage records exported.
+// - error: An error if the records could not be read or published.
+func (l *Blnk) ExportDailyUsage(ctx context.Context, day time.Time) (int, error) {
+	date := day.UTC().Format(usageDateLayout)
+	claimed, err := l.redis.SetNX(ctx, fmt.Sprintf("%s:%s", usageExportedMarker, date), time.Now().Unix(), usageRetention).Result()
+	if err != nil {
+		return 0, err
+	}
+	if !claimed {
+		return 0, nil
+	}
+
+	markerKey := fmt.Sprintf("%s:%s", usageExportedMarker, date)
+	records, err := l.usageForDay(ctx, date, model.UsageFilter{})
+	if err != nil {
+		l.redis.Del(ctx, markerKey)
+		return 0, err
+	}
+	for _, record := range records {
+		if err := l.SendWebhook(NewWebhook{Event: "usage.daily", Payload: record}); err != nil {
+			l.redis.Del(ctx, markerKey)
+			return 0, err
+		}
+	}
+	return len(records), nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/internal/tenant"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageMetering(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis: config.RedisConfig{Dns: mr.Addr()},
+		Queue: config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+	})
+
+	b, err := NewBlnk(new(mocks.MockDataSource))
+	assert.NoError(t, err)
+	ctx := context.Background()
+	now := time.Now()
+	today := now.UTC().Format(usageDateLayout)
+
+	assert.NoError(t, b.RecordAPICall(tenant.WithTenant(ctx, "team_a")))
+	assert.NoError(t, b.RecordAPICall(tenant.WithTenant(ctx, "team_a")))
+	assert.NoError(t, b.RecordAPICall(ctx))
+	assert.NoError(t, b.incrementUsage(ctx, "team_a", "ldg_1", now, map[string]int64{usageFieldTransactions: 1, usageFieldStorage: 120}))
+
+	records, err := b.GetUsage(ctx, model.UsageFilter{Tenant: "team_a"})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.UsageRecord{
+		{Date: today, Tenant: "team_a", APICalls: 2},
+		{Date: today, Tenant: "team_a", LedgerID: "ldg_1", TransactionsPosted: 1, StorageBytes: 120},
+	}, records)
+
+	records, err = b.GetUsage(ctx, model.UsageFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, usageDefaultTenant, records[0].Tenant)
+
+	_, err = b.GetUsage(ctx, model.UsageFilter{From: "2024-02-01", To: "2024-01-01"})
+	assert.Error(t, err)
+
+	exported, err := b.ExportDailyUsage(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, exported)
+
+	exported, err = b.ExportDailyUsage(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exported)
+}
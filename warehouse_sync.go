@@ -0,0 +1,186 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	redlock "github.com/blnkfinance/blnk/internal/lock"
+	"github.com/blnkfinance/blnk/internal/warehouse"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+const warehouseSyncLockTimeout = 30 * time.Minute
+
+// newWarehouseSink returns the sink of the configured warehouse. It is a variable so tests can replace the
+// warehouse.
+var newWarehouseSink = func(cnf *config.Configuration) (warehouse.Sink, error) {
+	return warehouse.New(cnf)
+}
+
+// RunWarehouseSync exports the transactions, balances and identities written since the last sync to the
+// configured warehouse. Each entity is read in batches after its watermark, the updated_at and ID of the last
+// row exported, and the watermark is saved after every batch the warehouse accepts, so a failed sync resumes
+// where it stopped. Rows written within the settle delay are left for the next sync. A failure stops the sync
+// of that entity only.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - *model.WarehouseSyncRun: What was exported for each entity.
+// - error: An error if the sync could not start or an entity could not be exported.
+func (l *Blnk) RunWarehouseSync(ctx context.Context) (*model.WarehouseSyncRun, error) {
+	ctx, span := tracer.Start(ctx, "RunWarehouseSync")
+	defer span.End()
+
+	cnf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	if cnf.Warehouse.Provider == "" {
+		return nil, errors.New("warehouse sync is not configured")
+	}
+
+	locker := redlock.NewLocker(l.redis, "warehouse-sync", model.GenerateUUIDWithSuffix("loc"))
+	if err := locker.Lock(ctx, warehouseSyncLockTimeout); err != nil {
+		return nil, fmt.Errorf("failed to acquire lock for warehouse sync: %w", err)
+	}
+	defer l.releaseLock(ctx, locker)
+
+	sink, err := newWarehouseSink(cnf)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer func() {
+		if err := sink.Close(); err != nil {
+			logrus.Warnf("failed to close %s warehouse: %v", sink.Name(), err)
+		}
+	}()
+
+	run := &model.WarehouseSyncRun{Provider: sink.Name(), StartedAt: time.Now().UTC(), Entities: []model.WarehouseEntitySync{}}
+	until := run.StartedAt.Add(-cnf.Warehouse.SettleDelay)
+	var errs []error
+	for _, entity := range model.WarehouseEntities {
+		table := cnf.Warehouse.Tables[entity]
+		if table.Disabled {
+			continue
+		}
+		synced, err := l.syncWarehouseEntity(ctx, sink, cnf.Warehouse, entity, until)
+		if err != nil {
+			span.RecordError(err)
+			synced.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", entity, err))
+		}
+		run.Entities = append(run.Entities, synced)
+	}
+	run.CompletedAt = time.Now().UTC()
+	return run, errors.Join(errs...)
+}
+
+// GetWarehouseSyncStates lists how far each entity has been exported to the warehouse and how its last sync
+// went.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - []*model.WarehouseSyncState: The state of each entity that has been synced.
+// - error: An error if the states could not be retrieved.
+func (l *Blnk) GetWarehouseSyncStates(ctx context.Context) ([]*model.WarehouseSyncState, error) {
+	return l.datasource.ListWarehouseSyncStates(ctx)
+}
+
+// syncWarehouseEntity exports the rows of an entity written since its watermark and before until.
+func (l *Blnk) syncWarehouseEntity(ctx context.Context, sink warehouse.Sink, cfg config.WarehouseConfig, entity string, until time.Time) (model.WarehouseEntitySync, error) {
+	table := cfg.Tables[entity]
+	synced := model.WarehouseEntitySync{Entity: entity, Table: warehouseTableName(entity, table)}
+
+	state, err := l.datasource.GetWarehouseSyncState(ctx, entity)
+	if err != nil {
+		return synced, err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return synced, err
+		}
+		batch, err := l.datasource.GetWarehouseBatch(ctx, entity, state.Watermark, until, cfg.BatchSize)
+		if err != nil {
+			return synced, l.saveWarehouseSyncFailure(ctx, state, err)
+		}
+		if len(batch.Rows) == 0 {
+			break
+		}
+		if err := sink.Write(ctx, mapWarehouseBatch(batch, synced.Table, table)); err != nil {
+			return synced, l.saveWarehouseSyncFailure(ctx, state, err)
+		}
+
+		state.Watermark = batch.Last
+		state.RowsSynced += int64(len(batch.Rows))
+		state.LastError = ""
+		if err := l.datasource.SaveWarehouseSyncState(ctx, state); err != nil {
+			return synced, err
+		}
+		synced.Rows += len(batch.Rows)
+		synced.Batches++
+		if len(batch.Rows) < cfg.BatchSize {
+			break
+		}
+	}
+
+	now := time.Now().UTC()
+	state.LastSyncedAt = &now
+	state.LastError = ""
+	return synced, l.datasource.SaveWarehouseSyncState(ctx, state)
+}
+
+// saveWarehouseSyncFailure records the failure of an entity's sync and returns it. The watermark is kept, so
+// the next sync retries the batch that failed.
+func (l *Blnk) saveWarehouseSyncFailure(ctx context.Context, state *model.WarehouseSyncState, cause error) error {
+	state.LastError = cause.Error()
+	if err := l.datasource.SaveWarehouseSyncState(ctx, state); err != nil {
+		logrus.Errorf("failed to save warehouse sync state of %s: %v", state.Entity, err)
+	}
+	return cause
+}
+
+// warehouseTableName returns the table an entity is written to, which defaults to the name of the entity.
+func warehouseTableName(entity string, table config.WarehouseTableConfig) string {
+	if table.Table != "" {
+		return table.Table
+	}
+	return entity
+}
+
+// mapWarehouseBatch applies the schema mapping of a table to a batch: excluded columns are dropped and the
+// rest are renamed as configured.
+func mapWarehouseBatch(batch *model.WarehouseBatch, name string, table config.WarehouseTableConfig) warehouse.Batch {
+	var keep []int
+	mapped := warehouse.Batch{Table: name, RowIDs: batch.RowIDs}
+	for i, column := range batch.Columns {
+		if slices.Contains(table.Exclude, column) {
+			continue
+		}
+		keep = append(keep, i)
+		if renamed, ok := table.Columns[column]; ok && renamed != "" {
+			column = renamed
+		}
+		mapped.Columns = append(mapped.Columns, column)
+	}
+
+	mapped.Rows = make([][]interface{}, len(batch.Rows))
+	for r, row := range batch.Rows {
+		values := make([]interface{}, len(keep))
+		for j, i := range keep {
+			values[j] = row[i]
+		}
+		mapped.Rows[r] = values
+	}
+	return mapped
+}
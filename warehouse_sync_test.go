@@ -0,0 +1,115 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/warehouse"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWarehouseSink struct {
+	batches []warehouse.Batch
+	err     error
+}
+
+func (s *fakeWarehouseSink) Name() string { return "fake" }
+
+func (s *fakeWarehouseSink) Write(_ context.Context, batch warehouse.Batch) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *fakeWarehouseSink) Close() error { return nil }
+
+func useFakeWarehouseSink(t *testing.T, sink *fakeWarehouseSink) {
+	previous := newWarehouseSink
+	newWarehouseSink = func(*config.Configuration) (warehouse.Sink, error) { return sink, nil }
+	t.Cleanup(func() { newWarehouseSink = previous })
+}
+
+func TestRunWarehouseSync(t *testing.T) {
+	b, mockDS := newBalanceShardTestBlnk(t)
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.Warehouse = config.WarehouseConfig{
+		Provider:  "bigquery",
+		BatchSize: 2,
+		Tables: map[string]config.WarehouseTableConfig{
+			model.WarehouseTransactions: {Table: "ledger_transactions", Columns: map[string]string{"transaction_id": "id"}, Exclude: []string{"meta_data"}},
+			model.WarehouseBalances:     {Disabled: true},
+		},
+	}
+	sink := &fakeWarehouseSink{}
+	useFakeWarehouseSink(t, sink)
+
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	first := &model.WarehouseBatch{
+		Entity:  model.WarehouseTransactions,
+		Columns: []string{"transaction_id", "meta_data", "updated_at"},
+		Rows:    [][]interface{}{{"txn_1", "{}", at}, {"txn_2", "{}", at}},
+		RowIDs:  []string{"txn_1@1", "txn_2@1"},
+		Last:    model.WarehouseWatermark{At: at, ID: "txn_2"},
+	}
+	second := &model.WarehouseBatch{Entity: model.WarehouseTransactions, Columns: first.Columns, Rows: [][]interface{}{}, Last: first.Last}
+
+	mockDS.On("GetWarehouseSyncState", mock.Anything, model.WarehouseTransactions).Return(&model.WarehouseSyncState{Entity: model.WarehouseTransactions}, nil)
+	mockDS.On("GetWarehouseBatch", mock.Anything, model.WarehouseTransactions, model.WarehouseWatermark{}, mock.Anything, 2).Return(first, nil).Once()
+	mockDS.On("GetWarehouseBatch", mock.Anything, model.WarehouseTransactions, first.Last, mock.Anything, 2).Return(second, nil).Once()
+	mockDS.On("GetWarehouseSyncState", mock.Anything, model.WarehouseIdentities).Return(&model.WarehouseSyncState{Entity: model.WarehouseIdentities}, nil)
+	mockDS.On("GetWarehouseBatch", mock.Anything, model.WarehouseIdentities, model.WarehouseWatermark{}, mock.Anything, 2).Return(nil, errors.New("connection reset"))
+
+	var saved []model.WarehouseSyncState
+	mockDS.On("SaveWarehouseSyncState", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = append(saved, *args.Get(1).(*model.WarehouseSyncState))
+	}).Return(nil)
+
+	run, err := b.RunWarehouseSync(context.Background())
+	assert.ErrorContains(t, err, "identities: connection reset")
+	require.Len(t, run.Entities, 2)
+	assert.Equal(t, model.WarehouseEntitySync{Entity: model.WarehouseTransactions, Table: "ledger_transactions", Rows: 2, Batches: 1}, run.Entities[0])
+	assert.Equal(t, "connection reset", run.Entities[1].Error)
+
+	require.Len(t, sink.batches, 1)
+	assert.Equal(t, "ledger_transactions", sink.batches[0].Table)
+	assert.Equal(t, []string{"id", "updated_at"}, sink.batches[0].Columns)
+	assert.Equal(t, [][]interface{}{{"txn_1", at}, {"txn_2", at}}, sink.batches[0].Rows)
+
+	require.Len(t, saved, 3)
+	assert.Equal(t, first.Last, saved[0].Watermark)
+	assert.Equal(t, int64(2), saved[0].RowsSynced)
+	assert.NotNil(t, saved[1].LastSyncedAt)
+	assert.Equal(t, "connection reset", saved[2].LastError)
+}
+
+func TestRunWarehouseSync_NotConfigured(t *testing.T) {
+	b, _ := newBalanceShardTestBlnk(t)
+
+	_, err := b.RunWarehouseSync(context.Background())
+	assert.ErrorContains(t, err, "not configured")
+}
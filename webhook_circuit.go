@@ -0,0 +1,417 @@
+package blnk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/internal/notification"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	webhookCircuitKeyPrefix = "webhook-circuit"
+	webhookCircuitIndexKey  = "webhook-circuits"
+	webhookProbeLockTTL     = time.Minute
+)
+
+// webhookStatusError is returned when a webhook endpoint answers a delivery with a status outside the 2XX range.
+type webhookStatusError struct {
+	StatusCode int
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook endpoint responded with status %d", e.StatusCode)
+}
+
+// webhookEndpointID returns the ID of a webhook endpoint, used in its Redis keys instead of the URL.
+func webhookEndpointID(endpoint string) string {
+	sum := sha256.Sum256([]byte(endpoint))
+	return hex.EncodeToString(sum[:8])
+}
+
+// webhookCircuitKey returns the Redis key of an endpoint's circuit, or of one of its parts when suffix is set.
+func webhookCircuitKey(endpointID, suffix string) string {
+	key := fmt.Sprintf("%s:%s", webhookCircuitKeyPrefix, endpointID)
+	if suffix != "" {
+		key += ":" + suffix
+	}
+	return key
+}
+
+// loadWebhookCircuit loads a stored circuit. It returns nil when the endpoint has no stored circuit.
+func (l *Blnk) loadWebhookCircuit(ctx context.Context, endpointID string) (*model.WebhookCircuit, error) {
+	data, err := l.redis.Get(ctx, webhookCircuitKey(endpointID, "")).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var circuit model.WebhookCircuit
+	if err := json.Unmarshal(data, &circuit); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal webhook circuit: %w", err)
+	}
+	return &circuit, nil
+}
+
+// getWebhookCircuit returns the circuit of an endpoint. Endpoints without a stored circuit are closed.
+func (l *Blnk) getWebhookCircuit(ctx context.Context, endpoint string) (*model.WebhookCircuit, error) {
+	id := webhookEndpointID(endpoint)
+	circuit, err := l.loadWebhookCircuit(ctx, id)
+	if err != nil || circuit != nil {
+		return circuit, err
+	}
+	return &model.WebhookCircuit{EndpointID: id, Endpoint: endpoint, State: model.CircuitClosed}, nil
+}
+
+// saveWebhookCircuit stores a circuit and adds it to the circuits the prober visits.
+func (l *Blnk) saveWebhookCircuit(ctx context.Context, circuit *model.WebhookCircuit) error {
+	circuit.UpdatedAt = time.Now()
+	data, err := json.Marshal(circuit)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook circuit: %w", err)
+	}
+	pipe := l.redis.TxPipeline()
+	pipe.Set(ctx, webhookCircuitKey(circuit.EndpointID, ""), data, 0)
+	pipe.SAdd(ctx, webhookCircuitIndexKey, circuit.EndpointID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// sendThroughCircuit sends a webhook delivery through the circuit breaker of the configured endpoint.
+// While the circuit is not closed the delivery is parked instead of sent. A failure that brings the
+// endpoint's consecutive failures to the threshold opens the circuit, and the failed delivery is parked
+// so that it is not retried by the queue in the meantime.
+func (l *Blnk) sendThroughCircuit(ctx context.Context, conf *config.Configuration, task []byte, send func() error) error {
+	circuit, err := l.getWebhookCircuit(ctx, conf.Notification.Webhook.Url)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to read webhook circuit, delivering anyway")
+		return send()
+	}
+	if circuit.State != model.CircuitClosed {
+		return l.parkWebhook(ctx, circuit.EndpointID, task)
+	}
+
+	failuresKey := webhookCircuitKey(circuit.EndpointID, "failures")
+	sendErr := send()
+	if sendErr == nil {
+		l.redis.Del(ctx, failuresKey)
+		return nil
+	}
+
+	failures, err := l.redis.Incr(ctx, failuresKey).Result()
+	if err != nil || failures < int64(conf.WebhookCircuit.FailureThreshold) {
+		return sendErr
+	}
+	// Only the delivery that reaches the threshold opens the circuit; concurrent ones just park
+	if failures == int64(conf.WebhookCircuit.FailureThreshold) {
+		l.openWebhookCircuit(ctx, circuit, failures, conf.WebhookCircuit.ProbeInterval, sendErr)
+	}
+	return l.parkWebhook(ctx, circuit.EndpointID, task)
+}
+
+// parkWebhook holds back a delivery until the endpoint's circuit closes.
+func (l *Blnk) parkWebhook(ctx context.Context, endpointID string, task []byte) error {
+	return l.redis.RPush(ctx, webhookCircuitKey(endpointID, "parked"), task).Err()
+}
+
+// openWebhookCircuit marks an endpoint as degraded and notifies operators.
+func (l *Blnk) openWebhookCircuit(ctx context.Context, circuit *model.WebhookCircuit, failures int64, probeInterval time.Duration, cause error) {
+	now := time.Now()
+	nextProbe := now.Add(probeInterval)
+	circuit.State = model.CircuitOpen
+	circuit.ConsecutiveFailures = failures
+	circuit.ProbeInterval = probeInterval
+	circuit.LastError = cause.Error()
+	circuit.OpenedAt = &now
+	circuit.NextProbeAt = &nextProbe
+	if err := l.saveWebhookCircuit(ctx, circuit); err != nil {
+		notification.NotifyError(fmt.Errorf("failed to open webhook circuit: %w", err))
+		return
+	}
+
+	notification.NotifyError(fmt.Errorf("webhook endpoint %s is degraded after %d consecutive failures, deliveries are parked until it recovers: %w",
+		circuit.Endpoint, failures, cause))
+	l.sendWebhookCircuitEvent("webhook.circuit_opened", circuit)
+}
+
+// sendWebhookCircuitEvent publishes a change of circuit state. The event for an opened circuit is parked
+// with the other deliveries, so subscribers receive it once the endpoint recovers.
+func (l *Blnk) sendWebhookCircuitEvent(event string, circuit *model.WebhookCircuit) {
+	payload := *circuit
+	go func() {
+		if err := l.SendWebhook(NewWebhook{Event: event, Payload: payload}); err != nil {
+			notification.NotifyError(err)
+		}
+	}()
+}
+
+// ProbeWebhookCircuits sends a probe to every open webhook circuit whose probe is due. The probe is the
+// oldest parked delivery. A successful probe closes the circuit and releases the parked deliveries back
+// to the webhook queue; a failed probe keeps the circuit open and doubles the time until the next probe.
+// A circuit open for longer than the configured disable period is disabled instead of probed, and disabled
+// circuits are left alone. Circuits of endpoints that are no longer configured are closed, so their
+// deliveries go to the current one.
+//
+// Parameters:
+// - ctx: The context for the operation.
+//
+// Returns:
+// - int: The number of circuits closed.
+// - error: An error if the circuits could not be read.
+func (l *Blnk) ProbeWebhookCircuits(ctx context.Context) (int, error) {
+	conf, err := config.Fetch()
+	if err != nil {
+		return 0, err
+	}
+	ids, err := l.redis.SMembers(ctx, webhookCircuitIndexKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, id := range ids {
+		circuit, err := l.loadWebhookCircuit(ctx, id)
+		if err != nil {
+			logrus.WithError(err).WithField("endpoint_id", id).Error("failed to load webhook circuit")
+			continue
+		}
+		if circuit == nil {
+			l.redis.SRem(ctx, webhookCircuitIndexKey, id)
+			continue
+		}
+
+		if circuit.State != model.CircuitClosed && circuit.Endpoint == conf.Notification.Webhook.Url {
+			if circuit.State == model.CircuitDisabled {
+				continue
+			}
+			if disableAfter := conf.WebhookCircuit.DisableAfter; disableAfter > 0 && circuit.OpenedAt != nil && time.Since(*circuit.OpenedAt) >= disableAfter {
+				if err := l.disableWebhookCircuit(ctx, circuit, disableAfter); err != nil {
+					logrus.WithError(err).WithField("endpoint", circuit.Endpoint).Error("failed to disable webhook endpoint")
+				}
+				continue
+			}
+			if circuit.NextProbeAt != nil && time.Now().Before(*circuit.NextProbeAt) {
+				continue
+			}
+			acquired, err := l.redis.SetNX(ctx, webhookCircuitKey(id, "probe"), 1, webhookProbeLockTTL).Result()
+			if err != nil || !acquired {
+				continue
+			}
+			err = l.probeWebhookCircuit(ctx, conf, circuit)
+			l.redis.Del(ctx, webhookCircuitKey(id, "probe"))
+			if err != nil {
+				logrus.WithError(err).WithField("endpoint", circuit.Endpoint).Warn("webhook circuit probe failed")
+				continue
+			}
+		}
+
+		if err := l.closeWebhookCircuit(ctx, circuit); err != nil {
+			logrus.WithError(err).WithField("endpoint", circuit.Endpoint).Error("failed to close webhook circuit")
+			continue
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// disableWebhookCircuit disables an endpoint that has failed every delivery since its circuit opened, so it is
+// no longer probed, and alerts operators. Its deliveries stay parked until it is enabled again.
+func (l *Blnk) disableWebhookCircuit(ctx context.Context, circuit *model.WebhookCircuit, disableAfter time.Duration) error {
+	now := time.Now()
+	circuit.State = model.CircuitDisabled
+	circuit.NextProbeAt = nil
+	circuit.DisabledAt = &now
+	if err := l.saveWebhookCircuit(ctx, circuit); err != nil {
+		return err
+	}
+
+	notification.NotifyError(fmt.Errorf("webhook endpoint %s (%s) has been disabled after failing every delivery for %s; deliveries stay parked until it is enabled with POST /webhooks/%s/enable: %s",
+		circuit.Endpoint, circuit.EndpointID, disableAfter, circuit.EndpointID, circuit.LastError))
+	l.sendWebhookCircuitEvent("webhook.endpoint_disabled", circuit)
+	return nil
+}
+
+// probeWebhookCircuit sends the oldest parked delivery of an open circuit. The delivery is removed from the
+// parked list only when the probe succeeds.
+func (l *Blnk) probeWebhookCircuit(ctx context.Context, conf *config.Configuration, circuit *model.WebhookCircuit) error {
+	circuit.State = model.CircuitHalfOpen
+	if err := l.saveWebhookCircuit(ctx, circuit); err != nil {
+		return err
+	}
+
+	parkedKey := webhookCircuitKey(circuit.EndpointID, "parked")
+	task, err := l.redis.LIndex(ctx, parkedKey, 0).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// Nothing is waiting, so the next delivery finds out whether the endpoint recovered
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var webhook NewWebhook
+	if err := json.Unmarshal(task, &webhook); err != nil {
+		l.redis.LPop(ctx, parkedKey)
+		return fmt.Errorf("dropped malformed parked webhook: %w", err)
+	}
+
+	err = l.deliverWebhook(ctx, circuit.Endpoint, webhook, func() error {
+		return processHTTP(webhook, l.webhookClient(), l.webhookSigningSecrets(ctx))
+	})
+	if err != nil {
+		now := time.Now()
+		interval := min(circuit.ProbeInterval*2, conf.WebhookCircuit.MaxProbeInterval)
+		nextProbe := now.Add(interval)
+		circuit.State = model.CircuitOpen
+		circuit.ConsecutiveFailures++
+		circuit.ProbeInterval = interval
+		circuit.LastError = err.Error()
+		circuit.NextProbeAt = &nextProbe
+		if saveErr := l.saveWebhookCircuit(ctx, circuit); saveErr != nil {
+			logrus.WithError(saveErr).Error("failed to save webhook circuit")
+		}
+		return err
+	}
+
+	l.redis.LPop(ctx, parkedKey)
+	return nil
+}
+
+// closeWebhookCircuit closes a circuit and releases its parked deliveries back to the webhook queue in the
+// order they were parked. The circuit is marked closed before the release so the released deliveries are
+// sent rather than parked again, and is only removed once every delivery has been released.
+func (l *Blnk) closeWebhookCircuit(ctx context.Context, circuit *model.WebhookCircuit) error {
+	reopened := circuit.State != model.CircuitClosed
+	if reopened {
+		circuit.State = model.CircuitClosed
+		circuit.ConsecutiveFailures = 0
+		circuit.NextProbeAt = nil
+		if err := l.saveWebhookCircuit(ctx, circuit); err != nil {
+			return err
+		}
+		l.redis.Del(ctx, webhookCircuitKey(circuit.EndpointID, "failures"))
+	}
+
+	parkedKey := webhookCircuitKey(circuit.EndpointID, "parked")
+	for {
+		task, err := l.redis.LPop(ctx, parkedKey).Bytes()
+		if errors.Is(err, redis.Nil) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := l.enqueueWebhook(task); err != nil {
+			l.redis.LPush(ctx, parkedKey, task)
+			return err
+		}
+	}
+
+	pipe := l.redis.TxPipeline()
+	pipe.Del(ctx, webhookCircuitKey(circuit.EndpointID, ""))
+	pipe.SRem(ctx, webhookCircuitIndexKey, circuit.EndpointID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	if reopened {
+		logrus.WithField("endpoint", circuit.Endpoint).Info("webhook endpoint recovered, circuit closed")
+		l.sendWebhookCircuitEvent("webhook.circuit_closed", circuit)
+	}
+	return nil
+}
+
+// EnableWebhookEndpoint enables a disabled webhook endpoint again. A "webhook.ping" event is sent to the endpoint
+// first, and only when it is delivered is the circuit closed and the parked deliveries released to the webhook
+// queue. A failed ping leaves the endpoint disabled.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - endpointID: The ID of the endpoint, as listed with its circuit, or "current" for the configured endpoint.
+//
+// Returns:
+// - *model.WebhookCircuit: The circuit of the endpoint, closed.
+// - error: An error if the endpoint is not disabled or not the configured endpoint, or the ping failed.
+func (l *Blnk) EnableWebhookEndpoint(ctx context.Context, endpointID string) (*model.WebhookCircuit, error) {
+	conf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	if conf.Notification.Webhook.Url == "" {
+		return nil, apierror.NewAPIError(apierror.ErrNotFound, "no webhook endpoint is configured", nil)
+	}
+	if endpointID == CurrentWebhookEndpoint {
+		endpointID = webhookEndpointID(conf.Notification.Webhook.Url)
+	}
+
+	circuit, err := l.loadWebhookCircuit(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if circuit == nil || circuit.State != model.CircuitDisabled {
+		return nil, apierror.NewAPIError(apierror.ErrConflict, fmt.Sprintf("webhook endpoint %s is not disabled", endpointID), nil)
+	}
+	if circuit.Endpoint != conf.Notification.Webhook.Url {
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, fmt.Sprintf("webhook endpoint %s is no longer configured and is released to the current endpoint by the prober", endpointID), nil)
+	}
+
+	ping := NewWebhook{Event: "webhook.ping", Payload: map[string]interface{}{"endpoint_id": endpointID, "sent_at": time.Now().UTC()}}
+	err = l.deliverWebhook(ctx, circuit.Endpoint, ping, func() error {
+		return processHTTP(ping, l.webhookClient(), l.webhookSigningSecrets(ctx))
+	})
+	if err != nil {
+		circuit.LastError = err.Error()
+		if saveErr := l.saveWebhookCircuit(ctx, circuit); saveErr != nil {
+			logrus.WithError(saveErr).Error("failed to save webhook circuit")
+		}
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, fmt.Sprintf("verification ping failed, the endpoint stays disabled: %v", err), err)
+	}
+
+	if err := l.closeWebhookCircuit(ctx, circuit); err != nil {
+		return nil, err
+	}
+	circuit.DisabledAt = nil
+	logrus.WithField("endpoint", circuit.Endpoint).Info("webhook endpoint enabled after a successful verification ping")
+	return circuit, nil
+}
+
+// ListWebhookCircuits returns the circuits of webhook endpoints that are degraded or recovering.
+//
+// Parameters:
+// - ctx: The context for the operation.
+//
+// Returns:
+// - []*model.WebhookCircuit: The stored circuits with their consecutive failures and parked deliveries.
+// - error: An error if the circuits could not be read.
+func (l *Blnk) ListWebhookCircuits(ctx context.Context) ([]*model.WebhookCircuit, error) {
+	ids, err := l.redis.SMembers(ctx, webhookCircuitIndexKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	circuits := []*model.WebhookCircuit{}
+	for _, id := range ids {
+		circuit, err := l.loadWebhookCircuit(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if circuit == nil {
+			continue
+		}
+		if circuit.Parked, err = l.redis.LLen(ctx, webhookCircuitKey(id, "parked")).Result(); err != nil {
+			return nil, err
+		}
+		circuits = append(circuits, circuit)
+	}
+	return circuits, nil
+}
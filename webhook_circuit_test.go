@@ -0,0 +1,224 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newWebhookCircuitTestBlnk returns a Blnk instance delivering webhooks to a server that answers with the
+// status held in status.
+func newWebhookCircuitTestBlnk(t *testing.T, status *atomic.Int32, hits *atomic.Int32) (*Blnk, *miniredis.Miniredis) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(server.Close)
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis:        config.RedisConfig{Dns: mr.Addr()},
+		Queue:        config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+		Notification: config.Notification{Webhook: config.WebhookConfig{Url: server.URL}},
+		WebhookCircuit: config.WebhookCircuitConfig{
+			FailureThreshold: 2,
+			ProbeInterval:    time.Millisecond,
+			MaxProbeInterval: time.Hour,
+		},
+	})
+
+	ds := new(mocks.MockDataSource)
+	ds.On("RecordWebhookDelivery", mock.Anything, mock.Anything).Return(nil)
+
+	b, err := NewBlnk(ds)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = b.Close() })
+	return b, mr
+}
+
+func webhookTask(t *testing.T, event string) *asynq.Task {
+	payload, err := json.Marshal(NewWebhook{Event: event, Payload: map[string]interface{}{"id": event}})
+	require.NoError(t, err)
+	return asynq.NewTask("webhook_queue", payload)
+}
+
+func TestWebhookCircuit_OpensAfterConsecutiveFailures(t *testing.T) {
+	var status, hits atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	b, _ := newWebhookCircuitTestBlnk(t, &status, &hits)
+	ctx := context.Background()
+
+	assert.NoError(t, b.ProcessWebhook(ctx, webhookTask(t, "transaction.applied")))
+	circuits, err := b.ListWebhookCircuits(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, circuits)
+
+	assert.NoError(t, b.ProcessWebhook(ctx, webhookTask(t, "transaction.applied")))
+	circuits, err = b.ListWebhookCircuits(ctx)
+	require.NoError(t, err)
+	require.Len(t, circuits, 1)
+	assert.Equal(t, model.CircuitOpen, circuits[0].State)
+	assert.Equal(t, int64(2), circuits[0].ConsecutiveFailures)
+	assert.Equal(t, int64(1), circuits[0].Parked)
+	assert.Contains(t, circuits[0].LastError, "status 503")
+
+	// Deliveries are parked without reaching the endpoint while the circuit is open
+	assert.NoError(t, b.ProcessWebhook(ctx, webhookTask(t, "transaction.queued")))
+	assert.Equal(t, int32(2), hits.Load())
+	circuits, err = b.ListWebhookCircuits(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, circuits[0].Parked, int64(2))
+}
+
+func TestWebhookCircuit_SuccessResetsFailures(t *testing.T) {
+	var status, hits atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	b, _ := newWebhookCircuitTestBlnk(t, &status, &hits)
+	ctx := context.Background()
+
+	assert.NoError(t, b.ProcessWebhook(ctx, webhookTask(t, "transaction.applied")))
+	status.Store(http.StatusOK)
+	assert.NoError(t, b.ProcessWebhook(ctx, webhookTask(t, "transaction.applied")))
+	status.Store(http.StatusInternalServerError)
+	assert.NoError(t, b.ProcessWebhook(ctx, webhookTask(t, "transaction.applied")))
+
+	circuits, err := b.ListWebhookCircuits(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, circuits)
+}
+
+func TestWebhookCircuit_ProbeClosesAndReleases(t *testing.T) {
+	var status, hits atomic.Int32
+	status.Store(http.StatusBadGateway)
+	b, mr := newWebhookCircuitTestBlnk(t, &status, &hits)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, b.ProcessWebhook(ctx, webhookTask(t, "transaction.applied")))
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// A failed probe keeps the circuit open and backs off
+	closed, err := b.ProbeWebhookCircuits(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, closed)
+	circuits, err := b.ListWebhookCircuits(ctx)
+	require.NoError(t, err)
+	require.Len(t, circuits, 1)
+	assert.Equal(t, model.CircuitOpen, circuits[0].State)
+	assert.Equal(t, 2*time.Millisecond, circuits[0].ProbeInterval)
+	parked := circuits[0].Parked
+
+	time.Sleep(5 * time.Millisecond)
+	status.Store(http.StatusOK)
+	hitsBefore := hits.Load()
+	closed, err = b.ProbeWebhookCircuits(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, closed)
+	assert.Equal(t, hitsBefore+1, hits.Load())
+
+	circuits, err = b.ListWebhookCircuits(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, circuits)
+
+	// The probe delivered the oldest parked webhook and the rest went back to the queue
+	pending, err := mr.List("asynq:{webhook_queue}:pending")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, int64(len(pending)), parked-1)
+}
+
+func TestWebhookCircuit_DisablesDormantEndpoint(t *testing.T) {
+	var status, hits atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	b, mr := newWebhookCircuitTestBlnk(t, &status, &hits)
+	cnf, err := config.Fetch()
+	require.NoError(t, err)
+	cnf.WebhookCircuit.DisableAfter = time.Hour
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, b.ProcessWebhook(ctx, webhookTask(t, "transaction.applied")))
+	}
+	circuits, err := b.ListWebhookCircuits(ctx)
+	require.NoError(t, err)
+	require.Len(t, circuits, 1)
+	circuit := circuits[0]
+
+	// An endpoint open for less than the disable period is still probed
+	_, err = b.EnableWebhookEndpoint(ctx, CurrentWebhookEndpoint)
+	assert.ErrorContains(t, err, "is not disabled")
+
+	openedAt := time.Now().Add(-2 * time.Hour)
+	circuit.OpenedAt = &openedAt
+	require.NoError(t, b.saveWebhookCircuit(ctx, circuit))
+	hitsBefore := hits.Load()
+	closed, err := b.ProbeWebhookCircuits(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, closed)
+	assert.Equal(t, hitsBefore, hits.Load())
+
+	circuits, err = b.ListWebhookCircuits(ctx)
+	require.NoError(t, err)
+	require.Len(t, circuits, 1)
+	assert.Equal(t, model.CircuitDisabled, circuits[0].State)
+	assert.NotNil(t, circuits[0].DisabledAt)
+	assert.Nil(t, circuits[0].NextProbeAt)
+
+	// Disabled endpoints are not probed again
+	_, err = b.ProbeWebhookCircuits(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, hitsBefore, hits.Load())
+
+	// A failed verification ping leaves the endpoint disabled
+	_, err = b.EnableWebhookEndpoint(ctx, circuit.EndpointID)
+	assert.ErrorContains(t, err, "verification ping failed")
+	assert.Equal(t, hitsBefore+1, hits.Load())
+	circuits, err = b.ListWebhookCircuits(ctx)
+	require.NoError(t, err)
+	require.Len(t, circuits, 1)
+	assert.Equal(t, model.CircuitDisabled, circuits[0].State)
+
+	status.Store(http.StatusOK)
+	enabled, err := b.EnableWebhookEndpoint(ctx, CurrentWebhookEndpoint)
+	require.NoError(t, err)
+	assert.Equal(t, model.CircuitClosed, enabled.State)
+	circuits, err = b.ListWebhookCircuits(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, circuits)
+
+	pending, err := mr.List("asynq:{webhook_queue}:pending")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(pending), 2)
+}
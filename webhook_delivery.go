@@ -0,0 +1,114 @@
+package blnk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/sirupsen/logrus"
+)
+
+// CurrentWebhookEndpoint can be used in place of an endpoint ID to refer to the configured webhook endpoint.
+const CurrentWebhookEndpoint = "current"
+
+// defaultWebhookAnalyticsWindow is the window webhook analytics cover when none is chosen.
+const defaultWebhookAnalyticsWindow = "24h"
+
+// webhookAnalyticsWindows are the windows webhook analytics can cover, ending now.
+var webhookAnalyticsWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// deliverWebhook sends a webhook to an endpoint and records the delivery for the endpoint's analytics. A failure
+// to record the delivery is logged and does not fail it.
+func (l *Blnk) deliverWebhook(ctx context.Context, endpoint string, webhook NewWebhook, send func() error) error {
+	started := time.Now()
+	err := send()
+
+	delivery := &model.WebhookDelivery{
+		DeliveryID:  model.GenerateUUIDWithSuffix("whd"),
+		EndpointID:  webhookEndpointID(endpoint),
+		Event:       webhook.Event,
+		Succeeded:   err == nil,
+		LatencyMs:   time.Since(started).Milliseconds(),
+		DeliveredAt: started.UTC(),
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+		var statusErr *webhookStatusError
+		if errors.As(err, &statusErr) {
+			delivery.StatusCode = statusErr.StatusCode
+		}
+	}
+	if recordErr := l.datasource.RecordWebhookDelivery(ctx, delivery); recordErr != nil {
+		logrus.WithError(recordErr).WithField("event", webhook.Event).Warn("failed to record webhook delivery")
+	}
+	return err
+}
+
+// GetWebhookAnalytics summarises the deliveries to a webhook endpoint within a window ending now: their success
+// rate, their median and 95th percentile latency, the statuses failed deliveries were answered with and the
+// volume of each event type.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+// - endpointID string: The ID of the endpoint, as listed with its circuit, or "current" for the configured endpoint.
+// - window string: One of "1h", "24h", "7d" and "30d", or empty for the last 24 hours.
+//
+// Returns:
+// - *model.WebhookAnalytics: The summary of the deliveries.
+// - error: An error if the window is unknown or the deliveries could not be summarised.
+func (l *Blnk) GetWebhookAnalytics(ctx context.Context, endpointID, window string) (*model.WebhookAnalytics, error) {
+	ctx, span := tracer.Start(ctx, "GetWebhookAnalytics")
+	defer span.End()
+
+	if window == "" {
+		window = defaultWebhookAnalyticsWindow
+	}
+	duration, ok := webhookAnalyticsWindows[window]
+	if !ok {
+		return nil, apierror.NewAPIError(apierror.ErrInvalidInput, fmt.Sprintf("invalid window %q, expected one of 1h, 24h, 7d or 30d", window), nil)
+	}
+	if endpointID == CurrentWebhookEndpoint {
+		cnf, err := config.Fetch()
+		if err != nil {
+			return nil, err
+		}
+		if cnf.Notification.Webhook.Url == "" {
+			return nil, apierror.NewAPIError(apierror.ErrNotFound, "no webhook endpoint is configured", nil)
+		}
+		endpointID = webhookEndpointID(cnf.Notification.Webhook.Url)
+	}
+
+	to := time.Now().UTC()
+	analytics, err := l.datasource.GetWebhookAnalytics(ctx, endpointID, to.Add(-duration), to)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	analytics.Window = window
+	return analytics, nil
+}
+
+// PruneWebhookDeliveries deletes the records of webhook deliveries older than the configured retention.
+//
+// Parameters:
+// - ctx context.Context: The context for the operation.
+//
+// Returns:
+// - int64: The number of records deleted.
+// - error: An error if the records could not be deleted.
+func (l *Blnk) PruneWebhookDeliveries(ctx context.Context) (int64, error) {
+	cnf, err := config.Fetch()
+	if err != nil {
+		return 0, err
+	}
+	return l.datasource.PruneWebhookDeliveries(ctx, time.Now().UTC().Add(-cnf.Notification.Webhook.DeliveryRetention))
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/internal/apierror"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessWebhook_RecordsDelivery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis:        config.RedisConfig{Dns: mr.Addr()},
+		Queue:        config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+		Notification: config.Notification{Webhook: config.WebhookConfig{Url: server.URL}},
+	})
+
+	var recorded *model.WebhookDelivery
+	ds := new(mocks.MockDataSource)
+	ds.On("RecordWebhookDelivery", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(*model.WebhookDelivery)
+	}).Return(nil)
+
+	b, err := NewBlnk(ds)
+	require.NoError(t, err)
+	defer b.Close()
+
+	assert.NoError(t, b.ProcessWebhook(context.Background(), webhookTask(t, "transaction.applied")))
+	require.NotNil(t, recorded)
+	assert.Equal(t, webhookEndpointID(server.URL), recorded.EndpointID)
+	assert.Equal(t, "transaction.applied", recorded.Event)
+	assert.False(t, recorded.Succeeded)
+	assert.Equal(t, http.StatusBadRequest, recorded.StatusCode)
+}
+
+func TestGetWebhookAnalytics(t *testing.T) {
+	config.ConfigStore.Store(&config.Configuration{
+		Notification: config.Notification{Webhook: config.WebhookConfig{Url: "https://example.com/hooks"}},
+	})
+	ds := new(mocks.MockDataSource)
+	b := &Blnk{datasource: ds}
+	endpointID := webhookEndpointID("https://example.com/hooks")
+
+	ds.On("GetWebhookAnalytics", mock.Anything, endpointID, mock.Anything, mock.Anything).
+		Return(&model.WebhookAnalytics{EndpointID: endpointID}, nil).
+		Run(func(args mock.Arguments) {
+			from, to := args.Get(2).(time.Time), args.Get(3).(time.Time)
+			assert.Equal(t, 7*24*time.Hour, to.Sub(from))
+		})
+
+	analytics, err := b.GetWebhookAnalytics(context.Background(), CurrentWebhookEndpoint, "7d")
+	require.NoError(t, err)
+	assert.Equal(t, "7d", analytics.Window)
+
+	_, err = b.GetWebhookAnalytics(context.Background(), endpointID, "2w")
+	var apiErr apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.ErrInvalidInput, apiErr.Code)
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/internal/hooks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/redis/go-redis/v9"
+	"github.com/wacul/ptr"
+)
+
+const (
+	webhookReplayKeyPrefix  = "webhook-replays"
+	webhookReplayTTL        = 7 * 24 * time.Hour
+	webhookReplayPageSize   = 100
+	webhookReplaySaveEvery  = 50
+	defaultReplayRate       = 10
+	maxReplayRatePerSecond  = 100
+	webhookReplayDeliveryTO = 10 * time.Second
+)
+
+// ReplayWebhooks starts a background re-delivery of all events for a resource type created within a time window.
+// Events are rebuilt from the stored records and posted to the requested URL at no more than RatePerSecond.
+// The URL must be the configured webhook URL or the URL of a registered hook, so that a replay cannot send
+// records to an arbitrary endpoint. Supported resource types are "transactions", "balances" and "ledgers".
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - replay: The replay request describing the resource type, window, endpoint and rate.
+//
+// Returns:
+// - *model.WebhookReplay: The created replay, whose progress can be polled with GetWebhookReplay.
+// - error: An error if the request is invalid or the replay cannot be recorded.
+func (l *Blnk) ReplayWebhooks(ctx context.Context, replay model.WebhookReplay) (*model.WebhookReplay, error) {
+	if err := validateWebhookReplay(&replay); err != nil {
+		return nil, err
+	}
+	if err := l.checkReplayTarget(ctx, replay.URL); err != nil {
+		return nil, err
+	}
+
+	replay.ReplayID = model.GenerateUUIDWithSuffix("replay")
+	replay.Status = model.ReplayStatusRunning
+	replay.CreatedAt = time.Now()
+
+	if err := l.saveWebhookReplay(ctx, &replay); err != nil {
+		return nil, err
+	}
+
+	// The replay outlives the request but keeps its values, such as the tenant
+	go l.runWebhookReplay(context.WithoutCancel(ctx), replay)
+
+	return &replay, nil
+}
+
+// GetWebhookReplay retrieves the current state of a webhook replay.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - replayID: The ID of the replay.
+//
+// Returns:
+// - *model.WebhookReplay: The replay and its delivery counters.
+// - error: An error if the replay does not exist or cannot be read.
+func (l *Blnk) GetWebhookReplay(ctx context.Context, replayID string) (*model.WebhookReplay, error) {
+	data, err := l.redis.Get(ctx, fmt.Sprintf("%s:%s", webhookReplayKeyPrefix, replayID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("webhook replay not found: %s", replayID)
+		}
+		return nil, err
+	}
+
+	var replay model.WebhookReplay
+	if err := json.Unmarshal(data, &replay); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal webhook replay: %w", err)
+	}
+	return &replay, nil
+}
+
+// validateWebhookReplay checks a replay request and applies the default rate.
+func validateWebhookReplay(replay *model.WebhookReplay) error {
+	switch replay.ResourceType {
+	case "transactions", "balances", "ledgers":
+	default:
+		return fmt.Errorf("unsupported resource type: %s", replay.ResourceType)
+	}
+
+	if _, err := url.ParseRequestURI(replay.URL); err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+
+	if replay.StartDate.IsZero() || replay.EndDate.IsZero() {
+		return errors.New("start_date and end_date are required")
+	}
+	if replay.EndDate.Before(replay.StartDate) {
+		return errors.New("end_date must be after start_date")
+	}
+
+	if replay.RatePerSecond <= 0 {
+		replay.RatePerSecond = defaultReplayRate
+	}
+	if replay.RatePerSecond > maxReplayRatePerSecond {
+		return fmt.Errorf("rate_per_second cannot exceed %d", maxReplayRatePerSecond)
+	}
+	return nil
+}
+
+// checkReplayTarget reports an error unless the target is the configured webhook URL or belongs to a
+// registered hook.
+func (l *Blnk) checkReplayTarget(ctx context.Context, target string) error {
+	if conf, err := config.Fetch(); err == nil && conf.Notification.Webhook.Url != "" && conf.Notification.Webhook.Url == target {
+		return nil
+	}
+
+	for _, hookType := range []hooks.HookType{hooks.PreTransaction, hooks.PostTransaction} {
+		registered, err := l.Hooks.ListHooks(ctx, hookType)
+		if err != nil {
+			return err
+		}
+		for _, hook := range registered {
+			if hook.URL == target {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("url %s is neither the configured webhook url nor a registered hook", target)
+}
+
+// saveWebhookReplay persists the replay state so that progress survives across requests.
+func (l *Blnk) saveWebhookReplay(ctx context.Context, replay *model.WebhookReplay) error {
+	data, err := json.Marshal(replay)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook replay: %w", err)
+	}
+	key := fmt.Sprintf("%s:%s", webhookReplayKeyPrefix, replay.ReplayID)
+	return l.redis.Set(ctx, key, data, webhookReplayTTL).Err()
+}
+
+// runWebhookReplay delivers the replayed events at the requested rate and records the outcome.
+func (l *Blnk) runWebhookReplay(ctx context.Context, replay model.WebhookReplay) {
+	ticker := time.NewTicker(time.Second / time.Duration(replay.RatePerSecond))
+	defer ticker.Stop()
+
+	deliver := func(event NewWebhook) {
+		<-ticker.C
+		if err := postWebhook(ctx, l.webhookClient(), replay.URL, replay.Headers, l.webhookSigningSecrets(ctx), event); err != nil {
+			replay.Failed++
+			log.Printf("Webhook replay %s: delivery failed: %v", replay.ReplayID, err)
+		} else {
+			replay.Delivered++
+		}
+
+		if (replay.Delivered+replay.Failed)%webhookReplaySaveEvery == 0 {
+			if err := l.saveWebhookReplay(ctx, &replay); err != nil {
+				log.Printf("Webhook replay %s: failed to save progress: %v", replay.ReplayID, err)
+			}
+		}
+	}
+
+	err := l.replayEvents(ctx, replay, deliver)
+
+	replay.CompletedAt = ptr.Time(time.Now())
+	replay.Status = model.ReplayStatusCompleted
+	if err != nil {
+		replay.Status = model.ReplayStatusFailed
+		replay.Error = err.Error()
+	}
+	if err := l.saveWebhookReplay(ctx, &replay); err != nil {
+		log.Printf("Webhook replay %s: failed to save result: %v", replay.ReplayID, err)
+	}
+}
+
+// replayEvents rebuilds the events for the replay's resource type and window and passes each to deliver.
+func (l *Blnk) replayEvents(ctx context.Context, replay model.WebhookReplay, deliver func(NewWebhook)) error {
+	switch replay.ResourceType {
+	case "transactions":
+		page := model.Page{Limit: webhookReplayPageSize}
+		for {
+			txns, err := l.datasource.GetTransactionsCreatedBetween(ctx, replay.StartDate, replay.EndDate, page)
+			if err != nil {
+				return err
+			}
+			for _, txn := range txns {
+				deliver(NewWebhook{Event: getEventFromStatus(txn.Status), Payload: txn})
+			}
+			if len(txns) < webhookReplayPageSize {
+				return nil
+			}
+			page.After = model.NextPageCursor(txns, webhookReplayPageSize, (*model.Transaction).PageCursor)
+		}
+
+	case "balances":
+		// Balances are listed newest first, so paging stops once the window has been passed.
+		page := model.Page{Limit: webhookReplayPageSize}
+		for {
+			balances, err := l.datasource.GetAllBalances(ctx, page)
+			if err != nil {
+				return err
+			}
+			for _, balance := range balances {
+				if balance.CreatedAt.Before(replay.StartDate) {
+					return nil
+				}
+				if !balance.CreatedAt.After(replay.EndDate) {
+					deliver(NewWebhook{Event: "balance.created", Payload: balance})
+				}
+			}
+			if len(balances) < webhookReplayPageSize {
+				return nil
+			}
+			page.After = model.NextPageCursor(balances, webhookReplayPageSize, model.Balance.PageCursor)
+		}
+
+	case "ledgers":
+		page := model.Page{Limit: webhookReplayPageSize}
+		for {
+			ledgers, err := l.datasource.GetAllLedgers(ctx, page)
+			if err != nil {
+				return err
+			}
+			for _, ledger := range ledgers {
+				if ledger.CreatedAt.Before(replay.StartDate) {
+					return nil
+				}
+				if !ledger.CreatedAt.After(replay.EndDate) {
+					deliver(NewWebhook{Event: "ledger.created", Payload: ledger})
+				}
+			}
+			if len(ledgers) < webhookReplayPageSize {
+				return nil
+			}
+			page.After = model.NextPageCursor(ledgers, webhookReplayPageSize, model.Ledger.PageCursor)
+		}
+	}
+
+	return fmt.Errorf("unsupported resource type: %s", replay.ResourceType)
+}
+
+// postWebhook delivers a single signed webhook to the given URL, rendered with the URL's payload template for the
+// event when it has one, and treats any non-2XX response as a failure.
+func postWebhook(ctx context.Context, client *http.Client, target string, headers map[string]string, secrets []string, data NewWebhook) error {
+	payload, contentType, err := webhookBody(target, data)
+	if err != nil {
+		return err
+	}
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookReplayDeliveryTO)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if signature := signWebhookPayload(payload, secrets, time.Now()); signature != "" {
+		req.Header.Set(WebhookSignatureHeader, signature)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/blnkfinance/blnk/internal/hooks"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateWebhookReplay(t *testing.T) {
+	now := time.Now()
+	valid := model.WebhookReplay{
+		ResourceType: "ledgers",
+		URL:          "http://example.com/hook",
+		StartDate:    now.Add(-time.Hour),
+		EndDate:      now,
+	}
+
+	replay := valid
+	assert.NoError(t, validateWebhookReplay(&replay))
+	assert.Equal(t, defaultReplayRate, replay.RatePerSecond)
+
+	replay = valid
+	replay.ResourceType = "identities"
+	assert.Error(t, validateWebhookReplay(&replay))
+
+	replay = valid
+	replay.URL = ""
+	assert.Error(t, validateWebhookReplay(&replay))
+
+	replay = valid
+	replay.EndDate = now.Add(-2 * time.Hour)
+	assert.Error(t, validateWebhookReplay(&replay))
+
+	replay = valid
+	replay.RatePerSecond = maxReplayRatePerSecond + 1
+	assert.Error(t, validateWebhookReplay(&replay))
+}
+
+func TestReplayWebhooks_Ledgers(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	var mu sync.Mutex
+	var received []NewWebhook
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "replay", r.Header.Get("X-Source"))
+		var event NewWebhook
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis:        config.RedisConfig{Dns: mr.Addr()},
+		Queue:        config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+		Notification: config.Notification{Webhook: config.WebhookConfig{Url: server.URL}},
+	})
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	mockDS := new(mocks.MockDataSource)
+	mockDS.On("GetAllLedgers", mock.Anything, model.Page{Limit: webhookReplayPageSize}).Return([]model.Ledger{
+		{LedgerID: "ldg_after", CreatedAt: end.Add(time.Hour)},
+		{LedgerID: "ldg_inside", CreatedAt: start.Add(time.Hour)},
+		{LedgerID: "ldg_before", CreatedAt: start.Add(-time.Hour)},
+	}, nil)
+
+	b, err := NewBlnk(mockDS)
+	assert.NoError(t, err)
+
+	replay, err := b.ReplayWebhooks(context.Background(), model.WebhookReplay{
+		ResourceType:  "ledgers",
+		URL:           server.URL,
+		Headers:       map[string]string{"X-Source": "replay"},
+		StartDate:     start,
+		EndDate:       end,
+		RatePerSecond: 50,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, model.ReplayStatusRunning, replay.Status)
+
+	assert.Eventually(t, func() bool {
+		state, err := b.GetWebhookReplay(context.Background(), replay.ReplayID)
+		return err == nil && state.Status == model.ReplayStatusCompleted
+	}, 5*time.Second, 20*time.Millisecond)
+
+	state, err := b.GetWebhookReplay(context.Background(), replay.ReplayID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, state.Delivered)
+	assert.Equal(t, 0, state.Failed)
+	assert.NotNil(t, state.CompletedAt)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, received, 1)
+	assert.Equal(t, "ledger.created", received[0].Event)
+}
+
+func TestReplayWebhooks_RejectsUnregisteredURL(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis:        config.RedisConfig{Dns: mr.Addr()},
+		Queue:        config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+		Notification: config.Notification{Webhook: config.WebhookConfig{Url: "https://hooks.example.com/blnk"}},
+	})
+
+	b, err := NewBlnk(new(mocks.MockDataSource))
+	assert.NoError(t, err)
+	assert.NoError(t, b.Hooks.RegisterHook(context.Background(), &hooks.Hook{
+		Name: "fraud-check",
+		URL:  "https://fraud.example.com/check",
+		Type: hooks.PreTransaction,
+	}))
+
+	replay := model.WebhookReplay{
+		ResourceType: "ledgers",
+		StartDate:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:      time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	replay.URL = "https://attacker.example.com/collect"
+	_, err = b.ReplayWebhooks(context.Background(), replay)
+	assert.ErrorContains(t, err, "neither the configured webhook url nor a registered hook")
+
+	assert.NoError(t, b.checkReplayTarget(context.Background(), "https://hooks.example.com/blnk"))
+	assert.NoError(t, b.checkReplayTarget(context.Background(), "https://fraud.example.com/check"))
+}
+
+func TestGetWebhookReplay_NotFound(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis: config.RedisConfig{Dns: mr.Addr()},
+		Queue: config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+	})
+
+	b, err := NewBlnk(new(mocks.MockDataSource))
+	assert.NoError(t, err)
+
+	_, err = b.GetWebhookReplay(context.Background(), "replay_missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/model"
+	"github.com/redis/go-redis/v9"
+	"github.com/wacul/ptr"
+)
+
+const (
+	webhookSigningSecretKey = "webhook-signing-secret"
+	// WebhookSignatureHeader carries the HMAC signatures of a webhook delivery.
+	WebhookSignatureHeader = "X-Blnk-Signature"
+)
+
+// signWebhookPayload builds the signature header value for a payload.
+// Each secret produces a "v1" entry over "<timestamp>.<payload>", so receivers that know either the
+// current or the previous secret can verify the delivery during a rotation.
+//
+// Parameters:
+// - payload []byte: The request body being sent.
+// - secrets []string: The secrets to sign with.
+// - timestamp time.Time: The signing time.
+//
+// Returns:
+// - string: The header value, or an empty string when there are no secrets.
+func signWebhookPayload(payload []byte, secrets []string, timestamp time.Time) string {
+	if len(secrets) == 0 {
+		return ""
+	}
+
+	ts := fmt.Sprintf("%d", timestamp.Unix())
+	parts := []string{"t=" + ts}
+	for _, secret := range secrets {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(ts + "."))
+		mac.Write(payload)
+		parts = append(parts, "v1="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return strings.Join(parts, ",")
+}
+
+// getWebhookSigningSecret loads the signing secret from Redis, falling back to the configured secret
+// when it has never been rotated.
+func (l *Blnk) getWebhookSigningSecret(ctx context.Context) (*model.WebhookSigningSecret, error) {
+	data, err := l.redis.Get(ctx, webhookSigningSecretKey).Bytes()
+	if err == nil {
+		var secret model.WebhookSigningSecret
+		if err := json.Unmarshal(data, &secret); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal webhook signing secret: %w", err)
+		}
+		return &secret, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	conf, err := config.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	return &model.WebhookSigningSecret{Current: conf.Notification.Webhook.SigningSecret}, nil
+}
+
+// webhookSigningSecrets returns the secrets that outgoing deliveries should be signed with.
+func (l *Blnk) webhookSigningSecrets(ctx context.Context) []string {
+	secret, err := l.getWebhookSigningSecret(ctx)
+	if err != nil {
+		return nil
+	}
+	return secret.ActiveSecrets()
+}
+
+// RotateWebhookSigningSecret generates a new webhook signing secret. Deliveries are signed with both
+// the new and the old secret until the overlap period ends.
+//
+// Parameters:
+// - ctx: The context for the operation.
+// - overlap: How long the old secret keeps signing deliveries. The configured default is used when zero.
+//
+// Returns:
+// - *model.WebhookSigningSecret: The new signing secret. This is the only time the secret is returned.
+// - error: An error if the secret cannot be generated or stored.
+func (l *Blnk) RotateWebhookSigningSecret(ctx context.Context, overlap time.Duration) (*model.WebhookSigningSecret, error) {
+	existing, err := l.getWebhookSigningSecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	newSecret, err := model.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	secret := &model.WebhookSigningSecret{
+		Current:   newSecret,
+		RotatedAt: &now,
+	}
+	if existing.Current != "" {
+		secret.Previous = existing.Current
+		secret.PreviousExpiresAt = ptr.Time(now.Add(rotationOverlap(overlap)))
+	}
+
+	if err := l.saveWebhookSigningSecret(ctx, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// ExpireWebhookSigningSecretRotation stops signing deliveries with the previous secret immediately.
+//
+// Parameters:
+// - ctx: The context for the operation.
+//
+// Returns:
+// - error: An error if there is no rotation in progress or the state cannot be stored.
+func (l *Blnk) ExpireWebhookSigningSecretRotation(ctx context.Context) error {
+	secret, err := l.getWebhookSigningSecret(ctx)
+	if err != nil {
+		return err
+	}
+	if !secret.IsRotating() {
+		return errors.New("no webhook signing secret rotation in progress")
+	}
+
+	secret.Previous = ""
+	secret.PreviousExpiresAt = nil
+	return l.saveWebhookSigningSecret(ctx, secret)
+}
+
+// GetWebhookSigningSecretState returns the rotation state of the webhook signing secret without exposing the secrets.
+//
+// Parameters:
+// - ctx: The context for the operation.
+//
+// Returns:
+// - *model.WebhookSigningSecretState: The rotation state.
+// - error: An error if the state cannot be loaded.
+func (l *Blnk) GetWebhookSigningSecretState(ctx context.Context) (*model.WebhookSigningSecretState, error) {
+	secret, err := l.getWebhookSigningSecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &model.WebhookSigningSecretState{
+		Configured: secret.Current != "",
+		Rotating:   secret.IsRotating(),
+		RotatedAt:  secret.RotatedAt,
+	}
+	if state.Rotating {
+		state.PreviousExpiresAt = secret.PreviousExpiresAt
+	}
+	return state, nil
+}
+
+// saveWebhookSigningSecret stores the signing secret state in Redis.
+func (l *Blnk) saveWebhookSigningSecret(ctx context.Context, secret *model.WebhookSigningSecret) error {
+	data, err := json.Marshal(secret)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook signing secret: %w", err)
+	}
+	return l.redis.Set(ctx, webhookSigningSecretKey, data, 0).Err()
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 Blnk Finance Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blnk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blnkfinance/blnk/config"
+	"github.com/blnkfinance/blnk/database/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	payload := []byte(`{"event":"ledger.created"}`)
+	ts := time.Unix(1700000000, 0)
+
+	assert.Empty(t, signWebhookPayload(payload, nil, ts))
+
+	header := signWebhookPayload(payload, []string{"new-secret", "old-secret"}, ts)
+	parts := strings.Split(header, ",")
+	assert.Len(t, parts, 3)
+	assert.Equal(t, "t=1700000000", parts[0])
+
+	mac := hmac.New(sha256.New, []byte("old-secret"))
+	mac.Write([]byte("1700000000."))
+	mac.Write(payload)
+	assert.Equal(t, "v1="+hex.EncodeToString(mac.Sum(nil)), parts[2])
+}
+
+func TestRotateWebhookSigningSecret(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	config.ConfigStore.Store(&config.Configuration{
+		Redis: config.RedisConfig{Dns: mr.Addr()},
+		Queue: config.QueueConfig{WebhookQueue: "webhook_queue", NumberOfQueues: 1},
+		Notification: config.Notification{
+			Webhook: config.WebhookConfig{SigningSecret: "configured-secret"},
+		},
+	})
+
+	b, err := NewBlnk(new(mocks.MockDataSource))
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	assert.Equal(t, []string{"configured-secret"}, b.webhookSigningSecrets(ctx))
+
+	secret, err := b.RotateWebhookSigningSecret(ctx, time.Hour)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "configured-secret", secret.Current)
+	assert.Equal(t, []string{secret.Current, "configured-secret"}, b.webhookSigningSecrets(ctx))
+
+	state, err := b.GetWebhookSigningSecretState(ctx)
+	assert.NoError(t, err)
+	assert.True(t, state.Configured)
+	assert.True(t, state.Rotating)
+	assert.NotNil(t, state.PreviousExpiresAt)
+
+	assert.NoError(t, b.ExpireWebhookSigningSecretRotation(ctx))
+	assert.Equal(t, []string{secret.Current}, b.webhookSigningSecrets(ctx))
+	assert.Error(t, b.ExpireWebhookSigningSecretRotation(ctx))
+}
@@ -17,10 +17,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -57,29 +59,35 @@ func getEventFromStatus(status string) string {
 		return "transaction.void"
 	case strings.ToLower(StatusRejected):
 		return "transaction.rejected"
+	case strings.ToLower(StatusDeferred):
+		return "transaction.deferred"
+	case strings.ToLower(StatusChallenged):
+		return "transaction.challenged"
 	default:
 		return "transaction.unknown"
 	}
 }
 
-// processHTTP sends a webhook notification via HTTP POST request.
+// processHTTP sends a webhook notification via HTTP POST request. The body is rendered with the endpoint's
+// payload template for the event when it has one.
 //
 // Parameters:
 // - data NewWebhook: The webhook notification data to send.
 // - client *http.Client: The HTTP client to use for the request.
+// - secrets []string: The signing secrets used to build the signature header, if any.
 //
 // Returns:
-// - error: An error if the request or processing fails.
-func processHTTP(data NewWebhook, client *http.Client) error {
+// - error: An error if the request fails or the endpoint answers with a status outside the 2XX range.
+func processHTTP(data NewWebhook, client *http.Client, secrets []string) error {
 	conf, err := config.Fetch()
 	if err != nil {
 		log.Println("Error fetching config:", err)
 		return err
 	}
 
-	jsonData, err := json.Marshal(data)
+	jsonData, contentType, err := webhookBody(conf.Notification.Webhook.Url, data)
 	if err != nil {
-		log.Println("Error marshaling data:", err)
+		log.Println("Error encoding data:", err)
 		return err
 	}
 	payload := bytes.NewBuffer(jsonData)
@@ -90,9 +98,15 @@ func processHTTP(data NewWebhook, client *http.Client) error {
 		return err
 	}
 
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 	for key, value := range conf.Notification.Webhook.Headers {
 		req.Header.Set(key, value)
 	}
+	if signature := signWebhookPayload(jsonData, secrets, time.Now()); signature != "" {
+		req.Header.Set(WebhookSignatureHeader, signature)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -109,7 +123,7 @@ func processHTTP(data NewWebhook, client *http.Client) error {
 	// Check if the status code is not in the 2XX success range
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		log.Printf("Request failed with status code: %d\n", resp.StatusCode)
-		return nil
+		return &webhookStatusError{StatusCode: resp.StatusCode}
 	}
 
 	// Read the response body
@@ -151,6 +165,16 @@ func (b *Blnk) SendWebhook(newWebhook NewWebhook) error {
 	if err != nil {
 		return err
 	}
+	return b.enqueueWebhook(payload)
+}
+
+// enqueueWebhook adds an encoded webhook notification to the webhook queue.
+func (b *Blnk) enqueueWebhook(payload []byte) error {
+	conf, err := config.Fetch()
+	if err != nil {
+		return err
+	}
+
 	taskOptions := []asynq.Option{asynq.Queue(conf.Queue.WebhookQueue)}
 	task := asynq.NewTask(conf.Queue.WebhookQueue, payload, taskOptions...)
 	info, err := b.asynqClient.Enqueue(task)
@@ -161,15 +185,17 @@ func (b *Blnk) SendWebhook(newWebhook NewWebhook) error {
 	return err
 }
 
-// ProcessWebhook processes a webhook notification task from the queue.
+// ProcessWebhook processes a webhook notification task from the queue. Deliveries go through the
+// endpoint's circuit breaker, which parks them while the endpoint is degraded, and every delivery sent is
+// recorded for the endpoint's analytics.
 //
 // Parameters:
-// - _ context.Context: The context for the operation.
+// - ctx context.Context: The context for the operation.
 // - task *asynq.Task: The task containing the webhook notification data.
 //
 // Returns:
 // - error: An error if the webhook processing fails.
-func (b *Blnk) ProcessWebhook(_ context.Context, task *asynq.Task) error {
+func (b *Blnk) ProcessWebhook(ctx context.Context, task *asynq.Task) error {
 	conf, err := config.Fetch()
 	if err != nil {
 		return err
@@ -183,9 +209,21 @@ func (b *Blnk) ProcessWebhook(_ context.Context, task *asynq.Task) error {
 		log.Printf("Error unmarshaling task payload: %v", err)
 		return err
 	}
-	err = processHTTP(payload, b.httpClient)
-	if err != nil {
-		return err
+	send := func() error {
+		return b.deliverWebhook(ctx, conf.Notification.Webhook.Url, payload, func() error {
+			return processHTTP(payload, b.webhookClient(), b.webhookSigningSecrets(ctx))
+		})
 	}
-	return nil
+	if conf.WebhookCircuit.FailureThreshold > 0 {
+		err = b.sendThroughCircuit(ctx, conf, task.Payload(), send)
+	} else {
+		err = send()
+	}
+
+	// Deliveries the endpoint answered are not retried by the queue; they only count toward the breaker
+	var statusErr *webhookStatusError
+	if errors.As(err, &statusErr) {
+		return nil
+	}
+	return err
 }
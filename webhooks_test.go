@@ -142,7 +142,7 @@ func TestConnectionReuse(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := processHTTP(testWebhook, blnk.httpClient)
+			err := processHTTP(testWebhook, blnk.httpClient, nil)
 			assert.NoError(t, err)
 		}()
 	}
@@ -181,10 +181,16 @@ func TestHTTPClientConfiguration(t *testing.T) {
 	}
 	defer mr.Close()
 
+	// Egress timeouts are defaulted when the configuration is loaded, so they are set here
 	cnf := &config.Configuration{
 		Redis: config.RedisConfig{
 			Dns: mr.Addr(),
 		},
+		Notification: config.Notification{
+			Webhook: config.WebhookConfig{
+				Egress: config.EgressConfig{ConnectTimeout: 10, TLSHandshakeTimeout: 10, RequestTimeout: 30},
+			},
+		},
 	}
 	config.ConfigStore.Store(cnf)
 
@@ -253,8 +259,8 @@ func TestProcessWebhookWithReusedClient(t *testing.T) {
 	webhook2 := NewWebhook{Event: "test.event2", Payload: map[string]string{"id": "2"}}
 
 	// Process webhooks using the same client
-	err1 := processHTTP(webhook1, blnk.httpClient)
-	err2 := processHTTP(webhook2, blnk.httpClient)
+	err1 := processHTTP(webhook1, blnk.httpClient, nil)
+	err2 := processHTTP(webhook2, blnk.httpClient, nil)
 
 	assert.NoError(t, err1)
 	assert.NoError(t, err2)